@@ -0,0 +1,225 @@
+package libp2p
+
+import (
+	"fmt"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/p2p/muxer/yamux"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/holepunch"
+	"github.com/TheNoobiCat/go-libp2p/p2p/security/noise"
+	tls "github.com/TheNoobiCat/go-libp2p/p2p/security/tls"
+	quic "github.com/TheNoobiCat/go-libp2p/p2p/transport/quic"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcp"
+	libp2pwebrtc "github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc"
+	ws "github.com/TheNoobiCat/go-libp2p/p2p/transport/websocket"
+	webtransport "github.com/TheNoobiCat/go-libp2p/p2p/transport/webtransport"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// BuilderError is returned by a Builder when applying one of its steps fails.
+type BuilderError struct {
+	// Step is the index of the failed step, in the order the steps were added.
+	Step int
+	// Name is the name of the failed step, e.g. "WithTCP".
+	Name string
+	Err  error
+}
+
+func (e *BuilderError) Error() string {
+	return fmt.Sprintf("libp2p builder: step %d (%s): %s", e.Step, e.Name, e.Err)
+}
+
+func (e *BuilderError) Unwrap() error { return e.Err }
+
+// BuilderStep is a named configuration step of a Builder.
+type BuilderStep struct {
+	Name   string
+	Option Option
+}
+
+// Builder is a fluent alternative to passing options to New. Every method takes
+// typed arguments and records a named step, so that errors point at the step that
+// caused them and the configuration can be inspected with Steps:
+//
+//	h, err := libp2p.NewBuilder().
+//		WithIdentity(priv).
+//		WithTCP().
+//		WithQUIC().
+//		WithListenAddrStrings("/ip4/0.0.0.0/tcp/0", "/ip4/0.0.0.0/udp/0/quic-v1").
+//		Build()
+//
+// Like New, Build falls back on the defaults for everything that wasn't configured,
+// unless WithoutDefaults is used. Options that don't have a dedicated method can be
+// added with WithOption. A Builder is not safe for concurrent use.
+type Builder struct {
+	steps      []BuilderStep
+	noDefaults bool
+}
+
+// NewBuilder creates an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// WithOption adds an arbitrary option as a step with the given name.
+func (b *Builder) WithOption(name string, opt Option) *Builder {
+	b.steps = append(b.steps, BuilderStep{Name: name, Option: opt})
+	return b
+}
+
+// WithoutDefaults disables the fallback on defaults. See NewWithoutDefaults.
+func (b *Builder) WithoutDefaults() *Builder {
+	b.noDefaults = true
+	return b
+}
+
+// WithIdentity sets the private key of the host.
+func (b *Builder) WithIdentity(sk crypto.PrivKey) *Builder {
+	return b.WithOption("WithIdentity", Identity(sk))
+}
+
+// WithListenAddrStrings adds listen addresses, given as strings.
+func (b *Builder) WithListenAddrStrings(addrs ...string) *Builder {
+	return b.WithOption("WithListenAddrStrings", ListenAddrStrings(addrs...))
+}
+
+// WithListenAddrs adds listen addresses.
+func (b *Builder) WithListenAddrs(addrs ...ma.Multiaddr) *Builder {
+	return b.WithOption("WithListenAddrs", ListenAddrs(addrs...))
+}
+
+// WithTCP adds the TCP transport.
+func (b *Builder) WithTCP(opts ...tcp.Option) *Builder {
+	return b.WithOption("WithTCP", Transport(tcp.NewTCPTransport, anySlice(opts)...))
+}
+
+// WithQUIC adds the QUIC transport.
+func (b *Builder) WithQUIC() *Builder {
+	return b.WithOption("WithQUIC", Transport(quic.NewTransport))
+}
+
+// WithWebSocket adds the WebSocket transport.
+func (b *Builder) WithWebSocket(opts ...ws.Option) *Builder {
+	return b.WithOption("WithWebSocket", Transport(ws.New, anySlice(opts)...))
+}
+
+// WithWebTransport adds the WebTransport transport.
+func (b *Builder) WithWebTransport(opts ...webtransport.Option) *Builder {
+	return b.WithOption("WithWebTransport", Transport(webtransport.New, anySlice(opts)...))
+}
+
+// WithWebRTC adds the WebRTC Direct transport.
+func (b *Builder) WithWebRTC(opts ...libp2pwebrtc.Option) *Builder {
+	return b.WithOption("WithWebRTC", Transport(libp2pwebrtc.New, anySlice(opts)...))
+}
+
+// WithNoise adds the Noise security protocol.
+func (b *Builder) WithNoise() *Builder {
+	return b.WithOption("WithNoise", Security(noise.ID, noise.New))
+}
+
+// WithTLS adds the TLS security protocol.
+func (b *Builder) WithTLS() *Builder {
+	return b.WithOption("WithTLS", Security(tls.ID, tls.New))
+}
+
+// WithYamux adds the yamux stream multiplexer.
+func (b *Builder) WithYamux() *Builder {
+	return b.WithOption("WithYamux", Muxer(yamux.ID, yamux.DefaultTransport))
+}
+
+// WithPeerstore sets the peerstore.
+func (b *Builder) WithPeerstore(ps peerstore.Peerstore) *Builder {
+	return b.WithOption("WithPeerstore", Peerstore(ps))
+}
+
+// WithConnManager sets the connection manager.
+func (b *Builder) WithConnManager(cm connmgr.ConnManager) *Builder {
+	return b.WithOption("WithConnManager", ConnectionManager(cm))
+}
+
+// WithConnectionGater sets the connection gater.
+func (b *Builder) WithConnectionGater(cg connmgr.ConnectionGater) *Builder {
+	return b.WithOption("WithConnectionGater", ConnectionGater(cg))
+}
+
+// WithResourceManager sets the resource manager.
+func (b *Builder) WithResourceManager(rm network.ResourceManager) *Builder {
+	return b.WithOption("WithResourceManager", ResourceManager(rm))
+}
+
+// WithUserAgent sets the user agent sent with identify.
+func (b *Builder) WithUserAgent(userAgent string) *Builder {
+	return b.WithOption("WithUserAgent", UserAgent(userAgent))
+}
+
+// WithNATPortMap enables NAT port mapping.
+func (b *Builder) WithNATPortMap() *Builder {
+	return b.WithOption("WithNATPortMap", NATPortMap())
+}
+
+// WithHolePunching enables hole punching.
+func (b *Builder) WithHolePunching(opts ...holepunch.Option) *Builder {
+	return b.WithOption("WithHolePunching", EnableHolePunching(opts...))
+}
+
+// WithoutRelay disables the relay transport.
+func (b *Builder) WithoutRelay() *Builder {
+	return b.WithOption("WithoutRelay", DisableRelay())
+}
+
+// Steps returns the steps added to the builder, in order.
+func (b *Builder) Steps() []BuilderStep {
+	steps := make([]BuilderStep, len(b.steps))
+	copy(steps, b.steps)
+	return steps
+}
+
+// Option returns the builder's configuration as a single option, without the
+// defaults. It can be passed to New.
+func (b *Builder) Option() Option {
+	opts := make([]Option, 0, len(b.steps))
+	for _, s := range b.steps {
+		opts = append(opts, s.Option)
+	}
+	return ChainOptions(opts...)
+}
+
+// Config applies the steps and returns the resulting configuration. If a step fails,
+// the error is a *BuilderError.
+func (b *Builder) Config() (*Config, error) {
+	var cfg Config
+	for i, s := range b.steps {
+		if err := cfg.Apply(s.Option); err != nil {
+			return nil, &BuilderError{Step: i, Name: s.Name, Err: err}
+		}
+	}
+	if !b.noDefaults {
+		if err := cfg.Apply(FallbackDefaults); err != nil {
+			return nil, &BuilderError{Step: len(b.steps), Name: "FallbackDefaults", Err: err}
+		}
+	}
+	return &cfg, nil
+}
+
+// Build constructs the libp2p node.
+func (b *Builder) Build() (host.Host, error) {
+	cfg, err := b.Config()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.NewNode()
+}
+
+func anySlice[T any](s []T) []interface{} {
+	res := make([]interface{}, 0, len(s))
+	for _, v := range s {
+		res = append(res, v)
+	}
+	return res
+}
@@ -0,0 +1,74 @@
+package libp2p
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcp"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+
+	b := NewBuilder().
+		WithIdentity(priv).
+		WithTCP(tcp.DisableReuseport()).
+		WithNoise().
+		WithYamux().
+		WithoutRelay().
+		WithListenAddrStrings("/ip4/127.0.0.1/tcp/0")
+	names := make([]string, 0, len(b.Steps()))
+	for _, s := range b.Steps() {
+		names = append(names, s.Name)
+	}
+	require.Equal(t, []string{"WithIdentity", "WithTCP", "WithNoise", "WithYamux", "WithoutRelay", "WithListenAddrStrings"}, names)
+
+	h, err := b.Build()
+	require.NoError(t, err)
+	defer h.Close()
+	require.Equal(t, id, h.ID())
+	addrs := h.Network().ListenAddresses()
+	require.Len(t, addrs, 1)
+	_, err = addrs[0].ValueForProtocol(ma.P_TCP)
+	require.NoError(t, err)
+}
+
+func TestBuilderErrors(t *testing.T) {
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+
+	_, err = NewBuilder().
+		WithIdentity(priv).
+		WithListenAddrStrings("/ip4/127.0.0.1/tcp/0", "not a multiaddr").
+		Build()
+	var berr *BuilderError
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, 1, berr.Step)
+	require.Equal(t, "WithListenAddrStrings", berr.Name)
+
+	_, err = NewBuilder().
+		WithIdentity(priv).
+		WithTCP().
+		WithIdentity(priv).
+		Build()
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, 2, berr.Step)
+	require.Equal(t, "WithIdentity", berr.Name)
+
+	myErr := errors.New("custom error")
+	_, err = NewBuilder().
+		WithOption("custom", func(*Config) error { return myErr }).
+		Build()
+	require.ErrorIs(t, err, myErr)
+	require.ErrorAs(t, err, &berr)
+	require.Equal(t, "custom", berr.Name)
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/autorelay"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
+
+	"github.com/benbjohnson/clock"
+)
+
+// instantTimer adapts a clock.Clock to the timers of the swarm and of AutoRelay,
+// which trigger at an instant rather than after a duration.
+type instantTimer struct {
+	cl clock.Clock
+	t  *clock.Timer
+	c  chan time.Time
+}
+
+func newInstantTimer(cl clock.Clock, when time.Time) *instantTimer {
+	t := &instantTimer{cl: cl, c: make(chan time.Time, 1)}
+	d := cl.Until(when)
+	t.t = cl.AfterFunc(d, t.fire)
+	if d <= 0 {
+		t.t.Stop()
+		t.fire()
+	}
+	return t
+}
+
+// fire sends the current time on the channel, unless a previous value wasn't
+// received yet, like a time.Timer.
+func (t *instantTimer) fire() {
+	select {
+	case t.c <- t.cl.Now():
+	default:
+	}
+}
+
+func (t *instantTimer) Reset(when time.Time) bool {
+	d := t.cl.Until(when)
+	if d > 0 {
+		return t.t.Reset(d)
+	}
+	// A mock timer reset to an instant that already passed only fires when the mock
+	// is moved forward. Fire right away, as a real timer would.
+	active := t.t.Stop()
+	t.fire()
+	return active
+}
+
+func (t *instantTimer) Stop() bool           { return t.t.Stop() }
+func (t *instantTimer) Ch() <-chan time.Time { return t.c }
+
+// swarmClock adapts a clock.Clock to a swarm.Clock.
+type swarmClock struct{ clock.Clock }
+
+var _ swarm.Clock = swarmClock{}
+
+func (c swarmClock) InstantTimer(when time.Time) swarm.InstantTimer {
+	return newInstantTimer(c.Clock, when)
+}
+
+// autoRelayClock adapts a clock.Clock to an autorelay.ClockWithInstantTimer.
+type autoRelayClock struct{ clock.Clock }
+
+var _ autorelay.ClockWithInstantTimer = autoRelayClock{}
+
+func (c autoRelayClock) InstantTimer(when time.Time) autorelay.InstantTimer {
+	return newInstantTimer(c.Clock, when)
+}
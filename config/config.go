@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"slices"
 	"time"
 
@@ -23,15 +24,22 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/sec"
 	"github.com/TheNoobiCat/go-libp2p/core/sec/insecure"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/backoff"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/autonat"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/autorelay"
 	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
 	blankhost "github.com/TheNoobiCat/go-libp2p/p2p/host/blank"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/browsercheck"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/introspect"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore/pstoremem"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
 	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
 	routed "github.com/TheNoobiCat/go-libp2p/p2p/host/routed"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/conngater"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/tofu"
 	tptu "github.com/TheNoobiCat/go-libp2p/p2p/net/upgrader"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/autonatv2"
 	circuitv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/client"
@@ -41,8 +49,10 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/quicreuse"
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcpreuse"
 	libp2pwebrtc "github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc"
+	"github.com/TheNoobiCat/go-libp2p/x/rate"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/benbjohnson/clock"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
 	"github.com/quic-go/quic-go"
@@ -66,6 +76,35 @@ type AutoNATConfig struct {
 	ThrottleGlobalLimit int
 	ThrottlePeerLimit   int
 	ThrottleInterval    time.Duration
+	ProbeSchedule       *autonat.ProbeSchedule
+}
+
+// ServicePeerRateLimits are the per peer rate limits for the built-in protocol services.
+// A zero limit disables per peer rate limiting for that service.
+type ServicePeerRateLimits struct {
+	Identify rate.Limit
+	Ping     rate.Limit
+	AutoNAT  rate.Limit
+}
+
+// AddrAdvertisement configures how the host advertises its own addresses.
+type AddrAdvertisement struct {
+	// SelfAddrTTL is how long the host's own addresses and signed peer record remain
+	// valid in its peerstore. They are refreshed periodically while the host runs.
+	// Zero means they never expire.
+	SelfAddrTTL time.Duration
+	// PushDebounce is the time the host's addresses must remain unchanged before
+	// identify pushes them to connected peers. Zero disables debouncing.
+	PushDebounce time.Duration
+	// MinPushInterval is the minimum time between two identify pushes. Zero means
+	// pushes are sent as soon as possible.
+	MinPushInterval time.Duration
+	// PeerPushInterval is the minimum time between two identify pushes to the same
+	// peer. Zero means pushes aren't rate limited per peer.
+	PeerPushInterval time.Duration
+	// LANMode restricts the private and link-local addresses to the peers on the same
+	// subnet as the host. Other peers only learn the host's public addresses.
+	LANMode bool
 }
 
 type Security struct {
@@ -73,6 +112,13 @@ type Security struct {
 	Constructor interface{}
 }
 
+// Negotiator negotiates the security protocol and the stream multiplexer of the
+// connections of the transports handling any of Protocols, see tptu.WithNegotiator.
+type Negotiator struct {
+	Negotiator tptu.Negotiator
+	Protocols  []int
+}
+
 // Config describes a set of settings for a libp2p node
 //
 // This is *not* a stable interface. Use the options defined in the root
@@ -84,6 +130,10 @@ type Config struct {
 	// Set it via the UserAgent option function.
 	UserAgent string
 
+	// UserAgentForConn overrides the user agent sent on a connection. It is set
+	// using the [UserAgentForConn] option.
+	UserAgentForConn func(network.Conn) string
+
 	// ProtocolVersion is the protocol version that identifies the family
 	// of protocols used by the peer in the Identify protocol. It is set
 	// using the [ProtocolVersion] option.
@@ -95,9 +145,15 @@ type Config struct {
 	Transports         []fx.Option
 	Muxers             []tptu.StreamMuxer
 	SecurityTransports []Security
+	SecurityPreference tptu.SecurityPreference
+	Negotiators        []Negotiator
 	Insecure           bool
 	PSK                pnet.PSK
 
+	// QUICStatelessResetKey is the key used for QUIC stateless resets. If nil, it is
+	// derived from PeerKey.
+	QUICStatelessResetKey *quic.StatelessResetKey
+
 	DialTimeout time.Duration
 
 	RelayCustom bool
@@ -109,9 +165,18 @@ type Config struct {
 	ListenAddrs     []ma.Multiaddr
 	AddrsFactory    bhost.AddrsFactory
 	ConnectionGater connmgr.ConnectionGater
+	// PeerPinning pins peers to the DNS names and addresses used to reach them on
+	// first use.
+	PeerPinning *tofu.Store
+
+	// BackoffRegistry tracks the backoffs of the swarm and of AutoRelay.
+	BackoffRegistry *backoff.Registry
 
 	ConnManager     connmgr.ConnManager
 	ResourceManager network.ResourceManager
+	// ConnManagerDialHeadroom refuses outbound dials while the connection manager is
+	// above its high water mark.
+	ConnManagerDialHeadroom bool
 
 	NATManager NATManagerC
 	Peerstore  peerstore.Peerstore
@@ -126,31 +191,96 @@ type Config struct {
 	EnableAutoRelay bool
 	AutoRelayOpts   []autorelay.Option
 	AutoNATConfig
+	ServicePeerRateLimits ServicePeerRateLimits
+	AddrAdvertisement     AddrAdvertisement
+	Reputation            *reputation.Registry
+
+	NodeInfoAllowlist []peer.ID
+
+	IntrospectionListenAddr string
 
 	EnableHolePunching  bool
 	HolePunchingOptions []holepunch.Option
 
 	DisableMetrics       bool
 	PrometheusRegisterer prometheus.Registerer
-
-	DialRanker network.DialRanker
+	// MetricsDisabled disables the metrics of individual subsystems, see
+	// metricshelper.Subsystems.
+	MetricsDisabled map[string]bool
+	// MetricsRegisterers overrides PrometheusRegisterer for individual subsystems.
+	MetricsRegisterers map[string]prometheus.Registerer
+	// MetricsConstLabels are added to all metrics. The collectors are shared by all
+	// the nodes of the process, so they can't tell apart nodes sharing a registry.
+	MetricsConstLabels prometheus.Labels
+
+	DialRanker        network.DialRanker
+	DialRankingPolicy swarm.DialRankingPolicy
 
 	SwarmOpts []swarm.Option
 
 	DisableIdentifyAddressDiscovery bool
+	LazyIdentify                    bool
+	SignedPeerRecordPolicy          identify.SignedRecordPolicy
+
+	AddrFilters *ma.Filters
+
+	AdvertisedHostname string
+	ReplaceIPAddrs     bool
 
 	EnableAutoNATv2 bool
 
+	// InboundNegotiationTimeout and MaxNegotiatingStreamsPerPeer bound the inbound
+	// streams negotiating their protocol.
+	InboundNegotiationTimeout    time.Duration
+	MaxNegotiatingStreamsPerPeer int
+
+	// PprofLabels tags the goroutines of stream handlers and dials with pprof labels.
+	PprofLabels bool
+
+	// Clock is used by the swarm, identify, AutoRelay, hole punching and the default
+	// peerstore and connection manager, if set.
+	Clock clock.Clock
+
+	// BrowserAddrChecker is the AutoNAT v2 server dialing back the browser-facing
+	// addresses of the host. The check is disabled if nil.
+	BrowserAddrChecker   *peer.AddrInfo
+	BrowserAddrCheckOpts []browsercheck.Option
+
 	UDPBlackHoleSuccessCounter        *swarm.BlackHoleSuccessCounter
 	CustomUDPBlackHoleSuccessCounter  bool
 	IPv6BlackHoleSuccessCounter       *swarm.BlackHoleSuccessCounter
 	CustomIPv6BlackHoleSuccessCounter bool
+	BlackHolePolicies                 map[swarm.BlackHoleKey]swarm.BlackHolePolicy
 
 	UserFxOptions []fx.Option
 
 	ShareTCPListener bool
 }
 
+// metricsRegisterers returns the registerers of the metrics subsystems, or nil if
+// metrics are disabled.
+func (cfg *Config) metricsRegisterers() *metricshelper.Registerers {
+	if cfg.DisableMetrics {
+		return nil
+	}
+	reg := cfg.PrometheusRegisterer
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	return &metricshelper.Registerers{
+		Default:      reg,
+		PerSubsystem: cfg.MetricsRegisterers,
+		Disabled:     cfg.MetricsDisabled,
+		ConstLabels:  cfg.MetricsConstLabels,
+	}
+}
+
+// metricsRegisterer returns the registerer of the metrics subsystem, and false if
+// its metrics are disabled.
+func (cfg *Config) metricsRegisterer(subsystem string) (prometheus.Registerer, bool) {
+	return cfg.metricsRegisterers().For(subsystem)
+}
+
 func (cfg *Config) makeSwarm(eventBus event.Bus, enableMetrics bool) (*swarm.Swarm, error) {
 	if cfg.Peerstore == nil {
 		return nil, fmt.Errorf("no peerstore specified")
@@ -187,12 +317,18 @@ func (cfg *Config) makeSwarm(eventBus event.Bus, enableMetrics bool) (*swarm.Swa
 		swarm.WithUDPBlackHoleSuccessCounter(cfg.UDPBlackHoleSuccessCounter),
 		swarm.WithIPv6BlackHoleSuccessCounter(cfg.IPv6BlackHoleSuccessCounter),
 	)
+	for k, p := range cfg.BlackHolePolicies {
+		opts = append(opts, swarm.WithBlackHolePolicy(k, p))
+	}
 	if cfg.Reporter != nil {
 		opts = append(opts, swarm.WithMetrics(cfg.Reporter))
 	}
 	if cfg.ConnectionGater != nil {
 		opts = append(opts, swarm.WithConnectionGater(cfg.ConnectionGater))
 	}
+	if cfg.AddrFilters != nil {
+		opts = append(opts, swarm.WithAddrFilters(cfg.AddrFilters))
+	}
 	if cfg.DialTimeout != 0 {
 		opts = append(opts, swarm.WithDialTimeout(cfg.DialTimeout))
 	}
@@ -205,10 +341,25 @@ func (cfg *Config) makeSwarm(eventBus event.Bus, enableMetrics bool) (*swarm.Swa
 	if cfg.DialRanker != nil {
 		opts = append(opts, swarm.WithDialRanker(cfg.DialRanker))
 	}
+	if cfg.DialRankingPolicy != nil {
+		opts = append(opts, swarm.WithDialRankingPolicy(cfg.DialRankingPolicy))
+	}
+	if cfg.BackoffRegistry != nil {
+		opts = append(opts, swarm.WithDialBackoff(cfg.BackoffRegistry))
+	}
+	if cfg.PprofLabels {
+		opts = append(opts, swarm.WithPprofLabels())
+	}
+	if cfg.ConnManagerDialHeadroom {
+		opts = append(opts, swarm.WithConnManagerHeadroom(cfg.ConnManager))
+	}
+	if cfg.Clock != nil {
+		opts = append(opts, swarm.WithClock(swarmClock{cfg.Clock}))
+	}
 
-	if enableMetrics {
+	if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemSwarm); ok && enableMetrics {
 		opts = append(opts,
-			swarm.WithMetricsTracer(swarm.NewMetricsTracer(swarm.WithRegisterer(cfg.PrometheusRegisterer))))
+			swarm.WithMetricsTracer(swarm.NewMetricsTracer(swarm.WithRegisterer(reg))))
 	}
 	// TODO: Make the swarm implementation configurable.
 	return swarm.NewSwarm(pid, cfg.Peerstore, eventBus, opts...)
@@ -237,6 +388,7 @@ func (cfg *Config) makeAutoNATV2Host() (host.Host, error) {
 		DialRanker:                  swarm.NoDelayDialRanker,
 		UDPBlackHoleSuccessCounter:  cfg.UDPBlackHoleSuccessCounter,
 		IPv6BlackHoleSuccessCounter: cfg.IPv6BlackHoleSuccessCounter,
+		BlackHolePolicies:           cfg.BlackHolePolicies,
 		ResourceManager:             cfg.ResourceManager,
 		SwarmOpts: []swarm.Option{
 			// Don't update black hole state for failed autonat dials
@@ -288,7 +440,25 @@ func (cfg *Config) makeAutoNATV2Host() (host.Host, error) {
 func (cfg *Config) addTransports() ([]fx.Option, error) {
 	fxopts := []fx.Option{
 		fx.WithLogger(func() fxevent.Logger { return getFXLogger() }),
-		fx.Provide(fx.Annotate(tptu.New, fx.ParamTags(`name:"security"`))),
+		fx.Provide(fx.Annotate(
+			func(security []sec.SecureTransport, muxers []tptu.StreamMuxer, psk pnet.PSK, rcmgr network.ResourceManager, gater connmgr.ConnectionGater) (transport.Upgrader, error) {
+				var opts []tptu.Option
+				if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemTransports); ok {
+					opts = append(opts, tptu.WithMetricsTracer(tptu.NewMetricsTracer(tptu.WithRegisterer(reg))))
+				}
+				if r, ok := cfg.Reporter.(metrics.MuxerReporter); ok {
+					opts = append(opts, tptu.WithBandwidthReporter(r))
+				}
+				if cfg.SecurityPreference != nil {
+					opts = append(opts, tptu.WithSecurityPreference(cfg.SecurityPreference))
+				}
+				for _, n := range cfg.Negotiators {
+					opts = append(opts, tptu.WithNegotiator(n.Negotiator, n.Protocols...))
+				}
+				return tptu.New(security, muxers, psk, rcmgr, gater, opts...)
+			},
+			fx.ParamTags(`name:"security"`),
+		)),
 		fx.Supply(cfg.Muxers),
 		fx.Provide(func() connmgr.ConnectionGater { return cfg.ConnectionGater }),
 		fx.Provide(func() pnet.PSK { return cfg.PSK }),
@@ -297,7 +467,11 @@ func (cfg *Config) addTransports() ([]fx.Option, error) {
 			if !cfg.ShareTCPListener {
 				return nil
 			}
-			return tcpreuse.NewConnMgr(tcpreuse.EnvReuseportVal, upgrader)
+			var opts []tcpreuse.ConnMgrOption
+			if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemTransports); ok {
+				opts = append(opts, tcpreuse.WithMetricsTracer(tcpreuse.NewMetricsTracer(tcpreuse.WithRegisterer(reg))))
+			}
+			return tcpreuse.NewConnMgr(tcpreuse.EnvReuseportVal, upgrader, opts...)
 		}),
 		fx.Provide(func(cm *quicreuse.ConnManager, sw *swarm.Swarm) libp2pwebrtc.ListenUDPFn {
 			hasQuicAddrPortFor := func(network string, laddr *net.UDPAddr) bool {
@@ -374,7 +548,12 @@ func (cfg *Config) addTransports() ([]fx.Option, error) {
 			)))
 	}
 
-	fxopts = append(fxopts, fx.Provide(PrivKeyToStatelessResetKey))
+	if cfg.QUICStatelessResetKey != nil {
+		srk := *cfg.QUICStatelessResetKey
+		fxopts = append(fxopts, fx.Provide(func() quic.StatelessResetKey { return srk }))
+	} else {
+		fxopts = append(fxopts, fx.Provide(PrivKeyToStatelessResetKey))
+	}
 	fxopts = append(fxopts, fx.Provide(PrivKeyToTokenGeneratorKey))
 	if cfg.QUICReuse != nil {
 		fxopts = append(fxopts, cfg.QUICReuse...)
@@ -402,8 +581,8 @@ func (cfg *Config) addTransports() ([]fx.Option, error) {
 						return rcmgr.VerifySourceAddress(addr)
 					}),
 				}
-				if !cfg.DisableMetrics {
-					opts = append(opts, quicreuse.EnableMetrics(cfg.PrometheusRegisterer))
+				if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemTransports); ok {
+					opts = append(opts, quicreuse.EnableMetrics(reg))
 				}
 				cm, err := quicreuse.NewConnManager(key, tokenGenerator, opts...)
 				if err != nil {
@@ -417,19 +596,32 @@ func (cfg *Config) addTransports() ([]fx.Option, error) {
 
 	fxopts = append(fxopts, fx.Invoke(
 		fx.Annotate(
-			func(swrm *swarm.Swarm, tpts []transport.Transport) error {
+			func(swrm *swarm.Swarm, eventBus event.Bus, tpts []transport.Transport) error {
 				for _, t := range tpts {
 					if err := swrm.AddTransport(t); err != nil {
 						return err
 					}
+					// e.g. the QUIC transport's session resumption events
+					if t, ok := t.(interface{ SetEventBus(event.Bus) error }); ok {
+						if err := t.SetEventBus(eventBus); err != nil {
+							return err
+						}
+					}
 				}
 				return nil
 			},
-			fx.ParamTags("", `group:"transport"`),
+			fx.ParamTags("", "", `group:"transport"`),
 		)),
 	)
 	if cfg.Relay {
-		fxopts = append(fxopts, fx.Invoke(circuitv2.AddTransport))
+		var relayOpts []circuitv2.Option
+		if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemRelay); ok {
+			relayOpts = append(relayOpts, circuitv2.WithMetricsTracer(
+				circuitv2.NewMetricsTracer(circuitv2.WithRegisterer(reg), circuitv2.WithClock(cfg.Clock))))
+		}
+		fxopts = append(fxopts, fx.Invoke(func(h host.Host, upgrader transport.Upgrader) error {
+			return circuitv2.AddTransport(h, upgrader, relayOpts...)
+		}))
 	}
 	return fxopts, nil
 }
@@ -442,6 +634,7 @@ func (cfg *Config) newBasicHost(swrm *swarm.Swarm, eventBus event.Bus, an *auton
 		NATManager:                      cfg.NATManager,
 		EnablePing:                      !cfg.DisablePing,
 		UserAgent:                       cfg.UserAgent,
+		UserAgentForConn:                cfg.UserAgentForConn,
 		ProtocolVersion:                 cfg.ProtocolVersion,
 		EnableHolePunching:              cfg.EnableHolePunching,
 		HolePunchingOptions:             cfg.HolePunchingOptions,
@@ -449,8 +642,27 @@ func (cfg *Config) newBasicHost(swrm *swarm.Swarm, eventBus event.Bus, an *auton
 		RelayServiceOpts:                cfg.RelayServiceOpts,
 		EnableMetrics:                   !cfg.DisableMetrics,
 		PrometheusRegisterer:            cfg.PrometheusRegisterer,
+		MetricsRegisterers:              cfg.metricsRegisterers(),
 		DisableIdentifyAddressDiscovery: cfg.DisableIdentifyAddressDiscovery,
+		LazyIdentify:                    cfg.LazyIdentify,
+		IdentifySignedRecordPolicy:      cfg.SignedPeerRecordPolicy,
+		AddrFilters:                     cfg.AddrFilters,
+		AdvertisedHostname:              cfg.AdvertisedHostname,
+		ReplaceIPAddrs:                  cfg.ReplaceIPAddrs,
+		IdentifyPeerRateLimit:           cfg.ServicePeerRateLimits.Identify,
+		SelfAddrTTL:                     cfg.AddrAdvertisement.SelfAddrTTL,
+		IdentifyPushDebounce:            cfg.AddrAdvertisement.PushDebounce,
+		IdentifyMinPushInterval:         cfg.AddrAdvertisement.MinPushInterval,
+		IdentifyPeerPushInterval:        cfg.AddrAdvertisement.PeerPushInterval,
+		IdentifyLANMode:                 cfg.AddrAdvertisement.LANMode,
+		PingPeerRateLimit:               cfg.ServicePeerRateLimits.Ping,
+		NodeInfoAllowlist:               cfg.NodeInfoAllowlist,
+		Reputation:                      cfg.Reputation,
 		AutoNATv2:                       an,
+		InboundNegotiationTimeout:       cfg.InboundNegotiationTimeout,
+		MaxNegotiatingStreamsPerPeer:    cfg.MaxNegotiatingStreamsPerPeer,
+		EnablePprofLabels:               cfg.PprofLabels,
+		Clock:                           cfg.Clock,
 	})
 	if err != nil {
 		return nil, err
@@ -498,17 +710,57 @@ func (cfg *Config) NewNode() (host.Host, error) {
 		return nil, validateErr
 	}
 
-	if !cfg.DisableMetrics {
-		rcmgr.MustRegisterWith(cfg.PrometheusRegisterer)
+	if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemResourceManager); ok {
+		rcmgr.MustRegisterWith(reg)
+	}
+
+	if cfg.AddrFilters != nil {
+		// Reject connections from filtered addresses before the handshake.
+		cfg.ConnectionGater = conngater.NewAddrFiltersGater(cfg.AddrFilters, cfg.ConnectionGater)
+		cfg.AutoRelayOpts = append([]autorelay.Option{autorelay.WithAddrFilters(cfg.AddrFilters)}, cfg.AutoRelayOpts...)
+	}
+	if cfg.BackoffRegistry != nil {
+		cfg.AutoRelayOpts = append([]autorelay.Option{autorelay.WithBackoffRegistry(cfg.BackoffRegistry)}, cfg.AutoRelayOpts...)
+	}
+	if cfg.Clock != nil {
+		cfg.AutoRelayOpts = append([]autorelay.Option{autorelay.WithClock(autoRelayClock{cfg.Clock})}, cfg.AutoRelayOpts...)
+	}
+
+	var pinGater *tofu.Gater
+	if cfg.PeerPinning != nil {
+		pinGater = tofu.NewGater(cfg.PeerPinning, cfg.ConnectionGater)
+		cfg.ConnectionGater = pinGater
+		cfg.SwarmOpts = append(cfg.SwarmOpts, swarm.WithPreDialHook(pinGater))
 	}
 
 	fxopts := []fx.Option{
 		fx.Provide(func() event.Bus {
-			return eventbus.NewBus(eventbus.WithMetricsTracer(eventbus.NewMetricsTracer(eventbus.WithRegisterer(cfg.PrometheusRegisterer))))
+			if cfg.DisableMetrics {
+				// DisableMetrics never applied to the event bus metrics.
+				return eventbus.NewBus(eventbus.WithMetricsTracer(eventbus.NewMetricsTracer(eventbus.WithRegisterer(cfg.PrometheusRegisterer))))
+			}
+			if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemEventBus); ok {
+				return eventbus.NewBus(eventbus.WithMetricsTracer(eventbus.NewMetricsTracer(eventbus.WithRegisterer(reg))))
+			}
+			return eventbus.NewBus()
 		}),
 		fx.Provide(func() crypto.PrivKey {
 			return cfg.PeerKey
 		}),
+		fx.Invoke(func(eventBus event.Bus, lifecycle fx.Lifecycle) error {
+			if pinGater == nil {
+				return nil
+			}
+			lifecycle.Append(fx.StopHook(pinGater.Close))
+			return pinGater.SetEventBus(eventBus)
+		}),
+		fx.Invoke(func(eventBus event.Bus) error {
+			// emit the resource manager denials on the host's event bus
+			if rm, ok := cfg.ResourceManager.(interface{ SetEventBus(event.Bus) error }); ok {
+				return rm.SetEventBus(eventBus)
+			}
+			return nil
+		}),
 		// Make sure the swarm constructor depends on the quicreuse.ConnManager.
 		// That way, the ConnManager will be started before the swarm, and more importantly,
 		// the swarm will be stopped before the ConnManager.
@@ -538,8 +790,8 @@ func (cfg *Config) NewNode() (host.Host, error) {
 				return nil, err
 			}
 			var mt autonatv2.MetricsTracer
-			if !cfg.DisableMetrics {
-				mt = autonatv2.NewMetricsTracer(cfg.PrometheusRegisterer)
+			if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemAutoNAT); ok {
+				mt = autonatv2.NewMetricsTracer(reg)
 			}
 			autoNATv2, err := autonatv2.New(ah, autonatv2.WithMetricsTracer(mt))
 			if err != nil {
@@ -573,27 +825,54 @@ func (cfg *Config) NewNode() (host.Host, error) {
 	}
 
 	// enable autorelay
+	// The *autorelay.AutoRelay is provided to the fx graph, so that users can retrieve it
+	// with fx.Populate. It is nil if autorelay is disabled.
 	fxopts = append(fxopts,
-		fx.Invoke(func(h *bhost.BasicHost, lifecycle fx.Lifecycle) error {
+		fx.Provide(func(h *bhost.BasicHost, lifecycle fx.Lifecycle) (*autorelay.AutoRelay, error) {
 			if cfg.EnableAutoRelay {
-				if !cfg.DisableMetrics {
+				if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemRelay); ok {
 					mt := autorelay.WithMetricsTracer(
-						autorelay.NewMetricsTracer(autorelay.WithRegisterer(cfg.PrometheusRegisterer)))
-					mtOpts := []autorelay.Option{mt}
+						autorelay.NewMetricsTracer(autorelay.WithRegisterer(reg)))
+					clientMT := autorelay.WithClientMetricsTracer(
+						circuitv2.NewMetricsTracer(circuitv2.WithRegisterer(reg), circuitv2.WithClock(cfg.Clock)))
+					mtOpts := []autorelay.Option{mt, clientMT}
 					cfg.AutoRelayOpts = append(mtOpts, cfg.AutoRelayOpts...)
 				}
 
 				ar, err := autorelay.NewAutoRelay(h, cfg.AutoRelayOpts...)
 				if err != nil {
-					return err
+					return nil, err
 				}
 				lifecycle.Append(fx.StartStopHook(ar.Start, ar.Close))
-				return nil
+				return ar, nil
 			}
-			return nil
+			return nil, nil
+		}),
+		fx.Invoke(func(*autorelay.AutoRelay) {}),
+	)
+
+	// enable the browser address check
+	// Like autorelay, the *browsercheck.Checker is provided to the fx graph. It is nil if
+	// the check is disabled.
+	fxopts = append(fxopts,
+		fx.Provide(func(h *bhost.BasicHost, an *autonatv2.AutoNAT, lifecycle fx.Lifecycle) (*browsercheck.Checker, error) {
+			if cfg.BrowserAddrChecker == nil {
+				return nil, nil
+			}
+			c, err := browsercheck.New(h, an, *cfg.BrowserAddrChecker, cfg.BrowserAddrCheckOpts...)
+			if err != nil {
+				return nil, err
+			}
+			lifecycle.Append(fx.StartStopHook(c.Start, c.Close))
+			return c, nil
 		}),
+		fx.Invoke(func(*browsercheck.Checker) {}),
 	)
 
+	if cfg.IntrospectionListenAddr != "" {
+		fxopts = append(fxopts, fx.Invoke(cfg.serveIntrospection))
+	}
+
 	var bh *bhost.BasicHost
 	fxopts = append(fxopts, fx.Invoke(func(bho *bhost.BasicHost) { bh = bho }))
 	fxopts = append(fxopts, fx.Invoke(func(h *bhost.BasicHost, lifecycle fx.Lifecycle) {
@@ -628,6 +907,31 @@ func (cfg *Config) NewNode() (host.Host, error) {
 	return &closableBasicHost{App: app, BasicHost: bh}, nil
 }
 
+// serveIntrospection serves the introspection endpoint of h on a plain TCP listener.
+func (cfg *Config) serveIntrospection(h *bhost.BasicHost, lifecycle fx.Lifecycle) {
+	srv := &http.Server{
+		Handler:           introspect.Handler(h),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	lifecycle.Append(fx.Hook{
+		OnStart: func(context.Context) error {
+			l, err := net.Listen("tcp", cfg.IntrospectionListenAddr)
+			if err != nil {
+				return fmt.Errorf("failed to listen for introspection: %w", err)
+			}
+			go func() {
+				if err := srv.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Errorf("introspection server failed: %s", err)
+				}
+			}()
+			return nil
+		},
+		OnStop: func(context.Context) error {
+			return srv.Close()
+		},
+	})
+}
+
 func (cfg *Config) addAutoNAT(h *bhost.BasicHost) error {
 	// Only use public addresses for autonat
 	addrFunc := func() []ma.Multiaddr {
@@ -643,9 +947,9 @@ func (cfg *Config) addAutoNAT(h *bhost.BasicHost) error {
 	autonatOpts := []autonat.Option{
 		autonat.UsingAddresses(addrFunc),
 	}
-	if !cfg.DisableMetrics {
+	if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemAutoNAT); ok {
 		autonatOpts = append(autonatOpts, autonat.WithMetricsTracer(
-			autonat.NewMetricsTracer(autonat.WithRegisterer(cfg.PrometheusRegisterer)),
+			autonat.NewMetricsTracer(autonat.WithRegisterer(reg)),
 		))
 	}
 	if cfg.AutoNATConfig.ThrottleInterval != 0 {
@@ -653,6 +957,16 @@ func (cfg *Config) addAutoNAT(h *bhost.BasicHost) error {
 			autonat.WithThrottling(cfg.AutoNATConfig.ThrottleGlobalLimit, cfg.AutoNATConfig.ThrottleInterval),
 			autonat.WithPeerThrottling(cfg.AutoNATConfig.ThrottlePeerLimit))
 	}
+	if cfg.AutoNATConfig.ProbeSchedule != nil {
+		autonatOpts = append(autonatOpts, autonat.WithProbeSchedule(*cfg.AutoNATConfig.ProbeSchedule))
+	}
+	if l := cfg.ServicePeerRateLimits.AutoNAT; l.RPS != 0 {
+		limiter := &rate.PeerLimiter{PeerLimit: l, Service: autonat.ServiceName}
+		if reg, ok := cfg.metricsRegisterer(metricshelper.SubsystemAutoNAT); ok {
+			limiter.MetricsTracer = rate.NewMetricsTracer(rate.WithRegisterer(reg))
+		}
+		autonatOpts = append(autonatOpts, autonat.WithPeerRateLimiter(limiter))
+	}
 	if cfg.AutoNATConfig.EnableService {
 		autonatPrivKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
 		if err != nil {
@@ -2,6 +2,10 @@ package config
 
 import (
 	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNilOption(t *testing.T) {
@@ -21,3 +25,35 @@ func TestNilOption(t *testing.T) {
 		t.Fatalf("expected to have handled 3 options, handled %d", optsRun)
 	}
 }
+
+func TestInstantTimer(t *testing.T) {
+	cl := clock.NewMock()
+	timer := swarmClock{cl}.InstantTimer(cl.Now().Add(time.Second))
+	select {
+	case <-timer.Ch():
+		t.Fatal("timer fired too early")
+	default:
+	}
+	cl.Add(time.Second)
+	select {
+	case <-timer.Ch():
+	default:
+		t.Fatal("expected the timer to fire")
+	}
+
+	// instants in the past fire right away, without moving the mock forward
+	timer.Reset(cl.Now().Add(-time.Second))
+	select {
+	case <-timer.Ch():
+	default:
+		t.Fatal("expected the timer to fire")
+	}
+	require.False(t, timer.Stop())
+
+	past := autoRelayClock{cl}.InstantTimer(cl.Now())
+	select {
+	case <-past.Ch():
+	default:
+		t.Fatal("expected the timer to fire")
+	}
+}
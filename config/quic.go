@@ -18,7 +18,7 @@ const (
 
 func PrivKeyToStatelessResetKey(key crypto.PrivKey) (quic.StatelessResetKey, error) {
 	var statelessResetKey quic.StatelessResetKey
-	keyBytes, err := key.Raw()
+	keyBytes, err := crypto.SecretSeed(key)
 	if err != nil {
 		return statelessResetKey, err
 	}
@@ -31,7 +31,7 @@ func PrivKeyToStatelessResetKey(key crypto.PrivKey) (quic.StatelessResetKey, err
 
 func PrivKeyToTokenGeneratorKey(key crypto.PrivKey) (quic.TokenGeneratorKey, error) {
 	var tokenKey quic.TokenGeneratorKey
-	keyBytes, err := key.Raw()
+	keyBytes, err := crypto.SecretSeed(key)
 	if err != nil {
 		return tokenKey, err
 	}
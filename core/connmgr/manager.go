@@ -94,6 +94,15 @@ type TagInfo struct {
 	Conns map[string]time.Time
 }
 
+// HighWaterChecker is implemented by the connection managers that trim connections
+// above a high water mark. The swarm uses it to refuse outbound dials that would be
+// trimmed right away, see swarm.WithConnManagerHeadroom.
+type HighWaterChecker interface {
+	// AboveHighWater returns true if there are as many connections as the high water
+	// mark, or more. New connections would then be trimmed.
+	AboveHighWater() bool
+}
+
 // GetConnLimiter provides access to a component's total connection limit.
 type GetConnLimiter interface {
 	// GetConnLimit returns the total connection limit of the implementing component.
@@ -0,0 +1,162 @@
+package crypto
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+
+	pb "github.com/TheNoobiCat/go-libp2p/core/crypto/pb"
+)
+
+// ErrKeyNotExportable is returned by the Raw method of private keys backed by a
+// Signer, since their key material can't leave the device holding them.
+var ErrKeyNotExportable = errors.New("private key is not exportable")
+
+// Signer performs the private key operations of a key held outside of the process,
+// e.g. in an HSM, a TPM or a remote KMS. Adapters for PKCS#11 or cloud KMS APIs
+// implement Signer, or the standard library crypto.Signer, see StdSigner.
+type Signer interface {
+	// GetPublic returns the public key of the signing key.
+	GetPublic() PubKey
+	// Sign signs data the way the PrivKey of the same type does, e.g. ECDSA keys
+	// sign the SHA-256 hash of data and return the ASN.1 encoded signature.
+	Sign(data []byte) ([]byte, error)
+}
+
+// SignerPrivKey is a PrivKey delegating signing to a Signer. It can be used as the
+// identity of a host: the security handshakes and signed records only sign with the
+// identity key. Its Raw method returns ErrKeyNotExportable, so it can't be
+// marshaled, e.g. by peerstores persisting keys.
+//
+// Some transports derive secrets from the identity key, such as the QUIC stateless
+// reset key, see SecretSeed. By default these are derived from a random seed, so
+// they change when the host restarts.
+type SignerPrivKey struct {
+	signer Signer
+	seed   []byte
+}
+
+var _ PrivKey = &SignerPrivKey{}
+
+// SignerOption configures a SignerPrivKey.
+type SignerOption func(*SignerPrivKey) error
+
+// WithSecretSeed sets the seed returned by SecretSeed. Persisting the seed, e.g.
+// next to the key handle, keeps the secrets derived from the identity key stable
+// across restarts. The seed must be kept secret and be at least 32 bytes.
+func WithSecretSeed(seed []byte) SignerOption {
+	return func(k *SignerPrivKey) error {
+		if len(seed) < 32 {
+			return errors.New("secret seed must be at least 32 bytes")
+		}
+		k.seed = seed
+		return nil
+	}
+}
+
+// NewSignerPrivKey returns a PrivKey delegating signing to s.
+func NewSignerPrivKey(s Signer, opts ...SignerOption) (*SignerPrivKey, error) {
+	if s == nil || s.GetPublic() == nil {
+		return nil, ErrNilPrivateKey
+	}
+	k := &SignerPrivKey{signer: s}
+	for _, opt := range opts {
+		if err := opt(k); err != nil {
+			return nil, err
+		}
+	}
+	if k.seed == nil {
+		k.seed = make([]byte, 32)
+		if _, err := rand.Read(k.seed); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// Type returns the type of the public key.
+func (k *SignerPrivKey) Type() pb.KeyType {
+	return k.signer.GetPublic().Type()
+}
+
+// Raw returns ErrKeyNotExportable.
+func (k *SignerPrivKey) Raw() ([]byte, error) {
+	return nil, ErrKeyNotExportable
+}
+
+// Equals checks whether o is a private key with the same public key.
+func (k *SignerPrivKey) Equals(o Key) bool {
+	op, ok := o.(PrivKey)
+	if !ok {
+		return false
+	}
+	return k.GetPublic().Equals(op.GetPublic())
+}
+
+// Sign signs data with the Signer.
+func (k *SignerPrivKey) Sign(data []byte) ([]byte, error) {
+	return k.signer.Sign(data)
+}
+
+// GetPublic returns the public key of the Signer.
+func (k *SignerPrivKey) GetPublic() PubKey {
+	return k.signer.GetPublic()
+}
+
+// SecretSeed returns the seed of the secrets derived from the key.
+func (k *SignerPrivKey) SecretSeed() []byte {
+	return k.seed
+}
+
+// SecretSeed returns the key material to derive secrets from k with a KDF, like the
+// QUIC stateless reset key. It's the raw key, unless k is a SignerPrivKey.
+func SecretSeed(k PrivKey) ([]byte, error) {
+	if sk, ok := k.(interface{ SecretSeed() []byte }); ok {
+		return sk.SecretSeed(), nil
+	}
+	return k.Raw()
+}
+
+type stdSigner struct {
+	signer crypto.Signer
+	pub    PubKey
+}
+
+// StdSigner returns a Signer for a standard library crypto.Signer holding an
+// Ed25519, ECDSA or RSA key. RSA keys sign with PKCS #1 v1.5.
+func StdSigner(s crypto.Signer) (Signer, error) {
+	if s == nil {
+		return nil, ErrNilPrivateKey
+	}
+	var pub PubKey
+	switch p := s.Public().(type) {
+	case ed25519.PublicKey:
+		pub = &Ed25519PublicKey{k: p}
+	case *ecdsa.PublicKey:
+		pub = &ECDSAPublicKey{pub: p}
+	case *rsa.PublicKey:
+		if p.N.BitLen() < MinRsaKeyBits {
+			return nil, ErrRsaKeyTooSmall
+		}
+		pub = &RsaPublicKey{k: *p}
+	default:
+		return nil, ErrBadKeyType
+	}
+	return &stdSigner{signer: s, pub: pub}, nil
+}
+
+func (s *stdSigner) GetPublic() PubKey {
+	return s.pub
+}
+
+func (s *stdSigner) Sign(data []byte) ([]byte, error) {
+	if s.pub.Type() == pb.KeyType_Ed25519 {
+		return s.signer.Sign(rand.Reader, data, crypto.Hash(0))
+	}
+	hash := sha256.Sum256(data)
+	return s.signer.Sign(rand.Reader, hash[:], crypto.SHA256)
+}
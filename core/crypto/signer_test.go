@@ -0,0 +1,90 @@
+package crypto_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	. "github.com/TheNoobiCat/go-libp2p/core/crypto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerPrivKey(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, MinRsaKeyBits)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name   string
+		signer crypto.Signer
+		key    crypto.PrivateKey
+	}{
+		{"ed25519", edKey, &edKey},
+		{"ecdsa", ecKey, ecKey},
+		{"rsa", rsaKey, rsaKey},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s, err := StdSigner(tc.signer)
+			require.NoError(t, err)
+			sk, err := NewSignerPrivKey(s)
+			require.NoError(t, err)
+
+			data := []byte("hello world")
+			sig, err := sk.Sign(data)
+			require.NoError(t, err)
+			ok, err := sk.GetPublic().Verify(data, sig)
+			require.NoError(t, err)
+			require.True(t, ok)
+
+			priv, _, err := KeyPairFromStdKey(tc.key)
+			require.NoError(t, err)
+			require.True(t, sk.Equals(priv))
+			require.True(t, priv.GetPublic().Equals(sk.GetPublic()))
+			require.Equal(t, priv.Type(), sk.Type())
+
+			_, err = sk.Raw()
+			require.ErrorIs(t, err, ErrKeyNotExportable)
+			_, err = MarshalPrivateKey(sk)
+			require.ErrorIs(t, err, ErrKeyNotExportable)
+		})
+	}
+}
+
+func TestSecretSeed(t *testing.T) {
+	priv, _, err := GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	raw, err := priv.Raw()
+	require.NoError(t, err)
+	seed, err := SecretSeed(priv)
+	require.NoError(t, err)
+	require.Equal(t, raw, seed)
+
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	s, err := StdSigner(edKey)
+	require.NoError(t, err)
+	sk, err := NewSignerPrivKey(s)
+	require.NoError(t, err)
+	seed, err = SecretSeed(sk)
+	require.NoError(t, err)
+	require.Len(t, seed, 32)
+
+	persisted := make([]byte, 32)
+	rand.Read(persisted)
+	sk, err = NewSignerPrivKey(s, WithSecretSeed(persisted))
+	require.NoError(t, err)
+	seed, err = SecretSeed(sk)
+	require.NoError(t, err)
+	require.Equal(t, persisted, seed)
+
+	_, err = NewSignerPrivKey(s, WithSecretSeed(persisted[:16]))
+	require.Error(t, err)
+}
@@ -1,6 +1,9 @@
 package event
 
 import (
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/record"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -85,4 +88,59 @@ type EvtLocalAddressesUpdated struct {
 // EvtAutoRelayAddrsUpdated is sent by the autorelay when the node's relay addresses are updated
 type EvtAutoRelayAddrsUpdated struct {
 	RelayAddrs []ma.Multiaddr
+	// Vouchers are the signed reservation vouchers of the relays of RelayAddrs, keyed
+	// by relay. Relays that didn't issue a voucher are omitted.
+	Vouchers map[peer.ID]*record.Envelope
+}
+
+// ObservedAddr is an address of the local host as observed by other peers, along
+// with the confidence in the observation.
+type ObservedAddr struct {
+	// Addr is the observed address.
+	Addr ma.Multiaddr
+	// LocalAddr is the local listen address the observing peers are connected to.
+	LocalAddr ma.Multiaddr
+	// Observers is the number of distinct observers currently connected that
+	// reported Addr. Observers are counted by IPv4 address and IPv6 /56 prefix, so
+	// that a single host can't inflate the count.
+	Observers int
+	// FirstObserved is the time the oldest of the current observations was made.
+	FirstObserved time.Time
+	// LastObserved is the time Addr was last reported.
+	LastObserved time.Time
+	// Confidence is the number of observers relative to the number of observers
+	// required to activate the address, capped at 1.
+	Confidence float64
+	// Activated is true if enough peers observed Addr for the host to advertise it.
+	Activated bool
+}
+
+// EvtObservedAddrsChanged is emitted by the identify service when an observed
+// address is added or removed, or when the number of its observers changes.
+type EvtObservedAddrsChanged struct {
+	// Addrs contains all the addresses currently observed.
+	Addrs []ObservedAddr
+}
+
+// BrowserAddrDefect is a defect preventing browsers from dialing one of the host's
+// addresses.
+type BrowserAddrDefect struct {
+	// Addr is the defective address.
+	Addr ma.Multiaddr
+	// Err describes the defect.
+	Err error
+}
+
+// EvtBrowserAddrsChecked is emitted after the host's browser-facing addresses, its
+// /tls/ws, /webtransport and /webrtc-direct addresses, have been checked.
+//
+// Experimental: This API is unstable. Any changes to this event will be done without a deprecation notice.
+type EvtBrowserAddrsChecked struct {
+	// Dialable are the addresses found dialable by browsers.
+	Dialable []ma.Multiaddr
+	// Defects are the defects found.
+	Defects []BrowserAddrDefect
+	// Unverified are the addresses without defect whose reachability couldn't be
+	// verified.
+	Unverified []ma.Multiaddr
 }
@@ -0,0 +1,14 @@
+package event
+
+// EvtBootstrapStatusChanged is emitted by the bootstrap subsystem when the number of
+// connected bootstrap peers changes.
+type EvtBootstrapStatusChanged struct {
+	// Connected is the number of bootstrap peers we're connected to.
+	Connected int
+	// Known is the number of bootstrap peers known from the configured peers and the
+	// last resolution of the configured names.
+	Known int
+	// Healthy is true if we're connected to at least the configured minimum number of
+	// bootstrap peers, or to all known bootstrap peers if there are fewer.
+	Healthy bool
+}
@@ -1,6 +1,10 @@
 package event
 
-import "github.com/TheNoobiCat/go-libp2p/core/network"
+import (
+	"net/netip"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+)
 
 // EvtNATDeviceTypeChanged is an event struct to be emitted when the type of the NAT device changes for a Transport Protocol.
 //
@@ -16,3 +20,29 @@ type EvtNATDeviceTypeChanged struct {
 	// how they impact Connectivity and Hole Punching.
 	NatDeviceType network.NATDeviceType
 }
+
+// NATMapping is the status of a port mapping, or of an IPv6 firewall pinhole, made
+// on the NAT device.
+type NATMapping struct {
+	// Protocol is "tcp" or "udp".
+	Protocol string
+	// Internal is the local address mapped. The IP is only set for pinholes.
+	Internal netip.AddrPort
+	// External is the address reachable from outside the NAT. It's invalid if the
+	// mapping isn't established.
+	External netip.AddrPort
+	// Pinhole is true for IPv6 firewall pinholes, that allow inbound connections to
+	// Internal.
+	Pinhole bool
+	// Err is the error of the last attempt to establish the mapping.
+	Err error
+}
+
+// EvtNATMappingsUpdated is emitted when the port mappings and pinholes made on the NAT
+// device, or their status, change.
+type EvtNATMappingsUpdated struct {
+	// Backend is the kind of port mapping service used, e.g. "NAT-PMP" or "PCP".
+	Backend string
+	// Mappings are all the port mappings and pinholes.
+	Mappings []NATMapping
+}
@@ -1,8 +1,12 @@
 package event
 
 import (
+	"time"
+
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 // EvtPeerConnectednessChanged should be emitted every time the "connectedness" to a
@@ -52,4 +56,110 @@ type EvtPeerConnectednessChanged struct {
 	Peer peer.ID
 	// Connectedness is the new connectedness state.
 	Connectedness network.Connectedness
+	// CloseReason is the reason the last connection to the peer was closed. It is
+	// only set on NotConnected events, and may be nil if the event wasn't caused by
+	// a connection closing.
+	CloseReason *network.ConnCloseReason
+}
+
+// EvtConnectionDowngraded is emitted by the swarm, if downgrade detection is enabled,
+// when a connection to a peer negotiated weaker parameters than the previous connection
+// to the peer over the same transport and in the same direction, e.g. plaintext instead
+// of a secure channel or no early muxer negotiation. It is also emitted when a connection
+// is refused because it violates the security parameters pinned for the peer.
+type EvtConnectionDowngraded struct {
+	// Peer is the remote peer.
+	Peer peer.ID
+	// Addr is the remote address of the connection.
+	Addr ma.Multiaddr
+	// Direction is the direction of the connection.
+	Direction network.Direction
+	// Previous is the state of the previous connection. It is the zero value if the
+	// connection was refused because of a pin and there was no previous connection.
+	Previous network.ConnectionState
+	// Current is the state of the new connection.
+	Current network.ConnectionState
+	// Refused is true if the connection was refused.
+	Refused bool
+}
+
+// EvtConnectionMigrated is emitted by the swarm, if connection migration is enabled,
+// when a connection to a peer is established that is better than an existing one, e.g.
+// a direct connection established by hole punching while the peer is connected through
+// a relay. New streams are opened on the better connection.
+type EvtConnectionMigrated struct {
+	// Peer is the remote peer.
+	Peer peer.ID
+	// From is the state of the worse connection.
+	From network.ConnectionState
+	// FromAddr is the remote address of the worse connection.
+	FromAddr ma.Multiaddr
+	// To is the state of the better connection.
+	To network.ConnectionState
+	// ToAddr is the remote address of the better connection.
+	ToAddr ma.Multiaddr
+	// Closing is true if the worse connection is drained, and closed once its streams
+	// are done.
+	Closing bool
+}
+
+// EvtConnectionResumption is emitted by the QUIC transport, if session resumption is
+// enabled, for every established connection. It reports whether the connection resumed
+// a previous TLS session, and whether it used 0-RTT.
+type EvtConnectionResumption struct {
+	// Peer is the remote peer.
+	Peer peer.ID
+	// Direction is the direction of the connection.
+	Direction network.Direction
+	// Resumed is true if the handshake resumed a previous session. For outbound
+	// connections, false means that no session ticket was cached for the peer or
+	// that the peer rejected it.
+	Resumed bool
+	// Used0RTT is true if 0-RTT data was accepted.
+	Used0RTT bool
+	// Rejected0RTT is true if the peer rejected the 0-RTT data of an outbound
+	// connection. The streams opened before the handshake completed failed, and the
+	// connection is closed.
+	Rejected0RTT bool
+}
+
+// EvtDialTrace is emitted by the swarm, if dial tracing is enabled, for every request
+// to connect to a peer that required dialing. It records how the addresses of the peer
+// were ranked, which of them were dialed, and the outcome of the dials.
+type EvtDialTrace struct {
+	// Peer is the dialed peer.
+	Peer peer.ID
+	// Start is the time the request was received.
+	Start time.Time
+	// Duration is the time it took to complete the request.
+	Duration time.Duration
+	// Addrs are the addresses considered for dialing, in the order of their dial delay.
+	Addrs []DialTraceAddr
+	// Excluded are the addresses that weren't considered for dialing, e.g. because
+	// they are black holed or filtered. Their Error is the reason they were excluded.
+	Excluded []DialTraceAddr
+	// ConnAddr is the remote address of the connection the request completed with.
+	// It is nil if the request failed.
+	ConnAddr ma.Multiaddr
+	// Error is the error the request failed with. It is nil on success.
+	Error error
+}
+
+// DialTraceAddr records the dial to an address of a peer in an EvtDialTrace.
+type DialTraceAddr struct {
+	// Addr is the address.
+	Addr ma.Multiaddr
+	// Delay is the dial delay assigned to the address by the dial ranking.
+	Delay time.Duration
+	// Dialed is true if the address was dialed.
+	Dialed bool
+	// DialedAt is the time the dial started.
+	DialedAt time.Time
+	// Duration is the duration of the dial. It is zero if the dial didn't complete
+	// before the request.
+	Duration time.Duration
+	// Connected is true if the dial succeeded.
+	Connected bool
+	// Error is the error the dial failed with, or the reason the address wasn't dialed.
+	Error error
 }
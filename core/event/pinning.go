@@ -0,0 +1,21 @@
+package event
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// EvtPeerPinViolation is emitted by the trust-on-first-use subsystem when a DNS name
+// or an address pinned to a peer is used to dial or reach a different peer.
+type EvtPeerPinViolation struct {
+	// Addr is the pinned DNS name or address.
+	Addr ma.Multiaddr
+	// Pinned is the peer Addr was pinned to on first use.
+	Pinned peer.ID
+	// Peer is the peer Addr was used for.
+	Peer peer.ID
+	// Refused is true if the dial was refused, false if the violation was only
+	// reported.
+	Refused bool
+}
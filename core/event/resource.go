@@ -0,0 +1,57 @@
+package event
+
+import (
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// DeniedResource is the kind of resource the resource manager denied.
+type DeniedResource string
+
+const (
+	// DeniedConn is a connection, when opening it or attaching it to a peer.
+	DeniedConn DeniedResource = "conn"
+	// DeniedStream is a stream, when opening it or attaching it to a protocol or a
+	// service.
+	DeniedStream DeniedResource = "stream"
+	// DeniedMemory is a memory reservation.
+	DeniedMemory DeniedResource = "memory"
+)
+
+// EvtResourceDenied is emitted when the resource manager denies a connection, a
+// stream or a memory reservation. It describes what hit the limit.
+type EvtResourceDenied struct {
+	// Time is when the resource was denied.
+	Time time.Time
+	// Resource is the kind of resource denied.
+	Resource DeniedResource
+	// Scope is the name of the scope whose limit was exceeded, e.g. "system",
+	// "transient" or "peer:<peer ID>". It's empty if the resource wasn't denied by a
+	// scope limit, e.g. by the limit of connections per IP address.
+	Scope string
+	// Direction is the direction of the connection or stream, if any.
+	Direction network.Direction
+	// Limit is the limit that was exceeded.
+	Limit int64
+	// Current is the usage of the scope when the resource was denied.
+	Current int64
+	// Attempted is the amount that was requested.
+	Attempted int64
+	// Peer is the peer the resource was requested for, if known.
+	Peer peer.ID
+	// Protocol is the protocol of the stream the resource was requested for, if
+	// known.
+	Protocol protocol.ID
+	// Service is the service of the stream the resource was requested for, if
+	// known.
+	Service string
+	// Endpoint is the remote address of the connection, if any.
+	Endpoint ma.Multiaddr
+	// Err is the error returned to the caller.
+	Err error
+}
@@ -5,6 +5,7 @@ package host
 
 import (
 	"context"
+	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
 	"github.com/TheNoobiCat/go-libp2p/core/event"
@@ -73,3 +74,63 @@ type Host interface {
 	// EventBus returns the hosts eventbus
 	EventBus() event.Bus
 }
+
+// ProtocolPauser is implemented by hosts that can stop accepting inbound streams for
+// a protocol without removing its handler, e.g. to put a service in maintenance mode
+// or to shed load.
+type ProtocolPauser interface {
+	// PauseProtocol refuses the inbound streams for pid until ResumeProtocol is
+	// called. If errCode is network.StreamNoError, pid is refused during protocol
+	// negotiation, as if it wasn't supported. Otherwise the streams are reset with
+	// errCode once negotiated, telling the peer why they were refused.
+	PauseProtocol(pid protocol.ID, errCode network.StreamErrorCode)
+	// ResumeProtocol accepts the inbound streams for pid again.
+	ResumeProtocol(pid protocol.ID)
+}
+
+// StreamHandlerLimits are limits on the inbound streams of a stream handler, so that
+// a single misbehaving protocol can't starve the rest of the node. The zero value
+// doesn't limit anything.
+type StreamHandlerLimits struct {
+	// MaxStreams is the maximum number of inbound streams of the protocol open
+	// concurrently. It's enforced by the resource manager, as the inbound stream
+	// limit of the protocol scope, so a stream counts until it's closed, even if the
+	// handler returned before. The streams exceeding it are reset with
+	// network.StreamResourceLimitExceeded. It has no effect if the resource manager
+	// doesn't allow setting the limits of its scopes, e.g. the
+	// network.NullResourceManager.
+	MaxStreams int
+	// StreamMemory is the memory reserved in the resource manager for each stream,
+	// from the time it's handled until it's closed. The streams it can't be reserved
+	// for are reset with network.StreamResourceLimitExceeded.
+	StreamMemory int
+	// Timeout is the maximum duration of the handling of a stream. The stream is reset
+	// with network.StreamHandlerTimeout if the handler hasn't returned by then.
+	Timeout time.Duration
+}
+
+// AddrsStreamOpener is implemented by hosts that can open streams to peers with an
+// explicitly supplied address list.
+type AddrsStreamOpener interface {
+	// NewStreamWithAddrs opens a new stream to peer p, like NewStream, dialing addrs
+	// instead of the addresses of p in the peerstore if a connection is needed. addrs
+	// are used for this call only, they aren't added to the peerstore. The addresses
+	// p advertises over identify on a new connection are still recorded.
+	NewStreamWithAddrs(ctx context.Context, p peer.ID, addrs []ma.Multiaddr, pids ...protocol.ID) (network.Stream, error)
+}
+
+// StreamHandlerLimiter is implemented by hosts that can enforce limits on the inbound
+// streams of their stream handlers.
+type StreamHandlerLimiter interface {
+	// SetStreamHandlerWithLimits sets the protocol handler on the Host's Mux, like
+	// SetStreamHandler, enforcing limits on the streams it handles.
+	SetStreamHandlerWithLimits(pid protocol.ID, limits StreamHandlerLimits, handler network.StreamHandler)
+}
+
+// UserAgentSetter is implemented by hosts whose user-agent can be changed at runtime.
+type UserAgentSetter interface {
+	// SetUserAgent changes the user-agent the host identifies itself with, and
+	// pushes it to the connected peers. An empty user-agent resets it to the
+	// default one.
+	SetUserAgent(ua string)
+}
@@ -0,0 +1,93 @@
+package host
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// NodeInfoVersion is the version of the NodeInfo document. It is incremented whenever
+// a field is removed or its meaning changes. Adding fields doesn't change the version.
+const NodeInfoVersion = 1
+
+// AddrConfidence describes how confident the host is that peers can reach it on an address.
+type AddrConfidence string
+
+const (
+	// AddrConfidenceConfirmed is used for addresses that were verified to be reachable.
+	AddrConfidenceConfirmed AddrConfidence = "confirmed"
+	// AddrConfidenceUnreachable is used for addresses that were verified to be unreachable.
+	AddrConfidenceUnreachable AddrConfidence = "unreachable"
+	// AddrConfidenceUnknown is used for addresses whose reachability wasn't verified.
+	AddrConfidenceUnknown AddrConfidence = "unknown"
+)
+
+// NodeInfoAddr is an address of the host with the confidence in its reachability.
+type NodeInfoAddr struct {
+	Addr       ma.Multiaddr   `json:"addr"`
+	Confidence AddrConfidence `json:"confidence"`
+}
+
+// NodeInfoRelay describes the host's use of relays.
+type NodeInfoRelay struct {
+	// Relays are the relays the host advertises circuit addresses for.
+	Relays []peer.ID `json:"relays"`
+	// Service is true if the host runs a relay service for other peers.
+	Service bool `json:"service"`
+}
+
+// NodeInfoResources summarizes the resource usage of the host.
+type NodeInfoResources struct {
+	// System is the usage of the system scope.
+	System network.ScopeStat `json:"system"`
+	// Transient is the usage of the transient scope.
+	Transient network.ScopeStat `json:"transient"`
+	// SystemLimits are the limits of the system scope. Nil if the resource manager
+	// doesn't expose its limits.
+	SystemLimits *NodeInfoLimits `json:"systemLimits,omitempty"`
+}
+
+// NodeInfoLimits are the limits of a resource scope.
+type NodeInfoLimits struct {
+	Streams         int   `json:"streams"`
+	StreamsInbound  int   `json:"streamsInbound"`
+	StreamsOutbound int   `json:"streamsOutbound"`
+	Conns           int   `json:"conns"`
+	ConnsInbound    int   `json:"connsInbound"`
+	ConnsOutbound   int   `json:"connsOutbound"`
+	FD              int   `json:"fd"`
+	Memory          int64 `json:"memory"`
+}
+
+// NodeInfo is a machine readable description of a host, e.g. for dashboards and
+// support bundles. It is serialized as JSON.
+type NodeInfo struct {
+	// Version is the NodeInfoVersion of the document.
+	Version         int     `json:"version"`
+	PeerID          peer.ID `json:"peerId"`
+	AgentVersion    string  `json:"agentVersion,omitempty"`
+	ProtocolVersion string  `json:"protocolVersion,omitempty"`
+	// Addrs are the addresses the host advertises to other peers.
+	Addrs []NodeInfoAddr `json:"addrs"`
+	// ListenAddrs are the addresses the host listens on.
+	ListenAddrs []ma.Multiaddr `json:"listenAddrs"`
+	// Protocols are the protocols the host has handlers for.
+	Protocols []protocol.ID `json:"protocols"`
+	// Transports are the transports the host listens on, e.g. "tcp" or "quic-v1".
+	Transports []string `json:"transports"`
+	// Reachability is the reachability of the host, "Public", "Private" or "Unknown".
+	Reachability string        `json:"reachability"`
+	Relay        NodeInfoRelay `json:"relay"`
+	// Peers is the number of connected peers.
+	Peers int `json:"peers"`
+	// Conns is the number of open connections.
+	Conns     int               `json:"conns"`
+	Resources NodeInfoResources `json:"resources"`
+}
+
+// NodeInfoProvider is implemented by hosts that can describe themselves.
+type NodeInfoProvider interface {
+	NodeInfo() NodeInfo
+}
@@ -29,6 +29,8 @@ func NewBandwidthCounter() *BandwidthCounter {
 	return new(BandwidthCounter)
 }
 
+var _ MuxerReporter = (*BandwidthCounter)(nil)
+
 // LogSentMessage records the size of an outgoing message
 // without associating the bandwidth to a specific peer or protocol.
 func (bwc *BandwidthCounter) LogSentMessage(size int64) {
@@ -55,6 +57,32 @@ func (bwc *BandwidthCounter) LogRecvMessageStream(size int64, proto protocol.ID,
 	bwc.peerIn.Get(string(p)).Mark(uint64(size))
 }
 
+// LogSentConnMessage records the size of data written to a connection with the given peer,
+// including the stream multiplexer's framing.
+func (bwc *BandwidthCounter) LogSentConnMessage(size int64, p peer.ID) {
+	bwc.totalOut.Mark(uint64(size))
+	bwc.peerOut.Get(string(p)).Mark(uint64(size))
+}
+
+// LogRecvConnMessage records the size of data read from a connection with the given peer,
+// including the stream multiplexer's framing.
+func (bwc *BandwidthCounter) LogRecvConnMessage(size int64, p peer.ID) {
+	bwc.totalIn.Mark(uint64(size))
+	bwc.peerIn.Get(string(p)).Mark(uint64(size))
+}
+
+// LogSentProtocolMessage records the size of an outgoing message over a stream with the
+// given protocol.ID.
+func (bwc *BandwidthCounter) LogSentProtocolMessage(size int64, proto protocol.ID) {
+	bwc.protocolOut.Get(string(proto)).Mark(uint64(size))
+}
+
+// LogRecvProtocolMessage records the size of an incoming message over a stream with the
+// given protocol.ID.
+func (bwc *BandwidthCounter) LogRecvProtocolMessage(size int64, proto protocol.ID) {
+	bwc.protocolIn.Get(string(proto)).Mark(uint64(size))
+}
+
 // GetBandwidthForPeer returns a Stats struct with bandwidth metrics associated with the given peer.ID.
 // The metrics returned include all traffic sent / received for the peer, regardless of protocol.
 func (bwc *BandwidthCounter) GetBandwidthForPeer(p peer.ID) (out Stats) {
@@ -155,6 +183,34 @@ func (bwc *BandwidthCounter) GetBandwidthByProtocol() map[protocol.ID]Stats {
 	return protocols
 }
 
+// BandwidthSnapshot is a point-in-time copy of all the statistics of a BandwidthCounter.
+type BandwidthSnapshot struct {
+	Totals     Stats
+	ByPeer     map[peer.ID]Stats
+	ByProtocol map[protocol.ID]Stats
+}
+
+// Snapshot returns the current statistics. This method may be very expensive.
+func (bwc *BandwidthCounter) Snapshot() BandwidthSnapshot {
+	return BandwidthSnapshot{
+		Totals:     bwc.GetBandwidthTotals(),
+		ByPeer:     bwc.GetBandwidthByPeer(),
+		ByProtocol: bwc.GetBandwidthByProtocol(),
+	}
+}
+
+// ResetProtocol clears the stats of the given protocol.ID.
+func (bwc *BandwidthCounter) ResetProtocol(proto protocol.ID) {
+	bwc.protocolIn.Remove(string(proto))
+	bwc.protocolOut.Remove(string(proto))
+}
+
+// ResetPeer clears the stats of the given peer.ID.
+func (bwc *BandwidthCounter) ResetPeer(p peer.ID) {
+	bwc.peerIn.Remove(string(p))
+	bwc.peerOut.Remove(string(p))
+}
+
 // Reset clears all stats.
 func (bwc *BandwidthCounter) Reset() {
 	bwc.totalIn.Reset()
@@ -168,3 +168,39 @@ func TestResetBandwidthCounter(t *testing.T) {
 		require.Empty(t, bwc.GetBandwidthByPeer(), "expected 0 peers")
 	}
 }
+
+func TestBandwidthCounterMuxerReporter(t *testing.T) {
+	bwc := NewBandwidthCounter()
+
+	p1, p2 := peer.ID("peer-1"), peer.ID("peer-2")
+	proto1, proto2 := protocol.ID("proto-1"), protocol.ID("proto-2")
+
+	bwc.LogSentConnMessage(110, p1)
+	bwc.LogRecvConnMessage(60, p1)
+	bwc.LogSentConnMessage(10, p2)
+	bwc.LogSentProtocolMessage(100, proto1)
+	bwc.LogRecvProtocolMessage(50, proto1)
+	bwc.LogSentProtocolMessage(5, proto2)
+
+	time.Sleep(200 * time.Millisecond) // make sure the meters are registered with the sweeper
+	cl.Add(time.Second)
+
+	snap := bwc.Snapshot()
+	require.Equal(t, int64(120), snap.Totals.TotalOut)
+	require.Equal(t, int64(60), snap.Totals.TotalIn)
+	require.Len(t, snap.ByPeer, 2)
+	require.Equal(t, int64(110), snap.ByPeer[p1].TotalOut)
+	require.Equal(t, int64(60), snap.ByPeer[p1].TotalIn)
+	require.Len(t, snap.ByProtocol, 2)
+	require.Equal(t, int64(100), snap.ByProtocol[proto1].TotalOut)
+	require.Equal(t, int64(50), snap.ByProtocol[proto1].TotalIn)
+
+	bwc.ResetProtocol(proto1)
+	bwc.ResetPeer(p2)
+	snap = bwc.Snapshot()
+	require.Equal(t, int64(120), snap.Totals.TotalOut)
+	require.Len(t, snap.ByProtocol, 1)
+	require.Equal(t, int64(5), snap.ByProtocol[proto2].TotalOut)
+	require.Len(t, snap.ByPeer, 1)
+	require.Equal(t, int64(110), snap.ByPeer[p1].TotalOut)
+}
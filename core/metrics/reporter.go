@@ -29,3 +29,21 @@ type Reporter interface {
 	GetBandwidthByPeer() map[peer.ID]Stats
 	GetBandwidthByProtocol() map[protocol.ID]Stats
 }
+
+// MuxerReporter is a Reporter that accounts traffic at the stream multiplexer layer.
+//
+// When a MuxerReporter is used, connections upgraded by the libp2p upgrader report the
+// bytes the stream multiplexer reads from and writes to the connection, including the
+// multiplexer's framing, using LogSentConnMessage and LogRecvConnMessage. These account
+// for the totals and the per peer statistics. The payload of each stream is reported
+// with LogSentProtocolMessage and LogRecvProtocolMessage for the per protocol statistics.
+// Connections of transports with built-in stream multiplexing, like QUIC, are still
+// accounted using LogSentMessage, LogRecvMessage, LogSentMessageStream and
+// LogRecvMessageStream.
+type MuxerReporter interface {
+	Reporter
+	LogSentConnMessage(int64, peer.ID)
+	LogRecvConnMessage(int64, peer.ID)
+	LogSentProtocolMessage(int64, protocol.ID)
+	LogRecvProtocolMessage(int64, protocol.ID)
+}
@@ -0,0 +1,85 @@
+package network
+
+import (
+	"errors"
+	"io"
+	"time"
+
+	pool "github.com/libp2p/go-buffer-pool"
+)
+
+// MaxGracefulCloseDrain is the maximum number of bytes CloseGracefully discards
+// while waiting for the peer to close its side of the stream.
+const MaxGracefulCloseDrain = 64 << 10
+
+// GracefulCloser is implemented by MuxedStreams that close gracefully more
+// efficiently than CloseGracefully does using the MuxedStream methods. Callers should
+// use CloseGracefully rather than calling CloseGracefully on the stream directly.
+type GracefulCloser interface {
+	// CloseGracefully implements CloseGracefully for the stream.
+	CloseGracefully(deadline time.Time) error
+}
+
+// CloseGracefully closes s for writing, discards the data the peer still sends until
+// it closes its side of the stream, and then closes s.
+//
+// This is what the requesting side of a request / response protocol should do once
+// it read the response: it makes sure that the peer received the request and that
+// the stream is freed on both ends, without waiting forever for a misbehaving peer.
+//
+// If the peer doesn't close its side by deadline, or sends more than
+// MaxGracefulCloseDrain bytes, s is closed anyway and the error is returned. s is
+// freed in all cases, the caller must not use it anymore.
+func CloseGracefully(s MuxedStream, deadline time.Time) error {
+	if gc, ok := s.(GracefulCloser); ok {
+		return gc.CloseGracefully(deadline)
+	}
+	if err := s.CloseWrite(); err != nil {
+		s.Reset()
+		return err
+	}
+	if err := s.SetReadDeadline(deadline); err != nil {
+		s.Reset()
+		return err
+	}
+	err := Drain(s, MaxGracefulCloseDrain)
+	if cerr := s.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// AwaitEOF waits until the peer closes its side of s, and returns ErrUnexpectedData
+// if the peer sends data instead. It returns the read error if the peer didn't close
+// its side by deadline. It doesn't close s.
+func AwaitEOF(s MuxedStream, deadline time.Time) error {
+	if err := s.SetReadDeadline(deadline); err != nil {
+		return err
+	}
+	err := Drain(s, 0)
+	s.SetReadDeadline(time.Time{})
+	return err
+}
+
+// Drain reads and discards the data of r until io.EOF. It returns nil once it reads
+// io.EOF, and ErrUnexpectedData if r returns more than max bytes before that.
+//
+// It's meant to be used by the implementations of GracefulCloser.
+func Drain(r io.Reader, max int64) error {
+	buf := pool.Get(1024)
+	defer pool.Put(buf)
+	var total int64
+	for {
+		n, err := r.Read(buf)
+		total += int64(n)
+		if total > max {
+			return ErrUnexpectedData
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -2,6 +2,7 @@ package network
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 
@@ -16,8 +17,11 @@ import (
 type ConnErrorCode uint32
 
 type ConnError struct {
-	Remote         bool
-	ErrorCode      ConnErrorCode
+	Remote    bool
+	ErrorCode ConnErrorCode
+	// Reason is the optional reason string sent along with the error code. Only
+	// some transports, e.g. QUIC, support sending it.
+	Reason         string
 	TransportError error
 }
 
@@ -26,10 +30,14 @@ func (c *ConnError) Error() string {
 	if c.Remote {
 		side = "remote"
 	}
+	code := fmt.Sprintf("code: 0x%x", c.ErrorCode)
+	if c.Reason != "" {
+		code += fmt.Sprintf(" (%s)", c.Reason)
+	}
 	if c.TransportError != nil {
-		return fmt.Sprintf("connection closed (%s): code: 0x%x: transport error: %s", side, c.ErrorCode, c.TransportError)
+		return fmt.Sprintf("connection closed (%s): %s: transport error: %s", side, code, c.TransportError)
 	}
-	return fmt.Sprintf("connection closed (%s): code: 0x%x", side, c.ErrorCode)
+	return fmt.Sprintf("connection closed (%s): %s", side, code)
 }
 
 func (c *ConnError) Is(target error) bool {
@@ -56,6 +64,33 @@ const (
 	ConnCodeOutOfRange            ConnErrorCode = 0x1008
 )
 
+// ConnCloseReason describes why a connection was closed.
+type ConnCloseReason struct {
+	// Remote is true if the remote peer closed the connection.
+	Remote bool
+	// ErrorCode is the error code the connection was closed with. It is ConnNoError
+	// if the connection was closed without an error code, or if the transport
+	// doesn't support sending error codes.
+	ErrorCode ConnErrorCode
+	// Reason is the optional reason string the remote peer sent along with the
+	// error code.
+	Reason string
+	// Err is the transport error that closed the connection. It is nil if the
+	// connection was closed by calling Close or CloseWithError.
+	Err error
+}
+
+// NewConnCloseReason returns the reason for a connection closed with err. If err
+// doesn't carry a ConnError, the connection is assumed to have failed, e.g. because
+// the underlying connection was reset, and Remote is false.
+func NewConnCloseReason(err error) ConnCloseReason {
+	var ce *ConnError
+	if errors.As(err, &ce) {
+		return ConnCloseReason{Remote: ce.Remote, ErrorCode: ce.ErrorCode, Reason: ce.Reason, Err: err}
+	}
+	return ConnCloseReason{Err: err}
+}
+
 // Conn is a connection to a remote peer. It multiplexes streams.
 // Usually there is no need to use a Conn directly, but it may
 // be useful to get information about the peer on the other side:
@@ -87,6 +122,10 @@ type Conn interface {
 	// IsClosed returns whether a connection is fully closed, so it can
 	// be garbage collected.
 	IsClosed() bool
+
+	// CloseReason returns the reason the connection was closed. It returns false
+	// if the connection is still open.
+	CloseReason() (ConnCloseReason, bool)
 }
 
 // ConnectionState holds information about the connection.
@@ -3,6 +3,8 @@ package network
 import (
 	"context"
 	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 // DialPeerTimeout is the default timeout for a single call to `DialPeer`. When
@@ -15,6 +17,10 @@ type dialPeerTimeoutCtxKey struct{}
 type forceDirectDialCtxKey struct{}
 type allowLimitedConnCtxKey struct{}
 type simConnectCtxKey struct{ isClient bool }
+type transportConstraintCtxKey struct{}
+type addrFilterCtxKey struct{}
+type dialAddrsCtxKey struct{}
+type dialAboveHighWaterCtxKey struct{}
 
 var noDial = noDialCtxKey{}
 var forceDirectDial = forceDirectDialCtxKey{}
@@ -40,6 +46,23 @@ func GetForceDirectDial(ctx context.Context) (forceDirect bool, reason string) {
 	return false, ""
 }
 
+// WithDialAboveHighWater constructs a new context with an option that instructs the
+// network to dial a peer even if the connection manager has more connections than
+// its high water mark.
+func WithDialAboveHighWater(ctx context.Context, reason string) context.Context {
+	return context.WithValue(ctx, dialAboveHighWaterCtxKey{}, reason)
+}
+
+// GetDialAboveHighWater returns true if the dial above high water option is set in
+// the context.
+func GetDialAboveHighWater(ctx context.Context) (dialAboveHighWater bool, reason string) {
+	v := ctx.Value(dialAboveHighWaterCtxKey{})
+	if v != nil {
+		return true, v.(string)
+	}
+	return false, ""
+}
+
 // WithSimultaneousConnect constructs a new context with an option that instructs the transport
 // to apply hole punching logic where applicable.
 // EXPERIMENTAL
@@ -128,3 +151,65 @@ func GetUseTransient(ctx context.Context) (usetransient bool, reason string) {
 	}
 	return false, ""
 }
+
+// WithTransportConstraint constructs a new context with an option that constrains a
+// single Connect, DialPeer or NewStream call to connections over the given transports:
+// existing connections over other transports aren't used, and only addresses of the
+// given transports are dialed.
+//
+// Transports are named by the multiaddr protocol identifying them, e.g. "tcp",
+// "quic-v1", "webtransport", "webrtc-direct", "ws" or "p2p-circuit". The transport of
+// an address is its outermost one: /ip4/1.2.3.4/udp/1/quic-v1/webtransport is a
+// webtransport address, not a quic-v1 one.
+// EXPERIMENTAL
+func WithTransportConstraint(ctx context.Context, transports ...string) context.Context {
+	if len(transports) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, transportConstraintCtxKey{}, transports)
+}
+
+// GetTransportConstraint returns the transports set with WithTransportConstraint, or
+// nil if the transports aren't constrained.
+// EXPERIMENTAL
+func GetTransportConstraint(ctx context.Context) []string {
+	v, _ := ctx.Value(transportConstraintCtxKey{}).([]string)
+	return v
+}
+
+// WithAddrFilter constructs a new context with an option that constrains a single
+// Connect, DialPeer or NewStream call to connections with a remote address accepted
+// by f, e.g. manet.IsPublicAddr. If ctx already has an address filter, addresses must
+// be accepted by both filters.
+// EXPERIMENTAL
+func WithAddrFilter(ctx context.Context, f func(ma.Multiaddr) bool) context.Context {
+	if prev := GetAddrFilter(ctx); prev != nil {
+		next := f
+		f = func(a ma.Multiaddr) bool { return prev(a) && next(a) }
+	}
+	return context.WithValue(ctx, addrFilterCtxKey{}, f)
+}
+
+// GetAddrFilter returns the address filter set with WithAddrFilter, or nil if none is
+// set.
+// EXPERIMENTAL
+func GetAddrFilter(ctx context.Context) func(ma.Multiaddr) bool {
+	v, _ := ctx.Value(addrFilterCtxKey{}).(func(ma.Multiaddr) bool)
+	return v
+}
+
+// WithDialAddrs constructs a new context with an option that makes a single Connect,
+// DialPeer or NewStream call dial addrs instead of the addresses of the peer in the
+// peerstore. addrs aren't added to the peerstore. Existing connections to the peer
+// are still used.
+// EXPERIMENTAL
+func WithDialAddrs(ctx context.Context, addrs []ma.Multiaddr) context.Context {
+	return context.WithValue(ctx, dialAddrsCtxKey{}, addrs)
+}
+
+// GetDialAddrs returns the addresses set with WithDialAddrs, and false if none are set.
+// EXPERIMENTAL
+func GetDialAddrs(ctx context.Context) ([]ma.Multiaddr, bool) {
+	v, ok := ctx.Value(dialAddrsCtxKey{}).([]ma.Multiaddr)
+	return v, ok
+}
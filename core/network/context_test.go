@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 )
 
@@ -57,3 +58,35 @@ func TestSimultaneousConnect(t *testing.T) {
 		require.Equal(t, "foo", reason)
 	})
 }
+
+func TestTransportConstraint(t *testing.T) {
+	ctx := context.Background()
+	require.Nil(t, GetTransportConstraint(ctx))
+	require.Equal(t, ctx, WithTransportConstraint(ctx))
+
+	ctx = WithTransportConstraint(ctx, "quic-v1", "webtransport")
+	require.Equal(t, []string{"quic-v1", "webtransport"}, GetTransportConstraint(ctx))
+}
+
+func TestAddrFilter(t *testing.T) {
+	ctx := context.Background()
+	require.Nil(t, GetAddrFilter(ctx))
+
+	tcp := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+	quic := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1")
+	ip6 := ma.StringCast("/ip6/::1/udp/1/quic-v1")
+	isIP4 := func(a ma.Multiaddr) bool { return a[0].Code() == ma.P_IP4 }
+	isUDP := func(a ma.Multiaddr) bool { return a[1].Code() == ma.P_UDP }
+
+	ctx = WithAddrFilter(ctx, isIP4)
+	f := GetAddrFilter(ctx)
+	require.True(t, f(tcp))
+	require.True(t, f(quic))
+	require.False(t, f(ip6))
+
+	// filters are combined
+	f = GetAddrFilter(WithAddrFilter(ctx, isUDP))
+	require.False(t, f(tcp))
+	require.True(t, f(quic))
+	require.False(t, f(ip6))
+}
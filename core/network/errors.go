@@ -14,8 +14,15 @@ func (e temporaryError) Timeout() bool   { return false }
 var _ net.Error = temporaryError("")
 
 // ErrNoRemoteAddrs is returned when there are no addresses associated with a peer during a dial.
+// It matches (using errors.Is) the errors returned by dials to peers without any usable
+// address. Retrying won't succeed until new addresses of the peer are learned.
 var ErrNoRemoteAddrs = errors.New("no remote addresses")
 
+// ErrAllDialsFailed is returned when all the dials to the addresses of a peer failed. It
+// matches (using errors.Is) the errors returned by such dials, which also carry the
+// error of every address; the swarm returns a *swarm.DialError. Retrying may succeed.
+var ErrAllDialsFailed = errors.New("all dials failed")
+
 // ErrNoConn is returned when attempting to open a stream to a peer with the NoDial
 // option and no usable connection is available.
 var ErrNoConn = errors.New("no usable connection to peer")
@@ -31,9 +38,27 @@ var ErrTransientConn = ErrLimitedConn
 var ErrLimitedConn = errors.New("limited connection to peer")
 
 // ErrResourceLimitExceeded is returned when attempting to perform an operation that would
-// exceed system resource limits.
+// exceed system resource limits. Retrying once resources were released may succeed.
 var ErrResourceLimitExceeded = temporaryError("resource limit exceeded")
 
 // ErrResourceScopeClosed is returned when attempting to reserve resources in a closed resource
 // scope.
 var ErrResourceScopeClosed = errors.New("resource scope closed")
+
+// ErrPeerGated is returned when the connection gater rejects a connection. It matches
+// (using errors.Is) the errors returned by dials and upgrades rejected by the gater.
+// Retrying won't succeed unless the gater changes its decision.
+var ErrPeerGated = errors.New("connection gated")
+
+// ErrNegotiationFailed is returned when the peer doesn't support any of the protocols
+// requested when opening a stream, or the negotiation failed otherwise. The error
+// wraps the cause of the failure.
+var ErrNegotiationFailed = errors.New("protocol negotiation failed")
+
+// ErrPingNotSupported is returned by the Ping of a Conn when neither the stream multiplexer nor
+// the transport of the connection can ping the peer.
+var ErrPingNotSupported = errors.New("connection doesn't support ping")
+
+// ErrUnexpectedData is returned by AwaitEOF and CloseGracefully when the peer sends
+// data instead of closing its side of the stream.
+var ErrUnexpectedData = errors.New("unexpected data on stream")
@@ -53,6 +53,8 @@ const (
 	StreamShutdown                  StreamErrorCode = 0x1007
 	StreamGated                     StreamErrorCode = 0x1008
 	StreamCodeOutOfRange            StreamErrorCode = 0x1009
+	StreamNegotiationTimeout        StreamErrorCode = 0x100A
+	StreamHandlerTimeout            StreamErrorCode = 0x100B
 )
 
 // MuxedStream is a bidirectional io pipe within a connection.
@@ -139,6 +141,47 @@ type MuxedConn interface {
 	AcceptStream() (MuxedStream, error)
 }
 
+// MuxerStats are statistics about the streams of a MuxedConn. They show whether the
+// application keeps up with reading the data the peer sends, or whether the peer is
+// being back-pressured by flow control.
+type MuxerStats struct {
+	// NumStreams is the number of open streams.
+	NumStreams int
+	// BufferedBytes is the number of bytes received on the streams that haven't been
+	// read by the application yet. It is 0 if the stream multiplexer doesn't expose
+	// it, like yamux.
+	BufferedBytes int64
+	// RecvWindow is the aggregate receive window of the streams, in bytes.
+	RecvWindow int64
+	// RTT is the smoothed round trip time of the connection, as measured by the
+	// stream multiplexer's pings or by the transport. It is 0 if it's unknown.
+	RTT time.Duration
+}
+
+// RecvWindowUtilization returns the fraction of the aggregate receive window that is
+// filled with unread data. A value close to 1 means that the peer is blocked by flow
+// control.
+func (s MuxerStats) RecvWindowUtilization() float64 {
+	if s.RecvWindow <= 0 {
+		return 0
+	}
+	return min(float64(s.BufferedBytes)/float64(s.RecvWindow), 1)
+}
+
+// MuxerStatsReporter is implemented by MuxedConns that report MuxerStats.
+type MuxerStatsReporter interface {
+	MuxerStats() MuxerStats
+}
+
+// Pinger is implemented by MuxedConns and transport connections that can measure the
+// round trip time of the connection without opening a stream, e.g. with a yamux ping.
+// The Conns of the swarm implement it with the ping of their stream multiplexer or
+// transport, and return ErrPingNotSupported if neither can ping the peer.
+type Pinger interface {
+	// Ping measures the round trip time of the connection.
+	Ping(ctx context.Context) (time.Duration, error)
+}
+
 // Multiplexer wraps a net.Conn with a stream multiplexing
 // implementation and returns a MuxedConn that supports opening
 // multiple streams over the underlying net.Conn
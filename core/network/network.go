@@ -12,6 +12,7 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 
 	ma "github.com/multiformats/go-multiaddr"
 )
@@ -112,6 +113,22 @@ type ConnStats struct {
 	NumStreams int
 }
 
+type statMuxer struct{}
+
+// StatMuxer is the key of the connection's MuxerStatsReporter in ConnStats.Extra, if
+// its stream multiplexer reports MuxerStats. Use ConnStats.MuxerStats to query it.
+var StatMuxer = statMuxer{}
+
+// MuxerStats returns the current stream multiplexer statistics of the connection. It
+// returns false if the stream multiplexer doesn't report them.
+func (s ConnStats) MuxerStats() (MuxerStats, bool) {
+	r, ok := s.Extra[StatMuxer].(MuxerStatsReporter)
+	if !ok {
+		return MuxerStats{}, false
+	}
+	return r.MuxerStats(), true
+}
+
 // Stats stores metadata pertaining to a given Stream / Conn.
 type Stats struct {
 	// Direction specifies whether this is an inbound or an outbound connection.
@@ -124,6 +141,18 @@ type Stats struct {
 	Limited bool
 	// Extra stores additional metadata about this connection.
 	Extra map[interface{}]interface{}
+
+	// The following fields are only set for streams.
+
+	// Protocol is the application protocol negotiated on the stream.
+	Protocol protocol.ID
+	// BytesRead is the number of bytes read from the stream.
+	BytesRead int64
+	// BytesWritten is the number of bytes written to the stream.
+	BytesWritten int64
+	// LastActivity is the last time data was read from or written to the stream, or
+	// the time the stream was opened if no data was transferred yet.
+	LastActivity time.Time
 }
 
 // StreamHandler is the type of function used to listen for
@@ -0,0 +1,91 @@
+package network
+
+import (
+	"slices"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ConnectionQuality summarizes the connections to a peer. It helps deciding whether
+// to start a heavy transfer with the peer, e.g. only over a direct connection.
+type ConnectionQuality struct {
+	// Connectedness is the best connectedness over all the connections.
+	Connectedness Connectedness
+	// NumConns is the number of open connections.
+	NumConns int
+	// Transports are the transports of the open connections, as reported by
+	// ConnectionState.Transport, sorted and deduplicated.
+	Transports []string
+	// Direct is true if at least one connection is direct, i.e. not relayed.
+	Direct bool
+	// Relayed is true if at least one connection goes through a relay.
+	Relayed bool
+	// Limited is true if all the connections are limited. See Stats.Limited.
+	Limited bool
+	// RTT is the estimated round trip time to the peer, 0 if it's unknown.
+	RTT time.Duration
+	// Age is how long the oldest open connection has been open.
+	Age time.Duration
+}
+
+// ConnectionQualityReporter is implemented by Networks that summarize the connections
+// to a peer.
+type ConnectionQualityReporter interface {
+	// ConnectionQuality summarizes the connections to the given peer.
+	ConnectionQuality(peer.ID) ConnectionQuality
+}
+
+// NewConnectionQuality summarizes conns, the connections to a peer. rtt is the
+// estimated round trip time to the peer, usually the peerstore's LatencyEWMA.
+// Closed connections are ignored.
+//
+// It's meant to be used by the implementations of ConnectionQualityReporter.
+func NewConnectionQuality(conns []Conn, rtt time.Duration, now time.Time) ConnectionQuality {
+	q := ConnectionQuality{RTT: rtt}
+	var haveUnlimited bool
+	for _, c := range conns {
+		if c.IsClosed() {
+			continue
+		}
+		q.NumConns++
+		stat := c.Stat()
+		if stat.Limited {
+			q.Limited = true
+		} else {
+			haveUnlimited = true
+		}
+		if isRelayAddr(c.RemoteMultiaddr()) {
+			q.Relayed = true
+		} else {
+			q.Direct = true
+		}
+		if t := c.ConnState().Transport; t != "" && !slices.Contains(q.Transports, t) {
+			q.Transports = append(q.Transports, t)
+		}
+		if !stat.Opened.IsZero() {
+			q.Age = max(q.Age, now.Sub(stat.Opened))
+		}
+	}
+	slices.Sort(q.Transports)
+	switch {
+	case haveUnlimited:
+		q.Connectedness = Connected
+		q.Limited = false
+	case q.Limited:
+		q.Connectedness = Limited
+	default:
+		q.Connectedness = NotConnected
+	}
+	return q
+}
+
+func isRelayAddr(a ma.Multiaddr) bool {
+	if a == nil {
+		return false
+	}
+	_, err := a.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
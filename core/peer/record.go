@@ -1,7 +1,9 @@
 package peer
 
 import (
+	"bytes"
 	"fmt"
+	"slices"
 	"sync"
 	"time"
 
@@ -92,6 +94,11 @@ type PeerRecord struct {
 	// but newer PeerRecords MUST have a greater Seq value than older records
 	// for the same peer.
 	Seq uint64
+
+	// Vouchers contains serialized signed envelopes in which third parties vouch for
+	// some of the Addrs, e.g. the reservation vouchers of the relays of the relayed
+	// addresses. They are opaque to the PeerRecord, consumers must verify them.
+	Vouchers [][]byte
 }
 
 // NewPeerRecord returns a PeerRecord with a timestamp-based sequence number.
@@ -122,6 +129,7 @@ func PeerRecordFromProtobuf(msg *pb.PeerRecord) (*PeerRecord, error) {
 	record.PeerID = id
 	record.Addrs = addrsFromProtobuf(msg.Addresses)
 	record.Seq = msg.Seq
+	record.Vouchers = msg.Vouchers
 
 	return record, nil
 }
@@ -214,7 +222,7 @@ func (r *PeerRecord) Equal(other *PeerRecord) bool {
 			return false
 		}
 	}
-	return true
+	return slices.EqualFunc(r.Vouchers, other.Vouchers, bytes.Equal)
 }
 
 // ToProtobuf returns the equivalent Protocol Buffer struct object of a PeerRecord.
@@ -227,6 +235,7 @@ func (r *PeerRecord) ToProtobuf() (*pb.PeerRecord, error) {
 		PeerId:    idBytes,
 		Addresses: addrsToProtobuf(r.Addrs),
 		Seq:       r.Seq,
+		Vouchers:  r.Vouchers,
 	}, nil
 }
 
@@ -53,6 +53,36 @@ func TestSignedPeerRecordFromEnvelope(t *testing.T) {
 	})
 }
 
+func TestPeerRecordVouchers(t *testing.T) {
+	priv, _, err := test.RandTestKeyPair(crypto.Ed25519, 256)
+	test.AssertNilError(t, err)
+	id, err := IDFromPrivateKey(priv)
+	test.AssertNilError(t, err)
+
+	rec := &PeerRecord{
+		PeerID:   id,
+		Addrs:    test.GenerateTestAddrs(2),
+		Seq:      TimestampSeq(),
+		Vouchers: [][]byte{[]byte("voucher1"), []byte("voucher2")},
+	}
+	envelope, err := record.Seal(rec, priv)
+	test.AssertNilError(t, err)
+	envBytes, err := envelope.Marshal()
+	test.AssertNilError(t, err)
+
+	_, untypedRecord, err := record.ConsumeEnvelope(envBytes, PeerRecordEnvelopeDomain)
+	test.AssertNilError(t, err)
+	rec2 := untypedRecord.(*PeerRecord)
+	if !rec.Equal(rec2) {
+		t.Error("expected the vouchers to be unaltered after round-trip serde")
+	}
+
+	rec2.Vouchers = rec2.Vouchers[:1]
+	if rec.Equal(rec2) {
+		t.Error("expected records with different vouchers not to be equal")
+	}
+}
+
 // This is pretty much guaranteed to pass on Linux no matter how we implement it, but Windows has
 // low clock precision. This makes sure we never get a duplicate.
 func TestTimestampSeq(t *testing.T) {
@@ -213,6 +213,182 @@ type Metrics interface {
 	RemovePeer(peer.ID)
 }
 
+// AddrMetrics tracks the latency of the individual addresses of peers, as measured
+// over the connections to these addresses. It's used to dial the fastest addresses
+// first.
+//
+// It is optional, callers should type-assert on the AddrMetrics interface:
+//
+//	if am, ok := aPeerstore.(AddrMetrics); ok {
+//	    am.RecordAddrLatency(p, addr, rtt)
+//	}
+type AddrMetrics interface {
+	// RecordAddrLatency records a new latency measurement for an address of a peer.
+	RecordAddrLatency(peer.ID, ma.Multiaddr, time.Duration)
+
+	// AddrLatencyEWMA returns an exponentially-weighted moving avg. of the latency
+	// measurements for an address of a peer. It is 0 if there are none.
+	AddrLatencyEWMA(peer.ID, ma.Multiaddr) time.Duration
+}
+
+// AddrSource is where an address of a peer was learned from.
+type AddrSource string
+
+const (
+	// AddrSourceManual is for addresses provided by the operator, e.g. bootstrap
+	// peers or static relays.
+	AddrSourceManual AddrSource = "manual"
+	// AddrSourceIdentify is for addresses advertised by the peer over identify.
+	AddrSourceIdentify AddrSource = "identify"
+	// AddrSourcePeerRecord is for addresses from a signed peer record.
+	AddrSourcePeerRecord AddrSource = "peer-record"
+	// AddrSourceRouting is for addresses found by peer routing, e.g. the DHT.
+	AddrSourceRouting AddrSource = "routing"
+	// AddrSourceMDNS is for addresses discovered over mDNS.
+	AddrSourceMDNS AddrSource = "mdns"
+	// AddrSourceRelayReservation is for addresses of relays we reserve a slot with.
+	AddrSourceRelayReservation AddrSource = "relay-reservation"
+)
+
+// AddrProvenance records where an address of a peer was learned from, and when.
+type AddrProvenance struct {
+	Addr ma.Multiaddr
+	// Sources maps the sources the address was learned from to the last time it
+	// was added from them. It is empty if the address was only added without a source.
+	Sources map[AddrSource]time.Time
+}
+
+// AddrProvenanceBook tracks where the addresses of peers were learned from. The
+// sources of an address are forgotten when the address is removed.
+//
+// It is optional, callers should type-assert on the AddrProvenanceBook interface,
+// or use AddAddrsFrom:
+//
+//	if pb, ok := aPeerstore.(AddrProvenanceBook); ok {
+//	    addrs := pb.AddrsFrom(p, AddrSourceManual)
+//	}
+type AddrProvenanceBook interface {
+	// AddAddrsFrom calls AddAddrs, recording src as a source of addrs.
+	AddAddrsFrom(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src AddrSource)
+
+	// SetAddrsFrom calls SetAddrs, recording src as a source of addrs.
+	SetAddrsFrom(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src AddrSource)
+
+	// AddrProvenance returns the provenance of the valid addresses of a peer.
+	AddrProvenance(p peer.ID) []AddrProvenance
+
+	// AddrsFrom returns the valid addresses of a peer learned from any of srcs.
+	AddrsFrom(p peer.ID, srcs ...AddrSource) []ma.Multiaddr
+}
+
+// AddAddrsFrom adds addrs to ab, recording src as their source if ab is an
+// AddrProvenanceBook.
+func AddAddrsFrom(ab AddrBook, p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src AddrSource) {
+	if pb, ok := ab.(AddrProvenanceBook); ok {
+		pb.AddAddrsFrom(p, addrs, ttl, src)
+		return
+	}
+	ab.AddAddrs(p, addrs, ttl)
+}
+
+// AddrsCertified returns whether all the current addresses of p in ab are certified,
+// i.e. they were last advertised by p in a signed peer record rather than in an
+// unsigned list. It returns false if p has no addresses, or if ab isn't an
+// AddrProvenanceBook.
+func AddrsCertified(ab AddrBook, p peer.ID) bool {
+	pb, ok := ab.(AddrProvenanceBook)
+	if !ok {
+		return false
+	}
+	provs := pb.AddrProvenance(p)
+	if len(provs) == 0 {
+		return false
+	}
+	for _, prov := range provs {
+		certified, ok := prov.Sources[AddrSourcePeerRecord]
+		if !ok || certified.Before(prov.Sources[AddrSourceIdentify]) {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangeKind is the kind of a peerstore Change.
+type ChangeKind int
+
+const (
+	// AddrsAdded means that addresses were added for the peer.
+	AddrsAdded ChangeKind = iota
+	// AddrsUpdated means that the TTL of addresses of the peer was updated.
+	AddrsUpdated
+	// AddrsRemoved means that addresses of the peer were removed, or expired.
+	AddrsRemoved
+	// ProtocolsAdded means that protocols were added for the peer.
+	ProtocolsAdded
+	// ProtocolsRemoved means that protocols of the peer were removed.
+	ProtocolsRemoved
+	// KeysAdded means that a public or private key was added for the peer.
+	KeysAdded
+	// KeysRemoved means that the keys of the peer were removed.
+	KeysRemoved
+	// ChangesDropped means that the subscriber fell too far behind and changes were
+	// dropped after the previous change. It carries no peer. Subscribers that track
+	// the peerstore incrementally should read its current state again.
+	ChangesDropped
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case AddrsAdded:
+		return "addrs added"
+	case AddrsUpdated:
+		return "addrs updated"
+	case AddrsRemoved:
+		return "addrs removed"
+	case ProtocolsAdded:
+		return "protocols added"
+	case ProtocolsRemoved:
+		return "protocols removed"
+	case KeysAdded:
+		return "keys added"
+	case KeysRemoved:
+		return "keys removed"
+	case ChangesDropped:
+		return "changes dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// Change is a change to the information stored about a peer.
+type Change struct {
+	Kind ChangeKind
+	Peer peer.ID
+	// Addrs are the added, updated or removed addresses, for the address changes.
+	Addrs []ma.Multiaddr
+	// Protocols are the added or removed protocols, for the protocol changes.
+	Protocols []protocol.ID
+}
+
+// ChangeNotifier notifies about the changes to the addresses, protocols and keys
+// stored in a peerstore, so that they don't need to be polled.
+//
+// It is optional, callers should type-assert on the ChangeNotifier interface. It's
+// implemented by the in-memory peerstore (pstoremem), not by the datastore backed
+// one (pstoreds):
+//
+//	if cn, ok := aPeerstore.(ChangeNotifier); ok {
+//	    changes := cn.SubscribeChanges(ctx)
+//	}
+type ChangeNotifier interface {
+	// SubscribeChanges returns a channel on which all changes are published, in the
+	// order they were made. The changes are buffered, the peerstore is never blocked
+	// by a slow subscriber. If a subscriber falls too far behind, the changes that
+	// don't fit in its buffer are dropped, and a ChangesDropped change is sent in
+	// their place. The channel is closed when ctx is canceled.
+	SubscribeChanges(ctx context.Context) <-chan Change
+}
+
 // ProtoBook tracks the protocols supported by peers.
 type ProtoBook interface {
 	GetProtocols(peer.ID) ([]protocol.ID, error)
@@ -0,0 +1,115 @@
+package peerstore
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ErrQueryNotSupported is returned by QueryPeers if the peerstore doesn't support a
+// filter of the query.
+var ErrQueryNotSupported = errors.New("peerstore doesn't support the query")
+
+// Query selects peers from a peerstore, see QueryPeers.
+type Query struct {
+	// Protocols only selects peers supporting at least one of the protocols.
+	Protocols []protocol.ID
+	// AddrPrefix only selects peers with an address starting with the prefix, e.g.
+	// /ip4/10.0.0.1 or /dns4/example.com. Only the matching addresses of the selected
+	// peers are returned.
+	AddrPrefix ma.Multiaddr
+	// ConnectedOnly only selects peers with addresses added with ConnectedAddrTTL,
+	// that is the peers the host is connected to.
+	ConnectedOnly bool
+	// After is the cursor of the page: only peers with an ID greater than After are
+	// selected. Set it to the Next field of the previous page to get the next page.
+	After peer.ID
+	// Limit is the maximum number of peers returned. Zero means no limit.
+	Limit int
+}
+
+// QueryResult is a page of the peers selected by a Query.
+type QueryResult struct {
+	// Peers are the selected peers with their addresses, sorted by peer ID.
+	Peers []peer.AddrInfo
+	// Next is the cursor of the next page. It's empty on the last page.
+	Next peer.ID
+}
+
+// Querier is implemented by peerstores that can select peers by a Query without
+// loading the addresses of all peers into memory.
+type Querier interface {
+	QueryPeers(Query) (QueryResult, error)
+}
+
+// QueryPeers returns the peers of ps selected by q. If ps doesn't implement Querier,
+// the addresses of the peers are loaded one at a time, and ConnectedOnly queries
+// fail with ErrQueryNotSupported.
+func QueryPeers(ps Peerstore, q Query) (QueryResult, error) {
+	if qr, ok := ps.(Querier); ok {
+		return qr.QueryPeers(q)
+	}
+	if q.ConnectedOnly {
+		return QueryResult{}, ErrQueryNotSupported
+	}
+	return RunQuery(q, ps.Peers(), ps, func(p peer.ID) ([]ma.Multiaddr, bool) {
+		return ps.Addrs(p), false
+	})
+}
+
+// RunQuery is a helper for implementing Querier. It runs q against the candidate
+// peers, in any order, using pb to filter by protocol, and addrs to get the valid
+// addresses of a peer and whether it's connected. Addresses are only loaded for the
+// candidates that pass the cheaper filters, until the page is full.
+func RunQuery(q Query, peers []peer.ID, pb ProtoBook, addrs func(peer.ID) (addrs []ma.Multiaddr, connected bool)) (QueryResult, error) {
+	if q.Limit < 0 {
+		return QueryResult{}, errors.New("negative query limit")
+	}
+	if q.After != "" {
+		peers = slices.DeleteFunc(peers, func(p peer.ID) bool { return p <= q.After })
+	}
+	slices.Sort(peers)
+
+	var res QueryResult
+	for _, p := range peers {
+		if q.Limit > 0 && len(res.Peers) == q.Limit {
+			// there are candidates left
+			res.Next = res.Peers[len(res.Peers)-1].ID
+			break
+		}
+		if len(q.Protocols) > 0 {
+			supported, err := pb.SupportsProtocols(p, q.Protocols...)
+			if err != nil || len(supported) == 0 {
+				continue
+			}
+		}
+		as, connected := addrs(p)
+		if q.ConnectedOnly && !connected {
+			continue
+		}
+		if q.AddrPrefix != nil {
+			as = slices.DeleteFunc(as, func(a ma.Multiaddr) bool { return !hasPrefix(a, q.AddrPrefix) })
+			if len(as) == 0 {
+				continue
+			}
+		}
+		res.Peers = append(res.Peers, peer.AddrInfo{ID: p, Addrs: as})
+	}
+	return res, nil
+}
+
+func hasPrefix(a, prefix ma.Multiaddr) bool {
+	if len(a) < len(prefix) {
+		return false
+	}
+	for i, c := range prefix {
+		if !c.Equal(&a[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,526 @@
+package libp2p
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/keystore"
+	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/p2p/security/noise"
+	tls "github.com/TheNoobiCat/go-libp2p/p2p/security/tls"
+	quic "github.com/TheNoobiCat/go-libp2p/p2p/transport/quic"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcp"
+	libp2pwebrtc "github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc"
+	ws "github.com/TheNoobiCat/go-libp2p/p2p/transport/websocket"
+	webtransport "github.com/TheNoobiCat/go-libp2p/p2p/transport/webtransport"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFormat is the encoding of a DeclarativeConfig document.
+type ConfigFormat int
+
+const (
+	ConfigJSON ConfigFormat = iota
+	ConfigYAML
+)
+
+func (f ConfigFormat) String() string {
+	switch f {
+	case ConfigJSON:
+		return "json"
+	case ConfigYAML:
+		return "yaml"
+	default:
+		return fmt.Sprintf("ConfigFormat(%d)", int(f))
+	}
+}
+
+// DeclarativeConfig describes a libp2p node in a JSON or YAML document, so that
+// operators can manage the configuration of a node without recompiling it:
+//
+//	listenAddrs:
+//	  - /ip4/0.0.0.0/tcp/4001
+//	  - /ip4/0.0.0.0/udp/4001/quic-v1
+//	transports: [tcp, quic]
+//	security: [noise, tls]
+//	connManager:
+//	  lowWater: 100
+//	  highWater: 400
+//	  gracePeriod: 30s
+//	relay:
+//	  staticRelays:
+//	    - /ip4/1.2.3.4/tcp/4001/p2p/12D3KooW...
+//
+// Load it with LoadConfigFile or ParseConfig, and pass the result of Options to New.
+// Everything that isn't configured falls back on the defaults of New. Effective
+// returns the configuration with these defaults filled in.
+type DeclarativeConfig struct {
+	Identity IdentityConfig `json:"identity,omitzero"`
+	// ListenAddrs are the multiaddrs to listen on.
+	ListenAddrs []string `json:"listenAddrs,omitempty"`
+	// Transports are the enabled transports: tcp, quic, websocket, webtransport and
+	// webrtc-direct.
+	Transports []string `json:"transports,omitempty"`
+	// Security are the security protocols, in order of preference: noise and tls.
+	Security    []string          `json:"security,omitempty"`
+	ConnManager ConnManagerConfig `json:"connManager,omitzero"`
+	// Limits are the resource manager limits, in the JSON format of the
+	// resource-manager package. They override the default limits, scaled to the
+	// resources of the machine.
+	Limits *rcmgr.PartialLimitConfig `json:"limits,omitempty"`
+	Relay  RelayConfig               `json:"relay,omitzero"`
+	NAT    NATConfig                 `json:"nat,omitzero"`
+	// UserAgent is sent to other peers by identify.
+	UserAgent      string `json:"userAgent,omitempty"`
+	DisableMetrics bool   `json:"disableMetrics,omitempty"`
+}
+
+// IdentityConfig configures the identity of a DeclarativeConfig node.
+type IdentityConfig struct {
+	// KeyFile is the path of the unencrypted private key, see keystore.FileKeystore.
+	// An Ed25519 key is generated and stored if the file doesn't exist. Without a
+	// key file, the node uses a random identity.
+	KeyFile string `json:"keyFile,omitempty"`
+}
+
+// ConnManagerConfig configures the connection manager of a DeclarativeConfig node,
+// see connmgr.NewConnManager.
+type ConnManagerConfig struct {
+	LowWater    int            `json:"lowWater,omitempty"`
+	HighWater   int            `json:"highWater,omitempty"`
+	GracePeriod ConfigDuration `json:"gracePeriod,omitempty"`
+}
+
+// RelayConfig configures the circuit v2 relay of a DeclarativeConfig node.
+type RelayConfig struct {
+	// DisableClient disables dialing and accepting relayed connections.
+	DisableClient bool `json:"disableClient,omitempty"`
+	// Service runs a relay service for other peers.
+	Service bool `json:"service,omitempty"`
+	// StaticRelays are the p2p multiaddrs of the relays that autorelay reserves
+	// slots with when the node isn't publicly reachable.
+	StaticRelays []string `json:"staticRelays,omitempty"`
+	// HolePunching enables hole punching of relayed connections.
+	HolePunching bool `json:"holePunching,omitempty"`
+}
+
+// NATConfig configures the NAT traversal of a DeclarativeConfig node.
+type NATConfig struct {
+	// PortMap opens a port in the NAT with UPnP or NAT-PMP.
+	PortMap bool `json:"portMap,omitempty"`
+	// Service runs an AutoNAT service for other peers.
+	Service bool `json:"service,omitempty"`
+	// Reachability forces the reachability of the node to "public" or "private",
+	// instead of detecting it with AutoNAT.
+	Reachability string `json:"reachability,omitempty"`
+}
+
+// ConfigDuration is a time.Duration encoded as a string, e.g. "1m30s".
+type ConfigDuration time.Duration
+
+func (d ConfigDuration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *ConfigDuration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("invalid duration %s: expected a string, e.g. \"30s\"", b)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = ConfigDuration(v)
+	return nil
+}
+
+// defaultConnMgrGracePeriod is the default grace period of connmgr.NewConnManager.
+const defaultConnMgrGracePeriod = time.Minute
+
+var (
+	configTransports = map[string]Option{
+		"tcp":           Transport(tcp.NewTCPTransport),
+		"quic":          Transport(quic.NewTransport),
+		"websocket":     Transport(ws.New),
+		"webtransport":  Transport(webtransport.New),
+		"webrtc-direct": Transport(libp2pwebrtc.New),
+	}
+	// defaultConfigTransports are the transports of DefaultTransports.
+	defaultConfigTransports = []string{"tcp", "quic", "websocket", "webtransport", "webrtc-direct"}
+
+	configSecurity = map[string]Option{
+		"noise": Security(noise.ID, noise.New),
+		"tls":   Security(tls.ID, tls.New),
+	}
+	// defaultConfigSecurity are the security protocols of DefaultSecurity.
+	defaultConfigSecurity = []string{"tls", "noise"}
+)
+
+// LoadConfigFile loads the DeclarativeConfig document at path. The format is derived
+// from the extension of the file: .json, or .yaml and .yml.
+func LoadConfigFile(path string) (*DeclarativeConfig, error) {
+	var format ConfigFormat
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		format = ConfigJSON
+	case ".yaml", ".yml":
+		format = ConfigYAML
+	default:
+		return nil, fmt.Errorf("unknown config file extension %q: expected .json, .yaml or .yml", ext)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := ParseConfig(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// ParseConfig decodes a DeclarativeConfig document and validates it. Unknown fields
+// are rejected.
+func ParseConfig(data []byte, format ConfigFormat) (*DeclarativeConfig, error) {
+	switch format {
+	case ConfigJSON:
+	case ConfigYAML:
+		// YAML documents are converted to JSON, so that both formats share the JSON
+		// schema, including the custom encodings of the limits.
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, err
+		}
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("unsupported YAML document: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown config format %s", format)
+	}
+
+	c := &DeclarativeConfig{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the config document")
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Marshal encodes c in the given format. The result can be parsed by ParseConfig.
+func (c *DeclarativeConfig) Marshal(format ConfigFormat) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case ConfigJSON:
+		return data, nil
+	case ConfigYAML:
+		// Convert the JSON encoding, to keep the order of the fields.
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		n, err := jsonToYAML(dec)
+		if err != nil {
+			return nil, err
+		}
+		var b bytes.Buffer
+		enc := yaml.NewEncoder(&b)
+		enc.SetIndent(2)
+		if err := enc.Encode(n); err != nil {
+			return nil, err
+		}
+		if err := enc.Close(); err != nil {
+			return nil, err
+		}
+		return b.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown config format %s", format)
+	}
+}
+
+// jsonToYAML converts the next JSON value read from dec to a YAML node.
+func jsonToYAML(dec *json.Decoder) (*yaml.Node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		n := &yaml.Node{Kind: yaml.MappingNode}
+		if t == '[' {
+			n.Kind = yaml.SequenceNode
+		}
+		for dec.More() {
+			if n.Kind == yaml.MappingNode {
+				key, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				n.Content = append(n.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.(string)})
+			}
+			v, err := jsonToYAML(dec)
+			if err != nil {
+				return nil, err
+			}
+			n.Content = append(n.Content, v)
+		}
+		// consume the closing delimiter
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		return n, nil
+	case string:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: t}, nil
+	case json.Number:
+		tag := "!!int"
+		if _, err := t.Int64(); err != nil {
+			tag = "!!float"
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: t.String()}, nil
+	case bool:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!bool", Value: strconv.FormatBool(t)}, nil
+	case nil:
+		return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null", Value: "null"}, nil
+	default:
+		return nil, fmt.Errorf("unexpected JSON token %v", tok)
+	}
+}
+
+// Validate checks that c is a valid configuration. It reports all the invalid
+// fields.
+func (c *DeclarativeConfig) Validate() error {
+	var errs []error
+	fail := func(field string, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", field, fmt.Sprintf(format, args...)))
+	}
+
+	for i, t := range c.Transports {
+		if _, ok := configTransports[t]; !ok {
+			fail(fmt.Sprintf("transports[%d]", i), "unknown transport %q", t)
+		} else if slices.Index(c.Transports, t) != i {
+			fail(fmt.Sprintf("transports[%d]", i), "duplicate transport %q", t)
+		}
+	}
+	transports := c.Transports
+	if len(transports) == 0 {
+		transports = defaultConfigTransports
+	}
+	for i, s := range c.ListenAddrs {
+		field := fmt.Sprintf("listenAddrs[%d]", i)
+		a, err := ma.NewMultiaddr(s)
+		if err != nil {
+			fail(field, "%s", err)
+			continue
+		}
+		if t := configTransportFor(a); t == "" {
+			fail(field, "no transport for %s", a)
+		} else if !slices.Contains(transports, t) {
+			fail(field, "transport %s of %s isn't enabled", t, a)
+		}
+	}
+	for i, s := range c.Security {
+		if _, ok := configSecurity[s]; !ok {
+			fail(fmt.Sprintf("security[%d]", i), "unknown security protocol %q", s)
+		} else if slices.Index(c.Security, s) != i {
+			fail(fmt.Sprintf("security[%d]", i), "duplicate security protocol %q", s)
+		}
+	}
+
+	if cm := c.ConnManager; cm != (ConnManagerConfig{}) {
+		if cm.LowWater < 0 || cm.HighWater <= 0 || cm.LowWater > cm.HighWater {
+			fail("connManager", "invalid watermarks: expected 0 <= lowWater <= highWater and highWater > 0, got %d and %d", cm.LowWater, cm.HighWater)
+		}
+		if cm.GracePeriod < 0 {
+			fail("connManager.gracePeriod", "negative grace period")
+		}
+	}
+
+	for i, s := range c.Relay.StaticRelays {
+		field := fmt.Sprintf("relay.staticRelays[%d]", i)
+		if c.Relay.DisableClient {
+			fail(field, "static relays require the relay client")
+			break
+		}
+		if _, err := peer.AddrInfoFromString(s); err != nil {
+			fail(field, "%s", err)
+		}
+	}
+	if c.Relay.HolePunching && c.Relay.DisableClient {
+		fail("relay.holePunching", "hole punching requires the relay client")
+	}
+
+	switch c.NAT.Reachability {
+	case "", "public", "private":
+	default:
+		fail("nat.reachability", "invalid reachability %q: expected public or private", c.NAT.Reachability)
+	}
+	return errors.Join(errs...)
+}
+
+// configTransportFor returns the name of the transport listening on a, or an empty
+// string if there's none.
+func configTransportFor(a ma.Multiaddr) string {
+	var t string
+	// the last transport protocol of the address wins, e.g. /tcp/4001/ws is a
+	// websocket address
+	ma.ForEach(a, func(c ma.Component) bool {
+		switch c.Protocol().Code {
+		case ma.P_TCP:
+			t = "tcp"
+		case ma.P_WS, ma.P_WSS:
+			t = "websocket"
+		case ma.P_QUIC_V1:
+			t = "quic"
+		case ma.P_WEBTRANSPORT:
+			t = "webtransport"
+		case ma.P_WEBRTC_DIRECT:
+			t = "webrtc-direct"
+		}
+		return true
+	})
+	return t
+}
+
+// Effective returns a copy of c with the defaults that New falls back on filled in,
+// i.e. the configuration that the node runs with. The limits are left unset, their
+// defaults depend on the resources of the machine.
+func (c *DeclarativeConfig) Effective() *DeclarativeConfig {
+	e := *c
+	if len(e.ListenAddrs) == 0 && len(e.Transports) == 0 {
+		e.ListenAddrs = defaultListenAddrs
+	}
+	if len(e.Transports) == 0 {
+		e.Transports = defaultConfigTransports
+	}
+	if len(e.Security) == 0 {
+		e.Security = defaultConfigSecurity
+	}
+	if e.ConnManager == (ConnManagerConfig{}) {
+		e.ConnManager = ConnManagerConfig{LowWater: defaultConnMgrLowWater, HighWater: defaultConnMgrHighWater}
+	}
+	if e.ConnManager.GracePeriod == 0 {
+		e.ConnManager.GracePeriod = ConfigDuration(defaultConnMgrGracePeriod)
+	}
+	e.ListenAddrs = slices.Clone(e.ListenAddrs)
+	e.Transports = slices.Clone(e.Transports)
+	e.Security = slices.Clone(e.Security)
+	e.Relay.StaticRelays = slices.Clone(e.Relay.StaticRelays)
+	return &e
+}
+
+// Options returns the options configuring a node as described by c. The resource and
+// connection managers are only created when the options are applied.
+func (c *DeclarativeConfig) Options() ([]Option, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	var opts []Option
+	if c.Identity.KeyFile != "" {
+		ks, err := keystore.NewFileKeystore(c.Identity.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, IdentityFromKeystore(ks))
+	}
+	if len(c.ListenAddrs) > 0 {
+		opts = append(opts, ListenAddrStrings(c.ListenAddrs...))
+	}
+	for _, t := range c.Transports {
+		opts = append(opts, configTransports[t])
+	}
+	for _, s := range c.Security {
+		opts = append(opts, configSecurity[s])
+	}
+
+	if cm := c.ConnManager; cm != (ConnManagerConfig{}) {
+		opts = append(opts, func(cfg *Config) error {
+			var cmOpts []connmgr.Option
+			if cm.GracePeriod > 0 {
+				cmOpts = append(cmOpts, connmgr.WithGracePeriod(time.Duration(cm.GracePeriod)))
+			}
+			mgr, err := connmgr.NewConnManager(cm.LowWater, cm.HighWater, cmOpts...)
+			if err != nil {
+				return err
+			}
+			return cfg.Apply(ConnectionManager(mgr))
+		})
+	}
+	if c.Limits != nil {
+		partial := *c.Limits
+		opts = append(opts, func(cfg *Config) error {
+			limits := rcmgr.DefaultLimits
+			SetDefaultServiceLimits(&limits)
+			mgr, err := rcmgr.NewResourceManager(rcmgr.NewFixedLimiter(partial.Build(limits.AutoScale())))
+			if err != nil {
+				return err
+			}
+			return cfg.Apply(ResourceManager(mgr))
+		})
+	}
+
+	if c.Relay.DisableClient {
+		opts = append(opts, DisableRelay())
+	}
+	if c.Relay.Service {
+		opts = append(opts, EnableRelayService())
+	}
+	if len(c.Relay.StaticRelays) > 0 {
+		relays := make([]peer.AddrInfo, 0, len(c.Relay.StaticRelays))
+		for _, s := range c.Relay.StaticRelays {
+			ai, err := peer.AddrInfoFromString(s)
+			if err != nil {
+				return nil, err
+			}
+			relays = append(relays, *ai)
+		}
+		opts = append(opts, EnableAutoRelayWithStaticRelays(relays))
+	}
+	if c.Relay.HolePunching {
+		opts = append(opts, EnableHolePunching())
+	}
+
+	if c.NAT.PortMap {
+		opts = append(opts, NATPortMap())
+	}
+	if c.NAT.Service {
+		opts = append(opts, EnableNATService())
+	}
+	switch c.NAT.Reachability {
+	case "public":
+		opts = append(opts, ForceReachabilityPublic())
+	case "private":
+		opts = append(opts, ForceReachabilityPrivate())
+	}
+
+	if c.UserAgent != "" {
+		opts = append(opts, UserAgent(c.UserAgent))
+	}
+	if c.DisableMetrics {
+		opts = append(opts, DisableMetrics())
+	}
+	return opts, nil
+}
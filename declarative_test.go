@@ -0,0 +1,175 @@
+package libp2p
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/keystore"
+	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigYAML = `
+listenAddrs:
+  - /ip4/127.0.0.1/tcp/0
+  - /ip4/127.0.0.1/udp/0/quic-v1
+transports: [tcp, quic]
+security: [noise, tls]
+connManager:
+  lowWater: 10
+  highWater: 20
+  gracePeriod: 30s
+limits:
+  System:
+    Conns: 128
+    Memory: unlimited
+relay:
+  staticRelays:
+    - /ip4/1.2.3.4/tcp/4001/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC
+userAgent: test/1.0
+disableMetrics: true
+`
+
+const testConfigJSON = `{
+  "listenAddrs": ["/ip4/127.0.0.1/tcp/0", "/ip4/127.0.0.1/udp/0/quic-v1"],
+  "transports": ["tcp", "quic"],
+  "security": ["noise", "tls"],
+  "connManager": {"lowWater": 10, "highWater": 20, "gracePeriod": "30s"},
+  "limits": {"System": {"Conns": 128, "Memory": "unlimited"}},
+  "relay": {"staticRelays": ["/ip4/1.2.3.4/tcp/4001/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC"]},
+  "userAgent": "test/1.0",
+  "disableMetrics": true
+}`
+
+func TestParseConfig(t *testing.T) {
+	fromYAML, err := ParseConfig([]byte(testConfigYAML), ConfigYAML)
+	require.NoError(t, err)
+	fromJSON, err := ParseConfig([]byte(testConfigJSON), ConfigJSON)
+	require.NoError(t, err)
+	require.Equal(t, fromJSON, fromYAML)
+
+	require.Equal(t, []string{"tcp", "quic"}, fromJSON.Transports)
+	require.Equal(t, ConnManagerConfig{LowWater: 10, HighWater: 20, GracePeriod: ConfigDuration(30 * time.Second)}, fromJSON.ConnManager)
+	require.Equal(t, rcmgr.LimitVal(128), fromJSON.Limits.System.Conns)
+	require.Equal(t, rcmgr.Unlimited64, fromJSON.Limits.System.Memory)
+
+	empty, err := ParseConfig(nil, ConfigYAML)
+	require.NoError(t, err)
+	require.Equal(t, &DeclarativeConfig{}, empty)
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name, doc, err string
+	}{
+		{"unknown field", `{"listenAdrs": []}`, `unknown field "listenAdrs"`},
+		{"unknown transport", `{"transports": ["tcp", "carrier-pigeon"]}`, `transports[1]: unknown transport "carrier-pigeon"`},
+		{"duplicate transport", `{"transports": ["tcp", "tcp"]}`, `transports[1]: duplicate transport "tcp"`},
+		{"invalid listen addr", `{"listenAddrs": ["/ip4/1.2.3.4/tcp"]}`, `listenAddrs[0]`},
+		{"listen addr without transport", `{"listenAddrs": ["/ip4/1.2.3.4/udp/1/quic-v1"], "transports": ["tcp"]}`, `listenAddrs[0]: transport quic of /ip4/1.2.3.4/udp/1/quic-v1 isn't enabled`},
+		{"unknown security", `{"security": ["ssl"]}`, `security[0]: unknown security protocol "ssl"`},
+		{"watermarks", `{"connManager": {"lowWater": 20, "highWater": 10}}`, `connManager: invalid watermarks`},
+		{"duration", `{"connManager": {"highWater": 10, "gracePeriod": 30}}`, `invalid duration 30`},
+		{"static relay", `{"relay": {"staticRelays": ["/ip4/1.2.3.4/tcp/1"]}}`, `relay.staticRelays[0]`},
+		{"static relay without client", `{"relay": {"disableClient": true, "staticRelays": ["/ip4/1.2.3.4/tcp/4001/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC"]}}`, `static relays require the relay client`},
+		{"reachability", `{"nat": {"reachability": "sometimes"}}`, `nat.reachability: invalid reachability "sometimes"`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseConfig([]byte(tc.doc), ConfigJSON)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	// all the invalid fields are reported
+	_, err := ParseConfig([]byte(`{"transports": ["foo"], "security": ["bar"]}`), ConfigJSON)
+	require.ErrorContains(t, err, "transports[0]")
+	require.ErrorContains(t, err, "security[0]")
+}
+
+func TestConfigRoundTrip(t *testing.T) {
+	c, err := ParseConfig([]byte(testConfigYAML), ConfigYAML)
+	require.NoError(t, err)
+	for _, format := range []ConfigFormat{ConfigJSON, ConfigYAML} {
+		t.Run(format.String(), func(t *testing.T) {
+			for _, c := range []*DeclarativeConfig{c, c.Effective(), {}} {
+				data, err := c.Marshal(format)
+				require.NoError(t, err)
+				c2, err := ParseConfig(data, format)
+				require.NoError(t, err, string(data))
+				require.Equal(t, c, c2)
+			}
+		})
+	}
+}
+
+func TestConfigEffective(t *testing.T) {
+	e := (&DeclarativeConfig{}).Effective()
+	require.Equal(t, defaultListenAddrs, e.ListenAddrs)
+	require.Equal(t, []string{"tcp", "quic", "websocket", "webtransport", "webrtc-direct"}, e.Transports)
+	require.Equal(t, []string{"tls", "noise"}, e.Security)
+	require.Equal(t, ConnManagerConfig{LowWater: 160, HighWater: 192, GracePeriod: ConfigDuration(time.Minute)}, e.ConnManager)
+	require.NoError(t, e.Validate())
+
+	// Without listen addresses, a node with configured transports doesn't listen.
+	e = (&DeclarativeConfig{Transports: []string{"tcp"}, ConnManager: ConnManagerConfig{HighWater: 10}}).Effective()
+	require.Empty(t, e.ListenAddrs)
+	require.Equal(t, []string{"tcp"}, e.Transports)
+	require.Equal(t, ConnManagerConfig{HighWater: 10, GracePeriod: ConfigDuration(time.Minute)}, e.ConnManager)
+}
+
+func TestNewFromConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+identity:
+  keyFile: `+keyFile+`
+listenAddrs: [/ip4/127.0.0.1/tcp/0]
+transports: [tcp]
+connManager:
+  lowWater: 1
+  highWater: 2
+limits:
+  System:
+    ConnsInbound: 3
+disableMetrics: true
+`), 0o600))
+
+	c, err := LoadConfigFile(path)
+	require.NoError(t, err)
+	opts, err := c.Options()
+	require.NoError(t, err)
+	h, err := New(opts...)
+	require.NoError(t, err)
+	defer h.Close()
+
+	// the identity was stored in the key file
+	sk, err := keystore.NewFileKeystore(keyFile)
+	require.NoError(t, err)
+	priv, err := sk.Load()
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+	require.Equal(t, id, h.ID())
+
+	require.True(t, slices.ContainsFunc(h.Network().ListenAddresses(), func(a ma.Multiaddr) bool {
+		_, err := a.ValueForProtocol(ma.P_TCP)
+		return err == nil
+	}))
+	var limit rcmgr.ResourceLimits
+	h.Network().ResourceManager().ViewSystem(func(s network.ResourceScope) error {
+		limit = s.(interface{ Limit() rcmgr.Limit }).Limit().(*rcmgr.BaseLimit).ToResourceLimits()
+		return nil
+	})
+	require.Equal(t, rcmgr.LimitVal(3), limit.ConnsInbound)
+
+	_, err = LoadConfigFile(filepath.Join(dir, "config.toml"))
+	require.ErrorContains(t, err, "unknown config file extension")
+}
@@ -61,7 +61,11 @@ var DefaultPrivateTransports = ChainOptions(
 
 // DefaultPeerstore configures libp2p to use the default peerstore.
 var DefaultPeerstore Option = func(cfg *Config) error {
-	ps, err := pstoremem.NewPeerstore()
+	var opts []pstoremem.Option
+	if cfg.Clock != nil {
+		opts = append(opts, pstoremem.WithClock(cfg.Clock))
+	}
+	ps, err := pstoremem.NewPeerstore(opts...)
 	if err != nil {
 		return err
 	}
@@ -77,20 +81,22 @@ var RandomIdentity = func(cfg *Config) error {
 	return cfg.Apply(Identity(priv))
 }
 
+// defaultListenAddrs are the listen addresses of DefaultListenAddrs.
+var defaultListenAddrs = []string{
+	"/ip4/0.0.0.0/tcp/0",
+	"/ip4/0.0.0.0/udp/0/quic-v1",
+	"/ip4/0.0.0.0/udp/0/quic-v1/webtransport",
+	"/ip4/0.0.0.0/udp/0/webrtc-direct",
+	"/ip6/::/tcp/0",
+	"/ip6/::/udp/0/quic-v1",
+	"/ip6/::/udp/0/quic-v1/webtransport",
+	"/ip6/::/udp/0/webrtc-direct",
+}
+
 // DefaultListenAddrs configures libp2p to use default listen address.
 var DefaultListenAddrs = func(cfg *Config) error {
-	addrs := []string{
-		"/ip4/0.0.0.0/tcp/0",
-		"/ip4/0.0.0.0/udp/0/quic-v1",
-		"/ip4/0.0.0.0/udp/0/quic-v1/webtransport",
-		"/ip4/0.0.0.0/udp/0/webrtc-direct",
-		"/ip6/::/tcp/0",
-		"/ip6/::/udp/0/quic-v1",
-		"/ip6/::/udp/0/quic-v1/webtransport",
-		"/ip6/::/udp/0/webrtc-direct",
-	}
-	listenAddrs := make([]multiaddr.Multiaddr, 0, len(addrs))
-	for _, s := range addrs {
+	listenAddrs := make([]multiaddr.Multiaddr, 0, len(defaultListenAddrs))
+	for _, s := range defaultListenAddrs {
 		addr, err := multiaddr.NewMultiaddr(s)
 		if err != nil {
 			return err
@@ -117,9 +123,19 @@ var DefaultResourceManager = func(cfg *Config) error {
 	return cfg.Apply(ResourceManager(mgr))
 }
 
+// The watermarks of the DefaultConnectionManager.
+const (
+	defaultConnMgrLowWater  = 160
+	defaultConnMgrHighWater = 192
+)
+
 // DefaultConnectionManager creates a default connection manager
 var DefaultConnectionManager = func(cfg *Config) error {
-	mgr, err := connmgr.NewConnManager(160, 192)
+	var opts []connmgr.Option
+	if cfg.Clock != nil {
+		opts = append(opts, connmgr.WithClock(cfg.Clock))
+	}
+	mgr, err := connmgr.NewConnManager(defaultConnMgrLowWater, defaultConnMgrHighWater, opts...)
 	if err != nil {
 		return err
 	}
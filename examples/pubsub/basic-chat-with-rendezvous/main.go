@@ -6,13 +6,13 @@ import (
 	"flag"
 	"fmt"
 	"os"
-	"sync"
 
 	"github.com/TheNoobiCat/go-libp2p"
 	dht "github.com/TheNoobiCat/go-libp2p-kad-dht"
 	pubsub "github.com/TheNoobiCat/go-libp2p-pubsub"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/host"
-	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/bootstrap"
 	drouting "github.com/TheNoobiCat/go-libp2p/p2p/discovery/routing"
 	dutil "github.com/TheNoobiCat/go-libp2p/p2p/discovery/util"
 )
@@ -60,22 +60,42 @@ func initDHT(ctx context.Context, h host.Host) *dht.IpfsDHT {
 	if err = kademliaDHT.Bootstrap(ctx); err != nil {
 		panic(err)
 	}
-	var wg sync.WaitGroup
-	for _, peerAddr := range dht.DefaultBootstrapPeers {
-		peerinfo, _ := peer.AddrInfoFromP2pAddr(peerAddr)
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			if err := h.Connect(ctx, *peerinfo); err != nil {
-				fmt.Println("Bootstrap warning:", err)
-			}
-		}()
+	if err := bootstrapConnect(ctx, h); err != nil {
+		fmt.Println("Bootstrap warning:", err)
 	}
-	wg.Wait()
 
 	return kademliaDHT
 }
 
+// bootstrapConnect keeps the host connected to the default bootstrap peers, and waits
+// until it's connected to at least one of them.
+func bootstrapConnect(ctx context.Context, h host.Host) error {
+	sub, err := h.EventBus().Subscribe(new(event.EvtBootstrapStatusChanged))
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
+
+	b, err := bootstrap.New(h, bootstrap.WithAddrs(dht.DefaultBootstrapPeers...))
+	if err != nil {
+		return err
+	}
+	b.Start()
+
+	for {
+		select {
+		case e := <-sub.Out():
+			if status := e.(event.EvtBootstrapStatusChanged); status.Connected > 0 {
+				fmt.Printf("Bootstrapped with %d of %d peers\n", status.Connected, status.Known)
+				return nil
+			}
+		case <-ctx.Done():
+			b.Close()
+			return ctx.Err()
+		}
+	}
+}
+
 func discoverPeers(ctx context.Context, h host.Host) {
 	kademliaDHT := initDHT(ctx, h)
 	routingDiscovery := drouting.NewRoutingDiscovery(kademliaDHT)
@@ -5,7 +5,6 @@ import (
 	"log"
 
 	"github.com/TheNoobiCat/go-libp2p"
-	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/client"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/relay"
 
@@ -121,11 +120,8 @@ func run() {
 		return
 	}
 
-	// Since we just tried and failed to dial, the dialer system will, by default
-	// prevent us from redialing again so quickly. Since we know what we're doing, we
-	// can use this ugly hack (it's on our TODO list to make it a little cleaner)
-	// to tell the dialer "no, its okay, let's try this again"
-	unreachable1.Network().(*swarm.Swarm).Backoff().Clear(unreachable2.ID())
+	// The dial above failed because unreachable2 has no addresses, so no address was
+	// put on dial backoff: the relay address can be dialed right away.
 
 	log.Println("Now let's attempt to connect the hosts via the relay node")
 
@@ -8,11 +8,11 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"sync"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
-	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/bootstrap"
 
 	ma "github.com/multiformats/go-multiaddr"
 )
@@ -79,52 +79,36 @@ func convertPeers(peers []string) []peer.AddrInfo {
 	return pinfos
 }
 
-// This code is borrowed from the go-ipfs bootstrap process
+// bootstrapConnect keeps the host connected to the bootstrap peers, and waits until
+// it's connected to at least one of them.
 func bootstrapConnect(ctx context.Context, ph host.Host, peers []peer.AddrInfo) error {
 	if len(peers) < 1 {
 		return errors.New("not enough bootstrap peers")
 	}
 
-	errs := make(chan error, len(peers))
-	var wg sync.WaitGroup
-	for _, p := range peers {
-
-		// performed asynchronously because when performed synchronously, if
-		// one `Connect` call hangs, subsequent calls are more likely to
-		// fail/abort due to an expiring context.
-		// Also, performed asynchronously for dial speed.
-
-		wg.Add(1)
-		go func(p peer.AddrInfo) {
-			defer wg.Done()
-			defer log.Println(ctx, "bootstrapDial", ph.ID(), p.ID)
-			log.Printf("%s bootstrapping to %s", ph.ID(), p.ID)
+	sub, err := ph.EventBus().Subscribe(new(event.EvtBootstrapStatusChanged))
+	if err != nil {
+		return err
+	}
+	defer sub.Close()
 
-			ph.Peerstore().AddAddrs(p.ID, p.Addrs, peerstore.PermanentAddrTTL)
-			if err := ph.Connect(ctx, p); err != nil {
-				log.Println(ctx, "bootstrapDialFailed", p.ID)
-				log.Printf("failed to bootstrap with %v: %s", p.ID, err)
-				errs <- err
-				return
-			}
-			log.Println(ctx, "bootstrapDialSuccess", p.ID)
-			log.Printf("bootstrapped with %v", p.ID)
-		}(p)
+	b, err := bootstrap.New(ph, bootstrap.WithPeers(peers...), bootstrap.WithMinPeers(len(peers)))
+	if err != nil {
+		return err
 	}
-	wg.Wait()
+	b.Start()
 
-	// our failure condition is when no connection attempt succeeded.
-	// So drain the errs channel, counting the results.
-	close(errs)
-	count := 0
-	var err error
-	for err = range errs {
-		if err != nil {
-			count++
+	for {
+		select {
+		case e := <-sub.Out():
+			status := e.(event.EvtBootstrapStatusChanged)
+			if status.Connected > 0 {
+				log.Printf("bootstrapped with %d of %d peers", status.Connected, status.Known)
+				return nil
+			}
+		case <-ctx.Done():
+			b.Close()
+			return fmt.Errorf("failed to bootstrap: %w", ctx.Err())
 		}
 	}
-	if count == len(peers) {
-		return fmt.Errorf("failed to bootstrap. %s", err)
-	}
-	return nil
 }
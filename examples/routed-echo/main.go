@@ -70,7 +70,7 @@ func makeRoutedHost(listenPort int, randseed int64, bootstrapPeers []peer.AddrIn
 	// Make the routed host
 	routedHost := rhost.Wrap(basicHost, dht)
 
-	// connect to the chosen ipfs nodes
+	// connect to the chosen ipfs nodes, and stay connected to them
 	err = bootstrapConnect(ctx, routedHost, bootstrapPeers)
 	if err != nil {
 		return nil, err
@@ -8,12 +8,15 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"crypto/x509/pkix"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"math/big"
 	"net"
+	"net/http"
 	"net/netip"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -22,15 +25,25 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	"github.com/TheNoobiCat/go-libp2p/core/pnet"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/core/record"
 	"github.com/TheNoobiCat/go-libp2p/core/routing"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/introspect"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/keystore"
 	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+	"github.com/TheNoobiCat/go-libp2p/p2p/muxer/yamux"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/tofu"
+	tptu "github.com/TheNoobiCat/go-libp2p/p2p/net/upgrader"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/ping"
 	"github.com/TheNoobiCat/go-libp2p/p2p/security/noise"
 	sectls "github.com/TheNoobiCat/go-libp2p/p2p/security/tls"
@@ -42,7 +55,11 @@ import (
 	webtransport "github.com/TheNoobiCat/go-libp2p/p2p/transport/webtransport"
 	"go.uber.org/goleak"
 
+	"github.com/benbjohnson/clock"
+
 	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 )
 
@@ -825,3 +842,328 @@ func BenchmarkAllAddrs(b *testing.B) {
 		addrsHost.AllAddrs()
 	}
 }
+
+func TestListenAddrsOnInterface(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	require.NoError(t, err)
+	var loopback string
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 && iface.Flags&net.FlagUp != 0 {
+			loopback = iface.Name
+			break
+		}
+	}
+	if loopback == "" {
+		t.Skip("no loopback interface")
+	}
+
+	h, err := New(
+		ListenAddrsOnInterface(loopback, "/ip4/0.0.0.0/tcp/0"),
+		Transport(tcp.NewTCPTransport),
+	)
+	require.NoError(t, err)
+	defer h.Close()
+	var tcpAddrs int
+	for _, a := range h.Network().ListenAddresses() {
+		if _, err := a.ValueForProtocol(ma.P_TCP); err != nil {
+			continue
+		}
+		require.True(t, manet.IsIPLoopback(a), "%s", a)
+		tcpAddrs++
+	}
+	require.NotZero(t, tcpAddrs)
+
+	_, err = New(ListenAddrsOnInterface(loopback, "/ip4/127.0.0.1/tcp/0"))
+	require.Error(t, err)
+	_, err = New(ListenAddrsOnInterface("does-not-exist", "/ip4/0.0.0.0/tcp/0"))
+	require.Error(t, err)
+}
+
+func TestAddrFilters(t *testing.T) {
+	f := ma.NewFilters()
+	h1, err := New(AddrFilters(f), ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := New(ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h2.Close()
+	require.NotEmpty(t, h1.Addrs())
+
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	f.AddFilter(*loopback, ma.ActionDeny)
+	require.Empty(t, h1.Addrs())
+
+	err = h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	require.ErrorIs(t, err, swarm.ErrAddrFiltered)
+	err = h2.Connect(context.Background(), peer.AddrInfo{ID: h1.ID(), Addrs: h1.Network().ListenAddresses()})
+	require.Error(t, err)
+
+	f.RemoveLiteral(*loopback)
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+}
+
+func TestIntrospectionListenAddr(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := l.Addr().String()
+	l.Close()
+
+	h, err := New(NoListenAddrs, IntrospectionListenAddr(addr))
+	require.NoError(t, err)
+
+	resp, err := http.Get("http://" + addr)
+	require.NoError(t, err)
+	var state introspect.State
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	resp.Body.Close()
+	require.Equal(t, h.ID(), state.PeerID)
+
+	require.NoError(t, h.Close())
+	_, err = http.Get("http://" + addr)
+	require.Error(t, err)
+}
+
+func TestYamuxOption(t *testing.T) {
+	h, err := New(Yamux(yamux.WithMaxStreamWindow(32<<20)), NoListenAddrs)
+	require.NoError(t, err)
+	h.Close()
+
+	_, err = New(Yamux(yamux.WithMaxMessageSize(1)), NoListenAddrs)
+	require.Error(t, err)
+}
+
+func TestSecurityPreferenceOption(t *testing.T) {
+	h1, err := New(
+		ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		Transport(tcp.NewTCPTransport),
+		SecurityPreference(tptu.SecurityRules(
+			tptu.SecurityRule{Networks: []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}, Protocols: []protocol.ID{sectls.ID}},
+		)),
+	)
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := New(NoListenAddrs, Transport(tcp.NewTCPTransport), Security(noise.ID, noise.New), Security(sectls.ID, sectls.New))
+	require.NoError(t, err)
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{ID: h1.ID(), Addrs: h1.Addrs()}))
+	conns := h2.Network().ConnsToPeer(h1.ID())
+	require.NotEmpty(t, conns)
+	require.Equal(t, protocol.ID(sectls.ID), conns[0].ConnState().Security)
+}
+
+func TestNegotiatorOption(t *testing.T) {
+	static := Negotiator(tptu.StaticNegotiator{Security: noise.ID, Muxer: yamux.ID}, ma.P_TCP)
+	h1, err := New(ListenAddrStrings("/ip4/127.0.0.1/tcp/0"), Transport(tcp.NewTCPTransport), static)
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := New(NoListenAddrs, Transport(tcp.NewTCPTransport), static)
+	require.NoError(t, err)
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{ID: h1.ID(), Addrs: h1.Addrs()}))
+	conns := h2.Network().ConnsToPeer(h1.ID())
+	require.NotEmpty(t, conns)
+	require.Equal(t, protocol.ID(noise.ID), conns[0].ConnState().Security)
+	require.Equal(t, protocol.ID(yamux.ID), conns[0].ConnState().StreamMultiplexer)
+
+	_, err = New(NoListenAddrs, Negotiator(nil))
+	require.ErrorContains(t, err, "nil negotiator")
+}
+
+func TestIdentityFromKeystore(t *testing.T) {
+	ks, err := keystore.NewFileKeystore(filepath.Join(t.TempDir(), "identity.key"))
+	require.NoError(t, err)
+	h1, err := New(NoListenAddrs, IdentityFromKeystore(ks))
+	require.NoError(t, err)
+	h1.Close()
+	h2, err := New(NoListenAddrs, IdentityFromKeystore(ks))
+	require.NoError(t, err)
+	defer h2.Close()
+	require.Equal(t, h1.ID(), h2.ID())
+
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	_, err = New(NoListenAddrs, Identity(priv), IdentityFromKeystore(ks))
+	require.ErrorContains(t, err, "cannot specify multiple identities")
+}
+
+func TestMetricsPerSubsystem(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	swarmReg := prometheus.NewRegistry()
+	h, err := New(
+		ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+		PrometheusRegisterer(reg),
+		MetricsRegistererFor(metricshelper.SubsystemSwarm, swarmReg),
+		DisableMetricsFor(metricshelper.SubsystemEventBus),
+		MetricsConstLabels(prometheus.Labels{"env": "test"}),
+	)
+	require.NoError(t, err)
+	defer h.Close()
+
+	hasMetric := func(reg *prometheus.Registry, prefix string) bool {
+		mfs, err := reg.Gather()
+		require.NoError(t, err)
+		for _, mf := range mfs {
+			if strings.HasPrefix(mf.GetName(), prefix) {
+				for _, l := range mf.GetMetric()[0].GetLabel() {
+					if l.GetName() == "env" && l.GetValue() == "test" {
+						return true
+					}
+				}
+			}
+		}
+		return false
+	}
+	h2, err := New(NoListenAddrs, DisableMetrics())
+	require.NoError(t, err)
+	defer h2.Close()
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}))
+
+	require.Eventually(t, func() bool { return hasMetric(swarmReg, "libp2p_swarm_") }, 5*time.Second, 10*time.Millisecond)
+	require.False(t, hasMetric(reg, "libp2p_swarm_"))
+	require.False(t, hasMetric(reg, "libp2p_eventbus_"))
+	require.True(t, hasMetric(reg, "libp2p_identify_"))
+
+	_, err = New(DisableMetricsFor("foo"))
+	require.ErrorContains(t, err, "unknown metrics subsystem")
+
+	// DisableMetrics doesn't disable the event bus metrics
+	h3, err := New(NoListenAddrs, DisableMetrics())
+	require.NoError(t, err)
+	defer h3.Close()
+	require.Eventually(t, func() bool {
+		mfs, err := prometheus.DefaultGatherer.Gather()
+		require.NoError(t, err)
+		for _, mf := range mfs {
+			if strings.HasPrefix(mf.GetName(), "libp2p_eventbus_") {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSignerIdentity(t *testing.T) {
+	std, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	signer, err := crypto.StdSigner(std)
+	require.NoError(t, err)
+	sk, err := crypto.NewSignerPrivKey(signer)
+	require.NoError(t, err)
+
+	h1, err := New(
+		Identity(sk),
+		ListenAddrStrings("/ip4/127.0.0.1/tcp/0", "/ip4/127.0.0.1/udp/0/quic-v1", "/ip4/127.0.0.1/udp/0/quic-v1/webtransport"),
+	)
+	require.NoError(t, err)
+	defer h1.Close()
+	expectedID, err := peer.IDFromPrivateKey(sk)
+	require.NoError(t, err)
+	require.Equal(t, expectedID, h1.ID())
+
+	for _, tc := range []struct {
+		name string
+		opts []Option
+		code int
+	}{
+		{"noise", []Option{Security(noise.ID, noise.New)}, ma.P_TCP},
+		{"tls", []Option{Security(sectls.ID, sectls.New)}, ma.P_TCP},
+		{"quic", []Option{Transport(quic.NewTransport)}, ma.P_QUIC_V1},
+		{"webtransport", []Option{Transport(webtransport.New)}, ma.P_WEBTRANSPORT},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			h2, err := New(append(tc.opts, NoListenAddrs)...)
+			require.NoError(t, err)
+			defer h2.Close()
+
+			var addrs []ma.Multiaddr
+			for _, a := range h1.Addrs() {
+				if _, err := a.ValueForProtocol(tc.code); err == nil {
+					addrs = append(addrs, a)
+				}
+			}
+			require.NotEmpty(t, addrs)
+			require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{ID: h1.ID(), Addrs: addrs}))
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			res := <-ping.Ping(ctx, h2, h1.ID())
+			require.NoError(t, res.Error)
+		})
+	}
+
+	env, err := record.Seal(peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: h1.ID(), Addrs: h1.Addrs()}), sk)
+	require.NoError(t, err)
+	b, err := env.Marshal()
+	require.NoError(t, err)
+	_, rec, err := record.ConsumeEnvelope(b, peer.PeerRecordEnvelopeDomain)
+	require.NoError(t, err)
+	require.Equal(t, h1.ID(), rec.(*peer.PeerRecord).PeerID)
+}
+
+func TestPeerPinning(t *testing.T) {
+	store, err := tofu.NewStore(tofu.WithPrivateAddrs())
+	require.NoError(t, err)
+	h1, err := New(NoListenAddrs, PeerPinning(store))
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := New(ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h2.Close()
+
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	p, ok := store.PeerForAddr(h2.Addrs()[0])
+	require.True(t, ok)
+	require.Equal(t, h2.ID(), p)
+
+	_, err = New(NoListenAddrs, PeerPinning(store), PeerPinning(store))
+	require.ErrorContains(t, err, "cannot specify multiple peer pinning stores")
+}
+
+func TestQUICResumptionEvent(t *testing.T) {
+	h1, err := New(NoListenAddrs, Transport(quic.NewTransportWithOptions, quic.EnableSessionResumption(10)))
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := New(ListenAddrStrings("/ip4/127.0.0.1/udp/0/quic-v1"), Transport(quic.NewTransportWithOptions, quic.EnableSessionResumption(10)))
+	require.NoError(t, err)
+	defer h2.Close()
+	sub, err := h1.EventBus().Subscribe(new(event.EvtConnectionResumption))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	select {
+	case e := <-sub.Out():
+		evt := e.(event.EvtConnectionResumption)
+		require.Equal(t, h2.ID(), evt.Peer)
+		require.Equal(t, network.DirOutbound, evt.Direction)
+		require.False(t, evt.Resumed)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for the resumption event")
+	}
+}
+
+func TestClockOption(t *testing.T) {
+	cl := clock.NewMock()
+	cl.Set(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	h1, err := New(NoListenAddrs, WithClock(cl))
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := New(ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h2.Close()
+
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	ids := h1.(interface{ IDService() identify.IDService }).IDService()
+	c := h1.Network().ConnsToPeer(h2.ID())[0]
+	ids.IdentifyConn(c)
+	obs := ids.(identify.ConnObservedAddrsReporter).ConnObservedAddrs(c)
+	require.Len(t, obs, 1)
+	require.Equal(t, cl.Now(), obs[0].FirstSeen)
+	// the default connection manager uses the clock too
+	require.Equal(t, cl.Now(), h1.ConnManager().GetTagInfo(h2.ID()).FirstSeen)
+
+	_, err = New(NoListenAddrs, WithClock(cl), WithClock(cl))
+	require.ErrorContains(t, err, "clock already configured")
+}
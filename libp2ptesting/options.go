@@ -0,0 +1,84 @@
+// Package libp2ptesting provides libp2p options that make it easy to construct hosts
+// for unit tests with libp2p.New.
+//
+// The options inject in-memory transports, no-op security and fast muxers through the
+// regular libp2p configuration, so tests exercise the same code paths as production hosts
+// without touching the network:
+//
+//	h, err := libp2p.New(libp2ptesting.InMemory())
+//
+// Fake or mock implementations can be injected with Transport, Security and Muxer.
+package libp2ptesting
+
+import (
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/core/sec"
+	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/muxer/yamux"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/memory"
+)
+
+// InstantMuxerID is the protocol ID used by the muxer configured with InstantMuxer.
+const InstantMuxerID = yamux.ID
+
+// InMemory configures a hermetic host: it only uses the in-memory transport, listens
+// on a fresh /memory address, doesn't encrypt connections, uses the instant muxer and
+// disables metrics and relaying.
+//
+// Options passed after InMemory can override any of these choices.
+func InMemory() libp2p.Option {
+	return libp2p.ChainOptions(
+		MemoryTransport(),
+		libp2p.ListenAddrStrings("/memory/0"),
+		NoSecurity(),
+		InstantMuxer(),
+		libp2p.DisableRelay(),
+		libp2p.DisableMetrics(),
+		libp2p.ResourceManager(&network.NullResourceManager{}),
+	)
+}
+
+// MemoryTransport configures libp2p to use the in-memory transport. Hosts using it can
+// only connect to other hosts in the same process.
+func MemoryTransport() libp2p.Option {
+	return libp2p.Transport(memory.NewTransport)
+}
+
+// NoSecurity configures libp2p to not encrypt connections. Peers still exchange their
+// public keys, so peer IDs are authenticated against what the remote claims.
+func NoSecurity() libp2p.Option {
+	return libp2p.NoSecurity
+}
+
+// InstantMuxer configures libp2p to use yamux tuned for in-process connections: writes
+// are not coalesced, streams start with the maximum window and keep-alives are disabled.
+func InstantMuxer() libp2p.Option {
+	cfg := *yamux.DefaultTransport
+	cfg.EnableKeepAlive = false
+	cfg.MeasureRTTInterval = time.Hour
+	cfg.WriteCoalesceDelay = 0
+	cfg.InitialStreamWindowSize = cfg.MaxStreamWindowSize
+	return libp2p.Muxer(InstantMuxerID, &cfg)
+}
+
+// Transport configures libp2p to use an already constructed transport, e.g. a fake or
+// a mock. Unlike libp2p.Transport, it doesn't require a constructor.
+func Transport(t transport.Transport) libp2p.Option {
+	return libp2p.Transport(func() transport.Transport { return t })
+}
+
+// Security configures libp2p to use an already constructed security transport, e.g. a
+// fake or a mock. The transport is registered under its own protocol ID.
+func Security(st sec.SecureTransport) libp2p.Option {
+	return libp2p.Security(string(st.ID()), func(protocol.ID) sec.SecureTransport { return st })
+}
+
+// Muxer configures libp2p to use an already constructed stream multiplexer under the
+// given protocol ID. It is equivalent to libp2p.Muxer and provided for completeness.
+func Muxer(id protocol.ID, m network.Multiplexer) libp2p.Option {
+	return libp2p.Muxer(string(id), m)
+}
@@ -0,0 +1,84 @@
+package libp2ptesting
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/transport"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInMemoryHosts(t *testing.T) {
+	h1, err := libp2p.New(InMemory())
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := libp2p.New(InMemory())
+	require.NoError(t, err)
+	defer h2.Close()
+
+	require.Len(t, h2.Addrs(), 1)
+	_, err = h2.Addrs()[0].ValueForProtocol(ma.P_MEMORY)
+	require.NoError(t, err)
+
+	h2.SetStreamHandler("/echo", func(s network.Stream) {
+		defer s.Close()
+		io.Copy(s, s)
+	})
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+
+	s, err := h1.NewStream(context.Background(), h2.ID(), "/echo")
+	require.NoError(t, err)
+	_, err = s.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, s.CloseWrite())
+	b, err := io.ReadAll(s)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+}
+
+var errFakeDial = errors.New("fake dial")
+
+type fakeTransport struct {
+	dials atomic.Int32
+}
+
+var _ transport.Transport = &fakeTransport{}
+
+func (f *fakeTransport) Dial(context.Context, ma.Multiaddr, peer.ID) (transport.CapableConn, error) {
+	f.dials.Add(1)
+	return nil, errFakeDial
+}
+
+func (f *fakeTransport) CanDial(addr ma.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(ma.P_MEMORY)
+	return err == nil
+}
+
+func (f *fakeTransport) Listen(ma.Multiaddr) (transport.Listener, error) {
+	return nil, errors.New("cannot listen")
+}
+
+func (f *fakeTransport) Protocols() []int { return []int{ma.P_MEMORY} }
+func (f *fakeTransport) Proxy() bool      { return false }
+
+func TestInjectTransport(t *testing.T) {
+	fake := &fakeTransport{}
+	h1, err := libp2p.New(Transport(fake), libp2p.NoListenAddrs, NoSecurity(), InstantMuxer())
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := libp2p.New(InMemory())
+	require.NoError(t, err)
+	defer h2.Close()
+
+	err = h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	require.ErrorIs(t, err, errFakeDial)
+	require.Equal(t, int32(1), fake.dials.Load())
+}
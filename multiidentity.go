@@ -0,0 +1,196 @@
+package libp2p
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// ListenerIdentity is an identity of a MultiIdentityHost and the listeners it's
+// presented on.
+type ListenerIdentity struct {
+	// Key is the private key of the identity.
+	Key crypto.PrivKey
+	// ListenAddrs are the addresses the identity listens on.
+	ListenAddrs []ma.Multiaddr
+	// Options are additional options of the identity, e.g. its transports or
+	// connection gater. They are applied after the options shared by all identities.
+	Options []Option
+}
+
+// MultiIdentityHost is a node presenting different peer identities on different
+// listeners, e.g. a public identity on QUIC and a private network identity on a
+// separate interface. It's used by gateways bridging networks.
+//
+// Every identity is backed by its own host, with its own peerstore, so that
+// information learned about peers on one network doesn't leak to the other: peers
+// connected to one identity can't learn about the other identities of the node.
+//
+// A resource manager or connection manager set by the shared options is shared by
+// all identities. It's closed once, by Close, after the hosts of all identities.
+type MultiIdentityHost struct {
+	hosts []host.Host
+
+	// rcmgr and cmgr are the resource manager and connection manager set by the
+	// shared options, if any.
+	rcmgr network.ResourceManager
+	cmgr  connmgr.ConnManager
+}
+
+// NewMultiIdentityHost constructs a host for every identity, using the options
+// shared by all identities followed by the options of the identity. Shared options
+// must not set the peerstore, since peerstores can't be shared between identities.
+func NewMultiIdentityHost(shared []Option, identities ...ListenerIdentity) (*MultiIdentityHost, error) {
+	if len(identities) == 0 {
+		return nil, errors.New("no identities")
+	}
+	m := &MultiIdentityHost{hosts: make([]host.Host, 0, len(identities))}
+	for i, id := range identities {
+		if id.Key == nil {
+			m.Close()
+			return nil, fmt.Errorf("identity %d: no key", i)
+		}
+		opts := make([]Option, 0, len(shared)+len(id.Options)+2)
+		opts = append(opts, shared...)
+		opts = append(opts, m.shareComponents, Identity(id.Key), ListenAddrs(id.ListenAddrs...))
+		opts = append(opts, id.Options...)
+		h, err := New(opts...)
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("identity %d: %w", i, err)
+		}
+		for _, other := range m.hosts {
+			var err error
+			switch {
+			case other.ID() == h.ID():
+				err = fmt.Errorf("identity %d: duplicate identity %s", i, h.ID())
+			case other.Peerstore() == h.Peerstore():
+				err = fmt.Errorf("identity %d: peerstore shared with identity %s", i, other.ID())
+			}
+			if err != nil {
+				h.Close()
+				m.Close()
+				return nil, err
+			}
+		}
+		m.hosts = append(m.hosts, h)
+	}
+	return m, nil
+}
+
+// shareComponents records the resource manager and connection manager set by the
+// shared options, and hands the identity wrappers that don't close them: every host
+// closes its resource manager and connection manager, and they must only be closed
+// once.
+func (m *MultiIdentityHost) shareComponents(cfg *Config) error {
+	if cfg.ResourceManager != nil {
+		m.rcmgr = cfg.ResourceManager
+		cfg.ResourceManager = sharedResourceManager{cfg.ResourceManager}
+	}
+	if cfg.ConnManager != nil {
+		m.cmgr = cfg.ConnManager
+		cfg.ConnManager = sharedConnManager{cfg.ConnManager}
+	}
+	return nil
+}
+
+// sharedResourceManager is a resource manager shared by the identities of a
+// MultiIdentityHost. It's closed by the MultiIdentityHost.
+type sharedResourceManager struct {
+	network.ResourceManager
+}
+
+func (sharedResourceManager) Close() error { return nil }
+
+// SetEventBus emits the denials of the resource manager on the event bus of every
+// identity, if the resource manager supports it.
+func (rm sharedResourceManager) SetEventBus(bus event.Bus) error {
+	if r, ok := rm.ResourceManager.(interface{ SetEventBus(event.Bus) error }); ok {
+		return r.SetEventBus(bus)
+	}
+	return nil
+}
+
+// sharedConnManager is a connection manager shared by the identities of a
+// MultiIdentityHost. It's closed by the MultiIdentityHost.
+type sharedConnManager struct {
+	connmgr.ConnManager
+}
+
+func (sharedConnManager) Close() error { return nil }
+
+// Hosts returns the hosts of the identities, in the order the identities were given.
+func (m *MultiIdentityHost) Hosts() []host.Host {
+	hosts := make([]host.Host, len(m.hosts))
+	copy(hosts, m.hosts)
+	return hosts
+}
+
+// Host returns the host of identity p, or nil if p isn't an identity of m.
+func (m *MultiIdentityHost) Host(p peer.ID) host.Host {
+	for _, h := range m.hosts {
+		if h.ID() == p {
+			return h
+		}
+	}
+	return nil
+}
+
+// HostForListenAddr returns the host listening on a, or nil if no identity listens
+// on a. a must be one of the addresses returned by Network().ListenAddresses() of
+// the host, i.e. with the ports resolved.
+func (m *MultiIdentityHost) HostForListenAddr(a ma.Multiaddr) host.Host {
+	for _, h := range m.hosts {
+		for _, la := range h.Network().ListenAddresses() {
+			if la.Equal(a) {
+				return h
+			}
+		}
+	}
+	return nil
+}
+
+// SetStreamHandler sets the protocol handler on all identities.
+func (m *MultiIdentityHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
+	for _, h := range m.hosts {
+		h.SetStreamHandler(pid, handler)
+	}
+}
+
+// RemoveStreamHandler removes the protocol handler from all identities.
+func (m *MultiIdentityHost) RemoveStreamHandler(pid protocol.ID) {
+	for _, h := range m.hosts {
+		h.RemoveStreamHandler(pid)
+	}
+}
+
+// Close closes the hosts of all identities, and then the resource manager and
+// connection manager they share.
+func (m *MultiIdentityHost) Close() error {
+	var errs []error
+	for _, h := range m.hosts {
+		if err := h.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing %s: %w", h.ID(), err))
+		}
+	}
+	if m.cmgr != nil {
+		if err := m.cmgr.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing connection manager: %w", err))
+		}
+	}
+	if m.rcmgr != nil {
+		if err := m.rcmgr.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("closing resource manager: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
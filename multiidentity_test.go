@@ -0,0 +1,111 @@
+package libp2p
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcp"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMultiIdentityHost(t *testing.T) {
+	genKey := func() crypto.PrivKey {
+		t.Helper()
+		priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		require.NoError(t, err)
+		return priv
+	}
+	shared := []Option{Transport(tcp.NewTCPTransport), DisableRelay()}
+	m, err := NewMultiIdentityHost(shared,
+		ListenerIdentity{Key: genKey(), ListenAddrs: []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/0")}},
+		ListenerIdentity{Key: genKey(), ListenAddrs: []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/0")}},
+	)
+	require.NoError(t, err)
+	defer m.Close()
+	hosts := m.Hosts()
+	require.Len(t, hosts, 2)
+	require.NotEqual(t, hosts[0].ID(), hosts[1].ID())
+	for _, h := range hosts {
+		require.Equal(t, h, m.Host(h.ID()))
+		require.Equal(t, h, m.HostForListenAddr(h.Network().ListenAddresses()[0]))
+	}
+
+	m.SetStreamHandler("/test", func(s network.Stream) {
+		s.Write([]byte(s.Conn().LocalPeer()))
+		s.Close()
+	})
+
+	client, err := New(NoListenAddrs, Transport(tcp.NewTCPTransport))
+	require.NoError(t, err)
+	defer client.Close()
+	for _, h := range hosts {
+		require.NoError(t, client.Connect(context.Background(), peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}))
+		s, err := client.NewStream(context.Background(), h.ID(), "/test")
+		require.NoError(t, err)
+		b := make([]byte, 100)
+		n, _ := s.Read(b)
+		require.Equal(t, h.ID(), peer.ID(b[:n]))
+	}
+
+	// the peerstores are segregated
+	require.NotContains(t, hosts[0].Peerstore().Peers(), hosts[1].ID())
+	require.NotContains(t, hosts[1].Peerstore().Peers(), hosts[0].ID())
+}
+
+func TestMultiIdentityHostErrors(t *testing.T) {
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	_, err = NewMultiIdentityHost(nil,
+		ListenerIdentity{Key: priv, Options: []Option{NoListenAddrs}},
+		ListenerIdentity{Key: priv, Options: []Option{NoListenAddrs}},
+	)
+	require.ErrorContains(t, err, "duplicate identity")
+}
+
+type closeCountingRcmgr struct {
+	network.NullResourceManager
+	closed int
+}
+
+func (rm *closeCountingRcmgr) Close() error {
+	rm.closed++
+	return nil
+}
+
+type closeCountingConnMgr struct {
+	connmgr.NullConnMgr
+	closed int
+}
+
+func (cm *closeCountingConnMgr) Close() error {
+	cm.closed++
+	return nil
+}
+
+func TestMultiIdentityHostSharedComponents(t *testing.T) {
+	genKey := func() crypto.PrivKey {
+		t.Helper()
+		priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		require.NoError(t, err)
+		return priv
+	}
+	rm := &closeCountingRcmgr{}
+	cm := &closeCountingConnMgr{}
+	shared := []Option{NoListenAddrs, DisableRelay(), ResourceManager(rm), ConnectionManager(cm)}
+	m, err := NewMultiIdentityHost(shared,
+		ListenerIdentity{Key: genKey()},
+		ListenerIdentity{Key: genKey()},
+		ListenerIdentity{Key: genKey()},
+	)
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+	require.Equal(t, 1, rm.closed)
+	require.Equal(t, 1, cm.closed)
+}
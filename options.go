@@ -4,11 +4,15 @@ package libp2p
 // those are in defaults.go).
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"net"
+	"net/netip"
 	"reflect"
+	"slices"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/config"
@@ -21,16 +25,30 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/pnet"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/backoff"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/autonat"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/autorelay"
 	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/browsercheck"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/keystore"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+	"github.com/TheNoobiCat/go-libp2p/p2p/muxer/yamux"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/nat"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/reuseport"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/tofu"
 	tptu "github.com/TheNoobiCat/go-libp2p/p2p/net/upgrader"
 	relayv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/relay"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/holepunch"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/quicreuse"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/benbjohnson/clock"
 	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/quic-go/quic-go"
 	"go.uber.org/fx"
 )
 
@@ -57,6 +75,53 @@ func ListenAddrs(addrs ...ma.Multiaddr) Option {
 	}
 }
 
+// ListenAddrsOnInterface configures libp2p to listen on the given (unparsed)
+// addresses on the network interface with the given name, e.g. "eth1". The addresses
+// must use the unspecified IP address, /ip4/0.0.0.0 or /ip6/::, which is replaced
+// by the interface's addresses of the same address family. Link local IPv6
+// addresses are skipped.
+//
+// The interface's addresses are resolved when the option is applied. Addresses
+// added to the interface later aren't listened on.
+func ListenAddrsOnInterface(iface string, s ...string) Option {
+	return func(cfg *Config) error {
+		ips, err := reuseport.InterfaceAddrs(iface)
+		if err != nil {
+			return fmt.Errorf("listen on interface %s: %w", iface, err)
+		}
+		for _, addrstr := range s {
+			a, err := ma.NewMultiaddr(addrstr)
+			if err != nil {
+				return err
+			}
+			ipComp, rest := ma.SplitFirst(a)
+			if ipComp == nil || (ipComp.Code() != ma.P_IP4 && ipComp.Code() != ma.P_IP6) {
+				return fmt.Errorf("listen address %s doesn't start with an IP address", a)
+			}
+			ip, _ := netip.AddrFromSlice(ipComp.RawValue())
+			if !ip.IsUnspecified() {
+				return fmt.Errorf("listen address %s doesn't use the unspecified IP address", a)
+			}
+			var found bool
+			for _, ifaceIP := range ips {
+				if ifaceIP.Is4() != ip.Is4() || ifaceIP.IsLinkLocalUnicast() {
+					continue
+				}
+				ifaceAddr, err := manet.FromIP(ifaceIP.AsSlice())
+				if err != nil {
+					return err
+				}
+				cfg.ListenAddrs = append(cfg.ListenAddrs, ifaceAddr.Encapsulate(rest))
+				found = true
+			}
+			if !found {
+				return fmt.Errorf("interface %s has no address to listen on %s", iface, a)
+			}
+		}
+		return nil
+	}
+}
+
 // Security configures libp2p to use the given security transport (or transport
 // constructor).
 //
@@ -80,6 +145,36 @@ func Security(name string, constructor interface{}) Option {
 	}
 }
 
+// SecurityPreference configures libp2p to consult pref when negotiating the security
+// protocol of a connection, e.g. to require TLS for peers in some networks and prefer
+// noise otherwise, see upgrader.SecurityRules. By default, the security transports
+// are negotiated in the order they were configured in.
+func SecurityPreference(pref tptu.SecurityPreference) Option {
+	return func(cfg *Config) error {
+		if cfg.SecurityPreference != nil {
+			return errors.New("cannot specify multiple security preferences")
+		}
+		cfg.SecurityPreference = pref
+		return nil
+	}
+}
+
+// Negotiator configures libp2p to negotiate the security protocol and the stream
+// multiplexer of the connections of transports handling any of protocols (e.g.
+// ma.P_TCP) with n instead of multistream-select, e.g. to agree on them statically
+// inside a controlled cluster with upgrader.StaticNegotiator and save a round trip.
+// Without protocols, n applies to all transports using the upgrader. It doesn't
+// apply to transports with built-in security and multiplexing, like QUIC and WebRTC.
+func Negotiator(n tptu.Negotiator, protocols ...int) Option {
+	return func(cfg *Config) error {
+		if n == nil {
+			return errors.New("nil negotiator")
+		}
+		cfg.Negotiators = append(cfg.Negotiators, config.Negotiator{Negotiator: n, Protocols: protocols})
+		return nil
+	}
+}
+
 // NoSecurity is an option that completely disables all transport security.
 // It's incompatible with all other transport security protocols.
 var NoSecurity Option = func(cfg *Config) error {
@@ -99,6 +194,19 @@ func Muxer(name string, muxer network.Multiplexer) Option {
 	}
 }
 
+// Yamux configures libp2p to use the yamux stream multiplexer, tuned with opts, e.g. to
+// raise the stream windows of high throughput deployments, or to lower the windows and
+// buffers of low memory devices. It replaces DefaultMuxers.
+func Yamux(opts ...yamux.Option) Option {
+	return func(cfg *Config) error {
+		t, err := yamux.NewTransport(opts...)
+		if err != nil {
+			return fmt.Errorf("invalid yamux configuration: %w", err)
+		}
+		return Muxer(yamux.ID, t)(cfg)
+	}
+}
+
 func QUICReuse(constructor interface{}, opts ...quicreuse.Option) Option {
 	return func(cfg *Config) error {
 		tag := `group:"quicreuseopts"`
@@ -126,6 +234,23 @@ func QUICReuse(constructor interface{}, opts ...quicreuse.Option) Option {
 	}
 }
 
+// QUICStatelessResetKey sets the key used by the QUIC transports to generate
+// stateless reset tokens. When a node restarts, it sends stateless resets for the
+// connections it lost, so that peers detect the restart immediately instead of
+// waiting for the idle timeout. This only works if the key is stable across
+// restarts. By default, the key is derived from the host's private key, this
+// option is useful when running several nodes with different identities behind
+// the same load balancer, or when the identity isn't stable.
+func QUICStatelessResetKey(key quic.StatelessResetKey) Option {
+	return func(cfg *Config) error {
+		if cfg.QUICStatelessResetKey != nil {
+			return errors.New("cannot specify multiple QUIC stateless reset keys")
+		}
+		cfg.QUICStatelessResetKey = &key
+		return nil
+	}
+}
+
 // Transport configures libp2p to use the given transport (or transport
 // constructor).
 //
@@ -242,6 +367,22 @@ func Identity(sk crypto.PrivKey) Option {
 	}
 }
 
+// IdentityFromKeystore configures libp2p to use the identity key stored in ks. If ks
+// doesn't store a key yet, an Ed25519 key is generated and stored.
+func IdentityFromKeystore(ks keystore.Keystore) Option {
+	return func(cfg *Config) error {
+		if cfg.PeerKey != nil {
+			return fmt.Errorf("cannot specify multiple identities")
+		}
+		sk, err := keystore.LoadOrGenerate(ks, crypto.Ed25519, -1)
+		if err != nil {
+			return err
+		}
+		cfg.PeerKey = sk
+		return nil
+	}
+}
+
 // ConnectionManager configures libp2p to use the given connection manager.
 //
 // The current "standard" connection manager lives in github.com/TheNoobiCat/go-libp2p-connmgr. See
@@ -256,6 +397,35 @@ func ConnectionManager(connman connmgr.ConnManager) Option {
 	}
 }
 
+// WithClock makes the swarm's dial scheduling and backoffs, identify pushes,
+// AutoRelay, hole punching retries and the default peerstore use cl instead of the
+// system clock. Combined with a mock clock, e.g. clock.NewMock, this lets tests run
+// on virtual time instead of sleeping. A connection manager has to be given the clock
+// separately, see connmgr.WithClock.
+func WithClock(cl clock.Clock) Option {
+	return func(cfg *Config) error {
+		if cfg.Clock != nil {
+			return errors.New("clock already configured")
+		}
+		cfg.Clock = cl
+		return nil
+	}
+}
+
+// ConnManagerDialHeadroom makes libp2p refuse new outbound dials while the connection
+// manager has as many connections as its high water mark, or more, instead of dialing
+// connections that would be trimmed right away. The dials fail with
+// swarm.ErrDialRefusedHighWater. Dials to protected peers, and dials whose context is
+// marked with network.WithDialAboveHighWater or network.WithForceDirectDial, go
+// through. The connection manager must implement connmgr.HighWaterChecker, as the
+// one in p2p/net/connmgr does.
+func ConnManagerDialHeadroom() Option {
+	return func(cfg *Config) error {
+		cfg.ConnManagerDialHeadroom = true
+		return nil
+	}
+}
+
 // AddrsFactory configures libp2p to use the given address factory.
 func AddrsFactory(factory config.AddrsFactory) Option {
 	return func(cfg *Config) error {
@@ -267,6 +437,27 @@ func AddrsFactory(factory config.AddrsFactory) Option {
 	}
 }
 
+// AdvertiseHostname configures the host to advertise its public IP addresses under
+// hostname, as /dns4 and /dns6 addresses. Secure websocket and webtransport addresses
+// carry hostname as SNI. If replaceIPAddrs is true, the public IP addresses aren't
+// advertised anymore, otherwise both are advertised. The signed peer record contains
+// the same addresses.
+//
+// The hostname is applied after the AddrsFactory, if any.
+func AdvertiseHostname(hostname string, replaceIPAddrs bool) Option {
+	return func(cfg *Config) error {
+		if cfg.AdvertisedHostname != "" {
+			return fmt.Errorf("cannot specify multiple advertised hostnames")
+		}
+		if _, err := bhost.HostnameAddrsFactory(hostname, replaceIPAddrs); err != nil {
+			return err
+		}
+		cfg.AdvertisedHostname = hostname
+		cfg.ReplaceIPAddrs = replaceIPAddrs
+		return nil
+	}
+}
+
 // EnableRelay configures libp2p to enable the relay transport.
 // This option only configures libp2p to accept inbound connections from relays
 // and make outbound connections_through_ relays when requested by the remote peer.
@@ -387,6 +578,82 @@ func AutoNATServiceRateLimit(global, perPeer int, interval time.Duration) Option
 	}
 }
 
+// AutoNATProbeSchedule configures when the AutoNAT client probes the reachability of
+// the host. By default, the refresh interval backs off while probes confirm the
+// reachability, and the host probes soon after its addresses change.
+func AutoNATProbeSchedule(s autonat.ProbeSchedule) Option {
+	return func(cfg *Config) error {
+		cfg.AutoNATConfig.ProbeSchedule = &s
+		return nil
+	}
+}
+
+// ServicePeerRateLimits configures per peer token bucket rate limits for the identify,
+// ping and AutoNAT services. Identify and ping streams over the limit are reset with
+// network.StreamRateLimited, AutoNAT dial back requests over the limit are refused with
+// E_DIAL_REFUSED. A zero limit disables per peer rate limiting for that service.
+func ServicePeerRateLimits(limits config.ServicePeerRateLimits) Option {
+	return func(cfg *Config) error {
+		cfg.ServicePeerRateLimits = limits
+		return nil
+	}
+}
+
+// AddrAdvertisement configures how long the host's own addresses remain valid in its
+// signed peer record and how often identify pushes address changes to connected peers.
+// Debouncing pushes is useful on mobile nodes, whose addresses change frequently.
+// Limiting the pushes per peer protects connected peers from being flooded by a host
+// with flapping listeners. Local-first applications can set LANMode to only advertise
+// their private addresses to the peers on the same subnet.
+func AddrAdvertisement(a config.AddrAdvertisement) Option {
+	return func(cfg *Config) error {
+		if a.SelfAddrTTL < 0 || a.PushDebounce < 0 || a.MinPushInterval < 0 || a.PeerPushInterval < 0 {
+			return errors.New("address advertisement durations must not be negative")
+		}
+		cfg.AddrAdvertisement = a
+		return nil
+	}
+}
+
+// Reputation configures the registry that the host's services (identify, the relay
+// service and hole punching) report misbehaving peers to. The registry isn't closed when the host is closed. To act on the scores,
+// pass it to the connection manager with reputation.WithConnManager, or refuse
+// connections to peers with a bad reputation using reputation.NewGater.
+func Reputation(r *reputation.Registry) Option {
+	return func(cfg *Config) error {
+		if cfg.Reputation != nil {
+			return errors.New("cannot specify multiple reputation registries")
+		}
+		cfg.Reputation = r
+		return nil
+	}
+}
+
+// ServeNodeInfo serves the host's NodeInfo document to the given peers using the node
+// info protocol (see the nodeinfo package). Requests from other peers are refused.
+func ServeNodeInfo(peers ...peer.ID) Option {
+	return func(cfg *Config) error {
+		cfg.NodeInfoAllowlist = append(cfg.NodeInfoAllowlist, peers...)
+		return nil
+	}
+}
+
+// IntrospectionListenAddr serves the host's introspection endpoint (see the introspect
+// package) over plain HTTP on the given TCP address, e.g. "127.0.0.1:5001". The
+// endpoint isn't authenticated, so it should only listen on a loopback address.
+func IntrospectionListenAddr(addr string) Option {
+	return func(cfg *Config) error {
+		if cfg.IntrospectionListenAddr != "" {
+			return errors.New("cannot specify multiple introspection listen addresses")
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return fmt.Errorf("invalid introspection listen address: %w", err)
+		}
+		cfg.IntrospectionListenAddr = addr
+		return nil
+	}
+}
+
 // ConnectionGater configures libp2p to use the given ConnectionGater
 // to actively reject inbound/outbound connections based on the lifecycle stage
 // of the connection.
@@ -402,6 +669,34 @@ func ConnectionGater(cg connmgr.ConnectionGater) Option {
 	}
 }
 
+// PeerPinning enables trust-on-first-use pinning of peers in s: the DNS names and
+// addresses used to reach a peer are pinned to it, and dialing a different peer with
+// them is reported with an event.EvtPeerPinViolation, or refused, depending on the
+// mode of s. The pinning gater wraps the configured ConnectionGater.
+func PeerPinning(s *tofu.Store) Option {
+	return func(cfg *Config) error {
+		if cfg.PeerPinning != nil {
+			return errors.New("cannot specify multiple peer pinning stores")
+		}
+		cfg.PeerPinning = s
+		return nil
+	}
+}
+
+// BackoffRegistry makes the swarm track the addresses it backs off from dialing in r,
+// and AutoRelay back off from the candidates it failed to connect to in r. r can be
+// shared with other components connecting to peers, such as a
+// backoff.BackoffConnector, so that they all back off consistently.
+func BackoffRegistry(r *backoff.Registry) Option {
+	return func(cfg *Config) error {
+		if cfg.BackoffRegistry != nil {
+			return errors.New("cannot specify multiple backoff registries")
+		}
+		cfg.BackoffRegistry = r
+		return nil
+	}
+}
+
 // ResourceManager configures libp2p to use the given ResourceManager.
 // When using the p2p/host/resource-manager implementation of the ResourceManager interface,
 // it is recommended to set limits for libp2p protocol by calling SetDefaultServiceLimits.
@@ -421,6 +716,23 @@ func NATPortMap() Option {
 	return NATManager(bhost.NewNATManager)
 }
 
+// NATPortMapBackend configures libp2p to use the default NATManager with the port
+// mapping backend returned by discover instead of a UPnP or NAT-PMP gateway, e.g.
+// nat.PCP for the Port Control Protocol, which also opens IPv6 firewall pinholes:
+//
+//	libp2p.NATPortMapBackend(nat.PCP)
+//
+// or a static configuration made with nat.NewStaticBackend:
+//
+//	libp2p.NATPortMapBackend(func(context.Context) (nat.Backend, error) {
+//		return nat.NewStaticBackend(extAddr, mappings...), nil
+//	})
+//
+// The status of the mappings is emitted as event.EvtNATMappingsUpdated.
+func NATPortMapBackend(discover func(context.Context) (nat.Backend, error)) Option {
+	return NATManager(bhost.NewNATManagerWithBackend(discover))
+}
+
 // NATManager will configure libp2p to use the requested NATManager. This
 // function should be passed a NATManager *constructor* that takes a libp2p Network.
 func NATManager(nm config.NATManagerC) Option {
@@ -493,6 +805,22 @@ func UserAgent(userAgent string) Option {
 	}
 }
 
+// UserAgentForConn overrides the user-agent sent on a connection, e.g. to send
+// different user-agents to different peers. If f returns an empty string, the
+// user-agent set with UserAgent is sent.
+//
+// The user-agent can also be changed at runtime, for all connections, with the
+// SetUserAgent method of hosts implementing host.UserAgentSetter.
+func UserAgentForConn(f func(c network.Conn) string) Option {
+	return func(cfg *Config) error {
+		if cfg.UserAgentForConn != nil {
+			return errors.New("cannot specify multiple user-agent overrides")
+		}
+		cfg.UserAgentForConn = f
+		return nil
+	}
+}
+
 // MultiaddrResolver sets the libp2p dns resolver
 func MultiaddrResolver(rslv network.MultiaddrDNSResolver) Option {
 	return func(cfg *Config) error {
@@ -575,6 +903,64 @@ func PrometheusRegisterer(reg prometheus.Registerer) Option {
 	}
 }
 
+// DisableMetricsFor disables the metrics of the given subsystems, see
+// metricshelper.Subsystems. The other subsystems keep reporting metrics.
+func DisableMetricsFor(subsystems ...string) Option {
+	return func(cfg *Config) error {
+		for _, sub := range subsystems {
+			if !slices.Contains(metricshelper.Subsystems, sub) {
+				return fmt.Errorf("unknown metrics subsystem: %s", sub)
+			}
+			if cfg.MetricsDisabled == nil {
+				cfg.MetricsDisabled = make(map[string]bool)
+			}
+			cfg.MetricsDisabled[sub] = true
+		}
+		return nil
+	}
+}
+
+// MetricsRegistererFor configures libp2p to use reg as the Registerer of the metrics
+// subsystem, instead of the Registerer set with PrometheusRegisterer. It has no
+// effect if metrics are disabled with DisableMetrics.
+func MetricsRegistererFor(subsystem string, reg prometheus.Registerer) Option {
+	return func(cfg *Config) error {
+		if !slices.Contains(metricshelper.Subsystems, subsystem) {
+			return fmt.Errorf("unknown metrics subsystem: %s", subsystem)
+		}
+		if reg == nil {
+			return errors.New("registerer cannot be nil")
+		}
+		if _, ok := cfg.MetricsRegisterers[subsystem]; ok {
+			return fmt.Errorf("registerer already set for metrics subsystem %s", subsystem)
+		}
+		if cfg.MetricsRegisterers == nil {
+			cfg.MetricsRegisterers = make(map[string]prometheus.Registerer)
+		}
+		cfg.MetricsRegisterers[subsystem] = reg
+		return nil
+	}
+}
+
+// MetricsConstLabels adds constant labels to all libp2p metrics, e.g. the name of
+// the environment or of the application. The metric collectors are shared by all the
+// libp2p nodes of the process, so the labels can't tell apart several nodes sharing a
+// registry: their metrics are aggregated.
+func MetricsConstLabels(labels prometheus.Labels) Option {
+	return func(cfg *Config) error {
+		if cfg.MetricsConstLabels == nil {
+			cfg.MetricsConstLabels = make(prometheus.Labels, len(labels))
+		}
+		for k, v := range labels {
+			if _, ok := cfg.MetricsConstLabels[k]; ok {
+				return fmt.Errorf("metrics label %s already set", k)
+			}
+			cfg.MetricsConstLabels[k] = v
+		}
+		return nil
+	}
+}
+
 // DialRanker configures libp2p to use d as the dial ranker. To enable smart
 // dialing use `swarm.DefaultDialRanker`. use `swarm.NoDelayDialRanker` to
 // disable smart dialing.
@@ -590,6 +976,20 @@ func DialRanker(d network.DialRanker) Option {
 	}
 }
 
+// DialRankingPolicy configures libp2p to use p for scheduling dials to a peer's
+// addresses. Unlike DialRanker, the policy is given the peer and metadata about every
+// address (transport, public / private, relay, last successful dial). A policy can
+// replace the default ranking or wrap swarm.DefaultDialRanker.
+func DialRankingPolicy(p swarm.DialRankingPolicy) Option {
+	return func(cfg *Config) error {
+		if cfg.DialRankingPolicy != nil {
+			return errors.New("dial ranking policy already configured")
+		}
+		cfg.DialRankingPolicy = p
+		return nil
+	}
+}
+
 // SwarmOpts configures libp2p to use swarm with opts
 func SwarmOpts(opts ...swarm.Option) Option {
 	return func(cfg *Config) error {
@@ -609,6 +1009,34 @@ func DisableIdentifyAddressDiscovery() Option {
 	}
 }
 
+// LazyIdentify defers the identify exchange on a connection until the first stream
+// is opened on it, instead of running it as soon as the connection is established.
+// This saves the exchange for connections that are closed without being used, e.g.
+// by crawlers. Connect returns without waiting for identify. Services that need the
+// identify data of a peer wait for it on demand.
+func LazyIdentify() Option {
+	return func(cfg *Config) error {
+		cfg.LazyIdentify = true
+		return nil
+	}
+}
+
+// SignedPeerRecordPolicy sets how identify handles the peers that don't send a signed
+// peer record: accept their unsigned addresses (the default), accept but flag them, or
+// ignore them. Use peerstore.AddrsCertified to check whether the addresses of a peer
+// are certified. This allows migrating a network to signed peer records gradually.
+func SignedPeerRecordPolicy(p identify.SignedRecordPolicy) Option {
+	return func(cfg *Config) error {
+		switch p {
+		case identify.SignedRecordAccept, identify.SignedRecordFlag, identify.SignedRecordRequire:
+		default:
+			return fmt.Errorf("invalid signed peer record policy: %s", p)
+		}
+		cfg.SignedPeerRecordPolicy = p
+		return nil
+	}
+}
+
 // EnableAutoNATv2 enables autonat v2
 func EnableAutoNATv2() Option {
 	return func(cfg *Config) error {
@@ -617,6 +1045,49 @@ func EnableAutoNATv2() Option {
 	}
 }
 
+// InboundNegotiation bounds the inbound streams negotiating their protocol. Streams
+// that don't complete their negotiation within timeout are reset with
+// network.StreamNegotiationTimeout, and a peer can't have more than maxPerPeer
+// streams negotiating. A zero timeout keeps the default negotiation timeout, a zero
+// maxPerPeer means unlimited.
+func InboundNegotiation(timeout time.Duration, maxPerPeer int) Option {
+	return func(cfg *config.Config) error {
+		if timeout < 0 || maxPerPeer < 0 {
+			return errors.New("inbound negotiation limits must not be negative")
+		}
+		cfg.InboundNegotiationTimeout = timeout
+		cfg.MaxNegotiatingStreamsPerPeer = maxPerPeer
+		return nil
+	}
+}
+
+// PprofLabels tags the goroutines of inbound stream handlers, dial workers and dials
+// with pprof labels: the peer, truncated, the protocol and the transport. This lets
+// CPU and heap profiles of busy nodes be attributed to peers and protocols, at the
+// cost of a few allocations per stream and dial. It's meant for debugging.
+func PprofLabels() Option {
+	return func(cfg *config.Config) error {
+		cfg.PprofLabels = true
+		return nil
+	}
+}
+
+// BrowserAddrCheck enables checking that the host's /tls/ws, /webtransport and
+// /webrtc-direct addresses are dialable by browsers, by having the AutoNAT v2 server
+// checker dial them back. The results are emitted as event.EvtBrowserAddrsChecked.
+// This enables AutoNAT v2.
+func BrowserAddrCheck(checker peer.AddrInfo, opts ...browsercheck.Option) Option {
+	return func(cfg *config.Config) error {
+		if cfg.BrowserAddrChecker != nil {
+			return errors.New("cannot specify multiple browser address checkers")
+		}
+		cfg.BrowserAddrChecker = &checker
+		cfg.BrowserAddrCheckOpts = opts
+		cfg.EnableAutoNATv2 = true
+		return nil
+	}
+}
+
 // UDPBlackHoleSuccessCounter configures libp2p to use f as the black hole filter for UDP addrs
 func UDPBlackHoleSuccessCounter(f *swarm.BlackHoleSuccessCounter) Option {
 	return func(cfg *Config) error {
@@ -635,6 +1106,19 @@ func IPv6BlackHoleSuccessCounter(f *swarm.BlackHoleSuccessCounter) Option {
 	}
 }
 
+// BlackHolePolicy configures libp2p to use p as the black hole filter for the addrs matching key,
+// e.g. swarm.BlackHoleKey{Transport: ma.P_TCP, Network: ma.P_IP6} for TCP over IPv6 addrs.
+// A nil p disables the black hole filter for key.
+func BlackHolePolicy(key swarm.BlackHoleKey, p swarm.BlackHolePolicy) Option {
+	return func(cfg *Config) error {
+		if cfg.BlackHolePolicies == nil {
+			cfg.BlackHolePolicies = make(map[swarm.BlackHoleKey]swarm.BlackHolePolicy)
+		}
+		cfg.BlackHolePolicies[key] = p
+		return nil
+	}
+}
+
 // WithFxOption adds a user provided fx.Option to the libp2p constructor.
 // Experimental: This option is subject to change or removal.
 func WithFxOption(opts ...fx.Option) Option {
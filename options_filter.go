@@ -1,36 +1,25 @@
 package libp2p
 
 import (
-	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
-	"github.com/TheNoobiCat/go-libp2p/core/control"
-	"github.com/TheNoobiCat/go-libp2p/core/network"
-	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"errors"
 
 	ma "github.com/multiformats/go-multiaddr"
 )
 
-// filtersConnectionGater is an adapter that turns multiaddr.Filter into a
-// connmgr.ConnectionGater.
-type filtersConnectionGater ma.Filters
-
-var _ connmgr.ConnectionGater = (*filtersConnectionGater)(nil)
-
-func (f *filtersConnectionGater) InterceptAddrDial(_ peer.ID, addr ma.Multiaddr) (allow bool) {
-	return !(*ma.Filters)(f).AddrBlocked(addr)
-}
-
-func (f *filtersConnectionGater) InterceptPeerDial(_ peer.ID) (allow bool) {
-	return true
-}
-
-func (f *filtersConnectionGater) InterceptAccept(connAddr network.ConnMultiaddrs) (allow bool) {
-	return !(*ma.Filters)(f).AddrBlocked(connAddr.RemoteMultiaddr())
-}
-
-func (f *filtersConnectionGater) InterceptSecured(_ network.Direction, _ peer.ID, connAddr network.ConnMultiaddrs) (allow bool) {
-	return !(*ma.Filters)(f).AddrBlocked(connAddr.RemoteMultiaddr())
-}
-
-func (f *filtersConnectionGater) InterceptUpgraded(_ network.Conn) (allow bool, reason control.DisconnectReason) {
-	return true, 0
+// AddrFilters configures the address filters of the host. The host refuses to dial,
+// accept connections from or listen on the addresses blocked by f. Blocked addresses
+// are not advertised, and relays are not used on blocked addresses.
+//
+// f can be updated while the host runs, e.g. using AddFilter and RemoveLiteral, and
+// changes apply to new connections. The filters are also enforced by the connection
+// gater, so that connections from blocked addresses are rejected before the
+// handshake.
+func AddrFilters(f *ma.Filters) Option {
+	return func(cfg *Config) error {
+		if cfg.AddrFilters != nil {
+			return errors.New("cannot configure multiple address filters")
+		}
+		cfg.AddrFilters = f
+		return nil
+	}
 }
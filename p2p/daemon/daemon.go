@@ -0,0 +1,516 @@
+// Package daemon implements a control server for a libp2p host, so that non-Go
+// applications can drive an embedded go-libp2p node.
+//
+// The server listens on a Unix socket. Clients send varint length prefixed pb.Request
+// messages and receive a pb.Response for each of them:
+//   - Identify returns the host's peer ID and addresses.
+//   - Connect connects to a peer, and Disconnect closes the connections to a peer.
+//   - ListPeers returns the connected peers.
+//   - StreamOpen opens a stream to a peer. Once the response is sent, the client's
+//     connection is bridged to the stream: everything written to it is sent on the
+//     stream, and the data received on the stream can be read from it.
+//   - StreamHandler handles the inbound streams for a set of protocols by connecting to
+//     a Unix socket of the client, writing a pb.StreamInfo message, and bridging the
+//     connection to the stream.
+//   - Subscribe turns the connection into a feed of pb.Event messages.
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/p2p/daemon/pb"
+
+	logging "github.com/ipfs/go-log/v2"
+	"github.com/libp2p/go-msgio"
+	"github.com/libp2p/go-msgio/pbio"
+	ma "github.com/multiformats/go-multiaddr"
+	"google.golang.org/protobuf/proto"
+)
+
+var log = logging.Logger("daemon")
+
+const (
+	// maxRequestSize is the maximum size of a request.
+	maxRequestSize = 64 << 10
+	// defaultTimeout is the timeout of the requests that don't set one.
+	defaultTimeout = time.Minute
+)
+
+// Server is a control server for a host.
+type Server struct {
+	host       host.Host
+	ln         net.Listener
+	socketPath string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mx       sync.Mutex
+	conns    map[net.Conn]struct{}
+	handlers map[protocol.ID]string // socket path of the client handling the protocol
+}
+
+// NewServer starts a control server for h, listening on a Unix socket at socketPath.
+// The socket is only accessible to the current user.
+func NewServer(h host.Host, socketPath string) (*Server, error) {
+	ln, err := listen(socketPath)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Server{
+		host:       h,
+		ln:         ln,
+		socketPath: socketPath,
+		ctx:        ctx,
+		cancel:     cancel,
+		conns:      make(map[net.Conn]struct{}),
+		handlers:   make(map[protocol.ID]string),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// listen listens on a Unix socket at socketPath that is only accessible to the current
+// user. The socket is created in a private directory, and only linked to socketPath
+// once its permissions are restricted, so that nobody can connect in the meantime.
+func listen(socketPath string) (net.Listener, error) {
+	dir, err := os.MkdirTemp(filepath.Dir(socketPath), ".p2pd-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, "sock")
+	ln, err := net.ListenUnix("unix", &net.UnixAddr{Name: tmpPath, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	// the socket is removed at socketPath by Close, the temporary path is already gone
+	ln.SetUnlinkOnClose(false)
+	if err := os.Chmod(tmpPath, 0o600); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	// unlike a rename, linking fails if socketPath exists
+	if err := os.Link(tmpPath, socketPath); err != nil {
+		ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+// Addr returns the address of the Unix socket the server listens on.
+func (s *Server) Addr() net.Addr {
+	return &net.UnixAddr{Name: s.socketPath, Net: "unix"}
+}
+
+// Close stops the server, closes the clients' connections and removes the stream
+// handlers the clients set. The host isn't closed.
+func (s *Server) Close() error {
+	s.cancel()
+	err := s.ln.Close()
+	if rerr := os.Remove(s.socketPath); rerr != nil && !errors.Is(rerr, os.ErrNotExist) && err == nil {
+		err = rerr
+	}
+
+	s.mx.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	for p := range s.handlers {
+		s.host.RemoveStreamHandler(p)
+	}
+	s.handlers = nil
+	s.mx.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.ln.Accept()
+		if err != nil {
+			if s.ctx.Err() == nil {
+				log.Errorw("accept failed", "err", err)
+			}
+			return
+		}
+		if !s.trackConn(c) {
+			c.Close()
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer s.untrackConn(c)
+			s.handleConn(c)
+		}()
+	}
+}
+
+// trackConn tracks c to close it when the server is closed. It returns false if the
+// server is already closed.
+func (s *Server) trackConn(c net.Conn) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.ctx.Err() != nil {
+		return false
+	}
+	s.conns[c] = struct{}{}
+	return true
+}
+
+func (s *Server) untrackConn(c net.Conn) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	delete(s.conns, c)
+}
+
+// handleConn serves the requests of a client until it closes the connection, or the
+// connection is bridged to a stream or used for events.
+func (s *Server) handleConn(c net.Conn) {
+	defer c.Close()
+
+	// The reader isn't buffered, so that the data following a StreamOpen request can
+	// be read from c once it's bridged to the stream.
+	r := msgio.NewVarintReaderSize(c, maxRequestSize)
+	w := pbio.NewDelimitedWriter(c)
+	for {
+		var req pb.Request
+		if err := readMsg(r, &req); err != nil {
+			if !errors.Is(err, io.EOF) {
+				log.Debugw("failed to read request", "err", err)
+			}
+			return
+		}
+
+		switch req.Request.(type) {
+		case *pb.Request_StreamOpen:
+			s.openStream(c, w, req.GetStreamOpen())
+			return
+		case *pb.Request_Subscribe:
+			s.subscribe(c, w)
+			return
+		}
+
+		if err := w.WriteMsg(s.handleRequest(&req)); err != nil {
+			log.Debugw("failed to write response", "err", err)
+			return
+		}
+	}
+}
+
+func readMsg(r msgio.Reader, msg proto.Message) error {
+	b, err := r.ReadMsg()
+	if err != nil {
+		return err
+	}
+	defer r.ReleaseMsg(b)
+	return proto.Unmarshal(b, msg)
+}
+
+func (s *Server) handleRequest(req *pb.Request) *pb.Response {
+	var err error
+	resp := &pb.Response{}
+	switch req.Request.(type) {
+	case *pb.Request_Identify:
+		resp.Response = &pb.Response_Identify{Identify: &pb.IdentifyResponse{
+			Id:    []byte(s.host.ID()),
+			Addrs: addrsToBytes(s.host.Addrs()),
+		}}
+	case *pb.Request_Connect:
+		err = s.connect(req.GetConnect())
+	case *pb.Request_Disconnect:
+		err = s.disconnect(req.GetDisconnect())
+	case *pb.Request_ListPeers:
+		resp.Response = &pb.Response_ListPeers{ListPeers: s.listPeers()}
+	case *pb.Request_StreamHandler:
+		err = s.setStreamHandler(req.GetStreamHandler())
+	case *pb.Request_RemoveStreamHandler:
+		s.removeStreamHandler(req.GetRemoveStreamHandler())
+	default:
+		err = errors.New("unknown request")
+	}
+	if err != nil {
+		return errorResponse(err)
+	}
+	return resp
+}
+
+func errorResponse(err error) *pb.Response {
+	return &pb.Response{Status: pb.Response_ERROR, Error: err.Error()}
+}
+
+func (s *Server) connect(req *pb.ConnectRequest) error {
+	p, err := peer.IDFromBytes(req.GetPeer())
+	if err != nil {
+		return err
+	}
+	addrs, err := addrsFromBytes(req.GetAddrs())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.requestContext(req.GetTimeoutMillis())
+	defer cancel()
+	return s.host.Connect(ctx, peer.AddrInfo{ID: p, Addrs: addrs})
+}
+
+func (s *Server) disconnect(req *pb.DisconnectRequest) error {
+	p, err := peer.IDFromBytes(req.GetPeer())
+	if err != nil {
+		return err
+	}
+	return s.host.Network().ClosePeer(p)
+}
+
+func (s *Server) listPeers() *pb.ListPeersResponse {
+	resp := &pb.ListPeersResponse{}
+	for _, p := range s.host.Network().Peers() {
+		info := &pb.PeerInfo{Id: []byte(p)}
+		for _, c := range s.host.Network().ConnsToPeer(p) {
+			info.Addrs = append(info.Addrs, c.RemoteMultiaddr().Bytes())
+		}
+		resp.Peers = append(resp.Peers, info)
+	}
+	return resp
+}
+
+func (s *Server) requestContext(timeoutMillis int64) (context.Context, context.CancelFunc) {
+	timeout := defaultTimeout
+	if timeoutMillis > 0 {
+		timeout = time.Duration(timeoutMillis) * time.Millisecond
+	}
+	return context.WithTimeout(s.ctx, timeout)
+}
+
+// openStream opens the requested stream and bridges c to it.
+func (s *Server) openStream(c net.Conn, w pbio.Writer, req *pb.StreamOpenRequest) {
+	str, err := s.newStream(req)
+	if err != nil {
+		w.WriteMsg(errorResponse(err))
+		return
+	}
+	resp := &pb.Response{Response: &pb.Response_StreamInfo{StreamInfo: streamInfo(str)}}
+	if err := w.WriteMsg(resp); err != nil {
+		log.Debugw("failed to write response", "err", err)
+		str.Reset()
+		return
+	}
+	bridge(c, str)
+}
+
+func (s *Server) newStream(req *pb.StreamOpenRequest) (network.Stream, error) {
+	p, err := peer.IDFromBytes(req.GetPeer())
+	if err != nil {
+		return nil, err
+	}
+	if len(req.GetProtocols()) == 0 {
+		return nil, errors.New("no protocols")
+	}
+	ctx, cancel := s.requestContext(req.GetTimeoutMillis())
+	defer cancel()
+	return s.host.NewStream(ctx, p, protocol.ConvertFromStrings(req.GetProtocols())...)
+}
+
+func streamInfo(str network.Stream) *pb.StreamInfo {
+	return &pb.StreamInfo{
+		Peer:     []byte(str.Conn().RemotePeer()),
+		Addr:     str.Conn().RemoteMultiaddr().Bytes(),
+		Protocol: string(str.Protocol()),
+	}
+}
+
+func (s *Server) setStreamHandler(req *pb.StreamHandlerRequest) error {
+	path := req.GetSocketPath()
+	if path == "" {
+		return errors.New("no socket path")
+	}
+	if len(req.GetProtocols()) == 0 {
+		return errors.New("no protocols")
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.handlers == nil {
+		return errors.New("server closed")
+	}
+	for _, p := range protocol.ConvertFromStrings(req.GetProtocols()) {
+		s.handlers[p] = path
+		s.host.SetStreamHandler(p, s.handleStream)
+	}
+	return nil
+}
+
+func (s *Server) removeStreamHandler(req *pb.RemoveStreamHandlerRequest) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	for _, p := range protocol.ConvertFromStrings(req.GetProtocols()) {
+		if _, ok := s.handlers[p]; ok {
+			delete(s.handlers, p)
+			s.host.RemoveStreamHandler(p)
+		}
+	}
+}
+
+// handleStream bridges an inbound stream to the client handling its protocol.
+func (s *Server) handleStream(str network.Stream) {
+	s.mx.Lock()
+	path, ok := s.handlers[str.Protocol()]
+	s.mx.Unlock()
+	if !ok {
+		str.Reset()
+		return
+	}
+
+	var d net.Dialer
+	ctx, cancel := s.requestContext(0)
+	defer cancel()
+	c, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		log.Debugw("failed to connect to stream handler", "path", path, "err", err)
+		str.Reset()
+		return
+	}
+	defer c.Close()
+	if err := pbio.NewDelimitedWriter(c).WriteMsg(streamInfo(str)); err != nil {
+		log.Debugw("failed to write stream info", "path", path, "err", err)
+		str.Reset()
+		return
+	}
+	bridge(c, str)
+}
+
+// bridge copies the data between c and str until both directions are closed.
+func bridge(c net.Conn, str network.Stream) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := io.Copy(str, c); err != nil {
+			str.Reset()
+			c.Close()
+			return
+		}
+		str.CloseWrite()
+	}()
+	if _, err := io.Copy(c, str); err != nil {
+		str.Reset()
+		c.Close()
+	} else if cw, ok := c.(interface{ CloseWrite() error }); ok {
+		cw.CloseWrite()
+	}
+	wg.Wait()
+	str.Close()
+}
+
+// subscribe writes the host's events to w until the client closes the connection c or
+// the server is closed.
+func (s *Server) subscribe(c net.Conn, w pbio.WriteCloser) {
+	sub, err := s.host.EventBus().Subscribe([]any{
+		new(event.EvtPeerConnectednessChanged),
+		new(event.EvtPeerIdentificationCompleted),
+		new(event.EvtLocalAddressesUpdated),
+	})
+	if err != nil {
+		w.WriteMsg(errorResponse(fmt.Errorf("failed to subscribe: %w", err)))
+		return
+	}
+	defer sub.Close()
+	if err := w.WriteMsg(&pb.Response{}); err != nil {
+		return
+	}
+
+	// The client doesn't send anything after subscribing. Detect that it closed the
+	// connection by reading from it, events may not be written for a long time.
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		io.Copy(io.Discard, c)
+	}()
+
+	for {
+		select {
+		case e := <-sub.Out():
+			evt := toEvent(e)
+			if evt == nil {
+				continue
+			}
+			if err := w.WriteMsg(evt); err != nil {
+				log.Debugw("failed to write event", "err", err)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func toEvent(e any) *pb.Event {
+	switch e := e.(type) {
+	case event.EvtPeerConnectednessChanged:
+		evt := &pb.Event{Type: pb.Event_PEER_CONNECTED, Peer: []byte(e.Peer)}
+		switch e.Connectedness {
+		case network.Connected:
+		case network.Limited:
+			evt.Limited = true
+		default:
+			evt.Type = pb.Event_PEER_DISCONNECTED
+		}
+		return evt
+	case event.EvtPeerIdentificationCompleted:
+		return &pb.Event{
+			Type:      pb.Event_PEER_IDENTIFIED,
+			Peer:      []byte(e.Peer),
+			Addrs:     addrsToBytes(e.ListenAddrs),
+			Protocols: protocol.ConvertToStrings(e.Protocols),
+		}
+	case event.EvtLocalAddressesUpdated:
+		addrs := make([]ma.Multiaddr, 0, len(e.Current))
+		for _, a := range e.Current {
+			addrs = append(addrs, a.Address)
+		}
+		return &pb.Event{Type: pb.Event_LOCAL_ADDRS_UPDATED, Addrs: addrsToBytes(addrs)}
+	default:
+		return nil
+	}
+}
+
+func addrsToBytes(addrs []ma.Multiaddr) [][]byte {
+	res := make([][]byte, 0, len(addrs))
+	for _, a := range addrs {
+		res = append(res, a.Bytes())
+	}
+	return res
+}
+
+func addrsFromBytes(bs [][]byte) ([]ma.Multiaddr, error) {
+	addrs := make([]ma.Multiaddr, 0, len(bs))
+	for _, b := range bs {
+		a, err := ma.NewMultiaddrBytes(b)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, a)
+	}
+	return addrs, nil
+}
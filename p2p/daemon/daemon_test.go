@@ -0,0 +1,232 @@
+package daemon
+
+import (
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/daemon/pb"
+	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+
+	"github.com/libp2p/go-msgio/pbio"
+	"github.com/stretchr/testify/require"
+)
+
+func newHost(t *testing.T) *bhost.BasicHost {
+	t.Helper()
+	bus := eventbus.NewBus()
+	h, err := bhost.NewHost(swarmt.GenSwarm(t, swarmt.EventBus(bus)), &bhost.HostOpts{EventBus: bus})
+	require.NoError(t, err)
+	h.Start()
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+// socketDir returns a short directory for Unix sockets, t.TempDir can exceed the
+// maximum socket path length.
+func socketDir(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "p2pd")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func newServer(t *testing.T, h *bhost.BasicHost) *Server {
+	t.Helper()
+	s, err := NewServer(h, filepath.Join(socketDir(t), "control.sock"))
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+type client struct {
+	net.Conn
+	r pbio.ReadCloser
+	w pbio.WriteCloser
+}
+
+func dial(t *testing.T, s *Server) *client {
+	t.Helper()
+	c, err := net.Dial("unix", s.Addr().String())
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return &client{Conn: c, r: pbio.NewDelimitedReader(c, maxRequestSize), w: pbio.NewDelimitedWriter(c)}
+}
+
+func (c *client) request(t *testing.T, req *pb.Request) *pb.Response {
+	t.Helper()
+	require.NoError(t, c.w.WriteMsg(req))
+	var resp pb.Response
+	require.NoError(t, c.r.ReadMsg(&resp))
+	return &resp
+}
+
+func TestIdentifyAndConnect(t *testing.T) {
+	h1, h2 := newHost(t), newHost(t)
+	c := dial(t, newServer(t, h1))
+
+	resp := c.request(t, &pb.Request{Request: &pb.Request_Identify{Identify: &pb.IdentifyRequest{}}})
+	require.Equal(t, pb.Response_OK, resp.GetStatus())
+	id, err := peer.IDFromBytes(resp.GetIdentify().GetId())
+	require.NoError(t, err)
+	require.Equal(t, h1.ID(), id)
+	require.Len(t, resp.GetIdentify().GetAddrs(), len(h1.Addrs()))
+
+	resp = c.request(t, &pb.Request{Request: &pb.Request_Connect{Connect: &pb.ConnectRequest{
+		Peer:  []byte(h2.ID()),
+		Addrs: addrsToBytes(h2.Addrs()),
+	}}})
+	require.Equal(t, pb.Response_OK, resp.GetStatus(), resp.GetError())
+	require.Equal(t, network.Connected, h1.Network().Connectedness(h2.ID()))
+
+	resp = c.request(t, &pb.Request{Request: &pb.Request_ListPeers{ListPeers: &pb.ListPeersRequest{}}})
+	require.Len(t, resp.GetListPeers().GetPeers(), 1)
+	require.Equal(t, []byte(h2.ID()), resp.GetListPeers().GetPeers()[0].GetId())
+
+	resp = c.request(t, &pb.Request{Request: &pb.Request_Disconnect{Disconnect: &pb.DisconnectRequest{Peer: []byte(h2.ID())}}})
+	require.Equal(t, pb.Response_OK, resp.GetStatus(), resp.GetError())
+	require.Equal(t, network.NotConnected, h1.Network().Connectedness(h2.ID()))
+
+	resp = c.request(t, &pb.Request{Request: &pb.Request_Connect{Connect: &pb.ConnectRequest{Peer: []byte("invalid")}}})
+	require.Equal(t, pb.Response_ERROR, resp.GetStatus())
+	require.NotEmpty(t, resp.GetError())
+}
+
+func TestStreams(t *testing.T) {
+	h1, h2 := newHost(t), newHost(t)
+	require.NoError(t, h1.Connect(t.Context(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+
+	// The client of h2's server handles /echo on its own socket.
+	ln, err := net.Listen("unix", filepath.Join(socketDir(t), "handler.sock"))
+	require.NoError(t, err)
+	defer ln.Close()
+	infos := make(chan *pb.StreamInfo, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		var info pb.StreamInfo
+		if err := pbio.NewDelimitedReader(&byteReader{c}, maxRequestSize).ReadMsg(&info); err != nil {
+			return
+		}
+		infos <- &info
+		io.Copy(c, c)
+		c.(*net.UnixConn).CloseWrite()
+	}()
+
+	c2 := dial(t, newServer(t, h2))
+	resp := c2.request(t, &pb.Request{Request: &pb.Request_StreamHandler{StreamHandler: &pb.StreamHandlerRequest{
+		SocketPath: ln.Addr().String(),
+		Protocols:  []string{"/echo"},
+	}}})
+	require.Equal(t, pb.Response_OK, resp.GetStatus(), resp.GetError())
+
+	c1 := dial(t, newServer(t, h1))
+	require.NoError(t, c1.w.WriteMsg(&pb.Request{Request: &pb.Request_StreamOpen{StreamOpen: &pb.StreamOpenRequest{
+		Peer:      []byte(h2.ID()),
+		Protocols: []string{"/echo"},
+	}}}))
+	var r pb.Response
+	require.NoError(t, pbio.NewDelimitedReader(&byteReader{c1.Conn}, maxRequestSize).ReadMsg(&r))
+	require.Equal(t, pb.Response_OK, r.GetStatus(), r.GetError())
+	require.Equal(t, "/echo", r.GetStreamInfo().GetProtocol())
+	require.Equal(t, []byte(h2.ID()), r.GetStreamInfo().GetPeer())
+
+	select {
+	case info := <-infos:
+		require.Equal(t, []byte(h1.ID()), info.GetPeer())
+		require.Equal(t, "/echo", info.GetProtocol())
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream wasn't handled")
+	}
+
+	_, err = c1.Write([]byte("hello"))
+	require.NoError(t, err)
+	c1.Conn.(*net.UnixConn).CloseWrite()
+	b, err := io.ReadAll(c1.Conn)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+}
+
+func TestSubscribe(t *testing.T) {
+	h1, h2 := newHost(t), newHost(t)
+	c := dial(t, newServer(t, h1))
+	resp := c.request(t, &pb.Request{Request: &pb.Request_Subscribe{Subscribe: &pb.SubscribeRequest{}}})
+	require.Equal(t, pb.Response_OK, resp.GetStatus(), resp.GetError())
+
+	require.NoError(t, h1.Connect(t.Context(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	var connected, identified bool
+	for !connected || !identified {
+		var evt pb.Event
+		require.NoError(t, c.SetReadDeadline(time.Now().Add(5*time.Second)))
+		require.NoError(t, c.r.ReadMsg(&evt))
+		if string(evt.GetPeer()) != string(h2.ID()) {
+			continue
+		}
+		switch evt.GetType() {
+		case pb.Event_PEER_CONNECTED:
+			connected = true
+		case pb.Event_PEER_IDENTIFIED:
+			identified = true
+			require.NotEmpty(t, evt.GetProtocols())
+		}
+	}
+}
+
+func TestSubscribeClientClose(t *testing.T) {
+	s := newServer(t, newHost(t))
+	c := dial(t, s)
+	resp := c.request(t, &pb.Request{Request: &pb.Request_Subscribe{Subscribe: &pb.SubscribeRequest{}}})
+	require.Equal(t, pb.Response_OK, resp.GetStatus(), resp.GetError())
+
+	// the subscription ends when the client goes away, even without any events
+	c.Close()
+	require.Eventually(t, func() bool {
+		s.mx.Lock()
+		defer s.mx.Unlock()
+		return len(s.conns) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSocketPermissions(t *testing.T) {
+	h := newHost(t)
+	path := filepath.Join(socketDir(t), "control.sock")
+	s, err := NewServer(h, path)
+	require.NoError(t, err)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+	// the private directory the socket was created in is removed
+	entries, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	// an existing socket isn't replaced
+	_, err = NewServer(h, path)
+	require.Error(t, err)
+
+	require.NoError(t, s.Close())
+	_, err = os.Stat(path)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+// byteReader reads one byte at a time, so that reading a message doesn't consume the
+// data following it.
+type byteReader struct{ r io.Reader }
+
+func (b *byteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return b.r.Read(p)
+}
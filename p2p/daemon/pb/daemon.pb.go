@@ -0,0 +1,1236 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.2
+// source: p2p/daemon/pb/daemon.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Response_Status int32
+
+const (
+	Response_OK    Response_Status = 0
+	Response_ERROR Response_Status = 1
+)
+
+// Enum value maps for Response_Status.
+var (
+	Response_Status_name = map[int32]string{
+		0: "OK",
+		1: "ERROR",
+	}
+	Response_Status_value = map[string]int32{
+		"OK":    0,
+		"ERROR": 1,
+	}
+)
+
+func (x Response_Status) Enum() *Response_Status {
+	p := new(Response_Status)
+	*p = x
+	return p
+}
+
+func (x Response_Status) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Response_Status) Descriptor() protoreflect.EnumDescriptor {
+	return file_p2p_daemon_pb_daemon_proto_enumTypes[0].Descriptor()
+}
+
+func (Response_Status) Type() protoreflect.EnumType {
+	return &file_p2p_daemon_pb_daemon_proto_enumTypes[0]
+}
+
+func (x Response_Status) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Response_Status.Descriptor instead.
+func (Response_Status) EnumDescriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{1, 0}
+}
+
+type Event_Type int32
+
+const (
+	Event_PEER_CONNECTED      Event_Type = 0
+	Event_PEER_DISCONNECTED   Event_Type = 1
+	Event_PEER_IDENTIFIED     Event_Type = 2
+	Event_LOCAL_ADDRS_UPDATED Event_Type = 3
+)
+
+// Enum value maps for Event_Type.
+var (
+	Event_Type_name = map[int32]string{
+		0: "PEER_CONNECTED",
+		1: "PEER_DISCONNECTED",
+		2: "PEER_IDENTIFIED",
+		3: "LOCAL_ADDRS_UPDATED",
+	}
+	Event_Type_value = map[string]int32{
+		"PEER_CONNECTED":      0,
+		"PEER_DISCONNECTED":   1,
+		"PEER_IDENTIFIED":     2,
+		"LOCAL_ADDRS_UPDATED": 3,
+	}
+)
+
+func (x Event_Type) Enum() *Event_Type {
+	p := new(Event_Type)
+	*p = x
+	return p
+}
+
+func (x Event_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Event_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_p2p_daemon_pb_daemon_proto_enumTypes[1].Descriptor()
+}
+
+func (Event_Type) Type() protoreflect.EnumType {
+	return &file_p2p_daemon_pb_daemon_proto_enumTypes[1]
+}
+
+func (x Event_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Event_Type.Descriptor instead.
+func (Event_Type) EnumDescriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{14, 0}
+}
+
+type Request struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Request:
+	//
+	//	*Request_Identify
+	//	*Request_Connect
+	//	*Request_Disconnect
+	//	*Request_ListPeers
+	//	*Request_StreamOpen
+	//	*Request_StreamHandler
+	//	*Request_RemoveStreamHandler
+	//	*Request_Subscribe
+	Request       isRequest_Request `protobuf_oneof:"request"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Request) Reset() {
+	*x = Request{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Request) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request) ProtoMessage() {}
+
+func (x *Request) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request.ProtoReflect.Descriptor instead.
+func (*Request) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Request) GetRequest() isRequest_Request {
+	if x != nil {
+		return x.Request
+	}
+	return nil
+}
+
+func (x *Request) GetIdentify() *IdentifyRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_Identify); ok {
+			return x.Identify
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetConnect() *ConnectRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_Connect); ok {
+			return x.Connect
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetDisconnect() *DisconnectRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_Disconnect); ok {
+			return x.Disconnect
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetListPeers() *ListPeersRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_ListPeers); ok {
+			return x.ListPeers
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetStreamOpen() *StreamOpenRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_StreamOpen); ok {
+			return x.StreamOpen
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetStreamHandler() *StreamHandlerRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_StreamHandler); ok {
+			return x.StreamHandler
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetRemoveStreamHandler() *RemoveStreamHandlerRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_RemoveStreamHandler); ok {
+			return x.RemoveStreamHandler
+		}
+	}
+	return nil
+}
+
+func (x *Request) GetSubscribe() *SubscribeRequest {
+	if x != nil {
+		if x, ok := x.Request.(*Request_Subscribe); ok {
+			return x.Subscribe
+		}
+	}
+	return nil
+}
+
+type isRequest_Request interface {
+	isRequest_Request()
+}
+
+type Request_Identify struct {
+	Identify *IdentifyRequest `protobuf:"bytes,1,opt,name=identify,proto3,oneof"`
+}
+
+type Request_Connect struct {
+	Connect *ConnectRequest `protobuf:"bytes,2,opt,name=connect,proto3,oneof"`
+}
+
+type Request_Disconnect struct {
+	Disconnect *DisconnectRequest `protobuf:"bytes,3,opt,name=disconnect,proto3,oneof"`
+}
+
+type Request_ListPeers struct {
+	ListPeers *ListPeersRequest `protobuf:"bytes,4,opt,name=listPeers,proto3,oneof"`
+}
+
+type Request_StreamOpen struct {
+	StreamOpen *StreamOpenRequest `protobuf:"bytes,5,opt,name=streamOpen,proto3,oneof"`
+}
+
+type Request_StreamHandler struct {
+	StreamHandler *StreamHandlerRequest `protobuf:"bytes,6,opt,name=streamHandler,proto3,oneof"`
+}
+
+type Request_RemoveStreamHandler struct {
+	RemoveStreamHandler *RemoveStreamHandlerRequest `protobuf:"bytes,7,opt,name=removeStreamHandler,proto3,oneof"`
+}
+
+type Request_Subscribe struct {
+	Subscribe *SubscribeRequest `protobuf:"bytes,8,opt,name=subscribe,proto3,oneof"`
+}
+
+func (*Request_Identify) isRequest_Request() {}
+
+func (*Request_Connect) isRequest_Request() {}
+
+func (*Request_Disconnect) isRequest_Request() {}
+
+func (*Request_ListPeers) isRequest_Request() {}
+
+func (*Request_StreamOpen) isRequest_Request() {}
+
+func (*Request_StreamHandler) isRequest_Request() {}
+
+func (*Request_RemoveStreamHandler) isRequest_Request() {}
+
+func (*Request_Subscribe) isRequest_Request() {}
+
+type Response struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Status Response_Status        `protobuf:"varint,1,opt,name=status,proto3,enum=daemon.pb.Response_Status" json:"status,omitempty"`
+	Error  string                 `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	// Types that are valid to be assigned to Response:
+	//
+	//	*Response_Identify
+	//	*Response_ListPeers
+	//	*Response_StreamInfo
+	Response      isResponse_Response `protobuf_oneof:"response"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Response) Reset() {
+	*x = Response{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Response) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response) ProtoMessage() {}
+
+func (x *Response) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response.ProtoReflect.Descriptor instead.
+func (*Response) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Response) GetStatus() Response_Status {
+	if x != nil {
+		return x.Status
+	}
+	return Response_OK
+}
+
+func (x *Response) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Response) GetResponse() isResponse_Response {
+	if x != nil {
+		return x.Response
+	}
+	return nil
+}
+
+func (x *Response) GetIdentify() *IdentifyResponse {
+	if x != nil {
+		if x, ok := x.Response.(*Response_Identify); ok {
+			return x.Identify
+		}
+	}
+	return nil
+}
+
+func (x *Response) GetListPeers() *ListPeersResponse {
+	if x != nil {
+		if x, ok := x.Response.(*Response_ListPeers); ok {
+			return x.ListPeers
+		}
+	}
+	return nil
+}
+
+func (x *Response) GetStreamInfo() *StreamInfo {
+	if x != nil {
+		if x, ok := x.Response.(*Response_StreamInfo); ok {
+			return x.StreamInfo
+		}
+	}
+	return nil
+}
+
+type isResponse_Response interface {
+	isResponse_Response()
+}
+
+type Response_Identify struct {
+	Identify *IdentifyResponse `protobuf:"bytes,3,opt,name=identify,proto3,oneof"`
+}
+
+type Response_ListPeers struct {
+	ListPeers *ListPeersResponse `protobuf:"bytes,4,opt,name=listPeers,proto3,oneof"`
+}
+
+type Response_StreamInfo struct {
+	StreamInfo *StreamInfo `protobuf:"bytes,5,opt,name=streamInfo,proto3,oneof"`
+}
+
+func (*Response_Identify) isResponse_Response() {}
+
+func (*Response_ListPeers) isResponse_Response() {}
+
+func (*Response_StreamInfo) isResponse_Response() {}
+
+type IdentifyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IdentifyRequest) Reset() {
+	*x = IdentifyRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IdentifyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IdentifyRequest) ProtoMessage() {}
+
+func (x *IdentifyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IdentifyRequest.ProtoReflect.Descriptor instead.
+func (*IdentifyRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{2}
+}
+
+type IdentifyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addrs         [][]byte               `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *IdentifyResponse) Reset() {
+	*x = IdentifyResponse{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IdentifyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IdentifyResponse) ProtoMessage() {}
+
+func (x *IdentifyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IdentifyResponse.ProtoReflect.Descriptor instead.
+func (*IdentifyResponse) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *IdentifyResponse) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *IdentifyResponse) GetAddrs() [][]byte {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+type ConnectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peer          []byte                 `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	Addrs         [][]byte               `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	TimeoutMillis int64                  `protobuf:"varint,3,opt,name=timeoutMillis,proto3" json:"timeoutMillis,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConnectRequest) Reset() {
+	*x = ConnectRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConnectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConnectRequest) ProtoMessage() {}
+
+func (x *ConnectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConnectRequest.ProtoReflect.Descriptor instead.
+func (*ConnectRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ConnectRequest) GetPeer() []byte {
+	if x != nil {
+		return x.Peer
+	}
+	return nil
+}
+
+func (x *ConnectRequest) GetAddrs() [][]byte {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *ConnectRequest) GetTimeoutMillis() int64 {
+	if x != nil {
+		return x.TimeoutMillis
+	}
+	return 0
+}
+
+type DisconnectRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peer          []byte                 `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DisconnectRequest) Reset() {
+	*x = DisconnectRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DisconnectRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DisconnectRequest) ProtoMessage() {}
+
+func (x *DisconnectRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DisconnectRequest.ProtoReflect.Descriptor instead.
+func (*DisconnectRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *DisconnectRequest) GetPeer() []byte {
+	if x != nil {
+		return x.Peer
+	}
+	return nil
+}
+
+type ListPeersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeersRequest) Reset() {
+	*x = ListPeersRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeersRequest) ProtoMessage() {}
+
+func (x *ListPeersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeersRequest.ProtoReflect.Descriptor instead.
+func (*ListPeersRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{6}
+}
+
+type PeerInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            []byte                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Addrs         [][]byte               `protobuf:"bytes,2,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerInfo) Reset() {
+	*x = PeerInfo{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerInfo) ProtoMessage() {}
+
+func (x *PeerInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerInfo.ProtoReflect.Descriptor instead.
+func (*PeerInfo) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *PeerInfo) GetId() []byte {
+	if x != nil {
+		return x.Id
+	}
+	return nil
+}
+
+func (x *PeerInfo) GetAddrs() [][]byte {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+type ListPeersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peers         []*PeerInfo            `protobuf:"bytes,1,rep,name=peers,proto3" json:"peers,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListPeersResponse) Reset() {
+	*x = ListPeersResponse{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListPeersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListPeersResponse) ProtoMessage() {}
+
+func (x *ListPeersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListPeersResponse.ProtoReflect.Descriptor instead.
+func (*ListPeersResponse) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListPeersResponse) GetPeers() []*PeerInfo {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type StreamOpenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peer          []byte                 `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	Protocols     []string               `protobuf:"bytes,2,rep,name=protocols,proto3" json:"protocols,omitempty"`
+	TimeoutMillis int64                  `protobuf:"varint,3,opt,name=timeoutMillis,proto3" json:"timeoutMillis,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamOpenRequest) Reset() {
+	*x = StreamOpenRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamOpenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamOpenRequest) ProtoMessage() {}
+
+func (x *StreamOpenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamOpenRequest.ProtoReflect.Descriptor instead.
+func (*StreamOpenRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *StreamOpenRequest) GetPeer() []byte {
+	if x != nil {
+		return x.Peer
+	}
+	return nil
+}
+
+func (x *StreamOpenRequest) GetProtocols() []string {
+	if x != nil {
+		return x.Protocols
+	}
+	return nil
+}
+
+func (x *StreamOpenRequest) GetTimeoutMillis() int64 {
+	if x != nil {
+		return x.TimeoutMillis
+	}
+	return 0
+}
+
+type StreamHandlerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SocketPath    string                 `protobuf:"bytes,1,opt,name=socketPath,proto3" json:"socketPath,omitempty"`
+	Protocols     []string               `protobuf:"bytes,2,rep,name=protocols,proto3" json:"protocols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamHandlerRequest) Reset() {
+	*x = StreamHandlerRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamHandlerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamHandlerRequest) ProtoMessage() {}
+
+func (x *StreamHandlerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamHandlerRequest.ProtoReflect.Descriptor instead.
+func (*StreamHandlerRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *StreamHandlerRequest) GetSocketPath() string {
+	if x != nil {
+		return x.SocketPath
+	}
+	return ""
+}
+
+func (x *StreamHandlerRequest) GetProtocols() []string {
+	if x != nil {
+		return x.Protocols
+	}
+	return nil
+}
+
+type RemoveStreamHandlerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Protocols     []string               `protobuf:"bytes,1,rep,name=protocols,proto3" json:"protocols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveStreamHandlerRequest) Reset() {
+	*x = RemoveStreamHandlerRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveStreamHandlerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveStreamHandlerRequest) ProtoMessage() {}
+
+func (x *RemoveStreamHandlerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveStreamHandlerRequest.ProtoReflect.Descriptor instead.
+func (*RemoveStreamHandlerRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RemoveStreamHandlerRequest) GetProtocols() []string {
+	if x != nil {
+		return x.Protocols
+	}
+	return nil
+}
+
+type StreamInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Peer          []byte                 `protobuf:"bytes,1,opt,name=peer,proto3" json:"peer,omitempty"`
+	Addr          []byte                 `protobuf:"bytes,2,opt,name=addr,proto3" json:"addr,omitempty"`
+	Protocol      string                 `protobuf:"bytes,3,opt,name=protocol,proto3" json:"protocol,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamInfo) Reset() {
+	*x = StreamInfo{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamInfo) ProtoMessage() {}
+
+func (x *StreamInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamInfo.ProtoReflect.Descriptor instead.
+func (*StreamInfo) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StreamInfo) GetPeer() []byte {
+	if x != nil {
+		return x.Peer
+	}
+	return nil
+}
+
+func (x *StreamInfo) GetAddr() []byte {
+	if x != nil {
+		return x.Addr
+	}
+	return nil
+}
+
+func (x *StreamInfo) GetProtocol() string {
+	if x != nil {
+		return x.Protocol
+	}
+	return ""
+}
+
+type SubscribeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SubscribeRequest) Reset() {
+	*x = SubscribeRequest{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SubscribeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeRequest) ProtoMessage() {}
+
+func (x *SubscribeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeRequest) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{13}
+}
+
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          Event_Type             `protobuf:"varint,1,opt,name=type,proto3,enum=daemon.pb.Event_Type" json:"type,omitempty"`
+	Peer          []byte                 `protobuf:"bytes,2,opt,name=peer,proto3" json:"peer,omitempty"`
+	Addrs         [][]byte               `protobuf:"bytes,3,rep,name=addrs,proto3" json:"addrs,omitempty"`
+	Protocols     []string               `protobuf:"bytes,4,rep,name=protocols,proto3" json:"protocols,omitempty"`
+	Limited       bool                   `protobuf:"varint,5,opt,name=limited,proto3" json:"limited,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_p2p_daemon_pb_daemon_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_p2p_daemon_pb_daemon_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Event) GetType() Event_Type {
+	if x != nil {
+		return x.Type
+	}
+	return Event_PEER_CONNECTED
+}
+
+func (x *Event) GetPeer() []byte {
+	if x != nil {
+		return x.Peer
+	}
+	return nil
+}
+
+func (x *Event) GetAddrs() [][]byte {
+	if x != nil {
+		return x.Addrs
+	}
+	return nil
+}
+
+func (x *Event) GetProtocols() []string {
+	if x != nil {
+		return x.Protocols
+	}
+	return nil
+}
+
+func (x *Event) GetLimited() bool {
+	if x != nil {
+		return x.Limited
+	}
+	return false
+}
+
+var File_p2p_daemon_pb_daemon_proto protoreflect.FileDescriptor
+
+const file_p2p_daemon_pb_daemon_proto_rawDesc = "" +
+	"\n" +
+	"\x1ap2p/daemon/pb/daemon.proto\x12\tdaemon.pb\"\xa3\x04\n" +
+	"\aRequest\x128\n" +
+	"\bidentify\x18\x01 \x01(\v2\x1a.daemon.pb.IdentifyRequestH\x00R\bidentify\x125\n" +
+	"\aconnect\x18\x02 \x01(\v2\x19.daemon.pb.ConnectRequestH\x00R\aconnect\x12>\n" +
+	"\n" +
+	"disconnect\x18\x03 \x01(\v2\x1c.daemon.pb.DisconnectRequestH\x00R\n" +
+	"disconnect\x12;\n" +
+	"\tlistPeers\x18\x04 \x01(\v2\x1b.daemon.pb.ListPeersRequestH\x00R\tlistPeers\x12>\n" +
+	"\n" +
+	"streamOpen\x18\x05 \x01(\v2\x1c.daemon.pb.StreamOpenRequestH\x00R\n" +
+	"streamOpen\x12G\n" +
+	"\rstreamHandler\x18\x06 \x01(\v2\x1f.daemon.pb.StreamHandlerRequestH\x00R\rstreamHandler\x12Y\n" +
+	"\x13removeStreamHandler\x18\a \x01(\v2%.daemon.pb.RemoveStreamHandlerRequestH\x00R\x13removeStreamHandler\x12;\n" +
+	"\tsubscribe\x18\b \x01(\v2\x1b.daemon.pb.SubscribeRequestH\x00R\tsubscribeB\t\n" +
+	"\arequest\"\xaf\x02\n" +
+	"\bResponse\x122\n" +
+	"\x06status\x18\x01 \x01(\x0e2\x1a.daemon.pb.Response.StatusR\x06status\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x129\n" +
+	"\bidentify\x18\x03 \x01(\v2\x1b.daemon.pb.IdentifyResponseH\x00R\bidentify\x12<\n" +
+	"\tlistPeers\x18\x04 \x01(\v2\x1c.daemon.pb.ListPeersResponseH\x00R\tlistPeers\x127\n" +
+	"\n" +
+	"streamInfo\x18\x05 \x01(\v2\x15.daemon.pb.StreamInfoH\x00R\n" +
+	"streamInfo\"\x1b\n" +
+	"\x06Status\x12\x06\n" +
+	"\x02OK\x10\x00\x12\t\n" +
+	"\x05ERROR\x10\x01B\n" +
+	"\n" +
+	"\bresponse\"\x11\n" +
+	"\x0fIdentifyRequest\"8\n" +
+	"\x10IdentifyResponse\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x14\n" +
+	"\x05addrs\x18\x02 \x03(\fR\x05addrs\"`\n" +
+	"\x0eConnectRequest\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\fR\x04peer\x12\x14\n" +
+	"\x05addrs\x18\x02 \x03(\fR\x05addrs\x12$\n" +
+	"\rtimeoutMillis\x18\x03 \x01(\x03R\rtimeoutMillis\"'\n" +
+	"\x11DisconnectRequest\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\fR\x04peer\"\x12\n" +
+	"\x10ListPeersRequest\"0\n" +
+	"\bPeerInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\fR\x02id\x12\x14\n" +
+	"\x05addrs\x18\x02 \x03(\fR\x05addrs\">\n" +
+	"\x11ListPeersResponse\x12)\n" +
+	"\x05peers\x18\x01 \x03(\v2\x13.daemon.pb.PeerInfoR\x05peers\"k\n" +
+	"\x11StreamOpenRequest\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\fR\x04peer\x12\x1c\n" +
+	"\tprotocols\x18\x02 \x03(\tR\tprotocols\x12$\n" +
+	"\rtimeoutMillis\x18\x03 \x01(\x03R\rtimeoutMillis\"T\n" +
+	"\x14StreamHandlerRequest\x12\x1e\n" +
+	"\n" +
+	"socketPath\x18\x01 \x01(\tR\n" +
+	"socketPath\x12\x1c\n" +
+	"\tprotocols\x18\x02 \x03(\tR\tprotocols\":\n" +
+	"\x1aRemoveStreamHandlerRequest\x12\x1c\n" +
+	"\tprotocols\x18\x01 \x03(\tR\tprotocols\"P\n" +
+	"\n" +
+	"StreamInfo\x12\x12\n" +
+	"\x04peer\x18\x01 \x01(\fR\x04peer\x12\x12\n" +
+	"\x04addr\x18\x02 \x01(\fR\x04addr\x12\x1a\n" +
+	"\bprotocol\x18\x03 \x01(\tR\bprotocol\"\x12\n" +
+	"\x10SubscribeRequest\"\xf5\x01\n" +
+	"\x05Event\x12)\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x15.daemon.pb.Event.TypeR\x04type\x12\x12\n" +
+	"\x04peer\x18\x02 \x01(\fR\x04peer\x12\x14\n" +
+	"\x05addrs\x18\x03 \x03(\fR\x05addrs\x12\x1c\n" +
+	"\tprotocols\x18\x04 \x03(\tR\tprotocols\x12\x18\n" +
+	"\alimited\x18\x05 \x01(\bR\alimited\"_\n" +
+	"\x04Type\x12\x12\n" +
+	"\x0ePEER_CONNECTED\x10\x00\x12\x15\n" +
+	"\x11PEER_DISCONNECTED\x10\x01\x12\x13\n" +
+	"\x0fPEER_IDENTIFIED\x10\x02\x12\x17\n" +
+	"\x13LOCAL_ADDRS_UPDATED\x10\x03B+Z)github.com/libp2p/go-libp2p/p2p/daemon/pbb\x06proto3"
+
+var (
+	file_p2p_daemon_pb_daemon_proto_rawDescOnce sync.Once
+	file_p2p_daemon_pb_daemon_proto_rawDescData []byte
+)
+
+func file_p2p_daemon_pb_daemon_proto_rawDescGZIP() []byte {
+	file_p2p_daemon_pb_daemon_proto_rawDescOnce.Do(func() {
+		file_p2p_daemon_pb_daemon_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_p2p_daemon_pb_daemon_proto_rawDesc), len(file_p2p_daemon_pb_daemon_proto_rawDesc)))
+	})
+	return file_p2p_daemon_pb_daemon_proto_rawDescData
+}
+
+var file_p2p_daemon_pb_daemon_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
+var file_p2p_daemon_pb_daemon_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_p2p_daemon_pb_daemon_proto_goTypes = []any{
+	(Response_Status)(0),               // 0: daemon.pb.Response.Status
+	(Event_Type)(0),                    // 1: daemon.pb.Event.Type
+	(*Request)(nil),                    // 2: daemon.pb.Request
+	(*Response)(nil),                   // 3: daemon.pb.Response
+	(*IdentifyRequest)(nil),            // 4: daemon.pb.IdentifyRequest
+	(*IdentifyResponse)(nil),           // 5: daemon.pb.IdentifyResponse
+	(*ConnectRequest)(nil),             // 6: daemon.pb.ConnectRequest
+	(*DisconnectRequest)(nil),          // 7: daemon.pb.DisconnectRequest
+	(*ListPeersRequest)(nil),           // 8: daemon.pb.ListPeersRequest
+	(*PeerInfo)(nil),                   // 9: daemon.pb.PeerInfo
+	(*ListPeersResponse)(nil),          // 10: daemon.pb.ListPeersResponse
+	(*StreamOpenRequest)(nil),          // 11: daemon.pb.StreamOpenRequest
+	(*StreamHandlerRequest)(nil),       // 12: daemon.pb.StreamHandlerRequest
+	(*RemoveStreamHandlerRequest)(nil), // 13: daemon.pb.RemoveStreamHandlerRequest
+	(*StreamInfo)(nil),                 // 14: daemon.pb.StreamInfo
+	(*SubscribeRequest)(nil),           // 15: daemon.pb.SubscribeRequest
+	(*Event)(nil),                      // 16: daemon.pb.Event
+}
+var file_p2p_daemon_pb_daemon_proto_depIdxs = []int32{
+	4,  // 0: daemon.pb.Request.identify:type_name -> daemon.pb.IdentifyRequest
+	6,  // 1: daemon.pb.Request.connect:type_name -> daemon.pb.ConnectRequest
+	7,  // 2: daemon.pb.Request.disconnect:type_name -> daemon.pb.DisconnectRequest
+	8,  // 3: daemon.pb.Request.listPeers:type_name -> daemon.pb.ListPeersRequest
+	11, // 4: daemon.pb.Request.streamOpen:type_name -> daemon.pb.StreamOpenRequest
+	12, // 5: daemon.pb.Request.streamHandler:type_name -> daemon.pb.StreamHandlerRequest
+	13, // 6: daemon.pb.Request.removeStreamHandler:type_name -> daemon.pb.RemoveStreamHandlerRequest
+	15, // 7: daemon.pb.Request.subscribe:type_name -> daemon.pb.SubscribeRequest
+	0,  // 8: daemon.pb.Response.status:type_name -> daemon.pb.Response.Status
+	5,  // 9: daemon.pb.Response.identify:type_name -> daemon.pb.IdentifyResponse
+	10, // 10: daemon.pb.Response.listPeers:type_name -> daemon.pb.ListPeersResponse
+	14, // 11: daemon.pb.Response.streamInfo:type_name -> daemon.pb.StreamInfo
+	9,  // 12: daemon.pb.ListPeersResponse.peers:type_name -> daemon.pb.PeerInfo
+	1,  // 13: daemon.pb.Event.type:type_name -> daemon.pb.Event.Type
+	14, // [14:14] is the sub-list for method output_type
+	14, // [14:14] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_p2p_daemon_pb_daemon_proto_init() }
+func file_p2p_daemon_pb_daemon_proto_init() {
+	if File_p2p_daemon_pb_daemon_proto != nil {
+		return
+	}
+	file_p2p_daemon_pb_daemon_proto_msgTypes[0].OneofWrappers = []any{
+		(*Request_Identify)(nil),
+		(*Request_Connect)(nil),
+		(*Request_Disconnect)(nil),
+		(*Request_ListPeers)(nil),
+		(*Request_StreamOpen)(nil),
+		(*Request_StreamHandler)(nil),
+		(*Request_RemoveStreamHandler)(nil),
+		(*Request_Subscribe)(nil),
+	}
+	file_p2p_daemon_pb_daemon_proto_msgTypes[1].OneofWrappers = []any{
+		(*Response_Identify)(nil),
+		(*Response_ListPeers)(nil),
+		(*Response_StreamInfo)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_p2p_daemon_pb_daemon_proto_rawDesc), len(file_p2p_daemon_pb_daemon_proto_rawDesc)),
+			NumEnums:      2,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_p2p_daemon_pb_daemon_proto_goTypes,
+		DependencyIndexes: file_p2p_daemon_pb_daemon_proto_depIdxs,
+		EnumInfos:         file_p2p_daemon_pb_daemon_proto_enumTypes,
+		MessageInfos:      file_p2p_daemon_pb_daemon_proto_msgTypes,
+	}.Build()
+	File_p2p_daemon_pb_daemon_proto = out.File
+	file_p2p_daemon_pb_daemon_proto_goTypes = nil
+	file_p2p_daemon_pb_daemon_proto_depIdxs = nil
+}
@@ -17,25 +17,43 @@ type BackoffConnector struct {
 	host       host.Host
 	connTryDur time.Duration
 	backoff    BackoffFactory
+	registry   *Registry
 	mux        sync.Mutex
 }
 
+type BackoffConnectorOption func(*BackoffConnector) error
+
+// WithBackoffConnectorRegistry makes the BackoffConnector skip the peers backed off by
+// r, and record the failed connection attempts in r.
+func WithBackoffConnectorRegistry(r *Registry) BackoffConnectorOption {
+	return func(c *BackoffConnector) error {
+		c.registry = r
+		return nil
+	}
+}
+
 // NewBackoffConnector creates a utility to connect to peers, but only if we have not recently tried connecting to them already
 // cacheSize is the size of a TwoQueueCache
 // connectionTryDuration is how long we attempt to connect to a peer before giving up
 // backoff describes the strategy used to decide how long to backoff after previously attempting to connect to a peer
-func NewBackoffConnector(h host.Host, cacheSize int, connectionTryDuration time.Duration, backoff BackoffFactory) (*BackoffConnector, error) {
+func NewBackoffConnector(h host.Host, cacheSize int, connectionTryDuration time.Duration, backoff BackoffFactory, opts ...BackoffConnectorOption) (*BackoffConnector, error) {
 	cache, err := lru.New2Q[peer.ID, *connCacheData](cacheSize)
 	if err != nil {
 		return nil, err
 	}
 
-	return &BackoffConnector{
+	c := &BackoffConnector{
 		cache:      cache,
 		host:       h,
 		connTryDur: connectionTryDuration,
 		backoff:    backoff,
-	}, nil
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
 }
 
 type connCacheData struct {
@@ -58,6 +76,10 @@ func (c *BackoffConnector) Connect(ctx context.Context, peerCh <-chan peer.AddrI
 				continue
 			}
 
+			if c.registry != nil && c.registry.PeerBackoff(pi.ID) {
+				continue
+			}
+
 			c.mux.Lock()
 			var cachedPeer *connCacheData
 			if tv, ok := c.cache.Get(pi.ID); ok {
@@ -82,8 +104,14 @@ func (c *BackoffConnector) Connect(ctx context.Context, peerCh <-chan peer.AddrI
 				err := c.host.Connect(ctx, pi)
 				if err != nil {
 					log.Debugf("Error connecting to pubsub peer %s: %s", pi.ID, err.Error())
+					if c.registry != nil {
+						c.registry.AddPeerBackoff(pi.ID)
+					}
 					return
 				}
+				if c.registry != nil {
+					c.registry.Clear(pi.ID)
+				}
 			}(pi)
 
 		case <-ctx.Done():
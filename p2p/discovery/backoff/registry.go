@@ -0,0 +1,289 @@
+package backoff
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// DefaultRegistryMinBackoff is the default delay of the first backoff of a
+	// Registry.
+	DefaultRegistryMinBackoff = 5 * time.Second
+	// DefaultRegistryMaxBackoff is the default cap of the backoffs of a Registry.
+	DefaultRegistryMaxBackoff = 5 * time.Minute
+)
+
+// RegistryOption configures a Registry.
+type RegistryOption func(*Registry) error
+
+// WithPeerBackoff sets the strategy used to back off from peers, once a connection
+// attempt to them failed. By default, the backoff doubles after every failure, from
+// the minimum to the maximum backoff, with full jitter.
+func WithPeerBackoff(f BackoffFactory) RegistryOption {
+	return func(r *Registry) error {
+		r.peerBackoff = f
+		return nil
+	}
+}
+
+// WithAddrBackoff sets the strategy used to back off from addresses, once a dial to
+// them failed. By default, the backoff doubles after every failure, from the minimum
+// to the maximum backoff, with full jitter.
+func WithAddrBackoff(f BackoffFactory) RegistryOption {
+	return func(r *Registry) error {
+		r.addrBackoff = f
+		return nil
+	}
+}
+
+// WithBackoffBounds sets the minimum and maximum backoffs. The maximum caps the
+// backoffs of all strategies, including the ones set with WithPeerBackoff and
+// WithAddrBackoff, while the minimum only applies to the default strategies. It also
+// sets how long entries are kept around once their backoff expired.
+func WithBackoffBounds(min, max time.Duration) RegistryOption {
+	return func(r *Registry) error {
+		if min <= 0 || max < min {
+			return errors.New("invalid backoff bounds: must have 0 < min <= max")
+		}
+		r.min, r.max = min, max
+		return nil
+	}
+}
+
+// WithBackoffJitter sets the jitter of the default strategies. The default is
+// FullJitter.
+func WithBackoffJitter(j Jitter) RegistryOption {
+	return func(r *Registry) error {
+		r.jitter = j
+		return nil
+	}
+}
+
+// Registry tracks the failed connection attempts to peers, and the failed dials to
+// their addresses, and backs off from them accordingly. It's meant to be shared by
+// all the components dialing peers, so that they back off consistently: the swarm
+// (see swarm.WithDialBackoff), the BackoffConnector (see
+// WithBackoffConnectorRegistry) and AutoRelay (see autorelay.WithBackoffRegistry).
+//
+// A successful connection to a peer clears the backoffs of the peer and of all its
+// addresses. Entries are forgotten once their backoff expired for as long as the
+// maximum backoff.
+//
+// It's safe for concurrent use.
+type Registry struct {
+	min, max    time.Duration
+	jitter      Jitter
+	peerBackoff BackoffFactory
+	addrBackoff BackoffFactory
+	now         func() time.Time
+
+	mx     sync.Mutex
+	peers  map[peer.ID]*peerEntry
+	lastGC time.Time
+}
+
+type entry struct {
+	strat       BackoffStrategy
+	failures    int
+	lastFailure time.Time
+	until       time.Time
+}
+
+type peerEntry struct {
+	entry
+	addrs map[string]*addrEntry
+}
+
+type addrEntry struct {
+	entry
+	addr ma.Multiaddr
+}
+
+// NewRegistry creates a Registry.
+func NewRegistry(opts ...RegistryOption) (*Registry, error) {
+	r := &Registry{
+		min:    DefaultRegistryMinBackoff,
+		max:    DefaultRegistryMaxBackoff,
+		jitter: FullJitter,
+		now:    time.Now,
+		peers:  make(map[peer.ID]*peerEntry),
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if r.peerBackoff == nil {
+		r.peerBackoff = NewExponentialBackoff(r.min, r.max, r.jitter, r.min, 2, 0, rand.NewSource(time.Now().UnixNano()))
+	}
+	if r.addrBackoff == nil {
+		r.addrBackoff = NewExponentialBackoff(r.min, r.max, r.jitter, r.min, 2, 0, rand.NewSource(time.Now().UnixNano()))
+	}
+	r.lastGC = r.now()
+	return r, nil
+}
+
+// fail records a failure on e, and returns the backoff.
+func (r *Registry) fail(e *entry, f BackoffFactory, now time.Time) time.Duration {
+	if e.strat == nil {
+		e.strat = f()
+	}
+	d := min(e.strat.Delay(), r.max)
+	e.failures++
+	e.lastFailure = now
+	e.until = now.Add(d)
+	return d
+}
+
+func (r *Registry) peer(p peer.ID) *peerEntry {
+	pe, ok := r.peers[p]
+	if !ok {
+		pe = &peerEntry{}
+		r.peers[p] = pe
+	}
+	return pe
+}
+
+// AddPeerBackoff records a failed connection attempt to p, and returns how long p
+// is backed off.
+func (r *Registry) AddPeerBackoff(p peer.ID) time.Duration {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	now := r.now()
+	r.maybeGC(now)
+	pe := r.peer(p)
+	return r.fail(&pe.entry, r.peerBackoff, now)
+}
+
+// AddBackoff records a failed dial to addr of p.
+func (r *Registry) AddBackoff(p peer.ID, addr ma.Multiaddr) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	now := r.now()
+	r.maybeGC(now)
+	pe := r.peer(p)
+	if pe.addrs == nil {
+		pe.addrs = make(map[string]*addrEntry, 1)
+	}
+	k := string(addr.Bytes())
+	ae, ok := pe.addrs[k]
+	if !ok {
+		ae = &addrEntry{addr: addr}
+		pe.addrs[k] = ae
+	}
+	r.fail(&ae.entry, r.addrBackoff, now)
+}
+
+// PeerBackoff returns whether connecting to p should be backed off from.
+func (r *Registry) PeerBackoff(p peer.ID) bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	pe, ok := r.peers[p]
+	return ok && r.now().Before(pe.until)
+}
+
+// Backoff returns whether dialing addr of p should be backed off from.
+func (r *Registry) Backoff(p peer.ID, addr ma.Multiaddr) bool {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	pe, ok := r.peers[p]
+	if !ok {
+		return false
+	}
+	ae, ok := pe.addrs[string(addr.Bytes())]
+	return ok && r.now().Before(ae.until)
+}
+
+// Clear clears the backoffs of p and of all its addresses. It should be called once
+// a connection to p is established.
+func (r *Registry) Clear(p peer.ID) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	delete(r.peers, p)
+}
+
+// maybeGC forgets the entries whose backoff expired for as long as the maximum
+// backoff. It runs at most once per maximum backoff.
+func (r *Registry) maybeGC(now time.Time) {
+	if now.Sub(r.lastGC) < r.max {
+		return
+	}
+	r.lastGC = now
+	expired := now.Add(-r.max)
+	for p, pe := range r.peers {
+		for k, ae := range pe.addrs {
+			if ae.until.Before(expired) {
+				delete(pe.addrs, k)
+			}
+		}
+		if len(pe.addrs) == 0 && pe.until.Before(expired) {
+			delete(r.peers, p)
+		}
+	}
+}
+
+// BackoffState is the state of a backoff.
+type BackoffState struct {
+	// Failures is the number of failures since the last successful connection.
+	Failures int
+	// LastFailure is the time of the last failure.
+	LastFailure time.Time
+	// Until is the time the backoff expires.
+	Until time.Time
+}
+
+// AddrBackoffState is the backoff state of an address.
+type AddrBackoffState struct {
+	Addr ma.Multiaddr
+	BackoffState
+}
+
+// PeerBackoffState is the backoff state of a peer, and of its addresses.
+type PeerBackoffState struct {
+	Peer peer.ID
+	// BackoffState is the state of the peer itself. It's zero if no connection
+	// attempt to the peer failed, but dials to some of its addresses did.
+	BackoffState
+	Addrs []AddrBackoffState
+}
+
+func (e *entry) state() BackoffState {
+	return BackoffState{Failures: e.failures, LastFailure: e.lastFailure, Until: e.until}
+}
+
+func (pe *peerEntry) state(p peer.ID) PeerBackoffState {
+	s := PeerBackoffState{Peer: p, BackoffState: pe.entry.state()}
+	for _, ae := range pe.addrs {
+		s.Addrs = append(s.Addrs, AddrBackoffState{Addr: ae.addr, BackoffState: ae.entry.state()})
+	}
+	return s
+}
+
+// Peer returns the backoff state of p, and whether it's tracked.
+func (r *Registry) Peer(p peer.ID) (PeerBackoffState, bool) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	pe, ok := r.peers[p]
+	if !ok {
+		return PeerBackoffState{}, false
+	}
+	return pe.state(p), true
+}
+
+// Peers returns the backoff states of all the tracked peers. This includes the peers
+// whose backoff expired, but that haven't been forgotten yet.
+func (r *Registry) Peers() []PeerBackoffState {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	states := make([]PeerBackoffState, 0, len(r.peers))
+	for p, pe := range r.peers {
+		states = append(states, pe.state(p))
+	}
+	return states
+}
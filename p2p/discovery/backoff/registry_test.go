@@ -0,0 +1,120 @@
+package backoff
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	r, err := NewRegistry(WithBackoffBounds(time.Second, 4*time.Second), WithBackoffJitter(NoJitter))
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	p := peer.ID("peer")
+	a1 := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+	a2 := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1")
+
+	require.False(t, r.Backoff(p, a1))
+	r.AddBackoff(p, a1)
+	require.True(t, r.Backoff(p, a1))
+	require.False(t, r.Backoff(p, a2))
+	require.False(t, r.PeerBackoff(p))
+
+	// the backoff doubles after every failure, up to the cap
+	for _, d := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		require.Equal(t, d, r.AddPeerBackoff(p))
+	}
+	require.True(t, r.PeerBackoff(p))
+
+	s, ok := r.Peer(p)
+	require.True(t, ok)
+	require.Equal(t, 4, s.Failures)
+	require.Equal(t, now.Add(4*time.Second), s.Until)
+	require.Len(t, s.Addrs, 1)
+	require.True(t, s.Addrs[0].Addr.Equal(a1))
+	require.Equal(t, 1, s.Addrs[0].Failures)
+	require.Equal(t, now.Add(time.Second), s.Addrs[0].Until)
+
+	now = now.Add(2 * time.Second)
+	require.False(t, r.Backoff(p, a1))
+	require.True(t, r.PeerBackoff(p))
+
+	r.Clear(p)
+	require.False(t, r.PeerBackoff(p))
+	_, ok = r.Peer(p)
+	require.False(t, ok)
+
+	// a cleared peer starts over
+	require.Equal(t, time.Second, r.AddPeerBackoff(p))
+}
+
+func TestRegistryGC(t *testing.T) {
+	r, err := NewRegistry(WithBackoffBounds(time.Second, time.Minute), WithAddrBackoff(NewFixedBackoff(time.Second)))
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	r.AddBackoff(p1, ma.StringCast("/ip4/1.2.3.4/tcp/1"))
+	require.Len(t, r.Peers(), 1)
+
+	// the entry of p1 is kept while it's backed off, and for the maximum backoff after
+	now = now.Add(time.Minute)
+	r.AddBackoff(p2, ma.StringCast("/ip4/1.2.3.4/tcp/2"))
+	require.Len(t, r.Peers(), 2)
+
+	now = now.Add(time.Minute)
+	r.AddBackoff(p2, ma.StringCast("/ip4/1.2.3.4/tcp/2"))
+	peers := r.Peers()
+	require.Len(t, peers, 1)
+	require.Equal(t, p2, peers[0].Peer)
+	require.Equal(t, 2, peers[0].Addrs[0].Failures)
+}
+
+func TestRegistryInvalidBounds(t *testing.T) {
+	_, err := NewRegistry(WithBackoffBounds(time.Minute, time.Second))
+	require.Error(t, err)
+	_, err = NewRegistry(WithBackoffBounds(0, time.Second))
+	require.Error(t, err)
+}
+
+func TestBackoffConnectorRegistry(t *testing.T) {
+	hosts := getNetHosts(t, 3)
+	primary := &maxDialHost{
+		Host:        hosts[0],
+		timesDialed: make(map[peer.ID]int),
+		maxTimesToDial: map[peer.ID]int{
+			hosts[2].ID(): 0,
+		},
+	}
+
+	r, err := NewRegistry()
+	require.NoError(t, err)
+	r.AddPeerBackoff(hosts[2].ID())
+
+	bc, err := NewBackoffConnector(primary, 10, time.Minute, NewFixedBackoff(time.Hour), WithBackoffConnectorRegistry(r))
+	require.NoError(t, err)
+
+	bc.Connect(context.Background(), loadCh(hosts))
+	require.Eventually(t, func() bool { return len(primary.Network().Peers()) == 1 }, 3*time.Second, 10*time.Millisecond)
+	// the peer backed off by the registry was never dialed
+	require.Never(t, func() bool { return len(primary.Network().Peers()) > 1 }, 200*time.Millisecond, 10*time.Millisecond)
+	primary.mux.Lock()
+	require.Zero(t, primary.timesDialed[hosts[2].ID()])
+	primary.mux.Unlock()
+
+	// failed connection attempts are recorded in the registry
+	unreachable := peer.AddrInfo{ID: "unreachable", Addrs: []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/1")}}
+	ch := make(chan peer.AddrInfo, 1)
+	ch <- unreachable
+	close(ch)
+	bc.Connect(context.Background(), ch)
+	require.Eventually(t, func() bool { return r.PeerBackoff(unreachable.ID) }, 3*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,490 @@
+// Package bootstrap keeps a host connected to a minimum number of bootstrap peers.
+//
+// Bootstrap peers are configured statically, or as /dnsaddr names and SRV records
+// that are resolved periodically, so that operators can change the bootstrap peers
+// without releasing new software:
+//
+//	b, err := bootstrap.New(h,
+//		bootstrap.WithAddrs(ma.StringCast("/dnsaddr/bootstrap.libp2p.io")),
+//		bootstrap.WithMinPeers(4),
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	b.Start()
+//	defer b.Close()
+//
+// The status of the bootstrap connections is emitted on the event bus of the host as
+// event.EvtBootstrapStatusChanged.
+package bootstrap
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math/rand"
+	"net"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/backoff"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+
+	logging "github.com/ipfs/go-log/v2"
+	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+var log = logging.Logger("bootstrap")
+
+const (
+	defaultMinPeers        = 4
+	defaultCheckInterval   = 30 * time.Second
+	defaultResolveInterval = 10 * time.Minute
+
+	// maxDNSAddrDepth is the maximum depth of nested /dnsaddr records.
+	maxDNSAddrDepth = 4
+	connectTimeout  = 30 * time.Second
+	resolveTimeout  = 30 * time.Second
+)
+
+type srvName struct {
+	service, proto, name string
+}
+
+// srvRank is the priority and weight of the SRV record a peer was resolved from.
+type srvRank struct {
+	priority, weight uint16
+}
+
+// rankedAddr is an address to resolve, with the rank of its SRV record, if any.
+type rankedAddr struct {
+	addr ma.Multiaddr
+	rank *srvRank
+}
+
+type bootstrapPeer struct {
+	info peer.AddrInfo
+	// rank is the rank of the SRV record the peer was resolved from, nil for the other
+	// peers.
+	rank    *srvRank
+	backoff backoff.BackoffStrategy
+	// nextAttempt is the earliest time of the next connection attempt.
+	nextAttempt time.Time
+	connecting  bool
+}
+
+// Bootstrapper keeps a host connected to a minimum number of bootstrap peers. When
+// it's connected to fewer peers, it connects to bootstrap peers in random order,
+// backing off from the peers it failed to connect to. The peers resolved from SRV
+// records are tried after the other peers, in the order of RFC 2782: by priority, and
+// in a random order weighted by the weight of the records within a priority.
+type Bootstrapper struct {
+	h host.Host
+
+	static          []peer.AddrInfo
+	dnsaddrs        []ma.Multiaddr
+	srvs            []srvName
+	minPeers        int
+	checkInterval   time.Duration
+	resolveInterval time.Duration
+	backoff         backoff.BackoffFactory
+	resolver        *madns.Resolver
+	lookupSRV       func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)
+
+	emitter event.Emitter
+
+	mx         sync.Mutex
+	peers      map[peer.ID]*bootstrapPeer
+	lastStatus event.EvtBootstrapStatusChanged
+	statusSent bool
+
+	ctx       context.Context
+	ctxCancel context.CancelFunc
+	wg        sync.WaitGroup
+	startOnce sync.Once
+	closeOnce sync.Once
+	// trigger triggers a check of the connections
+	trigger chan struct{}
+}
+
+// New creates a Bootstrapper for h. Call Start to start connecting.
+func New(h host.Host, opts ...Option) (*Bootstrapper, error) {
+	b := &Bootstrapper{
+		h:               h,
+		minPeers:        defaultMinPeers,
+		checkInterval:   defaultCheckInterval,
+		resolveInterval: defaultResolveInterval,
+		backoff: backoff.NewExponentialBackoff(time.Second, 5*time.Minute, backoff.FullJitter,
+			time.Second, 2, 0, rand.NewSource(time.Now().UnixNano())),
+		resolver:  madns.DefaultResolver,
+		lookupSRV: net.DefaultResolver.LookupSRV,
+		peers:     make(map[peer.ID]*bootstrapPeer),
+		trigger:   make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		if err := opt(b); err != nil {
+			return nil, err
+		}
+	}
+	if len(b.static) == 0 && len(b.dnsaddrs) == 0 && len(b.srvs) == 0 {
+		return nil, fmt.Errorf("bootstrap: no bootstrap peers configured")
+	}
+	emitter, err := h.EventBus().Emitter(new(event.EvtBootstrapStatusChanged), eventbus.Stateful)
+	if err != nil {
+		return nil, err
+	}
+	b.emitter = emitter
+	b.ctx, b.ctxCancel = context.WithCancel(context.Background())
+	return b, nil
+}
+
+// Start starts resolving the bootstrap names and connecting to bootstrap peers.
+func (b *Bootstrapper) Start() {
+	b.startOnce.Do(func() {
+		b.h.Network().Notify(b)
+		b.wg.Add(1)
+		go b.background()
+	})
+}
+
+// Close stops connecting to bootstrap peers. Existing connections are kept.
+func (b *Bootstrapper) Close() error {
+	b.closeOnce.Do(func() {
+		b.ctxCancel()
+		b.wg.Wait()
+		b.h.Network().StopNotify(b)
+		b.emitter.Close()
+	})
+	return nil
+}
+
+// Peers returns the known bootstrap peers.
+func (b *Bootstrapper) Peers() []peer.AddrInfo {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	peers := make([]peer.AddrInfo, 0, len(b.peers))
+	for _, bp := range b.peers {
+		peers = append(peers, bp.info)
+	}
+	return peers
+}
+
+// Status returns the current status of the bootstrap connections.
+func (b *Bootstrapper) Status() event.EvtBootstrapStatusChanged {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	return b.statusLocked()
+}
+
+func (b *Bootstrapper) background() {
+	defer b.wg.Done()
+
+	b.resolve()
+	b.check()
+
+	checkTicker := time.NewTicker(b.checkInterval)
+	defer checkTicker.Stop()
+	resolveTicker := time.NewTicker(b.resolveInterval)
+	defer resolveTicker.Stop()
+	for {
+		select {
+		case <-checkTicker.C:
+		case <-b.trigger:
+		case <-resolveTicker.C:
+			b.resolve()
+		case <-b.ctx.Done():
+			return
+		}
+		b.check()
+	}
+}
+
+// resolve resolves the bootstrap names and updates the known bootstrap peers. If a
+// name fails to resolve, the peers it resolved to previously are kept.
+func (b *Bootstrapper) resolve() {
+	ctx, cancel := context.WithTimeout(b.ctx, resolveTimeout)
+	defer cancel()
+
+	addrs := make([]rankedAddr, 0, len(b.dnsaddrs))
+	for _, a := range b.dnsaddrs {
+		addrs = append(addrs, rankedAddr{addr: a})
+	}
+	resolved := true
+	for _, srv := range b.srvs {
+		_, records, err := b.lookupSRV(ctx, srv.service, srv.proto, srv.name)
+		if err != nil {
+			log.Debugw("failed to look up SRV records", "name", srv.name, "error", err)
+			resolved = false
+			continue
+		}
+		for _, r := range records {
+			a, err := ma.NewComponent("dnsaddr", trimDot(r.Target))
+			if err != nil {
+				log.Debugw("invalid SRV target", "name", srv.name, "target", r.Target, "error", err)
+				continue
+			}
+			addrs = append(addrs, rankedAddr{addr: a.Multiaddr(), rank: &srvRank{priority: r.Priority, weight: r.Weight}})
+		}
+	}
+
+	var p2pAddrs []ma.Multiaddr
+	// ranks are the ranks of the peers resolved from SRV records. A peer resolved from
+	// several records gets the best rank, and none if it's also configured otherwise.
+	ranks := make(map[peer.ID]*srvRank)
+	unranked := make(map[peer.ID]struct{}, len(b.static))
+	for _, ai := range b.static {
+		unranked[ai.ID] = struct{}{}
+	}
+	for depth := 0; len(addrs) > 0 && depth < maxDNSAddrDepth; depth++ {
+		var next []rankedAddr
+		for _, a := range addrs {
+			res, err := b.resolver.Resolve(ctx, a.addr)
+			if err != nil {
+				log.Debugw("failed to resolve bootstrap address", "addr", a.addr, "error", err)
+				resolved = false
+				continue
+			}
+			for _, r := range res {
+				if first, _ := ma.SplitFirst(r); first != nil && first.Code() == ma.P_DNSADDR {
+					next = append(next, rankedAddr{addr: r, rank: a.rank})
+					continue
+				}
+				p2pAddrs = append(p2pAddrs, r)
+				id, err := peer.IDFromP2PAddr(r)
+				if err != nil {
+					continue
+				}
+				if a.rank == nil {
+					unranked[id] = struct{}{}
+				} else if rank, ok := ranks[id]; !ok || a.rank.priority < rank.priority ||
+					(a.rank.priority == rank.priority && a.rank.weight > rank.weight) {
+					ranks[id] = a.rank
+				}
+			}
+		}
+		addrs = next
+	}
+
+	infos, err := peer.AddrInfosFromP2pAddrs(p2pAddrs...)
+	if err != nil {
+		log.Debugw("resolved invalid bootstrap address", "error", err)
+		resolved = false
+	}
+	infos = append(infos, b.static...)
+
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	known := make(map[peer.ID]struct{}, len(infos))
+	for _, ai := range infos {
+		if ai.ID == b.h.ID() {
+			continue
+		}
+		known[ai.ID] = struct{}{}
+		bp, ok := b.peers[ai.ID]
+		if !ok {
+			bp = &bootstrapPeer{backoff: b.backoff()}
+			b.peers[ai.ID] = bp
+		}
+		bp.info = ai
+		bp.rank = ranks[ai.ID]
+		if _, ok := unranked[ai.ID]; ok {
+			bp.rank = nil
+		}
+	}
+	if !resolved {
+		return
+	}
+	for p := range b.peers {
+		if _, ok := known[p]; !ok {
+			delete(b.peers, p)
+		}
+	}
+}
+
+// check connects to bootstrap peers if we're connected to fewer than the minimum.
+func (b *Bootstrapper) check() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+
+	connected, connecting := 0, 0
+	var candidates []*bootstrapPeer
+	now := time.Now()
+	for p, bp := range b.peers {
+		switch {
+		case b.h.Network().Connectedness(p) == network.Connected:
+			connected++
+		case bp.connecting:
+			connecting++
+		case !now.Before(bp.nextAttempt):
+			candidates = append(candidates, bp)
+		}
+	}
+	orderCandidates(candidates)
+	for _, bp := range candidates {
+		if connected+connecting >= b.minPeers {
+			break
+		}
+		connecting++
+		bp.connecting = true
+		b.wg.Add(1)
+		go b.connect(bp)
+	}
+	b.emitStatusLocked()
+}
+
+func (b *Bootstrapper) connect(bp *bootstrapPeer) {
+	defer b.wg.Done()
+
+	b.mx.Lock()
+	ai := bp.info
+	b.mx.Unlock()
+
+	ctx, cancel := context.WithTimeout(b.ctx, connectTimeout)
+	defer cancel()
+	peerstore.AddAddrsFrom(b.h.Peerstore(), ai.ID, ai.Addrs, peerstore.TempAddrTTL, peerstore.AddrSourceManual)
+	err := b.h.Connect(ctx, ai)
+
+	b.mx.Lock()
+	bp.connecting = false
+	if err != nil {
+		bp.nextAttempt = time.Now().Add(bp.backoff.Delay())
+		log.Debugw("failed to connect to bootstrap peer", "peer", ai.ID, "retry", bp.nextAttempt, "error", err)
+	} else {
+		bp.backoff.Reset()
+		bp.nextAttempt = time.Time{}
+	}
+	b.mx.Unlock()
+
+	if err != nil && b.ctx.Err() == nil {
+		// try another peer
+		b.triggerCheck()
+	}
+}
+
+func (b *Bootstrapper) triggerCheck() {
+	select {
+	case b.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (b *Bootstrapper) statusLocked() event.EvtBootstrapStatusChanged {
+	connected := 0
+	for p := range b.peers {
+		if b.h.Network().Connectedness(p) == network.Connected {
+			connected++
+		}
+	}
+	return event.EvtBootstrapStatusChanged{
+		Connected: connected,
+		Known:     len(b.peers),
+		Healthy:   connected > 0 && connected >= min(b.minPeers, len(b.peers)),
+	}
+}
+
+func (b *Bootstrapper) emitStatusLocked() {
+	status := b.statusLocked()
+	if b.statusSent && status == b.lastStatus {
+		return
+	}
+	b.statusSent = true
+	b.lastStatus = status
+	if err := b.emitter.Emit(status); err != nil {
+		log.Debugw("failed to emit bootstrap status", "error", err)
+	}
+}
+
+// Connected implements network.Notifiee. It triggers a check, so that the status is
+// updated.
+func (b *Bootstrapper) Connected(network.Network, network.Conn) {
+	b.triggerCheck()
+}
+
+// Disconnected implements network.Notifiee. It triggers a check, so that we connect
+// to another bootstrap peer if needed.
+func (b *Bootstrapper) Disconnected(network.Network, network.Conn) {
+	b.triggerCheck()
+}
+
+func (b *Bootstrapper) Listen(network.Network, ma.Multiaddr)      {}
+func (b *Bootstrapper) ListenClose(network.Network, ma.Multiaddr) {}
+
+var _ network.Notifiee = &Bootstrapper{}
+
+func trimDot(name string) string {
+	if len(name) > 0 && name[len(name)-1] == '.' {
+		return name[:len(name)-1]
+	}
+	return name
+}
+
+// orderCandidates orders the peers to connect to: the peers that weren't resolved from
+// SRV records in random order, then the peers resolved from SRV records in the order
+// of RFC 2782.
+func orderCandidates(candidates []*bootstrapPeer) {
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	slices.SortStableFunc(candidates, func(a, b *bootstrapPeer) int {
+		switch {
+		case a.rank == nil && b.rank == nil:
+			return 0
+		case a.rank == nil:
+			return -1
+		case b.rank == nil:
+			return 1
+		default:
+			return cmp.Compare(a.rank.priority, b.rank.priority)
+		}
+	})
+	for i := 0; i < len(candidates); {
+		j := i + 1
+		for j < len(candidates) && candidates[i].rank != nil && candidates[j].rank != nil &&
+			candidates[j].rank.priority == candidates[i].rank.priority {
+			j++
+		}
+		if candidates[i].rank != nil {
+			shuffleByWeight(candidates[i:j])
+		}
+		i = j
+	}
+}
+
+// shuffleByWeight orders peers resolved from SRV records of the same priority with the
+// weighted random selection of RFC 2782: every position is filled by a peer chosen with
+// a probability proportional to its weight. Peers with a weight of 0 have a small
+// chance of being chosen.
+func shuffleByWeight(peers []*bootstrapPeer) {
+	// the peers with a weight of 0 first, so that they are only chosen if the random
+	// number is 0
+	slices.SortStableFunc(peers, func(a, b *bootstrapPeer) int {
+		return cmp.Compare(min(a.rank.weight, 1), min(b.rank.weight, 1))
+	})
+	sum := 0
+	for _, p := range peers {
+		sum += int(p.rank.weight)
+	}
+	for len(peers) > 1 {
+		s := 0
+		n := rand.Intn(sum + 1)
+		for i := range peers {
+			s += int(peers[i].rank.weight)
+			if s >= n {
+				if i > 0 {
+					// keep the order of the remaining peers
+					p := peers[i]
+					copy(peers[1:i+1], peers[:i])
+					peers[0] = p
+				}
+				break
+			}
+		}
+		sum -= int(peers[0].rank.weight)
+		peers = peers[1:]
+	}
+}
@@ -0,0 +1,149 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/blank"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+	"github.com/stretchr/testify/require"
+)
+
+func newHost(t *testing.T) host.Host {
+	t.Helper()
+	h := bhost.NewBlankHost(swarmt.GenSwarm(t))
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func p2pAddr(t *testing.T, h host.Host) string {
+	t.Helper()
+	addrs, err := peer.AddrInfoToP2pAddrs(&peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()[:1]})
+	require.NoError(t, err)
+	return addrs[0].String()
+}
+
+func TestBootstrapDNSAddr(t *testing.T) {
+	h := newHost(t)
+	peers := []host.Host{newHost(t), newHost(t), newHost(t)}
+
+	resolver, err := madns.NewResolver(madns.WithDefaultResolver(&madns.MockResolver{
+		TXT: map[string][]string{
+			"_dnsaddr.bootstrap.example.com": {
+				"dnsaddr=" + p2pAddr(t, peers[0]),
+				"dnsaddr=/dnsaddr/nested.example.com",
+			},
+			"_dnsaddr.nested.example.com":     {"dnsaddr=" + p2pAddr(t, peers[1])},
+			"_dnsaddr.srv-target.example.com": {"dnsaddr=" + p2pAddr(t, peers[2])},
+		},
+	}))
+	require.NoError(t, err)
+
+	sub, err := h.EventBus().Subscribe(new(event.EvtBootstrapStatusChanged))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	b, err := New(h,
+		WithAddrs(ma.StringCast("/dnsaddr/bootstrap.example.com")),
+		WithSRV("libp2p", "tcp", "example.com"),
+		WithSRVLookup(func(_ context.Context, service, proto, name string) (string, []*net.SRV, error) {
+			if service != "libp2p" || proto != "tcp" || name != "example.com" {
+				return "", nil, fmt.Errorf("unexpected SRV lookup: %s %s %s", service, proto, name)
+			}
+			return "", []*net.SRV{{Target: "srv-target.example.com."}}, nil
+		}),
+		WithResolver(resolver),
+		WithMinPeers(2),
+		WithCheckInterval(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+	b.Start()
+	defer b.Close()
+
+	require.Eventually(t, func() bool { return len(b.Peers()) == 3 }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool { return b.Status().Healthy }, 5*time.Second, 10*time.Millisecond)
+	// only connects to the minimum number of peers
+	time.Sleep(200 * time.Millisecond)
+	require.Len(t, h.Network().Peers(), 2)
+
+	var last event.EvtBootstrapStatusChanged
+	timeout := time.After(5 * time.Second)
+	for !last.Healthy {
+		select {
+		case e := <-sub.Out():
+			last = e.(event.EvtBootstrapStatusChanged)
+		case <-timeout:
+			t.Fatal("didn't receive healthy status")
+		}
+	}
+	require.Equal(t, event.EvtBootstrapStatusChanged{Connected: 2, Known: 3, Healthy: true}, last)
+
+	// reconnects to another peer after a disconnection
+	p := h.Network().Peers()[0]
+	require.NoError(t, h.Network().ClosePeer(p))
+	require.Eventually(t, func() bool {
+		return len(h.Network().Peers()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBootstrapBackoff(t *testing.T) {
+	h := newHost(t)
+	other := newHost(t)
+	unreachable := newHost(t)
+	unreachableInfo := peer.AddrInfo{ID: unreachable.ID(), Addrs: unreachable.Addrs()}
+	unreachable.Close()
+
+	b, err := New(h,
+		WithPeers(unreachableInfo, peer.AddrInfo{ID: other.ID(), Addrs: other.Addrs()}),
+		WithMinPeers(2),
+		WithCheckInterval(50*time.Millisecond),
+	)
+	require.NoError(t, err)
+	b.Start()
+	defer b.Close()
+
+	require.Eventually(t, func() bool {
+		return h.Network().Connectedness(other.ID()) == network.Connected
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, func() bool {
+		b.mx.Lock()
+		defer b.mx.Unlock()
+		return !b.peers[unreachable.ID()].nextAttempt.IsZero()
+	}, 10*time.Second, 10*time.Millisecond)
+	status := b.Status()
+	require.Equal(t, 1, status.Connected)
+	require.False(t, status.Healthy)
+}
+
+func TestOrderCandidates(t *testing.T) {
+	unranked := &bootstrapPeer{}
+	heavy := &bootstrapPeer{rank: &srvRank{priority: 10, weight: 90}}
+	light := &bootstrapPeer{rank: &srvRank{priority: 10, weight: 10}}
+	zero := &bootstrapPeer{rank: &srvRank{priority: 10, weight: 0}}
+	backup := &bootstrapPeer{rank: &srvRank{priority: 20, weight: 100}}
+
+	heavyFirst := 0
+	for range 1000 {
+		candidates := []*bootstrapPeer{backup, zero, light, heavy, unranked}
+		orderCandidates(candidates)
+		require.Equal(t, unranked, candidates[0])
+		require.ElementsMatch(t, []*bootstrapPeer{heavy, light, zero}, candidates[1:4])
+		require.Equal(t, backup, candidates[4])
+		if candidates[1] == heavy {
+			heavyFirst++
+		}
+	}
+	// the heavy peer is chosen first with a probability of 90/101
+	require.Greater(t, heavyFirst, 800)
+	require.Less(t, heavyFirst, 980)
+}
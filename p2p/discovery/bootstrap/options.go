@@ -0,0 +1,121 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/backoff"
+
+	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+)
+
+// Option configures a Bootstrapper.
+type Option func(*Bootstrapper) error
+
+// WithPeers adds static bootstrap peers.
+func WithPeers(peers ...peer.AddrInfo) Option {
+	return func(b *Bootstrapper) error {
+		b.static = append(b.static, peers...)
+		return nil
+	}
+}
+
+// WithAddrs adds bootstrap addresses. They are either /p2p addresses of static peers,
+// or /dnsaddr names resolved to the addresses of the bootstrap peers, e.g.
+// /dnsaddr/bootstrap.libp2p.io.
+func WithAddrs(addrs ...ma.Multiaddr) Option {
+	return func(b *Bootstrapper) error {
+		for _, a := range addrs {
+			if first, _ := ma.SplitFirst(a); first != nil && first.Code() == ma.P_DNSADDR {
+				b.dnsaddrs = append(b.dnsaddrs, a)
+				continue
+			}
+			ai, err := peer.AddrInfoFromP2pAddr(a)
+			if err != nil {
+				return err
+			}
+			b.static = append(b.static, *ai)
+		}
+		return nil
+	}
+}
+
+// WithSRV adds the SRV records of _service._proto.name. The target of every record is
+// resolved as a /dnsaddr name, since SRV records don't carry peer IDs. SRV records
+// allow operators to weight bootstrap clusters using standard DNS tooling: the peers
+// are tried by priority, and in a random order weighted by the weight of the records
+// within a priority.
+func WithSRV(service, proto, name string) Option {
+	return func(b *Bootstrapper) error {
+		if name == "" {
+			return errors.New("bootstrap: empty SRV name")
+		}
+		b.srvs = append(b.srvs, srvName{service: service, proto: proto, name: name})
+		return nil
+	}
+}
+
+// WithMinPeers sets the number of bootstrap peers to stay connected to. Defaults to 4.
+func WithMinPeers(n int) Option {
+	return func(b *Bootstrapper) error {
+		if n <= 0 {
+			return errors.New("bootstrap: minimum number of peers must be positive")
+		}
+		b.minPeers = n
+		return nil
+	}
+}
+
+// WithCheckInterval sets the interval at which the connections to the bootstrap
+// peers are checked. Defaults to 30 seconds.
+func WithCheckInterval(d time.Duration) Option {
+	return func(b *Bootstrapper) error {
+		if d <= 0 {
+			return errors.New("bootstrap: check interval must be positive")
+		}
+		b.checkInterval = d
+		return nil
+	}
+}
+
+// WithResolveInterval sets the interval at which the DNS names are resolved again.
+// Defaults to 10 minutes.
+func WithResolveInterval(d time.Duration) Option {
+	return func(b *Bootstrapper) error {
+		if d <= 0 {
+			return errors.New("bootstrap: resolve interval must be positive")
+		}
+		b.resolveInterval = d
+		return nil
+	}
+}
+
+// WithBackoff sets the backoff applied to a bootstrap peer after a failed connection
+// attempt. Defaults to an exponential backoff from 1 second to 5 minutes.
+func WithBackoff(bkf backoff.BackoffFactory) Option {
+	return func(b *Bootstrapper) error {
+		b.backoff = bkf
+		return nil
+	}
+}
+
+// WithResolver sets the resolver used to resolve /dnsaddr names.
+func WithResolver(r *madns.Resolver) Option {
+	return func(b *Bootstrapper) error {
+		b.resolver = r
+		return nil
+	}
+}
+
+// WithSRVLookup sets the function used to look up SRV records. Defaults to
+// net.DefaultResolver.LookupSRV.
+func WithSRVLookup(lookup func(ctx context.Context, service, proto, name string) (string, []*net.SRV, error)) Option {
+	return func(b *Bootstrapper) error {
+		b.lookupSRV = lookup
+		return nil
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 
 	"github.com/libp2p/zeroconf/v2"
 
@@ -47,10 +48,11 @@ type mdnsService struct {
 	resolverWG sync.WaitGroup
 	server     *zeroconf.Server
 
-	notifee Notifee
+	notifee  Notifee
+	metadata Metadata
 }
 
-func NewMdnsService(host host.Host, serviceName string, notifee Notifee) *mdnsService {
+func NewMdnsService(host host.Host, serviceName string, notifee Notifee, opts ...Option) *mdnsService {
 	if serviceName == "" {
 		serviceName = ServiceName
 	}
@@ -60,6 +62,9 @@ func NewMdnsService(host host.Host, serviceName string, notifee Notifee) *mdnsSe
 		peerName:    randomString(32 + rand.Intn(32)), // generate a random string between 32 and 63 characters long
 		notifee:     notifee,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
 	s.ctx, s.ctxCancel = context.WithCancel(context.Background())
 	return s
 }
@@ -121,7 +126,10 @@ func (s *mdnsService) startServer() error {
 	if err != nil {
 		return err
 	}
-	var txts []string
+	txts, err := s.metadata.txts()
+	if err != nil {
+		return err
+	}
 	for _, addr := range addrs {
 		if manet.IsThinWaist(addr) { // don't announce circuit addresses
 			txts = append(txts, dnsaddrPrefix+addr.String())
@@ -159,9 +167,12 @@ func (s *mdnsService) startResolver(ctx context.Context) {
 			// We only care about the TXT records.
 			// Ignore A, AAAA and PTR.
 			addrs := make([]ma.Multiaddr, 0, len(entry.Text)) // assume that all TXT records are dnsaddrs
+			var md Metadata
 			for _, s := range entry.Text {
 				if !strings.HasPrefix(s, dnsaddrPrefix) {
-					log.Debug("missing dnsaddr prefix")
+					if !md.parseTXT(s) {
+						log.Debug("missing dnsaddr prefix")
+					}
 					continue
 				}
 				addr, err := ma.NewMultiaddr(s[len(dnsaddrPrefix):])
@@ -180,7 +191,12 @@ func (s *mdnsService) startResolver(ctx context.Context) {
 				if info.ID == s.host.ID() {
 					continue
 				}
-				go s.notifee.HandlePeerFound(info)
+				peerstore.AddAddrsFrom(s.host.Peerstore(), info.ID, info.Addrs, peerstore.TempAddrTTL, peerstore.AddrSourceMDNS)
+				if n, ok := s.notifee.(MetadataNotifee); ok {
+					go n.HandlePeerFoundWithMetadata(info, md)
+				} else {
+					go s.notifee.HandlePeerFound(info)
+				}
 			}
 		}
 	}()
@@ -1,22 +1,26 @@
 package mdns
 
 import (
+	"fmt"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
-func setupMDNS(t *testing.T, notifee Notifee) peer.ID {
+func setupMDNS(t *testing.T, notifee Notifee, opts ...Option) peer.ID {
 	t.Helper()
 	host, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
 	require.NoError(t, err)
-	s := NewMdnsService(host, "", notifee)
+	s := NewMdnsService(host, "", notifee, opts...)
 	require.NoError(t, s.Start())
 	t.Cleanup(func() {
 		host.Close()
@@ -96,3 +100,88 @@ func TestOtherDiscovery(t *testing.T) {
 		"expected peers to find each other",
 	)
 }
+
+type metadataNotif struct {
+	notif
+	metadata map[peer.ID]Metadata
+}
+
+var _ MetadataNotifee = &metadataNotif{}
+
+func (n *metadataNotif) HandlePeerFoundWithMetadata(info peer.AddrInfo, md Metadata) {
+	n.mutex.Lock()
+	n.metadata[info.ID] = md
+	n.mutex.Unlock()
+	n.HandlePeerFound(info)
+}
+
+func (n *metadataNotif) GetMetadata(p peer.ID) (Metadata, bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	md, ok := n.metadata[p]
+	return md, ok
+}
+
+func TestMetadata(t *testing.T) {
+	md := Metadata{
+		AgentVersion: "test/1.0",
+		Protocols:    []protocol.ID{"/chat/1.0.0", "/files/2.0.0"},
+	}
+	withMetadata := setupMDNS(t, &notif{}, WithMetadata(md))
+	withoutMetadata := setupMDNS(t, &notif{})
+	n := &metadataNotif{metadata: make(map[peer.ID]Metadata)}
+	setupMDNS(t, n)
+
+	require.Eventually(t, func() bool {
+		_, ok1 := n.GetMetadata(withMetadata)
+		_, ok2 := n.GetMetadata(withoutMetadata)
+		return ok1 && ok2
+	}, 25*time.Second, 5*time.Millisecond, "expected peers to be found")
+	got, _ := n.GetMetadata(withMetadata)
+	require.Equal(t, md, got)
+	got, _ = n.GetMetadata(withoutMetadata)
+	require.Zero(t, got)
+}
+
+func TestAddrProvenance(t *testing.T) {
+	found := setupMDNS(t, &notif{})
+
+	host, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer host.Close()
+	n := &notif{}
+	s := NewMdnsService(host, "", n)
+	require.NoError(t, s.Start())
+	defer s.Close()
+
+	require.Eventually(t, func() bool {
+		for _, info := range n.GetPeers() {
+			if info.ID == found {
+				return true
+			}
+		}
+		return false
+	}, 25*time.Second, 5*time.Millisecond, "expected peer to be found")
+	pb, ok := host.Peerstore().(peerstore.AddrProvenanceBook)
+	require.True(t, ok)
+	require.NotEmpty(t, pb.AddrsFrom(found, peerstore.AddrSourceMDNS))
+}
+
+func TestMetadataLimits(t *testing.T) {
+	_, err := Metadata{AgentVersion: strings.Repeat("a", 250)}.txts()
+	require.ErrorContains(t, err, "exceeds 255 bytes")
+
+	md := Metadata{}
+	for i := 0; i < 20; i++ {
+		md.Protocols = append(md.Protocols, protocol.ID(fmt.Sprintf("/%s/%d", strings.Repeat("p", 50), i)))
+	}
+	_, err = md.txts()
+	require.ErrorContains(t, err, "exceeds 1024 bytes")
+
+	host, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer host.Close()
+	s := NewMdnsService(host, "", &notif{}, WithMetadata(md))
+	require.Error(t, s.Start())
+	require.NoError(t, s.Close())
+}
@@ -0,0 +1,86 @@
+package mdns
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+)
+
+const (
+	agentVersionPrefix = "agent="
+	protocolPrefix     = "proto="
+
+	// maxTXTLength is the maximum length of a single TXT string.
+	maxTXTLength = 255
+	// maxMetadataSize is the maximum total size of the metadata TXT strings.
+	// mDNS responses have to fit in a single packet, and most of it is taken by the dnsaddrs.
+	maxMetadataSize = 1024
+)
+
+// Metadata is advertised by a peer alongside its addresses in the TXT records
+// of its mDNS service, so that peers on the LAN can filter it before connecting.
+type Metadata struct {
+	// AgentVersion is the agent version of the peer.
+	AgentVersion string
+	// Protocols are the application protocols supported by the peer.
+	Protocols []protocol.ID
+}
+
+// MetadataNotifee is a Notifee that also receives the metadata advertised by the
+// peers found. If the notifee of the service implements it,
+// HandlePeerFoundWithMetadata is called instead of HandlePeerFound.
+// Peers that don't advertise metadata are reported with an empty Metadata.
+type MetadataNotifee interface {
+	Notifee
+	HandlePeerFoundWithMetadata(peer.AddrInfo, Metadata)
+}
+
+// Option configures the mDNS service.
+type Option func(*mdnsService)
+
+// WithMetadata advertises md in the TXT records of the service.
+// Every field is encoded as a separate TXT string which must not exceed 255 bytes,
+// and the metadata must not exceed 1024 bytes in total, or Start fails.
+func WithMetadata(md Metadata) Option {
+	return func(s *mdnsService) {
+		s.metadata = md
+	}
+}
+
+// txts returns the TXT strings encoding md.
+func (md Metadata) txts() ([]string, error) {
+	var txts []string
+	if md.AgentVersion != "" {
+		txts = append(txts, agentVersionPrefix+md.AgentVersion)
+	}
+	for _, p := range md.Protocols {
+		txts = append(txts, protocolPrefix+string(p))
+	}
+	var size int
+	for _, txt := range txts {
+		if len(txt) > maxTXTLength {
+			return nil, fmt.Errorf("mdns: metadata TXT string %q exceeds %d bytes", txt, maxTXTLength)
+		}
+		size += len(txt)
+	}
+	if size > maxMetadataSize {
+		return nil, fmt.Errorf("mdns: metadata of %d bytes exceeds %d bytes", size, maxMetadataSize)
+	}
+	return txts, nil
+}
+
+// parseTXT adds the metadata encoded by txt to md. It returns false if txt
+// doesn't encode metadata.
+func (md *Metadata) parseTXT(txt string) bool {
+	switch {
+	case strings.HasPrefix(txt, agentVersionPrefix):
+		md.AgentVersion = txt[len(agentVersionPrefix):]
+	case strings.HasPrefix(txt, protocolPrefix):
+		md.Protocols = append(md.Protocols, protocol.ID(txt[len(protocolPrefix):]))
+	default:
+		return false
+	}
+	return true
+}
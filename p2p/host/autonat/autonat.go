@@ -43,7 +43,12 @@ type AmbientAutoNAT struct {
 	// If it is <3, then multiple autoNAT peers may be contacted for dialback
 	// If only a single autoNAT peer is known, then the confidence increases
 	// for each failure until it reaches 3.
-	confidence    int
+	confidence int
+	// confidentProbes is the number of consecutive probes confirming the status at
+	// maximum confidence. The refresh interval backs off with it.
+	confidentProbes int
+	// addrsChanged is set when our addresses changed since the last probe.
+	addrsChanged  bool
 	lastInbound   time.Time
 	lastProbe     time.Time
 	recentProbes  map[peer.ID]time.Time
@@ -63,6 +68,11 @@ type StaticAutoNAT struct {
 	service      *autoNATService
 }
 
+var (
+	_ ServiceThrottler = (*AmbientAutoNAT)(nil)
+	_ ServiceThrottler = (*StaticAutoNAT)(nil)
+)
+
 // New creates a new NAT autodiscovery system attached to a host
 func New(h host.Host, options ...Option) (AutoNAT, error) {
 	var err error
@@ -220,6 +230,7 @@ func (as *AmbientAutoNAT) background() {
 		case <-timer.C:
 			timerRunning = false
 			forceProbe = false
+			as.addrsChanged = false
 			// Update the last probe time. We use it to ensure
 			// that we don't spam the peerstore.
 			as.lastProbe = time.Now()
@@ -228,11 +239,14 @@ func (as *AmbientAutoNAT) background() {
 		case <-as.ctx.Done():
 			return
 		}
-		// On address update, reduce confidence from maximum so that we schedule
-		// the next probe sooner
-		hasNewAddr := as.checkAddrs()
-		if hasNewAddr && as.confidence == maxConfidence {
-			as.confidence--
+		// On address update, reduce confidence from maximum and reset the backoff so
+		// that we schedule the next probe sooner
+		if as.checkAddrs() {
+			as.addrsChanged = true
+			as.confidentProbes = 0
+			if as.confidence == maxConfidence {
+				as.confidence--
+			}
 		}
 
 		if timerRunning && !timer.Stop() {
@@ -246,6 +260,9 @@ func (as *AmbientAutoNAT) background() {
 func (as *AmbientAutoNAT) checkAddrs() (hasNewAddr bool) {
 	currentAddrs := as.addressFunc()
 	hasNewAddr = slices.ContainsFunc(currentAddrs, func(a ma.Multiaddr) bool {
+		if !manet.IsPublicAddr(a) {
+			return false
+		}
 		_, ok := as.ourAddrs[string(a.Bytes())]
 		return !ok
 	})
@@ -263,7 +280,7 @@ func (as *AmbientAutoNAT) checkAddrs() (hasNewAddr bool) {
 func (as *AmbientAutoNAT) scheduleProbe(forceProbe bool) time.Duration {
 	now := time.Now()
 	currentStatus := *as.status.Load()
-	nextProbeAfter := as.config.refreshInterval
+	nextProbeAfter := as.refreshInterval()
 	receivedInbound := as.lastInbound.After(as.lastProbe)
 	switch {
 	case forceProbe && currentStatus == network.ReachabilityUnknown:
@@ -284,6 +301,10 @@ func (as *AmbientAutoNAT) scheduleProbe(forceProbe bool) time.Duration {
 		nextProbeAfter *= 2
 		nextProbeAfter = min(nextProbeAfter, maxRefreshInterval)
 	}
+	if as.addrsChanged {
+		// Verify new addresses quickly
+		nextProbeAfter = min(nextProbeAfter, as.config.addrChangeDelay)
+	}
 	nextProbeTime := as.lastProbe.Add(nextProbeAfter)
 	if nextProbeTime.Before(now) {
 		nextProbeTime = now
@@ -295,6 +316,17 @@ func (as *AmbientAutoNAT) scheduleProbe(forceProbe bool) time.Duration {
 	return nextProbeTime.Sub(now)
 }
 
+// refreshInterval returns the interval between probes once we're confident about our
+// reachability. It doubles with every probe confirming the status, up to the
+// configured maximum.
+func (as *AmbientAutoNAT) refreshInterval() time.Duration {
+	d := as.config.refreshInterval
+	for i := 0; i < as.confidentProbes && d < as.config.maxRefreshInterval; i++ {
+		d *= 2
+	}
+	return min(d, as.config.maxRefreshInterval)
+}
+
 // handleDialResponse updates the current status based on dial response.
 func (as *AmbientAutoNAT) handleDialResponse(dialErr error) {
 	var observation network.Reachability
@@ -314,6 +346,11 @@ func (as *AmbientAutoNAT) handleDialResponse(dialErr error) {
 func (as *AmbientAutoNAT) recordObservation(observation network.Reachability) {
 
 	currentStatus := *as.status.Load()
+	if as.confidence == maxConfidence && observation == currentStatus {
+		as.confidentProbes++
+	} else {
+		as.confidentProbes = 0
+	}
 
 	if observation == network.ReachabilityPublic {
 		changed := false
@@ -441,6 +478,15 @@ func (as *AmbientAutoNAT) Close() error {
 	return nil
 }
 
+// ServiceThrottleState returns the throttling state of the AutoNAT service. It
+// returns false if the service isn't running.
+func (as *AmbientAutoNAT) ServiceThrottleState() (ThrottleState, bool) {
+	if as.service == nil {
+		return ThrottleState{}, false
+	}
+	return as.service.throttleState(), true
+}
+
 // Status returns the AutoNAT observed reachability status.
 func (s *StaticAutoNAT) Status() network.Reachability {
 	return s.reachability
@@ -452,3 +498,12 @@ func (s *StaticAutoNAT) Close() error {
 	}
 	return nil
 }
+
+// ServiceThrottleState returns the throttling state of the AutoNAT service. It
+// returns false if the service isn't running.
+func (s *StaticAutoNAT) ServiceThrottleState() (ThrottleState, bool) {
+	if s.service == nil {
+		return ThrottleState{}, false
+	}
+	return s.service.throttleState(), true
+}
@@ -328,3 +328,56 @@ func TestStaticNat(t *testing.T) {
 	}
 	expectEvent(t, s, network.ReachabilityPrivate, 3*time.Second)
 }
+
+func TestAutoNATProbeSchedule(t *testing.T) {
+	an := &AmbientAutoNAT{config: &config{}}
+	require.NoError(t, defaults(an.config))
+	require.NoError(t, WithProbeSchedule(ProbeSchedule{
+		RetryInterval:      10 * time.Second,
+		RefreshInterval:    time.Minute,
+		MaxRefreshInterval: 5 * time.Minute,
+		AddrChangeDelay:    time.Second,
+	})(an.config))
+
+	public := network.ReachabilityPublic
+	an.status.Store(&public)
+	an.confidence = maxConfidence
+	an.lastProbe = time.Now()
+	expectNextProbe := func(expected time.Duration) {
+		t.Helper()
+		require.InDelta(t, expected, an.scheduleProbe(false), float64(time.Second))
+	}
+
+	expectNextProbe(time.Minute)
+	// every probe confirming the status backs off, up to the maximum
+	an.recordObservation(network.ReachabilityPublic)
+	expectNextProbe(2 * time.Minute)
+	an.recordObservation(network.ReachabilityPublic)
+	expectNextProbe(4 * time.Minute)
+	an.recordObservation(network.ReachabilityPublic)
+	expectNextProbe(5 * time.Minute)
+
+	// address changes trigger a probe soon
+	an.addrsChanged = true
+	expectNextProbe(time.Second)
+	an.addrsChanged = false
+	expectNextProbe(5 * time.Minute)
+
+	// a contradicting observation resets the backoff
+	an.recordObservation(network.ReachabilityPrivate)
+	expectNextProbe(10 * time.Second)
+	an.recordObservation(network.ReachabilityPublic)
+	require.Equal(t, maxConfidence, an.confidence)
+	expectNextProbe(time.Minute)
+}
+
+func TestProbeScheduleOptions(t *testing.T) {
+	c := &config{}
+	require.NoError(t, defaults(c))
+	require.NoError(t, WithProbeSchedule(ProbeSchedule{RefreshInterval: 2 * time.Hour})(c))
+	require.Equal(t, 2*time.Hour, c.maxRefreshInterval)
+	require.Error(t, WithProbeSchedule(ProbeSchedule{RefreshInterval: time.Hour, MaxRefreshInterval: time.Minute})(c))
+
+	require.NoError(t, WithSchedule(time.Second, time.Minute)(c))
+	require.Equal(t, time.Minute, c.maxRefreshInterval)
+}
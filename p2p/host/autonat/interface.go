@@ -17,6 +17,15 @@ type AutoNAT interface {
 	io.Closer
 }
 
+// ServiceThrottler is implemented by the AutoNATs returned by New. It exposes the
+// dial back throttling state of the AutoNAT service, so that operators can tune its
+// capacity with WithThrottling and WithPeerThrottling.
+type ServiceThrottler interface {
+	// ServiceThrottleState returns the throttling state of the AutoNAT service. It
+	// returns false if the service isn't running.
+	ServiceThrottleState() (ThrottleState, bool)
+}
+
 // Client is a stateless client interface to AutoNAT peers
 type Client interface {
 	// DialBack requests from a peer providing AutoNAT services to test dial back
@@ -6,6 +6,7 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/x/rate"
 )
 
 // config holds configurable options for the autonat subsystem.
@@ -23,6 +24,8 @@ type config struct {
 	bootDelay          time.Duration
 	retryInterval      time.Duration
 	refreshInterval    time.Duration
+	maxRefreshInterval time.Duration
+	addrChangeDelay    time.Duration
 	requestTimeout     time.Duration
 	throttlePeerPeriod time.Duration
 
@@ -33,12 +36,15 @@ type config struct {
 	throttlePeerMax     int
 	throttleResetPeriod time.Duration
 	throttleResetJitter time.Duration
+	peerRateLimiter     *rate.PeerLimiter
 }
 
 var defaults = func(c *config) error {
 	c.bootDelay = 15 * time.Second
 	c.retryInterval = 90 * time.Second
 	c.refreshInterval = 15 * time.Minute
+	c.maxRefreshInterval = time.Hour
+	c.addrChangeDelay = 5 * time.Second
 	c.requestTimeout = 30 * time.Second
 	c.throttlePeerPeriod = 90 * time.Second
 
@@ -97,11 +103,59 @@ func UsingAddresses(addrFunc AddrFunc) Option {
 // address of the host. retryInterval indicates how often probes should be made
 // when the host lacks confidence about its address, while refreshInterval
 // is the schedule of periodic probes when the host believes it knows its
-// steady-state reachability.
+// steady-state reachability. The refresh interval doesn't back off, use
+// WithProbeSchedule for an adaptive schedule.
 func WithSchedule(retryInterval, refreshInterval time.Duration) Option {
 	return func(c *config) error {
 		c.retryInterval = retryInterval
 		c.refreshInterval = refreshInterval
+		c.maxRefreshInterval = refreshInterval
+		return nil
+	}
+}
+
+// ProbeSchedule configures when the AutoNAT client probes its reachability.
+type ProbeSchedule struct {
+	// RetryInterval is the interval between probes while the reachability is unknown
+	// or the client isn't confident about it.
+	RetryInterval time.Duration
+	// RefreshInterval is the interval between probes once the client is confident
+	// about its reachability.
+	RefreshInterval time.Duration
+	// MaxRefreshInterval caps the refresh interval, which doubles after every probe
+	// confirming a confident verdict. Setting it to RefreshInterval disables the
+	// backoff.
+	MaxRefreshInterval time.Duration
+	// AddrChangeDelay is the time after the previous probe at which the client probes
+	// again when its addresses change.
+	AddrChangeDelay time.Duration
+}
+
+// WithProbeSchedule configures the schedule of the AutoNAT client probes. Zero
+// fields keep their default values: a retry interval of 90s, a refresh interval of
+// 15m backing off up to 1h, and probing 5s after address changes.
+func WithProbeSchedule(s ProbeSchedule) Option {
+	return func(c *config) error {
+		if s.RetryInterval < 0 || s.RefreshInterval < 0 || s.MaxRefreshInterval < 0 || s.AddrChangeDelay < 0 {
+			return errors.New("negative probe schedule interval")
+		}
+		if s.RetryInterval != 0 {
+			c.retryInterval = s.RetryInterval
+		}
+		if s.RefreshInterval != 0 {
+			c.refreshInterval = s.RefreshInterval
+		}
+		if s.MaxRefreshInterval != 0 {
+			c.maxRefreshInterval = s.MaxRefreshInterval
+		} else {
+			c.maxRefreshInterval = max(c.maxRefreshInterval, c.refreshInterval)
+		}
+		if s.AddrChangeDelay != 0 {
+			c.addrChangeDelay = s.AddrChangeDelay
+		}
+		if c.maxRefreshInterval < c.refreshInterval {
+			return errors.New("max refresh interval is shorter than the refresh interval")
+		}
 		return nil
 	}
 }
@@ -146,6 +200,17 @@ func WithPeerThrottling(amount int) Option {
 	}
 }
 
+// WithPeerRateLimiter limits the rate at which an individual peer can request dial backs.
+// Unlike WithPeerThrottling, which caps the number of dial backs per reset period, l is a
+// token bucket that allows short bursts. Requests over the limit are refused with
+// E_DIAL_REFUSED.
+func WithPeerRateLimiter(l *rate.PeerLimiter) Option {
+	return func(c *config) error {
+		c.peerRateLimiter = l
+		return nil
+	}
+}
+
 // WithMetricsTracer uses mt to track autonat metrics
 func WithMetricsTracer(mt MetricsTracer) Option {
 	return func(c *config) error {
@@ -3,6 +3,7 @@ package autonat
 import (
 	"context"
 	"errors"
+	"maps"
 	"math/rand"
 	"sync"
 	"time"
@@ -37,6 +38,29 @@ type autoNATService struct {
 	mx         sync.Mutex
 	reqs       map[peer.ID]int
 	globalReqs int
+	nextReset  time.Time
+}
+
+// ThrottleState is a snapshot of the dial back throttling state of the AutoNAT
+// service. The counters are reset every reset period.
+type ThrottleState struct {
+	// Enabled is true if the service is answering dial back requests. The service is
+	// disabled while the host is private.
+	Enabled bool
+	// GlobalRequests is the number of dial backs in the current period.
+	GlobalRequests int
+	// GlobalLimit is the maximum number of dial backs per period. Zero means no limit.
+	GlobalLimit int
+	// PeerRequests is the number of dial backs per peer in the current period.
+	PeerRequests map[peer.ID]int
+	// PeerLimit is the maximum number of dial backs per peer and period.
+	PeerLimit int
+	// ResetPeriod is the period after which the counters are reset, plus a random
+	// jitter of up to ResetJitter.
+	ResetPeriod time.Duration
+	ResetJitter time.Duration
+	// NextReset is the time of the next reset of the counters.
+	NextReset time.Time
 }
 
 // NewAutoNATService creates a new AutoNATService instance attached to a host
@@ -106,6 +130,12 @@ func (as *autoNATService) handleStream(s network.Stream) {
 }
 
 func (as *autoNATService) handleDial(p peer.ID, obsaddr ma.Multiaddr, mpi *pb.Message_PeerInfo) *pb.Message_DialResponse {
+	if as.config.peerRateLimiter != nil && !as.config.peerRateLimiter.Allow(p) {
+		if as.config.metricsTracer != nil {
+			as.config.metricsTracer.OutgoingDialRefused(rate_limited)
+		}
+		return newDialResponseError(pb.Message_E_DIAL_REFUSED, "rate limited")
+	}
 	if mpi == nil {
 		return newDialResponseError(pb.Message_E_BAD_REQUEST, "missing peer info")
 	}
@@ -278,21 +308,45 @@ func (as *autoNATService) Close() error {
 	return as.config.dialer.Close()
 }
 
+func (as *autoNATService) throttleState() ThrottleState {
+	as.instanceLock.Lock()
+	enabled := as.instance != nil
+	as.instanceLock.Unlock()
+
+	as.mx.Lock()
+	defer as.mx.Unlock()
+	return ThrottleState{
+		Enabled:        enabled,
+		GlobalRequests: as.globalReqs,
+		GlobalLimit:    as.config.throttleGlobalMax,
+		PeerRequests:   maps.Clone(as.reqs),
+		PeerLimit:      as.config.throttlePeerMax,
+		ResetPeriod:    as.config.throttleResetPeriod,
+		ResetJitter:    as.config.throttleResetJitter,
+		NextReset:      as.nextReset,
+	}
+}
+
 func (as *autoNATService) background(ctx context.Context) {
 	defer close(as.backgroundRunning)
 
+	as.mx.Lock()
+	as.nextReset = time.Now().Add(as.config.throttleResetPeriod)
+	as.mx.Unlock()
 	timer := time.NewTimer(as.config.throttleResetPeriod)
 	defer timer.Stop()
 
 	for {
 		select {
 		case <-timer.C:
+			jitter := rand.Float32() * float32(as.config.throttleResetJitter)
+			next := as.config.throttleResetPeriod + time.Duration(int64(jitter))
 			as.mx.Lock()
 			as.reqs = make(map[peer.ID]int)
 			as.globalReqs = 0
+			as.nextReset = time.Now().Add(next)
 			as.mx.Unlock()
-			jitter := rand.Float32() * float32(as.config.throttleResetJitter)
-			timer.Reset(as.config.throttleResetPeriod + time.Duration(int64(jitter)))
+			timer.Reset(next)
 		case <-ctx.Done():
 			return
 		}
@@ -9,8 +9,10 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/blank"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+	"github.com/TheNoobiCat/go-libp2p/x/rate"
 
 	"github.com/stretchr/testify/require"
 )
@@ -127,6 +129,27 @@ func TestAutoNATServiceDialRateLimiter(t *testing.T) {
 	}
 }
 
+func TestAutoNATServicePeerRateLimiter(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := makeAutoNATConfig(t)
+	defer c.host.Close()
+	defer c.dialer.Close()
+
+	c.peerRateLimiter = &rate.PeerLimiter{PeerLimit: rate.Limit{RPS: 0.001, Burst: 1}}
+	_ = makeAutoNATService(t, c)
+
+	hc, ac := makeAutoNATClient(t)
+	defer hc.Close()
+	connect(t, c.host, hc)
+
+	require.NoError(t, ac.DialBack(ctx, c.host.ID()))
+	err := ac.DialBack(ctx, c.host.ID())
+	require.Error(t, err)
+	require.True(t, IsDialRefused(err))
+}
+
 func TestAutoNATServiceGlobalLimiter(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -226,3 +249,32 @@ func TestAutoNATServiceStartup(t *testing.T) {
 		t.Fatalf("autonat should report public, but didn't")
 	}
 }
+
+func TestAutoNATServiceThrottleState(t *testing.T) {
+	c := makeAutoNATConfig(t)
+	defer c.host.Close()
+	defer c.dialer.Close()
+
+	c.throttleResetPeriod = time.Minute
+	c.throttleResetJitter = 0
+	c.throttlePeerMax = 2
+	c.throttleGlobalMax = 10
+	svc := makeAutoNATService(t, c)
+
+	hc, ac := makeAutoNATClient(t)
+	defer hc.Close()
+	connect(t, c.host, hc)
+	require.NoError(t, ac.DialBack(context.Background(), c.host.ID()))
+
+	st := svc.throttleState()
+	require.True(t, st.Enabled)
+	require.Equal(t, 1, st.GlobalRequests)
+	require.Equal(t, 10, st.GlobalLimit)
+	require.Equal(t, map[peer.ID]int{hc.ID(): 1}, st.PeerRequests)
+	require.Equal(t, 2, st.PeerLimit)
+	require.Equal(t, time.Minute, st.ResetPeriod)
+	require.WithinDuration(t, time.Now().Add(time.Minute), st.NextReset, 5*time.Second)
+
+	svc.Disable()
+	require.False(t, svc.throttleState().Enabled)
+}
@@ -105,3 +105,12 @@ func (r *AutoRelay) Close() error {
 	r.refCount.Wait()
 	return err
 }
+
+// CandidateScores returns the scores of the current relay candidates and of the relays
+// we hold reservations with, best first.
+//
+// When using libp2p.New, the AutoRelay instance can be obtained with
+// libp2p.WithFxOption(fx.Populate(&autoRelay)).
+func (r *AutoRelay) CandidateScores() []CandidateScore {
+	return r.relayFinder.candidateScores()
+}
@@ -21,6 +21,7 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/fx"
 )
 
 const protoIDv2 = circuitv2_proto.ProtoIDv2Hop
@@ -580,6 +581,9 @@ func TestAutoRelayAddrsEvent(t *testing.T) {
 		if checkAddrsContainsPeersAsRelay(evt.RelayAddrs, relays[1].ID()) {
 			collect.Errorf("expected %s to not be in %v", relays[1].ID(), evt.RelayAddrs)
 		}
+		if _, ok := evt.Vouchers[relays[0].ID()]; !ok {
+			collect.Errorf("expected the voucher of %s to be advertised", relays[0].ID())
+		}
 	}, 5*time.Second, 50*time.Millisecond)
 	for _, r := range relays[1:] {
 		peerChan <- peer.AddrInfo{ID: r.ID(), Addrs: r.Addrs()}
@@ -601,3 +605,44 @@ func TestAutoRelayAddrsEvent(t *testing.T) {
 	case <-time.After(1 * time.Second):
 	}
 }
+
+func TestCandidateScoring(t *testing.T) {
+	const numStaticRelays = 3
+	var staticRelays []peer.AddrInfo
+	for i := 0; i < numStaticRelays; i++ {
+		r := newRelay(t)
+		t.Cleanup(func() { r.Close() })
+		staticRelays = append(staticRelays, peer.AddrInfo{ID: r.ID(), Addrs: r.Addrs()})
+	}
+	best := staticRelays[2].ID
+
+	var ar *autorelay.AutoRelay
+	h, err := libp2p.New(
+		libp2p.ForceReachabilityPrivate(),
+		libp2p.EnableAutoRelayWithStaticRelays(staticRelays,
+			autorelay.WithNumRelays(1),
+			autorelay.WithMinCandidates(numStaticRelays),
+			autorelay.WithCandidateScorer(func(s autorelay.CandidateScore) float64 {
+				if s.ID == best {
+					return 1
+				}
+				return autorelay.DefaultCandidateScorer(s)
+			}),
+		),
+		libp2p.WithFxOption(fx.Populate(&ar)),
+	)
+	require.NoError(t, err)
+	defer h.Close()
+	require.NotNil(t, ar)
+
+	require.Eventually(t, func() bool { return numRelays(h) > 0 }, 10*time.Second, 50*time.Millisecond)
+	require.Equal(t, []peer.ID{best}, usedRelays(h))
+
+	scores := ar.CandidateScores()
+	require.NotEmpty(t, scores)
+	require.Equal(t, best, scores[0].ID)
+	require.Equal(t, 1, scores[0].ReservationAttempts)
+	require.Equal(t, 1, scores[0].ReservationSuccesses)
+	require.NotZero(t, scores[0].RTT)
+	require.NotZero(t, scores[0].Throughput)
+}
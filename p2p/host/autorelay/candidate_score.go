@@ -0,0 +1,186 @@
+package autorelay
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/ping"
+)
+
+const (
+	// probeCount is the number of pings sent to a candidate to measure its quality.
+	probeCount = 3
+	// probeTimeout bounds the time spent probing a single candidate.
+	probeTimeout = 5 * time.Second
+
+	// unknownRTT is the RTT assumed by the DefaultCandidateScorer for candidates that
+	// couldn't be probed.
+	unknownRTT = 250 * time.Millisecond
+	// referenceRTT is the RTT at which the DefaultCandidateScorer halves the score.
+	referenceRTT = 100 * time.Millisecond
+	// minThroughput is the throughput in bytes per second at which the
+	// DefaultCandidateScorer halves the score.
+	minThroughput = 1 << 10
+)
+
+// CandidateScore is the measured quality of a relay candidate or of a relay we hold
+// a reservation with.
+type CandidateScore struct {
+	ID peer.ID
+	// RTT is the average round trip time measured when probing the peer. Zero if unknown.
+	RTT time.Duration
+	// Throughput is the throughput, in bytes per second, observed while probing the peer.
+	// The probe is small, so this mostly reflects the relay's load and the path's latency.
+	// Zero if unknown.
+	Throughput float64
+	// LastProbe is the time the peer was last probed.
+	LastProbe time.Time
+	// ReservationAttempts is the number of reservations requested from the peer,
+	// including refreshes.
+	ReservationAttempts int
+	// ReservationSuccesses is the number of successful reservation requests.
+	ReservationSuccesses int
+	// Score is the score assigned by the CandidateScorer. Higher is better.
+	Score float64
+}
+
+// CandidateScorer assigns a score to a relay candidate. AutoRelay requests reservations
+// from the candidates with the highest scores first.
+type CandidateScorer func(CandidateScore) float64
+
+// DefaultCandidateScorer prefers candidates with a low RTT that have granted our
+// reservation requests in the past. It halves the score for every referenceRTT of
+// latency and penalizes candidates with a very low throughput.
+func DefaultCandidateScorer(s CandidateScore) float64 {
+	// Laplace smoothing: peers we never asked for a reservation start at 0.5.
+	successRate := float64(s.ReservationSuccesses+1) / float64(s.ReservationAttempts+2)
+	rtt := s.RTT
+	if rtt <= 0 {
+		rtt = unknownRTT
+	}
+	score := successRate / (1 + float64(rtt)/float64(referenceRTT))
+	if s.Throughput > 0 {
+		score *= s.Throughput / (s.Throughput + minThroughput)
+	}
+	return score
+}
+
+// probe measures the RTT and throughput to the peer using the ping protocol. If the peer
+// doesn't respond to pings, it falls back to the latency recorded in the peerstore.
+func (rf *relayFinder) probe(ctx context.Context, p peer.ID) (rtt time.Duration, throughput float64) {
+	ctx, cancel := context.WithTimeout(ctx, probeTimeout)
+	defer cancel()
+
+	start := time.Now()
+	results := ping.Ping(ctx, rf.host, p)
+	var total time.Duration
+	var n int
+	for n < probeCount {
+		res, ok := <-results
+		if !ok || res.Error != nil {
+			break
+		}
+		total += res.RTT
+		n++
+	}
+	if n == 0 {
+		return rf.host.Peerstore().LatencyEWMA(p), 0
+	}
+	return total / time.Duration(n), float64(2*ping.PingSize*n) / time.Since(start).Seconds()
+}
+
+// recordProbe stores the result of probing p. Assumes caller holds candidateMx mutex.
+func (rf *relayFinder) recordProbe(p peer.ID, rtt time.Duration, throughput float64) {
+	s := rf.scoreLocked(p)
+	s.RTT = rtt
+	s.Throughput = throughput
+	s.LastProbe = rf.conf.clock.Now()
+}
+
+// recordReservation records the outcome of a reservation request with p.
+func (rf *relayFinder) recordReservation(p peer.ID, success bool) {
+	rf.candidateMx.Lock()
+	defer rf.candidateMx.Unlock()
+	s := rf.scoreLocked(p)
+	s.ReservationAttempts++
+	if success {
+		s.ReservationSuccesses++
+	}
+}
+
+// scoreLocked returns the score entry for p, creating it if necessary. Assumes caller
+// holds candidateMx mutex.
+func (rf *relayFinder) scoreLocked(p peer.ID) *CandidateScore {
+	s, ok := rf.scores[p]
+	if !ok {
+		s = &CandidateScore{ID: p}
+		rf.scores[p] = s
+	}
+	return s
+}
+
+// score returns the score of p. Assumes caller holds candidateMx mutex.
+func (rf *relayFinder) score(p peer.ID) float64 {
+	s, ok := rf.scores[p]
+	if !ok {
+		return rf.conf.scorer(CandidateScore{ID: p})
+	}
+	return rf.conf.scorer(*s)
+}
+
+// clearScores removes the scores of peers that are neither candidates, relays nor on
+// backoff.
+func (rf *relayFinder) clearScores() {
+	rf.relayMx.Lock()
+	relays := make(map[peer.ID]struct{}, len(rf.relays))
+	for p := range rf.relays {
+		relays[p] = struct{}{}
+	}
+	rf.relayMx.Unlock()
+
+	rf.candidateMx.Lock()
+	defer rf.candidateMx.Unlock()
+	for p := range rf.scores {
+		_, isCandidate := rf.candidates[p]
+		_, onBackoff := rf.backoff[p]
+		_, isRelay := relays[p]
+		if !isCandidate && !onBackoff && !isRelay {
+			delete(rf.scores, p)
+		}
+	}
+}
+
+// candidateScores returns the scores of all current candidates and relays, best first.
+func (rf *relayFinder) candidateScores() []CandidateScore {
+	rf.relayMx.Lock()
+	relays := make([]peer.ID, 0, len(rf.relays))
+	for p := range rf.relays {
+		relays = append(relays, p)
+	}
+	rf.relayMx.Unlock()
+
+	rf.candidateMx.Lock()
+	defer rf.candidateMx.Unlock()
+	scores := make([]CandidateScore, 0, len(rf.candidates)+len(relays))
+	add := func(p peer.ID) {
+		s := CandidateScore{ID: p}
+		if ss, ok := rf.scores[p]; ok {
+			s = *ss
+		}
+		s.Score = rf.conf.scorer(s)
+		scores = append(scores, s)
+	}
+	for p := range rf.candidates {
+		add(p)
+	}
+	for _, p := range relays {
+		if _, ok := rf.candidates[p]; !ok {
+			add(p)
+		}
+	}
+	slices.SortFunc(scores, func(a, b CandidateScore) int { return cmp.Compare(b.Score, a.Score) })
+	return scores
+}
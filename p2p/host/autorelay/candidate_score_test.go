@@ -0,0 +1,33 @@
+package autorelay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultCandidateScorer(t *testing.T) {
+	fast := CandidateScore{RTT: 10 * time.Millisecond}
+	slow := CandidateScore{RTT: 500 * time.Millisecond}
+	require.Greater(t, DefaultCandidateScorer(fast), DefaultCandidateScorer(slow))
+
+	// unknown RTT ranks between fast and slow peers
+	unknown := CandidateScore{}
+	require.Greater(t, DefaultCandidateScorer(fast), DefaultCandidateScorer(unknown))
+	require.Greater(t, DefaultCandidateScorer(unknown), DefaultCandidateScorer(slow))
+
+	// failed reservations lower the score
+	failing := fast
+	failing.ReservationAttempts = 4
+	require.Greater(t, DefaultCandidateScorer(fast), DefaultCandidateScorer(failing))
+	reliable := fast
+	reliable.ReservationAttempts = 4
+	reliable.ReservationSuccesses = 4
+	require.Greater(t, DefaultCandidateScorer(reliable), DefaultCandidateScorer(fast))
+
+	// very low throughput lowers the score
+	throttled := fast
+	throttled.Throughput = 100
+	require.Greater(t, DefaultCandidateScorer(fast), DefaultCandidateScorer(throttled))
+}
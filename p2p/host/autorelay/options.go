@@ -6,6 +6,10 @@ import (
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/backoff"
+	circuitv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/client"
+
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 // AutoRelay will call this function when it needs new candidates because it is
@@ -42,6 +46,16 @@ type config struct {
 	setMinCandidates bool
 	// see WithMetricsTracer
 	metricsTracer MetricsTracer
+	// see WithClientMetricsTracer
+	clientMetricsTracer circuitv2.MetricsTracer
+	// see WithCandidateScorer
+	scorer CandidateScorer
+	// see WithoutCandidateProbing
+	disableProbing bool
+	// see WithAddrFilters
+	addrFilters *ma.Filters
+	// see WithBackoffRegistry
+	backoffRegistry *backoff.Registry
 }
 
 var defaultConfig = config{
@@ -53,6 +67,7 @@ var defaultConfig = config{
 	desiredRelays:   2,
 	maxCandidateAge: 30 * time.Minute,
 	minInterval:     30 * time.Second,
+	scorer:          DefaultCandidateScorer,
 }
 
 var (
@@ -231,3 +246,55 @@ func WithMetricsTracer(mt MetricsTracer) Option {
 		return nil
 	}
 }
+
+// WithClientMetricsTracer configures autorelay to use mt to track the metrics of the
+// reservation requests made by the relay client
+func WithClientMetricsTracer(mt circuitv2.MetricsTracer) Option {
+	return func(c *config) error {
+		c.clientMetricsTracer = mt
+		return nil
+	}
+}
+
+// WithCandidateScorer sets the function used to rank relay candidates. Reservations are
+// requested from the candidates with the highest scores first. Defaults to
+// DefaultCandidateScorer.
+func WithCandidateScorer(s CandidateScorer) Option {
+	return func(c *config) error {
+		if s == nil {
+			return errors.New("candidate scorer must not be nil")
+		}
+		c.scorer = s
+		return nil
+	}
+}
+
+// WithoutCandidateProbing disables pinging new candidates to measure their RTT and
+// throughput. Candidates are then ranked using the latency recorded in the peerstore and
+// their reservation history only.
+func WithoutCandidateProbing() Option {
+	return func(c *config) error {
+		c.disableProbing = true
+		return nil
+	}
+}
+
+// WithAddrFilters ignores the relay addresses blocked by f. Candidates with only
+// blocked addresses are not used, and no relay addresses are advertised for blocked
+// addresses of the relays.
+func WithAddrFilters(f *ma.Filters) Option {
+	return func(c *config) error {
+		c.addrFilters = f
+		return nil
+	}
+}
+
+// WithBackoffRegistry makes AutoRelay skip the candidates backed off by r, and record
+// the failed connection attempts to candidates and relays in r. This is on top of the
+// backoff after failing to obtain a reservation, see WithBackoff.
+func WithBackoffRegistry(r *backoff.Registry) Option {
+	return func(c *config) error {
+		c.backoffRegistry = r
+		return nil
+	}
+}
@@ -2,9 +2,11 @@ package autorelay
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
 	"slices"
 	"sync"
@@ -16,6 +18,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/record"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
 	circuitv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/client"
 	circuitv2_proto "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/proto"
@@ -31,8 +34,8 @@ const protoIDv2 = circuitv2_proto.ProtoIDv2Hop
 // we call it a candidate, and consider using it as a relay.
 //
 // Relay: Out of the list candidates, the ones we have a reservation with.
-// Currently, we just randomly select a candidate, but we can employ more sophisticated
-// selection strategies here (e.g. by facotring in the RTT).
+// Candidates are probed when they're found and ranked by their CandidateScore, which
+// factors in the RTT, the observed throughput and past reservation successes.
 
 const (
 	rsvpRefreshInterval = time.Minute
@@ -66,6 +69,7 @@ type relayFinder struct {
 	candidateMx                sync.Mutex
 	candidates                 map[peer.ID]*candidate
 	backoff                    map[peer.ID]time.Time
+	scores                     map[peer.ID]*CandidateScore
 	maybeConnectToRelayTrigger chan struct{} // cap: 1
 	// Any time _something_ happens that might cause us to need new candidates.
 	// This could be
@@ -80,6 +84,8 @@ type relayFinder struct {
 	relays  map[peer.ID]*circuitv2.Reservation
 
 	circuitAddrs []ma.Multiaddr
+	// vouchers are the signed reservation vouchers of the relays of circuitAddrs.
+	vouchers map[peer.ID]*record.Envelope
 
 	// A channel that triggers a run of `runScheduledWork`.
 	triggerRunScheduledWork chan struct{}
@@ -107,6 +113,7 @@ func newRelayFinder(host host.Host, conf *config) (*relayFinder, error) {
 		peerSource:                 conf.peerSource,
 		candidates:                 make(map[peer.ID]*candidate),
 		backoff:                    make(map[peer.ID]time.Time),
+		scores:                     make(map[peer.ID]*CandidateScore),
 		candidateFound:             make(chan struct{}, 1),
 		maybeConnectToRelayTrigger: make(chan struct{}, 1),
 		maybeRequestNewCandidates:  make(chan struct{}, 1),
@@ -235,28 +242,41 @@ func (rf *relayFinder) background(ctx context.Context) {
 }
 
 func (rf *relayFinder) updateAddrs() {
-	oldAddrs := rf.circuitAddrs
-	rf.circuitAddrs = rf.getCircuitAddrs()
+	oldAddrs, oldVouchers := rf.circuitAddrs, rf.vouchers
+	rf.circuitAddrs, rf.vouchers = rf.getCircuitAddrs()
 
-	if areSortedAddrsDifferent(rf.circuitAddrs, oldAddrs) {
+	addrsChanged := areSortedAddrsDifferent(rf.circuitAddrs, oldAddrs)
+	if addrsChanged {
 		log.Debug("relay addresses updated", rf.circuitAddrs)
 		rf.metricsTracer.RelayAddressUpdated()
 		rf.metricsTracer.RelayAddressCount(len(rf.circuitAddrs))
-		if err := rf.emitter.Emit(event.EvtAutoRelayAddrsUpdated{RelayAddrs: slices.Clone(rf.circuitAddrs)}); err != nil {
+	}
+	// Refreshing a reservation renews its voucher. Emit the new vouchers even if the
+	// addresses didn't change, so that the advertised vouchers don't expire.
+	if addrsChanged || !maps.EqualFunc(rf.vouchers, oldVouchers, (*record.Envelope).Equal) {
+		if err := rf.emitter.Emit(event.EvtAutoRelayAddrsUpdated{
+			RelayAddrs: slices.Clone(rf.circuitAddrs),
+			Vouchers:   maps.Clone(rf.vouchers),
+		}); err != nil {
 			log.Error("failed to emit event.EvtAutoRelayAddrs with RelayAddrs", rf.circuitAddrs, err)
 		}
 	}
 }
 
-// This function returns the p2p-circuit addrs for the host.
+// This function returns the p2p-circuit addrs for the host, and the reservation vouchers
+// of their relays.
 // The returned addresses are of the form <relay's-addr>/p2p/<relay's-id>/p2p-circuit.
-func (rf *relayFinder) getCircuitAddrs() []ma.Multiaddr {
+func (rf *relayFinder) getCircuitAddrs() ([]ma.Multiaddr, map[peer.ID]*record.Envelope) {
 	rf.relayMx.Lock()
 	defer rf.relayMx.Unlock()
 
 	raddrs := make([]ma.Multiaddr, 0, 4*len(rf.relays)+4)
-	for p := range rf.relays {
-		addrs := cleanupAddressSet(rf.host.Peerstore().Addrs(p))
+	vouchers := make(map[peer.ID]*record.Envelope, len(rf.relays))
+	for p, rsvp := range rf.relays {
+		addrs := rf.filterAddrs(cleanupAddressSet(rf.host.Peerstore().Addrs(p)))
+		if len(addrs) > 0 && rsvp.SignedVoucher != nil {
+			vouchers[p] = rsvp.SignedVoucher
+		}
 		circuit := ma.StringCast(fmt.Sprintf("/p2p/%s/p2p-circuit", p))
 		for _, addr := range addrs {
 			pub := addr.Encapsulate(circuit)
@@ -269,7 +289,15 @@ func (rf *relayFinder) getCircuitAddrs() []ma.Multiaddr {
 	if len(raddrs) > maxRelayAddrs {
 		raddrs = raddrs[:maxRelayAddrs]
 	}
-	return raddrs
+	return raddrs, vouchers
+}
+
+// filterAddrs removes the addresses blocked by the address filters.
+func (rf *relayFinder) filterAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	if rf.conf.addrFilters == nil {
+		return addrs
+	}
+	return ma.FilterAddrs(addrs, func(a ma.Multiaddr) bool { return !rf.conf.addrFilters.AddrBlocked(a) })
 }
 
 func (rf *relayFinder) runScheduledWork(ctx context.Context, now time.Time, scheduledWork *scheduledWorkTimes, peerSourceRateLimiter chan<- struct{}) time.Time {
@@ -284,6 +312,7 @@ func (rf *relayFinder) runScheduledWork(ctx context.Context, now time.Time, sche
 
 	if now.After(scheduledWork.nextBackoff) {
 		scheduledWork.nextBackoff = rf.clearBackoff(now)
+		rf.clearScores()
 	}
 
 	if now.After(scheduledWork.nextOldCandidateCheck) {
@@ -428,6 +457,10 @@ func (rf *relayFinder) findNodes(ctx context.Context, peerSourceRateLimiter <-ch
 				log.Debugw("skipping node that we recently failed to obtain a reservation with", "id", pi.ID, "last attempt", rf.conf.clock.Since(backoffStart))
 				continue
 			}
+			if r := rf.conf.backoffRegistry; r != nil && r.PeerBackoff(pi.ID) {
+				log.Debugw("skipping node that we recently failed to connect to", "id", pi.ID)
+				continue
+			}
 			if numCandidates >= rf.conf.maxCandidates {
 				log.Debugw("skipping node. Already have enough candidates", "id", pi.ID, "num", numCandidates, "max", rf.conf.maxCandidates)
 				continue
@@ -488,6 +521,14 @@ func (rf *relayFinder) handleNewNode(ctx context.Context, pi peer.AddrInfo) (add
 		return false
 	}
 
+	if len(pi.Addrs) > 0 {
+		pi.Addrs = rf.filterAddrs(pi.Addrs)
+		if len(pi.Addrs) == 0 {
+			log.Debugf("node %s not accepted as a candidate: all addresses are filtered", pi.ID)
+			return false
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
 	defer cancel()
 	supportsV2, err := rf.tryNode(ctx, pi)
@@ -500,11 +541,18 @@ func (rf *relayFinder) handleNewNode(ctx context.Context, pi peer.AddrInfo) (add
 	}
 	rf.metricsTracer.CandidateChecked(true)
 
+	var rtt time.Duration
+	var throughput float64
+	if !rf.conf.disableProbing {
+		rtt, throughput = rf.probe(ctx, pi.ID)
+	}
+
 	rf.candidateMx.Lock()
 	if len(rf.candidates) > rf.conf.maxCandidates {
 		rf.candidateMx.Unlock()
 		return false
 	}
+	rf.recordProbe(pi.ID, rtt, throughput)
 	log.Debugw("node supports relay protocol", "peer", pi.ID, "supports circuit v2", supportsV2)
 	rf.addCandidate(&candidate{
 		added:           rf.conf.clock.Now(),
@@ -521,6 +569,7 @@ var errProtocolNotSupported = errors.New("doesn't speak circuit v2")
 // It does not modify any internal state.
 func (rf *relayFinder) tryNode(ctx context.Context, pi peer.AddrInfo) (supportsRelayV2 bool, err error) {
 	if err := rf.host.Connect(ctx, pi); err != nil {
+		rf.addPeerBackoff(pi.ID)
 		return false, fmt.Errorf("error connecting to relay %s: %w", pi.ID, err)
 	}
 
@@ -620,6 +669,9 @@ func (rf *relayFinder) maybeConnectToRelay(ctx context.Context) {
 			continue
 		}
 		rsvp, err := rf.connectToRelay(ctx, cand)
+		if cand.supportsRelayV2 {
+			rf.recordReservation(id, err == nil)
+		}
 		if err != nil {
 			log.Debugw("failed to connect to relay", "peer", id, "error", err)
 			rf.notifyMaybeNeedNewCandidates()
@@ -656,6 +708,7 @@ func (rf *relayFinder) connectToRelay(ctx context.Context, cand *candidate) (*ci
 	// make sure we're still connected.
 	if rf.host.Network().Connectedness(id) != network.Connected {
 		if err := rf.host.Connect(ctx, cand.ai); err != nil {
+			rf.addPeerBackoff(id)
 			rf.candidateMx.Lock()
 			rf.removeCandidate(cand.ai.ID)
 			rf.candidateMx.Unlock()
@@ -668,7 +721,7 @@ func (rf *relayFinder) connectToRelay(ctx context.Context, cand *candidate) (*ci
 	rf.candidateMx.Unlock()
 	var err error
 	if cand.supportsRelayV2 {
-		rsvp, err = circuitv2.Reserve(ctx, rf.host, cand.ai)
+		rsvp, err = circuitv2.Reserve(ctx, rf.host, cand.ai, circuitv2.WithReserveMetricsTracer(rf.conf.clientMetricsTracer))
 		if err != nil {
 			err = fmt.Errorf("failed to reserve slot: %w", err)
 		}
@@ -679,6 +732,14 @@ func (rf *relayFinder) connectToRelay(ctx context.Context, cand *candidate) (*ci
 	return rsvp, err
 }
 
+// addPeerBackoff records a failed connection attempt to p in the backoff registry, if
+// any.
+func (rf *relayFinder) addPeerBackoff(p peer.ID) {
+	if rf.conf.backoffRegistry != nil {
+		rf.conf.backoffRegistry.AddPeerBackoff(p)
+	}
+}
+
 func (rf *relayFinder) refreshReservations(ctx context.Context, now time.Time) bool {
 	rf.relayMx.Lock()
 
@@ -703,7 +764,8 @@ func (rf *relayFinder) refreshReservations(ctx context.Context, now time.Time) b
 }
 
 func (rf *relayFinder) refreshRelayReservation(ctx context.Context, p peer.ID) error {
-	rsvp, err := circuitv2.Reserve(ctx, rf.host, peer.AddrInfo{ID: p})
+	rsvp, err := circuitv2.Reserve(ctx, rf.host, peer.AddrInfo{ID: p}, circuitv2.WithReserveMetricsTracer(rf.conf.clientMetricsTracer))
+	rf.recordReservation(p, err == nil)
 
 	rf.relayMx.Lock()
 	if err != nil {
@@ -722,6 +784,10 @@ func (rf *relayFinder) refreshRelayReservation(ctx context.Context, p peer.ID) e
 	log.Debugw("refreshed relay slot reservation", "relay", p)
 	rf.relays[p] = rsvp
 	rf.relayMx.Unlock()
+	if rsvp.SignedVoucher != nil {
+		// advertise the renewed voucher
+		rf.notifyRelayReservationUpdated()
+	}
 	return nil
 }
 
@@ -759,11 +825,17 @@ func (rf *relayFinder) selectCandidates() []*candidate {
 		}
 	}
 
-	// TODO: better relay selection strategy; this just selects random relays,
-	// but we should probably use ping latency as the selection metric
+	// Shuffle first, so that candidates with equal scores are tried in random order.
 	rand.Shuffle(len(candidates), func(i, j int) {
 		candidates[i], candidates[j] = candidates[j], candidates[i]
 	})
+	scores := make(map[peer.ID]float64, len(candidates))
+	for _, cand := range candidates {
+		scores[cand.ai.ID] = rf.score(cand.ai.ID)
+	}
+	slices.SortStableFunc(candidates, func(a, b *candidate) int {
+		return cmp.Compare(scores[b.ai.ID], scores[a.ai.ID])
+	})
 	return candidates
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"net"
 	"slices"
 	"sync"
@@ -12,6 +13,8 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/record"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/basic/internal/backoff"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
@@ -37,6 +40,8 @@ type hostAddrs struct {
 	unreachableAddrs []ma.Multiaddr
 	unknownAddrs     []ma.Multiaddr
 	relayAddrs       []ma.Multiaddr
+	// relayVouchers are the reservation vouchers of the relays of relayAddrs.
+	relayVouchers map[peer.ID]*record.Envelope
 }
 
 type addrsManager struct {
@@ -146,7 +151,7 @@ func (a *addrsManager) NetNotifee() network.Notifiee {
 }
 
 func (a *addrsManager) triggerAddrsUpdate() {
-	a.updateAddrs(false, nil)
+	a.updateAddrs(false, nil, nil)
 	select {
 	case a.triggerAddrsUpdateChan <- struct{}{}:
 	default:
@@ -184,11 +189,13 @@ func (a *addrsManager) startBackgroundWorker() error {
 	}
 
 	var relayAddrs []ma.Multiaddr
+	var relayVouchers map[peer.ID]*record.Envelope
 	// update relay addrs in case we're private
 	select {
 	case e := <-autoRelayAddrsSub.Out():
 		if evt, ok := e.(event.EvtAutoRelayAddrsUpdated); ok {
 			relayAddrs = slices.Clone(evt.RelayAddrs)
+			relayVouchers = maps.Clone(evt.Vouchers)
 		}
 	default:
 	}
@@ -202,15 +209,15 @@ func (a *addrsManager) startBackgroundWorker() error {
 	}
 	// update addresses before starting the worker loop. This ensures that any address updates
 	// before calling addrsManager.Start are correctly reported after Start returns.
-	a.updateAddrs(true, relayAddrs)
+	a.updateAddrs(true, relayAddrs, relayVouchers)
 
 	a.wg.Add(1)
-	go a.background(autoRelayAddrsSub, autonatReachabilitySub, emitter, relayAddrs)
+	go a.background(autoRelayAddrsSub, autonatReachabilitySub, emitter, relayAddrs, relayVouchers)
 	return nil
 }
 
 func (a *addrsManager) background(autoRelayAddrsSub, autonatReachabilitySub event.Subscription,
-	emitter event.Emitter, relayAddrs []ma.Multiaddr,
+	emitter event.Emitter, relayAddrs []ma.Multiaddr, relayVouchers map[peer.ID]*record.Envelope,
 ) {
 	defer a.wg.Done()
 	defer func() {
@@ -228,7 +235,7 @@ func (a *addrsManager) background(autoRelayAddrsSub, autonatReachabilitySub even
 	defer ticker.Stop()
 	var previousAddrs hostAddrs
 	for {
-		currAddrs := a.updateAddrs(true, relayAddrs)
+		currAddrs := a.updateAddrs(true, relayAddrs, relayVouchers)
 		a.notifyAddrsChanged(emitter, previousAddrs, currAddrs)
 		previousAddrs = currAddrs
 		select {
@@ -238,6 +245,7 @@ func (a *addrsManager) background(autoRelayAddrsSub, autonatReachabilitySub even
 		case e := <-autoRelayAddrsSub.Out():
 			if evt, ok := e.(event.EvtAutoRelayAddrsUpdated); ok {
 				relayAddrs = slices.Clone(evt.RelayAddrs)
+				relayVouchers = maps.Clone(evt.Vouchers)
 			}
 		case e := <-autonatReachabilitySub.Out():
 			if evt, ok := e.(event.EvtLocalReachabilityChanged); ok {
@@ -251,7 +259,7 @@ func (a *addrsManager) background(autoRelayAddrsSub, autonatReachabilitySub even
 
 // updateAddrs updates the addresses of the host and returns the new updated
 // addrs
-func (a *addrsManager) updateAddrs(updateRelayAddrs bool, relayAddrs []ma.Multiaddr) hostAddrs {
+func (a *addrsManager) updateAddrs(updateRelayAddrs bool, relayAddrs []ma.Multiaddr, relayVouchers map[peer.ID]*record.Envelope) hostAddrs {
 	// Must lock while doing both recompute and update as this method is called from
 	// multiple goroutines.
 	a.addrsMx.Lock()
@@ -264,6 +272,7 @@ func (a *addrsManager) updateAddrs(updateRelayAddrs bool, relayAddrs []ma.Multia
 	}
 	if !updateRelayAddrs {
 		relayAddrs = a.currentAddrs.relayAddrs
+		relayVouchers = a.currentAddrs.relayVouchers
 	} else {
 		// Copy the callers slice
 		relayAddrs = slices.Clone(relayAddrs)
@@ -277,6 +286,7 @@ func (a *addrsManager) updateAddrs(updateRelayAddrs bool, relayAddrs []ma.Multia
 		unreachableAddrs: append(a.currentAddrs.unreachableAddrs[:0], currUnreachableAddrs...),
 		unknownAddrs:     append(a.currentAddrs.unknownAddrs[:0], currUnknownAddrs...),
 		relayAddrs:       append(a.currentAddrs.relayAddrs[:0], relayAddrs...),
+		relayVouchers:    relayVouchers,
 	}
 
 	return hostAddrs{
@@ -286,6 +296,7 @@ func (a *addrsManager) updateAddrs(updateRelayAddrs bool, relayAddrs []ma.Multia
 		unreachableAddrs: currUnreachableAddrs,
 		unknownAddrs:     currUnknownAddrs,
 		relayAddrs:       relayAddrs,
+		relayVouchers:    relayVouchers,
 	}
 }
 
@@ -296,7 +307,9 @@ func (a *addrsManager) notifyAddrsChanged(emitter event.Emitter, previous, curre
 			a.addrsReachabilityTracker.UpdateAddrs(current.localAddrs)
 		}
 	}
-	if areAddrsDifferent(previous.addrs, current.addrs) {
+	// The host also needs to sign a new peer record when the relay vouchers are renewed.
+	if areAddrsDifferent(previous.addrs, current.addrs) ||
+		!maps.EqualFunc(previous.relayVouchers, current.relayVouchers, (*record.Envelope).Equal) {
 		log.Debugf("host addresses updated: %s", current.localAddrs)
 		select {
 		case a.addrsUpdatedChan <- struct{}{}:
@@ -337,6 +350,14 @@ func (a *addrsManager) Addrs() []ma.Multiaddr {
 	return a.getAddrs(directAddrs, relayAddrs)
 }
 
+// RelayVouchers returns the reservation vouchers of the relays of the node's relay
+// addresses, keyed by relay.
+func (a *addrsManager) RelayVouchers() map[peer.ID]*record.Envelope {
+	a.addrsMx.RLock()
+	defer a.addrsMx.RUnlock()
+	return maps.Clone(a.currentAddrs.relayVouchers)
+}
+
 // getAddrs returns the node's dialable addresses. Mutates localAddrs
 func (a *addrsManager) getAddrs(localAddrs []ma.Multiaddr, relayAddrs []ma.Multiaddr) []ma.Multiaddr {
 	addrs := localAddrs
@@ -346,6 +367,7 @@ func (a *addrsManager) getAddrs(localAddrs []ma.Multiaddr, relayAddrs []ma.Multi
 		if len(relayAddrs) > 0 {
 			addrs = slices.DeleteFunc(addrs, manet.IsPublicAddr)
 			addrs = append(addrs, relayAddrs...)
+			addrs = a.appendWebRTCAddrs(addrs, relayAddrs)
 		}
 	}
 	// Make a copy. Consumers can modify the slice elements
@@ -356,6 +378,18 @@ func (a *addrsManager) getAddrs(localAddrs []ma.Multiaddr, relayAddrs []ma.Multi
 	return addrs
 }
 
+// appendWebRTCAddrs appends the /webrtc addresses derived from relayAddrs, if the host
+// listens on /webrtc.
+func (a *addrsManager) appendWebRTCAddrs(dst []ma.Multiaddr, relayAddrs []ma.Multiaddr) []ma.Multiaddr {
+	if !slices.ContainsFunc(a.listenAddrs(), webrtcAddr.Equal) {
+		return dst
+	}
+	for _, r := range relayAddrs {
+		dst = append(dst, r.Encapsulate(webrtcAddr))
+	}
+	return dst
+}
+
 // HolePunchAddrs returns all the host's direct public addresses, reachable or unreachable,
 // suitable for hole punching.
 func (a *addrsManager) HolePunchAddrs() []ma.Multiaddr {
@@ -389,7 +423,10 @@ func (a *addrsManager) getConfirmedAddrs(localAddrs []ma.Multiaddr) (reachableAd
 	return removeNotInSource(reachableAddrs, localAddrs), removeNotInSource(unreachableAddrs, localAddrs), removeNotInSource(unknownAddrs, localAddrs)
 }
 
-var p2pCircuitAddr = ma.StringCast("/p2p-circuit")
+var (
+	p2pCircuitAddr = ma.StringCast("/p2p-circuit")
+	webrtcAddr     = ma.StringCast("/webrtc")
+)
 
 func (a *addrsManager) getLocalAddrs() []ma.Multiaddr {
 	listenAddrs := a.listenAddrs()
@@ -405,8 +442,10 @@ func (a *addrsManager) getLocalAddrs() []ma.Multiaddr {
 	// The p2p-circuit listener reports its address as just /p2p-circuit. This is
 	// useless for dialing. Users need to manage their circuit addresses themselves,
 	// or use AutoRelay.
+	// The same goes for the /webrtc listener, whose addresses are derived from the
+	// relay addresses.
 	finalAddrs = slices.DeleteFunc(finalAddrs, func(a ma.Multiaddr) bool {
-		return a.Equal(p2pCircuitAddr)
+		return a.Equal(p2pCircuitAddr) || a.Equal(webrtcAddr)
 	})
 
 	// Remove any unspecified address from the list
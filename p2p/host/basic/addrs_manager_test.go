@@ -382,6 +382,22 @@ func TestAddrsManager(t *testing.T) {
 		}, 5*time.Second, 50*time.Millisecond)
 	})
 
+	t.Run("webrtc addrs derived from relay addrs", func(t *testing.T) {
+		webrtc := ma.StringCast("/webrtc")
+		am := newAddrsManagerTestCase(t, addrsManagerArgs{
+			ListenAddrs: func() []ma.Multiaddr { return []ma.Multiaddr{lhquic, webrtc} },
+		})
+		am.PushReachability(network.ReachabilityPrivate)
+		relayAddr := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1/p2p/QmdXGaeGiVA745XorV1jr11RHxB9z4fqykm6xCUPX1aTJo/p2p-circuit")
+		am.PushRelay([]ma.Multiaddr{relayAddr})
+
+		expectedAddrs := []ma.Multiaddr{relayAddr, relayAddr.Encapsulate(webrtc), lhquic}
+		require.EventuallyWithT(t, func(collect *assert.CollectT) {
+			assert.ElementsMatch(collect, am.Addrs(), expectedAddrs, "%s\n%s", am.Addrs(), expectedAddrs)
+			assert.ElementsMatch(collect, am.DirectAddrs(), []ma.Multiaddr{lhquic}, "%s", am.DirectAddrs())
+		}, 5*time.Second, 50*time.Millisecond)
+	})
+
 	t.Run("addrs factory gets relay addrs", func(t *testing.T) {
 		relayAddr := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1/p2p/QmdXGaeGiVA745XorV1jr11RHxB9z4fqykm6xCUPX1aTJo/p2p-circuit")
 		publicQUIC2 := ma.StringCast("/ip4/1.2.3.4/udp/2/quic-v1")
@@ -1,10 +1,13 @@
 package basichost
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"runtime/pprof"
 	"slices"
 	"sync"
 	"time"
@@ -23,15 +26,22 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/pstoremanager"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/relaysvc"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
+	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/autonatv2"
+	circuitproto "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/proto"
 	relayv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/relay"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/holepunch"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/nodeinfo"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/ping"
 	libp2pwebrtc "github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc"
 	libp2pwebtransport "github.com/TheNoobiCat/go-libp2p/p2p/transport/webtransport"
+	"github.com/TheNoobiCat/go-libp2p/x/rate"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/benbjohnson/clock"
 	logging "github.com/ipfs/go-log/v2"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
@@ -81,6 +91,10 @@ type BasicHost struct {
 	relayManager *relaysvc.RelayManager
 
 	negtimeout time.Duration
+	// inboundNegTimeout is the negotiation timeout of inbound streams.
+	inboundNegTimeout time.Duration
+	negotiations      *negotiationTracker
+	pprofLabels       bool
 
 	emitters struct {
 		evtLocalProtocolsUpdated event.Emitter
@@ -88,8 +102,10 @@ type BasicHost struct {
 	}
 
 	disableSignedPeerRecord bool
+	lazyIdentify            bool
 	signKey                 crypto.PrivKey
 	caBook                  peerstore.CertifiedAddrBook
+	selfAddrTTL             time.Duration
 
 	autoNATMx sync.RWMutex
 	autoNat   autonat.AutoNAT
@@ -97,9 +113,25 @@ type BasicHost struct {
 	autonatv2        *autonatv2.AutoNAT
 	addressManager   *addrsManager
 	addrsUpdatedChan chan struct{}
+
+	protocolVersion string
+	nodeInfo        *nodeinfo.Service
+
+	metricsRegisterer func(subsystem string) (prometheus.Registerer, bool)
+
+	pausedMx        sync.RWMutex
+	pausedProtocols map[protocol.ID]network.StreamErrorCode
 }
 
-var _ host.Host = (*BasicHost)(nil)
+var (
+	_ host.Host                 = (*BasicHost)(nil)
+	_ host.ProtocolPauser       = (*BasicHost)(nil)
+	_ host.StreamHandlerLimiter = (*BasicHost)(nil)
+	_ host.AddrsStreamOpener    = (*BasicHost)(nil)
+	_ host.UserAgentSetter      = (*BasicHost)(nil)
+
+	_ metricshelper.RegistererProvider = (*BasicHost)(nil)
+)
 
 // HostOpts holds options that can be passed to NewHost in order to
 // customize construction of the *BasicHost.
@@ -115,6 +147,23 @@ type HostOpts struct {
 	// DefaultNegotiationTimeout. If below 0, timeouts on streams will be
 	// deactivated.
 	NegotiationTimeout time.Duration
+	// InboundNegotiationTimeout, if above 0, replaces NegotiationTimeout for the
+	// inbound streams. Inbound streams that don't complete their negotiation in time
+	// are reset with network.StreamNegotiationTimeout.
+	InboundNegotiationTimeout time.Duration
+	// MaxNegotiatingStreamsPerPeer is the maximum number of inbound streams a peer can
+	// have negotiating their protocol. Streams above it are reset with
+	// network.StreamResourceLimitExceeded. Zero means unlimited.
+	MaxNegotiatingStreamsPerPeer int
+
+	// EnablePprofLabels tags the goroutines of the inbound stream handlers with pprof
+	// labels, the peer, the transport and the protocol, so that CPU and heap profiles
+	// can be attributed to peers and protocols.
+	EnablePprofLabels bool
+
+	// Clock, if set, is used by identify and hole punching to schedule their pushes and
+	// retries, e.g. a mock clock in tests.
+	Clock clock.Clock
 
 	// AddrsFactory holds a function which can be used to override or filter the result of Addrs.
 	// If omitted, there's no override or filtering, and the results of Addrs and AllAddrs are the same.
@@ -138,6 +187,10 @@ type HostOpts struct {
 	// UserAgent sets the user-agent for the host.
 	UserAgent string
 
+	// UserAgentForConn overrides the user-agent sent on a connection, if it returns a
+	// non-empty string.
+	UserAgentForConn func(network.Conn) string
+
 	// ProtocolVersion sets the protocol version for the host.
 	ProtocolVersion string
 
@@ -153,13 +206,62 @@ type HostOpts struct {
 	EnableMetrics bool
 	// PrometheusRegisterer is the PrometheusRegisterer used for metrics
 	PrometheusRegisterer prometheus.Registerer
+	// MetricsRegisterers, if set, selects the registerer of every metrics subsystem,
+	// instead of EnableMetrics and PrometheusRegisterer.
+	MetricsRegisterers *metricshelper.Registerers
 	// AutoNATv2MetricsTracker tracks AutoNATv2 address reachability metrics
 	AutoNATv2MetricsTracker MetricsTracker
 
 	// DisableIdentifyAddressDiscovery disables address discovery using peer provided observed addresses in identify
 	DisableIdentifyAddressDiscovery bool
+	// LazyIdentify defers identifying a connection until the first stream is opened on it.
+	LazyIdentify bool
+
+	// IdentifySignedRecordPolicy is how identify handles the peers that don't send a
+	// signed peer record.
+	IdentifySignedRecordPolicy identify.SignedRecordPolicy
+
+	// AddrFilters removes the addresses it blocks from the host's advertised addresses.
+	AddrFilters *ma.Filters
+	// AdvertisedHostname is advertised as /dns4 and /dns6 addresses in place of the
+	// host's public IP addresses. See HostnameAddrsFactory.
+	AdvertisedHostname string
+	// ReplaceIPAddrs stops advertising the public IP addresses when AdvertisedHostname
+	// is set. Otherwise both are advertised.
+	ReplaceIPAddrs bool
+
+	// SelfAddrTTL is how long the host's own addresses and signed peer record remain valid
+	// in its peerstore. The host refreshes them periodically while it runs. Zero means
+	// they never expire.
+	SelfAddrTTL time.Duration
+	// IdentifyPushDebounce is the time the host's addresses and protocols must remain
+	// unchanged before identify pushes them to connected peers. Zero disables debouncing.
+	IdentifyPushDebounce time.Duration
+	// IdentifyMinPushInterval is the minimum time between two identify pushes. Zero
+	// means pushes are sent as soon as possible.
+	IdentifyMinPushInterval time.Duration
+	// IdentifyPeerPushInterval is the minimum time between two identify pushes to the
+	// same peer. Zero means pushes aren't rate limited per peer.
+	IdentifyPeerPushInterval time.Duration
+	// IdentifyLANMode only advertises the private and link-local addresses to the peers
+	// on the same subnet as the host.
+	IdentifyLANMode bool
+
+	// IdentifyPeerRateLimit limits the identify requests a single peer can make. A zero limit
+	// disables per peer rate limiting.
+	IdentifyPeerRateLimit rate.Limit
+	// PingPeerRateLimit limits the ping streams a single peer can open. A zero limit disables
+	// per peer rate limiting.
+	PingPeerRateLimit rate.Limit
+
+	// Reputation is the registry the host's services report misbehaving peers to.
+	Reputation *reputation.Registry
 
 	AutoNATv2 *autonatv2.AutoNAT
+
+	// NodeInfoAllowlist are the peers allowed to request this host's NodeInfo using the
+	// node info protocol. The protocol is disabled if empty.
+	NodeInfoAllowlist []peer.ID
 }
 
 // NewHost constructs a new *BasicHost and activates it by attaching its stream and connection handlers to the given inet.Network.
@@ -186,7 +288,14 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 		ctx:                     hostCtx,
 		ctxCancel:               cancel,
 		disableSignedPeerRecord: opts.DisableSignedPeerRecord,
+		lazyIdentify:            opts.LazyIdentify,
 		addrsUpdatedChan:        make(chan struct{}, 1),
+		protocolVersion:         opts.ProtocolVersion,
+		selfAddrTTL:             peerstore.PermanentAddrTTL,
+		metricsRegisterer:       opts.metricsRegisterer,
+	}
+	if opts.SelfAddrTTL > 0 {
+		h.selfAddrTTL = opts.SelfAddrTTL
 	}
 
 	if h.emitters.evtLocalProtocolsUpdated, err = h.eventbus.Emitter(&event.EvtLocalProtocolsUpdated{}, eventbus.Stateful); err != nil {
@@ -204,19 +313,51 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 		identify.UserAgent(opts.UserAgent),
 		identify.ProtocolVersion(opts.ProtocolVersion),
 	}
+	if opts.UserAgentForConn != nil {
+		idOpts = append(idOpts, identify.UserAgentForConn(opts.UserAgentForConn))
+	}
 
 	// we can't set this as a default above because it depends on the *BasicHost.
 	if h.disableSignedPeerRecord {
 		idOpts = append(idOpts, identify.DisableSignedPeerRecord())
 	}
-	if opts.EnableMetrics {
+	if reg, ok := opts.metricsRegisterer(metricshelper.SubsystemIdentify); ok {
 		idOpts = append(idOpts,
 			identify.WithMetricsTracer(
-				identify.NewMetricsTracer(identify.WithRegisterer(opts.PrometheusRegisterer))))
+				identify.NewMetricsTracer(identify.WithRegisterer(reg))))
 	}
 	if opts.DisableIdentifyAddressDiscovery {
 		idOpts = append(idOpts, identify.DisableObservedAddrManager())
 	}
+	if opts.LazyIdentify {
+		idOpts = append(idOpts, identify.Lazy())
+	}
+	if opts.IdentifyPushDebounce > 0 {
+		idOpts = append(idOpts, identify.WithPushDebounce(opts.IdentifyPushDebounce))
+	}
+	if opts.IdentifyMinPushInterval > 0 {
+		idOpts = append(idOpts, identify.WithMinPushInterval(opts.IdentifyMinPushInterval))
+	}
+	if opts.IdentifyPeerPushInterval > 0 {
+		idOpts = append(idOpts, identify.WithPeerPushInterval(opts.IdentifyPeerPushInterval))
+	}
+	if opts.IdentifyLANMode {
+		idOpts = append(idOpts, identify.LANMode())
+	}
+	if opts.IdentifySignedRecordPolicy != identify.SignedRecordAccept {
+		idOpts = append(idOpts, identify.WithSignedPeerRecordPolicy(opts.IdentifySignedRecordPolicy))
+	}
+	if opts.IdentifyPeerRateLimit.RPS != 0 {
+		idOpts = append(idOpts, identify.WithPeerRateLimiter(newPeerRateLimiter(identify.ServiceName, opts.IdentifyPeerRateLimit, opts)))
+	}
+
+	if opts.Reputation != nil {
+		idOpts = append(idOpts, identify.WithReputationReporter(opts.Reputation))
+	}
+
+	if opts.Clock != nil {
+		idOpts = append(idOpts, identify.WithClock(opts.Clock))
+	}
 
 	h.ids, err = identify.NewIDService(h, idOpts...)
 	if err != nil {
@@ -227,10 +368,32 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 	if opts.AddrsFactory != nil {
 		addrFactory = opts.AddrsFactory
 	}
+	if f := opts.AddrFilters; f != nil {
+		factory := addrFactory
+		addrFactory = func(addrs []ma.Multiaddr) []ma.Multiaddr {
+			return ma.FilterAddrs(factory(addrs), func(a ma.Multiaddr) bool { return !f.AddrBlocked(a) })
+		}
+	}
+	if opts.AdvertisedHostname != "" {
+		hostnameFactory, err := HostnameAddrsFactory(opts.AdvertisedHostname, opts.ReplaceIPAddrs)
+		if err != nil {
+			return nil, err
+		}
+		factory := addrFactory
+		addrFactory = func(addrs []ma.Multiaddr) []ma.Multiaddr {
+			return hostnameFactory(factory(addrs))
+		}
+	}
 
 	var natmgr NATManager
 	if opts.NATManager != nil {
 		natmgr = opts.NATManager(h.Network())
+		if nm, ok := natmgr.(*natManager); ok {
+			if err := nm.setEventBus(h.eventbus); err != nil {
+				nm.Close()
+				return nil, fmt.Errorf("failed to create NAT manager emitter: %w", err)
+			}
+		}
 	}
 	var tfl func(ma.Multiaddr) transport.Transport
 	if s, ok := h.Network().(interface {
@@ -247,6 +410,7 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 	if h.autonatv2 != nil {
 		autonatv2Client = h.autonatv2
 	}
+	hostMetricsReg, hostMetricsEnabled := opts.metricsRegisterer(metricshelper.SubsystemHost)
 	h.addressManager, err = newAddrsManager(
 		h.eventbus,
 		natmgr,
@@ -256,8 +420,8 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 		h.ids,
 		h.addrsUpdatedChan,
 		autonatv2Client,
-		opts.EnableMetrics,
-		opts.PrometheusRegisterer,
+		hostMetricsEnabled,
+		hostMetricsReg,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create address service: %w", err)
@@ -267,9 +431,15 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 	h.Network().Notify(h.addressManager.NetNotifee())
 
 	if opts.EnableHolePunching {
-		if opts.EnableMetrics {
+		if opts.Reputation != nil {
+			opts.HolePunchingOptions = append([]holepunch.Option{holepunch.WithReputationReporter(opts.Reputation)}, opts.HolePunchingOptions...)
+		}
+		if opts.Clock != nil {
+			opts.HolePunchingOptions = append([]holepunch.Option{holepunch.WithClock(opts.Clock)}, opts.HolePunchingOptions...)
+		}
+		if reg, ok := opts.metricsRegisterer(metricshelper.SubsystemHolePunch); ok {
 			hpOpts := []holepunch.Option{
-				holepunch.WithMetricsTracer(holepunch.NewMetricsTracer(holepunch.WithRegisterer(opts.PrometheusRegisterer)))}
+				holepunch.WithMetricsTracer(holepunch.NewMetricsTracer(holepunch.WithRegisterer(reg)))}
 			opts.HolePunchingOptions = append(hpOpts, opts.HolePunchingOptions...)
 
 		}
@@ -282,6 +452,12 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 	if uint64(opts.NegotiationTimeout) != 0 {
 		h.negtimeout = opts.NegotiationTimeout
 	}
+	h.inboundNegTimeout = h.negtimeout
+	if opts.InboundNegotiationTimeout > 0 {
+		h.inboundNegTimeout = opts.InboundNegotiationTimeout
+	}
+	h.pprofLabels = opts.EnablePprofLabels
+	h.negotiations = newNegotiationTracker(opts.MaxNegotiatingStreamsPerPeer, hostMetricsReg, hostMetricsEnabled)
 
 	if opts.ConnManager == nil {
 		h.cmgr = &connmgr.NullConnMgr{}
@@ -291,18 +467,29 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 	}
 
 	if opts.EnableRelayService {
-		if opts.EnableMetrics {
+		if reg, ok := opts.metricsRegisterer(metricshelper.SubsystemRelay); ok {
 			// Prefer explicitly provided metrics tracer
 			metricsOpt := []relayv2.Option{
 				relayv2.WithMetricsTracer(
-					relayv2.NewMetricsTracer(relayv2.WithRegisterer(opts.PrometheusRegisterer)))}
+					relayv2.NewMetricsTracer(relayv2.WithRegisterer(reg)))}
 			opts.RelayServiceOpts = append(metricsOpt, opts.RelayServiceOpts...)
 		}
+		if opts.Reputation != nil {
+			opts.RelayServiceOpts = append([]relayv2.Option{relayv2.WithReputationReporter(opts.Reputation)}, opts.RelayServiceOpts...)
+		}
 		h.relayManager = relaysvc.NewRelayManager(h, opts.RelayServiceOpts...)
 	}
 
 	if opts.EnablePing {
-		h.pings = ping.NewPingService(h)
+		var pingOpts []ping.Option
+		if opts.PingPeerRateLimit.RPS != 0 {
+			pingOpts = append(pingOpts, ping.WithPeerRateLimiter(newPeerRateLimiter(ping.ServiceName, opts.PingPeerRateLimit, opts)))
+		}
+		h.pings = ping.NewPingService(h, pingOpts...)
+	}
+
+	if len(opts.NodeInfoAllowlist) > 0 {
+		h.nodeInfo = nodeinfo.NewService(h, h, opts.NodeInfoAllowlist...)
 	}
 
 	if !h.disableSignedPeerRecord {
@@ -317,7 +504,7 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create signed record for self: %w", err)
 		}
-		if _, err := h.caBook.ConsumePeerRecord(rec, peerstore.PermanentAddrTTL); err != nil {
+		if _, err := h.caBook.ConsumePeerRecord(rec, h.selfAddrTTL); err != nil {
 			return nil, fmt.Errorf("failed to persist signed record to peerstore: %w", err)
 		}
 	}
@@ -326,6 +513,25 @@ func NewHost(n network.Network, opts *HostOpts) (*BasicHost, error) {
 	return h, nil
 }
 
+// newPeerRateLimiter returns the per-peer rate limiter of a service, reporting its
+// metrics if the host metrics are enabled.
+func newPeerRateLimiter(service string, l rate.Limit, opts *HostOpts) *rate.PeerLimiter {
+	limiter := &rate.PeerLimiter{PeerLimit: l, Service: service}
+	if reg, ok := opts.metricsRegisterer(metricshelper.SubsystemHost); ok {
+		limiter.MetricsTracer = rate.NewMetricsTracer(rate.WithRegisterer(reg))
+	}
+	return limiter
+}
+
+// metricsRegisterer returns the registerer of the metrics subsystem, and false if
+// its metrics are disabled.
+func (opts *HostOpts) metricsRegisterer(subsystem string) (prometheus.Registerer, bool) {
+	if opts.MetricsRegisterers != nil {
+		return opts.MetricsRegisterers.For(subsystem)
+	}
+	return opts.PrometheusRegisterer, opts.EnableMetrics
+}
+
 // Start starts background tasks in the host
 // TODO: Return error and handle it in the caller?
 func (h *BasicHost) Start() {
@@ -346,7 +552,7 @@ func (h *BasicHost) Start() {
 		if err != nil {
 			log.Errorf("failed to create signed record: %w", err)
 		}
-		if _, err := h.caBook.ConsumePeerRecord(rec, peerstore.PermanentAddrTTL); err != nil {
+		if _, err := h.caBook.ConsumePeerRecord(rec, h.selfAddrTTL); err != nil {
 			log.Errorf("failed to persist signed record to peerstore: %w", err)
 		}
 	}
@@ -362,9 +568,26 @@ func (h *BasicHost) Start() {
 func (h *BasicHost) newStreamHandler(s network.Stream) {
 	before := time.Now()
 
-	if h.negtimeout > 0 {
-		if err := s.SetDeadline(time.Now().Add(h.negtimeout)); err != nil {
+	p := s.Conn().RemotePeer()
+	var labelCtx context.Context
+	if h.pprofLabels {
+		// The swarm runs every inbound stream on a goroutine of its own.
+		labelCtx = pprof.WithLabels(context.Background(), pprof.Labels(
+			metricshelper.PprofLabelPeer, metricshelper.PprofPeer(p),
+			metricshelper.PprofLabelTransport, s.Conn().ConnState().Transport,
+		))
+		pprof.SetGoroutineLabels(labelCtx)
+	}
+	if !h.negotiations.start(p) {
+		log.Debugf("too many streams negotiating their protocol: %s", p)
+		s.ResetWithError(network.StreamResourceLimitExceeded)
+		return
+	}
+
+	if h.inboundNegTimeout > 0 {
+		if err := s.SetDeadline(time.Now().Add(h.inboundNegTimeout)); err != nil {
 			log.Debug("setting stream deadline: ", err)
+			h.negotiations.done(p, negotiationFailed)
 			s.Reset()
 			return
 		}
@@ -373,6 +596,13 @@ func (h *BasicHost) newStreamHandler(s network.Stream) {
 	protoID, handle, err := h.Mux().Negotiate(s)
 	took := time.Since(before)
 	if err != nil {
+		if isTimeout(err) {
+			log.Debugf("protocol negotiation timed out: %s (took %s)", p, took)
+			h.negotiations.done(p, negotiationTimeout)
+			s.ResetWithError(network.StreamNegotiationTimeout)
+			return
+		}
+		h.negotiations.done(p, negotiationFailed)
 		if err == io.EOF {
 			logf := log.Debugf
 			if took > time.Second*10 {
@@ -385,8 +615,9 @@ func (h *BasicHost) newStreamHandler(s network.Stream) {
 		s.ResetWithError(network.StreamProtocolNegotiationFailed)
 		return
 	}
+	h.negotiations.done(p, negotiationSuccess)
 
-	if h.negtimeout > 0 {
+	if h.inboundNegTimeout > 0 {
 		if err := s.SetDeadline(time.Time{}); err != nil {
 			log.Debugf("resetting stream deadline: ", err)
 			s.Reset()
@@ -394,6 +625,12 @@ func (h *BasicHost) newStreamHandler(s network.Stream) {
 		}
 	}
 
+	if code, ok := h.pausedErrorCode(protoID); ok {
+		log.Debugf("refusing stream for paused protocol %s from %s", protoID, s.Conn().RemotePeer())
+		s.ResetWithError(code)
+		return
+	}
+
 	if err := s.SetProtocol(protoID); err != nil {
 		log.Debugf("error setting stream protocol: %s", err)
 		s.ResetWithError(network.StreamResourceLimitExceeded)
@@ -402,10 +639,23 @@ func (h *BasicHost) newStreamHandler(s network.Stream) {
 
 	log.Debugf("negotiated: %s (took %s)", protoID, took)
 
+	if h.lazyIdentify && protoID != identify.ID && protoID != identify.IDPush {
+		// Identify the connection in the background, the handler may need the
+		// peer's identify data.
+		h.ids.IdentifyWait(s.Conn())
+	}
+
+	if h.pprofLabels {
+		pprof.SetGoroutineLabels(pprof.WithLabels(labelCtx, pprof.Labels(metricshelper.PprofLabelProtocol, string(protoID))))
+	}
 	handle(protoID, s)
 }
 
-func (h *BasicHost) makeUpdatedAddrEvent(prev, current []ma.Multiaddr) *event.EvtLocalAddressesUpdated {
+// makeUpdatedAddrEvent returns the event for the change of the host's addresses from
+// prev to current, or nil if they didn't change. If vouchersRenewed is set, the event
+// is returned even if the addresses didn't change, to advertise the renewed vouchers in
+// a new signed peer record.
+func (h *BasicHost) makeUpdatedAddrEvent(prev, current []ma.Multiaddr, vouchersRenewed bool) *event.EvtLocalAddressesUpdated {
 	if prev == nil && current == nil {
 		return nil
 	}
@@ -437,7 +687,7 @@ func (h *BasicHost) makeUpdatedAddrEvent(prev, current []ma.Multiaddr) *event.Ev
 		evt.Removed = append(evt.Removed, updated)
 	}
 
-	if !addrsAdded && len(evt.Removed) == 0 {
+	if !addrsAdded && len(evt.Removed) == 0 && !vouchersRenewed {
 		return nil
 	}
 
@@ -463,28 +713,61 @@ func (h *BasicHost) makeSignedPeerRecord(addrs []ma.Multiaddr) (*record.Envelope
 	if err != nil {
 		peerRecordSize += 2 * len(k) // 1 for signature, 1 for public key
 	}
+	// advertise the reservation vouchers of the relays with the relay addresses
+	var vouchers [][]byte
+	for _, env := range h.relayVouchers(addrs) {
+		b, err := env.Marshal()
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal relay voucher: %w", err)
+		}
+		vouchers = append(vouchers, b)
+		peerRecordSize += len(b)
+	}
+	slices.SortFunc(vouchers, bytes.Compare)
 	// we want the final address list to be small for keeping the signed peer record in size
 	addrs = trimHostAddrList(addrs, maxPeerRecordSize-peerRecordSize-256) // 256 B of buffer
 	rec := peer.PeerRecordFromAddrInfo(peer.AddrInfo{
 		ID:    h.ID(),
 		Addrs: addrs,
 	})
+	rec.Vouchers = vouchers
 	return record.Seal(rec, h.signKey)
 }
 
+// relayVouchers returns the reservation vouchers of the relays of the relay addresses
+// in addrs.
+func (h *BasicHost) relayVouchers(addrs []ma.Multiaddr) map[peer.ID]*record.Envelope {
+	all := h.addressManager.RelayVouchers()
+	if len(all) == 0 {
+		return nil
+	}
+	vouchers := make(map[peer.ID]*record.Envelope, len(all))
+	for _, a := range addrs {
+		relay, err := circuitproto.RelayID(a)
+		if err != nil {
+			continue
+		}
+		if env, ok := all[relay]; ok {
+			vouchers[relay] = env
+		}
+	}
+	return vouchers
+}
+
 func (h *BasicHost) background() {
 	defer h.refCount.Done()
 	var lastAddrs []ma.Multiaddr
+	var lastVouchers map[peer.ID]*record.Envelope
 
-	emitAddrChange := func(currentAddrs []ma.Multiaddr, lastAddrs []ma.Multiaddr) {
-		changeEvt := h.makeUpdatedAddrEvent(lastAddrs, currentAddrs)
+	emitAddrChange := func(currentAddrs []ma.Multiaddr, lastAddrs []ma.Multiaddr, vouchersRenewed bool) {
+		changeEvt := h.makeUpdatedAddrEvent(lastAddrs, currentAddrs, vouchersRenewed)
 		if changeEvt == nil {
 			return
 		}
 		// Our addresses have changed.
 		// store the signed peer record in the peer store.
 		if !h.disableSignedPeerRecord {
-			if _, err := h.caBook.ConsumePeerRecord(changeEvt.SignedPeerRecord, peerstore.PermanentAddrTTL); err != nil {
+			if _, err := h.caBook.ConsumePeerRecord(changeEvt.SignedPeerRecord, h.selfAddrTTL); err != nil {
 				log.Errorf("failed to persist signed peer record in peer store, err=%s", err)
 				return
 			}
@@ -494,7 +777,7 @@ func (h *BasicHost) background() {
 		for _, ua := range changeEvt.Removed {
 			removedAddrs = append(removedAddrs, ua.Address)
 		}
-		h.Peerstore().SetAddrs(h.ID(), currentAddrs, peerstore.PermanentAddrTTL)
+		h.Peerstore().SetAddrs(h.ID(), currentAddrs, h.selfAddrTTL)
 		h.Peerstore().SetAddrs(h.ID(), removedAddrs, 0)
 
 		// emit addr change event
@@ -503,19 +786,43 @@ func (h *BasicHost) background() {
 		}
 	}
 
+	// Refresh our own addresses before they expire from the peerstore.
+	var refresh <-chan time.Time
+	if h.selfAddrTTL != peerstore.PermanentAddrTTL {
+		t := time.NewTicker(h.selfAddrTTL / 2)
+		defer t.Stop()
+		refresh = t.C
+	}
+
 	for {
 		curr := h.Addrs()
-		emitAddrChange(curr, lastAddrs)
-		lastAddrs = curr
+		vouchers := h.relayVouchers(curr)
+		emitAddrChange(curr, lastAddrs, !maps.EqualFunc(vouchers, lastVouchers, (*record.Envelope).Equal))
+		lastAddrs, lastVouchers = curr, vouchers
 
 		select {
 		case <-h.addrsUpdatedChan:
+		case <-refresh:
+			h.refreshSelfAddrs(lastAddrs)
 		case <-h.ctx.Done():
 			return
 		}
 	}
 }
 
+// refreshSelfAddrs extends the TTL of the host's addresses and signed peer record in the
+// peerstore.
+func (h *BasicHost) refreshSelfAddrs(addrs []ma.Multiaddr) {
+	if !h.disableSignedPeerRecord {
+		if rec := h.caBook.GetPeerRecord(h.ID()); rec != nil {
+			if _, err := h.caBook.ConsumePeerRecord(rec, h.selfAddrTTL); err != nil {
+				log.Errorf("failed to refresh signed peer record in peer store, err=%s", err)
+			}
+		}
+	}
+	h.Peerstore().SetAddrs(h.ID(), addrs, h.selfAddrTTL)
+}
+
 // ID returns the (local) peer.ID associated with this Host
 func (h *BasicHost) ID() peer.ID {
 	return h.Network().LocalPeer()
@@ -541,10 +848,27 @@ func (h *BasicHost) IDService() identify.IDService {
 	return h.ids
 }
 
+// SetUserAgent changes the user-agent the host identifies itself with, and pushes it
+// to the connected peers. An empty user-agent resets it to the default one. It's a
+// no-op if the identify service doesn't implement identify.UserAgentSetter.
+func (h *BasicHost) SetUserAgent(ua string) {
+	if s, ok := h.ids.(identify.UserAgentSetter); ok {
+		s.SetUserAgent(ua)
+		return
+	}
+	log.Warn("the identify service can't change the user-agent")
+}
+
 func (h *BasicHost) EventBus() event.Bus {
 	return h.eventbus
 }
 
+// MetricsRegisterer returns the registerer of the metrics subsystem, and false if its
+// metrics are disabled.
+func (h *BasicHost) MetricsRegisterer(subsystem string) (prometheus.Registerer, bool) {
+	return h.metricsRegisterer(subsystem)
+}
+
 // SetStreamHandler sets the protocol handler on the Host's Mux.
 // This is equivalent to:
 //
@@ -552,7 +876,8 @@ func (h *BasicHost) EventBus() event.Bus {
 //
 // (Thread-safe)
 func (h *BasicHost) SetStreamHandler(pid protocol.ID, handler network.StreamHandler) {
-	h.Mux().AddHandler(pid, func(_ protocol.ID, rwc io.ReadWriteCloser) error {
+	m := func(p protocol.ID) bool { return p == pid && !h.isRefused(p) }
+	h.Mux().AddHandlerWithFunc(pid, m, func(_ protocol.ID, rwc io.ReadWriteCloser) error {
 		is := rwc.(network.Stream)
 		handler(is)
 		return nil
@@ -565,7 +890,8 @@ func (h *BasicHost) SetStreamHandler(pid protocol.ID, handler network.StreamHand
 // SetStreamHandlerMatch sets the protocol handler on the Host's Mux
 // using a matching function to do protocol comparisons
 func (h *BasicHost) SetStreamHandlerMatch(pid protocol.ID, m func(protocol.ID) bool, handler network.StreamHandler) {
-	h.Mux().AddHandlerWithFunc(pid, m, func(_ protocol.ID, rwc io.ReadWriteCloser) error {
+	match := func(p protocol.ID) bool { return m(p) && !h.isRefused(p) }
+	h.Mux().AddHandlerWithFunc(pid, match, func(_ protocol.ID, rwc io.ReadWriteCloser) error {
 		is := rwc.(network.Stream)
 		handler(is)
 		return nil
@@ -583,6 +909,44 @@ func (h *BasicHost) RemoveStreamHandler(pid protocol.ID) {
 	})
 }
 
+// PauseProtocol refuses the inbound streams for pid until ResumeProtocol is called,
+// without removing its handler. If errCode is network.StreamNoError, pid is refused
+// during protocol negotiation, as if it wasn't supported. Otherwise the streams are
+// reset with errCode once negotiated.
+//
+// pid is still advertised to the peers. Refusing pid during negotiation only works
+// for the handlers set with SetStreamHandler and SetStreamHandlerMatch.
+func (h *BasicHost) PauseProtocol(pid protocol.ID, errCode network.StreamErrorCode) {
+	h.pausedMx.Lock()
+	defer h.pausedMx.Unlock()
+	if h.pausedProtocols == nil {
+		h.pausedProtocols = make(map[protocol.ID]network.StreamErrorCode)
+	}
+	h.pausedProtocols[pid] = errCode
+}
+
+// ResumeProtocol accepts the inbound streams for pid again.
+func (h *BasicHost) ResumeProtocol(pid protocol.ID) {
+	h.pausedMx.Lock()
+	defer h.pausedMx.Unlock()
+	delete(h.pausedProtocols, pid)
+}
+
+// pausedErrorCode returns the error code to reset the streams for pid with, if pid is
+// paused.
+func (h *BasicHost) pausedErrorCode(pid protocol.ID) (network.StreamErrorCode, bool) {
+	h.pausedMx.RLock()
+	defer h.pausedMx.RUnlock()
+	code, ok := h.pausedProtocols[pid]
+	return code, ok
+}
+
+// isRefused returns whether pid is paused and refused during protocol negotiation.
+func (h *BasicHost) isRefused(pid protocol.ID) bool {
+	code, ok := h.pausedErrorCode(pid)
+	return ok && code == network.StreamNoError
+}
+
 // NewStream opens a new stream to given peer p, and writes a p2p/protocol
 // header with given protocol.ID. If there is no connection to p, attempts
 // to create one. If ProtocolID is "", writes no header.
@@ -609,7 +973,7 @@ func (h *BasicHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.I
 		// TODO: It would be nicer to get the actual error from the swarm,
 		// but this will require some more work.
 		if errors.Is(err, network.ErrNoConn) {
-			return nil, errors.New("connection failed")
+			return nil, fmt.Errorf("connection failed: %w", err)
 		}
 		return nil, fmt.Errorf("failed to open stream: %w", err)
 	}
@@ -656,13 +1020,13 @@ func (h *BasicHost) NewStream(ctx context.Context, p peer.ID, pids ...protocol.I
 	select {
 	case err = <-errCh:
 		if err != nil {
-			return nil, fmt.Errorf("failed to negotiate protocol: %w", err)
+			return nil, fmt.Errorf("%w: %w", network.ErrNegotiationFailed, err)
 		}
 	case <-ctx.Done():
 		s.ResetWithError(network.StreamProtocolNegotiationFailed)
 		// wait for `SelectOneOf` to error out because of resetting the stream.
 		<-errCh
-		return nil, fmt.Errorf("failed to negotiate protocol: %w", ctx.Err())
+		return nil, fmt.Errorf("%w: %w", network.ErrNegotiationFailed, ctx.Err())
 	}
 
 	if err := s.SetProtocol(selected); err != nil {
@@ -686,6 +1050,13 @@ func (h *BasicHost) preferredProtocol(p peer.ID, pids []protocol.ID) (protocol.I
 	return out, nil
 }
 
+// NewStreamWithAddrs opens a new stream to peer p, like NewStream, dialing addrs
+// instead of the addresses of p in the peerstore if there is no connection to p.
+// addrs aren't added to the peerstore.
+func (h *BasicHost) NewStreamWithAddrs(ctx context.Context, p peer.ID, addrs []ma.Multiaddr, pids ...protocol.ID) (network.Stream, error) {
+	return h.NewStream(network.WithDialAddrs(ctx, addrs), p, pids...)
+}
+
 // Connect ensures there is a connection between this host and the peer with
 // given peer.ID. If there is not an active connection, Connect will issue a
 // h.Network.Dial, and block until a connection is open, or an error is returned.
@@ -697,7 +1068,10 @@ func (h *BasicHost) Connect(ctx context.Context, pi peer.AddrInfo) error {
 
 	forceDirect, _ := network.GetForceDirectDial(ctx)
 	canUseLimitedConn, _ := network.GetAllowLimitedConn(ctx)
-	if !forceDirect {
+	// With transport or address constraints, an existing connection might not be
+	// acceptable, the swarm checks when dialing.
+	constrained := network.GetTransportConstraint(ctx) != nil || network.GetAddrFilter(ctx) != nil
+	if !forceDirect && !constrained {
 		connectedness := h.Network().Connectedness(pi.ID)
 		if connectedness == network.Connected || (canUseLimitedConn && connectedness == network.Limited) {
 			return nil
@@ -721,6 +1095,10 @@ func (h *BasicHost) dialPeer(ctx context.Context, p peer.ID) error {
 	// returns. On the other hand, we don't _really_ need to wait for this.
 	//
 	// This is mostly here to preserve existing behavior.
+	if h.lazyIdentify {
+		log.Debugf("host %s finished dialing %s", h.ID(), p)
+		return nil
+	}
 	select {
 	case <-h.ids.IdentifyWait(c):
 	case <-ctx.Done():
@@ -775,6 +1153,88 @@ func (h *BasicHost) ConfirmedAddrs() (reachable []ma.Multiaddr, unreachable []ma
 	return h.addressManager.ConfirmedAddrs()
 }
 
+// NodeInfo returns a machine readable description of the host.
+//
+// Address confidence requires AutoNATv2 to be enabled, otherwise the reachability of
+// all addresses is unknown.
+func (h *BasicHost) NodeInfo() host.NodeInfo {
+	info := host.NodeInfo{
+		Version:         host.NodeInfoVersion,
+		PeerID:          h.ID(),
+		ProtocolVersion: h.protocolVersion,
+		ListenAddrs:     h.Network().ListenAddresses(),
+		Protocols:       h.Mux().Protocols(),
+		Reachability:    h.Reachability().String(),
+		Peers:           len(h.Network().Peers()),
+		Conns:           len(h.Network().Conns()),
+	}
+
+	if s, ok := h.ids.(identify.UserAgentSetter); ok {
+		info.AgentVersion = s.UserAgent()
+	}
+
+	confidence := make(map[string]host.AddrConfidence)
+	reachable, unreachable, _ := h.ConfirmedAddrs()
+	for _, a := range reachable {
+		confidence[string(a.Bytes())] = host.AddrConfidenceConfirmed
+	}
+	for _, a := range unreachable {
+		confidence[string(a.Bytes())] = host.AddrConfidenceUnreachable
+	}
+	relays := make(map[peer.ID]struct{})
+	for _, a := range h.Addrs() {
+		c, ok := confidence[string(a.Bytes())]
+		if !ok {
+			c = host.AddrConfidenceUnknown
+		}
+		info.Addrs = append(info.Addrs, host.NodeInfoAddr{Addr: a, Confidence: c})
+
+		if relayAddr, _ := ma.SplitFunc(a, func(c ma.Component) bool { return c.Code() == ma.P_CIRCUIT }); len(relayAddr) != len(a) {
+			if ai, err := peer.AddrInfoFromP2pAddr(relayAddr); err == nil {
+				relays[ai.ID] = struct{}{}
+			}
+		}
+	}
+	for p := range relays {
+		info.Relay.Relays = append(info.Relay.Relays, p)
+	}
+	info.Relay.Service = slices.Contains(info.Protocols, circuitproto.ProtoIDv2Hop)
+
+	transports := make(map[string]struct{})
+	for _, a := range info.ListenAddrs {
+		transports[metricshelper.GetTransport(a)] = struct{}{}
+	}
+	for t := range transports {
+		info.Transports = append(info.Transports, t)
+	}
+	slices.Sort(info.Transports)
+
+	if rm := h.Network().ResourceManager(); rm != nil {
+		_ = rm.ViewSystem(func(s network.ResourceScope) error {
+			info.Resources.System = s.Stat()
+			if l, ok := s.(interface{ Limit() rcmgr.Limit }); ok {
+				limit := l.Limit()
+				info.Resources.SystemLimits = &host.NodeInfoLimits{
+					Streams:         limit.GetStreamTotalLimit(),
+					StreamsInbound:  limit.GetStreamLimit(network.DirInbound),
+					StreamsOutbound: limit.GetStreamLimit(network.DirOutbound),
+					Conns:           limit.GetConnTotalLimit(),
+					ConnsInbound:    limit.GetConnLimit(network.DirInbound),
+					ConnsOutbound:   limit.GetConnLimit(network.DirOutbound),
+					FD:              limit.GetFDLimit(),
+					Memory:          limit.GetMemoryLimit(),
+				}
+			}
+			return nil
+		})
+		_ = rm.ViewTransient(func(s network.ResourceScope) error {
+			info.Resources.Transient = s.Stat()
+			return nil
+		})
+	}
+	return info
+}
+
 func trimHostAddrList(addrs []ma.Multiaddr, maxSize int) []ma.Multiaddr {
 	totalSize := 0
 	for _, a := range addrs {
@@ -874,6 +1334,9 @@ func (h *BasicHost) Close() error {
 		if h.autonatv2 != nil {
 			h.autonatv2.Close()
 		}
+		if h.nodeInfo != nil {
+			h.nodeInfo.Close()
+		}
 
 		_ = h.emitters.evtLocalProtocolsUpdated.Close()
 		_ = h.emitters.evtLocalAddrsUpdated.Close()
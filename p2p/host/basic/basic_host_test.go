@@ -1,11 +1,15 @@
 package basichost
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
 	"io"
 	"reflect"
+	"runtime/pprof"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -13,6 +17,7 @@ import (
 	"time"
 
 	"github.com/libp2p/go-libp2p-testing/race"
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
@@ -20,14 +25,20 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	"github.com/TheNoobiCat/go-libp2p/core/record"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/autonat"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+	circuitproto "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/proto"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
 
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/multiformats/go-multiaddr/matest"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -125,6 +136,86 @@ func TestSignedPeerRecordWithNoListenAddrs(t *testing.T) {
 	require.NotEmpty(t, rec.(*peer.PeerRecord).Addrs)
 }
 
+func TestSignedPeerRecordRelayVouchers(t *testing.T) {
+	h, err := NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h.Close()
+	h.Start()
+
+	relayPriv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	relay, err := peer.IDFromPrivateKey(relayPriv)
+	require.NoError(t, err)
+	relayAddr := ma.StringCast(fmt.Sprintf("/ip4/1.2.3.4/udp/1/quic-v1/p2p/%s/p2p-circuit", relay))
+	signVoucher := func(exp time.Time) *record.Envelope {
+		env, err := record.Seal(&circuitproto.ReservationVoucher{Relay: relay, Peer: h.ID(), Expiration: exp}, relayPriv)
+		require.NoError(t, err)
+		return env
+	}
+
+	rchEm, err := h.EventBus().Emitter(new(event.EvtLocalReachabilityChanged), eventbus.Stateful)
+	require.NoError(t, err)
+	defer rchEm.Close()
+	raEm, err := h.EventBus().Emitter(new(event.EvtAutoRelayAddrsUpdated), eventbus.Stateful)
+	require.NoError(t, err)
+	defer raEm.Close()
+	require.NoError(t, rchEm.Emit(event.EvtLocalReachabilityChanged{Reachability: network.ReachabilityPrivate}))
+
+	cab, ok := peerstore.GetCertifiedAddrBook(h.Peerstore())
+	require.True(t, ok)
+	requireVoucher := func(env *record.Envelope) {
+		t.Helper()
+		b, err := env.Marshal()
+		require.NoError(t, err)
+		var rec *peer.PeerRecord
+		require.Eventually(t, func() bool {
+			r, err := cab.GetPeerRecord(h.ID()).Record()
+			require.NoError(t, err)
+			rec = r.(*peer.PeerRecord)
+			return len(rec.Vouchers) == 1 && bytes.Equal(rec.Vouchers[0], b)
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, []ma.Multiaddr{relayAddr}, circuitproto.VouchedRelayAddrs(rec, time.Now()))
+	}
+
+	env := signVoucher(time.Now().Add(time.Hour))
+	require.NoError(t, raEm.Emit(event.EvtAutoRelayAddrsUpdated{
+		RelayAddrs: []ma.Multiaddr{relayAddr},
+		Vouchers:   map[peer.ID]*record.Envelope{relay: env},
+	}))
+	requireVoucher(env)
+
+	// A renewed voucher is advertised even though the addresses didn't change.
+	env = signVoucher(time.Now().Add(2 * time.Hour))
+	require.NoError(t, raEm.Emit(event.EvtAutoRelayAddrsUpdated{
+		RelayAddrs: []ma.Multiaddr{relayAddr},
+		Vouchers:   map[peer.ID]*record.Envelope{relay: env},
+	}))
+	requireVoucher(env)
+}
+
+func TestSelfAddrTTL(t *testing.T) {
+	const ttl = 300 * time.Millisecond
+	h, err := NewHost(swarmt.GenSwarm(t), &HostOpts{SelfAddrTTL: ttl})
+	require.NoError(t, err)
+	defer h.Close()
+	h.Start()
+
+	cab, ok := peerstore.GetCertifiedAddrBook(h.Peerstore())
+	require.True(t, ok)
+	// The addresses are refreshed before they expire.
+	for i := 0; i < 5; i++ {
+		require.NotEmpty(t, h.Peerstore().Addrs(h.ID()))
+		require.NotNil(t, cab.GetPeerRecord(h.ID()))
+		time.Sleep(ttl / 2)
+	}
+
+	// They expire once the host stops refreshing them.
+	h.Close()
+	require.Eventually(t, func() bool {
+		return len(h.Peerstore().Addrs(h.ID())) == 0 && cab.GetPeerRecord(h.ID()) == nil
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
 func TestProtocolHandlerEvents(t *testing.T) {
 	h, err := NewHost(swarmt.GenSwarm(t), nil)
 	require.NoError(t, err)
@@ -388,9 +479,44 @@ func TestHostProtoMismatch(t *testing.T) {
 	})
 
 	_, err := h2.NewStream(ctx, h1.ID(), "/foo", "/bar", "/baz/1.0.0")
-	if err == nil {
-		t.Fatal("expected new stream to fail")
+	require.ErrorIs(t, err, network.ErrNegotiationFailed)
+}
+
+func TestHostConnectErrors(t *testing.T) {
+	h, err := NewHost(swarmt.GenSwarm(t, swarmt.OptDialOnly), nil)
+	require.NoError(t, err)
+	defer h.Close()
+	h.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	p, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+
+	err = h.Connect(ctx, peer.AddrInfo{ID: p})
+	require.ErrorIs(t, err, network.ErrNoRemoteAddrs)
+	require.NotErrorIs(t, err, network.ErrAllDialsFailed)
+
+	// nobody listens on the address
+	other, err := NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	var addr ma.Multiaddr
+	for _, a := range other.Network().ListenAddresses() {
+		if _, err := a.ValueForProtocol(ma.P_TCP); err == nil {
+			addr = a
+			break
+		}
 	}
+	require.NotNil(t, addr)
+	other.Close()
+	err = h.Connect(ctx, peer.AddrInfo{ID: p, Addrs: []ma.Multiaddr{addr}})
+	require.ErrorIs(t, err, network.ErrAllDialsFailed)
+	var de *swarm.DialError
+	require.ErrorAs(t, err, &de)
+	require.Len(t, de.DialErrors, 1)
+	require.True(t, de.DialErrors[0].Address.Equal(addr))
 }
 
 func TestHostProtoPreknowledge(t *testing.T) {
@@ -468,6 +594,43 @@ func TestHostProtoPreknowledge(t *testing.T) {
 	s.Close()
 }
 
+func TestLazyIdentify(t *testing.T) {
+	h1, err := NewHost(swarmt.GenSwarm(t), &HostOpts{LazyIdentify: true})
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := NewHost(swarmt.GenSwarm(t), &HostOpts{LazyIdentify: true, UserAgent: "lazy"})
+	require.NoError(t, err)
+	defer h2.Close()
+	h1.Start()
+	h2.Start()
+
+	h2.SetStreamHandler("/foo", func(s network.Stream) { s.Close() })
+	identified := func(h host.Host, p peer.ID) bool {
+		av, err := h.Peerstore().Get(p, "AgentVersion")
+		return err == nil && av != nil
+	}
+
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	require.Never(t, func() bool {
+		return identified(h1, h2.ID()) || identified(h2, h1.ID())
+	}, 300*time.Millisecond, 50*time.Millisecond)
+
+	// The first stream triggers identify on both sides.
+	s, err := h1.NewStream(context.Background(), h2.ID(), "/foo")
+	require.NoError(t, err)
+	defer s.Close()
+	av, err := h1.Peerstore().Get(h2.ID(), "AgentVersion")
+	require.NoError(t, err)
+	require.Equal(t, "lazy", av)
+	protos, err := h1.Peerstore().GetProtocols(h2.ID())
+	require.NoError(t, err)
+	require.Contains(t, protos, protocol.ID("/foo"))
+
+	_, err = s.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	require.Eventually(t, func() bool { return identified(h2, h1.ID()) }, 5*time.Second, 10*time.Millisecond)
+}
+
 func TestNewDialOld(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -981,3 +1144,304 @@ func TestHostTimeoutNewStream(t *testing.T) {
 	require.Error(t, err)
 	require.ErrorContains(t, err, "context deadline exceeded")
 }
+
+func TestNodeInfo(t *testing.T) {
+	h, err := NewHost(swarmt.GenSwarm(t), &HostOpts{UserAgent: "nodeinfo-test/1.0", ProtocolVersion: "test/1.0"})
+	require.NoError(t, err)
+	defer h.Close()
+	h.Start()
+	h.SetStreamHandler("/test", func(s network.Stream) { s.Close() })
+
+	info := h.NodeInfo()
+	require.Equal(t, host.NodeInfoVersion, info.Version)
+	require.Equal(t, h.ID(), info.PeerID)
+	require.Equal(t, "nodeinfo-test/1.0", info.AgentVersion)
+	require.Equal(t, "test/1.0", info.ProtocolVersion)
+	require.Contains(t, info.Protocols, protocol.ID("/test"))
+	require.ElementsMatch(t, h.Network().ListenAddresses(), info.ListenAddrs)
+	require.Len(t, info.Addrs, len(h.Addrs()))
+	for _, a := range info.Addrs {
+		// AutoNATv2 is disabled
+		require.Equal(t, host.AddrConfidenceUnknown, a.Confidence)
+	}
+	require.Contains(t, info.Transports, "tcp")
+	require.Equal(t, "Unknown", info.Reachability)
+	require.Empty(t, info.Relay.Relays)
+	require.False(t, info.Relay.Service)
+
+	// the document roundtrips through JSON
+	b, err := json.Marshal(info)
+	require.NoError(t, err)
+	var decoded host.NodeInfo
+	require.NoError(t, json.Unmarshal(b, &decoded))
+	require.Equal(t, info.PeerID, decoded.PeerID)
+	require.Len(t, decoded.Addrs, len(info.Addrs))
+}
+
+func TestPauseProtocol(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h1, h2 := getHostPair(t)
+	defer h1.Close()
+	defer h2.Close()
+
+	const proto = "/paused"
+	h1.SetStreamHandler(proto, func(s network.Stream) {
+		s.Write([]byte("pong"))
+		s.Close()
+	})
+	readStream := func() error {
+		h2.Peerstore().RemoveProtocols(h1.ID(), proto)
+		s, err := h2.NewStream(ctx, h1.ID(), proto)
+		if err != nil {
+			return err
+		}
+		defer s.Close()
+		_, err = io.ReadAll(s)
+		return err
+	}
+	require.NoError(t, readStream())
+
+	pauser := h1.(host.ProtocolPauser)
+	pauser.PauseProtocol(proto, network.StreamRateLimited)
+	require.ErrorIs(t, readStream(), &network.StreamError{ErrorCode: network.StreamRateLimited, Remote: true})
+
+	pauser.PauseProtocol(proto, network.StreamNoError)
+	require.ErrorIs(t, readStream(), network.ErrNegotiationFailed)
+	require.Contains(t, h1.Mux().Protocols(), protocol.ID(proto))
+
+	pauser.ResumeProtocol(proto)
+	require.NoError(t, readStream())
+}
+
+func TestStreamHandlerLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h1, h2 := getHostPair(t)
+	defer h1.Close()
+	defer h2.Close()
+	limiter := h1.(host.StreamHandlerLimiter)
+
+	// newLimitedHost returns a host with a resource manager, connected to h2
+	newLimitedHost := func(t *testing.T) *BasicHost {
+		t.Helper()
+		rm, err := rcmgr.NewResourceManager(rcmgr.NewFixedLimiter(rcmgr.DefaultLimits.AutoScale()))
+		require.NoError(t, err)
+		h, err := NewHost(swarmt.GenSwarm(t, swarmt.WithSwarmOpts(swarm.WithResourceManager(rm))), nil)
+		require.NoError(t, err)
+		h.Start()
+		t.Cleanup(func() { h.Close() })
+		require.NoError(t, h2.Connect(ctx, peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}))
+		return h
+	}
+	openStream := func(h host.Host, proto protocol.ID) network.Stream {
+		t.Helper()
+		s, err := h2.NewStream(ctx, h.ID(), proto)
+		require.NoError(t, err)
+		// send the protocol negotiation, the stream may already be reset by the time
+		// this is written
+		s.Write([]byte("ping"))
+		return s
+	}
+	limitExceeded := &network.StreamError{ErrorCode: network.StreamResourceLimitExceeded, Remote: true}
+
+	t.Run("max streams", func(t *testing.T) {
+		h := newLimitedHost(t)
+		const proto = "/limited/streams"
+		streams := make(chan network.Stream, 2)
+		// the handler returns right away, the stream is kept open
+		h.SetStreamHandlerWithLimits(proto, host.StreamHandlerLimits{MaxStreams: 1}, func(s network.Stream) {
+			streams <- s
+		})
+		s1 := openStream(h, proto)
+		defer s1.Close()
+		hs1 := <-streams
+		s2 := openStream(h, proto)
+		defer s2.Close()
+		_, err := io.ReadAll(s2)
+		require.ErrorIs(t, err, limitExceeded)
+
+		// the slot is released when the stream is closed
+		hs1.Write([]byte("pong"))
+		hs1.Close()
+		b, err := io.ReadAll(s1)
+		require.NoError(t, err)
+		require.Equal(t, "pong", string(b))
+		require.Eventually(t, func() bool {
+			var n int
+			h.Network().ResourceManager().ViewProtocol(proto, func(s network.ProtocolScope) error {
+				n = s.Stat().NumStreamsInbound
+				return nil
+			})
+			return n == 0
+		}, 5*time.Second, 10*time.Millisecond)
+		s3 := openStream(h, proto)
+		defer s3.Close()
+		hs3 := <-streams
+		hs3.Close()
+		_, err = io.ReadAll(s3)
+		require.NoError(t, err)
+	})
+
+	t.Run("stream memory", func(t *testing.T) {
+		h := newLimitedHost(t)
+		const proto = "/limited/memory"
+		h.SetStreamHandlerWithLimits(proto, host.StreamHandlerLimits{StreamMemory: 1 << 40}, func(s network.Stream) {
+			s.Close()
+		})
+		// NewStream waits for the negotiation to finish unless h2 already learned the
+		// protocols of h from identify.
+		s, err := h2.NewStream(ctx, h.ID(), proto)
+		if err == nil {
+			defer s.Close()
+			s.Write([]byte("ping"))
+			_, err = io.ReadAll(s)
+		}
+		require.ErrorIs(t, err, limitExceeded)
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		const proto = "/limited/timeout"
+		done := make(chan struct{})
+		limiter.SetStreamHandlerWithLimits(proto, host.StreamHandlerLimits{Timeout: 50 * time.Millisecond}, func(s network.Stream) {
+			// the handler is stuck until the stream is reset
+			io.ReadAll(s)
+			close(done)
+		})
+		s := openStream(h1, proto)
+		defer s.Close()
+		_, err := io.ReadAll(s)
+		require.ErrorIs(t, err, &network.StreamError{ErrorCode: network.StreamHandlerTimeout, Remote: true})
+		<-done
+	})
+}
+
+func TestInboundNegotiationLimits(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h1, err := NewHost(swarmt.GenSwarm(t), &HostOpts{
+		InboundNegotiationTimeout:    200 * time.Millisecond,
+		MaxNegotiatingStreamsPerPeer: 1,
+	})
+	require.NoError(t, err)
+	defer h1.Close()
+	h1.Start()
+	h2, err := NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h2.Close()
+	h2.Start()
+	require.NoError(t, h2.Connect(ctx, peer.AddrInfo{ID: h1.ID(), Addrs: h1.Addrs()}))
+
+	// raw streams never negotiate their protocol
+	s1, err := h2.Network().NewStream(ctx, h1.ID())
+	require.NoError(t, err)
+	defer s1.Close()
+	s1.Write([]byte("x"))
+	require.Eventually(t, func() bool { return h1.NegotiatingStreams() == 1 }, time.Second, 10*time.Millisecond)
+
+	s2, err := h2.Network().NewStream(ctx, h1.ID())
+	require.NoError(t, err)
+	defer s2.Close()
+	s2.Write([]byte("x"))
+	_, err = s2.Read(make([]byte, 1))
+	require.ErrorIs(t, err, &network.StreamError{ErrorCode: network.StreamResourceLimitExceeded, Remote: true})
+
+	_, err = io.ReadAll(s1)
+	require.ErrorIs(t, err, &network.StreamError{ErrorCode: network.StreamNegotiationTimeout, Remote: true})
+	require.Eventually(t, func() bool { return h1.NegotiatingStreams() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+func TestNegotiatingStreamsGaugeSharedByHosts(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	gauge := func() float64 {
+		mfs, err := reg.Gather()
+		require.NoError(t, err)
+		for _, mf := range mfs {
+			if mf.GetName() == "libp2p_host_negotiating_streams" {
+				return mf.GetMetric()[0].GetGauge().GetValue()
+			}
+		}
+		t.Fatal("negotiating streams gauge not found")
+		return 0
+	}
+	t1 := newNegotiationTracker(0, reg, true)
+	t2 := newNegotiationTracker(0, reg, true)
+	initial := gauge()
+
+	p := test.RandPeerIDFatal(t)
+	require.True(t, t1.start(p))
+	require.True(t, t1.start(p))
+	require.True(t, t2.start(p))
+	require.Equal(t, initial+3, gauge())
+	t2.done(p, negotiationSuccess)
+	require.Equal(t, initial+2, gauge())
+	t1.done(p, negotiationSuccess)
+	t1.done(p, negotiationSuccess)
+	require.Equal(t, initial, gauge())
+}
+
+func TestNewStreamWithAddrs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h1, err := NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h1.Close()
+	h1.Start()
+	h2, err := NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h2.Close()
+	h2.Start()
+	h2.SetStreamHandler("/test", func(s network.Stream) { s.Close() })
+
+	// h1 doesn't know the addresses of h2
+	_, err = h1.NewStream(ctx, h2.ID(), "/test")
+	require.Error(t, err)
+
+	s, err := h1.NewStreamWithAddrs(ctx, h2.ID(), h2.Addrs(), "/test")
+	require.NoError(t, err)
+	require.Equal(t, protocol.ID("/test"), s.Protocol())
+	s.Close()
+}
+
+func TestStreamHandlerPprofLabels(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	h1, err := NewHost(swarmt.GenSwarm(t), &HostOpts{EnablePprofLabels: true})
+	require.NoError(t, err)
+	defer h1.Close()
+	h1.Start()
+	h2, err := NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h2.Close()
+	h2.Start()
+
+	handling := make(chan struct{})
+	done := make(chan struct{})
+	h1.SetStreamHandler("/pprof-test", func(s network.Stream) {
+		close(handling)
+		<-done
+		s.Close()
+	})
+	require.NoError(t, h2.Connect(ctx, peer.AddrInfo{ID: h1.ID(), Addrs: h1.Addrs()}))
+	s, err := h2.NewStream(ctx, h1.ID(), "/pprof-test")
+	require.NoError(t, err)
+	defer s.Close()
+	s.Write([]byte("x"))
+	select {
+	case <-handling:
+	case <-ctx.Done():
+		t.Fatal("stream wasn't handled")
+	}
+	defer close(done)
+
+	var buf bytes.Buffer
+	require.NoError(t, pprof.Lookup("goroutine").WriteTo(&buf, 1))
+	profile := buf.String()
+	require.Contains(t, profile, fmt.Sprintf("%q:%q", metricshelper.PprofLabelProtocol, "/pprof-test"))
+	require.Contains(t, profile, fmt.Sprintf("%q:%q", metricshelper.PprofLabelPeer, metricshelper.PprofPeer(h2.ID())))
+}
@@ -0,0 +1,94 @@
+package basichost
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+)
+
+// SetStreamHandlerWithLimits sets the protocol handler on the Host's Mux, like
+// SetStreamHandler, enforcing limits on the streams it handles.
+func (h *BasicHost) SetStreamHandlerWithLimits(pid protocol.ID, limits host.StreamHandlerLimits, handler network.StreamHandler) {
+	if limits.MaxStreams > 0 {
+		h.limitProtocolStreams(pid, limits.MaxStreams)
+	}
+	h.SetStreamHandler(pid, limitStreamHandler(pid, limits, handler))
+}
+
+// SetStreamHandlerMatchWithLimits sets the protocol handler on the Host's Mux using a
+// matching function, like SetStreamHandlerMatch, enforcing limits on the streams it
+// handles. MaxStreams applies to each matched protocol, from the first stream of the
+// protocol handled on.
+func (h *BasicHost) SetStreamHandlerMatchWithLimits(pid protocol.ID, m func(protocol.ID) bool, limits host.StreamHandlerLimits, handler network.StreamHandler) {
+	next := limitStreamHandler(pid, limits, handler)
+	if limits.MaxStreams > 0 {
+		var limited sync.Map
+		h.limitProtocolStreams(pid, limits.MaxStreams)
+		limited.Store(pid, struct{}{})
+		handler = func(s network.Stream) {
+			if _, ok := limited.LoadOrStore(s.Protocol(), struct{}{}); !ok {
+				h.limitProtocolStreams(s.Protocol(), limits.MaxStreams)
+			}
+			next(s)
+		}
+	} else {
+		handler = next
+	}
+	h.SetStreamHandlerMatch(pid, m, handler)
+}
+
+// limitProtocolStreams sets the inbound stream limit of the protocol scope of pid.
+// The resource manager then rejects the streams exceeding it when the protocol is
+// set on them, and the streams are reset with network.StreamResourceLimitExceeded.
+func (h *BasicHost) limitProtocolStreams(pid protocol.ID, maxStreams int) {
+	rm := h.Network().ResourceManager()
+	if rm == nil {
+		return
+	}
+	_ = rm.ViewProtocol(pid, func(s network.ProtocolScope) error {
+		l, ok := s.(rcmgr.ResourceScopeLimiter)
+		if !ok {
+			log.Warnw("the resource manager doesn't allow limiting the streams of the protocol", "protocol", pid)
+			return nil
+		}
+		cur := l.Limit()
+		l.SetLimit(rcmgr.BaseLimit{
+			Streams:         cur.GetStreamTotalLimit(),
+			StreamsInbound:  maxStreams,
+			StreamsOutbound: cur.GetStreamLimit(network.DirOutbound),
+			Conns:           cur.GetConnTotalLimit(),
+			ConnsInbound:    cur.GetConnLimit(network.DirInbound),
+			ConnsOutbound:   cur.GetConnLimit(network.DirOutbound),
+			FD:              cur.GetFDLimit(),
+			Memory:          cur.GetMemoryLimit(),
+		})
+		return nil
+	})
+}
+
+func limitStreamHandler(pid protocol.ID, limits host.StreamHandlerLimits, handler network.StreamHandler) network.StreamHandler {
+	return func(s network.Stream) {
+		if limits.StreamMemory > 0 {
+			if err := s.Scope().ReserveMemory(limits.StreamMemory, network.ReservationPriorityAlways); err != nil {
+				log.Debugw("failed to reserve stream memory", "protocol", pid, "peer", s.Conn().RemotePeer(), "error", err)
+				s.ResetWithError(network.StreamResourceLimitExceeded)
+				return
+			}
+			// the memory is released with the stream scope when the stream is closed
+		}
+
+		if limits.Timeout > 0 {
+			t := time.AfterFunc(limits.Timeout, func() {
+				log.Debugw("stream handler timed out", "protocol", pid, "peer", s.Conn().RemotePeer())
+				s.ResetWithError(network.StreamHandlerTimeout)
+			})
+			defer t.Stop()
+		}
+
+		handler(s)
+	}
+}
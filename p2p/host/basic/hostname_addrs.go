@@ -0,0 +1,89 @@
+package basichost
+
+import (
+	"fmt"
+	"net"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// HostnameAddrsFactory returns an AddrsFactory that advertises the public IP addresses
+// of the host under hostname: /ip4 addresses are advertised as /dns4/<hostname> and /ip6
+// addresses as /dns6/<hostname>. Secure websocket and webtransport addresses carry
+// hostname as SNI, so that clients request the right certificate. If replace is true,
+// the public IP addresses are not advertised anymore, otherwise both are advertised.
+// Private, loopback and relay addresses are advertised unchanged.
+//
+// The factory is applied after HostOpts.AddrsFactory, so the signed peer record
+// contains the same addresses.
+func HostnameAddrsFactory(hostname string, replace bool) (AddrsFactory, error) {
+	if hostname == "" {
+		return nil, fmt.Errorf("empty hostname")
+	}
+	if net.ParseIP(hostname) != nil {
+		return nil, fmt.Errorf("hostname %s is an IP address", hostname)
+	}
+	for _, p := range []string{"dns4", "dns6", "sni"} {
+		if _, err := ma.NewComponent(p, hostname); err != nil {
+			return nil, fmt.Errorf("invalid hostname %s: %w", hostname, err)
+		}
+	}
+	return func(addrs []ma.Multiaddr) []ma.Multiaddr {
+		out := make([]ma.Multiaddr, 0, len(addrs))
+		for _, a := range addrs {
+			ha := toHostnameAddr(a, hostname)
+			if ha == nil || !replace {
+				out = append(out, a)
+			}
+			if ha != nil {
+				out = append(out, ha)
+			}
+		}
+		return ma.Unique(out)
+	}, nil
+}
+
+// toHostnameAddr returns a with its public IP address replaced by hostname, or nil if a
+// isn't a public IP address.
+func toHostnameAddr(a ma.Multiaddr, hostname string) ma.Multiaddr {
+	if len(a) == 0 || !manet.IsPublicAddr(a) {
+		return nil
+	}
+	if _, err := a.ValueForProtocol(ma.P_CIRCUIT); err == nil {
+		return nil
+	}
+	var dnsProto string
+	switch a[0].Code() {
+	case ma.P_IP4:
+		dnsProto = "dns4"
+	case ma.P_IP6:
+		dnsProto = "dns6"
+	default:
+		return nil
+	}
+	dns, err := ma.NewComponent(dnsProto, hostname)
+	if err != nil {
+		return nil
+	}
+	sni, err := ma.NewComponent("sni", hostname)
+	if err != nil {
+		return nil
+	}
+	_, err = a.ValueForProtocol(ma.P_WEBTRANSPORT)
+	isWebTransport := err == nil
+
+	out := make(ma.Multiaddr, 0, len(a)+1)
+	out = append(out, *dns)
+	for _, c := range a[1:] {
+		if c.Code() == ma.P_SNI {
+			// replaced by the hostname below
+			continue
+		}
+		out = append(out, c)
+		if c.Code() == ma.P_TLS || (c.Code() == ma.P_QUIC_V1 && isWebTransport) {
+			out = append(out, *sni)
+		}
+	}
+	return out
+}
@@ -0,0 +1,86 @@
+package basichost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multiaddr/matest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostnameAddrsFactory(t *testing.T) {
+	addrs := []ma.Multiaddr{
+		ma.StringCast("/ip4/1.2.3.4/tcp/4001"),
+		ma.StringCast("/ip6/2600::1/udp/4001/quic-v1"),
+		ma.StringCast("/ip4/1.2.3.4/tcp/443/tls/ws"),
+		ma.StringCast("/ip4/1.2.3.4/tcp/443/tls/sni/other.example.com/ws"),
+		ma.StringCast("/ip4/1.2.3.4/udp/4001/quic-v1/webtransport"),
+		ma.StringCast("/ip4/192.168.1.1/tcp/4001"),
+		ma.StringCast("/ip4/5.6.7.8/tcp/4001/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit"),
+	}
+	converted := []ma.Multiaddr{
+		ma.StringCast("/dns4/example.com/tcp/4001"),
+		ma.StringCast("/dns6/example.com/udp/4001/quic-v1"),
+		ma.StringCast("/dns4/example.com/tcp/443/tls/sni/example.com/ws"),
+		ma.StringCast("/dns4/example.com/udp/4001/quic-v1/sni/example.com/webtransport"),
+	}
+	unchanged := addrs[5:]
+
+	t.Run("supplement", func(t *testing.T) {
+		f, err := HostnameAddrsFactory("example.com", false)
+		require.NoError(t, err)
+		matest.AssertMultiaddrsMatch(t, append(append([]ma.Multiaddr{}, addrs...), converted...), f(addrs))
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		f, err := HostnameAddrsFactory("example.com", true)
+		require.NoError(t, err)
+		matest.AssertMultiaddrsMatch(t, append(append([]ma.Multiaddr{}, unchanged...), converted...), f(addrs))
+	})
+
+	t.Run("invalid hostname", func(t *testing.T) {
+		for _, h := range []string{"", "1.2.3.4", "2001:db8::1", "example.com/foo"} {
+			_, err := HostnameAddrsFactory(h, false)
+			require.Error(t, err, h)
+		}
+	})
+}
+
+func TestAdvertisedHostnameInSignedPeerRecord(t *testing.T) {
+	public := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	h, err := NewHost(swarmt.GenSwarm(t), &HostOpts{
+		AddrsFactory: func(addrs []ma.Multiaddr) []ma.Multiaddr {
+			return append(addrs, public)
+		},
+		AdvertisedHostname: "example.com",
+		ReplaceIPAddrs:     true,
+	})
+	require.NoError(t, err)
+	defer h.Close()
+	h.Start()
+
+	dnsAddr := ma.StringCast("/dns4/example.com/tcp/4001")
+	require.Contains(t, h.Addrs(), dnsAddr)
+	require.NotContains(t, h.Addrs(), public)
+
+	cab, ok := peerstore.GetCertifiedAddrBook(h.Peerstore())
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		env := cab.GetPeerRecord(h.ID())
+		if env == nil {
+			return false
+		}
+		rec := peerRecordFromEnvelope(t, env)
+		matest.AssertMultiaddrsMatch(t, h.Addrs(), rec.Addrs)
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestAdvertisedHostnameInvalid(t *testing.T) {
+	_, err := NewHost(swarmt.GenSwarm(t), &HostOpts{AdvertisedHostname: "1.2.3.4"})
+	require.Error(t, err)
+}
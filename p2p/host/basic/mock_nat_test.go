@@ -14,6 +14,7 @@ import (
 	netip "net/netip"
 	reflect "reflect"
 
+	event "github.com/TheNoobiCat/go-libp2p/core/event"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -55,6 +56,20 @@ func (mr *MockNATMockRecorder) AddMapping(ctx, protocol, port any) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddMapping", reflect.TypeOf((*MockNAT)(nil).AddMapping), ctx, protocol, port)
 }
 
+// AddPinhole mocks base method.
+func (m *MockNAT) AddPinhole(ctx context.Context, protocol string, addr netip.AddrPort) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPinhole", ctx, protocol, addr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPinhole indicates an expected call of AddPinhole.
+func (mr *MockNATMockRecorder) AddPinhole(ctx, protocol, addr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPinhole", reflect.TypeOf((*MockNAT)(nil).AddPinhole), ctx, protocol, addr)
+}
+
 // Close mocks base method.
 func (m *MockNAT) Close() error {
 	m.ctrl.T.Helper()
@@ -84,6 +99,20 @@ func (mr *MockNATMockRecorder) GetMapping(protocol, port any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetMapping", reflect.TypeOf((*MockNAT)(nil).GetMapping), protocol, port)
 }
 
+// Mappings mocks base method.
+func (m *MockNAT) Mappings() []event.NATMapping {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Mappings")
+	ret0, _ := ret[0].([]event.NATMapping)
+	return ret0
+}
+
+// Mappings indicates an expected call of Mappings.
+func (mr *MockNATMockRecorder) Mappings() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Mappings", reflect.TypeOf((*MockNAT)(nil).Mappings))
+}
+
 // RemoveMapping mocks base method.
 func (m *MockNAT) RemoveMapping(ctx context.Context, protocol string, port int) error {
 	m.ctrl.T.Helper()
@@ -97,3 +126,31 @@ func (mr *MockNATMockRecorder) RemoveMapping(ctx, protocol, port any) *gomock.Ca
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveMapping", reflect.TypeOf((*MockNAT)(nil).RemoveMapping), ctx, protocol, port)
 }
+
+// RemovePinhole mocks base method.
+func (m *MockNAT) RemovePinhole(ctx context.Context, protocol string, addr netip.AddrPort) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RemovePinhole", ctx, protocol, addr)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RemovePinhole indicates an expected call of RemovePinhole.
+func (mr *MockNATMockRecorder) RemovePinhole(ctx, protocol, addr any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemovePinhole", reflect.TypeOf((*MockNAT)(nil).RemovePinhole), ctx, protocol, addr)
+}
+
+// Type mocks base method.
+func (m *MockNAT) Type() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Type")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// Type indicates an expected call of Type.
+func (mr *MockNATMockRecorder) Type() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Type", reflect.TypeOf((*MockNAT)(nil).Type))
+}
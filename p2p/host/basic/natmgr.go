@@ -2,14 +2,18 @@ package basichost
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"net/netip"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
 	inat "github.com/TheNoobiCat/go-libp2p/p2p/net/nat"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -30,20 +34,52 @@ func NewNATManager(net network.Network) NATManager {
 	return newNATManager(net)
 }
 
+// NewNATManagerWithBackend returns a NATManager constructor creating the port
+// mappings with the backend returned by discover, e.g. a PCP or a static backend,
+// instead of a UPnP or NAT-PMP gateway. IPv6 listen addresses are pinholed if the
+// backend supports it.
+func NewNATManagerWithBackend(discover func(context.Context) (inat.Backend, error)) func(network.Network) NATManager {
+	return func(net network.Network) NATManager {
+		return newNATManagerWithDiscovery(net, func(ctx context.Context) (nat, error) {
+			b, err := discover(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return inat.NewNAT(b), nil
+		})
+	}
+}
+
 type entry struct {
 	protocol string
 	port     int
+	// addr is the address of pinholes, it's invalid for port mappings.
+	addr netip.Addr
 }
 
 type nat interface {
 	AddMapping(ctx context.Context, protocol string, port int) error
 	RemoveMapping(ctx context.Context, protocol string, port int) error
 	GetMapping(protocol string, port int) (netip.AddrPort, bool)
+	AddPinhole(ctx context.Context, protocol string, addr netip.AddrPort) error
+	RemovePinhole(ctx context.Context, protocol string, addr netip.AddrPort) error
+	Mappings() []event.NATMapping
+	Type() string
 	io.Closer
 }
 
 // so we can mock it in tests
-var discoverNAT = func(ctx context.Context) (nat, error) { return inat.DiscoverNAT(ctx) }
+var discoverNAT = func(ctx context.Context) (nat, error) {
+	n, err := inat.DiscoverNAT(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// interfaceAddrs returns the addresses of the interfaces, to find the addresses to
+// pinhole for unspecified IPv6 listen addresses. Overridden in tests.
+var interfaceAddrs = manet.InterfaceMultiaddrs
 
 // natManager takes care of adding + removing port mappings to the nat.
 // Initialized with the host if it has a NATPortMap option enabled.
@@ -52,9 +88,15 @@ var discoverNAT = func(ctx context.Context) (nat, error) { return inat.DiscoverN
 //     as the network signals Listen() or ListenClose().
 //   - closing the natManager closes the nat and its mappings.
 type natManager struct {
-	net   network.Network
-	natMx sync.RWMutex
-	nat   nat
+	net      network.Network
+	discover func(context.Context) (nat, error)
+	natMx    sync.RWMutex
+	nat      nat
+
+	emitterMx sync.Mutex
+	emitter   event.Emitter
+	// lastMappings are the mappings of the last EvtNATMappingsUpdated emitted.
+	lastMappings []event.NATMapping
 
 	syncFlag chan struct{} // cap: 1
 
@@ -66,9 +108,14 @@ type natManager struct {
 }
 
 func newNATManager(net network.Network) *natManager {
+	return newNATManagerWithDiscovery(net, discoverNAT)
+}
+
+func newNATManagerWithDiscovery(net network.Network, discover func(context.Context) (nat, error)) *natManager {
 	ctx, cancel := context.WithCancel(context.Background())
 	nmgr := &natManager{
 		net:       net,
+		discover:  discover,
 		syncFlag:  make(chan struct{}, 1),
 		ctx:       ctx,
 		ctxCancel: cancel,
@@ -84,9 +131,82 @@ func newNATManager(net network.Network) *natManager {
 func (nmgr *natManager) Close() error {
 	nmgr.ctxCancel()
 	nmgr.refCount.Wait()
+
+	nmgr.emitterMx.Lock()
+	defer nmgr.emitterMx.Unlock()
+	if nmgr.emitter != nil {
+		return nmgr.emitter.Close()
+	}
+	return nil
+}
+
+// setEventBus makes the natManager emit EvtNATMappingsUpdated on bus.
+func (nmgr *natManager) setEventBus(bus event.Bus) error {
+	emitter, err := bus.Emitter(new(event.EvtNATMappingsUpdated), eventbus.Stateful)
+	if err != nil {
+		return err
+	}
+	nmgr.emitterMx.Lock()
+	nmgr.emitter = emitter
+	nmgr.emitterMx.Unlock()
+	// Emit the mappings made before the event bus was set.
+	nmgr.emitMappings()
 	return nil
 }
 
+// emitMappings emits EvtNATMappingsUpdated if the mappings changed since the last
+// event.
+func (nmgr *natManager) emitMappings() {
+	nmgr.emitterMx.Lock()
+	defer nmgr.emitterMx.Unlock()
+	if nmgr.emitter == nil {
+		return
+	}
+	nmgr.natMx.RLock()
+	n := nmgr.nat
+	nmgr.natMx.RUnlock()
+	if n == nil {
+		return
+	}
+
+	evt := event.EvtNATMappingsUpdated{
+		Backend:  n.Type(),
+		Mappings: n.Mappings(),
+	}
+	slices.SortFunc(evt.Mappings, compareNATMappings)
+	if nmgr.lastMappings != nil && slices.EqualFunc(evt.Mappings, nmgr.lastMappings, equalNATMappings) {
+		return
+	}
+	nmgr.lastMappings = evt.Mappings
+	if err := nmgr.emitter.Emit(evt); err != nil {
+		log.Errorw("failed to emit event", "error", err)
+	}
+}
+
+func compareNATMappings(a, b event.NATMapping) int {
+	if c := a.Internal.Compare(b.Internal); c != 0 {
+		return c
+	}
+	if a.Protocol < b.Protocol {
+		return -1
+	}
+	if a.Protocol > b.Protocol {
+		return 1
+	}
+	return 0
+}
+
+func equalNATMappings(a, b event.NATMapping) bool {
+	errString := func(err error) string {
+		if err == nil {
+			return ""
+		}
+		return err.Error()
+	}
+	return a.Protocol == b.Protocol && a.Internal == b.Internal && a.External == b.External &&
+		a.Pinhole == b.Pinhole && errString(a.Err) == errString(b.Err)
+}
+
 func (nmgr *natManager) HasDiscoveredNAT() bool {
 	nmgr.natMx.RLock()
 	defer nmgr.natMx.RUnlock()
@@ -107,7 +227,7 @@ func (nmgr *natManager) background(ctx context.Context) {
 
 	discoverCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
-	natInstance, err := discoverNAT(discoverCtx)
+	natInstance, err := nmgr.discover(discoverCtx)
 	if err != nil {
 		log.Info("DiscoverNAT error:", err)
 		return
@@ -124,10 +244,18 @@ func (nmgr *natManager) background(ctx context.Context) {
 	defer nmgr.net.StopNotify((*nmgrNetNotifiee)(nmgr))
 
 	nmgr.doSync() // sync one first.
+	nmgr.emitMappings()
+
+	// The mappings are renewed in the background, their status may change.
+	ticker := time.NewTicker(inat.MappingDuration / 3)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-nmgr.syncFlag:
 			nmgr.doSync() // sync when our listen addresses change.
+			nmgr.emitMappings()
+		case <-ticker.C:
+			nmgr.emitMappings()
 		case <-ctx.Done():
 			return
 		}
@@ -148,7 +276,53 @@ func (nmgr *natManager) doSync() {
 		nmgr.tracked[e] = false
 	}
 	var newAddresses []entry
-	for _, maddr := range nmgr.net.ListenAddresses() {
+	for _, e := range natEntries(nmgr.net.ListenAddresses()) {
+		if _, ok := nmgr.tracked[e]; ok {
+			nmgr.tracked[e] = true
+		} else {
+			newAddresses = append(newAddresses, e)
+		}
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Close old mappings
+	for e, v := range nmgr.tracked {
+		if !v {
+			if e.addr.IsValid() {
+				nmgr.nat.RemovePinhole(nmgr.ctx, e.protocol, netip.AddrPortFrom(e.addr, uint16(e.port)))
+			} else {
+				nmgr.nat.RemoveMapping(nmgr.ctx, e.protocol, e.port)
+			}
+			delete(nmgr.tracked, e)
+		}
+	}
+
+	// Create new mappings.
+	for _, e := range newAddresses {
+		if e.addr.IsValid() {
+			addr := netip.AddrPortFrom(e.addr, uint16(e.port))
+			if err := nmgr.nat.AddPinhole(nmgr.ctx, e.protocol, addr); err != nil {
+				if errors.Is(err, inat.ErrPinholesNotSupported) {
+					log.Debugf("not pinholing %s %s: %s", e.protocol, addr, err)
+				} else {
+					log.Errorf("failed to pinhole %s %s: %s", e.protocol, addr, err)
+				}
+			}
+		} else if err := nmgr.nat.AddMapping(nmgr.ctx, e.protocol, e.port); err != nil {
+			log.Errorf("failed to port-map %s port %d: %s", e.protocol, e.port, err)
+		}
+		nmgr.tracked[e] = false
+	}
+}
+
+// natEntries returns the port mappings and pinholes needed for listenAddrs. Every
+// port is mapped, and global IPv6 addresses are also pinholed.
+func natEntries(listenAddrs []ma.Multiaddr) []entry {
+	var entries []entry
+	var ifaceAddrs []ma.Multiaddr
+	for _, maddr := range listenAddrs {
 		// Strip the IP
 		maIP, rest := ma.SplitFirst(maddr)
 		if maIP == nil || len(rest) == 0 {
@@ -187,32 +361,37 @@ func (nmgr *natManager) doSync() {
 			// bug in multiaddr
 			panic(err)
 		}
-		e := entry{protocol: protocol, port: int(port)}
-		if _, ok := nmgr.tracked[e]; ok {
-			nmgr.tracked[e] = true
-		} else {
-			newAddresses = append(newAddresses, e)
-		}
-	}
+		entries = append(entries, entry{protocol: protocol, port: int(port)})
 
-	var wg sync.WaitGroup
-	defer wg.Wait()
-
-	// Close old mappings
-	for e, v := range nmgr.tracked {
-		if !v {
-			nmgr.nat.RemoveMapping(nmgr.ctx, e.protocol, e.port)
-			delete(nmgr.tracked, e)
+		if maIP.Protocol().Code != ma.P_IP6 {
+			continue
 		}
-	}
-
-	// Create new mappings.
-	for _, e := range newAddresses {
-		if err := nmgr.nat.AddMapping(nmgr.ctx, e.protocol, e.port); err != nil {
-			log.Errorf("failed to port-map %s port %d: %s", e.protocol, e.port, err)
+		var pinholeIPs []net.IP
+		if ip.IsUnspecified() {
+			if ifaceAddrs == nil {
+				ifaceAddrs, err = interfaceAddrs()
+				if err != nil {
+					log.Warnw("failed to get interface addresses", "error", err)
+				}
+			}
+			for _, a := range ifaceAddrs {
+				if c, _ := ma.SplitFirst(a); c != nil && c.Protocol().Code == ma.P_IP6 {
+					pinholeIPs = append(pinholeIPs, net.IP(c.RawValue()))
+				}
+			}
+		} else {
+			pinholeIPs = append(pinholeIPs, ip)
+		}
+		for _, pip := range pinholeIPs {
+			// Unique local addresses aren't reachable from the internet.
+			if !pip.IsGlobalUnicast() || pip.IsPrivate() {
+				continue
+			}
+			addr, _ := netip.AddrFromSlice(pip)
+			entries = append(entries, entry{protocol: protocol, port: int(port), addr: addr})
 		}
-		nmgr.tracked[e] = false
 	}
+	return entries
 }
 
 func (nmgr *natManager) GetMapping(addr ma.Multiaddr) ma.Multiaddr {
@@ -10,6 +10,8 @@ import (
 
 	ma "github.com/multiformats/go-multiaddr"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
 
 	"go.uber.org/mock/gomock"
@@ -106,3 +108,72 @@ func TestAddAndRemoveListeners(t *testing.T) {
 	mockNAT.EXPECT().RemoveMapping(gomock.Any(), "tcp", 1234).MaxTimes(1)
 	mockNAT.EXPECT().Close().MaxTimes(1)
 }
+
+func TestNATEntries(t *testing.T) {
+	origInterfaceAddrs := interfaceAddrs
+	defer func() { interfaceAddrs = origInterfaceAddrs }()
+	interfaceAddrs = func() ([]ma.Multiaddr, error) {
+		return []ma.Multiaddr{
+			ma.StringCast("/ip4/192.168.1.2"),
+			ma.StringCast("/ip6/::1"),
+			ma.StringCast("/ip6/fd00::2"),
+			ma.StringCast("/ip6/fe80::2"),
+			ma.StringCast("/ip6/2001:db8::2"),
+		}, nil
+	}
+
+	entries := natEntries([]ma.Multiaddr{
+		ma.StringCast("/ip4/0.0.0.0/tcp/1234"),
+		ma.StringCast("/ip4/127.0.0.1/tcp/1235"),
+		ma.StringCast("/ip6/::/udp/1236/quic-v1"),
+		ma.StringCast("/ip6/2001:db8::3/tcp/1237"),
+	})
+	require.ElementsMatch(t, []entry{
+		{protocol: "tcp", port: 1234},
+		{protocol: "udp", port: 1236},
+		{protocol: "udp", port: 1236, addr: netip.MustParseAddr("2001:db8::2")},
+		{protocol: "tcp", port: 1237},
+		{protocol: "tcp", port: 1237, addr: netip.MustParseAddr("2001:db8::3")},
+	}, entries)
+}
+
+func TestNATMappingsEvent(t *testing.T) {
+	mockNAT, reset := setupMockNAT(t)
+	defer reset()
+
+	sw := swarmt.GenSwarm(t)
+	defer sw.Close()
+	bus := eventbus.NewBus()
+	sub, err := bus.Subscribe(new(event.EvtNATMappingsUpdated))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	mapping := event.NATMapping{
+		Protocol: "udp",
+		Internal: netip.AddrPortFrom(netip.Addr{}, 1234),
+		External: netip.MustParseAddrPort("1.2.3.4:4321"),
+	}
+	mockNAT.EXPECT().Type().Return("PCP").AnyTimes()
+	mockNAT.EXPECT().Mappings().Return([]event.NATMapping{mapping}).AnyTimes()
+	m := newNATManager(sw)
+	require.NoError(t, m.setEventBus(bus))
+
+	select {
+	case e := <-sub.Out():
+		evt := e.(event.EvtNATMappingsUpdated)
+		require.Equal(t, "PCP", evt.Backend)
+		require.Equal(t, []event.NATMapping{mapping}, evt.Mappings)
+	case <-time.After(time.Second):
+		t.Fatal("didn't receive EvtNATMappingsUpdated")
+	}
+	// Unchanged mappings aren't emitted again.
+	m.emitMappings()
+	select {
+	case <-sub.Out():
+		t.Fatal("unexpected EvtNATMappingsUpdated")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	mockNAT.EXPECT().Close().MaxTimes(1)
+	require.NoError(t, m.Close())
+}
@@ -0,0 +1,127 @@
+package basichost
+
+import (
+	"errors"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const negotiationMetricNamespace = "libp2p_host"
+
+var (
+	negotiatingStreams = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: negotiationMetricNamespace,
+			Name:      "negotiating_streams",
+			Help:      "Number of inbound streams negotiating their protocol",
+		},
+	)
+	negotiationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: negotiationMetricNamespace,
+			Name:      "inbound_negotiations_total",
+			Help:      "Inbound stream protocol negotiations by result",
+		},
+		[]string{"result"},
+	)
+	negotiationCollectors = []prometheus.Collector{
+		negotiatingStreams,
+		negotiationsTotal,
+	}
+)
+
+// Results of the negotiation of an inbound stream.
+const (
+	negotiationSuccess = "success"
+	negotiationTimeout = "timeout"
+	negotiationLimit   = "limit"
+	negotiationFailed  = "failed"
+)
+
+// negotiationTracker tracks the inbound streams that are negotiating their protocol,
+// and bounds their number per peer.
+type negotiationTracker struct {
+	// maxPerPeer is the maximum number of streams a peer can have negotiating. Zero
+	// means unlimited.
+	maxPerPeer int
+	metrics    bool
+
+	mx      sync.Mutex
+	total   int
+	perPeer map[peer.ID]int
+}
+
+func newNegotiationTracker(maxPerPeer int, reg prometheus.Registerer, enableMetrics bool) *negotiationTracker {
+	if enableMetrics {
+		metricshelper.RegisterCollectors(reg, negotiationCollectors...)
+	}
+	return &negotiationTracker{
+		maxPerPeer: maxPerPeer,
+		metrics:    enableMetrics,
+		perPeer:    make(map[peer.ID]int),
+	}
+}
+
+// start tracks a stream of p starting its negotiation. It returns false if p has too
+// many streams negotiating.
+func (t *negotiationTracker) start(p peer.ID) bool {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.maxPerPeer > 0 && t.perPeer[p] >= t.maxPerPeer {
+		t.record(negotiationLimit)
+		return false
+	}
+	t.perPeer[p]++
+	t.total++
+	if t.metrics {
+		// the gauge is shared by the hosts of the process
+		negotiatingStreams.Inc()
+	}
+	return true
+}
+
+// done tracks the end of the negotiation of a stream of p.
+func (t *negotiationTracker) done(p peer.ID, result string) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	if t.perPeer[p] <= 1 {
+		delete(t.perPeer, p)
+	} else {
+		t.perPeer[p]--
+	}
+	t.total--
+	if t.metrics {
+		negotiatingStreams.Dec()
+	}
+	t.record(result)
+}
+
+func (t *negotiationTracker) record(result string) {
+	if t.metrics {
+		negotiationsTotal.WithLabelValues(result).Inc()
+	}
+}
+
+// count returns the number of streams negotiating.
+func (t *negotiationTracker) count() int {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return t.total
+}
+
+// NegotiatingStreams returns the number of inbound streams that are negotiating their
+// protocol.
+func (h *BasicHost) NegotiatingStreams() int {
+	return h.negotiations.count()
+}
+
+func isTimeout(err error) bool {
+	var nerr net.Error
+	return errors.Is(err, os.ErrDeadlineExceeded) || (errors.As(err, &nerr) && nerr.Timeout())
+}
@@ -0,0 +1,115 @@
+package basichost
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+)
+
+// PrewarmProtectTag is the connection manager tag under which peers are protected by
+// Prewarm, unless set with WithPrewarmProtectTag.
+const PrewarmProtectTag = "prewarm"
+
+const defaultPrewarmParallelism = 16
+
+// PrewarmResult is the result of prewarming the connection to a peer.
+type PrewarmResult struct {
+	Peer peer.ID
+	// Err is the error connecting to the peer, nil if the peer is connected.
+	Err error
+	// AlreadyConnected is true if the peer was connected before Prewarm was called.
+	AlreadyConnected bool
+	// Duration is the time it took to connect to the peer.
+	Duration time.Duration
+}
+
+type prewarmConfig struct {
+	parallelism int
+	protectTag  string
+}
+
+// PrewarmOption is an option for Prewarm.
+type PrewarmOption func(*prewarmConfig)
+
+// WithPrewarmParallelism sets the maximum number of peers connected to concurrently.
+// Defaults to 16.
+func WithPrewarmParallelism(n int) PrewarmOption {
+	return func(c *prewarmConfig) {
+		if n > 0 {
+			c.parallelism = n
+		}
+	}
+}
+
+// WithPrewarmProtectTag sets the connection manager tag under which the connected
+// peers are protected. An empty tag disables protection.
+func WithPrewarmProtectTag(tag string) PrewarmOption {
+	return func(c *prewarmConfig) {
+		c.protectTag = tag
+	}
+}
+
+// Prewarm connects to peers concurrently, with bounded parallelism, and returns a
+// result for every peer, in the same order. Peers that are already connected are not
+// dialed again. This is intended for application startup, where a known set of peers
+// must be connected quickly.
+//
+// Connected peers are protected in the connection manager with PrewarmProtectTag, so
+// that they are not pruned. The application unprotects them when they're not needed
+// anymore.
+func (h *BasicHost) Prewarm(ctx context.Context, peers []peer.AddrInfo, opts ...PrewarmOption) []PrewarmResult {
+	return Prewarm(ctx, h, peers, opts...)
+}
+
+// Prewarm connects h to peers. See (*BasicHost).Prewarm.
+func Prewarm(ctx context.Context, h host.Host, peers []peer.AddrInfo, opts ...PrewarmOption) []PrewarmResult {
+	cfg := prewarmConfig{
+		parallelism: defaultPrewarmParallelism,
+		protectTag:  PrewarmProtectTag,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	results := make([]PrewarmResult, len(peers))
+	sem := make(chan struct{}, cfg.parallelism)
+	var wg sync.WaitGroup
+	for i, pi := range peers {
+		results[i].Peer = pi.ID
+		if h.Network().Connectedness(pi.ID) == network.Connected {
+			results[i].AlreadyConnected = true
+			protectPrewarmed(h, pi.ID, cfg.protectTag)
+			continue
+		}
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func(res *PrewarmResult, pi peer.AddrInfo) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			start := time.Now()
+			res.Err = h.Connect(ctx, pi)
+			res.Duration = time.Since(start)
+			if res.Err == nil {
+				protectPrewarmed(h, pi.ID, cfg.protectTag)
+			}
+		}(&results[i], pi)
+	}
+	wg.Wait()
+	return results
+}
+
+func protectPrewarmed(h host.Host, p peer.ID, tag string) {
+	if tag != "" {
+		h.ConnManager().Protect(p, tag)
+	}
+}
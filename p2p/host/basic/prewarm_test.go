@@ -0,0 +1,60 @@
+package basichost
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type protectingConnMgr struct {
+	connmgr.NullConnMgr
+	mu        sync.Mutex
+	protected map[peer.ID]string
+}
+
+func (cm *protectingConnMgr) Protect(p peer.ID, tag string) {
+	cm.mu.Lock()
+	defer cm.mu.Unlock()
+	cm.protected[p] = tag
+}
+
+func TestPrewarm(t *testing.T) {
+	cm := &protectingConnMgr{protected: make(map[peer.ID]string)}
+	h, err := NewHost(swarmt.GenSwarm(t), &HostOpts{ConnManager: cm})
+	require.NoError(t, err)
+	h.Start()
+	defer h.Close()
+
+	var peers []peer.AddrInfo
+	for i := 0; i < 3; i++ {
+		other, err := NewHost(swarmt.GenSwarm(t), nil)
+		require.NoError(t, err)
+		other.Start()
+		defer other.Close()
+		peers = append(peers, peer.AddrInfo{ID: other.ID(), Addrs: other.Addrs()})
+	}
+	require.NoError(t, h.Connect(context.Background(), peers[0]))
+	// a peer without addresses
+	unreachable := test.RandPeerIDFatal(t)
+	peers = append(peers, peer.AddrInfo{ID: unreachable})
+
+	results := h.Prewarm(context.Background(), peers, WithPrewarmParallelism(2))
+	require.Len(t, results, 4)
+	require.True(t, results[0].AlreadyConnected)
+	for i, res := range results {
+		require.Equal(t, peers[i].ID, res.Peer)
+	}
+	for _, res := range results[:3] {
+		require.NoError(t, res.Err)
+		require.Equal(t, PrewarmProtectTag, cm.protected[res.Peer])
+	}
+	require.Error(t, results[3].Err)
+	require.NotContains(t, cm.protected, unreachable)
+}
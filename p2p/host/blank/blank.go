@@ -36,10 +36,16 @@ type BlankHost struct {
 }
 
 type config struct {
-	cmgr     connmgr.ConnManager
-	eventBus event.Bus
+	cmgr             connmgr.ConnManager
+	eventBus         event.Bus
+	mux              *mstream.MultistreamMuxer[protocol.ID]
+	middlewares      []StreamMiddleware
+	skipSignedRecord bool
 }
 
+// StreamMiddleware wraps the handler of inbound streams.
+type StreamMiddleware func(next network.StreamHandler) network.StreamHandler
+
 type Option = func(cfg *config)
 
 func WithConnectionManager(cmgr connmgr.ConnManager) Option {
@@ -54,6 +60,30 @@ func WithEventBus(eventBus event.Bus) Option {
 	}
 }
 
+// WithMultistreamMuxer sets the muxer used to negotiate the protocols of streams.
+func WithMultistreamMuxer(mux *mstream.MultistreamMuxer[protocol.ID]) Option {
+	return func(cfg *config) {
+		cfg.mux = mux
+	}
+}
+
+// WithStreamMiddleware wraps the handling of inbound streams, before protocol
+// negotiation, with the middlewares. The first middleware is the outermost one. This is
+// useful in tests, to observe, delay or reset streams.
+func WithStreamMiddleware(mw ...StreamMiddleware) Option {
+	return func(cfg *config) {
+		cfg.middlewares = append(cfg.middlewares, mw...)
+	}
+}
+
+// WithoutSignedRecord skips persisting a signed peer record for the host to the
+// peerstore. This allows using a peerstore that isn't a peerstore.CertifiedAddrBook.
+func WithoutSignedRecord() Option {
+	return func(cfg *config) {
+		cfg.skipSignedRecord = true
+	}
+}
+
 func NewBlankHost(n network.Network, options ...Option) *BlankHost {
 	cfg := config{
 		cmgr: &connmgr.NullConnMgr{},
@@ -65,9 +95,12 @@ func NewBlankHost(n network.Network, options ...Option) *BlankHost {
 	bh := &BlankHost{
 		n:        n,
 		cmgr:     cfg.cmgr,
-		mux:      mstream.NewMultistreamMuxer[protocol.ID](),
+		mux:      cfg.mux,
 		eventbus: cfg.eventBus,
 	}
+	if bh.mux == nil {
+		bh.mux = mstream.NewMultistreamMuxer[protocol.ID]()
+	}
 	if bh.eventbus == nil {
 		bh.eventbus = eventbus.NewBus(eventbus.WithMetricsTracer(eventbus.NewMetricsTracer()))
 	}
@@ -80,12 +113,18 @@ func NewBlankHost(n network.Network, options ...Option) *BlankHost {
 		return nil
 	}
 
-	n.SetStreamHandler(bh.newStreamHandler)
-
-	// persist a signed peer record for self to the peerstore.
-	if err := bh.initSignedRecord(); err != nil {
-		log.Errorf("error creating blank host, err=%s", err)
-		return nil
+	handler := bh.newStreamHandler
+	for i := len(cfg.middlewares) - 1; i >= 0; i-- {
+		handler = cfg.middlewares[i](handler)
+	}
+	n.SetStreamHandler(handler)
+
+	if !cfg.skipSignedRecord {
+		// persist a signed peer record for self to the peerstore.
+		if err := bh.initSignedRecord(); err != nil {
+			log.Errorf("error creating blank host, err=%s", err)
+			return nil
+		}
 	}
 
 	return bh
@@ -160,7 +199,7 @@ func (bh *BlankHost) NewStream(ctx context.Context, p peer.ID, protos ...protoco
 	selected, err := mstream.SelectOneOf(protos, s)
 	if err != nil {
 		s.Reset()
-		return nil, fmt.Errorf("failed to negotiate protocol: %w", err)
+		return nil, fmt.Errorf("%w: %w", network.ErrNegotiationFailed, err)
 	}
 
 	s.SetProtocol(selected)
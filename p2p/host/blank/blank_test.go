@@ -0,0 +1,84 @@
+package blankhost
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore/pstoremem"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+
+	mstream "github.com/multiformats/go-multistream"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithoutSignedRecord(t *testing.T) {
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+	ps, err := pstoremem.NewPeerstore()
+	require.NoError(t, err)
+	defer ps.Close()
+	require.NoError(t, ps.AddPrivKey(id, priv))
+
+	// hide the peerstore.CertifiedAddrBook implementation
+	s, err := swarm.NewSwarm(id, struct{ peerstore.Peerstore }{ps}, eventbus.NewBus())
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.Nil(t, NewBlankHost(s))
+	h := NewBlankHost(s, WithoutSignedRecord())
+	require.NotNil(t, h)
+	require.Nil(t, ps.GetPeerRecord(id))
+}
+
+func TestStreamMiddlewareAndMuxer(t *testing.T) {
+	// only written by the stream handler goroutine, read once the stream was handled
+	var order []string
+	mux := mstream.NewMultistreamMuxer[protocol.ID]()
+	h1 := NewBlankHost(swarmt.GenSwarm(t),
+		WithMultistreamMuxer(mux),
+		WithStreamMiddleware(
+			func(next network.StreamHandler) network.StreamHandler {
+				return func(s network.Stream) {
+					order = append(order, "outer")
+					next(s)
+				}
+			},
+			func(next network.StreamHandler) network.StreamHandler {
+				return func(s network.Stream) {
+					order = append(order, "inner")
+					next(s)
+				}
+			},
+		),
+	)
+	require.NotNil(t, h1)
+	defer h1.Close()
+	require.Same(t, mux, h1.Mux())
+
+	h2 := NewBlankHost(swarmt.GenSwarm(t))
+	require.NotNil(t, h2)
+	defer h2.Close()
+
+	handled := make(chan struct{})
+	h1.SetStreamHandler("/test", func(s network.Stream) {
+		s.Close()
+		close(handled)
+	})
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{ID: h1.ID(), Addrs: h1.Addrs()}))
+	s, err := h2.NewStream(context.Background(), h1.ID(), "/test")
+	require.NoError(t, err)
+	defer s.Close()
+	<-handled
+
+	require.Equal(t, []string{"outer", "inner"}, order)
+}
@@ -0,0 +1,129 @@
+package browsercheck
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+)
+
+var (
+	// ErrNoCertHash is the defect of /webtransport and /webrtc-direct addresses
+	// without certhash.
+	ErrNoCertHash = errors.New("no certhash")
+	// ErrInvalidCertHash is the defect of addresses with a certhash that isn't a
+	// SHA-256 multihash, the only hash function supported by browsers.
+	ErrInvalidCertHash = errors.New("invalid certhash")
+	// ErrNoSNI is the defect of secure WebSocket addresses with neither a DNS name
+	// nor an SNI, browsers can't verify the certificate of an IP address.
+	ErrNoSNI = errors.New("no DNS name or SNI")
+	// ErrBadPort is the defect of addresses with port 0 or a port blocked by browsers.
+	ErrBadPort = errors.New("bad port")
+	// ErrUnreachable is the defect of addresses the checker peer failed to dial.
+	ErrUnreachable = errors.New("unreachable from the checker peer")
+)
+
+// badPorts are the ports browsers refuse to fetch from, see
+// https://fetch.spec.whatwg.org/#bad-port. WebSocket and WebTransport are subject
+// to it, WebRTC isn't.
+var badPorts = map[int]struct{}{
+	1: {}, 7: {}, 9: {}, 11: {}, 13: {}, 15: {}, 17: {}, 19: {}, 20: {}, 21: {}, 22: {}, 23: {},
+	25: {}, 37: {}, 42: {}, 43: {}, 53: {}, 69: {}, 77: {}, 79: {}, 87: {}, 95: {}, 101: {},
+	102: {}, 103: {}, 104: {}, 109: {}, 110: {}, 111: {}, 113: {}, 115: {}, 117: {}, 119: {},
+	123: {}, 135: {}, 137: {}, 139: {}, 143: {}, 161: {}, 179: {}, 389: {}, 427: {}, 465: {},
+	512: {}, 513: {}, 514: {}, 515: {}, 526: {}, 530: {}, 531: {}, 532: {}, 540: {}, 548: {},
+	554: {}, 556: {}, 563: {}, 587: {}, 601: {}, 636: {}, 989: {}, 990: {}, 993: {}, 995: {},
+	1719: {}, 1720: {}, 1723: {}, 2049: {}, 3659: {}, 4045: {}, 4190: {}, 5060: {}, 5061: {},
+	6000: {}, 6566: {}, 6665: {}, 6666: {}, 6667: {}, 6668: {}, 6669: {}, 6679: {}, 6697: {},
+	10080: {},
+}
+
+type addrInfo struct {
+	browser      bool
+	secureWS     bool
+	webtransport bool
+	certhashes   []string
+	named        bool
+	port         int
+}
+
+func parseAddr(a ma.Multiaddr) addrInfo {
+	var (
+		info    addrInfo
+		tls     bool
+		circuit bool
+	)
+	for _, c := range a {
+		switch c.Code() {
+		case ma.P_DNS, ma.P_DNS4, ma.P_DNS6, ma.P_SNI:
+			info.named = true
+		case ma.P_TCP, ma.P_UDP:
+			info.port, _ = strconv.Atoi(c.Value())
+		case ma.P_TLS:
+			tls = true
+		case ma.P_WS:
+			info.secureWS = tls
+		case ma.P_WSS:
+			info.secureWS = true
+		case ma.P_WEBTRANSPORT:
+			info.browser = true
+			info.webtransport = true
+		case ma.P_WEBRTC_DIRECT:
+			info.browser = true
+		case ma.P_CERTHASH:
+			info.certhashes = append(info.certhashes, c.Value())
+		case ma.P_CIRCUIT:
+			circuit = true
+		}
+	}
+	info.browser = (info.browser || info.secureWS) && !circuit
+	return info
+}
+
+// isBrowserAddr returns whether a is a public address that browsers can dial.
+func isBrowserAddr(a ma.Multiaddr) bool {
+	return parseAddr(a).browser && manet.IsPublicAddr(a)
+}
+
+// checkAddr returns the defect of the browser-facing address a that can be found
+// without dialing it, or nil.
+func checkAddr(a ma.Multiaddr) error {
+	info := parseAddr(a)
+	if info.port == 0 {
+		return fmt.Errorf("%w: 0", ErrBadPort)
+	}
+	if info.secureWS {
+		if _, ok := badPorts[info.port]; ok {
+			return fmt.Errorf("%w: %d", ErrBadPort, info.port)
+		}
+		if !info.named {
+			return ErrNoSNI
+		}
+		return nil
+	}
+	// webtransport or webrtc-direct
+	if _, ok := badPorts[info.port]; ok && info.webtransport {
+		return fmt.Errorf("%w: %d", ErrBadPort, info.port)
+	}
+	if len(info.certhashes) == 0 {
+		return ErrNoCertHash
+	}
+	for _, s := range info.certhashes {
+		_, b, err := multibase.Decode(s)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidCertHash, err)
+		}
+		dh, err := multihash.Decode(b)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidCertHash, err)
+		}
+		if dh.Code != multihash.SHA2_256 {
+			return fmt.Errorf("%w: hash function %s isn't supported", ErrInvalidCertHash, multihash.Codes[dh.Code])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package browsercheck
+
+import (
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multibase"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+func certhash(t *testing.T, code uint64) string {
+	t.Helper()
+	mh, err := multihash.Sum([]byte("cert"), code, -1)
+	require.NoError(t, err)
+	s, err := multibase.Encode(multibase.Base58BTC, mh)
+	require.NoError(t, err)
+	return s
+}
+
+func TestCheckAddr(t *testing.T) {
+	sha256 := certhash(t, multihash.SHA2_256)
+	sha512 := certhash(t, multihash.SHA2_512)
+
+	for _, tc := range []struct {
+		addr    string
+		browser bool
+		err     error
+	}{
+		{addr: "/ip4/1.2.3.4/tcp/443/ws"},
+		{addr: "/ip4/1.2.3.4/udp/443/quic-v1"},
+		{addr: "/ip4/1.2.3.4/tcp/1/p2p/12D3KooWAbhtHHvPmAgnubLu9ozbkcSqUw9vVTR3Jb6sbFtsGXAF/p2p-circuit/webrtc"},
+		{addr: "/ip4/192.168.1.1/udp/443/quic-v1/webtransport/certhash/" + sha256},
+		{addr: "/dns4/example.com/tcp/443/tls/ws", browser: true},
+		{addr: "/dns4/example.com/tcp/443/wss", browser: true},
+		{addr: "/ip4/1.2.3.4/tcp/443/tls/sni/example.com/ws", browser: true},
+		{addr: "/ip4/1.2.3.4/tcp/443/tls/ws", browser: true, err: ErrNoSNI},
+		{addr: "/dns4/example.com/tcp/6667/tls/ws", browser: true, err: ErrBadPort},
+		{addr: "/ip4/1.2.3.4/udp/443/quic-v1/webtransport/certhash/" + sha256, browser: true},
+		{addr: "/ip4/1.2.3.4/udp/443/quic-v1/webtransport", browser: true, err: ErrNoCertHash},
+		{addr: "/ip4/1.2.3.4/udp/443/quic-v1/webtransport/certhash/" + sha512, browser: true, err: ErrInvalidCertHash},
+		{addr: "/ip4/1.2.3.4/udp/0/quic-v1/webtransport/certhash/" + sha256, browser: true, err: ErrBadPort},
+		{addr: "/ip4/1.2.3.4/udp/5060/quic-v1/webtransport/certhash/" + sha256, browser: true, err: ErrBadPort},
+		{addr: "/ip4/1.2.3.4/udp/5060/webrtc-direct/certhash/" + sha256, browser: true},
+		{addr: "/ip4/1.2.3.4/udp/443/webrtc-direct", browser: true, err: ErrNoCertHash},
+	} {
+		t.Run(tc.addr, func(t *testing.T) {
+			a := ma.StringCast(tc.addr)
+			require.Equal(t, tc.browser, isBrowserAddr(a))
+			if !tc.browser {
+				return
+			}
+			err := checkAddr(a)
+			if tc.err == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, tc.err)
+			}
+		})
+	}
+}
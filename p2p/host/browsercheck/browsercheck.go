@@ -0,0 +1,245 @@
+// Package browsercheck verifies that the browser-facing addresses of a host, its
+// /tls/ws, /webtransport and /webrtc-direct addresses, are dialable by browsers.
+//
+// Every address is first checked for the defects that can be found without dialing
+// it: a missing or invalid certhash, a secure WebSocket address without DNS name or
+// SNI, or a port blocked by browsers. The remaining addresses are then dialed back by
+// a checker peer running an AutoNAT v2 server, which verifies the certhashes and the
+// certificate served for the SNI like a browser does. The results are emitted as
+// event.EvtBrowserAddrsChecked on the event bus of the host, whenever the addresses
+// of the host change and periodically.
+package browsercheck
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/autonatv2"
+
+	logging "github.com/ipfs/go-log/v2"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+var log = logging.Logger("browsercheck")
+
+const (
+	defaultInterval     = time.Hour
+	defaultProbeTimeout = 30 * time.Second
+)
+
+// reachabilityClient makes AutoNAT v2 dial requests. It's implemented by
+// *autonatv2.AutoNAT.
+type reachabilityClient interface {
+	GetReachabilityFrom(ctx context.Context, p peer.ID, reqs []autonatv2.Request) (autonatv2.Result, error)
+}
+
+type config struct {
+	interval     time.Duration
+	probeTimeout time.Duration
+}
+
+// Option is an option for New.
+type Option func(*config) error
+
+// WithInterval sets the interval between two checks of unchanged addresses.
+// Defaults to 1 hour.
+func WithInterval(d time.Duration) Option {
+	return func(c *config) error {
+		if d <= 0 {
+			return errors.New("browsercheck: interval must be positive")
+		}
+		c.interval = d
+		return nil
+	}
+}
+
+// WithProbeTimeout sets the timeout of the dial request of an address to the checker
+// peer. Defaults to 30 seconds.
+func WithProbeTimeout(d time.Duration) Option {
+	return func(c *config) error {
+		if d <= 0 {
+			return errors.New("browsercheck: probe timeout must be positive")
+		}
+		c.probeTimeout = d
+		return nil
+	}
+}
+
+// Checker checks the browser-facing addresses of a host.
+type Checker struct {
+	host    host.Host
+	client  reachabilityClient
+	checker peer.AddrInfo
+	conf    config
+	// addrs returns the addresses of the host. Overridden in tests.
+	addrs func() []ma.Multiaddr
+
+	emitter event.Emitter
+	// checkMx serializes the checks.
+	checkMx sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New returns a Checker of the addresses of h, dialed back by the AutoNAT v2 server
+// checker using the AutoNAT v2 client an of h.
+func New(h host.Host, an *autonatv2.AutoNAT, checker peer.AddrInfo, opts ...Option) (*Checker, error) {
+	if an == nil {
+		return nil, errors.New("browsercheck: AutoNAT v2 is required")
+	}
+	return newChecker(h, an, checker, opts...)
+}
+
+func newChecker(h host.Host, client reachabilityClient, checker peer.AddrInfo, opts ...Option) (*Checker, error) {
+	conf := config{
+		interval:     defaultInterval,
+		probeTimeout: defaultProbeTimeout,
+	}
+	for _, opt := range opts {
+		if err := opt(&conf); err != nil {
+			return nil, err
+		}
+	}
+	if checker.ID == "" {
+		return nil, errors.New("browsercheck: checker peer is required")
+	}
+	emitter, err := h.EventBus().Emitter(new(event.EvtBrowserAddrsChecked), eventbus.Stateful)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Checker{
+		host:    h,
+		client:  client,
+		checker: checker,
+		conf:    conf,
+		addrs:   h.Addrs,
+		emitter: emitter,
+		ctx:     ctx,
+		cancel:  cancel,
+	}, nil
+}
+
+// Start checks the addresses of the host, and checks them again whenever they change
+// and periodically.
+func (c *Checker) Start() error {
+	sub, err := c.host.EventBus().Subscribe(new(event.EvtLocalAddressesUpdated), eventbus.Name("browsercheck"))
+	if err != nil {
+		return err
+	}
+	c.wg.Add(1)
+	go c.background(sub)
+	return nil
+}
+
+// Close stops the Checker.
+func (c *Checker) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	return c.emitter.Close()
+}
+
+func (c *Checker) background(sub event.Subscription) {
+	defer c.wg.Done()
+	defer sub.Close()
+
+	ticker := time.NewTicker(c.conf.interval)
+	defer ticker.Stop()
+
+	var checked []ma.Multiaddr
+	check := func(force bool) {
+		addrs := browserAddrs(c.addrs())
+		if !force && slices.EqualFunc(addrs, checked, ma.Multiaddr.Equal) {
+			return
+		}
+		checked = addrs
+		c.check(c.ctx, addrs)
+		ticker.Reset(c.conf.interval)
+	}
+
+	check(true)
+	for {
+		select {
+		case <-sub.Out():
+			check(false)
+		case <-ticker.C:
+			check(true)
+		case <-c.ctx.Done():
+			return
+		}
+	}
+}
+
+// Check checks the browser-facing addresses of the host now. The result is also
+// emitted on the event bus.
+func (c *Checker) Check(ctx context.Context) event.EvtBrowserAddrsChecked {
+	return c.check(ctx, browserAddrs(c.addrs()))
+}
+
+func (c *Checker) check(ctx context.Context, addrs []ma.Multiaddr) event.EvtBrowserAddrsChecked {
+	c.checkMx.Lock()
+	defer c.checkMx.Unlock()
+
+	var evt event.EvtBrowserAddrsChecked
+	if len(addrs) > 0 {
+		c.host.Peerstore().AddAddrs(c.checker.ID, c.checker.Addrs, peerstore.TempAddrTTL)
+	}
+	for _, a := range addrs {
+		if err := checkAddr(a); err != nil {
+			log.Warnw("browsers can't dial address", "addr", a, "error", err)
+			evt.Defects = append(evt.Defects, event.BrowserAddrDefect{Addr: a, Err: err})
+			continue
+		}
+		switch c.probe(ctx, a) {
+		case network.ReachabilityPublic:
+			evt.Dialable = append(evt.Dialable, a)
+		case network.ReachabilityPrivate:
+			log.Warnw("browsers can't dial address", "addr", a, "error", ErrUnreachable)
+			evt.Defects = append(evt.Defects, event.BrowserAddrDefect{Addr: a, Err: ErrUnreachable})
+		default:
+			evt.Unverified = append(evt.Unverified, a)
+		}
+	}
+	if err := c.emitter.Emit(evt); err != nil {
+		log.Errorw("failed to emit event", "error", err)
+	}
+	return evt
+}
+
+// probe has the checker peer dial a.
+func (c *Checker) probe(ctx context.Context, a ma.Multiaddr) network.Reachability {
+	ctx, cancel := context.WithTimeout(ctx, c.conf.probeTimeout)
+	defer cancel()
+	res, err := c.client.GetReachabilityFrom(ctx, c.checker.ID, []autonatv2.Request{{Addr: a, SendDialData: true}})
+	if err != nil {
+		log.Debugw("failed to probe address", "addr", a, "error", err)
+		return network.ReachabilityUnknown
+	}
+	if res.AllAddrsRefused {
+		log.Debugw("checker peer refused to dial address", "addr", a)
+		return network.ReachabilityUnknown
+	}
+	return res.Reachability
+}
+
+// browserAddrs returns the browser-facing addresses of addrs.
+func browserAddrs(addrs []ma.Multiaddr) []ma.Multiaddr {
+	var res []ma.Multiaddr
+	for _, a := range addrs {
+		if isBrowserAddr(a) {
+			res = append(res, a)
+		}
+	}
+	return res
+}
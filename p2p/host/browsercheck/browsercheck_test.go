@@ -0,0 +1,86 @@
+package browsercheck
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/blank"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/autonatv2"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multihash"
+	"github.com/stretchr/testify/require"
+)
+
+type mockClient struct {
+	mx      sync.Mutex
+	results map[string]network.Reachability
+	probed  []ma.Multiaddr
+}
+
+func (m *mockClient) GetReachabilityFrom(_ context.Context, _ peer.ID, reqs []autonatv2.Request) (autonatv2.Result, error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	a := reqs[0].Addr
+	m.probed = append(m.probed, a)
+	r, ok := m.results[string(a.Bytes())]
+	if !ok {
+		return autonatv2.Result{}, errors.New("stream reset")
+	}
+	return autonatv2.Result{Addr: a, Reachability: r}, nil
+}
+
+func TestChecker(t *testing.T) {
+	h := bhost.NewBlankHost(swarmt.GenSwarm(t))
+	defer h.Close()
+
+	sha256 := certhash(t, multihash.SHA2_256)
+	dialable := ma.StringCast("/ip4/1.2.3.4/udp/443/quic-v1/webtransport/certhash/" + sha256)
+	unreachable := ma.StringCast("/dns4/example.com/tcp/443/tls/ws")
+	unverified := ma.StringCast("/ip4/1.2.3.4/udp/443/webrtc-direct/certhash/" + sha256)
+	defective := ma.StringCast("/ip4/1.2.3.4/tcp/443/tls/ws")
+	client := &mockClient{results: map[string]network.Reachability{
+		string(dialable.Bytes()):    network.ReachabilityPublic,
+		string(unreachable.Bytes()): network.ReachabilityPrivate,
+	}}
+
+	c, err := newChecker(h, client, peer.AddrInfo{ID: test.RandPeerIDFatal(t)})
+	require.NoError(t, err)
+	defer c.Close()
+	c.addrs = func() []ma.Multiaddr {
+		return []ma.Multiaddr{
+			ma.StringCast("/ip4/1.2.3.4/udp/443/quic-v1"),
+			dialable, unreachable, unverified, defective,
+		}
+	}
+
+	sub, err := h.EventBus().Subscribe(new(event.EvtBrowserAddrsChecked))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	evt := c.Check(context.Background())
+	require.Equal(t, []ma.Multiaddr{dialable}, evt.Dialable)
+	require.Equal(t, []ma.Multiaddr{unverified}, evt.Unverified)
+	require.Len(t, evt.Defects, 2)
+	require.Equal(t, unreachable, evt.Defects[0].Addr)
+	require.ErrorIs(t, evt.Defects[0].Err, ErrUnreachable)
+	require.Equal(t, defective, evt.Defects[1].Addr)
+	require.ErrorIs(t, evt.Defects[1].Err, ErrNoSNI)
+	// defective addresses aren't probed
+	require.Len(t, client.probed, 3)
+
+	select {
+	case e := <-sub.Out():
+		require.Equal(t, evt, e)
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event emitted")
+	}
+}
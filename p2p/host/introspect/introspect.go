@@ -0,0 +1,207 @@
+// Package introspect exposes the current state of a host as a JSON document over
+// HTTP, as a foundation for dashboards and debugging tools.
+//
+// The document contains the addresses of the host, its connections and streams and
+// its resource usage, so the handler must only be served to trusted clients, e.g. on
+// a loopback listener:
+//
+//	go http.ListenAndServe("127.0.0.1:5001", introspect.Handler(h))
+//
+// or to authenticated peers over libp2p, using libp2phttp:
+//
+//	httpHost.SetHTTPHandler(introspect.ProtocolID, introspect.Handler(h))
+package introspect
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+
+	logging "github.com/ipfs/go-log/v2"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+var log = logging.Logger("introspect")
+
+// ProtocolID is the protocol ID under which the handler is served using libp2phttp.
+const ProtocolID = "/libp2p/introspect/1.0.0"
+
+// StateVersion is the version of the State document. It is incremented whenever a
+// field is removed or its meaning changes. Adding fields doesn't change the version.
+const StateVersion = 1
+
+// State is a snapshot of the state of a host.
+type State struct {
+	// Version is the StateVersion of the document.
+	Version int     `json:"version"`
+	PeerID  peer.ID `json:"peerId"`
+	// ListenAddrs are the addresses the host listens on.
+	ListenAddrs []ma.Multiaddr `json:"listenAddrs"`
+	// Addrs are the addresses the host advertises to other peers.
+	Addrs       []ma.Multiaddr `json:"addrs"`
+	Connections []Connection   `json:"connections"`
+	// Streams are the open streams, per protocol. Streams that haven't negotiated a
+	// protocol yet are counted under the empty protocol.
+	Streams   []ProtocolStreams `json:"streams"`
+	Resources Resources         `json:"resources"`
+	// RelayReservations are the relays the host has a reservation with, derived from
+	// the circuit addresses it advertises.
+	RelayReservations []RelayReservation `json:"relayReservations"`
+}
+
+// Connection describes an open connection.
+type Connection struct {
+	ID         string       `json:"id"`
+	Peer       peer.ID      `json:"peer"`
+	LocalAddr  ma.Multiaddr `json:"localAddr"`
+	RemoteAddr ma.Multiaddr `json:"remoteAddr"`
+	Transport  string       `json:"transport"`
+	Security   protocol.ID  `json:"security,omitempty"`
+	Muxer      protocol.ID  `json:"muxer,omitempty"`
+	Direction  string       `json:"direction"`
+	Opened     time.Time    `json:"opened"`
+	Limited    bool         `json:"limited,omitempty"`
+	Streams    int          `json:"streams"`
+}
+
+// ProtocolStreams is the number of open streams of a protocol.
+type ProtocolStreams struct {
+	Protocol protocol.ID `json:"protocol"`
+	Inbound  int         `json:"inbound"`
+	Outbound int         `json:"outbound"`
+}
+
+// Resources is the usage of the resource manager scopes. Services, Protocols and Peers
+// are only set if the resource manager exposes them.
+type Resources struct {
+	System    network.ScopeStat                 `json:"system"`
+	Transient network.ScopeStat                 `json:"transient"`
+	Services  map[string]network.ScopeStat      `json:"services,omitempty"`
+	Protocols map[protocol.ID]network.ScopeStat `json:"protocols,omitempty"`
+	Peers     map[peer.ID]network.ScopeStat     `json:"peers,omitempty"`
+}
+
+// RelayReservation is a reservation of the host with a relay.
+type RelayReservation struct {
+	Relay peer.ID `json:"relay"`
+	// Addrs are the circuit addresses the host advertises through the relay.
+	Addrs []ma.Multiaddr `json:"addrs"`
+}
+
+// Snapshot returns the current state of h.
+func Snapshot(h host.Host) *State {
+	s := &State{
+		Version:     StateVersion,
+		PeerID:      h.ID(),
+		ListenAddrs: h.Network().ListenAddresses(),
+		Addrs:       h.Addrs(),
+	}
+
+	streams := make(map[protocol.ID]*ProtocolStreams)
+	for _, c := range h.Network().Conns() {
+		stat := c.Stat()
+		cs := c.ConnState()
+		strs := c.GetStreams()
+		s.Connections = append(s.Connections, Connection{
+			ID:         c.ID(),
+			Peer:       c.RemotePeer(),
+			LocalAddr:  c.LocalMultiaddr(),
+			RemoteAddr: c.RemoteMultiaddr(),
+			Transport:  cs.Transport,
+			Security:   cs.Security,
+			Muxer:      cs.StreamMultiplexer,
+			Direction:  stat.Direction.String(),
+			Opened:     stat.Opened,
+			Limited:    stat.Limited,
+			Streams:    len(strs),
+		})
+		for _, str := range strs {
+			ps, ok := streams[str.Protocol()]
+			if !ok {
+				ps = &ProtocolStreams{Protocol: str.Protocol()}
+				streams[str.Protocol()] = ps
+			}
+			if str.Stat().Direction == network.DirInbound {
+				ps.Inbound++
+			} else {
+				ps.Outbound++
+			}
+		}
+	}
+	for _, ps := range streams {
+		s.Streams = append(s.Streams, *ps)
+	}
+	slices.SortFunc(s.Streams, func(a, b ProtocolStreams) int { return cmp.Compare(a.Protocol, b.Protocol) })
+
+	if rm := h.Network().ResourceManager(); rm != nil {
+		if st, ok := rm.(rcmgr.ResourceManagerState); ok {
+			stat := st.Stat()
+			s.Resources = Resources{
+				System:    stat.System,
+				Transient: stat.Transient,
+				Services:  stat.Services,
+				Protocols: stat.Protocols,
+				Peers:     stat.Peers,
+			}
+		} else {
+			_ = rm.ViewSystem(func(scope network.ResourceScope) error {
+				s.Resources.System = scope.Stat()
+				return nil
+			})
+			_ = rm.ViewTransient(func(scope network.ResourceScope) error {
+				s.Resources.Transient = scope.Stat()
+				return nil
+			})
+		}
+	}
+
+	reservations := make(map[peer.ID]*RelayReservation)
+	for _, a := range s.Addrs {
+		relayAddr, _ := ma.SplitFunc(a, func(c ma.Component) bool { return c.Code() == ma.P_CIRCUIT })
+		if len(relayAddr) == len(a) {
+			continue
+		}
+		ai, err := peer.AddrInfoFromP2pAddr(relayAddr)
+		if err != nil {
+			continue
+		}
+		r, ok := reservations[ai.ID]
+		if !ok {
+			r = &RelayReservation{Relay: ai.ID}
+			reservations[ai.ID] = r
+		}
+		r.Addrs = append(r.Addrs, a)
+	}
+	for _, r := range reservations {
+		s.RelayReservations = append(s.RelayReservations, *r)
+	}
+	slices.SortFunc(s.RelayReservations, func(a, b RelayReservation) int { return cmp.Compare(a.Relay, b.Relay) })
+	return s
+}
+
+// Handler returns an http.Handler serving the State of h as JSON on GET requests.
+func Handler(h host.Host) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		if r.Method == http.MethodHead {
+			return
+		}
+		if err := json.NewEncoder(w).Encode(Snapshot(h)); err != nil {
+			log.Debugf("error writing introspection state: %s", err)
+		}
+	})
+}
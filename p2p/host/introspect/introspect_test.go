@@ -0,0 +1,68 @@
+package introspect_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/introspect"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	h1, err := bhost.NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h1.Close()
+	h1.Start()
+	h2, err := bhost.NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h2.Close()
+	h2.Start()
+
+	const proto = "/test/1.0.0"
+	h2.SetStreamHandler(proto, func(s network.Stream) {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, h1.Connect(ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	str, err := h1.NewStream(ctx, h2.ID(), proto)
+	require.NoError(t, err)
+	defer str.Close()
+
+	srv := httptest.NewServer(introspect.Handler(h1))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var state introspect.State
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&state))
+	require.Equal(t, introspect.StateVersion, state.Version)
+	require.Equal(t, h1.ID(), state.PeerID)
+	require.ElementsMatch(t, h1.Network().ListenAddresses(), state.ListenAddrs)
+
+	require.Len(t, state.Connections, 1)
+	c := state.Connections[0]
+	require.Equal(t, h2.ID(), c.Peer)
+	require.Equal(t, network.DirOutbound.String(), c.Direction)
+	require.NotEmpty(t, c.Transport)
+	require.NotZero(t, c.Streams)
+
+	require.Contains(t, state.Streams, introspect.ProtocolStreams{Protocol: proto, Outbound: 1})
+
+	resp, err = http.Post(srv.URL, "application/json", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,200 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	// ErrPassphraseRequired is returned when loading an encrypted key without a
+	// passphrase.
+	ErrPassphraseRequired = errors.New("keystore: key is encrypted, passphrase required")
+	// ErrDecrypt is returned when an encrypted key can't be decrypted, because the
+	// passphrase is wrong or the file is corrupted.
+	ErrDecrypt = errors.New("keystore: failed to decrypt key: wrong passphrase or corrupted file")
+	// ErrUnencrypted is returned when loading an unencrypted key with a passphrase,
+	// unless AllowUnencrypted is set.
+	ErrUnencrypted = errors.New("keystore: key is not encrypted")
+)
+
+// encryptedMagic prefixes encrypted key files. Unencrypted key files contain the key
+// serialized with crypto.MarshalPrivateKey.
+var encryptedMagic = []byte("libp2p-key-v1\n")
+
+const (
+	saltLen = 16
+	// scrypt parameters recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// FileOption configures a FileKeystore.
+type FileOption func(*FileKeystore) error
+
+// WithPassphrase encrypts the key at rest, with a key derived from passphrase using
+// scrypt.
+func WithPassphrase(passphrase []byte) FileOption {
+	return func(ks *FileKeystore) error {
+		if len(passphrase) == 0 {
+			return errors.New("keystore: empty passphrase")
+		}
+		ks.passphrase = passphrase
+		return nil
+	}
+}
+
+// AllowUnencrypted allows a keystore with a passphrase to load an unencrypted key,
+// e.g. to encrypt a key written without a passphrase by storing it again. Without it,
+// loading an unencrypted key with a passphrase fails with ErrUnencrypted, since the
+// file may have been replaced by a key the owner of the passphrase didn't write.
+func AllowUnencrypted() FileOption {
+	return func(ks *FileKeystore) error {
+		ks.allowUnencrypted = true
+		return nil
+	}
+}
+
+// FileKeystore stores a key in a file, readable only by the owner. Without a
+// passphrase, the file contains the key serialized with crypto.MarshalPrivateKey, as
+// written by most applications.
+type FileKeystore struct {
+	path             string
+	passphrase       []byte
+	allowUnencrypted bool
+}
+
+var _ Keystore = &FileKeystore{}
+
+// NewFileKeystore returns a Keystore storing the key at path.
+func NewFileKeystore(path string, opts ...FileOption) (*FileKeystore, error) {
+	ks := &FileKeystore{path: path}
+	for _, opt := range opts {
+		if err := opt(ks); err != nil {
+			return nil, err
+		}
+	}
+	return ks, nil
+}
+
+// Load loads the key from the file.
+func (ks *FileKeystore) Load() (crypto.PrivKey, error) {
+	data, err := os.ReadFile(ks.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("keystore: failed to read key: %w", err)
+	}
+	if bytes.HasPrefix(data, encryptedMagic) {
+		if ks.passphrase == nil {
+			return nil, ErrPassphraseRequired
+		}
+		data, err = decrypt(data[len(encryptedMagic):], ks.passphrase)
+		if err != nil {
+			return nil, err
+		}
+	} else if ks.passphrase != nil && !ks.allowUnencrypted {
+		return nil, ErrUnencrypted
+	}
+	sk, err := crypto.UnmarshalPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: failed to unmarshal key: %w", err)
+	}
+	return sk, nil
+}
+
+// Store atomically replaces the file with sk.
+func (ks *FileKeystore) Store(sk crypto.PrivKey) error {
+	data, err := crypto.MarshalPrivateKey(sk)
+	if err != nil {
+		return fmt.Errorf("keystore: failed to marshal key: %w", err)
+	}
+	if ks.passphrase != nil {
+		enc, err := encrypt(data, ks.passphrase)
+		if err != nil {
+			return err
+		}
+		data = append(append([]byte{}, encryptedMagic...), enc...)
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(ks.path), "."+filepath.Base(ks.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("keystore: failed to create key file: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if err := f.Chmod(0o600); err != nil {
+		f.Close()
+		return fmt.Errorf("keystore: failed to create key file: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("keystore: failed to write key: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("keystore: failed to write key: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("keystore: failed to write key: %w", err)
+	}
+	if err := os.Rename(f.Name(), ks.path); err != nil {
+		return fmt.Errorf("keystore: failed to write key: %w", err)
+	}
+	return nil
+}
+
+// encrypt encrypts data with XChaCha20-Poly1305, using a key derived from passphrase.
+// The output is salt || nonce || ciphertext.
+func encrypt(data, passphrase []byte) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(salt, nonce...)
+	return aead.Seal(out, nonce, data, nil), nil
+}
+
+func decrypt(data, passphrase []byte) ([]byte, error) {
+	if len(data) < saltLen+chacha20poly1305.NonceSizeX {
+		return nil, ErrDecrypt
+	}
+	salt, data := data[:saltLen], data[saltLen:]
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce, data := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, data, nil)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
+
+func newAEAD(passphrase, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key(passphrase, salt, scryptN, scryptR, scryptP, chacha20poly1305.KeySize)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: failed to derive key: %w", err)
+	}
+	return chacha20poly1305.NewX(key)
+}
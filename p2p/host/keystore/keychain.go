@@ -0,0 +1,50 @@
+package keystore
+
+import (
+	"fmt"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+)
+
+// Keychain is a secret store of the operating system, e.g. the macOS Keychain, the
+// Windows Credential Manager or the Secret Service on Linux. It's implemented by
+// wrapping a keychain library, to avoid depending on platform specific code.
+type Keychain interface {
+	// Get returns the secret of the given service and account, or ErrNotFound if there
+	// is none.
+	Get(service, account string) ([]byte, error)
+	// Set stores the secret of the given service and account, replacing the stored
+	// secret, if any.
+	Set(service, account string, secret []byte) error
+}
+
+type keychainKeystore struct {
+	kc               Keychain
+	service, account string
+}
+
+// NewKeychainKeystore returns a Keystore storing the key in kc, under the given
+// service and account.
+func NewKeychainKeystore(kc Keychain, service, account string) Keystore {
+	return &keychainKeystore{kc: kc, service: service, account: account}
+}
+
+func (ks *keychainKeystore) Load() (crypto.PrivKey, error) {
+	data, err := ks.kc.Get(ks.service, ks.account)
+	if err != nil {
+		return nil, err
+	}
+	sk, err := crypto.UnmarshalPrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: failed to unmarshal key: %w", err)
+	}
+	return sk, nil
+}
+
+func (ks *keychainKeystore) Store(sk crypto.PrivKey) error {
+	data, err := crypto.MarshalPrivateKey(sk)
+	if err != nil {
+		return fmt.Errorf("keystore: failed to marshal key: %w", err)
+	}
+	return ks.kc.Set(ks.service, ks.account, data)
+}
@@ -0,0 +1,69 @@
+// Package keystore stores the identity key of a host, so that the host keeps its
+// peer ID across restarts.
+//
+// Keys can be stored in a file, optionally encrypted with a passphrase, or in the
+// keychain of the operating system through the Keychain interface:
+//
+//	ks, err := keystore.NewFileKeystore("identity.key", keystore.WithPassphrase(passphrase))
+//	if err != nil {
+//		return err
+//	}
+//	h, err := libp2p.New(libp2p.IdentityFromKeystore(ks))
+package keystore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+)
+
+// ErrNotFound is returned by Keystore.Load if no key is stored.
+var ErrNotFound = errors.New("keystore: key not found")
+
+// Keystore stores a single private key.
+type Keystore interface {
+	// Load returns the stored key, or ErrNotFound if no key is stored.
+	Load() (crypto.PrivKey, error)
+	// Store stores the key, replacing the stored key, if any.
+	Store(crypto.PrivKey) error
+}
+
+// LoadOrGenerate returns the key stored in ks. If no key is stored, it generates a
+// key of type typ (see crypto.GenerateKeyPair) and stores it.
+func LoadOrGenerate(ks Keystore, typ, bits int) (crypto.PrivKey, error) {
+	sk, err := ks.Load()
+	if err == nil {
+		return sk, nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	sk, _, err = crypto.GenerateKeyPair(typ, bits)
+	if err != nil {
+		return nil, fmt.Errorf("keystore: failed to generate key: %w", err)
+	}
+	if err := ks.Store(sk); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+// Rotate generates a new key of type typ, stores it in ks and returns it along with
+// the previously stored key, which is nil if no key was stored. Rotating the key
+// changes the peer ID of the host, so callers should keep the old key until peers
+// learned about the new peer ID.
+func Rotate(ks Keystore, typ, bits int) (newKey, oldKey crypto.PrivKey, err error) {
+	oldKey, err = ks.Load()
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, nil, err
+	}
+	newKey, _, err = crypto.GenerateKeyPair(typ, bits)
+	if err != nil {
+		return nil, nil, fmt.Errorf("keystore: failed to generate key: %w", err)
+	}
+	if err := ks.Store(newKey); err != nil {
+		return nil, nil, err
+	}
+	return newKey, oldKey, nil
+}
@@ -0,0 +1,120 @@
+package keystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKeystore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.key")
+	ks, err := NewFileKeystore(path)
+	require.NoError(t, err)
+	_, err = ks.Load()
+	require.ErrorIs(t, err, ErrNotFound)
+
+	sk, err := LoadOrGenerate(ks, crypto.Ed25519, -1)
+	require.NoError(t, err)
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	// the file contains the marshaled key
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	loaded, err := crypto.UnmarshalPrivateKey(data)
+	require.NoError(t, err)
+	require.True(t, sk.Equals(loaded))
+
+	loaded, err = LoadOrGenerate(ks, crypto.Ed25519, -1)
+	require.NoError(t, err)
+	require.True(t, sk.Equals(loaded))
+}
+
+func TestFileKeystoreEncrypted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.key")
+	ks, err := NewFileKeystore(path, WithPassphrase([]byte("secret")))
+	require.NoError(t, err)
+	sk, err := LoadOrGenerate(ks, crypto.Ed25519, -1)
+	require.NoError(t, err)
+
+	loaded, err := ks.Load()
+	require.NoError(t, err)
+	require.True(t, sk.Equals(loaded))
+
+	noPassphrase, err := NewFileKeystore(path)
+	require.NoError(t, err)
+	_, err = noPassphrase.Load()
+	require.ErrorIs(t, err, ErrPassphraseRequired)
+
+	wrong, err := NewFileKeystore(path, WithPassphrase([]byte("wrong")))
+	require.NoError(t, err)
+	_, err = wrong.Load()
+	require.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = NewFileKeystore(path, WithPassphrase(nil))
+	require.Error(t, err)
+}
+
+func TestFileKeystoreUnencryptedWithPassphrase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "identity.key")
+	plain, err := NewFileKeystore(path)
+	require.NoError(t, err)
+	sk, err := LoadOrGenerate(plain, crypto.Ed25519, -1)
+	require.NoError(t, err)
+
+	ks, err := NewFileKeystore(path, WithPassphrase([]byte("secret")))
+	require.NoError(t, err)
+	_, err = ks.Load()
+	require.ErrorIs(t, err, ErrUnencrypted)
+	// the key isn't replaced by a generated one
+	_, err = LoadOrGenerate(ks, crypto.Ed25519, -1)
+	require.ErrorIs(t, err, ErrUnencrypted)
+
+	// migrate the key to an encrypted file
+	migrate, err := NewFileKeystore(path, WithPassphrase([]byte("secret")), AllowUnencrypted())
+	require.NoError(t, err)
+	loaded, err := migrate.Load()
+	require.NoError(t, err)
+	require.True(t, sk.Equals(loaded))
+	require.NoError(t, migrate.Store(loaded))
+
+	loaded, err = ks.Load()
+	require.NoError(t, err)
+	require.True(t, sk.Equals(loaded))
+}
+
+type mockKeychain map[string][]byte
+
+func (kc mockKeychain) Get(service, account string) ([]byte, error) {
+	secret, ok := kc[service+"/"+account]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return secret, nil
+}
+
+func (kc mockKeychain) Set(service, account string, secret []byte) error {
+	kc[service+"/"+account] = secret
+	return nil
+}
+
+func TestRotate(t *testing.T) {
+	ks := NewKeychainKeystore(mockKeychain{}, "libp2p", "host")
+	first, old, err := Rotate(ks, crypto.Ed25519, -1)
+	require.NoError(t, err)
+	require.Nil(t, old)
+
+	second, old, err := Rotate(ks, crypto.Ed25519, -1)
+	require.NoError(t, err)
+	require.True(t, first.Equals(old))
+	require.False(t, first.Equals(second))
+
+	loaded, err := ks.Load()
+	require.NoError(t, err)
+	require.True(t, second.Equals(loaded))
+}
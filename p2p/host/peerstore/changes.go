@@ -0,0 +1,187 @@
+package peerstore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	pstore "github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// maxPendingChanges is the number of changes buffered for a subscriber. Further
+// changes are dropped until the subscriber catches up.
+const maxPendingChanges = 1024
+
+// ChangeSubManager publishes the changes to a peerstore to the subscribers. It
+// implements pstore.ChangeNotifier, and is shared by the books of a peerstore.
+// A nil ChangeSubManager discards all changes.
+type ChangeSubManager struct {
+	mu   sync.RWMutex
+	subs map[*changeSub]struct{}
+}
+
+var _ pstore.ChangeNotifier = (*ChangeSubManager)(nil)
+
+type changeSub struct {
+	mu      sync.Mutex
+	pending []pstore.Change
+	// dropped is set once changes were dropped, until pending is popped.
+	dropped bool
+	// notify is signaled when a change is added to pending.
+	notify chan struct{}
+}
+
+func (s *changeSub) push(c pstore.Change) {
+	s.mu.Lock()
+	switch {
+	case s.dropped:
+	case len(s.pending) >= maxPendingChanges:
+		// the subscriber is told that it missed changes after the ones it got
+		s.dropped = true
+		s.pending = append(s.pending, pstore.Change{Kind: pstore.ChangesDropped})
+	default:
+		s.pending = append(s.pending, c)
+	}
+	s.mu.Unlock()
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (s *changeSub) pop() []pstore.Change {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := s.pending
+	s.pending = nil
+	s.dropped = false
+	return pending
+}
+
+// NewChangeSubManager initializes a ChangeSubManager.
+func NewChangeSubManager() *ChangeSubManager {
+	return &ChangeSubManager{subs: make(map[*changeSub]struct{})}
+}
+
+// HasSubscribers returns whether there are subscribers. Books use it to skip
+// collecting the changes when nobody is listening.
+func (mgr *ChangeSubManager) HasSubscribers() bool {
+	if mgr == nil {
+		return false
+	}
+	mgr.mu.RLock()
+	defer mgr.mu.RUnlock()
+	return len(mgr.subs) > 0
+}
+
+// Broadcast publishes c to all subscribers. It never blocks, the changes are
+// buffered for slow subscribers, up to a limit after which they're dropped.
+func (mgr *ChangeSubManager) Broadcast(c pstore.Change) {
+	if mgr == nil {
+		return
+	}
+	mgr.mu.RLock()
+	defer mgr.mu.RUnlock()
+	for sub := range mgr.subs {
+		sub.push(c)
+	}
+}
+
+// SubscribeChanges returns a channel on which all changes published after the call
+// are sent. The channel is closed when ctx is canceled.
+func (mgr *ChangeSubManager) SubscribeChanges(ctx context.Context) <-chan pstore.Change {
+	out := make(chan pstore.Change)
+	if mgr == nil {
+		go func() {
+			<-ctx.Done()
+			close(out)
+		}()
+		return out
+	}
+
+	sub := &changeSub{notify: make(chan struct{}, 1)}
+	mgr.mu.Lock()
+	mgr.subs[sub] = struct{}{}
+	mgr.mu.Unlock()
+
+	go func() {
+		defer close(out)
+		defer func() {
+			mgr.mu.Lock()
+			delete(mgr.subs, sub)
+			mgr.mu.Unlock()
+		}()
+
+		for {
+			for _, c := range sub.pop() {
+				select {
+				case out <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-sub.notify:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// AddrChanges collects the changes to the addresses of a peer made by an operation
+// on an address book, to publish them together. A nil AddrChanges discards the
+// changes.
+type AddrChanges struct {
+	peer                    peer.ID
+	added, updated, removed []ma.Multiaddr
+}
+
+// NewAddrChanges returns an AddrChanges for p. It returns nil if there are no
+// subscribers.
+func (mgr *ChangeSubManager) NewAddrChanges(p peer.ID) *AddrChanges {
+	if !mgr.HasSubscribers() {
+		return nil
+	}
+	return &AddrChanges{peer: p}
+}
+
+// Added records that a was added.
+func (c *AddrChanges) Added(a ma.Multiaddr) {
+	if c != nil {
+		c.added = append(c.added, a)
+	}
+}
+
+// Updated records that the TTL of a was updated.
+func (c *AddrChanges) Updated(a ma.Multiaddr) {
+	if c != nil {
+		c.updated = append(c.updated, a)
+	}
+}
+
+// Removed records that a was removed.
+func (c *AddrChanges) Removed(a ma.Multiaddr) {
+	if c != nil {
+		c.removed = append(c.removed, a)
+	}
+}
+
+// PublishAddrChanges publishes the changes collected in c.
+func (mgr *ChangeSubManager) PublishAddrChanges(c *AddrChanges) {
+	if c == nil {
+		return
+	}
+	if len(c.added) > 0 {
+		mgr.Broadcast(pstore.Change{Kind: pstore.AddrsAdded, Peer: c.peer, Addrs: c.added})
+	}
+	if len(c.updated) > 0 {
+		mgr.Broadcast(pstore.Change{Kind: pstore.AddrsUpdated, Peer: c.peer, Addrs: c.updated})
+	}
+	if len(c.removed) > 0 {
+		mgr.Broadcast(pstore.Change{Kind: pstore.AddrsRemoved, Peer: c.peer, Addrs: c.removed})
+	}
+}
@@ -0,0 +1,62 @@
+package peerstore
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	pstore "github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangesDroppedForSlowSubscriber(t *testing.T) {
+	mgr := NewChangeSubManager()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := mgr.SubscribeChanges(ctx)
+	next := func() pstore.Change {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a change")
+			return pstore.Change{}
+		}
+	}
+	change := func(i int) pstore.Change {
+		return pstore.Change{Kind: pstore.AddrsAdded, Peer: peer.ID(strconv.Itoa(i))}
+	}
+	pending := func() int {
+		mgr.mu.RLock()
+		defer mgr.mu.RUnlock()
+		n := 0
+		for sub := range mgr.subs {
+			sub.mu.Lock()
+			n += len(sub.pending)
+			sub.mu.Unlock()
+		}
+		return n
+	}
+
+	// wait for the subscriber to block on sending the first change
+	mgr.Broadcast(change(0))
+	require.Eventually(t, func() bool { return pending() == 0 }, 5*time.Second, time.Millisecond)
+
+	for i := 1; i <= maxPendingChanges+10; i++ {
+		mgr.Broadcast(change(i))
+	}
+	require.Equal(t, maxPendingChanges+1, pending())
+
+	for i := 0; i <= maxPendingChanges; i++ {
+		require.Equal(t, change(i), next())
+	}
+	require.Equal(t, pstore.Change{Kind: pstore.ChangesDropped}, next())
+
+	// once the subscriber caught up, changes are delivered again
+	mgr.Broadcast(change(-1))
+	require.Equal(t, change(-1), next())
+}
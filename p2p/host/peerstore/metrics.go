@@ -5,6 +5,8 @@ import (
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 // LatencyEWMASmoothing governs the decay of the EWMA (the speed
@@ -12,35 +14,59 @@ import (
 // 1 is 100% change, 0 is no change.
 var LatencyEWMASmoothing = 0.1
 
+// maxAddrLatencies is the maximum number of addresses per peer for which latency
+// measurements are kept.
+const maxAddrLatencies = 32
+
 type metrics struct {
 	mutex  sync.RWMutex
 	latmap map[peer.ID]time.Duration
+	// addrLatmap maps the addresses of a peer to their latency EWMA
+	addrLatmap map[peer.ID]map[string]time.Duration
 }
 
 func NewMetrics() *metrics {
 	return &metrics{
-		latmap: make(map[peer.ID]time.Duration),
+		latmap:     make(map[peer.ID]time.Duration),
+		addrLatmap: make(map[peer.ID]map[string]time.Duration),
 	}
 }
 
 // RecordLatency records a new latency measurement
 func (m *metrics) RecordLatency(p peer.ID, next time.Duration) {
-	nextf := float64(next)
-	s := LatencyEWMASmoothing
-	if s > 1 || s < 0 {
-		s = 0.1 // ignore the knob. it's broken. look, it jiggles.
-	}
-
 	m.mutex.Lock()
 	ewma, found := m.latmap[p]
-	ewmaf := float64(ewma)
+	m.latmap[p] = updateEWMA(ewma, found, next)
+	m.mutex.Unlock()
+}
+
+// RecordAddrLatency records a new latency measurement for an address of a peer.
+func (m *metrics) RecordAddrLatency(p peer.ID, a ma.Multiaddr, next time.Duration) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	lats, ok := m.addrLatmap[p]
+	if !ok {
+		lats = make(map[string]time.Duration)
+		m.addrLatmap[p] = lats
+	}
+	k := string(a.Bytes())
+	ewma, found := lats[k]
+	if !found && len(lats) >= maxAddrLatencies {
+		return
+	}
+	lats[k] = updateEWMA(ewma, found, next)
+}
+
+func updateEWMA(ewma time.Duration, found bool, next time.Duration) time.Duration {
 	if !found {
-		m.latmap[p] = next // when no data, just take it as the mean.
-	} else {
-		nextf = ((1.0 - s) * ewmaf) + (s * nextf)
-		m.latmap[p] = time.Duration(nextf)
+		return next // when no data, just take it as the mean.
 	}
-	m.mutex.Unlock()
+	s := LatencyEWMASmoothing
+	if s > 1 || s < 0 {
+		s = 0.1 // ignore the knob. it's broken. look, it jiggles.
+	}
+	return time.Duration(((1.0 - s) * float64(ewma)) + (s * float64(next)))
 }
 
 // LatencyEWMA returns an exponentially-weighted moving avg.
@@ -51,8 +77,17 @@ func (m *metrics) LatencyEWMA(p peer.ID) time.Duration {
 	return m.latmap[p]
 }
 
+// AddrLatencyEWMA returns an exponentially-weighted moving avg.
+// of all measurements of the latency of an address of a peer.
+func (m *metrics) AddrLatencyEWMA(p peer.ID, a ma.Multiaddr) time.Duration {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+	return m.addrLatmap[p][string(a.Bytes())]
+}
+
 func (m *metrics) RemovePeer(p peer.ID) {
 	m.mutex.Lock()
 	delete(m.latmap, p)
+	delete(m.addrLatmap, p)
 	m.mutex.Unlock()
 }
@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/test"
+
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 func TestLatencyEWMAFun(t *testing.T) {
@@ -61,3 +63,31 @@ func TestLatencyEWMA(t *testing.T) {
 		t.Fatalf("latency outside of expected range. expected %d ± %d, got %d", exp, sig, lat)
 	}
 }
+
+func TestAddrLatencyEWMA(t *testing.T) {
+	m := NewMetrics()
+	id, err := test.RandPeerID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a1 := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1")
+	a2 := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+
+	m.RecordAddrLatency(id, a1, 10*time.Millisecond)
+	m.RecordAddrLatency(id, a1, 20*time.Millisecond)
+	m.RecordAddrLatency(id, a2, 100*time.Millisecond)
+	if lat := m.AddrLatencyEWMA(id, a1); lat != 11*time.Millisecond {
+		t.Fatalf("expected 11ms for %s, got %s", a1, lat)
+	}
+	if lat := m.AddrLatencyEWMA(id, a2); lat != 100*time.Millisecond {
+		t.Fatalf("expected 100ms for %s, got %s", a2, lat)
+	}
+	if lat := m.LatencyEWMA(id); lat != 0 {
+		t.Fatalf("address latencies must not affect the peer latency, got %s", lat)
+	}
+
+	m.RemovePeer(id)
+	if lat := m.AddrLatencyEWMA(id, a1); lat != 0 {
+		t.Fatalf("expected no latency after removing the peer, got %s", lat)
+	}
+}
@@ -166,6 +166,7 @@ func (rc realclock) After(d time.Duration) <-chan time.Time {
 
 var _ pstore.AddrBook = (*dsAddrBook)(nil)
 var _ pstore.CertifiedAddrBook = (*dsAddrBook)(nil)
+var _ pstore.AddrProvenanceBook = (*dsAddrBook)(nil)
 
 // NewAddrBook initializes a new datastore-backed address book. It serves as a drop-in replacement for pstoremem
 // (memory-backed peerstore), and works with any datastore implementing the ds.Batching interface.
@@ -277,7 +278,16 @@ func (ab *dsAddrBook) AddAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duratio
 		return
 	}
 	addrs = cleanAddrs(addrs, p)
-	ab.setAddrs(p, addrs, ttl, ttlExtend, false)
+	ab.setAddrs(p, addrs, ttl, ttlExtend, false, "")
+}
+
+// AddAddrsFrom calls AddAddrs, recording src as a source of addrs.
+func (ab *dsAddrBook) AddAddrsFrom(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src pstore.AddrSource) {
+	if ttl <= 0 {
+		return
+	}
+	addrs = cleanAddrs(addrs, p)
+	ab.setAddrs(p, addrs, ttl, ttlExtend, false, src)
 }
 
 // ConsumePeerRecord adds addresses from a signed peer.PeerRecord (contained in
@@ -303,7 +313,7 @@ func (ab *dsAddrBook) ConsumePeerRecord(recordEnvelope *record.Envelope, ttl tim
 	}
 
 	addrs := cleanAddrs(rec.Addrs, rec.PeerID)
-	err = ab.setAddrs(rec.PeerID, addrs, ttl, ttlExtend, true)
+	err = ab.setAddrs(rec.PeerID, addrs, ttl, ttlExtend, true, pstore.AddrSourcePeerRecord)
 	if err != nil {
 		return false, err
 	}
@@ -385,12 +395,17 @@ func (ab *dsAddrBook) SetAddr(p peer.ID, addr ma.Multiaddr, ttl time.Duration) {
 
 // SetAddrs will add or update the TTLs of addresses in the AddrBook.
 func (ab *dsAddrBook) SetAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration) {
+	ab.SetAddrsFrom(p, addrs, ttl, "")
+}
+
+// SetAddrsFrom calls SetAddrs, recording src as a source of addrs.
+func (ab *dsAddrBook) SetAddrsFrom(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src pstore.AddrSource) {
 	addrs = cleanAddrs(addrs, p)
 	if ttl <= 0 {
 		ab.deleteAddrs(p, addrs)
 		return
 	}
-	ab.setAddrs(p, addrs, ttl, ttlOverride, false)
+	ab.setAddrs(p, addrs, ttl, ttlOverride, false, src)
 }
 
 // UpdateAddrs will update any addresses for a given peer and TTL combination to
@@ -442,6 +457,89 @@ func (ab *dsAddrBook) Addrs(p peer.ID) []ma.Multiaddr {
 	return addrs
 }
 
+// AddrProvenance returns the provenance of the non-expired addresses of p.
+func (ab *dsAddrBook) AddrProvenance(p peer.ID) []pstore.AddrProvenance {
+	pr, err := ab.loadRecord(p, true, true)
+	if err != nil {
+		log.Warnf("failed to load peerstore entry for peer %s while querying addr provenance, err: %v", p, err)
+		return nil
+	}
+
+	pr.RLock()
+	defer pr.RUnlock()
+
+	provs := make([]pstore.AddrProvenance, 0, len(pr.Addrs))
+	for _, a := range pr.Addrs {
+		addr, err := ma.NewMultiaddrBytes(a.Addr)
+		if err != nil {
+			continue
+		}
+		prov := pstore.AddrProvenance{Addr: addr}
+		if len(a.Sources) > 0 {
+			prov.Sources = make(map[pstore.AddrSource]time.Time, len(a.Sources))
+			for src, t := range a.Sources {
+				prov.Sources[pstore.AddrSource(src)] = time.Unix(t, 0)
+			}
+		}
+		provs = append(provs, prov)
+	}
+	return provs
+}
+
+// AddrsFrom returns the non-expired addresses of p learned from any of srcs.
+func (ab *dsAddrBook) AddrsFrom(p peer.ID, srcs ...pstore.AddrSource) []ma.Multiaddr {
+	pr, err := ab.loadRecord(p, true, true)
+	if err != nil {
+		log.Warnf("failed to load peerstore entry for peer %s while querying addrs, err: %v", p, err)
+		return nil
+	}
+
+	pr.RLock()
+	defer pr.RUnlock()
+
+	var addrs []ma.Multiaddr
+	for _, a := range pr.Addrs {
+		for _, src := range srcs {
+			if _, ok := a.Sources[string(src)]; !ok {
+				continue
+			}
+			if addr, err := ma.NewMultiaddrBytes(a.Addr); err == nil {
+				addrs = append(addrs, addr)
+			}
+			break
+		}
+	}
+	return addrs
+}
+
+// queryAddrs returns the non-expired addresses of p, and whether any of them was
+// added with the connected TTL. Records loaded from the datastore aren't cached, to
+// not evict the cache when scanning many peers.
+func (ab *dsAddrBook) queryAddrs(p peer.ID) ([]ma.Multiaddr, bool) {
+	pr, err := ab.loadRecord(p, false, true)
+	if err != nil {
+		log.Warnf("failed to load peerstore entry for peer %s while querying peers, err: %v", p, err)
+		return nil, false
+	}
+
+	pr.RLock()
+	defer pr.RUnlock()
+
+	addrs := make([]ma.Multiaddr, 0, len(pr.Addrs))
+	var connected bool
+	for _, a := range pr.Addrs {
+		addr, err := ma.NewMultiaddrBytes(a.Addr)
+		if err != nil {
+			continue
+		}
+		addrs = append(addrs, addr)
+		if time.Duration(a.Ttl) >= pstore.ConnectedAddrTTL {
+			connected = true
+		}
+	}
+	return addrs, connected
+}
+
 // Peers returns all of the peer IDs for which the AddrBook has addresses.
 func (ab *dsAddrBook) PeersWithAddrs() peer.IDSlice {
 	ids, err := uniquePeerIds(ab.ds, addrBookBase, func(result query.Result) string {
@@ -470,7 +568,7 @@ func (ab *dsAddrBook) ClearAddrs(p peer.ID) {
 	}
 }
 
-func (ab *dsAddrBook) setAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, mode ttlWriteMode, _ bool) (err error) {
+func (ab *dsAddrBook) setAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, mode ttlWriteMode, _ bool, src pstore.AddrSource) (err error) {
 	if len(addrs) == 0 {
 		return nil
 	}
@@ -488,7 +586,8 @@ func (ab *dsAddrBook) setAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duratio
 	// 	return nil
 	// }
 
-	newExp := ab.clock.Now().Add(ttl).Unix()
+	now := ab.clock.Now()
+	newExp := now.Add(ttl).Unix()
 	addrsMap := make(map[string]*pb.AddrBookRecord_AddrEntry, len(pr.Addrs))
 	for _, addr := range pr.Addrs {
 		addrsMap[string(addr.Addr)] = addr
@@ -514,6 +613,7 @@ func (ab *dsAddrBook) setAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duratio
 		default:
 			panic("BUG: unimplemented ttl mode")
 		}
+		addSource(existingEntry, src, now)
 		return existingEntry
 	}
 
@@ -532,6 +632,7 @@ func (ab *dsAddrBook) setAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duratio
 				Ttl:    int64(ttl),
 				Expiry: newExp,
 			}
+			addSource(entry, src, now)
 			entries = append(entries, entry)
 
 			// note: there's a minor chance that writing the record will fail, in which case we would've broadcast
@@ -548,10 +649,21 @@ func (ab *dsAddrBook) setAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duratio
 	// }
 
 	pr.dirty = true
-	pr.clean(ab.clock.Now())
+	pr.clean(now)
 	return pr.flush(ab.ds)
 }
 
+// addSource records src as a source of the address, last added at now.
+func addSource(entry *pb.AddrBookRecord_AddrEntry, src pstore.AddrSource, now time.Time) {
+	if src == "" {
+		return
+	}
+	if entry.Sources == nil {
+		entry.Sources = make(map[string]int64, 1)
+	}
+	entry.Sources[string(src)] = now.Unix()
+}
+
 // deletes addresses in place, avoiding copies until we encounter the first deletion.
 // does not preserve order, but entries are re-sorted before flushing to disk anyway.
 func deleteInPlace(s []*pb.AddrBookRecord_AddrEntry, addrs []ma.Multiaddr) []*pb.AddrBookRecord_AddrEntry {
@@ -58,6 +58,7 @@ func DefaultOpts() Options {
 
 type pstoreds struct {
 	peerstore.Metrics
+	peerstore.AddrMetrics
 
 	*dsKeyBook
 	*dsAddrBook
@@ -66,6 +67,9 @@ type pstoreds struct {
 }
 
 var _ peerstore.Peerstore = &pstoreds{}
+var _ peerstore.Querier = &pstoreds{}
+var _ peerstore.AddrMetrics = &pstoreds{}
+var _ peerstore.AddrProvenanceBook = &pstoreds{}
 
 // NewPeerstore creates a peerstore backed by the provided persistent datastore.
 // It's the caller's responsibility to call RemovePeer to ensure
@@ -91,8 +95,10 @@ func NewPeerstore(ctx context.Context, store ds.Batching, opts Options) (*pstore
 		return nil, err
 	}
 
+	metrics := pstore.NewMetrics()
 	return &pstoreds{
-		Metrics:        pstore.NewMetrics(),
+		Metrics:        metrics,
+		AddrMetrics:    metrics,
 		dsKeyBook:      keyBook,
 		dsAddrBook:     addrBook,
 		dsPeerMetadata: peerMetadata,
@@ -177,6 +183,11 @@ func (ps *pstoreds) PeerInfo(p peer.ID) peer.AddrInfo {
 	}
 }
 
+// QueryPeers returns the peers selected by q.
+func (ps *pstoreds) QueryPeers(q peerstore.Query) (peerstore.QueryResult, error) {
+	return peerstore.RunQuery(q, ps.Peers(), ps.dsProtoBook, ps.dsAddrBook.queryAddrs)
+}
+
 // RemovePeer removes entries associated with a peer from:
 // * the KeyBook
 // * the ProtoBook
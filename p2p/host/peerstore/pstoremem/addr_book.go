@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"sort"
 	"sync"
 	"time"
@@ -12,6 +13,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	"github.com/TheNoobiCat/go-libp2p/core/record"
+	pstore "github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore"
 
 	logging "github.com/ipfs/go-log/v2"
 	ma "github.com/multiformats/go-multiaddr"
@@ -24,10 +26,23 @@ type expiringAddr struct {
 	TTL    time.Duration
 	Expiry time.Time
 	Peer   peer.ID
+	// Sources maps the sources the address was learned from to the last time it
+	// was added from them. nil if the address was only added without a source.
+	Sources map[peerstore.AddrSource]time.Time
 	// to sort by expiry time, -1 means it's not in the heap
 	heapIndex int
 }
 
+func (e *expiringAddr) addSource(src peerstore.AddrSource, now time.Time) {
+	if src == "" {
+		return
+	}
+	if e.Sources == nil {
+		e.Sources = make(map[peerstore.AddrSource]time.Time, 1)
+	}
+	e.Sources[src] = now
+}
+
 func (e *expiringAddr) ExpiredBy(t time.Time) bool {
 	return !t.Before(e.Expiry)
 }
@@ -179,11 +194,14 @@ type memoryAddrBook struct {
 	cancel   func()
 
 	subManager *AddrSubManager
-	clock      clock
+	// changes publishes the changes to the addresses. It's set by NewPeerstore.
+	changes *pstore.ChangeSubManager
+	clock   clock
 }
 
 var _ peerstore.AddrBook = (*memoryAddrBook)(nil)
 var _ peerstore.CertifiedAddrBook = (*memoryAddrBook)(nil)
+var _ peerstore.AddrProvenanceBook = (*memoryAddrBook)(nil)
 
 func NewAddrBook(opts ...AddrBookOption) *memoryAddrBook {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -265,6 +283,9 @@ func (mab *memoryAddrBook) gc() {
 			return
 		}
 		mab.maybeDeleteSignedPeerRecordUnlocked(ea.Peer)
+		if mab.changes.HasSubscribers() {
+			mab.changes.Broadcast(peerstore.Change{Kind: peerstore.AddrsRemoved, Peer: ea.Peer, Addrs: []ma.Multiaddr{ea.Addr}})
+		}
 	}
 }
 
@@ -286,7 +307,12 @@ func (mab *memoryAddrBook) AddAddr(p peer.ID, addr ma.Multiaddr, ttl time.Durati
 // AddAddrs adds `addrs` for peer `p`, which will expire after the given `ttl`.
 // This function never reduces the TTL or expiration of an address.
 func (mab *memoryAddrBook) AddAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration) {
-	mab.addAddrs(p, addrs, ttl)
+	mab.addAddrs(p, addrs, ttl, "")
+}
+
+// AddAddrsFrom calls AddAddrs, recording src as a source of addrs.
+func (mab *memoryAddrBook) AddAddrsFrom(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src peerstore.AddrSource) {
+	mab.addAddrs(p, addrs, ttl, src)
 }
 
 // ConsumePeerRecord adds addresses from a signed peer.PeerRecord, which will expire after the given TTL.
@@ -320,7 +346,7 @@ func (mab *memoryAddrBook) ConsumePeerRecord(recordEnvelope *record.Envelope, tt
 		Envelope: recordEnvelope,
 		Seq:      rec.Seq,
 	}
-	mab.addAddrsUnlocked(rec.PeerID, rec.Addrs, ttl)
+	mab.addAddrsUnlocked(rec.PeerID, rec.Addrs, ttl, peerstore.AddrSourcePeerRecord)
 	return true, nil
 }
 
@@ -330,14 +356,14 @@ func (mab *memoryAddrBook) maybeDeleteSignedPeerRecordUnlocked(p peer.ID) {
 	}
 }
 
-func (mab *memoryAddrBook) addAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration) {
+func (mab *memoryAddrBook) addAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src peerstore.AddrSource) {
 	mab.mu.Lock()
 	defer mab.mu.Unlock()
 
-	mab.addAddrsUnlocked(p, addrs, ttl)
+	mab.addAddrsUnlocked(p, addrs, ttl, src)
 }
 
-func (mab *memoryAddrBook) addAddrsUnlocked(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration) {
+func (mab *memoryAddrBook) addAddrsUnlocked(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src peerstore.AddrSource) {
 	defer mab.maybeDeleteSignedPeerRecordUnlocked(p)
 
 	// if ttl is zero, exit. nothing to do.
@@ -350,7 +376,11 @@ func (mab *memoryAddrBook) addAddrsUnlocked(p peer.ID, addrs []ma.Multiaddr, ttl
 		return
 	}
 
-	exp := mab.clock.Now().Add(ttl)
+	changes := mab.changes.NewAddrChanges(p)
+	defer mab.changes.PublishAddrChanges(changes)
+
+	now := mab.clock.Now()
+	exp := now.Add(ttl)
 	for _, addr := range addrs {
 		// Remove suffix of /p2p/peer-id from address
 		addr, addrPid := peer.SplitAddr(addr)
@@ -366,9 +396,12 @@ func (mab *memoryAddrBook) addAddrsUnlocked(p peer.ID, addrs []ma.Multiaddr, ttl
 		if !found {
 			// not found, announce it.
 			entry := &expiringAddr{Addr: addr, Expiry: exp, TTL: ttl, Peer: p}
+			entry.addSource(src, now)
 			mab.addrs.Insert(entry)
 			mab.subManager.BroadcastAddr(p, addr)
+			changes.Added(addr)
 		} else {
+			a.addSource(src, now)
 			// update ttl & exp to whichever is greater between new and existing entry
 			var changed bool
 			if ttl > a.TTL {
@@ -381,6 +414,7 @@ func (mab *memoryAddrBook) addAddrsUnlocked(p peer.ID, addrs []ma.Multiaddr, ttl
 			}
 			if changed {
 				mab.addrs.Update(a)
+				changes.Updated(addr)
 			}
 		}
 	}
@@ -394,12 +428,24 @@ func (mab *memoryAddrBook) SetAddr(p peer.ID, addr ma.Multiaddr, ttl time.Durati
 // SetAddrs sets the ttl on addresses. This clears any TTL there previously.
 // This is used when we receive the best estimate of the validity of an address.
 func (mab *memoryAddrBook) SetAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration) {
+	mab.setAddrs(p, addrs, ttl, "")
+}
+
+// SetAddrsFrom calls SetAddrs, recording src as a source of addrs.
+func (mab *memoryAddrBook) SetAddrsFrom(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src peerstore.AddrSource) {
+	mab.setAddrs(p, addrs, ttl, src)
+}
+
+func (mab *memoryAddrBook) setAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Duration, src peerstore.AddrSource) {
 	mab.mu.Lock()
 	defer mab.mu.Unlock()
 
 	defer mab.maybeDeleteSignedPeerRecordUnlocked(p)
+	changes := mab.changes.NewAddrChanges(p)
+	defer mab.changes.PublishAddrChanges(changes)
 
-	exp := mab.clock.Now().Add(ttl)
+	now := mab.clock.Now()
+	exp := now.Add(ttl)
 	for _, addr := range addrs {
 		addr, addrPid := peer.SplitAddr(addr)
 		if addr == nil {
@@ -415,15 +461,19 @@ func (mab *memoryAddrBook) SetAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Du
 			if ttl > 0 {
 				if a.IsConnected() && !ttlIsConnected(ttl) && mab.addrs.NumUnconnectedAddrs() >= mab.maxUnconnectedAddrs {
 					mab.addrs.Delete(a)
+					changes.Removed(a.Addr)
 				} else {
 					a.Addr = addr
 					a.Expiry = exp
 					a.TTL = ttl
+					a.addSource(src, now)
 					mab.addrs.Update(a)
 					mab.subManager.BroadcastAddr(p, addr)
+					changes.Updated(addr)
 				}
 			} else {
 				mab.addrs.Delete(a)
+				changes.Removed(a.Addr)
 			}
 		} else {
 			if ttl > 0 {
@@ -431,8 +481,10 @@ func (mab *memoryAddrBook) SetAddrs(p peer.ID, addrs []ma.Multiaddr, ttl time.Du
 					continue
 				}
 				entry := &expiringAddr{Addr: addr, Expiry: exp, TTL: ttl, Peer: p}
+				entry.addSource(src, now)
 				mab.addrs.Insert(entry)
 				mab.subManager.BroadcastAddr(p, addr)
+				changes.Added(addr)
 			}
 		}
 	}
@@ -445,20 +497,25 @@ func (mab *memoryAddrBook) UpdateAddrs(p peer.ID, oldTTL time.Duration, newTTL t
 	defer mab.mu.Unlock()
 
 	defer mab.maybeDeleteSignedPeerRecordUnlocked(p)
+	changes := mab.changes.NewAddrChanges(p)
+	defer mab.changes.PublishAddrChanges(changes)
 
 	exp := mab.clock.Now().Add(newTTL)
 	for _, a := range mab.addrs.Addrs[p] {
 		if oldTTL == a.TTL {
 			if newTTL == 0 {
 				mab.addrs.Delete(a)
+				changes.Removed(a.Addr)
 			} else {
 				// We are over limit, drop these addresses.
 				if ttlIsConnected(oldTTL) && !ttlIsConnected(newTTL) && mab.addrs.NumUnconnectedAddrs() >= mab.maxUnconnectedAddrs {
 					mab.addrs.Delete(a)
+					changes.Removed(a.Addr)
 				} else {
 					a.TTL = newTTL
 					a.Expiry = exp
 					mab.addrs.Update(a)
+					changes.Updated(a.Addr)
 				}
 			}
 		}
@@ -475,6 +532,61 @@ func (mab *memoryAddrBook) Addrs(p peer.ID) []ma.Multiaddr {
 	return validAddrs(mab.clock.Now(), mab.addrs.Addrs[p])
 }
 
+// AddrProvenance returns the provenance of the valid addresses of p.
+func (mab *memoryAddrBook) AddrProvenance(p peer.ID) []peerstore.AddrProvenance {
+	mab.mu.RLock()
+	defer mab.mu.RUnlock()
+	amap := mab.addrs.Addrs[p]
+	now := mab.clock.Now()
+	provs := make([]peerstore.AddrProvenance, 0, len(amap))
+	for _, a := range amap {
+		if !a.ExpiredBy(now) {
+			provs = append(provs, peerstore.AddrProvenance{Addr: a.Addr, Sources: maps.Clone(a.Sources)})
+		}
+	}
+	return provs
+}
+
+// AddrsFrom returns the valid addresses of p learned from any of srcs.
+func (mab *memoryAddrBook) AddrsFrom(p peer.ID, srcs ...peerstore.AddrSource) []ma.Multiaddr {
+	mab.mu.RLock()
+	defer mab.mu.RUnlock()
+	now := mab.clock.Now()
+	var addrs []ma.Multiaddr
+	for _, a := range mab.addrs.Addrs[p] {
+		if a.ExpiredBy(now) {
+			continue
+		}
+		for _, src := range srcs {
+			if _, ok := a.Sources[src]; ok {
+				addrs = append(addrs, a.Addr)
+				break
+			}
+		}
+	}
+	return addrs
+}
+
+// queryAddrs returns the valid addresses of p, and whether any of them was added
+// with the connected TTL.
+func (mab *memoryAddrBook) queryAddrs(p peer.ID) ([]ma.Multiaddr, bool) {
+	mab.mu.RLock()
+	defer mab.mu.RUnlock()
+	amap, ok := mab.addrs.Addrs[p]
+	if !ok {
+		return nil, false
+	}
+	now := mab.clock.Now()
+	var connected bool
+	for _, a := range amap {
+		if !a.ExpiredBy(now) && a.IsConnected() {
+			connected = true
+			break
+		}
+	}
+	return validAddrs(now, amap), connected
+}
+
 func validAddrs(now time.Time, amap map[string]*expiringAddr) []ma.Multiaddr {
 	good := make([]ma.Multiaddr, 0, len(amap))
 	if amap == nil {
@@ -515,9 +627,13 @@ func (mab *memoryAddrBook) ClearAddrs(p peer.ID) {
 	mab.mu.Lock()
 	defer mab.mu.Unlock()
 
+	changes := mab.changes.NewAddrChanges(p)
+	defer mab.changes.PublishAddrChanges(changes)
+
 	delete(mab.signedPeerRecords, p)
 	for _, a := range mab.addrs.Addrs[p] {
 		mab.addrs.Delete(a)
+		changes.Removed(a.Addr)
 	}
 }
 
@@ -9,6 +9,9 @@ import (
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	mockClock "github.com/benbjohnson/clock"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 )
@@ -186,6 +189,50 @@ func TestPeerLimits(t *testing.T) {
 	require.Equal(t, 1024, ab.addrs.NumUnconnectedAddrs())
 }
 
+func TestAddrProvenance(t *testing.T) {
+	clk := mockClock.NewMock()
+	ab := NewAddrBook(WithClock(clk))
+	defer ab.Close()
+
+	p := peer.ID("p")
+	a1 := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+	a2 := ma.StringCast("/ip4/1.2.3.4/tcp/2")
+	a3 := ma.StringCast("/ip4/1.2.3.4/tcp/3")
+
+	t1 := clk.Now()
+	ab.AddAddrsFrom(p, []ma.Multiaddr{a1, a2}, time.Hour, peerstore.AddrSourceIdentify)
+	clk.Add(time.Second)
+	t2 := clk.Now()
+	peerstore.AddAddrsFrom(ab, p, []ma.Multiaddr{a2}, time.Hour, peerstore.AddrSourceRouting)
+	ab.AddAddr(p, a3, time.Hour)
+
+	provs := ab.AddrProvenance(p)
+	slices.SortFunc(provs, func(a, b peerstore.AddrProvenance) int { return a.Addr.Compare(b.Addr) })
+	require.Equal(t, []peerstore.AddrProvenance{
+		{Addr: a1, Sources: map[peerstore.AddrSource]time.Time{peerstore.AddrSourceIdentify: t1}},
+		{Addr: a2, Sources: map[peerstore.AddrSource]time.Time{peerstore.AddrSourceIdentify: t1, peerstore.AddrSourceRouting: t2}},
+		{Addr: a3},
+	}, provs)
+
+	require.ElementsMatch(t, []ma.Multiaddr{a2}, ab.AddrsFrom(p, peerstore.AddrSourceRouting))
+	require.ElementsMatch(t, []ma.Multiaddr{a1, a2}, ab.AddrsFrom(p, peerstore.AddrSourceRouting, peerstore.AddrSourceIdentify))
+	require.Empty(t, ab.AddrsFrom(p, peerstore.AddrSourceMDNS))
+
+	// adding an address again from the same source updates the time it was seen
+	clk.Add(time.Second)
+	ab.SetAddrsFrom(p, []ma.Multiaddr{a1}, time.Hour, peerstore.AddrSourceIdentify)
+	for _, prov := range ab.AddrProvenance(p) {
+		if prov.Addr.Equal(a1) {
+			require.Equal(t, clk.Now(), prov.Sources[peerstore.AddrSourceIdentify])
+		}
+	}
+
+	// expired addresses are not returned
+	ab.SetAddrs(p, []ma.Multiaddr{a2}, 0)
+	require.ElementsMatch(t, []ma.Multiaddr{a1}, ab.AddrsFrom(p, peerstore.AddrSourceRouting, peerstore.AddrSourceIdentify))
+	require.Len(t, ab.AddrProvenance(p), 2)
+}
+
 func BenchmarkPeerAddrs(b *testing.B) {
 	sizes := [...]int{1, 10, 100, 1000, 10_000, 100_000, 1000_000}
 	for _, sz := range sizes {
@@ -46,6 +46,62 @@ func TestPeerstoreProtoStoreLimits(t *testing.T) {
 	pt.TestPeerstoreProtoStoreLimits(t, ps, limit)
 }
 
+type countingMetricsTracer struct {
+	evicted map[string]int
+}
+
+func (mt *countingMetricsTracer) MetadataEvicted(reason string) {
+	mt.evicted[reason]++
+}
+
+func TestPeerMetadataBounds(t *testing.T) {
+	clk := mockClock.NewMock()
+	mt := &countingMetricsTracer{evicted: make(map[string]int)}
+	ps, err := NewPeerstore(
+		WithMetadataTTL(time.Hour),
+		WithMaxMetadataEntries(3),
+		WithMetadataClock(clk),
+		WithMetadataMetricsTracer(mt),
+	)
+	require.NoError(t, err)
+	defer ps.Close()
+
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	require.NoError(t, ps.Put(p1, "a", 1))
+	require.NoError(t, ps.Put(p1, "b", 2))
+	require.NoError(t, ps.Put(p2, "a", 3))
+	// rewriting an entry makes it the most recent one
+	require.NoError(t, ps.Put(p1, "a", 4))
+
+	// the store is full, p1/b is the least recently written entry
+	require.NoError(t, ps.Put(p2, "b", 5))
+	_, err = ps.Get(p1, "b")
+	require.ErrorIs(t, err, pstore.ErrNotFound)
+	v, err := ps.Get(p1, "a")
+	require.NoError(t, err)
+	require.Equal(t, 4, v)
+	require.Equal(t, 1, mt.evicted["capacity"])
+
+	clk.Add(30 * time.Minute)
+	require.NoError(t, ps.Put(p2, "a", 6))
+	clk.Add(30 * time.Minute)
+	// p1/a and p2/b expired, p2/a was rewritten
+	_, err = ps.Get(p1, "a")
+	require.ErrorIs(t, err, pstore.ErrNotFound)
+	v, err = ps.Get(p2, "a")
+	require.NoError(t, err)
+	require.Equal(t, 6, v)
+
+	require.NoError(t, ps.Put(p2, "c", 7))
+	require.Equal(t, 2, mt.evicted["ttl"])
+	require.Len(t, ps.ds, 1)
+	require.Equal(t, 2, ps.order.Len())
+
+	ps.RemovePeer(p2)
+	require.Empty(t, ps.ds)
+	require.Zero(t, ps.order.Len())
+}
+
 func TestInMemoryAddrBook(t *testing.T) {
 	clk := mockClock.NewMock()
 	pt.TestAddrBook(t, func() (pstore.AddrBook, func()) {
@@ -6,16 +6,20 @@ import (
 
 	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
-	pstore "github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	pstore "github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore"
 )
 
 type memoryKeyBook struct {
 	sync.RWMutex // same lock. wont happen a ton.
 	pks          map[peer.ID]ic.PubKey
 	sks          map[peer.ID]ic.PrivKey
+
+	// changes publishes the changes to the keys. It's set by NewPeerstore.
+	changes *pstore.ChangeSubManager
 }
 
-var _ pstore.KeyBook = (*memoryKeyBook)(nil)
+var _ peerstore.KeyBook = (*memoryKeyBook)(nil)
 
 func NewKeyBook() *memoryKeyBook {
 	return &memoryKeyBook{
@@ -62,8 +66,11 @@ func (mkb *memoryKeyBook) AddPubKey(p peer.ID, pk ic.PubKey) error {
 	}
 
 	mkb.Lock()
+	defer mkb.Unlock()
+	if _, ok := mkb.pks[p]; !ok {
+		mkb.changes.Broadcast(peerstore.Change{Kind: peerstore.KeysAdded, Peer: p})
+	}
 	mkb.pks[p] = pk
-	mkb.Unlock()
 	return nil
 }
 
@@ -84,14 +91,22 @@ func (mkb *memoryKeyBook) AddPrivKey(p peer.ID, sk ic.PrivKey) error {
 	}
 
 	mkb.Lock()
+	defer mkb.Unlock()
+	if _, ok := mkb.sks[p]; !ok {
+		mkb.changes.Broadcast(peerstore.Change{Kind: peerstore.KeysAdded, Peer: p})
+	}
 	mkb.sks[p] = sk
-	mkb.Unlock()
 	return nil
 }
 
 func (mkb *memoryKeyBook) RemovePeer(p peer.ID) {
 	mkb.Lock()
+	defer mkb.Unlock()
+	_, hasPub := mkb.pks[p]
+	_, hasPriv := mkb.sks[p]
+	if hasPub || hasPriv {
+		mkb.changes.Broadcast(peerstore.Change{Kind: peerstore.KeysRemoved, Peer: p})
+	}
 	delete(mkb.sks, p)
 	delete(mkb.pks, p)
-	mkb.Unlock()
 }
@@ -1,35 +1,113 @@
 package pstoremem
 
 import (
+	"container/list"
 	"sync"
+	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	pstore "github.com/TheNoobiCat/go-libp2p/core/peerstore"
 )
 
+type metadataEntry struct {
+	p      peer.ID
+	key    string
+	val    interface{}
+	expiry time.Time
+}
+
 type memoryPeerMetadata struct {
 	// store other data, like versions
-	ds     map[peer.ID]map[string]interface{}
+	ds     map[peer.ID]map[string]*list.Element
 	dslock sync.RWMutex
+	// entries ordered by the time they were last written, oldest first
+	order *list.List
+
+	ttl           time.Duration
+	maxEntries    int
+	clock         clock
+	metricsTracer MetricsTracer
 }
 
 var _ pstore.PeerMetadata = (*memoryPeerMetadata)(nil)
 
-func NewPeerMetadata() *memoryPeerMetadata {
-	return &memoryPeerMetadata{
-		ds: make(map[peer.ID]map[string]interface{}),
+// MetadataOption is an option for the metadata store.
+type MetadataOption func(*memoryPeerMetadata)
+
+// WithMetadataTTL expires metadata entries that weren't written for d. Defaults to
+// no expiry.
+func WithMetadataTTL(d time.Duration) MetadataOption {
+	return func(m *memoryPeerMetadata) {
+		m.ttl = d
+	}
+}
+
+// WithMaxMetadataEntries bounds the number of metadata entries, across all peers.
+// When the bound is reached, the least recently written entry is evicted. Defaults to
+// no bound.
+func WithMaxMetadataEntries(n int) MetadataOption {
+	return func(m *memoryPeerMetadata) {
+		m.maxEntries = n
+	}
+}
+
+// WithMetadataClock sets the clock used to expire metadata entries.
+func WithMetadataClock(clock clock) MetadataOption {
+	return func(m *memoryPeerMetadata) {
+		m.clock = clock
+	}
+}
+
+// WithMetadataMetricsTracer records the evictions of metadata entries.
+func WithMetadataMetricsTracer(mt MetricsTracer) MetadataOption {
+	return func(m *memoryPeerMetadata) {
+		m.metricsTracer = mt
+	}
+}
+
+func NewPeerMetadata(opts ...MetadataOption) *memoryPeerMetadata {
+	m := &memoryPeerMetadata{
+		ds:    make(map[peer.ID]map[string]*list.Element),
+		order: list.New(),
+		clock: realclock{},
+	}
+	for _, opt := range opts {
+		opt(m)
 	}
+	return m
 }
 
 func (ps *memoryPeerMetadata) Put(p peer.ID, key string, val interface{}) error {
 	ps.dslock.Lock()
 	defer ps.dslock.Unlock()
+
+	now := ps.clock.Now()
+	ps.expireLocked(now)
+
+	var expiry time.Time
+	if ps.ttl > 0 {
+		expiry = now.Add(ps.ttl)
+	}
 	m, ok := ps.ds[p]
 	if !ok {
-		m = make(map[string]interface{})
+		m = make(map[string]*list.Element)
 		ps.ds[p] = m
 	}
-	m[key] = val
+	if el, ok := m[key]; ok {
+		e := el.Value.(*metadataEntry)
+		e.val = val
+		e.expiry = expiry
+		ps.order.MoveToBack(el)
+		return nil
+	}
+	m[key] = ps.order.PushBack(&metadataEntry{p: p, key: key, val: val, expiry: expiry})
+
+	if ps.maxEntries > 0 && ps.order.Len() > ps.maxEntries {
+		ps.removeLocked(ps.order.Front())
+		if ps.metricsTracer != nil {
+			ps.metricsTracer.MetadataEvicted(evictionReasonCapacity)
+		}
+	}
 	return nil
 }
 
@@ -40,15 +118,49 @@ func (ps *memoryPeerMetadata) Get(p peer.ID, key string) (interface{}, error) {
 	if !ok {
 		return nil, pstore.ErrNotFound
 	}
-	val, ok := m[key]
+	el, ok := m[key]
 	if !ok {
 		return nil, pstore.ErrNotFound
 	}
-	return val, nil
+	e := el.Value.(*metadataEntry)
+	// expired entries are removed on the next Put
+	if !e.expiry.IsZero() && !ps.clock.Now().Before(e.expiry) {
+		return nil, pstore.ErrNotFound
+	}
+	return e.val, nil
 }
 
 func (ps *memoryPeerMetadata) RemovePeer(p peer.ID) {
 	ps.dslock.Lock()
+	for _, el := range ps.ds[p] {
+		ps.order.Remove(el)
+	}
 	delete(ps.ds, p)
 	ps.dslock.Unlock()
 }
+
+// expireLocked removes the expired entries. As all entries have the same TTL, they
+// expire in the order they were written.
+func (ps *memoryPeerMetadata) expireLocked(now time.Time) {
+	if ps.ttl <= 0 {
+		return
+	}
+	for el := ps.order.Front(); el != nil; el = ps.order.Front() {
+		if now.Before(el.Value.(*metadataEntry).expiry) {
+			return
+		}
+		ps.removeLocked(el)
+		if ps.metricsTracer != nil {
+			ps.metricsTracer.MetadataEvicted(evictionReasonTTL)
+		}
+	}
+}
+
+func (ps *memoryPeerMetadata) removeLocked(el *list.Element) {
+	e := ps.order.Remove(el).(*metadataEntry)
+	m := ps.ds[e.p]
+	delete(m, e.key)
+	if len(m) == 0 {
+		delete(ps.ds, e.p)
+	}
+}
@@ -0,0 +1,67 @@
+package pstoremem
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricNamespace = "libp2p_peerstore"
+
+const (
+	evictionReasonTTL      = "ttl"
+	evictionReasonCapacity = "capacity"
+)
+
+var (
+	metadataEvictionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "metadata_evictions_total",
+			Help:      "Peer metadata entries evicted, by reason",
+		},
+		[]string{"reason"},
+	)
+
+	collectors = []prometheus.Collector{
+		metadataEvictionsTotal,
+	}
+)
+
+// MetricsTracer records the evictions of the in-memory peerstore.
+type MetricsTracer interface {
+	// MetadataEvicted is called when a metadata entry is evicted because it expired
+	// ("ttl") or the store is full ("capacity").
+	MetadataEvicted(reason string)
+}
+
+type metricsTracer struct{}
+
+var _ MetricsTracer = &metricsTracer{}
+
+type metricsTracerSetting struct {
+	reg prometheus.Registerer
+}
+
+type MetricsTracerOption func(*metricsTracerSetting)
+
+func WithRegisterer(reg prometheus.Registerer) MetricsTracerOption {
+	return func(s *metricsTracerSetting) {
+		if reg != nil {
+			s.reg = reg
+		}
+	}
+}
+
+func NewMetricsTracer(opts ...MetricsTracerOption) MetricsTracer {
+	setting := &metricsTracerSetting{reg: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt(setting)
+	}
+	metricshelper.RegisterCollectors(setting.reg, collectors...)
+	return &metricsTracer{}
+}
+
+func (mt *metricsTracer) MetadataEvicted(reason string) {
+	metadataEvictionsTotal.WithLabelValues(reason).Inc()
+}
@@ -1,6 +1,7 @@
 package pstoremem
 
 import (
+	"context"
 	"fmt"
 	"io"
 
@@ -11,14 +12,21 @@ import (
 
 type pstoremem struct {
 	peerstore.Metrics
+	peerstore.AddrMetrics
 
 	*memoryKeyBook
 	*memoryAddrBook
 	*memoryProtoBook
 	*memoryPeerMetadata
+
+	changes *pstore.ChangeSubManager
 }
 
 var _ peerstore.Peerstore = &pstoremem{}
+var _ peerstore.ChangeNotifier = &pstoremem{}
+var _ peerstore.Querier = &pstoremem{}
+var _ peerstore.AddrMetrics = &pstoremem{}
+var _ peerstore.AddrProvenanceBook = &pstoremem{}
 
 type Option interface{}
 
@@ -28,12 +36,15 @@ type Option interface{}
 func NewPeerstore(opts ...Option) (ps *pstoremem, err error) {
 	var protoBookOpts []ProtoBookOption
 	var addrBookOpts []AddrBookOption
+	var metadataOpts []MetadataOption
 	for _, opt := range opts {
 		switch o := opt.(type) {
 		case ProtoBookOption:
 			protoBookOpts = append(protoBookOpts, o)
 		case AddrBookOption:
 			addrBookOpts = append(addrBookOpts, o)
+		case MetadataOption:
+			metadataOpts = append(metadataOpts, o)
 		default:
 			return nil, fmt.Errorf("unexpected peer store option: %v", o)
 		}
@@ -46,12 +57,21 @@ func NewPeerstore(opts ...Option) (ps *pstoremem, err error) {
 		return nil, err
 	}
 
+	kb := NewKeyBook()
+	changes := pstore.NewChangeSubManager()
+	ab.changes = changes
+	pb.changes = changes
+	kb.changes = changes
+
+	metrics := pstore.NewMetrics()
 	return &pstoremem{
-		Metrics:            pstore.NewMetrics(),
-		memoryKeyBook:      NewKeyBook(),
+		Metrics:            metrics,
+		AddrMetrics:        metrics,
+		memoryKeyBook:      kb,
 		memoryAddrBook:     ab,
 		memoryProtoBook:    pb,
-		memoryPeerMetadata: NewPeerMetadata(),
+		memoryPeerMetadata: NewPeerMetadata(metadataOpts...),
+		changes:            changes,
 	}, nil
 }
 
@@ -98,6 +118,18 @@ func (ps *pstoremem) PeerInfo(p peer.ID) peer.AddrInfo {
 	}
 }
 
+// QueryPeers returns the peers selected by q.
+func (ps *pstoremem) QueryPeers(q peerstore.Query) (peerstore.QueryResult, error) {
+	return peerstore.RunQuery(q, ps.Peers(), ps.memoryProtoBook, ps.memoryAddrBook.queryAddrs)
+}
+
+// SubscribeChanges returns a channel on which the changes to the addresses, protocols
+// and keys are published. Expired addresses are reported as removed when they're
+// garbage collected.
+func (ps *pstoremem) SubscribeChanges(ctx context.Context) <-chan peerstore.Change {
+	return ps.changes.SubscribeChanges(ctx)
+}
+
 // RemovePeer removes entries associated with a peer from:
 // * the KeyBook
 // * the ProtoBook
@@ -1,9 +1,15 @@
 package pstoremem
 
 import (
+	"context"
+	"crypto/rand"
 	"testing"
+	"time"
 
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 )
@@ -21,3 +27,67 @@ func TestPeerStoreAddrBookOpts(t *testing.T) {
 	res = ps.Addrs("p2")
 	require.Empty(t, res)
 }
+
+func TestPeerStoreChanges(t *testing.T) {
+	ps, err := NewPeerstore()
+	require.NoError(t, err)
+	defer ps.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := ps.SubscribeChanges(ctx)
+	next := func() peerstore.Change {
+		t.Helper()
+		select {
+		case c := <-changes:
+			return c
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a change")
+			return peerstore.Change{}
+		}
+	}
+
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	p, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+	a1 := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1")
+	a2 := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+
+	ps.AddAddrs(p, []ma.Multiaddr{a1, a2}, peerstore.TempAddrTTL)
+	require.Equal(t, peerstore.Change{Kind: peerstore.AddrsAdded, Peer: p, Addrs: []ma.Multiaddr{a1, a2}}, next())
+	// adding known addresses with a shorter TTL doesn't change anything
+	ps.AddAddr(p, a1, time.Second)
+	ps.SetAddr(p, a1, peerstore.ConnectedAddrTTL)
+	require.Equal(t, peerstore.Change{Kind: peerstore.AddrsUpdated, Peer: p, Addrs: []ma.Multiaddr{a1}}, next())
+	ps.UpdateAddrs(p, peerstore.ConnectedAddrTTL, 0)
+	require.Equal(t, peerstore.Change{Kind: peerstore.AddrsRemoved, Peer: p, Addrs: []ma.Multiaddr{a1}}, next())
+	ps.ClearAddrs(p)
+	require.Equal(t, peerstore.Change{Kind: peerstore.AddrsRemoved, Peer: p, Addrs: []ma.Multiaddr{a2}}, next())
+
+	require.NoError(t, ps.AddProtocols(p, "/a", "/b"))
+	c := next()
+	require.Equal(t, peerstore.ProtocolsAdded, c.Kind)
+	require.ElementsMatch(t, []protocol.ID{"/a", "/b"}, c.Protocols)
+	require.NoError(t, ps.SetProtocols(p, "/b", "/c"))
+	require.Equal(t, peerstore.Change{Kind: peerstore.ProtocolsAdded, Peer: p, Protocols: []protocol.ID{"/c"}}, next())
+	require.Equal(t, peerstore.Change{Kind: peerstore.ProtocolsRemoved, Peer: p, Protocols: []protocol.ID{"/a"}}, next())
+	require.NoError(t, ps.RemoveProtocols(p, "/b", "/d"))
+	require.Equal(t, peerstore.Change{Kind: peerstore.ProtocolsRemoved, Peer: p, Protocols: []protocol.ID{"/b"}}, next())
+
+	require.NoError(t, ps.AddPrivKey(p, priv))
+	require.Equal(t, peerstore.Change{Kind: peerstore.KeysAdded, Peer: p}, next())
+	require.NoError(t, ps.AddPubKey(p, priv.GetPublic()))
+	require.Equal(t, peerstore.Change{Kind: peerstore.KeysAdded, Peer: p}, next())
+	ps.RemovePeer(p)
+	require.Equal(t, peerstore.Change{Kind: peerstore.KeysRemoved, Peer: p}, next())
+	require.Equal(t, peerstore.Change{Kind: peerstore.ProtocolsRemoved, Peer: p, Protocols: []protocol.ID{"/c"}}, next())
+
+	cancel()
+	select {
+	case _, ok := <-changes:
+		require.False(t, ok)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the channel to be closed")
+	}
+}
@@ -5,8 +5,9 @@ import (
 	"sync"
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
-	pstore "github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	pstore "github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore"
 )
 
 type protoSegment struct {
@@ -26,9 +27,12 @@ type memoryProtoBook struct {
 	segments protoSegments
 
 	maxProtos int
+
+	// changes publishes the changes to the protocols. It's set by NewPeerstore.
+	changes *pstore.ChangeSubManager
 }
 
-var _ pstore.ProtoBook = (*memoryProtoBook)(nil)
+var _ peerstore.ProtoBook = (*memoryProtoBook)(nil)
 
 type ProtoBookOption func(book *memoryProtoBook) error
 
@@ -72,8 +76,23 @@ func (pb *memoryProtoBook) SetProtocols(p peer.ID, protos ...protocol.ID) error
 
 	s := pb.segments.get(p)
 	s.Lock()
+	defer s.Unlock()
+	if pb.changes.HasSubscribers() {
+		var added, removed []protocol.ID
+		for proto := range newprotos {
+			if _, ok := s.protocols[p][proto]; !ok {
+				added = append(added, proto)
+			}
+		}
+		for proto := range s.protocols[p] {
+			if _, ok := newprotos[proto]; !ok {
+				removed = append(removed, proto)
+			}
+		}
+		pb.publish(p, peerstore.ProtocolsAdded, added)
+		pb.publish(p, peerstore.ProtocolsRemoved, removed)
+	}
 	s.protocols[p] = newprotos
-	s.Unlock()
 
 	return nil
 }
@@ -92,9 +111,14 @@ func (pb *memoryProtoBook) AddProtocols(p peer.ID, protos ...protocol.ID) error
 		return errTooManyProtocols
 	}
 
+	var added []protocol.ID
 	for _, proto := range protos {
-		protomap[proto] = struct{}{}
+		if _, ok := protomap[proto]; !ok {
+			protomap[proto] = struct{}{}
+			added = append(added, proto)
+		}
 	}
+	pb.publish(p, peerstore.ProtocolsAdded, added)
 	return nil
 }
 
@@ -122,12 +146,17 @@ func (pb *memoryProtoBook) RemoveProtocols(p peer.ID, protos ...protocol.ID) err
 		return nil
 	}
 
+	var removed []protocol.ID
 	for _, proto := range protos {
-		delete(protomap, proto)
+		if _, ok := protomap[proto]; ok {
+			delete(protomap, proto)
+			removed = append(removed, proto)
+		}
 	}
 	if len(protomap) == 0 {
 		delete(s.protocols, p)
 	}
+	pb.publish(p, peerstore.ProtocolsRemoved, removed)
 	return nil
 }
 
@@ -162,6 +191,20 @@ func (pb *memoryProtoBook) FirstSupportedProtocol(p peer.ID, protos ...protocol.
 func (pb *memoryProtoBook) RemovePeer(p peer.ID) {
 	s := pb.segments.get(p)
 	s.Lock()
+	defer s.Unlock()
+	if pb.changes.HasSubscribers() {
+		removed := make([]protocol.ID, 0, len(s.protocols[p]))
+		for proto := range s.protocols[p] {
+			removed = append(removed, proto)
+		}
+		pb.publish(p, peerstore.ProtocolsRemoved, removed)
+	}
 	delete(s.protocols, p)
-	s.Unlock()
+}
+
+// publish publishes a change of the protocols of p, if there are any.
+func (pb *memoryProtoBook) publish(p peer.ID, kind peerstore.ChangeKind, protos []protocol.ID) {
+	if len(protos) > 0 {
+		pb.changes.Broadcast(peerstore.Change{Kind: kind, Peer: p, Protocols: protos})
+	}
 }
@@ -0,0 +1,315 @@
+// Package seed warms up the address book of a host from a seed file, and optionally
+// connects to some of the seeded peers.
+//
+// A seed file lists one entry per line, in the format of /dnsaddr TXT records, so that
+// it can be generated from, or published as, DNS seed records:
+//
+//	# bootstrap nodes
+//	dnsaddr=/ip4/1.2.3.4/tcp/4001/p2p/12D3KooWJkt...
+//	/ip4/1.2.3.4/udp/4001/quic-v1/p2p/12D3KooWJkt...
+//	record=CAASpgIwggEiMA0GCSqGSIb3DQEBAQUAA4IBDwAwggEKAoIBAQ...
+//
+// Lines with a dnsaddr= prefix or without a prefix are multiaddrs ending with the peer
+// ID of the peer. Lines with a record= prefix are signed peer records, encoded as
+// base64 envelopes. Empty lines and lines starting with # are ignored.
+//
+//	seeds, err := seed.ParseFile("seeds.txt")
+//	if err != nil {
+//		return err
+//	}
+//	err = seed.Load(ctx, h, seeds, seed.WithConnect(4))
+package seed
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/record"
+
+	logging "github.com/ipfs/go-log/v2"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+var log = logging.Logger("peerstore/seed")
+
+const (
+	dnsaddrPrefix = "dnsaddr="
+	recordPrefix  = "record="
+
+	defaultConnectTimeout = 30 * time.Second
+)
+
+// Seed is a peer of a seed file.
+type Seed struct {
+	// AddrInfo holds the unsigned addresses of the peer. The addresses of Record
+	// aren't included.
+	peer.AddrInfo
+	// Record is the signed peer record of the peer, if the seed file has one.
+	Record *record.Envelope
+}
+
+// Parse parses a seed file. The entries of the same peer are merged.
+func Parse(r io.Reader) ([]Seed, error) {
+	var seeds []Seed
+	index := make(map[peer.ID]int)
+	get := func(p peer.ID) *Seed {
+		i, ok := index[p]
+		if !ok {
+			i = len(seeds)
+			index[p] = i
+			seeds = append(seeds, Seed{AddrInfo: peer.AddrInfo{ID: p}})
+		}
+		return &seeds[i]
+	}
+
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if s, ok := strings.CutPrefix(line, recordPrefix); ok {
+			env, rec, err := parseRecord(s)
+			if err != nil {
+				return nil, fmt.Errorf("seed: line %d: %w", n, err)
+			}
+			sd := get(rec.PeerID)
+			if sd.Record != nil {
+				return nil, fmt.Errorf("seed: line %d: duplicate record for peer %s", n, rec.PeerID)
+			}
+			sd.Record = env
+			continue
+		}
+		a, err := ma.NewMultiaddr(strings.TrimPrefix(line, dnsaddrPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("seed: line %d: %w", n, err)
+		}
+		ai, err := peer.AddrInfoFromP2pAddr(a)
+		if err != nil {
+			return nil, fmt.Errorf("seed: line %d: %w", n, err)
+		}
+		sd := get(ai.ID)
+		sd.Addrs = append(sd.Addrs, ai.Addrs...)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+	return seeds, nil
+}
+
+func parseRecord(s string) (*record.Envelope, *peer.PeerRecord, error) {
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid record encoding: %w", err)
+	}
+	var rec peer.PeerRecord
+	env, err := record.ConsumeTypedEnvelope(data, &rec)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid record: %w", err)
+	}
+	return env, &rec, nil
+}
+
+// ParseFile parses the seed file at path.
+func ParseFile(path string) ([]Seed, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("seed: %w", err)
+	}
+	defer f.Close()
+	return Parse(f)
+}
+
+// FormatRecord returns the seed file line of the signed peer record env.
+func FormatRecord(env *record.Envelope) (string, error) {
+	data, err := env.Marshal()
+	if err != nil {
+		return "", err
+	}
+	return recordPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+type config struct {
+	ttl            time.Duration
+	recordTTL      time.Duration
+	signedOnly     bool
+	connect        int
+	connectTimeout time.Duration
+}
+
+// Option is an option for Load.
+type Option func(*config) error
+
+// WithTTL sets the TTL of the addresses of the seeds without a signed peer record.
+// Defaults to peerstore.AddressTTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) error {
+		c.ttl = ttl
+		return nil
+	}
+}
+
+// WithRecordTTL sets the TTL of the addresses of the seeds with a signed peer record.
+// Defaults to peerstore.AddressTTL.
+func WithRecordTTL(ttl time.Duration) Option {
+	return func(c *config) error {
+		c.recordTTL = ttl
+		return nil
+	}
+}
+
+// SignedOnly makes Load ignore the unsigned addresses of the seeds, and so the seeds
+// without a signed peer record.
+func SignedOnly() Option {
+	return func(c *config) error {
+		c.signedOnly = true
+		return nil
+	}
+}
+
+// WithConnect makes Load connect to n randomly chosen seeds, in parallel. Load fails
+// if it can't connect to any of them.
+func WithConnect(n int) Option {
+	return func(c *config) error {
+		if n < 0 {
+			return errors.New("seed: number of peers to connect to must not be negative")
+		}
+		c.connect = n
+		return nil
+	}
+}
+
+// WithConnectTimeout sets the timeout of the connections to the seeds. Defaults to
+// 30 seconds.
+func WithConnectTimeout(d time.Duration) Option {
+	return func(c *config) error {
+		c.connectTimeout = d
+		return nil
+	}
+}
+
+// Load adds the addresses of seeds to the peerstore of h. The signed peer records are
+// consumed by the certified address book if the peerstore has one. The addresses of
+// the records that are rejected, e.g. because the peerstore has a newer record of the
+// peer, are skipped.
+func Load(ctx context.Context, h host.Host, seeds []Seed, opts ...Option) error {
+	cfg := config{
+		ttl:            peerstore.AddressTTL,
+		recordTTL:      peerstore.AddressTTL,
+		connectTimeout: defaultConnectTimeout,
+	}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return err
+		}
+	}
+
+	ps := h.Peerstore()
+	cab, hasCAB := peerstore.GetCertifiedAddrBook(ps)
+	loaded := make([]peer.AddrInfo, 0, len(seeds))
+	for _, sd := range seeds {
+		if sd.ID == h.ID() {
+			continue
+		}
+		var addrs []ma.Multiaddr
+		if sd.Record != nil {
+			recAddrs, err := consumeRecord(ps, cab, hasCAB, sd.ID, sd.Record, cfg.recordTTL)
+			if err != nil {
+				log.Debugw("rejected peer record", "peer", sd.ID, "error", err)
+			}
+			addrs = append(addrs, recAddrs...)
+		}
+		if !cfg.signedOnly && len(sd.Addrs) > 0 {
+			peerstore.AddAddrsFrom(ps, sd.ID, sd.Addrs, cfg.ttl, peerstore.AddrSourceManual)
+			addrs = append(addrs, sd.Addrs...)
+		}
+		if len(addrs) == 0 {
+			continue
+		}
+		loaded = append(loaded, peer.AddrInfo{ID: sd.ID, Addrs: addrs})
+	}
+
+	if cfg.connect == 0 || len(loaded) == 0 {
+		return nil
+	}
+	return connect(ctx, h, loaded, cfg.connect, cfg.connectTimeout)
+}
+
+// consumeRecord adds the addresses of the signed peer record env of p to the peerstore,
+// and returns them. It returns no addresses if the record is rejected.
+func consumeRecord(ps peerstore.Peerstore, cab peerstore.CertifiedAddrBook, hasCAB bool, p peer.ID, env *record.Envelope, ttl time.Duration) ([]ma.Multiaddr, error) {
+	r, err := env.Record()
+	if err != nil {
+		return nil, err
+	}
+	rec, ok := r.(*peer.PeerRecord)
+	if !ok {
+		return nil, errors.New("not a peer record")
+	}
+	if rec.PeerID != p || !p.MatchesPublicKey(env.PublicKey) {
+		return nil, errors.New("record isn't signed by the peer")
+	}
+	if !hasCAB {
+		peerstore.AddAddrsFrom(ps, p, rec.Addrs, ttl, peerstore.AddrSourcePeerRecord)
+		return rec.Addrs, nil
+	}
+	accepted, err := cab.ConsumePeerRecord(env, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if !accepted {
+		return nil, errors.New("peerstore has a newer record")
+	}
+	return rec.Addrs, nil
+}
+
+// LoadFile parses the seed file at path and loads it with Load.
+func LoadFile(ctx context.Context, h host.Host, path string, opts ...Option) error {
+	seeds, err := ParseFile(path)
+	if err != nil {
+		return err
+	}
+	return Load(ctx, h, seeds, opts...)
+}
+
+func connect(ctx context.Context, h host.Host, peers []peer.AddrInfo, n int, timeout time.Duration) error {
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if n < len(peers) {
+		peers = peers[:n]
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(peers))
+	for i, ai := range peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			errs[i] = h.Connect(ctx, ai)
+			if errs[i] != nil {
+				log.Debugw("failed to connect to seed", "peer", ai.ID, "error", errs[i])
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("seed: failed to connect to any seed: %w", errors.Join(errs...))
+}
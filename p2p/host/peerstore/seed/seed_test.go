@@ -0,0 +1,170 @@
+package seed
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p"
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/record"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func signedRecord(t *testing.T, addrs ...ma.Multiaddr) (peer.ID, *record.Envelope) {
+	t.Helper()
+	priv, _, err := test.RandTestKeyPair(crypto.Ed25519, 256)
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+	env, err := record.Seal(peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: id, Addrs: addrs}), priv)
+	require.NoError(t, err)
+	return id, env
+}
+
+func TestParse(t *testing.T) {
+	p1, err := test.RandPeerID()
+	require.NoError(t, err)
+	a1 := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	a2 := ma.StringCast("/ip4/1.2.3.4/udp/4001/quic-v1")
+	a3 := ma.StringCast("/ip4/5.6.7.8/tcp/4001")
+	p2, env := signedRecord(t, a3)
+	recLine, err := FormatRecord(env)
+	require.NoError(t, err)
+
+	seeds, err := Parse(strings.NewReader(fmt.Sprintf(`
+# seeds
+dnsaddr=%s/p2p/%s
+  %s/p2p/%s
+
+%s
+`, a1, p1, a2, p1, recLine)))
+	require.NoError(t, err)
+	require.Len(t, seeds, 2)
+	require.Equal(t, peer.AddrInfo{ID: p1, Addrs: []ma.Multiaddr{a1, a2}}, seeds[0].AddrInfo)
+	require.Nil(t, seeds[0].Record)
+	require.Equal(t, peer.AddrInfo{ID: p2}, seeds[1].AddrInfo)
+	require.NotNil(t, seeds[1].Record)
+
+	for _, tc := range []struct {
+		doc, err string
+	}{
+		{"/ip4/1.2.3.4/tcp/4001", "line 1"},
+		{"# comment\n/ip4/1.2.3.4/tcp", "line 2"},
+		{"record=!!!", "invalid record encoding"},
+		{"record=" + strings.TrimPrefix(recLine, recordPrefix)[:20], "invalid record"},
+		{recLine + "\n" + recLine, "duplicate record"},
+	} {
+		_, err := Parse(strings.NewReader(tc.doc))
+		require.ErrorContains(t, err, tc.err, tc.doc)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	defer h.Close()
+
+	p1, err := test.RandPeerID()
+	require.NoError(t, err)
+	a1 := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	a2 := ma.StringCast("/ip4/5.6.7.8/tcp/4001")
+	a3 := ma.StringCast("/ip4/5.6.7.8/udp/4001/quic-v1")
+	p2, env := signedRecord(t, a2)
+	seeds := []Seed{
+		{AddrInfo: peer.AddrInfo{ID: p1, Addrs: []ma.Multiaddr{a1}}},
+		{AddrInfo: peer.AddrInfo{ID: p2, Addrs: []ma.Multiaddr{a3}}, Record: env},
+	}
+
+	require.NoError(t, Load(context.Background(), h, seeds, SignedOnly()))
+	require.Empty(t, h.Peerstore().Addrs(p1))
+	require.Equal(t, []ma.Multiaddr{a2}, h.Peerstore().Addrs(p2))
+	cab, ok := peerstore.GetCertifiedAddrBook(h.Peerstore())
+	require.True(t, ok)
+	require.NotNil(t, cab.GetPeerRecord(p2))
+
+	require.NoError(t, Load(context.Background(), h, seeds, WithTTL(time.Hour)))
+	require.Equal(t, []ma.Multiaddr{a1}, h.Peerstore().Addrs(p1))
+	ab := h.Peerstore().(peerstore.AddrProvenanceBook)
+	require.Equal(t, []ma.Multiaddr{a1}, ab.AddrsFrom(p1, peerstore.AddrSourceManual))
+	require.Equal(t, []ma.Multiaddr{a3}, ab.AddrsFrom(p2, peerstore.AddrSourceManual))
+}
+
+func TestLoadRejectedRecord(t *testing.T) {
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	defer h.Close()
+
+	priv, _, err := test.RandTestKeyPair(crypto.Ed25519, 256)
+	require.NoError(t, err)
+	p, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+	seal := func(seq uint64, a ma.Multiaddr) *record.Envelope {
+		rec := peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: p, Addrs: []ma.Multiaddr{a}})
+		rec.Seq = seq
+		env, err := record.Seal(rec, priv)
+		require.NoError(t, err)
+		return env
+	}
+	a1 := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	a2 := ma.StringCast("/ip4/5.6.7.8/tcp/4001")
+	cab, ok := peerstore.GetCertifiedAddrBook(h.Peerstore())
+	require.True(t, ok)
+	_, err = cab.ConsumePeerRecord(seal(2, a1), time.Hour)
+	require.NoError(t, err)
+
+	// the stale record is rejected, and so are its addresses
+	require.NoError(t, Load(context.Background(), h, []Seed{{AddrInfo: peer.AddrInfo{ID: p}, Record: seal(1, a2)}}))
+	require.Equal(t, []ma.Multiaddr{a1}, h.Peerstore().Addrs(p))
+
+	// so is a record of another peer
+	other, err := test.RandPeerID()
+	require.NoError(t, err)
+	require.NoError(t, Load(context.Background(), h, []Seed{{AddrInfo: peer.AddrInfo{ID: other}, Record: seal(3, a2)}}))
+	require.Empty(t, h.Peerstore().Addrs(other))
+}
+
+func TestLoadFileConnect(t *testing.T) {
+	var lines []string
+	var ids []peer.ID
+	for i := 0; i < 3; i++ {
+		h, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+		require.NoError(t, err)
+		defer h.Close()
+		ids = append(ids, h.ID())
+		addrs, err := peer.AddrInfoToP2pAddrs(&peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()})
+		require.NoError(t, err)
+		lines = append(lines, "dnsaddr="+addrs[0].String())
+	}
+	// a seed that can't be connected to
+	unreachable, err := test.RandPeerID()
+	require.NoError(t, err)
+	lines = append(lines, "/ip4/127.0.0.1/tcp/1/p2p/"+unreachable.String())
+
+	path := filepath.Join(t.TempDir(), "seeds")
+	require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o600))
+
+	h, err := libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	defer h.Close()
+	require.NoError(t, LoadFile(context.Background(), h, path, WithConnect(4)))
+	for _, id := range ids {
+		require.NotEmpty(t, h.Network().ConnsToPeer(id))
+	}
+
+	h2, err := libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	defer h2.Close()
+	err = Load(context.Background(), h2, []Seed{{AddrInfo: peer.AddrInfo{ID: unreachable, Addrs: []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/1")}}}},
+		WithConnect(1), WithConnectTimeout(time.Second))
+	require.ErrorContains(t, err, "failed to connect to any seed")
+}
@@ -24,6 +24,7 @@ var addressBookSuite = map[string]func(book pstore.AddrBook, clk *mockClock.Mock
 	"ClearWithIter":        testClearWithIterator,
 	"PeersWithAddresses":   testPeersWithAddrs,
 	"CertifiedAddresses":   testCertifiedAddresses,
+	"AddrProvenance":       testAddrProvenance,
 }
 
 type AddrBookFactory func() (pstore.AddrBook, func())
@@ -528,3 +529,53 @@ func testCertifiedAddresses(m pstore.AddrBook, clk *mockClock.Mock) func(*testin
 		}
 	}
 }
+
+func testAddrProvenance(m pstore.AddrBook, clk *mockClock.Mock) func(*testing.T) {
+	return func(t *testing.T) {
+		pb, ok := m.(pstore.AddrProvenanceBook)
+		if !ok {
+			t.Skip("address book doesn't track address provenance")
+		}
+
+		id := GeneratePeerIDs(1)[0]
+		addrs := GenerateAddrs(3)
+
+		pb.AddAddrsFrom(id, addrs[:2], time.Hour, pstore.AddrSourceIdentify)
+		clk.Add(time.Second)
+		pb.SetAddrsFrom(id, addrs[1:2], time.Hour, pstore.AddrSourceRouting)
+		m.AddAddr(id, addrs[2], time.Hour)
+
+		AssertAddressesEqual(t, addrs, m.Addrs(id))
+		AssertAddressesEqual(t, addrs[1:2], pb.AddrsFrom(id, pstore.AddrSourceRouting))
+		AssertAddressesEqual(t, addrs[:2], pb.AddrsFrom(id, pstore.AddrSourceIdentify, pstore.AddrSourceRouting))
+		AssertAddressesEqual(t, nil, pb.AddrsFrom(id, pstore.AddrSourceManual))
+
+		provs := pb.AddrProvenance(id)
+		if len(provs) != 3 {
+			t.Fatalf("expected provenance of 3 addrs, got %d", len(provs))
+		}
+		for _, prov := range provs {
+			var expected []pstore.AddrSource
+			switch {
+			case prov.Addr.Equal(addrs[0]):
+				expected = []pstore.AddrSource{pstore.AddrSourceIdentify}
+			case prov.Addr.Equal(addrs[1]):
+				expected = []pstore.AddrSource{pstore.AddrSourceIdentify, pstore.AddrSourceRouting}
+			}
+			if len(prov.Sources) != len(expected) {
+				t.Fatalf("expected %d sources for %s, got %v", len(expected), prov.Addr, prov.Sources)
+			}
+			for _, src := range expected {
+				if _, ok := prov.Sources[src]; !ok {
+					t.Fatalf("expected %s to be learned from %s", prov.Addr, src)
+				}
+			}
+		}
+
+		// the sources are forgotten when the address is removed
+		m.SetAddrs(id, addrs[1:2], 0)
+		AssertAddressesEqual(t, addrs[:1], pb.AddrsFrom(id, pstore.AddrSourceIdentify, pstore.AddrSourceRouting))
+		m.AddAddr(id, addrs[1], time.Hour)
+		AssertAddressesEqual(t, nil, pb.AddrsFrom(id, pstore.AddrSourceRouting))
+	}
+}
@@ -26,6 +26,7 @@ var peerstoreSuite = map[string]func(pstore.Peerstore) func(*testing.T){
 	"BasicPeerstore":           testBasicPeerstore,
 	"Metadata":                 testMetadata,
 	"CertifiedAddrBook":        testCertifiedAddrBook,
+	"QueryPeers":               testQueryPeers,
 }
 
 type PeerstoreFactory func() (pstore.Peerstore, func())
@@ -319,6 +320,63 @@ func testBasicPeerstore(ps pstore.Peerstore) func(t *testing.T) {
 	}
 }
 
+func testQueryPeers(ps pstore.Peerstore) func(t *testing.T) {
+	return func(t *testing.T) {
+		pids := GeneratePeerIDs(10)
+		sort.Slice(pids, func(i, j int) bool { return pids[i] < pids[j] })
+		for i, p := range pids {
+			ttl := time.Hour
+			if i%2 == 0 {
+				ttl = pstore.ConnectedAddrTTL
+			}
+			ps.AddAddrs(p, []ma.Multiaddr{
+				Multiaddr(fmt.Sprintf("/ip4/10.0.0.%d/tcp/4001", i)),
+				Multiaddr(fmt.Sprintf("/ip4/192.168.0.%d/tcp/4001", i)),
+			}, ttl)
+			if i < 3 {
+				require.NoError(t, ps.AddProtocols(p, "/test/1.0.0"))
+			}
+		}
+
+		ids := func(res pstore.QueryResult) []peer.ID {
+			var ids []peer.ID
+			for _, ai := range res.Peers {
+				ids = append(ids, ai.ID)
+			}
+			return ids
+		}
+
+		res, err := pstore.QueryPeers(ps, pstore.Query{Protocols: []protocol.ID{"/test/1.0.0", "/other"}})
+		require.NoError(t, err)
+		require.Equal(t, pids[:3], ids(res))
+		require.Empty(t, res.Next)
+
+		res, err = pstore.QueryPeers(ps, pstore.Query{ConnectedOnly: true})
+		require.NoError(t, err)
+		require.Equal(t, []peer.ID{pids[0], pids[2], pids[4], pids[6], pids[8]}, ids(res))
+
+		res, err = pstore.QueryPeers(ps, pstore.Query{AddrPrefix: Multiaddr("/ip4/10.0.0.3")})
+		require.NoError(t, err)
+		require.Equal(t, []peer.ID{pids[3]}, ids(res))
+		AssertAddressesEqual(t, []ma.Multiaddr{Multiaddr("/ip4/10.0.0.3/tcp/4001")}, res.Peers[0].Addrs)
+
+		// paginate
+		var all []peer.ID
+		q := pstore.Query{Limit: 4}
+		for {
+			res, err := pstore.QueryPeers(ps, q)
+			require.NoError(t, err)
+			require.LessOrEqual(t, len(res.Peers), 4)
+			all = append(all, ids(res)...)
+			if res.Next == "" {
+				break
+			}
+			q.After = res.Next
+		}
+		require.Equal(t, pids, all)
+	}
+}
+
 func testMetadata(ps pstore.Peerstore) func(t *testing.T) {
 	return func(t *testing.T) {
 		t.Run("putting and getting", func(t *testing.T) {
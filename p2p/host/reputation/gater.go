@@ -0,0 +1,60 @@
+package reputation
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/control"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Gater is a connection gater that refuses connections to and from peers whose
+// score is below a threshold. It can be chained with another gater, which is
+// consulted for all connections that the reputation allows.
+type Gater struct {
+	registry  *Registry
+	threshold float64
+	next      connmgr.ConnectionGater
+}
+
+var _ connmgr.ConnectionGater = (*Gater)(nil)
+
+// NewGater creates a gater refusing peers with a score below threshold. next is
+// optional and can be nil.
+func NewGater(r *Registry, threshold float64, next connmgr.ConnectionGater) *Gater {
+	return &Gater{registry: r, threshold: threshold, next: next}
+}
+
+func (g *Gater) allowed(p peer.ID) bool {
+	return g.registry.Score(p) >= g.threshold
+}
+
+func (g *Gater) InterceptPeerDial(p peer.ID) bool {
+	if !g.allowed(p) {
+		return false
+	}
+	return g.next == nil || g.next.InterceptPeerDial(p)
+}
+
+func (g *Gater) InterceptAddrDial(p peer.ID, a ma.Multiaddr) bool {
+	return g.next == nil || g.next.InterceptAddrDial(p, a)
+}
+
+func (g *Gater) InterceptAccept(addrs network.ConnMultiaddrs) bool {
+	return g.next == nil || g.next.InterceptAccept(addrs)
+}
+
+func (g *Gater) InterceptSecured(dir network.Direction, p peer.ID, addrs network.ConnMultiaddrs) bool {
+	if !g.allowed(p) {
+		return false
+	}
+	return g.next == nil || g.next.InterceptSecured(dir, p, addrs)
+}
+
+func (g *Gater) InterceptUpgraded(c network.Conn) (bool, control.DisconnectReason) {
+	if g.next == nil {
+		return true, 0
+	}
+	return g.next.InterceptUpgraded(c)
+}
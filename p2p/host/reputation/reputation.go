@@ -0,0 +1,399 @@
+// Package reputation implements a registry of peer reputation scores shared by the
+// services of a host.
+//
+// Services report positive or negative observations about peers to the Registry.
+// Scores decay exponentially towards zero, so that peers eventually recover from
+// past misbehavior and past good behavior doesn't protect a peer forever. The
+// registry can persist scores to a datastore, feed them to the connection manager
+// as a peer tag, and gate connections to peers with a bad reputation (see Gater).
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("reputation")
+
+const (
+	ns = "/libp2p/host/reputation"
+
+	// ConnManagerTag is the tag used to report the scores to the connection manager.
+	ConnManagerTag = "reputation"
+
+	defaultHalfLife      = time.Hour
+	defaultSweepInterval = time.Minute
+
+	// scores whose absolute value is below minScore are forgotten
+	minScore = 0.01
+)
+
+// Reporter is implemented by the Registry. Services that report observations
+// should depend on this interface.
+type Reporter interface {
+	// Report adds delta to the score of the peer for the given service. Use
+	// negative values to report misbehavior.
+	Report(p peer.ID, service string, delta float64)
+}
+
+// Option is an option for the Registry.
+type Option func(*Registry) error
+
+// WithHalfLife sets the time it takes for a score to decay to half its value.
+// Defaults to one hour.
+func WithHalfLife(d time.Duration) Option {
+	return func(r *Registry) error {
+		if d <= 0 {
+			return errors.New("half life must be positive")
+		}
+		r.halfLife = d
+		return nil
+	}
+}
+
+// WithBounds limits the score a single service can assign to a peer to the
+// interval [lo, hi].
+func WithBounds(lo, hi float64) Option {
+	return func(r *Registry) error {
+		if lo > hi {
+			return errors.New("lower bound exceeds upper bound")
+		}
+		r.lo, r.hi = lo, hi
+		return nil
+	}
+}
+
+// WithSweepInterval sets the interval at which decayed scores are forgotten,
+// persisted and reported to the connection manager. Defaults to one minute.
+func WithSweepInterval(d time.Duration) Option {
+	return func(r *Registry) error {
+		if d <= 0 {
+			return errors.New("sweep interval must be positive")
+		}
+		r.sweepInterval = d
+		return nil
+	}
+}
+
+// WithDatastore persists the scores to the datastore. Scores are loaded when the
+// Registry is created and written on every sweep and when the Registry is closed.
+func WithDatastore(d datastore.Datastore) Option {
+	return func(r *Registry) error {
+		r.ds = namespace.Wrap(d, datastore.NewKey(ns))
+		return nil
+	}
+}
+
+// WithConnManager reports the scores to the connection manager, as the value of
+// the ConnManagerTag tag, rounded to the nearest integer. This makes the
+// connection manager prune connections to peers with a bad reputation first.
+func WithConnManager(cm connmgr.ConnManager) Option {
+	return func(r *Registry) error {
+		r.cm = cm
+		return nil
+	}
+}
+
+// WithClock sets the clock used by the Registry.
+func WithClock(c clock.Clock) Option {
+	return func(r *Registry) error {
+		r.clock = c
+		return nil
+	}
+}
+
+// Registry keeps the reputation scores of peers. It is safe for concurrent use.
+type Registry struct {
+	halfLife      time.Duration
+	sweepInterval time.Duration
+	lo, hi        float64
+	ds            datastore.Datastore
+	cm            connmgr.ConnManager
+	clock         clock.Clock
+
+	mu    sync.Mutex
+	peers map[peer.ID]*peerScores
+	// peers whose scores changed since the last sweep
+	dirty map[peer.ID]struct{}
+
+	closeOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+var _ Reporter = (*Registry)(nil)
+
+type peerScores struct {
+	Services map[string]float64 `json:"services"`
+	// Updated is the time the scores were last decayed.
+	Updated time.Time `json:"updated"`
+}
+
+// NewRegistry creates a new Registry. It must be closed when no longer needed.
+func NewRegistry(opts ...Option) (*Registry, error) {
+	r := &Registry{
+		halfLife:      defaultHalfLife,
+		sweepInterval: defaultSweepInterval,
+		lo:            math.Inf(-1),
+		hi:            math.Inf(1),
+		clock:         clock.New(),
+		peers:         make(map[peer.ID]*peerScores),
+		dirty:         make(map[peer.ID]struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(r); err != nil {
+			return nil, err
+		}
+	}
+	if r.ds != nil {
+		if err := r.load(context.Background()); err != nil {
+			return nil, err
+		}
+	}
+	r.ctx, r.cancel = context.WithCancel(context.Background())
+	r.wg.Add(1)
+	go r.background()
+	return r, nil
+}
+
+// decay brings the scores of ps up to date. Assumes the caller holds r.mu.
+func (r *Registry) decay(ps *peerScores, now time.Time) {
+	elapsed := now.Sub(ps.Updated)
+	if elapsed <= 0 {
+		return
+	}
+	f := math.Exp2(-float64(elapsed) / float64(r.halfLife))
+	for s, v := range ps.Services {
+		ps.Services[s] = v * f
+	}
+	ps.Updated = now
+}
+
+// Report adds delta to the score of the peer for the given service.
+func (r *Registry) Report(p peer.ID, service string, delta float64) {
+	if delta == 0 || math.IsNaN(delta) {
+		return
+	}
+	now := r.clock.Now()
+	r.mu.Lock()
+	ps, ok := r.peers[p]
+	if !ok {
+		ps = &peerScores{Services: make(map[string]float64), Updated: now}
+		r.peers[p] = ps
+	}
+	r.decay(ps, now)
+	ps.Services[service] = min(max(ps.Services[service]+delta, r.lo), r.hi)
+	r.dirty[p] = struct{}{}
+	score := total(ps)
+	r.mu.Unlock()
+
+	if r.cm != nil {
+		r.cm.TagPeer(p, ConnManagerTag, int(math.Round(score)))
+	}
+}
+
+func total(ps *peerScores) float64 {
+	var sum float64
+	for _, v := range ps.Services {
+		sum += v
+	}
+	return sum
+}
+
+// Score returns the score of the peer, the sum of the scores reported by all
+// services. Peers without observations have a score of 0.
+func (r *Registry) Score(p peer.ID) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ps, ok := r.peers[p]
+	if !ok {
+		return 0
+	}
+	r.decay(ps, r.clock.Now())
+	return total(ps)
+}
+
+// ServiceScores returns the score of the peer per service.
+func (r *Registry) ServiceScores(p peer.ID) map[string]float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ps, ok := r.peers[p]
+	if !ok {
+		return nil
+	}
+	r.decay(ps, r.clock.Now())
+	res := make(map[string]float64, len(ps.Services))
+	for s, v := range ps.Services {
+		res[s] = v
+	}
+	return res
+}
+
+// Scores returns the score of all peers with observations.
+func (r *Registry) Scores() map[peer.ID]float64 {
+	now := r.clock.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	res := make(map[peer.ID]float64, len(r.peers))
+	for p, ps := range r.peers {
+		r.decay(ps, now)
+		res[p] = total(ps)
+	}
+	return res
+}
+
+// Forget removes all observations about the peer.
+func (r *Registry) Forget(p peer.ID) {
+	r.mu.Lock()
+	_, ok := r.peers[p]
+	delete(r.peers, p)
+	if ok {
+		r.dirty[p] = struct{}{}
+	}
+	r.mu.Unlock()
+
+	if ok && r.cm != nil {
+		r.cm.UntagPeer(p, ConnManagerTag)
+	}
+}
+
+func (r *Registry) background() {
+	defer r.wg.Done()
+	t := r.clock.Ticker(r.sweepInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			r.sweep()
+		case <-r.ctx.Done():
+			return
+		}
+	}
+}
+
+// sweep forgets the scores that decayed to zero, persists the changed scores and
+// updates the connection manager's tags.
+func (r *Registry) sweep() {
+	now := r.clock.Now()
+	type update struct {
+		p      peer.ID
+		score  float64
+		remove bool
+	}
+	var updates []update
+	r.mu.Lock()
+	for p, ps := range r.peers {
+		r.decay(ps, now)
+		for s, v := range ps.Services {
+			if math.Abs(v) < minScore {
+				delete(ps.Services, s)
+			}
+		}
+		if len(ps.Services) == 0 {
+			delete(r.peers, p)
+			r.dirty[p] = struct{}{}
+			updates = append(updates, update{p: p, remove: true})
+			continue
+		}
+		updates = append(updates, update{p: p, score: total(ps)})
+	}
+	r.mu.Unlock()
+
+	if r.cm != nil {
+		for _, u := range updates {
+			if u.remove {
+				r.cm.UntagPeer(u.p, ConnManagerTag)
+			} else {
+				r.cm.TagPeer(u.p, ConnManagerTag, int(math.Round(u.score)))
+			}
+		}
+	}
+	if err := r.flush(r.ctx); err != nil {
+		log.Warnw("failed to persist reputation scores", "error", err)
+	}
+}
+
+// flush writes the changed scores to the datastore.
+func (r *Registry) flush(ctx context.Context) error {
+	if r.ds == nil {
+		return nil
+	}
+	r.mu.Lock()
+	puts := make(map[peer.ID][]byte, len(r.dirty))
+	for p := range r.dirty {
+		ps, ok := r.peers[p]
+		if !ok {
+			puts[p] = nil
+			continue
+		}
+		b, err := json.Marshal(ps)
+		if err != nil {
+			r.mu.Unlock()
+			return err
+		}
+		puts[p] = b
+	}
+	clear(r.dirty)
+	r.mu.Unlock()
+
+	var errs []error
+	for p, b := range puts {
+		key := datastore.NewKey(p.String())
+		if b == nil {
+			errs = append(errs, r.ds.Delete(ctx, key))
+		} else {
+			errs = append(errs, r.ds.Put(ctx, key, b))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Registry) load(ctx context.Context) error {
+	res, err := r.ds.Query(ctx, query.Query{})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	for e := range res.Next() {
+		if e.Error != nil {
+			return e.Error
+		}
+		p, err := peer.Decode(datastore.RawKey(e.Key).BaseNamespace())
+		if err != nil {
+			log.Debugw("ignoring invalid reputation entry", "key", e.Key, "error", err)
+			continue
+		}
+		var ps peerScores
+		if err := json.Unmarshal(e.Value, &ps); err != nil || ps.Services == nil {
+			log.Debugw("ignoring invalid reputation entry", "peer", p, "error", err)
+			continue
+		}
+		r.peers[p] = &ps
+	}
+	return nil
+}
+
+// Close stops the Registry and persists the scores.
+func (r *Registry) Close() error {
+	var err error
+	r.closeOnce.Do(func() {
+		r.cancel()
+		r.wg.Wait()
+		err = r.flush(context.Background())
+	})
+	return err
+}
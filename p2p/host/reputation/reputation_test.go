@@ -0,0 +1,156 @@
+package reputation
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+
+	"github.com/benbjohnson/clock"
+	"github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	"github.com/stretchr/testify/require"
+)
+
+func newRegistry(t *testing.T, opts ...Option) *Registry {
+	t.Helper()
+	r, err := NewRegistry(opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+func TestScoreDecay(t *testing.T) {
+	cl := clock.NewMock()
+	r := newRegistry(t, WithClock(cl), WithHalfLife(time.Hour))
+
+	p := peer.ID("peer")
+	require.Zero(t, r.Score(p))
+	r.Report(p, "identify", -8)
+	r.Report(p, "relay", 4)
+	require.InDelta(t, -4, r.Score(p), 1e-9)
+
+	cl.Add(time.Hour)
+	require.InDelta(t, -2, r.Score(p), 1e-9)
+	scores := r.ServiceScores(p)
+	require.InDelta(t, -4, scores["identify"], 1e-9)
+	require.InDelta(t, 2, scores["relay"], 1e-9)
+
+	r.Report(p, "identify", 4)
+	require.InDelta(t, 2, r.Score(p), 1e-9)
+	require.Len(t, r.Scores(), 1)
+
+	r.Forget(p)
+	require.Zero(t, r.Score(p))
+	require.Empty(t, r.Scores())
+}
+
+func TestScoreBounds(t *testing.T) {
+	r := newRegistry(t, WithBounds(-10, 5))
+	p := peer.ID("peer")
+	for i := 0; i < 5; i++ {
+		r.Report(p, "identify", -5)
+		r.Report(p, "relay", 5)
+	}
+	require.InDelta(t, -5, r.Score(p), 1e-6)
+
+	_, err := NewRegistry(WithBounds(1, -1))
+	require.Error(t, err)
+}
+
+func TestSweepForgetsDecayedScores(t *testing.T) {
+	cl := clock.NewMock()
+	r := newRegistry(t, WithClock(cl), WithHalfLife(time.Minute), WithSweepInterval(time.Minute))
+	r.Report("peer", "identify", 1)
+	require.Eventually(t, func() bool {
+		// the background goroutine might not have created its ticker yet
+		cl.Add(time.Minute)
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		return len(r.peers) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestPersistence(t *testing.T) {
+	ds := dssync.MutexWrap(datastore.NewMapDatastore())
+	p1, p2 := test.RandPeerIDFatal(t), test.RandPeerIDFatal(t)
+
+	r, err := NewRegistry(WithDatastore(ds))
+	require.NoError(t, err)
+	r.Report(p1, "identify", -3)
+	r.Report(p2, "relay", 2)
+	require.NoError(t, r.Close())
+
+	r, err = NewRegistry(WithDatastore(ds))
+	require.NoError(t, err)
+	require.InDelta(t, -3, r.Score(p1), 0.01)
+	require.InDelta(t, 2, r.Score(p2), 0.01)
+	r.Forget(p2)
+	require.NoError(t, r.Close())
+
+	r = newRegistry(t, WithDatastore(ds))
+	require.InDelta(t, -3, r.Score(p1), 0.01)
+	require.Zero(t, r.Score(p2))
+}
+
+type tagRecorder struct {
+	connmgr.NullConnMgr
+	mx   sync.Mutex
+	tags map[peer.ID]int
+}
+
+func (cm *tagRecorder) TagPeer(p peer.ID, tag string, v int) {
+	cm.mx.Lock()
+	defer cm.mx.Unlock()
+	if tag == ConnManagerTag {
+		cm.tags[p] = v
+	}
+}
+
+func (cm *tagRecorder) UntagPeer(p peer.ID, tag string) {
+	cm.mx.Lock()
+	defer cm.mx.Unlock()
+	if tag == ConnManagerTag {
+		delete(cm.tags, p)
+	}
+}
+
+func TestConnManagerTag(t *testing.T) {
+	cm := &tagRecorder{tags: make(map[peer.ID]int)}
+	r := newRegistry(t, WithConnManager(cm))
+	p := peer.ID("peer")
+	r.Report(p, "identify", -2.6)
+	cm.mx.Lock()
+	require.Equal(t, -3, cm.tags[p])
+	cm.mx.Unlock()
+
+	r.Forget(p)
+	cm.mx.Lock()
+	require.NotContains(t, cm.tags, p)
+	cm.mx.Unlock()
+}
+
+func TestGater(t *testing.T) {
+	r := newRegistry(t)
+	good, bad := peer.ID("good"), peer.ID("bad")
+	r.Report(bad, "identify", -10)
+
+	g := NewGater(r, -5, nil)
+	require.True(t, g.InterceptPeerDial(good))
+	require.False(t, g.InterceptPeerDial(bad))
+	require.True(t, g.InterceptSecured(network.DirInbound, good, nil))
+	require.False(t, g.InterceptSecured(network.DirInbound, bad, nil))
+
+	g = NewGater(r, -5, &blockAll{})
+	require.False(t, g.InterceptPeerDial(good))
+}
+
+type blockAll struct {
+	connmgr.ConnectionGater
+}
+
+func (*blockAll) InterceptPeerDial(peer.ID) bool { return false }
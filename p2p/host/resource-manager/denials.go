@@ -0,0 +1,218 @@
+package rcmgr
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+)
+
+// DefaultDenialLogSize is the number of denials kept by the resource manager by
+// default.
+const DefaultDenialLogSize = 128
+
+// denialEmitBufSize is the number of denials that can be waiting to be emitted on
+// the event bus. Further denials aren't emitted.
+const denialEmitBufSize = 256
+
+// WithDenialLogSize sets the number of the most recent denials kept by the resource
+// manager, see Denials. Zero disables the log; denials are still emitted on the
+// event bus, if any. Defaults to DefaultDenialLogSize.
+func WithDenialLogSize(n int) Option {
+	return func(r *resourceManager) error {
+		if n < 0 {
+			return errors.New("denial log size cannot be negative")
+		}
+		r.denials.size = n
+		return nil
+	}
+}
+
+// denialLog keeps the most recent denials in a ring buffer, and emits them on the
+// event buses set with SetEventBus.
+type denialLog struct {
+	size int
+
+	mx       sync.Mutex
+	ring     []event.EvtResourceDenied
+	next     int
+	emitters []event.Emitter
+
+	emitCh chan event.EvtResourceDenied
+}
+
+func newDenialLog() *denialLog {
+	return &denialLog{
+		size:   DefaultDenialLogSize,
+		emitCh: make(chan event.EvtResourceDenied, denialEmitBufSize),
+	}
+}
+
+// record records a denial. The scope exceeded and its usage are taken from err. It
+// never blocks.
+func (l *denialLog) record(d event.EvtResourceDenied, err error) {
+	if l == nil {
+		return
+	}
+	d.Time = time.Now()
+	d.Err = err
+	var cerr *ErrStreamOrConnLimitExceeded
+	var merr *ErrMemoryLimitExceeded
+	switch {
+	case errors.As(err, &cerr):
+		d.Scope = cerr.scope
+		d.Limit, d.Current, d.Attempted = int64(cerr.limit), int64(cerr.current), int64(cerr.attempted)
+	case errors.As(err, &merr):
+		d.Scope = merr.scope
+		d.Limit, d.Current, d.Attempted = merr.limit, merr.current, merr.attempted
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if l.size > 0 {
+		if len(l.ring) < l.size {
+			l.ring = append(l.ring, d)
+		} else {
+			l.ring[l.next] = d
+		}
+		l.next = (l.next + 1) % l.size
+	}
+	if len(l.emitters) > 0 {
+		select {
+		case l.emitCh <- d:
+		default:
+			log.Debugw("dropping resource denied event", "scope", d.Scope)
+		}
+	}
+}
+
+// denials returns the logged denials, oldest first.
+func (l *denialLog) denials() []event.EvtResourceDenied {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	if len(l.ring) < l.size {
+		return append([]event.EvtResourceDenied(nil), l.ring...)
+	}
+	ds := make([]event.EvtResourceDenied, 0, len(l.ring))
+	ds = append(ds, l.ring[l.next:]...)
+	return append(ds, l.ring[:l.next]...)
+}
+
+func (l *denialLog) addEmitter(em event.Emitter) {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	l.emitters = append(l.emitters, em)
+}
+
+func (l *denialLog) closeEmitters() {
+	l.mx.Lock()
+	defer l.mx.Unlock()
+	for _, em := range l.emitters {
+		em.Close()
+	}
+	l.emitters = nil
+}
+
+// emitLoop emits the recorded denials on the event buses.
+func (r *resourceManager) emitLoop() {
+	defer r.wg.Done()
+	for {
+		select {
+		case d := <-r.denials.emitCh:
+			r.denials.mx.Lock()
+			emitters := r.denials.emitters
+			r.denials.mx.Unlock()
+			for _, em := range emitters {
+				if err := em.Emit(d); err != nil {
+					log.Debugw("failed to emit resource denied event", "err", err)
+				}
+			}
+		case <-r.cancelCtx.Done():
+			return
+		}
+	}
+}
+
+// Denials returns the most recent connections, streams and memory reservations the
+// resource manager denied, oldest first.
+func (r *resourceManager) Denials() []event.EvtResourceDenied {
+	return r.denials.denials()
+}
+
+// SetEventBus makes the resource manager emit an event.EvtResourceDenied on bus for
+// every denial. The events are emitted asynchronously, and dropped if the
+// subscribers don't keep up. It can be called for several buses, e.g. by the hosts
+// sharing the resource manager.
+func (r *resourceManager) SetEventBus(bus event.Bus) error {
+	em, err := bus.Emitter(new(event.EvtResourceDenied))
+	if err != nil {
+		return err
+	}
+	r.denials.addEmitter(em)
+	return nil
+}
+
+// GetDenials returns the most recent denials of the given resource manager, oldest
+// first. It returns nil if rcmgr isn't a resource manager created by this package.
+func GetDenials(rcmgr network.ResourceManager) []event.EvtResourceDenied {
+	r, ok := rcmgr.(*resourceManager)
+	if !ok {
+		return nil
+	}
+	return r.Denials()
+}
+
+// recordMemoryDenial records the denial of a memory reservation in s. The peer,
+// protocol and service are taken from the root of the span tree s belongs to.
+func (s *resourceScope) recordMemoryDenial(err error) {
+	if s.denials == nil || !errors.Is(err, network.ErrResourceLimitExceeded) {
+		return
+	}
+	d := event.EvtResourceDenied{Resource: event.DeniedMemory}
+	root := s
+	for root.owner != nil {
+		root = root.owner
+	}
+	if root.denialAttrs != nil {
+		root.Lock()
+		root.denialAttrs(&d)
+		root.Unlock()
+	}
+	s.denials.record(d, err)
+}
+
+func (s *connectionScope) setDenialAttrs() {
+	s.denials = s.rcmgr.denials
+	s.denialAttrs = func(d *event.EvtResourceDenied) {
+		d.Direction = s.dir
+		d.Endpoint = s.endpoint
+		if s.peer != nil {
+			d.Peer = s.peer.peer
+		}
+	}
+}
+
+// denial returns the denial of attaching the connection to p.
+func (s *connectionScope) denial(p peer.ID) event.EvtResourceDenied {
+	return event.EvtResourceDenied{Resource: event.DeniedConn, Direction: s.dir, Peer: p, Endpoint: s.endpoint}
+}
+
+func (s *streamScope) fillDenial(d *event.EvtResourceDenied) {
+	d.Direction = s.dir
+	d.Peer = s.peer.peer
+	if s.proto != nil {
+		d.Protocol = s.proto.proto
+	}
+	if s.svc != nil {
+		d.Service = s.svc.service
+	}
+}
+
+// denial returns the denial of attaching the stream to proto and svc.
+func (s *streamScope) denial(proto protocol.ID, svc string) event.EvtResourceDenied {
+	return event.EvtResourceDenied{Resource: event.DeniedStream, Direction: s.dir, Peer: s.peer.peer, Protocol: proto, Service: svc}
+}
@@ -0,0 +1,82 @@
+package rcmgr
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenials(t *testing.T) {
+	limits := PartialLimitConfig{
+		PeerDefault: ResourceLimits{StreamsInbound: 1},
+		Stream:      ResourceLimits{Memory: 1024},
+	}.Build(DefaultLimits.AutoScale())
+	rcmgr, err := NewResourceManager(NewFixedLimiter(limits), WithDenialLogSize(2))
+	require.NoError(t, err)
+	defer rcmgr.Close()
+
+	bus := eventbus.NewBus()
+	require.NoError(t, rcmgr.(interface{ SetEventBus(event.Bus) error }).SetEventBus(bus))
+	sub, err := bus.Subscribe(new(event.EvtResourceDenied))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	p := peer.ID("A")
+	s, err := rcmgr.OpenStream(p, network.DirInbound)
+	require.NoError(t, err)
+	defer s.Done()
+	require.NoError(t, s.SetProtocol("/a"))
+	require.Empty(t, GetDenials(rcmgr))
+
+	_, err = rcmgr.OpenStream(p, network.DirInbound)
+	require.ErrorIs(t, err, network.ErrResourceLimitExceeded)
+	denials := GetDenials(rcmgr)
+	require.Len(t, denials, 1)
+	d := denials[0]
+	require.Equal(t, event.DeniedStream, d.Resource)
+	require.Equal(t, peerScopeName(p), d.Scope)
+	require.Equal(t, network.DirInbound, d.Direction)
+	require.Equal(t, int64(1), d.Limit)
+	require.Equal(t, int64(1), d.Current)
+	require.Equal(t, int64(1), d.Attempted)
+	require.Equal(t, p, d.Peer)
+	require.Equal(t, err, d.Err)
+
+	select {
+	case e := <-sub.Out():
+		require.Equal(t, d, e.(event.EvtResourceDenied))
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a resource denied event")
+	}
+
+	// memory reservations of spans are attributed to the stream's peer and protocol
+	span, err := s.BeginSpan()
+	require.NoError(t, err)
+	defer span.Done()
+	require.ErrorIs(t, span.ReserveMemory(2048, network.ReservationPriorityAlways), network.ErrResourceLimitExceeded)
+	denials = GetDenials(rcmgr)
+	require.Len(t, denials, 2)
+	d = denials[1]
+	require.Equal(t, event.DeniedMemory, d.Resource)
+	require.True(t, strings.HasPrefix(d.Scope, "stream-"), d.Scope)
+	require.Equal(t, int64(1024), d.Limit)
+	require.Equal(t, int64(2048), d.Attempted)
+	require.Equal(t, p, d.Peer)
+	require.Equal(t, network.DirInbound, d.Direction)
+	require.Equal(t, "/a", string(d.Protocol))
+
+	// only the most recent denials are kept
+	_, err = rcmgr.OpenStream(p, network.DirInbound)
+	require.Error(t, err)
+	denials = GetDenials(rcmgr)
+	require.Len(t, denials, 2)
+	require.Equal(t, event.DeniedMemory, denials[0].Resource)
+	require.Equal(t, event.DeniedStream, denials[1].Resource)
+}
@@ -9,6 +9,8 @@ import (
 type ErrStreamOrConnLimitExceeded struct {
 	current, attempted, limit int
 	err                       error
+	// scope is the name of the scope whose limit was exceeded
+	scope string
 }
 
 func (e *ErrStreamOrConnLimitExceeded) Error() string { return e.err.Error() }
@@ -56,6 +58,8 @@ type ErrMemoryLimitExceeded struct {
 	current, attempted, limit int64
 	priority                  uint8
 	err                       error
+	// scope is the name of the scope whose limit was exceeded
+	scope string
 }
 
 func (e *ErrMemoryLimitExceeded) Error() string { return e.err.Error() }
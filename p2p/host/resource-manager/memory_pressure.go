@@ -0,0 +1,240 @@
+package rcmgr
+
+import (
+	"errors"
+	"math"
+	"runtime/debug"
+	runtimemetrics "runtime/metrics"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+
+	"github.com/pbnjay/memory"
+)
+
+// heapLiveMetric is the runtime metric of the heap memory occupied by live objects, as
+// measured by the last garbage collection.
+const heapLiveMetric = "/gc/heap/live:bytes"
+
+// DefaultMemoryPressureScale is the default fraction of the inbound connection and
+// stream limits, and of the memory limits, kept under memory pressure.
+const DefaultMemoryPressureScale = 0.5
+
+// MemoryPressureOption configures a MemoryPressureLimiter.
+type MemoryPressureOption func(*MemoryPressureLimiter) error
+
+// WithHeapWatermarks sets the watermarks of the live heap. The limiter enters memory
+// pressure when the live heap reaches high, and leaves it when it drops below low.
+//
+// By default, high is 80% of the Go runtime memory limit (GOMEMLIMIT) if one is set,
+// and half of the system memory otherwise. low is 75% of high.
+func WithHeapWatermarks(high, low uint64) MemoryPressureOption {
+	return func(l *MemoryPressureLimiter) error {
+		if high == 0 || low > high {
+			return errors.New("invalid heap watermarks: must have 0 < low <= high")
+		}
+		l.highWatermark = high
+		l.lowWatermark = low
+		return nil
+	}
+}
+
+// WithMemoryPressureScale sets the fraction of the inbound connection and stream
+// limits, and of the memory limits, of the system and transient scopes kept under
+// memory pressure. It must be between 0 and 1: with 0, all new inbound connections
+// and streams are refused. The default is DefaultMemoryPressureScale.
+func WithMemoryPressureScale(scale float64) MemoryPressureOption {
+	return func(l *MemoryPressureLimiter) error {
+		if scale < 0 || scale > 1 {
+			return errors.New("memory pressure scale must be between 0 and 1")
+		}
+		l.scale = scale
+		return nil
+	}
+}
+
+// WithMemoryPressureCheckInterval sets how often the memory usage is checked. The
+// default is one second.
+func WithMemoryPressureCheckInterval(d time.Duration) MemoryPressureOption {
+	return func(l *MemoryPressureLimiter) error {
+		if d <= 0 {
+			return errors.New("memory pressure check interval must be positive")
+		}
+		l.interval = d
+		return nil
+	}
+}
+
+// MemoryPressureLimiter is a Limiter adjusting the limits of another Limiter to the
+// memory usage of the process. Unlike AutoScale, which computes the limits once from
+// the system memory, it periodically checks the live heap reported by the garbage
+// collector: once it reaches the high watermark, the inbound connection and stream
+// limits, and the memory limits, of the system and transient scopes are scaled down,
+// shedding new inbound connections and streams. The limits are restored once the live
+// heap drops below the low watermark.
+//
+// The limits of allowlisted peers aren't adjusted. The resource manager closes the
+// limiter when it's closed.
+type MemoryPressureLimiter struct {
+	Limiter
+
+	highWatermark, lowWatermark uint64
+	scale                       float64
+	interval                    time.Duration
+	// readHeapLive returns the size of the live heap, in bytes
+	readHeapLive func() uint64
+
+	pressure atomic.Bool
+	heapLive atomic.Uint64
+
+	closeOnce sync.Once
+	closing   chan struct{}
+	done      chan struct{}
+}
+
+var _ Limiter = (*MemoryPressureLimiter)(nil)
+
+// NewMemoryPressureLimiter returns a MemoryPressureLimiter adjusting the limits of
+// base. It starts checking the memory usage of the process until it's closed.
+func NewMemoryPressureLimiter(base Limiter, opts ...MemoryPressureOption) (*MemoryPressureLimiter, error) {
+	l := &MemoryPressureLimiter{
+		Limiter:      base,
+		scale:        DefaultMemoryPressureScale,
+		interval:     time.Second,
+		readHeapLive: readHeapLive,
+		closing:      make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		if err := opt(l); err != nil {
+			return nil, err
+		}
+	}
+	if l.highWatermark == 0 {
+		l.highWatermark = defaultHighWatermark()
+		l.lowWatermark = l.highWatermark / 4 * 3
+	}
+	l.check()
+	go l.background()
+	return l, nil
+}
+
+func defaultHighWatermark() uint64 {
+	if limit := debug.SetMemoryLimit(-1); limit != math.MaxInt64 {
+		return uint64(limit) / 5 * 4
+	}
+	return memory.TotalMemory() / 2
+}
+
+func readHeapLive() uint64 {
+	s := []runtimemetrics.Sample{{Name: heapLiveMetric}}
+	runtimemetrics.Read(s)
+	if s[0].Value.Kind() != runtimemetrics.KindUint64 {
+		return 0
+	}
+	return s[0].Value.Uint64()
+}
+
+func (l *MemoryPressureLimiter) background() {
+	defer close(l.done)
+	t := time.NewTicker(l.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			l.check()
+		case <-l.closing:
+			return
+		}
+	}
+}
+
+// check samples the live heap and updates the pressure state.
+func (l *MemoryPressureLimiter) check() {
+	heap := l.readHeapLive()
+	l.heapLive.Store(heap)
+	heapLiveBytes.Set(float64(heap))
+
+	pressure := l.pressure.Load()
+	switch {
+	case !pressure && heap >= l.highWatermark:
+		log.Warnw("entering memory pressure, scaling down inbound limits", "heap_live", heap, "high_watermark", l.highWatermark)
+	case pressure && heap < l.lowWatermark:
+		log.Infow("leaving memory pressure, restoring limits", "heap_live", heap, "low_watermark", l.lowWatermark)
+	default:
+		return
+	}
+	l.pressure.Store(!pressure)
+	if pressure {
+		memoryPressure.Set(0)
+		memoryPressureTransitions.WithLabelValues("leave").Inc()
+	} else {
+		memoryPressure.Set(1)
+		memoryPressureTransitions.WithLabelValues("enter").Inc()
+	}
+}
+
+// UnderPressure returns whether the limits are currently scaled down.
+func (l *MemoryPressureLimiter) UnderPressure() bool {
+	return l.pressure.Load()
+}
+
+// HeapLive returns the size of the live heap at the last check, in bytes.
+func (l *MemoryPressureLimiter) HeapLive() uint64 {
+	return l.heapLive.Load()
+}
+
+// Close stops checking the memory usage.
+func (l *MemoryPressureLimiter) Close() error {
+	l.closeOnce.Do(func() { close(l.closing) })
+	<-l.done
+	return nil
+}
+
+func (l *MemoryPressureLimiter) GetSystemLimits() Limit {
+	return &pressureLimit{Limit: l.Limiter.GetSystemLimits(), l: l}
+}
+
+func (l *MemoryPressureLimiter) GetTransientLimits() Limit {
+	return &pressureLimit{Limit: l.Limiter.GetTransientLimits(), l: l}
+}
+
+// pressureLimit scales down the inbound connection and stream limits, and the memory
+// limit, of a Limit under memory pressure.
+type pressureLimit struct {
+	Limit
+	l *MemoryPressureLimiter
+}
+
+func (p *pressureLimit) scale(n int) int {
+	if n == math.MaxInt || !p.l.pressure.Load() {
+		return n
+	}
+	return int(float64(n) * p.l.scale)
+}
+
+func (p *pressureLimit) GetStreamLimit(dir network.Direction) int {
+	n := p.Limit.GetStreamLimit(dir)
+	if dir != network.DirInbound {
+		return n
+	}
+	return p.scale(n)
+}
+
+func (p *pressureLimit) GetConnLimit(dir network.Direction) int {
+	n := p.Limit.GetConnLimit(dir)
+	if dir != network.DirInbound {
+		return n
+	}
+	return p.scale(n)
+}
+
+func (p *pressureLimit) GetMemoryLimit() int64 {
+	n := p.Limit.GetMemoryLimit()
+	if n == math.MaxInt64 || !p.l.pressure.Load() {
+		return n
+	}
+	return int64(float64(n) * p.l.scale)
+}
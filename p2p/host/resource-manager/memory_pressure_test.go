@@ -0,0 +1,130 @@
+package rcmgr
+
+import (
+	"math"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func withHeapLiveReader(f func() uint64) MemoryPressureOption {
+	return func(l *MemoryPressureLimiter) error {
+		l.readHeapLive = f
+		return nil
+	}
+}
+
+func TestMemoryPressureLimiter(t *testing.T) {
+	var heap atomic.Uint64
+	heap.Store(100)
+	base := NewFixedLimiter(ConcreteLimitConfig{
+		system: BaseLimit{
+			ConnsInbound:    10,
+			ConnsOutbound:   10,
+			Conns:           20,
+			StreamsInbound:  math.MaxInt,
+			StreamsOutbound: 100,
+			Streams:         math.MaxInt,
+			Memory:          1 << 20,
+			FD:              10,
+		},
+	})
+	l, err := NewMemoryPressureLimiter(base,
+		WithHeapWatermarks(1000, 500),
+		WithMemoryPressureCheckInterval(time.Hour),
+		withHeapLiveReader(heap.Load),
+	)
+	require.NoError(t, err)
+	defer l.Close()
+
+	sys := l.GetSystemLimits()
+	checkLimits := func(connsIn int, memory int64) {
+		t.Helper()
+		require.Equal(t, connsIn, sys.GetConnLimit(network.DirInbound))
+		require.Equal(t, memory, sys.GetMemoryLimit())
+		// outbound and unlimited limits are never scaled
+		require.Equal(t, 10, sys.GetConnLimit(network.DirOutbound))
+		require.Equal(t, 20, sys.GetConnTotalLimit())
+		require.Equal(t, math.MaxInt, sys.GetStreamLimit(network.DirInbound))
+		require.Equal(t, 100, sys.GetStreamLimit(network.DirOutbound))
+	}
+
+	require.False(t, l.UnderPressure())
+	require.Equal(t, uint64(100), l.HeapLive())
+	checkLimits(10, 1<<20)
+
+	heap.Store(1000)
+	l.check()
+	require.True(t, l.UnderPressure())
+	checkLimits(5, 1<<19)
+
+	// hysteresis: stay under pressure until the heap drops below the low watermark
+	heap.Store(600)
+	l.check()
+	require.True(t, l.UnderPressure())
+	heap.Store(499)
+	l.check()
+	require.False(t, l.UnderPressure())
+	checkLimits(10, 1<<20)
+}
+
+func TestMemoryPressureLimiterOptions(t *testing.T) {
+	base := NewFixedLimiter(DefaultLimits.AutoScale())
+	_, err := NewMemoryPressureLimiter(base, WithHeapWatermarks(0, 0))
+	require.Error(t, err)
+	_, err = NewMemoryPressureLimiter(base, WithHeapWatermarks(100, 200))
+	require.Error(t, err)
+	_, err = NewMemoryPressureLimiter(base, WithMemoryPressureScale(1.5))
+	require.Error(t, err)
+	_, err = NewMemoryPressureLimiter(base, WithMemoryPressureCheckInterval(0))
+	require.Error(t, err)
+
+	l, err := NewMemoryPressureLimiter(base)
+	require.NoError(t, err)
+	require.NotZero(t, l.highWatermark)
+	require.Equal(t, l.highWatermark/4*3, l.lowWatermark)
+	require.NoError(t, l.Close())
+	// closing twice is fine
+	require.NoError(t, l.Close())
+}
+
+func TestMemoryPressureShedsInboundConns(t *testing.T) {
+	var heap atomic.Uint64
+	limits := DefaultLimits.AutoScale()
+	limits.system.ConnsInbound = 4
+	limits.transient.ConnsInbound = 4
+	l, err := NewMemoryPressureLimiter(NewFixedLimiter(limits),
+		WithHeapWatermarks(1000, 500),
+		WithMemoryPressureScale(0),
+		WithMemoryPressureCheckInterval(time.Hour),
+		withHeapLiveReader(heap.Load),
+	)
+	require.NoError(t, err)
+	mgr, err := NewResourceManager(l)
+	require.NoError(t, err)
+	defer mgr.Close()
+
+	addr := dummyMA
+	c, err := mgr.OpenConnection(network.DirInbound, false, addr)
+	require.NoError(t, err)
+	defer c.Done()
+
+	heap.Store(1000)
+	l.check()
+	_, err = mgr.OpenConnection(network.DirInbound, false, addr)
+	require.ErrorIs(t, err, network.ErrResourceLimitExceeded)
+	// outbound connections are still allowed
+	c2, err := mgr.OpenConnection(network.DirOutbound, false, addr)
+	require.NoError(t, err)
+	c2.Done()
+
+	heap.Store(0)
+	l.check()
+	c3, err := mgr.OpenConnection(network.DirInbound, false, addr)
+	require.NoError(t, err)
+	c3.Done()
+}
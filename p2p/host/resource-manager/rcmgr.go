@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/netip"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
@@ -26,6 +28,7 @@ type resourceManager struct {
 	limits Limiter
 
 	connLimiter                    *connLimiter
+	streamLimiter                  *streamLimiter
 	connRateLimiter                *rate.Limiter
 	verifySourceAddressRateLimiter *rate.Limiter
 
@@ -33,6 +36,8 @@ type resourceManager struct {
 	metrics        *metrics
 	disableMetrics bool
 
+	denials *denialLog
+
 	allowlist *Allowlist
 
 	system    *systemScope
@@ -113,6 +118,8 @@ type connectionScope struct {
 	peer          *peerScope
 	endpoint      multiaddr.Multiaddr
 	ip            netip.Addr
+	// ipTracked is true if the stream limiter attributes the peer's streams to ip
+	ipTracked bool
 }
 
 var _ network.ConnScope = (*connectionScope)(nil)
@@ -129,6 +136,9 @@ type streamScope struct {
 
 	peerProtoScope *resourceScope
 	peerSvcScope   *resourceScope
+
+	// ip is the IP address the stream limiter attributed this stream to, if any
+	ip netip.Addr
 }
 
 var _ network.StreamScope = (*streamScope)(nil)
@@ -141,11 +151,13 @@ func NewResourceManager(limits Limiter, opts ...Option) (network.ResourceManager
 	r := &resourceManager{
 		limits:          limits,
 		connLimiter:     newConnLimiter(),
+		streamLimiter:   newStreamLimiter(),
 		allowlist:       &allowlist,
 		svc:             make(map[string]*serviceScope),
 		proto:           make(map[protocol.ID]*protocolScope),
 		peer:            make(map[peer.ID]*peerScope),
 		connRateLimiter: newConnRateLimiter(),
+		denials:         newDenialLog(),
 	}
 
 	for _, opt := range opts {
@@ -177,6 +189,30 @@ func NewResourceManager(limits Limiter, opts ...Option) (network.ResourceManager
 	}
 	r.verifySourceAddressRateLimiter = newVerifySourceAddressRateLimiter(r.connLimiter)
 
+	if r.streamLimiter.enabled() {
+		registeredStreamLimiterPrefixes := make(map[netip.Prefix]struct{})
+		for _, npLimit := range r.streamLimiter.networkPrefixLimitV4 {
+			registeredStreamLimiterPrefixes[npLimit.Network] = struct{}{}
+		}
+		for _, npLimit := range r.streamLimiter.networkPrefixLimitV6 {
+			registeredStreamLimiterPrefixes[npLimit.Network] = struct{}{}
+		}
+		for _, network := range allowlist.allowedNetworks {
+			prefix, err := netip.ParsePrefix(network.String())
+			if err != nil {
+				log.Debugf("failed to parse prefix from allowlist %s, %s", network, err)
+				continue
+			}
+			if _, ok := registeredStreamLimiterPrefixes[prefix]; !ok {
+				// trusted networks may exceed the subnet stream limits
+				r.streamLimiter.addNetworkPrefixLimit(prefix.Addr().Is6(), NetworkPrefixStreamLimit{
+					Network:     prefix,
+					StreamCount: r.limits.GetAllowlistedSystemLimits().GetStreamTotalLimit(),
+				})
+			}
+		}
+	}
+
 	if !r.disableMetrics {
 		var sr TraceReporter
 		sr, err := NewStatsTraceReporter()
@@ -215,8 +251,9 @@ func NewResourceManager(limits Limiter, opts ...Option) (network.ResourceManager
 
 	r.cancelCtx, r.cancel = context.WithCancel(context.Background())
 
-	r.wg.Add(1)
+	r.wg.Add(2)
 	go r.background()
+	go r.emitLoop()
 
 	return r, nil
 }
@@ -380,13 +417,18 @@ func (r *resourceManager) OpenConnection(dir network.Direction, usefd bool, endp
 }
 
 func (r *resourceManager) openConnection(dir network.Direction, usefd bool, endpoint multiaddr.Multiaddr, ip netip.Addr) (network.ConnManagementScope, error) {
+	denial := event.EvtResourceDenied{Resource: event.DeniedConn, Direction: dir, Endpoint: endpoint}
 	if !r.connRateLimiter.Allow(ip) {
-		return nil, errors.New("rate limit exceeded")
+		err := errors.New("rate limit exceeded")
+		r.denials.record(denial, err)
+		return nil, err
 	}
 
 	if ip.IsValid() {
 		if ok := r.connLimiter.addConn(ip); !ok {
-			return nil, fmt.Errorf("connections per ip limit exceeded for %s", endpoint)
+			err := fmt.Errorf("connections per ip limit exceeded for %s", endpoint)
+			r.denials.record(denial, err)
+			return nil, err
 		}
 	}
 
@@ -408,6 +450,7 @@ func (r *resourceManager) openConnection(dir network.Direction, usefd bool, endp
 	if err != nil {
 		conn.Done()
 		r.metrics.BlockConn(dir, usefd)
+		r.denials.record(denial, err)
 		return nil, err
 	}
 
@@ -416,14 +459,28 @@ func (r *resourceManager) openConnection(dir network.Direction, usefd bool, endp
 }
 
 func (r *resourceManager) OpenStream(p peer.ID, dir network.Direction) (network.StreamManagementScope, error) {
+	var ip netip.Addr
+	if r.streamLimiter.enabled() {
+		var ok bool
+		ip, ok = r.streamLimiter.addStream(p)
+		if !ok {
+			r.metrics.BlockStream(p, dir)
+			err := fmt.Errorf("streams per ip limit exceeded for %s: %w", ip, network.ErrResourceLimitExceeded)
+			r.denials.record(event.EvtResourceDenied{Resource: event.DeniedStream, Direction: dir, Peer: p}, err)
+			return nil, err
+		}
+	}
+
 	peer := r.getPeerScope(p)
 	stream := newStreamScope(dir, r.limits.GetStreamLimits(p), peer, r)
+	stream.ip = ip
 	peer.DecRef() // we have the reference in edges
 
 	err := stream.AddStream(dir)
 	if err != nil {
 		stream.Done()
 		r.metrics.BlockStream(p, dir)
+		r.denials.record(event.EvtResourceDenied{Resource: event.DeniedStream, Direction: dir, Peer: p}, err)
 		return nil, err
 	}
 
@@ -434,7 +491,11 @@ func (r *resourceManager) OpenStream(p peer.ID, dir network.Direction) (network.
 func (r *resourceManager) Close() error {
 	r.cancel()
 	r.wg.Wait()
+	r.denials.closeEmitters()
 	r.trace.Close()
+	if c, ok := r.limits.(io.Closer); ok {
+		return c.Close()
+	}
 
 	return nil
 }
@@ -511,52 +572,65 @@ func (r *resourceManager) gc() {
 }
 
 func newSystemScope(limit Limit, rcmgr *resourceManager, name string) *systemScope {
-	return &systemScope{
+	s := &systemScope{
 		resourceScope: newResourceScope(limit, nil, name, rcmgr.trace, rcmgr.metrics),
 	}
+	s.denials = rcmgr.denials
+	return s
 }
 
 func newTransientScope(limit Limit, rcmgr *resourceManager, name string, systemScope *resourceScope) *transientScope {
-	return &transientScope{
+	s := &transientScope{
 		resourceScope: newResourceScope(limit,
 			[]*resourceScope{systemScope},
 			name, rcmgr.trace, rcmgr.metrics),
 		system: rcmgr.system,
 	}
+	s.denials = rcmgr.denials
+	return s
 }
 
 func newServiceScope(service string, limit Limit, rcmgr *resourceManager) *serviceScope {
-	return &serviceScope{
+	s := &serviceScope{
 		resourceScope: newResourceScope(limit,
 			[]*resourceScope{rcmgr.system.resourceScope},
 			fmt.Sprintf("service:%s", service), rcmgr.trace, rcmgr.metrics),
 		service: service,
 		rcmgr:   rcmgr,
 	}
+	s.denials = rcmgr.denials
+	s.denialAttrs = func(d *event.EvtResourceDenied) { d.Service = service }
+	return s
 }
 
 func newProtocolScope(proto protocol.ID, limit Limit, rcmgr *resourceManager) *protocolScope {
-	return &protocolScope{
+	s := &protocolScope{
 		resourceScope: newResourceScope(limit,
 			[]*resourceScope{rcmgr.system.resourceScope},
 			fmt.Sprintf("protocol:%s", proto), rcmgr.trace, rcmgr.metrics),
 		proto: proto,
 		rcmgr: rcmgr,
 	}
+	s.denials = rcmgr.denials
+	s.denialAttrs = func(d *event.EvtResourceDenied) { d.Protocol = proto }
+	return s
 }
 
 func newPeerScope(p peer.ID, limit Limit, rcmgr *resourceManager) *peerScope {
-	return &peerScope{
+	s := &peerScope{
 		resourceScope: newResourceScope(limit,
 			[]*resourceScope{rcmgr.system.resourceScope},
 			peerScopeName(p), rcmgr.trace, rcmgr.metrics),
 		peer:  p,
 		rcmgr: rcmgr,
 	}
+	s.denials = rcmgr.denials
+	s.denialAttrs = func(d *event.EvtResourceDenied) { d.Peer = p }
+	return s
 }
 
 func newConnectionScope(dir network.Direction, usefd bool, limit Limit, rcmgr *resourceManager, endpoint multiaddr.Multiaddr, ip netip.Addr) *connectionScope {
-	return &connectionScope{
+	s := &connectionScope{
 		resourceScope: newResourceScope(limit,
 			[]*resourceScope{rcmgr.transient.resourceScope, rcmgr.system.resourceScope},
 			connScopeName(rcmgr.nextConnId()), rcmgr.trace, rcmgr.metrics),
@@ -566,10 +640,12 @@ func newConnectionScope(dir network.Direction, usefd bool, limit Limit, rcmgr *r
 		endpoint: endpoint,
 		ip:       ip,
 	}
+	s.setDenialAttrs()
+	return s
 }
 
 func newAllowListedConnectionScope(dir network.Direction, usefd bool, limit Limit, rcmgr *resourceManager, endpoint multiaddr.Multiaddr) *connectionScope {
-	return &connectionScope{
+	s := &connectionScope{
 		resourceScope: newResourceScope(limit,
 			[]*resourceScope{rcmgr.allowlistedTransient.resourceScope, rcmgr.allowlistedSystem.resourceScope},
 			connScopeName(rcmgr.nextConnId()), rcmgr.trace, rcmgr.metrics),
@@ -579,10 +655,12 @@ func newAllowListedConnectionScope(dir network.Direction, usefd bool, limit Limi
 		endpoint:      endpoint,
 		isAllowlisted: true,
 	}
+	s.setDenialAttrs()
+	return s
 }
 
 func newStreamScope(dir network.Direction, limit Limit, peer *peerScope, rcmgr *resourceManager) *streamScope {
-	return &streamScope{
+	s := &streamScope{
 		resourceScope: newResourceScope(limit,
 			[]*resourceScope{peer.resourceScope, rcmgr.transient.resourceScope, rcmgr.system.resourceScope},
 			streamScopeName(rcmgr.nextStreamId()), rcmgr.trace, rcmgr.metrics),
@@ -590,6 +668,9 @@ func newStreamScope(dir network.Direction, limit Limit, peer *peerScope, rcmgr *
 		rcmgr: peer.rcmgr,
 		peer:  peer,
 	}
+	s.denials = rcmgr.denials
+	s.denialAttrs = s.fillDenial
+	return s
 }
 
 func IsSystemScope(name string) bool {
@@ -732,6 +813,9 @@ func (s *connectionScope) Done() {
 	if s.ip.IsValid() {
 		s.rcmgr.connLimiter.rmConn(s.ip)
 	}
+	if s.ipTracked {
+		s.rcmgr.streamLimiter.rmPeerConn(s.peer.peer, s.ip)
+	}
 	s.resourceScope.doneUnlocked()
 }
 
@@ -804,6 +888,7 @@ func (s *connectionScope) SetPeer(p peer.ID) error {
 			// was _almost_ an allowlisted connection.
 			if err := s.transferAllowedToStandard(); err != nil {
 				// Failed to transfer this connection to the standard scopes
+				s.rcmgr.denials.record(s.denial(p), err)
 				return err
 			}
 
@@ -821,6 +906,7 @@ func (s *connectionScope) SetPeer(p peer.ID) error {
 		s.peer.DecRef()
 		s.peer = nil
 		s.rcmgr.metrics.BlockPeer(p)
+		s.rcmgr.denials.record(s.denial(p), err)
 		return err
 	}
 
@@ -834,10 +920,27 @@ func (s *connectionScope) SetPeer(p peer.ID) error {
 	}
 	s.resourceScope.edges = edges
 
+	if s.ip.IsValid() && s.rcmgr.streamLimiter.enabled() {
+		s.rcmgr.streamLimiter.addPeerConn(p, s.ip)
+		s.ipTracked = true
+	}
+
 	s.rcmgr.metrics.AllowPeer(p)
 	return nil
 }
 
+func (s *streamScope) Done() {
+	s.Lock()
+	defer s.Unlock()
+	if s.done {
+		return
+	}
+	if s.ip.IsValid() {
+		s.rcmgr.streamLimiter.rmStream(s.ip)
+	}
+	s.resourceScope.doneUnlocked()
+}
+
 func (s *streamScope) ProtocolScope() network.ProtocolScope {
 	s.Lock()
 	defer s.Unlock()
@@ -866,6 +969,7 @@ func (s *streamScope) SetProtocol(proto protocol.ID) error {
 		s.proto.DecRef()
 		s.proto = nil
 		s.rcmgr.metrics.BlockProtocol(proto)
+		s.rcmgr.denials.record(s.denial(proto, ""), err)
 		return err
 	}
 
@@ -877,6 +981,7 @@ func (s *streamScope) SetProtocol(proto protocol.ID) error {
 		s.peerProtoScope.DecRef()
 		s.peerProtoScope = nil
 		s.rcmgr.metrics.BlockProtocolPeer(proto, s.peer.peer)
+		s.rcmgr.denials.record(s.denial(proto, ""), err)
 		return err
 	}
 
@@ -927,6 +1032,7 @@ func (s *streamScope) SetService(svc string) error {
 		s.svc.DecRef()
 		s.svc = nil
 		s.rcmgr.metrics.BlockService(svc)
+		s.rcmgr.denials.record(s.denial(s.proto.proto, svc), err)
 		return err
 	}
 
@@ -939,6 +1045,7 @@ func (s *streamScope) SetService(svc string) error {
 		s.peerSvcScope.DecRef()
 		s.peerSvcScope = nil
 		s.rcmgr.metrics.BlockServicePeer(svc, s.peer.peer)
+		s.rcmgr.denials.record(s.denial(s.proto.proto, svc), err)
 		return err
 	}
 
@@ -1,12 +1,14 @@
 package rcmgr
 
 import (
+	"errors"
 	"fmt"
 	"math"
 	"math/big"
 	"strings"
 	"sync"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 )
 
@@ -41,9 +43,14 @@ type resourceScope struct {
 	owner *resourceScope   // set in span scopes, which define trees
 	edges []*resourceScope // set in DAG scopes, it's the linearized parent set
 
-	name    string   // for debugging purposes
-	trace   *trace   // debug tracing
-	metrics *metrics // metrics collection
+	name    string     // for debugging purposes
+	trace   *trace     // debug tracing
+	metrics *metrics   // metrics collection
+	denials *denialLog // denial reporting
+
+	// denialAttrs sets the peer, protocol and service of the denials of memory
+	// reservations in the scope and its spans. It's called with the scope locked.
+	denialAttrs func(*event.EvtResourceDenied)
 }
 
 var _ network.ResourceScope = (*resourceScope)(nil)
@@ -71,6 +78,7 @@ func newResourceScopeSpan(owner *resourceScope, id int) *resourceScope {
 		name:    fmt.Sprintf("%s.span-%d", owner.name, id),
 		trace:   owner.trace,
 		metrics: owner.metrics,
+		denials: owner.denials,
 	}
 	r.trace.CreateScope(r.name, r.rc.limit)
 	return r
@@ -333,10 +341,26 @@ func (rc *resources) stat() network.ScopeStat {
 
 // resourceScope implementation
 func (s *resourceScope) wrapError(err error) error {
+	// the first scope to wrap a limit error is the one whose limit was exceeded
+	var cerr *ErrStreamOrConnLimitExceeded
+	var merr *ErrMemoryLimitExceeded
+	if errors.As(err, &cerr) && cerr.scope == "" {
+		cerr.scope = s.name
+	} else if errors.As(err, &merr) && merr.scope == "" {
+		merr.scope = s.name
+	}
 	return fmt.Errorf("%s: %w", s.name, err)
 }
 
 func (s *resourceScope) ReserveMemory(size int, prio uint8) error {
+	err := s.reserveMemory(size, prio)
+	if err != nil {
+		s.recordMemoryDenial(err)
+	}
+	return err
+}
+
+func (s *resourceScope) reserveMemory(size int, prio uint8) error {
 	s.Lock()
 	defer s.Unlock()
 
@@ -364,7 +388,7 @@ func (s *resourceScope) ReserveMemory(size int, prio uint8) error {
 
 func (s *resourceScope) reserveMemoryForEdges(size int, prio uint8) error {
 	if s.owner != nil {
-		return s.owner.ReserveMemory(size, prio)
+		return s.owner.reserveMemory(size, prio)
 	}
 
 	var reserved int
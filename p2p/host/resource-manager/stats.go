@@ -123,6 +123,23 @@ var (
 		Name:      "blocked_resources",
 		Help:      "Number of blocked resources",
 	}, []string{"dir", "scope", "resource"})
+
+	// Memory pressure, see MemoryPressureLimiter
+	heapLiveBytes = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Name:      "heap_live_bytes",
+		Help:      "Live heap of the process at the last memory pressure check",
+	})
+	memoryPressure = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricNamespace,
+		Name:      "memory_pressure",
+		Help:      "Whether the limits are scaled down due to memory pressure",
+	})
+	memoryPressureTransitions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricNamespace,
+		Name:      "memory_pressure_transitions_total",
+		Help:      "Number of times the limiter entered or left memory pressure",
+	}, []string{"transition"})
 )
 
 var (
@@ -157,6 +174,10 @@ func MustRegisterWith(reg prometheus.Registerer) {
 		previousConnMemory,
 		fds,
 		blockedResources,
+
+		heapLiveBytes,
+		memoryPressure,
+		memoryPressureTransitions,
 	)
 }
 
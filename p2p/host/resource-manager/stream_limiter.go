@@ -0,0 +1,247 @@
+package rcmgr
+
+import (
+	"net/netip"
+	"slices"
+	"sync"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+)
+
+type StreamLimitPerSubnet struct {
+	// This defines how big the subnet is. For example, a /24 subnet has a
+	// PrefixLength of 24. All streams of peers connected from IPs that share the
+	// same 24 bit prefix are bound to the same limit.
+	PrefixLength int
+	// The maximum number of streams allowed for each subnet.
+	StreamCount int
+}
+
+type NetworkPrefixStreamLimit struct {
+	// The Network prefix for which this limit applies.
+	Network netip.Prefix
+
+	// The maximum number of streams allowed for this subnet.
+	StreamCount int
+}
+
+// Network prefixes limits must be sorted by most specific to least specific.
+func sortNetworkPrefixStreamLimits(limits []NetworkPrefixStreamLimit) []NetworkPrefixStreamLimit {
+	slices.SortStableFunc(limits, func(a, b NetworkPrefixStreamLimit) int {
+		return b.Network.Bits() - a.Network.Bits()
+	})
+	return limits
+}
+
+// WithNetworkPrefixStreamLimit sets the limits for the number of streams allowed
+// for peers connected from a specific Network Prefix. Use this when you want to set
+// different limits for a specific subnet than the limit per subnet.
+func WithNetworkPrefixStreamLimit(ipv4 []NetworkPrefixStreamLimit, ipv6 []NetworkPrefixStreamLimit) Option {
+	return func(rm *resourceManager) error {
+		if ipv4 != nil {
+			rm.streamLimiter.networkPrefixLimitV4 = sortNetworkPrefixStreamLimits(slices.Clone(ipv4))
+		}
+		if ipv6 != nil {
+			rm.streamLimiter.networkPrefixLimitV6 = sortNetworkPrefixStreamLimits(slices.Clone(ipv6))
+		}
+		return nil
+	}
+}
+
+// WithStreamLimitPerSubnet sets the limits for the number of streams allowed per
+// subnet, e.g. per /24 for IPv4 or per /48 for IPv6. This contains Sybil attacks where
+// many peer IDs are connected from the same network and each stays within its
+// per peer limits. Subnets covered by a network prefix stream limit use that limit
+// instead. By default there are no per subnet stream limits.
+//
+// Streams are attributed to the IP address of the peer's connection, so streams of
+// peers connected only via relays or allowlisted connections are not limited.
+// Allowlisted networks get the allowlisted system stream limit.
+func WithStreamLimitPerSubnet(ipv4 []StreamLimitPerSubnet, ipv6 []StreamLimitPerSubnet) Option {
+	return func(rm *resourceManager) error {
+		if ipv4 != nil {
+			rm.streamLimiter.limitPerSubnetV4 = ipv4
+		}
+		if ipv6 != nil {
+			rm.streamLimiter.limitPerSubnetV6 = ipv6
+		}
+		return nil
+	}
+}
+
+// peerIP is an IP address a peer is connected from and the number of connections
+// from that address.
+type peerIP struct {
+	ip    netip.Addr
+	conns int
+}
+
+type streamLimiter struct {
+	mu sync.Mutex
+
+	// Specific Network Prefix limits. If these are set, they take precedence over the
+	// subnet limits.
+	// These must be sorted by most specific to least specific.
+	networkPrefixLimitV4 []NetworkPrefixStreamLimit
+	networkPrefixLimitV6 []NetworkPrefixStreamLimit
+	streamsPerNetwork    map[netip.Prefix]int
+
+	// Subnet limits.
+	limitPerSubnetV4 []StreamLimitPerSubnet
+	limitPerSubnetV6 []StreamLimitPerSubnet
+	streamsPerSubnet map[netip.Prefix]int
+
+	// peerIPs are the IP addresses the peers are connected from, in the order the
+	// connections were established. Streams are attributed to the first one.
+	peerIPs map[peer.ID][]peerIP
+}
+
+func newStreamLimiter() *streamLimiter {
+	return &streamLimiter{
+		streamsPerNetwork: make(map[netip.Prefix]int),
+		streamsPerSubnet:  make(map[netip.Prefix]int),
+		peerIPs:           make(map[peer.ID][]peerIP),
+	}
+}
+
+// enabled returns true if any stream limits are configured. When they aren't, the
+// limiter doesn't track any state.
+func (sl *streamLimiter) enabled() bool {
+	return len(sl.limitPerSubnetV4) > 0 || len(sl.limitPerSubnetV6) > 0 ||
+		len(sl.networkPrefixLimitV4) > 0 || len(sl.networkPrefixLimitV6) > 0
+}
+
+func (sl *streamLimiter) addNetworkPrefixLimit(isIP6 bool, npLimit NetworkPrefixStreamLimit) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	if isIP6 {
+		sl.networkPrefixLimitV6 = sortNetworkPrefixStreamLimits(append(sl.networkPrefixLimitV6, npLimit))
+	} else {
+		sl.networkPrefixLimitV4 = sortNetworkPrefixStreamLimits(append(sl.networkPrefixLimitV4, npLimit))
+	}
+}
+
+// addPeerConn records that p has a connection from ip.
+func (sl *streamLimiter) addPeerConn(p peer.ID, ip netip.Addr) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	ips := sl.peerIPs[p]
+	for i := range ips {
+		if ips[i].ip == ip {
+			ips[i].conns++
+			return
+		}
+	}
+	sl.peerIPs[p] = append(ips, peerIP{ip: ip, conns: 1})
+}
+
+// rmPeerConn removes a connection recorded with addPeerConn.
+func (sl *streamLimiter) rmPeerConn(p peer.ID, ip netip.Addr) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	ips := sl.peerIPs[p]
+	for i := range ips {
+		if ips[i].ip != ip {
+			continue
+		}
+		ips[i].conns--
+		if ips[i].conns <= 0 {
+			ips = slices.Delete(ips, i, i+1)
+		}
+		break
+	}
+	if len(ips) == 0 {
+		delete(sl.peerIPs, p)
+	} else {
+		sl.peerIPs[p] = ips
+	}
+}
+
+func (sl *streamLimiter) limitsFor(ip netip.Addr) ([]NetworkPrefixStreamLimit, []StreamLimitPerSubnet) {
+	if ip.Is6() {
+		return sl.networkPrefixLimitV6, sl.limitPerSubnetV6
+	}
+	return sl.networkPrefixLimitV4, sl.limitPerSubnetV4
+}
+
+// addStream adds a stream for peer p. It returns the IP address the stream was
+// attributed to, which must be passed to rmStream, and whether the stream is allowed.
+// The returned address is invalid if p isn't connected from any known IP address.
+func (sl *streamLimiter) addStream(p peer.ID) (netip.Addr, bool) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	ips := sl.peerIPs[p]
+	if len(ips) == 0 {
+		return netip.Addr{}, true
+	}
+	ip := ips[0].ip
+	networkPrefixLimits, limits := sl.limitsFor(ip)
+
+	// Check Network Prefix limits first
+	for _, limit := range networkPrefixLimits {
+		if limit.Network.Contains(ip) {
+			if sl.streamsPerNetwork[limit.Network]+1 > limit.StreamCount {
+				return ip, false
+			}
+			sl.streamsPerNetwork[limit.Network]++
+			// Done. If we find a match in the network prefix limits, we use
+			// that and don't use the general subnet limits.
+			return ip, true
+		}
+	}
+
+	for _, limit := range limits {
+		prefix, err := ip.Prefix(limit.PrefixLength)
+		if err != nil {
+			return ip, false
+		}
+		if sl.streamsPerSubnet[prefix]+1 > limit.StreamCount {
+			return ip, false
+		}
+	}
+
+	// All limit checks passed, now we update the counts
+	for _, limit := range limits {
+		prefix, _ := ip.Prefix(limit.PrefixLength)
+		sl.streamsPerSubnet[prefix]++
+	}
+	return ip, true
+}
+
+// rmStream removes a stream added with addStream.
+func (sl *streamLimiter) rmStream(ip netip.Addr) {
+	sl.mu.Lock()
+	defer sl.mu.Unlock()
+	networkPrefixLimits, limits := sl.limitsFor(ip)
+
+	for _, limit := range networkPrefixLimits {
+		if limit.Network.Contains(ip) {
+			if sl.streamsPerNetwork[limit.Network] <= 0 {
+				log.Errorf("unexpected stream count for ip %s. Was this not added with addStream first?", ip)
+				return
+			}
+			sl.streamsPerNetwork[limit.Network]--
+			if sl.streamsPerNetwork[limit.Network] == 0 {
+				delete(sl.streamsPerNetwork, limit.Network)
+			}
+			return
+		}
+	}
+
+	for _, limit := range limits {
+		prefix, err := ip.Prefix(limit.PrefixLength)
+		if err != nil {
+			log.Errorf("unexpected error getting prefix: %v", err)
+			continue
+		}
+		count, ok := sl.streamsPerSubnet[prefix]
+		if !ok || count == 0 {
+			log.Errorf("unexpected stream count for %s ok=%v count=%v", prefix, ok, count)
+			continue
+		}
+		sl.streamsPerSubnet[prefix]--
+		if sl.streamsPerSubnet[prefix] <= 0 {
+			delete(sl.streamsPerSubnet, prefix)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package rcmgr
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+
+	"github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStreamLimiterPerSubnet(t *testing.T) {
+	sl := newStreamLimiter()
+	sl.limitPerSubnetV4 = []StreamLimitPerSubnet{{PrefixLength: 24, StreamCount: 2}}
+	sl.limitPerSubnetV6 = []StreamLimitPerSubnet{{PrefixLength: 48, StreamCount: 1}}
+
+	p1, p2, p3 := peer.ID("p1"), peer.ID("p2"), peer.ID("p3")
+	sl.addPeerConn(p1, netip.MustParseAddr("1.2.3.4"))
+	sl.addPeerConn(p2, netip.MustParseAddr("1.2.3.5"))
+	sl.addPeerConn(p3, netip.MustParseAddr("1:2:3:4::1"))
+
+	ip, ok := sl.addStream(p1)
+	require.True(t, ok)
+	require.Equal(t, netip.MustParseAddr("1.2.3.4"), ip)
+	_, ok = sl.addStream(p2)
+	require.True(t, ok)
+	// p1 and p2 share the /24
+	_, ok = sl.addStream(p1)
+	require.False(t, ok)
+	_, ok = sl.addStream(p2)
+	require.False(t, ok)
+
+	sl.rmStream(ip)
+	_, ok = sl.addStream(p2)
+	require.True(t, ok)
+
+	// IPv6 limits are independent
+	_, ok = sl.addStream(p3)
+	require.True(t, ok)
+	_, ok = sl.addStream(p3)
+	require.False(t, ok)
+
+	// peers without a known IP aren't limited
+	for range 10 {
+		ip, ok := sl.addStream("unknown")
+		require.True(t, ok)
+		require.False(t, ip.IsValid())
+	}
+}
+
+func TestStreamLimiterNetworkPrefix(t *testing.T) {
+	sl := newStreamLimiter()
+	sl.limitPerSubnetV4 = []StreamLimitPerSubnet{{PrefixLength: 24, StreamCount: 1}}
+	sl.networkPrefixLimitV4 = sortNetworkPrefixStreamLimits([]NetworkPrefixStreamLimit{
+		{Network: netip.MustParsePrefix("1.2.0.0/16"), StreamCount: 3},
+	})
+	sl.addPeerConn("p1", netip.MustParseAddr("1.2.3.4"))
+	for range 3 {
+		_, ok := sl.addStream("p1")
+		require.True(t, ok)
+	}
+	_, ok := sl.addStream("p1")
+	require.False(t, ok)
+	// the network prefix limit takes precedence over the subnet limits
+	require.Empty(t, sl.streamsPerSubnet)
+}
+
+func TestStreamLimiterPeerConns(t *testing.T) {
+	sl := newStreamLimiter()
+	ip1, ip2 := netip.MustParseAddr("1.2.3.4"), netip.MustParseAddr("5.6.7.8")
+	sl.addPeerConn("p1", ip1)
+	sl.addPeerConn("p1", ip1)
+	sl.addPeerConn("p1", ip2)
+
+	ip, _ := sl.addStream("p1")
+	require.Equal(t, ip1, ip)
+
+	sl.rmPeerConn("p1", ip1)
+	ip, _ = sl.addStream("p1")
+	require.Equal(t, ip1, ip)
+
+	sl.rmPeerConn("p1", ip1)
+	ip, _ = sl.addStream("p1")
+	require.Equal(t, ip2, ip)
+
+	sl.rmPeerConn("p1", ip2)
+	require.Empty(t, sl.peerIPs)
+}
+
+func TestResourceManagerStreamLimitPerSubnet(t *testing.T) {
+	rcmgr, err := NewResourceManager(NewFixedLimiter(DefaultLimits.AutoScale()),
+		WithStreamLimitPerSubnet([]StreamLimitPerSubnet{{PrefixLength: 24, StreamCount: 2}}, nil),
+		WithAllowlistedMultiaddrs([]multiaddr.Multiaddr{multiaddr.StringCast("/ip4/5.6.7.0/ipcidr/24")}),
+	)
+	require.NoError(t, err)
+	defer rcmgr.Close()
+
+	connect := func(addr string) peer.ID {
+		p := test.RandPeerIDFatal(t)
+		c, err := rcmgr.OpenConnection(network.DirInbound, true, multiaddr.StringCast(addr))
+		require.NoError(t, err)
+		t.Cleanup(c.Done)
+		require.NoError(t, c.SetPeer(p))
+		return p
+	}
+
+	p1 := connect("/ip4/1.2.3.4/tcp/1234")
+	p2 := connect("/ip4/1.2.3.5/tcp/1234")
+
+	s1, err := rcmgr.OpenStream(p1, network.DirInbound)
+	require.NoError(t, err)
+	s2, err := rcmgr.OpenStream(p2, network.DirInbound)
+	require.NoError(t, err)
+	_, err = rcmgr.OpenStream(p2, network.DirInbound)
+	require.ErrorIs(t, err, network.ErrResourceLimitExceeded)
+
+	s1.Done()
+	s3, err := rcmgr.OpenStream(p2, network.DirInbound)
+	require.NoError(t, err)
+	s2.Done()
+	s3.Done()
+
+	// allowlisted networks can exceed the subnet limit
+	p3 := connect("/ip4/5.6.7.8/tcp/1234")
+	for range 5 {
+		s, err := rcmgr.OpenStream(p3, network.DirInbound)
+		require.NoError(t, err)
+		defer s.Done()
+	}
+}
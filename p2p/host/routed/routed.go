@@ -12,10 +12,13 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	basichost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
 
 	logging "github.com/ipfs/go-log/v2"
 
 	ma "github.com/multiformats/go-multiaddr"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 var log = logging.Logger("routedhost")
@@ -49,7 +52,10 @@ func (rh *RoutedHost) Connect(ctx context.Context, pi peer.AddrInfo) error {
 	// first, check if we're already connected unless force direct dial.
 	forceDirect, _ := network.GetForceDirectDial(ctx)
 	canUseLimitedConn, _ := network.GetAllowLimitedConn(ctx)
-	if !forceDirect {
+	// With transport or address constraints, an existing connection might not be
+	// acceptable, the swarm checks when dialing.
+	constrained := network.GetTransportConstraint(ctx) != nil || network.GetAddrFilter(ctx) != nil
+	if !forceDirect && !constrained {
 		connectedness := rh.Network().Connectedness(pi.ID)
 		if connectedness == network.Connected || (canUseLimitedConn && connectedness == network.Limited) {
 			return nil
@@ -98,13 +104,10 @@ func (rh *RoutedHost) Connect(ctx context.Context, pi peer.AddrInfo) error {
 			continue
 		}
 
-		relayAddrs, err := rh.findPeerAddrs(ctx, relayID)
-		if err != nil {
+		// findPeerAddrs adds the addrs of the relay to the peerstore
+		if _, err := rh.findPeerAddrs(ctx, relayID); err != nil {
 			log.Debugf("failed to find relay %s: %s", relay, err)
-			continue
 		}
-
-		rh.Peerstore().AddAddrs(relayID, relayAddrs, peerstore.TempAddrTTL)
 	}
 
 	// if we're here, we got some addrs. let's use our wrapped host to connect.
@@ -144,6 +147,12 @@ func (rh *RoutedHost) Connect(ctx context.Context, pi peer.AddrInfo) error {
 	return nil
 }
 
+// Prewarm connects to peers concurrently, using the routing system to find the
+// addresses of peers without known addresses. See (*basichost.BasicHost).Prewarm.
+func (rh *RoutedHost) Prewarm(ctx context.Context, peers []peer.AddrInfo, opts ...basichost.PrewarmOption) []basichost.PrewarmResult {
+	return basichost.Prewarm(ctx, rh, peers, opts...)
+}
+
 func (rh *RoutedHost) findPeerAddrs(ctx context.Context, id peer.ID) ([]ma.Multiaddr, error) {
 	pi, err := rh.route.FindPeer(ctx, id)
 	if err != nil {
@@ -160,6 +169,7 @@ func (rh *RoutedHost) findPeerAddrs(ctx context.Context, id peer.ID) ([]ma.Multi
 		return nil, err
 	}
 
+	peerstore.AddAddrsFrom(rh.Peerstore(), id, pi.Addrs, peerstore.TempAddrTTL, peerstore.AddrSourceRouting)
 	return pi.Addrs, nil
 }
 
@@ -195,6 +205,18 @@ func (rh *RoutedHost) SetStreamHandlerMatch(pid protocol.ID, m func(protocol.ID)
 	rh.host.SetStreamHandlerMatch(pid, m, handler)
 }
 
+// SetStreamHandlerWithLimits sets the protocol handler on the wrapped host, enforcing
+// limits on the streams it handles. The handler is set without limits if the wrapped
+// host doesn't implement host.StreamHandlerLimiter.
+func (rh *RoutedHost) SetStreamHandlerWithLimits(pid protocol.ID, limits host.StreamHandlerLimits, handler network.StreamHandler) {
+	if l, ok := rh.host.(host.StreamHandlerLimiter); ok {
+		l.SetStreamHandlerWithLimits(pid, limits, handler)
+		return
+	}
+	log.Warnw("the wrapped host can't limit the streams of the handler", "protocol", pid)
+	rh.host.SetStreamHandler(pid, handler)
+}
+
 func (rh *RoutedHost) RemoveStreamHandler(pid protocol.ID) {
 	rh.host.RemoveStreamHandler(pid)
 }
@@ -221,4 +243,38 @@ func (rh *RoutedHost) ConnManager() connmgr.ConnManager {
 	return rh.host.ConnManager()
 }
 
-var _ (host.Host) = (*RoutedHost)(nil)
+// NewStreamWithAddrs opens a new stream to peer p, dialing addrs if there is no
+// connection to p. Unlike NewStream, the routing system isn't queried for the
+// addresses of p.
+func (rh *RoutedHost) NewStreamWithAddrs(ctx context.Context, p peer.ID, addrs []ma.Multiaddr, pids ...protocol.ID) (network.Stream, error) {
+	return rh.host.NewStream(network.WithDialAddrs(ctx, addrs), p, pids...)
+}
+
+// SetUserAgent changes the user-agent of the wrapped host. It's a no-op if the
+// wrapped host doesn't implement host.UserAgentSetter.
+func (rh *RoutedHost) SetUserAgent(ua string) {
+	if s, ok := rh.host.(host.UserAgentSetter); ok {
+		s.SetUserAgent(ua)
+		return
+	}
+	log.Warn("the wrapped host can't change its user-agent")
+}
+
+// MetricsRegisterer returns the registerer of the metrics subsystem of the wrapped
+// host, and false if the wrapped host doesn't report metrics.
+func (rh *RoutedHost) MetricsRegisterer(subsystem string) (prometheus.Registerer, bool) {
+	if p, ok := rh.host.(metricshelper.RegistererProvider); ok {
+		return p.MetricsRegisterer(subsystem)
+	}
+	return nil, false
+}
+
+var (
+	_ host.Host              = (*RoutedHost)(nil)
+	_ host.AddrsStreamOpener = (*RoutedHost)(nil)
+
+	_ host.StreamHandlerLimiter = (*RoutedHost)(nil)
+	_ host.UserAgentSetter      = (*RoutedHost)(nil)
+
+	_ metricshelper.RegistererProvider = (*RoutedHost)(nil)
+)
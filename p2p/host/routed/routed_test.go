@@ -7,6 +7,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	basic "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
 
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
@@ -90,3 +91,14 @@ func TestRoutedHostConnectFindPeerNoUsefulAddrs(t *testing.T) {
 	require.Error(t, rh.Connect(context.Background(), pi))
 	require.Equal(t, 1, mr.callCount, "the mocked FindPeer function should have been called")
 }
+
+func TestRoutedHostSetUserAgent(t *testing.T) {
+	h, err := basic.NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h.Close()
+	h.Start()
+
+	rh := Wrap(h, &mockRouting{})
+	rh.SetUserAgent("test/1.0")
+	require.Equal(t, "test/1.0", h.IDService().(identify.UserAgentSetter).UserAgent())
+}
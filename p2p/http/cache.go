@@ -0,0 +1,316 @@
+package libp2phttp
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+)
+
+// DefaultResponseCacheSize is the default size of a ResponseCache, in bytes.
+const DefaultResponseCacheSize = 16 << 20 // 16MB
+
+// ResponseCache is a client side cache of the responses to GET requests, keyed by
+// server, scheme, host and path. The server is identified by its peer ID if known, and
+// by its address otherwise. The host is the Host header of the request, which is also
+// the TLS server name of HTTPS requests, so that virtual hosts served at the same
+// address don't share responses. It honors the Cache-Control, Expires and ETag headers of the responses:
+//   - fresh responses are served from the cache,
+//   - stale responses with an ETag or a Last-Modified header are revalidated with a
+//     conditional request,
+//   - responses with Cache-Control: no-store, or with a Vary header, aren't cached.
+//
+// Only 200 responses to requests without an Authorization or Range header are
+// cached. Responses larger than an eighth of the cache size aren't cached.
+//
+// A ResponseCache is used by setting Host.ResponseCache. It's safe for concurrent use.
+type ResponseCache struct {
+	maxSize int64
+	now     func() time.Time
+
+	mx      sync.Mutex
+	size    int64
+	lru     *list.List // of *cacheEntry, most recently used first
+	entries map[cacheKey]*list.Element
+}
+
+type cacheKey struct {
+	server string
+	scheme string
+	host   string
+	path   string
+}
+
+type cacheEntry struct {
+	key        cacheKey
+	serverPeer peer.ID
+	status     int
+	header     http.Header
+	body       []byte
+	expires    time.Time
+}
+
+func (e *cacheEntry) size() int64 {
+	size := int64(len(e.body) + len(e.key.server) + len(e.key.scheme) + len(e.key.host) + len(e.key.path))
+	for k, vs := range e.header {
+		for _, v := range vs {
+			size += int64(len(k) + len(v))
+		}
+	}
+	return size
+}
+
+// NewResponseCache creates a ResponseCache holding up to maxSize bytes of responses.
+func NewResponseCache(maxSize int64) *ResponseCache {
+	if maxSize <= 0 {
+		maxSize = DefaultResponseCacheSize
+	}
+	return &ResponseCache{
+		maxSize: maxSize,
+		now:     time.Now,
+		lru:     list.New(),
+		entries: make(map[cacheKey]*list.Element),
+	}
+}
+
+// Purge removes the responses of server p.
+func (c *ResponseCache) Purge(p peer.ID) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	for _, el := range c.entries {
+		if el.Value.(*cacheEntry).serverPeer == p {
+			c.remove(el)
+		}
+	}
+}
+
+// PurgeAll removes all responses.
+func (c *ResponseCache) PurgeAll() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.lru.Init()
+	clear(c.entries)
+	c.size = 0
+}
+
+// Size returns the size of the cached responses, in bytes.
+func (c *ResponseCache) Size() int64 {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	return c.size
+}
+
+func (c *ResponseCache) remove(el *list.Element) {
+	e := c.lru.Remove(el).(*cacheEntry)
+	delete(c.entries, e.key)
+	c.size -= e.size()
+}
+
+func (c *ResponseCache) get(k cacheKey) (*cacheEntry, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return el.Value.(*cacheEntry), true
+}
+
+func (c *ResponseCache) add(e *cacheEntry) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		c.remove(el)
+	}
+	size := e.size()
+	if size > c.maxSize/8 {
+		return
+	}
+	for c.size+size > c.maxSize {
+		c.remove(c.lru.Back())
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	c.size += size
+}
+
+func (c *ResponseCache) delete(k cacheKey) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if el, ok := c.entries[k]; ok {
+		c.remove(el)
+	}
+}
+
+// roundTrip serves r from the cache, or sends it with next, caching the response.
+// server is the peer ID of the server, or its host if the peer ID isn't known.
+func (c *ResponseCache) roundTrip(server string, serverPeer peer.ID, r *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if r.Method != http.MethodGet || r.Header.Get("Authorization") != "" || r.Header.Get("Range") != "" {
+		return next(r)
+	}
+	reqDirectives := parseCacheControl(r.Header)
+	if _, ok := reqDirectives["no-store"]; ok {
+		return next(r)
+	}
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+	k := cacheKey{server: server, scheme: r.URL.Scheme, host: host, path: r.URL.RequestURI()}
+
+	e, ok := c.get(k)
+	if ok {
+		_, noCache := reqDirectives["no-cache"]
+		if !noCache && c.now().Before(e.expires) {
+			return e.response(r), nil
+		}
+		etag, lastModified := e.header.Get("ETag"), e.header.Get("Last-Modified")
+		if (etag != "" || lastModified != "") && r.Header.Get("If-None-Match") == "" && r.Header.Get("If-Modified-Since") == "" {
+			r = r.Clone(r.Context())
+			if etag != "" {
+				r.Header.Set("If-None-Match", etag)
+			}
+			if lastModified != "" {
+				r.Header.Set("If-Modified-Since", lastModified)
+			}
+		} else {
+			ok = false
+		}
+	}
+
+	resp, err := next(r)
+	if err != nil {
+		return nil, err
+	}
+	if ok && resp.StatusCode == http.StatusNotModified {
+		resp.Body.Close()
+		// Update the stored response with the headers of the 304 response, as
+		// required by RFC 9111, section 4.3.4.
+		updated := *e
+		updated.header = e.header.Clone()
+		for h, vs := range resp.Header {
+			updated.header[h] = vs
+		}
+		updated.expires = c.expires(updated.header)
+		c.add(&updated)
+		return updated.response(r), nil
+	}
+	return c.store(k, serverPeer, resp)
+}
+
+// store caches resp if it's cacheable, and returns a response with the same body.
+func (c *ResponseCache) store(k cacheKey, serverPeer peer.ID, resp *http.Response) (*http.Response, error) {
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Vary") != "" {
+		c.delete(k)
+		return resp, nil
+	}
+	directives := parseCacheControl(resp.Header)
+	if _, ok := directives["no-store"]; ok {
+		c.delete(k)
+		return resp, nil
+	}
+	expires := c.expires(resp.Header)
+	if !c.now().Before(expires) && resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		// can't be served from the cache nor revalidated
+		c.delete(k)
+		return resp, nil
+	}
+
+	maxEntrySize := c.maxSize / 8
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxEntrySize+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if int64(len(body)) > maxEntrySize {
+		resp.Body = readCloser{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	c.add(&cacheEntry{
+		key:        k,
+		serverPeer: serverPeer,
+		status:     resp.StatusCode,
+		header:     resp.Header.Clone(),
+		body:       body,
+		expires:    expires,
+	})
+	return resp, nil
+}
+
+// expires returns the time the response with header h becomes stale.
+func (c *ResponseCache) expires(h http.Header) time.Time {
+	now := c.now()
+	directives := parseCacheControl(h)
+	if _, ok := directives["no-cache"]; ok {
+		return now
+	}
+	if v, ok := directives["max-age"]; ok {
+		secs, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || secs < 0 {
+			return now
+		}
+		age, _ := strconv.ParseInt(h.Get("Age"), 10, 64)
+		return now.Add(time.Duration(secs-max(age, 0)) * time.Second)
+	}
+	if v := h.Get("Expires"); v != "" {
+		t, err := http.ParseTime(v)
+		if err != nil {
+			return now
+		}
+		if date, err := http.ParseTime(h.Get("Date")); err == nil {
+			// use the server's clock to compute the lifetime
+			return now.Add(t.Sub(date))
+		}
+		return t
+	}
+	return now
+}
+
+// response returns a response to r from e.
+func (e *cacheEntry) response(r *http.Request) *http.Response {
+	if e.serverPeer != "" {
+		r = r.WithContext(context.WithValue(r.Context(), serverPeerIDContextKey{}, e.serverPeer))
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       r,
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// parseCacheControl returns the directives of the Cache-Control header of h.
+func parseCacheControl(h http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, v := range h.Values("Cache-Control") {
+		for _, d := range strings.Split(v, ",") {
+			d = strings.TrimSpace(d)
+			if d == "" {
+				continue
+			}
+			name, value, _ := strings.Cut(d, "=")
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
@@ -5,8 +5,8 @@ package libp2phttp
 import (
 	"bufio"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -27,7 +27,10 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	httpauth "github.com/TheNoobiCat/go-libp2p/p2p/http/auth"
 	gostream "github.com/TheNoobiCat/go-libp2p/p2p/net/gostream"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcpreuse"
 	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 var log = logging.Logger("libp2phttp")
@@ -74,11 +77,21 @@ type ProtocolMeta struct {
 
 type PeerMeta map[protocol.ID]ProtocolMeta
 
-// WellKnownHandler is an http.Handler that serves the well-known resource
+// WellKnownHandler is an http.Handler that serves the well-known resource. It's
+// served as JSON, CBOR or protobuf, depending on the Accept header of the request,
+// see the WellKnownContentType constants. JSON is served if the request accepts any
+// of them equally.
 type WellKnownHandler struct {
+	// EnableRevalidation sets an ETag and Cache-Control: no-cache on the responses,
+	// so that clients caching them, e.g. with a ResponseCache, revalidate their
+	// copy with a conditional request every time, since the mapping may change.
+	// Conditional requests matching the ETag get a 304 response.
+	EnableRevalidation bool
+
 	wellknownMapMu   sync.Mutex
 	wellKnownMapping PeerMeta
-	wellKnownCache   []byte
+	// wellKnownCache holds the encoded mapping, by media type
+	wellKnownCache map[string][]byte
 }
 
 // streamHostListen returns a net.Listener that listens on libp2p streams for HTTP/1.1 messages.
@@ -87,10 +100,9 @@ func streamHostListen(streamHost host.Host) (net.Listener, error) {
 }
 
 func (h *WellKnownHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	// Check if the requests accepts JSON
-	accepts := r.Header.Get("Accept")
-	if accepts != "" && !(strings.Contains(accepts, "application/json") || strings.Contains(accepts, "*/*")) {
-		http.Error(w, "Only application/json is supported", http.StatusNotAcceptable)
+	contentType, ok := negotiateWellKnownContentType(r.Header.Get("Accept"))
+	if !ok {
+		http.Error(w, "Only "+strings.Join(wellKnownContentTypes, ", ")+" are supported", http.StatusNotAcceptable)
 		return
 	}
 
@@ -99,14 +111,17 @@ func (h *WellKnownHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Return a JSON object with the well-known protocols
+	// Return the well-known protocols in the negotiated encoding
 	h.wellknownMapMu.Lock()
-	mapping := h.wellKnownCache
+	mapping, ok := h.wellKnownCache[contentType]
 	var err error
-	if mapping == nil {
-		mapping, err = json.Marshal(h.wellKnownMapping)
+	if !ok {
+		mapping, err = marshalPeerMeta(contentType, h.wellKnownMapping)
 		if err == nil {
-			h.wellKnownCache = mapping
+			if h.wellKnownCache == nil {
+				h.wellKnownCache = make(map[string][]byte, len(wellKnownContentTypes))
+			}
+			h.wellKnownCache[contentType] = mapping
 		}
 	}
 	h.wellknownMapMu.Unlock()
@@ -114,11 +129,37 @@ func (h *WellKnownHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Marshal error", http.StatusInternalServerError)
 		return
 	}
-	w.Header().Add("Content-Type", "application/json")
+	w.Header().Set("Vary", "Accept")
+	if h.EnableRevalidation {
+		// The ETag is computed over the encoded mapping, so it differs between
+		// encodings.
+		etag := fmt.Sprintf(`"%x"`, sha256.Sum256(mapping))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "no-cache")
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	}
+	w.Header().Add("Content-Type", wellKnownContentTypeHeader(contentType))
 	w.Header().Add("Content-Length", strconv.Itoa(len(mapping)))
 	w.Write(mapping)
 }
 
+// etagMatches returns true if the If-None-Match header ifNoneMatch matches etag. The
+// comparison is weak, as required by RFC 9110, section 13.1.2.
+func etagMatches(ifNoneMatch, etag string) bool {
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	for _, t := range strings.Split(ifNoneMatch, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(t), "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 func (h *WellKnownHandler) AddProtocolMeta(p protocol.ID, protocolMeta ProtocolMeta) {
 	h.wellknownMapMu.Lock()
 	if h.wellKnownMapping == nil {
@@ -153,9 +194,20 @@ type Host struct {
 	ListenAddrs []ma.Multiaddr
 	// TLSConfig is the TLS config for the server to use
 	TLSConfig *tls.Config
+	// AutoTLS, if set and TLSConfig is nil, obtains and renews the certificates of
+	// the HTTPS listeners using ACME. The domain should be part of the listen
+	// addresses, e.g. /ip4/0.0.0.0/tcp/443/tls/sni/example.com/http, so that it is
+	// advertised.
+	AutoTLS *autocert.Manager
 	// InsecureAllowHTTP indicates if the server is allowed to serve unencrypted
 	// HTTP requests over TCP.
 	InsecureAllowHTTP bool
+	// SharedTCP, if set, is used to listen on the listen addresses, so that they can
+	// share a port with the TCP and WebSocket transports of a host constructed with
+	// libp2p.ShareTCPListener. The ConnMgr of the host can be obtained with
+	// libp2p.WithFxOption(fx.Populate(&connMgr)). An HTTPS address can't share its
+	// port with a secure WebSocket address, see tcpreuse.ConnMgr.HTTPSListener.
+	SharedTCP *tcpreuse.ConnMgr
 
 	// ServerPeerIDAuth sets the Server's signing key and TTL for server
 	// provided tokens.
@@ -196,6 +248,10 @@ type Host struct {
 	// newer go-libp2p version and we can remove all this code.
 	EnableCompatibilityWithLegacyWellKnownEndpoint bool
 
+	// ResponseCache, if set, caches the responses to GET requests made by the
+	// Host's round trippers, over both HTTP and stream transports.
+	ResponseCache *ResponseCache
+
 	// peerMetadata is an LRU cache of a peer's well-known protocol map.
 	peerMetadata *lru.Cache[peer.ID, PeerMeta]
 	// createHTTPTransport is used to lazily create the httpTransport in a thread-safe way.
@@ -255,6 +311,13 @@ func (h *Host) PeerID() peer.ID {
 
 var ErrNoListeners = errors.New("nothing to listen on")
 
+func (h *Host) serverTLSConfig() *tls.Config {
+	if h.TLSConfig == nil && h.AutoTLS != nil {
+		return h.AutoTLS.TLSConfig()
+	}
+	return h.TLSConfig
+}
+
 func (h *Host) setupListeners(listenerErrCh chan error) error {
 	for _, addr := range h.ListenAddrs {
 		parsedAddr, err := parseMultiaddr(addr)
@@ -268,7 +331,12 @@ func (h *Host) setupListeners(listenerErrCh chan error) error {
 		}
 
 		host := ipaddr.String()
-		l, err := net.Listen("tcp", host+":"+parsedAddr.port)
+		var l net.Listener
+		if h.SharedTCP != nil {
+			l, err = h.sharedTCPListen(ipaddr.IP, parsedAddr.port, parsedAddr.useHTTPS)
+		} else {
+			l, err = net.Listen("tcp", host+":"+parsedAddr.port)
+		}
 		if err != nil {
 			return err
 		}
@@ -295,7 +363,7 @@ func (h *Host) setupListeners(listenerErrCh chan error) error {
 			go func() {
 				srv := http.Server{
 					Handler:   maybeDecorateContextWithAuthMiddleware(h.ServerPeerIDAuth, h.ServeMux),
-					TLSConfig: h.TLSConfig,
+					TLSConfig: h.serverTLSConfig(),
 				}
 				listenerErrCh <- srv.ServeTLS(l, "", "")
 			}()
@@ -316,6 +384,21 @@ func (h *Host) setupListeners(listenerErrCh chan error) error {
 	return nil
 }
 
+func (h *Host) sharedTCPListen(ip net.IP, port string, useHTTPS bool) (net.Listener, error) {
+	p, err := strconv.Atoi(port)
+	if err != nil {
+		return nil, err
+	}
+	laddr, err := manet.FromNetAddr(&net.TCPAddr{IP: ip, Port: p})
+	if err != nil {
+		return nil, err
+	}
+	if useHTTPS {
+		return h.SharedTCP.HTTPSListener(laddr)
+	}
+	return h.SharedTCP.HTTPListener(laddr)
+}
+
 // Serve starts the HTTP transport listeners. Always returns a non-nil error.
 // If there are no listeners, returns ErrNoListeners.
 func (h *Host) Serve() error {
@@ -368,12 +451,8 @@ func (h *Host) Serve() error {
 			srv := &http.Server{
 				Handler: connectionCloseHeaderMiddleware(h.ServeMux),
 				ConnContext: func(ctx context.Context, c net.Conn) context.Context {
-					remote := c.RemoteAddr()
-					if remote.Network() == gostream.Network {
-						remoteID, err := peer.Decode(remote.String())
-						if err == nil {
-							return context.WithValue(ctx, clientPeerIDContextKey{}, remoteID)
-						}
+					if sc, ok := c.(gostream.Conn); ok {
+						return context.WithValue(ctx, clientPeerIDContextKey{}, sc.RemotePeer())
 					}
 					return ctx
 				},
@@ -490,6 +569,13 @@ func (rt *streamRoundTripper) GetPeerMetadata() (PeerMeta, error) {
 
 // RoundTrip implements http.RoundTripper.
 func (rt *streamRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	if rt.httpHost != nil && rt.httpHost.ResponseCache != nil {
+		return rt.httpHost.ResponseCache.roundTrip(rt.server.String(), rt.server, r, rt.roundTrip)
+	}
+	return rt.roundTrip(r)
+}
+
+func (rt *streamRoundTripper) roundTrip(r *http.Request) (*http.Response, error) {
 	// Add the addresses we learned about for this server
 	if !rt.skipAddAddrs {
 		rt.addrsAdded.Do(func() {
@@ -672,6 +758,13 @@ func (rt *roundTripperForSpecificServer) RoundTrip(r *http.Request) (*http.Respo
 	r.URL.Scheme = rt.scheme
 	r.URL.Host = rt.targetServerAddr
 	r.Host = rt.sni
+	if rt.httpHost != nil && rt.httpHost.ResponseCache != nil {
+		server := rt.server.String()
+		if rt.server == "" {
+			server = rt.targetServerAddr
+		}
+		return rt.httpHost.ResponseCache.roundTrip(server, rt.server, r, rt.RoundTripper.RoundTrip)
+	}
 	return rt.RoundTripper.RoundTrip(r)
 }
 
@@ -802,6 +895,9 @@ func (h *Host) RoundTrip(r *http.Request) (*http.Response, error) {
 			resp.Request = resp.Request.WithContext(ctxWithServerID)
 			return resp, nil
 		}
+		if h.ResponseCache != nil {
+			return h.ResponseCache.roundTrip(r.URL.Host, "", r, h.DefaultClientRoundTripper.RoundTrip)
+		}
 		return h.DefaultClientRoundTripper.RoundTrip(r)
 	case "multiaddr":
 		break
@@ -876,6 +972,9 @@ func (h *Host) RoundTrip(r *http.Request) (*http.Response, error) {
 			return resp, nil
 		}
 
+		if h.ResponseCache != nil {
+			return h.ResponseCache.roundTrip(u.Host, "", r, rt.RoundTrip)
+		}
 		return rt.RoundTrip(r)
 	}
 
@@ -1147,7 +1246,7 @@ func requestPeerMeta(ctx context.Context, roundtripper http.RoundTripper, wellKn
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept", wellKnownAccept)
 
 	client := http.Client{Transport: roundtripper}
 	resp, err := client.Do(req)
@@ -1160,16 +1259,14 @@ func requestPeerMeta(ctx context.Context, roundtripper http.RoundTripper, wellKn
 		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	meta := PeerMeta{}
-	err = json.NewDecoder(&io.LimitedReader{
-		R: resp.Body,
-		N: peerMetadataLimit,
-	}).Decode(&meta)
+	b, err := io.ReadAll(io.LimitReader(resp.Body, peerMetadataLimit+1))
 	if err != nil {
 		return nil, err
 	}
-
-	return meta, nil
+	if len(b) > peerMetadataLimit {
+		return nil, fmt.Errorf("well-known resource larger than %d bytes", peerMetadataLimit)
+	}
+	return unmarshalPeerMeta(resp.Header.Get("Content-Type"), b)
 }
 
 // SetPeerMetadata adds a peer's protocol metadata to the http host. Useful if
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io"
 	"math/big"
+	"mime"
 	"net"
 	"net/http"
 	"net/netip"
@@ -20,6 +21,8 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -627,6 +630,109 @@ func TestSetHandlerAtPath(t *testing.T) {
 	}
 }
 
+func TestWellKnownContentNegotiation(t *testing.T) {
+	wk := &libp2phttp.WellKnownHandler{}
+	revalidatingWK := &libp2phttp.WellKnownHandler{EnableRevalidation: true}
+	for _, h := range []*libp2phttp.WellKnownHandler{wk, revalidatingWK} {
+		h.AddProtocolMeta(httpping.PingProtocolID, libp2phttp.ProtocolMeta{Path: "/ping/"})
+		h.AddProtocolMeta("/hello/1", libp2phttp.ProtocolMeta{Path: "/hello/"})
+	}
+	var revalidate atomic.Bool
+	expected := libp2phttp.PeerMeta{
+		httpping.PingProtocolID: {Path: "/ping/"},
+		"/hello/1":              {Path: "/hello/"},
+	}
+
+	// forceAccept serves the well-known resource as if the client only accepted ct
+	var forceAccept atomic.Value
+	forceAccept.Store("")
+	mux := http.NewServeMux()
+	mux.Handle(libp2phttp.WellKnownProtocols, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := forceAccept.Load().(string); ct != "" {
+			r.Header.Set("Accept", ct)
+		}
+		if revalidate.Load() {
+			revalidatingWK.ServeHTTP(w, r)
+			return
+		}
+		wk.ServeHTTP(w, r)
+	}))
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: mux}
+	go server.Serve(l)
+	defer server.Close()
+	wkURL := "http://" + l.Addr().String() + libp2phttp.WellKnownProtocols
+
+	get := func(accept, ifNoneMatch string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, wkURL, nil)
+		require.NoError(t, err)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	for _, tc := range []struct {
+		accept      string
+		contentType string
+	}{
+		{"", libp2phttp.WellKnownContentTypeJSON},
+		{"*/*", libp2phttp.WellKnownContentTypeJSON},
+		{"application/*", libp2phttp.WellKnownContentTypeJSON},
+		{"application/cbor", libp2phttp.WellKnownContentTypeCBOR},
+		{"application/cbor, */*;q=0.5", libp2phttp.WellKnownContentTypeCBOR},
+		{"application/x-protobuf, application/cbor;q=0.9, application/json;q=0.8", libp2phttp.WellKnownContentTypeProtobuf},
+		{"application/x-protobuf; proto=libp2phttp.pb.PeerMeta", libp2phttp.WellKnownContentTypeProtobuf},
+		{"application/x-protobuf; proto=other.Message, application/json;q=0.5", libp2phttp.WellKnownContentTypeJSON},
+		{"application/json;q=0.1, application/cbor;q=0.5", libp2phttp.WellKnownContentTypeCBOR},
+		{"application/*, application/json;q=0", libp2phttp.WellKnownContentTypeCBOR},
+	} {
+		resp := get(tc.accept, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode, tc.accept)
+		mediaType, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		require.Equal(t, tc.contentType, mediaType, tc.accept)
+		if mediaType == libp2phttp.WellKnownContentTypeProtobuf {
+			require.Equal(t, map[string]string{"proto": "libp2phttp.pb.PeerMeta"}, params)
+		}
+		require.Equal(t, "Accept", resp.Header.Get("Vary"))
+	}
+	require.Equal(t, http.StatusNotAcceptable, get("text/html", "").StatusCode)
+	require.Equal(t, http.StatusNotAcceptable, get("application/json;q=0", "").StatusCode)
+
+	// no ETag unless revalidation is enabled
+	require.Empty(t, get("application/json", "").Header.Get("ETag"))
+	require.Empty(t, get("application/json", "").Header.Get("Cache-Control"))
+	revalidate.Store(true)
+
+	// the ETag differs between encodings, and can be revalidated
+	jsonETag := get("application/json", "").Header.Get("ETag")
+	cborETag := get("application/cbor", "").Header.Get("ETag")
+	require.NotEmpty(t, jsonETag)
+	require.NotEqual(t, jsonETag, cborETag)
+	require.Equal(t, http.StatusNotModified, get("application/json", jsonETag).StatusCode)
+	require.Equal(t, http.StatusNotModified, get("application/cbor", `"other", W/`+cborETag).StatusCode)
+	require.Equal(t, http.StatusOK, get("application/json", cborETag).StatusCode)
+
+	// the client decodes every encoding
+	for _, ct := range []string{"", libp2phttp.WellKnownContentTypeJSON, libp2phttp.WellKnownContentTypeCBOR, libp2phttp.WellKnownContentTypeProtobuf} {
+		forceAccept.Store(ct)
+		var clientHost libp2phttp.Host
+		rt, err := clientHost.NewConstrainedRoundTripper(peer.AddrInfo{Addrs: []ma.Multiaddr{ma.StringCast(fmt.Sprintf("/ip4/127.0.0.1/tcp/%d/http", l.Addr().(*net.TCPAddr).Port))}})
+		require.NoError(t, err)
+		meta, err := rt.(libp2phttp.PeerMetadataGetter).GetPeerMetadata()
+		require.NoError(t, err, ct)
+		require.Equal(t, expected, meta, ct)
+	}
+}
+
 func TestServerLegacyWellKnownResource(t *testing.T) {
 	mkHTTPServer := func(wellKnown string) ma.Multiaddr {
 		mux := http.NewServeMux()
@@ -1146,3 +1252,129 @@ func TestAuthenticatedRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestResponseCache(t *testing.T) {
+	serverHost, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/udp/0/quic-v1"))
+	require.NoError(t, err)
+	httpHost := libp2phttp.Host{
+		StreamHost:        serverHost,
+		ListenAddrs:       []ma.Multiaddr{ma.StringCast("/ip4/127.0.0.1/tcp/0/http")},
+		InsecureAllowHTTP: true,
+	}
+	var mx sync.Mutex
+	hits := make(map[string]int)
+	httpHost.SetHTTPHandlerAtPath("/cache-test", "/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mx.Lock()
+		hits[r.URL.Path]++
+		mx.Unlock()
+		switch r.URL.Path {
+		case "/cached":
+			w.Header().Set("Cache-Control", "max-age=60")
+		case "/etag":
+			w.Header().Set("Cache-Control", "no-cache")
+			w.Header().Set("ETag", `"v1"`)
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		case "/nostore":
+			w.Header().Set("Cache-Control", "no-store, max-age=60")
+		}
+		w.Write([]byte("hello " + r.URL.Path))
+	}))
+	go httpHost.Serve()
+	defer httpHost.Close()
+
+	expectHits := func(path string, n int) {
+		t.Helper()
+		mx.Lock()
+		defer mx.Unlock()
+		require.Equal(t, n, hits[path], path)
+	}
+
+	for _, tc := range []struct {
+		name string
+		opts []libp2phttp.RoundTripperOption
+	}{
+		{"stream", []libp2phttp.RoundTripperOption{libp2phttp.ServerMustAuthenticatePeerID}},
+		{"http", []libp2phttp.RoundTripperOption{libp2phttp.PreferHTTPTransport}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mx.Lock()
+			clear(hits)
+			mx.Unlock()
+
+			clientHost, err := libp2p.New(libp2p.NoListenAddrs)
+			require.NoError(t, err)
+			defer clientHost.Close()
+			cache := libp2phttp.NewResponseCache(1 << 20)
+			client := libp2phttp.Host{StreamHost: clientHost, ResponseCache: cache}
+			rt, err := client.NewConstrainedRoundTripper(peer.AddrInfo{
+				ID:    serverHost.ID(),
+				Addrs: append(httpHost.Addrs(), serverHost.Addrs()...),
+			}, tc.opts...)
+			require.NoError(t, err)
+			c := http.Client{Transport: rt}
+
+			get := func(path string) {
+				t.Helper()
+				resp, err := c.Get(path)
+				require.NoError(t, err)
+				defer resp.Body.Close()
+				require.Equal(t, http.StatusOK, resp.StatusCode)
+				b, err := io.ReadAll(resp.Body)
+				require.NoError(t, err)
+				require.Equal(t, "hello "+path, string(b))
+			}
+
+			for _, path := range []string{"/cached", "/etag", "/nostore"} {
+				get(path)
+				get(path)
+			}
+			expectHits("/cached", 1)
+			// revalidated with a 304
+			expectHits("/etag", 2)
+			expectHits("/nostore", 2)
+			require.NotZero(t, cache.Size())
+
+			cache.Purge(serverHost.ID())
+			require.Zero(t, cache.Size())
+			get("/cached")
+			expectHits("/cached", 2)
+		})
+	}
+}
+
+func TestResponseCacheVirtualHosts(t *testing.T) {
+	var hits atomic.Int32
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		w.Header().Set("Cache-Control", "max-age=60")
+		w.Write([]byte("hello " + r.Host))
+	})}
+	go server.Serve(l)
+	defer server.Close()
+
+	client := libp2phttp.Host{ResponseCache: libp2phttp.NewResponseCache(1 << 20)}
+	c := http.Client{Transport: &client}
+	get := func(host string) {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "http://"+l.Addr().String()+"/", nil)
+		require.NoError(t, err)
+		req.Host = host
+		resp, err := c.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		require.Equal(t, "hello "+host, string(b))
+	}
+
+	// the virtual hosts served at the same address don't share responses
+	get("a.example.com")
+	get("b.example.com")
+	get("a.example.com")
+	require.Equal(t, int32(2), hits.Load())
+}
@@ -0,0 +1,208 @@
+package libp2phttp
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/p2p/http/pb"
+
+	"github.com/fxamacker/cbor/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// The media types of the representations of the well-known protocols resource.
+const (
+	WellKnownContentTypeJSON     = "application/json"
+	WellKnownContentTypeCBOR     = "application/cbor"
+	WellKnownContentTypeProtobuf = "application/x-protobuf"
+)
+
+// cborEncMode encodes the well-known resource deterministically, with the protocols
+// sorted by length and then lexicographically. Its CBOR encoding mirrors the JSON one:
+// a map from the protocol IDs to maps with a "path" key.
+var cborEncMode = func() cbor.EncMode {
+	em, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return em
+}()
+
+// wellKnownProtobufMessage is the name of the protobuf message of the well-known
+// resource. It's the value of the proto parameter of WellKnownContentTypeProtobuf.
+var wellKnownProtobufMessage = string((*pb.PeerMeta)(nil).ProtoReflect().Descriptor().FullName())
+
+// wellKnownContentTypeHeader returns the Content-Type header of the well-known resource
+// encoded in contentType, one of wellKnownContentTypes. The protobuf encoding names its
+// message, as there's no standard media type for protobuf messages.
+func wellKnownContentTypeHeader(contentType string) string {
+	if contentType == WellKnownContentTypeProtobuf {
+		return mime.FormatMediaType(contentType, map[string]string{"proto": wellKnownProtobufMessage})
+	}
+	return contentType
+}
+
+// wellKnownContentTypes are the media types served by the WellKnownHandler, in order of
+// preference when a client accepts several of them equally.
+var wellKnownContentTypes = []string{WellKnownContentTypeJSON, WellKnownContentTypeCBOR, WellKnownContentTypeProtobuf}
+
+// wellKnownAccept is the Accept header of the requests for the well-known resource. It
+// prefers the most compact encodings, and falls back to JSON for older servers.
+var wellKnownAccept = wellKnownContentTypeHeader(WellKnownContentTypeProtobuf) + ", " + WellKnownContentTypeCBOR + ";q=0.9, " + WellKnownContentTypeJSON + ";q=0.8"
+
+// negotiateWellKnownContentType returns the media type to serve the well-known resource
+// with, given the Accept header of the request. It returns false if the client doesn't
+// accept any of them.
+func negotiateWellKnownContentType(accept string) (string, bool) {
+	if strings.TrimSpace(accept) == "" {
+		return WellKnownContentTypeJSON, true
+	}
+	var best string
+	var bestQ float64
+	var bestExplicit bool
+	for _, ct := range wellKnownContentTypes {
+		q, explicit := acceptQuality(accept, ct)
+		// Among the media types accepted equally, prefer the ones the client named.
+		if q > bestQ || (q == bestQ && q > 0 && explicit && !bestExplicit) {
+			best, bestQ, bestExplicit = ct, q, explicit
+		}
+	}
+	return best, bestQ > 0
+}
+
+// acceptQuality returns the quality given to contentType by the most specific media
+// range of the Accept header matching it, and whether that range is contentType itself
+// rather than a wildcard. Media ranges with a proto parameter only match the protobuf
+// encoding of the well-known resource's message.
+func acceptQuality(accept, contentType string) (q float64, explicit bool) {
+	typ, _, _ := strings.Cut(contentType, "/")
+	specificity := -1
+	for _, r := range strings.Split(accept, ",") {
+		mediaRange, params, _ := strings.Cut(r, ";")
+		var s int
+		switch strings.ToLower(strings.TrimSpace(mediaRange)) {
+		case contentType:
+			s = 2
+		case typ + "/*":
+			s = 1
+		case "*/*":
+			s = 0
+		default:
+			continue
+		}
+		if s < specificity {
+			continue
+		}
+		rq := 1.0
+		matches := true
+		for _, p := range strings.Split(params, ";") {
+			k, v, ok := strings.Cut(p, "=")
+			if !ok {
+				continue
+			}
+			k, v = strings.TrimSpace(k), strings.Trim(strings.TrimSpace(v), `"`)
+			switch {
+			case strings.EqualFold(k, "q"):
+				if f, err := strconv.ParseFloat(v, 64); err == nil {
+					rq = f
+				}
+			case strings.EqualFold(k, "proto"):
+				matches = contentType == WellKnownContentTypeProtobuf && v == wellKnownProtobufMessage
+			}
+		}
+		if !matches {
+			continue
+		}
+		specificity, q = s, rq
+	}
+	return q, specificity == 2
+}
+
+// marshalPeerMeta encodes meta in the given media type, one of wellKnownContentTypes.
+// The CBOR and protobuf encodings are deterministic.
+func marshalPeerMeta(contentType string, meta PeerMeta) ([]byte, error) {
+	switch contentType {
+	case WellKnownContentTypeCBOR:
+		return cborEncMode.Marshal(meta)
+	case WellKnownContentTypeProtobuf:
+		return marshalPeerMetaProtobuf(meta)
+	default:
+		return json.Marshal(meta)
+	}
+}
+
+// unmarshalPeerMeta decodes the well-known resource served with the given Content-Type
+// header. A missing Content-Type is taken to be JSON.
+func unmarshalPeerMeta(contentType string, b []byte) (PeerMeta, error) {
+	if contentType == "" {
+		contentType = WellKnownContentTypeJSON
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, err
+	}
+	switch mediaType {
+	case WellKnownContentTypeJSON:
+		meta := PeerMeta{}
+		if err := json.Unmarshal(b, &meta); err != nil {
+			return nil, err
+		}
+		return meta, nil
+	case WellKnownContentTypeCBOR:
+		meta := PeerMeta{}
+		if err := cbor.Unmarshal(b, &meta); err != nil {
+			return nil, err
+		}
+		return meta, nil
+	case WellKnownContentTypeProtobuf:
+		if msg, ok := params["proto"]; ok && msg != wellKnownProtobufMessage {
+			return nil, fmt.Errorf("unsupported protobuf message for the well-known resource: %s", msg)
+		}
+		return unmarshalPeerMetaProtobuf(b)
+	default:
+		return nil, fmt.Errorf("unsupported content type for the well-known resource: %s", mediaType)
+	}
+}
+
+// sortedProtocols returns the protocols of meta, sorted by length and then
+// lexicographically, like the keys of the CBOR encoding.
+func sortedProtocols(meta PeerMeta) []protocol.ID {
+	protos := make([]protocol.ID, 0, len(meta))
+	for p := range meta {
+		protos = append(protos, p)
+	}
+	slices.SortFunc(protos, func(a, b protocol.ID) int {
+		if len(a) != len(b) {
+			return len(a) - len(b)
+		}
+		return strings.Compare(string(a), string(b))
+	})
+	return protos
+}
+
+// marshalPeerMetaProtobuf encodes meta as a pb.PeerMeta, with the protocols sorted with
+// sortedProtocols.
+func marshalPeerMetaProtobuf(meta PeerMeta) ([]byte, error) {
+	msg := &pb.PeerMeta{Protocols: make([]*pb.Protocol, 0, len(meta))}
+	for _, p := range sortedProtocols(meta) {
+		msg.Protocols = append(msg.Protocols, &pb.Protocol{Id: string(p), Path: meta[p].Path})
+	}
+	return proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+}
+
+func unmarshalPeerMetaProtobuf(b []byte) (PeerMeta, error) {
+	var msg pb.PeerMeta
+	if err := proto.Unmarshal(b, &msg); err != nil {
+		return nil, err
+	}
+	meta := make(PeerMeta, len(msg.GetProtocols()))
+	for _, p := range msg.GetProtocols() {
+		meta[protocol.ID(p.GetId())] = ProtocolMeta{Path: p.GetPath()}
+	}
+	return meta, nil
+}
@@ -0,0 +1,21 @@
+package metricshelper
+
+import "github.com/TheNoobiCat/go-libp2p/core/peer"
+
+// Keys of the pprof labels set on the goroutines of stream handlers and dials, when
+// enabled.
+const (
+	PprofLabelPeer      = "libp2p_peer"
+	PprofLabelProtocol  = "libp2p_protocol"
+	PprofLabelTransport = "libp2p_transport"
+)
+
+// PprofPeer returns the value of the peer pprof label of p, its peer ID truncated to
+// the last 8 characters to keep profiles readable.
+func PprofPeer(p peer.ID) string {
+	s := p.String()
+	if len(s) > 8 {
+		return s[len(s)-8:]
+	}
+	return s
+}
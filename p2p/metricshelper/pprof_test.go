@@ -0,0 +1,17 @@
+package metricshelper
+
+import (
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+)
+
+func TestPprofPeer(t *testing.T) {
+	p, err := peer.Decode("12D3KooWQ8vrERR8bnPByEjjtqV6hTWehaf8TmK7qR1cUsyrPpfZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := PprofPeer(p); got != "UsyrPpfZ" {
+		t.Fatalf("expected UsyrPpfZ, got %s", got)
+	}
+}
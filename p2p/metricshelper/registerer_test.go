@@ -30,3 +30,41 @@ func TestRegisterCollectors(t *testing.T) {
 	require.NotPanics(t, func() { RegisterCollectors(reg, c1, c2) })
 	require.NotPanics(t, func() { RegisterCollectors(reg, c3) }, "should not panic on duplicate registration")
 }
+
+func TestRegisterersFor(t *testing.T) {
+	def := prometheus.NewRegistry()
+	relay := prometheus.NewRegistry()
+	r := &Registerers{
+		Default:      def,
+		PerSubsystem: map[string]prometheus.Registerer{SubsystemRelay: relay},
+		Disabled:     map[string]bool{SubsystemEventBus: true},
+		ConstLabels:  prometheus.Labels{"env": "test"},
+	}
+
+	_, ok := r.For(SubsystemEventBus)
+	require.False(t, ok)
+
+	for _, tc := range []struct {
+		subsystem string
+		registry  *prometheus.Registry
+	}{
+		{SubsystemSwarm, def},
+		{SubsystemRelay, relay},
+	} {
+		reg, ok := r.For(tc.subsystem)
+		require.True(t, ok)
+		c := prometheus.NewCounter(prometheus.CounterOpts{Name: tc.subsystem + "_total"})
+		RegisterCollectors(reg, c)
+		c.Inc()
+		mfs, err := tc.registry.Gather()
+		require.NoError(t, err)
+		require.Len(t, mfs, 1)
+		require.Equal(t, tc.subsystem+"_total", mfs[0].GetName())
+		require.Equal(t, "env", mfs[0].GetMetric()[0].GetLabel()[0].GetName())
+		require.Equal(t, "test", mfs[0].GetMetric()[0].GetLabel()[0].GetValue())
+	}
+
+	var nilRegisterers *Registerers
+	_, ok = nilRegisterers.For(SubsystemSwarm)
+	require.False(t, ok)
+}
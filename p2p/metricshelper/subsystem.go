@@ -0,0 +1,74 @@
+package metricshelper
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics subsystems that can be configured separately.
+const (
+	SubsystemSwarm           = "swarm"
+	SubsystemIdentify        = "identify"
+	SubsystemAutoNAT         = "autonat"
+	SubsystemRelay           = "relay"
+	SubsystemHolePunch       = "holepunch"
+	SubsystemEventBus        = "eventbus"
+	SubsystemTransports      = "transports"
+	SubsystemResourceManager = "resource-manager"
+	SubsystemHost            = "host"
+	SubsystemReqResp         = "reqresp"
+)
+
+// Subsystems lists the metrics subsystems.
+var Subsystems = []string{
+	SubsystemSwarm,
+	SubsystemIdentify,
+	SubsystemAutoNAT,
+	SubsystemRelay,
+	SubsystemHolePunch,
+	SubsystemEventBus,
+	SubsystemTransports,
+	SubsystemResourceManager,
+	SubsystemHost,
+	SubsystemReqResp,
+}
+
+// RegistererProvider is implemented by hosts that report metrics. Services running on
+// top of a host use it to report their metrics to the same registerer.
+type RegistererProvider interface {
+	// MetricsRegisterer returns the registerer of the metrics subsystem, and false if
+	// its metrics are disabled.
+	MetricsRegisterer(subsystem string) (prometheus.Registerer, bool)
+}
+
+// Registerers selects the registerer of every metrics subsystem.
+type Registerers struct {
+	// Default is the registerer of the subsystems without a registerer in
+	// PerSubsystem. If nil, only the subsystems in PerSubsystem are enabled.
+	Default prometheus.Registerer
+	// PerSubsystem overrides the registerer of a subsystem.
+	PerSubsystem map[string]prometheus.Registerer
+	// Disabled disables the metrics of a subsystem.
+	Disabled map[string]bool
+	// ConstLabels are added to all metrics, e.g. the name of the environment. The
+	// collectors are shared by all the nodes of the process, so the labels can't tell
+	// apart nodes sharing a registry.
+	ConstLabels prometheus.Labels
+}
+
+// For returns the registerer of subsystem, and false if its metrics are disabled.
+func (r *Registerers) For(subsystem string) (prometheus.Registerer, bool) {
+	if r == nil || r.Disabled[subsystem] {
+		return nil, false
+	}
+	reg, ok := r.PerSubsystem[subsystem]
+	if !ok {
+		reg = r.Default
+	}
+	if reg == nil {
+		return nil, false
+	}
+	if len(r.ConstLabels) > 0 {
+		reg = prometheus.WrapRegistererWith(r.ConstLabels, reg)
+	}
+	return reg, true
+}
@@ -2,6 +2,7 @@ package yamux
 
 import (
 	"context"
+	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 
@@ -9,13 +10,22 @@ import (
 )
 
 // conn implements mux.MuxedConn over yamux.Session.
-type conn yamux.Session
+type conn struct {
+	session *yamux.Session
+	// stats is nil if the session wasn't created by the Transport
+	stats *connStats
+}
 
-var _ network.MuxedConn = &conn{}
+var (
+	_ network.MuxedConn          = &conn{}
+	_ network.MuxerStatsReporter = &conn{}
+	_ network.Pinger             = &conn{}
+)
 
 // NewMuxedConn constructs a new MuxedConn from a yamux.Session.
+// The MuxerStats of the returned conn only contain the number of streams.
 func NewMuxedConn(m *yamux.Session) network.MuxedConn {
-	return (*conn)(m)
+	return &conn{session: m}
 }
 
 // Close closes underlying yamux
@@ -39,15 +49,56 @@ func (c *conn) OpenStream(ctx context.Context) (network.MuxedStream, error) {
 		return nil, parseError(err)
 	}
 
-	return (*stream)(s), nil
+	return c.newStream(s), nil
 }
 
 // AcceptStream accepts a stream opened by the other side.
 func (c *conn) AcceptStream() (network.MuxedStream, error) {
 	s, err := c.yamux().AcceptStream()
-	return (*stream)(s), parseError(err)
+	if err != nil {
+		return nil, parseError(err)
+	}
+	return c.newStream(s), nil
+}
+
+// MuxerStats returns the number of streams, the aggregate receive window and the RTT
+// of the session. go-yamux doesn't expose the data buffered by its streams, so the
+// buffered bytes aren't reported.
+func (c *conn) MuxerStats() network.MuxerStats {
+	st := network.MuxerStats{NumStreams: c.yamux().NumStreams()}
+	if c.stats != nil {
+		st.RecvWindow = c.stats.recvWindow.Load()
+		st.RTT = c.stats.getRTT()
+	}
+	return st
+}
+
+// Ping sends a yamux ping and returns the RTT. Concurrent calls share the same ping.
+func (c *conn) Ping(ctx context.Context) (time.Duration, error) {
+	type result struct {
+		rtt time.Duration
+		err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		rtt, err := c.yamux().Ping()
+		resCh <- result{rtt: rtt, err: parseError(err)}
+	}()
+	select {
+	case res := <-resCh:
+		if res.err == nil && c.stats != nil {
+			c.stats.recordRTT(res.rtt)
+		}
+		return res.rtt, res.err
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (c *conn) newStream(s *yamux.Stream) *stream {
+	return (*stream)(s)
 }
 
 func (c *conn) yamux() *yamux.Session {
-	return (*yamux.Session)(c)
+	return c.session
 }
@@ -0,0 +1,92 @@
+package yamux
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/libp2p/go-yamux/v5"
+)
+
+// Option configures a Transport created by NewTransport.
+type Option func(*yamux.Config) error
+
+// NewTransport returns a Transport with the configuration of DefaultTransport, modified
+// by opts. Every host can use its own Transport, e.g. with larger windows for high
+// throughput deployments, or smaller windows and buffers for low memory devices.
+func NewTransport(opts ...Option) (*Transport, error) {
+	cfg := *DefaultTransport.Config()
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	if err := yamux.VerifyConfig(&cfg); err != nil {
+		return nil, err
+	}
+	return (*Transport)(&cfg), nil
+}
+
+// WithInitialStreamWindow sets the initial receive window of streams. It must be at
+// least 256 KiB.
+func WithInitialStreamWindow(size uint32) Option {
+	return func(c *yamux.Config) error {
+		c.InitialStreamWindowSize = size
+		return nil
+	}
+}
+
+// WithMaxStreamWindow sets the maximum receive window of streams, which limits the
+// throughput of a stream to the window size per round trip. It must be at least the
+// initial stream window. Defaults to 16 MiB.
+func WithMaxStreamWindow(size uint32) Option {
+	return func(c *yamux.Config) error {
+		c.MaxStreamWindowSize = size
+		return nil
+	}
+}
+
+// WithReadBufferSize sets the size of the read buffer of a connection. Zero disables
+// buffering, which is the default, as yamux runs over a security transport that
+// buffers internally.
+func WithReadBufferSize(size int) Option {
+	return func(c *yamux.Config) error {
+		if size < 0 {
+			return errors.New("negative read buffer size")
+		}
+		c.ReadBufSize = size
+		return nil
+	}
+}
+
+// WithMaxMessageSize sets the maximum size of a frame written on a stream, and thus the
+// size of the write buffers. It must be at least 1 KiB. Defaults to 64 KiB.
+func WithMaxMessageSize(size uint32) Option {
+	return func(c *yamux.Config) error {
+		c.MaxMessageSize = size
+		return nil
+	}
+}
+
+// WithWriteCoalesceDelay sets the maximum time writes are delayed to be coalesced with
+// other writes. Zero disables coalescing.
+func WithWriteCoalesceDelay(d time.Duration) Option {
+	return func(c *yamux.Config) error {
+		c.WriteCoalesceDelay = d
+		return nil
+	}
+}
+
+// WithMeasureRTT enables or disables the periodic measurement of the round trip time,
+// which is used to size the stream windows. When disabled, the round trip time is only
+// measured when the connection is established.
+func WithMeasureRTT(enabled bool) Option {
+	return func(c *yamux.Config) error {
+		if enabled {
+			c.MeasureRTTInterval = yamux.DefaultConfig().MeasureRTTInterval
+		} else {
+			c.MeasureRTTInterval = math.MaxInt64
+		}
+		return nil
+	}
+}
@@ -0,0 +1,64 @@
+package yamux
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/libp2p/go-yamux/v5"
+)
+
+// connStats tracks the aggregate receive window of the streams of a yamux session,
+// and the round trip time.
+//
+// go-yamux doesn't expose the state of its streams. The receive windows are the memory
+// the streams reserve for them, and the RTT is sampled from the pings sent with Ping,
+// and from a ping sent when the session starts. The bytes buffered by the streams
+// aren't known.
+type connStats struct {
+	recvWindow atomic.Int64
+	rtt        atomic.Int64 // smoothed, in nanoseconds
+}
+
+func (cs *connStats) getRTT() time.Duration {
+	return time.Duration(cs.rtt.Load())
+}
+
+func (cs *connStats) recordRTT(rtt time.Duration) {
+	// Smooth the same way as the session does for its own RTT measurements.
+	if !cs.rtt.CompareAndSwap(0, int64(rtt)) {
+		cs.rtt.Store(cs.rtt.Load()/2 + int64(rtt)/2)
+	}
+}
+
+// statsSpan accounts the memory reserved by a stream for its receive window.
+type statsSpan struct {
+	yamux.MemoryManager
+	stats    *connStats
+	reserved atomic.Int64
+}
+
+func (s *statsSpan) ReserveMemory(size int, prio uint8) error {
+	if err := s.MemoryManager.ReserveMemory(size, prio); err != nil {
+		return err
+	}
+	s.reserved.Add(int64(size))
+	s.stats.recvWindow.Add(int64(size))
+	return nil
+}
+
+func (s *statsSpan) ReleaseMemory(size int) {
+	s.MemoryManager.ReleaseMemory(size)
+	s.reserved.Add(-int64(size))
+	s.stats.recvWindow.Add(-int64(size))
+}
+
+func (s *statsSpan) Done() {
+	s.MemoryManager.Done()
+	s.stats.recvWindow.Add(-s.reserved.Swap(0))
+}
+
+type nullSpan struct{}
+
+func (nullSpan) ReserveMemory(int, uint8) error { return nil }
+func (nullSpan) ReleaseMemory(int)              {}
+func (nullSpan) Done()                          {}
@@ -27,6 +27,10 @@ func parseError(err error) error {
 	if errors.As(err, &ce) {
 		return &network.ConnError{Remote: ce.Remote, ErrorCode: network.ConnErrorCode(ce.ErrorCode), TransportError: err}
 	}
+	if errors.Is(err, yamux.ErrRemoteGoAway) {
+		// The remote closed the session without an error code.
+		return &network.ConnError{Remote: true, ErrorCode: network.ConnNoError, TransportError: err}
+	}
 	if errors.Is(err, yamux.ErrStreamReset) {
 		return fmt.Errorf("%w: %w", network.ErrReset, err)
 	}
@@ -40,9 +40,16 @@ type Transport yamux.Config
 var _ network.Multiplexer = &Transport{}
 
 func (t *Transport) NewConn(nc net.Conn, isServer bool, scope network.PeerScope) (network.MuxedConn, error) {
-	var newSpan func() (yamux.MemoryManager, error)
-	if scope != nil {
-		newSpan = func() (yamux.MemoryManager, error) { return scope.BeginSpan() }
+	stats := &connStats{}
+	newSpan := func() (yamux.MemoryManager, error) {
+		if scope == nil {
+			return &statsSpan{MemoryManager: nullSpan{}, stats: stats}, nil
+		}
+		span, err := scope.BeginSpan()
+		if err != nil {
+			return nil, err
+		}
+		return &statsSpan{MemoryManager: span, stats: stats}, nil
 	}
 
 	var s *yamux.Session
@@ -55,7 +62,14 @@ func (t *Transport) NewConn(nc net.Conn, isServer bool, scope network.PeerScope)
 	if err != nil {
 		return nil, err
 	}
-	return NewMuxedConn(s), nil
+	// Sample the RTT once. The session measures it when it starts as well, the ping is
+	// shared with that measurement.
+	go func() {
+		if rtt, err := s.Ping(); err == nil {
+			stats.recordRTT(rtt)
+		}
+	}()
+	return &conn{session: s, stats: stats}, nil
 }
 
 func (t *Transport) Config() *yamux.Config {
@@ -1,9 +1,16 @@
 package yamux
 
 import (
+	"context"
+	"io"
+	"net"
 	"testing"
+	"time"
 
+	"github.com/TheNoobiCat/go-libp2p/core/network"
 	tmux "github.com/TheNoobiCat/go-libp2p/p2p/muxer/testsuite"
+
+	"github.com/stretchr/testify/require"
 )
 
 func TestDefaultTransport(t *testing.T) {
@@ -13,3 +20,140 @@ func TestDefaultTransport(t *testing.T) {
 
 	tmux.SubtestAll(t, DefaultTransport)
 }
+
+func TestMuxerStats(t *testing.T) {
+	c1, c2 := net.Pipe()
+	client, err := DefaultTransport.NewConn(c1, false, nil)
+	require.NoError(t, err)
+	defer client.Close()
+	server, err := DefaultTransport.NewConn(c2, true, nil)
+	require.NoError(t, err)
+	defer server.Close()
+
+	cstr, err := client.OpenStream(context.Background())
+	require.NoError(t, err)
+	_, err = cstr.Write(make([]byte, 1000))
+	require.NoError(t, err)
+	sstr, err := server.AcceptStream()
+	require.NoError(t, err)
+
+	stats := func() network.MuxerStats { return server.(network.MuxerStatsReporter).MuxerStats() }
+	st := stats()
+	require.Equal(t, 1, st.NumStreams)
+	require.GreaterOrEqual(t, st.RecvWindow, int64(256<<10))
+	// the session pings the peer to measure the RTT when it starts
+	require.Eventually(t, func() bool { return stats().RTT > 0 }, 5*time.Second, 10*time.Millisecond)
+
+	_, err = io.ReadFull(sstr, make([]byte, 1000))
+	require.NoError(t, err)
+	require.NoError(t, sstr.Close())
+	require.NoError(t, cstr.Close())
+	require.Eventually(t, func() bool { return stats().NumStreams == 0 && stats().RecvWindow == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestNewTransport(t *testing.T) {
+	tpt, err := NewTransport(
+		WithInitialStreamWindow(512<<10),
+		WithMaxStreamWindow(1<<20),
+		WithReadBufferSize(4096),
+		WithMaxMessageSize(16<<10),
+		WithMeasureRTT(false),
+	)
+	require.NoError(t, err)
+	cfg := tpt.Config()
+	require.Equal(t, uint32(512<<10), cfg.InitialStreamWindowSize)
+	require.Equal(t, uint32(1<<20), cfg.MaxStreamWindowSize)
+	require.Equal(t, 4096, cfg.ReadBufSize)
+	require.Equal(t, uint32(16<<10), cfg.MaxMessageSize)
+	// the default transport is unchanged
+	require.Equal(t, uint32(16<<20), DefaultTransport.Config().MaxStreamWindowSize)
+
+	c1, c2 := net.Pipe()
+	client, err := tpt.NewConn(c1, false, nil)
+	require.NoError(t, err)
+	defer client.Close()
+	server, err := tpt.NewConn(c2, true, nil)
+	require.NoError(t, err)
+	defer server.Close()
+	cstr, err := client.OpenStream(context.Background())
+	require.NoError(t, err)
+	go cstr.Write([]byte("foo"))
+	sstr, err := server.AcceptStream()
+	require.NoError(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(sstr, buf)
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(buf))
+
+	_, err = NewTransport(WithInitialStreamWindow(1<<20), WithMaxStreamWindow(512<<10))
+	require.Error(t, err)
+	_, err = NewTransport(WithReadBufferSize(-1))
+	require.Error(t, err)
+}
+
+func TestCloseGracefully(t *testing.T) {
+	c1, c2 := net.Pipe()
+	client, err := DefaultTransport.NewConn(c1, false, nil)
+	require.NoError(t, err)
+	defer client.Close()
+	server, err := DefaultTransport.NewConn(c2, true, nil)
+	require.NoError(t, err)
+	defer server.Close()
+
+	open := func() (network.MuxedStream, network.MuxedStream) {
+		cstr, err := client.OpenStream(context.Background())
+		require.NoError(t, err)
+		_, err = cstr.Write([]byte("request"))
+		require.NoError(t, err)
+		sstr, err := server.AcceptStream()
+		require.NoError(t, err)
+		return cstr, sstr
+	}
+
+	t.Run("peer closes", func(t *testing.T) {
+		cstr, sstr := open()
+		done := make(chan error, 1)
+		go func() {
+			done <- network.CloseGracefully(cstr, time.Now().Add(5*time.Second))
+		}()
+		// the request is received, followed by an EOF
+		b, err := io.ReadAll(sstr)
+		require.NoError(t, err)
+		require.Equal(t, "request", string(b))
+		_, err = sstr.Write([]byte("trailing data"))
+		require.NoError(t, err)
+		require.NoError(t, sstr.Close())
+		require.NoError(t, <-done)
+	})
+
+	t.Run("peer doesn't close", func(t *testing.T) {
+		cstr, sstr := open()
+		defer sstr.Reset()
+		err := network.CloseGracefully(cstr, time.Now().Add(100*time.Millisecond))
+		var nerr net.Error
+		require.ErrorAs(t, err, &nerr)
+		require.True(t, nerr.Timeout())
+	})
+
+	t.Run("peer sends too much", func(t *testing.T) {
+		cstr, sstr := open()
+		defer sstr.Reset()
+		go sstr.Write(make([]byte, network.MaxGracefulCloseDrain+1))
+		err := network.CloseGracefully(cstr, time.Now().Add(5*time.Second))
+		require.ErrorIs(t, err, network.ErrUnexpectedData)
+	})
+
+	t.Run("await EOF", func(t *testing.T) {
+		cstr, sstr := open()
+		defer cstr.Reset()
+		defer sstr.Reset()
+		_, err := io.ReadFull(sstr, make([]byte, len("request")))
+		require.NoError(t, err)
+		require.NoError(t, cstr.CloseWrite())
+		require.NoError(t, network.AwaitEOF(sstr, time.Now().Add(5*time.Second)))
+
+		_, err = sstr.Write([]byte("response"))
+		require.NoError(t, err)
+		require.ErrorIs(t, network.AwaitEOF(cstr, time.Now().Add(5*time.Second)), network.ErrUnexpectedData)
+	})
+}
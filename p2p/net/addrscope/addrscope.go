@@ -0,0 +1,161 @@
+// Package addrscope classifies multiaddrs by the scope they're reachable in, and
+// decides which of our addresses to advertise to a peer depending on where it connects
+// from.
+package addrscope
+
+import (
+	"net"
+	"net/netip"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Scope is the scope an address is reachable in.
+type Scope int
+
+const (
+	// Unknown is the scope of the addresses that can't be classified, e.g. non IP
+	// addresses or unspecified addresses.
+	Unknown Scope = iota
+	// Loopback addresses are only reachable from the same host.
+	Loopback
+	// LinkLocal addresses are only reachable from the same link.
+	LinkLocal
+	// Private addresses are only reachable from the same private network.
+	Private
+	// Public addresses are reachable from the internet, at least in principle.
+	Public
+)
+
+func (s Scope) String() string {
+	switch s {
+	case Loopback:
+		return "loopback"
+	case LinkLocal:
+		return "link-local"
+	case Private:
+		return "private"
+	case Public:
+		return "public"
+	default:
+		return "unknown"
+	}
+}
+
+// Of returns the scope of a. DNS addresses are classified by manet: localhost
+// names are private, all other names are public.
+func Of(a ma.Multiaddr) Scope {
+	if a == nil {
+		return Unknown
+	}
+	if manet.IsIPLoopback(a) {
+		return Loopback
+	}
+	if ip, ok := ipOf(a); ok && (ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast()) {
+		return LinkLocal
+	}
+	if manet.IsPrivateAddr(a) {
+		return Private
+	}
+	if manet.IsPublicAddr(a) {
+		return Public
+	}
+	return Unknown
+}
+
+// ipOf returns the IP address of a, if it starts with one.
+func ipOf(a ma.Multiaddr) (netip.Addr, bool) {
+	if len(a) == 0 {
+		return netip.Addr{}, false
+	}
+	switch c := a[0]; c.Protocol().Code {
+	case ma.P_IP4, ma.P_IP6:
+		ip, ok := netip.AddrFromSlice(c.RawValue())
+		return ip.Unmap(), ok
+	case ma.P_IP6ZONE:
+		if len(a) > 1 && a[1].Protocol().Code == ma.P_IP6 {
+			ip, ok := netip.AddrFromSlice(a[1].RawValue())
+			return ip, ok
+		}
+	}
+	return netip.Addr{}, false
+}
+
+// InterfaceSubnets returns the subnets of the local network interfaces.
+func InterfaceSubnets() ([]netip.Prefix, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, err
+	}
+	subnets := make([]netip.Prefix, 0, len(addrs))
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(ipnet.IP)
+		if !ok {
+			continue
+		}
+		ones, _ := ipnet.Mask.Size()
+		ip = ip.Unmap()
+		if ip.Is4() && ones > 32 {
+			ones -= 96
+		}
+		subnets = append(subnets, netip.PrefixFrom(ip, ones).Masked())
+	}
+	return subnets, nil
+}
+
+// IsLANPeer returns whether a peer connecting from remote is on the same subnet as
+// us, i.e. remote is a private or link-local address in one of subnets.
+func IsLANPeer(remote ma.Multiaddr, subnets []netip.Prefix) bool {
+	if s := Of(remote); s != Private && s != LinkLocal {
+		return false
+	}
+	ip, ok := ipOf(remote)
+	if !ok {
+		return false
+	}
+	for _, p := range subnets {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ForPeer returns the addresses among addrs to advertise to a peer connecting from
+// remote, when advertising private addresses only to the peers on the same subnet:
+//   - peers connecting over loopback get all the addresses
+//   - peers on the same subnet (see IsLANPeer) get all but the loopback addresses
+//   - all other peers only get the public addresses
+func ForPeer(addrs []ma.Multiaddr, remote ma.Multiaddr, subnets []netip.Prefix) []ma.Multiaddr {
+	switch {
+	case Of(remote) == Loopback:
+		return addrs
+	case IsLANPeer(remote, subnets):
+		return ma.FilterAddrs(addrs, func(a ma.Multiaddr) bool { return Of(a) != Loopback })
+	default:
+		return ma.FilterAddrs(addrs, func(a ma.Multiaddr) bool { return Of(a) == Public })
+	}
+}
+
+// FromPeer returns the addresses among addrs, as advertised by a peer connected from
+// remote, that are worth dialing:
+//   - if remote is a loopback address, no filtering is applied
+//   - if it's a private or link-local address, the loopback addresses are filtered out
+//   - if it's a public address, all non-public addresses are filtered out
+//   - if none of the above, (e.g. discard prefix), no filtering is applied.
+//     We can't do anything meaningful here so we do nothing.
+func FromPeer(addrs []ma.Multiaddr, remote ma.Multiaddr) []ma.Multiaddr {
+	switch Of(remote) {
+	case Private, LinkLocal:
+		return ma.FilterAddrs(addrs, func(a ma.Multiaddr) bool { return Of(a) != Loopback })
+	case Public:
+		return ma.FilterAddrs(addrs, func(a ma.Multiaddr) bool { return Of(a) == Public })
+	default:
+		return addrs
+	}
+}
@@ -0,0 +1,91 @@
+package addrscope
+
+import (
+	"net/netip"
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOf(t *testing.T) {
+	for _, tc := range []struct {
+		addr  string
+		scope Scope
+	}{
+		{"/ip4/127.0.0.1/tcp/1234", Loopback},
+		{"/ip6/::1/udp/1234/quic-v1", Loopback},
+		{"/dns4/localhost/tcp/1234", Private},
+		{"/ip4/169.254.10.1/tcp/1234", LinkLocal},
+		{"/ip6/fe80::1/tcp/1234", LinkLocal},
+		{"/ip6zone/eth0/ip6/fe80::1/tcp/1234", LinkLocal},
+		{"/ip4/192.168.1.2/tcp/1234", Private},
+		{"/ip4/10.0.0.1/udp/1234/quic-v1", Private},
+		{"/ip6/fd00::1/tcp/1234", Private},
+		{"/ip4/1.2.3.4/tcp/1234", Public},
+		{"/ip6/2001:4860::1/tcp/1234", Public},
+		{"/dns4/example.com/tcp/1234", Public},
+		{"/ip4/0.0.0.0/tcp/1234", Unknown},
+	} {
+		t.Run(tc.addr, func(t *testing.T) {
+			require.Equal(t, tc.scope, Of(ma.StringCast(tc.addr)))
+		})
+	}
+}
+
+func TestIsLANPeer(t *testing.T) {
+	subnets := []netip.Prefix{
+		netip.MustParsePrefix("192.168.1.0/24"),
+		netip.MustParsePrefix("fe80::/64"),
+	}
+	require.True(t, IsLANPeer(ma.StringCast("/ip4/192.168.1.20/tcp/1"), subnets))
+	require.True(t, IsLANPeer(ma.StringCast("/ip6/fe80::2/tcp/1"), subnets))
+	require.False(t, IsLANPeer(ma.StringCast("/ip4/192.168.2.20/tcp/1"), subnets))
+	require.False(t, IsLANPeer(ma.StringCast("/ip4/10.0.0.1/tcp/1"), subnets))
+	require.False(t, IsLANPeer(ma.StringCast("/ip4/127.0.0.1/tcp/1"), []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}))
+	require.False(t, IsLANPeer(ma.StringCast("/ip4/1.2.3.4/tcp/1"), []netip.Prefix{netip.MustParsePrefix("1.2.3.0/24")}))
+}
+
+var testAddrs = []ma.Multiaddr{
+	ma.StringCast("/ip4/127.0.0.1/tcp/1"),
+	ma.StringCast("/ip4/169.254.10.1/tcp/1"),
+	ma.StringCast("/ip4/192.168.1.2/tcp/1"),
+	ma.StringCast("/ip4/1.2.3.4/tcp/1"),
+}
+
+func TestForPeer(t *testing.T) {
+	subnets := []netip.Prefix{netip.MustParsePrefix("192.168.1.0/24")}
+	for _, tc := range []struct {
+		name     string
+		remote   string
+		expected []ma.Multiaddr
+	}{
+		{"loopback", "/ip4/127.0.0.1/tcp/2", testAddrs},
+		{"same subnet", "/ip4/192.168.1.3/tcp/2", testAddrs[1:]},
+		{"other private network", "/ip4/10.0.0.1/tcp/2", testAddrs[3:]},
+		{"public", "/ip4/5.6.7.8/tcp/2", testAddrs[3:]},
+		{"relayed", "/ip4/5.6.7.8/tcp/2/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit", testAddrs[3:]},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, ForPeer(testAddrs, ma.StringCast(tc.remote), subnets))
+		})
+	}
+}
+
+func TestFromPeer(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		remote   string
+		expected []ma.Multiaddr
+	}{
+		{"loopback", "/ip4/127.0.0.1/tcp/2", testAddrs},
+		{"link-local", "/ip4/169.254.10.2/tcp/2", testAddrs[1:]},
+		{"private", "/ip4/10.0.0.1/tcp/2", testAddrs[1:]},
+		{"public", "/ip4/5.6.7.8/tcp/2", testAddrs[3:]},
+		{"unknown", "/ip4/0.0.0.0/tcp/2", testAddrs},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, FromPeer(testAddrs, ma.StringCast(tc.remote)))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package conngater
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/control"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// filtersGater rejects connections to and from addresses blocked by the filters, and
+// delegates all other decisions to the next gater.
+type filtersGater struct {
+	filters *ma.Filters
+	next    connmgr.ConnectionGater
+}
+
+var _ connmgr.ConnectionGater = (*filtersGater)(nil)
+
+// NewAddrFiltersGater returns a gater rejecting connections to and from the addresses
+// blocked by f. Changes to f apply to new connections. Connections that aren't
+// rejected by the filters are gated by next, if not nil.
+func NewAddrFiltersGater(f *ma.Filters, next connmgr.ConnectionGater) connmgr.ConnectionGater {
+	return &filtersGater{filters: f, next: next}
+}
+
+func (g *filtersGater) InterceptPeerDial(p peer.ID) (allow bool) {
+	return g.next == nil || g.next.InterceptPeerDial(p)
+}
+
+func (g *filtersGater) InterceptAddrDial(p peer.ID, a ma.Multiaddr) (allow bool) {
+	if g.filters.AddrBlocked(a) {
+		return false
+	}
+	return g.next == nil || g.next.InterceptAddrDial(p, a)
+}
+
+func (g *filtersGater) InterceptAccept(cma network.ConnMultiaddrs) (allow bool) {
+	if g.filters.AddrBlocked(cma.RemoteMultiaddr()) {
+		return false
+	}
+	return g.next == nil || g.next.InterceptAccept(cma)
+}
+
+func (g *filtersGater) InterceptSecured(dir network.Direction, p peer.ID, cma network.ConnMultiaddrs) (allow bool) {
+	if g.filters.AddrBlocked(cma.RemoteMultiaddr()) {
+		return false
+	}
+	return g.next == nil || g.next.InterceptSecured(dir, p, cma)
+}
+
+func (g *filtersGater) InterceptUpgraded(c network.Conn) (allow bool, reason control.DisconnectReason) {
+	if g.next == nil {
+		return true, 0
+	}
+	return g.next.InterceptUpgraded(c)
+}
@@ -19,6 +19,12 @@ import (
 
 var log = logging.Logger("connmgr")
 
+// drainer is implemented by connections that can refuse new streams while their open
+// streams finish, and close themselves once they're done, see swarm.Conn.DrainAndClose.
+type drainer interface {
+	DrainAndClose(timeout time.Duration, errCode network.ConnErrorCode)
+}
+
 // BasicConnMgr is a ConnManager that trims connections whenever the count exceeds the
 // high watermark. New connections are given a grace period before they're subject
 // to trimming. Trims are automatically run on demand, only if the time from the
@@ -47,6 +53,9 @@ type BasicConnMgr struct {
 	lastTrimMu sync.RWMutex
 	lastTrim   time.Time
 
+	drainingMu sync.Mutex
+	draining   map[network.Conn]struct{}
+
 	refCount                sync.WaitGroup
 	ctx                     context.Context
 	cancel                  func()
@@ -54,8 +63,9 @@ type BasicConnMgr struct {
 }
 
 var (
-	_ connmgr.ConnManager = (*BasicConnMgr)(nil)
-	_ connmgr.Decayer     = (*BasicConnMgr)(nil)
+	_ connmgr.ConnManager      = (*BasicConnMgr)(nil)
+	_ connmgr.Decayer          = (*BasicConnMgr)(nil)
+	_ connmgr.HighWaterChecker = (*BasicConnMgr)(nil)
 )
 
 type segment struct {
@@ -130,6 +140,7 @@ func NewConnManager(low, hi int, opts ...Option) (*BasicConnMgr, error) {
 		cfg:       cfg,
 		clock:     cfg.clock,
 		protected: make(map[peer.ID]map[string]struct{}, 16),
+		draining:  make(map[network.Conn]struct{}),
 		segments:  segments{},
 	}
 
@@ -268,6 +279,13 @@ type peerInfos []*peerInfo
 // equal). If `sortByMoreStreams` is true it will sort peers with more streams
 // before those with fewer streams. This is useful to prioritize freeing memory.
 func (p peerInfos) SortByValueAndStreams(segments *segments, sortByMoreStreams bool) {
+	p.sortByValue(segments, sortByMoreStreams, false)
+}
+
+// sortByValue sorts peerInfos like SortByValueAndStreams. If fewerStreamsFirst is set,
+// peers with fewer streams are sorted before those with more streams regardless of
+// the direction of their connections, so that the trimmed connections drain quickly.
+func (p peerInfos) sortByValue(segments *segments, sortByMoreStreams, fewerStreamsFirst bool) {
 	sort.Slice(p, func(i, j int) bool {
 		left, right := p[i], p[j]
 
@@ -308,7 +326,7 @@ func (p peerInfos) SortByValueAndStreams(segments *segments, sortByMoreStreams b
 		leftIncoming, leftStreams := incomingAndStreams(left.conns)
 		rightIncoming, rightStreams := incomingAndStreams(right.conns)
 		// prefer closing inactive connections (no streams open)
-		if rightStreams != leftStreams && (leftStreams == 0 || rightStreams == 0) {
+		if rightStreams != leftStreams && (leftStreams == 0 || rightStreams == 0 || fewerStreamsFirst) {
 			return leftStreams < rightStreams
 		}
 		// incoming connections are preferred for pruning
@@ -383,9 +401,30 @@ func (cm *BasicConnMgr) doTrim() {
 func (cm *BasicConnMgr) trim() {
 	// do the actual trim.
 	for _, c := range cm.getConnsToClose() {
+		cm.closeConn(c)
+	}
+}
+
+// closeConn closes a trimmed connection. If a drain period is configured, connections
+// with open streams are drained first.
+func (cm *BasicConnMgr) closeConn(c network.Conn) {
+	d, ok := c.(drainer)
+	if !ok || cm.cfg.drainPeriod == 0 || c.Stat().NumStreams == 0 {
 		log.Debugw("closing conn", "peer", c.RemotePeer())
 		c.CloseWithError(network.ConnGarbageCollected)
+		return
+	}
+
+	cm.drainingMu.Lock()
+	if _, ok := cm.draining[c]; ok {
+		cm.drainingMu.Unlock()
+		return
 	}
+	cm.draining[c] = struct{}{}
+	cm.drainingMu.Unlock()
+
+	log.Debugw("draining conn", "peer", c.RemotePeer())
+	d.DrainAndClose(cm.cfg.drainPeriod, network.ConnGarbageCollected)
 }
 
 func (cm *BasicConnMgr) getConnsToCloseEmergency(target int) []network.Conn {
@@ -405,6 +444,10 @@ func (cm *BasicConnMgr) getConnsToCloseEmergency(target int) []network.Conn {
 	}
 	cm.plk.RUnlock()
 
+	if cm.cfg.trimScorer != nil {
+		return cm.getConnsToCloseEmergencyScored(candidates, target)
+	}
+
 	// Sort peers according to their value.
 	candidates.SortByValueAndStreams(&cm.segments, true)
 
@@ -464,7 +507,9 @@ func (cm *BasicConnMgr) getConnsToClose() []network.Conn {
 		return nil
 	}
 
-	if int(cm.connCount.Load()) <= cm.cfg.lowWater {
+	// connections that are being drained are already on their way out
+	draining := cm.drainingConns()
+	if int(cm.connCount.Load())-len(draining) <= cm.cfg.lowWater {
 		log.Info("open connection count below limit")
 		return nil
 	}
@@ -488,12 +533,16 @@ func (cm *BasicConnMgr) getConnsToClose() []network.Conn {
 			// note that we're copying the entry here,
 			// but since inf.conns is a map, it will still point to the original object
 			candidates = append(candidates, inf)
-			ncandidates += len(inf.conns)
+			ncandidates += countLive(inf.conns, draining)
 		}
 		s.Unlock()
 	}
 	cm.plk.RUnlock()
 
+	if cm.cfg.trimScorer != nil {
+		return cm.getConnsToCloseScored(candidates, draining)
+	}
+
 	if ncandidates < cm.cfg.lowWater {
 		log.Info("open connection count above limit but too many are in the grace period")
 		// We have too many connections but fewer than lowWater
@@ -503,8 +552,9 @@ func (cm *BasicConnMgr) getConnsToClose() []network.Conn {
 		return nil
 	}
 
-	// Sort peers according to their value.
-	candidates.SortByValueAndStreams(&cm.segments, false)
+	// Sort peers according to their value. When draining, prefer the peers with the
+	// fewest streams, their connections are closed the soonest.
+	candidates.sortByValue(&cm.segments, false, cm.cfg.drainPeriod > 0)
 
 	target := ncandidates - cm.cfg.lowWater
 
@@ -525,9 +575,11 @@ func (cm *BasicConnMgr) getConnsToClose() []network.Conn {
 			delete(s.peers, inf.id)
 		} else {
 			for c := range inf.conns {
-				selected = append(selected, c)
+				if _, ok := draining[c]; !ok {
+					selected = append(selected, c)
+				}
 			}
-			target -= len(inf.conns)
+			target -= countLive(inf.conns, draining)
 		}
 		s.Unlock()
 	}
@@ -535,6 +587,31 @@ func (cm *BasicConnMgr) getConnsToClose() []network.Conn {
 	return selected
 }
 
+// drainingConns returns a snapshot of the connections being drained.
+func (cm *BasicConnMgr) drainingConns() map[network.Conn]struct{} {
+	cm.drainingMu.Lock()
+	defer cm.drainingMu.Unlock()
+	if len(cm.draining) == 0 {
+		return nil
+	}
+	draining := make(map[network.Conn]struct{}, len(cm.draining))
+	for c := range cm.draining {
+		draining[c] = struct{}{}
+	}
+	return draining
+}
+
+// countLive returns the number of connections in conns that aren't being drained.
+func countLive(conns map[network.Conn]time.Time, draining map[network.Conn]struct{}) int {
+	n := len(conns)
+	for c := range conns {
+		if _, ok := draining[c]; ok {
+			n--
+		}
+	}
+	return n
+}
+
 // GetTagInfo is called to fetch the tag information associated with a given
 // peer, nil is returned if p refers to an unknown peer.
 func (cm *BasicConnMgr) GetTagInfo(p peer.ID) *connmgr.TagInfo {
@@ -629,6 +706,12 @@ type CMInfo struct {
 	ConnCount int
 }
 
+// AboveHighWater returns true if there are at least as many connections as the high
+// water mark.
+func (cm *BasicConnMgr) AboveHighWater() bool {
+	return cm.cfg.highWater > 0 && int(cm.connCount.Load()) >= cm.cfg.highWater
+}
+
 // GetInfo returns the configuration and status data for this connection manager.
 func (cm *BasicConnMgr) GetInfo() CMInfo {
 	cm.lastTrimMu.RLock()
@@ -724,6 +807,10 @@ func (nn *cmNotifee) Disconnected(_ network.Network, c network.Conn) {
 		delete(s.peers, p)
 	}
 	cm.connCount.Add(-1)
+
+	cm.drainingMu.Lock()
+	delete(cm.draining, c)
+	cm.drainingMu.Unlock()
 }
 
 // Listen is no-op in this implementation.
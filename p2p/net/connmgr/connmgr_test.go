@@ -2,6 +2,7 @@ package connmgr
 
 import (
 	"context"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	tu "github.com/TheNoobiCat/go-libp2p/core/test"
 
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
@@ -368,6 +370,22 @@ func TestGetInfo(t *testing.T) {
 	}
 }
 
+func TestAboveHighWater(t *testing.T) {
+	cm, err := NewConnManager(1, 2, WithGracePeriod(time.Hour))
+	require.NoError(t, err)
+	defer cm.Close()
+	not := cm.Notifee()
+
+	require.False(t, cm.AboveHighWater())
+	not.Connected(nil, randConn(t, nil))
+	require.False(t, cm.AboveHighWater())
+	c := randConn(t, nil)
+	not.Connected(nil, c)
+	require.True(t, cm.AboveHighWater())
+	not.Disconnected(nil, c)
+	require.False(t, cm.AboveHighWater())
+}
+
 func TestDoubleConnection(t *testing.T) {
 	const gp = 10 * time.Minute
 	cm, err := NewConnManager(1, 5, WithGracePeriod(gp))
@@ -476,6 +494,159 @@ func TestGracePeriod(t *testing.T) {
 	}
 }
 
+type drainConn struct {
+	*tconn
+	disconnected func(network.Network, network.Conn)
+	streams      atomic.Int32
+	drained      atomic.Bool
+	timeout      time.Duration
+	errCode      network.ConnErrorCode
+}
+
+func (c *drainConn) Stat() network.ConnStats {
+	return network.ConnStats{NumStreams: int(c.streams.Load())}
+}
+
+func (c *drainConn) DrainAndClose(timeout time.Duration, errCode network.ConnErrorCode) {
+	c.drained.Store(true)
+	c.timeout = timeout
+	c.errCode = errCode
+}
+
+func (c *drainConn) CloseWithError(_ network.ConnErrorCode) error {
+	atomic.StoreUint32(&c.closed, 1)
+	c.disconnected(nil, c)
+	return nil
+}
+
+func TestTrimDrainsConns(t *testing.T) {
+	cm, err := NewConnManager(1, 3, WithGracePeriod(0), WithSilencePeriod(time.Hour), WithDrainPeriod(time.Minute))
+	require.NoError(t, err)
+	defer cm.Close()
+	not := cm.Notifee()
+
+	newConn := func(streams int32) *drainConn {
+		c := &drainConn{tconn: &tconn{peer: tu.RandPeerIDFatal(t)}, disconnected: not.Disconnected}
+		c.streams.Store(streams)
+		not.Connected(nil, c)
+		return c
+	}
+	idle := newConn(0)
+	busy := newConn(1)
+	busier := newConn(2)
+	kept := newConn(1)
+	cm.TagPeer(kept.peer, "kept", 100)
+	// connections that can't be drained are closed right away
+	plain := &tconn{peer: tu.RandPeerIDFatal(t), disconnectNotify: not.Disconnected}
+	not.Connected(nil, plain)
+
+	cm.TrimOpenConns(context.Background())
+	require.True(t, idle.isClosed(), "connections without streams should be closed right away")
+	require.False(t, idle.drained.Load())
+	require.True(t, plain.isClosed())
+	for _, c := range []*drainConn{busy, busier} {
+		require.True(t, c.drained.Load())
+		require.False(t, c.isClosed())
+		require.Equal(t, time.Minute, c.timeout)
+		require.Equal(t, network.ConnGarbageCollected, c.errCode)
+	}
+	require.False(t, kept.drained.Load())
+
+	// trimming again doesn't trim more connections while the others are draining
+	cm.TrimOpenConns(context.Background())
+	require.False(t, kept.drained.Load())
+
+	// closed connections are no longer tracked as draining
+	busy.CloseWithError(network.ConnGarbageCollected)
+	busier.CloseWithError(network.ConnGarbageCollected)
+	require.Empty(t, cm.drainingConns())
+	require.False(t, kept.isClosed())
+}
+
+type protoStream struct {
+	network.Stream
+	proto protocol.ID
+}
+
+func (s *protoStream) Protocol() protocol.ID { return s.proto }
+
+type scoredTestConn struct {
+	*tconn
+	transport string
+	dir       network.Direction
+	streams   []network.Stream
+}
+
+func (c *scoredTestConn) Stat() network.ConnStats {
+	return network.ConnStats{Stats: network.Stats{Direction: c.dir}, NumStreams: len(c.streams)}
+}
+
+func (c *scoredTestConn) GetStreams() []network.Stream { return c.streams }
+
+func (c *scoredTestConn) ConnState() network.ConnectionState {
+	return network.ConnectionState{Transport: c.transport}
+}
+
+func TestTrimScorer(t *testing.T) {
+	const validator = protocol.ID("/validator/1.0.0")
+	var infos sync.Map
+	scorer := func(ci ConnInfo) int {
+		infos.Store(ci.Conn, ci)
+		if slices.Contains(ci.Protocols, validator) {
+			return NeverTrim
+		}
+		// prefer keeping QUIC connections, then use the tags
+		if ci.Transport == "quic" {
+			return 1000 + ci.Value
+		}
+		return ci.Value
+	}
+	cm, err := NewConnManager(2, 4, WithGracePeriod(0), WithSilencePeriod(time.Hour), WithTrimScorer(scorer))
+	require.NoError(t, err)
+	defer cm.Close()
+	not := cm.Notifee()
+
+	newConn := func(transport string, protos ...protocol.ID) *scoredTestConn {
+		c := &scoredTestConn{tconn: &tconn{peer: tu.RandPeerIDFatal(t), disconnectNotify: not.Disconnected}, transport: transport, dir: network.DirInbound}
+		for _, p := range protos {
+			c.streams = append(c.streams, &protoStream{proto: p})
+		}
+		not.Connected(nil, c)
+		return c
+	}
+	validator1 := newConn("tcp", "/foo", validator)
+	validator2 := newConn("tcp", validator)
+	quic := newConn("quic")
+	tagged := newConn("tcp")
+	cm.TagPeer(tagged.peer, "tag", 100)
+	untagged := newConn("tcp", "/foo")
+
+	cm.TrimOpenConns(context.Background())
+	require.True(t, untagged.isClosed())
+	for _, c := range []*scoredTestConn{validator1, validator2, quic, tagged} {
+		require.False(t, c.isClosed())
+	}
+
+	v, ok := infos.Load(network.Conn(tagged))
+	require.True(t, ok)
+	ci := v.(ConnInfo)
+	require.Equal(t, tagged.peer, ci.Peer)
+	require.Equal(t, map[string]int{"tag": 100}, ci.Tags)
+	require.Equal(t, 100, ci.Value)
+	require.Equal(t, network.DirInbound, ci.Direction)
+	require.Equal(t, "tcp", ci.Transport)
+	v, ok = infos.Load(network.Conn(validator1))
+	require.True(t, ok)
+	require.Equal(t, []protocol.ID{"/foo", validator}, v.(ConnInfo).Protocols)
+
+	// the validators are only closed by an emergency trim if there's no other choice
+	cm.ForceTrim()
+	require.True(t, tagged.isClosed())
+	require.True(t, quic.isClosed())
+	require.False(t, validator1.isClosed())
+	require.False(t, validator2.isClosed())
+}
+
 // see https://github.com/TheNoobiCat/go-libp2p-connmgr/issues/23
 func TestQuickBurstRespectsSilencePeriod(t *testing.T) {
 	mockClock := clock.NewMock()
@@ -818,6 +989,7 @@ func (m mockConn) NewStream(_ context.Context) (network.Stream, error) { panic("
 func (m mockConn) GetStreams() []network.Stream                        { panic("implement me") }
 func (m mockConn) Scope() network.ConnScope                            { panic("implement me") }
 func (m mockConn) ConnState() network.ConnectionState                  { return network.ConnectionState{} }
+func (m mockConn) CloseReason() (network.ConnCloseReason, bool)        { panic("implement me") }
 
 func makeSegmentsWithPeerInfos(peerInfos peerInfos) *segments {
 	var s = func() *segments {
@@ -873,6 +1045,18 @@ func TestPeerInfoSorting(t *testing.T) {
 		require.Equal(t, peerInfos{p1, p2}, pis)
 	})
 
+	t.Run("when draining, prefer peers with fewer streams", func(t *testing.T) {
+		inbound := network.ConnStats{Stats: network.Stats{Direction: network.DirInbound}, NumStreams: 2}
+		outbound := network.ConnStats{Stats: network.Stats{Direction: network.DirOutbound}, NumStreams: 1}
+		p1 := &peerInfo{id: peer.ID("peer1"), conns: map[network.Conn]time.Time{&mockConn{stats: inbound}: time.Now()}}
+		p2 := &peerInfo{id: peer.ID("peer2"), conns: map[network.Conn]time.Time{&mockConn{stats: outbound}: time.Now()}}
+		pis := peerInfos{p1, p2}
+		pis.SortByValueAndStreams(makeSegmentsWithPeerInfos(pis), false)
+		require.Equal(t, peerInfos{p1, p2}, pis)
+		pis.sortByValue(makeSegmentsWithPeerInfos(pis), false, true)
+		require.Equal(t, peerInfos{p2, p1}, pis)
+	})
+
 	t.Run("in a memory emergency, starts with incoming connections and higher streams", func(t *testing.T) {
 		incoming := network.ConnStats{}
 		incoming.Direction = network.DirInbound
@@ -13,8 +13,10 @@ type config struct {
 	lowWater      int
 	gracePeriod   time.Duration
 	silencePeriod time.Duration
+	drainPeriod   time.Duration
 	decayer       *DecayerCfg
 	clock         clock.Clock
+	trimScorer    TrimScorer
 }
 
 // Option represents an option for the basic connection manager.
@@ -61,3 +63,35 @@ func WithSilencePeriod(p time.Duration) Option {
 		return nil
 	}
 }
+
+// WithDrainPeriod sets the drain period.
+// Connections with open streams are not closed right away when they're trimmed. Instead,
+// new streams are refused on them, and they're closed once their streams have finished,
+// or the drain period has elapsed, whichever comes first. Connections without open
+// streams are closed right away, and peers with fewer open streams are trimmed before
+// peers of the same value with more streams. Connections that can't be drained, see
+// swarm.Conn.DrainAndClose, are closed right away. Defaults to 0, closing trimmed
+// connections right away.
+//
+// Emergency trims (see ForceTrim) don't drain connections.
+func WithDrainPeriod(p time.Duration) Option {
+	return func(cfg *config) error {
+		if p < 0 {
+			return errors.New("drain period must be non-negative")
+		}
+		cfg.drainPeriod = p
+		return nil
+	}
+}
+
+// WithTrimScorer sets a TrimScorer deciding the order in which connections are trimmed.
+// It can be used to express policies the tags can't, for example never trimming the
+// connections to certain peers or on certain protocols.
+// Without a scorer, the connections of the peers with the lowest tag values are trimmed
+// first.
+func WithTrimScorer(s TrimScorer) Option {
+	return func(cfg *config) error {
+		cfg.trimScorer = s
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package connmgr
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+)
+
+// NeverTrim is the trim priority of connections that must not be trimmed. These
+// connections are treated like the connections of protected peers: they're only closed
+// by an emergency trim (see ForceTrim), when there's no other way to get back under the
+// limit.
+const NeverTrim = math.MaxInt
+
+// ConnInfo describes a connection that is a candidate for trimming.
+type ConnInfo struct {
+	// Conn is the connection.
+	Conn network.Conn
+	// Peer is the remote peer.
+	Peer peer.ID
+	// Tags are the tags of the peer, including the decaying tags.
+	Tags map[string]int
+	// Value is the sum of the tag values of the peer.
+	Value int
+	// Protocols are the protocols of the streams open on the connection.
+	Protocols []protocol.ID
+	// Direction is the direction of the connection.
+	Direction network.Direction
+	// Age is the time since the connection manager learned about the connection.
+	Age time.Duration
+	// Transport is the transport of the connection, for example: tcp.
+	Transport string
+	// Limited indicates that the connection is limited, e.g. a relayed connection.
+	Limited bool
+}
+
+// TrimScorer returns the trim priority of a connection. Connections with a lower
+// priority are trimmed first; connections with the same priority are trimmed in the
+// order of the default heuristics. Return NeverTrim to keep the connection.
+//
+// The scorer is called with the connection manager's locks held, it must not call back
+// into the connection manager.
+type TrimScorer func(ConnInfo) int
+
+type scoredConn struct {
+	conn     network.Conn
+	priority int
+}
+
+// scoreConns scores the connections of the candidates with the configured TrimScorer,
+// skipping the draining connections, and returns them in the order they should be
+// trimmed. The candidates must be sorted by the default heuristics already, this order
+// is kept for connections with the same priority. Connections scored NeverTrim are only
+// returned if keepNeverTrim is set.
+func (cm *BasicConnMgr) scoreConns(candidates peerInfos, draining map[network.Conn]struct{}, keepNeverTrim bool) []network.Conn {
+	now := cm.clock.Now()
+	scored := make([]scoredConn, 0, len(candidates))
+	for _, inf := range candidates {
+		s := cm.segments.get(inf.id)
+		s.Lock()
+		var tags map[string]int
+		for c, start := range inf.conns {
+			if _, ok := draining[c]; ok {
+				continue
+			}
+			if tags == nil {
+				tags = make(map[string]int, len(inf.tags)+len(inf.decaying))
+				for t, v := range inf.tags {
+					tags[t] = v
+				}
+				for t, v := range inf.decaying {
+					tags[t.name] = v.Value
+				}
+			}
+			stat := c.Stat()
+			streams := c.GetStreams()
+			protos := make([]protocol.ID, 0, len(streams))
+			for _, str := range streams {
+				if p := str.Protocol(); p != "" {
+					protos = append(protos, p)
+				}
+			}
+			priority := cm.cfg.trimScorer(ConnInfo{
+				Conn:      c,
+				Peer:      inf.id,
+				Tags:      tags,
+				Value:     inf.value,
+				Protocols: protos,
+				Direction: stat.Direction,
+				Age:       now.Sub(start),
+				Transport: c.ConnState().Transport,
+				Limited:   stat.Limited,
+			})
+			if priority == NeverTrim && !keepNeverTrim {
+				continue
+			}
+			scored = append(scored, scoredConn{conn: c, priority: priority})
+		}
+		s.Unlock()
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool { return scored[i].priority < scored[j].priority })
+	conns := make([]network.Conn, 0, len(scored))
+	for _, sc := range scored {
+		conns = append(conns, sc.conn)
+	}
+	return conns
+}
+
+// getConnsToCloseScored selects the connections to close using the configured
+// TrimScorer. The candidates are the unprotected peers out of their grace period.
+func (cm *BasicConnMgr) getConnsToCloseScored(candidates peerInfos, draining map[network.Conn]struct{}) []network.Conn {
+	candidates.sortByValue(&cm.segments, false, cm.cfg.drainPeriod > 0)
+	conns := cm.scoreConns(candidates, draining, false)
+	if len(conns) < cm.cfg.lowWater {
+		log.Info("open connection count above limit but too many are in the grace period or never trimmed")
+		return nil
+	}
+
+	// prune the temporary entries for early tags that have gone past the grace period
+	// and still hold no connections.
+	for _, inf := range candidates {
+		s := cm.segments.get(inf.id)
+		s.Lock()
+		if len(inf.conns) == 0 && inf.temp {
+			delete(s.peers, inf.id)
+		}
+		s.Unlock()
+	}
+	return conns[:len(conns)-cm.cfg.lowWater]
+}
+
+// getConnsToCloseEmergencyScored selects target connections to close using the
+// configured TrimScorer, falling back to protected and NeverTrim connections if there
+// aren't enough other connections.
+func (cm *BasicConnMgr) getConnsToCloseEmergencyScored(candidates peerInfos, target int) []network.Conn {
+	candidates.SortByValueAndStreams(&cm.segments, true)
+	conns := cm.scoreConns(candidates, nil, false)
+	if len(conns) >= target {
+		return conns[:target]
+	}
+
+	// We didn't find enough connections that may be trimmed.
+	// We have no choice but to kill some protected connections.
+	candidates = candidates[:0]
+	cm.plk.RLock()
+	for _, s := range cm.segments.buckets {
+		s.Lock()
+		for _, inf := range s.peers {
+			candidates = append(candidates, inf)
+		}
+		s.Unlock()
+	}
+	cm.plk.RUnlock()
+
+	candidates.SortByValueAndStreams(&cm.segments, true)
+	conns = cm.scoreConns(candidates, nil, true)
+	return conns[:min(target, len(conns))]
+}
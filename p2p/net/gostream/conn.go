@@ -11,15 +11,38 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 )
 
+// Conn is implemented by the net.Conns returned by gostream. It gives access to
+// the underlying libp2p stream:
+//
+//	if c, ok := netConn.(gostream.Conn); ok {
+//		p := c.RemotePeer()
+//	}
+type Conn interface {
+	net.Conn
+
+	// Stream returns the libp2p stream the conn wraps.
+	Stream() network.Stream
+	// RemotePeer returns the ID of the peer at the other end of the stream.
+	RemotePeer() peer.ID
+	// Protocol returns the protocol negotiated on the stream.
+	Protocol() protocol.ID
+}
+
+// stream is network.Stream under a name that doesn't collide with conn's Stream
+// method.
+type stream = network.Stream
+
 // conn is an implementation of net.Conn which wraps
 // libp2p streams.
 type conn struct {
-	network.Stream
+	stream
 	ignoreEOF bool
 }
 
+var _ Conn = (*conn)(nil)
+
 func (c *conn) Read(b []byte) (int, error) {
-	n, err := c.Stream.Read(b)
+	n, err := c.stream.Read(b)
 	if err != nil && c.ignoreEOF && err == io.EOF {
 		return n, nil
 	}
@@ -33,12 +56,22 @@ func newConn(s network.Stream, ignoreEOF bool) net.Conn {
 
 // LocalAddr returns the local network address.
 func (c *conn) LocalAddr() net.Addr {
-	return &addr{c.Stream.Conn().LocalPeer()}
+	return &addr{c.stream.Conn().LocalPeer()}
 }
 
 // RemoteAddr returns the remote network address.
 func (c *conn) RemoteAddr() net.Addr {
-	return &addr{c.Stream.Conn().RemotePeer()}
+	return &addr{c.stream.Conn().RemotePeer()}
+}
+
+// Stream returns the libp2p stream the conn wraps.
+func (c *conn) Stream() network.Stream {
+	return c.stream
+}
+
+// RemotePeer returns the ID of the remote peer.
+func (c *conn) RemotePeer() peer.ID {
+	return c.stream.Conn().RemotePeer()
 }
 
 // Dial opens a stream to the destination address
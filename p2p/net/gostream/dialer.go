@@ -0,0 +1,83 @@
+package gostream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+)
+
+type protocolCtxKey struct{}
+
+// WithProtocol returns a context that makes Dialer.DialContext open the stream
+// with the given protocol instead of the Dialer's default protocol.
+func WithProtocol(ctx context.Context, tag protocol.ID) context.Context {
+	return context.WithValue(ctx, protocolCtxKey{}, tag)
+}
+
+// GetProtocol returns the protocol set with WithProtocol, if any.
+func GetProtocol(ctx context.Context) (tag protocol.ID, ok bool) {
+	tag, ok = ctx.Value(protocolCtxKey{}).(protocol.ID)
+	return tag, ok
+}
+
+// Dialer opens libp2p streams and returns them as net.Conns. Its DialContext method
+// can be used as the dial function of net/http's Transport or of RPC frameworks.
+//
+// Dials are cancelled when the context is done, this includes establishing the
+// connection to the peer and negotiating the protocol.
+type Dialer struct {
+	// Host is the host used to open streams.
+	Host host.Host
+	// Protocol is the protocol used to open streams. It can be overridden for a
+	// single dial using WithProtocol.
+	Protocol protocol.ID
+	// Pool is an optional pool of idle streams. If set, dials reuse idle streams
+	// and closing a conn returns its stream to the pool.
+	Pool *Pool
+}
+
+// DialContext dials the peer with the given address. The address is a peer ID,
+// optionally followed by a port which is ignored, as used by net/http. The network
+// is ignored too: net/http always dials "tcp".
+func (d *Dialer) DialContext(ctx context.Context, _, address string) (net.Conn, error) {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		address = host
+	}
+	p, err := peer.Decode(address)
+	if err != nil {
+		return nil, fmt.Errorf("gostream: invalid address %q: %w", address, err)
+	}
+	return d.DialPeer(ctx, p)
+}
+
+// DialPeer opens a stream to the peer and returns it as a net.Conn.
+func (d *Dialer) DialPeer(ctx context.Context, p peer.ID) (net.Conn, error) {
+	tag, ok := GetProtocol(ctx)
+	if !ok {
+		tag = d.Protocol
+	}
+	if tag == "" {
+		return nil, errors.New("gostream: no protocol")
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if d.Pool != nil {
+		if s := d.Pool.get(p, tag); s != nil {
+			return d.Pool.newConn(s), nil
+		}
+	}
+	s, err := d.Host.NewStream(ctx, p, tag)
+	if err != nil {
+		return nil, err
+	}
+	if d.Pool != nil {
+		return d.Pool.newConn(s), nil
+	}
+	return newConn(s, false), nil
+}
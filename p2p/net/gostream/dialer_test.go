@@ -0,0 +1,233 @@
+package gostream
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newConnectedHosts(t *testing.T) (srv, client host.Host) {
+	t.Helper()
+	srv, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	t.Cleanup(func() { srv.Close() })
+	client, err = libp2p.New(libp2p.NoListenAddrs)
+	require.NoError(t, err)
+	t.Cleanup(func() { client.Close() })
+	require.NoError(t, client.Connect(context.Background(), peer.AddrInfo{ID: srv.ID(), Addrs: srv.Addrs()}))
+	return srv, client
+}
+
+// setLineEchoHandler echoes lines, prefixed with the protocol. It returns a counter
+// of the streams handled.
+func setLineEchoHandler(h host.Host, tag protocol.ID) *atomic.Int32 {
+	var streams atomic.Int32
+	h.SetStreamHandler(tag, func(s network.Stream) {
+		defer s.Close()
+		streams.Add(1)
+		r := bufio.NewReader(s)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if _, err := fmt.Fprintf(s, "%s %s", tag, line); err != nil {
+				return
+			}
+		}
+	})
+	return &streams
+}
+
+func request(t *testing.T, c net.Conn, msg string) string {
+	t.Helper()
+	_, err := io.WriteString(c, msg+"\n")
+	require.NoError(t, err)
+	resp, err := bufio.NewReader(c).ReadString('\n')
+	require.NoError(t, err)
+	return resp[:len(resp)-1]
+}
+
+func TestDialerProtocolOverride(t *testing.T) {
+	srv, client := newConnectedHosts(t)
+	setLineEchoHandler(srv, "/a")
+	setLineEchoHandler(srv, "/b")
+
+	d := &Dialer{Host: client, Protocol: "/a"}
+	c, err := d.DialContext(context.Background(), Network, srv.ID().String())
+	require.NoError(t, err)
+	require.Equal(t, "/a hello", request(t, c, "hello"))
+	c.Close()
+
+	// net/http appends a port to the address
+	c, err = d.DialContext(WithProtocol(context.Background(), "/b"), Network, srv.ID().String()+":80")
+	require.NoError(t, err)
+	require.Equal(t, "/b hello", request(t, c, "hello"))
+	c.Close()
+
+	// net/http dials "tcp"
+	c, err = d.DialContext(context.Background(), "tcp", srv.ID().String())
+	require.NoError(t, err)
+	require.Equal(t, "/a hello", request(t, c, "hello"))
+	c.Close()
+
+	_, err = d.DialContext(context.Background(), Network, "not a peer id")
+	require.Error(t, err)
+}
+
+func TestDialerHTTPTransport(t *testing.T) {
+	srv, client := newConnectedHosts(t)
+	const proto = "/http-test"
+	l, err := Listen(srv, proto)
+	require.NoError(t, err)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "hello %s", r.URL.Path)
+	})}
+	go server.Serve(l)
+	defer server.Close()
+
+	d := &Dialer{Host: client, Protocol: proto}
+	hc := &http.Client{Transport: &http.Transport{DialContext: d.DialContext}}
+	defer hc.CloseIdleConnections()
+	for i := 0; i < 2; i++ {
+		resp, err := hc.Get("http://" + srv.ID().String() + "/world")
+		require.NoError(t, err)
+		b, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, "hello /world", string(b))
+	}
+}
+
+func TestDialerCancel(t *testing.T) {
+	srv, client := newConnectedHosts(t)
+	setLineEchoHandler(srv, "/a")
+
+	d := &Dialer{Host: client, Protocol: "/a"}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := d.DialPeer(ctx, srv.ID())
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDialerPool(t *testing.T) {
+	srv, client := newConnectedHosts(t)
+	streams := setLineEchoHandler(srv, "/a")
+
+	pool, err := NewPool(WithMaxIdlePerPeer(1))
+	require.NoError(t, err)
+	defer pool.Close()
+	d := &Dialer{Host: client, Protocol: "/a", Pool: pool}
+
+	for i := 0; i < 3; i++ {
+		c, err := d.DialPeer(context.Background(), srv.ID())
+		require.NoError(t, err)
+		require.Equal(t, "/a hello", request(t, c, "hello"))
+		require.NoError(t, c.Close())
+		require.Equal(t, 1, pool.Len())
+	}
+	require.Equal(t, int32(1), streams.Load())
+
+	// only one idle stream is kept
+	c1, err := d.DialPeer(context.Background(), srv.ID())
+	require.NoError(t, err)
+	c2, err := d.DialPeer(context.Background(), srv.ID())
+	require.NoError(t, err)
+	require.Equal(t, "/a hello", request(t, c2, "hello"))
+	require.Equal(t, 0, pool.Len())
+	c1.Close()
+	c2.Close()
+	require.Equal(t, 1, pool.Len())
+	require.Equal(t, int32(2), streams.Load())
+
+	// broken streams aren't returned to the pool
+	c, err := d.DialPeer(context.Background(), srv.ID())
+	require.NoError(t, err)
+	c.(interface{ CloseWrite() error }).CloseWrite()
+	_, err = c.Read(make([]byte, 1))
+	require.ErrorIs(t, err, io.EOF)
+	c.Close()
+	require.Equal(t, 0, pool.Len())
+}
+
+func TestPoolIdleTimeout(t *testing.T) {
+	srv, client := newConnectedHosts(t)
+	setLineEchoHandler(srv, "/a")
+
+	pool, err := NewPool(WithIdleTimeout(50 * time.Millisecond))
+	require.NoError(t, err)
+	defer pool.Close()
+	d := &Dialer{Host: client, Protocol: "/a", Pool: pool}
+
+	c, err := d.DialPeer(context.Background(), srv.ID())
+	require.NoError(t, err)
+	require.Equal(t, "/a hello", request(t, c, "hello"))
+	c.Close()
+	require.Equal(t, 1, pool.Len())
+	require.Eventually(t, func() bool { return pool.Len() == 0 }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestConnExposesStream(t *testing.T) {
+	srv, client := newConnectedHosts(t)
+	setLineEchoHandler(srv, "/a")
+
+	pool, err := NewPool()
+	require.NoError(t, err)
+	defer pool.Close()
+	for _, d := range []*Dialer{{Host: client, Protocol: "/a"}, {Host: client, Protocol: "/a", Pool: pool}} {
+		c, err := d.DialPeer(context.Background(), srv.ID())
+		require.NoError(t, err)
+		require.Equal(t, "/a hello", request(t, c, "hello"))
+		sc, ok := c.(Conn)
+		require.True(t, ok)
+		require.Equal(t, srv.ID(), sc.RemotePeer())
+		require.Equal(t, protocol.ID("/a"), sc.Protocol())
+		require.Equal(t, srv.ID(), sc.Stream().Conn().RemotePeer())
+		c.Close()
+	}
+
+	l, err := Listen(srv, "/b")
+	require.NoError(t, err)
+	defer l.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		c, err := l.Accept()
+		if err == nil {
+			accepted <- c
+		}
+	}()
+
+	c, err := Dial(context.Background(), client, srv.ID(), "/b")
+	require.NoError(t, err)
+	defer c.Close()
+	require.Equal(t, srv.ID(), c.(Conn).RemotePeer())
+	// the stream is only opened on the listener's side once the dialer writes
+	_, err = c.Write([]byte("hello\n"))
+	require.NoError(t, err)
+
+	var lc net.Conn
+	select {
+	case lc = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the stream")
+	}
+	defer lc.Close()
+	require.Implements(t, (*Conn)(nil), lc)
+	require.Equal(t, client.ID(), lc.(Conn).RemotePeer())
+	require.Equal(t, protocol.ID("/b"), lc.(Conn).Protocol())
+}
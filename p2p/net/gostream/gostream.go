@@ -9,6 +9,13 @@
 // This means your connections will take advantage of  LibP2P's multi-routes,
 // NAT transversal and stream multiplexing.
 //
+// A Dialer can be plugged into libraries that take a dial function, like
+// net/http's Transport. It can optionally keep idle streams in a Pool to avoid
+// negotiating a new stream for every request.
+//
+// The returned net.Conns implement Conn, which exposes the underlying stream, the
+// remote peer and the negotiated protocol.
+//
 // Note that LibP2P hosts cannot dial to themselves, so there is no possibility
 // of using the same Host as server and as client.
 package gostream
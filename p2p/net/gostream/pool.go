@@ -0,0 +1,246 @@
+package gostream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+)
+
+const (
+	defaultMaxIdlePerPeer = 2
+	defaultIdleTimeout    = 90 * time.Second
+)
+
+// Pool keeps idle streams so that they can be reused by later dials to the same
+// peer and protocol, saving the protocol negotiation. It is used by a Dialer.
+//
+// Reusing streams is only safe for protocols that allow sending several requests
+// sequentially on a stream, and the application must read each response completely
+// before closing the conn. Conns are returned to the pool when closed. Conns on
+// which a read or write failed, including reading an EOF, are closed instead.
+type Pool struct {
+	maxIdlePerPeer int
+	idleTimeout    time.Duration
+
+	mu     sync.Mutex
+	closed bool
+	idle   map[poolKey][]idleStream
+	timer  *time.Timer
+}
+
+type poolKey struct {
+	p   peer.ID
+	tag protocol.ID
+}
+
+type idleStream struct {
+	s     network.Stream
+	since time.Time
+}
+
+// PoolOption configures a Pool.
+type PoolOption func(*Pool) error
+
+// WithMaxIdlePerPeer sets the maximum number of idle streams kept per peer and
+// protocol. Defaults to 2.
+func WithMaxIdlePerPeer(n int) PoolOption {
+	return func(p *Pool) error {
+		p.maxIdlePerPeer = n
+		return nil
+	}
+}
+
+// WithIdleTimeout sets the time after which idle streams are closed. Defaults to
+// 90 seconds.
+func WithIdleTimeout(d time.Duration) PoolOption {
+	return func(p *Pool) error {
+		p.idleTimeout = d
+		return nil
+	}
+}
+
+// NewPool creates a new stream pool.
+func NewPool(opts ...PoolOption) (*Pool, error) {
+	p := &Pool{
+		maxIdlePerPeer: defaultMaxIdlePerPeer,
+		idleTimeout:    defaultIdleTimeout,
+		idle:           make(map[poolKey][]idleStream),
+	}
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// get returns an idle stream to the peer, or nil if there is none.
+func (p *Pool) get(pid peer.ID, tag protocol.ID) network.Stream {
+	key := poolKey{p: pid, tag: tag}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for {
+		streams := p.idle[key]
+		if len(streams) == 0 {
+			return nil
+		}
+		// use the most recently used stream, it's the least likely to have been
+		// closed by the peer
+		is := streams[len(streams)-1]
+		if len(streams) == 1 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = streams[:len(streams)-1]
+		}
+		if is.s.Conn().IsClosed() || time.Since(is.since) > p.idleTimeout {
+			is.s.Reset()
+			continue
+		}
+		return is.s
+	}
+}
+
+// put returns a stream to the pool. It returns false if the stream wasn't added,
+// in which case the caller must close it.
+func (p *Pool) put(s network.Stream) bool {
+	key := poolKey{p: s.Conn().RemotePeer(), tag: s.Protocol()}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed || len(p.idle[key]) >= p.maxIdlePerPeer || s.Conn().IsClosed() {
+		return false
+	}
+	p.idle[key] = append(p.idle[key], idleStream{s: s, since: time.Now()})
+	if p.timer == nil {
+		p.timer = time.AfterFunc(p.idleTimeout, p.expire)
+	}
+	return true
+}
+
+// expire closes the streams that were idle for longer than the idle timeout.
+func (p *Pool) expire() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.timer = nil
+	if p.closed {
+		return
+	}
+	var next time.Time
+	for key, streams := range p.idle {
+		n := 0
+		for _, is := range streams {
+			if time.Since(is.since) >= p.idleTimeout {
+				is.s.Close()
+				continue
+			}
+			if next.IsZero() || is.since.Before(next) {
+				next = is.since
+			}
+			streams[n] = is
+			n++
+		}
+		clear(streams[n:])
+		if n == 0 {
+			delete(p.idle, key)
+		} else {
+			p.idle[key] = streams[:n]
+		}
+	}
+	if !next.IsZero() {
+		p.timer = time.AfterFunc(time.Until(next.Add(p.idleTimeout)), p.expire)
+	}
+}
+
+// Len returns the number of idle streams in the pool.
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var n int
+	for _, streams := range p.idle {
+		n += len(streams)
+	}
+	return n
+}
+
+// Close closes all idle streams. Conns closed after the pool was closed close
+// their streams.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	for _, streams := range p.idle {
+		for _, is := range streams {
+			is.s.Close()
+		}
+	}
+	p.idle = nil
+	return nil
+}
+
+func (p *Pool) newConn(s network.Stream) *pooledConn {
+	return &pooledConn{conn: conn{stream: s}, pool: p}
+}
+
+// pooledConn is a conn that returns its stream to the pool when closed.
+type pooledConn struct {
+	conn
+	pool *Pool
+
+	mu     sync.Mutex
+	broken bool
+	closed bool
+}
+
+func (c *pooledConn) Read(b []byte) (int, error) {
+	n, err := c.conn.Read(b)
+	if err != nil {
+		c.markBroken()
+	}
+	return n, err
+}
+
+func (c *pooledConn) Write(b []byte) (int, error) {
+	n, err := c.conn.Write(b)
+	if err != nil {
+		c.markBroken()
+	}
+	return n, err
+}
+
+func (c *pooledConn) markBroken() {
+	c.mu.Lock()
+	c.broken = true
+	c.mu.Unlock()
+}
+
+// Close returns the stream to the pool. If the stream can't be reused, it is
+// closed.
+func (c *pooledConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	broken := c.broken
+	c.mu.Unlock()
+
+	if !broken && c.stream.SetDeadline(time.Time{}) == nil && c.pool.put(c.stream) {
+		return nil
+	}
+	return c.stream.Close()
+}
+
+// Reset resets the stream. It isn't returned to the pool.
+func (c *pooledConn) Reset() error {
+	c.mu.Lock()
+	c.closed = true
+	c.mu.Unlock()
+	return c.stream.Reset()
+}
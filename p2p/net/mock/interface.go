@@ -26,6 +26,10 @@ type PeerOptions struct {
 
 	// gater is the ConnectionGater to use when adding a peer. If nil, no connection gater will be used.
 	gater connmgr.ConnectionGater
+
+	// ResourceManager accounts the connections and streams of the peer. If nil, no
+	// limits are applied.
+	ResourceManager network.ResourceManager
 }
 
 type Mocknet interface {
@@ -78,11 +82,18 @@ type Mocknet interface {
 }
 
 // LinkOptions are used to change aspects of the links.
-// Sorry but they dont work yet :(
 type LinkOptions struct {
 	Latency   time.Duration
 	Bandwidth float64 // in bytes-per-second
-	// we can make these values distributions down the road.
+	// Jitter is the maximum random delay added to Latency for every write. The data
+	// is still delivered in order.
+	Jitter time.Duration
+	// DialLatency is the time it takes to establish a connection over the link.
+	DialLatency time.Duration
+	// Limited marks the connections over the link as limited, like relayed
+	// connections are. Streams can only be opened on them with
+	// network.WithAllowLimitedConn.
+	Limited bool
 }
 
 // Link represents the **possibility** of a connection between
@@ -6,6 +6,7 @@ import (
 	"strconv"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
@@ -38,8 +39,10 @@ type conn struct {
 	rconn   *conn // counterpart
 	streams list.List
 	stat    network.ConnStats
+	scope   network.ConnManagementScope
 
-	closeOnce sync.Once
+	closeOnce   sync.Once
+	closeReason network.ConnCloseReason
 
 	isClosed atomic.Bool
 
@@ -51,6 +54,8 @@ func newConn(ln, rn *peernet, l *link, dir network.Direction) *conn {
 	c.local = ln.peer
 	c.remote = rn.peer
 	c.stat.Direction = dir
+	c.stat.Limited = l.opts.Limited
+	c.scope = &network.NullScope{}
 	c.id = connCounter.Add(1)
 
 	c.localAddr = ln.ps.Addrs(ln.peer)[0]
@@ -69,6 +74,20 @@ func newConn(ln, rn *peernet, l *link, dir network.Direction) *conn {
 	return c
 }
 
+// openScope opens the resource manager scope of the connection.
+func (c *conn) openScope() error {
+	scope, err := c.net.ResourceManager().OpenConnection(c.stat.Direction, false, c.remoteAddr)
+	if err != nil {
+		return err
+	}
+	if err := scope.SetPeer(c.remote); err != nil {
+		scope.Done()
+		return err
+	}
+	c.scope = scope
+	return nil
+}
+
 func (c *conn) IsClosed() bool {
 	return c.isClosed.Load()
 }
@@ -78,20 +97,53 @@ func (c *conn) ID() string {
 }
 
 func (c *conn) Close() error {
+	return c.CloseWithError(network.ConnNoError)
+}
+
+// closeWithReason closes the connection and its counterpart. The counterpart
+// observes the error code as sent by the remote peer.
+func (c *conn) closeWithReason(reason network.ConnCloseReason) {
 	c.closeOnce.Do(func() {
+		c.closeReason = reason
 		c.isClosed.Store(true)
-		go c.rconn.Close()
+		go c.rconn.closeWithReason(network.ConnCloseReason{Remote: !reason.Remote, ErrorCode: reason.ErrorCode})
 		c.teardown()
 	})
-	return nil
+}
+
+func (c *conn) CloseReason() (network.ConnCloseReason, bool) {
+	if !c.isClosed.Load() {
+		return network.ConnCloseReason{}, false
+	}
+	return c.closeReason, true
+}
+
+// Ping waits for the latency of the link in both directions, and returns it.
+func (c *conn) Ping(ctx context.Context) (time.Duration, error) {
+	if c.isClosed.Load() {
+		return 0, &network.ConnError{ErrorCode: c.closeReason.ErrorCode, Remote: c.closeReason.Remote}
+	}
+	rtt := c.link.GetLatency() + c.rconn.link.GetLatency()
+	t := time.NewTimer(rtt)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return rtt, nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
 }
 
 func (c *conn) teardown() {
+	// The streams fail with the error code the connection was closed with.
+	localErr := &network.ConnError{ErrorCode: c.closeReason.ErrorCode, Remote: c.closeReason.Remote}
+	remoteErr := &network.ConnError{ErrorCode: c.closeReason.ErrorCode, Remote: !c.closeReason.Remote}
 	for _, s := range c.allStreams() {
-		s.Reset()
+		s.(*stream).resetWith(localErr, remoteErr)
 	}
 
 	c.net.removeConn(c)
+	c.scope.Done()
 }
 
 func (c *conn) addStream(s *stream) {
@@ -125,21 +177,39 @@ func (c *conn) allStreams() []network.Stream {
 }
 
 func (c *conn) remoteOpenedStream(s *stream) {
+	scope, err := c.net.ResourceManager().OpenStream(c.remote, network.DirInbound)
+	if err != nil {
+		log.Debugf("resource manager blocked incoming stream from %s: %s", c.remote, err)
+		c.addStream(s)
+		s.ResetWithError(network.StreamResourceLimitExceeded)
+		return
+	}
+	s.scope = scope
 	c.addStream(s)
 	c.net.handleNewStream(s)
 }
 
-func (c *conn) openStream() *stream {
+func (c *conn) openStream(scope network.StreamManagementScope) *stream {
 	sl, sr := newStreamPair()
+	sl.scope = scope
 	go c.rconn.remoteOpenedStream(sr)
 	c.addStream(sl)
 	return sl
 }
 
-func (c *conn) NewStream(context.Context) (network.Stream, error) {
+func (c *conn) NewStream(ctx context.Context) (network.Stream, error) {
 	log.Debugf("Conn.NewStreamWithProtocol: %s --> %s", c.local, c.remote)
 
-	s := c.openStream()
+	if c.stat.Limited {
+		if useLimited, _ := network.GetAllowLimitedConn(ctx); !useLimited {
+			return nil, network.ErrLimitedConn
+		}
+	}
+	scope, err := c.net.ResourceManager().OpenStream(c.remote, network.DirOutbound)
+	if err != nil {
+		return nil, err
+	}
+	s := c.openStream(scope)
 	return s, nil
 }
 
@@ -183,9 +253,10 @@ func (c *conn) Stat() network.ConnStats {
 }
 
 func (c *conn) Scope() network.ConnScope {
-	return &network.NullScope{}
+	return c.scope
 }
 
-func (c *conn) CloseWithError(_ network.ConnErrorCode) error {
-	return c.Close()
+func (c *conn) CloseWithError(errCode network.ConnErrorCode) error {
+	c.closeWithReason(network.ConnCloseReason{ErrorCode: errCode})
+	return nil
 }
@@ -1,6 +1,7 @@
 package mocknet
 
 import (
+	"math/rand"
 	"sync"
 	"time"
 
@@ -80,6 +81,9 @@ func (l *link) Options() LinkOptions {
 func (l *link) GetLatency() time.Duration {
 	l.RLock()
 	defer l.RUnlock()
+	if l.opts.Jitter > 0 {
+		return l.opts.Latency + time.Duration(rand.Int63n(int64(l.opts.Jitter)))
+	}
 	return l.opts.Latency
 }
 
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
 	"github.com/TheNoobiCat/go-libp2p/core/event"
@@ -32,6 +33,8 @@ type peernet struct {
 	// connection gater to check before dialing or accepting connections. May be nil to allow all.
 	gater connmgr.ConnectionGater
 
+	rcmgr network.ResourceManager
+
 	// implement network.Network
 	streamHandler network.StreamHandler
 
@@ -53,6 +56,7 @@ func newPeernet(m *mocknet, p peer.ID, opts PeerOptions, bus event.Bus) (*peerne
 		peer:    p,
 		ps:      opts.ps,
 		gater:   opts.gater,
+		rcmgr:   opts.ResourceManager,
 		emitter: emitter,
 
 		connsByPeer: map[peer.ID]map[*conn]struct{}{},
@@ -106,11 +110,11 @@ func (pn *peernet) handleNewStream(s network.Stream) {
 
 // DialPeer attempts to establish a connection to a given peer.
 // Respects the context.
-func (pn *peernet) DialPeer(_ context.Context, p peer.ID) (network.Conn, error) {
-	return pn.connect(p)
+func (pn *peernet) DialPeer(ctx context.Context, p peer.ID) (network.Conn, error) {
+	return pn.connect(ctx, p)
 }
 
-func (pn *peernet) connect(p peer.ID) (*conn, error) {
+func (pn *peernet) connect(ctx context.Context, p peer.ID) (*conn, error) {
 	if p == pn.peer {
 		return nil, fmt.Errorf("attempted to dial self %s", p)
 	}
@@ -121,8 +125,10 @@ func (pn *peernet) connect(p peer.ID) (*conn, error) {
 	if found && len(cs) > 0 {
 		var chosen *conn
 		for c := range cs { // because cs is a map
-			chosen = c // select first
-			break
+			// prefer unlimited connections
+			if chosen == nil || (chosen.stat.Limited && !c.stat.Limited) {
+				chosen = c
+			}
 		}
 		pn.RUnlock()
 		return chosen, nil
@@ -144,15 +150,32 @@ func (pn *peernet) connect(p peer.ID) (*conn, error) {
 	// if many links found, how do we select? for now, randomly...
 	// this would be an interesting place to test logic that can measure
 	// links (network interfaces) and select properly
-	l := links[rand.Intn(len(links))]
+	l := links[rand.Intn(len(links))].(*link)
+
+	if d := l.Options().DialLatency; d > 0 {
+		t := time.NewTimer(d)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return nil, ctx.Err()
+		}
+	}
 
 	log.Debugf("%s dialing %s openingConn", pn.peer, p)
 	// create a new connection with link
-	return pn.openConn(p, l.(*link))
+	return pn.openConn(p, l)
 }
 
 func (pn *peernet) openConn(_ peer.ID, l *link) (*conn, error) {
 	lc, rc := l.newConnPair(pn)
+	if err := lc.openScope(); err != nil {
+		return nil, fmt.Errorf("%v resource manager blocked connection to %v: %w", lc.local, lc.remote, err)
+	}
+	if err := rc.openScope(); err != nil {
+		lc.scope.Done()
+		return nil, fmt.Errorf("%v resource manager blocked connection from %v: %w", rc.local, rc.remote, err)
+	}
 	addConnPair(pn, rc.net, lc, rc)
 	log.Debugf("%s opening connection to %s", pn.LocalPeer(), lc.RemotePeer())
 	abort := func() {
@@ -245,7 +268,7 @@ func (pn *peernet) addConn(c *conn) {
 
 	pn.emitter.Emit(event.EvtPeerConnectednessChanged{
 		Peer:          c.remote,
-		Connectedness: network.Connected,
+		Connectedness: pn.Connectedness(c.remote),
 	})
 }
 
@@ -275,9 +298,11 @@ func (pn *peernet) removeConn(c *conn) {
 		})
 	}()
 
+	reason, _ := c.CloseReason()
 	c.net.emitter.Emit(event.EvtPeerConnectednessChanged{
 		Peer:          c.remote,
 		Connectedness: network.NotConnected,
+		CloseReason:   &reason,
 	})
 }
 
@@ -381,19 +406,29 @@ func (pn *peernet) InterfaceListenAddresses() ([]ma.Multiaddr, error) {
 	return pn.ListenAddresses(), nil
 }
 
-// Connectedness returns a state signaling connection capabilities
-// For now only returns Connecter || NotConnected. Expand into more later.
+// Connectedness returns a state signaling connection capabilities.
+// It returns Limited if all the connections to p are limited.
 func (pn *peernet) Connectedness(p peer.ID) network.Connectedness {
 	pn.Lock()
 	defer pn.Unlock()
 
-	cs, found := pn.connsByPeer[p]
-	if found && len(cs) > 0 {
-		return network.Connected
+	connectedness := network.NotConnected
+	for c := range pn.connsByPeer[p] {
+		if !c.stat.Limited {
+			return network.Connected
+		}
+		connectedness = network.Limited
 	}
-	return network.NotConnected
+	return connectedness
+}
+
+// ConnectionQuality summarizes the connections to the given peer.
+func (pn *peernet) ConnectionQuality(p peer.ID) network.ConnectionQuality {
+	return network.NewConnectionQuality(pn.ConnsToPeer(p), pn.ps.LatencyEWMA(p), time.Now())
 }
 
+var _ network.ConnectionQualityReporter = (*peernet)(nil)
+
 // NewStream returns a new stream to given peer p.
 // If there is no connection to p, attempts to create one.
 func (pn *peernet) NewStream(ctx context.Context, p peer.ID) (network.Stream, error) {
@@ -437,7 +472,10 @@ func (pn *peernet) notifyAll(notification func(f network.Notifiee)) {
 }
 
 func (pn *peernet) ResourceManager() network.ResourceManager {
-	return &network.NullResourceManager{}
+	if pn.rcmgr == nil {
+		return &network.NullResourceManager{}
+	}
+	return pn.rcmgr
 }
 
 func (pn *peernet) CanDial(_ peer.ID, _ ma.Multiaddr) bool {
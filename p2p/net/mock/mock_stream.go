@@ -6,6 +6,7 @@ import (
 	"io"
 	"net"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -31,8 +32,12 @@ type stream struct {
 
 	writeErr error
 
+	resetMu  sync.Mutex
+	resetErr error // the error returned by the reads and writes once the stream is reset
+
 	protocol atomic.Pointer[protocol.ID]
 	stat     network.Stats
+	scope    network.StreamManagementScope
 }
 
 var ErrClosed = errors.New("stream closed")
@@ -63,6 +68,7 @@ func newStream(w *io.PipeWriter, r *io.PipeReader, dir network.Direction) *strea
 		closed:    make(chan struct{}),
 		toDeliver: make(chan *transportObject),
 		stat:      network.Stats{Direction: dir},
+		scope:     &network.NullScope{},
 	}
 
 	go s.transport()
@@ -100,10 +106,15 @@ func (s *stream) Protocol() protocol.ID {
 }
 
 func (s *stream) Stat() network.Stats {
-	return s.stat
+	stat := s.stat
+	stat.Protocol = s.Protocol()
+	return stat
 }
 
 func (s *stream) SetProtocol(proto protocol.ID) error {
+	if err := s.scope.SetProtocol(proto); err != nil {
+		return err
+	}
 	s.protocol.Store(&proto)
 	return nil
 }
@@ -129,42 +140,57 @@ func (s *stream) Close() error {
 	return s.CloseWrite()
 }
 
+// Reset resets the stream. Like the stream muxers do, the local and remote reads and
+// writes fail with network.ErrReset.
 func (s *stream) Reset() error {
-	// Cancel any pending reads/writes with an error.
-	s.write.CloseWithError(network.ErrReset)
-	s.read.CloseWithError(network.ErrReset)
-
-	select {
-	case s.reset <- struct{}{}:
-	default:
-	}
-	<-s.closed
-
+	s.resetWith(network.ErrReset, network.ErrReset)
 	// No meaningful error case here.
 	return nil
 }
 
-// ResetWithError resets the stream. It ignores the provided error code.
-// TODO: Implement error code support.
-func (s *stream) ResetWithError(_ network.StreamErrorCode) error {
-	// Cancel any pending reads/writes with an error.
+// ResetWithError resets the stream. The local reads and writes fail with a
+// *network.StreamError carrying errCode, and so do the remote ones, with Remote set.
+func (s *stream) ResetWithError(errCode network.StreamErrorCode) error {
+	s.resetWith(
+		&network.StreamError{ErrorCode: errCode},
+		&network.StreamError{ErrorCode: errCode, Remote: true},
+	)
+	// No meaningful error case here.
+	return nil
+}
 
-	s.write.CloseWithError(network.ErrReset)
-	s.read.CloseWithError(network.ErrReset)
+// resetWith resets the stream, failing the local reads and writes with localErr and
+// the remote ones with remoteErr. Only the first reset is taken into account.
+func (s *stream) resetWith(localErr, remoteErr error) {
+	s.resetMu.Lock()
+	if s.resetErr == nil {
+		s.resetErr = localErr
+		// Cancel any pending reads/writes with an error.
+		s.write.CloseWithError(remoteErr)
+		s.read.CloseWithError(remoteErr)
+	}
+	s.resetMu.Unlock()
 
 	select {
 	case s.reset <- struct{}{}:
 	default:
 	}
 	<-s.closed
+}
 
-	// No meaningful error case here.
-	return nil
+func (s *stream) getResetErr() error {
+	s.resetMu.Lock()
+	defer s.resetMu.Unlock()
+	if s.resetErr == nil {
+		return network.ErrReset
+	}
+	return s.resetErr
 }
 
 func (s *stream) teardown() {
 	// at this point, no streams are writing.
 	s.conn.removeStream(s)
+	s.scope.Done()
 
 	// Mark as closed.
 	close(s.closed)
@@ -187,7 +213,15 @@ func (s *stream) SetWriteDeadline(_ time.Time) error {
 }
 
 func (s *stream) Read(b []byte) (int, error) {
-	return s.read.Read(b)
+	n, err := s.read.Read(b)
+	if err == io.ErrClosedPipe {
+		s.resetMu.Lock()
+		if s.resetErr != nil {
+			err = s.resetErr
+		}
+		s.resetMu.Unlock()
+	}
+	return n, err
 }
 
 // transport will grab message arrival times, wait until that time, and
@@ -253,7 +287,7 @@ func (s *stream) transport() {
 				case s.reset <- struct{}{}:
 				default:
 				}
-				return network.ErrReset
+				return s.getResetErr()
 			}
 			if err := drainBuf(); err != nil {
 				return err
@@ -273,14 +307,14 @@ func (s *stream) transport() {
 		// Reset takes precedent.
 		select {
 		case <-s.reset:
-			s.writeErr = network.ErrReset
+			s.writeErr = s.getResetErr()
 			return
 		default:
 		}
 
 		select {
 		case <-s.reset:
-			s.writeErr = network.ErrReset
+			s.writeErr = s.getResetErr()
 			return
 		case <-s.close:
 			if err := drainBuf(); err != nil {
@@ -307,7 +341,7 @@ func (s *stream) transport() {
 }
 
 func (s *stream) Scope() network.StreamScope {
-	return &network.NullScope{}
+	return s.scope
 }
 
 func (s *stream) cancelWrite(err error) {
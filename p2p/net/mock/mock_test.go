@@ -685,6 +685,31 @@ func TestEventBus(t *testing.T) {
 	}
 }
 
+func TestCloseReason(t *testing.T) {
+	mn, err := FullMeshLinked(2)
+	require.NoError(t, err)
+	defer mn.Close()
+
+	h0, h1 := mn.Hosts()[0], mn.Hosts()[1]
+	c, err := mn.ConnectPeers(h0.ID(), h1.ID())
+	require.NoError(t, err)
+	rc := h1.Network().ConnsToPeer(h0.ID())[0]
+
+	_, ok := c.CloseReason()
+	require.False(t, ok)
+	require.NoError(t, c.CloseWithError(network.ConnGated))
+
+	reason, ok := c.CloseReason()
+	require.True(t, ok)
+	require.Equal(t, network.ConnCloseReason{ErrorCode: network.ConnGated}, reason)
+	require.Eventually(t, func() bool {
+		_, ok := rc.CloseReason()
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	reason, _ = rc.CloseReason()
+	require.Equal(t, network.ConnCloseReason{Remote: true, ErrorCode: network.ConnGated}, reason)
+}
+
 func TestBlockByPeerID(t *testing.T) {
 	m, gater1, host1, _, host2 := WithConnectionGaters(t)
 
@@ -749,3 +774,201 @@ func WithConnectionGaters(t *testing.T) (Mocknet, *conngater.BasicConnectionGate
 	}
 	return m, gater1, host1, gater2, host2
 }
+
+func TestStreamErrorCodes(t *testing.T) {
+	mn, err := FullMeshConnected(2)
+	require.NoError(t, err)
+	defer mn.Close()
+
+	h0, h1 := mn.Hosts()[0], mn.Hosts()[1]
+	accepted := make(chan network.Stream, 1)
+	h1.SetStreamHandler(protocol.TestingID, func(s network.Stream) { accepted <- s })
+
+	s, err := h0.NewStream(context.Background(), h1.ID(), protocol.TestingID)
+	require.NoError(t, err)
+	_, err = s.Write([]byte("ping"))
+	require.NoError(t, err)
+	rs := <-accepted
+	_, err = io.ReadFull(rs, make([]byte, 4))
+	require.NoError(t, err)
+
+	require.NoError(t, s.ResetWithError(network.StreamProtocolViolation))
+	_, err = s.Read(make([]byte, 1))
+	require.ErrorIs(t, err, &network.StreamError{ErrorCode: network.StreamProtocolViolation})
+	require.ErrorIs(t, err, network.ErrReset)
+	_, err = s.Write([]byte("ping"))
+	require.ErrorIs(t, err, &network.StreamError{ErrorCode: network.StreamProtocolViolation})
+
+	_, err = rs.Read(make([]byte, 1))
+	require.ErrorIs(t, err, &network.StreamError{ErrorCode: network.StreamProtocolViolation, Remote: true})
+}
+
+func TestStreamReset(t *testing.T) {
+	mn, err := FullMeshConnected(2)
+	require.NoError(t, err)
+	defer mn.Close()
+
+	h0, h1 := mn.Hosts()[0], mn.Hosts()[1]
+	accepted := make(chan network.Stream, 1)
+	h1.SetStreamHandler(protocol.TestingID, func(s network.Stream) { accepted <- s })
+
+	s, err := h0.NewStream(context.Background(), h1.ID(), protocol.TestingID)
+	require.NoError(t, err)
+	_, err = s.Write([]byte("ping"))
+	require.NoError(t, err)
+	rs := <-accepted
+	_, err = io.ReadFull(rs, make([]byte, 4))
+	require.NoError(t, err)
+
+	// a reset without an error code fails the reads and writes with ErrReset itself,
+	// not with a *network.StreamError
+	require.NoError(t, s.Reset())
+	_, err = s.Read(make([]byte, 1))
+	require.Equal(t, network.ErrReset, err)
+	_, err = s.Write([]byte("ping"))
+	require.Equal(t, network.ErrReset, err)
+	_, err = rs.Read(make([]byte, 1))
+	require.Equal(t, network.ErrReset, err)
+}
+
+func TestConnErrorCodes(t *testing.T) {
+	mn, err := FullMeshConnected(2)
+	require.NoError(t, err)
+	defer mn.Close()
+
+	h0, h1 := mn.Hosts()[0], mn.Hosts()[1]
+	accepted := make(chan network.Stream, 1)
+	h1.SetStreamHandler(protocol.TestingID, func(s network.Stream) { accepted <- s })
+
+	s, err := h0.NewStream(context.Background(), h1.ID(), protocol.TestingID)
+	require.NoError(t, err)
+	_, err = s.Write([]byte("ping"))
+	require.NoError(t, err)
+	rs := <-accepted
+	_, err = io.ReadFull(rs, make([]byte, 4))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Conn().CloseWithError(network.ConnGarbageCollected))
+	_, err = s.Read(make([]byte, 1))
+	require.ErrorIs(t, err, &network.ConnError{ErrorCode: network.ConnGarbageCollected})
+	_, err = rs.Read(make([]byte, 1))
+	require.ErrorIs(t, err, &network.ConnError{ErrorCode: network.ConnGarbageCollected, Remote: true})
+}
+
+func TestConnPing(t *testing.T) {
+	mn := New()
+	defer mn.Close()
+	mn.SetLinkDefaults(LinkOptions{Latency: 20 * time.Millisecond})
+	h0, err := mn.GenPeer()
+	require.NoError(t, err)
+	h1, err := mn.GenPeer()
+	require.NoError(t, err)
+	require.NoError(t, mn.LinkAll())
+
+	c, err := mn.ConnectPeers(h0.ID(), h1.ID())
+	require.NoError(t, err)
+	rtt, err := c.(network.Pinger).Ping(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 40*time.Millisecond, rtt)
+
+	require.NoError(t, c.CloseWithError(network.ConnGated))
+	_, err = c.(network.Pinger).Ping(context.Background())
+	require.ErrorIs(t, err, &network.ConnError{ErrorCode: network.ConnGated})
+}
+
+func TestLimitedLink(t *testing.T) {
+	mn := New()
+	defer mn.Close()
+	mn.SetLinkDefaults(LinkOptions{Limited: true})
+	h0, err := mn.GenPeer()
+	require.NoError(t, err)
+	h1, err := mn.GenPeer()
+	require.NoError(t, err)
+	require.NoError(t, mn.LinkAll())
+	h1.SetStreamHandler(protocol.TestingID, func(s network.Stream) { s.Close() })
+
+	c, err := mn.ConnectPeers(h0.ID(), h1.ID())
+	require.NoError(t, err)
+	require.True(t, c.Stat().Limited)
+	require.Equal(t, network.Limited, h0.Network().Connectedness(h1.ID()))
+	require.True(t, h0.Network().(network.ConnectionQualityReporter).ConnectionQuality(h1.ID()).Limited)
+
+	_, err = h0.NewStream(context.Background(), h1.ID(), protocol.TestingID)
+	require.ErrorIs(t, err, network.ErrLimitedConn)
+	s, err := h0.NewStream(network.WithAllowLimitedConn(context.Background(), "test"), h1.ID(), protocol.TestingID)
+	require.NoError(t, err)
+	s.Close()
+}
+
+func TestDialLatency(t *testing.T) {
+	mn := New()
+	defer mn.Close()
+	mn.SetLinkDefaults(LinkOptions{DialLatency: 100 * time.Millisecond})
+	h0, err := mn.GenPeer()
+	require.NoError(t, err)
+	h1, err := mn.GenPeer()
+	require.NoError(t, err)
+	require.NoError(t, mn.LinkAll())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = h0.Network().DialPeer(ctx, h1.ID())
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	start := time.Now()
+	_, err = h0.Network().DialPeer(context.Background(), h1.ID())
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+// blockingResourceManager blocks the connections and streams once the limit is reached.
+type blockingResourceManager struct {
+	network.NullResourceManager
+	mx                   sync.Mutex
+	conns, streams       int
+	maxConns, maxStreams int
+}
+
+func (r *blockingResourceManager) OpenConnection(network.Direction, bool, ma.Multiaddr) (network.ConnManagementScope, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.conns >= r.maxConns {
+		return nil, network.ErrResourceLimitExceeded
+	}
+	r.conns++
+	return &network.NullScope{}, nil
+}
+
+func (r *blockingResourceManager) OpenStream(peer.ID, network.Direction) (network.StreamManagementScope, error) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if r.streams >= r.maxStreams {
+		return nil, network.ErrResourceLimitExceeded
+	}
+	r.streams++
+	return &network.NullScope{}, nil
+}
+
+func TestResourceManager(t *testing.T) {
+	mn := New()
+	defer mn.Close()
+	h0, err := mn.GenPeer()
+	require.NoError(t, err)
+	h1, err := mn.GenPeerWithOptions(PeerOptions{ResourceManager: &blockingResourceManager{maxConns: 1}})
+	require.NoError(t, err)
+	h2, err := mn.GenPeerWithOptions(PeerOptions{ResourceManager: &blockingResourceManager{}})
+	require.NoError(t, err)
+	require.NoError(t, mn.LinkAll())
+
+	_, err = mn.ConnectPeers(h0.ID(), h2.ID())
+	require.ErrorIs(t, err, network.ErrResourceLimitExceeded)
+
+	// h1 accepts the connection, but not the streams
+	_, err = mn.ConnectPeers(h0.ID(), h1.ID())
+	require.NoError(t, err)
+	h1.SetStreamHandler(protocol.TestingID, func(s network.Stream) { s.Close() })
+	s, err := h0.Network().NewStream(context.Background(), h1.ID())
+	require.NoError(t, err)
+	_, err = s.Read(make([]byte, 1))
+	require.ErrorIs(t, err, &network.StreamError{ErrorCode: network.StreamResourceLimitExceeded, Remote: true})
+}
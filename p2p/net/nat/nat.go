@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/netip"
 	"sync"
 	"sync/atomic"
@@ -11,12 +12,17 @@ import (
 
 	logging "github.com/ipfs/go-log/v2"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/nat/internal/nat"
 )
 
 // ErrNoMapping signals no mapping exists for an address
 var ErrNoMapping = errors.New("mapping not established")
 
+// ErrPinholesNotSupported is returned when adding a pinhole with a backend that
+// doesn't support them.
+var ErrPinholesNotSupported = errors.New("backend doesn't support pinholes")
+
 var log = logging.Logger("nat")
 
 // MappingDuration is a default port mapping duration.
@@ -26,9 +32,45 @@ const MappingDuration = time.Minute
 // CacheTime is the time a mapping will cache an external address for
 const CacheTime = 15 * time.Second
 
+// Backend creates port mappings on a NAT device. UPnP and NAT-PMP gateways are found
+// with DiscoverNAT, other backends, like PCP, a static configuration or a cloud NAT
+// API, are used with NewNAT.
+type Backend interface {
+	// Type returns the kind of port mapping service used.
+	Type() string
+	// GetExternalAddress returns the external address of the NAT.
+	GetExternalAddress() (addr net.IP, err error)
+	// AddPortMapping maps a port on the local host to an external port, for timeout.
+	// A zero timeout requests a permanent mapping.
+	AddPortMapping(ctx context.Context, protocol string, internalPort int, description string, timeout time.Duration) (mappedExternalPort int, err error)
+	// DeletePortMapping removes a port mapping.
+	DeletePortMapping(ctx context.Context, protocol string, internalPort int) error
+}
+
+// PinholeBackend is a Backend that can also open IPv6 firewall pinholes, allowing
+// inbound connections to a global IPv6 address of the host without translation.
+type PinholeBackend interface {
+	Backend
+	// AddPinhole opens a pinhole to addr, for timeout. A zero timeout requests a
+	// permanent pinhole.
+	AddPinhole(ctx context.Context, protocol string, addr netip.AddrPort, timeout time.Duration) error
+	// DeletePinhole closes a pinhole.
+	DeletePinhole(ctx context.Context, protocol string, addr netip.AddrPort) error
+}
+
+var _ Backend = nat.NAT(nil)
+
 type entry struct {
 	protocol string
 	port     int
+	// addr is the internal address of pinholes. It's invalid for port mappings.
+	addr netip.Addr
+}
+
+type mapping struct {
+	// extPort is the external port, 0 if the mapping isn't established.
+	extPort int
+	err     error
 }
 
 // so we can mock it in tests
@@ -40,11 +82,6 @@ func DiscoverNAT(ctx context.Context) (*NAT, error) {
 	if err != nil {
 		return nil, err
 	}
-	var extAddr netip.Addr
-	extIP, err := natInstance.GetExternalAddress()
-	if err == nil {
-		extAddr, _ = netip.AddrFromSlice(extIP)
-	}
 
 	// Log the device addr.
 	addr, err := natInstance.GetDeviceAddress()
@@ -53,21 +90,25 @@ func DiscoverNAT(ctx context.Context) (*NAT, error) {
 	} else {
 		log.Debug("DiscoverGateway address:", addr)
 	}
+	return NewNAT(natInstance), nil
+}
 
+// NewNAT returns a NAT managing port mappings with backend b.
+func NewNAT(b Backend) *NAT {
 	ctx, cancel := context.WithCancel(context.Background())
 	nat := &NAT{
-		nat:       natInstance,
-		mappings:  make(map[entry]int),
+		nat:       b,
+		mappings:  make(map[entry]mapping),
 		ctx:       ctx,
 		ctxCancel: cancel,
 	}
-	nat.extAddr.Store(&extAddr)
+	nat.updateExternalAddr()
 	nat.refCount.Add(1)
 	go func() {
 		defer nat.refCount.Done()
 		nat.background()
 	}()
-	return nat, nil
+	return nat
 }
 
 // NAT is an object that manages address port mappings in
@@ -76,7 +117,7 @@ func DiscoverNAT(ctx context.Context) (*NAT, error) {
 // and keep an up-to-date list of all the external addresses.
 type NAT struct {
 	natmu sync.Mutex
-	nat   nat.NAT
+	nat   Backend
 	// External IP of the NAT. Will be renewed periodically (every CacheTime).
 	extAddr atomic.Pointer[netip.Addr]
 
@@ -86,7 +127,12 @@ type NAT struct {
 
 	mappingmu sync.RWMutex // guards mappings
 	closed    bool
-	mappings  map[entry]int
+	mappings  map[entry]mapping
+}
+
+// Type returns the kind of port mapping service used.
+func (nat *NAT) Type() string {
+	return nat.nat.Type()
 }
 
 // Close shuts down all port mappings. NAT can no longer be used.
@@ -107,12 +153,47 @@ func (nat *NAT) GetMapping(protocol string, port int) (addr netip.AddrPort, foun
 	if !nat.extAddr.Load().IsValid() {
 		return netip.AddrPort{}, false
 	}
-	extPort, found := nat.mappings[entry{protocol: protocol, port: port}]
+	m, found := nat.mappings[entry{protocol: protocol, port: port}]
 	// The mapping may have an invalid port.
-	if !found || extPort == 0 {
+	if !found || m.extPort == 0 {
 		return netip.AddrPort{}, false
 	}
-	return netip.AddrPortFrom(*nat.extAddr.Load(), uint16(extPort)), true
+	return netip.AddrPortFrom(*nat.extAddr.Load(), uint16(m.extPort)), true
+}
+
+// HasPinhole returns whether a pinhole to addr is established.
+func (nat *NAT) HasPinhole(protocol string, addr netip.AddrPort) bool {
+	nat.mappingmu.Lock()
+	defer nat.mappingmu.Unlock()
+
+	m, found := nat.mappings[entry{protocol: protocol, port: int(addr.Port()), addr: addr.Addr()}]
+	return found && m.extPort != 0
+}
+
+// Mappings returns the status of the port mappings and pinholes.
+func (nat *NAT) Mappings() []event.NATMapping {
+	nat.mappingmu.Lock()
+	defer nat.mappingmu.Unlock()
+
+	extAddr := nat.extAddr.Load().Unmap()
+	res := make([]event.NATMapping, 0, len(nat.mappings))
+	for e, m := range nat.mappings {
+		s := event.NATMapping{Protocol: e.protocol, Err: m.err}
+		if e.addr.IsValid() {
+			s.Pinhole = true
+			s.Internal = netip.AddrPortFrom(e.addr, uint16(e.port))
+			if m.extPort != 0 {
+				s.External = s.Internal
+			}
+		} else {
+			s.Internal = netip.AddrPortFrom(netip.Addr{}, uint16(e.port))
+			if m.extPort != 0 && extAddr.IsValid() {
+				s.External = netip.AddrPortFrom(extAddr, uint16(m.extPort))
+			}
+		}
+		res = append(res, s)
+	}
+	return res
 }
 
 // AddMapping attempts to construct a mapping on protocol and internal port.
@@ -121,10 +202,26 @@ func (nat *NAT) GetMapping(protocol string, port int) (addr netip.AddrPort, foun
 // May not succeed, and mappings may change over time;
 // NAT devices may not respect our port requests, and even lie.
 func (nat *NAT) AddMapping(ctx context.Context, protocol string, port int) error {
-	switch protocol {
+	return nat.add(ctx, entry{protocol: protocol, port: port})
+}
+
+// AddPinhole attempts to open an IPv6 firewall pinhole to addr. Like AddMapping, it
+// blocks until the pinhole was opened, and it periodically renews the pinhole.
+func (nat *NAT) AddPinhole(ctx context.Context, protocol string, addr netip.AddrPort) error {
+	if _, ok := nat.nat.(PinholeBackend); !ok {
+		return ErrPinholesNotSupported
+	}
+	if !addr.Addr().Is6() || addr.Addr().Is4In6() {
+		return fmt.Errorf("invalid pinhole address: %s", addr)
+	}
+	return nat.add(ctx, entry{protocol: protocol, port: int(addr.Port()), addr: addr.Addr()})
+}
+
+func (nat *NAT) add(ctx context.Context, e entry) error {
+	switch e.protocol {
 	case "tcp", "udp":
 	default:
-		return fmt.Errorf("invalid protocol: %s", protocol)
+		return fmt.Errorf("invalid protocol: %s", e.protocol)
 	}
 
 	nat.mappingmu.Lock()
@@ -136,30 +233,43 @@ func (nat *NAT) AddMapping(ctx context.Context, protocol string, port int) error
 
 	// do it once synchronously, so first mapping is done right away, and before exiting,
 	// allowing users -- in the optimistic case -- to use results right after.
-	extPort := nat.establishMapping(ctx, protocol, port)
+	extPort, err := nat.establishMapping(ctx, e)
+	if err == nil && !nat.extAddr.Load().IsValid() {
+		// Some backends only learn the external address when creating a mapping.
+		nat.updateExternalAddr()
+	}
 	// Don't validate the mapping here, we refresh the mappings based on this map.
 	// We can try getting a port again in case it succeeds. In the worst case,
 	// this is one extra LAN request every few minutes.
-	nat.mappings[entry{protocol: protocol, port: port}] = extPort
+	nat.mappings[e] = mapping{extPort: extPort, err: err}
 	return nil
 }
 
 // RemoveMapping removes a port mapping.
 // It blocks until the NAT has removed the mapping.
 func (nat *NAT) RemoveMapping(ctx context.Context, protocol string, port int) error {
+	return nat.remove(ctx, entry{protocol: protocol, port: port})
+}
+
+// RemovePinhole closes a pinhole.
+// It blocks until the NAT has closed the pinhole.
+func (nat *NAT) RemovePinhole(ctx context.Context, protocol string, addr netip.AddrPort) error {
+	return nat.remove(ctx, entry{protocol: protocol, port: int(addr.Port()), addr: addr.Addr()})
+}
+
+func (nat *NAT) remove(ctx context.Context, e entry) error {
 	nat.mappingmu.Lock()
 	defer nat.mappingmu.Unlock()
 
-	switch protocol {
+	switch e.protocol {
 	case "tcp", "udp":
-		e := entry{protocol: protocol, port: port}
 		if _, ok := nat.mappings[e]; ok {
 			delete(nat.mappings, e)
-			return nat.nat.DeletePortMapping(ctx, protocol, port)
+			return nat.deleteMapping(ctx, e)
 		}
 		return errors.New("unknown mapping")
 	default:
-		return fmt.Errorf("invalid protocol: %s", protocol)
+		return fmt.Errorf("invalid protocol: %s", e.protocol)
 	}
 }
 
@@ -174,7 +284,7 @@ func (nat *NAT) background() {
 	defer t.Stop()
 
 	var in []entry
-	var out []int // port numbers
+	var out []mapping
 	for {
 		select {
 		case now := <-t.C:
@@ -189,7 +299,8 @@ func (nat *NAT) background() {
 				// Establishing the mapping involves network requests.
 				// Don't hold the mutex, just save the ports.
 				for _, e := range in {
-					out = append(out, nat.establishMapping(nat.ctx, e.protocol, e.port))
+					extPort, err := nat.establishMapping(nat.ctx, e)
+					out = append(out, mapping{extPort: extPort, err: err})
 				}
 				nat.mappingmu.Lock()
 				for i, p := range in {
@@ -202,12 +313,7 @@ func (nat *NAT) background() {
 				nextMappingUpdate = time.Now().Add(mappingUpdate)
 			}
 			if now.After(nextAddrUpdate) {
-				var extAddr netip.Addr
-				extIP, err := nat.nat.GetExternalAddress()
-				if err == nil {
-					extAddr, _ = netip.AddrFromSlice(extIP)
-				}
-				nat.extAddr.Store(&extAddr)
+				nat.updateExternalAddr()
 				nextAddrUpdate = time.Now().Add(CacheTime)
 			}
 			t.Reset(time.Until(minTime(nextAddrUpdate, nextMappingUpdate)))
@@ -217,7 +323,7 @@ func (nat *NAT) background() {
 			defer cancel()
 			for e := range nat.mappings {
 				delete(nat.mappings, e)
-				nat.nat.DeletePortMapping(ctx, e.protocol, e.port)
+				nat.deleteMapping(ctx, e)
 			}
 			nat.mappingmu.Unlock()
 			return
@@ -225,32 +331,66 @@ func (nat *NAT) background() {
 	}
 }
 
-func (nat *NAT) establishMapping(ctx context.Context, protocol string, internalPort int) (externalPort int) {
-	log.Debugf("Attempting port map: %s/%d", protocol, internalPort)
+func (nat *NAT) updateExternalAddr() {
+	var extAddr netip.Addr
+	extIP, err := nat.nat.GetExternalAddress()
+	if err == nil {
+		extAddr, _ = netip.AddrFromSlice(extIP)
+	}
+	nat.extAddr.Store(&extAddr)
+}
+
+func (nat *NAT) establishMapping(ctx context.Context, e entry) (externalPort int, err error) {
+	if e.addr.IsValid() {
+		return nat.establishPinhole(ctx, e)
+	}
+	log.Debugf("Attempting port map: %s/%d", e.protocol, e.port)
 	const comment = "libp2p"
 
 	nat.natmu.Lock()
-	var err error
-	externalPort, err = nat.nat.AddPortMapping(ctx, protocol, internalPort, comment, MappingDuration)
+	externalPort, err = nat.nat.AddPortMapping(ctx, e.protocol, e.port, comment, MappingDuration)
 	if err != nil {
 		// Some hardware does not support mappings with timeout, so try that
-		externalPort, err = nat.nat.AddPortMapping(ctx, protocol, internalPort, comment, 0)
+		externalPort, err = nat.nat.AddPortMapping(ctx, e.protocol, e.port, comment, 0)
 	}
 	nat.natmu.Unlock()
 
 	if err != nil || externalPort == 0 {
 		if err != nil {
-			log.Warnf("NAT port mapping failed: protocol=%s internal_port=%d error=%q", protocol, internalPort, err)
+			log.Warnf("NAT port mapping failed: protocol=%s internal_port=%d error=%q", e.protocol, e.port, err)
 		} else {
-			log.Warnf("NAT port mapping failed: protocol=%s internal_port=%d external_port=0", protocol, internalPort)
+			log.Warnf("NAT port mapping failed: protocol=%s internal_port=%d external_port=0", e.protocol, e.port)
+			err = errors.New("invalid external port 0")
 		}
 		// we do not close if the mapping failed,
 		// because it may work again next time.
-		return 0
+		return 0, err
 	}
 
-	log.Debugf("NAT Mapping: %d --> %d (%s)", externalPort, internalPort, protocol)
-	return externalPort
+	log.Debugf("NAT Mapping: %d --> %d (%s)", externalPort, e.port, e.protocol)
+	return externalPort, nil
+}
+
+func (nat *NAT) establishPinhole(ctx context.Context, e entry) (int, error) {
+	addr := netip.AddrPortFrom(e.addr, uint16(e.port))
+	log.Debugf("Attempting pinhole: %s/%s", e.protocol, addr)
+
+	nat.natmu.Lock()
+	err := nat.nat.(PinholeBackend).AddPinhole(ctx, e.protocol, addr, MappingDuration)
+	nat.natmu.Unlock()
+	if err != nil {
+		log.Warnf("NAT pinhole failed: protocol=%s addr=%s error=%q", e.protocol, addr, err)
+		return 0, err
+	}
+	log.Debugf("NAT pinhole: %s (%s)", addr, e.protocol)
+	return e.port, nil
+}
+
+func (nat *NAT) deleteMapping(ctx context.Context, e entry) error {
+	if e.addr.IsValid() {
+		return nat.nat.(PinholeBackend).DeletePinhole(ctx, e.protocol, netip.AddrPortFrom(e.addr, uint16(e.port)))
+	}
+	return nat.nat.DeletePortMapping(ctx, e.protocol, e.port)
 }
 
 func minTime(a, b time.Time) time.Time {
@@ -82,3 +82,34 @@ func TestAddMappingInvalidPort(t *testing.T) {
 	_, found := nat.GetMapping("tcp", 10000)
 	require.False(t, found, "didn't expect a port mapping for invalid nat-ed port")
 }
+
+func TestStaticBackend(t *testing.T) {
+	extAddr := netip.MustParseAddr("1.2.3.4")
+	nat := NewNAT(NewStaticBackend(extAddr, StaticMapping{Protocol: "udp", InternalPort: 4001, ExternalPort: 14001}))
+	defer nat.Close()
+	require.Equal(t, "static", nat.Type())
+
+	require.NoError(t, nat.AddMapping(context.Background(), "udp", 4001))
+	require.NoError(t, nat.AddMapping(context.Background(), "tcp", 4001))
+	mapped, found := nat.GetMapping("udp", 4001)
+	require.True(t, found)
+	require.Equal(t, netip.AddrPortFrom(extAddr, 14001), mapped)
+	_, found = nat.GetMapping("tcp", 4001)
+	require.False(t, found)
+
+	mappings := nat.Mappings()
+	require.Len(t, mappings, 2)
+	for _, m := range mappings {
+		require.False(t, m.Pinhole)
+		require.Equal(t, uint16(4001), m.Internal.Port())
+		if m.Protocol == "udp" {
+			require.NoError(t, m.Err)
+			require.Equal(t, netip.AddrPortFrom(extAddr, 14001), m.External)
+		} else {
+			require.Error(t, m.Err)
+			require.False(t, m.External.IsValid())
+		}
+	}
+
+	require.ErrorIs(t, nat.AddPinhole(context.Background(), "udp", netip.MustParseAddrPort("[2001:db8::1]:4001")), ErrPinholesNotSupported)
+}
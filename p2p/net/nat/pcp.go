@@ -0,0 +1,386 @@
+package nat
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/libp2p/go-netroute"
+)
+
+// PCPPort is the port PCP servers listen on.
+const PCPPort = 5351
+
+const (
+	pcpVersion           = 2
+	pcpOpAnnounce        = 0
+	pcpOpMap             = 1
+	pcpResponseBit       = 0x80
+	pcpHeaderLen         = 24
+	pcpMapLen            = 36
+	pcpMaxPacketLen      = 1100
+	pcpAttempts          = 3
+	pcpAttemptTimeout    = time.Second
+	pcpPermanentLifetime = math.MaxUint32
+)
+
+var pcpResultCodes = [...]string{
+	"SUCCESS",
+	"UNSUPP_VERSION",
+	"NOT_AUTHORIZED",
+	"MALFORMED_REQUEST",
+	"UNSUPP_OPCODE",
+	"UNSUPP_OPTION",
+	"MALFORMED_OPTION",
+	"NETWORK_FAILURE",
+	"NO_RESOURCES",
+	"UNSUPP_PROTOCOL",
+	"USER_EX_QUOTA",
+	"CANNOT_PROVIDE_EXTERNAL",
+	"ADDRESS_MISMATCH",
+	"EXCESSIVE_REMOTE_PEERS",
+}
+
+// PCPError is a result code, other than SUCCESS, returned by a PCP server.
+type PCPError uint8
+
+func (e PCPError) Error() string {
+	if int(e) < len(pcpResultCodes) {
+		return "pcp: " + pcpResultCodes[e]
+	}
+	return fmt.Sprintf("pcp: result code %d", uint8(e))
+}
+
+type pcpKey struct {
+	protocol string
+	// addr is the internal address of pinholes. It's invalid for port mappings.
+	addr netip.Addr
+	port int
+}
+
+type pcpMapping struct {
+	nonce   [12]byte
+	extPort int
+}
+
+// PCPBackend is a Backend creating port mappings and IPv6 pinholes with the Port
+// Control Protocol (RFC 6887), supported by most recent home routers.
+type PCPBackend struct {
+	// server4 and server6 are the PCP servers used for IPv4 port mappings and IPv6
+	// pinholes. Either may be invalid.
+	server4 netip.AddrPort
+	server6 netip.AddrPort
+
+	mx       sync.Mutex
+	extAddr  netip.Addr
+	mappings map[pcpKey]*pcpMapping
+}
+
+var _ PinholeBackend = (*PCPBackend)(nil)
+
+// NewPCPBackend returns a PCPBackend using the PCP servers listed, at most an IPv4
+// one, used for port mappings, and an IPv6 one, used for pinholes.
+func NewPCPBackend(servers ...netip.AddrPort) (*PCPBackend, error) {
+	b := &PCPBackend{mappings: make(map[pcpKey]*pcpMapping)}
+	for _, s := range servers {
+		s = netip.AddrPortFrom(s.Addr().Unmap(), s.Port())
+		switch {
+		case !s.IsValid():
+			return nil, fmt.Errorf("invalid PCP server: %s", s)
+		case s.Addr().Is4() && !b.server4.IsValid():
+			b.server4 = s
+		case s.Addr().Is6() && !b.server6.IsValid():
+			b.server6 = s
+		default:
+			return nil, fmt.Errorf("duplicate PCP server: %s", s)
+		}
+	}
+	if !b.server4.IsValid() && !b.server6.IsValid() {
+		return nil, errors.New("no PCP server")
+	}
+	return b, nil
+}
+
+// DiscoverPCP returns a PCPBackend using the default IPv4 and IPv6 gateways that
+// answer PCP requests.
+func DiscoverPCP(ctx context.Context) (*PCPBackend, error) {
+	router, err := netroute.New()
+	if err != nil {
+		return nil, err
+	}
+	var servers []netip.AddrPort
+	var errs []error
+	for _, dst := range []net.IP{net.IPv4zero, net.IPv6unspecified} {
+		iface, gw, _, err := router.Route(dst)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		addr, ok := netip.AddrFromSlice(gw)
+		if !ok {
+			continue
+		}
+		addr = addr.Unmap()
+		if addr.Is6() && addr.IsLinkLocalUnicast() && iface != nil {
+			addr = addr.WithZone(iface.Name)
+		}
+		server := netip.AddrPortFrom(addr, PCPPort)
+		if err := pcpAnnounce(ctx, server); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", server, err))
+			continue
+		}
+		servers = append(servers, server)
+	}
+	if len(servers) == 0 {
+		return nil, fmt.Errorf("no PCP server found: %w", errors.Join(errs...))
+	}
+	return NewPCPBackend(servers...)
+}
+
+// PCP is DiscoverPCP returning a Backend, so that it can be passed to
+// libp2p.NATPortMapBackend.
+func PCP(ctx context.Context) (Backend, error) {
+	b, err := DiscoverPCP(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// pcpAnnounce sends an ANNOUNCE request to server, checking that it speaks PCP.
+func pcpAnnounce(ctx context.Context, server netip.AddrPort) error {
+	conn, err := net.DialUDP("udp", nil, net.UDPAddrFromAddrPort(server))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	req := pcpHeader(conn, pcpOpAnnounce, 0)
+	_, err = pcpRoundTrip(ctx, conn, req, func(resp []byte) bool { return resp[1] == pcpResponseBit|pcpOpAnnounce })
+	return err
+}
+
+func (b *PCPBackend) Type() string {
+	return "PCP"
+}
+
+// GetExternalAddress returns the external IPv4 address assigned by the server to the
+// last port mapping.
+func (b *PCPBackend) GetExternalAddress() (net.IP, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if !b.extAddr.IsValid() {
+		return nil, errors.New("no external address known yet")
+	}
+	return b.extAddr.AsSlice(), nil
+}
+
+func (b *PCPBackend) AddPortMapping(ctx context.Context, protocol string, internalPort int, _ string, timeout time.Duration) (int, error) {
+	if !b.server4.IsValid() {
+		return 0, errors.New("no IPv4 PCP server")
+	}
+	key := pcpKey{protocol: protocol, port: internalPort}
+	ext, port, err := b.doMap(ctx, b.server4, netip.Addr{}, key, pcpLifetime(timeout), netip.IPv4Unspecified())
+	if err != nil {
+		return 0, err
+	}
+	b.mx.Lock()
+	b.extAddr = ext
+	b.mx.Unlock()
+	return port, nil
+}
+
+func (b *PCPBackend) DeletePortMapping(ctx context.Context, protocol string, internalPort int) error {
+	if !b.server4.IsValid() {
+		return errors.New("no IPv4 PCP server")
+	}
+	return b.deleteMapping(ctx, b.server4, netip.Addr{}, pcpKey{protocol: protocol, port: internalPort})
+}
+
+// AddPinhole opens a pinhole by requesting a mapping of addr to itself, from addr.
+func (b *PCPBackend) AddPinhole(ctx context.Context, protocol string, addr netip.AddrPort, timeout time.Duration) error {
+	if !b.server6.IsValid() {
+		return errors.New("no IPv6 PCP server")
+	}
+	key := pcpKey{protocol: protocol, addr: addr.Addr(), port: int(addr.Port())}
+	ext, port, err := b.doMap(ctx, b.server6, addr.Addr(), key, pcpLifetime(timeout), addr.Addr())
+	if err != nil {
+		return err
+	}
+	if ext != addr.Addr() || port != int(addr.Port()) {
+		// The server created a mapping instead of a pinhole.
+		b.deleteMapping(ctx, b.server6, addr.Addr(), key)
+		return fmt.Errorf("pcp: server mapped pinhole %s to %s", addr, netip.AddrPortFrom(ext, uint16(port)))
+	}
+	return nil
+}
+
+func (b *PCPBackend) DeletePinhole(ctx context.Context, protocol string, addr netip.AddrPort) error {
+	if !b.server6.IsValid() {
+		return errors.New("no IPv6 PCP server")
+	}
+	return b.deleteMapping(ctx, b.server6, addr.Addr(), pcpKey{protocol: protocol, addr: addr.Addr(), port: int(addr.Port())})
+}
+
+func (b *PCPBackend) deleteMapping(ctx context.Context, server netip.AddrPort, local netip.Addr, key pcpKey) error {
+	b.mx.Lock()
+	_, ok := b.mappings[key]
+	b.mx.Unlock()
+	if !ok {
+		return nil
+	}
+	_, _, err := b.doMap(ctx, server, local, key, 0, netip.Addr{})
+	b.mx.Lock()
+	delete(b.mappings, key)
+	b.mx.Unlock()
+	return err
+}
+
+// doMap sends a MAP request for key to server, from local if valid, and returns the
+// assigned external address and port. A zero lifetime deletes the mapping.
+func (b *PCPBackend) doMap(ctx context.Context, server netip.AddrPort, local netip.Addr, key pcpKey, lifetime uint32, suggestedAddr netip.Addr) (netip.Addr, int, error) {
+	var proto byte
+	switch key.protocol {
+	case "tcp":
+		proto = 6
+	case "udp":
+		proto = 17
+	default:
+		return netip.Addr{}, 0, fmt.Errorf("invalid protocol: %s", key.protocol)
+	}
+
+	b.mx.Lock()
+	m, ok := b.mappings[key]
+	if !ok {
+		m = &pcpMapping{}
+		rand.Read(m.nonce[:])
+		b.mappings[key] = m
+	}
+	nonce := m.nonce
+	suggestedPort := m.extPort
+	b.mx.Unlock()
+	if key.addr.IsValid() {
+		suggestedPort = key.port
+	}
+
+	var laddr *net.UDPAddr
+	if local.IsValid() {
+		laddr = net.UDPAddrFromAddrPort(netip.AddrPortFrom(local, 0))
+	}
+	conn, err := net.DialUDP("udp", laddr, net.UDPAddrFromAddrPort(server))
+	if err != nil {
+		return netip.Addr{}, 0, err
+	}
+	defer conn.Close()
+
+	req := pcpHeader(conn, pcpOpMap, lifetime)
+	req = append(req, nonce[:]...)
+	req = append(req, proto, 0, 0, 0)
+	req = binary.BigEndian.AppendUint16(req, uint16(key.port))
+	req = binary.BigEndian.AppendUint16(req, uint16(suggestedPort))
+	if !suggestedAddr.IsValid() {
+		suggestedAddr = netip.IPv6Unspecified()
+	}
+	s16 := suggestedAddr.As16()
+	req = append(req, s16[:]...)
+
+	resp, err := pcpRoundTrip(ctx, conn, req, func(resp []byte) bool {
+		return resp[1] == pcpResponseBit|pcpOpMap &&
+			len(resp) >= pcpHeaderLen+pcpMapLen &&
+			[12]byte(resp[pcpHeaderLen:pcpHeaderLen+12]) == nonce &&
+			resp[pcpHeaderLen+12] == proto &&
+			binary.BigEndian.Uint16(resp[pcpHeaderLen+16:]) == uint16(key.port)
+	})
+	if err != nil {
+		return netip.Addr{}, 0, err
+	}
+	extPort := int(binary.BigEndian.Uint16(resp[pcpHeaderLen+18:]))
+	extAddr := netip.AddrFrom16([16]byte(resp[pcpHeaderLen+20 : pcpHeaderLen+36])).Unmap()
+	if lifetime > 0 {
+		b.mx.Lock()
+		m.extPort = extPort
+		b.mx.Unlock()
+	}
+	return extAddr, extPort, nil
+}
+
+// pcpHeader returns the common request header, for a request sent on conn.
+func pcpHeader(conn *net.UDPConn, opcode byte, lifetime uint32) []byte {
+	req := make([]byte, 0, pcpHeaderLen+pcpMapLen)
+	req = append(req, pcpVersion, opcode, 0, 0)
+	req = binary.BigEndian.AppendUint32(req, lifetime)
+	local := conn.LocalAddr().(*net.UDPAddr).AddrPort().Addr()
+	if local.Is4() {
+		local = netip.AddrFrom16(local.As16())
+	}
+	l16 := local.As16()
+	return append(req, l16[:]...)
+}
+
+// pcpRoundTrip sends req on conn until it receives a response accepted by match,
+// and returns it.
+func pcpRoundTrip(ctx context.Context, conn *net.UDPConn, req []byte, match func([]byte) bool) ([]byte, error) {
+	buf := make([]byte, pcpMaxPacketLen)
+	err := errors.New("pcp: no response")
+	for i := 0; i < pcpAttempts; i++ {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		deadline := time.Now().Add(pcpAttemptTimeout)
+		if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+			deadline = d
+		}
+		conn.SetReadDeadline(deadline)
+		for {
+			n, rerr := conn.Read(buf)
+			if rerr != nil {
+				var nerr net.Error
+				if errors.As(rerr, &nerr) && nerr.Timeout() {
+					break
+				}
+				// ICMP port unreachable, the gateway doesn't speak PCP.
+				return nil, rerr
+			}
+			resp := buf[:n]
+			if n < pcpHeaderLen || resp[1]&pcpResponseBit == 0 {
+				continue
+			}
+			if resp[0] != pcpVersion {
+				return nil, fmt.Errorf("pcp: unsupported version %d", resp[0])
+			}
+			if code := resp[3]; code != 0 {
+				if resp[1] == pcpResponseBit|req[1] {
+					return nil, PCPError(code)
+				}
+				continue
+			}
+			if match(resp) {
+				return resp, nil
+			}
+		}
+	}
+	return nil, err
+}
+
+func pcpLifetime(timeout time.Duration) uint32 {
+	if timeout <= 0 {
+		return pcpPermanentLifetime
+	}
+	s := timeout / time.Second
+	if s < 1 {
+		return 1
+	}
+	if s > math.MaxUint32 {
+		return math.MaxUint32
+	}
+	return uint32(s)
+}
@@ -0,0 +1,156 @@
+package nat
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"net/netip"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakePCPServer answers PCP requests, mapping internal ports to extPort on extAddr
+// and opening pinholes.
+type fakePCPServer struct {
+	conn    *net.UDPConn
+	extAddr netip.Addr
+	extPort uint16
+	result  byte
+
+	mx       sync.Mutex
+	mappings map[[12]byte]uint32 // nonce -> lifetime
+}
+
+// result is the result code of every response.
+func newFakePCPServer(t *testing.T, network string, laddr string, result byte) *fakePCPServer {
+	t.Helper()
+	conn, err := net.ListenUDP(network, net.UDPAddrFromAddrPort(netip.MustParseAddrPort(laddr)))
+	require.NoError(t, err)
+	s := &fakePCPServer{
+		conn:     conn,
+		extAddr:  netip.MustParseAddr("1.2.3.4"),
+		extPort:  40000,
+		result:   result,
+		mappings: make(map[[12]byte]uint32),
+	}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakePCPServer) addr() netip.AddrPort {
+	return s.conn.LocalAddr().(*net.UDPAddr).AddrPort()
+}
+
+func (s *fakePCPServer) lifetimes() map[[12]byte]uint32 {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	res := make(map[[12]byte]uint32, len(s.mappings))
+	for k, v := range s.mappings {
+		res[k] = v
+	}
+	return res
+}
+
+func (s *fakePCPServer) serve() {
+	buf := make([]byte, pcpMaxPacketLen)
+	for {
+		n, raddr, err := s.conn.ReadFromUDPAddrPort(buf)
+		if err != nil {
+			return
+		}
+		req := buf[:n]
+		resp := make([]byte, pcpHeaderLen, pcpHeaderLen+pcpMapLen)
+		resp[0] = pcpVersion
+		resp[1] = pcpResponseBit | req[1]
+		resp[3] = s.result
+		copy(resp[4:8], req[4:8])
+		if req[1] == pcpOpMap {
+			clientAddr := netip.AddrFrom16([16]byte(req[8:24])).Unmap()
+			if clientAddr != raddr.Addr().Unmap() {
+				resp[3] = 12 // ADDRESS_MISMATCH
+			}
+			body := append([]byte(nil), req[pcpHeaderLen:pcpHeaderLen+pcpMapLen]...)
+			suggested := netip.AddrFrom16([16]byte(body[20:36])).Unmap()
+			ext := s.extAddr
+			extPort := s.extPort
+			if suggested.Is6() && !suggested.IsUnspecified() {
+				// pinhole
+				ext = suggested
+				extPort = binary.BigEndian.Uint16(body[16:18])
+			}
+			binary.BigEndian.PutUint16(body[18:20], extPort)
+			e16 := ext.As16()
+			copy(body[20:36], e16[:])
+			resp = append(resp, body...)
+			s.mx.Lock()
+			s.mappings[[12]byte(body[:12])] = binary.BigEndian.Uint32(req[4:8])
+			s.mx.Unlock()
+		}
+		s.conn.WriteToUDPAddrPort(resp, raddr)
+	}
+}
+
+func TestPCPPortMapping(t *testing.T) {
+	s := newFakePCPServer(t, "udp4", "127.0.0.1:0", 0)
+	b, err := NewPCPBackend(s.addr())
+	require.NoError(t, err)
+	require.NoError(t, pcpAnnounce(context.Background(), s.addr()))
+
+	nat := NewNAT(b)
+	defer nat.Close()
+	require.Equal(t, "PCP", nat.Type())
+	require.NoError(t, nat.AddMapping(context.Background(), "udp", 4001))
+	mapped, found := nat.GetMapping("udp", 4001)
+	require.True(t, found)
+	require.Equal(t, netip.MustParseAddrPort("1.2.3.4:40000"), mapped)
+
+	lifetimes := s.lifetimes()
+	require.Len(t, lifetimes, 1)
+	for _, l := range lifetimes {
+		require.Equal(t, uint32(MappingDuration/time.Second), l)
+	}
+
+	require.NoError(t, nat.RemoveMapping(context.Background(), "udp", 4001))
+	for _, l := range s.lifetimes() {
+		require.Zero(t, l)
+	}
+}
+
+func TestPCPPinhole(t *testing.T) {
+	s := newFakePCPServer(t, "udp6", "[::1]:0", 0)
+	b, err := NewPCPBackend(s.addr())
+	require.NoError(t, err)
+
+	// Without an IPv4 server, port mappings fail.
+	_, err = b.AddPortMapping(context.Background(), "udp", 4001, "", time.Minute)
+	require.Error(t, err)
+
+	nat := NewNAT(b)
+	defer nat.Close()
+	addr := netip.MustParseAddrPort("[::1]:4001")
+	require.NoError(t, nat.AddPinhole(context.Background(), "udp", addr))
+	require.True(t, nat.HasPinhole("udp", addr))
+
+	mappings := nat.Mappings()
+	require.Len(t, mappings, 1)
+	require.True(t, mappings[0].Pinhole)
+	require.Equal(t, addr, mappings[0].Internal)
+	require.Equal(t, addr, mappings[0].External)
+
+	require.NoError(t, nat.RemovePinhole(context.Background(), "udp", addr))
+	require.False(t, nat.HasPinhole("udp", addr))
+}
+
+func TestPCPError(t *testing.T) {
+	s := newFakePCPServer(t, "udp4", "127.0.0.1:0", 2) // NOT_AUTHORIZED
+	b, err := NewPCPBackend(s.addr())
+	require.NoError(t, err)
+
+	_, err = b.AddPortMapping(context.Background(), "tcp", 4001, "", time.Minute)
+	require.ErrorIs(t, err, PCPError(2))
+	require.EqualError(t, err, "pcp: NOT_AUTHORIZED")
+}
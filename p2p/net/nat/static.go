@@ -0,0 +1,58 @@
+package nat
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"time"
+)
+
+// StaticMapping is a port mapping configured manually on a NAT device.
+type StaticMapping struct {
+	// Protocol is "tcp" or "udp".
+	Protocol     string
+	InternalPort int
+	ExternalPort int
+}
+
+type staticKey struct {
+	protocol string
+	port     int
+}
+
+type staticBackend struct {
+	extAddr  netip.Addr
+	mappings map[staticKey]int
+}
+
+// NewStaticBackend returns a Backend reporting the port mappings configured manually
+// on a NAT device with the external address extAddr. Ports that aren't configured
+// can't be mapped.
+func NewStaticBackend(extAddr netip.Addr, mappings ...StaticMapping) Backend {
+	b := &staticBackend{extAddr: extAddr, mappings: make(map[staticKey]int, len(mappings))}
+	for _, m := range mappings {
+		b.mappings[staticKey{protocol: m.Protocol, port: m.InternalPort}] = m.ExternalPort
+	}
+	return b
+}
+
+func (b *staticBackend) Type() string {
+	return "static"
+}
+
+func (b *staticBackend) GetExternalAddress() (net.IP, error) {
+	return b.extAddr.AsSlice(), nil
+}
+
+func (b *staticBackend) AddPortMapping(_ context.Context, protocol string, internalPort int, _ string, _ time.Duration) (int, error) {
+	port, ok := b.mappings[staticKey{protocol: protocol, port: internalPort}]
+	if !ok {
+		return 0, fmt.Errorf("no static mapping for %s/%d", protocol, internalPort)
+	}
+	return port, nil
+}
+
+func (b *staticBackend) DeletePortMapping(context.Context, string, int) error {
+	return nil
+}
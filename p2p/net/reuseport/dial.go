@@ -2,6 +2,8 @@ package reuseport
 
 import (
 	"context"
+	"net"
+	"net/netip"
 
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
@@ -33,7 +35,16 @@ func (t *Transport) DialContext(ctx context.Context, raddr ma.Multiaddr) (manet.
 	default:
 		return nil, ErrWrongProto
 	}
-	conn, err := d.DialContext(ctx, network, addr)
+	srcs, useEgress, err := t.egressSourceAddrs(raddr)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	if useEgress {
+		conn, err = d.dialFrom(ctx, srcs, network, addr)
+	} else {
+		conn, err = d.DialContext(ctx, network, addr)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -45,6 +56,24 @@ func (t *Transport) DialContext(ctx context.Context, raddr ma.Multiaddr) (manet.
 	return maconn, nil
 }
 
+// egressSourceAddrs returns the source addresses the egress policy selects for
+// dialing raddr, if any.
+func (t *Transport) egressSourceAddrs(raddr ma.Multiaddr) ([]netip.Addr, bool, error) {
+	p := t.egress.Load()
+	if p == nil {
+		return nil, false, nil
+	}
+	ip, err := manet.ToIP(raddr)
+	if err != nil {
+		return nil, false, err
+	}
+	dst, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return nil, false, nil
+	}
+	return p.SourceAddrs(dst)
+}
+
 func (n *network) getDialer(_ string) *dialer {
 	n.mu.RLock()
 	d := n.dialer
@@ -0,0 +1,149 @@
+package reuseport
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+)
+
+// EgressRule routes dials to destinations in Network through the network interface
+// named Interface.
+type EgressRule struct {
+	// Network is the destination network the rule applies to. Use 0.0.0.0/0 or ::/0
+	// to match all destinations of an address family.
+	Network netip.Prefix
+	// Interface is the name of the network interface to dial from, e.g. "eth1".
+	Interface string
+}
+
+// EgressPolicy selects the network interface used to dial a destination on
+// multi-homed hosts. The most specific matching rule wins. Destinations that don't
+// match any rule are dialed as usual, letting the system's routing table decide.
+//
+// The policy selects the source address of the dial. The kernel still routes the
+// packets according to its routing table, so on hosts that don't do source based
+// routing, a route via the interface must exist for the policy to take effect.
+type EgressPolicy struct {
+	// sorted by most specific to least specific
+	rules []EgressRule
+}
+
+// interfaceAddrs is a variable so that tests can stub it out.
+var interfaceAddrs = InterfaceAddrs
+
+// InterfaceAddrs returns the IP addresses of the network interface with the given name.
+func InterfaceAddrs(name string) ([]netip.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]netip.Addr, 0, len(addrs))
+	for _, a := range addrs {
+		ipnet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		ip, ok := netip.AddrFromSlice(ipnet.IP)
+		if !ok {
+			continue
+		}
+		res = append(res, ip.Unmap())
+	}
+	return res, nil
+}
+
+// NewEgressPolicy creates an EgressPolicy from the rules.
+func NewEgressPolicy(rules ...EgressRule) (*EgressPolicy, error) {
+	for _, r := range rules {
+		if !r.Network.IsValid() {
+			return nil, errors.New("egress rule: invalid network")
+		}
+		if r.Interface == "" {
+			return nil, fmt.Errorf("egress rule for %s: interface name required", r.Network)
+		}
+	}
+	rules = slices.Clone(rules)
+	slices.SortStableFunc(rules, func(a, b EgressRule) int {
+		return cmp.Compare(b.Network.Bits(), a.Network.Bits())
+	})
+	return &EgressPolicy{rules: rules}, nil
+}
+
+// Match returns the rule for dialing ip, if any.
+func (p *EgressPolicy) Match(ip netip.Addr) (EgressRule, bool) {
+	if p == nil {
+		return EgressRule{}, false
+	}
+	ip = ip.Unmap()
+	for _, r := range p.rules {
+		if r.Network.Contains(ip) {
+			return r, true
+		}
+	}
+	return EgressRule{}, false
+}
+
+// SourceAddrs returns the addresses of the egress interface that can be used to dial
+// ip. It returns false if no rule matches ip, and an error if the interface has no
+// address of ip's address family.
+func (p *EgressPolicy) SourceAddrs(ip netip.Addr) ([]netip.Addr, bool, error) {
+	r, ok := p.Match(ip)
+	if !ok {
+		return nil, false, nil
+	}
+	addrs, err := interfaceAddrs(r.Interface)
+	if err != nil {
+		return nil, true, fmt.Errorf("egress interface %s: %w", r.Interface, err)
+	}
+	ip = ip.Unmap()
+	srcs := make([]netip.Addr, 0, len(addrs))
+	for _, a := range addrs {
+		// Link local source addresses only work for link local destinations.
+		if a.Is4() != ip.Is4() || (a.IsLinkLocalUnicast() && !ip.IsLinkLocalUnicast()) {
+			continue
+		}
+		srcs = append(srcs, a)
+	}
+	if len(srcs) == 0 {
+		return nil, true, fmt.Errorf("egress interface %s has no address to dial %s", r.Interface, ip)
+	}
+	return srcs, true, nil
+}
+
+// SetEgressPolicy sets the policy used to select the source interface of dials.
+// Passing nil removes the policy.
+func (t *Transport) SetEgressPolicy(p *EgressPolicy) {
+	t.egress.Store(p)
+}
+
+// dialFrom dials addr from one of the source addresses, reusing the port of a
+// listener if possible.
+//
+// In-order:
+//
+//  1. If we're explicitly listening on one of the source addresses, we'll use that
+//     listener's address and port.
+//  2. If we're listening on one or more unspecified addresses, we'll use the first
+//     source address and the port of one of these listeners.
+//  3. Otherwise, we'll use the first source address and let the system pick the port.
+func (d *dialer) dialFrom(ctx context.Context, srcs []netip.Addr, network, addr string) (net.Conn, error) {
+	for _, l := range append(slices.Clip(d.specific), d.loopback...) {
+		if ip, ok := netip.AddrFromSlice(l.IP); ok && slices.Contains(srcs, ip.Unmap()) {
+			// If the port is in use, retry from the same address.
+			return reuseDialWithFallback(ctx, l, &net.Dialer{LocalAddr: &net.TCPAddr{IP: l.IP}}, network, addr)
+		}
+	}
+	fallback := &net.Dialer{LocalAddr: &net.TCPAddr{IP: srcs[0].AsSlice()}}
+	if u := randAddr(d.unspecified); u != nil {
+		return reuseDialWithFallback(ctx, &net.TCPAddr{IP: srcs[0].AsSlice(), Port: u.Port}, fallback, network, addr)
+	}
+	return fallback.DialContext(ctx, network, addr)
+}
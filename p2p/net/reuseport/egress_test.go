@@ -0,0 +1,131 @@
+package reuseport
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"runtime"
+	"testing"
+	"time"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func stubInterfaceAddrs(t *testing.T, ifaces map[string][]netip.Addr) {
+	t.Helper()
+	orig := interfaceAddrs
+	t.Cleanup(func() { interfaceAddrs = orig })
+	interfaceAddrs = func(name string) ([]netip.Addr, error) {
+		addrs, ok := ifaces[name]
+		if !ok {
+			return nil, &net.OpError{Op: "route", Err: net.UnknownNetworkError(name)}
+		}
+		return addrs, nil
+	}
+}
+
+func TestEgressPolicyMatch(t *testing.T) {
+	p, err := NewEgressPolicy(
+		EgressRule{Network: netip.MustParsePrefix("0.0.0.0/0"), Interface: "eth0"},
+		EgressRule{Network: netip.MustParsePrefix("10.0.0.0/8"), Interface: "eth1"},
+		EgressRule{Network: netip.MustParsePrefix("10.1.0.0/16"), Interface: "eth2"},
+	)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		ip    string
+		iface string
+	}{
+		{"1.2.3.4", "eth0"},
+		{"10.2.3.4", "eth1"},
+		{"10.1.3.4", "eth2"},
+		{"::ffff:10.1.3.4", "eth2"},
+		{"2001:db8::1", ""},
+	} {
+		r, ok := p.Match(netip.MustParseAddr(tc.ip))
+		require.Equal(t, tc.iface != "", ok, tc.ip)
+		require.Equal(t, tc.iface, r.Interface, tc.ip)
+	}
+
+	_, err = NewEgressPolicy(EgressRule{Network: netip.MustParsePrefix("10.0.0.0/8")})
+	require.Error(t, err)
+	_, err = NewEgressPolicy(EgressRule{Interface: "eth0"})
+	require.Error(t, err)
+}
+
+func TestEgressPolicySourceAddrs(t *testing.T) {
+	stubInterfaceAddrs(t, map[string][]netip.Addr{
+		"eth0": {netip.MustParseAddr("192.168.1.2"), netip.MustParseAddr("fe80::1"), netip.MustParseAddr("2001:db8::2")},
+		"eth1": {netip.MustParseAddr("192.168.2.2")},
+	})
+	p, err := NewEgressPolicy(
+		EgressRule{Network: netip.MustParsePrefix("0.0.0.0/0"), Interface: "eth0"},
+		EgressRule{Network: netip.MustParsePrefix("::/0"), Interface: "eth1"},
+		EgressRule{Network: netip.MustParsePrefix("2001:db8::/32"), Interface: "eth0"},
+		EgressRule{Network: netip.MustParsePrefix("10.0.0.0/8"), Interface: "eth3"},
+	)
+	require.NoError(t, err)
+
+	srcs, ok, err := p.SourceAddrs(netip.MustParseAddr("1.2.3.4"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("192.168.1.2")}, srcs)
+
+	srcs, ok, err = p.SourceAddrs(netip.MustParseAddr("2001:db8::1"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("2001:db8::2")}, srcs)
+
+	// eth1 has no IPv6 address
+	_, ok, err = p.SourceAddrs(netip.MustParseAddr("2a00::1"))
+	require.True(t, ok)
+	require.Error(t, err)
+
+	// eth3 doesn't exist
+	_, ok, err = p.SourceAddrs(netip.MustParseAddr("10.0.0.1"))
+	require.True(t, ok)
+	require.Error(t, err)
+}
+
+func TestEgressPolicyDial(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("binding to 127.0.0.2 requires linux")
+	}
+	stubInterfaceAddrs(t, map[string][]netip.Addr{"test0": {netip.MustParseAddr("127.0.0.2")}})
+	p, err := NewEgressPolicy(EgressRule{Network: netip.MustParsePrefix("127.0.0.0/8"), Interface: "test0"})
+	require.NoError(t, err)
+
+	var trA, trB Transport
+	listenerA, err := trA.Listen(loopbackV4)
+	require.NoError(t, err)
+	defer listenerA.Close()
+
+	trB.SetEgressPolicy(p)
+	dial := func() *net.TCPAddr {
+		t.Helper()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		connChan := acceptOne(t, listenerA)
+		c, err := trB.DialContext(ctx, listenerA.Multiaddr())
+		require.NoError(t, err)
+		setLingerZero(c)
+		laddr := c.LocalAddr().(*net.TCPAddr)
+		(<-connChan).Close()
+		c.Close()
+		return laddr
+	}
+
+	// no listener, the system picks the port
+	laddr := dial()
+	require.Equal(t, "127.0.0.2", laddr.IP.String())
+
+	// listening on the egress interface, the port is reused
+	listenerB, err := trB.Listen(ma.StringCast("/ip4/127.0.0.2/tcp/0"))
+	require.NoError(t, err)
+	defer listenerB.Close()
+	laddr = dial()
+	require.Equal(t, "127.0.0.2", laddr.IP.String())
+	require.Equal(t, listenerB.Addr().(*net.TCPAddr).Port, laddr.Port)
+
+}
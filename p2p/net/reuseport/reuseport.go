@@ -14,6 +14,11 @@ func reuseDial(ctx context.Context, laddr *net.TCPAddr, network, raddr string) (
 	if laddr == nil {
 		return fallbackDialer.DialContext(ctx, network, raddr)
 	}
+	return reuseDialWithFallback(ctx, laddr, &fallbackDialer, network, raddr)
+}
+
+// Dials using reuseport and then redials with the fallback dialer if that fails.
+func reuseDialWithFallback(ctx context.Context, laddr *net.TCPAddr, fallback *net.Dialer, network, raddr string) (con net.Conn, err error) {
 
 	d := net.Dialer{
 		LocalAddr: laddr,
@@ -29,7 +34,7 @@ func reuseDial(ctx context.Context, laddr *net.TCPAddr, network, raddr string) (
 		// We could have an existing socket open or we could have one
 		// stuck in TIME-WAIT.
 		log.Debugf("failed to reuse port, will try again with a random port: %s", err)
-		con, err = fallbackDialer.DialContext(ctx, network, raddr)
+		con, err = fallback.DialContext(ctx, network, raddr)
 	}
 	return con, err
 }
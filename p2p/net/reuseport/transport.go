@@ -12,6 +12,7 @@ package reuseport
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 
 	logging "github.com/ipfs/go-log/v2"
 )
@@ -26,6 +27,8 @@ var ErrWrongProto = errors.New("can only dial TCP over IPv4 or IPv6")
 type Transport struct {
 	v4 network
 	v6 network
+
+	egress atomic.Pointer[EgressPolicy]
 }
 
 type network struct {
@@ -0,0 +1,208 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/transport"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// AddrProberConfig configures the background address prober.
+type AddrProberConfig struct {
+	// Interval is the time between two probing rounds. Defaults to 10 minutes.
+	Interval time.Duration
+	// SampleSize is the maximum number of addresses probed per round. Defaults to 8.
+	SampleSize int
+	// Timeout is the timeout of a single probe. Defaults to 15 seconds.
+	Timeout time.Duration
+	// MaxFailures is the number of consecutive failed probes after which an address is
+	// removed from the peerstore. Defaults to 3.
+	MaxFailures int
+	// Backoff is the time an address isn't probed after a failed probe. It doubles
+	// with every consecutive failure. Defaults to Interval.
+	Backoff time.Duration
+}
+
+// WithAddrProber enables a background prober that periodically dials a sample of the
+// stored addresses of connected peers, other than the addresses they're connected on.
+// Probes go through the dial limiter, like any other dial of the swarm, and connections
+// established by probes are closed immediately. Addresses that keep failing are removed
+// from the peerstore, so that the address book stays fresh for reconnects.
+func WithAddrProber(cfg AddrProberConfig) Option {
+	return func(s *Swarm) error {
+		if cfg.Interval < 0 || cfg.SampleSize < 0 || cfg.Timeout < 0 || cfg.MaxFailures < 0 || cfg.Backoff < 0 {
+			return errors.New("swarm: invalid address prober config")
+		}
+		if cfg.Interval == 0 {
+			cfg.Interval = 10 * time.Minute
+		}
+		if cfg.SampleSize == 0 {
+			cfg.SampleSize = 8
+		}
+		if cfg.Timeout == 0 {
+			cfg.Timeout = 15 * time.Second
+		}
+		if cfg.MaxFailures == 0 {
+			cfg.MaxFailures = 3
+		}
+		if cfg.Backoff == 0 {
+			cfg.Backoff = cfg.Interval
+		}
+		s.addrProber = &addrProber{
+			s:      s,
+			cfg:    cfg,
+			states: make(map[probeKey]*probeState),
+		}
+		return nil
+	}
+}
+
+type probeKey struct {
+	p    peer.ID
+	addr string
+}
+
+type probeState struct {
+	failures  int
+	nextProbe time.Time
+}
+
+type probeCandidate struct {
+	p    peer.ID
+	addr ma.Multiaddr
+}
+
+// addrProber is only accessed from its run loop.
+type addrProber struct {
+	s      *Swarm
+	cfg    AddrProberConfig
+	states map[probeKey]*probeState
+}
+
+// start starts the probing rounds. The timer is created before returning, so that the
+// first round is scheduled relative to the time the swarm was constructed.
+func (ap *addrProber) start() {
+	t := ap.s.clock.InstantTimer(ap.s.clock.Now().Add(ap.cfg.Interval))
+	ap.s.refs.Add(1)
+	go ap.run(t)
+}
+
+func (ap *addrProber) run(t InstantTimer) {
+	defer ap.s.refs.Done()
+	defer t.Stop()
+	for {
+		select {
+		case <-t.Ch():
+			ap.probeRound(ap.s.ctx)
+			t.Reset(ap.s.clock.Now().Add(ap.cfg.Interval))
+		case <-ap.s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (ap *addrProber) probeRound(ctx context.Context) {
+	now := ap.s.clock.Now()
+	connected := make(map[peer.ID]struct{})
+	var candidates []probeCandidate
+	for _, p := range ap.s.Peers() {
+		connected[p] = struct{}{}
+		inUse := make(map[string]struct{})
+		for _, c := range ap.s.ConnsToPeer(p) {
+			inUse[string(c.RemoteMultiaddr().Bytes())] = struct{}{}
+		}
+		for _, a := range ap.s.peers.Addrs(p) {
+			if _, ok := inUse[string(a.Bytes())]; ok || isRelayAddr(a) || !ap.s.CanDial(p, a) {
+				continue
+			}
+			if st, ok := ap.states[probeKey{p: p, addr: string(a.Bytes())}]; ok && now.Before(st.nextProbe) {
+				continue
+			}
+			candidates = append(candidates, probeCandidate{p: p, addr: a})
+		}
+	}
+	// forget the addresses of peers we're not connected to anymore
+	for k := range ap.states {
+		if _, ok := connected[k.p]; !ok {
+			delete(ap.states, k)
+		}
+	}
+
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	if len(candidates) > ap.cfg.SampleSize {
+		candidates = candidates[:ap.cfg.SampleSize]
+	}
+	for _, c := range candidates {
+		if ctx.Err() != nil {
+			return
+		}
+		ap.probe(ctx, c.p, c.addr)
+	}
+}
+
+func (ap *addrProber) probe(ctx context.Context, p peer.ID, addr ma.Multiaddr) {
+	k := probeKey{p: p, addr: string(addr.Bytes())}
+	if ap.s.gater != nil && !ap.s.gater.InterceptAddrDial(p, addr) {
+		delete(ap.states, k)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ap.cfg.Timeout)
+	defer cancel()
+	c, err := ap.dial(ctx, p, addr)
+	if err == nil {
+		c.CloseWithError(network.ConnNoError)
+		delete(ap.states, k)
+		return
+	}
+	if ap.s.ctx.Err() != nil {
+		return
+	}
+
+	st, ok := ap.states[k]
+	if !ok {
+		st = &probeState{}
+		ap.states[k] = st
+	}
+	st.failures++
+	if st.failures >= ap.cfg.MaxFailures {
+		log.Debugw("removing stale address", "peer", p, "addr", addr, "error", err)
+		ap.s.peers.SetAddr(p, addr, 0)
+		delete(ap.states, k)
+		return
+	}
+	st.nextProbe = ap.s.clock.Now().Add(ap.cfg.Backoff << (st.failures - 1))
+}
+
+// dial dials addr through the dial limiter, and waits for the result of the dial.
+func (ap *addrProber) dial(ctx context.Context, p peer.ID, addr ma.Multiaddr) (transport.CapableConn, error) {
+	// The channel is unbuffered: if ctx is done before the result is received, the
+	// limiter closes the connection instead of handing it over.
+	resp := make(chan transport.DialUpdate)
+	ap.s.limiter.AddDialJob(&dialJob{
+		addr:    addr,
+		peer:    p,
+		resp:    resp,
+		ctx:     ctx,
+		timeout: ap.cfg.Timeout,
+	})
+	for {
+		select {
+		case u := <-resp:
+			switch u.Kind {
+			case transport.UpdateKindDialSuccessful:
+				return u.Conn, nil
+			case transport.UpdateKindDialFailed:
+				return nil, u.Err
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
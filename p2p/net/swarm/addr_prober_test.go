@@ -0,0 +1,121 @@
+package swarm
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAddrProber(t *testing.T) {
+	s1 := makeSwarmWithNoListenAddrs(t, WithAddrProber(AddrProberConfig{
+		Interval:    50 * time.Millisecond,
+		MaxFailures: 2,
+		Backoff:     time.Millisecond,
+	}))
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+
+	// an address nobody listens on
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stale, err := manet.FromNetAddr(l.Addr())
+	require.NoError(t, err)
+	l.Close()
+
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+	s1.Peerstore().AddAddr(s2.LocalPeer(), stale, peerstore.PermanentAddrTTL)
+
+	hasAddr := func(a ma.Multiaddr) bool {
+		for _, addr := range s1.Peerstore().Addrs(s2.LocalPeer()) {
+			if addr.Equal(a) {
+				return true
+			}
+		}
+		return false
+	}
+	require.Eventually(t, func() bool { return !hasAddr(stale) }, 5*time.Second, 10*time.Millisecond)
+	// the working addresses are kept, and the probes' connections are closed
+	for _, a := range s2.ListenAddresses() {
+		require.True(t, hasAddr(a))
+	}
+	require.Len(t, s1.ConnsToPeer(s2.LocalPeer()), 1)
+}
+
+func TestAddrProberClock(t *testing.T) {
+	const interval = time.Hour
+	cl := newMockClock()
+	s1 := makeSwarmWithNoListenAddrs(t, WithClock(cl), WithAddrProber(AddrProberConfig{
+		Interval:    interval,
+		MaxFailures: 2,
+	}))
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stale, err := manet.FromNetAddr(l.Addr())
+	require.NoError(t, err)
+	l.Close()
+
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+	s1.Peerstore().AddAddr(s2.LocalPeer(), stale, peerstore.PermanentAddrTTL)
+
+	hasStale := func() bool {
+		for _, addr := range s1.Peerstore().Addrs(s2.LocalPeer()) {
+			if addr.Equal(stale) {
+				return true
+			}
+		}
+		return false
+	}
+	// no probes are sent until the interval has passed on the swarm's clock
+	time.Sleep(100 * time.Millisecond)
+	require.True(t, hasStale())
+	// the backoff defaults to the interval, and doubles after every failure
+	require.Eventually(t, func() bool {
+		cl.AdvanceBy(interval)
+		return !hasStale()
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestAddrProberDialLimiter(t *testing.T) {
+	s1 := makeSwarmWithNoListenAddrs(t, WithMaxConcurrentDialsPerPeer(1), WithAddrProber(AddrProberConfig{Interval: time.Hour}))
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+	p := s2.LocalPeer()
+	s1.Peerstore().AddAddrs(p, s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+
+	// take the only dial token of the peer
+	s1.limiter.lk.Lock()
+	s1.limiter.activePerPeer[p]++
+	s1.limiter.lk.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err := s1.addrProber.dial(ctx, p, s2.ListenAddresses()[0])
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	s1.limiter.lk.Lock()
+	delete(s1.limiter.activePerPeer, p)
+	delete(s1.limiter.waitingOnPeerLimit, p)
+	s1.limiter.lk.Unlock()
+
+	c, err := s1.addrProber.dial(context.Background(), p, s2.ListenAddresses()[0])
+	require.NoError(t, err)
+	c.CloseWithError(network.ConnNoError)
+}
@@ -1,36 +1,110 @@
 package swarm
 
 import (
+	"cmp"
 	"fmt"
+	"slices"
+	"strings"
 	"sync"
 
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
 )
 
+// BlackHoleState is the state of black hole detection for a class of addresses.
 type BlackHoleState int
 
 const (
-	blackHoleStateProbing BlackHoleState = iota
-	blackHoleStateAllowed
-	blackHoleStateBlocked
+	// BlackHoleStateProbing allows all dials to find out the state of the black hole.
+	BlackHoleStateProbing BlackHoleState = iota
+	// BlackHoleStateAllowed allows all dials, there's no black hole.
+	BlackHoleStateAllowed
+	// BlackHoleStateBlocked refuses dials, apart from the occasional probe.
+	BlackHoleStateBlocked
 )
 
 func (st BlackHoleState) String() string {
 	switch st {
-	case blackHoleStateProbing:
+	case BlackHoleStateProbing:
 		return "Probing"
-	case blackHoleStateAllowed:
+	case BlackHoleStateAllowed:
 		return "Allowed"
-	case blackHoleStateBlocked:
+	case BlackHoleStateBlocked:
 		return "Blocked"
 	default:
 		return fmt.Sprintf("Unknown %d", st)
 	}
 }
 
+// BlackHoleKey identifies the class of addresses a BlackHolePolicy applies to: the
+// public addresses using the Transport protocol over the Network protocol. A zero
+// Transport or Network matches any transport or network. For example
+// BlackHoleKey{Transport: ma.P_TCP, Network: ma.P_IP6} matches TCP over IPv6 addresses,
+// and BlackHoleKey{Transport: ma.P_WEBRTC_DIRECT} matches WebRTC addresses.
+type BlackHoleKey struct {
+	// Transport is the multiaddr code of the transport protocol, e.g. ma.P_UDP.
+	Transport int
+	// Network is the multiaddr code of the network protocol, ma.P_IP4 or ma.P_IP6.
+	Network int
+}
+
+var (
+	// BlackHoleKeyUDP matches UDP addresses.
+	BlackHoleKeyUDP = BlackHoleKey{Transport: ma.P_UDP}
+	// BlackHoleKeyIPv6 matches IPv6 addresses.
+	BlackHoleKeyIPv6 = BlackHoleKey{Network: ma.P_IP6}
+)
+
+// Match returns whether a belongs to the class of addresses identified by k.
+func (k BlackHoleKey) Match(a ma.Multiaddr) bool {
+	return (k.Transport == 0 || isProtocolAddr(a, k.Transport)) &&
+		(k.Network == 0 || isProtocolAddr(a, k.Network))
+}
+
+func (k BlackHoleKey) String() string {
+	var parts []string
+	if k.Transport != 0 {
+		parts = append(parts, strings.ToUpper(protocolName(k.Transport)))
+	}
+	switch k.Network {
+	case 0:
+	case ma.P_IP4:
+		parts = append(parts, "IPv4")
+	case ma.P_IP6:
+		parts = append(parts, "IPv6")
+	default:
+		parts = append(parts, protocolName(k.Network))
+	}
+	if len(parts) == 0 {
+		return "any"
+	}
+	return strings.Join(parts, "/")
+}
+
+func protocolName(code int) string {
+	if p := ma.ProtocolWithCode(code); p.Code != 0 {
+		return p.Name
+	}
+	return fmt.Sprintf("proto-%d", code)
+}
+
+// BlackHolePolicy decides whether dials to a class of addresses are allowed, based on
+// the outcomes of the previous dials. BlackHoleSuccessCounter is the default policy.
+// Implementations must be safe for concurrent use.
+type BlackHolePolicy interface {
+	// HandleRequest is called once for every peer dial with matching addresses, and
+	// returns whether these addresses may be dialed.
+	HandleRequest() BlackHoleState
+	// RecordResult records the outcome of a dial to a matching address.
+	RecordResult(success bool)
+	// State returns the current state, without counting a request.
+	State() BlackHoleState
+	// Reset forgets the previous outcomes, going back to probing.
+	Reset()
+}
+
 // BlackHoleSuccessCounter provides black hole filtering for dials. This filter should be used in concert
-// with a UDP or IPv6 address filter to detect UDP or IPv6 black hole. In a black holed environment,
+// with an address filter, a BlackHoleKey, to detect e.g. UDP or IPv6 black hole. In a black holed environment,
 // dial requests are refused Requests are blocked if the number of successes in the last N dials is
 // less than MinSuccesses.
 // If a request succeeds in Blocked state, the filter state is reset and N subsequent requests are
@@ -45,7 +119,8 @@ type BlackHoleSuccessCounter struct {
 	// MinSuccesses is the minimum number of Success required in the last n dials
 	// to consider we are not blocked.
 	MinSuccesses int
-	// Name for the detector.
+	// Name for the detector. It's the label of its metrics, and defaults to the string
+	// representation of its BlackHoleKey.
 	Name string
 
 	mu sync.Mutex
@@ -60,6 +135,8 @@ type BlackHoleSuccessCounter struct {
 	state BlackHoleState
 }
 
+var _ BlackHolePolicy = (*BlackHoleSuccessCounter)(nil)
+
 // RecordResult records the outcome of a dial. A successful dial in Blocked state will change the
 // state of the filter to Probing. A failed dial only blocks subsequent requests if the success
 // fraction over the last n outcomes is less than the minSuccessFraction of the filter.
@@ -67,7 +144,7 @@ func (b *BlackHoleSuccessCounter) RecordResult(success bool) {
 	b.mu.Lock()
 	defer b.mu.Unlock()
 
-	if b.state == blackHoleStateBlocked && success {
+	if b.state == BlackHoleStateBlocked && success {
 		// If the call succeeds in a blocked state we reset to allowed.
 		// This is better than slowly accumulating values till we cross the minSuccessFraction
 		// threshold since a black hole is a binary property.
@@ -97,15 +174,23 @@ func (b *BlackHoleSuccessCounter) HandleRequest() BlackHoleState {
 
 	b.requests++
 
-	if b.state == blackHoleStateAllowed {
-		return blackHoleStateAllowed
-	} else if b.state == blackHoleStateProbing || b.requests%b.N == 0 {
-		return blackHoleStateProbing
+	if b.state == BlackHoleStateAllowed {
+		return BlackHoleStateAllowed
+	} else if b.state == BlackHoleStateProbing || b.requests%b.N == 0 {
+		return BlackHoleStateProbing
 	} else {
-		return blackHoleStateBlocked
+		return BlackHoleStateBlocked
 	}
 }
 
+// Reset forgets the outcomes of the previous dials, going back to Probing state.
+func (b *BlackHoleSuccessCounter) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.reset()
+}
+
 func (b *BlackHoleSuccessCounter) reset() {
 	b.successes = 0
 	b.dialResults = b.dialResults[:0]
@@ -117,11 +202,11 @@ func (b *BlackHoleSuccessCounter) updateState() {
 	st := b.state
 
 	if len(b.dialResults) < b.N {
-		b.state = blackHoleStateProbing
+		b.state = BlackHoleStateProbing
 	} else if b.successes >= b.MinSuccesses {
-		b.state = blackHoleStateAllowed
+		b.state = BlackHoleStateAllowed
 	} else {
-		b.state = blackHoleStateBlocked
+		b.state = BlackHoleStateBlocked
 	}
 
 	if st != b.state {
@@ -129,6 +214,7 @@ func (b *BlackHoleSuccessCounter) updateState() {
 	}
 }
 
+// State returns the current state of the filter.
 func (b *BlackHoleSuccessCounter) State() BlackHoleState {
 	b.mu.Lock()
 	defer b.mu.Unlock()
@@ -137,7 +223,6 @@ func (b *BlackHoleSuccessCounter) State() BlackHoleState {
 }
 
 type blackHoleInfo struct {
-	name            string
 	state           BlackHoleState
 	nextProbeAfter  int
 	successFraction float64
@@ -148,7 +233,7 @@ func (b *BlackHoleSuccessCounter) info() blackHoleInfo {
 	defer b.mu.Unlock()
 
 	nextProbeAfter := 0
-	if b.state == blackHoleStateBlocked {
+	if b.state == BlackHoleStateBlocked {
 		nextProbeAfter = b.N - (b.requests % b.N)
 	}
 
@@ -158,15 +243,15 @@ func (b *BlackHoleSuccessCounter) info() blackHoleInfo {
 	}
 
 	return blackHoleInfo{
-		name:            b.Name,
 		state:           b.state,
 		nextProbeAfter:  nextProbeAfter,
 		successFraction: successFraction,
 	}
 }
 
-// blackHoleDetector provides UDP and IPv6 black hole detection using a `BlackHoleSuccessCounter` for each.
-// For details of the black hole detection logic see `BlackHoleSuccessCounter`.
+// blackHoleDetector provides black hole detection for classes of addresses, identified by a
+// BlackHoleKey, using a BlackHolePolicy for each. By default UDP and IPv6 addresses are tracked with a
+// BlackHoleSuccessCounter each. For details of the black hole detection logic see `BlackHoleSuccessCounter`.
 // In Read Only mode, detector doesn't update the state of underlying filters and refuses requests
 // when black hole state is unknown. This is useful for Swarms made specifically for services like
 // AutoNAT where we care about accurately reporting the reachability of a peer.
@@ -175,59 +260,65 @@ func (b *BlackHoleSuccessCounter) info() blackHoleInfo {
 // of the black hole state are actually dialed and are not skipped because of dial prioritisation
 // logic.
 type blackHoleDetector struct {
-	udp, ipv6 *BlackHoleSuccessCounter
-	mt        MetricsTracer
-	readOnly  bool
+	// filters are sorted by key.
+	filters  []blackHoleFilter
+	mt       MetricsTracer
+	readOnly bool
 }
 
-// FilterAddrs filters the peer's addresses removing black holed addresses
-func (d *blackHoleDetector) FilterAddrs(addrs []ma.Multiaddr) (valid []ma.Multiaddr, blackHoled []ma.Multiaddr) {
-	hasUDP, hasIPv6 := false, false
-	for _, a := range addrs {
-		if !manet.IsPublicAddr(a) {
-			continue
-		}
-		if isProtocolAddr(a, ma.P_UDP) {
-			hasUDP = true
-		}
-		if isProtocolAddr(a, ma.P_IP6) {
-			hasIPv6 = true
-		}
+type blackHoleFilter struct {
+	key    BlackHoleKey
+	policy BlackHolePolicy
+}
+
+func newBlackHoleDetector(policies map[BlackHoleKey]BlackHolePolicy, mt MetricsTracer, readOnly bool) *blackHoleDetector {
+	d := &blackHoleDetector{mt: mt, readOnly: readOnly}
+	for k, p := range policies {
+		d.filters = append(d.filters, blackHoleFilter{key: k, policy: p})
 	}
+	slices.SortFunc(d.filters, func(a, b blackHoleFilter) int {
+		return cmp.Or(cmp.Compare(a.key.Transport, b.key.Transport), cmp.Compare(a.key.Network, b.key.Network))
+	})
+	return d
+}
 
-	udpRes := blackHoleStateAllowed
-	if d.udp != nil && hasUDP {
-		udpRes = d.getFilterState(d.udp)
-		d.trackMetrics(d.udp)
+// FilterAddrs filters the peer's addresses removing black holed addresses
+func (d *blackHoleDetector) FilterAddrs(addrs []ma.Multiaddr) (valid []ma.Multiaddr, blackHoled []ma.Multiaddr) {
+	blackHoled = make([]ma.Multiaddr, 0, len(addrs))
+	if len(d.filters) == 0 {
+		return addrs, blackHoled
 	}
 
-	ipv6Res := blackHoleStateAllowed
-	if d.ipv6 != nil && hasIPv6 {
-		ipv6Res = d.getFilterState(d.ipv6)
-		d.trackMetrics(d.ipv6)
+	states := make([]BlackHoleState, len(d.filters))
+	for i, f := range d.filters {
+		states[i] = BlackHoleStateAllowed
+		if slices.ContainsFunc(addrs, func(a ma.Multiaddr) bool { return manet.IsPublicAddr(a) && f.key.Match(a) }) {
+			states[i] = d.getFilterState(f.policy)
+			d.trackMetrics(f)
+		}
 	}
 
-	blackHoled = make([]ma.Multiaddr, 0, len(addrs))
 	return ma.FilterAddrs(
 		addrs,
 		func(a ma.Multiaddr) bool {
 			if !manet.IsPublicAddr(a) {
 				return true
 			}
-			// allow all UDP addresses while probing irrespective of IPv6 black hole state
-			if udpRes == blackHoleStateProbing && isProtocolAddr(a, ma.P_UDP) {
-				return true
-			}
-			// allow all IPv6 addresses while probing irrespective of UDP black hole state
-			if ipv6Res == blackHoleStateProbing && isProtocolAddr(a, ma.P_IP6) {
-				return true
-			}
-
-			if udpRes == blackHoleStateBlocked && isProtocolAddr(a, ma.P_UDP) {
-				blackHoled = append(blackHoled, a)
-				return false
+			blocked := false
+			for i, f := range d.filters {
+				if !f.key.Match(a) {
+					continue
+				}
+				switch states[i] {
+				case BlackHoleStateProbing:
+					// allow all the matching addresses while probing irrespective of the
+					// state of the other filters
+					return true
+				case BlackHoleStateBlocked:
+					blocked = true
+				}
 			}
-			if ipv6Res == blackHoleStateBlocked && isProtocolAddr(a, ma.P_IP6) {
+			if blocked {
 				blackHoled = append(blackHoled, a)
 				return false
 			}
@@ -236,36 +327,61 @@ func (d *blackHoleDetector) FilterAddrs(addrs []ma.Multiaddr) (valid []ma.Multia
 	), blackHoled
 }
 
-// RecordResult updates the state of the relevant BlackHoleSuccessCounters for addr
+// RecordResult updates the state of the relevant BlackHolePolicies for addr
 func (d *blackHoleDetector) RecordResult(addr ma.Multiaddr, success bool) {
 	if d.readOnly || !manet.IsPublicAddr(addr) {
 		return
 	}
-	if d.udp != nil && isProtocolAddr(addr, ma.P_UDP) {
-		d.udp.RecordResult(success)
-		d.trackMetrics(d.udp)
+	for _, f := range d.filters {
+		if f.key.Match(addr) {
+			f.policy.RecordResult(success)
+			d.trackMetrics(f)
+		}
 	}
-	if d.ipv6 != nil && isProtocolAddr(addr, ma.P_IP6) {
-		d.ipv6.RecordResult(success)
-		d.trackMetrics(d.ipv6)
+}
+
+// States returns the current state of every policy.
+func (d *blackHoleDetector) States() map[BlackHoleKey]BlackHoleState {
+	states := make(map[BlackHoleKey]BlackHoleState, len(d.filters))
+	for _, f := range d.filters {
+		states[f.key] = f.policy.State()
 	}
+	return states
 }
 
-func (d *blackHoleDetector) getFilterState(f *BlackHoleSuccessCounter) BlackHoleState {
+// Reset resets the policies of keys, or all the policies if keys is empty.
+func (d *blackHoleDetector) Reset(keys ...BlackHoleKey) {
+	for _, f := range d.filters {
+		if len(keys) == 0 || slices.Contains(keys, f.key) {
+			f.policy.Reset()
+			d.trackMetrics(f)
+		}
+	}
+}
+
+func (d *blackHoleDetector) getFilterState(p BlackHolePolicy) BlackHoleState {
 	if d.readOnly {
-		if f.State() != blackHoleStateAllowed {
-			return blackHoleStateBlocked
+		if p.State() != BlackHoleStateAllowed {
+			return BlackHoleStateBlocked
 		}
-		return blackHoleStateAllowed
+		return BlackHoleStateAllowed
 	}
-	return f.HandleRequest()
+	return p.HandleRequest()
 }
 
-func (d *blackHoleDetector) trackMetrics(f *BlackHoleSuccessCounter) {
+func (d *blackHoleDetector) trackMetrics(f blackHoleFilter) {
 	if d.readOnly || d.mt == nil {
 		return
 	}
 	// Track metrics only in non readOnly state
-	info := f.info()
-	d.mt.UpdatedBlackHoleSuccessCounter(info.name, info.state, info.nextProbeAfter, info.successFraction)
+	name := f.key.String()
+	if c, ok := f.policy.(*BlackHoleSuccessCounter); ok {
+		if c.Name != "" {
+			name = c.Name
+		}
+		info := c.info()
+		d.mt.UpdatedBlackHoleSuccessCounter(name, info.state, info.nextProbeAfter, info.successFraction)
+		return
+	}
+	d.mt.UpdatedBlackHoleSuccessCounter(name, f.policy.State(), 0, 0)
 }
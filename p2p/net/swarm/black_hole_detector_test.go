@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 )
@@ -13,10 +15,10 @@ func TestBlackHoleSuccessCounterReset(t *testing.T) {
 	bhf := &BlackHoleSuccessCounter{N: n, MinSuccesses: 2, Name: "test"}
 	// calls up to n should be probing
 	for i := 1; i <= n; i++ {
-		if bhf.HandleRequest() != blackHoleStateProbing {
+		if bhf.HandleRequest() != BlackHoleStateProbing {
 			t.Fatalf("expected calls up to n to be probes")
 		}
-		if bhf.State() != blackHoleStateProbing {
+		if bhf.State() != BlackHoleStateProbing {
 			t.Fatalf("expected state to be probing got %s", bhf.State())
 		}
 		bhf.RecordResult(false)
@@ -25,10 +27,10 @@ func TestBlackHoleSuccessCounterReset(t *testing.T) {
 	// after threshold calls every nth call should be a probe
 	for i := n + 1; i < 42; i++ {
 		result := bhf.HandleRequest()
-		if (i%n == 0 && result != blackHoleStateProbing) || (i%n != 0 && result != blackHoleStateBlocked) {
+		if (i%n == 0 && result != BlackHoleStateProbing) || (i%n != 0 && result != BlackHoleStateBlocked) {
 			t.Fatalf("expected every nth dial to be a probe")
 		}
-		if bhf.State() != blackHoleStateBlocked {
+		if bhf.State() != BlackHoleStateBlocked {
 			t.Fatalf("expected state to be blocked, got %s", bhf.State())
 		}
 	}
@@ -36,19 +38,19 @@ func TestBlackHoleSuccessCounterReset(t *testing.T) {
 	bhf.RecordResult(true)
 	// check if calls up to n are probes again
 	for i := 0; i < n; i++ {
-		if bhf.HandleRequest() != blackHoleStateProbing {
+		if bhf.HandleRequest() != BlackHoleStateProbing {
 			t.Fatalf("expected black hole detector state to reset after success")
 		}
-		if bhf.State() != blackHoleStateProbing {
+		if bhf.State() != BlackHoleStateProbing {
 			t.Fatalf("expected state to be probing got %s", bhf.State())
 		}
 		bhf.RecordResult(false)
 	}
 
 	// next call should be blocked
-	if bhf.HandleRequest() != blackHoleStateBlocked {
+	if bhf.HandleRequest() != BlackHoleStateBlocked {
 		t.Fatalf("expected dial to be blocked")
-		if bhf.State() != blackHoleStateBlocked {
+		if bhf.State() != BlackHoleStateBlocked {
 			t.Fatalf("expected state to be blocked, got %s", bhf.State())
 		}
 	}
@@ -60,13 +62,13 @@ func TestBlackHoleSuccessCounterSuccessFraction(t *testing.T) {
 		minSuccesses, successes int
 		result                  BlackHoleState
 	}{
-		{minSuccesses: 5, successes: 5, result: blackHoleStateAllowed},
-		{minSuccesses: 3, successes: 3, result: blackHoleStateAllowed},
-		{minSuccesses: 5, successes: 4, result: blackHoleStateBlocked},
-		{minSuccesses: 5, successes: 7, result: blackHoleStateAllowed},
-		{minSuccesses: 3, successes: 1, result: blackHoleStateBlocked},
-		{minSuccesses: 0, successes: 0, result: blackHoleStateAllowed},
-		{minSuccesses: 10, successes: 10, result: blackHoleStateAllowed},
+		{minSuccesses: 5, successes: 5, result: BlackHoleStateAllowed},
+		{minSuccesses: 3, successes: 3, result: BlackHoleStateAllowed},
+		{minSuccesses: 5, successes: 4, result: BlackHoleStateBlocked},
+		{minSuccesses: 5, successes: 7, result: BlackHoleStateAllowed},
+		{minSuccesses: 3, successes: 1, result: BlackHoleStateBlocked},
+		{minSuccesses: 0, successes: 0, result: BlackHoleStateAllowed},
+		{minSuccesses: 10, successes: 10, result: BlackHoleStateAllowed},
 	}
 	for i, tc := range tests {
 		t.Run(fmt.Sprintf("case-%d", i), func(t *testing.T) {
@@ -88,7 +90,7 @@ func TestBlackHoleSuccessCounterSuccessFraction(t *testing.T) {
 func TestBlackHoleDetectorInApplicableAddress(t *testing.T) {
 	udpF := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
 	ipv6F := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
-	bhd := &blackHoleDetector{udp: udpF, ipv6: ipv6F}
+	bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{BlackHoleKeyUDP: udpF, BlackHoleKeyIPv6: ipv6F}, nil, false)
 	addrs := []ma.Multiaddr{
 		ma.StringCast("/ip4/1.2.3.4/tcp/1234"),
 		ma.StringCast("/ip4/1.2.3.4/tcp/1233"),
@@ -106,7 +108,7 @@ func TestBlackHoleDetectorInApplicableAddress(t *testing.T) {
 
 func TestBlackHoleDetectorUDPDisabled(t *testing.T) {
 	ipv6F := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
-	bhd := &blackHoleDetector{ipv6: ipv6F}
+	bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{BlackHoleKeyIPv6: ipv6F}, nil, false)
 	publicAddr := ma.StringCast("/ip4/1.2.3.4/udp/1234/quic-v1")
 	privAddr := ma.StringCast("/ip4/192.168.1.5/udp/1234/quic-v1")
 	for i := 0; i < 100; i++ {
@@ -122,7 +124,7 @@ func TestBlackHoleDetectorUDPDisabled(t *testing.T) {
 
 func TestBlackHoleDetectorIPv6Disabled(t *testing.T) {
 	udpF := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
-	bhd := &blackHoleDetector{udp: udpF}
+	bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{BlackHoleKeyUDP: udpF}, nil, false)
 	publicAddr := ma.StringCast("/ip6/2001::1/tcp/1234")
 	privAddr := ma.StringCast("/ip6/::1/tcp/1234")
 	for i := 0; i < 100; i++ {
@@ -138,10 +140,10 @@ func TestBlackHoleDetectorIPv6Disabled(t *testing.T) {
 }
 
 func TestBlackHoleDetectorProbes(t *testing.T) {
-	bhd := &blackHoleDetector{
-		udp:  &BlackHoleSuccessCounter{N: 2, MinSuccesses: 1, Name: "udp"},
-		ipv6: &BlackHoleSuccessCounter{N: 3, MinSuccesses: 1, Name: "ipv6"},
-	}
+	bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{
+		BlackHoleKeyUDP:  &BlackHoleSuccessCounter{N: 2, MinSuccesses: 1, Name: "udp"},
+		BlackHoleKeyIPv6: &BlackHoleSuccessCounter{N: 3, MinSuccesses: 1, Name: "ipv6"},
+	}, nil, false)
 	udp6Addr := ma.StringCast("/ip6/2001::1/udp/1234/quic-v1")
 	addrs := []ma.Multiaddr{udp6Addr}
 	for i := 0; i < 3; i++ {
@@ -162,6 +164,27 @@ func TestBlackHoleDetectorProbes(t *testing.T) {
 
 }
 
+type blackHoleNamesTracer struct {
+	MetricsTracer
+	names []string
+}
+
+func (t *blackHoleNamesTracer) UpdatedBlackHoleSuccessCounter(name string, _ BlackHoleState, _ int, _ float64) {
+	t.names = append(t.names, name)
+}
+
+func TestBlackHoleDetectorMetricsName(t *testing.T) {
+	mt := &blackHoleNamesTracer{}
+	tcpKey := BlackHoleKey{Transport: ma.P_TCP}
+	bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{
+		BlackHoleKeyUDP: &BlackHoleSuccessCounter{N: 2, MinSuccesses: 1, Name: "custom"},
+		tcpKey:          &BlackHoleSuccessCounter{N: 2, MinSuccesses: 1},
+	}, mt, false)
+	bhd.RecordResult(ma.StringCast("/ip4/1.2.3.4/udp/1234/quic-v1"), true)
+	bhd.RecordResult(ma.StringCast("/ip4/1.2.3.4/tcp/1234"), true)
+	require.Equal(t, []string{"custom", "TCP"}, mt.names)
+}
+
 func TestBlackHoleDetectorAddrFiltering(t *testing.T) {
 	udp6Pub := ma.StringCast("/ip6/2001::1/udp/1234/quic-v1")
 	udp6Pri := ma.StringCast("/ip6/::1/udp/1234/quic-v1")
@@ -173,10 +196,10 @@ func TestBlackHoleDetectorAddrFiltering(t *testing.T) {
 	tcp4Pri := ma.StringCast("/ip4/192.168.1.5/tcp/1234/quic-v1")
 
 	makeBHD := func(udpBlocked, ipv6Blocked bool) *blackHoleDetector {
-		bhd := &blackHoleDetector{
-			udp:  &BlackHoleSuccessCounter{N: 100, MinSuccesses: 10, Name: "udp"},
-			ipv6: &BlackHoleSuccessCounter{N: 100, MinSuccesses: 10, Name: "ipv6"},
-		}
+		bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{
+			BlackHoleKeyUDP:  &BlackHoleSuccessCounter{N: 100, MinSuccesses: 10, Name: "udp"},
+			BlackHoleKeyIPv6: &BlackHoleSuccessCounter{N: 100, MinSuccesses: 10, Name: "ipv6"},
+		}, nil, false)
 		for i := 0; i < 100; i++ {
 			bhd.RecordResult(udp4Pub, !udpBlocked)
 		}
@@ -214,7 +237,7 @@ func TestBlackHoleDetectorAddrFiltering(t *testing.T) {
 func TestBlackHoleDetectorReadOnlyMode(t *testing.T) {
 	udpF := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
 	ipv6F := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
-	bhd := &blackHoleDetector{udp: udpF, ipv6: ipv6F, readOnly: true}
+	bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{BlackHoleKeyUDP: udpF, BlackHoleKeyIPv6: ipv6F}, nil, true)
 	publicAddr := ma.StringCast("/ip4/1.2.3.4/udp/1234/quic-v1")
 	privAddr := ma.StringCast("/ip6/::1/tcp/1234")
 	for i := 0; i < 100; i++ {
@@ -230,7 +253,7 @@ func TestBlackHoleDetectorReadOnlyMode(t *testing.T) {
 	require.ElementsMatch(t, wantRemovedAddrs, gotRemovedAddrs)
 
 	// a non readonly shared state black hole detector
-	nbhd := &blackHoleDetector{udp: bhd.udp, ipv6: bhd.ipv6, readOnly: false}
+	nbhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{BlackHoleKeyUDP: udpF, BlackHoleKeyIPv6: ipv6F}, nil, false)
 	for i := 0; i < 100; i++ {
 		nbhd.RecordResult(publicAddr, true)
 	}
@@ -242,3 +265,64 @@ func TestBlackHoleDetectorReadOnlyMode(t *testing.T) {
 	require.ElementsMatch(t, wantAddrs, gotAddrs)
 	require.ElementsMatch(t, wantRemovedAddrs, gotRemovedAddrs)
 }
+
+func TestBlackHoleDetectorCustomKeys(t *testing.T) {
+	tcp6 := BlackHoleKey{Transport: ma.P_TCP, Network: ma.P_IP6}
+	webrtc := BlackHoleKey{Transport: ma.P_WEBRTC_DIRECT}
+	require.Equal(t, "TCP/IPv6", tcp6.String())
+	require.Equal(t, "WEBRTC-DIRECT", webrtc.String())
+	require.Equal(t, "UDP", BlackHoleKeyUDP.String())
+	require.Equal(t, "IPv6", BlackHoleKeyIPv6.String())
+
+	tcp6F := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
+	webrtcF := &BlackHoleSuccessCounter{N: 10, MinSuccesses: 5}
+	bhd := newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{tcp6: tcp6F, webrtc: webrtcF}, nil, false)
+
+	tcp6Pub := ma.StringCast("/ip6/2001::1/tcp/1234")
+	tcp4Pub := ma.StringCast("/ip4/1.2.3.4/tcp/1234")
+	udp6Pub := ma.StringCast("/ip6/2001::1/udp/1234/quic-v1")
+	webrtcPub := ma.StringCast("/ip4/1.2.3.4/udp/1234/webrtc-direct")
+	for i := 0; i < 10; i++ {
+		bhd.RecordResult(tcp6Pub, false)
+		bhd.RecordResult(webrtcPub, false)
+	}
+	require.Equal(t, map[BlackHoleKey]BlackHoleState{tcp6: BlackHoleStateBlocked, webrtc: BlackHoleStateBlocked}, bhd.States())
+
+	addrs := []ma.Multiaddr{tcp6Pub, tcp4Pub, udp6Pub, webrtcPub}
+	gotAddrs, gotRemovedAddrs := bhd.FilterAddrs(addrs)
+	require.ElementsMatch(t, []ma.Multiaddr{tcp4Pub, udp6Pub}, gotAddrs)
+	require.ElementsMatch(t, []ma.Multiaddr{tcp6Pub, webrtcPub}, gotRemovedAddrs)
+
+	// resetting a key allows probing its addresses again
+	bhd.Reset(webrtc)
+	require.Equal(t, map[BlackHoleKey]BlackHoleState{tcp6: BlackHoleStateBlocked, webrtc: BlackHoleStateProbing}, bhd.States())
+	gotAddrs, _ = bhd.FilterAddrs(addrs)
+	require.ElementsMatch(t, []ma.Multiaddr{tcp4Pub, udp6Pub, webrtcPub}, gotAddrs)
+
+	bhd.Reset()
+	require.Equal(t, map[BlackHoleKey]BlackHoleState{tcp6: BlackHoleStateProbing, webrtc: BlackHoleStateProbing}, bhd.States())
+}
+
+// staticBlackHolePolicy always reports the same state.
+type staticBlackHolePolicy struct{ state BlackHoleState }
+
+func (p *staticBlackHolePolicy) HandleRequest() BlackHoleState { return p.state }
+func (p *staticBlackHolePolicy) RecordResult(bool)             {}
+func (p *staticBlackHolePolicy) State() BlackHoleState         { return p.state }
+func (p *staticBlackHolePolicy) Reset()                        { p.state = BlackHoleStateProbing }
+
+func TestSwarmBlackHolePolicy(t *testing.T) {
+	tcp4 := BlackHoleKey{Transport: ma.P_TCP, Network: ma.P_IP4}
+	s := makeSwarmWithNoListenAddrs(t,
+		WithUDPBlackHoleSuccessCounter(nil),
+		WithBlackHolePolicy(tcp4, &staticBlackHolePolicy{state: BlackHoleStateBlocked}),
+	)
+	defer s.Close()
+	require.Equal(t, map[BlackHoleKey]BlackHoleState{tcp4: BlackHoleStateBlocked, BlackHoleKeyIPv6: BlackHoleStateProbing}, s.BlackHoleStates())
+
+	s.ResetBlackHoleDetector(tcp4)
+	require.Equal(t, BlackHoleStateProbing, s.BlackHoleStates()[tcp4])
+
+	_, err := NewSwarm("", nil, eventbus.NewBus(), WithBlackHolePolicy(BlackHoleKey{}, &staticBlackHolePolicy{}))
+	require.Error(t, err)
+}
@@ -0,0 +1,95 @@
+package swarm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+)
+
+const defaultMigrationDrainTimeout = time.Minute
+
+// ConnMigrationConfig configures connection migration, see WithConnMigration.
+type ConnMigrationConfig struct {
+	// CloseWorse drains the connections that are worse than a new connection to the
+	// same peer: they refuse new streams, and are closed with network.ConnSupplanted
+	// once their streams are done. Otherwise the worse connections are kept open.
+	CloseWorse bool
+	// DrainTimeout is the maximum time a worse connection is drained before it's closed,
+	// even if it still has open streams. Defaults to 1 minute.
+	DrainTimeout time.Duration
+	// Rank ranks connections, higher is better. Defaults to DefaultConnRank.
+	Rank func(network.Conn) int
+}
+
+type connMigration struct {
+	ConnMigrationConfig
+	emitter event.Emitter
+}
+
+// WithConnMigration makes the swarm migrate to the best connection to a peer. New
+// streams are opened on the connection with the highest rank, and when a connection
+// with a higher rank than the existing connections to the peer is established, an
+// event.EvtConnectionMigrated is emitted for each worse connection. If
+// cfg.CloseWorse is set, the worse connections are drained and closed.
+//
+// Without connection migration, direct connections are preferred over relayed ones
+// for new streams, and connections are never closed because a better one exists.
+func WithConnMigration(cfg ConnMigrationConfig) Option {
+	return func(s *Swarm) error {
+		if cfg.DrainTimeout < 0 {
+			return errors.New("swarm: negative connection migration drain timeout")
+		}
+		if cfg.DrainTimeout == 0 {
+			cfg.DrainTimeout = defaultMigrationDrainTimeout
+		}
+		if cfg.Rank == nil {
+			cfg.Rank = DefaultConnRank
+		}
+		s.connMigration = &connMigration{ConnMigrationConfig: cfg}
+		return nil
+	}
+}
+
+// DefaultConnRank ranks limited connections lowest, then relayed connections, then
+// direct connections over stream transports like TCP and websocket, and QUIC based
+// connections highest.
+func DefaultConnRank(c network.Conn) int {
+	if c.Stat().Limited {
+		return 0
+	}
+	if isRelayAddr(c.RemoteMultiaddr()) {
+		return 1
+	}
+	switch c.ConnState().Transport {
+	case "quic", "quic-v1", "webtransport":
+		return 3
+	default:
+		return 2
+	}
+}
+
+// migrateConns migrates from the connections to the peer of c that are worse than c.
+func (s *Swarm) migrateConns(c *Conn) {
+	m := s.connMigration
+	rank := m.Rank(c)
+	for _, other := range s.ConnsToPeer(c.RemotePeer()) {
+		o := other.(*Conn)
+		if o == c || o.IsClosed() || o.draining.Load() || m.Rank(o) >= rank {
+			continue
+		}
+		log.Debugw("migrating connection", "peer", c.RemotePeer(), "from", o.RemoteMultiaddr(), "to", c.RemoteMultiaddr())
+		if m.CloseWorse {
+			o.DrainAndClose(m.DrainTimeout, network.ConnSupplanted)
+		}
+		m.emitter.Emit(event.EvtConnectionMigrated{
+			Peer:     c.RemotePeer(),
+			From:     o.ConnState(),
+			FromAddr: o.RemoteMultiaddr(),
+			To:       c.ConnState(),
+			ToAddr:   c.RemoteMultiaddr(),
+			Closing:  m.CloseWorse,
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package swarm
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnMigration(t *testing.T) {
+	bus := eventbus.NewBus()
+	s1 := makeSwarmWithBus(t, bus, WithConnMigration(ConnMigrationConfig{CloseWorse: true, DrainTimeout: 5 * time.Second}))
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+	s2.SetStreamHandler(func(s network.Stream) {})
+
+	sub, err := bus.Subscribe(new(event.EvtConnectionMigrated))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	var tcpAddr, quicAddr ma.Multiaddr
+	for _, a := range s2.ListenAddresses() {
+		if _, err := a.ValueForProtocol(ma.P_TCP); err == nil {
+			tcpAddr = a
+		} else {
+			quicAddr = a
+		}
+	}
+	p := s2.LocalPeer()
+	s1.Peerstore().AddAddrs(p, s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tc, err := s1.dialAddr(ctx, p, tcpAddr, nil)
+	require.NoError(t, err)
+	tcpConn, err := s1.addConn(tc, network.DirOutbound)
+	require.NoError(t, err)
+	str, err := s1.NewStream(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, tcpConn, str.Conn())
+
+	qc, err := s1.dialAddr(ctx, p, quicAddr, nil)
+	require.NoError(t, err)
+	quicConn, err := s1.addConn(qc, network.DirOutbound)
+	require.NoError(t, err)
+
+	select {
+	case e := <-sub.Out():
+		evt := e.(event.EvtConnectionMigrated)
+		require.Equal(t, p, evt.Peer)
+		require.Equal(t, "tcp", evt.From.Transport)
+		require.True(t, evt.FromAddr.Equal(tcpAddr))
+		require.Equal(t, "quic-v1", evt.To.Transport)
+		require.True(t, evt.ToAddr.Equal(quicAddr))
+		require.True(t, evt.Closing)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a migration event")
+	}
+
+	// new streams are opened on the QUIC connection
+	str2, err := s1.NewStream(ctx, p)
+	require.NoError(t, err)
+	require.Equal(t, quicConn, str2.Conn())
+	defer str2.Close()
+
+	// the TCP connection is closed once its stream is done
+	time.Sleep(200 * time.Millisecond)
+	require.False(t, tcpConn.IsClosed())
+	str.Close()
+	require.Eventually(t, tcpConn.IsClosed, 5*time.Second, 10*time.Millisecond)
+	reason, ok := tcpConn.CloseReason()
+	require.True(t, ok)
+	require.Equal(t, network.ConnSupplanted, reason.ErrorCode)
+	require.False(t, quicConn.IsClosed())
+}
+
+func TestConnMigrationKeepsWorseConns(t *testing.T) {
+	bus := eventbus.NewBus()
+	s1 := makeSwarmWithBus(t, bus, WithConnMigration(ConnMigrationConfig{
+		// rank the TCP connection higher, to check that a custom rank is used
+		Rank: func(c network.Conn) int {
+			if c.ConnState().Transport == "tcp" {
+				return 1
+			}
+			return 0
+		},
+	}))
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+
+	sub, err := bus.Subscribe(new(event.EvtConnectionMigrated))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	p := s2.LocalPeer()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var conns []*Conn
+	for _, a := range []string{"/udp/", "/tcp/"} {
+		for _, addr := range s2.ListenAddresses() {
+			if !strings.Contains(addr.String(), a) {
+				continue
+			}
+			tc, err := s1.dialAddr(ctx, p, addr, nil)
+			require.NoError(t, err)
+			c, err := s1.addConn(tc, network.DirOutbound)
+			require.NoError(t, err)
+			conns = append(conns, c)
+		}
+	}
+	require.Len(t, conns, 2)
+	quicConn, tcpConn := conns[0], conns[1]
+
+	select {
+	case e := <-sub.Out():
+		evt := e.(event.EvtConnectionMigrated)
+		require.Equal(t, "quic-v1", evt.From.Transport)
+		require.Equal(t, "tcp", evt.To.Transport)
+		require.False(t, evt.Closing)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a migration event")
+	}
+	require.Equal(t, tcpConn, s1.bestConnToPeer(p))
+	require.False(t, quicConn.IsClosed())
+	require.False(t, quicConn.draining.Load())
+}
@@ -19,7 +19,7 @@ type connectednessEventEmitter struct {
 	newConns      chan peer.ID
 	removeConnsMx sync.Mutex
 	// removeConns is a slice of peerIDs we have recently closed connections to
-	removeConns []peer.ID
+	removeConns []removedConn
 	// lastEvent is the last connectedness event sent for a particular peer.
 	lastEvent map[peer.ID]network.Connectedness
 	// connectedness is the function that gives the peers current connectedness state
@@ -58,7 +58,14 @@ func (c *connectednessEventEmitter) AddConn(p peer.ID) {
 	c.newConns <- p
 }
 
-func (c *connectednessEventEmitter) RemoveConn(p peer.ID) {
+// removedConn is a closed connection to peer p.
+type removedConn struct {
+	p      peer.ID
+	reason *network.ConnCloseReason
+}
+
+// RemoveConn records that a connection to p was closed for the given reason.
+func (c *connectednessEventEmitter) RemoveConn(p peer.ID, reason *network.ConnCloseReason) {
 	c.mx.RLock()
 	defer c.mx.RUnlock()
 	if c.ctx.Err() != nil {
@@ -72,7 +79,7 @@ func (c *connectednessEventEmitter) RemoveConn(p peer.ID) {
 	//
 	// We purposefully don't block/backpressure here to avoid deadlocks, since it's
 	// reasonable for a consumer of the event to want to remove a connection.
-	c.removeConns = append(c.removeConns, p)
+	c.removeConns = append(c.removeConns, removedConn{p: p, reason: reason})
 
 	c.removeConnsMx.Unlock()
 
@@ -92,7 +99,7 @@ func (c *connectednessEventEmitter) runEmitter() {
 	for {
 		select {
 		case p := <-c.newConns:
-			c.notifyPeer(p, true)
+			c.notifyPeer(p, true, nil)
 		case <-c.removeConnNotif:
 			c.sendConnRemovedNotifications()
 		case <-c.ctx.Done():
@@ -101,7 +108,7 @@ func (c *connectednessEventEmitter) runEmitter() {
 			for {
 				select {
 				case p := <-c.newConns:
-					c.notifyPeer(p, true)
+					c.notifyPeer(p, true, nil)
 				case <-c.removeConnNotif:
 					c.sendConnRemovedNotifications()
 				default:
@@ -115,20 +122,26 @@ func (c *connectednessEventEmitter) runEmitter() {
 // notifyPeer sends the peer connectedness event using the emitter.
 // Use forceNotConnectedEvent = true to send a NotConnected event even if
 // no Connected event was sent for this peer.
+// closeReason is the reason of the last closed connection, it's only set on
+// NotConnected events.
 // In case a peer is disconnected before we sent the Connected event, we still
 // send the Disconnected event because a connection to the peer can be observed
 // in such cases.
-func (c *connectednessEventEmitter) notifyPeer(p peer.ID, forceNotConnectedEvent bool) {
+func (c *connectednessEventEmitter) notifyPeer(p peer.ID, forceNotConnectedEvent bool, closeReason *network.ConnCloseReason) {
 	oldState := c.lastEvent[p]
 	c.lastEvent[p] = c.connectedness(p)
 	if c.lastEvent[p] == network.NotConnected {
 		delete(c.lastEvent, p)
 	}
 	if (forceNotConnectedEvent && c.lastEvent[p] == network.NotConnected) || c.lastEvent[p] != oldState {
-		c.emitter.Emit(event.EvtPeerConnectednessChanged{
+		evt := event.EvtPeerConnectednessChanged{
 			Peer:          p,
 			Connectedness: c.lastEvent[p],
-		})
+		}
+		if evt.Connectedness == network.NotConnected {
+			evt.CloseReason = closeReason
+		}
+		c.emitter.Emit(evt)
 	}
 }
 
@@ -137,7 +150,7 @@ func (c *connectednessEventEmitter) sendConnRemovedNotifications() {
 	removeConns := c.removeConns
 	c.removeConns = nil
 	c.removeConnsMx.Unlock()
-	for _, p := range removeConns {
-		c.notifyPeer(p, false)
+	for _, rc := range removeConns {
+		c.notifyPeer(rc.p, false, rc.reason)
 	}
 }
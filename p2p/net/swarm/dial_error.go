@@ -5,6 +5,7 @@ import (
 	"os"
 	"strings"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -19,6 +20,8 @@ type DialError struct {
 	DialErrors []TransportError
 	Cause      error
 	Skipped    int
+	// Trace is the trace of the dial, if dial tracing is enabled. See WithDialTracing.
+	Trace *event.EvtDialTrace
 }
 
 func (e *DialError) Timeout() bool {
@@ -5,6 +5,8 @@ import (
 	"os"
 	"testing"
 
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
 )
@@ -19,6 +21,7 @@ func TestDialError(t *testing.T) {
 	de := &DialError{Peer: "pid", Cause: ErrGaterDisallowedConnection}
 	require.ErrorIs(t, de, ErrGaterDisallowedConnection,
 		"DialError Unwrap should handle DialError.Cause")
+	require.ErrorIs(t, de, network.ErrPeerGated, "gater errors should match network.ErrPeerGated")
 	require.ErrorIs(t, de, de, "DialError Unwrap should handle match to self")
 
 	aa := ma.StringCast("/ip4/1.2.3.4/tcp/1234")
@@ -32,6 +35,14 @@ func TestDialError(t *testing.T) {
 	require.ErrorIs(t, de, ErrDialBackoff, "DialError.Unwrap should traverse TransportErrors")
 	require.ErrorIs(t, de, ErrNoTransport, "DialError.Unwrap should traverse TransportErrors")
 
+	de = &DialError{Peer: "pid", Cause: ErrNoGoodAddresses}
+	require.ErrorIs(t, de, network.ErrNoRemoteAddrs, "no addresses errors should match network.ErrNoRemoteAddrs")
+	de = &DialError{Peer: "pid", Cause: ErrDialRefusedHighWater}
+	require.ErrorIs(t, de, network.ErrResourceLimitExceeded,
+		"high water refusals should match network.ErrResourceLimitExceeded")
+	de = &DialError{Peer: "pid", Cause: ErrAllDialsFailed}
+	require.ErrorIs(t, de, network.ErrAllDialsFailed, "failed dials should match network.ErrAllDialsFailed")
+
 	de = &DialError{
 		Peer: "pid",
 		DialErrors: []TransportError{{Address: ab, Cause: ErrNoTransport},
@@ -0,0 +1,159 @@
+package swarm
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// maxDialHistoryPeers is the number of peers for which we remember the last successful dial
+// per address. Only used when a DialRankingPolicy is configured.
+const maxDialHistoryPeers = 1024
+
+// DialCandidate is an address passed to a DialRankingPolicy along with metadata
+// the swarm has about it.
+type DialCandidate struct {
+	Addr ma.Multiaddr
+	// Transport is the name of the protocol identifying the transport used to dial Addr,
+	// for example "quic-v1", "tcp", "webtransport", "ws", "webrtc-direct" or "p2p-circuit".
+	Transport string
+	// Public is true if Addr is a public IP address. Relay addresses are public if the relay
+	// address is public.
+	Public bool
+	// Private is true if Addr is a private or loopback IP address.
+	Private bool
+	// Relay is true if Addr is a circuit relay address.
+	Relay bool
+	// LastSuccess is the last time a dial to Addr succeeded. It is zero if the swarm has not
+	// successfully dialed the address recently.
+	LastSuccess time.Time
+	// RTT is the round trip time to Addr measured over previous connections, as
+	// recorded in the peerstore's AddrMetrics. It is zero if it's unknown.
+	RTT time.Duration
+}
+
+// DialRankingPolicy decides the schedule for dialing a peer's addresses. It has the same
+// contract as network.DialRanker but is given the peer and per address metadata.
+//
+// Implementations may replace the default ranking completely, or wrap it by calling
+// DefaultDialRanker on the addresses and adjusting the result.
+type DialRankingPolicy interface {
+	RankAddrs(p peer.ID, candidates []DialCandidate) []network.AddrDelay
+}
+
+// DialRankingPolicyFunc is an adapter to use ordinary functions as a DialRankingPolicy.
+type DialRankingPolicyFunc func(p peer.ID, candidates []DialCandidate) []network.AddrDelay
+
+// RankAddrs implements DialRankingPolicy.
+func (f DialRankingPolicyFunc) RankAddrs(p peer.ID, candidates []DialCandidate) []network.AddrDelay {
+	return f(p, candidates)
+}
+
+// CandidateAddrs returns the addresses of the candidates. It is useful for passing the
+// candidates to a network.DialRanker, like DefaultDialRanker.
+func CandidateAddrs(candidates []DialCandidate) []ma.Multiaddr {
+	addrs := make([]ma.Multiaddr, 0, len(candidates))
+	for _, c := range candidates {
+		addrs = append(addrs, c.Addr)
+	}
+	return addrs
+}
+
+// WithDialRankingPolicy configures the swarm to use p for ranking addresses for dialing.
+// It takes precedence over the ranker set with WithDialRanker.
+func WithDialRankingPolicy(p DialRankingPolicy) Option {
+	return func(s *Swarm) error {
+		if p == nil {
+			return errors.New("swarm: dial ranking policy cannot be nil")
+		}
+		s.dialRankingPolicy = p
+		s.dialHistory = newDialHistory()
+		return nil
+	}
+}
+
+// dialHistory records the last successful dial to a peer's addresses.
+type dialHistory struct {
+	mx    sync.Mutex
+	peers *lru.Cache[peer.ID, map[string]time.Time]
+}
+
+func newDialHistory() *dialHistory {
+	c, _ := lru.New[peer.ID, map[string]time.Time](maxDialHistoryPeers) // only errors for a non-positive size
+	return &dialHistory{peers: c}
+}
+
+func (h *dialHistory) recordSuccess(p peer.ID, a ma.Multiaddr, t time.Time) {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	m, ok := h.peers.Get(p)
+	if !ok {
+		m = make(map[string]time.Time)
+		h.peers.Add(p, m)
+	}
+	m[string(a.Bytes())] = t
+}
+
+func (h *dialHistory) lastSuccess(p peer.ID, a ma.Multiaddr) time.Time {
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	m, ok := h.peers.Peek(p)
+	if !ok {
+		return time.Time{}
+	}
+	return m[string(a.Bytes())]
+}
+
+// dialCandidates annotates addrs with the metadata passed to a DialRankingPolicy.
+func (s *Swarm) dialCandidates(p peer.ID, addrs []ma.Multiaddr) []DialCandidate {
+	am, _ := s.peers.(peerstore.AddrMetrics)
+	res := make([]DialCandidate, 0, len(addrs))
+	for _, a := range addrs {
+		c := DialCandidate{
+			Addr:      a,
+			Transport: transportName(a),
+			Public:    manet.IsPublicAddr(a),
+			Private:   manet.IsPrivateAddr(a),
+			Relay:     isRelayAddr(a),
+		}
+		if s.dialHistory != nil {
+			c.LastSuccess = s.dialHistory.lastSuccess(p, a)
+		}
+		if am != nil {
+			c.RTT = am.AddrLatencyEWMA(p, a)
+		}
+		res = append(res, c)
+	}
+	return res
+}
+
+// transportName returns the name of the protocol identifying the transport for a.
+func transportName(a ma.Multiaddr) string {
+	for _, p := range []int{
+		ma.P_CIRCUIT,
+		ma.P_WEBTRANSPORT,
+		ma.P_WEBRTC_DIRECT,
+		ma.P_WEBRTC,
+		ma.P_WSS,
+		ma.P_WS,
+		ma.P_QUIC_V1,
+		ma.P_QUIC,
+		ma.P_TCP,
+		ma.P_UDP,
+	} {
+		if isProtocolAddr(a, p) {
+			return ma.ProtocolWithCode(p).Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package swarm
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportName(t *testing.T) {
+	for addr, name := range map[string]string{
+		"/ip4/1.2.3.4/tcp/1":                      "tcp",
+		"/ip4/1.2.3.4/tcp/1/ws":                   "ws",
+		"/ip4/1.2.3.4/udp/1/quic-v1":              "quic-v1",
+		"/ip4/1.2.3.4/udp/1/quic-v1/webtransport": "webtransport",
+		"/ip4/1.2.3.4/udp/1/webrtc-direct":        "webrtc-direct",
+		"/ip4/1.2.3.4/udp/1/quic-v1/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit": "p2p-circuit",
+	} {
+		require.Equal(t, name, transportName(ma.StringCast(addr)), addr)
+	}
+}
+
+func TestDialRankingPolicy(t *testing.T) {
+	var mx sync.Mutex
+	var calls [][]DialCandidate
+	policy := DialRankingPolicyFunc(func(_ peer.ID, candidates []DialCandidate) []network.AddrDelay {
+		mx.Lock()
+		calls = append(calls, candidates)
+		mx.Unlock()
+		return DefaultDialRanker(CandidateAddrs(candidates))
+	})
+
+	s1 := makeSwarmWithNoListenAddrs(t, WithDialRankingPolicy(policy))
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+
+	var tcpAddr ma.Multiaddr
+	for _, a := range s2.ListenAddresses() {
+		if isProtocolAddr(a, ma.P_TCP) {
+			tcpAddr = a
+		}
+	}
+	require.NotNil(t, tcpAddr)
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), []ma.Multiaddr{tcpAddr}, peerstore.PermanentAddrTTL)
+
+	_, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+	require.NoError(t, s1.ClosePeer(s2.LocalPeer()))
+
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+
+	mx.Lock()
+	defer mx.Unlock()
+	require.Len(t, calls, 2)
+	for _, c := range calls {
+		require.Len(t, c, 1)
+		require.True(t, c[0].Addr.Equal(tcpAddr))
+		require.Equal(t, "tcp", c[0].Transport)
+		require.True(t, c[0].Private)
+		require.False(t, c[0].Public)
+		require.False(t, c[0].Relay)
+	}
+	require.True(t, calls[0][0].LastSuccess.IsZero())
+	require.False(t, calls[1][0].LastSuccess.IsZero())
+}
@@ -0,0 +1,86 @@
+package swarm
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// rttHeadStartFactor is the number of RTTs an address with a known RTT is dialed
+	// ahead of the next address. This is roughly the time the handshake takes.
+	rttHeadStartFactor = 2
+	// maxRTTHeadStart caps the head start of the addresses with a known RTT, so that a
+	// stale measurement doesn't delay the other addresses by much.
+	maxRTTHeadStart = PublicTCPDelay
+)
+
+// PreferLowRTT adjusts the dial schedule ranking so that the direct addresses with a
+// known RTT, as measured over previous connections, are dialed first, fastest first.
+//
+// The fastest address is dialed immediately, and every address is given a head start
+// of twice the fastest RTT, capped to 250ms, over the next one. The other addresses are
+// delayed by the same head start relative to ranking. Addresses with a known RTT are
+// never dialed later than in ranking. If no RTT is known, ranking is returned as is.
+//
+// candidates are the metadata of the ranked addresses, see DialCandidate.RTT.
+func PreferLowRTT(ranking []network.AddrDelay, candidates []DialCandidate) []network.AddrDelay {
+	var known []DialCandidate
+	for _, c := range candidates {
+		if c.RTT > 0 && !c.Relay {
+			known = append(known, c)
+		}
+	}
+	if len(known) == 0 {
+		return ranking
+	}
+	slices.SortStableFunc(known, func(a, b DialCandidate) int { return cmp.Compare(a.RTT, b.RTT) })
+	headStart := min(rttHeadStartFactor*known[0].RTT, maxRTTHeadStart)
+
+	rank := make(map[string]int, len(known))
+	for i, c := range known {
+		rank[string(c.Addr.Bytes())] = i
+	}
+	res := make([]network.AddrDelay, 0, len(ranking))
+	for _, ad := range ranking {
+		if i, ok := rank[string(ad.Addr.Bytes())]; ok {
+			ad.Delay = min(time.Duration(i)*headStart, ad.Delay)
+		} else {
+			ad.Delay += headStart
+		}
+		res = append(res, ad)
+	}
+	slices.SortStableFunc(res, func(a, b network.AddrDelay) int { return cmp.Compare(a.Delay, b.Delay) })
+	return res
+}
+
+// rankByRTT is the default ranking: DefaultDialRanker adjusted with PreferLowRTT.
+func (s *Swarm) rankByRTT(p peer.ID, addrs []ma.Multiaddr) []network.AddrDelay {
+	// DefaultDialRanker reorders addrs in place, the candidates keep their own copy.
+	candidates := s.dialCandidates(p, addrs)
+	return PreferLowRTT(DefaultDialRanker(addrs), candidates)
+}
+
+// recordRTT stores the RTT of an outbound connection in the peerstore, if it's known,
+// so that the fastest addresses of the peer are dialed first the next time.
+func (s *Swarm) recordRTT(c *Conn) {
+	stat := c.Stat()
+	if stat.Direction != network.DirOutbound {
+		return
+	}
+	am, ok := s.peers.(peerstore.AddrMetrics)
+	if !ok {
+		return
+	}
+	st, ok := stat.MuxerStats()
+	if !ok || st.RTT <= 0 {
+		return
+	}
+	am.RecordAddrLatency(c.RemotePeer(), c.RemoteMultiaddr(), st.RTT)
+}
@@ -0,0 +1,93 @@
+package swarm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreferLowRTT(t *testing.T) {
+	quic6 := ma.StringCast("/ip6/2001:db8::1/udp/1/quic-v1")
+	quic4 := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1")
+	tcp4 := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+	relay := ma.StringCast("/ip4/1.2.3.5/tcp/1/p2p/QmcgpsyWgH8Y8ajJz1Cu72KnS5uo2Aa2LpzU7kinSupNKC/p2p-circuit")
+	ranking := DefaultDialRanker([]ma.Multiaddr{quic6, quic4, tcp4, relay})
+	delays := func(res []network.AddrDelay) map[string]time.Duration {
+		m := make(map[string]time.Duration, len(res))
+		for _, ad := range res {
+			m[ad.Addr.String()] = ad.Delay
+		}
+		return m
+	}
+	orig := delays(ranking)
+
+	t.Run("no RTT", func(t *testing.T) {
+		candidates := []DialCandidate{{Addr: quic6}, {Addr: quic4}, {Addr: tcp4}, {Addr: relay, Relay: true, RTT: time.Millisecond}}
+		require.Equal(t, ranking, PreferLowRTT(ranking, candidates))
+	})
+
+	t.Run("known RTT", func(t *testing.T) {
+		candidates := []DialCandidate{
+			{Addr: quic6},
+			{Addr: quic4, RTT: 50 * time.Millisecond},
+			{Addr: tcp4, RTT: 10 * time.Millisecond},
+			{Addr: relay, Relay: true, RTT: time.Millisecond},
+		}
+		res := PreferLowRTT(ranking, candidates)
+		require.Len(t, res, len(ranking))
+		require.Equal(t, tcp4, res[0].Addr)
+		require.Zero(t, res[0].Delay)
+		d := delays(res)
+		require.Equal(t, 20*time.Millisecond, d[quic4.String()])
+		require.Equal(t, orig[quic6.String()]+20*time.Millisecond, d[quic6.String()])
+		require.Equal(t, orig[relay.String()]+20*time.Millisecond, d[relay.String()])
+	})
+
+	t.Run("head start is capped", func(t *testing.T) {
+		candidates := []DialCandidate{{Addr: quic6}, {Addr: quic4}, {Addr: tcp4, RTT: time.Second}, {Addr: relay, Relay: true}}
+		d := delays(PreferLowRTT(ranking, candidates))
+		require.Zero(t, d[tcp4.String()])
+		require.Equal(t, orig[quic6.String()]+maxRTTHeadStart, d[quic6.String()])
+	})
+}
+
+func TestRecordRTT(t *testing.T) {
+	s1 := makeSwarmWithNoListenAddrs(t)
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+	am, ok := s1.Peerstore().(peerstore.AddrMetrics)
+	require.True(t, ok)
+
+	for _, proto := range []int{ma.P_QUIC_V1, ma.P_TCP} {
+		t.Run(ma.ProtocolWithCode(proto).Name, func(t *testing.T) {
+			var addr ma.Multiaddr
+			for _, a := range s2.ListenAddresses() {
+				if isProtocolAddr(a, proto) && !isProtocolAddr(a, ma.P_WEBTRANSPORT) {
+					addr = a
+				}
+			}
+			require.NotNil(t, addr)
+			s1.Peerstore().ClearAddrs(s2.LocalPeer())
+			s1.Peerstore().AddAddrs(s2.LocalPeer(), []ma.Multiaddr{addr}, peerstore.PermanentAddrTTL)
+
+			c, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+			require.NoError(t, err)
+			require.Eventually(t, func() bool {
+				st, _ := c.Stat().MuxerStats()
+				return st.RTT > 0
+			}, 5*time.Second, 10*time.Millisecond)
+			require.NoError(t, s1.ClosePeer(s2.LocalPeer()))
+
+			require.Positive(t, am.AddrLatencyEWMA(s2.LocalPeer(), addr))
+			cands := s1.dialCandidates(s2.LocalPeer(), []ma.Multiaddr{addr})
+			require.Equal(t, am.AddrLatencyEWMA(s2.LocalPeer(), addr), cands[0].RTT)
+		})
+	}
+}
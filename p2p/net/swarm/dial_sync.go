@@ -7,6 +7,8 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 // dialWorkerFunc is used by dialSync to spawn a new dial worker
@@ -49,16 +51,28 @@ func (ad *activeDial) dial(ctx context.Context) (*Conn, error) {
 	if simConnect, isClient, reason := network.GetSimultaneousConnect(ctx); simConnect {
 		dialCtx = network.WithSimultaneousConnect(dialCtx, isClient, reason)
 	}
+	if transports := network.GetTransportConstraint(ctx); transports != nil {
+		dialCtx = network.WithTransportConstraint(dialCtx, transports...)
+	}
+	if f := network.GetAddrFilter(ctx); f != nil {
+		dialCtx = network.WithAddrFilter(dialCtx, f)
+	}
 
-	resch := make(chan dialResponse, 1)
+	req := dialRequest{ctx: dialCtx, resch: make(chan dialResponse, 1)}
+	if addrs, ok := network.GetDialAddrs(ctx); ok {
+		req.dialAddrs = addrs
+		if req.dialAddrs == nil {
+			req.dialAddrs = []ma.Multiaddr{}
+		}
+	}
 	select {
-	case ad.reqch <- dialRequest{ctx: dialCtx, resch: resch}:
+	case ad.reqch <- req:
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	}
 
 	select {
-	case res := <-resch:
+	case res := <-req.resch:
 		return res.conn, res.err
 	case <-ctx.Done():
 		return nil, ctx.Err()
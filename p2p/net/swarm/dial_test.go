@@ -2,6 +2,7 @@ package swarm_test
 
 import (
 	"context"
+	"errors"
 	"net"
 	"sync"
 	"testing"
@@ -11,6 +12,8 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	testutil "github.com/TheNoobiCat/go-libp2p/core/test"
+	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/discovery/backoff"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
 
@@ -465,6 +468,34 @@ func TestDialBackoffClears(t *testing.T) {
 	require.False(t, s1.Backoff().Backoff(s2.LocalPeer(), s2bad), "s2 should no longer be on backoff")
 }
 
+func TestDialBackoffRegistry(t *testing.T) {
+	r, err := backoff.NewRegistry()
+	require.NoError(t, err)
+	s1 := makeSwarms(t, 1, swarmt.WithSwarmOpts(swarm.WithDialTimeout(100*time.Millisecond), swarm.WithDialBackoff(r)))[0]
+	s2 := makeSwarms(t, 1)[0]
+	defer closeSwarms([]*swarm.Swarm{s1, s2})
+
+	_, s2bad, s2l := newSilentPeer(t)
+	go acceptAndHang(s2l)
+	defer s2l.Close()
+
+	s1.Peerstore().AddAddr(s2.LocalPeer(), s2bad, peerstore.PermanentAddrTTL)
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.Error(t, err)
+	require.True(t, r.Backoff(s2.LocalPeer(), s2bad))
+	// Backoff returns the registry
+	require.Same(t, r, s1.Backoff())
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.ErrorIs(t, err, swarm.ErrDialBackoff)
+
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+	c, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+	defer c.Close()
+	_, ok := r.Peer(s2.LocalPeer())
+	require.False(t, ok, "the backoffs of s2 should be cleared")
+}
+
 func TestDialPeerFailed(t *testing.T) {
 	swarms := makeSwarms(t, 2, swarmt.WithSwarmOpts(swarm.WithDialTimeout(100*time.Millisecond)))
 	defer closeSwarms(swarms)
@@ -520,6 +551,120 @@ func TestDialExistingConnection(t *testing.T) {
 	}
 }
 
+func TestDialTransportConstraint(t *testing.T) {
+	swarms := makeSwarms(t, 2)
+	defer closeSwarms(swarms)
+	s1 := swarms[0]
+	s2 := swarms[1]
+
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+
+	ctx := network.WithTransportConstraint(context.Background(), "quic-v1")
+	c1, err := s1.DialPeer(ctx, s2.LocalPeer())
+	require.NoError(t, err)
+	require.Equal(t, "quic-v1", c1.RemoteMultiaddr()[len(c1.RemoteMultiaddr())-1].Protocol().Name)
+
+	// the QUIC connection isn't acceptable, a TCP connection is dialed
+	ctx = network.WithTransportConstraint(context.Background(), "tcp")
+	c2, err := s1.DialPeer(ctx, s2.LocalPeer())
+	require.NoError(t, err)
+	require.Equal(t, "tcp", c2.RemoteMultiaddr()[len(c2.RemoteMultiaddr())-1].Protocol().Name)
+	require.Len(t, s1.ConnsToPeer(s2.LocalPeer()), 2)
+
+	// the existing connections are reused
+	str, err := s1.NewStream(network.WithTransportConstraint(context.Background(), "quic-v1"), s2.LocalPeer())
+	require.NoError(t, err)
+	require.Equal(t, c1, str.Conn())
+	str.Reset()
+	str, err = s1.NewStream(network.WithTransportConstraint(context.Background(), "tcp"), s2.LocalPeer())
+	require.NoError(t, err)
+	require.Equal(t, c2, str.Conn())
+	str.Reset()
+
+	// there are no addresses matching the constraint
+	ctx = network.WithTransportConstraint(context.Background(), "p2p-circuit")
+	_, err = s1.DialPeer(ctx, s2.LocalPeer())
+	require.ErrorIs(t, err, swarm.ErrNoGoodAddresses)
+}
+
+func TestDialAddrFilter(t *testing.T) {
+	swarms := makeSwarms(t, 2)
+	defer closeSwarms(swarms)
+	s1 := swarms[0]
+	s2 := swarms[1]
+
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+
+	_, err := s1.DialPeer(network.WithAddrFilter(context.Background(), manet.IsPublicAddr), s2.LocalPeer())
+	require.ErrorIs(t, err, swarm.ErrNoGoodAddresses)
+
+	isTCP := func(a ma.Multiaddr) bool {
+		_, err := a.ValueForProtocol(ma.P_TCP)
+		return err == nil
+	}
+	c, err := s1.DialPeer(network.WithAddrFilter(context.Background(), isTCP), s2.LocalPeer())
+	require.NoError(t, err)
+	require.True(t, isTCP(c.RemoteMultiaddr()))
+}
+
+func TestDialAddrs(t *testing.T) {
+	swarms := makeSwarms(t, 2)
+	defer closeSwarms(swarms)
+	s1 := swarms[0]
+	s2 := swarms[1]
+
+	// the peerstore addresses aren't dialed
+	s2silentAddrs, s2silentListener := newSilentListener(t)
+	defer s2silentListener.Close()
+	go acceptAndHang(s2silentListener)
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2silentAddrs, peerstore.PermanentAddrTTL)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	c, err := s1.DialPeer(network.WithDialAddrs(ctx, s2.ListenAddresses()), s2.LocalPeer())
+	require.NoError(t, err)
+	require.Contains(t, s2.ListenAddresses(), c.RemoteMultiaddr())
+	// and the dialed addresses aren't added to the peerstore
+	require.ElementsMatch(t, s2silentAddrs, s1.Peerstore().Addrs(s2.LocalPeer()))
+
+	_, err = s1.DialPeer(network.WithDialAddrs(ctx, nil), swarms[1].LocalPeer())
+	require.NoError(t, err, "the existing connection is used")
+}
+
+// dialCtxTransport is a relay transport that records the contexts it's dialed with.
+type dialCtxTransport struct {
+	dummyTransport
+	dialCtxs chan context.Context
+}
+
+func (t *dialCtxTransport) Dial(ctx context.Context, _ ma.Multiaddr, _ peer.ID) (transport.CapableConn, error) {
+	t.dialCtxs <- ctx
+	return nil, errors.New("dial failed")
+}
+
+func (t *dialCtxTransport) CanDial(_ ma.Multiaddr) bool { return true }
+
+func TestDialAddrsNotPassedToTransport(t *testing.T) {
+	s := swarmt.GenSwarm(t)
+	defer s.Close()
+	tpt := &dialCtxTransport{
+		dummyTransport: dummyTransport{protocols: []int{ma.P_CIRCUIT}, proxy: true},
+		dialCtxs:       make(chan context.Context, 1),
+	}
+	require.NoError(t, s.AddTransport(tpt))
+
+	relayAddr := ma.StringCast("/ip4/1.2.3.4/tcp/1/p2p/" + testutil.RandPeerIDFatal(t).String() + "/p2p-circuit")
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := s.DialPeer(network.WithDialAddrs(ctx, []ma.Multiaddr{relayAddr}), testutil.RandPeerIDFatal(t))
+	require.Error(t, err)
+
+	// The transport dials the relay itself, using the peerstore addresses of the relay.
+	dialCtx := <-tpt.dialCtxs
+	_, ok := network.GetDialAddrs(dialCtx)
+	require.False(t, ok)
+}
+
 func newSilentListener(t *testing.T) ([]ma.Multiaddr, net.Listener) {
 	lst, err := net.Listen("tcp4", "localhost:0")
 	if err != nil {
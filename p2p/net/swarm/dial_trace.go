@@ -0,0 +1,122 @@
+package swarm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+)
+
+// WithDialTracing makes the swarm record how it dials peers. For every request to
+// connect to a peer that requires dialing, an event.EvtDialTrace with the ranked
+// addresses, their dial delays and the outcome of the dials is emitted on the event bus.
+// On failure, the trace is also attached to the returned DialError.
+func WithDialTracing() Option {
+	return func(s *Swarm) error {
+		s.dialTracing = true
+		return nil
+	}
+}
+
+// dialTrace collects the trace of a dial request.
+type dialTrace struct {
+	evt event.EvtDialTrace
+	// addrs maps the bytes of the ranked addresses to their index in evt.Addrs
+	addrs map[string]int
+	cl    Clock
+}
+
+// newDialTrace starts the trace of a request. It returns nil if tracing is disabled.
+func (w *dialWorker) newDialTrace(start time.Time, ranking []network.AddrDelay, excluded []TransportError) *dialTrace {
+	if !w.s.dialTracing {
+		return nil
+	}
+	t := &dialTrace{
+		evt: event.EvtDialTrace{
+			Peer:  w.peer,
+			Start: start,
+			Addrs: make([]event.DialTraceAddr, 0, len(ranking)),
+		},
+		addrs: make(map[string]int, len(ranking)),
+		cl:    w.cl,
+	}
+	for _, a := range ranking {
+		t.addrs[string(a.Addr.Bytes())] = len(t.evt.Addrs)
+		t.evt.Addrs = append(t.evt.Addrs, event.DialTraceAddr{Addr: a.Addr, Delay: a.Delay})
+	}
+	for _, te := range excluded {
+		t.evt.Excluded = append(t.evt.Excluded, event.DialTraceAddr{Addr: te.Address, Error: te.Cause})
+	}
+	return t
+}
+
+func (t *dialTrace) addr(ad *addrDial) *event.DialTraceAddr {
+	if t == nil {
+		return nil
+	}
+	i, ok := t.addrs[string(ad.addr.Bytes())]
+	if !ok {
+		return nil
+	}
+	return &t.evt.Addrs[i]
+}
+
+// recordDial records that ad was dialed.
+func (t *dialTrace) recordDial(ad *addrDial) {
+	if ta := t.addr(ad); ta != nil {
+		ta.Dialed = true
+		ta.DialedAt = ad.dialedAt
+	}
+}
+
+// recordResult records the outcome of the dial to ad. err is nil on success.
+func (t *dialTrace) recordResult(ad *addrDial, err error) {
+	ta := t.addr(ad)
+	if ta == nil {
+		return
+	}
+	if ad.dialed {
+		ta.Dialed = true
+		ta.DialedAt = ad.dialedAt
+		ta.Duration = t.cl.Since(ad.dialedAt)
+	}
+	ta.Connected = err == nil
+	ta.Error = err
+}
+
+// traceDial records that ad was dialed in the traces of the pending requests.
+func (w *dialWorker) traceDial(ad *addrDial) {
+	if !w.s.dialTracing {
+		return
+	}
+	for pr := range w.pendingRequests {
+		pr.trace.recordDial(ad)
+	}
+}
+
+// respond sends the response to the request, completing its trace. The trace is
+// emitted after responding, in the background, so that a slow subscriber doesn't
+// delay the dial nor block the dial worker.
+func (w *dialWorker) respond(req dialRequest, t *dialTrace, res dialResponse) {
+	if t == nil {
+		req.resch <- res
+		return
+	}
+	t.evt.Duration = w.cl.Since(t.evt.Start)
+	if res.conn != nil {
+		t.evt.ConnAddr = res.conn.RemoteMultiaddr()
+	}
+	t.evt.Error = res.err
+	var derr *DialError
+	if errors.As(res.err, &derr) {
+		derr.Trace = &t.evt
+	}
+	req.resch <- res
+	evt := t.evt
+	go func() {
+		if err := w.s.dialTraceEmitter.Emit(evt); err != nil {
+			log.Debugw("failed to emit dial trace", "error", err)
+		}
+	}()
+}
@@ -0,0 +1,92 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialTracing(t *testing.T) {
+	bus := eventbus.NewBus()
+	s1 := makeSwarmWithBus(t, bus, WithDialTracing())
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+	sub, err := bus.Subscribe(new(event.EvtDialTrace))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	closedAddr := ma.StringCast("/ip4/127.0.0.1/tcp/1")
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), append(s2.ListenAddresses(), closedAddr), peerstore.PermanentAddrTTL)
+	conn, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+
+	var evt event.EvtDialTrace
+	select {
+	case e := <-sub.Out():
+		evt = e.(event.EvtDialTrace)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected a dial trace")
+	}
+	require.Equal(t, s2.LocalPeer(), evt.Peer)
+	require.NoError(t, evt.Error)
+	require.Equal(t, conn.RemoteMultiaddr(), evt.ConnAddr)
+	require.Len(t, evt.Addrs, 3)
+	connected := 0
+	for i, a := range evt.Addrs {
+		if i > 0 {
+			require.LessOrEqual(t, evt.Addrs[i-1].Delay, a.Delay)
+		}
+		if a.Connected {
+			connected++
+			require.True(t, a.Dialed)
+			require.True(t, a.Addr.Equal(conn.RemoteMultiaddr()))
+		}
+	}
+	require.Equal(t, 1, connected)
+
+	// the trace is attached to the error on failure
+	p := test.RandPeerIDFatal(t)
+	s1.Peerstore().AddAddrs(p, []ma.Multiaddr{closedAddr, ma.StringCast("/ip4/127.0.0.1/tcp/2/ws")}, peerstore.PermanentAddrTTL)
+	_, err = s1.DialPeer(context.Background(), p)
+	var derr *DialError
+	require.True(t, errors.As(err, &derr))
+	require.NotNil(t, derr.Trace)
+	require.Equal(t, p, derr.Trace.Peer)
+	require.Equal(t, err, derr.Trace.Error)
+	require.Len(t, derr.Trace.Addrs, 1)
+	require.True(t, derr.Trace.Addrs[0].Dialed)
+	require.Error(t, derr.Trace.Addrs[0].Error)
+	require.Len(t, derr.Trace.Excluded, 1)
+	require.Error(t, derr.Trace.Excluded[0].Error)
+}
+
+func TestDialTracingSlowSubscriber(t *testing.T) {
+	bus := eventbus.NewBus()
+	s := makeSwarmWithBus(t, bus, WithDialTracing())
+	defer s.Close()
+	// a subscriber that never reads its events
+	sub, err := bus.Subscribe(new(event.EvtDialTrace), eventbus.BufSize(1))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	closedAddr := ma.StringCast("/ip4/127.0.0.1/tcp/1")
+	for i := 0; i < 3; i++ {
+		p := test.RandPeerIDFatal(t)
+		s.Peerstore().AddAddrs(p, []ma.Multiaddr{closedAddr}, peerstore.PermanentAddrTTL)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		_, err := s.DialPeer(ctx, p)
+		cancel()
+		require.Error(t, err)
+		require.NotErrorIs(t, err, context.DeadlineExceeded)
+	}
+}
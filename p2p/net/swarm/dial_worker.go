@@ -24,6 +24,10 @@ type dialRequest struct {
 	ctx context.Context
 	// resch is the channel used to send the response for this query
 	resch chan dialResponse
+	// dialAddrs are the addresses set with network.WithDialAddrs, nil if none are set.
+	// They're kept out of ctx, which is passed on to the transports: a transport's own
+	// dials, e.g. to the relay of a relay address, must use the peerstore.
+	dialAddrs []ma.Multiaddr
 }
 
 // dialResponse is the response sent to dialRequests on the request's resch channel
@@ -46,6 +50,8 @@ type pendRequest struct {
 	// the addr is removed from the map and err is updated. On a successful dial, the dialRequest is
 	// completed and response is sent with the connection
 	addrs map[string]struct{}
+	// trace is the trace of the request, nil if dial tracing is disabled
+	trace *dialTrace
 }
 
 // addrDial tracks dials to a particular multiaddress.
@@ -60,6 +66,10 @@ type addrDial struct {
 	err error
 	// dialed indicates whether we have triggered the dial to the address
 	dialed bool
+	// dialedAt is the time the dial to the address was triggered
+	dialedAt time.Time
+	// cancel cancels the dial while it's in flight. It's nil once the dial has completed.
+	cancel context.CancelCauseFunc
 	// createdAt is the time this struct was created
 	createdAt time.Time
 	// dialRankingDelay is the delay in dialing this address introduced by the ranking logic
@@ -146,6 +156,23 @@ func (w *dialWorker) loop() {
 		}
 	}
 
+	// budgetTimer triggers when the dial budget of the pending requests is spent
+	budgetTimer := w.cl.InstantTimer(startTime.Add(math.MaxInt64))
+	defer budgetTimer.Stop()
+
+	budgetRunning := true
+	// startBudget starts the dial budget for new requests
+	startBudget := func() {
+		if budgetRunning && !budgetTimer.Stop() {
+			<-budgetTimer.Ch()
+		}
+		budgetRunning = false
+		if w.s.dialBudget > 0 {
+			budgetTimer.Reset(w.cl.Now().Add(w.s.dialBudget))
+			budgetRunning = true
+		}
+	}
+
 	// totalDials is used to track number of dials made by this worker for metrics
 	totalDials := 0
 loop:
@@ -162,7 +189,7 @@ loop:
 		case req, ok := <-w.reqch:
 			if !ok {
 				if w.s.metricsTracer != nil {
-					w.s.metricsTracer.DialCompleted(w.connected, totalDials, time.Since(startTime))
+					w.s.metricsTracer.DialCompleted(w.connected, totalDials, w.cl.Since(startTime))
 				}
 				return
 			}
@@ -177,14 +204,19 @@ loop:
 				continue loop
 			}
 
-			addrs, addrErrs, err := w.s.addrsForDial(req.ctx, w.peer)
+			reqStart := w.cl.Now()
+			addrsCtx := req.ctx
+			if req.dialAddrs != nil {
+				addrsCtx = network.WithDialAddrs(addrsCtx, req.dialAddrs)
+			}
+			addrs, addrErrs, err := w.s.addrsForDial(addrsCtx, w.peer)
 			if err != nil {
-				req.resch <- dialResponse{
+				w.respond(req, w.newDialTrace(reqStart, nil, addrErrs), dialResponse{
 					err: &DialError{
 						Peer:       w.peer,
 						DialErrors: addrErrs,
 						Cause:      err,
-					}}
+					}})
 				continue loop
 			}
 
@@ -198,6 +230,7 @@ loop:
 				req:   req,
 				addrs: make(map[string]struct{}, len(addrRanking)),
 				err:   &DialError{Peer: w.peer, DialErrors: addrErrs},
+				trace: w.newDialTrace(reqStart, addrRanking, addrErrs),
 			}
 			for _, adelay := range addrRanking {
 				pr.addrs[string(adelay.Addr.Bytes())] = struct{}{}
@@ -221,29 +254,37 @@ loop:
 
 				if ad.conn != nil {
 					// dial to this addr was successful, complete the request
-					req.resch <- dialResponse{conn: ad.conn}
+					pr.trace.recordResult(ad, nil)
+					w.respond(req, pr.trace, dialResponse{conn: ad.conn})
 					continue loop
 				}
 
 				if ad.err != nil {
 					// dial to this addr errored, accumulate the error
 					pr.err.recordErr(ad.addr, ad.err)
+					pr.trace.recordResult(ad, ad.err)
 					delete(pr.addrs, string(ad.addr.Bytes()))
 					continue
 				}
 
 				// dial is still pending, add to the join list
+				if ad.dialed {
+					pr.trace.recordDial(ad)
+				}
 				tojoin = append(tojoin, ad)
 			}
 
 			if len(todial) == 0 && len(tojoin) == 0 {
 				// all request applicable addrs have been dialed, we must have errored
 				pr.err.Cause = ErrAllDialsFailed
-				req.resch <- dialResponse{err: pr.err}
+				w.respond(req, pr.trace, dialResponse{err: pr.err})
 				continue loop
 			}
 
 			// The request has some pending or new dials
+			if len(w.pendingRequests) == 0 {
+				startBudget()
+			}
 			w.pendingRequests[pr] = struct{}{}
 
 			for _, ad := range tojoin {
@@ -265,7 +306,7 @@ loop:
 			}
 
 			if len(todial) > 0 {
-				now := time.Now()
+				now := w.cl.Now()
 				// these are new addresses, track them and add them to dq
 				for _, a := range todial {
 					w.trackedDials[string(a.Bytes())] = &addrDial{
@@ -285,7 +326,7 @@ loop:
 			// because if the timer triggered before the delay, it means that all
 			// the inflight dials have errored and we should dial the next batch of
 			// addresses
-			now := time.Now()
+			now := w.cl.Now()
 			for _, adelay := range dq.NextBatch() {
 				// spawn the dial
 				ad, ok := w.trackedDials[string(adelay.Addr.Bytes())]
@@ -294,13 +335,18 @@ loop:
 					continue
 				}
 				ad.dialed = true
+				ad.dialedAt = now
 				ad.dialRankingDelay = now.Sub(ad.createdAt)
-				err := w.s.dialNextAddr(ad.ctx, w.peer, ad.addr, w.resch)
+				w.traceDial(ad)
+				dialCtx, cancel := context.WithCancelCause(ad.ctx)
+				err := w.s.dialNextAddr(dialCtx, w.peer, ad.addr, w.resch)
 				if err != nil {
 					// Errored without attempting a dial. This happens in case of
 					// backoff or black hole.
+					cancel(nil)
 					w.dispatchError(ad, err)
 				} else {
+					ad.cancel = cancel
 					// the dial was successful. update inflight dials
 					dialsInFlight++
 					totalDials++
@@ -310,6 +356,14 @@ loop:
 			// schedule more dials
 			scheduleNextDial()
 
+		case <-budgetTimer.Ch():
+			budgetRunning = false
+			if len(w.pendingRequests) == 0 {
+				continue loop
+			}
+			w.exceedDialBudget(dq)
+			scheduleNextDial()
+
 		case res := <-w.resch:
 			// A dial to an address has completed.
 			// Update all requests waiting on this address. On success, complete the request.
@@ -338,6 +392,11 @@ loop:
 			}
 			dialsInFlight--
 			ad.expectedTCPUpgradeTime = time.Time{}
+			if ad.cancel != nil {
+				// release the dial context
+				ad.cancel(nil)
+				ad.cancel = nil
+			}
 			if res.Conn != nil {
 				// we got a connection, add it to the swarm
 				conn, err := w.s.addConn(res.Conn, network.DirOutbound)
@@ -350,18 +409,23 @@ loop:
 
 				for pr := range w.pendingRequests {
 					if _, ok := pr.addrs[string(ad.addr.Bytes())]; ok {
-						pr.req.resch <- dialResponse{conn: conn}
+						pr.trace.recordResult(ad, nil)
+						w.respond(pr.req, pr.trace, dialResponse{conn: conn})
 						delete(w.pendingRequests, pr)
 					}
 				}
 
 				ad.conn = conn
+				if w.s.dialHistory != nil {
+					w.s.dialHistory.recordSuccess(w.peer, ad.addr, w.cl.Now())
+				}
 				if !w.connected {
 					w.connected = true
 					if w.s.metricsTracer != nil {
 						w.s.metricsTracer.DialRankingDelay(ad.dialRankingDelay)
 					}
 				}
+				w.cancelLosingDials()
 
 				continue loop
 			}
@@ -371,7 +435,7 @@ loop:
 			if res.Err != ErrDialRefusedBlackHole && res.Err != context.Canceled && !w.connected {
 				// we only add backoff if there has not been a successful connection
 				// for consistency with the old dialer behavior.
-				w.s.backf.AddBackoff(w.peer, res.Addr)
+				w.s.dialBackoff.AddBackoff(w.peer, res.Addr)
 			} else if res.Err == ErrDialRefusedBlackHole {
 				log.Errorf("SWARM BUG: unexpected ErrDialRefusedBlackHole while dialing peer %s to addr %s",
 					w.peer, res.Addr)
@@ -386,6 +450,68 @@ loop:
 	}
 }
 
+// cancelLosingDials cancels the in-flight dials that no pending request is waiting for,
+// once a dial to the peer has succeeded. Letting them complete would only waste a
+// handshake and a connection slot, as the resulting connection isn't needed.
+// The canceled dials complete with an error, which is dispatched as usual.
+func (w *dialWorker) cancelLosingDials() {
+	for k, ad := range w.trackedDials {
+		if ad.cancel == nil {
+			continue
+		}
+		if w.isAwaited(k) {
+			continue
+		}
+		ad.cancel(errConcurrentDialSuccessful)
+		ad.cancel = nil
+		if mt, ok := w.s.metricsTracer.(CanceledDialTracer); ok {
+			mt.CanceledDial(ad.addr, w.cl.Since(ad.dialedAt))
+		}
+	}
+}
+
+// exceedDialBudget aborts the dials to the peer once the dial budget is spent. The
+// pending requests fail with ErrDialBudgetExceeded, the in-flight dials are canceled
+// and the queued addresses are dropped, so that later requests dial them again.
+func (w *dialWorker) exceedDialBudget(dq *dialQueue) {
+	for pr := range w.pendingRequests {
+		for a := range pr.addrs {
+			if ad, ok := w.trackedDials[a]; ok {
+				pr.err.recordErr(ad.addr, ErrDialBudgetExceeded)
+				pr.trace.recordResult(ad, ErrDialBudgetExceeded)
+			}
+		}
+		pr.err.Cause = ErrDialBudgetExceeded
+		w.respond(pr.req, pr.trace, dialResponse{err: pr.err})
+		delete(w.pendingRequests, pr)
+	}
+	for k, ad := range w.trackedDials {
+		if !ad.dialed {
+			delete(w.trackedDials, k)
+			continue
+		}
+		if ad.cancel != nil {
+			ad.cancel(ErrDialBudgetExceeded)
+			ad.cancel = nil
+		}
+	}
+	dq.q = dq.q[:0]
+	if mt, ok := w.s.metricsTracer.(DialBudgetTracer); ok {
+		mt.ExceededDialBudget()
+	}
+}
+
+// isAwaited returns whether a pending request is waiting for the dial to the address
+// with bytes addr.
+func (w *dialWorker) isAwaited(addr string) bool {
+	for pr := range w.pendingRequests {
+		if _, ok := pr.addrs[addr]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // dispatches an error to a specific addr dial
 func (w *dialWorker) dispatchError(ad *addrDial, err error) {
 	ad.err = err
@@ -393,6 +519,7 @@ func (w *dialWorker) dispatchError(ad *addrDial, err error) {
 		// accumulate the error
 		if _, ok := pr.addrs[string(ad.addr.Bytes())]; ok {
 			pr.err.recordErr(ad.addr, err)
+			pr.trace.recordResult(ad, err)
 			delete(pr.addrs, string(ad.addr.Bytes()))
 			if len(pr.addrs) == 0 {
 				// all addrs have erred, dispatch dial error
@@ -400,10 +527,10 @@ func (w *dialWorker) dispatchError(ad *addrDial, err error) {
 				// a simultaneous dial that started later and added new acceptable addrs
 				c := w.s.bestAcceptableConnToPeer(pr.req.ctx, w.peer)
 				if c != nil {
-					pr.req.resch <- dialResponse{conn: c}
+					w.respond(pr.req, pr.trace, dialResponse{conn: c})
 				} else {
 					pr.err.Cause = ErrAllDialsFailed
-					pr.req.resch <- dialResponse{err: pr.err}
+					w.respond(pr.req, pr.trace, dialResponse{err: pr.err})
 				}
 				delete(w.pendingRequests, pr)
 			}
@@ -425,7 +552,13 @@ func (w *dialWorker) rankAddrs(addrs []ma.Multiaddr, isSimConnect bool) []networ
 	if isSimConnect {
 		return NoDelayDialRanker(addrs)
 	}
-	return w.s.dialRanker(addrs)
+	if w.s.dialRankingPolicy != nil {
+		return w.s.dialRankingPolicy.RankAddrs(w.peer, w.s.dialCandidates(w.peer, addrs))
+	}
+	if w.s.dialRanker != nil {
+		return w.s.dialRanker(addrs)
+	}
+	return w.s.rankByRTT(w.peer, addrs)
 }
 
 // dialQueue is a priority queue used to schedule dials
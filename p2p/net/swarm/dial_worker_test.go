@@ -15,6 +15,7 @@ import (
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
@@ -70,6 +71,10 @@ func makeSwarm(t *testing.T) *Swarm {
 }
 
 func makeSwarmWithNoListenAddrs(t *testing.T, opts ...Option) *Swarm {
+	return makeSwarmWithBus(t, eventbus.NewBus(), opts...)
+}
+
+func makeSwarmWithBus(t *testing.T, bus event.Bus, opts ...Option) *Swarm {
 	priv, id := newPeer(t)
 
 	ps, err := pstoremem.NewPeerstore()
@@ -78,7 +83,7 @@ func makeSwarmWithNoListenAddrs(t *testing.T, opts ...Option) *Swarm {
 	ps.AddPrivKey(id, priv)
 	t.Cleanup(func() { ps.Close() })
 
-	s, err := NewSwarm(id, ps, eventbus.NewBus(), opts...)
+	s, err := NewSwarm(id, ps, bus, opts...)
 	require.NoError(t, err)
 
 	upgrader := makeUpgrader(t, s)
@@ -1024,6 +1029,190 @@ func TestDialWorkerLoopHolePunching(t *testing.T) {
 	}
 }
 
+type canceledDialsTracer struct {
+	*metricsTracer
+	mx       sync.Mutex
+	canceled []ma.Multiaddr
+}
+
+func (m *canceledDialsTracer) CanceledDial(addr ma.Multiaddr, wasted time.Duration) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.canceled = append(m.canceled, addr)
+}
+
+func (m *canceledDialsTracer) Canceled() []ma.Multiaddr {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]ma.Multiaddr(nil), m.canceled...)
+}
+
+func TestDialWorkerLoopCancelLosingDials(t *testing.T) {
+	tracer := &canceledDialsTracer{metricsTracer: &metricsTracer{}}
+	s1 := makeSwarmWithNoListenAddrs(t, WithMetricsTracer(tracer))
+	defer s1.Close()
+
+	s2 := makeSwarmWithNoListenAddrs(t)
+	defer s2.Close()
+	require.NoError(t, s2.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0")))
+	// t2 will succeed
+	t2 := s2.ListenAddresses()[0]
+
+	// t1 will accept and keep the other end waiting
+	recvCh := make(chan struct{})
+	list, ch := makeTCPListener(t, ma.StringCast("/ip4/127.0.0.1/tcp/0"), recvCh)
+	defer list.Close()
+	defer func() { ch <- struct{}{} }() // close listener
+	t1 := list.Multiaddr()
+
+	s1.dialRanker = func(addrs []ma.Multiaddr) (res []network.AddrDelay) {
+		res = make([]network.AddrDelay, len(addrs))
+		for i, a := range addrs {
+			delay := 100 * time.Millisecond
+			if a.Equal(t1) {
+				delay = 0
+			}
+			res[i] = network.AddrDelay{Addr: a, Delay: delay}
+		}
+		return
+	}
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), []ma.Multiaddr{t1, t2}, peerstore.PermanentAddrTTL)
+
+	reqch := make(chan dialRequest)
+	resch := make(chan dialResponse, 1)
+	worker := newDialWorker(s1, s2.LocalPeer(), reqch, nil)
+	go worker.loop()
+	defer worker.wg.Wait()
+	defer close(reqch)
+
+	reqch <- dialRequest{ctx: context.Background(), resch: resch}
+	<-recvCh // received connection on t1
+
+	select {
+	case r := <-resch:
+		require.NoError(t, r.err)
+		require.True(t, r.conn.RemoteMultiaddr().Equal(t2))
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected conn to succeed")
+	}
+
+	// the dial to t1 is canceled instead of waiting for the handshake to time out
+	require.Eventually(t, func() bool {
+		s1.limiter.lk.Lock()
+		defer s1.limiter.lk.Unlock()
+		return s1.limiter.fdConsuming == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	canceled := tracer.Canceled()
+	require.Len(t, canceled, 1)
+	require.True(t, canceled[0].Equal(t1))
+}
+
+type dialLimitsTracer struct {
+	*metricsTracer
+	mx             sync.Mutex
+	causes         []error
+	budgetExceeded int
+}
+
+func (m *dialLimitsTracer) FailedDialing(addr ma.Multiaddr, dialErr error, cause error) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.causes = append(m.causes, cause)
+}
+
+func (m *dialLimitsTracer) ExceededDialBudget() {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.budgetExceeded++
+}
+
+func (m *dialLimitsTracer) Causes() []error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]error(nil), m.causes...)
+}
+
+func (m *dialLimitsTracer) BudgetExceeded() int {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return m.budgetExceeded
+}
+
+func TestDialWorkerLoopDialLimits(t *testing.T) {
+	// dial runs a dial from a swarm with opts to a peer with a stalling address dialed
+	// first, and a working address dialed after a long delay.
+	dial := func(t *testing.T, opts ...Option) (dialResponse, *dialLimitsTracer) {
+		tracer := &dialLimitsTracer{metricsTracer: &metricsTracer{}}
+		s1 := makeSwarmWithNoListenAddrs(t, append(opts, WithMetricsTracer(tracer))...)
+		defer s1.Close()
+
+		s2 := makeSwarmWithNoListenAddrs(t)
+		defer s2.Close()
+		require.NoError(t, s2.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0")))
+		t2 := s2.ListenAddresses()[0]
+
+		// t1 accepts and never completes the handshake
+		recvCh := make(chan struct{}, 1)
+		list, ch := makeTCPListener(t, ma.StringCast("/ip4/127.0.0.1/tcp/0"), recvCh)
+		defer list.Close()
+		defer func() { ch <- struct{}{} }() // close listener
+		t1 := list.Multiaddr()
+
+		s1.dialRanker = func(addrs []ma.Multiaddr) (res []network.AddrDelay) {
+			res = make([]network.AddrDelay, len(addrs))
+			for i, a := range addrs {
+				delay := time.Minute
+				if a.Equal(t1) {
+					delay = 0
+				}
+				res[i] = network.AddrDelay{Addr: a, Delay: delay}
+			}
+			return
+		}
+		s1.Peerstore().AddAddrs(s2.LocalPeer(), []ma.Multiaddr{t1, t2}, peerstore.PermanentAddrTTL)
+
+		reqch := make(chan dialRequest)
+		resch := make(chan dialResponse, 1)
+		worker := newDialWorker(s1, s2.LocalPeer(), reqch, nil)
+		go worker.loop()
+		defer worker.wg.Wait()
+		defer close(reqch)
+
+		reqch <- dialRequest{ctx: context.Background(), resch: resch}
+		select {
+		case r := <-resch:
+			require.Eventually(t, func() bool {
+				s1.limiter.lk.Lock()
+				defer s1.limiter.lk.Unlock()
+				return s1.limiter.fdConsuming == 0
+			}, 5*time.Second, 10*time.Millisecond)
+			return r, tracer
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected dial to fail")
+			return dialResponse{}, nil
+		}
+	}
+
+	t.Run("budget", func(t *testing.T) {
+		r, tracer := dial(t, WithDialTimeout(time.Minute), WithDialBudget(200*time.Millisecond))
+		require.ErrorIs(t, r.err, ErrDialBudgetExceeded)
+		require.Equal(t, 1, tracer.BudgetExceeded())
+		require.Eventually(t, func() bool { return len(tracer.Causes()) == 1 }, 5*time.Second, 10*time.Millisecond)
+		require.ErrorIs(t, tracer.Causes()[0], ErrDialBudgetExceeded)
+	})
+
+	t.Run("attempt timeout", func(t *testing.T) {
+		// with the budget disabled, the dial to the working address is only
+		// triggered once the stalling address times out
+		r, tracer := dial(t, WithDialTimeout(200*time.Millisecond), WithDialBudget(0))
+		require.NoError(t, r.err)
+		require.Zero(t, tracer.BudgetExceeded())
+		causes := tracer.Causes()
+		require.Len(t, causes, 1)
+		require.ErrorIs(t, causes[0], errDialAttemptTimeout)
+	})
+}
+
 func TestDialWorkerLoopAddrDedup(t *testing.T) {
 	s1 := makeSwarm(t)
 	s2 := makeSwarm(t)
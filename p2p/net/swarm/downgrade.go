@@ -0,0 +1,177 @@
+package swarm
+
+import (
+	"encoding/gob"
+	"errors"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/core/sec/insecure"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+const (
+	// ConnSecurityPinKey is the peerstore metadata key under which the ConnSecurityPin of
+	// a peer is stored.
+	ConnSecurityPinKey = "libp2p/swarm/conn-security-pin"
+	// connParamsKey is the peerstore metadata key under which the parameters of the last
+	// connections to a peer are stored.
+	connParamsKey = "libp2p/swarm/conn-params"
+)
+
+func init() {
+	// Persistent peerstores serialize the metadata with gob.
+	gob.Register(ConnSecurityPin{})
+	gob.Register(connParams{})
+}
+
+// ErrConnSecurityPinViolated is returned when a connection is refused because it
+// violates the peer's ConnSecurityPin.
+var ErrConnSecurityPinViolated = errors.New("connection violates the peer's security pin")
+
+// ConnSecurityPin pins the parameters connections to a peer must negotiate. It only
+// applies to transports that negotiate a security protocol and a stream multiplexer,
+// like TCP and WebSocket. Pins are enforced if downgrade detection is enabled.
+type ConnSecurityPin struct {
+	// Security is the required security protocol. Empty allows any protocol.
+	Security protocol.ID
+	// Muxer is the required stream multiplexer. Empty allows any multiplexer.
+	Muxer protocol.ID
+	// EarlyMuxerNegotiation requires the multiplexer to be negotiated during the
+	// security handshake.
+	EarlyMuxerNegotiation bool
+}
+
+// PinConnSecurity stores the pin for p in the peerstore.
+func PinConnSecurity(ps peerstore.PeerMetadata, p peer.ID, pin ConnSecurityPin) error {
+	return ps.Put(p, ConnSecurityPinKey, pin)
+}
+
+// GetConnSecurityPin returns the pin stored for p, if any.
+func GetConnSecurityPin(ps peerstore.PeerMetadata, p peer.ID) (ConnSecurityPin, bool) {
+	v, err := ps.Get(p, ConnSecurityPinKey)
+	if err != nil {
+		return ConnSecurityPin{}, false
+	}
+	pin, ok := v.(ConnSecurityPin)
+	return pin, ok
+}
+
+func (pin ConnSecurityPin) violatedBy(cs network.ConnectionState) bool {
+	// Transports with built-in security, like QUIC, don't negotiate these parameters.
+	if cs.Security == "" {
+		return false
+	}
+	return (pin.Security != "" && cs.Security != pin.Security) ||
+		(pin.Muxer != "" && cs.StreamMultiplexer != pin.Muxer) ||
+		(pin.EarlyMuxerNegotiation && !cs.UsedEarlyMuxerNegotiation)
+}
+
+// WithDowngradeDetection makes the swarm compare the parameters negotiated by every
+// new connection with those of the previous connection to the same peer, over the same
+// transport and in the same direction, and emit an event.EvtConnectionDowngraded if they
+// got weaker: plaintext instead of a secure channel, mplex instead of yamux, or no
+// early muxer negotiation. Switching between equivalent protocols, like TLS and
+// Noise, or to a stronger one isn't reported. Connections violating the peer's
+// ConnSecurityPin are refused.
+//
+// The parameters of the last connection are stored in the peerstore, so that a change
+// of configuration of the peer is only reported once.
+func WithDowngradeDetection() Option {
+	return func(s *Swarm) error {
+		s.downgradeDetection = true
+		return nil
+	}
+}
+
+// connParams are the parameters of the last connections to a peer, keyed by
+// connParamsID. It only holds basic types, so that it can be stored by persistent
+// peerstores.
+type connParams map[string]network.ConnectionState
+
+// connParamsID identifies the connections over a transport, in a direction.
+func connParamsID(transport string, dir network.Direction) string {
+	return transport + "/" + dir.String()
+}
+
+// securityRanks ranks the security protocols from the weakest to the strongest.
+// Unknown protocols rank between plaintext and the protocols shipped with libp2p.
+var securityRanks = map[protocol.ID]int{
+	insecure.ID:  0,
+	"/noise":     2,
+	"/tls/1.0.0": 2,
+}
+
+// muxerRanks ranks the stream multiplexers from the weakest to the strongest. Unknown
+// multiplexers rank with mplex, which is deprecated.
+var muxerRanks = map[protocol.ID]int{
+	"/mplex/6.7.0": 1,
+	"/yamux/1.0.0": 2,
+}
+
+func rank(ranks map[protocol.ID]int, p protocol.ID) int {
+	if r, ok := ranks[p]; ok {
+		return r
+	}
+	return 1
+}
+
+// isDowngrade returns whether cur negotiated weaker parameters than prev. Switching to
+// a protocol of the same rank, or to a stronger one, isn't a downgrade.
+func isDowngrade(prev, cur network.ConnectionState) bool {
+	return rank(securityRanks, cur.Security) < rank(securityRanks, prev.Security) ||
+		rank(muxerRanks, cur.StreamMultiplexer) < rank(muxerRanks, prev.StreamMultiplexer) ||
+		(prev.UsedEarlyMuxerNegotiation && !cur.UsedEarlyMuxerNegotiation)
+}
+
+// checkDowngrade checks the parameters of a new connection to p. It returns an error if
+// the connection must be refused.
+func (s *Swarm) checkDowngrade(p peer.ID, addr ma.Multiaddr, dir network.Direction, cs network.ConnectionState) error {
+	evt := event.EvtConnectionDowngraded{Peer: p, Addr: addr, Direction: dir, Current: cs}
+	key := connParamsID(cs.Transport, dir)
+
+	s.downgradeMu.Lock()
+	defer s.downgradeMu.Unlock()
+
+	var prev connParams
+	if v, err := s.peers.Get(p, connParamsKey); err == nil {
+		prev, _ = v.(connParams)
+	}
+	prevCS, hasPrev := prev[key]
+	evt.Previous = prevCS
+
+	if pin, ok := GetConnSecurityPin(s.peers, p); ok && pin.violatedBy(cs) {
+		evt.Refused = true
+		log.Warnw("refusing connection violating security pin", "peer", p, "addr", addr, "security", cs.Security, "muxer", cs.StreamMultiplexer)
+		s.emitDowngrade(evt)
+		return ErrConnSecurityPinViolated
+	}
+	if hasPrev && isDowngrade(prevCS, cs) {
+		log.Warnw("connection downgrade detected", "peer", p, "addr", addr,
+			"previous_security", prevCS.Security, "security", cs.Security,
+			"previous_muxer", prevCS.StreamMultiplexer, "muxer", cs.StreamMultiplexer)
+		s.emitDowngrade(evt)
+	}
+	if hasPrev && prevCS == cs {
+		return nil
+	}
+	params := make(connParams, len(prev)+1)
+	for k, v := range prev {
+		params[k] = v
+	}
+	params[key] = cs
+	if err := s.peers.Put(p, connParamsKey, params); err != nil {
+		log.Debugw("failed to store connection parameters", "peer", p, "error", err)
+	}
+	return nil
+}
+
+func (s *Swarm) emitDowngrade(evt event.EvtConnectionDowngraded) {
+	if err := s.downgradeEmitter.Emit(evt); err != nil {
+		log.Debugw("failed to emit downgrade event", "error", err)
+	}
+}
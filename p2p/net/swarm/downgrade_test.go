@@ -0,0 +1,162 @@
+package swarm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/core/sec/insecure"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore/pstoreds"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore/pstoremem"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDowngradeDetection(t *testing.T) {
+	ps, err := pstoremem.NewPeerstore()
+	require.NoError(t, err)
+	defer ps.Close()
+	bus := eventbus.NewBus()
+	s, err := NewSwarm(test.RandPeerIDFatal(t), ps, bus, WithDowngradeDetection())
+	require.NoError(t, err)
+	defer s.Close()
+	sub, err := bus.Subscribe(new(event.EvtConnectionDowngraded))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	p := test.RandPeerIDFatal(t)
+	addr := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+	tls := network.ConnectionState{Transport: "tcp", Security: "/tls/1.0.0", StreamMultiplexer: "/yamux/1.0.0", UsedEarlyMuxerNegotiation: true}
+	noise := network.ConnectionState{Transport: "tcp", Security: "/noise", StreamMultiplexer: "/yamux/1.0.0"}
+	quic := network.ConnectionState{Transport: "quic-v1"}
+
+	expectEvent := func(prev, cur network.ConnectionState, refused bool) {
+		t.Helper()
+		select {
+		case e := <-sub.Out():
+			evt := e.(event.EvtConnectionDowngraded)
+			require.Equal(t, p, evt.Peer)
+			require.Equal(t, prev, evt.Previous)
+			require.Equal(t, cur, evt.Current)
+			require.Equal(t, refused, evt.Refused)
+		case <-time.After(time.Second):
+			t.Fatal("expected a downgrade event")
+		}
+	}
+	expectNoEvent := func() {
+		t.Helper()
+		select {
+		case e := <-sub.Out():
+			t.Fatalf("unexpected event: %v", e)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, tls))
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, tls))
+	// other transports and directions are tracked separately
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, quic))
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirInbound, noise))
+	expectNoEvent()
+
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, noise))
+	expectEvent(tls, noise, false)
+	// the change is only reported once
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, noise))
+	expectNoEvent()
+
+	require.NoError(t, PinConnSecurity(ps, p, ConnSecurityPin{Security: "/tls/1.0.0", EarlyMuxerNegotiation: true}))
+	require.ErrorIs(t, s.checkDowngrade(p, addr, network.DirOutbound, noise), ErrConnSecurityPinViolated)
+	expectEvent(noise, noise, true)
+	// upgrades aren't reported
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, tls))
+	expectNoEvent()
+	// pins don't apply to transports with built-in security
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, quic))
+	expectNoEvent()
+}
+
+func TestIsDowngrade(t *testing.T) {
+	tls := network.ConnectionState{Security: "/tls/1.0.0", StreamMultiplexer: "/yamux/1.0.0"}
+	noise := network.ConnectionState{Security: "/noise", StreamMultiplexer: "/yamux/1.0.0"}
+	plaintext := network.ConnectionState{Security: insecure.ID, StreamMultiplexer: "/yamux/1.0.0"}
+	mplex := network.ConnectionState{Security: "/tls/1.0.0", StreamMultiplexer: "/mplex/6.7.0"}
+	early := network.ConnectionState{Security: "/tls/1.0.0", StreamMultiplexer: "/yamux/1.0.0", UsedEarlyMuxerNegotiation: true}
+
+	require.False(t, isDowngrade(tls, noise))
+	require.False(t, isDowngrade(noise, tls))
+	require.True(t, isDowngrade(tls, plaintext))
+	require.False(t, isDowngrade(plaintext, tls))
+	require.True(t, isDowngrade(tls, mplex))
+	require.False(t, isDowngrade(mplex, tls))
+	require.True(t, isDowngrade(early, tls))
+	require.False(t, isDowngrade(tls, early))
+}
+
+func TestDowngradeDetectionPersistentPeerstore(t *testing.T) {
+	ps, err := pstoreds.NewPeerstore(context.Background(), dssync.MutexWrap(ds.NewMapDatastore()), pstoreds.DefaultOpts())
+	require.NoError(t, err)
+	defer ps.Close()
+	bus := eventbus.NewBus()
+	s, err := NewSwarm(test.RandPeerIDFatal(t), ps, bus, WithDowngradeDetection())
+	require.NoError(t, err)
+	defer s.Close()
+	sub, err := bus.Subscribe(new(event.EvtConnectionDowngraded))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	p := test.RandPeerIDFatal(t)
+	addr := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+	tls := network.ConnectionState{Transport: "tcp", Security: "/tls/1.0.0", StreamMultiplexer: "/yamux/1.0.0"}
+	plaintext := network.ConnectionState{Transport: "tcp", Security: insecure.ID, StreamMultiplexer: "/yamux/1.0.0"}
+
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, tls))
+	_, err = ps.Get(p, connParamsKey)
+	require.NoError(t, err)
+	require.NoError(t, s.checkDowngrade(p, addr, network.DirOutbound, plaintext))
+	select {
+	case e := <-sub.Out():
+		require.Equal(t, tls, e.(event.EvtConnectionDowngraded).Previous)
+	case <-time.After(time.Second):
+		t.Fatal("expected a downgrade event")
+	}
+
+	require.NoError(t, PinConnSecurity(ps, p, ConnSecurityPin{Security: "/tls/1.0.0"}))
+	pin, ok := GetConnSecurityPin(ps, p)
+	require.True(t, ok)
+	require.Equal(t, protocol.ID("/tls/1.0.0"), pin.Security)
+}
+
+func TestDowngradeDetectionRefusesPinnedConn(t *testing.T) {
+	s1 := makeSwarmWithNoListenAddrs(t, WithDowngradeDetection())
+	defer s1.Close()
+	s2 := makeSwarm(t)
+	defer s2.Close()
+
+	var tcpAddrs []ma.Multiaddr
+	for _, a := range s2.ListenAddresses() {
+		if _, err := a.ValueForProtocol(ma.P_TCP); err == nil {
+			tcpAddrs = append(tcpAddrs, a)
+		}
+	}
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), tcpAddrs, peerstore.PermanentAddrTTL)
+	require.NoError(t, PinConnSecurity(s1.Peerstore(), s2.LocalPeer(), ConnSecurityPin{Security: "/noise"}))
+
+	_, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.ErrorIs(t, err, ErrConnSecurityPinViolated)
+	require.Empty(t, s1.ConnsToPeer(s2.LocalPeer()))
+
+	require.NoError(t, PinConnSecurity(s1.Peerstore(), s2.LocalPeer(), ConnSecurityPin{Security: insecure.ID}))
+	s1.Backoff().Clear(s2.LocalPeer())
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+}
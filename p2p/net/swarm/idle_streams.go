@@ -0,0 +1,115 @@
+package swarm
+
+import (
+	"errors"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+)
+
+// maxIdleStreamCheckInterval is the maximum interval between two checks for idle
+// streams.
+const maxIdleStreamCheckInterval = 30 * time.Second
+
+// idleStreamReaper resets streams that didn't read or write any data for longer than
+// their idle timeout.
+type idleStreamReaper struct {
+	timeout     time.Duration
+	perProtocol map[protocol.ID]time.Duration
+}
+
+func (s *Swarm) idleStreamReaper() *idleStreamReaper {
+	if s.idleStreams == nil {
+		s.idleStreams = &idleStreamReaper{perProtocol: make(map[protocol.ID]time.Duration)}
+	}
+	return s.idleStreams
+}
+
+// WithIdleStreamTimeout configures the swarm to reset streams that didn't read or
+// write any data for longer than d. This protects from peers opening streams and
+// never using them, including streams whose protocol was never negotiated. It's
+// disabled by default, since some protocols keep idle streams open on purpose; use
+// WithProtocolIdleStreamTimeout to exempt them.
+func WithIdleStreamTimeout(d time.Duration) Option {
+	return func(s *Swarm) error {
+		if d < 0 {
+			return errors.New("swarm: negative idle stream timeout")
+		}
+		s.idleStreamReaper().timeout = d
+		return nil
+	}
+}
+
+// WithProtocolIdleStreamTimeout overrides the idle timeout set with
+// WithIdleStreamTimeout for streams of protocol p. A zero d never resets idle
+// streams of p.
+func WithProtocolIdleStreamTimeout(p protocol.ID, d time.Duration) Option {
+	return func(s *Swarm) error {
+		if d < 0 {
+			return errors.New("swarm: negative idle stream timeout")
+		}
+		s.idleStreamReaper().perProtocol[p] = d
+		return nil
+	}
+}
+
+func (r *idleStreamReaper) timeoutFor(p protocol.ID) time.Duration {
+	if d, ok := r.perProtocol[p]; ok {
+		return d
+	}
+	return r.timeout
+}
+
+// checkInterval returns the interval between two checks, or 0 if no stream is ever
+// reset.
+func (r *idleStreamReaper) checkInterval() time.Duration {
+	shortest := r.timeout
+	for _, d := range r.perProtocol {
+		if d > 0 && (shortest == 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	return min(shortest/2, maxIdleStreamCheckInterval)
+}
+
+// reapIdleStreams periodically resets the streams idle for longer than their idle
+// timeout.
+func (s *Swarm) reapIdleStreams(interval time.Duration) {
+	defer s.refs.Done()
+
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case now := <-t.C:
+			s.resetIdleStreams(now)
+		case <-s.ctx.Done():
+			return
+		}
+	}
+}
+
+func (s *Swarm) resetIdleStreams(now time.Time) {
+	for _, c := range s.Conns() {
+		for _, str := range c.GetStreams() {
+			st := str.(*Stream)
+			p := st.Protocol()
+			timeout := s.idleStreams.timeoutFor(p)
+			if timeout == 0 {
+				continue
+			}
+			lastActivity := st.stat.Opened
+			if t := st.lastActivity.Load(); t != 0 {
+				lastActivity = time.Unix(0, t)
+			}
+			if now.Sub(lastActivity) < timeout {
+				continue
+			}
+			log.Debugw("resetting idle stream", "peer", c.RemotePeer(), "protocol", p, "idle", now.Sub(lastActivity))
+			st.Reset()
+			if mt, ok := s.metricsTracer.(IdleStreamTracer); ok {
+				mt.ResetIdleStream(p)
+			}
+		}
+	}
+}
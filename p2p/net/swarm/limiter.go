@@ -3,12 +3,14 @@ package swarm
 import (
 	"context"
 	"os"
+	"runtime/pprof"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
 
 	ma "github.com/multiformats/go-multiaddr"
 )
@@ -19,6 +21,8 @@ type dialJob struct {
 	ctx     context.Context
 	resp    chan transport.DialUpdate
 	timeout time.Duration
+	// queued is when the job started waiting for a global dial token.
+	queued time.Time
 }
 
 func (dj *dialJob) cancelled() bool {
@@ -37,18 +41,26 @@ type dialLimiter struct {
 	activePerPeer      map[peer.ID]int
 	perPeerLimit       int
 	waitingOnPeerLimit map[peer.ID][]*dialJob
+
+	// globalLimit bounds the dials in flight, to all peers and over all transports.
+	// Zero means unlimited.
+	globalLimit     int
+	globalActive    int
+	waitingOnGlobal []*dialJob
+
+	metricsTracer DialQueueTracer
 }
 
 type dialfunc func(context.Context, peer.ID, ma.Multiaddr, chan<- transport.DialUpdate) (transport.CapableConn, error)
 
-func newDialLimiter(df dialfunc) *dialLimiter {
+func newDialLimiter(df dialfunc, perPeerLimit int) *dialLimiter {
 	fd := ConcurrentFdDials
 	if env := os.Getenv("LIBP2P_SWARM_FD_LIMIT"); env != "" {
 		if n, err := strconv.ParseInt(env, 10, 32); err == nil {
 			fd = int(n)
 		}
 	}
-	return newDialLimiterWithParams(df, fd, DefaultPerPeerRateLimit)
+	return newDialLimiterWithParams(df, fd, perPeerLimit)
 }
 
 func newDialLimiterWithParams(df dialfunc, fdLimit, perPeerLimit int) *dialLimiter {
@@ -84,8 +96,8 @@ func (dl *dialLimiter) freeFDToken() {
 		}
 		dl.fdConsuming++
 
-		// we already have activePerPeer token at this point so we can just dial
-		go dl.executeDial(next)
+		// we already have activePerPeer token at this point
+		dl.addCheckGlobalLimit(next)
 		return
 	}
 }
@@ -125,6 +137,9 @@ func (dl *dialLimiter) freePeerToken(dj *dialJob) {
 func (dl *dialLimiter) finishedDial(dj *dialJob) {
 	dl.lk.Lock()
 	defer dl.lk.Unlock()
+	if dl.globalLimit > 0 {
+		dl.freeGlobalToken()
+	}
 	if dl.shouldConsumeFd(dj.addr) {
 		dl.freeFDToken()
 	}
@@ -158,11 +173,68 @@ func (dl *dialLimiter) addCheckFdLimit(dj *dialJob) {
 		dl.fdConsuming++
 	}
 
+	dl.addCheckGlobalLimit(dj)
+}
+
+// addCheckGlobalLimit starts dj if there are less than globalLimit dials in flight,
+// or queues it.
+func (dl *dialLimiter) addCheckGlobalLimit(dj *dialJob) {
+	if dl.globalLimit > 0 {
+		if dl.globalActive >= dl.globalLimit {
+			log.Debugf("[limiter] blocked dial waiting on global limit; peer: %s; addr: %s; active: %d; waiting: %d",
+				dj.peer, dj.addr, dl.globalActive, len(dl.waitingOnGlobal))
+			dj.queued = time.Now()
+			dl.waitingOnGlobal = append(dl.waitingOnGlobal, dj)
+			if dl.metricsTracer != nil {
+				dl.metricsTracer.UpdatedDialQueueLength(len(dl.waitingOnGlobal))
+			}
+			return
+		}
+		dl.globalActive++
+		if dl.metricsTracer != nil {
+			var wait time.Duration
+			if !dj.queued.IsZero() {
+				wait = time.Since(dj.queued)
+			}
+			dl.metricsTracer.DialQueueDelay(wait)
+		}
+	}
+
 	log.Debugf("[limiter] executing dial; peer: %s; addr: %s; FD consuming: %d; waiting: %d",
 		dj.peer, dj.addr, dl.fdConsuming, len(dl.waitingOnFd))
 	go dl.executeDial(dj)
 }
 
+// freeGlobalToken frees a global dial token and starts the next queued dial.
+func (dl *dialLimiter) freeGlobalToken() {
+	dl.globalActive--
+	defer func() {
+		if dl.metricsTracer != nil {
+			dl.metricsTracer.UpdatedDialQueueLength(len(dl.waitingOnGlobal))
+		}
+	}()
+
+	for len(dl.waitingOnGlobal) > 0 && dl.globalActive < dl.globalLimit {
+		next := dl.waitingOnGlobal[0]
+		dl.waitingOnGlobal[0] = nil // clear out memory
+		dl.waitingOnGlobal = dl.waitingOnGlobal[1:]
+		if len(dl.waitingOnGlobal) == 0 {
+			// clear out memory.
+			dl.waitingOnGlobal = nil
+		}
+
+		// Skip over canceled dials, releasing the tokens they hold.
+		if next.cancelled() {
+			if dl.shouldConsumeFd(next.addr) {
+				dl.freeFDToken()
+			}
+			dl.freePeerToken(next)
+			continue
+		}
+		dl.addCheckGlobalLimit(next)
+	}
+}
+
 func (dl *dialLimiter) addCheckPeerLimit(dj *dialJob) {
 	if dl.activePerPeer[dj.peer] >= dl.perPeerLimit {
 		log.Debugf("[limiter] blocked dial waiting on peer limit; peer: %s; addr: %s; active: %d; "+
@@ -203,11 +275,15 @@ func (dl *dialLimiter) clearAllPeerDials(p peer.ID) {
 // it held during the dial.
 func (dl *dialLimiter) executeDial(j *dialJob) {
 	defer dl.finishedDial(j)
+	if _, ok := pprof.Label(j.ctx, metricshelper.PprofLabelPeer); ok {
+		// This goroutine may have been started by the dial of another peer.
+		pprof.SetGoroutineLabels(j.ctx)
+	}
 	if j.cancelled() {
 		return
 	}
 
-	dctx, cancel := context.WithTimeout(j.ctx, j.timeout)
+	dctx, cancel := context.WithTimeoutCause(j.ctx, j.timeout, errDialAttemptTimeout)
 	defer cancel()
 
 	con, err := dl.dialFunc(dctx, j.peer, j.addr, j.resp)
@@ -7,6 +7,7 @@ import (
 	"math/rand"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -16,6 +17,7 @@ import (
 
 	ma "github.com/multiformats/go-multiaddr"
 	mafmt "github.com/multiformats/go-multiaddr-fmt"
+	"github.com/stretchr/testify/require"
 )
 
 func addrWithPort(p int) ma.Multiaddr {
@@ -395,3 +397,83 @@ func TestFDLimitUnderflow(t *testing.T) {
 		t.Fatalf("l.fdConsuming < 0")
 	}
 }
+
+func TestGlobalLimiting(t *testing.T) {
+	var active, maxActive atomic.Int32
+	release := make(chan struct{})
+	df := func(_ context.Context, _ peer.ID, _ ma.Multiaddr, _ chan<- transport.DialUpdate) (transport.CapableConn, error) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		<-release
+		return nil, errors.New("test dial")
+	}
+	l := newDialLimiterWithParams(df, ConcurrentFdDials, 5)
+	l.globalLimit = 2
+
+	ctx := context.Background()
+	resch := make(chan transport.DialUpdate)
+	// Mix of FD consuming and non FD consuming addresses, all subject to the global limit.
+	addrs := []ma.Multiaddr{
+		addrWithPort(1),
+		ma.StringCast("/ip4/127.0.0.1/udp/1234/quic-v1"),
+		addrWithPort(2),
+		ma.StringCast("/ip4/127.0.0.1/udp/1235/quic-v1"),
+		addrWithPort(3),
+	}
+	for i, a := range addrs {
+		l.AddDialJob(&dialJob{ctx: ctx, peer: peer.ID(fmt.Sprintf("testpeer%d", i)), addr: a, resp: resch})
+	}
+
+	queued := func() int {
+		l.lk.Lock()
+		defer l.lk.Unlock()
+		return len(l.waitingOnGlobal)
+	}
+	require.Eventually(t, func() bool { return active.Load() == 2 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, 3, queued())
+
+	for range addrs {
+		release <- struct{}{}
+		select {
+		case <-resch:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for dial completion")
+		}
+	}
+	require.Equal(t, int32(2), maxActive.Load())
+	require.Zero(t, queued())
+}
+
+func TestGlobalLimitCanceledDials(t *testing.T) {
+	release := make(chan struct{})
+	df := func(_ context.Context, _ peer.ID, _ ma.Multiaddr, _ chan<- transport.DialUpdate) (transport.CapableConn, error) {
+		<-release
+		return nil, errors.New("test dial")
+	}
+	l := newDialLimiterWithParams(df, 1, 5)
+	l.globalLimit = 1
+
+	resch := make(chan transport.DialUpdate)
+	l.AddDialJob(&dialJob{ctx: context.Background(), peer: "testpeer1", addr: addrWithPort(1), resp: resch})
+
+	// This dial is queued on the global limit, and canceled while waiting.
+	ctx, cancel := context.WithCancel(context.Background())
+	l.AddDialJob(&dialJob{ctx: ctx, peer: "testpeer2", addr: ma.StringCast("/ip4/127.0.0.1/udp/1234/quic-v1"), resp: resch})
+	cancel()
+
+	release <- struct{}{}
+	<-resch
+
+	// The canceled dial must have released all its tokens without being dialed.
+	require.Eventually(t, func() bool {
+		l.lk.Lock()
+		defer l.lk.Unlock()
+		return l.globalActive == 0 && len(l.waitingOnGlobal) == 0 && len(l.activePerPeer) == 0 && l.fdConsuming == 0
+	}, time.Second, 10*time.Millisecond)
+}
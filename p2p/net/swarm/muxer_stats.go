@@ -0,0 +1,79 @@
+package swarm
+
+import (
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/transport"
+)
+
+const (
+	// muxerStatsInterval is the interval at which the muxer stats are reported to the
+	// metrics tracer.
+	muxerStatsInterval = 10 * time.Second
+	// backpressureThreshold is the receive window utilization above which a connection
+	// is considered back-pressured.
+	backpressureThreshold = 0.9
+)
+
+// getMuxerStatsReporter returns the MuxerStatsReporter of tc, if its stream
+// multiplexer reports MuxerStats.
+func getMuxerStatsReporter(tc transport.CapableConn) (network.MuxerStatsReporter, bool) {
+	if c, ok := tc.(*connWithMetrics); ok {
+		tc = c.CapableConn
+	}
+	r, ok := tc.(network.MuxerStatsReporter)
+	return r, ok
+}
+
+type transportMuxerStats struct {
+	bufferedBytes      int64
+	backpressuredConns int
+}
+
+// reportMuxerStats periodically reports the buffered bytes and the number of
+// back-pressured connections, per transport, to mt.
+func (s *Swarm) reportMuxerStats(mt MuxerStatsTracer) {
+	defer s.refs.Done()
+
+	t := time.NewTicker(muxerStatsInterval)
+	defer t.Stop()
+	// transports reported in the previous round, so that their gauges are reset when
+	// they don't have any connections left
+	reported := make(map[string]struct{})
+	for {
+		select {
+		case <-t.C:
+		case <-s.ctx.Done():
+			return
+		}
+
+		stats := make(map[string]*transportMuxerStats, len(reported))
+		for tpt := range reported {
+			stats[tpt] = &transportMuxerStats{}
+		}
+		for _, c := range s.Conns() {
+			ms, ok := c.Stat().MuxerStats()
+			if !ok {
+				continue
+			}
+			tpt := c.ConnState().Transport
+			st, ok := stats[tpt]
+			if !ok {
+				st = &transportMuxerStats{}
+				stats[tpt] = st
+			}
+			st.bufferedBytes += ms.BufferedBytes
+			if ms.RecvWindowUtilization() >= backpressureThreshold {
+				st.backpressuredConns++
+				log.Debugw("peer back-pressured by flow control", "peer", c.RemotePeer(), "addr", c.RemoteMultiaddr(),
+					"streams", ms.NumStreams, "buffered", ms.BufferedBytes, "window", ms.RecvWindow)
+			}
+		}
+		clear(reported)
+		for tpt, st := range stats {
+			mt.UpdatedMuxerStats(tpt, st.bufferedBytes, st.backpressuredConns)
+			reported[tpt] = struct{}{}
+		}
+	}
+}
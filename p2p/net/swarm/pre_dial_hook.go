@@ -0,0 +1,57 @@
+package swarm
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// PreDialHook is invoked before dialing a peer, to add or remove candidate addresses,
+// e.g. addresses provided by an external address hint service.
+//
+// PreDial receives the peer's addresses from the peerstore, possibly modified by the
+// previous hooks, and returns the addresses to dial. The returned addresses are resolved,
+// filtered and ranked like the addresses from the peerstore. Concurrent dials to the same
+// peer are coalesced, so the context only carries the dial options set using the network
+// package, e.g. network.WithForceDirectDial, and not arbitrary values of the caller's
+// context.
+//
+// If PreDial returns an error, the dial fails. Hooks that only provide hints should return
+// the addresses unchanged instead.
+type PreDialHook interface {
+	PreDial(ctx context.Context, p peer.ID, addrs []ma.Multiaddr) ([]ma.Multiaddr, error)
+}
+
+// PreDialHookFunc is a function implementing PreDialHook.
+type PreDialHookFunc func(ctx context.Context, p peer.ID, addrs []ma.Multiaddr) ([]ma.Multiaddr, error)
+
+func (f PreDialHookFunc) PreDial(ctx context.Context, p peer.ID, addrs []ma.Multiaddr) ([]ma.Multiaddr, error) {
+	return f(ctx, p, addrs)
+}
+
+// WithPreDialHook adds a hook invoked before dialing a peer. Hooks are invoked in the
+// order they were added.
+func WithPreDialHook(h PreDialHook) Option {
+	return func(s *Swarm) error {
+		if h == nil {
+			return fmt.Errorf("swarm: pre-dial hook cannot be nil")
+		}
+		s.preDialHooks = append(s.preDialHooks, h)
+		return nil
+	}
+}
+
+// runPreDialHooks invokes the pre-dial hooks.
+func (s *Swarm) runPreDialHooks(ctx context.Context, p peer.ID, addrs []ma.Multiaddr) ([]ma.Multiaddr, error) {
+	for _, h := range s.preDialHooks {
+		var err error
+		addrs, err = h.PreDial(ctx, p, addrs)
+		if err != nil {
+			return nil, fmt.Errorf("pre-dial hook: %w", err)
+		}
+	}
+	return addrs, nil
+}
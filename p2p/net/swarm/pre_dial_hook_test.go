@@ -0,0 +1,58 @@
+package swarm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreDialHook(t *testing.T) {
+	s2 := makeSwarm(t)
+	defer s2.Close()
+
+	hookErr := errors.New("peer blocked by hook")
+	var hookCalls int
+	var mode string
+	s1 := makeSwarmWithNoListenAddrs(t,
+		WithPreDialHook(PreDialHookFunc(func(ctx context.Context, p peer.ID, addrs []ma.Multiaddr) ([]ma.Multiaddr, error) {
+			hookCalls++
+			if mode == "block" {
+				return nil, hookErr
+			}
+			return addrs, nil
+		})),
+		// provide the addresses of s2, which are not in the peerstore
+		WithPreDialHook(PreDialHookFunc(func(ctx context.Context, p peer.ID, addrs []ma.Multiaddr) ([]ma.Multiaddr, error) {
+			if mode == "none" {
+				return nil, nil
+			}
+			if p == s2.LocalPeer() {
+				addrs = append(addrs, s2.ListenAddresses()...)
+			}
+			return addrs, nil
+		})),
+	)
+	defer s1.Close()
+
+	mode = "block"
+	_, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.ErrorIs(t, err, hookErr)
+
+	mode = "none"
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.ErrorIs(t, err, ErrNoAddresses)
+
+	mode = ""
+	c, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+	require.Equal(t, network.DirOutbound, c.Stat().Direction)
+	require.Equal(t, 3, hookCalls)
+	// addresses provided by hooks are added to the peerstore
+	require.NotEmpty(t, s1.Peerstore().Addrs(s2.LocalPeer()))
+}
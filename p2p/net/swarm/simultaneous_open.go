@@ -0,0 +1,58 @@
+package swarm
+
+import (
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+)
+
+// simultaneousOpenWindow is the maximum time between opening two connections in
+// opposite directions for them to be considered the result of a simultaneous open.
+const simultaneousOpenWindow = 10 * time.Second
+
+// WithSimultaneousOpenDedup configures the swarm to close duplicate connections
+// created when two peers dial each other at the same time.
+//
+// Both peers must agree on which connection to keep, so the tie-break is
+// deterministic: the connection dialed by the peer with the lower peer ID is kept.
+// Only direct connections opened within a few seconds of each other are considered.
+// The duplicate is closed with the network.ConnSupplanted error code.
+func WithSimultaneousOpenDedup() Option {
+	return func(s *Swarm) error {
+		s.dedupSimultaneousOpen = true
+		return nil
+	}
+}
+
+// keepOutbound returns whether the local peer keeps its outbound connection to p
+// when resolving a simultaneous open.
+func keepOutbound(local, p peer.ID) bool {
+	return local < p
+}
+
+// simultaneousConnLocked returns an open, direct connection to p in the opposite
+// direction of dir that was opened within simultaneousOpenWindow of opened.
+// Assumes the caller holds s.conns lock.
+func (s *Swarm) simultaneousConnLocked(p peer.ID, dir network.Direction, opened time.Time) *Conn {
+	for _, c := range s.conns.m[p] {
+		stat := c.Stat()
+		if stat.Limited || stat.Direction == dir || c.IsClosed() {
+			continue
+		}
+		if d := opened.Sub(stat.Opened); d < -simultaneousOpenWindow || d > simultaneousOpenWindow {
+			continue
+		}
+		return c
+	}
+	return nil
+}
+
+// closeDuplicate closes c, a duplicate connection resulting from a simultaneous open.
+func (s *Swarm) closeDuplicate(c *Conn) {
+	log.Debugw("closing duplicate connection", "peer", c.RemotePeer(), "dir", c.Stat().Direction, "addr", c.RemoteMultiaddr())
+	if s.metricsTracer != nil {
+		s.metricsTracer.ClosedDuplicateConnection(c.Stat().Direction, c.ConnState())
+	}
+	c.CloseWithError(network.ConnSupplanted)
+}
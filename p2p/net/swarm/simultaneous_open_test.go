@@ -0,0 +1,51 @@
+package swarm
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSimultaneousOpenDedup(t *testing.T) {
+	newSwarm := func() *Swarm {
+		s := makeSwarmWithNoListenAddrs(t, WithSimultaneousOpenDedup())
+		require.NoError(t, s.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0")))
+		return s
+	}
+	s1 := newSwarm()
+	defer s1.Close()
+	s2 := newSwarm()
+	defer s2.Close()
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2.ListenAddresses(), peerstore.PermanentAddrTTL)
+
+	c1, err := s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return len(s2.ConnsToPeer(s1.LocalPeer())) == 1 }, 5*time.Second, 10*time.Millisecond)
+
+	// Open a second connection in the other direction, as if both peers had dialed
+	// each other at the same time.
+	addr := s1.ListenAddresses()[0]
+	tc, err := s2.TransportForDialing(addr).Dial(context.Background(), addr, s1.LocalPeer())
+	require.NoError(t, err)
+	c2, err := s2.addConn(tc, network.DirOutbound)
+	require.NoError(t, err)
+
+	// The connection dialed by the peer with the lower peer ID is kept.
+	var keep, closed interface{ IsClosed() bool } = c1, tc
+	if s2.LocalPeer() < s1.LocalPeer() {
+		keep, closed = c2, c1
+	}
+	require.Eventually(t, func() bool { return closed.IsClosed() }, 5*time.Second, 10*time.Millisecond)
+	require.False(t, keep.IsClosed())
+	// The dial returns the connection that was kept.
+	require.False(t, c2.IsClosed())
+	require.Eventually(t, func() bool {
+		return len(s1.ConnsToPeer(s2.LocalPeer())) == 1 && len(s2.ConnsToPeer(s1.LocalPeer())) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+}
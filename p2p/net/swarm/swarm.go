@@ -34,6 +34,10 @@ const (
 	// protocol selection as well the handshake, if applicable.
 	defaultDialTimeoutLocal = 5 * time.Second
 
+	// defaultDialBudget is the maximum duration the swarm spends dialing the addresses
+	// of a peer, see WithDialBudget.
+	defaultDialBudget = 30 * time.Second
+
 	defaultNewStreamTimeout = 15 * time.Second
 )
 
@@ -50,6 +54,14 @@ var ErrAddrFiltered = errors.New("address filtered")
 // ErrDialTimeout is returned when one a dial times out due to the global timeout
 var ErrDialTimeout = errors.New("dial timed out")
 
+// ErrDialBudgetExceeded is returned when the addresses of a peer couldn't be dialed
+// within the dial budget, see WithDialBudget.
+var ErrDialBudgetExceeded = errors.New("dial budget exceeded")
+
+// errDialAttemptTimeout is the cause of a dial to an address that took longer than
+// the dial timeout.
+var errDialAttemptTimeout = errors.New("dial attempt timed out")
+
 type Option func(*Swarm) error
 
 // WithConnectionGater sets a connection gater
@@ -60,6 +72,21 @@ func WithConnectionGater(gater connmgr.ConnectionGater) Option {
 	}
 }
 
+// WithConnManagerHeadroom makes the swarm refuse new outbound dials while cm has as
+// many connections as its high water mark, or more, since cm would trim the new
+// connections right away. Dials to the peers cm protects, and the dials forced with
+// network.WithDialAboveHighWater or network.WithForceDirectDial, aren't refused.
+// cm must implement connmgr.HighWaterChecker.
+func WithConnManagerHeadroom(cm connmgr.ConnManager) Option {
+	return func(s *Swarm) error {
+		if _, ok := cm.(connmgr.HighWaterChecker); !ok {
+			return errors.New("swarm: connection manager doesn't report its high water mark")
+		}
+		s.connmgr = cm
+		return nil
+	}
+}
+
 // WithMultiaddrResolver sets a custom multiaddress resolver
 func WithMultiaddrResolver(resolver network.MultiaddrDNSResolver) Option {
 	return func(s *Swarm) error {
@@ -72,6 +99,7 @@ func WithMultiaddrResolver(resolver network.MultiaddrDNSResolver) Option {
 func WithMetrics(reporter metrics.Reporter) Option {
 	return func(s *Swarm) error {
 		s.bwc = reporter
+		s.muxerBwc, _ = reporter.(metrics.MuxerReporter)
 		return nil
 	}
 }
@@ -83,6 +111,8 @@ func WithMetricsTracer(t MetricsTracer) Option {
 	}
 }
 
+// WithDialTimeout sets the timeout of a single dial attempt to an address, including
+// the handshake. Defaults to 15s.
 func WithDialTimeout(t time.Duration) Option {
 	return func(s *Swarm) error {
 		s.dialTimeout = t
@@ -90,6 +120,8 @@ func WithDialTimeout(t time.Duration) Option {
 	}
 }
 
+// WithDialTimeoutLocal sets the timeout of a single dial attempt to a private
+// address. Defaults to 5s.
 func WithDialTimeoutLocal(t time.Duration) Option {
 	return func(s *Swarm) error {
 		s.dialTimeoutLocal = t
@@ -97,6 +129,50 @@ func WithDialTimeoutLocal(t time.Duration) Option {
 	}
 }
 
+// WithDialBudget sets the maximum duration the swarm spends dialing the addresses of
+// a peer. Once the budget is spent, the in-flight dials are canceled, the addresses
+// that weren't dialed yet are skipped, and the dial fails with ErrDialBudgetExceeded.
+// This keeps a few black holed addresses from stalling the dial until the context
+// deadline. The budget applies independently of the dial context, and starts anew
+// when a dial to a peer starts while no other dial to the peer is pending.
+// Defaults to 30s, 0 disables the budget.
+func WithDialBudget(t time.Duration) Option {
+	return func(s *Swarm) error {
+		if t < 0 {
+			return errors.New("swarm: dial budget cannot be negative")
+		}
+		s.dialBudget = t
+		return nil
+	}
+}
+
+// WithMaxConcurrentDialsPerPeer sets the maximum number of addresses of a peer the
+// swarm dials concurrently. Defaults to DefaultPerPeerRateLimit.
+func WithMaxConcurrentDialsPerPeer(n int) Option {
+	return func(s *Swarm) error {
+		if n <= 0 {
+			return errors.New("swarm: max concurrent dials per peer must be positive")
+		}
+		s.perPeerDialLimit = n
+		return nil
+	}
+}
+
+// WithMaxConcurrentDials bounds the number of addresses the swarm dials concurrently,
+// to all peers and over all transports. Further dials are queued until a dial
+// completes. This prevents bursts of dials, e.g. after a discovery query, from
+// exhausting the ephemeral ports. It's independent of the per-peer limit and of the
+// file descriptor limit of TCP dials. Zero, the default, means unlimited.
+func WithMaxConcurrentDials(n int) Option {
+	return func(s *Swarm) error {
+		if n < 0 {
+			return errors.New("swarm: max concurrent dials cannot be negative")
+		}
+		s.maxConcurrentDials = n
+		return nil
+	}
+}
+
 func WithResourceManager(m network.ResourceManager) Option {
 	return func(s *Swarm) error {
 		s.rcmgr = m
@@ -104,7 +180,9 @@ func WithResourceManager(m network.ResourceManager) Option {
 	}
 }
 
-// WithDialRanker configures swarm to use d as the DialRanker
+// WithDialRanker configures swarm to use d as the DialRanker. By default, the swarm
+// ranks addresses with DefaultDialRanker, and then dials the addresses that were fast
+// over previous connections first, see PreferLowRTT.
 func WithDialRanker(d network.DialRanker) Option {
 	return func(s *Swarm) error {
 		if d == nil {
@@ -119,18 +197,35 @@ func WithDialRanker(d network.DialRanker) Option {
 // n is the size of the sliding window used to evaluate black hole state
 // min is the minimum number of successes out of n required to not block requests
 func WithUDPBlackHoleSuccessCounter(f *BlackHoleSuccessCounter) Option {
-	return func(s *Swarm) error {
-		s.udpBHF = f
-		return nil
+	if f == nil {
+		return WithBlackHolePolicy(BlackHoleKeyUDP, nil)
 	}
+	return WithBlackHolePolicy(BlackHoleKeyUDP, f)
 }
 
 // WithIPv6BlackHoleSuccessCounter configures swarm to use the provided config for IPv6 black hole detection
 // n is the size of the sliding window used to evaluate black hole state
 // min is the minimum number of successes out of n required to not block requests
 func WithIPv6BlackHoleSuccessCounter(f *BlackHoleSuccessCounter) Option {
+	if f == nil {
+		return WithBlackHolePolicy(BlackHoleKeyIPv6, nil)
+	}
+	return WithBlackHolePolicy(BlackHoleKeyIPv6, f)
+}
+
+// WithBlackHolePolicy configures swarm to use p for black hole detection of the public addresses
+// matching key, e.g. TCP over IPv6 or WebRTC addresses. A nil p disables black hole detection
+// for key. The same policy may be shared between swarms.
+func WithBlackHolePolicy(key BlackHoleKey, p BlackHolePolicy) Option {
 	return func(s *Swarm) error {
-		s.ipv6BHF = f
+		if key == (BlackHoleKey{}) {
+			return errors.New("swarm: black hole key must specify a transport or a network")
+		}
+		if p == nil {
+			delete(s.bhPolicies, key)
+			return nil
+		}
+		s.bhPolicies[key] = p
 		return nil
 	}
 }
@@ -146,6 +241,45 @@ func WithReadOnlyBlackHoleDetector() Option {
 	}
 }
 
+// WithAddrFilters makes the swarm refuse to dial, accept connections from or listen
+// on the addresses blocked by f. f can be updated while the swarm runs, changes apply
+// to new dials, connections and listeners.
+func WithAddrFilters(f *ma.Filters) Option {
+	return func(s *Swarm) error {
+		s.addrFilters = f
+		return nil
+	}
+}
+
+// WithDialBackoff makes the swarm track the addresses it backs off from dialing in
+// b, instead of its own DialBackoff. This allows sharing the backoffs with other
+// components, see backoff.Registry.
+func WithDialBackoff(b DialBackoffTracker) Option {
+	return func(s *Swarm) error {
+		s.dialBackoff = b
+		return nil
+	}
+}
+
+// WithClock sets the clock used by the swarm to schedule dials and to track dial
+// backoffs, e.g. a mock clock in tests. Defaults to RealClock.
+func WithClock(cl Clock) Option {
+	return func(s *Swarm) error {
+		s.clock = cl
+		return nil
+	}
+}
+
+// WithPprofLabels tags the goroutines of the dial workers and of the dials with
+// pprof labels, the peer and the transport, so that CPU and heap profiles can be
+// attributed to peers. See the metricshelper.PprofLabel constants.
+func WithPprofLabels() Option {
+	return func(s *Swarm) error {
+		s.pprofLabels = true
+		return nil
+	}
+}
+
 // Swarm is a connection muxer, allowing connections to other peers to
 // be opened and closed, while still using the same Chan for all
 // communication. The Chan sends/receives Messages, which note the
@@ -165,8 +299,11 @@ type Swarm struct {
 	local peer.ID
 	peers peerstore.Peerstore
 
-	dialTimeout      time.Duration
-	dialTimeoutLocal time.Duration
+	dialTimeout        time.Duration
+	dialTimeoutLocal   time.Duration
+	dialBudget         time.Duration
+	perPeerDialLimit   int
+	maxConcurrentDials int
 
 	conns struct {
 		sync.RWMutex
@@ -203,25 +340,53 @@ type Swarm struct {
 	streamh atomic.Pointer[network.StreamHandler]
 
 	// dialing helpers
-	dsync   *dialSync
-	backf   DialBackoff
-	limiter *dialLimiter
-	gater   connmgr.ConnectionGater
+	dsync       *dialSync
+	backf       DialBackoff
+	dialBackoff DialBackoffTracker
+	limiter     *dialLimiter
+	gater       connmgr.ConnectionGater
+	// connmgr, if set, refuses dials above its high water mark
+	connmgr connmgr.ConnManager
 
 	closeOnce sync.Once
 	ctx       context.Context // is canceled when Close is called
 	ctxCancel context.CancelFunc
 
 	bwc           metrics.Reporter
+	muxerBwc      metrics.MuxerReporter
 	metricsTracer MetricsTracer
 
-	dialRanker network.DialRanker
+	dialRanker        network.DialRanker // nil for the default RTT aware ranking
+	dialRankingPolicy DialRankingPolicy
+	dialHistory       *dialHistory
 
 	connectednessEventEmitter *connectednessEventEmitter
-	udpBHF                    *BlackHoleSuccessCounter
-	ipv6BHF                   *BlackHoleSuccessCounter
+	bhPolicies                map[BlackHoleKey]BlackHolePolicy
 	bhd                       *blackHoleDetector
 	readOnlyBHD               bool
+
+	pprofLabels bool
+
+	clock Clock
+
+	dedupSimultaneousOpen bool
+
+	connMigration *connMigration
+
+	downgradeDetection bool
+	downgradeMu        sync.Mutex
+	downgradeEmitter   event.Emitter
+
+	dialTracing      bool
+	dialTraceEmitter event.Emitter
+
+	preDialHooks []PreDialHook
+
+	addrProber *addrProber
+
+	addrFilters *ma.Filters
+
+	idleStreams *idleStreamReaper
 }
 
 // NewSwarm constructs a Swarm.
@@ -239,14 +404,18 @@ func NewSwarm(local peer.ID, peers peerstore.Peerstore, eventBus event.Bus, opts
 		ctxCancel:         cancel,
 		dialTimeout:       defaultDialTimeout,
 		dialTimeoutLocal:  defaultDialTimeoutLocal,
+		dialBudget:        defaultDialBudget,
+		perPeerDialLimit:  DefaultPerPeerRateLimit,
 		multiaddrResolver: ResolverFromMaDNS{madns.DefaultResolver},
-		dialRanker:        DefaultDialRanker,
+		clock:             RealClock{},
 
 		// A black hole is a binary property. On a network if UDP dials are blocked or there is
 		// no IPv6 connectivity, all dials will fail. So a low success rate of 5 out 100 dials
 		// is good enough.
-		udpBHF:  &BlackHoleSuccessCounter{N: 100, MinSuccesses: 5, Name: "UDP"},
-		ipv6BHF: &BlackHoleSuccessCounter{N: 100, MinSuccesses: 5, Name: "IPv6"},
+		bhPolicies: map[BlackHoleKey]BlackHolePolicy{
+			BlackHoleKeyUDP:  &BlackHoleSuccessCounter{N: 100, MinSuccesses: 5, Name: "UDP"},
+			BlackHoleKeyIPv6: &BlackHoleSuccessCounter{N: 100, MinSuccesses: 5, Name: "IPv6"},
+		},
 	}
 
 	s.conns.m = make(map[peer.ID][]*Conn)
@@ -264,17 +433,49 @@ func NewSwarm(local peer.ID, peers peerstore.Peerstore, eventBus event.Bus, opts
 	if s.rcmgr == nil {
 		s.rcmgr = &network.NullResourceManager{}
 	}
+	if s.downgradeDetection {
+		if s.downgradeEmitter, err = eventBus.Emitter(new(event.EvtConnectionDowngraded)); err != nil {
+			return nil, err
+		}
+	}
+	if s.dialTracing {
+		if s.dialTraceEmitter, err = eventBus.Emitter(new(event.EvtDialTrace)); err != nil {
+			return nil, err
+		}
+	}
+	if s.connMigration != nil {
+		if s.connMigration.emitter, err = eventBus.Emitter(new(event.EvtConnectionMigrated)); err != nil {
+			return nil, err
+		}
+	}
 
 	s.dsync = newDialSync(s.dialWorkerLoop)
 
-	s.limiter = newDialLimiter(s.dialAddr)
-	s.backf.init(s.ctx)
+	s.limiter = newDialLimiter(s.dialAddr, s.perPeerDialLimit)
+	s.limiter.globalLimit = s.maxConcurrentDials
+	if mt, ok := s.metricsTracer.(DialQueueTracer); ok {
+		s.limiter.metricsTracer = mt
+	}
+	if s.dialBackoff == nil {
+		s.backf.clock = s.clock
+		s.backf.init(s.ctx)
+		s.dialBackoff = &s.backf
+	}
+
+	s.bhd = newBlackHoleDetector(s.bhPolicies, s.metricsTracer, s.readOnlyBHD)
 
-	s.bhd = &blackHoleDetector{
-		udp:      s.udpBHF,
-		ipv6:     s.ipv6BHF,
-		mt:       s.metricsTracer,
-		readOnly: s.readOnlyBHD,
+	if s.addrProber != nil {
+		s.addrProber.start()
+	}
+	if mt, ok := s.metricsTracer.(MuxerStatsTracer); ok {
+		s.refs.Add(1)
+		go s.reportMuxerStats(mt)
+	}
+	if s.idleStreams != nil {
+		if interval := s.idleStreams.checkInterval(); interval > 0 {
+			s.refs.Add(1)
+			go s.reapIdleStreams(interval)
+		}
 	}
 	return s, nil
 }
@@ -284,6 +485,11 @@ func (s *Swarm) Close() error {
 	return nil
 }
 
+// AddrFilters returns the address filters set with WithAddrFilters, or nil.
+func (s *Swarm) AddrFilters() *ma.Filters {
+	return s.addrFilters
+}
+
 // Done returns a channel that is closed when the swarm is closed.
 func (s *Swarm) Done() <-chan struct{} {
 	return s.ctx.Done()
@@ -329,6 +535,15 @@ func (s *Swarm) close() {
 	s.refs.Wait()
 	s.connectednessEventEmitter.Close()
 	s.emitter.Close()
+	if s.downgradeEmitter != nil {
+		s.downgradeEmitter.Close()
+	}
+	if s.dialTraceEmitter != nil {
+		s.dialTraceEmitter.Close()
+	}
+	if s.connMigration != nil {
+		s.connMigration.emitter.Close()
+	}
 
 	// Now close out any transports (if necessary). Do this after closing
 	// all connections/listeners.
@@ -372,6 +587,14 @@ func (s *Swarm) addConn(tc transport.CapableConn, dir network.Direction) (*Conn,
 	stat.Direction = dir
 	stat.Opened = time.Now()
 	isLimited := stat.Limited
+	if r, ok := getMuxerStatsReporter(tc); ok {
+		extra := make(map[interface{}]interface{}, len(stat.Extra)+1)
+		for k, v := range stat.Extra {
+			extra[k] = v
+		}
+		extra[network.StatMuxer] = r
+		stat.Extra = extra
+	}
 
 	// Wrap and register the connection.
 	c := &Conn{
@@ -380,6 +603,14 @@ func (s *Swarm) addConn(tc transport.CapableConn, dir network.Direction) (*Conn,
 		stat:  stat,
 		id:    s.nextConnID.Add(1),
 	}
+	if mc, ok := tc.(bandwidthMeteredConn); ok && s.muxerBwc != nil {
+		c.bandwidthMetered = mc.BandwidthMetered()
+	}
+
+	if s.addrFilters != nil && s.addrFilters.AddrBlocked(addr) {
+		tc.CloseWithError(network.ConnGated)
+		return nil, ErrAddrFiltered
+	}
 
 	// we ONLY check upgraded connections here so we can send them a Disconnect message.
 	// If we do this in the Upgrader, we will not be able to do this.
@@ -393,13 +624,20 @@ func (s *Swarm) addConn(tc transport.CapableConn, dir network.Direction) (*Conn,
 		}
 	}
 
+	if s.downgradeDetection {
+		if err := s.checkDowngrade(p, addr, dir, tc.ConnState()); err != nil {
+			tc.CloseWithError(network.ConnGated)
+			return nil, err
+		}
+	}
+
 	// Add the public key.
 	if pk := tc.RemotePublicKey(); pk != nil {
 		s.peers.AddPubKey(p, pk)
 	}
 
 	// Clear any backoffs
-	s.backf.Clear(p)
+	s.dialBackoff.Clear(p)
 
 	// Finally, add the peer.
 	s.conns.Lock()
@@ -410,6 +648,23 @@ func (s *Swarm) addConn(tc transport.CapableConn, dir network.Direction) (*Conn,
 		return nil, ErrSwarmClosed
 	}
 
+	var supplanted *Conn
+	if s.dedupSimultaneousOpen && !isLimited {
+		if other := s.simultaneousConnLocked(p, dir, stat.Opened); other != nil {
+			if (dir == network.DirOutbound) != keepOutbound(s.local, p) {
+				// The other connection wins, use it instead of the new one.
+				s.conns.Unlock()
+				log.Debugw("closing duplicate connection", "peer", p, "dir", dir, "addr", addr)
+				if s.metricsTracer != nil {
+					s.metricsTracer.ClosedDuplicateConnection(dir, tc.ConnState())
+				}
+				tc.CloseWithError(network.ConnSupplanted)
+				return other, nil
+			}
+			supplanted = other
+		}
+	}
+
 	c.streams.m = make(map[*Stream]struct{})
 	s.conns.m[p] = append(s.conns.m[p], c)
 	// Add two swarm refs:
@@ -445,6 +700,14 @@ func (s *Swarm) addConn(tc transport.CapableConn, dir network.Direction) (*Conn,
 	c.notifyLk.Unlock()
 
 	c.start()
+	// The handshake of some transports, like QUIC, already measured the RTT.
+	s.recordRTT(c)
+	if supplanted != nil {
+		s.closeDuplicate(supplanted)
+	}
+	if s.connMigration != nil {
+		s.migrateConns(c)
+	}
 	return c, nil
 }
 
@@ -488,7 +751,7 @@ func (s *Swarm) NewStream(ctx context.Context, p peer.ID) (network.Stream, error
 	// a non-closed connection.
 	numDials := 0
 	for {
-		c := s.bestConnToPeer(p)
+		c := s.bestMatchingConnToPeer(p, dialConstraintsFilter(ctx))
 		if c == nil {
 			if nodial, _ := network.GetNoDial(ctx); !nodial {
 				numDials++
@@ -592,6 +855,20 @@ func (s *Swarm) ConnsToPeer(p peer.ID) []network.Conn {
 }
 
 func isBetterConn(a, b *Conn) bool {
+	// Prefer connections that aren't being drained.
+	aDraining := a.draining.Load()
+	bDraining := b.draining.Load()
+	if aDraining != bDraining {
+		return !aDraining
+	}
+
+	// With connection migration, prefer the connection with the highest rank.
+	if m := a.swarm.connMigration; m != nil {
+		if aRank, bRank := m.Rank(a), m.Rank(b); aRank != bRank {
+			return aRank > bRank
+		}
+	}
+
 	// If one is limited and not the other, prefer the unlimited connection.
 	aLimited := a.Stat().Limited
 	bLimited := b.Stat().Limited
@@ -625,6 +902,12 @@ func isBetterConn(a, b *Conn) bool {
 
 // bestConnToPeer returns the best connection to peer.
 func (s *Swarm) bestConnToPeer(p peer.ID) *Conn {
+	return s.bestMatchingConnToPeer(p, nil)
+}
+
+// bestMatchingConnToPeer returns the best connection to peer accepted by match. A nil
+// match accepts all connections.
+func (s *Swarm) bestMatchingConnToPeer(p peer.ID, match func(*Conn) bool) *Conn {
 	// TODO: Prefer some transports over others.
 	// For now, prefers direct connections over Relayed connections.
 	// For tie-breaking, select the newest non-closed connection with the most streams.
@@ -637,6 +920,9 @@ func (s *Swarm) bestConnToPeer(p peer.ID) *Conn {
 			// We *will* garbage collect this soon anyways.
 			continue
 		}
+		if match != nil && !match(c) {
+			continue
+		}
 		if best == nil || isBetterConn(c, best) {
 			best = c
 		}
@@ -646,9 +932,11 @@ func (s *Swarm) bestConnToPeer(p peer.ID) *Conn {
 
 // bestAcceptableConnToPeer returns the best acceptable connection, considering the passed in ctx.
 // If network.WithForceDirectDial is used, it only returns a direct connections, ignoring
-// any limited (relayed) connections to the peer.
+// any limited (relayed) connections to the peer. Connections not allowed by the
+// constraints set with network.WithTransportConstraint and network.WithAddrFilter are
+// ignored.
 func (s *Swarm) bestAcceptableConnToPeer(ctx context.Context, p peer.ID) *Conn {
-	conn := s.bestConnToPeer(p)
+	conn := s.bestMatchingConnToPeer(p, dialConstraintsFilter(ctx))
 
 	forceDirect, _ := network.GetForceDirectDial(ctx)
 	if forceDirect && !isDirectConn(conn) {
@@ -692,6 +980,14 @@ func (s *Swarm) connectednessUnlocked(p peer.ID) network.Connectedness {
 	return network.NotConnected
 }
 
+// ConnectionQuality summarizes the connections to the given peer, see
+// network.ConnectionQuality. The RTT is the peerstore's latency estimate.
+func (s *Swarm) ConnectionQuality(p peer.ID) network.ConnectionQuality {
+	return network.NewConnectionQuality(s.ConnsToPeer(p), s.peers.LatencyEWMA(p), time.Now())
+}
+
+var _ network.ConnectionQualityReporter = (*Swarm)(nil)
+
 // Conns returns a slice of all connections.
 func (s *Swarm) Conns() []network.Conn {
 	s.conns.RLock()
@@ -753,9 +1049,23 @@ func (s *Swarm) LocalPeer() peer.ID {
 	return s.local
 }
 
-// Backoff returns the DialBackoff object for this swarm.
-func (s *Swarm) Backoff() *DialBackoff {
-	return &s.backf
+// Backoff returns the tracker of the addresses this swarm backs off from dialing: the
+// one set with WithDialBackoff, or the swarm's own DialBackoff otherwise.
+func (s *Swarm) Backoff() DialBackoffTracker {
+	return s.dialBackoff
+}
+
+// BlackHoleStates returns the current state of the black hole detection for every
+// configured BlackHoleKey.
+func (s *Swarm) BlackHoleStates() map[BlackHoleKey]BlackHoleState {
+	return s.bhd.States()
+}
+
+// ResetBlackHoleDetector resets the black hole detection for keys, or for all the keys
+// if none are given, so that the addresses are dialed again to find out their state.
+// Use it when connectivity is known to have changed, e.g. after switching networks.
+func (s *Swarm) ResetBlackHoleDetector(keys ...BlackHoleKey) {
+	s.bhd.Reset(keys...)
 }
 
 // notifyAll sends a signal to all Notifiees
@@ -853,6 +1163,11 @@ func (c *connWithMetrics) CloseWithError(errCode network.ConnErrorCode) error {
 	return c.closeErr
 }
 
+func (c *connWithMetrics) BandwidthMetered() bool {
+	mc, ok := c.CapableConn.(bandwidthMeteredConn)
+	return ok && mc.BandwidthMetered()
+}
+
 func (c *connWithMetrics) Stat() network.ConnStats {
 	if cs, ok := c.CapableConn.(network.ConnStat); ok {
 		return cs.Stat()
@@ -4,7 +4,9 @@ import (
 	"context"
 	"crypto/sha256"
 	"fmt"
+	"net"
 	"testing"
+	"time"
 
 	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
@@ -17,6 +19,7 @@ import (
 	libp2pquic "github.com/TheNoobiCat/go-libp2p/p2p/transport/quic"
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/quicreuse"
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcp"
+	libp2pwebrtc "github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc"
 	webtransport "github.com/TheNoobiCat/go-libp2p/p2p/transport/webtransport"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -106,4 +109,31 @@ func TestDialAddressSelection(t *testing.T) {
 	require.Equal(t, webtransportTr, s.TransportForDialing(ma.StringCast(fmt.Sprintf("/ip4/127.0.0.1/udp/1234/quic-v1/webtransport/certhash/%s", certHash))))
 	require.Nil(t, s.TransportForDialing(ma.StringCast("/ip4/1.2.3.4")))
 	require.Nil(t, s.TransportForDialing(ma.StringCast("/ip4/1.2.3.4/tcp/443/ws")))
+
+	webrtcAddr := ma.StringCast(fmt.Sprintf("/ip4/127.0.0.1/udp/1234/quic-v1/p2p/%s/p2p-circuit/webrtc/p2p/%s", id, id))
+	require.Nil(t, s.TransportForDialing(webrtcAddr))
+	webrtcTr, err := libp2pwebrtc.NewPrivateTransport(nil, nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, s.AddTransport(webrtcTr))
+	require.Equal(t, webrtcTr, s.TransportForDialing(webrtcAddr))
+	require.Equal(t, circuitTr, s.TransportForDialing(ma.StringCast(fmt.Sprintf("/ip4/127.0.0.1/udp/1234/quic/p2p-circuit/p2p/%s", id))))
+}
+
+func TestAddrFilters(t *testing.T) {
+	f := ma.NewFilters()
+	_, loopback, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	f.AddFilter(*loopback, ma.ActionDeny)
+
+	s1 := swarmt.GenSwarm(t, swarmt.OptDialOnly, swarmt.WithSwarmOpts(swarm.WithAddrFilters(f)))
+	require.ErrorIs(t, s1.AddListenAddr(ma.StringCast("/ip4/127.0.0.1/tcp/0")), swarm.ErrAddrFiltered)
+	s2 := swarmt.GenSwarm(t)
+	s1.Peerstore().AddAddrs(s2.LocalPeer(), s2.ListenAddresses(), time.Hour)
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.ErrorIs(t, err, swarm.ErrAddrFiltered)
+
+	// the filters are applied to new dials
+	f.RemoveLiteral(*loopback)
+	_, err = s1.DialPeer(context.Background(), s2.LocalPeer())
+	require.NoError(t, err)
 }
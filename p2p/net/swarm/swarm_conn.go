@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
@@ -20,6 +21,10 @@ import (
 // ErrConnClosed is returned when operating on a closed connection.
 var ErrConnClosed = errors.New("connection closed")
 
+// ErrConnDraining is returned when opening a stream on a connection that is being
+// drained.
+var ErrConnDraining = errors.New("connection draining")
+
 // Conn is the connection type used by swarm. In general, you won't use this
 // type directly.
 type Conn struct {
@@ -27,20 +32,41 @@ type Conn struct {
 	conn  transport.CapableConn
 	swarm *Swarm
 
-	closeOnce sync.Once
-	err       error
+	closeOnce   sync.Once
+	err         error
+	closeReason network.ConnCloseReason
+	closed      atomic.Bool
+	draining    atomic.Bool
+	// drainClosing is set once DrainAndClose was called.
+	drainClosing atomic.Bool
 
 	notifyLk sync.Mutex
 
 	streams struct {
 		sync.Mutex
 		m map[*Stream]struct{}
+		// drained is closed when the last stream of a connection passed to
+		// DrainAndClose is removed.
+		drained chan struct{}
 	}
 
 	stat network.ConnStats
+
+	// bandwidthMetered is true if the connection's traffic is reported to the swarm's
+	// metrics.MuxerReporter at the muxer layer.
+	bandwidthMetered bool
 }
 
-var _ network.Conn = &Conn{}
+// bandwidthMeteredConn is implemented by connections whose traffic is reported to a
+// metrics.MuxerReporter at the muxer layer, see upgrader.WithBandwidthReporter.
+type bandwidthMeteredConn interface {
+	BandwidthMetered() bool
+}
+
+var (
+	_ network.Conn   = &Conn{}
+	_ network.Pinger = &Conn{}
+)
 
 func (c *Conn) IsClosed() bool {
 	return c.conn.IsClosed()
@@ -58,20 +84,92 @@ func (c *Conn) ID() string {
 // open notifications must finish before we can fire off the close
 // notifications).
 func (c *Conn) Close() error {
-	c.closeOnce.Do(func() {
-		c.doClose(0)
-	})
-	return c.err
+	return c.closeWithReason(network.ConnCloseReason{})
 }
 
 func (c *Conn) CloseWithError(errCode network.ConnErrorCode) error {
+	return c.closeWithReason(network.ConnCloseReason{ErrorCode: errCode})
+}
+
+// closeWithReason closes the connection. The reason is reported by CloseReason.
+func (c *Conn) closeWithReason(reason network.ConnCloseReason) error {
 	c.closeOnce.Do(func() {
-		c.doClose(errCode)
+		c.doClose(reason)
 	})
 	return c.err
 }
 
-func (c *Conn) doClose(errCode network.ConnErrorCode) {
+// Drain stops new streams from being opened on the connection, in both directions.
+// Inbound streams are reset, and NewStream fails with ErrConnDraining. The open streams
+// are unaffected. The connection manager drains the connections it trims, so that their
+// streams can finish before they're closed.
+func (c *Conn) Drain() {
+	c.draining.Store(true)
+}
+
+// DrainAndClose drains the connection, see Drain, and closes it with errCode once its
+// open streams are done, or once timeout has elapsed on the swarm's clock, whichever
+// comes first. Connections without open streams are closed right away.
+func (c *Conn) DrainAndClose(timeout time.Duration, errCode network.ConnErrorCode) {
+	c.Drain()
+	if !c.drainClosing.CompareAndSwap(false, true) {
+		return
+	}
+
+	c.streams.Lock()
+	if c.stat.NumStreams == 0 {
+		c.streams.Unlock()
+		c.CloseWithError(errCode)
+		return
+	}
+	drained := make(chan struct{})
+	c.streams.drained = drained
+	c.streams.Unlock()
+
+	c.swarm.refs.Add(1)
+	go func() {
+		defer c.swarm.refs.Done()
+		timer := c.swarm.clock.InstantTimer(c.swarm.clock.Now().Add(timeout))
+		defer timer.Stop()
+		select {
+		case <-drained:
+		case <-timer.Ch():
+			log.Debugw("drain timeout elapsed, closing conn", "peer", c.RemotePeer(), "streams", c.Stat().NumStreams)
+		case <-c.swarm.ctx.Done():
+			return
+		}
+		c.CloseWithError(errCode)
+	}()
+}
+
+// CloseReason returns the reason the connection was closed.
+func (c *Conn) CloseReason() (network.ConnCloseReason, bool) {
+	if !c.closed.Load() {
+		return network.ConnCloseReason{}, false
+	}
+	return c.closeReason, true
+}
+
+// Ping measures the RTT of the connection with the ping of its stream multiplexer or
+// transport, without opening a stream.
+func (c *Conn) Ping(ctx context.Context) (time.Duration, error) {
+	if c.closed.Load() {
+		return 0, ErrConnClosed
+	}
+	tc := c.conn
+	if mc, ok := tc.(*connWithMetrics); ok {
+		tc = mc.CapableConn
+	}
+	p, ok := tc.(network.Pinger)
+	if !ok {
+		return 0, network.ErrPingNotSupported
+	}
+	return p.Ping(ctx)
+}
+
+func (c *Conn) doClose(reason network.ConnCloseReason) {
+	c.closeReason = reason
+	c.closed.Store(true)
 	c.swarm.removeConn(c)
 
 	// Prevent new streams from opening.
@@ -80,8 +178,13 @@ func (c *Conn) doClose(errCode network.ConnErrorCode) {
 	c.streams.m = nil
 	c.streams.Unlock()
 
-	if errCode != 0 {
-		c.err = c.conn.CloseWithError(errCode)
+	// Record the latest RTT while the connection still reports it.
+	c.swarm.recordRTT(c)
+
+	// There's no point in sending an error code on a connection closed by the
+	// transport.
+	if reason.ErrorCode != 0 && reason.Err == nil {
+		c.err = c.conn.CloseWithError(reason.ErrorCode)
 	} else {
 		c.err = c.conn.Close()
 	}
@@ -89,7 +192,7 @@ func (c *Conn) doClose(errCode network.ConnErrorCode) {
 	// Send the connectedness event after closing the connection.
 	// This ensures that both remote connection close and local connection
 	// close events are sent after the underlying transport connection is closed.
-	c.swarm.connectednessEventEmitter.RemoveConn(c.RemotePeer())
+	c.swarm.connectednessEventEmitter.RemoveConn(c.RemotePeer(), &reason)
 
 	// This is just for cleaning up state. The connection has already been closed.
 	// We *could* optimize this but it really isn't worth it.
@@ -115,6 +218,10 @@ func (c *Conn) removeStream(s *Stream) {
 	c.streams.Lock()
 	c.stat.NumStreams--
 	delete(c.streams.m, s)
+	if c.stat.NumStreams == 0 && c.streams.drained != nil {
+		close(c.streams.drained)
+		c.streams.drained = nil
+	}
 	c.streams.Unlock()
 	s.scope.Done()
 }
@@ -126,12 +233,18 @@ func (c *Conn) removeStream(s *Stream) {
 func (c *Conn) start() {
 	go func() {
 		defer c.swarm.refs.Done()
-		defer c.Close()
 		for {
 			ts, err := c.conn.AcceptStream()
 			if err != nil {
+				// If the connection was closed locally, this is a no-op and the
+				// reason passed to Close or CloseWithError is kept.
+				c.closeWithReason(network.NewConnCloseReason(err))
 				return
 			}
+			if c.draining.Load() {
+				ts.ResetWithError(network.StreamGarbageCollected)
+				continue
+			}
 			scope, err := c.swarm.ResourceManager().OpenStream(c.RemotePeer(), network.DirInbound)
 			if err != nil {
 				ts.ResetWithError(network.StreamResourceLimitExceeded)
@@ -211,6 +324,9 @@ func (c *Conn) Stat() network.ConnStats {
 
 // NewStream returns a new Stream from this connection
 func (c *Conn) NewStream(ctx context.Context) (network.Stream, error) {
+	if c.draining.Load() {
+		return nil, ErrConnDraining
+	}
 	if c.Stat().Limited {
 		if useLimited, _ := network.GetAllowLimitedConn(ctx); !useLimited {
 			return nil, network.ErrLimitedConn
@@ -5,15 +5,19 @@ import (
 	"errors"
 	"fmt"
 	"net/netip"
+	"runtime/pprof"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/canonicallog"
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
 
 	ma "github.com/multiformats/go-multiaddr"
 	mafmt "github.com/multiformats/go-multiaddr-fmt"
@@ -52,22 +56,40 @@ var (
 	// given multiaddr.
 	ErrNoTransport = errors.New("no transport for protocol")
 
-	// ErrAllDialsFailed is returned when connecting to a peer has ultimately failed
-	ErrAllDialsFailed = errors.New("all dials failed")
+	// ErrAllDialsFailed is returned when connecting to a peer has ultimately failed.
+	// It matches network.ErrAllDialsFailed.
+	ErrAllDialsFailed error = &networkError{"all dials failed", network.ErrAllDialsFailed}
 
 	// ErrNoAddresses is returned when we fail to find any addresses for a
-	// peer we're trying to dial.
-	ErrNoAddresses = errors.New("no addresses")
+	// peer we're trying to dial. It matches network.ErrNoRemoteAddrs.
+	ErrNoAddresses error = &networkError{"no addresses", network.ErrNoRemoteAddrs}
 
 	// ErrNoGoodAddresses is returned when we find addresses for a peer but
-	// can't use any of them.
-	ErrNoGoodAddresses = errors.New("no good addresses")
+	// can't use any of them. It matches network.ErrNoRemoteAddrs.
+	ErrNoGoodAddresses error = &networkError{"no good addresses", network.ErrNoRemoteAddrs}
+
+	// ErrDialRefusedHighWater is returned when the connection manager has reached its
+	// high water mark, see WithConnManagerHeadroom. It matches
+	// network.ErrResourceLimitExceeded.
+	ErrDialRefusedHighWater error = &networkError{"dial refused because the connection manager is above its high water mark", network.ErrResourceLimitExceeded}
 
 	// ErrGaterDisallowedConnection is returned when the gater prevents us from
-	// forming a connection with a peer.
-	ErrGaterDisallowedConnection = errors.New("gater disallows connection to peer")
+	// forming a connection with a peer. It matches network.ErrPeerGated.
+	ErrGaterDisallowedConnection error = &networkError{"gater disallows connection to peer", network.ErrPeerGated}
 )
 
+// networkError is an error of the swarm that matches (using errors.Is) one of the
+// errors of the network package, so that callers can tell failures apart without
+// depending on the swarm.
+type networkError struct {
+	msg    string
+	target error
+}
+
+func (e *networkError) Error() string { return e.msg }
+
+func (e *networkError) Is(target error) bool { return target == e.target }
+
 // ErrQUICDraft29 wraps ErrNoTransport and provide a more meaningful error message
 var ErrQUICDraft29 errQUICDraft29
 
@@ -94,6 +116,18 @@ const ConcurrentFdDials = 160
 // per peer
 var DefaultPerPeerRateLimit = 8
 
+// DialBackoffTracker tracks the addresses the swarm backs off from dialing.
+type DialBackoffTracker interface {
+	// Backoff returns whether dialing addr of p should be backed off from.
+	Backoff(p peer.ID, addr ma.Multiaddr) bool
+	// AddBackoff records a failed dial to addr of p.
+	AddBackoff(p peer.ID, addr ma.Multiaddr)
+	// Clear clears the backoffs of all the addresses of p, once connected to p.
+	Clear(p peer.ID)
+}
+
+var _ DialBackoffTracker = (*DialBackoff)(nil)
+
 // DialBackoff is a type for tracking peer dial backoffs. Dialbackoff is used to
 // avoid over-dialing the same, dead peers. Whenever we totally time out on all
 // addresses of a peer, we add the addresses to DialBackoff. Then, whenever we
@@ -107,6 +141,8 @@ var DefaultPerPeerRateLimit = 8
 type DialBackoff struct {
 	entries map[peer.ID]map[string]*backoffAddr
 	lock    sync.RWMutex
+	// clock is RealClock if nil
+	clock Clock
 }
 
 type backoffAddr struct {
@@ -118,18 +154,30 @@ func (db *DialBackoff) init(ctx context.Context) {
 	if db.entries == nil {
 		db.entries = make(map[peer.ID]map[string]*backoffAddr)
 	}
-	go db.background(ctx)
+	cl := db.clock
+	if cl == nil {
+		cl = RealClock{}
+	}
+	go db.background(ctx, cl, cl.InstantTimer(cl.Now().Add(BackoffMax)))
+}
+
+func (db *DialBackoff) now() time.Time {
+	if db.clock == nil {
+		return time.Now()
+	}
+	return db.clock.Now()
 }
 
-func (db *DialBackoff) background(ctx context.Context) {
-	ticker := time.NewTicker(BackoffMax)
-	defer ticker.Stop()
+// background cleans up the expired backoffs whenever timer fires.
+func (db *DialBackoff) background(ctx context.Context, cl Clock, timer InstantTimer) {
+	defer timer.Stop()
 	for {
 		select {
 		case <-ctx.Done():
 			return
-		case <-ticker.C:
+		case <-timer.Ch():
 			db.cleanup()
+			timer.Reset(cl.Now().Add(BackoffMax))
 		}
 	}
 }
@@ -141,7 +189,7 @@ func (db *DialBackoff) Backoff(p peer.ID, addr ma.Multiaddr) (backoff bool) {
 	defer db.lock.RUnlock()
 
 	ap, found := db.entries[p][string(addr.Bytes())]
-	return found && time.Now().Before(ap.until)
+	return found && db.now().Before(ap.until)
 }
 
 // BackoffBase is the base amount of time to backoff (default: 5s).
@@ -174,7 +222,7 @@ func (db *DialBackoff) AddBackoff(p peer.ID, addr ma.Multiaddr) {
 	if !ok {
 		bp[saddr] = &backoffAddr{
 			tries: 1,
-			until: time.Now().Add(BackoffBase),
+			until: db.now().Add(BackoffBase),
 		}
 		return
 	}
@@ -183,7 +231,7 @@ func (db *DialBackoff) AddBackoff(p peer.ID, addr ma.Multiaddr) {
 	if backoffTime > BackoffMax {
 		backoffTime = BackoffMax
 	}
-	ba.until = time.Now().Add(backoffTime)
+	ba.until = db.now().Add(backoffTime)
 	ba.tries++
 }
 
@@ -198,7 +246,7 @@ func (db *DialBackoff) Clear(p peer.ID) {
 func (db *DialBackoff) cleanup() {
 	db.lock.Lock()
 	defer db.lock.Unlock()
-	now := time.Now()
+	now := db.now()
 	for p, e := range db.entries {
 		good := false
 		for _, backoff := range e {
@@ -259,6 +307,11 @@ func (s *Swarm) dialPeer(ctx context.Context, p peer.ID) (*Conn, error) {
 		return nil, &DialError{Peer: p, Cause: ErrGaterDisallowedConnection}
 	}
 
+	if s.connmgr != nil && !s.allowDialAboveHighWater(ctx, p) {
+		log.Debugw("connection manager above high water mark, refusing dial", "peer", p)
+		return nil, &DialError{Peer: p, Cause: ErrDialRefusedHighWater}
+	}
+
 	// apply the DialPeer timeout
 	ctx, cancel := context.WithTimeout(ctx, network.GetDialPeerTimeout(ctx))
 	defer cancel()
@@ -290,14 +343,42 @@ func (s *Swarm) dialPeer(ctx context.Context, p peer.ID) (*Conn, error) {
 	return nil, err
 }
 
+// allowDialAboveHighWater returns true if the dial to p can proceed: the connection
+// manager is below its high water mark, p is protected, or the dial is forced.
+func (s *Swarm) allowDialAboveHighWater(ctx context.Context, p peer.ID) bool {
+	if !s.connmgr.(connmgr.HighWaterChecker).AboveHighWater() {
+		return true
+	}
+	if ok, _ := network.GetDialAboveHighWater(ctx); ok {
+		return true
+	}
+	if ok, _ := network.GetForceDirectDial(ctx); ok {
+		return true
+	}
+	return s.connmgr.IsProtected(p, "")
+}
+
 // dialWorkerLoop synchronizes and executes concurrent dials to a single peer
 func (s *Swarm) dialWorkerLoop(p peer.ID, reqch <-chan dialRequest) {
-	w := newDialWorker(s, p, reqch, nil)
+	if s.pprofLabels {
+		pprof.SetGoroutineLabels(pprof.WithLabels(context.Background(), pprof.Labels(metricshelper.PprofLabelPeer, metricshelper.PprofPeer(p))))
+	}
+	w := newDialWorker(s, p, reqch, s.clock)
 	w.loop()
 }
 
 func (s *Swarm) addrsForDial(ctx context.Context, p peer.ID) (goodAddrs []ma.Multiaddr, addrErrs []TransportError, err error) {
 	peerAddrs := s.peers.Addrs(p)
+	dialAddrs, hasDialAddrs := network.GetDialAddrs(ctx)
+	if hasDialAddrs {
+		peerAddrs = dialAddrs
+	}
+	if len(s.preDialHooks) > 0 {
+		peerAddrs, err = s.runPreDialHooks(ctx, p, peerAddrs)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
 	if len(peerAddrs) == 0 {
 		return nil, nil, ErrNoAddresses
 	}
@@ -310,16 +391,50 @@ func (s *Swarm) addrsForDial(ctx context.Context, p peer.ID) (goodAddrs []ma.Mul
 	if forceDirect, _ := network.GetForceDirectDial(ctx); forceDirect {
 		goodAddrs = ma.FilterAddrs(goodAddrs, s.nonProxyAddr)
 	}
+	if hasDialConstraints(ctx) {
+		goodAddrs = ma.FilterAddrs(goodAddrs, func(a ma.Multiaddr) bool { return dialConstraintsAllow(ctx, a) })
+	}
 
 	if len(goodAddrs) == 0 {
 		return nil, addrErrs, ErrNoGoodAddresses
 	}
 
-	s.peers.AddAddrs(p, goodAddrs, peerstore.TempAddrTTL)
+	if !hasDialAddrs {
+		s.peers.AddAddrs(p, goodAddrs, peerstore.TempAddrTTL)
+	}
 
 	return goodAddrs, addrErrs, nil
 }
 
+// hasDialConstraints returns whether ctx constrains the connections used and dialed,
+// with network.WithTransportConstraint or network.WithAddrFilter.
+func hasDialConstraints(ctx context.Context) bool {
+	return network.GetTransportConstraint(ctx) != nil || network.GetAddrFilter(ctx) != nil
+}
+
+// dialConstraintsAllow returns whether the constraints of ctx allow connections to the
+// remote address a.
+func dialConstraintsAllow(ctx context.Context, a ma.Multiaddr) bool {
+	if transports := network.GetTransportConstraint(ctx); transports != nil {
+		if !slices.Contains(transports, metricshelper.GetTransport(a)) {
+			return false
+		}
+	}
+	if f := network.GetAddrFilter(ctx); f != nil && !f(a) {
+		return false
+	}
+	return true
+}
+
+// dialConstraintsFilter returns a filter of the connections allowed by the
+// constraints of ctx, or nil if ctx has no constraints.
+func dialConstraintsFilter(ctx context.Context) func(*Conn) bool {
+	if !hasDialConstraints(ctx) {
+		return nil
+	}
+	return func(c *Conn) bool { return dialConstraintsAllow(ctx, c.RemoteMultiaddr()) }
+}
+
 func startsWithDNSComponent(m ma.Multiaddr) bool {
 	if m == nil {
 		return false
@@ -461,7 +576,7 @@ func (s *Swarm) resolveAddrs(ctx context.Context, pi peer.AddrInfo) []ma.Multiad
 func (s *Swarm) dialNextAddr(ctx context.Context, p peer.ID, addr ma.Multiaddr, resch chan transport.DialUpdate) error {
 	// check the dial backoff
 	if forceDirect, _ := network.GetForceDirectDial(ctx); !forceDirect {
-		if s.backf.Backoff(p, addr) {
+		if s.dialBackoff.Backoff(p, addr) {
 			return ErrDialBackoff
 		}
 	}
@@ -552,6 +667,13 @@ func (s *Swarm) filterKnownUndialables(p peer.ID, addrs []ma.Multiaddr) (goodAdd
 		},
 		// TODO: Consider allowing link-local addresses
 		func(addr ma.Multiaddr) bool { return !manet.IsIP6LinkLocal(addr) },
+		func(addr ma.Multiaddr) bool {
+			if s.addrFilters != nil && s.addrFilters.AddrBlocked(addr) {
+				addrErrs = append(addrErrs, TransportError{Address: addr, Cause: ErrAddrFiltered})
+				return false
+			}
+			return true
+		},
 		func(addr ma.Multiaddr) bool {
 			if s.gater != nil && !s.gater.InterceptAddrDial(p, addr) {
 				addrErrs = append(addrErrs, TransportError{Address: addr, Cause: ErrGaterDisallowedConnection})
@@ -570,6 +692,13 @@ func (s *Swarm) limitedDial(ctx context.Context, p peer.ID, a ma.Multiaddr, resp
 	if manet.IsPrivateAddr(a) && s.dialTimeoutLocal < s.dialTimeout {
 		timeout = s.dialTimeoutLocal
 	}
+	if s.pprofLabels {
+		// set on the goroutine of the dial by the limiter
+		ctx = pprof.WithLabels(ctx, pprof.Labels(
+			metricshelper.PprofLabelPeer, metricshelper.PprofPeer(p),
+			metricshelper.PprofLabelTransport, metricshelper.GetTransport(a),
+		))
+	}
 	s.limiter.AddDialJob(&dialJob{
 		addr:    a,
 		peer:    p,
@@ -662,18 +791,43 @@ func isRelayAddr(addr ma.Multiaddr) bool {
 	return err == nil
 }
 
+// isWebRTCAddr returns whether addr is a /webrtc address, a relay address followed by
+// /webrtc.
+func isWebRTCAddr(addr ma.Multiaddr) bool {
+	addr = withoutPeerID(addr)
+	return len(addr) > 0 && addr[len(addr)-1].Code() == ma.P_WEBRTC
+}
+
+// withoutPeerID returns addr without its trailing /p2p component, if any.
+func withoutPeerID(addr ma.Multiaddr) ma.Multiaddr {
+	if len(addr) > 0 && addr[len(addr)-1].Code() == ma.P_P2P {
+		return addr[:len(addr)-1]
+	}
+	return addr
+}
+
 // filterLowPriorityAddresses removes addresses inplace for which we have a better alternative
 //  1. If a /quic-v1 address is present, filter out /quic and /webtransport address on the same 2-tuple:
 //     QUIC v1 is preferred over the deprecated QUIC draft-29, and given the choice, we prefer using
 //     raw QUIC over using WebTransport.
 //  2. If a /tcp address is present, filter out /ws or /wss addresses on the same 2-tuple:
 //     We prefer using raw TCP over using WebSocket.
+//  3. If a /webrtc address is present, filter out the relay address it's derived from:
+//     The /webrtc transport opens the relayed connection used for signaling itself. A
+//     separate dial of the relay address would complete first, and the direct WebRTC
+//     connection would never be established. If the WebRTC connection fails, the
+//     request is answered with the relayed connection anyway.
 func filterLowPriorityAddresses(addrs []ma.Multiaddr) []ma.Multiaddr {
 	// make a map of QUIC v1 and TCP AddrPorts.
 	quicV1Addr := make(map[netip.AddrPort]struct{})
 	tcpAddr := make(map[netip.AddrPort]struct{})
+	// relay addresses of the /webrtc addresses, without the trailing /p2p component
+	webrtcRelayAddr := make(map[string]struct{})
 	for _, a := range addrs {
 		switch {
+		case isWebRTCAddr(a):
+			relay := withoutPeerID(a)
+			webrtcRelayAddr[string(withoutPeerID(relay[:len(relay)-1]).Bytes())] = struct{}{}
 		case isProtocolAddr(a, ma.P_WEBTRANSPORT):
 		case isProtocolAddr(a, ma.P_QUIC_V1):
 			ap, err := addrPort(a, ma.P_UDP)
@@ -694,6 +848,10 @@ func filterLowPriorityAddresses(addrs []ma.Multiaddr) []ma.Multiaddr {
 	i := 0
 	for _, a := range addrs {
 		switch {
+		case len(webrtcRelayAddr) > 0 && isRelayAddr(a) && !isWebRTCAddr(a):
+			if _, ok := webrtcRelayAddr[string(withoutPeerID(a).Bytes())]; ok {
+				continue
+			}
 		case isProtocolAddr(a, ma.P_WEBTRANSPORT) || isProtocolAddr(a, ma.P_QUIC):
 			ap, err := addrPort(a, ma.P_UDP)
 			if err != nil {
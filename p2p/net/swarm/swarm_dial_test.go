@@ -356,6 +356,44 @@ func TestAddrsForDialFiltering(t *testing.T) {
 	}
 }
 
+func TestFilterLowPriorityWebRTCAddrs(t *testing.T) {
+	relayID := test.RandPeerIDFatal(t)
+	p := test.RandPeerIDFatal(t)
+	relay1 := ma.StringCast("/ip4/1.2.3.4/udp/1/quic-v1/p2p/" + relayID.String() + "/p2p-circuit")
+	relay2 := ma.StringCast("/ip4/1.2.3.4/tcp/1/p2p/" + relayID.String() + "/p2p-circuit")
+	webrtc1 := relay1.Encapsulate(ma.StringCast("/webrtc"))
+	withPeer := func(a ma.Multiaddr) ma.Multiaddr {
+		return a.Encapsulate(ma.StringCast("/p2p/" + p.String()))
+	}
+
+	testCases := []struct {
+		name   string
+		input  []ma.Multiaddr
+		output []ma.Multiaddr
+	}{
+		{
+			name:   "relay-filtered",
+			input:  []ma.Multiaddr{relay1, relay2, webrtc1},
+			output: []ma.Multiaddr{relay2, webrtc1},
+		},
+		{
+			name:   "relay-filtered-with-peer-id",
+			input:  []ma.Multiaddr{withPeer(relay1), withPeer(relay2), withPeer(webrtc1)},
+			output: []ma.Multiaddr{withPeer(relay2), withPeer(webrtc1)},
+		},
+		{
+			name:   "no-webrtc",
+			input:  []ma.Multiaddr{relay1, relay2},
+			output: []ma.Multiaddr{relay1, relay2},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.ElementsMatch(t, tc.output, filterLowPriorityAddresses(tc.input))
+		})
+	}
+}
+
 func TestBlackHoledAddrBlocked(t *testing.T) {
 	resolver, err := madns.NewResolver()
 	if err != nil {
@@ -365,7 +403,9 @@ func TestBlackHoledAddrBlocked(t *testing.T) {
 	defer s.Close()
 
 	n := 3
-	s.bhd.ipv6 = &BlackHoleSuccessCounter{N: n, MinSuccesses: 1, Name: "IPv6"}
+	s.bhd = newBlackHoleDetector(map[BlackHoleKey]BlackHolePolicy{
+		BlackHoleKeyIPv6: &BlackHoleSuccessCounter{N: n, MinSuccesses: 1, Name: "IPv6"},
+	}, nil, false)
 
 	// All dials to this addr will fail.
 	// manet.IsPublic is aggressive for IPv6 addresses. Use a NAT64 address.
@@ -429,3 +469,26 @@ func TestSkipDialingManyDNS(t *testing.T) {
 	require.NoError(t, err)
 	require.Less(t, len(resolved), 3, "got: %v", resolved)
 }
+
+func TestDialBackoffClock(t *testing.T) {
+	cl := newMockClock()
+	s := makeSwarmWithNoListenAddrs(t, WithClock(cl))
+	defer s.Close()
+
+	_, p := newPeer(t)
+	a := ma.StringCast("/ip4/1.2.3.4/tcp/1")
+	s.Backoff().AddBackoff(p, a)
+	require.True(t, s.Backoff().Backoff(p, a))
+	cl.AdvanceBy(BackoffBase - time.Second)
+	require.True(t, s.Backoff().Backoff(p, a))
+	cl.AdvanceBy(time.Second)
+	require.False(t, s.Backoff().Backoff(p, a))
+
+	// expired entries are cleaned up on the clock too
+	cl.AdvanceBy(BackoffMax)
+	require.Eventually(t, func() bool {
+		s.backf.lock.RLock()
+		defer s.backf.lock.RUnlock()
+		return len(s.backf.entries) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -136,7 +136,7 @@ func TestConnectednessEvents(t *testing.T) {
 				return
 			}
 			if evt.Connectedness != network.Connected {
-				t.Errorf("invalid event received: expected: Connected, got: %s", evt)
+				t.Errorf("invalid event received: expected: Connected, got: %v", evt)
 				return
 			}
 		}
@@ -164,7 +164,7 @@ func TestConnectednessEvents(t *testing.T) {
 				return
 			}
 			if evt.Connectedness != network.NotConnected {
-				t.Errorf("invalid event received: expected: NotConnected, got: %s", evt)
+				t.Errorf("invalid event received: expected: NotConnected, got: %v", evt)
 				return
 			}
 		}
@@ -191,7 +191,7 @@ func TestConnectednessEvents(t *testing.T) {
 				return
 			}
 			if evt.Connectedness != network.NotConnected {
-				t.Errorf("invalid event received: expected: NotConnected, got: %s", evt)
+				t.Errorf("invalid event received: expected: NotConnected, got: %v", evt)
 				return
 			}
 		}
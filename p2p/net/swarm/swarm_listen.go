@@ -98,6 +98,10 @@ func (s *Swarm) ListenClose(addrs ...ma.Multiaddr) {
 // AddListenAddr tells the swarm to listen on a single address. Unlike Listen,
 // this method does not attempt to filter out bad addresses.
 func (s *Swarm) AddListenAddr(a ma.Multiaddr) error {
+	if s.addrFilters != nil && s.addrFilters.AddrBlocked(a) {
+		return fmt.Errorf("cannot listen on %s: %w", a, ErrAddrFiltered)
+	}
+
 	tpt := s.TransportForListening(a)
 	if tpt == nil {
 		// TransportForListening will return nil if either:
@@ -9,6 +9,7 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -118,6 +119,76 @@ var (
 		},
 		[]string{"name"},
 	)
+	duplicateConnsClosed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "duplicate_connections_closed_total",
+			Help:      "Duplicate connections closed after a simultaneous open",
+		},
+		[]string{"dir", "transport"},
+	)
+	muxerBufferedBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "muxer_buffered_bytes",
+			Help:      "Bytes received on streams but not read by the application yet",
+		},
+		[]string{"transport"},
+	)
+	muxerBackpressuredConns = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "muxer_backpressured_connections",
+			Help:      "Connections with their receive window almost full of unread data",
+		},
+		[]string{"transport"},
+	)
+	idleStreamsReset = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "idle_streams_reset_total",
+			Help:      "Streams reset for being idle for longer than the idle stream timeout",
+		},
+		[]string{"protocol"},
+	)
+	dialsCanceled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "dials_canceled_total",
+			Help:      "In-flight dials canceled after a concurrent dial to the same peer succeeded",
+		},
+		[]string{"transport", "ip_version"},
+	)
+	dialsCanceledWastedTime = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "dials_canceled_seconds_total",
+			Help:      "Time spent on dials canceled after a concurrent dial to the same peer succeeded",
+		},
+		[]string{"transport", "ip_version"},
+	)
+	dialBudgetExceeded = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "dial_budget_exceeded_total",
+			Help:      "Dials to a peer aborted because the dial budget was spent",
+		},
+	)
+	dialQueueLength = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "dial_queue_length",
+			Help:      "Dials waiting for the global limit of concurrent dials",
+		},
+	)
+	dialQueueDelay = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "dial_queue_delay_seconds",
+			Help:      "Time dials waited for the global limit of concurrent dials",
+			Buckets:   []float64{0.001, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+		},
+	)
 	collectors = []prometheus.Collector{
 		connsOpened,
 		keyTypes,
@@ -131,6 +202,15 @@ var (
 		blackHoleSuccessCounterSuccessFraction,
 		blackHoleSuccessCounterState,
 		blackHoleSuccessCounterNextRequestAllowedAfter,
+		duplicateConnsClosed,
+		muxerBufferedBytes,
+		muxerBackpressuredConns,
+		idleStreamsReset,
+		dialsCanceled,
+		dialsCanceledWastedTime,
+		dialBudgetExceeded,
+		dialQueueLength,
+		dialQueueDelay,
 	}
 )
 
@@ -142,11 +222,50 @@ type MetricsTracer interface {
 	DialCompleted(success bool, totalDials int, latency time.Duration)
 	DialRankingDelay(d time.Duration)
 	UpdatedBlackHoleSuccessCounter(name string, state BlackHoleState, nextProbeAfter int, successFraction float64)
+	ClosedDuplicateConnection(network.Direction, network.ConnectionState)
+}
+
+// MuxerStatsTracer is implemented by MetricsTracers that track the buffered bytes and
+// the number of back-pressured connections, per transport. See network.MuxerStats.
+type MuxerStatsTracer interface {
+	UpdatedMuxerStats(transport string, bufferedBytes int64, backpressuredConns int)
+}
+
+// IdleStreamTracer is implemented by MetricsTracers that count the streams reset for
+// being idle, see WithIdleStreamTimeout.
+type IdleStreamTracer interface {
+	ResetIdleStream(p protocol.ID)
+}
+
+// CanceledDialTracer is implemented by MetricsTracers that track the in-flight dials
+// canceled because a concurrent dial to the same peer succeeded.
+type CanceledDialTracer interface {
+	CanceledDial(addr ma.Multiaddr, wasted time.Duration)
+}
+
+// DialBudgetTracer is implemented by MetricsTracers that count the dials that exceeded
+// their budget, see WithDialBudget.
+type DialBudgetTracer interface {
+	ExceededDialBudget()
+}
+
+// DialQueueTracer is implemented by MetricsTracers that track the dials queued by the
+// global limit on concurrent dials, see WithMaxConcurrentDials.
+type DialQueueTracer interface {
+	UpdatedDialQueueLength(n int)
+	DialQueueDelay(d time.Duration)
 }
 
 type metricsTracer struct{}
 
-var _ MetricsTracer = &metricsTracer{}
+var (
+	_ MetricsTracer      = &metricsTracer{}
+	_ MuxerStatsTracer   = &metricsTracer{}
+	_ IdleStreamTracer   = &metricsTracer{}
+	_ CanceledDialTracer = &metricsTracer{}
+	_ DialBudgetTracer   = &metricsTracer{}
+	_ DialQueueTracer    = &metricsTracer{}
+)
 
 type metricsTracerSetting struct {
 	reg prometheus.Registerer
@@ -229,8 +348,14 @@ func (m *metricsTracer) CompletedHandshake(t time.Duration, cs network.Connectio
 func (m *metricsTracer) FailedDialing(addr ma.Multiaddr, dialErr error, cause error) {
 	transport := metricshelper.GetTransport(addr)
 	e := "other"
-	// dial deadline exceeded or the the parent contexts deadline exceeded
-	if errors.Is(dialErr, context.DeadlineExceeded) || errors.Is(cause, context.DeadlineExceeded) {
+	if errors.Is(cause, errDialAttemptTimeout) {
+		// the dial took longer than the dial timeout
+		e = "attempt timeout"
+	} else if errors.Is(cause, ErrDialBudgetExceeded) {
+		// the dial budget of the peer was spent
+		e = "dial budget"
+	} else if errors.Is(dialErr, context.DeadlineExceeded) || errors.Is(cause, context.DeadlineExceeded) {
+		// the parent contexts deadline exceeded
 		e = "deadline"
 	} else if errors.Is(dialErr, context.Canceled) {
 		// dial was cancelled.
@@ -296,3 +421,57 @@ func (m *metricsTracer) UpdatedBlackHoleSuccessCounter(name string, state BlackH
 	blackHoleSuccessCounterSuccessFraction.WithLabelValues(*tags...).Set(successFraction)
 	blackHoleSuccessCounterNextRequestAllowedAfter.WithLabelValues(*tags...).Set(float64(nextProbeAfter))
 }
+
+func (m *metricsTracer) ClosedDuplicateConnection(dir network.Direction, cs network.ConnectionState) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	*tags = append(*tags, metricshelper.GetDirection(dir))
+	if cs.Transport == "" {
+		*tags = append(*tags, "unknown")
+	} else {
+		*tags = append(*tags, cs.Transport)
+	}
+	duplicateConnsClosed.WithLabelValues(*tags...).Inc()
+}
+
+func (m *metricsTracer) UpdatedMuxerStats(transport string, bufferedBytes int64, backpressuredConns int) {
+	if transport == "" {
+		transport = "unknown"
+	}
+	muxerBufferedBytes.WithLabelValues(transport).Set(float64(bufferedBytes))
+	muxerBackpressuredConns.WithLabelValues(transport).Set(float64(backpressuredConns))
+}
+
+func (m *metricsTracer) ResetIdleStream(p protocol.ID) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	if p == "" {
+		*tags = append(*tags, "unnegotiated")
+	} else {
+		*tags = append(*tags, string(p))
+	}
+	idleStreamsReset.WithLabelValues(*tags...).Inc()
+}
+
+func (m *metricsTracer) CanceledDial(addr ma.Multiaddr, wasted time.Duration) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	*tags = append(*tags, metricshelper.GetTransport(addr), metricshelper.GetIPVersion(addr))
+	dialsCanceled.WithLabelValues(*tags...).Inc()
+	dialsCanceledWastedTime.WithLabelValues(*tags...).Add(wasted.Seconds())
+}
+
+func (m *metricsTracer) ExceededDialBudget() {
+	dialBudgetExceeded.Inc()
+}
+
+func (m *metricsTracer) UpdatedDialQueueLength(n int) {
+	dialQueueLength.Set(float64(n))
+}
+
+func (m *metricsTracer) DialQueueDelay(d time.Duration) {
+	dialQueueDelay.Observe(d.Seconds())
+}
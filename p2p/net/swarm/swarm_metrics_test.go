@@ -12,6 +12,7 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	ma "github.com/multiformats/go-multiaddr"
 
 	mrand "math/rand"
@@ -57,6 +58,7 @@ func TestMetricsNoAllocNoCover(t *testing.T) {
 	}
 
 	directions := []network.Direction{network.DirInbound, network.DirOutbound}
+	protocols := []protocol.ID{"", "/ipfs/ping/1.0.0", "/ipfs/id/1.0.0"}
 
 	_, pub1, err := crypto.GenerateEd25519Key(rand.Reader)
 	require.NoError(t, err)
@@ -69,6 +71,8 @@ func TestMetricsNoAllocNoCover(t *testing.T) {
 	errors := []error{
 		context.Canceled,
 		context.DeadlineExceeded,
+		errDialAttemptTimeout,
+		ErrDialBudgetExceeded,
 		&net.OpError{Err: syscall.ETIMEDOUT},
 	}
 
@@ -79,7 +83,7 @@ func TestMetricsNoAllocNoCover(t *testing.T) {
 	}
 
 	bhfNames := []string{"udp", "ipv6", "tcp", "icmp"}
-	bhfState := []BlackHoleState{blackHoleStateAllowed, blackHoleStateBlocked}
+	bhfState := []BlackHoleState{BlackHoleStateAllowed, BlackHoleStateBlocked}
 
 	tests := map[string]func(){
 		"OpenedConnection": func() {
@@ -102,6 +106,21 @@ func TestMetricsNoAllocNoCover(t *testing.T) {
 				mrand.Float64(),
 			)
 		},
+		"ClosedDuplicateConnection": func() {
+			mt.ClosedDuplicateConnection(randItem(directions), randItem(connections))
+		},
+		"UpdatedMuxerStats": func() {
+			mt.(MuxerStatsTracer).UpdatedMuxerStats(randItem(connections).Transport, mrand.Int63n(1<<20), mrand.Intn(10))
+		},
+		"ResetIdleStream": func() {
+			mt.(IdleStreamTracer).ResetIdleStream(randItem(protocols))
+		},
+		"CanceledDial": func() {
+			mt.(CanceledDialTracer).CanceledDial(randItem(addrs), time.Duration(mrand.Intn(1e10)))
+		},
+		"ExceededDialBudget":     func() { mt.(DialBudgetTracer).ExceededDialBudget() },
+		"UpdatedDialQueueLength": func() { mt.(DialQueueTracer).UpdatedDialQueueLength(mrand.Intn(100)) },
+		"DialQueueDelay":         func() { mt.(DialQueueTracer).DialQueueDelay(time.Duration(mrand.Intn(1e10))) },
 	}
 
 	for method, f := range tests {
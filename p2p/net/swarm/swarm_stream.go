@@ -11,7 +11,10 @@ import (
 )
 
 // Validate Stream conforms to the go-libp2p-net Stream interface
-var _ network.Stream = &Stream{}
+var (
+	_ network.Stream         = &Stream{}
+	_ network.GracefulCloser = &Stream{}
+)
 
 // Stream is the stream type used by swarm. In general, you won't use this type
 // directly.
@@ -30,6 +33,12 @@ type Stream struct {
 	protocol atomic.Pointer[protocol.ID]
 
 	stat network.Stats
+
+	bytesRead    atomic.Int64
+	bytesWritten atomic.Int64
+	// lastActivity is the unix time in nanoseconds of the last read or write, 0 if
+	// no data was transferred yet.
+	lastActivity atomic.Int64
 }
 
 func (s *Stream) ID() string {
@@ -56,8 +65,14 @@ func (s *Stream) Conn() network.Conn {
 // Read reads bytes from a stream.
 func (s *Stream) Read(p []byte) (int, error) {
 	n, err := s.stream.Read(p)
-	// TODO: push this down to a lower level for better accuracy.
-	if s.conn.swarm.bwc != nil {
+	if n > 0 {
+		s.bytesRead.Add(int64(n))
+		s.lastActivity.Store(time.Now().UnixNano())
+	}
+	if s.conn.bandwidthMetered {
+		// The connection's traffic is accounted at the muxer layer.
+		s.conn.swarm.muxerBwc.LogRecvProtocolMessage(int64(n), s.Protocol())
+	} else if s.conn.swarm.bwc != nil {
 		s.conn.swarm.bwc.LogRecvMessage(int64(n))
 		s.conn.swarm.bwc.LogRecvMessageStream(int64(n), s.Protocol(), s.Conn().RemotePeer())
 	}
@@ -67,8 +82,14 @@ func (s *Stream) Read(p []byte) (int, error) {
 // Write writes bytes to a stream, flushing for each call.
 func (s *Stream) Write(p []byte) (int, error) {
 	n, err := s.stream.Write(p)
-	// TODO: push this down to a lower level for better accuracy.
-	if s.conn.swarm.bwc != nil {
+	if n > 0 {
+		s.bytesWritten.Add(int64(n))
+		s.lastActivity.Store(time.Now().UnixNano())
+	}
+	if s.conn.bandwidthMetered {
+		// The connection's traffic is accounted at the muxer layer.
+		s.conn.swarm.muxerBwc.LogSentProtocolMessage(int64(n), s.Protocol())
+	} else if s.conn.swarm.bwc != nil {
 		s.conn.swarm.bwc.LogSentMessage(int64(n))
 		s.conn.swarm.bwc.LogSentMessageStream(int64(n), s.Protocol(), s.Conn().RemotePeer())
 	}
@@ -97,6 +118,15 @@ func (s *Stream) ResetWithError(errCode network.StreamErrorCode) error {
 	return err
 }
 
+// CloseGracefully closes the stream for writing, waits for the peer to close its side
+// of the stream, and frees all associated resources. See network.CloseGracefully.
+// The data discarded while waiting isn't accounted in the stream stats.
+func (s *Stream) CloseGracefully(deadline time.Time) error {
+	err := network.CloseGracefully(s.stream, deadline)
+	s.closeAndRemoveStream()
+	return err
+}
+
 func (s *Stream) closeAndRemoveStream() {
 	s.closeMx.Lock()
 	defer s.closeMx.Unlock()
@@ -177,7 +207,15 @@ func (s *Stream) SetWriteDeadline(t time.Time) error {
 
 // Stat returns metadata information for this stream.
 func (s *Stream) Stat() network.Stats {
-	return s.stat
+	stat := s.stat
+	stat.Protocol = s.Protocol()
+	stat.BytesRead = s.bytesRead.Load()
+	stat.BytesWritten = s.bytesWritten.Load()
+	stat.LastActivity = stat.Opened
+	if t := s.lastActivity.Load(); t != 0 {
+		stat.LastActivity = time.Unix(0, t)
+	}
+	return stat
 }
 
 func (s *Stream) Scope() network.StreamScope {
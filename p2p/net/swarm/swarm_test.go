@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
 	"github.com/TheNoobiCat/go-libp2p/core/control"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	mocknetwork "github.com/TheNoobiCat/go-libp2p/core/network/mocks"
@@ -18,6 +19,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	"github.com/TheNoobiCat/go-libp2p/core/test"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
 	. "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
 
@@ -364,6 +366,42 @@ func TestNoDial(t *testing.T) {
 	}
 }
 
+type highWaterConnMgr struct {
+	connmgr.NullConnMgr
+	above     bool
+	protected peer.ID
+}
+
+func (cm *highWaterConnMgr) AboveHighWater() bool                 { return cm.above }
+func (cm *highWaterConnMgr) IsProtected(p peer.ID, _ string) bool { return p == cm.protected }
+
+func TestConnManagerHeadroom(t *testing.T) {
+	cm := &highWaterConnMgr{above: true}
+	swarms := makeSwarms(t, 4, WithSwarmOpts(swarm.WithConnManagerHeadroom(cm)))
+	for _, s := range swarms[1:] {
+		swarms[0].Peerstore().AddAddrs(s.LocalPeer(), s.ListenAddresses(), peerstore.PermanentAddrTTL)
+	}
+
+	_, err := swarms[0].DialPeer(context.Background(), swarms[1].LocalPeer())
+	require.ErrorIs(t, err, swarm.ErrDialRefusedHighWater)
+
+	cm.protected = swarms[1].LocalPeer()
+	_, err = swarms[0].DialPeer(context.Background(), swarms[1].LocalPeer())
+	require.NoError(t, err)
+
+	ctx := network.WithDialAboveHighWater(context.Background(), "test")
+	_, err = swarms[0].DialPeer(ctx, swarms[2].LocalPeer())
+	require.NoError(t, err)
+
+	cm.above = false
+	_, err = swarms[0].DialPeer(context.Background(), swarms[3].LocalPeer())
+	require.NoError(t, err)
+
+	_, err = swarm.NewSwarm(swarms[0].LocalPeer(), swarms[0].Peerstore(), eventbus.NewBus(),
+		swarm.WithConnManagerHeadroom(&connmgr.NullConnMgr{}))
+	require.Error(t, err)
+}
+
 func TestCloseWithOpenStreams(t *testing.T) {
 	ctx := context.Background()
 	swarms := makeSwarms(t, 2)
@@ -456,6 +494,185 @@ func TestStreamCount(t *testing.T) {
 	require.Equal(t, 8, countStreams())
 }
 
+func TestStreamStats(t *testing.T) {
+	s1 := GenSwarm(t)
+	s2 := GenSwarm(t)
+	connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+
+	accepted := make(chan network.Stream, 1)
+	s1.SetStreamHandler(func(str network.Stream) { accepted <- str })
+	str, err := s2.NewStream(context.Background(), s1.LocalPeer())
+	require.NoError(t, err)
+	defer str.Close()
+	require.NoError(t, str.SetProtocol("/test"))
+
+	stat := str.Stat()
+	require.Equal(t, protocol.ID("/test"), stat.Protocol)
+	require.Zero(t, stat.BytesWritten)
+	require.Equal(t, stat.Opened, stat.LastActivity)
+
+	_, err = str.Write([]byte("foobar"))
+	require.NoError(t, err)
+	sstr := <-accepted
+	_, err = io.ReadFull(sstr, make([]byte, 6))
+	require.NoError(t, err)
+
+	conns := s2.ConnsToPeer(s1.LocalPeer())
+	require.Len(t, conns, 1)
+	strs := conns[0].GetStreams()
+	require.Len(t, strs, 1)
+	stat = strs[0].Stat()
+	require.Equal(t, int64(6), stat.BytesWritten)
+	require.Zero(t, stat.BytesRead)
+	require.True(t, stat.LastActivity.After(stat.Opened))
+
+	stat = sstr.Stat()
+	require.Equal(t, int64(6), stat.BytesRead)
+	require.False(t, stat.LastActivity.Before(stat.Opened))
+}
+
+func TestConnMuxerStats(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "tcp", opts: []Option{OptDisableQUIC, OptDisableWebTransport, OptDisableWebRTC}},
+		{name: "quic", opts: []Option{OptDisableTCP, OptDisableWebTransport, OptDisableWebRTC}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			s1 := GenSwarm(t, tc.opts...)
+			s2 := GenSwarm(t, tc.opts...)
+			connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+
+			str, err := s2.NewStream(context.Background(), s1.LocalPeer())
+			require.NoError(t, err)
+			defer str.Close()
+			conns := s2.ConnsToPeer(s1.LocalPeer())
+			require.Len(t, conns, 1)
+			stats, ok := conns[0].Stat().MuxerStats()
+			require.True(t, ok)
+			require.Equal(t, 1, stats.NumStreams)
+		})
+	}
+}
+
+func TestConnPing(t *testing.T) {
+	t.Run("tcp", func(t *testing.T) {
+		s1 := GenSwarm(t, OptDisableQUIC, OptDisableWebTransport, OptDisableWebRTC)
+		s2 := GenSwarm(t, OptDisableQUIC, OptDisableWebTransport, OptDisableWebRTC)
+		connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+
+		conns := s2.ConnsToPeer(s1.LocalPeer())
+		require.Len(t, conns, 1)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		rtt, err := conns[0].(network.Pinger).Ping(ctx)
+		require.NoError(t, err)
+		require.Greater(t, rtt, time.Duration(0))
+		require.Empty(t, conns[0].GetStreams())
+
+		require.NoError(t, conns[0].Close())
+		_, err = conns[0].(network.Pinger).Ping(ctx)
+		require.ErrorIs(t, err, swarm.ErrConnClosed)
+	})
+
+	t.Run("quic", func(t *testing.T) {
+		// quic-go doesn't allow sending a PING frame on demand
+		s1 := GenSwarm(t, OptDisableTCP, OptDisableWebTransport, OptDisableWebRTC)
+		s2 := GenSwarm(t, OptDisableTCP, OptDisableWebTransport, OptDisableWebRTC)
+		connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+
+		conns := s2.ConnsToPeer(s1.LocalPeer())
+		require.Len(t, conns, 1)
+		_, err := conns[0].(network.Pinger).Ping(context.Background())
+		require.ErrorIs(t, err, network.ErrPingNotSupported)
+	})
+}
+
+func TestConnectionQuality(t *testing.T) {
+	s1 := GenSwarm(t, OptDisableQUIC, OptDisableWebTransport, OptDisableWebRTC)
+	s2 := GenSwarm(t, OptDisableQUIC, OptDisableWebTransport, OptDisableWebRTC)
+	require.Equal(t, network.NotConnected, s2.ConnectionQuality(s1.LocalPeer()).Connectedness)
+
+	connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+	s2.Peerstore().RecordLatency(s1.LocalPeer(), 10*time.Millisecond)
+	q := s2.ConnectionQuality(s1.LocalPeer())
+	require.Equal(t, network.Connected, q.Connectedness)
+	require.Equal(t, 1, q.NumConns)
+	require.Equal(t, []string{"tcp"}, q.Transports)
+	require.True(t, q.Direct)
+	require.False(t, q.Relayed)
+	require.False(t, q.Limited)
+	require.Equal(t, 10*time.Millisecond, q.RTT)
+	require.Greater(t, q.Age, time.Duration(0))
+
+	require.NoError(t, s2.ClosePeer(s1.LocalPeer()))
+	require.Equal(t, network.ConnectionQuality{RTT: 10 * time.Millisecond}, s2.ConnectionQuality(s1.LocalPeer()))
+}
+
+func TestConnDrain(t *testing.T) {
+	s1 := GenSwarm(t)
+	s2 := GenSwarm(t)
+	connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+
+	accepted := make(chan network.Stream, 1)
+	s1.SetStreamHandler(func(str network.Stream) { accepted <- str })
+	str, err := s2.NewStream(context.Background(), s1.LocalPeer())
+	require.NoError(t, err)
+	_, err = str.Write([]byte("foo"))
+	require.NoError(t, err)
+	sstr := <-accepted
+
+	conns := s1.ConnsToPeer(s2.LocalPeer())
+	require.Len(t, conns, 1)
+	conns[0].(*swarm.Conn).Drain()
+
+	_, err = conns[0].NewStream(context.Background())
+	require.ErrorIs(t, err, swarm.ErrConnDraining)
+
+	// inbound streams are reset
+	str2, err := s2.NewStream(context.Background(), s1.LocalPeer())
+	require.NoError(t, err)
+	str2.Write([]byte("foo"))
+	_, err = str2.Read(make([]byte, 1))
+	require.ErrorIs(t, err, network.ErrReset)
+
+	// open streams are unaffected
+	_, err = sstr.Write([]byte("bar"))
+	require.NoError(t, err)
+	buf := make([]byte, 3)
+	_, err = io.ReadFull(str, buf)
+	require.NoError(t, err)
+	require.Equal(t, "bar", string(buf))
+}
+
+func TestConnDrainAndCloseTimeout(t *testing.T) {
+	s1 := GenSwarm(t)
+	s2 := GenSwarm(t)
+	connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+
+	accepted := make(chan network.Stream, 1)
+	s1.SetStreamHandler(func(str network.Stream) { accepted <- str })
+	str, err := s2.NewStream(context.Background(), s1.LocalPeer())
+	require.NoError(t, err)
+	defer str.Close()
+	_, err = str.Write([]byte("foo"))
+	require.NoError(t, err)
+	<-accepted
+
+	c := s1.ConnsToPeer(s2.LocalPeer())[0].(*swarm.Conn)
+	c.DrainAndClose(100*time.Millisecond, network.ConnGarbageCollected)
+	_, err = c.NewStream(context.Background())
+	require.ErrorIs(t, err, swarm.ErrConnDraining)
+
+	// the stream is never closed, so the connection is closed once the timeout elapsed
+	require.False(t, c.IsClosed())
+	require.Eventually(t, c.IsClosed, 5*time.Second, 10*time.Millisecond)
+	reason, ok := c.CloseReason()
+	require.True(t, ok)
+	require.Equal(t, network.ConnGarbageCollected, reason.ErrorCode)
+}
+
 func TestResourceManager(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -567,3 +784,51 @@ func TestListenCloseCount(t *testing.T) {
 	_, err := remainingAddrs[0].ValueForProtocol(ma.P_TCP)
 	require.NoError(t, err, "expected the TCP address to still be present")
 }
+
+func TestIdleStreamReaper(t *testing.T) {
+	s1 := GenSwarm(t, WithSwarmOpts(
+		swarm.WithIdleStreamTimeout(200*time.Millisecond),
+		swarm.WithProtocolIdleStreamTimeout("/keepalive", 0),
+	))
+	s2 := GenSwarm(t)
+	connectSwarms(t, context.Background(), []*swarm.Swarm{s2, s1})
+
+	accepted := make(chan network.Stream, 2)
+	s1.SetStreamHandler(func(str network.Stream) {
+		b := make([]byte, 1)
+		if _, err := str.Read(b); err != nil {
+			str.Reset()
+			return
+		}
+		if b[0] == 'k' {
+			str.SetProtocol("/keepalive")
+		}
+		accepted <- str
+		// wait for data that is never sent
+		str.Read(b)
+	})
+
+	// streams are accepted by s1 when data is written
+	keepalive, err := s2.NewStream(context.Background(), s1.LocalPeer())
+	require.NoError(t, err)
+	defer keepalive.Close()
+	_, err = keepalive.Write([]byte("k"))
+	require.NoError(t, err)
+	skeepalive := <-accepted
+
+	idle, err := s2.NewStream(context.Background(), s1.LocalPeer())
+	require.NoError(t, err)
+	defer idle.Close()
+	_, err = idle.Write([]byte("i"))
+	require.NoError(t, err)
+	<-accepted
+
+	// the idle stream is reset, the stream of the exempt protocol isn't
+	require.Eventually(t, func() bool {
+		conns := s1.ConnsToPeer(s2.LocalPeer())
+		return len(conns) == 1 && len(conns[0].GetStreams()) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	require.Equal(t, skeepalive, s1.ConnsToPeer(s2.LocalPeer())[0].GetStreams()[0])
+	_, err = idle.Read(make([]byte, 1))
+	require.Error(t, err)
+}
@@ -32,6 +32,10 @@ func (s *Swarm) TransportForDialing(a ma.Multiaddr) transport.Transport {
 		return nil
 	}
 	if isRelayAddr(a) {
+		// The relayed connection of a /webrtc address is only used for signaling.
+		if isWebRTCAddr(a) {
+			return s.transports.m[ma.P_WEBRTC]
+		}
 		return s.transports.m[ma.P_CIRCUIT]
 	}
 	if id, _ := peer.IDFromP2PAddr(a); id != "" {
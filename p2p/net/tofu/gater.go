@@ -0,0 +1,127 @@
+package tofu
+
+import (
+	"context"
+	"sync"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/control"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Gater pins the peers the host connects to in a Store, and checks the DNS names and
+// addresses dialed against the pins. It's a connection gater, and a swarm pre-dial
+// hook, since DNS names are resolved before the gater is consulted.
+type Gater struct {
+	store *Store
+	next  connmgr.ConnectionGater
+
+	mx      sync.Mutex
+	emitter event.Emitter
+}
+
+var _ connmgr.ConnectionGater = (*Gater)(nil)
+
+// NewGater returns a Gater pinning peers in s. Connections that aren't refused are
+// gated by next, if not nil.
+func NewGater(s *Store, next connmgr.ConnectionGater) *Gater {
+	return &Gater{store: s, next: next}
+}
+
+// SetEventBus emits an event.EvtPeerPinViolation on bus for every violation.
+func (g *Gater) SetEventBus(bus event.Bus) error {
+	em, err := bus.Emitter(new(event.EvtPeerPinViolation))
+	if err != nil {
+		return err
+	}
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	if g.emitter != nil {
+		g.emitter.Close()
+	}
+	g.emitter = em
+	return nil
+}
+
+// Close closes the event emitter.
+func (g *Gater) Close() error {
+	g.mx.Lock()
+	defer g.mx.Unlock()
+	if g.emitter == nil {
+		return nil
+	}
+	err := g.emitter.Close()
+	g.emitter = nil
+	return err
+}
+
+// violation reports that a, pinned to pinned, is used for p, and returns whether the
+// dial is allowed.
+func (g *Gater) violation(pinned peer.ID, a ma.Multiaddr, p peer.ID) (allow bool) {
+	refused := g.store.mode == Refuse
+	if refused {
+		log.Warnf("refusing to dial %s with %s, pinned to %s", p, a, pinned)
+	} else {
+		log.Warnf("dialing %s with %s, pinned to %s", p, a, pinned)
+	}
+	g.mx.Lock()
+	if g.emitter != nil {
+		g.emitter.Emit(event.EvtPeerPinViolation{Addr: a, Pinned: pinned, Peer: p, Refused: refused})
+	}
+	g.mx.Unlock()
+	return !refused
+}
+
+// PreDial checks the DNS names of the addresses of p, before they're resolved. Names
+// that aren't pinned yet are pinned to p once a connection to p is established.
+func (g *Gater) PreDial(_ context.Context, p peer.ID, addrs []ma.Multiaddr) ([]ma.Multiaddr, error) {
+	res := addrs[:0:0]
+	for _, a := range addrs {
+		if !isDNSAddr(a) {
+			res = append(res, a)
+			continue
+		}
+		if pinned, k, ok := g.store.check(p, a); ok {
+			if g.violation(pinned, k, p) {
+				res = append(res, a)
+			}
+			continue
+		}
+		g.store.dialing(p, a)
+		res = append(res, a)
+	}
+	return res, nil
+}
+
+func (g *Gater) InterceptPeerDial(p peer.ID) (allow bool) {
+	return g.next == nil || g.next.InterceptPeerDial(p)
+}
+
+func (g *Gater) InterceptAddrDial(p peer.ID, a ma.Multiaddr) (allow bool) {
+	if pinned, k, ok := g.store.check(p, a); ok && !g.violation(pinned, k, p) {
+		return false
+	}
+	return g.next == nil || g.next.InterceptAddrDial(p, a)
+}
+
+func (g *Gater) InterceptAccept(cma network.ConnMultiaddrs) (allow bool) {
+	return g.next == nil || g.next.InterceptAccept(cma)
+}
+
+func (g *Gater) InterceptSecured(dir network.Direction, p peer.ID, cma network.ConnMultiaddrs) (allow bool) {
+	return g.next == nil || g.next.InterceptSecured(dir, p, cma)
+}
+
+func (g *Gater) InterceptUpgraded(c network.Conn) (allow bool, reason control.DisconnectReason) {
+	if g.next != nil {
+		if allow, reason := g.next.InterceptUpgraded(c); !allow {
+			return allow, reason
+		}
+	}
+	g.store.connected(c)
+	return true, 0
+}
@@ -0,0 +1,471 @@
+// Package tofu pins peers on first use: it records the public key of every peer the
+// host dials, and the DNS names and addresses used to reach it. Once pinned, a
+// DNS name or address reaching a different peer is a violation, which is reported or
+// refused, depending on the Mode. This detects a name pointing to a new peer, because
+// the peer's key was rotated or because the name was hijacked.
+//
+// The Store is integrated with the host by a Gater, see libp2p.PeerPinning.
+package tofu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	"github.com/ipfs/go-datastore"
+	"github.com/ipfs/go-datastore/namespace"
+	"github.com/ipfs/go-datastore/query"
+	logging "github.com/ipfs/go-log/v2"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+var log = logging.Logger("net/tofu")
+
+const ns = "/libp2p/net/tofu/peer"
+
+const (
+	// maxPendingPeers is the maximum number of peers with DNS names waiting for a
+	// connection to be pinned.
+	maxPendingPeers = 1024
+	// maxPendingNames is the maximum number of DNS names per peer waiting for a
+	// connection to be pinned.
+	maxPendingNames = 16
+	// defaultMaxPins is the default maximum number of pinned peers.
+	defaultMaxPins = 4096
+)
+
+// Mode is the action taken on a violation.
+type Mode int
+
+const (
+	// Warn logs violations and emits an event.EvtPeerPinViolation, but allows the
+	// connection.
+	Warn Mode = iota
+	// Refuse additionally refuses to dial a peer using a DNS name or address pinned to
+	// another peer.
+	Refuse
+)
+
+// Pin is the record of a peer seen by the host.
+type Pin struct {
+	Peer peer.ID
+	// PubKey is the public key the peer presented in the security handshake.
+	PubKey crypto.PubKey
+	// Transport is the transport of the first connection to the peer, e.g. tcp.
+	Transport string
+	// Addrs are the DNS names and addresses pinned to the peer, without the /p2p
+	// component.
+	Addrs []ma.Multiaddr
+	// FirstSeen is the time of the first connection to the peer.
+	FirstSeen time.Time
+	// LastSeen is the time of the last connection to the peer. When the store is
+	// full, the peer seen the longest time ago is unpinned.
+	LastSeen time.Time
+}
+
+// Option configures a Store.
+type Option func(*Store) error
+
+// WithMode sets the action taken on violations. The default is Warn.
+func WithMode(m Mode) Option {
+	return func(s *Store) error {
+		s.mode = m
+		return nil
+	}
+}
+
+// WithDatastore persists the pins in ds.
+func WithDatastore(ds datastore.Datastore) Option {
+	return func(s *Store) error {
+		s.ds = namespace.Wrap(ds, datastore.NewKey(ns))
+		return nil
+	}
+}
+
+// WithMaxPins sets the maximum number of pinned peers. When a new peer is pinned in
+// a full store, the peer seen the longest time ago is unpinned. The default is 4096.
+func WithMaxPins(n int) Option {
+	return func(s *Store) error {
+		if n <= 0 {
+			return fmt.Errorf("invalid maximum number of pins: %d", n)
+		}
+		s.maxPins = n
+		return nil
+	}
+}
+
+// WithPrivateAddrs also pins private and loopback IP addresses. By default, only
+// public IP addresses and DNS names are pinned, since private addresses are reused
+// by peers on different networks.
+func WithPrivateAddrs() Option {
+	return func(s *Store) error {
+		s.privateAddrs = true
+		return nil
+	}
+}
+
+// Store records the peers seen by the host and the DNS names and addresses pinned to
+// them.
+type Store struct {
+	mode         Mode
+	privateAddrs bool
+	maxPins      int
+	ds           datastore.Datastore
+	// dsMx serializes the writes to ds, so that a pin is never overwritten with an
+	// older version.
+	dsMx sync.Mutex
+
+	mx    sync.Mutex
+	pins  map[peer.ID]*Pin
+	addrs map[string]peer.ID
+	// pending are the DNS names dialed for a peer, pinned once a connection to the
+	// peer is established.
+	pending map[peer.ID][]ma.Multiaddr
+}
+
+// NewStore creates a Store, loading the persisted pins if a datastore is configured.
+func NewStore(opts ...Option) (*Store, error) {
+	s := &Store{
+		maxPins: defaultMaxPins,
+		pins:    make(map[peer.ID]*Pin),
+		addrs:   make(map[string]peer.ID),
+		pending: make(map[peer.ID][]ma.Multiaddr),
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if s.ds != nil {
+		if err := s.load(context.Background()); err != nil {
+			return nil, err
+		}
+		// the limit may have been lowered since the pins were persisted
+		for _, p := range s.evict() {
+			if err := s.sync(p); err != nil {
+				return nil, fmt.Errorf("failed to delete pin for %s: %w", p, err)
+			}
+		}
+	}
+	return s, nil
+}
+
+// Mode returns the action taken on violations.
+func (s *Store) Mode() Mode {
+	return s.mode
+}
+
+// Lookup returns the record of p.
+func (s *Store) Lookup(p peer.ID) (Pin, bool) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	pin, ok := s.pins[p]
+	if !ok {
+		return Pin{}, false
+	}
+	return clonePin(pin), true
+}
+
+// Pins returns the records of all peers seen.
+func (s *Store) Pins() []Pin {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	pins := make([]Pin, 0, len(s.pins))
+	for _, pin := range s.pins {
+		pins = append(pins, clonePin(pin))
+	}
+	return pins
+}
+
+// PeerForAddr returns the peer the DNS name or address a is pinned to.
+func (s *Store) PeerForAddr(a ma.Multiaddr) (peer.ID, bool) {
+	k := s.pinAddr(a)
+	if k == nil {
+		return "", false
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	p, ok := s.addrs[string(k.Bytes())]
+	return p, ok
+}
+
+// Unpin forgets p and the DNS names and addresses pinned to it, e.g. after the peer
+// rotated its key. The next peer reached with these names is pinned instead.
+func (s *Store) Unpin(p peer.ID) error {
+	s.mx.Lock()
+	_, ok := s.pins[p]
+	s.remove(p)
+	s.mx.Unlock()
+	if !ok {
+		return nil
+	}
+	return s.sync(p)
+}
+
+// remove forgets p and the DNS names and addresses pinned to it. s.mx must be held.
+func (s *Store) remove(p peer.ID) {
+	pin, ok := s.pins[p]
+	if !ok {
+		return
+	}
+	for _, a := range pin.Addrs {
+		delete(s.addrs, string(a.Bytes()))
+	}
+	delete(s.pins, p)
+	delete(s.pending, p)
+}
+
+// evict unpins the peers seen the longest time ago until the store isn't over its
+// limit, and returns them. s.mx must be held.
+func (s *Store) evict() []peer.ID {
+	var evicted []peer.ID
+	for len(s.pins) > s.maxPins {
+		var oldest *Pin
+		for _, pin := range s.pins {
+			if oldest == nil || pin.LastSeen.Before(oldest.LastSeen) {
+				oldest = pin
+			}
+		}
+		s.remove(oldest.Peer)
+		evicted = append(evicted, oldest.Peer)
+	}
+	return evicted
+}
+
+// check returns the peer a is pinned to, if it's not p.
+func (s *Store) check(p peer.ID, a ma.Multiaddr) (pinned peer.ID, pinnedAddr ma.Multiaddr, violation bool) {
+	k := s.pinAddr(a)
+	if k == nil {
+		return "", nil, false
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	pinned, ok := s.addrs[string(k.Bytes())]
+	if !ok || pinned == p {
+		return "", nil, false
+	}
+	return pinned, k, true
+}
+
+// dialing records that the DNS name a is dialed for p. It's pinned to p once a
+// connection to p is established.
+func (s *Store) dialing(p peer.ID, a ma.Multiaddr) {
+	k := s.pinAddr(a)
+	if k == nil {
+		return
+	}
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if _, ok := s.addrs[string(k.Bytes())]; ok {
+		return
+	}
+	pending, ok := s.pending[p]
+	if !ok && len(s.pending) >= maxPendingPeers {
+		return
+	}
+	for _, pa := range pending {
+		if pa.Equal(k) {
+			return
+		}
+	}
+	if len(pending) < maxPendingNames {
+		s.pending[p] = append(pending, k)
+	}
+}
+
+// connected records the connection c. Only outbound connections to authenticated
+// peers are recorded: the remote address and the DNS names dialed for the peer are
+// pinned to the peer. Inbound connections are ignored, so that peers connecting to
+// the host can't fill the store.
+func (s *Store) connected(c network.Conn) {
+	if c.Stat().Direction != network.DirOutbound || c.RemotePublicKey() == nil {
+		return
+	}
+	p := c.RemotePeer()
+	now := time.Now()
+	s.mx.Lock()
+	pin, ok := s.pins[p]
+	changed := !ok
+	if !ok {
+		pin = &Pin{
+			Peer:      p,
+			PubKey:    c.RemotePublicKey(),
+			Transport: c.ConnState().Transport,
+			FirstSeen: now,
+		}
+		s.pins[p] = pin
+	}
+	pin.LastSeen = now
+	candidates := s.pending[p]
+	delete(s.pending, p)
+	if k := s.pinAddr(c.RemoteMultiaddr()); k != nil {
+		candidates = append(candidates, k)
+	}
+	for _, k := range candidates {
+		if _, ok := s.addrs[string(k.Bytes())]; ok {
+			continue
+		}
+		s.addrs[string(k.Bytes())] = p
+		pin.Addrs = append(pin.Addrs, k)
+		changed = true
+	}
+	evicted := s.evict()
+	s.mx.Unlock()
+
+	if s.ds == nil {
+		return
+	}
+	for _, e := range evicted {
+		if err := s.sync(e); err != nil {
+			log.Errorf("failed to delete pin for %s: %s", e, err)
+		}
+	}
+	if changed {
+		if err := s.sync(p); err != nil {
+			log.Errorf("failed to persist pin for %s: %s", p, err)
+		}
+	}
+}
+
+// pinAddr returns the address pinned for a: a without its /p2p and /certhash
+// components. It returns nil for addresses that aren't pinned: relay addresses and
+// dnsaddr names are shared by many peers, and private addresses are only pinned with
+// WithPrivateAddrs.
+func (s *Store) pinAddr(a ma.Multiaddr) ma.Multiaddr {
+	if len(a) == 0 {
+		return nil
+	}
+	switch a[0].Code() {
+	case ma.P_DNS, ma.P_DNS4, ma.P_DNS6:
+	case ma.P_IP4, ma.P_IP6:
+		if !s.privateAddrs && !manet.IsPublicAddr(a) {
+			return nil
+		}
+	default:
+		return nil
+	}
+	k := make(ma.Multiaddr, 0, len(a))
+	for _, c := range a {
+		switch c.Code() {
+		case ma.P_CIRCUIT:
+			return nil
+		case ma.P_P2P, ma.P_CERTHASH:
+			continue
+		}
+		k = append(k, c)
+	}
+	return k
+}
+
+func isDNSAddr(a ma.Multiaddr) bool {
+	if len(a) == 0 {
+		return false
+	}
+	switch a[0].Code() {
+	case ma.P_DNS, ma.P_DNS4, ma.P_DNS6:
+		return true
+	}
+	return false
+}
+
+func clonePin(pin *Pin) Pin {
+	c := *pin
+	c.Addrs = append([]ma.Multiaddr(nil), pin.Addrs...)
+	return c
+}
+
+type storedPin struct {
+	PubKey    []byte
+	Transport string
+	Addrs     [][]byte
+	FirstSeen time.Time
+	LastSeen  time.Time
+}
+
+// sync writes the current pin of p to the datastore, or deletes it if p isn't pinned
+// anymore. It's called without holding s.mx.
+func (s *Store) sync(p peer.ID) error {
+	if s.ds == nil {
+		return nil
+	}
+	s.dsMx.Lock()
+	defer s.dsMx.Unlock()
+	s.mx.Lock()
+	pin, ok := s.pins[p]
+	var c Pin
+	if ok {
+		c = clonePin(pin)
+	}
+	s.mx.Unlock()
+	if !ok {
+		return s.ds.Delete(context.Background(), datastore.NewKey(p.String()))
+	}
+	return s.persist(&c)
+}
+
+func (s *Store) persist(pin *Pin) error {
+	sp := storedPin{Transport: pin.Transport, FirstSeen: pin.FirstSeen, LastSeen: pin.LastSeen}
+	if pin.PubKey != nil {
+		b, err := crypto.MarshalPublicKey(pin.PubKey)
+		if err != nil {
+			return err
+		}
+		sp.PubKey = b
+	}
+	for _, a := range pin.Addrs {
+		sp.Addrs = append(sp.Addrs, a.Bytes())
+	}
+	b, err := json.Marshal(sp)
+	if err != nil {
+		return err
+	}
+	return s.ds.Put(context.Background(), datastore.NewKey(pin.Peer.String()), b)
+}
+
+func (s *Store) load(ctx context.Context) error {
+	res, err := s.ds.Query(ctx, query.Query{})
+	if err != nil {
+		return fmt.Errorf("failed to query pins: %w", err)
+	}
+	defer res.Close()
+	for r := range res.Next() {
+		if r.Error != nil {
+			return fmt.Errorf("failed to query pins: %w", r.Error)
+		}
+		p, err := peer.Decode(datastore.NewKey(r.Key).BaseNamespace())
+		if err != nil {
+			return fmt.Errorf("invalid pin key %s: %w", r.Key, err)
+		}
+		var sp storedPin
+		if err := json.Unmarshal(r.Value, &sp); err != nil {
+			return fmt.Errorf("invalid pin for %s: %w", p, err)
+		}
+		pin := &Pin{Peer: p, Transport: sp.Transport, FirstSeen: sp.FirstSeen, LastSeen: sp.LastSeen}
+		if pin.LastSeen.IsZero() {
+			pin.LastSeen = pin.FirstSeen
+		}
+		if sp.PubKey != nil {
+			pin.PubKey, err = crypto.UnmarshalPublicKey(sp.PubKey)
+			if err != nil {
+				return fmt.Errorf("invalid public key for %s: %w", p, err)
+			}
+		}
+		for _, b := range sp.Addrs {
+			a, err := ma.NewMultiaddrBytes(b)
+			if err != nil {
+				return fmt.Errorf("invalid pinned address for %s: %w", p, err)
+			}
+			pin.Addrs = append(pin.Addrs, a)
+			s.addrs[string(a.Bytes())] = p
+		}
+		s.pins[p] = pin
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package tofu
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+
+	ds "github.com/ipfs/go-datastore"
+	dssync "github.com/ipfs/go-datastore/sync"
+	ma "github.com/multiformats/go-multiaddr"
+	madns "github.com/multiformats/go-multiaddr-dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPinAddr(t *testing.T) {
+	s, err := NewStore()
+	require.NoError(t, err)
+	for addr, pinned := range map[string]string{
+		"/ip4/1.2.3.4/tcp/1234/p2p/12D3KooWGZyeRGBz9KGNjwZdPwX6LYYZfCoFzCC5BMRQKYtNXH9C":                     "/ip4/1.2.3.4/tcp/1234",
+		"/dns4/example.com/udp/443/quic-v1":                                                                  "/dns4/example.com/udp/443/quic-v1",
+		"/ip4/1.2.3.4/udp/443/quic-v1/webtransport/certhash/uEiD2ob6XsDcK2vNKkAvMb8jMSNi3m8BAlmVmkPZD2RDojA": "/ip4/1.2.3.4/udp/443/quic-v1/webtransport",
+		"/ip4/127.0.0.1/tcp/1234":      "",
+		"/ip4/192.168.1.1/tcp/1234":    "",
+		"/dnsaddr/bootstrap.libp2p.io": "",
+		"/ip4/1.2.3.4/tcp/1234/p2p/12D3KooWGZyeRGBz9KGNjwZdPwX6LYYZfCoFzCC5BMRQKYtNXH9C/p2p-circuit": "",
+	} {
+		k := s.pinAddr(ma.StringCast(addr))
+		if pinned == "" {
+			require.Nil(t, k, addr)
+		} else {
+			require.Equal(t, pinned, k.String(), addr)
+		}
+	}
+
+	s, err = NewStore(WithPrivateAddrs())
+	require.NoError(t, err)
+	require.NotNil(t, s.pinAddr(ma.StringCast("/ip4/127.0.0.1/tcp/1234")))
+}
+
+func TestGater(t *testing.T) {
+	var resolver madns.MockResolver
+	resolver.IP = map[string][]net.IPAddr{"b.example.com": {{IP: net.IPv4(127, 0, 0, 1)}}}
+	r, err := madns.NewResolver(madns.WithDefaultResolver(&resolver))
+	require.NoError(t, err)
+
+	d := dssync.MutexWrap(ds.NewMapDatastore())
+	store, err := NewStore(WithPrivateAddrs(), WithDatastore(d))
+	require.NoError(t, err)
+	g := NewGater(store, nil)
+	bus := eventbus.NewBus()
+	require.NoError(t, g.SetEventBus(bus))
+	defer g.Close()
+	sub, err := bus.Subscribe(new(event.EvtPeerPinViolation))
+	require.NoError(t, err)
+	defer sub.Close()
+
+	a := swarmt.GenSwarm(t, swarmt.OptConnGater(g), swarmt.WithSwarmOpts(
+		swarm.WithPreDialHook(g),
+		swarm.WithMultiaddrResolver(swarm.ResolverFromMaDNS{Resolver: r}),
+	))
+	b := swarmt.GenSwarm(t, swarmt.OptDisableQUIC, swarmt.OptDisableWebTransport, swarmt.OptDisableWebRTC)
+	c := test.RandPeerIDFatal(t)
+	defer a.Close()
+	defer b.Close()
+
+	port, err := b.ListenAddresses()[0].ValueForProtocol(ma.P_TCP)
+	require.NoError(t, err)
+	name := ma.StringCast("/dns4/b.example.com/tcp/" + port)
+	a.Peerstore().AddAddr(b.LocalPeer(), name, peerstore.PermanentAddrTTL)
+	_, err = a.DialPeer(context.Background(), b.LocalPeer())
+	require.NoError(t, err)
+
+	pin, ok := store.Lookup(b.LocalPeer())
+	require.True(t, ok)
+	require.True(t, pin.PubKey.Equals(b.Peerstore().PubKey(b.LocalPeer())))
+	require.Equal(t, "tcp", pin.Transport)
+	require.Len(t, pin.Addrs, 2)
+	p, ok := store.PeerForAddr(name)
+	require.True(t, ok)
+	require.Equal(t, b.LocalPeer(), p)
+	p, ok = store.PeerForAddr(b.ListenAddresses()[0])
+	require.True(t, ok)
+	require.Equal(t, b.LocalPeer(), p)
+
+	expectViolation := func(refused bool) {
+		t.Helper()
+		select {
+		case e := <-sub.Out():
+			evt := e.(event.EvtPeerPinViolation)
+			require.Equal(t, b.LocalPeer(), evt.Pinned)
+			require.Equal(t, c, evt.Peer)
+			require.Equal(t, refused, evt.Refused)
+		case <-time.After(5 * time.Second):
+			t.Fatal("expected a violation")
+		}
+	}
+
+	// In warn mode, a violation is reported but the address is dialed.
+	addrs, err := g.PreDial(context.Background(), c, []ma.Multiaddr{name})
+	require.NoError(t, err)
+	require.Equal(t, []ma.Multiaddr{name}, addrs)
+	expectViolation(false)
+
+	// In refuse mode, dials using addresses pinned to another peer are refused.
+	store.mode = Refuse
+	addrs, err = g.PreDial(context.Background(), c, []ma.Multiaddr{name})
+	require.NoError(t, err)
+	require.Empty(t, addrs)
+	expectViolation(true)
+	require.False(t, g.InterceptAddrDial(c, b.ListenAddresses()[0]))
+	expectViolation(true)
+	require.True(t, g.InterceptAddrDial(b.LocalPeer(), b.ListenAddresses()[0]))
+
+	// The pins are persisted.
+	loaded, err := NewStore(WithPrivateAddrs(), WithDatastore(d))
+	require.NoError(t, err)
+	pin2, ok := loaded.Lookup(b.LocalPeer())
+	require.True(t, ok)
+	require.True(t, pin.PubKey.Equals(pin2.PubKey))
+	require.ElementsMatch(t, pin.Addrs, pin2.Addrs)
+	p, ok = loaded.PeerForAddr(name)
+	require.True(t, ok)
+	require.Equal(t, b.LocalPeer(), p)
+
+	// Unpinning allows the names to be pinned to another peer.
+	require.NoError(t, loaded.Unpin(b.LocalPeer()))
+	_, ok = loaded.PeerForAddr(name)
+	require.False(t, ok)
+	loaded, err = NewStore(WithDatastore(d))
+	require.NoError(t, err)
+	require.Empty(t, loaded.Pins())
+}
+
+func TestInboundNotPinned(t *testing.T) {
+	store, err := NewStore(WithPrivateAddrs())
+	require.NoError(t, err)
+	g := NewGater(store, nil)
+	a := swarmt.GenSwarm(t, swarmt.OptConnGater(g), swarmt.OptDisableQUIC, swarmt.OptDisableWebTransport, swarmt.OptDisableWebRTC)
+	b := swarmt.GenSwarm(t)
+	defer a.Close()
+	defer b.Close()
+
+	b.Peerstore().AddAddrs(a.LocalPeer(), a.ListenAddresses(), peerstore.PermanentAddrTTL)
+	_, err = b.DialPeer(context.Background(), a.LocalPeer())
+	require.NoError(t, err)
+	require.Empty(t, store.Pins())
+}
+
+func TestMaxPins(t *testing.T) {
+	_, err := NewStore(WithMaxPins(0))
+	require.Error(t, err)
+
+	d := dssync.MutexWrap(ds.NewMapDatastore())
+	store, err := NewStore(WithPrivateAddrs(), WithDatastore(d), WithMaxPins(1))
+	require.NoError(t, err)
+	a := swarmt.GenSwarm(t, swarmt.OptConnGater(NewGater(store, nil)))
+	defer a.Close()
+
+	var peers []*swarm.Swarm
+	for i := 0; i < 2; i++ {
+		b := swarmt.GenSwarm(t, swarmt.OptDisableQUIC, swarmt.OptDisableWebTransport, swarmt.OptDisableWebRTC)
+		defer b.Close()
+		a.Peerstore().AddAddrs(b.LocalPeer(), b.ListenAddresses(), peerstore.PermanentAddrTTL)
+		_, err := a.DialPeer(context.Background(), b.LocalPeer())
+		require.NoError(t, err)
+		peers = append(peers, b)
+	}
+
+	// The first peer was unpinned to make room for the second one.
+	_, ok := store.Lookup(peers[0].LocalPeer())
+	require.False(t, ok)
+	_, ok = store.PeerForAddr(peers[0].ListenAddresses()[0])
+	require.False(t, ok)
+	_, ok = store.Lookup(peers[1].LocalPeer())
+	require.True(t, ok)
+
+	loaded, err := NewStore(WithDatastore(d))
+	require.NoError(t, err)
+	pins := loaded.Pins()
+	require.Len(t, pins, 1)
+	require.Equal(t, peers[1].LocalPeer(), pins[0].Peer)
+}
@@ -1,7 +1,9 @@
 package upgrader
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
@@ -19,9 +21,13 @@ type transportConn struct {
 	muxer                     protocol.ID
 	security                  protocol.ID
 	usedEarlyMuxerNegotiation bool
+	bandwidthMetered          bool
 }
 
-var _ transport.CapableConn = &transportConn{}
+var (
+	_ transport.CapableConn = &transportConn{}
+	_ network.Pinger        = &transportConn{}
+)
 
 func (t *transportConn) Transport() transport.Transport {
 	return t.transport
@@ -42,6 +48,12 @@ func (t *transportConn) String() string {
 	)
 }
 
+// BandwidthMetered returns true if the traffic of the connection is reported to a
+// metrics.MuxerReporter by the upgrader.
+func (t *transportConn) BandwidthMetered() bool {
+	return t.bandwidthMetered
+}
+
 func (t *transportConn) Stat() network.ConnStats {
 	return t.stat
 }
@@ -64,7 +76,27 @@ func (t *transportConn) ConnState() network.ConnectionState {
 	}
 }
 
+// Ping pings the peer with the stream multiplexer, if it supports pings.
+func (t *transportConn) Ping(ctx context.Context) (time.Duration, error) {
+	if p, ok := t.MuxedConn.(network.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return 0, network.ErrPingNotSupported
+}
+
 func (t *transportConn) CloseWithError(errCode network.ConnErrorCode) error {
 	defer t.scope.Done()
 	return t.MuxedConn.CloseWithError(errCode)
 }
+
+// muxerStatsConn is a transportConn whose stream multiplexer reports MuxerStats.
+type muxerStatsConn struct {
+	*transportConn
+	reporter network.MuxerStatsReporter
+}
+
+var _ network.MuxerStatsReporter = &muxerStatsConn{}
+
+func (c *muxerStatsConn) MuxerStats() network.MuxerStats {
+	return c.reporter.MuxerStats()
+}
@@ -0,0 +1,29 @@
+package upgrader
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/core/metrics"
+	"github.com/TheNoobiCat/go-libp2p/core/sec"
+)
+
+// meteredConn reports the bytes the stream multiplexer reads from and writes to the
+// secured connection.
+type meteredConn struct {
+	sec.SecureConn
+	reporter metrics.MuxerReporter
+}
+
+func (c *meteredConn) Read(b []byte) (int, error) {
+	n, err := c.SecureConn.Read(b)
+	if n > 0 {
+		c.reporter.LogRecvConnMessage(int64(n), c.RemotePeer())
+	}
+	return n, err
+}
+
+func (c *meteredConn) Write(b []byte) (int, error) {
+	n, err := c.SecureConn.Write(b)
+	if n > 0 {
+		c.reporter.LogSentConnMessage(int64(n), c.RemotePeer())
+	}
+	return n, err
+}
@@ -0,0 +1,123 @@
+package upgrader
+
+import (
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricNamespace = "libp2p_upgrader"
+
+var (
+	securityHandshakeLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "security_handshake_latency_seconds",
+			Help:      "Duration of the security protocol negotiation and handshake",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 1.3, 35),
+		},
+		[]string{"dir", "transport", "security"},
+	)
+	muxerNegotiationLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "muxer_negotiation_latency_seconds",
+			Help:      "Duration of the stream muxer negotiation",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 1.3, 35),
+		},
+		[]string{"dir", "transport", "security", "muxer", "early_muxer"},
+	)
+	upgradesInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "upgrades_in_flight",
+			Help:      "Number of connections currently being upgraded",
+		},
+		[]string{"dir", "transport"},
+	)
+	collectors = []prometheus.Collector{
+		securityHandshakeLatency,
+		muxerNegotiationLatency,
+		upgradesInFlight,
+	}
+)
+
+// MetricsTracer tracks the time spent in the different phases of a connection upgrade.
+type MetricsTracer interface {
+	// UpgradeStarted is called when the upgrade of a raw connection starts.
+	UpgradeStarted(dir network.Direction, transport string)
+	// UpgradeFinished is called when the upgrade of a raw connection ends, successfully or not.
+	UpgradeFinished(dir network.Direction, transport string)
+	// SecurityHandshakeCompleted is called after the security protocol was negotiated and the
+	// handshake completed successfully.
+	SecurityHandshakeCompleted(dir network.Direction, transport string, security protocol.ID, d time.Duration)
+	// MuxerNegotiationCompleted is called after the stream muxer was selected successfully.
+	MuxerNegotiationCompleted(dir network.Direction, transport string, security, muxer protocol.ID, earlyMuxer bool, d time.Duration)
+}
+
+type metricsTracer struct{}
+
+var _ MetricsTracer = &metricsTracer{}
+
+type metricsTracerSetting struct {
+	reg prometheus.Registerer
+}
+
+type MetricsTracerOption func(*metricsTracerSetting)
+
+func WithRegisterer(reg prometheus.Registerer) MetricsTracerOption {
+	return func(s *metricsTracerSetting) {
+		if reg != nil {
+			s.reg = reg
+		}
+	}
+}
+
+func NewMetricsTracer(opts ...MetricsTracerOption) MetricsTracer {
+	setting := &metricsTracerSetting{reg: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt(setting)
+	}
+	metricshelper.RegisterCollectors(setting.reg, collectors...)
+	return &metricsTracer{}
+}
+
+func (m *metricsTracer) UpgradeStarted(dir network.Direction, transport string) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	*tags = append(*tags, metricshelper.GetDirection(dir), transport)
+	upgradesInFlight.WithLabelValues(*tags...).Inc()
+}
+
+func (m *metricsTracer) UpgradeFinished(dir network.Direction, transport string) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	*tags = append(*tags, metricshelper.GetDirection(dir), transport)
+	upgradesInFlight.WithLabelValues(*tags...).Dec()
+}
+
+func (m *metricsTracer) SecurityHandshakeCompleted(dir network.Direction, transport string, security protocol.ID, d time.Duration) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	*tags = append(*tags, metricshelper.GetDirection(dir), transport, string(security))
+	securityHandshakeLatency.WithLabelValues(*tags...).Observe(d.Seconds())
+}
+
+func (m *metricsTracer) MuxerNegotiationCompleted(dir network.Direction, transport string, security, muxer protocol.ID, earlyMuxer bool, d time.Duration) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	em := "false"
+	if earlyMuxer {
+		em = "true"
+	}
+	*tags = append(*tags, metricshelper.GetDirection(dir), transport, string(security), string(muxer), em)
+	muxerNegotiationLatency.WithLabelValues(*tags...).Observe(d.Seconds())
+}
@@ -0,0 +1,84 @@
+package upgrader_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/core/sec/insecure"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/upgrader"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingTracer struct {
+	mx                 sync.Mutex
+	inFlight           int
+	security, muxer    []protocol.ID
+	transports         []string
+	securityDurations  []time.Duration
+	muxerNegotiationOK int
+}
+
+var _ upgrader.MetricsTracer = &recordingTracer{}
+
+func (r *recordingTracer) UpgradeStarted(_ network.Direction, _ string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.inFlight++
+}
+
+func (r *recordingTracer) UpgradeFinished(_ network.Direction, _ string) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.inFlight--
+}
+
+func (r *recordingTracer) SecurityHandshakeCompleted(_ network.Direction, transport string, security protocol.ID, d time.Duration) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.transports = append(r.transports, transport)
+	r.security = append(r.security, security)
+	r.securityDurations = append(r.securityDurations, d)
+}
+
+func (r *recordingTracer) MuxerNegotiationCompleted(_ network.Direction, _ string, _, muxer protocol.ID, _ bool, _ time.Duration) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.muxer = append(r.muxer, muxer)
+	r.muxerNegotiationOK++
+}
+
+func TestUpgraderMetrics(t *testing.T) {
+	tr := &recordingTracer{}
+	id, u := createUpgraderWithOpts(t, upgrader.WithMetricsTracer(tr))
+	ln := createListener(t, u)
+	defer ln.Close()
+
+	_, dialUpgrader := createUpgrader(t)
+	conn, err := dial(t, dialUpgrader, ln.Multiaddr(), id, &network.NullScope{})
+	require.NoError(t, err)
+	defer conn.Close()
+	sconn, err := ln.Accept()
+	require.NoError(t, err)
+	defer sconn.Close()
+
+	tr.mx.Lock()
+	defer tr.mx.Unlock()
+	require.Zero(t, tr.inFlight)
+	require.Equal(t, []protocol.ID{insecure.ID}, tr.security)
+	require.Equal(t, []string{"tcp"}, tr.transports)
+	require.Equal(t, []protocol.ID{"negotiate"}, tr.muxer)
+	require.Equal(t, 1, tr.muxerNegotiationOK)
+}
+
+func TestMetricsTracerNoPanic(t *testing.T) {
+	mt := upgrader.NewMetricsTracer(upgrader.WithRegisterer(prometheus.NewRegistry()))
+	mt.UpgradeStarted(network.DirInbound, "tcp")
+	mt.SecurityHandshakeCompleted(network.DirInbound, "tcp", "/noise", time.Millisecond)
+	mt.MuxerNegotiationCompleted(network.DirInbound, "tcp", "/noise", "/yamux/1.0.0", true, time.Millisecond)
+	mt.UpgradeFinished(network.DirInbound, "tcp")
+}
@@ -0,0 +1,99 @@
+package upgrader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/core/transport"
+)
+
+// Negotiator negotiates the security protocol and the stream multiplexer of the
+// connections upgraded by the upgrader. By default, they are negotiated with
+// multistream-select.
+//
+// Implementations must be safe for concurrent use. Negotiations are interrupted by
+// closing the connection once ctx is done.
+type Negotiator interface {
+	// NegotiateSecurity negotiates the security protocol of the insecure connection
+	// conn. ids are the allowed security protocols, in order of preference.
+	NegotiateSecurity(ctx context.Context, conn net.Conn, isServer bool, ids []protocol.ID) (protocol.ID, error)
+	// NegotiateMuxer negotiates the stream multiplexer of the secured connection conn.
+	// ids are the configured stream multiplexers, in order of preference. It isn't
+	// called if the muxer was already selected during the security handshake.
+	NegotiateMuxer(ctx context.Context, conn net.Conn, isServer bool, ids []protocol.ID) (protocol.ID, error)
+}
+
+type transportNegotiator struct {
+	negotiator Negotiator
+	// protocols the transports must handle for negotiator to apply. All transports
+	// if empty.
+	protocols []int
+}
+
+// WithNegotiator configures the upgrader to negotiate the security protocol and the
+// stream multiplexer of the connections of transports handling any of protocols,
+// e.g. ma.P_TCP, with n instead of multistream-select. Without protocols, n applies to
+// the connections of all transports. If multiple negotiators apply to a transport,
+// the first one configured is used.
+func WithNegotiator(n Negotiator, protocols ...int) Option {
+	return func(u *upgrader) error {
+		if n == nil {
+			return errors.New("nil negotiator")
+		}
+		u.negotiators = append(u.negotiators, transportNegotiator{negotiator: n, protocols: protocols})
+		return nil
+	}
+}
+
+// negotiatorFor returns the negotiator of the connections of t, or nil to use
+// multistream-select.
+func (u *upgrader) negotiatorFor(t transport.Transport) Negotiator {
+	if len(u.negotiators) == 0 {
+		return nil
+	}
+	var protos []int
+	if t != nil {
+		protos = t.Protocols()
+	}
+	for _, tn := range u.negotiators {
+		if len(tn.protocols) == 0 {
+			return tn.negotiator
+		}
+		for _, p := range protos {
+			if slices.Contains(tn.protocols, p) {
+				return tn.negotiator
+			}
+		}
+	}
+	return nil
+}
+
+// StaticNegotiator is a Negotiator that selects Security and Muxer without exchanging
+// any data, saving the round trips of multistream-select. Both ends of the connection
+// must agree on them out of band, e.g. in a cluster where all nodes share the same
+// configuration: connections with nodes using other protocols or multistream-select
+// fail.
+type StaticNegotiator struct {
+	Security protocol.ID
+	Muxer    protocol.ID
+}
+
+var _ Negotiator = StaticNegotiator{}
+
+func (n StaticNegotiator) NegotiateSecurity(_ context.Context, _ net.Conn, _ bool, ids []protocol.ID) (protocol.ID, error) {
+	if !slices.Contains(ids, n.Security) {
+		return "", fmt.Errorf("static security protocol %q isn't allowed", n.Security)
+	}
+	return n.Security, nil
+}
+
+func (n StaticNegotiator) NegotiateMuxer(_ context.Context, _ net.Conn, _ bool, ids []protocol.ID) (protocol.ID, error) {
+	if !slices.Contains(ids, n.Muxer) {
+		return "", fmt.Errorf("static stream multiplexer %q isn't configured", n.Muxer)
+	}
+	return n.Muxer, nil
+}
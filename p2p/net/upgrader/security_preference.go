@@ -0,0 +1,125 @@
+package upgrader
+
+import (
+	"errors"
+	"net/netip"
+	"slices"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+	mss "github.com/multiformats/go-multistream"
+)
+
+// ErrNoAllowedSecurity is returned when the security preference of a connection
+// doesn't allow any of the configured security transports.
+var ErrNoAllowedSecurity = errors.New("no allowed security protocol")
+
+// SecurityPreference returns the security protocols allowed on a connection with
+// remote address raddr, in order of preference. p is the peer we're dialing, and is
+// empty for inbound connections, since the peer isn't known before the handshake.
+// Returning nil uses all configured security transports in their configured order.
+//
+// On outbound connections we propose the protocols in the returned order. On inbound
+// connections the dialer picks the order, so only the set of allowed protocols
+// matters.
+type SecurityPreference func(dir network.Direction, p peer.ID, raddr ma.Multiaddr) []protocol.ID
+
+// WithSecurityPreference configures the upgrader to consult pref when negotiating the
+// security protocol of a connection, instead of always using the configured order. It
+// doesn't apply to transports with built-in security, like QUIC and WebRTC.
+func WithSecurityPreference(pref SecurityPreference) Option {
+	return func(u *upgrader) error {
+		u.securityPreference = pref
+		return nil
+	}
+}
+
+// SecurityRule is a rule of SecurityRules.
+type SecurityRule struct {
+	// Peers the rule applies to. Peer rules only match outbound connections.
+	Peers []peer.ID
+	// Networks the remote IP address must be in for the rule to apply.
+	Networks []netip.Prefix
+	// Protocols are the security protocols allowed on matching connections, in order
+	// of preference.
+	Protocols []protocol.ID
+}
+
+func (r *SecurityRule) matches(p peer.ID, raddr ma.Multiaddr) bool {
+	if len(r.Peers) == 0 && len(r.Networks) == 0 {
+		return true
+	}
+	if p != "" && slices.Contains(r.Peers, p) {
+		return true
+	}
+	if len(r.Networks) == 0 {
+		return false
+	}
+	ip, err := manet.ToIP(raddr)
+	if err != nil {
+		return false
+	}
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, n := range r.Networks {
+		if n.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// SecurityRules returns a SecurityPreference that applies the first rule matching the
+// connection. A rule matches if the peer is one of its Peers or the remote IP address
+// is in one of its Networks. A rule without Peers and Networks matches all
+// connections. If no rule matches, the configured order is used.
+//
+// For example, to require TLS for peers on 10.0.0.0/8 and prefer noise otherwise:
+//
+//	upgrader.SecurityRules(
+//		upgrader.SecurityRule{Networks: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, Protocols: []protocol.ID{libp2ptls.ID}},
+//		upgrader.SecurityRule{Protocols: []protocol.ID{noise.ID, libp2ptls.ID}},
+//	)
+func SecurityRules(rules ...SecurityRule) SecurityPreference {
+	return func(_ network.Direction, p peer.ID, raddr ma.Multiaddr) []protocol.ID {
+		for i := range rules {
+			if rules[i].matches(p, raddr) {
+				return rules[i].Protocols
+			}
+		}
+		return nil
+	}
+}
+
+// securityProtocols returns the security protocols to negotiate on a connection, in
+// order of preference, and the muxer to negotiate them with as the server.
+func (u *upgrader) securityProtocols(dir network.Direction, p peer.ID, raddr ma.Multiaddr) ([]protocol.ID, *mss.MultistreamMuxer[protocol.ID], error) {
+	if u.securityPreference == nil {
+		return u.securityIDs, u.securityMuxer, nil
+	}
+	pref := u.securityPreference(dir, p, raddr)
+	if pref == nil {
+		return u.securityIDs, u.securityMuxer, nil
+	}
+	ids := make([]protocol.ID, 0, len(pref))
+	for _, id := range pref {
+		if slices.Contains(u.securityIDs, id) && !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil, ErrNoAllowedSecurity
+	}
+	muxer := mss.NewMultistreamMuxer[protocol.ID]()
+	for _, id := range ids {
+		muxer.AddHandler(id, nil)
+	}
+	return ids, muxer, nil
+}
@@ -8,14 +8,17 @@ import (
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/metrics"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	ipnet "github.com/TheNoobiCat/go-libp2p/core/pnet"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	"github.com/TheNoobiCat/go-libp2p/core/sec"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/pnet"
 
+	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
 	mss "github.com/multiformats/go-multistream"
 )
@@ -41,6 +44,24 @@ func WithAcceptTimeout(t time.Duration) Option {
 	}
 }
 
+// WithMetricsTracer configures the upgrader to report the time spent in the security
+// handshake and in muxer negotiation to mt.
+func WithMetricsTracer(mt MetricsTracer) Option {
+	return func(u *upgrader) error {
+		u.metricsTracer = mt
+		return nil
+	}
+}
+
+// WithBandwidthReporter configures the upgrader to report the traffic of upgraded
+// connections to r at the stream multiplexer layer, including the multiplexer's framing.
+func WithBandwidthReporter(r metrics.MuxerReporter) Option {
+	return func(u *upgrader) error {
+		u.bwReporter = r
+		return nil
+	}
+}
+
 type StreamMuxer struct {
 	ID    protocol.ID
 	Muxer network.Multiplexer
@@ -61,12 +82,20 @@ type upgrader struct {
 	securityMuxer *mss.MultistreamMuxer[protocol.ID]
 	securityIDs   []protocol.ID
 
+	securityPreference SecurityPreference
+
+	// negotiators replace multistream-select for the connections of some transports
+	negotiators []transportNegotiator
+
 	// AcceptTimeout is the maximum duration an Accept is allowed to take.
 	// This includes the time between accepting the raw network connection,
 	// protocol selection as well as the handshake, if applicable.
 	//
 	// If unset, the default value (15s) is used.
 	acceptTimeout time.Duration
+
+	metricsTracer MetricsTracer
+	bwReporter    metrics.MuxerReporter
 }
 
 var _ transport.Upgrader = &upgrader{}
@@ -165,20 +194,32 @@ func (u *upgrader) upgrade(ctx context.Context, t transport.Transport, maconn ma
 		return nil, ipnet.ErrNotInPrivateNetwork
 	}
 
+	var tpt string
+	if u.metricsTracer != nil {
+		tpt = metricshelper.GetTransport(maconn.LocalMultiaddr())
+		u.metricsTracer.UpgradeStarted(dir, tpt)
+		defer u.metricsTracer.UpgradeFinished(dir, tpt)
+	}
+
 	isServer := dir == network.DirInbound
-	sconn, security, err := u.setupSecurity(ctx, conn, p, isServer)
+	negotiator := u.negotiatorFor(t)
+	start := time.Now()
+	sconn, security, err := u.setupSecurity(ctx, conn, p, dir, maconn.RemoteMultiaddr(), negotiator)
 	if err != nil {
 		conn.Close()
 		return nil, fmt.Errorf("failed to negotiate security protocol: %w", err)
 	}
+	if u.metricsTracer != nil {
+		u.metricsTracer.SecurityHandshakeCompleted(dir, tpt, security, time.Since(start))
+	}
 
 	// call the connection gater, if one is registered.
 	if u.connGater != nil && !u.connGater.InterceptSecured(dir, sconn.RemotePeer(), maconn) {
 		if err := maconn.Close(); err != nil {
 			log.Errorw("failed to close connection", "peer", p, "addr", maconn.RemoteMultiaddr(), "error", err)
 		}
-		return nil, fmt.Errorf("gater rejected connection with peer %s and addr %s with direction %d",
-			sconn.RemotePeer(), maconn.RemoteMultiaddr(), dir)
+		return nil, fmt.Errorf("gater rejected connection with peer %s and addr %s with direction %d: %w",
+			sconn.RemotePeer(), maconn.RemoteMultiaddr(), dir, network.ErrPeerGated)
 	}
 	// Only call SetPeer if it hasn't already been set -- this can happen when we don't know
 	// the peer in advance and in some bug scenarios.
@@ -193,11 +234,19 @@ func (u *upgrader) upgrade(ctx context.Context, t transport.Transport, maconn ma
 		}
 	}
 
-	muxer, smconn, err := u.setupMuxer(ctx, sconn, isServer, connScope.PeerScope())
+	start = time.Now()
+	var muxConn sec.SecureConn = sconn
+	if u.bwReporter != nil {
+		muxConn = &meteredConn{SecureConn: sconn, reporter: u.bwReporter}
+	}
+	muxer, smconn, err := u.setupMuxer(ctx, muxConn, isServer, connScope.PeerScope(), negotiator)
 	if err != nil {
 		sconn.Close()
 		return nil, fmt.Errorf("failed to negotiate stream multiplexer: %w", err)
 	}
+	if u.metricsTracer != nil {
+		u.metricsTracer.MuxerNegotiationCompleted(dir, tpt, security, muxer, sconn.ConnState().UsedEarlyMuxerNegotiation, time.Since(start))
+	}
 
 	tc := &transportConn{
 		MuxedConn:                 smconn,
@@ -209,12 +258,21 @@ func (u *upgrader) upgrade(ctx context.Context, t transport.Transport, maconn ma
 		muxer:                     muxer,
 		security:                  security,
 		usedEarlyMuxerNegotiation: sconn.ConnState().UsedEarlyMuxerNegotiation,
+		bandwidthMetered:          u.bwReporter != nil,
+	}
+	if r, ok := smconn.(network.MuxerStatsReporter); ok {
+		return &muxerStatsConn{transportConn: tc, reporter: r}, nil
 	}
 	return tc, nil
 }
 
-func (u *upgrader) setupSecurity(ctx context.Context, conn net.Conn, p peer.ID, isServer bool) (sec.SecureConn, protocol.ID, error) {
-	st, err := u.negotiateSecurity(ctx, conn, isServer)
+func (u *upgrader) setupSecurity(ctx context.Context, conn net.Conn, p peer.ID, dir network.Direction, raddr ma.Multiaddr, negotiator Negotiator) (sec.SecureConn, protocol.ID, error) {
+	ids, muxer, err := u.securityProtocols(dir, p, raddr)
+	if err != nil {
+		return nil, "", err
+	}
+	isServer := dir == network.DirInbound
+	st, err := u.negotiateSecurity(ctx, conn, isServer, ids, muxer, negotiator)
 	if err != nil {
 		return nil, "", err
 	}
@@ -226,13 +284,19 @@ func (u *upgrader) setupSecurity(ctx context.Context, conn net.Conn, p peer.ID,
 	return sconn, st.ID(), err
 }
 
-func (u *upgrader) negotiateMuxer(nc net.Conn, isServer bool) (*StreamMuxer, error) {
+func (u *upgrader) negotiateMuxer(ctx context.Context, nc net.Conn, isServer bool, negotiator Negotiator) (*StreamMuxer, error) {
 	if err := nc.SetDeadline(time.Now().Add(defaultNegotiateTimeout)); err != nil {
 		return nil, err
 	}
 
 	var proto protocol.ID
-	if isServer {
+	if negotiator != nil {
+		selected, err := negotiator.NegotiateMuxer(ctx, nc, isServer, u.muxerIDs)
+		if err != nil {
+			return nil, err
+		}
+		proto = selected
+	} else if isServer {
 		selected, _, err := u.muxerMuxer.Negotiate(nc)
 		if err != nil {
 			return nil, err
@@ -265,7 +329,7 @@ func (u *upgrader) getMuxerByID(id protocol.ID) *StreamMuxer {
 	return nil
 }
 
-func (u *upgrader) setupMuxer(ctx context.Context, conn sec.SecureConn, server bool, scope network.PeerScope) (protocol.ID, network.MuxedConn, error) {
+func (u *upgrader) setupMuxer(ctx context.Context, conn sec.SecureConn, server bool, scope network.PeerScope, negotiator Negotiator) (protocol.ID, network.MuxedConn, error) {
 	muxerSelected := conn.ConnState().StreamMultiplexer
 	// Use muxer selected from security handshake if available. Otherwise fall back to multistream-selection.
 	if len(muxerSelected) > 0 {
@@ -289,7 +353,7 @@ func (u *upgrader) setupMuxer(ctx context.Context, conn sec.SecureConn, server b
 	done := make(chan result, 1)
 	// TODO: The muxer should take a context.
 	go func() {
-		m, err := u.negotiateMuxer(conn, server)
+		m, err := u.negotiateMuxer(ctx, conn, server, negotiator)
 		if err != nil {
 			done <- result{err: err}
 			return
@@ -319,7 +383,7 @@ func (u *upgrader) getSecurityByID(id protocol.ID) sec.SecureTransport {
 	return nil
 }
 
-func (u *upgrader) negotiateSecurity(ctx context.Context, insecure net.Conn, server bool) (sec.SecureTransport, error) {
+func (u *upgrader) negotiateSecurity(ctx context.Context, insecure net.Conn, server bool, ids []protocol.ID, muxer *mss.MultistreamMuxer[protocol.ID], negotiator Negotiator) (sec.SecureTransport, error) {
 	type result struct {
 		proto protocol.ID
 		err   error
@@ -327,14 +391,20 @@ func (u *upgrader) negotiateSecurity(ctx context.Context, insecure net.Conn, ser
 
 	done := make(chan result, 1)
 	go func() {
+		if negotiator != nil {
+			var r result
+			r.proto, r.err = negotiator.NegotiateSecurity(ctx, insecure, server, ids)
+			done <- r
+			return
+		}
 		if server {
 			var r result
-			r.proto, _, r.err = u.securityMuxer.Negotiate(insecure)
+			r.proto, _, r.err = muxer.Negotiate(insecure)
 			done <- r
 			return
 		}
 		var r result
-		r.proto, r.err = mss.SelectOneOf(u.securityIDs, insecure)
+		r.proto, r.err = mss.SelectOneOf(ids, insecure)
 		done <- r
 	}()
 
@@ -5,13 +5,18 @@ import (
 	"crypto/rand"
 	"errors"
 	"net"
+	"net/netip"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/metrics"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	mocknetwork "github.com/TheNoobiCat/go-libp2p/core/network/mocks"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	"github.com/TheNoobiCat/go-libp2p/core/sec"
 	"github.com/TheNoobiCat/go-libp2p/core/sec/insecure"
 	"github.com/TheNoobiCat/go-libp2p/core/transport"
@@ -158,6 +163,7 @@ func TestOutboundConnectionGating(t *testing.T) {
 	conn, err = dial(t, dialUpgrader, ln.Multiaddr(), id, &network.NullScope{})
 	require.Error(err)
 	require.Contains(err.Error(), "gater rejected connection")
+	require.ErrorIs(err, network.ErrPeerGated)
 	require.Nil(conn)
 }
 
@@ -202,3 +208,192 @@ func TestOutboundResourceManagement(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+type countingReporter struct {
+	metrics.Reporter
+	mx          sync.Mutex
+	sent, recvd map[peer.ID]int64
+}
+
+var _ metrics.MuxerReporter = &countingReporter{}
+
+func newCountingReporter() *countingReporter {
+	return &countingReporter{sent: make(map[peer.ID]int64), recvd: make(map[peer.ID]int64)}
+}
+
+func (r *countingReporter) LogSentConnMessage(n int64, p peer.ID) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.sent[p] += n
+}
+
+func (r *countingReporter) LogRecvConnMessage(n int64, p peer.ID) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.recvd[p] += n
+}
+
+func (r *countingReporter) LogSentProtocolMessage(int64, protocol.ID) {}
+func (r *countingReporter) LogRecvProtocolMessage(int64, protocol.ID) {}
+
+func (r *countingReporter) stats(p peer.ID) (sent, recvd int64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.sent[p], r.recvd[p]
+}
+
+func TestBandwidthReporter(t *testing.T) {
+	id, u := createUpgrader(t)
+	ln := createListener(t, u)
+	defer ln.Close()
+
+	r := newCountingReporter()
+	_, dialUpgrader := createUpgraderWithOpts(t, upgrader.WithBandwidthReporter(r))
+	conn, err := dial(t, dialUpgrader, ln.Multiaddr(), id, &network.NullScope{})
+	require.NoError(t, err)
+	defer conn.Close()
+	require.True(t, conn.(interface{ BandwidthMetered() bool }).BandwidthMetered())
+
+	sconn, err := ln.Accept()
+	require.NoError(t, err)
+	defer sconn.Close()
+	testConn(t, conn, sconn)
+
+	// The traffic includes the muxer setup and framing, so it exceeds the payload.
+	require.Eventually(t, func() bool {
+		sent, recvd := r.stats(id)
+		return sent > int64(len("foobar")) && recvd >= int64(len("setup"))
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestSecurityPreference(t *testing.T) {
+	newUpgrader := func(t *testing.T, opts ...upgrader.Option) (peer.ID, transport.Upgrader) {
+		id, priv := newPeer(t)
+		u, err := upgrader.New(
+			[]sec.SecureTransport{insecure.NewWithIdentity("/plaintext1", id, priv), insecure.NewWithIdentity("/plaintext2", id, priv)},
+			[]upgrader.StreamMuxer{{ID: "negotiate", Muxer: &negotiatingMuxer{}}},
+			nil, nil, nil, opts...,
+		)
+		require.NoError(t, err)
+		return id, u
+	}
+	loopback := []netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")}
+
+	t.Run("server restricts protocols", func(t *testing.T) {
+		id, u := newUpgrader(t, upgrader.WithSecurityPreference(upgrader.SecurityRules(
+			upgrader.SecurityRule{Networks: loopback, Protocols: []protocol.ID{"/plaintext2"}},
+		)))
+		ln := createListener(t, u)
+		defer ln.Close()
+
+		_, cu := newUpgrader(t)
+		accepted := make(chan transport.CapableConn, 1)
+		go func() {
+			c, _ := ln.Accept()
+			accepted <- c
+		}()
+		conn, err := dial(t, cu, ln.Multiaddr(), id, &network.NullScope{})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, protocol.ID("/plaintext2"), conn.ConnState().Security)
+		sconn := <-accepted
+		require.NotNil(t, sconn)
+		defer sconn.Close()
+		require.Equal(t, protocol.ID("/plaintext2"), sconn.ConnState().Security)
+	})
+
+	t.Run("client orders protocols per peer", func(t *testing.T) {
+		id, u := newUpgrader(t)
+		ln := createListener(t, u)
+		defer ln.Close()
+		go func() {
+			if c, err := ln.Accept(); err == nil {
+				c.Close()
+			}
+		}()
+
+		_, cu := newUpgrader(t, upgrader.WithSecurityPreference(upgrader.SecurityRules(
+			upgrader.SecurityRule{Peers: []peer.ID{id}, Protocols: []protocol.ID{"/unknown", "/plaintext2", "/plaintext1"}},
+		)))
+		conn, err := dial(t, cu, ln.Multiaddr(), id, &network.NullScope{})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, protocol.ID("/plaintext2"), conn.ConnState().Security)
+	})
+
+	t.Run("no allowed protocol", func(t *testing.T) {
+		id, u := newUpgrader(t)
+		ln := createListener(t, u)
+		defer ln.Close()
+
+		_, cu := newUpgrader(t, upgrader.WithSecurityPreference(upgrader.SecurityRules(
+			upgrader.SecurityRule{Networks: loopback, Protocols: []protocol.ID{"/tls/1.0.0"}},
+		)))
+		_, err := dial(t, cu, ln.Multiaddr(), id, &network.NullScope{})
+		require.ErrorIs(t, err, upgrader.ErrNoAllowedSecurity)
+	})
+}
+
+func TestNegotiator(t *testing.T) {
+	newUpgrader := func(t *testing.T, opts ...upgrader.Option) (peer.ID, transport.Upgrader) {
+		id, priv := newPeer(t)
+		u, err := upgrader.New(
+			[]sec.SecureTransport{insecure.NewWithIdentity("/plaintext1", id, priv), insecure.NewWithIdentity("/plaintext2", id, priv)},
+			[]upgrader.StreamMuxer{{ID: "negotiate", Muxer: &negotiatingMuxer{}}},
+			nil, nil, nil, opts...,
+		)
+		require.NoError(t, err)
+		return id, u
+	}
+	static := upgrader.StaticNegotiator{Security: "/plaintext2", Muxer: "negotiate"}
+
+	t.Run("static", func(t *testing.T) {
+		id, u := newUpgrader(t, upgrader.WithNegotiator(static))
+		ln := createListener(t, u)
+		defer ln.Close()
+
+		_, cu := newUpgrader(t, upgrader.WithNegotiator(static))
+		accepted := make(chan transport.CapableConn, 1)
+		go func() {
+			c, _ := ln.Accept()
+			accepted <- c
+		}()
+		conn, err := dial(t, cu, ln.Multiaddr(), id, &network.NullScope{})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, protocol.ID("/plaintext2"), conn.ConnState().Security)
+		require.Equal(t, protocol.ID("negotiate"), conn.ConnState().StreamMultiplexer)
+		sconn := <-accepted
+		require.NotNil(t, sconn)
+		defer sconn.Close()
+		require.Equal(t, protocol.ID("/plaintext2"), sconn.ConnState().Security)
+		testConn(t, conn, sconn)
+	})
+
+	t.Run("not allowed", func(t *testing.T) {
+		_, cu := newUpgrader(t, upgrader.WithNegotiator(upgrader.StaticNegotiator{Security: "/tls/1.0.0", Muxer: "negotiate"}))
+		id, u := newUpgrader(t)
+		ln := createListener(t, u)
+		defer ln.Close()
+		_, err := dial(t, cu, ln.Multiaddr(), id, &network.NullScope{})
+		require.ErrorContains(t, err, `static security protocol "/tls/1.0.0" isn't allowed`)
+	})
+
+	t.Run("other transports", func(t *testing.T) {
+		// The negotiator doesn't apply to the test connections, which have no transport,
+		// so multistream-select is used.
+		_, cu := newUpgrader(t, upgrader.WithNegotiator(static, ma.P_TCP))
+		id, u := newUpgrader(t)
+		ln := createListener(t, u)
+		defer ln.Close()
+		go func() {
+			if c, err := ln.Accept(); err == nil {
+				c.Close()
+			}
+		}()
+		conn, err := dial(t, cu, ln.Multiaddr(), id, &network.NullScope{})
+		require.NoError(t, err)
+		defer conn.Close()
+		require.Equal(t, protocol.ID("/plaintext1"), conn.ConnState().Security)
+	})
+}
@@ -179,21 +179,9 @@ func (an *AutoNAT) Close() {
 
 // GetReachability makes a single dial request for checking reachability for requested addresses
 func (an *AutoNAT) GetReachability(ctx context.Context, reqs []Request) (Result, error) {
-	var filteredReqs []Request
-	if !an.allowPrivateAddrs {
-		filteredReqs = make([]Request, 0, len(reqs))
-		for _, r := range reqs {
-			if manet.IsPublicAddr(r.Addr) {
-				filteredReqs = append(filteredReqs, r)
-			} else {
-				log.Errorf("private address in reachability check: %s", r.Addr)
-			}
-		}
-		if len(filteredReqs) == 0 {
-			return Result{}, ErrPrivateAddrs
-		}
-	} else {
-		filteredReqs = reqs
+	filteredReqs, err := an.filterRequests(reqs)
+	if err != nil {
+		return Result{}, err
 	}
 	an.mx.Lock()
 	now := time.Now()
@@ -226,6 +214,53 @@ func (an *AutoNAT) GetReachability(ctx context.Context, reqs []Request) (Result,
 	return res, nil
 }
 
+// GetReachabilityFrom makes a single dial request to the server p for checking
+// reachability for requested addresses. Unlike GetReachability, p doesn't need to be
+// connected, and requests to it aren't throttled.
+func (an *AutoNAT) GetReachabilityFrom(ctx context.Context, p peer.ID, reqs []Request) (Result, error) {
+	filteredReqs, err := an.filterRequests(reqs)
+	if err != nil {
+		return Result{}, err
+	}
+	res, err := an.cli.GetReachability(ctx, p, filteredReqs)
+	if err != nil {
+		return res, fmt.Errorf("reachability check with %s failed: %w", p, err)
+	}
+	for i, r := range reqs {
+		if r.Addr.Equal(res.Addr) {
+			res.Idx = i
+			break
+		}
+	}
+	return res, nil
+}
+
+// filterRequests removes the requests for private addresses, unless they're allowed.
+func (an *AutoNAT) filterRequests(reqs []Request) ([]Request, error) {
+	if an.allowPrivateAddrs {
+		return reqs, nil
+	}
+	filteredReqs := make([]Request, 0, len(reqs))
+	for _, r := range reqs {
+		if manet.IsPublicAddr(r.Addr) {
+			filteredReqs = append(filteredReqs, r)
+		} else {
+			log.Errorf("private address in reachability check: %s", r.Addr)
+		}
+	}
+	if len(filteredReqs) == 0 {
+		return nil, ErrPrivateAddrs
+	}
+	return filteredReqs, nil
+}
+
+// ServerQuotaUsage returns the consumption of the server's rate limits over the last
+// minute. It helps operators of public servers to spot the peers and the networks
+// abusing the server.
+func (an *AutoNAT) ServerQuotaUsage() ServerQuotaUsage {
+	return an.srv.limiter.Usage()
+}
+
 func (an *AutoNAT) updatePeer(p peer.ID) {
 	an.mx.Lock()
 	defer an.mx.Unlock()
@@ -15,6 +15,14 @@ type MetricsTracer interface {
 	ClientCompletedRequest([]Request, Result, error)
 }
 
+// QuotaUsageTracer is implemented by MetricsTracers that track the consumption of the
+// server's rate limits.
+type QuotaUsageTracer interface {
+	// ServerQuotaUsage reports the consumption of the server's rate limits after a
+	// request.
+	ServerQuotaUsage(ServerQuotaUsage)
+}
+
 const metricNamespace = "libp2p_autonatv2"
 
 var (
@@ -42,13 +50,26 @@ var (
 		},
 		[]string{"outcome"},
 	)
+	serverQuotaUsage = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "server_quota_usage",
+			Help:      "Server Quota Usage over the last minute, for the most active peer, prefix and ASN",
+		},
+		[]string{"quota"},
+	)
 )
 
 type metricsTracer struct {
 }
 
+var (
+	_ MetricsTracer    = &metricsTracer{}
+	_ QuotaUsageTracer = &metricsTracer{}
+)
+
 func NewMetricsTracer(reg prometheus.Registerer) MetricsTracer {
-	metricshelper.RegisterCollectors(reg, requestsCompleted, clientRequestsCompleted, clientRequestsTotal)
+	metricshelper.RegisterCollectors(reg, requestsCompleted, clientRequestsCompleted, clientRequestsTotal, serverQuotaUsage)
 	return &metricsTracer{}
 }
 
@@ -120,6 +141,22 @@ func (m *metricsTracer) ClientCompletedRequest(reqs []Request, result Result, er
 	clientRequestsCompleted.WithLabelValues(*labels...).Inc()
 }
 
+func (m *metricsTracer) ServerQuotaUsage(u ServerQuotaUsage) {
+	serverQuotaUsage.WithLabelValues("global").Set(float64(u.Requests))
+	serverQuotaUsage.WithLabelValues("dial_data").Set(float64(u.DialDataRequests))
+	serverQuotaUsage.WithLabelValues("peer").Set(float64(maxValue(u.Peers)))
+	serverQuotaUsage.WithLabelValues("prefix").Set(float64(maxValue(u.Prefixes)))
+	serverQuotaUsage.WithLabelValues("asn").Set(float64(maxValue(u.ASNs)))
+}
+
+func maxValue[K comparable](m map[K]int) int {
+	res := 0
+	for _, v := range m {
+		res = max(res, v)
+	}
+	return res
+}
+
 func getIPOrDNSVersion(a ma.Multiaddr) string {
 	if len(a) == 0 {
 		return ""
@@ -145,7 +182,7 @@ func getErrString(e error) string {
 	switch e {
 	case nil:
 		errStr = "nil"
-	case errBadRequest, errDialDataRefused, errResourceLimitExceeded:
+	case errBadRequest, errDialDataRefused, errResourceLimitExceeded, errDialBackQuotaExceeded:
 		errStr = e.Error()
 	default:
 		errStr = "other"
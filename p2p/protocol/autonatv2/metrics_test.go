@@ -47,6 +47,9 @@ func TestMetricsNoAllocNoCover(t *testing.T) {
 				DialedAddr:       addrs[rand.Intn(len(addrs))],
 			})
 		},
+		"ServerQuotaUsage": func() {
+			mt.(QuotaUsageTracer).ServerQuotaUsage(ServerQuotaUsage{Requests: rand.Intn(60), DialDataRequests: rand.Intn(12)})
+		},
 		"CompletedClientRequest": func() {
 			mt.ClientCompletedRequest(reqs[rand.Intn(len(reqs))], Result{AllAddrsRefused: rand.Intn(2) == 1, Reachability: network.Reachability(rand.Intn(2)), Addr: addrs[rand.Intn(len(addrs))]}, errs[rand.Intn(len(errs))])
 		},
@@ -1,6 +1,9 @@
 package autonatv2
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 // autoNATSettings is used to configure AutoNAT
 type autoNATSettings struct {
@@ -8,6 +11,8 @@ type autoNATSettings struct {
 	serverRPM                            int
 	serverPerPeerRPM                     int
 	serverDialDataRPM                    int
+	serverPerPrefixRPM                   int
+	serverPerASNRPM                      int
 	maxConcurrentRequestsPerPeer         int
 	dataRequestPolicy                    dataRequestPolicyFunc
 	now                                  func() time.Time
@@ -22,6 +27,8 @@ func defaultSettings() *autoNATSettings {
 		serverRPM:                            60, // 1 every second
 		serverPerPeerRPM:                     12, // 1 every 5 seconds
 		serverDialDataRPM:                    12, // 1 every 5 seconds
+		serverPerPrefixRPM:                   12, // 1 every 5 seconds
+		serverPerASNRPM:                      30, // 1 every 2 seconds
 		maxConcurrentRequestsPerPeer:         2,
 		dataRequestPolicy:                    amplificationAttackPrevention,
 		amplificatonAttackPreventionDialWait: 3 * time.Second,
@@ -42,6 +49,21 @@ func WithServerRateLimit(rpm, perPeerRPM, dialDataRPM int, maxConcurrentRequests
 	}
 }
 
+// WithDialBackQuotas limits the dial-backs per minute to the addresses of the same IP
+// prefix (/24 for IPv4, /48 for IPv6) and of the same ASN (IPv6 only). This keeps the
+// server from being used to flood a network, even by many peers each staying within
+// its per peer rate limit. Zero disables the quota.
+func WithDialBackQuotas(perPrefixRPM, perASNRPM int) AutoNATOption {
+	return func(s *autoNATSettings) error {
+		if perPrefixRPM < 0 || perASNRPM < 0 {
+			return errors.New("dial back quotas must not be negative")
+		}
+		s.serverPerPrefixRPM = perPrefixRPM
+		s.serverPerASNRPM = perASNRPM
+		return nil
+	}
+}
+
 func WithMetricsTracer(m MetricsTracer) AutoNATOption {
 	return func(s *autoNATSettings) error {
 		s.metricsTracer = m
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/netip"
 	"os"
 	"runtime/debug"
 	"sync"
@@ -20,6 +21,7 @@ import (
 
 	"math/rand"
 
+	asnutil "github.com/libp2p/go-libp2p-asn-util"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
 )
@@ -28,6 +30,14 @@ var (
 	errResourceLimitExceeded = errors.New("resource limit exceeded")
 	errBadRequest            = errors.New("bad request")
 	errDialDataRefused       = errors.New("dial data refused")
+	errDialBackQuotaExceeded = errors.New("dial back quota exceeded")
+)
+
+const (
+	// dialBackIPv4PrefixLen and dialBackIPv6PrefixLen are the lengths of the IP prefixes
+	// the dial-back quotas are accounted for.
+	dialBackIPv4PrefixLen = 24
+	dialBackIPv6PrefixLen = 48
 )
 
 type dataRequestPolicyFunc = func(observedAddr, dialAddr ma.Multiaddr) bool
@@ -69,6 +79,8 @@ func newServer(dialer host.Host, s *autoNATSettings) *server {
 			RPM:                          s.serverRPM,
 			PerPeerRPM:                   s.serverPerPeerRPM,
 			DialDataRPM:                  s.serverDialDataRPM,
+			PerPrefixRPM:                 s.serverPerPrefixRPM,
+			PerASNRPM:                    s.serverPerASNRPM,
 			MaxConcurrentRequestsPerPeer: s.maxConcurrentRequestsPerPeer,
 			now:                          s.now,
 		},
@@ -104,6 +116,9 @@ func (as *server) handleDialRequest(s network.Stream) {
 		s.Conn().RemotePeer(), evt.ResponseStatus, evt.DialStatus, evt.Error)
 	if as.metricsTracer != nil {
 		as.metricsTracer.CompletedRequest(evt)
+		if qt, ok := as.metricsTracer.(QuotaUsageTracer); ok {
+			qt.ServerQuotaUsage(as.limiter.Usage())
+		}
 	}
 }
 
@@ -210,6 +225,31 @@ func (as *server) serveDialRequest(s network.Stream) EventDialRequestCompleted {
 		}
 	}
 
+	if !as.limiter.AcceptDialBack(dialAddr) {
+		msg = pb.Message{
+			Msg: &pb.Message_DialResponse{
+				DialResponse: &pb.DialResponse{
+					Status: pb.DialResponse_E_REQUEST_REJECTED,
+				},
+			},
+		}
+		if err := w.WriteMsg(&msg); err != nil {
+			s.Reset()
+			log.Debugf("failed to write request rejected response to %s: %s", p, err)
+			return EventDialRequestCompleted{
+				ResponseStatus: pb.DialResponse_E_REQUEST_REJECTED,
+				Error:          fmt.Errorf("write failed: %w", err),
+				DialedAddr:     dialAddr,
+			}
+		}
+		log.Debugf("rejected request from %s: dial back quota exceeded for %s", p, dialAddr)
+		return EventDialRequestCompleted{
+			ResponseStatus: pb.DialResponse_E_REQUEST_REJECTED,
+			Error:          errDialBackQuotaExceeded,
+			DialedAddr:     dialAddr,
+		}
+	}
+
 	nonce := msg.GetDialRequest().Nonce
 
 	isDialDataRequired := as.dialDataRequestPolicy(s.Conn().RemoteMultiaddr(), dialAddr)
@@ -385,6 +425,8 @@ func (as *server) dialBack(ctx context.Context, p peer.ID, addr ma.Multiaddr, no
 
 // rateLimiter implements a sliding window rate limit of requests per minute. It allows 1 concurrent request
 // per peer. It rate limits requests globally, at a peer level and depending on whether it requires dial data.
+// It also rate limits the dial-backs to the same IP prefix and ASN, so that the server can't be used to
+// flood a network.
 type rateLimiter struct {
 	// PerPeerRPM is the rate limit per peer
 	PerPeerRPM int
@@ -392,6 +434,10 @@ type rateLimiter struct {
 	RPM int
 	// DialDataRPM is the rate limit for requests that require dial data
 	DialDataRPM int
+	// PerPrefixRPM is the rate limit of dial-backs to the same IP prefix. Zero disables it.
+	PerPrefixRPM int
+	// PerASNRPM is the rate limit of dial-backs to the same ASN. Zero disables it.
+	PerASNRPM int
 	// MaxConcurrentRequestsPerPeer is the maximum number of concurrent requests per peer
 	MaxConcurrentRequestsPerPeer int
 
@@ -400,9 +446,14 @@ type rateLimiter struct {
 	reqs         []entry
 	peerReqs     map[peer.ID][]time.Time
 	dialDataReqs []time.Time
+	prefixReqs   map[netip.Prefix][]time.Time
+	asnReqs      map[uint32][]time.Time
 	// inProgressReqs tracks in progress requests. This is used to limit multiple
 	// concurrent requests by the same peer.
 	inProgressReqs map[peer.ID]int
+	// usage is the snapshot returned by Usage. It's reset whenever a request is
+	// accounted for or expires.
+	usage *ServerQuotaUsage
 
 	now func() time.Time // for tests
 }
@@ -416,6 +467,8 @@ func (r *rateLimiter) init() {
 	if r.peerReqs == nil {
 		r.peerReqs = make(map[peer.ID][]time.Time)
 		r.inProgressReqs = make(map[peer.ID]int)
+		r.prefixReqs = make(map[netip.Prefix][]time.Time)
+		r.asnReqs = make(map[uint32][]time.Time)
 	}
 }
 
@@ -439,6 +492,7 @@ func (r *rateLimiter) Accept(p peer.ID) bool {
 	r.inProgressReqs[p]++
 	r.reqs = append(r.reqs, entry{PeerID: p, Time: nw})
 	r.peerReqs[p] = append(r.peerReqs[p], nw)
+	r.usage = nil
 	return true
 }
 
@@ -455,9 +509,114 @@ func (r *rateLimiter) AcceptDialDataRequest() bool {
 		return false
 	}
 	r.dialDataReqs = append(r.dialDataReqs, nw)
+	r.usage = nil
+	return true
+}
+
+// AcceptDialBack returns whether dialing back a is within the dial-back quotas of its IP
+// prefix and ASN, and accounts for the dial-back if it is. Only public IP addresses are
+// accounted for.
+func (r *rateLimiter) AcceptDialBack(a ma.Multiaddr) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return false
+	}
+	prefix, asn, ok := dialBackGroups(a)
+	if !ok {
+		return true
+	}
+	r.init()
+	nw := r.now()
+	r.cleanup(nw)
+
+	if r.PerPrefixRPM > 0 && len(r.prefixReqs[prefix]) >= r.PerPrefixRPM {
+		return false
+	}
+	if r.PerASNRPM > 0 && asn != 0 && len(r.asnReqs[asn]) >= r.PerASNRPM {
+		return false
+	}
+	r.prefixReqs[prefix] = append(r.prefixReqs[prefix], nw)
+	if asn != 0 {
+		r.asnReqs[asn] = append(r.asnReqs[asn], nw)
+	}
+	r.usage = nil
 	return true
 }
 
+// dialBackGroups returns the IP prefix and the ASN the dial-backs to a are accounted
+// for. The ASN is only known for IPv6 addresses, and is 0 otherwise.
+func dialBackGroups(a ma.Multiaddr) (prefix netip.Prefix, asn uint32, ok bool) {
+	if !manet.IsPublicAddr(a) {
+		return netip.Prefix{}, 0, false
+	}
+	nip, err := manet.ToIP(a)
+	if err != nil {
+		return netip.Prefix{}, 0, false
+	}
+	ip, ok := netip.AddrFromSlice(nip)
+	if !ok {
+		return netip.Prefix{}, 0, false
+	}
+	ip = ip.Unmap()
+	if ip.Is4() {
+		prefix, _ = ip.Prefix(dialBackIPv4PrefixLen)
+	} else {
+		prefix, _ = ip.Prefix(dialBackIPv6PrefixLen)
+		asn = asnutil.AsnForIPv6(nip)
+	}
+	return prefix, asn, true
+}
+
+// ServerQuotaUsage is the consumption of the AutoNATv2 server's rate limits over the
+// last minute.
+type ServerQuotaUsage struct {
+	// Requests is the number of accepted requests.
+	Requests int
+	// DialDataRequests is the number of accepted requests that required dial data.
+	DialDataRequests int
+	// Peers is the number of accepted requests per peer.
+	Peers map[peer.ID]int
+	// Prefixes is the number of dial-backs per IP prefix.
+	Prefixes map[netip.Prefix]int
+	// ASNs is the number of dial-backs per ASN. It's only known for IPv6 addresses.
+	ASNs map[uint32]int
+}
+
+// Usage returns the current consumption of the quotas. The snapshot is only rebuilt
+// when the usage changed, and its maps are shared between callers: they must not be
+// modified.
+func (r *rateLimiter) Usage() ServerQuotaUsage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return ServerQuotaUsage{}
+	}
+	r.init()
+	r.cleanup(r.now())
+	if r.usage != nil {
+		return *r.usage
+	}
+	u := ServerQuotaUsage{
+		Requests:         len(r.reqs),
+		DialDataRequests: len(r.dialDataReqs),
+		Peers:            make(map[peer.ID]int, len(r.peerReqs)),
+		Prefixes:         make(map[netip.Prefix]int, len(r.prefixReqs)),
+		ASNs:             make(map[uint32]int, len(r.asnReqs)),
+	}
+	for p, reqs := range r.peerReqs {
+		u.Peers[p] = len(reqs)
+	}
+	for p, reqs := range r.prefixReqs {
+		u.Prefixes[p] = len(reqs)
+	}
+	for asn, reqs := range r.asnReqs {
+		u.ASNs[asn] = len(reqs)
+	}
+	r.usage = &u
+	return u
+}
+
 // cleanup removes stale requests.
 //
 // This is fast enough in rate limited cases and the state is small enough to
@@ -482,6 +641,9 @@ func (r *rateLimiter) cleanup(now time.Time) {
 			break
 		}
 	}
+	if idx > 0 {
+		r.usage = nil
+	}
 	r.reqs = r.reqs[idx:]
 
 	idx = len(r.dialDataReqs)
@@ -491,7 +653,40 @@ func (r *rateLimiter) cleanup(now time.Time) {
 			break
 		}
 	}
+	if idx > 0 {
+		r.usage = nil
+	}
 	r.dialDataReqs = r.dialDataReqs[idx:]
+
+	prefixesRemoved := cleanupTimes(r.prefixReqs, now)
+	asnsRemoved := cleanupTimes(r.asnReqs, now)
+	if prefixesRemoved || asnsRemoved {
+		r.usage = nil
+	}
+}
+
+// cleanupTimes removes the times older than a minute from m, and the keys left
+// without any. It returns whether any time was removed.
+func cleanupTimes[K comparable](m map[K][]time.Time, now time.Time) bool {
+	removed := false
+	for k, ts := range m {
+		idx := len(ts)
+		for i, t := range ts {
+			if now.Sub(t) < time.Minute {
+				idx = i
+				break
+			}
+		}
+		if idx > 0 {
+			removed = true
+		}
+		if idx == len(ts) {
+			delete(m, k)
+		} else {
+			m[k] = ts[idx:]
+		}
+	}
+	return removed
 }
 
 func (r *rateLimiter) CompleteRequest(p peer.ID) {
@@ -513,6 +708,9 @@ func (r *rateLimiter) Close() {
 	r.peerReqs = nil
 	r.inProgressReqs = nil
 	r.dialDataReqs = nil
+	r.prefixReqs = nil
+	r.asnReqs = nil
+	r.usage = nil
 }
 
 // amplificationAttackPrevention is a dialDataRequestPolicy which requests data when the peer's observed
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net/netip"
+	"reflect"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -475,6 +477,70 @@ func TestRateLimiterStress(t *testing.T) {
 	}
 }
 
+func TestRateLimiterDialBackQuotas(t *testing.T) {
+	cl := test.NewMockClock()
+	r := rateLimiter{PerPrefixRPM: 2, PerASNRPM: 3, now: cl.Now}
+
+	// the IPv4 addresses are grouped by /24 prefix
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip4/1.2.3.4/tcp/1")))
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip4/1.2.3.5/udp/1/quic-v1")))
+	require.False(t, r.AcceptDialBack(ma.StringCast("/ip4/1.2.3.6/tcp/1")))
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip4/1.2.4.6/tcp/1")))
+
+	// the IPv6 addresses are grouped by /48 prefix and by ASN
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip6/2001:4860:1::1/tcp/1")))
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip6/2001:4860:1::2/tcp/1")))
+	require.False(t, r.AcceptDialBack(ma.StringCast("/ip6/2001:4860:1::3/tcp/1")))
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip6/2001:4860:2::1/tcp/1")))
+	require.False(t, r.AcceptDialBack(ma.StringCast("/ip6/2001:4860:3::1/tcp/1")))
+
+	// private and DNS addresses aren't accounted for
+	for i := 0; i < 5; i++ {
+		require.True(t, r.AcceptDialBack(ma.StringCast("/ip4/192.168.1.1/tcp/1")))
+		require.True(t, r.AcceptDialBack(ma.StringCast("/dns4/example.com/tcp/1")))
+	}
+
+	u := r.Usage()
+	require.Equal(t, 2, u.Prefixes[netip.MustParsePrefix("1.2.3.0/24")])
+	require.Equal(t, 1, u.Prefixes[netip.MustParsePrefix("1.2.4.0/24")])
+	require.Equal(t, 2, u.Prefixes[netip.MustParsePrefix("2001:4860:1::/48")])
+	require.Len(t, u.ASNs, 1)
+	for _, n := range u.ASNs {
+		require.Equal(t, 3, n)
+	}
+
+	cl.AdvanceBy(time.Minute)
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip4/1.2.3.6/tcp/1")))
+	require.True(t, r.AcceptDialBack(ma.StringCast("/ip6/2001:4860:3::1/tcp/1")))
+	u = r.Usage()
+	require.Len(t, u.Prefixes, 2)
+	require.Len(t, u.ASNs, 1)
+}
+
+func TestRateLimiterUsageSnapshot(t *testing.T) {
+	cl := test.NewMockClock()
+	r := rateLimiter{RPM: 10, PerPeerRPM: 10, DialDataRPM: 10, MaxConcurrentRequestsPerPeer: 10, now: cl.Now}
+
+	require.True(t, r.Accept("peer-1"))
+	u := r.Usage()
+	require.Equal(t, 1, u.Requests)
+	// the snapshot is reused while the usage doesn't change
+	require.Equal(t, reflect.ValueOf(u.Peers).Pointer(), reflect.ValueOf(r.Usage().Peers).Pointer())
+
+	require.True(t, r.Accept("peer-2"))
+	require.True(t, r.AcceptDialDataRequest())
+	u = r.Usage()
+	require.Equal(t, 2, u.Requests)
+	require.Equal(t, 1, u.DialDataRequests)
+	require.Len(t, u.Peers, 2)
+
+	cl.AdvanceBy(time.Minute)
+	u = r.Usage()
+	require.Zero(t, u.Requests)
+	require.Zero(t, u.DialDataRequests)
+	require.Empty(t, u.Peers)
+}
+
 func TestReadDialData(t *testing.T) {
 	for N := 30_000; N < 30_010; N++ {
 		for msgSize := 100; msgSize < 256; msgSize++ {
@@ -34,6 +34,8 @@ type Client struct {
 	mx          sync.Mutex
 	activeDials map[peer.ID]*completion
 	hopCount    map[peer.ID]int
+
+	metricsTracer MetricsTracer
 }
 
 var _ io.Closer = &Client{}
@@ -50,9 +52,20 @@ type completion struct {
 	err   error
 }
 
+// Option is an option for the p2p-circuit/v2 client.
+type Option func(*Client) error
+
+// WithMetricsTracer configures the client to use mt to track metrics of relayed connections
+func WithMetricsTracer(mt MetricsTracer) Option {
+	return func(c *Client) error {
+		c.metricsTracer = mt
+		return nil
+	}
+}
+
 // New constructs a new p2p-circuit/v2 client, attached to the given host and using the given
 // upgrader to perform connection upgrades.
-func New(h host.Host, upgrader transport.Upgrader) (*Client, error) {
+func New(h host.Host, upgrader transport.Upgrader, opts ...Option) (*Client, error) {
 	cl := &Client{
 		host:        h,
 		upgrader:    upgrader,
@@ -60,6 +73,11 @@ func New(h host.Host, upgrader transport.Upgrader) (*Client, error) {
 		activeDials: make(map[peer.ID]*completion),
 		hopCount:    make(map[peer.ID]int),
 	}
+	for _, opt := range opts {
+		if err := opt(cl); err != nil {
+			return nil, err
+		}
+	}
 	cl.ctx, cl.ctxCancel = context.WithCancel(context.Background())
 	return cl, nil
 }
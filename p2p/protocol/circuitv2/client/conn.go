@@ -3,6 +3,8 @@ package client
 import (
 	"fmt"
 	"net"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
@@ -28,6 +30,11 @@ type Conn struct {
 	stream network.Stream
 	remote peer.AddrInfo
 	stat   network.ConnStats
+	dir    network.Direction
+
+	// bytes read and written, for metrics
+	bytesIn, bytesOut atomic.Int64
+	closeOnce         sync.Once
 
 	client *Client
 }
@@ -51,16 +58,25 @@ func (n *NetAddr) String() string {
 var _ manet.Conn = (*Conn)(nil)
 
 func (c *Conn) Close() error {
-	c.untagHop()
+	c.closeOnce.Do(func() {
+		c.untagHop()
+		if mt := c.client.metricsTracer; mt != nil {
+			mt.ConnectionClosed(c.dir, c.bytesIn.Load(), c.bytesOut.Load())
+		}
+	})
 	return c.stream.Reset()
 }
 
 func (c *Conn) Read(buf []byte) (int, error) {
-	return c.stream.Read(buf)
+	n, err := c.stream.Read(buf)
+	c.bytesIn.Add(int64(n))
+	return n, err
 }
 
 func (c *Conn) Write(buf []byte) (int, error) {
-	return c.stream.Write(buf)
+	n, err := c.stream.Write(buf)
+	c.bytesOut.Add(int64(n))
+	return n, err
 }
 
 func (c *Conn) SetDeadline(t time.Time) error {
@@ -132,6 +148,13 @@ func (c *Conn) tagHop() {
 	}
 }
 
+// trackOpened tracks the connection being opened in the metrics of the client.
+func (c *Conn) trackOpened() {
+	if mt := c.client.metricsTracer; mt != nil {
+		mt.ConnectionOpened(c.dir)
+	}
+}
+
 // untagHop removes the relay-hop-stream tag if necessary; it is invoked when a relayed connection
 // is closed.
 func (c *Conn) untagHop() {
@@ -127,10 +127,10 @@ func (c *Client) dialPeer(ctx context.Context, relay, dest peer.AddrInfo) (*Conn
 	if err != nil {
 		return nil, fmt.Errorf("error opening hop stream to relay: %w", err)
 	}
-	return c.connect(s, dest)
+	return c.connect(s, dest, aclToken(ctx, c.host.Peerstore(), relay.ID))
 }
 
-func (c *Client) connect(s network.Stream, dest peer.AddrInfo) (*Conn, error) {
+func (c *Client) connect(s network.Stream, dest peer.AddrInfo, token []byte) (*Conn, error) {
 	if err := s.Scope().ReserveMemory(maxMessageSize, network.ReservationPriorityAlways); err != nil {
 		s.Reset()
 		return nil, err
@@ -145,6 +145,7 @@ func (c *Client) connect(s network.Stream, dest peer.AddrInfo) (*Conn, error) {
 
 	msg.Type = pbv2.HopMessage_CONNECT.Enum()
 	msg.Peer = util.PeerInfoToPeerV2(dest)
+	msg.Token = token
 
 	s.SetDeadline(time.Now().Add(DialTimeout))
 
@@ -185,5 +186,5 @@ func (c *Client) connect(s network.Stream, dest peer.AddrInfo) (*Conn, error) {
 		stat.Extra[StatLimitData] = limit.GetData()
 	}
 
-	return &Conn{stream: s, remote: dest, stat: stat, client: c}, nil
+	return &Conn{stream: s, remote: dest, stat: stat, dir: network.DirOutbound, client: c}, nil
 }
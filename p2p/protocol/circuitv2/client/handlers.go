@@ -79,7 +79,7 @@ func (c *Client) handleStreamV2(s network.Stream) {
 
 	select {
 	case c.incoming <- accept{
-		conn: &Conn{stream: s, remote: src, stat: stat, client: c},
+		conn: &Conn{stream: s, remote: src, stat: stat, dir: network.DirInbound, client: c},
 		writeResponse: func() error {
 			return writeResponse(pbv2.Status_OK)
 		},
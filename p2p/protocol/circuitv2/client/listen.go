@@ -30,6 +30,7 @@ func (l *Listener) Accept() (manet.Conn, error) {
 			log.Debugf("accepted relay connection from %s through %s", evt.conn.remote.ID, evt.conn.RemoteMultiaddr())
 
 			evt.conn.tagHop()
+			evt.conn.trackOpened()
 			return evt.conn, nil
 
 		case <-l.ctx.Done():
@@ -0,0 +1,229 @@
+package client
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+	pbv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
+
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricNamespace = "libp2p_relayclient"
+
+var (
+	reservationRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "reservation_requests_total",
+			Help:      "Reservation Requests by Type and Response Status",
+		},
+		[]string{"type", "status"},
+	)
+	reservationRequestDurationSeconds = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "reservation_request_duration_seconds",
+			Help:      "Reservation Request Latency",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 12),
+		},
+		[]string{"type"},
+	)
+	reservationsActive = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "reservations_active",
+			Help:      "Active Reservations",
+		},
+	)
+
+	connectionsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "connections_total",
+			Help:      "Relayed Connections",
+		},
+		[]string{"dir", "type"},
+	)
+	dataTransferredBytesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "data_transferred_bytes_total",
+			Help:      "Bytes Transferred over Relayed Connections",
+		},
+		[]string{"dir"},
+	)
+
+	collectors = []prometheus.Collector{
+		reservationRequestsTotal,
+		reservationRequestDurationSeconds,
+		reservationsActive,
+		connectionsTotal,
+		dataTransferredBytesTotal,
+	}
+)
+
+// MetricsTracer is the interface for tracking metrics for the relay client
+type MetricsTracer interface {
+	// ReservationRequestFinished tracks a reservation request to relay that took d.
+	// status is pbv2.Status_CONNECTION_FAILED if the request failed without a
+	// response from the relay, and expiration is the expiration of the reservation
+	// if the request succeeded.
+	ReservationRequestFinished(relay peer.ID, status pbv2.Status, expiration time.Time, d time.Duration)
+
+	// ConnectionOpened tracks a relayed connection being opened
+	ConnectionOpened(dir network.Direction)
+	// ConnectionClosed tracks a relayed connection being closed, after bytesIn bytes
+	// were read and bytesOut bytes were written over it
+	ConnectionClosed(dir network.Direction, bytesIn, bytesOut int64)
+}
+
+type metricsTracer struct {
+	clock clock.Clock
+
+	mx sync.Mutex
+	// reservations maps the relays we have a reservation with to its expiration
+	reservations map[peer.ID]time.Time
+	// expiryTimer fires when the next reservation expires, to update
+	// reservationsActive
+	expiryTimer *clock.Timer
+}
+
+var _ MetricsTracer = &metricsTracer{}
+
+type metricsTracerSetting struct {
+	reg   prometheus.Registerer
+	clock clock.Clock
+}
+
+type MetricsTracerOption func(*metricsTracerSetting)
+
+func WithRegisterer(reg prometheus.Registerer) MetricsTracerOption {
+	return func(s *metricsTracerSetting) {
+		if reg != nil {
+			s.reg = reg
+		}
+	}
+}
+
+// WithClock sets the clock used to expire the reservations, e.g. a mock clock in tests.
+func WithClock(cl clock.Clock) MetricsTracerOption {
+	return func(s *metricsTracerSetting) {
+		if cl != nil {
+			s.clock = cl
+		}
+	}
+}
+
+func NewMetricsTracer(opts ...MetricsTracerOption) MetricsTracer {
+	setting := &metricsTracerSetting{reg: prometheus.DefaultRegisterer, clock: clock.New()}
+	for _, opt := range opts {
+		opt(setting)
+	}
+	metricshelper.RegisterCollectors(setting.reg, collectors...)
+	return &metricsTracer{clock: setting.clock, reservations: make(map[peer.ID]time.Time)}
+}
+
+func (mt *metricsTracer) ReservationRequestFinished(relay peer.ID, status pbv2.Status, expiration time.Time, d time.Duration) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	mt.mx.Lock()
+	// a request to a relay we have a valid reservation with is a refresh
+	exp, isRefresh := mt.reservations[relay]
+	isRefresh = isRefresh && exp.After(mt.clock.Now())
+	if status == pbv2.Status_OK {
+		mt.reservations[relay] = expiration
+	} else {
+		delete(mt.reservations, relay)
+	}
+	mt.updateReservationsActive()
+	mt.mx.Unlock()
+
+	if isRefresh {
+		*tags = append(*tags, "refresh")
+	} else {
+		*tags = append(*tags, "new")
+	}
+	reservationRequestDurationSeconds.WithLabelValues(*tags...).Observe(d.Seconds())
+	*tags = append(*tags, getStatus(status))
+	reservationRequestsTotal.WithLabelValues(*tags...).Inc()
+}
+
+// updateReservationsActive removes the expired reservations, updates
+// reservationsActive, and schedules the next update for when the next reservation
+// expires. mt.mx must be held.
+func (mt *metricsTracer) updateReservationsActive() {
+	now := mt.clock.Now()
+	var next time.Time
+	for p, exp := range mt.reservations {
+		if !exp.After(now) {
+			delete(mt.reservations, p)
+			continue
+		}
+		if next.IsZero() || exp.Before(next) {
+			next = exp
+		}
+	}
+	reservationsActive.Set(float64(len(mt.reservations)))
+
+	switch {
+	case next.IsZero():
+		if mt.expiryTimer != nil {
+			mt.expiryTimer.Stop()
+		}
+	case mt.expiryTimer == nil:
+		mt.expiryTimer = mt.clock.AfterFunc(next.Sub(now), func() {
+			mt.mx.Lock()
+			defer mt.mx.Unlock()
+			mt.updateReservationsActive()
+		})
+	default:
+		mt.expiryTimer.Reset(next.Sub(now))
+	}
+}
+
+func (mt *metricsTracer) ConnectionOpened(dir network.Direction) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+	*tags = append(*tags, metricshelper.GetDirection(dir), "opened")
+
+	connectionsTotal.WithLabelValues(*tags...).Inc()
+}
+
+func (mt *metricsTracer) ConnectionClosed(dir network.Direction, bytesIn, bytesOut int64) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+	*tags = append(*tags, metricshelper.GetDirection(dir), "closed")
+
+	connectionsTotal.WithLabelValues(*tags...).Inc()
+
+	*tags = (*tags)[:0]
+	*tags = append(*tags, "in")
+	dataTransferredBytesTotal.WithLabelValues(*tags...).Add(float64(bytesIn))
+	(*tags)[0] = "out"
+	dataTransferredBytesTotal.WithLabelValues(*tags...).Add(float64(bytesOut))
+}
+
+func getStatus(status pbv2.Status) string {
+	switch status {
+	case pbv2.Status_OK:
+		return "ok"
+	case pbv2.Status_RESERVATION_REFUSED:
+		return "reservation refused"
+	case pbv2.Status_RESOURCE_LIMIT_EXCEEDED:
+		return "resource limit exceeded"
+	case pbv2.Status_PERMISSION_DENIED:
+		return "permission denied"
+	case pbv2.Status_CONNECTION_FAILED:
+		return "connection failed"
+	case pbv2.Status_MALFORMED_MESSAGE:
+		return "malformed message"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,34 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	pbv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
+
+	"github.com/benbjohnson/clock"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsReservationsExpire(t *testing.T) {
+	cl := clock.NewMock()
+	mt := NewMetricsTracer(WithRegisterer(prometheus.NewRegistry()), WithClock(cl))
+	active := func() float64 {
+		var m dto.Metric
+		require.NoError(t, reservationsActive.Write(&m))
+		return m.GetGauge().GetValue()
+	}
+
+	mt.ReservationRequestFinished(peer.ID("relay1"), pbv2.Status_OK, cl.Now().Add(time.Minute), time.Second)
+	mt.ReservationRequestFinished(peer.ID("relay2"), pbv2.Status_OK, cl.Now().Add(time.Hour), time.Second)
+	require.Equal(t, 2.0, active())
+
+	// the gauge is updated when a reservation expires, without a new request
+	cl.Add(time.Minute)
+	require.Equal(t, 1.0, active())
+	cl.Add(time.Hour)
+	require.Equal(t, 0.0, active())
+}
@@ -0,0 +1,41 @@
+//go:build nocover
+
+package client
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	pbv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
+)
+
+func TestNoCoverNoAlloc(t *testing.T) {
+	statuses := []pbv2.Status{
+		pbv2.Status_OK,
+		pbv2.Status_RESERVATION_REFUSED,
+		pbv2.Status_RESOURCE_LIMIT_EXCEEDED,
+		pbv2.Status_CONNECTION_FAILED,
+	}
+	relays := []peer.ID{"relay1", "relay2", "relay3"}
+	dirs := []network.Direction{network.DirInbound, network.DirOutbound}
+	mt := NewMetricsTracer()
+	tests := map[string]func(){
+		"ReservationRequestFinished": func() {
+			mt.ReservationRequestFinished(relays[rand.Intn(len(relays))], statuses[rand.Intn(len(statuses))],
+				time.Now().Add(time.Hour), time.Duration(rand.Intn(1000))*time.Millisecond)
+		},
+		"ConnectionOpened": func() { mt.ConnectionOpened(dirs[rand.Intn(len(dirs))]) },
+		"ConnectionClosed": func() {
+			mt.ConnectionClosed(dirs[rand.Intn(len(dirs))], rand.Int63n(1000), rand.Int63n(1000))
+		},
+	}
+	for method, f := range tests {
+		allocs := testing.AllocsPerRun(1000, f)
+		if allocs > 0 {
+			t.Fatalf("Alloc Test: %s, got: %0.2f, expected: 0 allocs", method, allocs)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -35,6 +36,10 @@ type Reservation struct {
 
 	// Voucher is a signed reservation voucher provided by the relay
 	Voucher *proto.ReservationVoucher
+	// SignedVoucher is the envelope of Voucher, as signed by the relay. It can be
+	// advertised with the relayed addresses, to prove that the relay granted the
+	// reservation, see proto.ConsumeVoucher.
+	SignedVoucher *record.Envelope
 }
 
 // ReservationError is the error returned on failure to reserve a slot in the relay
@@ -58,11 +63,51 @@ func (re ReservationError) Unwrap() error {
 	return re.err
 }
 
+// ReserveOption is an option for Reserve.
+type ReserveOption func(*reserveConfig)
+
+type reserveConfig struct {
+	metricsTracer MetricsTracer
+}
+
+// WithReserveMetricsTracer configures Reserve to use mt to track metrics of the
+// reservation request.
+func WithReserveMetricsTracer(mt MetricsTracer) ReserveOption {
+	return func(c *reserveConfig) {
+		c.metricsTracer = mt
+	}
+}
+
 // Reserve reserves a slot in a relay and returns the reservation information.
 // Clients must reserve slots in order for the relay to relay connections to them.
-func Reserve(ctx context.Context, h host.Host, ai peer.AddrInfo) (*Reservation, error) {
+func Reserve(ctx context.Context, h host.Host, ai peer.AddrInfo, opts ...ReserveOption) (*Reservation, error) {
+	var cfg reserveConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	start := time.Now()
+	rsvp, err := reserve(ctx, h, ai)
+	if cfg.metricsTracer != nil {
+		status := pbv2.Status_OK
+		var expiration time.Time
+		if err != nil {
+			status = pbv2.Status_CONNECTION_FAILED
+			var re ReservationError
+			if errors.As(err, &re) {
+				status = re.Status
+			}
+		} else {
+			expiration = rsvp.Expiration
+		}
+		cfg.metricsTracer.ReservationRequestFinished(ai.ID, status, expiration, time.Since(start))
+	}
+	return rsvp, err
+}
+
+func reserve(ctx context.Context, h host.Host, ai peer.AddrInfo) (*Reservation, error) {
 	if len(ai.Addrs) > 0 {
-		h.Peerstore().AddAddrs(ai.ID, ai.Addrs, peerstore.TempAddrTTL)
+		peerstore.AddAddrsFrom(h.Peerstore(), ai.ID, ai.Addrs, peerstore.TempAddrTTL, peerstore.AddrSourceRelayReservation)
 	}
 
 	s, err := h.NewStream(ctx, ai.ID, proto.ProtoIDv2Hop)
@@ -77,6 +122,7 @@ func Reserve(ctx context.Context, h host.Host, ai peer.AddrInfo) (*Reservation,
 
 	var msg pbv2.HopMessage
 	msg.Type = pbv2.HopMessage_RESERVE.Enum()
+	msg.Token = aclToken(ctx, h.Peerstore(), ai.ID)
 
 	s.SetDeadline(time.Now().Add(ReserveTimeout))
 
@@ -165,6 +211,7 @@ func Reserve(ctx context.Context, h host.Host, ai peer.AddrInfo) (*Reservation,
 
 		}
 		result.Voucher = voucher
+		result.SignedVoucher = env
 	}
 
 	limit := msg.GetLimit()
@@ -140,10 +140,19 @@ func TestReservationFailures(t *testing.T) {
 			cl, err := libp2p.New(libp2p.ResourceManager(&network.NullResourceManager{}))
 			require.NoError(t, err)
 			defer cl.Close()
-			_, err = client.Reserve(context.Background(), cl, peer.AddrInfo{ID: host.ID(), Addrs: host.Addrs()})
+			mt := &mockMetricsTracer{}
+			_, err = client.Reserve(context.Background(), cl, peer.AddrInfo{ID: host.ID(), Addrs: host.Addrs()},
+				client.WithReserveMetricsTracer(mt))
+			require.Equal(t, []peer.ID{host.ID()}, mt.relays)
 			if tc.err == "" {
 				require.NoError(t, err)
+				require.Equal(t, []pbv2.Status{pbv2.Status_OK}, mt.statuses)
 			} else {
+				expected := tc.status
+				if expected == 0 {
+					expected = pbv2.Status_CONNECTION_FAILED
+				}
+				require.Equal(t, []pbv2.Status{expected}, mt.statuses)
 				require.Error(t, err)
 				require.Contains(t, err.Error(), tc.err)
 				if tc.status != 0 {
@@ -159,3 +168,19 @@ func TestReservationFailures(t *testing.T) {
 		})
 	}
 }
+
+type mockMetricsTracer struct {
+	relays   []peer.ID
+	statuses []pbv2.Status
+}
+
+var _ client.MetricsTracer = &mockMetricsTracer{}
+
+func (mt *mockMetricsTracer) ReservationRequestFinished(relay peer.ID, status pbv2.Status, _ time.Time, _ time.Duration) {
+	mt.relays = append(mt.relays, relay)
+	mt.statuses = append(mt.statuses, status)
+}
+
+func (mt *mockMetricsTracer) ConnectionOpened(network.Direction) {}
+
+func (mt *mockMetricsTracer) ConnectionClosed(network.Direction, int64, int64) {}
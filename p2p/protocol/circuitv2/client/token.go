@@ -0,0 +1,40 @@
+package client
+
+import (
+	"context"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+)
+
+// aclTokenKey is the peerstore metadata key of the access token presented to a relay.
+const aclTokenKey = "circuitv2/acl-token"
+
+type aclTokenCtxKey struct{}
+
+// WithACLToken returns a context that makes Reserve, and relayed dials using the
+// context, present token to the relay. It overrides the token set with SetACLToken.
+func WithACLToken(ctx context.Context, token []byte) context.Context {
+	return context.WithValue(ctx, aclTokenCtxKey{}, token)
+}
+
+// SetACLToken sets the access token h presents to relay when reserving a slot and
+// dialing through it, for relays that restrict access to peers with a valid token.
+// Unlike WithACLToken, it also applies to reservations made by autorelay.
+func SetACLToken(h host.Host, relay peer.ID, token []byte) error {
+	return h.Peerstore().Put(relay, aclTokenKey, token)
+}
+
+// aclToken returns the access token to present to relay, nil if there's none.
+func aclToken(ctx context.Context, ps peerstore.Peerstore, relay peer.ID) []byte {
+	if token, ok := ctx.Value(aclTokenCtxKey{}).([]byte); ok {
+		return token
+	}
+	v, err := ps.Get(relay, aclTokenKey)
+	if err != nil {
+		return nil
+	}
+	token, _ := v.([]byte)
+	return token
+}
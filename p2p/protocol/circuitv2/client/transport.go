@@ -18,13 +18,13 @@ var circuitAddr = ma.Cast(circuitProtocol.VCode)
 
 // AddTransport constructs a new p2p-circuit/v2 client and adds it as a transport to the
 // host network
-func AddTransport(h host.Host, upgrader transport.Upgrader) error {
+func AddTransport(h host.Host, upgrader transport.Upgrader, opts ...Option) error {
 	n, ok := h.Network().(transport.TransportNetwork)
 	if !ok {
 		return fmt.Errorf("%v is not a transport network", h.Network())
 	}
 
-	c, err := New(h, upgrader)
+	c, err := New(h, upgrader, opts...)
 	if err != nil {
 		return fmt.Errorf("error constructing circuit client: %w", err)
 	}
@@ -83,6 +83,7 @@ func (c *Client) dialAndUpgrade(ctx context.Context, a ma.Multiaddr, p peer.ID,
 		return nil, err
 	}
 	conn.tagHop()
+	conn.trackOpened()
 	cc, err := c.upgrader.Upgrade(ctx, c, conn, network.DirOutbound, p, connScope)
 	if err != nil {
 		return nil, err
@@ -1,12 +1,15 @@
 package proto
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/record"
 	pbv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
 
+	ma "github.com/multiformats/go-multiaddr"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -67,3 +70,76 @@ func (rv *ReservationVoucher) UnmarshalRecord(blob []byte) error {
 	rv.Expiration = time.Unix(int64(pbrv.GetExpiration()), 0)
 	return nil
 }
+
+// ErrVoucherExpired is returned when verifying a voucher whose reservation expired.
+var ErrVoucherExpired = errors.New("reservation voucher expired")
+
+// ConsumeVoucher verifies a serialized voucher envelope, as advertised in the peer
+// record of the reserving peer, and returns the voucher. It checks that the voucher
+// was signed by the relay it names, that it was issued to p, and that the reservation
+// didn't expire before now.
+func ConsumeVoucher(data []byte, p peer.ID, now time.Time) (*ReservationVoucher, error) {
+	env, rec, err := record.ConsumeEnvelope(data, RecordDomain)
+	if err != nil {
+		return nil, fmt.Errorf("error consuming voucher envelope: %w", err)
+	}
+	voucher, ok := rec.(*ReservationVoucher)
+	if !ok {
+		return nil, fmt.Errorf("unexpected voucher record type: %T", rec)
+	}
+	signer, err := peer.IDFromPublicKey(env.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid voucher signing public key: %w", err)
+	}
+	if signer != voucher.Relay {
+		return nil, fmt.Errorf("invalid voucher relay id: expected %s, got %s", signer, voucher.Relay)
+	}
+	if voucher.Peer != p {
+		return nil, fmt.Errorf("invalid voucher peer id: expected %s, got %s", p, voucher.Peer)
+	}
+	if !now.Before(voucher.Expiration) {
+		return nil, ErrVoucherExpired
+	}
+	return voucher, nil
+}
+
+// VouchedRelayAddrs returns the relayed addresses of rec whose relay vouched, in one
+// of rec.Vouchers, for a reservation of rec.PeerID that's still valid at now. The
+// other addresses, relayed or not, are omitted.
+func VouchedRelayAddrs(rec *peer.PeerRecord, now time.Time) []ma.Multiaddr {
+	relays := make(map[peer.ID]struct{}, len(rec.Vouchers))
+	for _, data := range rec.Vouchers {
+		voucher, err := ConsumeVoucher(data, rec.PeerID, now)
+		if err != nil {
+			continue
+		}
+		relays[voucher.Relay] = struct{}{}
+	}
+	if len(relays) == 0 {
+		return nil
+	}
+
+	var addrs []ma.Multiaddr
+	for _, a := range rec.Addrs {
+		relay, err := RelayID(a)
+		if err != nil {
+			continue
+		}
+		if _, ok := relays[relay]; ok {
+			addrs = append(addrs, a)
+		}
+	}
+	return addrs
+}
+
+// RelayID returns the ID of the relay of a relayed address, of the form
+// <relay's-addr>/p2p/<relay's-id>/p2p-circuit.
+func RelayID(a ma.Multiaddr) (peer.ID, error) {
+	relayAddr, circuit := ma.SplitFunc(a, func(c ma.Component) bool {
+		return c.Protocol().Code == ma.P_CIRCUIT
+	})
+	if circuit == nil {
+		return "", fmt.Errorf("%s is not a relay address", a)
+	}
+	return peer.IDFromP2PAddr(relayAddr)
+}
@@ -1,12 +1,15 @@
 package proto
 
 import (
+	"errors"
 	"testing"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/record"
+
+	ma "github.com/multiformats/go-multiaddr"
 )
 
 func TestReservationVoucher(t *testing.T) {
@@ -66,3 +69,76 @@ func TestReservationVoucher(t *testing.T) {
 		t.Fatal("expirations don't match")
 	}
 }
+
+func TestVouchedRelayAddrs(t *testing.T) {
+	relayPrivk, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPrivk, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerPrivk, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	relayID, err := peer.IDFromPrivateKey(relayPrivk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherID, err := peer.IDFromPrivateKey(otherPrivk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	peerID, err := peer.IDFromPrivateKey(peerPrivk)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	voucher := func(signer crypto.PrivKey, relay, p peer.ID, exp time.Time) []byte {
+		env, err := record.Seal(&ReservationVoucher{Relay: relay, Peer: p, Expiration: exp}, signer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := env.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+
+	valid := voucher(relayPrivk, relayID, peerID, now.Add(time.Hour))
+	if _, err := ConsumeVoucher(valid, peerID, now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConsumeVoucher(valid, otherID, now); err == nil {
+		t.Fatal("expected a voucher issued to another peer to be rejected")
+	}
+	if _, err := ConsumeVoucher(valid, peerID, now.Add(2*time.Hour)); !errors.Is(err, ErrVoucherExpired) {
+		t.Fatalf("expected an expired voucher error, got %v", err)
+	}
+	if _, err := ConsumeVoucher(voucher(otherPrivk, relayID, peerID, now.Add(time.Hour)), peerID, now); err == nil {
+		t.Fatal("expected a voucher not signed by the relay to be rejected")
+	}
+
+	relayAddr := ma.StringCast("/ip4/1.2.3.4/tcp/1/p2p/" + relayID.String() + "/p2p-circuit")
+	otherAddr := ma.StringCast("/ip4/1.2.3.5/tcp/1/p2p/" + otherID.String() + "/p2p-circuit")
+	rec := &peer.PeerRecord{
+		PeerID: peerID,
+		Addrs:  []ma.Multiaddr{ma.StringCast("/ip4/1.2.3.6/tcp/1"), relayAddr, otherAddr},
+		Vouchers: [][]byte{
+			valid,
+			// signed by the relay, but for another relay
+			voucher(relayPrivk, otherID, peerID, now.Add(time.Hour)),
+		},
+	}
+	addrs := VouchedRelayAddrs(rec, now)
+	if len(addrs) != 1 || !addrs[0].Equal(relayAddr) {
+		t.Fatalf("expected only %s to be vouched for, got %s", relayAddr, addrs)
+	}
+	if addrs := VouchedRelayAddrs(rec, now.Add(2*time.Hour)); len(addrs) != 0 {
+		t.Fatalf("expected no addresses to be vouched for after expiration, got %s", addrs)
+	}
+}
@@ -2,6 +2,7 @@ package relay
 
 import (
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	pbv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
 
 	ma "github.com/multiformats/go-multiaddr"
 )
@@ -15,3 +16,105 @@ type ACLFilter interface {
 	// to a destination peer.
 	AllowConnect(src peer.ID, srcAddr ma.Multiaddr, dest peer.ID) bool
 }
+
+// allowReserve checks the relay's ACLFilter for a reservation, and records denials.
+func (r *Relay) allowReserve(p peer.ID, a ma.Multiaddr, token []byte) bool {
+	if r.acl == nil {
+		return true
+	}
+	var ok bool
+	if tacl, isToken := r.acl.(TokenACLFilter); isToken {
+		ok = tacl.AllowReserveWithToken(p, a, token)
+	} else {
+		ok = r.acl.AllowReserve(p, a)
+	}
+	if !ok && r.metricsTracer != nil {
+		r.metricsTracer.ACLDenied(pbv2.HopMessage_RESERVE)
+	}
+	return ok
+}
+
+// allowConnect checks the relay's ACLFilter for a relayed connect, and records denials.
+func (r *Relay) allowConnect(src peer.ID, srcAddr ma.Multiaddr, dest peer.ID, token []byte) bool {
+	if r.acl == nil {
+		return true
+	}
+	var ok bool
+	if tacl, isToken := r.acl.(TokenACLFilter); isToken {
+		ok = tacl.AllowConnectWithToken(src, srcAddr, dest, token)
+	} else {
+		ok = r.acl.AllowConnect(src, srcAddr, dest)
+	}
+	if !ok && r.metricsTracer != nil {
+		r.metricsTracer.ACLDenied(pbv2.HopMessage_CONNECT)
+	}
+	return ok
+}
+
+// TokenACLFilter is an ACLFilter that also authorizes peers by the access token they
+// present in the RESERVE and CONNECT messages. If the relay's ACLFilter implements it,
+// the relay calls the token methods instead of the ACLFilter methods.
+type TokenACLFilter interface {
+	ACLFilter
+	// AllowReserveWithToken returns true if a reservation from a peer with the given
+	// peer ID, multiaddr and token is allowed. token is nil if the peer didn't present
+	// one.
+	AllowReserveWithToken(p peer.ID, a ma.Multiaddr, token []byte) bool
+	// AllowConnectWithToken returns true if a source peer, with a given multiaddr and
+	// token, is allowed to connect to a destination peer. token is nil if the source
+	// peer didn't present one.
+	AllowConnectWithToken(src peer.ID, srcAddr ma.Multiaddr, dest peer.ID, token []byte) bool
+}
+
+// AccessControl is a TokenACLFilter that restricts reservations and relayed connects
+// to peers in an allowlist, peers presenting a valid token, or peers matching a
+// predicate. A peer is allowed if any of the configured checks allows it, and denied
+// if none is configured.
+//
+// Relayed connects are authorized by their source peer. The destination peer always
+// has a reservation, so it was authorized when reserving.
+type AccessControl struct {
+	// Peers is the allowlist of peer IDs.
+	Peers map[peer.ID]struct{}
+	// VerifyToken returns true if token is a valid access token for p.
+	VerifyToken func(p peer.ID, token []byte) bool
+	// Allow returns true if the peer with the given peer ID and multiaddr is allowed.
+	Allow func(p peer.ID, a ma.Multiaddr) bool
+}
+
+var _ TokenACLFilter = &AccessControl{}
+
+// NewAllowlistACL returns an AccessControl allowing the given peers.
+func NewAllowlistACL(peers ...peer.ID) *AccessControl {
+	acl := &AccessControl{Peers: make(map[peer.ID]struct{}, len(peers))}
+	for _, p := range peers {
+		acl.Peers[p] = struct{}{}
+	}
+	return acl
+}
+
+func (acl *AccessControl) allow(p peer.ID, a ma.Multiaddr, token []byte) bool {
+	if _, ok := acl.Peers[p]; ok {
+		return true
+	}
+	if acl.VerifyToken != nil && token != nil && acl.VerifyToken(p, token) {
+		return true
+	}
+	return acl.Allow != nil && acl.Allow(p, a)
+}
+
+func (acl *AccessControl) AllowReserve(p peer.ID, a ma.Multiaddr) bool {
+	return acl.allow(p, a, nil)
+}
+
+func (acl *AccessControl) AllowConnect(src peer.ID, srcAddr ma.Multiaddr, _ peer.ID) bool {
+	return acl.allow(src, srcAddr, nil)
+}
+
+func (acl *AccessControl) AllowReserveWithToken(p peer.ID, a ma.Multiaddr, token []byte) bool {
+	return acl.allow(p, a, token)
+}
+
+func (acl *AccessControl) AllowConnectWithToken(src peer.ID, srcAddr ma.Multiaddr, _ peer.ID, token []byte) bool {
+	return acl.allow(src, srcAddr, token)
+}
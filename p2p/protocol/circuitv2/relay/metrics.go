@@ -68,6 +68,14 @@ var (
 		},
 		[]string{"reason"},
 	)
+	aclDenialsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "acl_denials_total",
+			Help:      "Requests Denied by the ACL",
+		},
+		[]string{"type"},
+	)
 	connectionDurationSeconds = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: metricNamespace,
@@ -92,6 +100,7 @@ var (
 		connectionsTotal,
 		connectionRequestResponseStatusTotal,
 		connectionRejectionsTotal,
+		aclDenialsTotal,
 		connectionDurationSeconds,
 		dataTransferredBytesTotal,
 	}
@@ -122,6 +131,9 @@ type MetricsTracer interface {
 	// ReservationRequestHandled tracks metrics on handling a relay reservation request
 	ReservationRequestHandled(status pbv2.Status)
 
+	// ACLDenied tracks a reservation or connection request denied by the ACLFilter
+	ACLDenied(reqType pbv2.HopMessage_Type)
+
 	// BytesTransferred tracks the total bytes transferred by the relay service
 	BytesTransferred(cnt int)
 }
@@ -228,6 +240,18 @@ func (mt *metricsTracer) ReservationRequestHandled(status pbv2.Status) {
 	}
 }
 
+func (mt *metricsTracer) ACLDenied(reqType pbv2.HopMessage_Type) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+	if reqType == pbv2.HopMessage_RESERVE {
+		*tags = append(*tags, "reservation")
+	} else {
+		*tags = append(*tags, "connection")
+	}
+
+	aclDenialsTotal.WithLabelValues(*tags...).Add(1)
+}
+
 func (mt *metricsTracer) BytesTransferred(cnt int) {
 	dataTransferredBytesTotal.Add(float64(cnt))
 }
@@ -26,6 +26,7 @@ func TestNoCoverNoAlloc(t *testing.T) {
 		"ReservationAllowed":        func() { mt.ReservationAllowed(rand.Intn(2) == 1) },
 		"ReservationClosed":         func() { mt.ReservationClosed(rand.Intn(10)) },
 		"ReservationRequestHandled": func() { mt.ReservationRequestHandled(statuses[rand.Intn(len(statuses))]) },
+		"ACLDenied":                 func() { mt.ACLDenied(pbv2.HopMessage_Type(rand.Intn(2))) },
 		"BytesTransferred":          func() { mt.BytesTransferred(rand.Intn(1000)) },
 	}
 	for method, f := range tests {
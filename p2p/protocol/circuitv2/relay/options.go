@@ -1,5 +1,7 @@
 package relay
 
+import "github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
+
 type Option func(*Relay) error
 
 // WithResources is a Relay option that sets specific relay resources for the relay.
@@ -34,6 +36,15 @@ func WithACL(acl ACLFilter) Option {
 	}
 }
 
+// WithReputationReporter reports peers that send malformed messages to the reputation
+// registry.
+func WithReputationReporter(rep reputation.Reporter) Option {
+	return func(r *Relay) error {
+		r.reputation = rep
+		return nil
+	}
+}
+
 // WithMetricsTracer is a Relay option that supplies a MetricsTracer for metrics
 func WithMetricsTracer(mt MetricsTracer) Option {
 	return func(r *Relay) error {
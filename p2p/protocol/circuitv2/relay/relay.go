@@ -14,6 +14,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/record"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
 	pbv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/proto"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/util"
@@ -37,6 +38,10 @@ const (
 	relayHopTagValue = 2
 
 	maxMessageSize = 4096
+
+	// malformedMessagePenalty is reported to the reputation registry for the peers
+	// sending malformed messages.
+	malformedMessagePenalty = -5
 )
 
 var log = logging.Logger("relay")
@@ -61,6 +66,7 @@ type Relay struct {
 	selfAddr ma.Multiaddr
 
 	metricsTracer MetricsTracer
+	reputation    reputation.Reporter
 }
 
 // New constructs a new limited relay that can provide relay services in the given host.
@@ -162,7 +168,7 @@ func (r *Relay) handleStream(s network.Stream) {
 	s.SetReadDeadline(time.Time{})
 	switch msg.GetType() {
 	case pbv2.HopMessage_RESERVE:
-		status := r.handleReserve(s)
+		status := r.handleReserve(s, &msg)
 		if r.metricsTracer != nil {
 			r.metricsTracer.ReservationRequestHandled(status)
 		}
@@ -172,11 +178,20 @@ func (r *Relay) handleStream(s network.Stream) {
 			r.metricsTracer.ConnectionRequestHandled(status)
 		}
 	default:
+		r.reportMisbehavior(s.Conn().RemotePeer(), malformedMessagePenalty)
 		r.handleError(s, pbv2.Status_MALFORMED_MESSAGE)
 	}
 }
 
-func (r *Relay) handleReserve(s network.Stream) pbv2.Status {
+// reportMisbehavior reports a negative observation about the peer to the reputation
+// registry, if any.
+func (r *Relay) reportMisbehavior(p peer.ID, penalty float64) {
+	if r.reputation != nil {
+		r.reputation.Report(p, ServiceName, penalty)
+	}
+}
+
+func (r *Relay) handleReserve(s network.Stream, msg *pbv2.HopMessage) pbv2.Status {
 	defer s.Close()
 	p := s.Conn().RemotePeer()
 	a := s.Conn().RemoteMultiaddr()
@@ -187,7 +202,7 @@ func (r *Relay) handleReserve(s network.Stream) pbv2.Status {
 		return pbv2.Status_PERMISSION_DENIED
 	}
 
-	if r.acl != nil && !r.acl.AllowReserve(p, a) {
+	if !r.allowReserve(p, a, msg.GetToken()) {
 		log.Debugf("refusing relay reservation for %s; permission denied", p)
 		r.handleError(s, pbv2.Status_PERMISSION_DENIED)
 		return pbv2.Status_PERMISSION_DENIED
@@ -270,11 +285,12 @@ func (r *Relay) handleConnect(s network.Stream, msg *pbv2.HopMessage) pbv2.Statu
 
 	dest, err := util.PeerToPeerInfoV2(msg.GetPeer())
 	if err != nil {
+		r.reportMisbehavior(src, malformedMessagePenalty)
 		fail(pbv2.Status_MALFORMED_MESSAGE)
 		return pbv2.Status_MALFORMED_MESSAGE
 	}
 
-	if r.acl != nil && !r.acl.AllowConnect(src, s.Conn().RemoteMultiaddr(), dest.ID) {
+	if !r.allowConnect(src, s.Conn().RemoteMultiaddr(), dest.ID, msg.GetToken()) {
 		log.Debugf("refusing connection from %s to %s; permission denied", src, dest.ID)
 		fail(pbv2.Status_PERMISSION_DENIED)
 		return pbv2.Status_PERMISSION_DENIED
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"testing"
 	"time"
 
@@ -23,10 +24,13 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/client"
+	pbv2 "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/proto"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/relay"
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcp"
 	"github.com/stretchr/testify/require"
 
+	"github.com/libp2p/go-msgio/pbio"
 	ma "github.com/multiformats/go-multiaddr"
 )
 
@@ -382,3 +386,95 @@ func TestRelayLimitData(t *testing.T) {
 	}
 
 }
+
+func TestRelayAccessControl(t *testing.T) {
+	ctx := context.Background()
+	hosts, upgraders := getNetHosts(t, ctx, 4)
+	for i, h := range hosts {
+		defer h.Close()
+		if i != 1 {
+			addTransport(t, h, upgraders[i])
+		}
+	}
+
+	acl := relay.NewAllowlistACL(hosts[0].ID())
+	acl.VerifyToken = func(_ peer.ID, token []byte) bool { return string(token) == "secret" }
+	r, err := relay.New(hosts[1], relay.WithACL(acl))
+	require.NoError(t, err)
+	defer r.Close()
+
+	for _, i := range []int{0, 2, 3} {
+		connect(t, hosts[1], hosts[i])
+	}
+	rinfo := hosts[1].Peerstore().PeerInfo(hosts[1].ID())
+
+	// allowlisted
+	_, err = client.Reserve(ctx, hosts[0], rinfo)
+	require.NoError(t, err)
+
+	// no token
+	_, err = client.Reserve(ctx, hosts[3], rinfo)
+	var rerr client.ReservationError
+	require.ErrorAs(t, err, &rerr)
+	require.Equal(t, pbv2.Status_PERMISSION_DENIED, rerr.Status)
+	// invalid token
+	_, err = client.Reserve(client.WithACLToken(ctx, []byte("wrong")), hosts[3], rinfo)
+	require.ErrorAs(t, err, &rerr)
+	require.Equal(t, pbv2.Status_PERMISSION_DENIED, rerr.Status)
+	// valid token
+	_, err = client.Reserve(client.WithACLToken(ctx, []byte("secret")), hosts[3], rinfo)
+	require.NoError(t, err)
+
+	raddr := ma.StringCast(fmt.Sprintf("/p2p/%s/p2p-circuit/p2p/%s", hosts[1].ID(), hosts[0].ID()))
+	require.Error(t, hosts[2].Connect(ctx, peer.AddrInfo{ID: hosts[0].ID(), Addrs: []ma.Multiaddr{raddr}}))
+
+	require.NoError(t, client.SetACLToken(hosts[2], hosts[1].ID(), []byte("secret")))
+	hosts[2].Network().(*swarm.Swarm).Backoff().Clear(hosts[0].ID())
+	require.NoError(t, hosts[2].Connect(ctx, peer.AddrInfo{ID: hosts[0].ID(), Addrs: []ma.Multiaddr{raddr}}))
+}
+
+type recordingReporter struct {
+	mx      sync.Mutex
+	reports map[peer.ID]float64
+}
+
+func (r *recordingReporter) Report(p peer.ID, service string, delta float64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if service == relay.ServiceName {
+		r.reports[p] += delta
+	}
+}
+
+func (r *recordingReporter) score(p peer.ID) float64 {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return r.reports[p]
+}
+
+func TestRelayReportsMalformedMessage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	hosts, _ := getNetHosts(t, ctx, 2)
+
+	rep := &recordingReporter{reports: make(map[peer.ID]float64)}
+	r, err := relay.New(hosts[1], relay.WithReputationReporter(rep))
+	require.NoError(t, err)
+	defer r.Close()
+
+	connect(t, hosts[0], hosts[1])
+
+	s, err := hosts[0].NewStream(ctx, hosts[1].ID(), proto.ProtoIDv2Hop)
+	require.NoError(t, err)
+	defer s.Close()
+
+	wr := pbio.NewDelimitedWriter(s)
+	require.NoError(t, wr.WriteMsg(&pbv2.HopMessage{Type: pbv2.HopMessage_STATUS.Enum()}))
+
+	var resp pbv2.HopMessage
+	rd := pbio.NewDelimitedReader(s, 4096)
+	require.NoError(t, rd.ReadMsg(&resp))
+	require.Equal(t, pbv2.Status_MALFORMED_MESSAGE, resp.GetStatus())
+	require.Negative(t, rep.score(hosts[0].ID()))
+}
@@ -303,6 +303,8 @@ func TestEndToEndSimConnect(t *testing.T) {
 			require.Equal(t, holepunch.StartHolePunchEvtT, h2Events[1].Type)
 			require.Equal(t, holepunch.HolePunchAttemptEvtT, h2Events[2].Type)
 			require.Equal(t, holepunch.EndHolePunchEvtT, h2Events[3].Type)
+			require.Equal(t, holepunch.MethodQUIC, h2Events[1].Evt.(*holepunch.StartHolePunchEvt).Method)
+			require.Equal(t, holepunch.MethodQUIC, h2Events[3].Evt.(*holepunch.EndHolePunchEvt).Method)
 
 			h1Events := h1tr.getEvents()
 			// We don't really expect a hole-punched connection to be established in this test,
@@ -445,12 +447,15 @@ func TestFailuresOnResponder(t *testing.T) {
 		errMsg           string
 		holePunchTimeout time.Duration
 		filter           func(remoteID peer.ID, maddrs []ma.Multiaddr) []ma.Multiaddr
+		// penalized is whether the initiator is reported to the reputation registry
+		penalized bool
 	}{
 		"initiator does NOT send a CONNECT message": {
 			initiator: func(s network.Stream) {
 				pbio.NewDelimitedWriter(s).WriteMsg(&holepunch_pb.HolePunch{Type: holepunch_pb.HolePunch_SYNC.Enum()})
 			},
-			errMsg: "expected CONNECT message",
+			errMsg:    "expected CONNECT message",
+			penalized: true,
 		},
 		"initiator does NOT send a SYNC message after a CONNECT message": {
 			initiator: func(s network.Stream) {
@@ -461,7 +466,8 @@ func TestFailuresOnResponder(t *testing.T) {
 				})
 				w.WriteMsg(&holepunch_pb.HolePunch{Type: holepunch_pb.HolePunch_CONNECT.Enum()})
 			},
-			errMsg: "expected SYNC message",
+			errMsg:    "expected SYNC message",
+			penalized: true,
 		},
 		"initiator does NOT reply within hole punch deadline": {
 			holePunchTimeout: 10 * time.Millisecond,
@@ -505,8 +511,13 @@ func TestFailuresOnResponder(t *testing.T) {
 				defer func() { holepunch.StreamTimeout = cpy }()
 			}
 			tr := &mockEventTracer{}
+			rep := &recordingReporter{}
 
-			opts := []holepunch.Option{holepunch.WithTracer(tr), holepunch.DirectDialTimeout(100 * time.Millisecond)}
+			opts := []holepunch.Option{
+				holepunch.WithTracer(tr),
+				holepunch.DirectDialTimeout(100 * time.Millisecond),
+				holepunch.WithReputationReporter(rep),
+			}
 			if tc.filter != nil {
 				f := mockMaddrFilter{
 					filterLocal:  tc.filter,
@@ -578,10 +589,34 @@ func TestFailuresOnResponder(t *testing.T) {
 			errs := getTracerError(tr)
 			require.Len(t, errs, 1)
 			require.Contains(t, errs[0], tc.errMsg)
+			if tc.penalized {
+				require.Equal(t, []peer.ID{h2.ID()}, rep.get())
+			} else {
+				require.Empty(t, rep.get())
+			}
 		})
 	}
 }
 
+type recordingReporter struct {
+	mx    sync.Mutex
+	peers []peer.ID
+}
+
+func (r *recordingReporter) Report(p peer.ID, service string, delta float64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	if service == holepunch.ServiceName && delta < 0 {
+		r.peers = append(r.peers, p)
+	}
+}
+
+func (r *recordingReporter) get() []peer.ID {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return slices.Clone(r.peers)
+}
+
 func ensureNoHolePunchingStream(t *testing.T, h1, h2 host.Host) {
 	require.Eventually(t, func() bool {
 		for _, c := range h1.Network().ConnsToPeer(h2.ID()) {
@@ -12,6 +12,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/holepunch/pb"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
+	"github.com/benbjohnson/clock"
 	"github.com/libp2p/go-msgio/pbio"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
@@ -49,6 +50,8 @@ type holePuncher struct {
 	tracer *tracer
 	filter AddrFilter
 
+	clock clock.Clock
+
 	// Prior to https://github.com/TheNoobiCat/go-libp2p/pull/3044, go-libp2p would
 	// pick the opposite roles for client/server a hole punch. Setting this to
 	// true preserves that behavior
@@ -65,6 +68,7 @@ func newHolePuncher(h host.Host, ids identify.IDService, listenAddrs func() []ma
 		listenAddrs: listenAddrs,
 
 		legacyBehavior: true,
+		clock:          clock.New(),
 	}
 	hp.ctx, hp.ctxCancel = context.WithCancel(context.Background())
 	h.Network().Notify((*netNotifiee)(hp))
@@ -146,19 +150,20 @@ func (hp *holePuncher) directConnect(rp peer.ID) error {
 			hp.tracer.ProtocolError(rp, err)
 			return err
 		}
+		method, dialAddrs := holePunchAddrs(addrs, obsAddrs)
 		synTime := rtt / 2
-		log.Debugf("peer RTT is %s; starting hole punch in %s", rtt, synTime)
+		log.Debugf("peer RTT is %s; starting %s hole punch in %s", rtt, method, synTime)
 
 		// wait for sync to reach the other peer and then punch a hole for it in our NAT
 		// by attempting a connect to it.
-		timer := time.NewTimer(synTime)
+		timer := hp.clock.Timer(synTime)
 		select {
 		case start := <-timer.C:
 			pi := peer.AddrInfo{
 				ID:    rp,
-				Addrs: addrs,
+				Addrs: dialAddrs,
 			}
-			hp.tracer.StartHolePunch(rp, addrs, rtt)
+			hp.tracer.StartHolePunch(rp, dialAddrs, rtt, method)
 			hp.tracer.HolePunchAttempt(pi.ID)
 			ctx, cancel := context.WithTimeout(hp.ctx, hp.directDialTimeout)
 			isClient := true
@@ -168,10 +173,14 @@ func (hp *holePuncher) directConnect(rp peer.ID) error {
 			err := holePunchConnect(ctx, hp.host, pi, isClient)
 			cancel()
 			dt := time.Since(start)
-			hp.tracer.EndHolePunch(rp, dt, err)
+			directConn := getDirectConnection(hp.host, rp)
+			if err == nil && directConn != nil {
+				method = connMethod(directConn)
+			}
+			hp.tracer.EndHolePunch(rp, dt, err, method)
 			if err == nil {
-				log.Debugw("hole punching with successful", "peer", rp, "time", dt)
-				hp.tracer.HolePunchFinished("initiator", i, addrs, obsAddrs, getDirectConnection(hp.host, rp))
+				log.Debugw("hole punching with successful", "peer", rp, "time", dt, "method", method)
+				hp.tracer.HolePunchFinished("initiator", i, addrs, obsAddrs, directConn)
 				return nil
 			}
 		case <-hp.ctx.Done():
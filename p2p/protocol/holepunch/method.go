@@ -0,0 +1,82 @@
+package holepunch
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Hole punching methods, reported in StartHolePunchEvt and EndHolePunchEvt.
+const (
+	// MethodQUIC punches a hole by dialing QUIC addresses from the listening socket.
+	MethodQUIC = "quic"
+	// MethodTCP punches a hole with a TCP simultaneous open: both peers send a SYN
+	// from their listening port at the time coordinated by the SYNC message.
+	MethodTCP = "tcp"
+)
+
+// addrMethod returns the hole punching method a can be used with, or "" if a can't be
+// hole punched.
+func addrMethod(a ma.Multiaddr) string {
+	var tcp, udp, quic bool
+	for _, c := range a {
+		switch c.Code() {
+		case ma.P_TCP:
+			tcp = true
+		case ma.P_UDP:
+			udp = true
+		case ma.P_QUIC_V1:
+			quic = true
+		case ma.P_WS, ma.P_WSS, ma.P_TLS, ma.P_CIRCUIT:
+			return ""
+		}
+	}
+	switch {
+	case udp && quic:
+		return MethodQUIC
+	case tcp:
+		return MethodTCP
+	default:
+		return ""
+	}
+}
+
+// connMethod returns the hole punching method c was established with.
+func connMethod(c network.Conn) string {
+	return addrMethod(c.RemoteMultiaddr())
+}
+
+// holePunchAddrs selects the hole punching method for the remote addresses, given the
+// addresses we sent to the remote peer, and returns the remote addresses to dial.
+// QUIC is preferred, since UDP hole punching succeeds more often. If the peers only
+// share TCP addresses, only the TCP addresses are dialed, so that the SYN is sent at
+// the time coordinated over the relayed connection, instead of being delayed by the
+// dial ranking.
+func holePunchAddrs(remote, local []ma.Multiaddr) (string, []ma.Multiaddr) {
+	var localQUIC, localTCP, remoteQUIC bool
+	for _, a := range local {
+		switch addrMethod(a) {
+		case MethodQUIC:
+			localQUIC = true
+		case MethodTCP:
+			localTCP = true
+		}
+	}
+	var tcpAddrs []ma.Multiaddr
+	for _, a := range remote {
+		switch addrMethod(a) {
+		case MethodQUIC:
+			remoteQUIC = true
+		case MethodTCP:
+			tcpAddrs = append(tcpAddrs, a)
+		}
+	}
+	switch {
+	case localQUIC && remoteQUIC:
+		return MethodQUIC, remote
+	case localTCP && len(tcpAddrs) > 0:
+		return MethodTCP, tcpAddrs
+	default:
+		return "", remote
+	}
+}
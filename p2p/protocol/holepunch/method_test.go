@@ -0,0 +1,34 @@
+package holepunch
+
+import (
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHolePunchAddrs(t *testing.T) {
+	quicAddr := ma.StringCast("/ip4/1.2.3.4/udp/4001/quic-v1")
+	tcpAddr := ma.StringCast("/ip4/1.2.3.4/tcp/4001")
+	wsAddr := ma.StringCast("/ip4/1.2.3.4/tcp/4002/ws")
+	localQUIC := ma.StringCast("/ip4/5.6.7.8/udp/4001/quic-v1")
+	localTCP := ma.StringCast("/ip4/5.6.7.8/tcp/4001")
+
+	for _, tc := range []struct {
+		name          string
+		remote, local []ma.Multiaddr
+		method        string
+		addrs         []ma.Multiaddr
+	}{
+		{"quic", []ma.Multiaddr{quicAddr, tcpAddr}, []ma.Multiaddr{localQUIC, localTCP}, MethodQUIC, []ma.Multiaddr{quicAddr, tcpAddr}},
+		{"tcp only remote", []ma.Multiaddr{tcpAddr, wsAddr}, []ma.Multiaddr{localQUIC, localTCP}, MethodTCP, []ma.Multiaddr{tcpAddr}},
+		{"tcp only local", []ma.Multiaddr{quicAddr, tcpAddr}, []ma.Multiaddr{localTCP}, MethodTCP, []ma.Multiaddr{tcpAddr}},
+		{"nothing shared", []ma.Multiaddr{quicAddr}, []ma.Multiaddr{localTCP}, "", []ma.Multiaddr{quicAddr}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			method, addrs := holePunchAddrs(tc.remote, tc.local)
+			require.Equal(t, tc.method, method)
+			require.Equal(t, tc.addrs, addrs)
+		})
+	}
+}
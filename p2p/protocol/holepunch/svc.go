@@ -12,8 +12,10 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/holepunch/pb"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
+	"github.com/benbjohnson/clock"
 	"github.com/libp2p/go-msgio/pbio"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -33,11 +35,19 @@ const (
 	ServiceName = "libp2p.holepunch"
 
 	maxMsgSize = 4 * 1024 // 4K
+
+	// protocolViolationPenalty is reported to the reputation registry for the peers
+	// violating the protocol.
+	protocolViolationPenalty = -5
 )
 
 // ErrClosed is returned when the hole punching is closed
 var ErrClosed = errors.New("hole punching service closing")
 
+// errProtocolViolation is wrapped by the errors of the hole punches the remote peer
+// didn't follow the protocol in.
+var errProtocolViolation = errors.New("protocol violation")
+
 type Option func(*Service) error
 
 func DirectDialTimeout(timeout time.Duration) Option {
@@ -47,6 +57,24 @@ func DirectDialTimeout(timeout time.Duration) Option {
 	}
 }
 
+// WithClock sets the clock used to schedule the hole punch attempts and the checks
+// for a public address, e.g. a mock clock in tests.
+func WithClock(cl clock.Clock) Option {
+	return func(s *Service) error {
+		s.clock = cl
+		return nil
+	}
+}
+
+// WithReputationReporter reports peers that violate the protocol when initiating a
+// hole punch to the reputation registry.
+func WithReputationReporter(r reputation.Reporter) Option {
+	return func(s *Service) error {
+		s.reputation = r
+		return nil
+	}
+}
+
 // The Service runs on every node that supports the DCUtR protocol.
 type Service struct {
 	ctx       context.Context
@@ -72,6 +100,10 @@ type Service struct {
 
 	refCount sync.WaitGroup
 
+	clock clock.Clock
+
+	reputation reputation.Reporter
+
 	// Prior to https://github.com/TheNoobiCat/go-libp2p/pull/3044, go-libp2p would
 	// pick the opposite roles for client/server a hole punch. Setting this to
 	// true preserves that behavior
@@ -106,6 +138,7 @@ func NewService(h host.Host, ids identify.IDService, listenAddrs func() []ma.Mul
 		hasPublicAddrsChan: make(chan struct{}),
 		directDialTimeout:  defaultDirectDialTimeout,
 		legacyBehavior:     true,
+		clock:              clock.New(),
 	}
 
 	for _, opt := range opts {
@@ -133,7 +166,7 @@ func (s *Service) waitForPublicAddr() {
 	// regularly (exponential backoff starting at 250 ms, capped at 5s).
 	duration := 250 * time.Millisecond
 	const maxDuration = 5 * time.Second
-	t := time.NewTimer(duration)
+	t := s.clock.Timer(duration)
 	defer t.Stop()
 	for {
 		if len(s.listenAddrs()) > 0 {
@@ -162,6 +195,7 @@ func (s *Service) waitForPublicAddr() {
 	s.holePuncher = newHolePuncher(s.host, s.ids, s.listenAddrs, s.tracer, s.filter)
 	s.holePuncher.directDialTimeout = s.directDialTimeout
 	s.holePuncher.legacyBehavior = s.legacyBehavior
+	s.holePuncher.clock = s.clock
 	s.holePuncherMx.Unlock()
 	close(s.hasPublicAddrsChan)
 }
@@ -184,7 +218,7 @@ func (s *Service) Close() error {
 func (s *Service) incomingHolePunch(str network.Stream) (rtt time.Duration, remoteAddrs []ma.Multiaddr, ownAddrs []ma.Multiaddr, err error) {
 	// sanity check: a hole punch request should only come from peers behind a relay
 	if !isRelayAddress(str.Conn().RemoteMultiaddr()) {
-		return 0, nil, nil, fmt.Errorf("received hole punch stream: %s", str.Conn().RemoteMultiaddr())
+		return 0, nil, nil, fmt.Errorf("%w: received hole punch stream: %s", errProtocolViolation, str.Conn().RemoteMultiaddr())
 	}
 	ownAddrs = s.listenAddrs()
 	if s.filter != nil {
@@ -214,7 +248,7 @@ func (s *Service) incomingHolePunch(str network.Stream) (rtt time.Duration, remo
 		return 0, nil, nil, fmt.Errorf("failed to read message from initiator: %w", err)
 	}
 	if t := msg.GetType(); t != pb.HolePunch_CONNECT {
-		return 0, nil, nil, fmt.Errorf("expected CONNECT message from initiator but got %d", t)
+		return 0, nil, nil, fmt.Errorf("%w: expected CONNECT message from initiator but got %d", errProtocolViolation, t)
 	}
 
 	obsDial := removeRelayAddrs(addrsFromBytes(msg.ObsAddrs))
@@ -242,7 +276,7 @@ func (s *Service) incomingHolePunch(str network.Stream) (rtt time.Duration, remo
 		return 0, nil, nil, fmt.Errorf("failed to read message from initiator: %w", err)
 	}
 	if t := msg.GetType(); t != pb.HolePunch_SYNC {
-		return 0, nil, nil, fmt.Errorf("expected SYNC message from initiator but got %d", t)
+		return 0, nil, nil, fmt.Errorf("%w: expected SYNC message from initiator but got %d", errProtocolViolation, t)
 	}
 	return time.Since(tstart), obsDial, ownAddrs, nil
 }
@@ -267,6 +301,9 @@ func (s *Service) handleNewStream(str network.Stream) {
 	rp := str.Conn().RemotePeer()
 	rtt, addrs, ownAddrs, err := s.incomingHolePunch(str)
 	if err != nil {
+		if s.reputation != nil && errors.Is(err, errProtocolViolation) {
+			s.reputation.Report(rp, ServiceName, protocolViolationPenalty)
+		}
 		s.tracer.ProtocolError(rp, err)
 		log.Debugw("error handling holepunching stream from", "peer", rp, "error", err)
 		str.Reset()
@@ -275,12 +312,13 @@ func (s *Service) handleNewStream(str network.Stream) {
 	str.Close()
 
 	// Hole punch now by forcing a connect
+	method, dialAddrs := holePunchAddrs(addrs, ownAddrs)
 	pi := peer.AddrInfo{
 		ID:    rp,
-		Addrs: addrs,
+		Addrs: dialAddrs,
 	}
-	s.tracer.StartHolePunch(rp, addrs, rtt)
-	log.Debugw("starting hole punch", "peer", rp)
+	s.tracer.StartHolePunch(rp, dialAddrs, rtt, method)
+	log.Debugw("starting hole punch", "peer", rp, "method", method)
 	start := time.Now()
 	s.tracer.HolePunchAttempt(pi.ID)
 	ctx, cancel := context.WithTimeout(s.ctx, s.directDialTimeout)
@@ -291,8 +329,12 @@ func (s *Service) handleNewStream(str network.Stream) {
 	err = holePunchConnect(ctx, s.host, pi, isClient)
 	cancel()
 	dt := time.Since(start)
-	s.tracer.EndHolePunch(rp, dt, err)
-	s.tracer.HolePunchFinished("receiver", 1, addrs, ownAddrs, getDirectConnection(s.host, rp))
+	directConn := getDirectConnection(s.host, rp)
+	if err == nil && directConn != nil {
+		method = connMethod(directConn)
+	}
+	s.tracer.EndHolePunch(rp, dt, err, method)
+	s.tracer.HolePunchFinished("receiver", 1, addrs, ownAddrs, directConn)
 }
 
 // DirectConnect is only exposed for testing purposes.
@@ -108,12 +108,18 @@ type ProtocolErrorEvt struct {
 type StartHolePunchEvt struct {
 	RemoteAddrs []string
 	RTT         time.Duration
+	// Method is the hole punching method attempted, MethodQUIC or MethodTCP. It's empty
+	// if the peers don't share an address that can be hole punched.
+	Method string `json:",omitempty"`
 }
 
 type EndHolePunchEvt struct {
 	Success      bool
 	EllapsedTime time.Duration
 	Error        string `json:",omitempty"`
+	// Method is the hole punching method of the direct connection on success, and the
+	// method attempted otherwise.
+	Method string `json:",omitempty"`
 }
 
 type HolePunchAttemptEvt struct {
@@ -182,7 +188,7 @@ func (t *tracer) ProtocolError(p peer.ID, err error) {
 	}
 }
 
-func (t *tracer) StartHolePunch(p peer.ID, obsAddrs []ma.Multiaddr, rtt time.Duration) {
+func (t *tracer) StartHolePunch(p peer.ID, obsAddrs []ma.Multiaddr, rtt time.Duration, method string) {
 	if t != nil && t.et != nil {
 		addrs := make([]string, 0, len(obsAddrs))
 		for _, a := range obsAddrs {
@@ -197,16 +203,18 @@ func (t *tracer) StartHolePunch(p peer.ID, obsAddrs []ma.Multiaddr, rtt time.Dur
 			Evt: &StartHolePunchEvt{
 				RemoteAddrs: addrs,
 				RTT:         rtt,
+				Method:      method,
 			},
 		})
 	}
 }
 
-func (t *tracer) EndHolePunch(p peer.ID, dt time.Duration, err error) {
+func (t *tracer) EndHolePunch(p peer.ID, dt time.Duration, err error, method string) {
 	if t != nil && t.et != nil {
 		evt := &EndHolePunchEvt{
 			Success:      err == nil,
 			EllapsedTime: dt,
+			Method:       method,
 		}
 		if err != nil {
 			evt.Error = err.Error()
@@ -20,10 +20,13 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/protocol"
 	"github.com/TheNoobiCat/go-libp2p/core/record"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/addrscope"
 	useragent "github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify/internal/user-agent"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify/pb"
 	"github.com/TheNoobiCat/go-libp2p/x/rate"
 
+	"github.com/benbjohnson/clock"
 	logging "github.com/ipfs/go-log/v2"
 	"github.com/libp2p/go-msgio/pbio"
 	ma "github.com/multiformats/go-multiaddr"
@@ -50,11 +53,23 @@ const (
 	maxOwnIdentifyMsgSize = 4 * 1024 // smaller than what we accept. This is 4k to be compatible with rust-libp2p
 	maxMessages           = 10
 	maxPushConcurrency    = 32
+	// maxPushDebounceFactor bounds the delay of a debounced push to this many times
+	// the push debounce.
+	maxPushDebounceFactor = 4
 	// number of addresses to keep for peers we have disconnected from for peerstore.RecentlyConnectedTTL time
 	// This number can be small as we already filter peer addresses based on whether the peer is connected to us over
 	// localhost, private IP or public IP address
 	recentlyConnectedPeerMaxAddrs = 20
 	connectedPeerMaxAddrs         = 500
+	// maxConnObservedAddrs is the number of observed addresses kept per connection.
+	maxConnObservedAddrs = 8
+
+	// penalties reported to the reputation registry
+	invalidPeerRecordPenalty = -5
+	keyMismatchPenalty       = -20
+	// uncertifiedAddrsPenalty is reported for the peers sending unsigned addresses,
+	// with SignedRecordFlag.
+	uncertifiedAddrsPenalty = -1
 )
 
 var (
@@ -73,10 +88,16 @@ var (
 )
 
 type identifySnapshot struct {
-	seq       uint64
-	protocols []protocol.ID
-	addrs     []ma.Multiaddr
-	record    *record.Envelope
+	seq          uint64
+	protocols    []protocol.ID
+	addrs        []ma.Multiaddr
+	agentVersion string
+	record       *record.Envelope
+	// publicRecord is record restricted to the public addresses, sent to the peers
+	// outside of our subnets in LAN mode.
+	publicRecord *record.Envelope
+	// subnets are the subnets of our network interfaces, set in LAN mode.
+	subnets []netip.Prefix
 }
 
 // Equal says if two snapshots are identical.
@@ -93,6 +114,9 @@ func (s identifySnapshot) Equal(other *identifySnapshot) bool {
 	if !slices.Equal(s.protocols, other.protocols) {
 		return false
 	}
+	if s.agentVersion != other.agentVersion {
+		return false
+	}
 	if len(s.addrs) != len(other.addrs) {
 		return false
 	}
@@ -119,10 +143,53 @@ type IDService interface {
 	// ObservedAddrsFor returns the addresses peers have reported we've dialed from,
 	// for a specific local address.
 	ObservedAddrsFor(local ma.Multiaddr) []ma.Multiaddr
+	// OwnObservedAddrConfidence returns all addresses peers have reported we've
+	// dialed from, including the ones that weren't reported often enough to be
+	// used, along with the number of distinct observers.
+	OwnObservedAddrConfidence() []event.ObservedAddr
 	Start()
 	io.Closer
 }
 
+// ConnObservedAddrsReporter is implemented by IDServices that keep the addresses the
+// remote peers of the connections reported observing us at.
+type ConnObservedAddrsReporter interface {
+	// ConnObservedAddrs returns the addresses the remote peer of c reported
+	// observing us at, oldest first. The last one is the address reported most
+	// recently. It returns nil if c isn't identified yet, or is closed.
+	ConnObservedAddrs(c network.Conn) []ConnObservedAddr
+}
+
+// UserAgentSetter is implemented by IDServices whose user agent can be changed at
+// runtime.
+type UserAgentSetter interface {
+	// UserAgent returns the user agent this node identifies itself with.
+	UserAgent() string
+	// SetUserAgent changes the user agent this node identifies itself with, and
+	// pushes it to the connected peers.
+	SetUserAgent(ua string)
+}
+
+// PeerVersionsReporter is implemented by IDServices that track the agent and
+// protocol versions of the connected peers.
+type PeerVersionsReporter interface {
+	// AgentVersions returns the number of connected peers by agent version, as
+	// learned by identify.
+	AgentVersions() map[string]int
+	// ProtocolVersions returns the number of connected peers by protocol version, as
+	// learned by identify.
+	ProtocolVersions() map[string]int
+	// PeersByAgentVersion returns the connected peers by agent version, as learned by
+	// identify.
+	PeersByAgentVersion() map[string][]peer.ID
+}
+
+var (
+	_ PeerVersionsReporter      = (*idService)(nil)
+	_ UserAgentSetter           = (*idService)(nil)
+	_ ConnObservedAddrsReporter = (*idService)(nil)
+)
+
 type identifyPushSupport uint8
 
 const (
@@ -141,6 +208,20 @@ type entry struct {
 	PushSupport identifyPushSupport
 	// Sequence is the sequence number of the last snapshot we sent to this peer.
 	Sequence uint64
+	// ObservedAddrs are the addresses the peer reported observing us at on this
+	// connection, oldest first.
+	ObservedAddrs []ConnObservedAddr
+}
+
+// ConnObservedAddr is an address the remote peer of a connection reported observing
+// us at, in an identify or identify push message.
+type ConnObservedAddr struct {
+	Addr ma.Multiaddr
+	// FirstSeen is when the peer first reported the address. Consecutive reports of
+	// the same address only update LastSeen.
+	FirstSeen time.Time
+	// LastSeen is when the peer last reported the address.
+	LastSeen time.Time
 }
 
 // idService is a structure that implements ProtocolIdentify.
@@ -153,9 +234,15 @@ type entry struct {
 //   - Our public Listen Addresses
 type idService struct {
 	Host            host.Host
-	UserAgent       string
 	ProtocolVersion string
 
+	userAgentMu sync.Mutex
+	userAgent   string
+	// userAgentForConn overrides the user agent sent on a connection, if set.
+	userAgentForConn func(network.Conn) string
+	// userAgentUpdated triggers a snapshot update after the user agent changed.
+	userAgentUpdated chan struct{}
+
 	metricsTracer MetricsTracer
 
 	setupCompleted chan struct{} // is closed when Start has finished setting up
@@ -175,6 +262,8 @@ type idService struct {
 
 	addrMu sync.Mutex
 
+	peerVersions peerVersions
+
 	// our own observed addresses.
 	observedAddrMgr            *ObservedAddrManager
 	disableObservedAddrManager bool
@@ -183,6 +272,7 @@ type idService struct {
 		evtPeerProtocolsUpdated        event.Emitter
 		evtPeerIdentificationCompleted event.Emitter
 		evtPeerIdentificationFailed    event.Emitter
+		evtObservedAddrsChanged        event.Emitter
 	}
 
 	currentSnapshot struct {
@@ -192,7 +282,22 @@ type idService struct {
 
 	natEmitter *natEmitter
 
-	rateLimiter *rate.Limiter
+	rateLimiter     *rate.Limiter
+	peerRateLimiter *rate.PeerLimiter
+
+	pushDebounce     time.Duration
+	minPushInterval  time.Duration
+	peerPushInterval time.Duration
+
+	reputation reputation.Reporter
+
+	lazy bool
+
+	lanMode bool
+
+	signedRecordPolicy SignedRecordPolicy
+
+	clock clock.Clock
 }
 
 type normalizer interface {
@@ -204,6 +309,7 @@ type normalizer interface {
 func NewIDService(h host.Host, opts ...Option) (*idService, error) {
 	cfg := config{
 		timeout: DefaultTimeout,
+		clock:   clock.New(),
 	}
 	for _, opt := range opts {
 		opt(&cfg)
@@ -217,8 +323,10 @@ func NewIDService(h host.Host, opts ...Option) (*idService, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &idService{
 		Host:                    h,
-		UserAgent:               userAgent,
 		ProtocolVersion:         cfg.protocolVersion,
+		userAgent:               userAgent,
+		userAgentForConn:        cfg.userAgentForConn,
+		userAgentUpdated:        make(chan struct{}, 1),
 		ctx:                     ctx,
 		ctxCancel:               cancel,
 		conns:                   make(map[network.Conn]entry),
@@ -226,6 +334,15 @@ func NewIDService(h host.Host, opts ...Option) (*idService, error) {
 		setupCompleted:          make(chan struct{}),
 		metricsTracer:           cfg.metricsTracer,
 		timeout:                 cfg.timeout,
+		peerRateLimiter:         cfg.peerRateLimiter,
+		signedRecordPolicy:      cfg.signedRecordPolicy,
+		pushDebounce:            cfg.pushDebounce,
+		minPushInterval:         cfg.minPushInterval,
+		peerPushInterval:        cfg.peerPushInterval,
+		reputation:              cfg.reputation,
+		lazy:                    cfg.lazy,
+		lanMode:                 cfg.lanMode,
+		clock:                   cfg.clock,
 		rateLimiter: &rate.Limiter{
 			GlobalLimit:         defaultGlobalRateLimit,
 			NetworkPrefixLimits: defaultNetworkPrefixRateLimits,
@@ -271,18 +388,62 @@ func NewIDService(h host.Host, opts ...Option) (*idService, error) {
 	if err != nil {
 		log.Warnf("identify service not emitting identification failed events; err: %s", err)
 	}
+	if !s.disableObservedAddrManager {
+		s.emitters.evtObservedAddrsChanged, err = h.EventBus().Emitter(&event.EvtObservedAddrsChanged{}, eventbus.Stateful)
+		if err != nil {
+			log.Warnf("identify service not emitting observed address events; err: %s", err)
+		}
+	}
 	return s, nil
 }
 
 func (ids *idService) Start() {
 	ids.Host.Network().Notify((*netNotifiee)(ids))
-	ids.Host.SetStreamHandler(ID, ids.handleIdentifyRequest)
-	ids.Host.SetStreamHandler(IDPush, ids.rateLimiter.Limit(ids.handlePush))
+	handleRequest, handlePush := ids.handleIdentifyRequest, ids.rateLimiter.Limit(ids.handlePush)
+	if ids.peerRateLimiter != nil {
+		handleRequest = ids.peerRateLimiter.Limit(handleRequest)
+		handlePush = ids.peerRateLimiter.Limit(handlePush)
+	}
+	ids.Host.SetStreamHandler(ID, handleRequest)
+	ids.Host.SetStreamHandler(IDPush, handlePush)
 	ids.updateSnapshot()
 	close(ids.setupCompleted)
 
 	ids.refCount.Add(1)
 	go ids.loop(ids.ctx)
+
+	if ids.emitters.evtObservedAddrsChanged != nil {
+		ids.refCount.Add(1)
+		go ids.observedAddrsLoop(ids.ctx)
+	}
+}
+
+// observedAddrsLoop emits an EvtObservedAddrsChanged event when the observed
+// addresses change.
+func (ids *idService) observedAddrsLoop(ctx context.Context) {
+	defer ids.refCount.Done()
+
+	var last []event.ObservedAddr
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ids.observedAddrMgr.addrsChangedNotif:
+		}
+		addrs := ids.observedAddrMgr.ObservedAddrs()
+		// Timestamps change on every observation. Only emit an event when the
+		// addresses or their observers change.
+		if slices.EqualFunc(last, addrs, func(a, b event.ObservedAddr) bool {
+			return a.Addr.Equal(b.Addr) && a.LocalAddr.Equal(b.LocalAddr) &&
+				a.Observers == b.Observers && a.Activated == b.Activated
+		}) {
+			continue
+		}
+		last = addrs
+		if err := ids.emitters.evtObservedAddrsChanged.Emit(event.EvtObservedAddrsChanged{Addrs: addrs}); err != nil {
+			log.Warnf("failed to emit observed addresses event: %s", err)
+		}
+	}
 }
 
 func (ids *idService) loop(ctx context.Context) {
@@ -308,39 +469,148 @@ func (ids *idService) loop(ctx context.Context) {
 	go func() {
 		defer ids.refCount.Done()
 
+		var lastPush time.Time
+		// the time of the last push to each peer, if a per peer push interval is set
+		lastPeerPush := make(map[peer.ID]time.Time)
+		// fires when the pushes deferred by the per peer push interval are due
+		var retry *clock.Timer
+		var retryC <-chan time.Time
+		defer func() {
+			if retry != nil {
+				retry.Stop()
+			}
+		}()
 		for {
 			select {
 			case <-ctx.Done():
 				return
 			case <-triggerPush:
-				ids.sendPushes(ctx)
+				if !ids.delayPush(ctx, triggerPush, lastPush) {
+					return
+				}
+			case <-retryC:
+			}
+			retryAt := ids.sendPushes(ctx, lastPeerPush)
+			lastPush = ids.clock.Now()
+			retryC = nil
+			if !retryAt.IsZero() {
+				// deferred pushes are subject to the minimum push interval too
+				if next := lastPush.Add(ids.minPushInterval); retryAt.Before(next) {
+					retryAt = next
+				}
+				if retry == nil {
+					retry = ids.clock.Timer(ids.clock.Until(retryAt))
+				} else {
+					retry.Reset(ids.clock.Until(retryAt))
+				}
+				retryC = retry.C
 			}
 		}
 	}()
 
 	for {
+		var e any
 		select {
-		case e, ok := <-sub.Out():
+		case ev, ok := <-sub.Out():
 			if !ok {
 				return
 			}
-			if updated := ids.updateSnapshot(); !updated {
-				continue
-			}
-			if ids.metricsTracer != nil {
-				ids.metricsTracer.TriggeredPushes(e)
-			}
+			e = ev
+		case <-ids.userAgentUpdated:
+			e = userAgentUpdated{}
+		case <-ctx.Done():
+			return
+		}
+		if updated := ids.updateSnapshot(); !updated {
+			continue
+		}
+		if ids.metricsTracer != nil {
+			ids.metricsTracer.TriggeredPushes(e)
+		}
+		select {
+		case triggerPush <- struct{}{}:
+		default: // we already have one more push queued, no need to queue another one
+		}
+	}
+}
+
+// userAgentUpdated is passed to the metrics tracer for the pushes triggered by
+// SetUserAgent.
+type userAgentUpdated struct{}
+
+// UserAgent returns the user agent this node identifies itself with.
+func (ids *idService) UserAgent() string {
+	ids.userAgentMu.Lock()
+	defer ids.userAgentMu.Unlock()
+	return ids.userAgent
+}
+
+// SetUserAgent changes the user agent this node identifies itself with, e.g. to
+// reflect the state of the application. The new user agent is pushed to the
+// connected peers. An empty user agent resets it to the default one.
+func (ids *idService) SetUserAgent(ua string) {
+	if ua == "" {
+		ua = useragent.DefaultUserAgent()
+	}
+	ids.userAgentMu.Lock()
+	ids.userAgent = ua
+	ids.userAgentMu.Unlock()
+	select {
+	case ids.userAgentUpdated <- struct{}{}:
+	default: // an update is already queued
+	}
+}
+
+// delayPush waits until the next push can be sent: until no further push was triggered
+// for the push debounce, and until the minimum push interval has passed since lastPush.
+// It returns false if ctx is done.
+func (ids *idService) delayPush(ctx context.Context, triggerPush <-chan struct{}, lastPush time.Time) bool {
+	if ids.pushDebounce > 0 {
+		quiet := ids.clock.Timer(ids.pushDebounce)
+		defer quiet.Stop()
+		deadline := ids.clock.Timer(maxPushDebounceFactor * ids.pushDebounce)
+		defer deadline.Stop()
+	debounce:
+		for {
 			select {
-			case triggerPush <- struct{}{}:
-			default: // we already have one more push queued, no need to queue another one
+			case <-triggerPush:
+				quiet.Reset(ids.pushDebounce)
+			case <-quiet.C:
+				break debounce
+			case <-deadline.C:
+				break debounce
+			case <-ctx.Done():
+				return false
 			}
+		}
+	}
+	if wait := ids.clock.Until(lastPush.Add(ids.minPushInterval)); ids.minPushInterval > 0 && wait > 0 {
+		t := ids.clock.Timer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
 		case <-ctx.Done():
-			return
+			return false
 		}
 	}
+	return true
 }
 
-func (ids *idService) sendPushes(ctx context.Context) {
+// sendPushes pushes the current snapshot to all connected peers that haven't received it
+// yet. Pushes to peers that received one less than the per peer push interval ago are
+// deferred. sendPushes returns the time at which the next deferred push is due, or the
+// zero time if no push was deferred. lastPeerPush is updated with the pushes that
+// succeeded.
+func (ids *idService) sendPushes(ctx context.Context, lastPeerPush map[peer.ID]time.Time) (retryAt time.Time) {
+	now := ids.clock.Now()
+	for p, t := range lastPeerPush {
+		if now.Sub(t) >= ids.peerPushInterval {
+			delete(lastPeerPush, p)
+		}
+	}
+	var pushedMu sync.Mutex
+	pushed := make(map[peer.ID]struct{})
+
 	ids.connsMu.RLock()
 	conns := make([]network.Conn, 0, len(ids.conns))
 	for c, e := range ids.conns {
@@ -370,6 +640,12 @@ func (ids *idService) sendPushes(ctx context.Context) {
 			log.Debugw("already sent this snapshot to peer", "peer", c.RemotePeer(), "seq", snapshot.seq)
 			continue
 		}
+		if last, ok := lastPeerPush[c.RemotePeer()]; ok {
+			if due := last.Add(ids.peerPushInterval); retryAt.IsZero() || due.Before(retryAt) {
+				retryAt = due
+			}
+			continue
+		}
 		// we haven't, send it now
 		sem <- struct{}{}
 		wg.Add(1)
@@ -388,9 +664,18 @@ func (ids *idService) sendPushes(ctx context.Context) {
 				log.Debugw("failed to send identify push", "peer", c.RemotePeer(), "error", err)
 				return
 			}
+			if ids.peerPushInterval > 0 {
+				pushedMu.Lock()
+				pushed[c.RemotePeer()] = struct{}{}
+				pushedMu.Unlock()
+			}
 		}(c)
 	}
 	wg.Wait()
+	for p := range pushed {
+		lastPeerPush[p] = now
+	}
+	return retryAt
 }
 
 // Close shuts down the idService
@@ -418,6 +703,40 @@ func (ids *idService) ObservedAddrsFor(local ma.Multiaddr) []ma.Multiaddr {
 	return ids.observedAddrMgr.AddrsFor(local)
 }
 
+func (ids *idService) ConnObservedAddrs(c network.Conn) []ConnObservedAddr {
+	ids.connsMu.RLock()
+	defer ids.connsMu.RUnlock()
+	return slices.Clone(ids.conns[c].ObservedAddrs)
+}
+
+// recordConnObservedAddr adds addr to the history of the addresses observed by the
+// remote peer of c.
+func (ids *idService) recordConnObservedAddr(c network.Conn, addr ma.Multiaddr) {
+	now := ids.clock.Now()
+	ids.connsMu.Lock()
+	defer ids.connsMu.Unlock()
+	e, ok := ids.conns[c]
+	if !ok { // might already have disconnected
+		return
+	}
+	if n := len(e.ObservedAddrs); n > 0 && e.ObservedAddrs[n-1].Addr.Equal(addr) {
+		e.ObservedAddrs[n-1].LastSeen = now
+		return
+	}
+	if len(e.ObservedAddrs) >= maxConnObservedAddrs {
+		e.ObservedAddrs = slices.Delete(e.ObservedAddrs, 0, 1)
+	}
+	e.ObservedAddrs = append(e.ObservedAddrs, ConnObservedAddr{Addr: addr, FirstSeen: now, LastSeen: now})
+	ids.conns[c] = e
+}
+
+func (ids *idService) OwnObservedAddrConfidence() []event.ObservedAddr {
+	if ids.disableObservedAddrManager {
+		return nil
+	}
+	return ids.observedAddrMgr.ObservedAddrs()
+}
+
 // IdentifyConn runs the Identify protocol on a connection.
 // It returns when we've received the peer's Identify message (or the request fails).
 // If successful, the peer store will contain the peer's addresses and supported protocols.
@@ -533,7 +852,7 @@ func (ids *idService) sendIdentifyResp(s network.Stream, isPush bool) error {
 	log.Debugw("sending snapshot", "seq", snapshot.seq, "protocols", snapshot.protocols, "addrs", snapshot.addrs)
 
 	mes := ids.createBaseIdentifyResponse(s.Conn(), &snapshot)
-	mes.SignedPeerRecord = ids.getSignedRecord(&snapshot)
+	mes.SignedPeerRecord = ids.getSignedRecord(s.Conn(), &snapshot)
 
 	log.Debugf("%s sending message to %s %s", ID, s.Conn().RemotePeer(), s.Conn().RemoteMultiaddr())
 	if err := ids.writeChunkedIdentifyMsg(s, mes); err != nil {
@@ -638,15 +957,13 @@ func (ids *idService) updateSnapshot() (updated bool) {
 	addrs := ids.Host.Addrs()
 	slices.SortFunc(addrs, func(a, b ma.Multiaddr) int { return bytes.Compare(a.Bytes(), b.Bytes()) })
 
-	usedSpace := len(ids.ProtocolVersion) + len(ids.UserAgent)
-	for i := 0; i < len(protos); i++ {
-		usedSpace += len(protos[i])
-	}
-	addrs = trimHostAddrList(addrs, maxOwnIdentifyMsgSize-usedSpace-256) // 256 bytes of buffer
+	agentVersion := ids.UserAgent()
+	addrs = trimHostAddrList(addrs, ids.addrsSpace(protos, agentVersion))
 
 	snapshot := identifySnapshot{
-		addrs:     addrs,
-		protocols: protos,
+		addrs:        addrs,
+		protocols:    protos,
+		agentVersion: agentVersion,
 	}
 
 	if !ids.disableSignedPeerRecord {
@@ -655,6 +972,26 @@ func (ids *idService) updateSnapshot() (updated bool) {
 		}
 	}
 
+	if ids.lanMode {
+		subnets, err := addrscope.InterfaceSubnets()
+		if err != nil {
+			log.Warnw("failed to get the subnets of the network interfaces", "err", err)
+		}
+		snapshot.subnets = subnets
+		if snapshot.record != nil {
+			// Re-signing the record gives it a new sequence number, don't do it if the
+			// record didn't change.
+			ids.currentSnapshot.Lock()
+			prev := ids.currentSnapshot.snapshot
+			ids.currentSnapshot.Unlock()
+			if prev.record != nil && prev.record.Equal(snapshot.record) {
+				snapshot.publicRecord = prev.publicRecord
+			} else {
+				snapshot.publicRecord = ids.publicRecord(snapshot.record)
+			}
+		}
+	}
+
 	ids.currentSnapshot.Lock()
 	defer ids.currentSnapshot.Unlock()
 
@@ -702,8 +1039,22 @@ func (ids *idService) createBaseIdentifyResponse(conn network.Conn, snapshot *id
 	// peers that do not yet support signed addresses will need this.
 	// Note: LocalMultiaddr is sometimes 0.0.0.0
 	viaLoopback := manet.IsIPLoopback(localAddr) || manet.IsIPLoopback(remoteAddr)
-	mes.ListenAddrs = make([][]byte, 0, len(snapshot.addrs))
-	for _, addr := range snapshot.addrs {
+	addrs := snapshot.addrs
+	agentVersion := snapshot.agentVersion
+	if ids.userAgentForConn != nil {
+		if ua := ids.userAgentForConn(conn); ua != "" {
+			agentVersion = ua
+		}
+	}
+	if len(agentVersion) > len(snapshot.agentVersion) {
+		// the snapshot's addresses leave room for its own user agent only
+		addrs = trimHostAddrList(addrs, ids.addrsSpace(snapshot.protocols, agentVersion))
+	}
+	if ids.lanMode {
+		addrs = addrscope.ForPeer(addrs, remoteAddr, snapshot.subnets)
+	}
+	mes.ListenAddrs = make([][]byte, 0, len(addrs))
+	for _, addr := range addrs {
 		if !viaLoopback && manet.IsIPLoopback(addr) {
 			continue
 		}
@@ -732,17 +1083,24 @@ func (ids *idService) createBaseIdentifyResponse(conn network.Conn, snapshot *id
 
 	// set protocol versions
 	mes.ProtocolVersion = &ids.ProtocolVersion
-	mes.AgentVersion = &ids.UserAgent
+	mes.AgentVersion = &agentVersion
 
 	return mes
 }
 
-func (ids *idService) getSignedRecord(snapshot *identifySnapshot) []byte {
-	if ids.disableSignedPeerRecord || snapshot.record == nil {
+func (ids *idService) getSignedRecord(conn network.Conn, snapshot *identifySnapshot) []byte {
+	rec := snapshot.record
+	if ids.lanMode {
+		remote := conn.RemoteMultiaddr()
+		if addrscope.Of(remote) != addrscope.Loopback && !addrscope.IsLANPeer(remote, snapshot.subnets) {
+			rec = snapshot.publicRecord
+		}
+	}
+	if ids.disableSignedPeerRecord || rec == nil {
 		return nil
 	}
 
-	recBytes, err := snapshot.record.Marshal()
+	recBytes, err := rec.Marshal()
 	if err != nil {
 		log.Errorw("failed to marshal signed record", "err", err)
 		return nil
@@ -751,6 +1109,38 @@ func (ids *idService) getSignedRecord(snapshot *identifySnapshot) []byte {
 	return recBytes
 }
 
+// publicRecord returns env restricted to the public addresses, re-signed with our key.
+// The relay vouchers of env are kept.
+// The restricted record gets its own sequence number, taken from the same counter as
+// the records created by the host, so that it's ordered with them: peers receiving
+// both records keep the latest one. It returns nil if that fails.
+func (ids *idService) publicRecord(env *record.Envelope) *record.Envelope {
+	r, err := env.Record()
+	if err != nil {
+		log.Errorw("failed to get the peer record from the envelope", "err", err)
+		return nil
+	}
+	rec, ok := r.(*peer.PeerRecord)
+	if !ok {
+		return nil
+	}
+	addrs := ma.FilterAddrs(rec.Addrs, func(a ma.Multiaddr) bool { return addrscope.Of(a) == addrscope.Public })
+	if len(addrs) == len(rec.Addrs) {
+		return env
+	}
+	sk := ids.Host.Peerstore().PrivKey(ids.Host.ID())
+	if sk == nil {
+		return nil
+	}
+	pub := &peer.PeerRecord{PeerID: rec.PeerID, Addrs: addrs, Seq: peer.TimestampSeq(), Vouchers: rec.Vouchers}
+	pubEnv, err := record.Seal(pub, sk)
+	if err != nil {
+		log.Errorw("failed to sign the public peer record", "err", err)
+		return nil
+	}
+	return pubEnv
+}
+
 // diff takes two slices of strings (a and b) and computes which elements were added and removed in b
 func diff(a, b []protocol.ID) (added, removed []protocol.ID) {
 	// This is O(n^2), but it's fine because the slices are small.
@@ -802,6 +1192,9 @@ func (ids *idService) consumeMessage(mes *pb.Identify, c network.Conn, isPush bo
 		obsAddr = nil
 	}
 
+	if obsAddr != nil {
+		ids.recordConnObservedAddr(c, obsAddr)
+	}
 	if obsAddr != nil && !ids.disableObservedAddrManager {
 		// TODO refactor this to use the emitted events instead of having this func call explicitly.
 		ids.observedAddrMgr.Record(c, obsAddr)
@@ -856,20 +1249,36 @@ func (ids *idService) consumeMessage(mes *pb.Identify, c network.Conn, isPush bo
 	if signedPeerRecord != nil {
 		signedAddrs, err := ids.consumeSignedPeerRecord(c.RemotePeer(), signedPeerRecord)
 		if err != nil {
+			// The unsigned addresses of a message with an invalid record aren't trusted
+			// either, whatever the policy.
 			log.Debugf("failed to consume signed peer record: %s", err)
+			ids.reportMisbehavior(p, invalidPeerRecordPenalty)
 			signedPeerRecord = nil
 		} else {
 			addrs = signedAddrs
 		}
 	} else {
-		addrs = lmaddrs
+		switch ids.signedRecordPolicy {
+		case SignedRecordRequire:
+			log.Debugf("ignoring unsigned addresses of %s", p)
+		case SignedRecordFlag:
+			log.Debugf("peer %s sent unsigned addresses", p)
+			ids.reportMisbehavior(p, uncertifiedAddrsPenalty)
+			addrs = lmaddrs
+		default:
+			addrs = lmaddrs
+		}
 	}
 	addrs = filterAddrs(addrs, c.RemoteMultiaddr())
 	if len(addrs) > connectedPeerMaxAddrs {
 		addrs = addrs[:connectedPeerMaxAddrs]
 	}
 
-	ids.Host.Peerstore().AddAddrs(p, addrs, ttl)
+	peerstore.AddAddrsFrom(ids.Host.Peerstore(), p, addrs, ttl, peerstore.AddrSourceIdentify)
+	if signedPeerRecord != nil {
+		// Record the addresses as certified, see peerstore.AddrsCertified.
+		peerstore.AddAddrsFrom(ids.Host.Peerstore(), p, addrs, ttl, peerstore.AddrSourcePeerRecord)
+	}
 
 	// Finally, expire all temporary addrs.
 	ids.Host.Peerstore().UpdateAddrs(p, peerstore.TempAddrTTL, 0)
@@ -883,6 +1292,13 @@ func (ids *idService) consumeMessage(mes *pb.Identify, c network.Conn, isPush bo
 
 	ids.Host.Peerstore().Put(p, "ProtocolVersion", pv)
 	ids.Host.Peerstore().Put(p, "AgentVersion", av)
+	// Only track connected peers. This is checked under addrMu, like on disconnect.
+	ids.addrMu.Lock()
+	switch ids.Host.Network().Connectedness(p) {
+	case network.Connected, network.Limited:
+		ids.updatePeerVersions(p, av, pv)
+	}
+	ids.addrMu.Unlock()
 
 	// get the key from the other side. we may not have it (no-auth transport)
 	ids.consumeReceivedPubKey(c, mes.PublicKey)
@@ -962,6 +1378,7 @@ func (ids *idService) consumeReceivedPubKey(c network.Conn, kb []byte) {
 		} else {
 			// we have a local peer.ID and it does not match the sent key... error.
 			log.Errorf("%s received key for remote peer %s mismatch: %s", lp, rp, np)
+			ids.reportMisbehavior(rp, keyMismatchPenalty)
 		}
 		return
 	}
@@ -1001,6 +1418,14 @@ func (ids *idService) consumeReceivedPubKey(c network.Conn, kb []byte) {
 	log.Errorf("%s local key and received key for %s do not match, but match peer.ID", lp, rp)
 }
 
+// reportMisbehavior reports a negative observation about the peer to the reputation
+// registry, if any.
+func (ids *idService) reportMisbehavior(p peer.ID, penalty float64) {
+	if ids.reputation != nil {
+		ids.reputation.Report(p, ServiceName, penalty)
+	}
+}
+
 // HasConsistentTransport returns true if the address 'a' shares a
 // protocol set with any address in the green set. This is used
 // to check if a given address might be one of the addresses a peer is
@@ -1061,6 +1486,9 @@ func (nn *netNotifiee) Connected(_ network.Network, c network.Conn) {
 	ids.addConnWithLock(c)
 	ids.connsMu.Unlock()
 
+	if ids.lazy {
+		return
+	}
 	nn.IDService().IdentifyWait(c)
 }
 
@@ -1087,6 +1515,7 @@ func (nn *netNotifiee) Disconnected(_ network.Network, c network.Conn) {
 	case network.Connected, network.Limited:
 		return
 	}
+	ids.removePeerVersions(c.RemotePeer())
 	// peerstore returns the elements in a random order as it uses a map to store the addresses
 	addrs := ids.Host.Peerstore().Addrs(c.RemotePeer())
 	n := len(addrs)
@@ -1107,23 +1536,20 @@ func (nn *netNotifiee) Disconnected(_ network.Network, c network.Conn) {
 func (nn *netNotifiee) Listen(_ network.Network, _ ma.Multiaddr)      {}
 func (nn *netNotifiee) ListenClose(_ network.Network, _ ma.Multiaddr) {}
 
-// filterAddrs filters the address slice based on the remote multiaddr:
-//   - if it's a localhost address, no filtering is applied
-//   - if it's a private network address, all localhost addresses are filtered out
-//   - if it's a public address, all non-public addresses are filtered out
-//   - if none of the above, (e.g. discard prefix), no filtering is applied.
-//     We can't do anything meaningful here so we do nothing.
+// filterAddrs filters the address slice based on the remote multiaddr, see
+// addrscope.FromPeer.
 func filterAddrs(addrs []ma.Multiaddr, remote ma.Multiaddr) []ma.Multiaddr {
-	switch {
-	case manet.IsIPLoopback(remote):
-		return addrs
-	case manet.IsPrivateAddr(remote):
-		return ma.FilterAddrs(addrs, func(a ma.Multiaddr) bool { return !manet.IsIPLoopback(a) })
-	case manet.IsPublicAddr(remote):
-		return ma.FilterAddrs(addrs, manet.IsPublicAddr)
-	default:
-		return addrs
+	return addrscope.FromPeer(addrs, remote)
+}
+
+// addrsSpace returns the space left for the listen addresses in our identify
+// messages, given the protocols and the agent version they carry.
+func (ids *idService) addrsSpace(protos []protocol.ID, agentVersion string) int {
+	usedSpace := len(ids.ProtocolVersion) + len(agentVersion)
+	for i := 0; i < len(protos); i++ {
+		usedSpace += len(protos[i])
 	}
+	return maxOwnIdentifyMsgSize - usedSpace - 256 // 256 bytes of buffer
 }
 
 func trimHostAddrList(addrs []ma.Multiaddr, maxSize int) []ma.Multiaddr {
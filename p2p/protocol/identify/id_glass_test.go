@@ -3,12 +3,17 @@ package identify
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/core/record"
 	recordPb "github.com/TheNoobiCat/go-libp2p/core/record/pb"
 	blhost "github.com/TheNoobiCat/go-libp2p/p2p/host/blank"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
@@ -122,6 +127,74 @@ func TestWrongSignedPeerRecord(t *testing.T) {
 	require.Empty(t, h1.Peerstore().Addrs(h3.ID()), "h1 should not know about h3 since it was relayed over h2")
 }
 
+type recordingReporter struct {
+	mx      sync.Mutex
+	reports []float64
+}
+
+func (r *recordingReporter) Report(_ peer.ID, _ string, delta float64) {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	r.reports = append(r.reports, delta)
+}
+
+func (r *recordingReporter) get() []float64 {
+	r.mx.Lock()
+	defer r.mx.Unlock()
+	return slices.Clone(r.reports)
+}
+
+func TestSignedPeerRecordForOtherPeer(t *testing.T) {
+	for _, policy := range []SignedRecordPolicy{SignedRecordAccept, SignedRecordFlag} {
+		t.Run(policy.String(), func(t *testing.T) {
+			h1 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+			defer h1.Close()
+			rep := &recordingReporter{}
+			ids, err := NewIDService(h1, WithSignedPeerRecordPolicy(policy), WithReputationReporter(rep))
+			require.NoError(t, err)
+			ids.Start()
+			defer ids.Close()
+
+			// h2 and h3 don't run identify, their messages are sent manually
+			h2 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+			defer h2.Close()
+			ids2, err := NewIDService(h2)
+			require.NoError(t, err)
+			h3 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+			defer h3.Close()
+			ids3, err := NewIDService(h3)
+			require.NoError(t, err)
+
+			require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{ID: h1.ID(), Addrs: h1.Addrs()}))
+			s, err := h2.NewStream(context.Background(), h1.ID(), IDPush)
+			require.NoError(t, err)
+
+			ids2.updateSnapshot()
+			ids2.currentSnapshot.Lock()
+			snapshot := ids2.currentSnapshot.snapshot
+			ids2.currentSnapshot.Unlock()
+			mes := ids2.createBaseIdentifyResponse(s.Conn(), &snapshot)
+
+			// the record is signed by h3, not h2
+			ids3.updateSnapshot()
+			ids3.currentSnapshot.Lock()
+			rec := ids3.currentSnapshot.snapshot.record
+			ids3.currentSnapshot.Unlock()
+			mes.SignedPeerRecord, err = rec.Marshal()
+			require.NoError(t, err)
+			require.NoError(t, ids2.writeChunkedIdentifyMsg(s, mes))
+			s.Close()
+
+			// the unsigned addresses of the message aren't accepted either, and the
+			// peer is only penalized once
+			require.Eventually(t, func() bool { return len(rep.get()) > 0 }, 5*time.Second, 10*time.Millisecond)
+			time.Sleep(100 * time.Millisecond)
+			require.Equal(t, []float64{invalidPeerRecordPenalty}, rep.get())
+			require.Empty(t, h1.Peerstore().Addrs(h2.ID()))
+		})
+	}
+}
+
 func TestInvalidSignedPeerRecord(t *testing.T) {
 	h1 := blhost.NewBlankHost(swarmt.GenSwarm(t))
 	defer h1.Close()
@@ -206,3 +279,87 @@ func TestIncomingAddrFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestLANModePublicRecord(t *testing.T) {
+	h := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	defer h.Close()
+	ids, err := NewIDService(h, LANMode())
+	require.NoError(t, err)
+	defer ids.Close()
+
+	sk := h.Peerstore().PrivKey(h.ID())
+	rec := peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: h.ID(), Addrs: []ma.Multiaddr{
+		ma.StringCast("/ip4/192.168.1.2/tcp/1"),
+		ma.StringCast("/ip4/1.2.3.4/tcp/1"),
+	}})
+	// relay vouchers are kept in the restricted record
+	rec.Vouchers = [][]byte{[]byte("voucher")}
+	env, err := record.Seal(rec, sk)
+	require.NoError(t, err)
+
+	pubEnv := ids.publicRecord(env)
+	require.NotNil(t, pubEnv)
+	_, r, err := record.ConsumeEnvelope(mustMarshal(t, pubEnv), peer.PeerRecordEnvelopeDomain)
+	require.NoError(t, err)
+	pubRec := r.(*peer.PeerRecord)
+	require.Equal(t, []ma.Multiaddr{ma.StringCast("/ip4/1.2.3.4/tcp/1")}, pubRec.Addrs)
+	require.Equal(t, rec.Vouchers, pubRec.Vouchers)
+	// the restricted record is ordered after the full record it's derived from, and
+	// before the next one
+	require.Greater(t, pubRec.Seq, rec.Seq)
+	require.Less(t, pubRec.Seq, peer.TimestampSeq())
+
+	// a record that only contains public addresses is reused as is
+	rec = peer.PeerRecordFromAddrInfo(peer.AddrInfo{ID: h.ID(), Addrs: []ma.Multiaddr{ma.StringCast("/ip4/1.2.3.4/tcp/1")}})
+	env, err = record.Seal(rec, sk)
+	require.NoError(t, err)
+	require.Same(t, env, ids.publicRecord(env))
+}
+
+func mustMarshal(t *testing.T, env *record.Envelope) []byte {
+	t.Helper()
+	b, err := env.Marshal()
+	require.NoError(t, err)
+	return b
+}
+
+func TestVersionLabelTruncation(t *testing.T) {
+	seen := make(map[string]struct{})
+	// the 2-byte character straddles the length limit
+	v := strings.Repeat("a", maxVersionLabelLen-1) + "é"
+	label := versionLabel(seen, v)
+	require.True(t, utf8.ValidString(label))
+	require.Equal(t, strings.Repeat("a", maxVersionLabelLen-1), label)
+
+	// reporting such a version must not panic
+	tr := NewMetricsTracer().(PeerVersionsTracer)
+	tr.AddedPeerVersions(v, v)
+	tr.RemovedPeerVersions(v, v)
+}
+
+func TestUserAgentForConnAddrsSpace(t *testing.T) {
+	h1 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	defer h1.Close()
+	h2 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	defer h2.Close()
+	longUA := strings.Repeat("a", 2000)
+	ids, err := NewIDService(h1, UserAgentForConn(func(network.Conn) string { return longUA }))
+	require.NoError(t, err)
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	c := h1.Network().ConnsToPeer(h2.ID())[0]
+
+	snapshot := identifySnapshot{agentVersion: ids.UserAgent()}
+	for i := 0; len(snapshot.addrs) < 200; i++ {
+		snapshot.addrs = append(snapshot.addrs, ma.StringCast(fmt.Sprintf("/ip4/1.2.%d.%d/tcp/4001", i/256, i%256)))
+	}
+	snapshot.addrs = trimHostAddrList(snapshot.addrs, ids.addrsSpace(nil, snapshot.agentVersion))
+
+	mes := ids.createBaseIdentifyResponse(c, &snapshot)
+	require.Equal(t, longUA, mes.GetAgentVersion())
+	size := len(ids.ProtocolVersion) + len(mes.GetAgentVersion())
+	for _, a := range mes.ListenAddrs {
+		size += len(a)
+	}
+	require.LessOrEqual(t, size, maxOwnIdentifyMsgSize-256)
+	require.NotEmpty(t, mes.ListenAddrs)
+}
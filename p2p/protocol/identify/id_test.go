@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"maps"
 	"math/rand"
 	"slices"
 	"sync"
@@ -23,6 +24,7 @@ import (
 	blhost "github.com/TheNoobiCat/go-libp2p/p2p/host/blank"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
 	"github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore/pstoremem"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
 	mocknet "github.com/TheNoobiCat/go-libp2p/p2p/net/mock"
 	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
@@ -550,6 +552,112 @@ func TestUserAgent(t *testing.T) {
 	}
 }
 
+func TestSetUserAgent(t *testing.T) {
+	h1, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := libp2p.New(
+		libp2p.UserAgent("bar"),
+		libp2p.UserAgentForConn(func(c network.Conn) string {
+			if c.RemotePeer() == h1.ID() {
+				return ""
+			}
+			return "baz"
+		}),
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"),
+	)
+	require.NoError(t, err)
+	defer h2.Close()
+	h3, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h3.Close()
+
+	agentVersion := func(h host.Host) string {
+		av, _ := h.Peerstore().Get(h2.ID(), "AgentVersion")
+		s, _ := av.(string)
+		return s
+	}
+
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	require.NoError(t, h3.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	require.Equal(t, "bar", agentVersion(h1))
+	require.Equal(t, "baz", agentVersion(h3))
+
+	ids := h2.(interface{ IDService() identify.IDService }).IDService().(identify.UserAgentSetter)
+	ids.SetUserAgent("bar (syncing)")
+	require.Equal(t, "bar (syncing)", ids.UserAgent())
+	// the new user agent is pushed
+	require.Eventually(t, func() bool { return agentVersion(h1) == "bar (syncing)" }, 5*time.Second, 10*time.Millisecond)
+	// the per connection override still applies
+	time.Sleep(100 * time.Millisecond)
+	require.Equal(t, "baz", agentVersion(h3))
+
+	ids.SetUserAgent("")
+	require.Equal(t, identify.DefaultUserAgent(), ids.UserAgent())
+	require.Eventually(t, func() bool { return agentVersion(h1) == identify.DefaultUserAgent() }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestConnObservedAddrs(t *testing.T) {
+	h1, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h2.Close()
+
+	ids1 := h1.(interface{ IDService() identify.IDService }).IDService()
+	obsReporter := ids1.(identify.ConnObservedAddrsReporter)
+	ids2 := h2.(interface{ IDService() identify.IDService }).IDService()
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	conns := h1.Network().ConnsToPeer(h2.ID())
+	require.Len(t, conns, 1)
+	c := conns[0]
+	ids1.IdentifyConn(c)
+
+	obs := obsReporter.ConnObservedAddrs(c)
+	require.Len(t, obs, 1)
+	require.True(t, obs[0].Addr.Equal(c.LocalMultiaddr()))
+	require.Equal(t, obs[0].FirstSeen, obs[0].LastSeen)
+
+	// a push reporting the same address only updates LastSeen
+	ids2.(identify.UserAgentSetter).SetUserAgent("foo")
+	require.Eventually(t, func() bool {
+		obs := obsReporter.ConnObservedAddrs(c)
+		return len(obs) == 1 && obs[0].LastSeen.After(obs[0].FirstSeen)
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, c.Close())
+	require.Eventually(t, func() bool { return obsReporter.ConnObservedAddrs(c) == nil }, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestPeerVersions(t *testing.T) {
+	h1, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h1.Close()
+	h2, err := libp2p.New(libp2p.UserAgent("foo/1.0"), libp2p.ProtocolVersion("test/1"), libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h2.Close()
+	h3, err := libp2p.New(libp2p.UserAgent("foo/1.0"), libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer h3.Close()
+
+	ids := h1.(interface{ IDService() identify.IDService }).IDService().(identify.PeerVersionsReporter)
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h3.ID(), Addrs: h3.Addrs()}))
+
+	require.Equal(t, map[string]int{"foo/1.0": 2}, ids.AgentVersions())
+	require.Equal(t, map[string]int{"test/1": 1, "": 1}, ids.ProtocolVersions())
+	peers := ids.PeersByAgentVersion()
+	require.Len(t, peers, 1)
+	require.ElementsMatch(t, []peer.ID{h2.ID(), h3.ID()}, peers["foo/1.0"])
+
+	require.NoError(t, h1.Network().ClosePeer(h2.ID()))
+	require.Eventually(t, func() bool {
+		return maps.Equal(map[string]int{"foo/1.0": 1}, ids.AgentVersions())
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, map[string][]peer.ID{"foo/1.0": {h3.ID()}}, ids.PeersByAgentVersion())
+}
+
 func TestNotListening(t *testing.T) {
 	// Make sure we don't panic if we're not listening on any addresses.
 	//
@@ -616,6 +724,143 @@ func TestSendPush(t *testing.T) {
 	}, time.Second, 10*time.Millisecond)
 }
 
+func TestSendPushDebounce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h1 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	h2 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	defer h2.Close()
+	defer h1.Close()
+
+	const debounce = 500 * time.Millisecond
+	ids1, err := identify.NewIDService(h1, identify.WithPushDebounce(debounce))
+	require.NoError(t, err)
+	defer ids1.Close()
+	ids1.Start()
+
+	ids2, err := identify.NewIDService(h2)
+	require.NoError(t, err)
+	defer ids2.Close()
+	ids2.Start()
+
+	err = h1.Connect(ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	require.NoError(t, err)
+
+	// wait for them to Identify each other
+	ids1.IdentifyConn(h1.Network().ConnsToPeer(h2.ID())[0])
+	ids2.IdentifyConn(h2.Network().ConnsToPeer(h1.ID())[0])
+
+	supports := func(proto protocol.ID) bool {
+		sup, err := h2.Peerstore().SupportsProtocols(h1.ID(), proto)
+		return err == nil && len(sup) == 1
+	}
+
+	// Flap a protocol. h2 only learns about the final state once h1's protocols were
+	// stable for the debounce.
+	start := time.Now()
+	h1.SetStreamHandler("flap", func(network.Stream) {})
+	time.Sleep(debounce / 5)
+	h1.RemoveStreamHandler("flap")
+	h1.SetStreamHandler("rand", func(network.Stream) {})
+	time.Sleep(debounce / 5)
+	require.False(t, supports("flap"))
+	require.False(t, supports("rand"))
+	require.Eventually(t, func() bool { return supports("rand") }, 5*time.Second, 10*time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(start), debounce)
+	require.False(t, supports("flap"))
+}
+
+func TestSendPushPeerInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h1 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	h2 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	defer h2.Close()
+	defer h1.Close()
+
+	const interval = time.Second
+	ids1, err := identify.NewIDService(h1, identify.WithPeerPushInterval(interval))
+	require.NoError(t, err)
+	defer ids1.Close()
+	ids1.Start()
+
+	ids2, err := identify.NewIDService(h2)
+	require.NoError(t, err)
+	defer ids2.Close()
+	ids2.Start()
+
+	err = h1.Connect(ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	require.NoError(t, err)
+
+	// wait for them to Identify each other
+	ids1.IdentifyConn(h1.Network().ConnsToPeer(h2.ID())[0])
+	ids2.IdentifyConn(h2.Network().ConnsToPeer(h1.ID())[0])
+
+	supports := func(proto protocol.ID) bool {
+		sup, err := h2.Peerstore().SupportsProtocols(h1.ID(), proto)
+		return err == nil && len(sup) == 1
+	}
+
+	// the first push is sent right away
+	h1.SetStreamHandler("first", func(network.Stream) {})
+	require.Eventually(t, func() bool { return supports("first") }, 5*time.Second, 10*time.Millisecond)
+	first := time.Now()
+
+	// the second one is deferred until the interval has passed
+	h1.SetStreamHandler("second", func(network.Stream) {})
+	time.Sleep(interval / 5)
+	require.False(t, supports("second"))
+	require.Eventually(t, func() bool { return supports("second") }, 5*time.Second, 10*time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(first), interval-100*time.Millisecond)
+}
+
+func TestSendPushPeerIntervalMinPushInterval(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	h1 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	h2 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+	defer h2.Close()
+	defer h1.Close()
+
+	const minInterval = 500 * time.Millisecond
+	ids1, err := identify.NewIDService(h1,
+		identify.WithMinPushInterval(minInterval),
+		identify.WithPeerPushInterval(minInterval+200*time.Millisecond),
+	)
+	require.NoError(t, err)
+	defer ids1.Close()
+	ids1.Start()
+
+	ids2, err := identify.NewIDService(h2)
+	require.NoError(t, err)
+	defer ids2.Close()
+	ids2.Start()
+
+	err = h1.Connect(ctx, peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()})
+	require.NoError(t, err)
+	ids1.IdentifyConn(h1.Network().ConnsToPeer(h2.ID())[0])
+	ids2.IdentifyConn(h2.Network().ConnsToPeer(h1.ID())[0])
+
+	supports := func(proto protocol.ID) bool {
+		sup, err := h2.Peerstore().SupportsProtocols(h1.ID(), proto)
+		return err == nil && len(sup) == 1
+	}
+
+	h1.SetStreamHandler("first", func(network.Stream) {})
+	require.Eventually(t, func() bool { return supports("first") }, 5*time.Second, 10*time.Millisecond)
+	first := time.Now()
+
+	// The second push is attempted after the minimum push interval, and deferred by
+	// the per peer push interval. The deferred push must not be sent before another
+	// minimum push interval has passed.
+	h1.SetStreamHandler("second", func(network.Stream) {})
+	require.Eventually(t, func() bool { return supports("second") }, 5*time.Second, 10*time.Millisecond)
+	require.GreaterOrEqual(t, time.Since(first), 2*minInterval-100*time.Millisecond)
+}
+
 func TestLargeIdentifyMessage(t *testing.T) {
 	if race.WithRace() {
 		t.Skip("setting peerstore.RecentlyConnectedAddrTTL is racy")
@@ -971,3 +1216,65 @@ func waitForDisconnectNotification(swarm *swarm.Swarm) <-chan struct{} {
 
 	return done
 }
+
+func TestSignedPeerRecordPolicy(t *testing.T) {
+	for _, tc := range []struct {
+		policy            identify.SignedRecordPolicy
+		signed            bool
+		expectAddrs       bool
+		expectCertified   bool
+		expectFlagPenalty bool
+	}{
+		{policy: identify.SignedRecordAccept, signed: true, expectAddrs: true, expectCertified: true},
+		{policy: identify.SignedRecordRequire, signed: true, expectAddrs: true, expectCertified: true},
+		{policy: identify.SignedRecordAccept, expectAddrs: true},
+		{policy: identify.SignedRecordFlag, expectAddrs: true, expectFlagPenalty: true},
+		{policy: identify.SignedRecordRequire},
+	} {
+		t.Run(fmt.Sprintf("%s/signed=%t", tc.policy, tc.signed), func(t *testing.T) {
+			h1 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+			defer h1.Close()
+			h2 := blhost.NewBlankHost(swarmt.GenSwarm(t))
+			defer h2.Close()
+
+			rep, err := reputation.NewRegistry()
+			require.NoError(t, err)
+			ids1, err := identify.NewIDService(h1, identify.WithSignedPeerRecordPolicy(tc.policy), identify.WithReputationReporter(rep))
+			require.NoError(t, err)
+			defer ids1.Close()
+			ids1.Start()
+
+			var opts []identify.Option
+			if tc.signed {
+				emitAddrChangeEvt(t, h2)
+			} else {
+				opts = append(opts, identify.DisableSignedPeerRecord())
+			}
+			ids2, err := identify.NewIDService(h2, opts...)
+			require.NoError(t, err)
+			defer ids2.Close()
+			ids2.Start()
+
+			require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+			conns := h1.Network().ConnsToPeer(h2.ID())
+			require.NotEmpty(t, conns)
+			select {
+			case <-ids1.IdentifyWait(conns[0]):
+			case <-time.After(5 * time.Second):
+				t.Fatal("identify timed out")
+			}
+
+			if tc.expectAddrs {
+				testKnowsAddrs(t, h1, h2.ID(), h2.Addrs())
+			} else {
+				require.Empty(t, h1.Peerstore().Addrs(h2.ID()))
+			}
+			require.Equal(t, tc.expectCertified, peerstore.AddrsCertified(h1.Peerstore(), h2.ID()))
+			if tc.expectFlagPenalty {
+				require.Less(t, rep.Score(h2.ID()), 0.0)
+			} else {
+				require.Zero(t, rep.Score(h2.ID()))
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package identify
 
 import (
+	"sync"
+	"unicode/utf8"
+
 	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
@@ -73,6 +76,22 @@ var (
 			Buckets:   buckets,
 		},
 	)
+	peerAgentVersions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "peer_agent_versions",
+			Help:      "Number of connected peers by agent version",
+		},
+		[]string{"agent_version"},
+	)
+	peerProtocolVersions = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: metricNamespace,
+			Name:      "peer_protocol_versions",
+			Help:      "Number of connected peers by protocol version",
+		},
+		[]string{"protocol_version"},
+	)
 	collectors = []prometheus.Collector{
 		pushesTriggered,
 		identify,
@@ -82,6 +101,8 @@ var (
 		addrsCount,
 		numProtocolsReceived,
 		numAddrsReceived,
+		peerAgentVersions,
+		peerProtocolVersions,
 	}
 	// 1 to 20 and then up to 100 in steps of 5
 	buckets = append(
@@ -104,9 +125,33 @@ type MetricsTracer interface {
 	IdentifySent(isPush bool, numProtocols int, numAddrs int)
 }
 
-type metricsTracer struct{}
+// PeerVersionsTracer is implemented by MetricsTracers that track the connected peers
+// by agent and protocol version.
+type PeerVersionsTracer interface {
+	// AddedPeerVersions counts a connected peer by agent and protocol version
+	AddedPeerVersions(agentVersion, protocolVersion string)
+
+	// RemovedPeerVersions stops counting a peer by agent and protocol version
+	RemovedPeerVersions(agentVersion, protocolVersion string)
+}
+
+const (
+	// maxVersionLabels bounds the number of distinct agent and protocol versions
+	// reported. Further versions are reported as "other".
+	maxVersionLabels = 64
+	// maxVersionLabelLen is the maximum length of a reported version.
+	maxVersionLabelLen = 64
+)
+
+type metricsTracer struct {
+	mx sync.Mutex
+	// agentLabels and protocolLabels are the versions reported so far
+	agentLabels    map[string]struct{}
+	protocolLabels map[string]struct{}
+}
 
 var _ MetricsTracer = &metricsTracer{}
+var _ PeerVersionsTracer = &metricsTracer{}
 
 type metricsTracerSetting struct {
 	reg prometheus.Registerer
@@ -128,7 +173,10 @@ func NewMetricsTracer(opts ...MetricsTracerOption) MetricsTracer {
 		opt(setting)
 	}
 	metricshelper.RegisterCollectors(setting.reg, collectors...)
-	return &metricsTracer{}
+	return &metricsTracer{
+		agentLabels:    make(map[string]struct{}),
+		protocolLabels: make(map[string]struct{}),
+	}
 }
 
 func (t *metricsTracer) TriggeredPushes(ev any) {
@@ -141,6 +189,8 @@ func (t *metricsTracer) TriggeredPushes(ev any) {
 		typ = "protocols_updated"
 	case event.EvtLocalAddressesUpdated:
 		typ = "addresses_updated"
+	case userAgentUpdated:
+		typ = "user_agent_updated"
 	}
 	*tags = append(*tags, typ)
 	pushesTriggered.WithLabelValues(*tags...).Inc()
@@ -204,3 +254,45 @@ func getPushSupport(s identifyPushSupport) string {
 		return "unknown"
 	}
 }
+
+func (t *metricsTracer) AddedPeerVersions(agentVersion, protocolVersion string) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	peerAgentVersions.WithLabelValues(versionLabel(t.agentLabels, agentVersion)).Inc()
+	peerProtocolVersions.WithLabelValues(versionLabel(t.protocolLabels, protocolVersion)).Inc()
+}
+
+func (t *metricsTracer) RemovedPeerVersions(agentVersion, protocolVersion string) {
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	peerAgentVersions.WithLabelValues(versionLabel(t.agentLabels, agentVersion)).Dec()
+	peerProtocolVersions.WithLabelValues(versionLabel(t.protocolLabels, protocolVersion)).Dec()
+}
+
+// versionLabel returns the label of version v, bounding the cardinality: versions
+// are truncated, and the versions after the first maxVersionLabels ones are reported
+// as "other". A version keeps its label once reported.
+func versionLabel(seen map[string]struct{}, v string) string {
+	if v == "" {
+		return "unknown"
+	}
+	if !utf8.ValidString(v) {
+		return "invalid"
+	}
+	if len(v) > maxVersionLabelLen {
+		// don't split a multi-byte character, the label must be valid UTF-8
+		n := maxVersionLabelLen
+		for n > 0 && !utf8.RuneStart(v[n]) {
+			n--
+		}
+		v = v[:n]
+	}
+	if _, ok := seen[v]; ok {
+		return v
+	}
+	if len(seen) >= maxVersionLabels {
+		return "other"
+	}
+	seen[v] = struct{}{}
+	return v
+}
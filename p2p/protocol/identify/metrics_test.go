@@ -22,12 +22,22 @@ func TestMetricsNoAllocNoCover(t *testing.T) {
 		identifyPushUnsupported,
 	}
 
+	agentVersions := []string{"", "go-libp2p/0.41.0", "kubo/0.34.0/", "rust-libp2p/0.55.0"}
+	protocolVersions := []string{"", "ipfs/0.1.0", "/ipfs/0.1.0"}
+
 	tr := NewMetricsTracer()
+	vt := tr.(PeerVersionsTracer)
 	tests := map[string]func(){
 		"TriggeredPushes":  func() { tr.TriggeredPushes(events[rand.Intn(len(events))]) },
 		"ConnPushSupport":  func() { tr.ConnPushSupport(pushSupport[rand.Intn(len(pushSupport))]) },
 		"IdentifyReceived": func() { tr.IdentifyReceived(rand.Intn(2) == 0, rand.Intn(20), rand.Intn(20)) },
 		"IdentifySent":     func() { tr.IdentifySent(rand.Intn(2) == 0, rand.Intn(20), rand.Intn(20)) },
+		"AddedPeerVersions": func() {
+			vt.AddedPeerVersions(agentVersions[rand.Intn(len(agentVersions))], protocolVersions[rand.Intn(len(protocolVersions))])
+		},
+		"RemovedPeerVersions": func() {
+			vt.RemovedPeerVersions(agentVersions[rand.Intn(len(agentVersions))], protocolVersions[rand.Intn(len(protocolVersions))])
+		},
 	}
 	for method, f := range tests {
 		allocs := testing.AllocsPerRun(1000, f)
@@ -6,8 +6,11 @@ import (
 	"net"
 	"slices"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -95,6 +98,9 @@ const observerSetCacheSize = 5
 type observerSet struct {
 	ObservedTWAddr ma.Multiaddr
 	ObservedBy     map[string]int
+	// FirstObserved and LastObserved are the times of the first and the latest
+	// observation in the set.
+	FirstObserved, LastObserved time.Time
 
 	mu               sync.RWMutex            // protects following
 	cachedMultiaddrs map[string]ma.Multiaddr // cache of localMultiaddr rest(addr - thinwaist) => output multiaddr
@@ -152,6 +158,8 @@ type ObservedAddrManager struct {
 	wch chan observation
 	// notified on recording an observation
 	addrRecordedNotif chan struct{}
+	// notified when the observed addresses change
+	addrsChangedNotif chan struct{}
 
 	// for closing
 	wg        sync.WaitGroup
@@ -180,6 +188,7 @@ func NewObservedAddrManager(listenAddrs, hostAddrs func() []ma.Multiaddr,
 		localAddrs:           make(map[string]*thinWaistWithCount),
 		wch:                  make(chan observation, observedAddrManagerWorkerChannelSize),
 		addrRecordedNotif:    make(chan struct{}, 1),
+		addrsChangedNotif:    make(chan struct{}, 1),
 		listenAddrs:          listenAddrs,
 		interfaceListenAddrs: interfaceListenAddrs,
 		hostAddrs:            hostAddrs,
@@ -275,6 +284,42 @@ func (o *ObservedAddrManager) Addrs() []ma.Multiaddr {
 	return addrs
 }
 
+// ObservedAddrs returns all addresses observed by currently connected peers, with
+// the number of peers that observed them. Unlike Addrs, it includes addresses that
+// weren't observed often enough to be activated. The result is sorted by local
+// address and then by the number of observers, most observed first.
+func (o *ObservedAddrManager) ObservedAddrs() []event.ObservedAddr {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+
+	var res []event.ObservedAddr
+	for _, t := range o.localAddrs {
+		localTWStr := string(t.TW.Bytes())
+		top := o.getTopExternalAddrs(localTWStr)
+		for _, s := range o.externalAddrs[localTWStr] {
+			res = append(res, event.ObservedAddr{
+				Addr:          s.cacheMultiaddr(t.Rest),
+				LocalAddr:     t.Addr,
+				Observers:     len(s.ObservedBy),
+				FirstObserved: s.FirstObserved,
+				LastObserved:  s.LastObserved,
+				Confidence:    min(float64(len(s.ObservedBy))/float64(ActivationThresh), 1),
+				Activated:     slices.Contains(top, s),
+			})
+		}
+	}
+	slices.SortFunc(res, func(a, b event.ObservedAddr) int {
+		if c := strings.Compare(a.LocalAddr.String(), b.LocalAddr.String()); c != 0 {
+			return c
+		}
+		if c := b.Observers - a.Observers; c != 0 {
+			return c
+		}
+		return strings.Compare(a.Addr.String(), b.Addr.String())
+	})
+	return res
+}
+
 func (o *ObservedAddrManager) getTopExternalAddrs(localTWStr string) []*observerSet {
 	observerSets := make([]*observerSet, 0, len(o.externalAddrs[localTWStr]))
 	for _, v := range o.externalAddrs[localTWStr] {
@@ -426,10 +471,19 @@ func (o *ObservedAddrManager) maybeRecordObservation(conn connMultiaddrs, observ
 	o.mu.Lock()
 	defer o.mu.Unlock()
 	o.recordObservationUnlocked(conn, localTW, observedTW)
+	o.notifyUnlocked()
+}
+
+// notifyUnlocked notifies the consumers of addrRecordedNotif and addrsChangedNotif.
+func (o *ObservedAddrManager) notifyUnlocked() {
 	select {
 	case o.addrRecordedNotif <- struct{}{}:
 	default:
 	}
+	select {
+	case o.addrsChangedNotif <- struct{}{}:
+	default:
+	}
 }
 
 func (o *ObservedAddrManager) recordObservationUnlocked(conn connMultiaddrs, localTW, observedTW thinWaist) {
@@ -458,6 +512,9 @@ func (o *ObservedAddrManager) recordObservationUnlocked(conn connMultiaddrs, loc
 	} else {
 		if prevObservedTWAddr.Equal(observedTW.TW) {
 			// we have received the same observation again, nothing to do
+			if s, ok := o.externalAddrs[localTWStr][observedTWStr]; ok {
+				s.LastObserved = time.Now()
+			}
 			return
 		}
 		// if we have a previous entry remove it from externalAddrs
@@ -486,11 +543,13 @@ func (o *ObservedAddrManager) removeExternalAddrsUnlocked(observer, localTWStr,
 }
 
 func (o *ObservedAddrManager) addExternalAddrsUnlocked(observedTWAddr ma.Multiaddr, observer, localTWStr, observedTWStr string) {
+	now := time.Now()
 	s, ok := o.externalAddrs[localTWStr][observedTWStr]
 	if !ok {
 		s = &observerSet{
 			ObservedTWAddr: observedTWAddr,
 			ObservedBy:     make(map[string]int),
+			FirstObserved:  now,
 		}
 		if _, ok := o.externalAddrs[localTWStr]; !ok {
 			o.externalAddrs[localTWStr] = make(map[string]*observerSet)
@@ -498,6 +557,7 @@ func (o *ObservedAddrManager) addExternalAddrsUnlocked(observedTWAddr ma.Multiad
 		o.externalAddrs[localTWStr][observedTWStr] = s
 	}
 	s.ObservedBy[observer]++
+	s.LastObserved = now
 }
 
 func (o *ObservedAddrManager) removeConn(conn connMultiaddrs) {
@@ -534,10 +594,7 @@ func (o *ObservedAddrManager) removeConn(conn connMultiaddrs) {
 	}
 
 	o.removeExternalAddrsUnlocked(observer, string(localTW.TW.Bytes()), string(observedTWAddr.Bytes()))
-	select {
-	case o.addrRecordedNotif <- struct{}{}:
-	default:
-	}
+	o.notifyUnlocked()
 }
 
 func (o *ObservedAddrManager) getNATType() (tcpNATType, udpNATType network.NATDeviceType) {
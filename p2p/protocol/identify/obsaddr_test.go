@@ -610,6 +610,48 @@ func TestObservedAddrManager(t *testing.T) {
 			return checkAllEntriesRemoved(o)
 		}, 1*time.Second, 100*time.Millisecond)
 	})
+	t.Run("ObservedAddrs confidence", func(t *testing.T) {
+		o := newObservedAddrMgr()
+		defer o.Close()
+		observed := ma.StringCast("/ip4/2.2.2.2/tcp/2")
+		conns := make([]*mockConn, ActivationThresh)
+		for i := range conns {
+			conns[i] = newConn(tcp4ListenAddr, ma.StringCast(fmt.Sprintf("/ip4/1.2.3.%d/tcp/1", i+1)))
+		}
+
+		start := time.Now()
+		o.Record(conns[0], observed)
+		require.EventuallyWithT(t, func(t *assert.CollectT) {
+			addrs := o.ObservedAddrs()
+			require.Len(t, addrs, 1)
+			require.True(t, addrs[0].Addr.Equal(observed))
+			require.True(t, addrs[0].LocalAddr.Equal(tcp4ListenAddr))
+			require.Equal(t, 1, addrs[0].Observers)
+			require.Equal(t, 1/float64(ActivationThresh), addrs[0].Confidence)
+			require.False(t, addrs[0].Activated)
+			require.False(t, addrs[0].FirstObserved.Before(start))
+		}, 1*time.Second, 10*time.Millisecond)
+		require.Empty(t, o.Addrs())
+
+		for _, c := range conns[1:] {
+			o.Record(c, observed)
+		}
+		require.EventuallyWithT(t, func(t *assert.CollectT) {
+			addrs := o.ObservedAddrs()
+			require.Len(t, addrs, 1)
+			require.Equal(t, ActivationThresh, addrs[0].Observers)
+			require.Equal(t, 1.0, addrs[0].Confidence)
+			require.True(t, addrs[0].Activated)
+			require.False(t, addrs[0].LastObserved.Before(addrs[0].FirstObserved))
+		}, 1*time.Second, 10*time.Millisecond)
+
+		for _, c := range conns {
+			o.removeConn(c)
+		}
+		require.Eventually(t, func() bool {
+			return len(o.ObservedAddrs()) == 0
+		}, 1*time.Second, 100*time.Millisecond)
+	})
 }
 
 func genIPMultiaddr(ip6 bool) ma.Multiaddr {
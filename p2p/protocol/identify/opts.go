@@ -1,14 +1,34 @@
 package identify
 
-import "time"
+import (
+	"fmt"
+	"time"
+
+	"github.com/benbjohnson/clock"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/reputation"
+	useragent "github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify/internal/user-agent"
+	"github.com/TheNoobiCat/go-libp2p/x/rate"
+)
 
 type config struct {
 	protocolVersion            string
 	userAgent                  string
+	userAgentForConn           func(network.Conn) string
 	disableSignedPeerRecord    bool
 	metricsTracer              MetricsTracer
 	disableObservedAddrManager bool
 	timeout                    time.Duration
+	peerRateLimiter            *rate.PeerLimiter
+	pushDebounce               time.Duration
+	minPushInterval            time.Duration
+	peerPushInterval           time.Duration
+	reputation                 reputation.Reporter
+	lazy                       bool
+	lanMode                    bool
+	signedRecordPolicy         SignedRecordPolicy
+	clock                      clock.Clock
 }
 
 // Option is an option function for identify.
@@ -29,6 +49,20 @@ func UserAgent(ua string) Option {
 	}
 }
 
+// UserAgentForConn overrides the user agent sent on a connection. If f returns an
+// empty string, the user agent set with UserAgent, or with SetUserAgent at runtime,
+// is sent. f is called for every identify request and push sent on the connection.
+func UserAgentForConn(f func(c network.Conn) string) Option {
+	return func(cfg *config) {
+		cfg.userAgentForConn = f
+	}
+}
+
+// DefaultUserAgent returns the user agent used when none is set with UserAgent.
+func DefaultUserAgent() string {
+	return useragent.DefaultUserAgent()
+}
+
 // DisableSignedPeerRecord disables populating signed peer records on the outgoing Identify response
 // and ONLY sends the unsigned addresses.
 func DisableSignedPeerRecord() Option {
@@ -57,3 +91,114 @@ func WithTimeout(timeout time.Duration) Option {
 		cfg.timeout = timeout
 	}
 }
+
+// WithPeerRateLimiter limits the number of identify and identify push requests a single
+// peer can make. Streams exceeding the limit are reset with network.StreamRateLimited.
+func WithPeerRateLimiter(l *rate.PeerLimiter) Option {
+	return func(cfg *config) {
+		cfg.peerRateLimiter = l
+	}
+}
+
+// WithPushDebounce delays identify pushes until the host's addresses and protocols
+// have remained unchanged for d. This avoids sending a push for every transient change,
+// e.g. on a flapping network interface. To bound the delay when changes keep coming,
+// a push is sent at the latest maxPushDebounceFactor times d after the first change.
+func WithPushDebounce(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.pushDebounce = d
+	}
+}
+
+// WithMinPushInterval sets the minimum time between two identify pushes.
+func WithMinPushInterval(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.minPushInterval = d
+	}
+}
+
+// WithPeerPushInterval sets the minimum time between two identify pushes to the same
+// peer. Pushes to a peer that received one less than d ago are deferred until d has
+// passed, and then carry the latest addresses and protocols. This bounds the pushes a
+// peer receives when the host's addresses keep changing.
+func WithPeerPushInterval(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.peerPushInterval = d
+	}
+}
+
+// WithClock sets the clock used to debounce and schedule identify pushes, and to
+// timestamp the observed addresses, e.g. a mock clock in tests.
+func WithClock(cl clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = cl
+	}
+}
+
+// WithReputationReporter reports peers that send invalid signed peer records or
+// public keys to the reputation registry.
+func WithReputationReporter(r reputation.Reporter) Option {
+	return func(cfg *config) {
+		cfg.reputation = r
+	}
+}
+
+// Lazy defers identifying new connections until IdentifyWait is called for them,
+// instead of identifying every connection as soon as it is established. The host
+// calls IdentifyWait when the first stream is opened on a connection. This avoids the
+// identify exchange for connections that are closed without being used.
+func Lazy() Option {
+	return func(cfg *config) {
+		cfg.lazy = true
+	}
+}
+
+// LANMode only advertises our private and link-local addresses to the peers on the
+// same subnet, see addrscope.ForPeer. Other peers only receive our public addresses,
+// and a signed peer record restricted to them.
+func LANMode() Option {
+	return func(cfg *config) {
+		cfg.lanMode = true
+	}
+}
+
+// SignedRecordPolicy is how identify handles the peers that don't send a signed peer
+// record. Whatever the policy, the listen addresses of a message with a signed peer
+// record that can't be consumed, e.g. because it's signed by another peer, are
+// rejected, and the peer is reported to the reputation registry, if any.
+type SignedRecordPolicy int
+
+const (
+	// SignedRecordAccept accepts the unsigned addresses of the peers. This is the
+	// default.
+	SignedRecordAccept SignedRecordPolicy = iota
+	// SignedRecordFlag accepts the unsigned addresses of the peers, but logs them and
+	// reports them to the reputation registry, if any. This allows finding the peers
+	// that still need to be upgraded before requiring signed peer records.
+	SignedRecordFlag
+	// SignedRecordRequire ignores the unsigned addresses of the peers. The rest of the
+	// identify message is still processed.
+	SignedRecordRequire
+)
+
+func (p SignedRecordPolicy) String() string {
+	switch p {
+	case SignedRecordAccept:
+		return "accept"
+	case SignedRecordFlag:
+		return "flag"
+	case SignedRecordRequire:
+		return "require"
+	default:
+		return fmt.Sprintf("SignedRecordPolicy(%d)", int(p))
+	}
+}
+
+// WithSignedPeerRecordPolicy sets how the peers that don't send a valid signed peer
+// record are handled. Use peerstore.AddrsCertified to check whether the addresses of a
+// peer are certified.
+func WithSignedPeerRecordPolicy(p SignedRecordPolicy) Option {
+	return func(cfg *config) {
+		cfg.signedRecordPolicy = p
+	}
+}
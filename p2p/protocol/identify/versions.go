@@ -0,0 +1,95 @@
+package identify
+
+import (
+	"sync"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+)
+
+// peerVersions tracks the agent and protocol versions of the connected peers, as
+// learned by identify.
+type peerVersions struct {
+	mx    sync.Mutex
+	peers map[peer.ID]versions
+}
+
+type versions struct {
+	agent, protocol string
+}
+
+// set records the versions of p, and returns the previously recorded ones.
+func (pv *peerVersions) set(p peer.ID, v versions) (prev versions, ok bool) {
+	pv.mx.Lock()
+	defer pv.mx.Unlock()
+	if pv.peers == nil {
+		pv.peers = make(map[peer.ID]versions)
+	}
+	prev, ok = pv.peers[p]
+	pv.peers[p] = v
+	return prev, ok
+}
+
+// remove forgets p, and returns its recorded versions.
+func (pv *peerVersions) remove(p peer.ID) (prev versions, ok bool) {
+	pv.mx.Lock()
+	defer pv.mx.Unlock()
+	prev, ok = pv.peers[p]
+	delete(pv.peers, p)
+	return prev, ok
+}
+
+func (pv *peerVersions) count(version func(versions) string) map[string]int {
+	pv.mx.Lock()
+	defer pv.mx.Unlock()
+	counts := make(map[string]int)
+	for _, v := range pv.peers {
+		counts[version(v)]++
+	}
+	return counts
+}
+
+// AgentVersions returns the number of connected peers by agent version. Peers that
+// haven't been identified yet aren't counted.
+func (ids *idService) AgentVersions() map[string]int {
+	return ids.peerVersions.count(func(v versions) string { return v.agent })
+}
+
+// ProtocolVersions returns the number of connected peers by protocol version. Peers
+// that haven't been identified yet aren't counted.
+func (ids *idService) ProtocolVersions() map[string]int {
+	return ids.peerVersions.count(func(v versions) string { return v.protocol })
+}
+
+// PeersByAgentVersion returns the connected peers by agent version. Peers that
+// haven't been identified yet aren't listed.
+func (ids *idService) PeersByAgentVersion() map[string][]peer.ID {
+	ids.peerVersions.mx.Lock()
+	defer ids.peerVersions.mx.Unlock()
+	peers := make(map[string][]peer.ID)
+	for p, v := range ids.peerVersions.peers {
+		peers[v.agent] = append(peers[v.agent], p)
+	}
+	return peers
+}
+
+// updatePeerVersions records the versions of the connected peer p.
+func (ids *idService) updatePeerVersions(p peer.ID, agent, protocol string) {
+	v := versions{agent: agent, protocol: protocol}
+	prev, ok := ids.peerVersions.set(p, v)
+	t, isTracer := ids.metricsTracer.(PeerVersionsTracer)
+	if !isTracer || (ok && prev == v) {
+		return
+	}
+	if ok {
+		t.RemovedPeerVersions(prev.agent, prev.protocol)
+	}
+	t.AddedPeerVersions(agent, protocol)
+}
+
+// removePeerVersions forgets the versions of p, once disconnected.
+func (ids *idService) removePeerVersions(p peer.ID) {
+	prev, ok := ids.peerVersions.remove(p)
+	if t, isTracer := ids.metricsTracer.(PeerVersionsTracer); ok && isTracer {
+		t.RemovedPeerVersions(prev.agent, prev.protocol)
+	}
+}
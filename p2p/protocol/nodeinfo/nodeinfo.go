@@ -0,0 +1,120 @@
+// Package nodeinfo implements a protocol to serve a host's NodeInfo document to
+// trusted peers, e.g. to collect support bundles from remote nodes.
+package nodeinfo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("nodeinfo")
+
+const (
+	// ID is the protocol ID of the node info protocol.
+	ID = "/libp2p/nodeinfo/1.0.0"
+
+	// ServiceName is the resource manager service name of the node info protocol.
+	ServiceName = "libp2p.nodeinfo"
+
+	// maxSize is the maximum size of a NodeInfo document.
+	maxSize = 1 << 20
+
+	streamTimeout = 10 * time.Second
+)
+
+// ErrTooLarge is returned by Fetch when the NodeInfo document exceeds the maximum size.
+var ErrTooLarge = errors.New("node info document too large")
+
+// Service serves the NodeInfo document of a host to allowlisted peers. Streams of
+// other peers are reset with network.StreamGated.
+type Service struct {
+	host     host.Host
+	provider host.NodeInfoProvider
+	allowed  map[peer.ID]struct{}
+}
+
+// NewService creates a Service and registers its stream handler on h. The document is
+// obtained from provider, which is usually the host itself.
+func NewService(h host.Host, provider host.NodeInfoProvider, allowed ...peer.ID) *Service {
+	s := &Service{
+		host:     h,
+		provider: provider,
+		allowed:  make(map[peer.ID]struct{}, len(allowed)),
+	}
+	for _, p := range allowed {
+		s.allowed[p] = struct{}{}
+	}
+	h.SetStreamHandler(ID, s.handleStream)
+	return s
+}
+
+// Close removes the stream handler.
+func (s *Service) Close() error {
+	s.host.RemoveStreamHandler(ID)
+	return nil
+}
+
+func (s *Service) handleStream(str network.Stream) {
+	if _, ok := s.allowed[str.Conn().RemotePeer()]; !ok {
+		log.Debugw("refusing node info request", "peer", str.Conn().RemotePeer())
+		_ = str.ResetWithError(network.StreamGated)
+		return
+	}
+	if err := str.Scope().SetService(ServiceName); err != nil {
+		log.Debugf("error attaching stream to node info service: %s", err)
+		str.Reset()
+		return
+	}
+	defer str.Close()
+	_ = str.SetDeadline(time.Now().Add(streamTimeout))
+
+	if err := json.NewEncoder(str).Encode(s.provider.NodeInfo()); err != nil {
+		log.Debugf("error writing node info: %s", err)
+		str.Reset()
+	}
+}
+
+// Fetch requests the NodeInfo document of peer p.
+func Fetch(ctx context.Context, h host.Host, p peer.ID) (*host.NodeInfo, error) {
+	str, err := h.NewStream(ctx, p, ID)
+	if err != nil {
+		return nil, err
+	}
+	defer str.Close()
+
+	if err := str.Scope().SetService(ServiceName); err != nil {
+		str.Reset()
+		return nil, fmt.Errorf("error attaching stream to node info service: %w", err)
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(streamTimeout)
+	}
+	_ = str.SetDeadline(deadline)
+	_ = str.CloseWrite()
+
+	b, err := io.ReadAll(io.LimitReader(str, maxSize+1))
+	if err != nil {
+		str.Reset()
+		return nil, err
+	}
+	if len(b) > maxSize {
+		str.Reset()
+		return nil, ErrTooLarge
+	}
+	var info host.NodeInfo
+	if err := json.Unmarshal(b, &info); err != nil {
+		return nil, fmt.Errorf("invalid node info document: %w", err)
+	}
+	return &info, nil
+}
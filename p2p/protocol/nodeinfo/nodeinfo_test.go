@@ -0,0 +1,49 @@
+package nodeinfo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/nodeinfo"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetch(t *testing.T) {
+	allowed, err := bhost.NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer allowed.Close()
+	allowed.Start()
+	other, err := bhost.NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer other.Close()
+	other.Start()
+
+	h, err := bhost.NewHost(swarmt.GenSwarm(t), &bhost.HostOpts{NodeInfoAllowlist: []peer.ID{allowed.ID()}})
+	require.NoError(t, err)
+	defer h.Close()
+	h.Start()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	pi := peer.AddrInfo{ID: h.ID(), Addrs: h.Addrs()}
+	require.NoError(t, allowed.Connect(ctx, pi))
+	require.NoError(t, other.Connect(ctx, pi))
+
+	info, err := nodeinfo.Fetch(ctx, allowed, h.ID())
+	require.NoError(t, err)
+	require.Equal(t, h.ID(), info.PeerID)
+	require.ElementsMatch(t, h.Network().ListenAddresses(), info.ListenAddrs)
+	require.Contains(t, info.Protocols, protocol.ID(nodeinfo.ID))
+
+	_, err = nodeinfo.Fetch(ctx, other, h.ID())
+	var se *network.StreamError
+	require.ErrorAs(t, err, &se)
+	require.Equal(t, network.StreamGated, se.ErrorCode)
+}
@@ -15,6 +15,8 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/host"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	"github.com/TheNoobiCat/go-libp2p/x/rate"
 )
 
 var log = logging.Logger("ping")
@@ -31,11 +33,31 @@ const (
 
 type PingService struct {
 	Host host.Host
+
+	peerRateLimiter *rate.PeerLimiter
 }
 
-func NewPingService(h host.Host) *PingService {
-	ps := &PingService{h}
-	h.SetStreamHandler(ID, ps.PingHandler)
+// Option is an option for the ping service.
+type Option func(*PingService)
+
+// WithPeerRateLimiter limits the number of ping streams a single peer can open.
+// Streams exceeding the limit are reset with network.StreamRateLimited.
+func WithPeerRateLimiter(l *rate.PeerLimiter) Option {
+	return func(ps *PingService) {
+		ps.peerRateLimiter = l
+	}
+}
+
+func NewPingService(h host.Host, opts ...Option) *PingService {
+	ps := &PingService{Host: h}
+	for _, opt := range opts {
+		opt(ps)
+	}
+	handler := ps.PingHandler
+	if ps.peerRateLimiter != nil {
+		handler = ps.peerRateLimiter.Limit(handler)
+	}
+	h.SetStreamHandler(ID, handler)
 	return ps
 }
 
@@ -152,6 +174,10 @@ func Ping(ctx context.Context, h host.Host, p peer.ID) <-chan Result {
 			// No error, record the RTT.
 			if res.Error == nil {
 				h.Peerstore().RecordLatency(p, res.RTT)
+				// The RTT of the dialed addresses is used for ranking them.
+				if am, ok := h.Peerstore().(peerstore.AddrMetrics); ok && s.Conn().Stat().Direction == network.DirOutbound {
+					am.RecordAddrLatency(p, s.Conn().RemoteMultiaddr(), res.RTT)
+				}
 			}
 
 			select {
@@ -9,6 +9,7 @@ import (
 	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
 	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
 	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/ping"
+	"github.com/TheNoobiCat/go-libp2p/x/rate"
 
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/require"
@@ -55,3 +56,28 @@ func testPing(t *testing.T, ps *ping.PingService, p peer.ID) {
 	}
 
 }
+
+func TestPingPeerRateLimit(t *testing.T) {
+	h1, err := bhost.NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h1.Close()
+	h1.Start()
+	h2, err := bhost.NewHost(swarmt.GenSwarm(t), nil)
+	require.NoError(t, err)
+	defer h2.Close()
+	h2.Start()
+
+	require.NoError(t, h1.Connect(context.Background(), peer.AddrInfo{ID: h2.ID(), Addrs: h2.Addrs()}))
+
+	ping.NewPingService(h2, ping.WithPeerRateLimiter(&rate.PeerLimiter{PeerLimit: rate.Limit{RPS: 0.001, Burst: 1}}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	res := <-ping.Ping(ctx, h1, h2.ID())
+	cancel()
+	require.NoError(t, res.Error)
+
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	res = <-ping.Ping(ctx, h1, h2.ID())
+	require.Error(t, res.Error)
+}
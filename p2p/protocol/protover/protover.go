@@ -0,0 +1,188 @@
+// Package protover negotiates the version of protocols that are versioned with
+// semantic versions, with IDs of the form <base>/<version>, e.g. /myapp/req/1.2.0.
+//
+// A protocol family is the set of versions of a protocol a node accepts, and is
+// identified by the base ID and a version range:
+//
+//	fam, err := protover.NewFamily("/myapp/req/1.x")
+//
+// The server registers a handler for the family, which is called with the version
+// negotiated for the stream:
+//
+//	fam.Handle(h, func(s network.Stream, v protover.Version) {
+//		if v.Minor >= 2 {
+//			// use the features added in 1.2.0
+//		}
+//	})
+//
+// The client opens streams with the versions it implements, and gets the highest
+// version the server accepts:
+//
+//	s, v, err := fam.NewStream(ctx, h, p, protover.MustParseVersion("1.2.0"), protover.MustParseVersion("1.1.0"))
+//
+// The family ID (/myapp/req/1.x) is what the server advertises to its peers. If it's
+// known from identify, the client only proposes the versions the server accepts.
+package protover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+
+	msmux "github.com/multiformats/go-multistream"
+)
+
+// ErrNoCommonVersion is returned by NewStream when the peer doesn't accept any of the
+// proposed versions.
+var ErrNoCommonVersion = errors.New("no common protocol version")
+
+// Handler handles a stream of a protocol family, with the negotiated version v.
+type Handler func(s network.Stream, v Version)
+
+// Family is a protocol family: the versions in a range of a protocol, with IDs of
+// the form <base>/<version>.
+type Family struct {
+	id   protocol.ID
+	base protocol.ID
+	r    Range
+}
+
+// NewFamily defines a protocol family from its ID, of the form <base>/<range>, e.g.
+// /myapp/req/1.x. See Range for the syntax of the range.
+func NewFamily(id protocol.ID) (*Family, error) {
+	i := strings.LastIndexByte(string(id), '/')
+	if i <= 0 || i == len(id)-1 {
+		return nil, fmt.Errorf("invalid protocol family %s: expected <base>/<range>", id)
+	}
+	r, err := ParseRange(string(id[i+1:]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid protocol family %s: %w", id, err)
+	}
+	return &Family{id: id, base: id[:i], r: r}, nil
+}
+
+// ID returns the family ID.
+func (f *Family) ID() protocol.ID {
+	return f.id
+}
+
+// Base returns the base protocol ID of the versions of the family.
+func (f *Family) Base() protocol.ID {
+	return f.base
+}
+
+// Range returns the versions of the family.
+func (f *Family) Range() Range {
+	return f.r
+}
+
+// ProtocolID returns the protocol ID of version v.
+func (f *Family) ProtocolID(v Version) protocol.ID {
+	return f.base + "/" + protocol.ID(v.String())
+}
+
+// Version returns the version of id, and whether id is a version of the family.
+func (f *Family) Version(id protocol.ID) (Version, bool) {
+	rest, ok := strings.CutPrefix(string(id), string(f.base)+"/")
+	if !ok {
+		return Version{}, false
+	}
+	v, err := ParseVersion(rest)
+	if err != nil || !f.r.Contains(v) {
+		return Version{}, false
+	}
+	return v, true
+}
+
+// Match returns whether id is a version of the family. It can be used with
+// SetStreamHandlerMatch.
+func (f *Family) Match(id protocol.ID) bool {
+	_, ok := f.Version(id)
+	return ok
+}
+
+// Handle registers the handler for the family on h. The handler is called for
+// streams negotiated with any version of the family.
+func (f *Family) Handle(h host.Host, handler Handler) {
+	h.SetStreamHandlerMatch(f.id, f.Match, func(s network.Stream) {
+		v, ok := f.Version(s.Protocol())
+		if !ok {
+			// can't happen, the protocol was matched
+			s.Reset()
+			return
+		}
+		handler(s, v)
+	})
+}
+
+// Unhandle removes the family's handler from h.
+func (f *Family) Unhandle(h host.Host) {
+	h.RemoveStreamHandler(f.id)
+}
+
+// NewStream opens a stream to p, negotiating the highest of versions that p accepts.
+// Only the versions of the family are proposed. If the families p accepts for the
+// base protocol are known, the versions p doesn't accept aren't proposed either.
+func (f *Family) NewStream(ctx context.Context, h host.Host, p peer.ID, versions ...Version) (network.Stream, Version, error) {
+	proposals := make([]Version, 0, len(versions))
+	for _, v := range versions {
+		if f.r.Contains(v) && !slices.Contains(proposals, v) {
+			proposals = append(proposals, v)
+		}
+	}
+	if remote := f.remoteRanges(h, p); len(remote) > 0 {
+		proposals = slices.DeleteFunc(proposals, func(v Version) bool {
+			return !slices.ContainsFunc(remote, func(r Range) bool { return r.Contains(v) })
+		})
+	}
+	if len(proposals) == 0 {
+		return nil, Version{}, ErrNoCommonVersion
+	}
+	slices.SortFunc(proposals, func(a, b Version) int { return b.Compare(a) })
+
+	pids := make([]protocol.ID, 0, len(proposals))
+	for _, v := range proposals {
+		pids = append(pids, f.ProtocolID(v))
+	}
+	s, err := h.NewStream(ctx, p, pids...)
+	if err != nil {
+		var nerr msmux.ErrNotSupported[protocol.ID]
+		if errors.As(err, &nerr) {
+			return nil, Version{}, fmt.Errorf("%w: %w", ErrNoCommonVersion, err)
+		}
+		return nil, Version{}, err
+	}
+	v, ok := f.Version(s.Protocol())
+	if !ok {
+		s.Reset()
+		return nil, Version{}, fmt.Errorf("negotiated unexpected protocol %s", s.Protocol())
+	}
+	return s, v, nil
+}
+
+// remoteRanges returns the ranges of the families of the base protocol that p
+// advertised.
+func (f *Family) remoteRanges(h host.Host, p peer.ID) []Range {
+	protos, err := h.Peerstore().GetProtocols(p)
+	if err != nil {
+		return nil
+	}
+	var ranges []Range
+	for _, id := range protos {
+		rest, ok := strings.CutPrefix(string(id), string(f.base)+"/")
+		if !ok || strings.Contains(rest, "/") {
+			continue
+		}
+		if r, err := ParseRange(rest); err == nil {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
@@ -0,0 +1,159 @@
+package protover_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/protover"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, err := protover.ParseVersion("1.20.3")
+	require.NoError(t, err)
+	require.Equal(t, protover.Version{Major: 1, Minor: 20, Patch: 3}, v)
+	require.Equal(t, "1.20.3", v.String())
+
+	for _, s := range []string{"", "1", "1.2", "1.2.3.4", "1.x.0", "01.2.3", "v1.2.3", "1.2.-3"} {
+		_, err := protover.ParseVersion(s)
+		require.Error(t, err, s)
+	}
+
+	require.Equal(t, -1, protover.MustParseVersion("1.2.3").Compare(protover.MustParseVersion("1.10.0")))
+	require.Equal(t, 0, protover.MustParseVersion("1.2.3").Compare(protover.MustParseVersion("1.2.3")))
+	require.Equal(t, 1, protover.MustParseVersion("2.0.0").Compare(protover.MustParseVersion("1.99.99")))
+}
+
+func TestRange(t *testing.T) {
+	for _, tc := range []struct {
+		r     string
+		in    []string
+		notIn []string
+	}{
+		{r: "*", in: []string{"0.0.0", "1.2.3", "99.0.0"}},
+		{r: "1.x", in: []string{"1.0.0", "1.99.1"}, notIn: []string{"0.9.0", "2.0.0"}},
+		{r: "1", in: []string{"1.0.0", "1.99.1"}, notIn: []string{"2.0.0"}},
+		{r: "1.2.x", in: []string{"1.2.0", "1.2.9"}, notIn: []string{"1.1.9", "1.3.0"}},
+		{r: "1.2.3", in: []string{"1.2.3"}, notIn: []string{"1.2.2", "1.2.4"}},
+		{r: "^1.2.3", in: []string{"1.2.3", "1.9.0"}, notIn: []string{"1.2.2", "2.0.0"}},
+		{r: "^0.2.3", in: []string{"0.2.3", "0.2.9"}, notIn: []string{"0.3.0", "1.0.0"}},
+		{r: "^0.0.3", in: []string{"0.0.3"}, notIn: []string{"0.0.4"}},
+		{r: "~1.2.3", in: []string{"1.2.3", "1.2.9"}, notIn: []string{"1.3.0"}},
+		{r: "~1", in: []string{"1.0.0", "1.9.0"}, notIn: []string{"2.0.0"}},
+		{r: ">=1.2.0,<1.5.0", in: []string{"1.2.0", "1.4.9"}, notIn: []string{"1.1.0", "1.5.0"}},
+		{r: ">1.2, <=2", in: []string{"1.2.1", "2.0.0"}, notIn: []string{"1.2.0", "2.0.1"}},
+		{r: "=1.0.0", in: []string{"1.0.0"}, notIn: []string{"1.0.1"}},
+	} {
+		r, err := protover.ParseRange(tc.r)
+		require.NoError(t, err, tc.r)
+		require.Equal(t, tc.r, r.String())
+		for _, v := range tc.in {
+			require.True(t, r.Contains(protover.MustParseVersion(v)), "%s should contain %s", tc.r, v)
+		}
+		for _, v := range tc.notIn {
+			require.False(t, r.Contains(protover.MustParseVersion(v)), "%s shouldn't contain %s", tc.r, v)
+		}
+	}
+
+	for _, s := range []string{"", "1.x.2", ">=", "^", "1.2.3.4", "a", ">=1.0.0,"} {
+		_, err := protover.ParseRange(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestNewFamily(t *testing.T) {
+	f, err := protover.NewFamily("/myapp/req/1.x")
+	require.NoError(t, err)
+	require.Equal(t, protocol.ID("/myapp/req"), f.Base())
+	require.Equal(t, protocol.ID("/myapp/req/1.x"), f.ID())
+	require.Equal(t, protocol.ID("/myapp/req/1.2.0"), f.ProtocolID(protover.MustParseVersion("1.2.0")))
+
+	v, ok := f.Version("/myapp/req/1.3.1")
+	require.True(t, ok)
+	require.Equal(t, protover.MustParseVersion("1.3.1"), v)
+	require.False(t, f.Match("/myapp/req/2.0.0"))
+	require.False(t, f.Match("/myapp/req/1.x"))
+	require.False(t, f.Match("/myapp/other/1.0.0"))
+
+	for _, id := range []protocol.ID{"", "1.x", "/myapp/req/", "/myapp/req/foo"} {
+		_, err := protover.NewFamily(id)
+		require.Error(t, err, id)
+	}
+}
+
+func newHosts(t *testing.T) (client, server host.Host) {
+	t.Helper()
+	var hosts [2]host.Host
+	for i := range hosts {
+		h, err := bhost.NewHost(swarmt.GenSwarm(t), nil)
+		require.NoError(t, err)
+		h.Start()
+		t.Cleanup(func() { h.Close() })
+		hosts[i] = h
+	}
+	require.NoError(t, hosts[0].Connect(context.Background(), peer.AddrInfo{ID: hosts[1].ID(), Addrs: hosts[1].Addrs()}))
+	return hosts[0], hosts[1]
+}
+
+func TestNegotiate(t *testing.T) {
+	client, server := newHosts(t)
+
+	serverFam, err := protover.NewFamily("/test/req/>=1.1.0,<1.4.0")
+	require.NoError(t, err)
+	negotiated := make(chan protover.Version, 1)
+	serverFam.Handle(server, func(s network.Stream, v protover.Version) {
+		require.Equal(t, serverFam.ProtocolID(v), s.Protocol())
+		negotiated <- v
+		s.Close()
+	})
+
+	clientFam, err := protover.NewFamily("/test/req/1.x")
+	require.NoError(t, err)
+	versions := func(vs ...string) []protover.Version {
+		res := make([]protover.Version, 0, len(vs))
+		for _, v := range vs {
+			res = append(res, protover.MustParseVersion(v))
+		}
+		return res
+	}
+
+	ctx := context.Background()
+	// the highest version accepted by the server is negotiated, regardless of the order
+	s, v, err := clientFam.NewStream(ctx, client, server.ID(), versions("1.0.0", "1.5.0", "1.3.2", "1.2.0", "2.0.0")...)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, protover.MustParseVersion("1.3.2"), v)
+	require.Equal(t, protover.MustParseVersion("1.3.2"), <-negotiated)
+
+	// the server advertised its family, so versions it doesn't accept fail early
+	require.Eventually(t, func() bool {
+		protos, _ := client.Peerstore().GetProtocols(server.ID())
+		for _, p := range protos {
+			if p == serverFam.ID() {
+				return true
+			}
+		}
+		return false
+	}, 5*time.Second, 10*time.Millisecond)
+	_, _, err = clientFam.NewStream(ctx, client, server.ID(), versions("1.0.0", "1.5.0")...)
+	require.ErrorIs(t, err, protover.ErrNoCommonVersion)
+
+	// without knowing the server's family, the negotiation fails
+	protos, err := client.Peerstore().GetProtocols(server.ID())
+	require.NoError(t, err)
+	require.NoError(t, client.Peerstore().RemoveProtocols(server.ID(), protos...))
+	_, _, err = clientFam.NewStream(ctx, client, server.ID(), versions("1.0.0", "1.5.0")...)
+	require.ErrorIs(t, err, protover.ErrNoCommonVersion)
+
+	serverFam.Unhandle(server)
+	_, _, err = clientFam.NewStream(ctx, client, server.ID(), versions("1.3.0")...)
+	require.ErrorIs(t, err, protover.ErrNoCommonVersion)
+}
@@ -0,0 +1,257 @@
+package protover
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version is a semantic version, without pre-release or build metadata.
+type Version struct {
+	Major, Minor, Patch uint64
+}
+
+// ParseVersion parses a version of the form MAJOR.MINOR.PATCH.
+func ParseVersion(s string) (Version, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("invalid version %q: expected MAJOR.MINOR.PATCH", s)
+	}
+	var nums [3]uint64
+	for i, p := range parts {
+		n, err := parseNumber(p)
+		if err != nil {
+			return Version{}, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// MustParseVersion is like ParseVersion, but panics if s is not a valid version.
+func MustParseVersion(s string) Version {
+	v, err := ParseVersion(s)
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func parseNumber(s string) (uint64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty number")
+	}
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("number %q has a leading zero", s)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Compare returns -1, 0 or 1 if v is lower than, equal to or higher than o.
+func (v Version) Compare(o Version) int {
+	switch {
+	case v.Major != o.Major:
+		return cmpUint(v.Major, o.Major)
+	case v.Minor != o.Minor:
+		return cmpUint(v.Minor, o.Minor)
+	default:
+		return cmpUint(v.Patch, o.Patch)
+	}
+}
+
+func cmpUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+type operator int
+
+const (
+	opEQ operator = iota
+	opGT
+	opGE
+	opLT
+	opLE
+)
+
+type comparator struct {
+	op operator
+	v  Version
+}
+
+func (c comparator) matches(v Version) bool {
+	cmp := v.Compare(c.v)
+	switch c.op {
+	case opEQ:
+		return cmp == 0
+	case opGT:
+		return cmp > 0
+	case opGE:
+		return cmp >= 0
+	case opLT:
+		return cmp < 0
+	case opLE:
+		return cmp <= 0
+	default:
+		return false
+	}
+}
+
+// Range is a set of versions. It is parsed from a comma separated list of
+// constraints, all of which a version must satisfy to be in the range:
+//
+//   - *: any version
+//   - 1.x, 1: >=1.0.0, <2.0.0
+//   - 1.2.x, 1.2: >=1.2.0, <1.3.0
+//   - 1.2.3: exactly 1.2.3
+//   - ^1.2.3: >=1.2.3, <2.0.0, or <0.3.0 for 0.2.3, following the semver compatibility rules
+//   - ~1.2.3: >=1.2.3, <1.3.0
+//   - >1.2.3, >=1.2.3, <1.2.3, <=1.2.3, =1.2.3: the comparison with 1.2.3
+//
+// For example, the range of versions 1.2.0 up to 1.4.x is >=1.2.0,<1.5.0.
+type Range struct {
+	s    string
+	cmps []comparator
+}
+
+// ParseRange parses a range, see Range.
+func ParseRange(s string) (Range, error) {
+	if s == "" {
+		return Range{}, fmt.Errorf("empty version range")
+	}
+	var cmps []comparator
+	for _, c := range strings.Split(s, ",") {
+		cs, err := parseConstraint(strings.TrimSpace(c))
+		if err != nil {
+			return Range{}, fmt.Errorf("invalid version range %q: %w", s, err)
+		}
+		cmps = append(cmps, cs...)
+	}
+	return Range{s: s, cmps: cmps}, nil
+}
+
+// MustParseRange is like ParseRange, but panics if s is not a valid range.
+func MustParseRange(s string) Range {
+	r, err := ParseRange(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// parseConstraint parses a single constraint into the comparators a version must
+// satisfy.
+func parseConstraint(c string) ([]comparator, error) {
+	for _, o := range []struct {
+		prefix string
+		op     operator
+	}{{">=", opGE}, {"<=", opLE}, {">", opGT}, {"<", opLT}, {"=", opEQ}} {
+		if rest, ok := strings.CutPrefix(c, o.prefix); ok {
+			v, _, err := parsePartial(rest)
+			if err != nil {
+				return nil, err
+			}
+			return []comparator{{op: o.op, v: v}}, nil
+		}
+	}
+	if rest, ok := strings.CutPrefix(c, "^"); ok {
+		v, n, err := parsePartial(rest)
+		if err != nil {
+			return nil, err
+		}
+		var upper Version
+		switch {
+		case v.Major > 0 || n == 1:
+			upper = Version{Major: v.Major + 1}
+		case v.Minor > 0 || n == 2:
+			upper = Version{Minor: v.Minor + 1}
+		default:
+			upper = Version{Patch: v.Patch + 1}
+		}
+		return []comparator{{op: opGE, v: v}, {op: opLT, v: upper}}, nil
+	}
+	if rest, ok := strings.CutPrefix(c, "~"); ok {
+		v, n, err := parsePartial(rest)
+		if err != nil {
+			return nil, err
+		}
+		upper := Version{Major: v.Major, Minor: v.Minor + 1}
+		if n == 1 {
+			upper = Version{Major: v.Major + 1}
+		}
+		return []comparator{{op: opGE, v: v}, {op: opLT, v: upper}}, nil
+	}
+
+	// an x-range or an exact version
+	v, n, err := parsePartial(c)
+	if err != nil {
+		return nil, err
+	}
+	switch n {
+	case 0:
+		return nil, nil
+	case 1:
+		return []comparator{{op: opGE, v: v}, {op: opLT, v: Version{Major: v.Major + 1}}}, nil
+	case 2:
+		return []comparator{{op: opGE, v: v}, {op: opLT, v: Version{Major: v.Major, Minor: v.Minor + 1}}}, nil
+	default:
+		return []comparator{{op: opEQ, v: v}}, nil
+	}
+}
+
+// parsePartial parses a version with optional minor and patch numbers, which may also
+// be wildcards (x, X or *). It returns the version, with the missing numbers set to 0,
+// and the number of numbers that were set.
+func parsePartial(s string) (Version, int, error) {
+	if s == "" {
+		return Version{}, 0, fmt.Errorf("empty version")
+	}
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, 0, fmt.Errorf("invalid version %q", s)
+	}
+	var nums [3]uint64
+	n := 0
+	for i, p := range parts {
+		if p == "x" || p == "X" || p == "*" {
+			// all following numbers must be wildcards as well
+			for _, q := range parts[i+1:] {
+				if q != "x" && q != "X" && q != "*" {
+					return Version{}, 0, fmt.Errorf("invalid version %q", s)
+				}
+			}
+			break
+		}
+		num, err := parseNumber(p)
+		if err != nil {
+			return Version{}, 0, fmt.Errorf("invalid version %q: %w", s, err)
+		}
+		nums[i] = num
+		n++
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, n, nil
+}
+
+// Contains returns whether v is in the range.
+func (r Range) Contains(v Version) bool {
+	for _, c := range r.cmps {
+		if !c.matches(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (r Range) String() string {
+	return r.s
+}
@@ -0,0 +1,70 @@
+package reqresp
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"google.golang.org/protobuf/proto"
+)
+
+// Codec encodes and decodes the requests and responses of a protocol. Other
+// encodings can be used by implementing this interface.
+type Codec interface {
+	// Name is used as the codec label of the metrics.
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(b []byte, v any) error
+}
+
+var (
+	// JSON encodes messages using encoding/json.
+	JSON Codec = jsonCodec{}
+	// Protobuf encodes messages using protocol buffers. The request and response
+	// types of the protocol must be generated protobuf messages.
+	Protobuf Codec = protobufCodec{}
+	// CBOR encodes messages using the deterministic CBOR encoding of RFC 8949.
+	CBOR Codec = cborCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                    { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)   { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(b []byte, v any) error { return json.Unmarshal(b, v) }
+
+type protobufCodec struct{}
+
+func (protobufCodec) Name() string { return "protobuf" }
+
+func (protobufCodec) Marshal(v any) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protobufCodec) Unmarshal(b []byte, v any) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a protobuf message", v)
+	}
+	return proto.Unmarshal(b, m)
+}
+
+// cborEncMode encodes messages deterministically, so that equal messages are encoded
+// to the same bytes.
+var cborEncMode = func() cbor.EncMode {
+	em, err := cbor.CoreDetEncOptions().EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return em
+}()
+
+type cborCodec struct{}
+
+func (cborCodec) Name() string                    { return "cbor" }
+func (cborCodec) Marshal(v any) ([]byte, error)   { return cborEncMode.Marshal(v) }
+func (cborCodec) Unmarshal(b []byte, v any) error { return cbor.Unmarshal(b, v) }
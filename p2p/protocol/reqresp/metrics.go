@@ -0,0 +1,103 @@
+package reqresp
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricNamespace = "libp2p_reqresp"
+
+var (
+	requestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "requests_total",
+			Help:      "Requests by protocol, direction and outcome",
+		},
+		[]string{"protocol", "codec", "dir", "outcome"},
+	)
+	requestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: metricNamespace,
+			Name:      "request_duration_seconds",
+			Help:      "Request duration",
+			Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+		},
+		[]string{"protocol", "dir"},
+	)
+
+	collectors = []prometheus.Collector{
+		requestsTotal,
+		requestDuration,
+	}
+)
+
+// MetricsTracer records the requests of request/response protocols.
+type MetricsTracer interface {
+	// RequestCompleted is called when a request was answered or failed.
+	RequestCompleted(proto protocol.ID, codec Codec, dir network.Direction, err error, d time.Duration)
+}
+
+type metricsTracer struct{}
+
+var _ MetricsTracer = &metricsTracer{}
+
+type metricsTracerSetting struct {
+	reg prometheus.Registerer
+}
+
+type MetricsTracerOption func(*metricsTracerSetting)
+
+func WithRegisterer(reg prometheus.Registerer) MetricsTracerOption {
+	return func(s *metricsTracerSetting) {
+		if reg != nil {
+			s.reg = reg
+		}
+	}
+}
+
+// NewMetricsTracer creates a MetricsTracer exporting prometheus metrics. It can be
+// shared by all protocols.
+func NewMetricsTracer(opts ...MetricsTracerOption) MetricsTracer {
+	setting := &metricsTracerSetting{reg: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt(setting)
+	}
+	metricshelper.RegisterCollectors(setting.reg, collectors...)
+	return &metricsTracer{}
+}
+
+func getOutcome(err error) string {
+	var rerr *RemoteError
+	switch {
+	case err == nil:
+		return "success"
+	case errors.As(err, &rerr):
+		return "remote_error"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	default:
+		return "failed"
+	}
+}
+
+func (mt *metricsTracer) RequestCompleted(proto protocol.ID, codec Codec, dir network.Direction, err error, d time.Duration) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	*tags = append(*tags, string(proto), codec.Name(), metricshelper.GetDirection(dir), getOutcome(err))
+	requestsTotal.WithLabelValues(*tags...).Inc()
+
+	*tags = (*tags)[:0]
+	*tags = append(*tags, string(proto), metricshelper.GetDirection(dir))
+	requestDuration.WithLabelValues(*tags...).Observe(d.Seconds())
+}
@@ -0,0 +1,341 @@
+// Package reqresp is a framework for typed request/response protocols over libp2p
+// streams.
+//
+// A protocol is defined once, with its request and response types and a codec:
+//
+//	echo, err := reqresp.New[EchoRequest, EchoResponse]("/myapp/echo/1.0.0", reqresp.JSON)
+//
+// JSON, Protobuf and CBOR codecs are provided.
+//
+// The server registers a handler on its host, and clients send requests:
+//
+//	echo.Handle(h, func(ctx context.Context, p peer.ID, req *EchoRequest) (*EchoResponse, error) {
+//		return &EchoResponse{Message: req.Message}, nil
+//	})
+//	resp, err := echo.Request(ctx, h, p, &EchoRequest{Message: "hello"})
+//
+// Every request is sent on a new stream. The request and the response are length
+// prefixed. The response is preceded by a status byte, so that errors returned by the
+// handler are reported to the client as a *RemoteError.
+package reqresp
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/protocol"
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
+	logging "github.com/ipfs/go-log/v2"
+)
+
+var log = logging.Logger("reqresp")
+
+const (
+	// DefaultServiceName is the resource manager service name of the streams, unless
+	// set with WithServiceName.
+	DefaultServiceName = "libp2p.reqresp"
+
+	defaultTimeout        = 10 * time.Second
+	defaultMaxMessageSize = 1 << 20
+
+	statusOK    byte = 0
+	statusError byte = 1
+)
+
+// ErrMessageTooLarge is returned when a request or response exceeds the maximum
+// message size.
+var ErrMessageTooLarge = errors.New("message too large")
+
+// RemoteError is returned by Request when the handler returned an error.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return "remote error: " + e.Message
+}
+
+// Handler handles a request from peer p. The context is cancelled when the request
+// times out. If the handler returns an error, its message is sent to the client.
+type Handler[Req, Resp any] func(ctx context.Context, p peer.ID, req *Req) (*Resp, error)
+
+type config struct {
+	timeout        time.Duration
+	maxMessageSize int
+	serviceName    string
+	metricsTracer  MetricsTracer
+}
+
+// Option is an option for a Protocol.
+type Option func(*config) error
+
+// WithTimeout sets the timeout of a request, including opening the stream and
+// reading the response. Requests time out earlier if their context has an earlier
+// deadline. The handler's context is cancelled after the timeout. Defaults to 10
+// seconds.
+func WithTimeout(d time.Duration) Option {
+	return func(c *config) error {
+		if d <= 0 {
+			return errors.New("timeout must be positive")
+		}
+		c.timeout = d
+		return nil
+	}
+}
+
+// WithMaxMessageSize sets the maximum size of encoded requests and responses.
+// Defaults to 1 MiB.
+func WithMaxMessageSize(n int) Option {
+	return func(c *config) error {
+		if n <= 0 {
+			return errors.New("max message size must be positive")
+		}
+		c.maxMessageSize = n
+		return nil
+	}
+}
+
+// WithServiceName sets the resource manager service name of the protocol's streams.
+func WithServiceName(name string) Option {
+	return func(c *config) error {
+		c.serviceName = name
+		return nil
+	}
+}
+
+// WithMetricsTracer records the requests of the protocol. By default, the requests are
+// recorded with the registerer of the host's reqresp metrics subsystem, if the host
+// implements metricshelper.RegistererProvider and the subsystem's metrics are enabled.
+func WithMetricsTracer(mt MetricsTracer) Option {
+	return func(c *config) error {
+		c.metricsTracer = mt
+		return nil
+	}
+}
+
+// Protocol is a request/response protocol with request type Req and response type
+// Resp. It is safe for concurrent use.
+type Protocol[Req, Resp any] struct {
+	id    protocol.ID
+	codec Codec
+	cfg   config
+
+	// hostTracers caches the default metrics tracers of the hosts, see metricsTracer.
+	hostTracers sync.Map // host.Host -> MetricsTracer
+}
+
+// New defines a request/response protocol.
+func New[Req, Resp any](id protocol.ID, codec Codec, opts ...Option) (*Protocol[Req, Resp], error) {
+	cfg := config{
+		timeout:        defaultTimeout,
+		maxMessageSize: defaultMaxMessageSize,
+		serviceName:    DefaultServiceName,
+	}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	return &Protocol[Req, Resp]{id: id, codec: codec, cfg: cfg}, nil
+}
+
+// ID returns the protocol ID.
+func (p *Protocol[Req, Resp]) ID() protocol.ID {
+	return p.id
+}
+
+// Handle registers the handler for the protocol on h.
+func (p *Protocol[Req, Resp]) Handle(h host.Host, handler Handler[Req, Resp]) {
+	mt := p.metricsTracer(h)
+	h.SetStreamHandler(p.id, func(s network.Stream) {
+		p.handleStream(s, handler, mt)
+	})
+}
+
+// Unhandle removes the protocol's handler from h.
+func (p *Protocol[Req, Resp]) Unhandle(h host.Host) {
+	h.RemoveStreamHandler(p.id)
+}
+
+// metricsTracer returns the metrics tracer of the requests handled or sent by h: the
+// one set with WithMetricsTracer, or the one reporting to the host's registerer. It
+// returns nil if metrics are disabled.
+func (p *Protocol[Req, Resp]) metricsTracer(h host.Host) MetricsTracer {
+	if p.cfg.metricsTracer != nil {
+		return p.cfg.metricsTracer
+	}
+	if mt, ok := p.hostTracers.Load(h); ok {
+		return mt.(MetricsTracer)
+	}
+	var mt MetricsTracer
+	if rp, ok := h.(metricshelper.RegistererProvider); ok {
+		if reg, ok := rp.MetricsRegisterer(metricshelper.SubsystemReqResp); ok {
+			mt = NewMetricsTracer(WithRegisterer(reg))
+		}
+	}
+	p.hostTracers.Store(h, mt)
+	return mt
+}
+
+func (p *Protocol[Req, Resp]) handleStream(s network.Stream, handler Handler[Req, Resp], mt MetricsTracer) {
+	start := time.Now()
+	err := p.serve(s, handler)
+	var rerr *RemoteError
+	if err == nil || errors.As(err, &rerr) {
+		s.Close()
+	} else {
+		log.Debugw("error handling request", "protocol", p.id, "peer", s.Conn().RemotePeer(), "error", err)
+		s.Reset()
+	}
+	if mt != nil {
+		mt.RequestCompleted(p.id, p.codec, network.DirInbound, err, time.Since(start))
+	}
+}
+
+func (p *Protocol[Req, Resp]) serve(s network.Stream, handler Handler[Req, Resp]) error {
+	if err := s.Scope().SetService(p.cfg.serviceName); err != nil {
+		return fmt.Errorf("error attaching stream to service: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), p.cfg.timeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	_ = s.SetDeadline(deadline)
+
+	b, err := readMsg(bufio.NewReader(s), p.cfg.maxMessageSize)
+	if err != nil {
+		return err
+	}
+	req := new(Req)
+	if err := p.codec.Unmarshal(b, req); err != nil {
+		return fmt.Errorf("invalid request: %w", err)
+	}
+
+	resp, herr := handler(ctx, s.Conn().RemotePeer(), req)
+	if herr != nil {
+		if err := writeResponse(s, statusError, []byte(herr.Error())); err != nil {
+			return err
+		}
+		return &RemoteError{Message: herr.Error()}
+	}
+	b, err = p.codec.Marshal(resp)
+	if err != nil {
+		return err
+	}
+	if len(b) > p.cfg.maxMessageSize {
+		return ErrMessageTooLarge
+	}
+	return writeResponse(s, statusOK, b)
+}
+
+// Request sends the request to peer to and returns the response.
+func (p *Protocol[Req, Resp]) Request(ctx context.Context, h host.Host, to peer.ID, req *Req) (_ *Resp, err error) {
+	start := time.Now()
+	if mt := p.metricsTracer(h); mt != nil {
+		defer func() {
+			mt.RequestCompleted(p.id, p.codec, network.DirOutbound, err, time.Since(start))
+		}()
+	}
+
+	b, err := p.codec.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > p.cfg.maxMessageSize {
+		return nil, ErrMessageTooLarge
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.cfg.timeout)
+	defer cancel()
+	s, err := h.NewStream(ctx, to, p.id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Scope().SetService(p.cfg.serviceName); err != nil {
+		s.Reset()
+		return nil, fmt.Errorf("error attaching stream to service: %w", err)
+	}
+	// unblock reads and writes when the context is done
+	stop := context.AfterFunc(ctx, func() { s.Reset() })
+	defer stop()
+
+	if err := writeRequest(s, b); err != nil {
+		s.Reset()
+		return nil, err
+	}
+	_ = s.CloseWrite()
+
+	r := bufio.NewReader(s)
+	status, err := r.ReadByte()
+	if err != nil {
+		s.Reset()
+		return nil, ctxErr(ctx, err)
+	}
+	b, err = readMsg(r, p.cfg.maxMessageSize)
+	if err != nil {
+		s.Reset()
+		return nil, ctxErr(ctx, err)
+	}
+	s.Close()
+
+	switch status {
+	case statusOK:
+	case statusError:
+		return nil, &RemoteError{Message: string(b)}
+	default:
+		return nil, fmt.Errorf("invalid response status %d", status)
+	}
+	resp := new(Resp)
+	if err := p.codec.Unmarshal(b, resp); err != nil {
+		return nil, fmt.Errorf("invalid response: %w", err)
+	}
+	return resp, nil
+}
+
+// ctxErr returns the context's error if the context is done, as it is the cause of
+// err.
+func ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func writeRequest(w io.Writer, b []byte) error {
+	buf := make([]byte, 0, binary.MaxVarintLen64+len(b))
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	_, err := w.Write(append(buf, b...))
+	return err
+}
+
+func writeResponse(w io.Writer, status byte, b []byte) error {
+	buf := make([]byte, 0, 1+binary.MaxVarintLen64+len(b))
+	buf = append(buf, status)
+	buf = binary.AppendUvarint(buf, uint64(len(b)))
+	_, err := w.Write(append(buf, b...))
+	return err
+}
+
+func readMsg(r *bufio.Reader, maxSize int) ([]byte, error) {
+	l, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+	if l > uint64(maxSize) {
+		return nil, ErrMessageTooLarge
+	}
+	b := make([]byte, l)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
@@ -0,0 +1,173 @@
+package reqresp_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	bhost "github.com/TheNoobiCat/go-libp2p/p2p/host/basic"
+	swarmt "github.com/TheNoobiCat/go-libp2p/p2p/net/swarm/testing"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/reqresp"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type echoRequest struct {
+	Message string
+	Fail    bool
+	Delay   time.Duration
+}
+
+type echoResponse struct {
+	Message string
+}
+
+func newHosts(t *testing.T, opts ...*bhost.HostOpts) (client, server host.Host) {
+	t.Helper()
+	var hosts [2]host.Host
+	for i := range hosts {
+		var o *bhost.HostOpts
+		if len(opts) > 0 {
+			o = opts[0]
+		}
+		h, err := bhost.NewHost(swarmt.GenSwarm(t), o)
+		require.NoError(t, err)
+		h.Start()
+		t.Cleanup(func() { h.Close() })
+		hosts[i] = h
+	}
+	require.NoError(t, hosts[0].Connect(context.Background(), peer.AddrInfo{ID: hosts[1].ID(), Addrs: hosts[1].Addrs()}))
+	return hosts[0], hosts[1]
+}
+
+func TestRequest(t *testing.T) {
+	client, server := newHosts(t)
+	reg := prometheus.NewRegistry()
+	echo, err := reqresp.New[echoRequest, echoResponse]("/test/echo/1.0.0", reqresp.JSON,
+		reqresp.WithTimeout(time.Second),
+		reqresp.WithMetricsTracer(reqresp.NewMetricsTracer(reqresp.WithRegisterer(reg))),
+	)
+	require.NoError(t, err)
+	echo.Handle(server, func(ctx context.Context, p peer.ID, req *echoRequest) (*echoResponse, error) {
+		require.Equal(t, client.ID(), p)
+		if req.Fail {
+			return nil, errors.New("failed as requested")
+		}
+		select {
+		case <-time.After(req.Delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return &echoResponse{Message: req.Message}, nil
+	})
+
+	// the collectors are global, count relative to the initial values
+	initial := make(map[string]float64)
+	for _, o := range []string{"outbound/success", "outbound/remote_error", "outbound/timeout", "inbound/success"} {
+		dir, outcome, _ := strings.Cut(o, "/")
+		initial[o] = requests(t, reg, dir, outcome)
+	}
+
+	ctx := context.Background()
+	resp, err := echo.Request(ctx, client, server.ID(), &echoRequest{Message: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "hello", resp.Message)
+
+	_, err = echo.Request(ctx, client, server.ID(), &echoRequest{Fail: true})
+	var rerr *reqresp.RemoteError
+	require.ErrorAs(t, err, &rerr)
+	require.Equal(t, "failed as requested", rerr.Message)
+
+	_, err = echo.Request(ctx, client, server.ID(), &echoRequest{Delay: time.Minute})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = echo.Request(ctx, client, server.ID(), &echoRequest{Message: strings.Repeat("a", 2<<20)})
+	require.ErrorIs(t, err, reqresp.ErrMessageTooLarge)
+
+	require.Equal(t, initial["outbound/success"]+1, requests(t, reg, "outbound", "success"))
+	require.Equal(t, initial["outbound/remote_error"]+1, requests(t, reg, "outbound", "remote_error"))
+	require.Equal(t, initial["outbound/timeout"]+1, requests(t, reg, "outbound", "timeout"))
+	require.Eventually(t, func() bool {
+		return requests(t, reg, "inbound", "success") == initial["inbound/success"]+1
+	}, time.Second, 10*time.Millisecond)
+
+	echo.Unhandle(server)
+	_, err = echo.Request(ctx, client, server.ID(), &echoRequest{Message: "hello"})
+	require.Error(t, err)
+}
+
+func requests(t *testing.T, reg *prometheus.Registry, dir, outcome string) float64 {
+	t.Helper()
+	mfs, err := reg.Gather()
+	require.NoError(t, err)
+	var v float64
+	for _, mf := range mfs {
+		if mf.GetName() != "libp2p_reqresp_requests_total" {
+			continue
+		}
+	next:
+		for _, m := range mf.GetMetric() {
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "dir":
+					if l.GetValue() != dir {
+						continue next
+					}
+				case "outcome":
+					if l.GetValue() != outcome {
+						continue next
+					}
+				}
+			}
+			v += m.GetCounter().GetValue()
+		}
+	}
+	return v
+}
+
+func TestProtobufCodec(t *testing.T) {
+	client, server := newHosts(t)
+	upper, err := reqresp.New[wrapperspb.StringValue, wrapperspb.StringValue]("/test/upper/1.0.0", reqresp.Protobuf)
+	require.NoError(t, err)
+	upper.Handle(server, func(_ context.Context, _ peer.ID, req *wrapperspb.StringValue) (*wrapperspb.StringValue, error) {
+		return wrapperspb.String(strings.ToUpper(req.GetValue())), nil
+	})
+
+	resp, err := upper.Request(context.Background(), client, server.ID(), wrapperspb.String("hello"))
+	require.NoError(t, err)
+	require.Equal(t, "HELLO", resp.GetValue())
+}
+
+func TestCBORCodec(t *testing.T) {
+	client, server := newHosts(t)
+	echo, err := reqresp.New[echoRequest, echoResponse]("/test/echo-cbor/1.0.0", reqresp.CBOR)
+	require.NoError(t, err)
+	echo.Handle(server, func(_ context.Context, _ peer.ID, req *echoRequest) (*echoResponse, error) {
+		return &echoResponse{Message: req.Message}, nil
+	})
+
+	resp, err := echo.Request(context.Background(), client, server.ID(), &echoRequest{Message: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, "hello", resp.Message)
+}
+
+func TestHostMetricsTracer(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	client, server := newHosts(t, &bhost.HostOpts{EnableMetrics: true, PrometheusRegisterer: reg})
+	echo, err := reqresp.New[echoRequest, echoResponse]("/test/echo-metrics/1.0.0", reqresp.JSON)
+	require.NoError(t, err)
+	echo.Handle(server, func(_ context.Context, _ peer.ID, req *echoRequest) (*echoResponse, error) {
+		return &echoResponse{Message: req.Message}, nil
+	})
+
+	initial := requests(t, reg, "outbound", "success")
+	_, err = echo.Request(context.Background(), client, server.ID(), &echoRequest{Message: "hello"})
+	require.NoError(t, err)
+	require.Equal(t, initial+1, requests(t, reg, "outbound", "success"))
+}
@@ -0,0 +1,90 @@
+package noise
+
+import (
+	pool "github.com/libp2p/go-buffer-pool"
+)
+
+// BufferPool provides the buffers used by Noise sessions to frame the handshake
+// and transport messages. It must be safe for concurrent use.
+// *pool.BufferPool from github.com/libp2p/go-buffer-pool implements it, and
+// pool.GlobalPool is used by default.
+type BufferPool interface {
+	// Get returns a buffer of the given length.
+	Get(length int) []byte
+	// Put returns a buffer obtained from Get to the pool.
+	Put(buf []byte)
+}
+
+var _ BufferPool = pool.GlobalPool
+
+// bufferConfig configures the buffers of a session.
+type bufferConfig struct {
+	// pool is the pool buffers are taken from. pool.GlobalPool if nil.
+	pool BufferPool
+	// zeroAlloc makes the session keep a read and a write buffer of the maximum
+	// message size for its lifetime, instead of taking them from the pool for
+	// every message.
+	zeroAlloc bool
+}
+
+func (c bufferConfig) getPool() BufferPool {
+	if c.pool == nil {
+		return pool.GlobalPool
+	}
+	return c.pool
+}
+
+// getReadBuffer returns a buffer of length n to read a transport message into.
+// It must be released with putReadBuffer. The caller must hold the read lock.
+func (s *secureSession) getReadBuffer(n int) []byte {
+	if !s.zeroAlloc {
+		return s.bufPool.Get(n)
+	}
+	if s.rbuf == nil {
+		s.rbuf = s.bufPool.Get(MaxTransportMsgLength)
+	}
+	return s.rbuf[:n]
+}
+
+func (s *secureSession) putReadBuffer(b []byte) {
+	if !s.zeroAlloc {
+		s.bufPool.Put(b)
+	}
+}
+
+// getWriteBuffer returns a buffer of length n to frame a transport message into.
+// It must be released with putWriteBuffer. The caller must hold the write lock.
+func (s *secureSession) getWriteBuffer(n int) []byte {
+	if !s.zeroAlloc {
+		return s.bufPool.Get(n)
+	}
+	if s.wbuf == nil {
+		s.wbuf = s.bufPool.Get(MaxTransportMsgLength + LengthPrefixLength)
+	}
+	return s.wbuf[:n]
+}
+
+func (s *secureSession) putWriteBuffer(b []byte) {
+	if !s.zeroAlloc {
+		s.bufPool.Put(b)
+	}
+}
+
+// releaseBuffers returns the buffers kept by the session in zero allocation mode
+// to the pool. It is called once the session is closed.
+func (s *secureSession) releaseBuffers() {
+	s.readLock.Lock()
+	if s.rbuf != nil {
+		s.bufPool.Put(s.rbuf)
+		// qbuf is a view of rbuf
+		s.rbuf, s.qbuf, s.qseek = nil, nil, 0
+	}
+	s.readLock.Unlock()
+
+	s.writeLock.Lock()
+	if s.wbuf != nil {
+		s.bufPool.Put(s.wbuf)
+		s.wbuf = nil
+	}
+	s.writeLock.Unlock()
+}
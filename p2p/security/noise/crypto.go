@@ -1,9 +1,63 @@
 package noise
 
 import (
+	"crypto/cipher"
+	"encoding/binary"
 	"errors"
+
+	"github.com/flynn/noise"
+	"golang.org/x/crypto/chacha20poly1305"
 )
 
+// cipherState protects the transport messages of a session once the handshake
+// is complete. It's equivalent to a noise.CipherState using ChaChaPoly, but
+// reuses the nonce buffer, so that encrypting and decrypting don't allocate.
+type cipherState struct {
+	aead  cipher.AEAD
+	n     uint64
+	nonce [chacha20poly1305.NonceSize]byte
+}
+
+func newCipherState(cs *noise.CipherState) (*cipherState, error) {
+	k := cs.UnsafeKey()
+	aead, err := chacha20poly1305.New(k[:])
+	if err != nil {
+		return nil, err
+	}
+	return &cipherState{aead: aead, n: cs.Nonce()}, nil
+}
+
+// setNonce encodes the nonce as specified by the Noise ChaChaPoly cipher functions:
+// 32 bits of zeros followed by the little-endian encoding of n.
+func (c *cipherState) setNonce() error {
+	if c.n > noise.MaxNonce {
+		return noise.ErrMaxNonce
+	}
+	binary.LittleEndian.PutUint64(c.nonce[4:], c.n)
+	return nil
+}
+
+func (c *cipherState) Encrypt(out, ad, plaintext []byte) ([]byte, error) {
+	if err := c.setNonce(); err != nil {
+		return nil, err
+	}
+	out = c.aead.Seal(out, c.nonce[:], plaintext, ad)
+	c.n++
+	return out, nil
+}
+
+func (c *cipherState) Decrypt(out, ad, ciphertext []byte) ([]byte, error) {
+	if err := c.setNonce(); err != nil {
+		return nil, err
+	}
+	out, err := c.aead.Open(out, c.nonce[:], ciphertext, ad)
+	if err != nil {
+		return nil, err
+	}
+	c.n++
+	return out, nil
+}
+
 // encrypt calls the cipher's encryption. It encrypts the provided plaintext,
 // slice-appending the ciphertext on out.
 //
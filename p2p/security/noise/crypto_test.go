@@ -3,10 +3,14 @@ package noise
 import (
 	"bytes"
 	"context"
+	"crypto/rand"
 	"net"
 	"testing"
 
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+
+	"github.com/flynn/noise"
+	"github.com/stretchr/testify/require"
 )
 
 func TestEncryptAndDecrypt_InitToResp(t *testing.T) {
@@ -93,7 +97,7 @@ func TestCryptoFailsIfHandshakeIncomplete(t *testing.T) {
 	init, resp := net.Pipe()
 	_ = resp.Close()
 
-	session, _ := newSecureSession(initTransport, context.TODO(), init, "remote-peer", nil, nil, nil, true, true)
+	session, _ := newSecureSession(initTransport, context.TODO(), init, "remote-peer", nil, nil, nil, bufferConfig{}, true, true)
 	_, err := session.encrypt(nil, []byte("hi"))
 	if err == nil {
 		t.Error("expected encryption error when handshake incomplete")
@@ -103,3 +107,30 @@ func TestCryptoFailsIfHandshakeIncomplete(t *testing.T) {
 		t.Error("expected decryption error when handshake incomplete")
 	}
 }
+
+func TestCipherStateMatchesNoise(t *testing.T) {
+	var k [32]byte
+	rand.Read(k[:])
+	ref := noise.UnsafeNewCipherState(cipherSuite, k, 0)
+	cs, err := newCipherState(noise.UnsafeNewCipherState(cipherSuite, k, 0))
+	require.NoError(t, err)
+	dec, err := newCipherState(noise.UnsafeNewCipherState(cipherSuite, k, 0))
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		plaintext := []byte("message")
+		want, err := ref.Encrypt(nil, nil, plaintext)
+		require.NoError(t, err)
+		got, err := cs.Encrypt(nil, nil, plaintext)
+		require.NoError(t, err)
+		require.Equal(t, want, got)
+
+		res, err := dec.Decrypt(nil, nil, got)
+		require.NoError(t, err)
+		require.Equal(t, plaintext, res)
+	}
+
+	cs.n = noise.MaxNonce + 1
+	_, err = cs.Encrypt(nil, nil, []byte("message"))
+	require.ErrorIs(t, err, noise.ErrMaxNonce)
+}
@@ -15,7 +15,6 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/p2p/security/noise/pb"
 
 	"github.com/flynn/noise"
-	pool "github.com/libp2p/go-buffer-pool"
 	"google.golang.org/protobuf/proto"
 )
 
@@ -64,8 +63,8 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 	}
 
 	// We can re-use this buffer for all handshake messages.
-	hbuf := pool.Get(2 << 10)
-	defer pool.Put(hbuf)
+	hbuf := s.bufPool.Get(2 << 10)
+	defer s.bufPool.Put(hbuf)
 
 	if s.initiator {
 		// stage 0 //
@@ -80,6 +79,7 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 			return fmt.Errorf("error reading handshake message: %w", err)
 		}
 		rcvdEd, err := s.handleRemoteHandshakePayload(plaintext, hs.PeerStatic())
+		s.bufPool.Put(plaintext)
 		if err != nil {
 			return err
 		}
@@ -99,15 +99,19 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 		if err != nil {
 			return err
 		}
-		if err := s.sendHandshakeMessage(hs, payload, hbuf); err != nil {
+		err = s.sendHandshakeMessage(hs, payload, hbuf)
+		s.bufPool.Put(payload)
+		if err != nil {
 			return fmt.Errorf("error sending handshake message: %w", err)
 		}
 		return nil
 	} else {
 		// stage 0 //
-		if _, err := s.readHandshakeMessage(hs); err != nil {
+		plaintext, err := s.readHandshakeMessage(hs)
+		if err != nil {
 			return fmt.Errorf("error reading handshake message: %w", err)
 		}
+		s.bufPool.Put(plaintext)
 
 		// stage 1 //
 		// Handshake Msg Len = len(DH ephemeral key) + len(DHT static key) +  MAC(static key is encrypted) + len(Payload) +
@@ -120,16 +124,19 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 		if err != nil {
 			return err
 		}
-		if err := s.sendHandshakeMessage(hs, payload, hbuf); err != nil {
+		err = s.sendHandshakeMessage(hs, payload, hbuf)
+		s.bufPool.Put(payload)
+		if err != nil {
 			return fmt.Errorf("error sending handshake message: %w", err)
 		}
 
 		// stage 2 //
-		plaintext, err := s.readHandshakeMessage(hs)
+		plaintext, err = s.readHandshakeMessage(hs)
 		if err != nil {
 			return fmt.Errorf("error reading handshake message: %w", err)
 		}
 		rcvdEd, err := s.handleRemoteHandshakePayload(plaintext, hs.PeerStatic())
+		s.bufPool.Put(plaintext)
 		if err != nil {
 			return err
 		}
@@ -147,14 +154,16 @@ func (s *secureSession) runHandshake(ctx context.Context) (err error) {
 //
 // It is called when the final handshake message is processed by
 // either sendHandshakeMessage or readHandshakeMessage.
-func (s *secureSession) setCipherStates(cs1, cs2 *noise.CipherState) {
-	if s.initiator {
-		s.enc = cs1
-		s.dec = cs2
-	} else {
-		s.enc = cs2
-		s.dec = cs1
+func (s *secureSession) setCipherStates(cs1, cs2 *noise.CipherState) error {
+	if !s.initiator {
+		cs1, cs2 = cs2, cs1
+	}
+	var err error
+	if s.enc, err = newCipherState(cs1); err != nil {
+		return err
 	}
+	s.dec, err = newCipherState(cs2)
+	return err
 }
 
 // sendHandshakeMessage sends the next handshake message in the sequence.
@@ -179,7 +188,7 @@ func (s *secureSession) sendHandshakeMessage(hs *noise.HandshakeState, payload [
 	}
 
 	if cs1 != nil && cs2 != nil {
-		s.setCipherStates(cs1, cs2)
+		return s.setCipherStates(cs1, cs2)
 	}
 	return nil
 }
@@ -187,7 +196,9 @@ func (s *secureSession) sendHandshakeMessage(hs *noise.HandshakeState, payload [
 // readHandshakeMessage reads a message from the insecure conn and tries to
 // process it as the expected next message in the handshake sequence.
 //
-// If the message contains a payload, it will be decrypted and returned.
+// If the message contains a payload, it will be decrypted and returned. The
+// returned buffer is taken from the session's buffer pool, and should be returned
+// to it once the payload has been handled.
 //
 // If this is the final message in the sequence, it calls setCipherStates
 // to initialize cipher states.
@@ -197,25 +208,32 @@ func (s *secureSession) readHandshakeMessage(hs *noise.HandshakeState) ([]byte,
 		return nil, err
 	}
 
-	buf := pool.Get(l)
-	defer pool.Put(buf)
+	buf := s.bufPool.Get(l)
+	defer s.bufPool.Put(buf)
 
 	if err := s.readNextMsgInsecure(buf); err != nil {
 		return nil, err
 	}
 
-	msg, cs1, cs2, err := hs.ReadMessage(nil, buf)
+	// the plaintext is shorter than the message
+	out := s.bufPool.Get(l)
+	msg, cs1, cs2, err := hs.ReadMessage(out[:0], buf)
 	if err != nil {
+		s.bufPool.Put(out)
 		return nil, err
 	}
 	if cs1 != nil && cs2 != nil {
-		s.setCipherStates(cs1, cs2)
+		if err := s.setCipherStates(cs1, cs2); err != nil {
+			s.bufPool.Put(out)
+			return nil, err
+		}
 	}
 	return msg, nil
 }
 
 // generateHandshakePayload creates a libp2p handshake payload with a
-// signature of our static noise key.
+// signature of our static noise key. The returned buffer is taken from the
+// session's buffer pool, and should be returned to it once the payload is sent.
 func (s *secureSession) generateHandshakePayload(localStatic noise.DHKey, ext *pb.NoiseExtensions) ([]byte, error) {
 	// obtain the public key from the handshake session, so we can sign it with
 	// our libp2p secret key.
@@ -225,19 +243,24 @@ func (s *secureSession) generateHandshakePayload(localStatic noise.DHKey, ext *p
 	}
 
 	// prepare payload to sign; perform signature.
-	toSign := append([]byte(payloadSigPrefix), localStatic.Public...)
+	toSign := s.bufPool.Get(len(payloadSigPrefix) + len(localStatic.Public))
+	copy(toSign[copy(toSign, payloadSigPrefix):], localStatic.Public)
 	signedPayload, err := s.localKey.Sign(toSign)
+	s.bufPool.Put(toSign)
 	if err != nil {
 		return nil, fmt.Errorf("error sigining handshake payload: %w", err)
 	}
 
 	// create payload
-	payloadEnc, err := proto.Marshal(&pb.NoiseHandshakePayload{
+	payload := &pb.NoiseHandshakePayload{
 		IdentityKey: localKeyRaw,
 		IdentitySig: signedPayload,
 		Extensions:  ext,
-	})
+	}
+	buf := s.bufPool.Get(proto.Size(payload))
+	payloadEnc, err := proto.MarshalOptions{}.MarshalAppend(buf[:0], payload)
 	if err != nil {
+		s.bufPool.Put(buf)
 		return nil, fmt.Errorf("error marshaling handshake payload: %w", err)
 	}
 	return payloadEnc, nil
@@ -271,8 +294,10 @@ func (s *secureSession) handleRemoteHandshakePayload(payload []byte, remoteStati
 
 	// verify payload is signed by asserted remote libp2p key.
 	sig := nhp.GetIdentitySig()
-	msg := append([]byte(payloadSigPrefix), remoteStatic...)
+	msg := s.bufPool.Get(len(payloadSigPrefix) + len(remoteStatic))
+	copy(msg[copy(msg, payloadSigPrefix):], remoteStatic)
 	ok, err := remotePubKey.Verify(msg, sig)
+	s.bufPool.Put(msg)
 	if err != nil {
 		return nil, fmt.Errorf("error verifying signature: %w", err)
 	} else if !ok {
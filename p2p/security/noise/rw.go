@@ -3,8 +3,8 @@ package noise
 import (
 	"encoding/binary"
 	"io"
+	"net"
 
-	pool "github.com/libp2p/go-buffer-pool"
 	"golang.org/x/crypto/chacha20poly1305"
 )
 
@@ -27,6 +27,10 @@ func (s *secureSession) Read(buf []byte) (int, error) {
 	s.readLock.Lock()
 	defer s.readLock.Unlock()
 
+	if s.closed.Load() {
+		return 0, net.ErrClosed
+	}
+
 	// 1. If we have queued received bytes:
 	//   1a. If len(buf) < len(queued), saturate buf, update seek pointer, return.
 	//   1b. If len(buf) >= len(queued), copy remaining to buf, release queued buffer back into pool, return.
@@ -42,7 +46,7 @@ func (s *secureSession) Read(buf []byte) (int, error) {
 		s.qseek += copied
 		if s.qseek == len(s.qbuf) {
 			// queued buffer is now empty, reset and release.
-			pool.Put(s.qbuf)
+			s.putReadBuffer(s.qbuf)
 			s.qseek, s.qbuf = 0, nil
 		}
 		return copied, nil
@@ -71,12 +75,14 @@ func (s *secureSession) Read(buf []byte) (int, error) {
 
 	// otherwise, we get a buffer from the pool so we can read the message into it
 	// and then decrypt in place, since we're retaining the buffer (or a view thereof).
-	cbuf := pool.Get(nextMsgLen)
+	cbuf := s.getReadBuffer(nextMsgLen)
 	if err := s.readNextMsgInsecure(cbuf); err != nil {
+		s.putReadBuffer(cbuf)
 		return 0, err
 	}
 
 	if s.qbuf, err = s.decrypt(cbuf[:0], cbuf); err != nil {
+		s.putReadBuffer(cbuf)
 		return 0, err
 	}
 
@@ -92,6 +98,10 @@ func (s *secureSession) Write(data []byte) (int, error) {
 	s.writeLock.Lock()
 	defer s.writeLock.Unlock()
 
+	if s.closed.Load() {
+		return 0, net.ErrClosed
+	}
+
 	var (
 		written int
 		cbuf    []byte
@@ -99,12 +109,12 @@ func (s *secureSession) Write(data []byte) (int, error) {
 	)
 
 	if total < MaxPlaintextLength {
-		cbuf = pool.Get(total + chacha20poly1305.Overhead + LengthPrefixLength)
+		cbuf = s.getWriteBuffer(total + chacha20poly1305.Overhead + LengthPrefixLength)
 	} else {
-		cbuf = pool.Get(MaxTransportMsgLength + LengthPrefixLength)
+		cbuf = s.getWriteBuffer(MaxTransportMsgLength + LengthPrefixLength)
 	}
 
-	defer pool.Put(cbuf)
+	defer s.putWriteBuffer(cbuf)
 
 	for written < total {
 		end := written + MaxPlaintextLength
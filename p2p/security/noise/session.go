@@ -5,10 +5,9 @@ import (
 	"context"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/flynn/noise"
-
 	"github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
@@ -35,8 +34,17 @@ type secureSession struct {
 	qbuf  []byte  // queued bytes buffer.
 	rlen  [2]byte // work buffer to read in the incoming message length.
 
-	enc *noise.CipherState
-	dec *noise.CipherState
+	bufPool   BufferPool
+	zeroAlloc bool
+	// closed is set when the session is closed, so that reads and writes don't take
+	// buffers anymore.
+	closed atomic.Bool
+	// rbuf and wbuf are the read and write buffers kept for the lifetime of the
+	// session in zero allocation mode.
+	rbuf, wbuf []byte
+
+	enc *cipherState
+	dec *cipherState
 
 	// noise prologue
 	prologue []byte
@@ -49,7 +57,7 @@ type secureSession struct {
 
 // newSecureSession creates a Noise session over the given insecureConn Conn, using
 // the libp2p identity keypair from the given Transport.
-func newSecureSession(tpt *Transport, ctx context.Context, insecure net.Conn, remote peer.ID, prologue []byte, initiatorEDH, responderEDH EarlyDataHandler, initiator, checkPeerID bool) (*secureSession, error) {
+func newSecureSession(tpt *Transport, ctx context.Context, insecure net.Conn, remote peer.ID, prologue []byte, initiatorEDH, responderEDH EarlyDataHandler, bufs bufferConfig, initiator, checkPeerID bool) (*secureSession, error) {
 	s := &secureSession{
 		insecureConn:              insecure,
 		insecureReader:            bufio.NewReader(insecure),
@@ -61,6 +69,8 @@ func newSecureSession(tpt *Transport, ctx context.Context, insecure net.Conn, re
 		initiatorEarlyDataHandler: initiatorEDH,
 		responderEarlyDataHandler: responderEDH,
 		checkPeerID:               checkPeerID,
+		bufPool:                   bufs.getPool(),
+		zeroAlloc:                 bufs.zeroAlloc,
 	}
 
 	// the go-routine we create to run the handshake will
@@ -128,7 +138,13 @@ func (s *secureSession) SetWriteDeadline(t time.Time) error {
 }
 
 func (s *secureSession) Close() error {
-	return s.insecureConn.Close()
+	s.closed.Store(true)
+	err := s.insecureConn.Close()
+	if s.zeroAlloc {
+		// closing the conn unblocks pending reads and writes
+		s.releaseBuffers()
+	}
+	return err
 }
 
 func SessionWithConnState(s *secureSession, muxer protocol.ID) *secureSession {
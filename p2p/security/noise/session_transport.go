@@ -62,6 +62,28 @@ func DisablePeerIDCheck() SessionOption {
 	}
 }
 
+// WithBufferPool makes the sessions take the buffers used for the handshake and
+// for framing the transport messages from p, instead of the global buffer pool.
+func WithBufferPool(p BufferPool) SessionOption {
+	return func(s *SessionTransport) error {
+		s.buffers.pool = p
+		return nil
+	}
+}
+
+// ZeroAllocation enables the strict zero allocation mode: every session keeps a
+// read and a write buffer of the maximum message size (64 KiB each) for its
+// lifetime, so that reading and writing never allocate nor touch the buffer pool.
+// This trades memory per connection for less GC pressure, which pays off for
+// connections carrying a lot of traffic, e.g. on relays. The buffers are returned
+// to the pool when the session is closed.
+func ZeroAllocation() SessionOption {
+	return func(s *SessionTransport) error {
+		s.buffers.zeroAlloc = true
+		return nil
+	}
+}
+
 var _ sec.SecureTransport = &SessionTransport{}
 
 // SessionTransport can be used
@@ -71,6 +93,7 @@ type SessionTransport struct {
 	// options
 	prologue           []byte
 	disablePeerIDCheck bool
+	buffers            bufferConfig
 
 	protocolID protocol.ID
 
@@ -81,7 +104,7 @@ type SessionTransport struct {
 // If p is empty, connections from any peer are accepted.
 func (i *SessionTransport) SecureInbound(ctx context.Context, insecure net.Conn, p peer.ID) (sec.SecureConn, error) {
 	checkPeerID := !i.disablePeerIDCheck && p != ""
-	c, err := newSecureSession(i.t, ctx, insecure, p, i.prologue, i.initiatorEarlyDataHandler, i.responderEarlyDataHandler, false, checkPeerID)
+	c, err := newSecureSession(i.t, ctx, insecure, p, i.prologue, i.initiatorEarlyDataHandler, i.responderEarlyDataHandler, i.buffers, false, checkPeerID)
 	if err != nil {
 		addr, maErr := manet.FromNetAddr(insecure.RemoteAddr())
 		if maErr == nil {
@@ -93,7 +116,7 @@ func (i *SessionTransport) SecureInbound(ctx context.Context, insecure net.Conn,
 
 // SecureOutbound runs the Noise handshake as the initiator.
 func (i *SessionTransport) SecureOutbound(ctx context.Context, insecure net.Conn, p peer.ID) (sec.SecureConn, error) {
-	return newSecureSession(i.t, ctx, insecure, p, i.prologue, i.initiatorEarlyDataHandler, i.responderEarlyDataHandler, true, !i.disablePeerIDCheck)
+	return newSecureSession(i.t, ctx, insecure, p, i.prologue, i.initiatorEarlyDataHandler, i.responderEarlyDataHandler, i.buffers, true, !i.disablePeerIDCheck)
 }
 
 func (i *SessionTransport) ID() protocol.ID {
@@ -24,13 +24,39 @@ type Transport struct {
 	localID    peer.ID
 	privateKey crypto.PrivKey
 	muxers     []protocol.ID
+	buffers    bufferConfig
 }
 
 var _ sec.SecureTransport = &Transport{}
 
+// Option is an option for New.
+type Option func(*Transport) error
+
+// WithTransportBufferPool is like WithBufferPool, for the sessions of the Transport.
+// It's also the default of the SessionTransports created with WithSessionOptions.
+func WithTransportBufferPool(p BufferPool) Option {
+	return func(t *Transport) error {
+		t.buffers.pool = p
+		return nil
+	}
+}
+
+// TransportZeroAllocation is like ZeroAllocation, for the sessions of the Transport.
+// It's also the default of the SessionTransports created with WithSessionOptions.
+func TransportZeroAllocation() Option {
+	return func(t *Transport) error {
+		t.buffers.zeroAlloc = true
+		return nil
+	}
+}
+
 // New creates a new Noise transport using the given private key as its
-// libp2p identity key.
-func New(id protocol.ID, privkey crypto.PrivKey, muxers []tptu.StreamMuxer) (*Transport, error) {
+// libp2p identity key. To pass options, wrap it in a constructor:
+//
+//	libp2p.Security(noise.ID, func(id protocol.ID, priv crypto.PrivKey, muxers []tptu.StreamMuxer) (*noise.Transport, error) {
+//		return noise.New(id, priv, muxers, noise.TransportZeroAllocation())
+//	})
+func New(id protocol.ID, privkey crypto.PrivKey, muxers []tptu.StreamMuxer, opts ...Option) (*Transport, error) {
 	localID, err := peer.IDFromPrivateKey(privkey)
 	if err != nil {
 		return nil, err
@@ -41,19 +67,25 @@ func New(id protocol.ID, privkey crypto.PrivKey, muxers []tptu.StreamMuxer) (*Tr
 		muxerIDs = append(muxerIDs, m.ID)
 	}
 
-	return &Transport{
+	t := &Transport{
 		protocolID: id,
 		localID:    localID,
 		privateKey: privkey,
 		muxers:     muxerIDs,
-	}, nil
+	}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
 }
 
 // SecureInbound runs the Noise handshake as the responder.
 // If p is empty, connections from any peer are accepted.
 func (t *Transport) SecureInbound(ctx context.Context, insecure net.Conn, p peer.ID) (sec.SecureConn, error) {
 	responderEDH := newTransportEDH(t)
-	c, err := newSecureSession(t, ctx, insecure, p, nil, nil, responderEDH, false, p != "")
+	c, err := newSecureSession(t, ctx, insecure, p, nil, nil, responderEDH, t.buffers, false, p != "")
 	if err != nil {
 		addr, maErr := manet.FromNetAddr(insecure.RemoteAddr())
 		if maErr == nil {
@@ -66,7 +98,7 @@ func (t *Transport) SecureInbound(ctx context.Context, insecure net.Conn, p peer
 // SecureOutbound runs the Noise handshake as the initiator.
 func (t *Transport) SecureOutbound(ctx context.Context, insecure net.Conn, p peer.ID) (sec.SecureConn, error) {
 	initiatorEDH := newTransportEDH(t)
-	c, err := newSecureSession(t, ctx, insecure, p, nil, initiatorEDH, nil, true, true)
+	c, err := newSecureSession(t, ctx, insecure, p, nil, initiatorEDH, nil, t.buffers, true, true)
 	if err != nil {
 		return c, err
 	}
@@ -74,7 +106,7 @@ func (t *Transport) SecureOutbound(ctx context.Context, insecure net.Conn, p pee
 }
 
 func (t *Transport) WithSessionOptions(opts ...SessionOption) (*SessionTransport, error) {
-	st := &SessionTransport{t: t, protocolID: t.protocolID}
+	st := &SessionTransport{t: t, protocolID: t.protocolID, buffers: t.buffers}
 	for _, opt := range opts {
 		if err := opt(st); err != nil {
 			return nil, err
@@ -8,6 +8,7 @@ import (
 	"io"
 	"math/rand"
 	"net"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	"github.com/TheNoobiCat/go-libp2p/core/sec"
 	"github.com/TheNoobiCat/go-libp2p/p2p/security/noise/pb"
 
+	pool "github.com/libp2p/go-buffer-pool"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -713,3 +715,116 @@ func TestHandshakeWithTransportEarlyData(t *testing.T) {
 		})
 	}
 }
+
+// countingPool counts the buffers taken from and returned to the pool.
+type countingPool struct {
+	pool.BufferPool
+	gets, puts atomic.Int32
+}
+
+func (p *countingPool) Get(length int) []byte {
+	p.gets.Add(1)
+	return p.BufferPool.Get(length)
+}
+
+func (p *countingPool) Put(buf []byte) {
+	p.puts.Add(1)
+	p.BufferPool.Put(buf)
+}
+
+func TestZeroAllocation(t *testing.T) {
+	initPool, respPool := &countingPool{}, &countingPool{}
+	initTransport, err := newTestTransport(t, crypto.Ed25519, 2048).WithSessionOptions(WithBufferPool(initPool), ZeroAllocation())
+	require.NoError(t, err)
+	respTransport, err := newTestTransport(t, crypto.Ed25519, 2048).WithSessionOptions(WithBufferPool(respPool), ZeroAllocation())
+	require.NoError(t, err)
+
+	init, resp := newConnPair(t)
+	var initConn sec.SecureConn
+	var initErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		initConn, initErr = initTransport.SecureOutbound(context.Background(), init, respTransport.t.localID)
+	}()
+	respConn, err := respTransport.SecureInbound(context.Background(), resp, "")
+	require.NoError(t, err)
+	<-done
+	require.NoError(t, initErr)
+
+	msg := make([]byte, 1000)
+	rand.Read(msg)
+	rbuf := make([]byte, 100)
+	roundTrip := func() {
+		if _, err := initConn.Write(msg); err != nil {
+			t.Fatal(err)
+		}
+		// read the message in chunks, so that it is queued
+		for n := 0; n < len(msg); {
+			m, err := respConn.Read(rbuf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(msg[n:n+m], rbuf[:m]) {
+				t.Fatal("unexpected message")
+			}
+			n += m
+		}
+	}
+	roundTrip()
+	gets := initPool.gets.Load() + respPool.gets.Load()
+	require.Zero(t, testing.AllocsPerRun(100, roundTrip))
+	// the buffers are kept by the sessions
+	require.Equal(t, gets, initPool.gets.Load()+respPool.gets.Load())
+
+	require.NoError(t, initConn.Close())
+	require.NoError(t, respConn.Close())
+	require.Equal(t, initPool.gets.Load(), initPool.puts.Load())
+	require.Equal(t, respPool.gets.Load(), respPool.puts.Load())
+}
+
+func TestTransportBufferOptions(t *testing.T) {
+	newTransport := func(p BufferPool) *Transport {
+		priv, _, err := crypto.GenerateKeyPair(crypto.Ed25519, 0)
+		require.NoError(t, err)
+		tr, err := New(ID, priv, nil, WithTransportBufferPool(p), TransportZeroAllocation())
+		require.NoError(t, err)
+		return tr
+	}
+	initPool, respPool := &countingPool{}, &countingPool{}
+	initTransport, respTransport := newTransport(initPool), newTransport(respPool)
+
+	init, resp := newConnPair(t)
+	var initConn sec.SecureConn
+	var initErr error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		initConn, initErr = initTransport.SecureOutbound(context.Background(), init, respTransport.localID)
+	}()
+	respConn, err := respTransport.SecureInbound(context.Background(), resp, "")
+	require.NoError(t, err)
+	<-done
+	require.NoError(t, initErr)
+
+	msg := []byte("hello")
+	_, err = initConn.Write(msg)
+	require.NoError(t, err)
+	buf := make([]byte, 1)
+	_, err = respConn.Read(buf)
+	require.NoError(t, err)
+	require.NotZero(t, initPool.gets.Load())
+	require.NotZero(t, respPool.gets.Load())
+
+	require.NoError(t, initConn.Close())
+	require.NoError(t, respConn.Close())
+	// reading and writing a closed session fails, without taking buffers
+	gets := initPool.gets.Load() + respPool.gets.Load()
+	_, err = initConn.Write(msg)
+	require.ErrorIs(t, err, net.ErrClosed)
+	_, err = respConn.Read(buf)
+	require.ErrorIs(t, err, net.ErrClosed)
+	require.Equal(t, gets, initPool.gets.Load()+respPool.gets.Load())
+	require.Equal(t, initPool.gets.Load(), initPool.puts.Load())
+	require.Equal(t, respPool.gets.Load(), respPool.puts.Load())
+}
@@ -36,13 +36,24 @@ type signedKey struct {
 
 // Identity is used to secure connections
 type Identity struct {
-	config tls.Config
+	config      tls.Config
+	verifyChain ChainVerifier
 }
 
+// ChainVerifier verifies the certificate chain presented by a peer, leaf first. It is
+// called after the libp2p key extension of the leaf was verified, p is the peer the
+// chain authenticates.
+type ChainVerifier func(chain []*x509.Certificate, p peer.ID) error
+
 // IdentityConfig is used to configure an Identity
 type IdentityConfig struct {
 	CertTemplate *x509.Certificate
 	KeyLogWriter io.Writer
+	// CertChain is the certificate chain presented to peers instead of a self-signed
+	// certificate.
+	CertChain *tls.Certificate
+	// VerifyChain replaces the verification of the peers' self-signed certificates.
+	VerifyChain ChainVerifier
 }
 
 // IdentityOption transforms an IdentityConfig to apply optional settings.
@@ -67,6 +78,54 @@ func WithKeyLogWriter(w io.Writer) IdentityOption {
 	}
 }
 
+// WithCertificateChain makes the Identity present chain, for example a certificate
+// issued by an external CA, instead of generating a self-signed certificate.
+//
+// The leaf certificate must carry the libp2p key extension for the identity's private
+// key, so that peers can still authenticate the peer ID. It is generated by
+// GenerateSignedExtension for the public key of the certificate, and usually added to
+// the certificate signing request sent to the CA.
+//
+// Peers only accept certificates that aren't self-signed if they verify chains with
+// WithChainVerifier.
+func WithCertificateChain(chain tls.Certificate) IdentityOption {
+	return func(c *IdentityConfig) {
+		c.CertChain = &chain
+	}
+}
+
+// WithChainVerifier makes the Identity verify the certificate chains of the peers with
+// v, instead of requiring a single self-signed certificate. Chains of any length are
+// accepted if the libp2p key extension of the leaf is valid and v returns nil.
+//
+// VerifyChainWithRoots returns a ChainVerifier for verifying that the chains were
+// issued by a set of CAs.
+func WithChainVerifier(v ChainVerifier) IdentityOption {
+	return func(c *IdentityConfig) {
+		c.VerifyChain = v
+	}
+}
+
+// VerifyChainWithRoots returns a ChainVerifier verifying that the leaf certificate was
+// issued by one of roots, using the other certificates of the chain as intermediates.
+func VerifyChainWithRoots(roots *x509.CertPool) ChainVerifier {
+	return func(chain []*x509.Certificate, _ peer.ID) error {
+		opts := x509.VerifyOptions{
+			Roots:         roots,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		}
+		for _, c := range chain[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		if _, err := chain[0].Verify(opts); err != nil {
+			// Wrap the error to avoid sending x509 errors on the wire.
+			return fmt.Errorf("certificate verification failed: %s", err)
+		}
+		return nil
+	}
+}
+
 // NewIdentity creates a new identity
 func NewIdentity(privKey ic.PrivKey, opts ...IdentityOption) (*Identity, error) {
 	config := IdentityConfig{}
@@ -74,19 +133,30 @@ func NewIdentity(privKey ic.PrivKey, opts ...IdentityOption) (*Identity, error)
 		opt(&config)
 	}
 
-	var err error
-	if config.CertTemplate == nil {
-		config.CertTemplate, err = certTemplate()
+	var cert *tls.Certificate
+	if config.CertChain != nil {
+		if config.CertTemplate != nil {
+			return nil, errors.New("cannot use both a certificate template and a certificate chain")
+		}
+		if err := checkCertificateChain(privKey, config.CertChain); err != nil {
+			return nil, err
+		}
+		cert = config.CertChain
+	} else {
+		var err error
+		if config.CertTemplate == nil {
+			config.CertTemplate, err = certTemplate()
+			if err != nil {
+				return nil, err
+			}
+		}
+		cert, err = keyToCertificate(privKey, config.CertTemplate)
 		if err != nil {
 			return nil, err
 		}
 	}
-
-	cert, err := keyToCertificate(privKey, config.CertTemplate)
-	if err != nil {
-		return nil, err
-	}
 	return &Identity{
+		verifyChain: config.VerifyChain,
 		config: tls.Config{
 			MinVersion:         tls.VersionTLS13,
 			InsecureSkipVerify: true, // This is not insecure here. We will verify the cert chain ourselves.
@@ -136,7 +206,12 @@ func (i *Identity) ConfigForPeer(remote peer.ID) (*tls.Config, <-chan ic.PubKey)
 			chain[i] = cert
 		}
 
-		pubKey, err := PubKeyFromCertChain(chain)
+		var pubKey ic.PubKey
+		if i.verifyChain == nil {
+			pubKey, err = PubKeyFromCertChain(chain)
+		} else {
+			pubKey, err = pubKeyFromLeaf(chain)
+		}
 		if err != nil {
 			return err
 		}
@@ -147,6 +222,15 @@ func (i *Identity) ConfigForPeer(remote peer.ID) (*tls.Config, <-chan ic.PubKey)
 			}
 			return sec.ErrPeerIDMismatch{Expected: remote, Actual: peerID}
 		}
+		if i.verifyChain != nil {
+			peerID, err := peer.IDFromPublicKey(pubKey)
+			if err != nil {
+				return err
+			}
+			if err := i.verifyChain(chain, peerID); err != nil {
+				return err
+			}
+		}
 		keyCh <- pubKey
 		return nil
 	}
@@ -161,13 +245,56 @@ func PubKeyFromCertChain(chain []*x509.Certificate) (ic.PubKey, error) {
 	cert := chain[0]
 	pool := x509.NewCertPool()
 	pool.AddCert(cert)
-	var found bool
-	var keyExt pkix.Extension
-	// find the libp2p key extension, skipping all unknown extensions
+	keyExt, err := takeKeyExtension(cert)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool}); err != nil {
+		// If we return an x509 error here, it will be sent on the wire.
+		// Wrap the error to avoid that.
+		return nil, fmt.Errorf("certificate verification failed: %s", err)
+	}
+	return pubKeyFromExtension(cert, keyExt)
+}
+
+// pubKeyFromLeaf extracts the remote's public key from the leaf of a chain verified by a
+// ChainVerifier. Only the key extension is verified.
+func pubKeyFromLeaf(chain []*x509.Certificate) (ic.PubKey, error) {
+	if len(chain) == 0 {
+		return nil, errors.New("expected at least one certificate in the chain")
+	}
+	keyExt, err := takeKeyExtension(chain[0])
+	if err != nil {
+		return nil, err
+	}
+	return pubKeyFromExtension(chain[0], keyExt)
+}
+
+// checkCertificateChain checks that the leaf of chain carries a valid key extension
+// for sk.
+func checkCertificateChain(sk ic.PrivKey, chain *tls.Certificate) error {
+	if len(chain.Certificate) == 0 {
+		return errors.New("empty certificate chain")
+	}
+	leaf, err := x509.ParseCertificate(chain.Certificate[0])
+	if err != nil {
+		return err
+	}
+	pubKey, err := pubKeyFromLeaf([]*x509.Certificate{leaf})
+	if err != nil {
+		return err
+	}
+	if !pubKey.Equals(sk.GetPublic()) {
+		return errors.New("the key extension of the certificate doesn't match the private key")
+	}
+	return nil
+}
+
+// takeKeyExtension finds the libp2p key extension of cert, skipping all unknown
+// extensions, and removes it from the unhandled critical extensions.
+func takeKeyExtension(cert *x509.Certificate) (pkix.Extension, error) {
 	for _, ext := range cert.Extensions {
 		if extensionIDEqual(ext.Id, extensionID) {
-			keyExt = ext
-			found = true
 			for i, oident := range cert.UnhandledCriticalExtensions {
 				if oident.Equal(ext.Id) {
 					// delete the extension from UnhandledCriticalExtensions
@@ -175,18 +302,15 @@ func PubKeyFromCertChain(chain []*x509.Certificate) (ic.PubKey, error) {
 					break
 				}
 			}
-			break
+			return ext, nil
 		}
 	}
-	if !found {
-		return nil, errors.New("expected certificate to contain the key extension")
-	}
-	if _, err := cert.Verify(x509.VerifyOptions{Roots: pool}); err != nil {
-		// If we return an x509 error here, it will be sent on the wire.
-		// Wrap the error to avoid that.
-		return nil, fmt.Errorf("certificate verification failed: %s", err)
-	}
+	return pkix.Extension{}, errors.New("expected certificate to contain the key extension")
+}
 
+// pubKeyFromExtension verifies that keyExt signs the public key of cert, and returns
+// the libp2p public key it contains.
+func pubKeyFromExtension(cert *x509.Certificate, keyExt pkix.Extension) (ic.PubKey, error) {
 	var sk signedKey
 	if _, err := asn1.Unmarshal(keyExt.Value, &sk); err != nil {
 		return nil, fmt.Errorf("unmarshalling signed certificate failed: %s", err)
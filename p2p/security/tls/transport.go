@@ -37,6 +37,19 @@ var _ sec.SecureTransport = &Transport{}
 
 // New creates a TLS encrypted transport
 func New(id protocol.ID, key ci.PrivKey, muxers []tptu.StreamMuxer) (*Transport, error) {
+	return NewWithOptions(id, key, muxers)
+}
+
+// NewWithOptions creates a TLS encrypted transport with an Identity configured by opts,
+// for example to present a certificate chain issued by an external CA:
+//
+//	libp2p.Security(libp2ptls.ID, func(id protocol.ID, key crypto.PrivKey, muxers []tptu.StreamMuxer) (*libp2ptls.Transport, error) {
+//		return libp2ptls.NewWithOptions(id, key, muxers,
+//			libp2ptls.WithCertificateChain(chain),
+//			libp2ptls.WithChainVerifier(libp2ptls.VerifyChainWithRoots(roots)),
+//		)
+//	})
+func NewWithOptions(id protocol.ID, key ci.PrivKey, muxers []tptu.StreamMuxer, opts ...IdentityOption) (*Transport, error) {
 	localPeer, err := peer.IDFromPrivateKey(key)
 	if err != nil {
 		return nil, err
@@ -52,7 +65,7 @@ func New(id protocol.ID, key ci.PrivKey, muxers []tptu.StreamMuxer) (*Transport,
 		muxers:     muxerIDs,
 	}
 
-	identity, err := NewIdentity(key)
+	identity, err := NewIdentity(key, opts...)
 	if err != nil {
 		return nil, err
 	}
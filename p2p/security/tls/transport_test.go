@@ -12,6 +12,7 @@ import (
 	"crypto/x509/pkix"
 	"encoding/asn1"
 	"fmt"
+	"io"
 	"math/big"
 	mrand "math/rand"
 	"net"
@@ -709,3 +710,117 @@ func TestInvalidCerts(t *testing.T) {
 		})
 	}
 }
+
+// issueCertificate issues a certificate for key, carrying the key extension, signed by
+// the CA.
+func issueCertificate(t *testing.T, key ic.PrivKey, ca *x509.Certificate, caKey crypto.Signer) tls.Certificate {
+	t.Helper()
+	certKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ext, err := GenerateSignedExtension(key, certKey.Public())
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:    big.NewInt(mrand.Int63()),
+		Subject:         pkix.Name{CommonName: "libp2p node"},
+		NotBefore:       time.Now().Add(-time.Hour),
+		NotAfter:        time.Now().Add(time.Hour),
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+		ExtraExtensions: []pkix.Extension{ext},
+	}
+	certDER, err := x509.CreateCertificate(rand.Reader, tmpl, ca, certKey.Public(), caKey)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{certDER, ca.Raw}, PrivateKey: certKey}
+}
+
+func TestExternalCA(t *testing.T) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "corporate CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, caKey.Public(), caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	clientID, clientKey := createPeer(t)
+	serverID, serverKey := createPeer(t)
+	newTransport := func(key ic.PrivKey, opts ...IdentityOption) *Transport {
+		tr, err := NewWithOptions(ID, key, nil, opts...)
+		require.NoError(t, err)
+		return tr
+	}
+	handshake := func(t *testing.T, client, server *Transport) (clientErr, serverErr error) {
+		clientInsecureConn, serverInsecureConn := connect(t)
+		errChan := make(chan error, 1)
+		go func() {
+			conn, err := server.SecureInbound(context.Background(), serverInsecureConn, "")
+			if err == nil {
+				require.Equal(t, clientID, conn.RemotePeer())
+				conn.Close()
+			}
+			errChan <- err
+		}()
+		conn, err := client.SecureOutbound(context.Background(), clientInsecureConn, serverID)
+		if err == nil {
+			// the client only learns that the server rejected its certificate when reading
+			_, err = conn.Read([]byte{0})
+			if err == io.EOF {
+				err = nil
+			}
+			conn.Close()
+		}
+		return err, <-errChan
+	}
+	verifier := WithChainVerifier(VerifyChainWithRoots(roots))
+
+	t.Run("issued by the CA", func(t *testing.T) {
+		client := newTransport(clientKey, WithCertificateChain(issueCertificate(t, clientKey, ca, caKey)), verifier)
+		server := newTransport(serverKey, WithCertificateChain(issueCertificate(t, serverKey, ca, caKey)), verifier)
+		clientErr, serverErr := handshake(t, client, server)
+		require.NoError(t, clientErr)
+		require.NoError(t, serverErr)
+	})
+
+	t.Run("self-signed certificate", func(t *testing.T) {
+		client := newTransport(clientKey, WithCertificateChain(issueCertificate(t, clientKey, ca, caKey)), verifier)
+		server := newTransport(serverKey)
+		clientErr, _ := handshake(t, client, server)
+		require.ErrorContains(t, clientErr, "certificate verification failed")
+	})
+
+	t.Run("peers without a verifier", func(t *testing.T) {
+		client := newTransport(clientKey)
+		server := newTransport(serverKey, WithCertificateChain(issueCertificate(t, serverKey, ca, caKey)), verifier)
+		clientErr, _ := handshake(t, client, server)
+		require.ErrorContains(t, clientErr, "expected one certificates in the chain")
+	})
+
+	t.Run("custom verifier", func(t *testing.T) {
+		var verified []peer.ID
+		server := newTransport(serverKey, WithChainVerifier(func(chain []*x509.Certificate, p peer.ID) error {
+			require.Len(t, chain, 2)
+			verified = append(verified, p)
+			return nil
+		}))
+		client := newTransport(clientKey, WithCertificateChain(issueCertificate(t, clientKey, ca, caKey)))
+		clientErr, serverErr := handshake(t, client, server)
+		require.NoError(t, clientErr)
+		require.NoError(t, serverErr)
+		require.Equal(t, []peer.ID{clientID}, verified)
+	})
+
+	t.Run("certificate for another key", func(t *testing.T) {
+		_, otherKey := createPeer(t)
+		_, err := NewIdentity(clientKey, WithCertificateChain(issueCertificate(t, otherKey, ca, caKey)))
+		require.Error(t, err)
+	})
+}
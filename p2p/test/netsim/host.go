@@ -0,0 +1,64 @@
+package netsim
+
+import (
+	"net"
+
+	"github.com/TheNoobiCat/go-libp2p"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	libp2pquic "github.com/TheNoobiCat/go-libp2p/p2p/transport/quic"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/quicreuse"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+// Host is a libp2p host on the simulated network.
+type Host struct {
+	host.Host
+	ip net.IP
+}
+
+// IP returns the host's IP address on the simulated network.
+func (h *Host) IP() net.IP {
+	return h.ip
+}
+
+type sourceIPSelector struct {
+	ip net.IP
+}
+
+func (s sourceIPSelector) PreferredSourceIPForDestination(_ *net.UDPAddr) (net.IP, error) {
+	return s.ip, nil
+}
+
+// NewHost creates a host with a new IP address on the network. The host listens on
+// QUIC only, and uses the Network's clock. opts are applied after the options of the
+// simulation, so they can override them, except for the transports and the clock.
+func (n *Network) NewHost(opts ...libp2p.Option) (*Host, error) {
+	ip := n.allocateIP()
+	listenAddr, err := manet.FromNetAddr(&net.UDPAddr{IP: ip})
+	if err != nil {
+		return nil, err
+	}
+	h, err := libp2p.New(append([]libp2p.Option{
+		libp2p.Transport(libp2pquic.NewTransport),
+		libp2p.QUICReuse(
+			quicreuse.NewConnManager,
+			quicreuse.OverrideSourceIPSelector(func() (quicreuse.SourceIPSelector, error) {
+				return sourceIPSelector{ip: ip}, nil
+			}),
+			quicreuse.OverrideListenUDP(func(_ string, addr *net.UDPAddr) (net.PacketConn, error) {
+				if addr.IP.IsUnspecified() {
+					addr = &net.UDPAddr{IP: ip, Port: addr.Port}
+				}
+				return n.ListenUDP(addr)
+			}),
+		),
+		libp2p.ListenAddrs(listenAddr.Encapsulate(ma.StringCast("/quic-v1"))),
+		libp2p.WithClock(n.clock),
+	}, opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Host{Host: h, ip: ip}, nil
+}
@@ -0,0 +1,280 @@
+// Package netsim simulates a network of in-process libp2p hosts.
+//
+// Hosts are connected over QUIC on simulated UDP sockets (see p2p/net/simconn). Every
+// packet is routed by the Network, which applies the latency, jitter and packet loss
+// configured for the link between the two hosts, and drops packets between hosts in
+// different partitions. Random decisions are taken from a seeded source, so a
+// simulation can be reproduced by using the same seed.
+//
+// Packet deliveries are scheduled on the Network's clock. With a mock clock, packets
+// are only delivered when the test advances the clock, which makes the order of
+// deliveries reproducible. The hosts use the Network's clock as well, see
+// libp2p.WithClock, but QUIC (e.g. retransmissions and idle timeouts) still uses the
+// system clock.
+package netsim
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/simconn"
+
+	"github.com/benbjohnson/clock"
+)
+
+// LinkSettings are the properties of the link between two hosts.
+type LinkSettings struct {
+	// Latency is the one way delay of packets.
+	Latency time.Duration
+	// Jitter is the maximum random delay added to the latency of every packet.
+	Jitter time.Duration
+	// Loss is the probability that a packet is dropped, between 0 and 1.
+	Loss float64
+}
+
+// Stats are the number of packets handled by the Network.
+type Stats struct {
+	Delivered int
+	Dropped   int
+}
+
+type config struct {
+	seed        int64
+	clock       clock.Clock
+	defaultLink LinkSettings
+}
+
+// Option is an option for a Network.
+type Option func(*config) error
+
+// WithSeed sets the seed of the random decisions of the network. Defaults to 0.
+func WithSeed(seed int64) Option {
+	return func(c *config) error {
+		c.seed = seed
+		return nil
+	}
+}
+
+// WithClock sets the clock used to schedule packet deliveries. Defaults to the
+// system clock.
+func WithClock(cl clock.Clock) Option {
+	return func(c *config) error {
+		c.clock = cl
+		return nil
+	}
+}
+
+// WithDefaultLink sets the settings of links that weren't configured with SetLink.
+func WithDefaultLink(l LinkSettings) Option {
+	return func(c *config) error {
+		if err := l.validate(); err != nil {
+			return err
+		}
+		c.defaultLink = l
+		return nil
+	}
+}
+
+func (l LinkSettings) validate() error {
+	if l.Latency < 0 || l.Jitter < 0 {
+		return errors.New("latency and jitter must not be negative")
+	}
+	if l.Loss < 0 || l.Loss > 1 {
+		return errors.New("loss must be between 0 and 1")
+	}
+	return nil
+}
+
+type linkKey struct {
+	a, b string
+}
+
+func newLinkKey(a, b net.IP) linkKey {
+	if a.String() > b.String() {
+		a, b = b, a
+	}
+	return linkKey{a: a.String(), b: b.String()}
+}
+
+// Network routes packets between simulated hosts. It is safe for concurrent use.
+type Network struct {
+	clock       clock.Clock
+	defaultLink LinkSettings
+
+	mu         sync.Mutex
+	rng        *rand.Rand
+	conns      map[string]*simconn.SimConn // by UDP address
+	links      map[linkKey]LinkSettings
+	partitions map[string]int // IP to partition, nil if there's no partition
+	nextIP     int
+	stats      Stats
+}
+
+var _ simconn.Router = &Network{}
+
+// New creates a network.
+func New(opts ...Option) (*Network, error) {
+	cfg := config{clock: clock.New()}
+	for _, opt := range opts {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	return &Network{
+		clock:       cfg.clock,
+		defaultLink: cfg.defaultLink,
+		rng:         rand.New(rand.NewSource(cfg.seed)),
+		conns:       make(map[string]*simconn.SimConn),
+		links:       make(map[linkKey]LinkSettings),
+	}, nil
+}
+
+// SetLink sets the settings of the link between the hosts with IP addresses a and b,
+// in both directions.
+func (n *Network) SetLink(a, b net.IP, l LinkSettings) error {
+	if err := l.validate(); err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.links[newLinkKey(a, b)] = l
+	return nil
+}
+
+// Partition splits the network: packets are only delivered between hosts of the
+// same group. Hosts that aren't part of any group form another group. It replaces
+// the previous partition.
+func (n *Network) Partition(groups ...[]net.IP) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.partitions = make(map[string]int)
+	for i, g := range groups {
+		for _, ip := range g {
+			n.partitions[ip.String()] = i + 1
+		}
+	}
+}
+
+// Heal removes the partition.
+func (n *Network) Heal() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.partitions = nil
+}
+
+// After runs f after d on the network's clock. It is used to script changes of the
+// network, e.g. partitions, during a simulation.
+func (n *Network) After(d time.Duration, f func()) *clock.Timer {
+	return n.clock.AfterFunc(d, f)
+}
+
+// Stats returns the number of packets delivered and dropped.
+func (n *Network) Stats() Stats {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.stats
+}
+
+// ListenUDP creates a simulated UDP socket on addr. If the port is 0, a free port is
+// allocated.
+func (n *Network) ListenUDP(addr *net.UDPAddr) (net.PacketConn, error) {
+	if addr.IP.IsUnspecified() {
+		return nil, errors.New("can't listen on an unspecified address")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	addr = &net.UDPAddr{IP: addr.IP, Port: addr.Port}
+	if addr.Port == 0 {
+		for port := 10000; ; port++ {
+			addr.Port = port
+			if _, ok := n.conns[addr.String()]; !ok {
+				break
+			}
+		}
+	} else if _, ok := n.conns[addr.String()]; ok {
+		return nil, errors.New("address already in use")
+	}
+	c := simconn.NewSimConn(addr, n)
+	n.conns[addr.String()] = c
+	return &conn{SimConn: c, network: n}, nil
+}
+
+// conn removes the socket from the network when it is closed.
+type conn struct {
+	*simconn.SimConn
+	network *Network
+}
+
+func (c *conn) Close() error {
+	c.network.removeConn(c.UnicastAddr())
+	return c.SimConn.Close()
+}
+
+func (n *Network) allocateIP() net.IP {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.nextIP++
+	return simconn.IntToPublicIPv4(n.nextIP)
+}
+
+func (n *Network) removeConn(addr net.Addr) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.conns, addr.String())
+}
+
+// SendPacket implements simconn.Router.
+func (n *Network) SendPacket(p simconn.Packet) error {
+	to, delay, err := n.route(p)
+	if err != nil || to == nil {
+		return err
+	}
+	if delay == 0 {
+		// deliver outside of the lock, the receiver may send packets in response
+		to.RecvPacket(p)
+		return nil
+	}
+	n.clock.AfterFunc(delay, func() { to.RecvPacket(p) })
+	return nil
+}
+
+// route returns the destination of p and the delay of its delivery. It returns a nil
+// destination if p is dropped.
+func (n *Network) route(p simconn.Packet) (to *simconn.SimConn, delay time.Duration, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	to, ok := n.conns[p.To.String()]
+	if !ok {
+		return nil, 0, errors.New("unknown destination")
+	}
+	fromIP, toIP := udpIP(p.From), udpIP(p.To)
+	link, ok := n.links[newLinkKey(fromIP, toIP)]
+	if !ok {
+		link = n.defaultLink
+	}
+	if n.partitions != nil && n.partitions[fromIP.String()] != n.partitions[toIP.String()] {
+		n.stats.Dropped++
+		return nil, 0, nil
+	}
+	if link.Loss > 0 && n.rng.Float64() < link.Loss {
+		n.stats.Dropped++
+		return nil, 0, nil
+	}
+	n.stats.Delivered++
+
+	delay = link.Latency
+	if link.Jitter > 0 {
+		delay += time.Duration(n.rng.Int63n(int64(link.Jitter)))
+	}
+	return to, delay, nil
+}
+
+func udpIP(a net.Addr) net.IP {
+	if ua, ok := a.(*net.UDPAddr); ok {
+		return ua.IP
+	}
+	return nil
+}
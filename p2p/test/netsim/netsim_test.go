@@ -0,0 +1,118 @@
+package netsim
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/ping"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func listen(t *testing.T, n *Network, ip int) net.PacketConn {
+	t.Helper()
+	c, err := n.ListenUDP(&net.UDPAddr{IP: net.IPv4(1, 0, 0, byte(ip)), Port: 1234})
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// sendAll sends count packets from a to b and returns the indexes of the packets
+// that were delivered.
+func sendAll(t *testing.T, a, b net.PacketConn, count int) []int {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		_, err := a.WriteTo([]byte{byte(i)}, b.LocalAddr())
+		require.NoError(t, err)
+	}
+	var received []int
+	buf := make([]byte, 10)
+	for {
+		b.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		n, _, err := b.ReadFrom(buf)
+		if err != nil {
+			return received
+		}
+		require.Equal(t, 1, n)
+		received = append(received, int(buf[0]))
+	}
+}
+
+func TestLossIsReproducible(t *testing.T) {
+	run := func(seed int64) ([]int, Stats) {
+		n, err := New(WithSeed(seed), WithDefaultLink(LinkSettings{Loss: 0.5}))
+		require.NoError(t, err)
+		a, b := listen(t, n, 1), listen(t, n, 2)
+		return sendAll(t, a, b, 100), n.Stats()
+	}
+
+	received, stats := run(42)
+	require.Equal(t, len(received), stats.Delivered)
+	require.Equal(t, 100, stats.Delivered+stats.Dropped)
+	require.NotZero(t, stats.Delivered)
+	require.NotZero(t, stats.Dropped)
+
+	again, _ := run(42)
+	require.Equal(t, received, again)
+	other, _ := run(43)
+	require.NotEqual(t, received, other)
+}
+
+func TestLatencyOnMockClock(t *testing.T) {
+	cl := clock.NewMock()
+	n, err := New(WithClock(cl))
+	require.NoError(t, err)
+	a, b := listen(t, n, 1), listen(t, n, 2)
+	ipA, ipB := a.LocalAddr().(*net.UDPAddr).IP, b.LocalAddr().(*net.UDPAddr).IP
+	require.NoError(t, n.SetLink(ipA, ipB, LinkSettings{Latency: 50 * time.Millisecond}))
+
+	require.Empty(t, sendAll(t, a, b, 1))
+	cl.Add(49 * time.Millisecond)
+	require.Empty(t, sendAll(t, a, b, 0))
+	cl.Add(time.Millisecond)
+	require.Equal(t, []int{0}, sendAll(t, a, b, 0))
+}
+
+func TestPartition(t *testing.T) {
+	n, err := New()
+	require.NoError(t, err)
+	a, b, c := listen(t, n, 1), listen(t, n, 2), listen(t, n, 3)
+	ipA, ipB := a.LocalAddr().(*net.UDPAddr).IP, b.LocalAddr().(*net.UDPAddr).IP
+
+	n.Partition([]net.IP{ipA, ipB})
+	require.Len(t, sendAll(t, a, b, 1), 1)
+	require.Empty(t, sendAll(t, a, c, 1))
+	n.Heal()
+	require.Len(t, sendAll(t, a, c, 1), 1)
+}
+
+func TestHosts(t *testing.T) {
+	n, err := New(WithDefaultLink(LinkSettings{Latency: 25 * time.Millisecond}))
+	require.NoError(t, err)
+	var hosts []*Host
+	for i := 0; i < 3; i++ {
+		h, err := n.NewHost()
+		require.NoError(t, err)
+		t.Cleanup(func() { h.Close() })
+		hosts = append(hosts, h)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, hosts[0].Connect(ctx, peer.AddrInfo{ID: hosts[1].ID(), Addrs: hosts[1].Addrs()}))
+	res := <-ping.Ping(ctx, hosts[0], hosts[1].ID())
+	require.NoError(t, res.Error)
+	require.GreaterOrEqual(t, res.RTT, 50*time.Millisecond)
+
+	n.Partition([]net.IP{hosts[0].IP(), hosts[1].IP()})
+	dialCtx, dialCancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer dialCancel()
+	require.Error(t, hosts[0].Connect(dialCtx, peer.AddrInfo{ID: hosts[2].ID(), Addrs: hosts[2].Addrs()}))
+
+	n.Heal()
+	require.NoError(t, hosts[2].Connect(ctx, peer.AddrInfo{ID: hosts[0].ID(), Addrs: hosts[0].Addrs()}))
+}
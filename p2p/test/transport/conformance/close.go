@@ -0,0 +1,98 @@
+package conformance
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SubtestAbruptClose tests that stream resets and connection closes are reported to
+// the other side.
+func SubtestAbruptClose(t *testing.T, tc TestCase) {
+	t.Run("ListenerStreamResets", func(t *testing.T) { testListenerStreamResets(t, tc) })
+	t.Run("DialerStreamResets", func(t *testing.T) { testDialerStreamResets(t, tc) })
+	t.Run("ConnClosedWhenRemoteCloses", func(t *testing.T) { testConnClosedWhenRemoteCloses(t, tc) })
+}
+
+func testListenerStreamResets(t *testing.T, tc TestCase) {
+	h1 := tc.HostGenerator(t, HostOpts{})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer h1.Close()
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{
+		ID:    h1.ID(),
+		Addrs: h1.Addrs(),
+	}))
+
+	h1.SetStreamHandler("reset", func(s network.Stream) {
+		s.Reset()
+	})
+
+	s, err := h2.NewStream(context.Background(), h1.ID(), "reset")
+	if err != nil {
+		require.ErrorIs(t, err, network.ErrReset)
+		return
+	}
+
+	_, err = s.Read([]byte{0})
+	require.ErrorIs(t, err, network.ErrReset)
+}
+
+func testDialerStreamResets(t *testing.T, tc TestCase) {
+	h1 := tc.HostGenerator(t, HostOpts{})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer h1.Close()
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{
+		ID:    h1.ID(),
+		Addrs: h1.Addrs(),
+	}))
+
+	errCh := make(chan error, 1)
+	acceptedCh := make(chan struct{}, 1)
+	h1.SetStreamHandler("echo", func(s network.Stream) {
+		acceptedCh <- struct{}{}
+		_, err := io.Copy(s, s)
+		errCh <- err
+	})
+
+	s, err := h2.NewStream(context.Background(), h1.ID(), "echo")
+	require.NoError(t, err)
+	s.Write([]byte{})
+	<-acceptedCh
+	s.Reset()
+	require.ErrorIs(t, <-errCh, network.ErrReset)
+}
+
+// testConnClosedWhenRemoteCloses tests that a connection is closed locally when it's closed by remote
+func testConnClosedWhenRemoteCloses(t *testing.T, tc TestCase) {
+	server := tc.HostGenerator(t, HostOpts{})
+	client := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer server.Close()
+	defer client.Close()
+
+	client.Peerstore().AddAddrs(server.ID(), server.Addrs(), peerstore.PermanentAddrTTL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := client.Connect(ctx, peer.AddrInfo{ID: server.ID(), Addrs: server.Addrs()})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return server.Network().Connectedness(client.ID()) != network.NotConnected
+	}, 5*time.Second, 50*time.Millisecond)
+	for _, c := range client.Network().ConnsToPeer(server.ID()) {
+		c.Close()
+	}
+	require.Eventually(t, func() bool {
+		return server.Network().Connectedness(client.ID()) == network.NotConnected
+	}, 5*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,133 @@
+package conformance
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SubtestDeadlines tests that stream deadlines interrupt blocked reads and writes,
+// and that the stream is still usable after a read timed out.
+func SubtestDeadlines(t *testing.T, tc TestCase) {
+	testReadWriteDeadlines(t, tc)
+	t.Run("StreamUsableAfterReadDeadline", func(t *testing.T) { testStreamReadDeadline(t, tc) })
+}
+
+func testReadWriteDeadlines(t *testing.T, tc TestCase) {
+	// Send a lot of data so that writes have to flush (can't just buffer it all)
+	sendBuf := make([]byte, 10<<20)
+	listener := tc.HostGenerator(t, HostOpts{})
+	defer listener.Close()
+	dialer := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer dialer.Close()
+
+	require.NoError(t, dialer.Connect(context.Background(), peer.AddrInfo{
+		ID:    listener.ID(),
+		Addrs: listener.Addrs(),
+	}))
+
+	// This simply stalls
+	listener.SetStreamHandler("/stall", func(s network.Stream) {
+		time.Sleep(time.Hour)
+		s.Close()
+	})
+
+	t.Run("ReadDeadline", func(t *testing.T) {
+		s, err := dialer.NewStream(context.Background(), listener.ID(), "/stall")
+		require.NoError(t, err)
+		defer s.Close()
+
+		start := time.Now()
+		// Set a deadline
+		s.SetReadDeadline(time.Now().Add(10 * time.Millisecond))
+		buf := make([]byte, 1)
+		_, err = s.Read(buf)
+		require.Error(t, err)
+		var nerr net.Error
+		require.ErrorAs(t, err, &nerr)
+		require.True(t, nerr.Timeout())
+		require.Less(t, time.Since(start), 1*time.Second)
+	})
+
+	t.Run("WriteDeadline", func(t *testing.T) {
+		s, err := dialer.NewStream(context.Background(), listener.ID(), "/stall")
+		require.NoError(t, err)
+		defer s.Close()
+
+		// Set a deadline
+		s.SetWriteDeadline(time.Now().Add(10 * time.Millisecond))
+		start := time.Now()
+		_, err = s.Write(sendBuf)
+		require.Error(t, err)
+		require.True(t, err.(net.Error).Timeout())
+		require.Less(t, time.Since(start), 1*time.Second)
+	})
+
+	// Like the above, but with SetDeadline
+	t.Run("SetDeadline", func(t *testing.T) {
+		for _, op := range []string{"Read", "Write"} {
+			t.Run(op, func(t *testing.T) {
+				s, err := dialer.NewStream(context.Background(), listener.ID(), "/stall")
+				require.NoError(t, err)
+				defer s.Close()
+
+				// Set a deadline
+				s.SetDeadline(time.Now().Add(10 * time.Millisecond))
+				start := time.Now()
+
+				if op == "Read" {
+					buf := make([]byte, 1)
+					_, err = s.Read(buf)
+				} else {
+					_, err = s.Write(sendBuf)
+				}
+				require.Error(t, err)
+				var nerr net.Error
+				require.ErrorAs(t, err, &nerr)
+				require.True(t, nerr.Timeout())
+				require.Less(t, time.Since(start), 1*time.Second)
+			})
+		}
+	})
+}
+
+func testStreamReadDeadline(t *testing.T, tc TestCase) {
+	h1 := tc.HostGenerator(t, HostOpts{})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer h1.Close()
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{
+		ID:    h1.ID(),
+		Addrs: h1.Addrs(),
+	}))
+
+	h1.SetStreamHandler("echo", func(s network.Stream) {
+		io.Copy(s, s)
+	})
+
+	s, err := h2.NewStream(context.Background(), h1.ID(), "echo")
+	require.NoError(t, err)
+	require.NoError(t, s.SetReadDeadline(time.Now().Add(100*time.Millisecond)))
+	_, err = s.Read([]byte{0})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "deadline")
+	var nerr net.Error
+	require.ErrorAs(t, err, &nerr, "expected a net.Error")
+	require.True(t, nerr.Timeout(), "expected net.Error.Timeout() == true")
+	// now test that the stream is still usable
+	s.SetReadDeadline(time.Time{})
+	_, err = s.Write([]byte("foobar"))
+	require.NoError(t, err)
+	b := make([]byte, 6)
+	_, err = s.Read(b)
+	require.Equal(t, "foobar", string(b))
+	require.NoError(t, err)
+}
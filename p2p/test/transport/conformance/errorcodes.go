@@ -0,0 +1,230 @@
+package conformance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// SubtestErrorCodes tests that the error codes of stream resets and connection closes
+// are sent to the other side.
+func SubtestErrorCodes(t *testing.T, tc TestCase) {
+	assertStreamErrors := func(s network.Stream, expectedError error) {
+		buf := make([]byte, 10)
+		_, err := s.Read(buf)
+		require.ErrorIs(t, err, expectedError)
+
+		_, err = s.Write(buf)
+		require.ErrorIs(t, err, expectedError)
+	}
+
+	if tc.Quirks.NoStreamErrorCodes {
+		t.Skipf("skipping: %s, not implemented", tc.Name)
+	}
+	server := tc.HostGenerator(t, HostOpts{})
+	client := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer server.Close()
+	defer client.Close()
+
+	client.Peerstore().AddAddrs(server.ID(), server.Addrs(), peerstore.PermanentAddrTTL)
+
+	// setup stream handler
+	remoteStreamQ := make(chan network.Stream)
+	server.SetStreamHandler("/test", func(s network.Stream) {
+		b := make([]byte, 10)
+		n, err := s.Read(b)
+		if !assert.NoError(t, err) {
+			return
+		}
+		_, err = s.Write(b[:n])
+		if !assert.NoError(t, err) {
+			return
+		}
+		remoteStreamQ <- s
+	})
+
+	// pingPong writes and reads "hello" on the stream
+	pingPong := func(s network.Stream) {
+		buf := []byte("hello")
+		_, err := s.Write(buf)
+		require.NoError(t, err)
+
+		_, err = s.Read(buf)
+		require.NoError(t, err)
+		require.Equal(t, buf, []byte("hello"))
+	}
+
+	t.Run("StreamResetWithError", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s, err := client.NewStream(ctx, server.ID(), "/test")
+		require.NoError(t, err)
+		pingPong(s)
+
+		remoteStream := <-remoteStreamQ
+		defer remoteStream.Reset()
+
+		err = s.ResetWithError(42)
+		require.NoError(t, err)
+		assertStreamErrors(s, &network.StreamError{
+			ErrorCode: 42,
+			Remote:    false,
+		})
+
+		assertStreamErrors(remoteStream, &network.StreamError{
+			ErrorCode: 42,
+			Remote:    true,
+		})
+	})
+	t.Run("StreamResetWithErrorByRemote", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s, err := client.NewStream(ctx, server.ID(), "/test")
+		require.NoError(t, err)
+		pingPong(s)
+
+		remoteStream := <-remoteStreamQ
+
+		err = remoteStream.ResetWithError(42)
+		require.NoError(t, err)
+
+		assertStreamErrors(s, &network.StreamError{
+			ErrorCode: 42,
+			Remote:    true,
+		})
+
+		assertStreamErrors(remoteStream, &network.StreamError{
+			ErrorCode: 42,
+			Remote:    false,
+		})
+	})
+
+	t.Run("StreamResetByConnCloseWithError", func(t *testing.T) {
+		if tc.Quirks.NoConnErrorCodes {
+			t.Skipf("skipping: %s, not implemented", tc.Name)
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s, err := client.NewStream(ctx, server.ID(), "/test")
+		require.NoError(t, err)
+		pingPong(s)
+
+		remoteStream := <-remoteStreamQ
+		defer remoteStream.Reset()
+
+		err = s.Conn().CloseWithError(42)
+		require.NoError(t, err)
+
+		assertStreamErrors(s, &network.ConnError{
+			ErrorCode: 42,
+			Remote:    false,
+		})
+
+		assertStreamErrors(remoteStream, &network.ConnError{
+			ErrorCode: 42,
+			Remote:    true,
+		})
+	})
+
+	t.Run("NewStreamErrorByConnCloseWithError", func(t *testing.T) {
+		if tc.Quirks.NoConnErrorCodes {
+			t.Skipf("skipping: %s, not implemented", tc.Name)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s, err := client.NewStream(ctx, server.ID(), "/test")
+		require.NoError(t, err)
+		pingPong(s)
+
+		err = s.Conn().CloseWithError(42)
+		require.NoError(t, err)
+
+		remoteStream := <-remoteStreamQ
+		defer remoteStream.Reset()
+
+		localErr := &network.ConnError{
+			ErrorCode: 42,
+			Remote:    false,
+		}
+
+		remoteErr := &network.ConnError{
+			ErrorCode: 42,
+			Remote:    true,
+		}
+
+		// assert these first to ensure that remote has closed the connection
+		assertStreamErrors(remoteStream, remoteErr)
+
+		_, err = s.Conn().NewStream(ctx)
+		require.ErrorIs(t, err, localErr)
+
+		_, err = remoteStream.Conn().NewStream(ctx)
+		require.ErrorIs(t, err, remoteErr)
+	})
+
+	t.Run("CloseReason", func(t *testing.T) {
+		// Transports that can't send connection error codes still report the remote
+		// close, just without the error code.
+		remoteCode := network.ConnErrorCode(42)
+		if tc.Quirks.NoConnErrorCodes {
+			remoteCode = network.ConnNoError
+		}
+
+		sub, err := server.EventBus().Subscribe(new(event.EvtPeerConnectednessChanged))
+		require.NoError(t, err)
+		defer sub.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		s, err := client.NewStream(ctx, server.ID(), "/test")
+		require.NoError(t, err)
+		pingPong(s)
+
+		remoteStream := <-remoteStreamQ
+		defer remoteStream.Reset()
+
+		_, ok := s.Conn().CloseReason()
+		require.False(t, ok)
+
+		err = s.Conn().CloseWithError(42)
+		require.NoError(t, err)
+
+		reason, ok := s.Conn().CloseReason()
+		require.True(t, ok)
+		require.Equal(t, network.ConnCloseReason{ErrorCode: 42}, reason)
+
+		require.Eventually(t, func() bool {
+			_, ok := remoteStream.Conn().CloseReason()
+			return ok
+		}, 5*time.Second, 10*time.Millisecond)
+		reason, _ = remoteStream.Conn().CloseReason()
+		require.True(t, reason.Remote)
+		require.Equal(t, remoteCode, reason.ErrorCode)
+
+		for {
+			select {
+			case e := <-sub.Out():
+				evt := e.(event.EvtPeerConnectednessChanged)
+				if evt.Connectedness != network.NotConnected {
+					continue
+				}
+				require.NotNil(t, evt.CloseReason)
+				require.True(t, evt.CloseReason.Remote)
+				require.Equal(t, remoteCode, evt.CloseReason.ErrorCode)
+				return
+			case <-ctx.Done():
+				t.Fatal("didn't receive NotConnected event")
+			}
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package conformance
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/sec"
+	"github.com/TheNoobiCat/go-libp2p/p2p/net/swarm"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/ping"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+// SubtestHandshake tests that hosts connect to each other, and that the peer ID of
+// the dialed peer is authenticated.
+func SubtestHandshake(t *testing.T, tc TestCase) {
+	t.Run("Ping", func(t *testing.T) { testPing(t, tc) })
+	t.Run("PeerIDMismatch", func(t *testing.T) { testPeerIDMismatch(t, tc) })
+}
+
+func testPing(t *testing.T, tc TestCase) {
+	h1 := tc.HostGenerator(t, HostOpts{})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer h1.Close()
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{
+		ID:    h1.ID(),
+		Addrs: h1.Addrs(),
+	}))
+
+	ctx := context.Background()
+	res := <-ping.Ping(ctx, h2, h1.ID())
+	require.NoError(t, res.Error)
+}
+
+// testPeerIDMismatch tests that the actual peer ID of the dialed peer can be discovered
+// from the error when dialing it with the wrong peer ID.
+func testPeerIDMismatch(t *testing.T, tc TestCase) {
+	// extracts the peerID of the dialed peer from the error
+	extractPeerIDFromError := func(inputErr error) (peer.ID, error) {
+		var dialErr *swarm.DialError
+		if !errors.As(inputErr, &dialErr) {
+			return "", inputErr
+		}
+		innerErr := dialErr.DialErrors[0].Cause
+
+		var peerIDMismatchErr sec.ErrPeerIDMismatch
+		if errors.As(innerErr, &peerIDMismatchErr) {
+			return peerIDMismatchErr.Actual, nil
+		}
+
+		return "", inputErr
+	}
+
+	h1 := tc.HostGenerator(t, HostOpts{})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer h1.Close()
+	defer h2.Close()
+
+	// runs a test to verify we can extract the peer ID from a target with just its address
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Use a bogus peer ID so that when we connect to the target we get an error telling
+	// us the targets real peer ID
+	bogusPeerId, err := peer.Decode("QmadAdJ3f63JyNs65X7HHzqDwV53ynvCcKtNFvdNaz3nhk")
+	require.NoError(t, err, "the hard coded bogus peerID is invalid")
+
+	ai := &peer.AddrInfo{
+		ID:    bogusPeerId,
+		Addrs: []ma.Multiaddr{h1.Addrs()[0]},
+	}
+
+	// Try connecting with the bogus peer ID
+	err = h2.Connect(ctx, *ai)
+	require.Error(t, err, "somehow we successfully connected to a bogus peerID!")
+
+	// Extract the actual peer ID from the error
+	newPeerId, err := extractPeerIDFromError(err)
+	require.NoError(t, err)
+	ai.ID = newPeerId
+	// Make sure the new ID is what we expected
+	require.Equal(t, h1.ID(), ai.ID)
+
+	// and just to double-check try connecting again to make sure it works
+	require.NoError(t, h2.Connect(ctx, *ai))
+}
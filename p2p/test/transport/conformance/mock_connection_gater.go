@@ -3,11 +3,11 @@
 //
 // Generated by this command:
 //
-//	mockgen -package transport_integration -destination mock_connection_gater_test.go github.com/TheNoobiCat/go-libp2p/core/connmgr ConnectionGater
+//	mockgen -package conformance -destination mock_connection_gater.go github.com/TheNoobiCat/go-libp2p/core/connmgr ConnectionGater
 //
 
-// Package transport_integration is a generated GoMock package.
-package transport_integration
+// Package conformance is a generated GoMock package.
+package conformance
 
 import (
 	reflect "reflect"
@@ -0,0 +1,392 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	mocknetwork "github.com/TheNoobiCat/go-libp2p/core/network/mocks"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/peerstore"
+	rcmgr "github.com/TheNoobiCat/go-libp2p/p2p/host/resource-manager"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/identify"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/ping"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/multiformats/go-multiaddr/matest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+)
+
+// SubtestResourceLimits tests that connections and streams are accounted by the
+// resource manager, and that the transport copes with the resource manager blocking
+// them.
+func SubtestResourceLimits(t *testing.T, tc TestCase) {
+	t.Run("ResourceManagerIsUsed", func(t *testing.T) { testResourceManagerIsUsed(t, tc) })
+	t.Run("MoreStreamsThanOurLimits", func(t *testing.T) { testMoreStreamsThanOurLimits(t, tc) })
+	if tc.Quirks.DropsBlockedConns {
+		t.Run("ConnDroppedWhenBlocked", func(t *testing.T) { testConnDroppedWhenBlocked(t, tc) })
+	} else {
+		t.Run("CloseConnWhenBlocked", func(t *testing.T) { testCloseConnWhenBlocked(t, tc) })
+	}
+}
+
+func testResourceManagerIsUsed(t *testing.T, tc TestCase) {
+	for _, testDialer := range []bool{true, false} {
+		t.Run(fmt.Sprintf("test_dialer=%v", testDialer), func(t *testing.T) {
+
+			var reservedMemory, releasedMemory atomic.Int32
+			defer func() {
+				require.Equal(t, reservedMemory.Load(), releasedMemory.Load())
+				require.NotEqual(t, 0, reservedMemory.Load())
+			}()
+
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			rcmgr := mocknetwork.NewMockResourceManager(ctrl)
+			rcmgr.EXPECT().Close()
+
+			var listener, dialer host.Host
+			var expectedPeer peer.ID
+			var expectedDir network.Direction
+			var expectedAddr gomock.Matcher
+			if testDialer {
+				listener = tc.HostGenerator(t, HostOpts{NoRcmgr: true})
+				dialer = tc.HostGenerator(t, HostOpts{NoListen: true, ResourceManager: rcmgr})
+				expectedPeer = listener.ID()
+				expectedDir = network.DirOutbound
+				expectedAddr = matest.MultiaddrMatcher{Multiaddr: listener.Addrs()[0]}
+			} else {
+				listener = tc.HostGenerator(t, HostOpts{ResourceManager: rcmgr})
+				dialer = tc.HostGenerator(t, HostOpts{NoListen: true, NoRcmgr: true})
+				expectedPeer = dialer.ID()
+				expectedDir = network.DirInbound
+				expectedAddr = gomock.Any()
+			}
+
+			peerScope := mocknetwork.NewMockPeerScope(ctrl)
+			peerScope.EXPECT().ReserveMemory(gomock.Any(), gomock.Any()).AnyTimes().Do(func(amount int, _ uint8) {
+				reservedMemory.Add(int32(amount))
+			})
+			peerScope.EXPECT().ReleaseMemory(gomock.Any()).AnyTimes().Do(func(amount int) {
+				releasedMemory.Add(int32(amount))
+			})
+			peerScope.EXPECT().BeginSpan().AnyTimes().DoAndReturn(func() (network.ResourceScopeSpan, error) {
+				s := mocknetwork.NewMockResourceScopeSpan(ctrl)
+				s.EXPECT().BeginSpan().AnyTimes().Return(mocknetwork.NewMockResourceScopeSpan(ctrl), nil)
+				// No need to track these memory reservations since we assert that Done is called
+				s.EXPECT().ReserveMemory(gomock.Any(), gomock.Any())
+				s.EXPECT().Done()
+				return s, nil
+			})
+			var calledSetPeer atomic.Bool
+
+			connScope := mocknetwork.NewMockConnManagementScope(ctrl)
+			connScope.EXPECT().SetPeer(expectedPeer).Do(func(peer.ID) {
+				calledSetPeer.Store(true)
+			})
+			connScope.EXPECT().PeerScope().AnyTimes().DoAndReturn(func() network.PeerScope {
+				if calledSetPeer.Load() {
+					return peerScope
+				}
+				return nil
+			})
+			if tc.Quirks.ReservesConnMemory {
+				// e.g. the webrtc receive buffer is a fix sized buffer allocated up front
+				connScope.EXPECT().ReserveMemory(gomock.Any(), gomock.Any())
+			}
+			connScope.EXPECT().Done().MinTimes(1)
+			// udp transports won't have FD
+			expectFd := !tc.Quirks.NoFD
+
+			if !testDialer && tc.Quirks.VerifiesSourceAddress {
+				rcmgr.EXPECT().VerifySourceAddress(gomock.Any()).Return(false)
+			}
+			rcmgr.EXPECT().OpenConnection(expectedDir, expectFd, expectedAddr).Return(connScope, nil)
+
+			var allStreamsDone sync.WaitGroup
+			rcmgr.EXPECT().OpenStream(expectedPeer, gomock.Any()).AnyTimes().DoAndReturn(func(_ peer.ID, _ network.Direction) (network.StreamManagementScope, error) {
+				allStreamsDone.Add(1)
+				streamScope := mocknetwork.NewMockStreamManagementScope(ctrl)
+				// No need to track these memory reservations since we assert that Done is called
+				streamScope.EXPECT().ReserveMemory(gomock.Any(), gomock.Any()).AnyTimes()
+				streamScope.EXPECT().ReleaseMemory(gomock.Any()).AnyTimes()
+				streamScope.EXPECT().BeginSpan().AnyTimes().DoAndReturn(func() (network.ResourceScopeSpan, error) {
+					s := mocknetwork.NewMockResourceScopeSpan(ctrl)
+					s.EXPECT().BeginSpan().AnyTimes().Return(mocknetwork.NewMockResourceScopeSpan(ctrl), nil)
+					s.EXPECT().Done()
+					return s, nil
+				})
+
+				streamScope.EXPECT().SetService(gomock.Any()).MaxTimes(1)
+				streamScope.EXPECT().SetProtocol(gomock.Any())
+
+				streamScope.EXPECT().Done().Do(func() {
+					allStreamsDone.Done()
+				})
+				return streamScope, nil
+			})
+
+			require.NoError(t, dialer.Connect(context.Background(), peer.AddrInfo{
+				ID:    listener.ID(),
+				Addrs: listener.Addrs(),
+			}))
+			// Wait for any in progress identifies to finish.
+			// We shouldn't have to do this, but basic host currently
+			// always does an identify.
+			<-dialer.(interface{ IDService() identify.IDService }).IDService().IdentifyWait(dialer.Network().ConnsToPeer(listener.ID())[0])
+			<-listener.(interface{ IDService() identify.IDService }).IDService().IdentifyWait(listener.Network().ConnsToPeer(dialer.ID())[0])
+			<-ping.Ping(context.Background(), dialer, listener.ID())
+			err := dialer.Network().ClosePeer(listener.ID())
+			require.NoError(t, err)
+
+			// Wait a bit for any pending .Adds before we call .Wait to avoid a data race.
+			// This shouldn't be necessary since it should be impossible
+			// for an OpenStream to happen *after* a ClosePeer, however
+			// in practice it does and leads to test flakiness.
+			time.Sleep(10 * time.Millisecond)
+			allStreamsDone.Wait()
+			dialer.Close()
+			listener.Close()
+		})
+	}
+}
+
+// testMoreStreamsThanOurLimits tests handling more streams than our and the
+// peer's resource limits. It spawns 1024 Go routines that try to open a stream
+// and send and receive data. If they encounter an error they'll try again after
+// a sleep. If the transport is well behaved, eventually all Go routines will
+// have sent and received a message.
+func testMoreStreamsThanOurLimits(t *testing.T, tc TestCase) {
+	const streamCount = 1024
+	if tc.Quirks.LimitedStreamIDs {
+		t.Skip("This test potentially exhausts the stream ID space.")
+	}
+	listenerLimits := rcmgr.PartialLimitConfig{
+		PeerDefault: rcmgr.ResourceLimits{
+			Streams:         32,
+			StreamsInbound:  16,
+			StreamsOutbound: 16,
+		},
+	}
+	r, err := rcmgr.NewResourceManager(rcmgr.NewFixedLimiter(listenerLimits.Build(rcmgr.DefaultLimits.AutoScale())))
+	require.NoError(t, err)
+	listener := tc.HostGenerator(t, HostOpts{ResourceManager: r})
+	dialer := tc.HostGenerator(t, HostOpts{NoListen: true, NoRcmgr: true})
+	defer listener.Close()
+	defer dialer.Close()
+
+	require.NoError(t, dialer.Connect(context.Background(), peer.AddrInfo{
+		ID:    listener.ID(),
+		Addrs: listener.Addrs(),
+	}))
+
+	var handledStreams atomic.Int32
+	var sawFirstErr atomic.Bool
+
+	workQueue := make(chan struct{}, streamCount)
+	for i := 0; i < streamCount; i++ {
+		workQueue <- struct{}{}
+	}
+	close(workQueue)
+
+	listener.SetStreamHandler("echo", func(s network.Stream) {
+		// Wait a bit so that we have more parallel streams open at the same time
+		time.Sleep(time.Millisecond * 10)
+		io.Copy(s, s)
+		s.Close()
+	})
+
+	wg := sync.WaitGroup{}
+	errCh := make(chan error, 1)
+	var completedStreams atomic.Int32
+
+	const maxWorkerCount = streamCount
+	workerCount := 4
+
+	var startWorker func(workerIdx int)
+	startWorker = func(workerIdx int) {
+		wg.Add(1)
+		defer wg.Done()
+		for {
+			_, ok := <-workQueue
+			if !ok {
+				return
+			}
+
+			// Inline function so we can use defer
+			func() {
+				var didErr bool
+				defer completedStreams.Add(1)
+				defer func() {
+					// Only the first worker adds more workers
+					if workerIdx == 0 && !didErr && !sawFirstErr.Load() {
+						nextWorkerCount := workerCount * 2
+						if nextWorkerCount < maxWorkerCount {
+							for i := workerCount; i < nextWorkerCount; i++ {
+								go startWorker(i)
+							}
+							workerCount = nextWorkerCount
+						}
+					}
+				}()
+
+				var s network.Stream
+				var err error
+				// maxRetries is an arbitrary retry amount if there's any error.
+				maxRetries := streamCount * 4
+				shouldRetry := func(_ error) bool {
+					didErr = true
+					sawFirstErr.Store(true)
+					maxRetries--
+					if maxRetries == 0 || len(errCh) > 0 {
+						select {
+						case errCh <- errors.New("max retries exceeded"):
+						default:
+						}
+						return false
+					}
+					return true
+				}
+
+				for {
+					s, err = dialer.NewStream(context.Background(), listener.ID(), "echo")
+					if err != nil {
+						if shouldRetry(err) {
+							time.Sleep(50 * time.Millisecond)
+							continue
+						}
+						t.Logf("opening stream failed: %v", err)
+						return
+					}
+					err = func(s network.Stream) error {
+						defer s.Close()
+						err = s.SetDeadline(time.Now().Add(100 * time.Millisecond))
+						if err != nil {
+							return err
+						}
+
+						_, err = s.Write([]byte("hello"))
+						if err != nil {
+							return err
+						}
+
+						err = s.CloseWrite()
+						if err != nil {
+							return err
+						}
+
+						b, err := io.ReadAll(s)
+						if err != nil {
+							return err
+						}
+						if !bytes.Equal(b, []byte("hello")) {
+							return errors.New("received data does not match sent data")
+						}
+						handledStreams.Add(1)
+
+						return nil
+					}(s)
+					if err != nil && shouldRetry(err) {
+						time.Sleep(50 * time.Millisecond)
+						continue
+					}
+					return
+				}
+			}()
+		}
+	}
+
+	// Create any initial parallel workers
+	for i := 1; i < workerCount; i++ {
+		go startWorker(i)
+	}
+
+	// Start the first worker
+	startWorker(0)
+
+	wg.Wait()
+	close(errCh)
+
+	require.NoError(t, <-errCh)
+	require.Equal(t, streamCount, int(handledStreams.Load()))
+	require.True(t, sawFirstErr.Load(), "Expected to see an error from the peer")
+}
+
+// testCloseConnWhenBlocked tests that the server closes the connection when the rcmgr blocks it.
+func testCloseConnWhenBlocked(t *testing.T, tc TestCase) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRcmgr := mocknetwork.NewMockResourceManager(ctrl)
+	if tc.Quirks.VerifiesSourceAddress {
+		mockRcmgr.EXPECT().VerifySourceAddress(gomock.Any()).AnyTimes().Return(false)
+		// If the initial TLS ClientHello is split into two quic-go might call the transport multiple times to open a
+		// connection. This will only be called multiple times if the connection is rejected. If were were to accept
+		// the connection, this would have been called only once.
+		mockRcmgr.EXPECT().OpenConnection(network.DirInbound, gomock.Any(), gomock.Any()).Return(nil, errors.New("connection blocked")).AnyTimes()
+	} else {
+		mockRcmgr.EXPECT().OpenConnection(network.DirInbound, gomock.Any(), gomock.Any()).Return(nil, errors.New("connection blocked"))
+	}
+	mockRcmgr.EXPECT().Close().AnyTimes()
+
+	server := tc.HostGenerator(t, HostOpts{ResourceManager: mockRcmgr})
+	client := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer server.Close()
+	defer client.Close()
+
+	client.Peerstore().AddAddrs(server.ID(), server.Addrs(), peerstore.PermanentAddrTTL)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := client.NewStream(ctx, server.ID(), ping.ID)
+	require.Error(t, err)
+	require.False(t, errors.Is(err, context.DeadlineExceeded), "expected error to be not be context deadline exceeded")
+}
+
+// testConnDroppedWhenBlocked is similar to testCloseConnWhenBlocked, but for
+// transports like WebRTC we don't have a connection when we block it.  Instead
+// we just ignore the connection attempt. This tests that the client hits the
+// connection attempt deadline and neither server nor client see a successful
+// connection attempt
+func testConnDroppedWhenBlocked(t *testing.T, tc TestCase) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRcmgr := mocknetwork.NewMockResourceManager(ctrl)
+	mockRcmgr.EXPECT().OpenConnection(network.DirInbound, gomock.Any(), gomock.Any()).AnyTimes().DoAndReturn(func(network.Direction, bool, ma.Multiaddr) (network.ConnManagementScope, error) {
+		// Block the connection
+		return nil, fmt.Errorf("connections blocked")
+	})
+	mockRcmgr.EXPECT().Close().AnyTimes()
+
+	server := tc.HostGenerator(t, HostOpts{ResourceManager: mockRcmgr})
+	client := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer server.Close()
+	defer client.Close()
+
+	serverSub, err := server.EventBus().Subscribe(new(event.EvtPeerConnectednessChanged))
+	require.NoError(t, err)
+	clientSub, err := client.EventBus().Subscribe(new(event.EvtPeerConnectednessChanged))
+	require.NoError(t, err)
+
+	client.Peerstore().AddAddrs(server.ID(), server.Addrs(), peerstore.PermanentAddrTTL)
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	_, err = client.NewStream(ctx, server.ID(), ping.ID)
+	require.Error(t, err)
+	require.True(t, errors.Is(err, context.DeadlineExceeded), "The client should have hit the deadline when connecting")
+	select {
+	case <-serverSub.Out():
+		t.Fatal("expected no connected event. Connection should have failed")
+	case <-clientSub.Out():
+		t.Fatal("expected no connected event. Connection should have failed")
+	case <-time.After(time.Second):
+	}
+}
@@ -0,0 +1,148 @@
+// Package conformance is a test suite for libp2p transports. It runs hosts using the
+// transport against each other, and checks that the transport behaves as the rest of
+// libp2p expects: handshakes, connection gating, resource limits, deadlines, large
+// transfers, and the semantics of resets and abrupt closes.
+//
+// Transport authors can run the suite against their own transport with a single call:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.Run(t, conformance.NewTestCase("MyTransport", "/ip4/127.0.0.1/tcp/0/my-transport", mytransport.New))
+//	}
+package conformance
+
+import (
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p"
+	"github.com/TheNoobiCat/go-libp2p/config"
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestCase is a transport configuration to run the suite against.
+type TestCase struct {
+	Name string
+	// HostGenerator creates a host using the transport. The host must listen on a
+	// single address, unless opts.NoListen is set.
+	HostGenerator func(t *testing.T, opts HostOpts) host.Host
+	// Quirks lists the ways the transport is expected to deviate from the default
+	// behavior.
+	Quirks Quirks
+}
+
+// HostOpts are the options HostGenerator must apply to the host it creates. See
+// HostOptions.
+type HostOpts struct {
+	NoListen        bool
+	NoRcmgr         bool
+	ConnGater       connmgr.ConnectionGater
+	ResourceManager network.ResourceManager
+}
+
+// Quirks are the legitimate ways a transport deviates from the behavior the suite
+// expects by default. The zero value fits TCP based transports.
+type Quirks struct {
+	// NoFD is set for transports whose connections don't use a file descriptor, such as
+	// the transports running over a shared UDP socket.
+	NoFD bool
+	// VerifiesSourceAddress is set for transports that call
+	// ResourceManager.VerifySourceAddress on inbound connections, and that may call
+	// ResourceManager.OpenConnection several times for a connection it blocked, like
+	// QUIC.
+	VerifiesSourceAddress bool
+	// DropsBlockedConns is set for transports that silently drop the connection
+	// attempts rejected by the resource manager or the connection gater, so that the
+	// dial times out.
+	DropsBlockedConns bool
+	// RetransmitsHandshake is set for transports where the retransmissions of a
+	// rejected handshake are accepted as new connections.
+	RetransmitsHandshake bool
+	// AcceptsOnTransportAddr is set for transports whose accepted connections only
+	// report the IP and port of the listen address as their local address.
+	AcceptsOnTransportAddr bool
+	// ReservesConnMemory is set for transports reserving memory in the connection
+	// scope up front.
+	ReservesConnMemory bool
+	// LimitedStreamIDs is set for transports with a stream ID space small enough to be
+	// exhausted by opening many streams.
+	LimitedStreamIDs bool
+	// NoStreamErrorCodes is set for transports that don't support error codes.
+	NoStreamErrorCodes bool
+	// NoConnErrorCodes is set for transports that support stream error codes, but
+	// not connection error codes.
+	NoConnErrorCodes bool
+}
+
+// HostOptions returns the libp2p options implementing opts, except for NoListen.
+// It's meant to be used by HostGenerators.
+func HostOptions(opts HostOpts) []config.Option {
+	var libp2pOpts []libp2p.Option
+
+	if opts.NoRcmgr {
+		libp2pOpts = append(libp2pOpts, libp2p.ResourceManager(&network.NullResourceManager{}))
+	}
+	if opts.ConnGater != nil {
+		libp2pOpts = append(libp2pOpts, libp2p.ConnectionGater(opts.ConnGater))
+	}
+
+	if opts.ResourceManager != nil {
+		libp2pOpts = append(libp2pOpts, libp2p.ResourceManager(opts.ResourceManager))
+	}
+	return libp2pOpts
+}
+
+// NewTestCase returns a TestCase for the transport constructed by constructor, as
+// passed to libp2p.Transport. The hosts only use this transport and listen on
+// listenAddr. opts are applied to all the hosts.
+func NewTestCase(name, listenAddr string, constructor any, opts ...libp2p.Option) TestCase {
+	return TestCase{
+		Name: name,
+		HostGenerator: func(t *testing.T, hostOpts HostOpts) host.Host {
+			libp2pOpts := HostOptions(hostOpts)
+			libp2pOpts = append(libp2pOpts, libp2p.Transport(constructor))
+			libp2pOpts = append(libp2pOpts, opts...)
+			if hostOpts.NoListen {
+				libp2pOpts = append(libp2pOpts, libp2p.NoListenAddrs)
+			} else {
+				libp2pOpts = append(libp2pOpts, libp2p.ListenAddrStrings(listenAddr))
+			}
+			h, err := libp2p.New(libp2pOpts...)
+			require.NoError(t, err)
+			return h
+		},
+	}
+}
+
+// Subtest tests a property of the transport.
+type Subtest struct {
+	Name string
+	Run  func(t *testing.T, tc TestCase)
+}
+
+// Subtests are the subtests run by Run.
+var Subtests = []Subtest{
+	{Name: "Handshake", Run: SubtestHandshake},
+	{Name: "Gating", Run: SubtestGating},
+	{Name: "ResourceLimits", Run: SubtestResourceLimits},
+	{Name: "Deadlines", Run: SubtestDeadlines},
+	{Name: "LargeTransfers", Run: SubtestLargeTransfers},
+	{Name: "AbruptClose", Run: SubtestAbruptClose},
+	{Name: "ErrorCodes", Run: SubtestErrorCodes},
+}
+
+// Run runs all the Subtests against tc.
+func Run(t *testing.T, tc TestCase) {
+	RunSubtests(t, tc, Subtests)
+}
+
+// RunSubtests runs tests against tc, each in its own subtest.
+func RunSubtests(t *testing.T, tc TestCase, tests []Subtest) {
+	for _, st := range tests {
+		t.Run(st.Name, func(t *testing.T) {
+			st.Run(t, tc)
+		})
+	}
+}
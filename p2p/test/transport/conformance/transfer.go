@@ -0,0 +1,210 @@
+package conformance
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	"github.com/stretchr/testify/require"
+)
+
+// SubtestLargeTransfers tests sending a lot of data, on a single stream and on many
+// streams.
+func SubtestLargeTransfers(t *testing.T, tc TestCase) {
+	t.Run("BigPing", func(t *testing.T) { testBigPing(t, tc) })
+	t.Run("LotsOfDataManyStreams", func(t *testing.T) { testLotsOfDataManyStreams(t, tc) })
+	t.Run("ManyStreams", func(t *testing.T) { testManyStreams(t, tc) })
+}
+
+func testBigPing(t *testing.T, tc TestCase) {
+	// 64k buffers
+	sendBuf := make([]byte, 64<<10)
+	recvBuf := make([]byte, 64<<10)
+	const totalSends = 64
+
+	// Fill with random bytes
+	_, err := rand.Read(sendBuf)
+	require.NoError(t, err)
+
+	h1 := tc.HostGenerator(t, HostOpts{})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer h1.Close()
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{
+		ID:    h1.ID(),
+		Addrs: h1.Addrs(),
+	}))
+
+	h1.SetStreamHandler("/big-ping", func(s network.Stream) {
+		io.Copy(s, s)
+		s.Close()
+	})
+
+	errCh := make(chan error, 1)
+	allocs := testing.AllocsPerRun(10, func() {
+		s, err := h2.NewStream(context.Background(), h1.ID(), "/big-ping")
+		require.NoError(t, err)
+		defer s.Close()
+
+		go func() {
+			for i := 0; i < totalSends; i++ {
+				_, err := io.ReadFull(s, recvBuf)
+				if err != nil {
+					errCh <- err
+					return
+				}
+				if !bytes.Equal(sendBuf, recvBuf) {
+					errCh <- fmt.Errorf("received data does not match sent data")
+				}
+
+			}
+			_, err = s.Read([]byte{0})
+			errCh <- err
+		}()
+
+		for i := 0; i < totalSends; i++ {
+			s.Write(sendBuf)
+		}
+		s.CloseWrite()
+		require.ErrorIs(t, <-errCh, io.EOF)
+	})
+
+	if int(allocs) > (len(sendBuf)*totalSends)/4 {
+		t.Logf("Expected fewer allocs, got: %f", allocs)
+	}
+}
+
+// testLotsOfDataManyStreams tests sending a lot of data on multiple streams.
+func testLotsOfDataManyStreams(t *testing.T, tc TestCase) {
+	// Skip on windows because of https://github.com/TheNoobiCat/go-libp2p/issues/2341
+	if runtime.GOOS == "windows" {
+		t.Skip("Skipping on windows because of https://github.com/TheNoobiCat/go-libp2p/issues/2341")
+	}
+
+	// 64k buffer
+	const bufSize = 64 << 10
+	sendBuf := [bufSize]byte{}
+	const totalStreams = 500
+	const parallel = 8
+	// Total sends are > 20MiB
+	require.Greater(t, len(sendBuf)*totalStreams, 20<<20)
+	t.Log("Total sends:", len(sendBuf)*totalStreams)
+
+	// Fill with random bytes
+	_, err := rand.Read(sendBuf[:])
+	require.NoError(t, err)
+
+	h1 := tc.HostGenerator(t, HostOpts{})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true})
+	defer h1.Close()
+	defer h2.Close()
+	start := time.Now()
+	defer func() {
+		t.Log("Total time:", time.Since(start))
+	}()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{
+		ID:    h1.ID(),
+		Addrs: h1.Addrs(),
+	}))
+
+	h1.SetStreamHandler("/big-ping", func(s network.Stream) {
+		io.Copy(s, s)
+		s.Close()
+	})
+
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i := 0; i < totalStreams; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			recvBuf := [bufSize]byte{}
+			defer func() { <-sem }()
+
+			s, err := h2.NewStream(context.Background(), h1.ID(), "/big-ping")
+			require.NoError(t, err)
+			defer s.Close()
+
+			_, err = s.Write(sendBuf[:])
+			require.NoError(t, err)
+			s.CloseWrite()
+
+			_, err = io.ReadFull(s, recvBuf[:])
+			require.NoError(t, err)
+			require.Equal(t, sendBuf, recvBuf)
+
+			_, err = s.Read([]byte{0})
+			require.ErrorIs(t, err, io.EOF)
+		}()
+	}
+
+	wg.Wait()
+}
+
+func testManyStreams(t *testing.T, tc TestCase) {
+	const streamCount = 128
+	h1 := tc.HostGenerator(t, HostOpts{NoRcmgr: true})
+	h2 := tc.HostGenerator(t, HostOpts{NoListen: true, NoRcmgr: true})
+	defer h1.Close()
+	defer h2.Close()
+
+	require.NoError(t, h2.Connect(context.Background(), peer.AddrInfo{
+		ID:    h1.ID(),
+		Addrs: h1.Addrs(),
+	}))
+
+	h1.SetStreamHandler("echo", func(s network.Stream) {
+		io.Copy(s, s)
+		s.CloseWrite()
+	})
+
+	streams := make([]network.Stream, streamCount)
+	for i := 0; i < streamCount; i++ {
+		s, err := h2.NewStream(context.Background(), h1.ID(), "echo")
+		require.NoError(t, err)
+		streams[i] = s
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(streamCount)
+	errCh := make(chan error, 1)
+	for _, s := range streams {
+		go func(s network.Stream) {
+			defer wg.Done()
+
+			s.Write([]byte("hello"))
+			s.CloseWrite()
+			b, err := io.ReadAll(s)
+			if err == nil {
+				if !bytes.Equal(b, []byte("hello")) {
+					err = fmt.Errorf("received data does not match sent data")
+				}
+			}
+			if err != nil {
+				select {
+				case errCh <- err:
+				default:
+				}
+			}
+		}(s)
+	}
+	wg.Wait()
+	close(errCh)
+
+	require.NoError(t, <-errCh)
+	for _, s := range streams {
+		require.NoError(t, s.Close())
+	}
+}
@@ -0,0 +1,163 @@
+package transport_integration
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/client"
+	"github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/relay"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcp"
+	libp2pwebrtc "github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebRTCPrivateToPrivate(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	r, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = relay.New(r)
+	require.NoError(t, err)
+	rinfo := peer.AddrInfo{ID: r.ID(), Addrs: r.Addrs()}
+
+	listener, err := libp2p.New(
+		libp2p.Transport(tcp.NewTCPTransport),
+		libp2p.Transport(libp2pwebrtc.NewPrivateTransport),
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0", "/webrtc"),
+	)
+	require.NoError(t, err)
+	defer listener.Close()
+	require.NoError(t, listener.Connect(ctx, rinfo))
+	_, err = client.Reserve(ctx, listener, rinfo)
+	require.NoError(t, err)
+
+	const proto = "/echo/1.0.0"
+	listener.SetStreamHandler(proto, func(s network.Stream) {
+		defer s.Close()
+		io.Copy(s, s)
+	})
+
+	dialer, err := libp2p.New(
+		libp2p.Transport(tcp.NewTCPTransport),
+		libp2p.Transport(libp2pwebrtc.NewPrivateTransport),
+		libp2p.NoListenAddrs,
+		// The signaling stream is opened on a relayed connection.
+		libp2p.EnableRelay(),
+	)
+	require.NoError(t, err)
+	defer dialer.Close()
+
+	webrtcAddr := r.Addrs()[0].Encapsulate(ma.StringCast("/p2p/" + r.ID().String() + "/p2p-circuit/webrtc"))
+	require.NoError(t, dialer.Connect(ctx, peer.AddrInfo{ID: listener.ID(), Addrs: []ma.Multiaddr{webrtcAddr}}))
+
+	s, err := dialer.NewStream(ctx, listener.ID(), proto)
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, "webrtc", s.Conn().ConnState().Transport)
+	require.False(t, s.Conn().Stat().Limited)
+	require.Equal(t, listener.ID(), s.Conn().RemotePeer())
+	_, err = s.Conn().RemoteMultiaddr().ValueForProtocol(ma.P_WEBRTC)
+	require.NoError(t, err)
+
+	_, err = s.Write([]byte("hello"))
+	require.NoError(t, err)
+	require.NoError(t, s.CloseWrite())
+	b, err := io.ReadAll(s)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(b))
+
+	// signaling streams on direct connections are reset
+	sig, err := r.NewStream(ctx, listener.ID(), libp2pwebrtc.SignalingProtocol)
+	require.NoError(t, err)
+	defer sig.Close()
+	require.NoError(t, sig.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = sig.Read(make([]byte, 1))
+	require.ErrorIs(t, err, network.ErrReset)
+}
+
+func TestWebRTCPrivateToPrivateAdvertisedAddrs(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	r, err := libp2p.New(libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0"))
+	require.NoError(t, err)
+	defer r.Close()
+	_, err = relay.New(r)
+	require.NoError(t, err)
+	rinfo := peer.AddrInfo{ID: r.ID(), Addrs: r.Addrs()}
+
+	listener, err := libp2p.New(
+		libp2p.Transport(tcp.NewTCPTransport),
+		libp2p.Transport(libp2pwebrtc.NewPrivateTransport),
+		libp2p.ListenAddrStrings("/ip4/127.0.0.1/tcp/0", "/webrtc"),
+		libp2p.ForceReachabilityPrivate(),
+	)
+	require.NoError(t, err)
+	defer listener.Close()
+	require.NoError(t, listener.Connect(ctx, rinfo))
+	_, err = client.Reserve(ctx, listener, rinfo)
+	require.NoError(t, err)
+	// Advertise the relay address, as autorelay does.
+	emitter, err := listener.EventBus().Emitter(new(event.EvtAutoRelayAddrsUpdated), eventbus.Stateful)
+	require.NoError(t, err)
+	defer emitter.Close()
+	relayAddr := r.Addrs()[0].Encapsulate(ma.StringCast("/p2p/" + r.ID().String() + "/p2p-circuit"))
+	require.NoError(t, emitter.Emit(event.EvtAutoRelayAddrsUpdated{RelayAddrs: []ma.Multiaddr{relayAddr}}))
+
+	// The listener advertises both the relay address and the /webrtc address derived
+	// from it. Leave out the direct addresses, they'd be preferred.
+	var addrs []ma.Multiaddr
+	require.Eventually(t, func() bool {
+		addrs = addrs[:0]
+		var hasRelay, hasWebRTC bool
+		for _, a := range listener.Addrs() {
+			if _, err := a.ValueForProtocol(ma.P_CIRCUIT); err != nil {
+				continue
+			}
+			addrs = append(addrs, a)
+			if _, err := a.ValueForProtocol(ma.P_WEBRTC); err == nil {
+				hasWebRTC = true
+			} else {
+				hasRelay = true
+			}
+		}
+		return hasRelay && hasWebRTC
+	}, 10*time.Second, 100*time.Millisecond)
+
+	dialer, err := libp2p.New(
+		libp2p.Transport(tcp.NewTCPTransport),
+		libp2p.Transport(libp2pwebrtc.NewPrivateTransport),
+		libp2p.NoListenAddrs,
+		libp2p.EnableRelay(),
+	)
+	require.NoError(t, err)
+	defer dialer.Close()
+
+	require.NoError(t, dialer.Connect(ctx, peer.AddrInfo{ID: listener.ID(), Addrs: addrs}))
+	conns := dialer.Network().ConnsToPeer(listener.ID())
+	require.NotEmpty(t, conns)
+	var hasWebRTCConn bool
+	for _, c := range conns {
+		if c.ConnState().Transport == "webrtc" {
+			hasWebRTCConn = true
+			require.False(t, c.Stat().Limited)
+		}
+	}
+	require.True(t, hasWebRTCConn, "expected a webrtc connection, got %v", conns)
+
+	s, err := dialer.NewStream(ctx, listener.ID(), "/ipfs/ping/1.0.0")
+	require.NoError(t, err)
+	defer s.Close()
+	require.Equal(t, "webrtc", s.Conn().ConnState().Transport)
+}
@@ -0,0 +1,225 @@
+// Package memory implements an in-memory libp2p transport.
+//
+// Addresses have the form /memory/<id>. Listening on /memory/0 picks an unused id.
+// Connections only work within a single process, which makes this transport useful
+// for fast and hermetic tests. Raw connections are secured and multiplexed by the
+// upgrader, just like TCP connections.
+package memory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/transport"
+
+	logging "github.com/ipfs/go-log/v2"
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+)
+
+var log = logging.Logger("memory-tpt")
+
+// ErrNoListener is returned when dialing an address nobody is listening on.
+var ErrNoListener = errors.New("memory transport: no listener on address")
+
+// registry tracks all memory listeners in this process.
+var registry = struct {
+	sync.Mutex
+	listeners map[uint64]*listener
+}{listeners: make(map[uint64]*listener)}
+
+// nextID is used to allocate ids for listeners on /memory/0 and for the local side of dialed
+// connections. It starts at a high value to avoid clashing with ids chosen by the user.
+var nextID atomic.Uint64
+
+func init() {
+	nextID.Store(1 << 32)
+}
+
+// Addr is the net.Addr of a memory connection.
+type Addr uint64
+
+func (a Addr) Network() string { return "memory" }
+func (a Addr) String() string  { return strconv.FormatUint(uint64(a), 10) }
+
+// Multiaddr returns the /memory multiaddr for a.
+func (a Addr) Multiaddr() ma.Multiaddr {
+	return ma.StringCast("/memory/" + a.String())
+}
+
+func addrFromMultiaddr(a ma.Multiaddr) (Addr, error) {
+	v, err := a.ValueForProtocol(ma.P_MEMORY)
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return Addr(id), nil
+}
+
+// Transport is the in-memory transport.
+type Transport struct {
+	upgrader transport.Upgrader
+	rcmgr    network.ResourceManager
+}
+
+var _ transport.Transport = &Transport{}
+
+// NewTransport creates a new in-memory transport.
+func NewTransport(upgrader transport.Upgrader, rcmgr network.ResourceManager) (*Transport, error) {
+	if rcmgr == nil {
+		rcmgr = &network.NullResourceManager{}
+	}
+	return &Transport{upgrader: upgrader, rcmgr: rcmgr}, nil
+}
+
+// CanDial returns true if this transport can dial the given multiaddr.
+func (t *Transport) CanDial(addr ma.Multiaddr) bool {
+	return len(addr) == 1 && addr[0].Protocol().Code == ma.P_MEMORY
+}
+
+// Dial dials the peer at the remote address.
+func (t *Transport) Dial(ctx context.Context, raddr ma.Multiaddr, p peer.ID) (transport.CapableConn, error) {
+	connScope, err := t.rcmgr.OpenConnection(network.DirOutbound, true, raddr)
+	if err != nil {
+		log.Debugw("resource manager blocked outgoing connection", "peer", p, "addr", raddr, "error", err)
+		return nil, err
+	}
+	c, err := t.dialWithScope(ctx, raddr, p, connScope)
+	if err != nil {
+		connScope.Done()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (t *Transport) dialWithScope(ctx context.Context, raddr ma.Multiaddr, p peer.ID, connScope network.ConnManagementScope) (transport.CapableConn, error) {
+	if err := connScope.SetPeer(p); err != nil {
+		log.Debugw("resource manager blocked outgoing connection for peer", "peer", p, "addr", raddr, "error", err)
+		return nil, err
+	}
+	ra, err := addrFromMultiaddr(raddr)
+	if err != nil {
+		return nil, err
+	}
+	registry.Lock()
+	l, ok := registry.listeners[uint64(ra)]
+	registry.Unlock()
+	if !ok {
+		return nil, ErrNoListener
+	}
+
+	local, remote := newPipe(Addr(nextID.Add(1)), ra)
+	select {
+	case l.connCh <- remote:
+	case <-l.closed:
+		return nil, ErrNoListener
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return t.upgrader.Upgrade(ctx, t, wrapConn(local), network.DirOutbound, p, connScope)
+}
+
+// Listen listens on the given multiaddr.
+func (t *Transport) Listen(laddr ma.Multiaddr) (transport.Listener, error) {
+	a, err := addrFromMultiaddr(laddr)
+	if err != nil {
+		return nil, err
+	}
+	if a == 0 {
+		a = Addr(nextID.Add(1))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.listeners[uint64(a)]; ok {
+		return nil, fmt.Errorf("memory transport: address %s already in use", a.Multiaddr())
+	}
+	l := &listener{
+		addr:   a,
+		connCh: make(chan *pipeConn),
+		closed: make(chan struct{}),
+	}
+	registry.listeners[uint64(a)] = l
+	return t.upgrader.UpgradeGatedMaListener(t, t.upgrader.GateMaListener(l)), nil
+}
+
+// Protocols returns the list of terminal protocols this transport can dial.
+func (t *Transport) Protocols() []int {
+	return []int{ma.P_MEMORY}
+}
+
+// Proxy always returns false for the memory transport.
+func (t *Transport) Proxy() bool {
+	return false
+}
+
+func (t *Transport) String() string {
+	return "memory"
+}
+
+// listener is a manet.Listener for memory connections.
+type listener struct {
+	addr   Addr
+	connCh chan *pipeConn
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+var _ manet.Listener = &listener{}
+
+func (l *listener) Accept() (manet.Conn, error) {
+	select {
+	case c := <-l.connCh:
+		return wrapConn(c), nil
+	case <-l.closed:
+		return nil, transport.ErrListenerClosed
+	}
+}
+
+func (l *listener) Close() error {
+	l.closeOnce.Do(func() {
+		registry.Lock()
+		delete(registry.listeners, uint64(l.addr))
+		registry.Unlock()
+		close(l.closed)
+	})
+	return nil
+}
+
+func (l *listener) Addr() net.Addr {
+	return l.addr
+}
+
+func (l *listener) Multiaddr() ma.Multiaddr {
+	return l.addr.Multiaddr()
+}
+
+// maConn adds the manet.Conn methods to a pipeConn.
+type maConn struct {
+	*pipeConn
+}
+
+var _ manet.Conn = maConn{}
+
+func wrapConn(c *pipeConn) maConn {
+	return maConn{c}
+}
+
+func (c maConn) LocalMultiaddr() ma.Multiaddr {
+	return c.localAddr.(Addr).Multiaddr()
+}
+
+func (c maConn) RemoteMultiaddr() ma.Multiaddr {
+	return c.remoteAddr.(Addr).Multiaddr()
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"crypto/rand"
+	"testing"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/sec"
+	"github.com/TheNoobiCat/go-libp2p/core/sec/insecure"
+	"github.com/TheNoobiCat/go-libp2p/p2p/muxer/yamux"
+	tptu "github.com/TheNoobiCat/go-libp2p/p2p/net/upgrader"
+	ttransport "github.com/TheNoobiCat/go-libp2p/p2p/transport/testsuite"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/stretchr/testify/require"
+)
+
+var muxers = []tptu.StreamMuxer{{ID: "/yamux", Muxer: yamux.DefaultTransport}}
+
+func makeTransport(t *testing.T) (peer.ID, *Transport) {
+	t.Helper()
+	priv, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	id, err := peer.IDFromPrivateKey(priv)
+	require.NoError(t, err)
+	u, err := tptu.New([]sec.SecureTransport{insecure.NewWithIdentity(insecure.ID, id, priv)}, muxers, nil, nil, nil)
+	require.NoError(t, err)
+	tpt, err := NewTransport(u, nil)
+	require.NoError(t, err)
+	return id, tpt
+}
+
+func TestMemoryTransport(t *testing.T) {
+	peerA, ta := makeTransport(t)
+	_, tb := makeTransport(t)
+	ttransport.SubtestTransport(t, ta, tb, "/memory/0", peerA)
+}
+
+func TestCanDial(t *testing.T) {
+	_, tpt := makeTransport(t)
+	require.True(t, tpt.CanDial(ma.StringCast("/memory/1234")))
+	require.False(t, tpt.CanDial(ma.StringCast("/ip4/1.2.3.4/tcp/1234")))
+}
+
+func TestListenAddrInUse(t *testing.T) {
+	_, tpt := makeTransport(t)
+	l, err := tpt.Listen(ma.StringCast("/memory/42"))
+	require.NoError(t, err)
+	_, err = tpt.Listen(ma.StringCast("/memory/42"))
+	require.Error(t, err)
+	require.NoError(t, l.Close())
+
+	l, err = tpt.Listen(ma.StringCast("/memory/42"))
+	require.NoError(t, err)
+	require.True(t, l.Multiaddr().Equal(ma.StringCast("/memory/42")))
+	l.Close()
+}
+
+func TestDialNoListener(t *testing.T) {
+	_, tpt := makeTransport(t)
+	p, _ := makeTransport(t)
+	_, err := tpt.Dial(context.Background(), ma.StringCast("/memory/43"), p)
+	require.ErrorIs(t, err, ErrNoListener)
+}
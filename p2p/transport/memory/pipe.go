@@ -0,0 +1,203 @@
+package memory
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// deadline is a resettable deadline that closes a channel when it expires.
+type deadline struct {
+	mx     sync.Mutex
+	timer  *time.Timer
+	cancel chan struct{} // closed when the deadline expires
+}
+
+func newDeadline() *deadline {
+	return &deadline{cancel: make(chan struct{})}
+}
+
+func (d *deadline) set(t time.Time) {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+
+	if d.timer != nil && !d.timer.Stop() {
+		<-d.cancel // wait for the timer callback to finish and close the channel
+	}
+	d.timer = nil
+
+	closed := isClosedChan(d.cancel)
+	if t.IsZero() {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		return
+	}
+
+	if dur := time.Until(t); dur > 0 {
+		if closed {
+			d.cancel = make(chan struct{})
+		}
+		cancel := d.cancel
+		d.timer = time.AfterFunc(dur, func() { close(cancel) })
+		return
+	}
+
+	if !closed {
+		close(d.cancel)
+	}
+}
+
+func (d *deadline) wait() chan struct{} {
+	d.mx.Lock()
+	defer d.mx.Unlock()
+	return d.cancel
+}
+
+func isClosedChan(c <-chan struct{}) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
+
+// buffer is one direction of a pipe. Writes never block.
+type buffer struct {
+	mx       sync.Mutex
+	buf      bytes.Buffer
+	closed   bool // the writer closed the buffer, readers get io.EOF once it is drained
+	reset    bool // the reader closed the buffer, writes fail
+	notifyCh chan struct{}
+}
+
+func newBuffer() *buffer {
+	return &buffer{notifyCh: make(chan struct{}, 1)}
+}
+
+func (b *buffer) notify() {
+	select {
+	case b.notifyCh <- struct{}{}:
+	default:
+	}
+}
+
+func (b *buffer) write(p []byte) (int, error) {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	if b.closed || b.reset {
+		return 0, io.ErrClosedPipe
+	}
+	n, _ := b.buf.Write(p)
+	b.notify()
+	return n, nil
+}
+
+func (b *buffer) read(p []byte, d *deadline) (int, error) {
+	for {
+		b.mx.Lock()
+		switch {
+		case b.reset:
+			b.mx.Unlock()
+			return 0, net.ErrClosed
+		case b.buf.Len() > 0:
+			n, _ := b.buf.Read(p)
+			b.mx.Unlock()
+			return n, nil
+		case b.closed:
+			b.mx.Unlock()
+			return 0, io.EOF
+		}
+		b.mx.Unlock()
+
+		select {
+		case <-b.notifyCh:
+		case <-d.wait():
+			return 0, os.ErrDeadlineExceeded
+		}
+	}
+}
+
+func (b *buffer) closeWrite() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.closed = true
+	b.notify()
+}
+
+func (b *buffer) closeRead() {
+	b.mx.Lock()
+	defer b.mx.Unlock()
+	b.reset = true
+	b.buf.Reset()
+	b.notify()
+}
+
+// pipeConn is one end of an in-memory, buffered, full duplex connection.
+type pipeConn struct {
+	r, w          *buffer
+	readDeadline  *deadline
+	writeDeadline *deadline
+
+	localAddr, remoteAddr net.Addr
+
+	closeOnce sync.Once
+}
+
+var _ net.Conn = &pipeConn{}
+
+// newPipe creates a pair of connected pipeConns.
+func newPipe(a, b net.Addr) (*pipeConn, *pipeConn) {
+	ab, ba := newBuffer(), newBuffer()
+	return &pipeConn{
+			r: ba, w: ab,
+			readDeadline: newDeadline(), writeDeadline: newDeadline(),
+			localAddr: a, remoteAddr: b,
+		}, &pipeConn{
+			r: ab, w: ba,
+			readDeadline: newDeadline(), writeDeadline: newDeadline(),
+			localAddr: b, remoteAddr: a,
+		}
+}
+
+func (c *pipeConn) Read(p []byte) (int, error) {
+	return c.r.read(p, c.readDeadline)
+}
+
+func (c *pipeConn) Write(p []byte) (int, error) {
+	if isClosedChan(c.writeDeadline.wait()) {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return c.w.write(p)
+}
+
+func (c *pipeConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.w.closeWrite()
+		c.r.closeRead()
+	})
+	return nil
+}
+
+func (c *pipeConn) LocalAddr() net.Addr  { return c.localAddr }
+func (c *pipeConn) RemoteAddr() net.Addr { return c.remoteAddr }
+
+func (c *pipeConn) SetDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	c.writeDeadline.set(t)
+	return nil
+}
+
+func (c *pipeConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *pipeConn) SetWriteDeadline(t time.Time) error {
+	c.writeDeadline.set(t)
+	return nil
+}
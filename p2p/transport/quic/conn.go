@@ -2,11 +2,13 @@ package libp2pquic
 
 import (
 	"context"
+	"sync/atomic"
 
 	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	tpt "github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/quicreuse"
 
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/quic-go/quic-go"
@@ -23,9 +25,16 @@ type conn struct {
 	remotePeerID    peer.ID
 	remotePubKey    ic.PubKey
 	remoteMultiaddr ma.Multiaddr
+
+	numStreams atomic.Int64
+	// rtt is nil if the RTT of the connection isn't tracked
+	rtt *quicreuse.RTTStats
 }
 
-var _ tpt.CapableConn = &conn{}
+var (
+	_ tpt.CapableConn            = &conn{}
+	_ network.MuxerStatsReporter = &conn{}
+)
 
 // Close closes the connection.
 // It must be called even if the peer closed the connection in order for
@@ -63,7 +72,7 @@ func (c *conn) OpenStream(ctx context.Context) (network.MuxedStream, error) {
 	if err != nil {
 		return nil, parseStreamError(err)
 	}
-	return &stream{Stream: qstr}, nil
+	return c.newStream(qstr), nil
 }
 
 // AcceptStream accepts a stream opened by the other side.
@@ -72,7 +81,21 @@ func (c *conn) AcceptStream() (network.MuxedStream, error) {
 	if err != nil {
 		return nil, parseStreamError(err)
 	}
-	return &stream{Stream: qstr}, nil
+	return c.newStream(qstr), nil
+}
+
+// MuxerStats returns the number of open streams and the RTT. quic-go doesn't expose the
+// state of its flow controllers, so the buffered bytes and receive window aren't reported.
+func (c *conn) MuxerStats() network.MuxerStats {
+	return network.MuxerStats{
+		NumStreams: int(c.numStreams.Load()),
+		RTT:        c.rtt.Smoothed(),
+	}
+}
+
+func (c *conn) newStream(qstr quic.Stream) *stream {
+	c.numStreams.Add(1)
+	return &stream{Stream: qstr, conn: c}
 }
 
 // LocalPeer returns our peer ID
@@ -8,15 +8,18 @@ import (
 	"io"
 	mrand "math/rand"
 	"net"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	mocknetwork "github.com/TheNoobiCat/go-libp2p/core/network/mocks"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	tpt "github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/host/eventbus"
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/quicreuse"
 
 	ma "github.com/multiformats/go-multiaddr"
@@ -305,6 +308,15 @@ func testStreams(t *testing.T, tc *connTestCase) {
 	data, err := io.ReadAll(sstr)
 	require.NoError(t, err)
 	require.Equal(t, data, []byte("foobar"))
+
+	// the RTT is known once the handshake completed
+	require.Positive(t, conn.(network.MuxerStatsReporter).MuxerStats().RTT)
+	require.Positive(t, serverConn.(network.MuxerStatsReporter).MuxerStats().RTT)
+
+	// and it remains known after the peer closed the connection
+	serverConn.Close()
+	require.Eventually(t, conn.IsClosed, 5*time.Second, 10*time.Millisecond)
+	require.Positive(t, conn.(network.MuxerStatsReporter).MuxerStats().RTT)
 }
 
 func testStreamsErrorCode(t *testing.T, tc *connTestCase) {
@@ -730,3 +742,142 @@ func TestHolePunching(t *testing.T) {
 	<-done1
 	<-done2
 }
+
+func TestSessionResumption(t *testing.T) {
+	serverID, serverKey := createPeer(t)
+	_, clientKey := createPeer(t)
+
+	var serverEvents, clientEvents []event.EvtConnectionResumption
+	var mx sync.Mutex
+	observer := func(events *[]event.EvtConnectionResumption) Option {
+		return WithResumptionObserver(func(e event.EvtConnectionResumption) {
+			mx.Lock()
+			defer mx.Unlock()
+			*events = append(*events, e)
+		})
+	}
+
+	serverTransport, err := NewTransportWithOptions(serverKey, newConnManager(t), nil, nil, nil, EnableSessionResumption(10), observer(&serverEvents))
+	require.NoError(t, err)
+	defer serverTransport.(io.Closer).Close()
+	ln := runServer(t, serverTransport, "/ip4/127.0.0.1/udp/0/quic-v1")
+	defer ln.Close()
+
+	clientTransport, err := NewTransportWithOptions(clientKey, newConnManager(t), nil, nil, nil, EnableSessionResumption(10), observer(&clientEvents))
+	require.NoError(t, err)
+	defer clientTransport.(io.Closer).Close()
+
+	for i := 0; i < 2; i++ {
+		conn, err := clientTransport.Dial(context.Background(), ln.Multiaddr(), serverID)
+		require.NoError(t, err)
+		require.True(t, conn.RemotePublicKey().Equals(serverKey.GetPublic()))
+		serverConn, err := ln.Accept()
+		require.NoError(t, err)
+		require.True(t, serverConn.RemotePublicKey().Equals(clientKey.GetPublic()))
+		// make sure the client received the session ticket
+		str, err := conn.OpenStream(context.Background())
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		sstr, err := serverConn.AcceptStream()
+		require.NoError(t, err)
+		_, err = io.ReadFull(sstr, make([]byte, 6))
+		require.NoError(t, err)
+		conn.Close()
+		serverConn.Close()
+	}
+
+	mx.Lock()
+	defer mx.Unlock()
+	require.Len(t, clientEvents, 2)
+	require.False(t, clientEvents[0].Resumed)
+	require.True(t, clientEvents[1].Resumed)
+	require.Equal(t, serverID, clientEvents[1].Peer)
+	require.Equal(t, network.DirOutbound, clientEvents[1].Direction)
+	require.Len(t, serverEvents, 2)
+	require.True(t, serverEvents[1].Resumed)
+}
+
+func TestSessionResumption0RTT(t *testing.T) {
+	// 0-RTT data is only sent to peers whose public key is in the peer ID
+	serverKey, _, err := ic.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	serverID, err := peer.IDFromPrivateKey(serverKey)
+	require.NoError(t, err)
+	_, clientKey := createPeer(t)
+
+	newTransport := func(key ic.PrivKey, cm *quicreuse.ConnManager) (tpt.Transport, event.Subscription) {
+		tr, err := NewTransportWithOptions(key, cm, nil, nil, nil, EnableSessionResumption(10))
+		require.NoError(t, err)
+		t.Cleanup(func() { tr.(io.Closer).Close() })
+		bus := eventbus.NewBus()
+		sub, err := bus.Subscribe(new(event.EvtConnectionResumption))
+		require.NoError(t, err)
+		t.Cleanup(func() { sub.Close() })
+		require.NoError(t, tr.(interface{ SetEventBus(event.Bus) error }).SetEventBus(bus))
+		return tr, sub
+	}
+	nextEvent := func(sub event.Subscription) event.EvtConnectionResumption {
+		t.Helper()
+		select {
+		case e := <-sub.Out():
+			return e.(event.EvtConnectionResumption)
+		case <-time.After(5 * time.Second):
+			t.Fatal("timeout waiting for the resumption event")
+			return event.EvtConnectionResumption{}
+		}
+	}
+
+	// closed when the server restarts
+	serverCM, err := quicreuse.NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{}, quicreuse.Enable0RTT())
+	require.NoError(t, err)
+	serverTransport, serverSub := newTransport(serverKey, serverCM)
+	ln := runServer(t, serverTransport, "/ip4/127.0.0.1/udp/0/quic-v1")
+	clientTransport, clientSub := newTransport(clientKey, newConnManager(t, quicreuse.Enable0RTT()))
+
+	for i := 0; i < 2; i++ {
+		conn, err := clientTransport.Dial(context.Background(), ln.Multiaddr(), serverID)
+		require.NoError(t, err)
+		// On the second connection, the stream is opened in the first flight.
+		str, err := conn.OpenStream(context.Background())
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		serverConn, err := ln.Accept()
+		require.NoError(t, err)
+		require.True(t, serverConn.RemotePublicKey().Equals(clientKey.GetPublic()))
+		sstr, err := serverConn.AcceptStream()
+		require.NoError(t, err)
+		_, err = io.ReadFull(sstr, make([]byte, 6))
+		require.NoError(t, err)
+		// make sure the client received the session ticket
+		_, err = sstr.Write([]byte("foobar"))
+		require.NoError(t, err)
+		_, err = io.ReadFull(str, make([]byte, 6))
+		require.NoError(t, err)
+
+		clientEvt := nextEvent(clientSub)
+		serverEvt := nextEvent(serverSub)
+		require.Equal(t, i == 1, clientEvt.Resumed)
+		require.Equal(t, i == 1, clientEvt.Used0RTT)
+		require.False(t, clientEvt.Rejected0RTT)
+		require.Equal(t, i == 1, serverEvt.Used0RTT)
+		conn.Close()
+		serverConn.Close()
+	}
+
+	// The server restarts on the same address, without 0-RTT. It rejects the 0-RTT data,
+	// and the connection is closed.
+	addr := ln.Multiaddr()
+	ln.Close()
+	serverCM.Close()
+	serverTransport2, _ := newTransport(serverKey, newConnManager(t))
+	ln = runServer(t, serverTransport2, addr.String())
+	defer ln.Close()
+	conn, err := clientTransport.Dial(context.Background(), addr, serverID)
+	require.NoError(t, err)
+	clientEvt := nextEvent(clientSub)
+	require.True(t, clientEvt.Rejected0RTT)
+	require.False(t, clientEvt.Used0RTT)
+	require.Eventually(t, conn.IsClosed, 5*time.Second, 10*time.Millisecond)
+}
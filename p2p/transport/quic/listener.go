@@ -125,6 +125,7 @@ func (l *listener) wrapConnWithScope(qconn quic.Connection, connScope network.Co
 	if err != nil {
 		return nil, err
 	}
+	l.transport.connManager.SetConnPeer(qconn, remotePeerID)
 	if err := connScope.SetPeer(remotePeerID); err != nil {
 		log.Debugw("resource manager blocked incoming connection for peer", "peer", remotePeerID, "addr", qconn.RemoteAddr(), "error", err)
 		return nil, err
@@ -134,6 +135,7 @@ func (l *listener) wrapConnWithScope(qconn quic.Connection, connScope network.Co
 	if !found {
 		return nil, errors.New("unknown QUIC version:" + qconn.ConnectionState().Version.String())
 	}
+	l.transport.observeResumption(qconn, remotePeerID, network.DirInbound, false)
 
 	return &conn{
 		quicConn:        qconn,
@@ -144,6 +146,7 @@ func (l *listener) wrapConnWithScope(qconn quic.Connection, connScope network.Co
 		remoteMultiaddr: remoteMultiaddr,
 		remotePeerID:    remotePeerID,
 		remotePubKey:    remotePubKey,
+		rtt:             l.transport.connManager.RTTStats(qconn),
 	}, nil
 }
 
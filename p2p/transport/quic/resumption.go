@@ -0,0 +1,159 @@
+package libp2pquic
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
+	"io"
+
+	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	p2ptls "github.com/TheNoobiCat/go-libp2p/p2p/security/tls"
+
+	"github.com/quic-go/quic-go"
+	"golang.org/x/crypto/hkdf"
+)
+
+const sessionTicketKeyInfo = "libp2p quic session ticket key"
+
+// EnableSessionResumption makes the transport resume the TLS sessions of previous
+// connections when reconnecting to a peer, skipping the certificate exchange and
+// verification. Up to cacheSize session tickets are cached for outbound
+// connections. Session tickets issued to peers are encrypted with a key derived
+// from the host's private key, so they remain valid across restarts.
+//
+// The peer's identity is still verified: a session can only be resumed with the
+// peer that issued the ticket. Application data is only sent as 0-RTT data if 0-RTT
+// is enabled on the ConnManager, see quicreuse.Enable0RTT.
+//
+// The transport emits an event.EvtConnectionResumption for every connection on the
+// host's event bus.
+//
+// Use with NewTransportWithOptions:
+//
+//	libp2p.Transport(libp2pquic.NewTransportWithOptions, libp2pquic.EnableSessionResumption(256))
+func EnableSessionResumption(cacheSize int) Option {
+	return func(t *transport) error {
+		if cacheSize <= 0 {
+			return errors.New("session cache size must be positive")
+		}
+		key, err := privKeyToSessionTicketKey(t.privKey)
+		if err != nil {
+			return err
+		}
+		t.sessionCache = tls.NewLRUClientSessionCache(cacheSize)
+		t.sessionTicketKey = key
+		return nil
+	}
+}
+
+// WithResumptionObserver sets a function that is called for every established
+// connection when session resumption is enabled, in addition to the event emitted on
+// the event bus. It must not block.
+func WithResumptionObserver(f func(event.EvtConnectionResumption)) Option {
+	return func(t *transport) error {
+		t.resumptionObserver = f
+		return nil
+	}
+}
+
+func privKeyToSessionTicketKey(key ic.PrivKey) ([32]byte, error) {
+	var ticketKey [32]byte
+	keyBytes, err := ic.SecretSeed(key)
+	if err != nil {
+		return ticketKey, err
+	}
+	keyReader := hkdf.New(sha256.New, keyBytes, nil, []byte(sessionTicketKeyInfo))
+	if _, err := io.ReadFull(keyReader, ticketKey[:]); err != nil {
+		return ticketKey, err
+	}
+	return ticketKey, nil
+}
+
+// resumedPubKey returns the public key of p from the certificate stored in the
+// resumed session.
+func resumedPubKey(conn quic.Connection, p peer.ID) (ic.PubKey, error) {
+	pubKey, err := p2ptls.PubKeyFromCertChain(conn.ConnectionState().TLS.PeerCertificates)
+	if err != nil {
+		return nil, err
+	}
+	if !p.MatchesPublicKey(pubKey) {
+		return nil, errors.New("resumed session for unexpected peer")
+	}
+	return pubKey, nil
+}
+
+// SetEventBus makes the transport emit an event.EvtConnectionResumption on bus for
+// every connection, if session resumption is enabled.
+func (t *transport) SetEventBus(bus event.Bus) error {
+	em, err := bus.Emitter(new(event.EvtConnectionResumption))
+	if err != nil {
+		return err
+	}
+	t.emitterMx.Lock()
+	defer t.emitterMx.Unlock()
+	if t.resumptionEmitter != nil {
+		t.resumptionEmitter.Close()
+	}
+	t.resumptionEmitter = em
+	return nil
+}
+
+func (t *transport) observeResumption(conn quic.Connection, p peer.ID, dir network.Direction, rejected0RTT bool) {
+	if t.sessionCache == nil {
+		return
+	}
+	cs := conn.ConnectionState()
+	evt := event.EvtConnectionResumption{
+		Peer:         p,
+		Direction:    dir,
+		Resumed:      cs.TLS.DidResume,
+		Used0RTT:     cs.Used0RTT,
+		Rejected0RTT: rejected0RTT,
+	}
+	if t.resumptionObserver != nil {
+		t.resumptionObserver(evt)
+	}
+	t.emitterMx.Lock()
+	defer t.emitterMx.Unlock()
+	if t.resumptionEmitter != nil {
+		if err := t.resumptionEmitter.Emit(evt); err != nil {
+			log.Debugw("failed to emit resumption event", "err", err)
+		}
+	}
+}
+
+// complete0RTT waits for the handshake of an outbound connection that sent 0-RTT
+// data. The connection is closed if the peer rejected the 0-RTT data, since the
+// streams opened until then failed, or if the resumed session isn't the peer's.
+func (t *transport) complete0RTT(c *conn, qconn quic.EarlyConnection) {
+	select {
+	case <-qconn.HandshakeComplete():
+	case <-qconn.Context().Done():
+	}
+	if qconn.Context().Err() != nil {
+		return
+	}
+	cs := qconn.ConnectionState()
+	t.observeResumption(qconn, c.remotePeerID, network.DirOutbound, !cs.Used0RTT)
+	if !cs.Used0RTT {
+		log.Debugw("peer rejected 0-RTT data", "peer", c.remotePeerID)
+		c.closeWithError(quic.ApplicationErrorCode(network.ConnNoError), "0-RTT rejected")
+		return
+	}
+	if _, err := resumedPubKey(qconn, c.remotePeerID); err != nil {
+		log.Debugw("invalid resumed session", "peer", c.remotePeerID, "err", err)
+		c.closeWithError(quic.ApplicationErrorCode(network.ConnProtocolViolation), "")
+	}
+}
+
+func isHandshakeComplete(conn quic.EarlyConnection) bool {
+	select {
+	case <-conn.HandshakeComplete():
+		return true
+	default:
+		return false
+	}
+}
@@ -3,6 +3,8 @@ package libp2pquic
 import (
 	"errors"
 	"math"
+	"sync/atomic"
+	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 
@@ -15,9 +17,14 @@ const (
 
 type stream struct {
 	quic.Stream
+	conn *conn
+	done atomic.Bool
 }
 
-var _ network.MuxedStream = &stream{}
+var (
+	_ network.MuxedStream    = &stream{}
+	_ network.GracefulCloser = &stream{}
+)
 
 func parseStreamError(err error) error {
 	if err == nil {
@@ -48,6 +55,7 @@ func parseStreamError(err error) error {
 		err = &network.ConnError{
 			ErrorCode:      code,
 			Remote:         ae.Remote,
+			Reason:         ae.ErrorMessage,
 			TransportError: ae,
 		}
 	}
@@ -65,18 +73,21 @@ func (s *stream) Write(b []byte) (n int, err error) {
 }
 
 func (s *stream) Reset() error {
+	s.setDone()
 	s.Stream.CancelRead(reset)
 	s.Stream.CancelWrite(reset)
 	return nil
 }
 
 func (s *stream) ResetWithError(errCode network.StreamErrorCode) error {
+	s.setDone()
 	s.Stream.CancelRead(quic.StreamErrorCode(errCode))
 	s.Stream.CancelWrite(quic.StreamErrorCode(errCode))
 	return nil
 }
 
 func (s *stream) Close() error {
+	s.setDone()
 	s.Stream.CancelRead(reset)
 	return s.Stream.Close()
 }
@@ -89,3 +100,31 @@ func (s *stream) CloseRead() error {
 func (s *stream) CloseWrite() error {
 	return s.Stream.Close()
 }
+
+// CloseGracefully sends a FIN and reads until the peer's FIN. If that fails, only the
+// receive side is canceled: unlike Close, this doesn't discard data we sent and that
+// the peer didn't acknowledge yet.
+func (s *stream) CloseGracefully(deadline time.Time) error {
+	defer s.setDone()
+	if err := s.Stream.Close(); err != nil {
+		s.Stream.CancelRead(reset)
+		s.Stream.CancelWrite(reset)
+		return err
+	}
+	if err := s.Stream.SetReadDeadline(deadline); err != nil {
+		s.Stream.CancelRead(reset)
+		return err
+	}
+	if err := network.Drain(s, network.MaxGracefulCloseDrain); err != nil {
+		s.Stream.CancelRead(reset)
+		return err
+	}
+	return nil
+}
+
+// setDone removes the stream from the connection's stream count.
+func (s *stream) setDone() {
+	if !s.done.Swap(true) {
+		s.conn.numStreams.Add(-1)
+	}
+}
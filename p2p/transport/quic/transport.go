@@ -12,6 +12,7 @@ import (
 
 	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
 	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/event"
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	"github.com/TheNoobiCat/go-libp2p/core/peer"
 	"github.com/TheNoobiCat/go-libp2p/core/pnet"
@@ -55,8 +56,19 @@ type transport struct {
 	listenersMu sync.Mutex
 	// map of UDPAddr as string to a virtualListeners
 	listeners map[string][]*virtualListener
+
+	// session resumption, nil if disabled
+	sessionCache       tls.ClientSessionCache
+	sessionTicketKey   [32]byte
+	resumptionObserver func(event.EvtConnectionResumption)
+
+	emitterMx         sync.Mutex
+	resumptionEmitter event.Emitter
 }
 
+// Option is an option for the QUIC transport.
+type Option func(*transport) error
+
 var _ tpt.Transport = &transport{}
 
 type holePunchKey struct {
@@ -71,6 +83,12 @@ type activeHolePunch struct {
 
 // NewTransport creates a new QUIC transport
 func NewTransport(key ic.PrivKey, connManager *quicreuse.ConnManager, psk pnet.PSK, gater connmgr.ConnectionGater, rcmgr network.ResourceManager) (tpt.Transport, error) {
+	return NewTransportWithOptions(key, connManager, psk, gater, rcmgr)
+}
+
+// NewTransportWithOptions creates a new QUIC transport configured with the given
+// options. Use it instead of NewTransport when passing options to libp2p.Transport.
+func NewTransportWithOptions(key ic.PrivKey, connManager *quicreuse.ConnManager, psk pnet.PSK, gater connmgr.ConnectionGater, rcmgr network.ResourceManager, opts ...Option) (tpt.Transport, error) {
 	if len(psk) > 0 {
 		log.Error("QUIC doesn't support private networks yet.")
 		return nil, errors.New("QUIC doesn't support private networks yet")
@@ -88,7 +106,7 @@ func NewTransport(key ic.PrivKey, connManager *quicreuse.ConnManager, psk pnet.P
 		rcmgr = &network.NullResourceManager{}
 	}
 
-	return &transport{
+	t := &transport{
 		privKey:      key,
 		localPeer:    localPeer,
 		identity:     identity,
@@ -100,7 +118,13 @@ func NewTransport(key ic.PrivKey, connManager *quicreuse.ConnManager, psk pnet.P
 		rnd:          *rand.New(rand.NewSource(time.Now().UnixNano())),
 
 		listeners: make(map[string][]*virtualListener),
-	}, nil
+	}
+	for _, opt := range opts {
+		if err := opt(t); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
 }
 
 func (t *transport) ListenOrder() int {
@@ -134,22 +158,56 @@ func (t *transport) dialWithScope(ctx context.Context, raddr ma.Multiaddr, p pee
 	}
 
 	tlsConf, keyCh := t.identity.ConfigForPeer(p)
+	if t.sessionCache != nil {
+		tlsConf.SessionTicketsDisabled = false
+		tlsConf.ClientSessionCache = t.sessionCache
+	}
 	ctx = quicreuse.WithAssociation(ctx, t)
 	pconn, err := t.connManager.DialQUIC(ctx, raddr, tlsConf, t.allowWindowIncrease)
 	if err != nil {
 		return nil, err
 	}
 
-	// Should be ready by this point, don't block.
 	var remotePubKey ic.PubKey
-	select {
-	case remotePubKey = <-keyCh:
-	default:
+	earlyConn, early := pconn.(quic.EarlyConnection)
+	if early && !isHandshakeComplete(earlyConn) {
+		// The handshake of a resumed session is still running, 0-RTT data is sent. The
+		// certificate of the session is only known once the peer responds. Use the
+		// public key from the peer ID if possible, otherwise wait for the handshake.
+		remotePubKey, err = p.ExtractPublicKey()
+		if err != nil {
+			select {
+			case <-earlyConn.HandshakeComplete():
+			case <-ctx.Done():
+				pconn.CloseWithError(1, "")
+				return nil, ctx.Err()
+			}
+			if err := pconn.Context().Err(); err != nil {
+				return nil, context.Cause(pconn.Context())
+			}
+		}
+	}
+	early = remotePubKey != nil
+	if !early {
+		// Should be ready by this point, don't block.
+		select {
+		case remotePubKey = <-keyCh:
+		default:
+		}
+	}
+	if remotePubKey == nil && pconn.ConnectionState().TLS.DidResume {
+		// The certificate isn't verified again when resuming a session.
+		remotePubKey, err = resumedPubKey(pconn, p)
+		if err != nil {
+			pconn.CloseWithError(1, "")
+			return nil, err
+		}
 	}
 	if remotePubKey == nil {
 		pconn.CloseWithError(1, "")
 		return nil, errors.New("p2p/transport/quic BUG: expected remote pub key to be set")
 	}
+	t.connManager.SetConnPeer(pconn, p)
 
 	localMultiaddr, err := quicreuse.ToQuicMultiaddr(pconn.LocalAddr(), pconn.ConnectionState().Version)
 	if err != nil {
@@ -165,12 +223,18 @@ func (t *transport) dialWithScope(ctx context.Context, raddr ma.Multiaddr, p pee
 		remotePubKey:    remotePubKey,
 		remotePeerID:    p,
 		remoteMultiaddr: raddr,
+		rtt:             t.connManager.RTTStats(pconn),
 	}
 	if t.gater != nil && !t.gater.InterceptSecured(network.DirOutbound, p, c) {
 		pconn.CloseWithError(quic.ApplicationErrorCode(network.ConnGated), "connection gated")
 		return nil, fmt.Errorf("secured connection gated")
 	}
 	t.addConn(pconn, c)
+	if early {
+		go t.complete0RTT(c, earlyConn)
+	} else {
+		t.observeResumption(pconn, p, network.DirOutbound, false)
+	}
 	return c, nil
 }
 
@@ -290,6 +354,10 @@ func (t *transport) Listen(addr ma.Multiaddr) (tpt.Listener, error) {
 		// the peer ID calculated here, we don't actually receive the peer's public key
 		// from the key chan.
 		conf, _ := t.identity.ConfigForPeer("")
+		if t.sessionCache != nil {
+			conf.SessionTicketsDisabled = false
+			conf.SetSessionTicketKeys([][32]byte{t.sessionTicketKey})
+		}
 		return conf, nil
 	}
 	tlsConf.NextProtos = []string{"libp2p"}
@@ -373,7 +441,14 @@ func (t *transport) String() string {
 }
 
 func (t *transport) Close() error {
-	return nil
+	t.emitterMx.Lock()
+	defer t.emitterMx.Unlock()
+	if t.resumptionEmitter == nil {
+		return nil
+	}
+	err := t.resumptionEmitter.Close()
+	t.resumptionEmitter = nil
+	return err
 }
 
 func (t *transport) CloseVirtualListener(l *virtualListener) error {
@@ -13,6 +13,8 @@ import (
 	"net"
 	"sync"
 
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
 	"github.com/libp2p/go-netroute"
 	ma "github.com/multiformats/go-multiaddr"
 	manet "github.com/multiformats/go-multiaddr/net"
@@ -56,6 +58,8 @@ type ConnManager struct {
 
 	enableMetrics bool
 	registerer    prometheus.Registerer
+	rtts          *rttTracker
+	qlog          *qlogCapturer
 
 	serverConfig *quic.Config
 	clientConfig *quic.Config
@@ -68,6 +72,17 @@ type ConnManager struct {
 	connContext connContextFunc
 
 	verifySourceAddress func(addr net.Addr) bool
+
+	masqueProxy *MASQUEProxy
+	masque      *masqueClient
+
+	enable0RTT bool
+
+	// dedicatedListenAddrs are the UDP addresses pinned with DedicatedListenSocket.
+	dedicatedListenAddrs map[string]struct{}
+	// dedicatedTransports are the transports of the dedicated sockets. Guarded by
+	// quicListenersMu.
+	dedicatedTransports map[*refcountedTransport]struct{}
 }
 
 type quicListenerEntry struct {
@@ -99,6 +114,8 @@ func NewConnManager(statelessResetKey quic.StatelessResetKey, tokenKey quic.Toke
 		registerer:         prometheus.DefaultRegisterer,
 		listenUDP:          defaultListenUDP,
 		sourceIPSelectorFn: defaultSourceIPSelectorFn,
+		rtts:               newRTTTracker(),
+		qlog:               newQlogCapturer(),
 	}
 	for _, o := range opts {
 		if err := o(cm); err != nil {
@@ -106,9 +123,21 @@ func NewConnManager(statelessResetKey quic.StatelessResetKey, tokenKey quic.Toke
 		}
 	}
 
+	if cm.masqueProxy != nil {
+		masque, err := newMASQUEClient(*cm.masqueProxy, cm.listenUDP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid MASQUE proxy: %w", err)
+		}
+		cm.masque = masque
+	}
+	if cm.enableMetrics {
+		metricshelper.RegisterCollectors(cm.registerer, collectors...)
+	}
+
 	quicConf := quicConfig.Clone()
 	quicConf.Tracer = cm.getTracer()
 	serverConfig := quicConf.Clone()
+	serverConfig.Allow0RTT = cm.enable0RTT
 
 	cm.clientConfig = quicConf
 	cm.serverConfig = serverConfig
@@ -135,30 +164,46 @@ func NewConnManager(statelessResetKey quic.StatelessResetKey, tokenKey quic.Toke
 }
 
 func (c *ConnManager) getTracer() func(context.Context, quiclogging.Perspective, quic.ConnectionID) *quiclogging.ConnectionTracer {
-	return func(_ context.Context, p quiclogging.Perspective, ci quic.ConnectionID) *quiclogging.ConnectionTracer {
-		var promTracer *quiclogging.ConnectionTracer
-		if c.enableMetrics {
-			switch p {
-			case quiclogging.PerspectiveClient:
-				promTracer = quicmetrics.NewClientConnectionTracerWithRegisterer(c.registerer)
-			case quiclogging.PerspectiveServer:
-				promTracer = quicmetrics.NewServerConnectionTracerWithRegisterer(c.registerer)
-			default:
-				log.Error("invalid logging perspective: %s", p)
-			}
-		}
-		var tracer *quiclogging.ConnectionTracer
+	return func(ctx context.Context, p quiclogging.Perspective, ci quic.ConnectionID) *quiclogging.ConnectionTracer {
+		var tracers []*quiclogging.ConnectionTracer
 		if qlogTracerDir != "" {
-			tracer = qloggerForDir(qlogTracerDir, p, ci)
-			if promTracer != nil {
-				tracer = quiclogging.NewMultiplexedConnectionTracer(promTracer,
-					tracer)
+			// The prometheus tracer is only used together with the qlog tracer.
+			if c.enableMetrics {
+				switch p {
+				case quiclogging.PerspectiveClient:
+					tracers = append(tracers, quicmetrics.NewClientConnectionTracerWithRegisterer(c.registerer))
+				case quiclogging.PerspectiveServer:
+					tracers = append(tracers, quicmetrics.NewServerConnectionTracerWithRegisterer(c.registerer))
+				default:
+					log.Error("invalid logging perspective: %s", p)
+				}
 			}
+			tracers = append(tracers, qloggerForDir(qlogTracerDir, p, ci))
+		}
+		if t := c.qlog.tracer(ctx, p, ci); t != nil {
+			tracers = append(tracers, t)
+		}
+		if t := c.rtts.tracer(ctx); t != nil {
+			tracers = append(tracers, t)
+		}
+		switch len(tracers) {
+		case 0:
+			return nil
+		case 1:
+			return tracers[0]
+		default:
+			return quiclogging.NewMultiplexedConnectionTracer(tracers...)
 		}
-		return tracer
 	}
 }
 
+// RTTStats returns the RTT statistics of a connection established by the ConnManager,
+// or nil if they're unknown. It must be called while the connection is open, the
+// statistics remain readable after it's closed.
+func (c *ConnManager) RTTStats(conn quic.Connection) *RTTStats {
+	return c.rtts.get(conn)
+}
+
 func (c *ConnManager) getReuse(network string) (*reuse, error) {
 	switch network {
 	case "udp4":
@@ -234,7 +279,7 @@ func (c *ConnManager) ListenQUICAndAssociate(association any, addr ma.Multiaddr,
 		}
 		key = tr.LocalAddr().String()
 		entry = quicListenerEntry{ln: ln}
-	} else if c.enableReuseport && association != nil {
+	} else if c.enableReuseport && association != nil && !c.isDedicated(laddr) {
 		reuse, err := c.getReuse(netw)
 		if err != nil {
 			return nil, fmt.Errorf("reuse error: %w", err)
@@ -285,6 +330,7 @@ func (c *ConnManager) SharedNonQUICPacketConn(_ string, laddr *net.UDPAddr) (net
 	t := entry.ln.transport
 	if t, ok := t.(*refcountedTransport); ok {
 		t.IncreaseCount()
+		t.addNonQUICUser(1)
 		ctx, cancel := context.WithCancel(context.Background())
 		return &nonQUICPacketConn{
 			ctx:             ctx,
@@ -297,6 +343,30 @@ func (c *ConnManager) SharedNonQUICPacketConn(_ string, laddr *net.UDPAddr) (net
 }
 
 func (c *ConnManager) transportForListen(association any, network string, laddr *net.UDPAddr) (RefCountedQUICTransport, error) {
+	if c.isDedicated(laddr) {
+		conn, err := c.listenUDP(network, laddr)
+		if err != nil {
+			return nil, err
+		}
+		if !c.enableReuseport {
+			return c.newSingleOwnerTransport(conn), nil
+		}
+		// Use a refcounted transport, so that non QUIC transports can share the socket.
+		tr := &refcountedTransport{
+			QUICTransport: &wrappedQUICTransport{
+				Transport: newQUICTransport(conn, &c.tokenKey, &c.srk, c.connContext, c.verifySourceAddress),
+			},
+			packetConn: conn,
+			dedicated:  true,
+		}
+		tr.IncreaseCount()
+		tr.associate(association)
+		if c.dedicatedTransports == nil {
+			c.dedicatedTransports = make(map[*refcountedTransport]struct{})
+		}
+		c.dedicatedTransports[tr] = struct{}{}
+		return tr, nil
+	}
 	if c.enableReuseport {
 		reuse, err := c.getReuse(network)
 		if err != nil {
@@ -332,6 +402,8 @@ func WithAssociation(ctx context.Context, association any) context.Context {
 // - Any other listening transport
 // - Any transport previously used for dialing
 // If none of these are available, it'll create a new transport.
+// If 0-RTT is enabled and a session for the peer is cached in tlsConf, the handshake of
+// the returned quic.EarlyConnection may still be running, see Enable0RTT.
 func (c *ConnManager) DialQUIC(ctx context.Context, raddr ma.Multiaddr, tlsConf *tls.Config, allowWindowIncrease func(conn quic.Connection, delta uint64) bool) (quic.Connection, error) {
 	naddr, v, err := FromQuicMultiaddr(raddr)
 	if err != nil {
@@ -352,13 +424,20 @@ func (c *ConnManager) DialQUIC(ctx context.Context, raddr ma.Multiaddr, tlsConf
 		return nil, errors.New("unknown QUIC version")
 	}
 
+	if c.masque != nil {
+		conn, err := c.dialProxied(ctx, naddr, tlsConf, quicConf)
+		c.recordDial(dialRouteProxied, err)
+		return conn, err
+	}
+
 	var tr RefCountedQUICTransport
 	association := ctx.Value(associationKey{})
 	tr, err = c.TransportWithAssociationForDial(association, netw, naddr)
 	if err != nil {
 		return nil, err
 	}
-	conn, err := tr.Dial(ctx, naddr, tlsConf, quicConf)
+	conn, err := c.dial(ctx, tr, naddr, tlsConf, quicConf)
+	c.recordDial(dialRouteDirect, err)
 	if err != nil {
 		tr.DecreaseCount()
 		return nil, err
@@ -366,6 +445,54 @@ func (c *ConnManager) DialQUIC(ctx context.Context, raddr ma.Multiaddr, tlsConf
 	return conn, nil
 }
 
+// dial dials raddr on tr, with 0-RTT if it's enabled and supported by tr.
+func (c *ConnManager) dial(ctx context.Context, tr RefCountedQUICTransport, raddr net.Addr, tlsConf *tls.Config, quicConf *quic.Config) (quic.Connection, error) {
+	et, ok := tr.(earlyTransport)
+	if !c.enable0RTT || !ok {
+		return tr.Dial(ctx, raddr, tlsConf, quicConf)
+	}
+	conn, err := et.DialEarly(ctx, raddr, tlsConf, quicConf)
+	if errors.Is(err, errEarlyNotSupported) {
+		return tr.Dial(ctx, raddr, tlsConf, quicConf)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialProxied dials raddr through the MASQUE proxy, using a dedicated QUIC transport
+// on top of the CONNECT-UDP tunnel. The tunnel is closed with the connection.
+func (c *ConnManager) dialProxied(ctx context.Context, raddr *net.UDPAddr, tlsConf *tls.Config, quicConf *quic.Config) (quic.Connection, error) {
+	pconn, err := c.masque.dial(ctx, raddr)
+	if err != nil {
+		return nil, err
+	}
+	// The proxied packets are tunneled in the packets to the proxy, they can't use
+	// the full path MTU.
+	quicConf.InitialPacketSize = masqueProxiedPacketSize
+	quicConf.DisablePathMTUDiscovery = true
+	tr := &quic.Transport{Conn: pconn}
+	conn, err := tr.Dial(ctx, raddr, tlsConf, quicConf)
+	if err != nil {
+		pconn.Close()
+		tr.Close()
+		return nil, err
+	}
+	go func() {
+		<-conn.Context().Done()
+		pconn.Close()
+		tr.Close()
+	}()
+	return conn, nil
+}
+
+func (c *ConnManager) recordDial(route dialRoute, err error) {
+	if c.enableMetrics {
+		dialsTotal.WithLabelValues(route.String(), getOutcome(err)).Inc()
+	}
+}
+
 // TransportForDial returns a transport for dialing `raddr`.
 // If reuseport is enabled, it attempts to reuse the QUIC Transport used for
 // previous listens or dials.
@@ -419,6 +546,9 @@ func (c *ConnManager) Protocols() []int {
 }
 
 func (c *ConnManager) Close() error {
+	if c.masque != nil {
+		c.masque.Close()
+	}
 	if !c.enableReuseport {
 		return nil
 	}
@@ -443,6 +573,35 @@ func (t *wrappedQUICTransport) Listen(tlsConf *tls.Config, conf *quic.Config) (Q
 	return t.Transport.Listen(tlsConf, conf)
 }
 
+func (t *wrappedQUICTransport) ListenEarly(tlsConf *tls.Config, conf *quic.Config) (QUICListener, error) {
+	ln, err := t.Transport.ListenEarly(tlsConf, conf)
+	if err != nil {
+		return nil, err
+	}
+	return earlyListener{ln}, nil
+}
+
+// earlyTransport is implemented by the QUIC transports that support 0-RTT.
+type earlyTransport interface {
+	DialEarly(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *quic.Config) (quic.EarlyConnection, error)
+	ListenEarly(tlsConf *tls.Config, conf *quic.Config) (QUICListener, error)
+}
+
+var _ earlyTransport = (*wrappedQUICTransport)(nil)
+
+// earlyListener adapts a quic.EarlyListener to QUICListener.
+type earlyListener struct {
+	*quic.EarlyListener
+}
+
+func (l earlyListener) Accept(ctx context.Context) (quic.Connection, error) {
+	conn, err := l.EarlyListener.Accept(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
 func newQUICTransport(
 	conn net.PacketConn,
 	tokenGeneratorKey *quic.TokenGeneratorKey,
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"slices"
 	"strings"
 	"sync"
 
@@ -71,7 +72,7 @@ func newQuicListener(tr RefCountedQUICTransport, quicConfig *quic.Config) (*quic
 	}
 	quicConf := quicConfig.Clone()
 	quicConf.AllowConnectionWindowIncrease = cl.allowWindowIncrease
-	ln, err := tr.Listen(tlsConf, quicConf)
+	ln, err := listen(tr, tlsConf, quicConf)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +81,20 @@ func newQuicListener(tr RefCountedQUICTransport, quicConfig *quic.Config) (*quic
 	return cl, nil
 }
 
+// listen listens on tr, accepting 0-RTT connections if enabled in quicConf and
+// supported by tr.
+func listen(tr RefCountedQUICTransport, tlsConf *tls.Config, quicConf *quic.Config) (QUICListener, error) {
+	if et, ok := tr.(earlyTransport); ok && quicConf.Allow0RTT {
+		ln, err := et.ListenEarly(tlsConf, quicConf)
+		if !errors.Is(err, errEarlyNotSupported) {
+			return ln, err
+		}
+	}
+	// 0-RTT packets are dropped by the listener
+	quicConf.Allow0RTT = false
+	return tr.Listen(tlsConf, quicConf)
+}
+
 func (l *quicListener) allowWindowIncrease(conn quic.Connection, delta uint64) bool {
 	l.protocolsMu.Lock()
 	defer l.protocolsMu.Unlock()
@@ -123,6 +138,18 @@ func (l *quicListener) Add(tlsConf *tls.Config, allowWindowIncrease func(conn qu
 	return ln, nil
 }
 
+// alpns returns the ALPNs accepted by the listener, sorted.
+func (l *quicListener) alpns() []string {
+	l.protocolsMu.Lock()
+	defer l.protocolsMu.Unlock()
+	alpns := make([]string, 0, len(l.protocols))
+	for proto := range l.protocols {
+		alpns = append(alpns, proto)
+	}
+	slices.Sort(alpns)
+	return alpns
+}
+
 func (l *quicListener) Run() error {
 	defer close(l.running)
 	defer l.transport.DecreaseCount()
@@ -134,16 +161,52 @@ func (l *quicListener) Run() error {
 			}
 			return err
 		}
-		proto := conn.ConnectionState().TLS.NegotiatedProtocol
-
-		l.protocolsMu.Lock()
-		ln, ok := l.protocols[proto]
-		if !ok {
-			l.protocolsMu.Unlock()
-			return fmt.Errorf("negotiated unknown protocol: %s", proto)
+		if ec, ok := conn.(quic.EarlyConnection); ok && !isHandshakeComplete(ec) && !conn.ConnectionState().Used0RTT {
+			// The peer's certificate is only verified once the handshake completes.
+			go func() {
+				select {
+				case <-ec.HandshakeComplete():
+				case <-l.running:
+					conn.CloseWithError(1, "")
+					return
+				}
+				if ec.Context().Err() != nil {
+					// the handshake failed
+					return
+				}
+				if err := l.dispatch(conn); err != nil {
+					log.Debugw("failed to dispatch connection", "err", err)
+					conn.CloseWithError(1, "")
+				}
+			}()
+			continue
 		}
-		ln.ln.add(conn)
-		l.protocolsMu.Unlock()
+		if err := l.dispatch(conn); err != nil {
+			return err
+		}
+	}
+}
+
+// dispatch hands conn to the listener of its protocol.
+func (l *quicListener) dispatch(conn quic.Connection) error {
+	proto := conn.ConnectionState().TLS.NegotiatedProtocol
+
+	l.protocolsMu.Lock()
+	defer l.protocolsMu.Unlock()
+	ln, ok := l.protocols[proto]
+	if !ok {
+		return fmt.Errorf("negotiated unknown protocol: %s", proto)
+	}
+	ln.ln.add(conn)
+	return nil
+}
+
+func isHandshakeComplete(conn quic.EarlyConnection) bool {
+	select {
+	case <-conn.HandshakeComplete():
+		return true
+	default:
+		return false
 	}
 }
 
@@ -0,0 +1,49 @@
+package quicreuse
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricNamespace = "libp2p_quicreuse"
+
+var (
+	dialsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "dials_total",
+			Help:      "QUIC dials by route (direct or through a MASQUE proxy) and outcome",
+		},
+		[]string{"route", "outcome"},
+	)
+	collectors = []prometheus.Collector{
+		dialsTotal,
+	}
+)
+
+// dialRoute is the route of a QUIC dial.
+type dialRoute int
+
+const (
+	// dialRouteDirect is a dial from a local UDP socket.
+	dialRouteDirect dialRoute = iota
+	// dialRouteProxied is a dial through a MASQUE proxy, see DialViaMASQUEProxy.
+	dialRouteProxied
+)
+
+func (r dialRoute) String() string {
+	switch r {
+	case dialRouteDirect:
+		return "direct"
+	case dialRouteProxied:
+		return "proxied"
+	default:
+		return "unknown"
+	}
+}
+
+func getOutcome(err error) string {
+	if err != nil {
+		return "failed"
+	}
+	return "success"
+}
@@ -24,6 +24,9 @@ func (n *nonQUICPacketConn) Close() error {
 
 	// Don't actually close the underlying transport since someone else might be using it.
 	// reuse has it's own gc to close unused transports.
+	if t, ok := n.owningTransport.(*refcountedTransport); ok {
+		t.addNonQUICUser(-1)
+	}
 	n.owningTransport.DecreaseCount()
 	return nil
 }
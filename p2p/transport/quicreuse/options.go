@@ -3,8 +3,11 @@ package quicreuse
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net"
 
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/quic-go/quic-go"
 )
@@ -66,3 +69,62 @@ func EnableMetrics(reg prometheus.Registerer) Option {
 		return nil
 	}
 }
+
+// DedicatedListenSocket pins the QUIC listeners on the given addresses to dedicated UDP
+// sockets. A dedicated socket is shared by the QUIC based transports listening on the
+// same address, e.g. QUIC and WebTransport, and by non QUIC transports like WebRTC, but
+// it's never used for dialing, and a listener on another address never reuses it.
+//
+// Addresses are matched on their IP and UDP port, e.g. /ip4/0.0.0.0/udp/4001. A port of
+// 0 matches all listeners on a random port on that IP.
+func DedicatedListenSocket(addrs ...ma.Multiaddr) Option {
+	return func(m *ConnManager) error {
+		if m.dedicatedListenAddrs == nil {
+			m.dedicatedListenAddrs = make(map[string]struct{}, len(addrs))
+		}
+		for _, a := range addrs {
+			netw, host, err := manet.DialArgs(a)
+			if err != nil {
+				return fmt.Errorf("invalid dedicated listen address %s: %w", a, err)
+			}
+			laddr, err := net.ResolveUDPAddr(netw, host)
+			if err != nil {
+				return fmt.Errorf("invalid dedicated listen address %s: %w", a, err)
+			}
+			m.dedicatedListenAddrs[laddr.String()] = struct{}{}
+		}
+		return nil
+	}
+}
+
+// DialViaMASQUEProxy dials QUIC connections, and thereby the QUIC and WebTransport
+// connections using this ConnManager, through a MASQUE proxy. The QUIC packets are
+// tunneled in HTTP datagrams over a CONNECT-UDP request (RFC 9298) to the proxy. This
+// allows dialing from networks that block UDP egress, but allow HTTP/3 to a proxy.
+//
+// The proxy's datagrams carry the full QUIC packets of the proxied connections, so the
+// path to the proxy needs to support packets of at least 1350 bytes.
+// Listening isn't affected. Proxied connections don't reuse the listening sockets, so
+// they can't be used for hole punching.
+func DialViaMASQUEProxy(p MASQUEProxy) Option {
+	return func(m *ConnManager) error {
+		m.masqueProxy = &p
+		return nil
+	}
+}
+
+// Enable0RTT makes the ConnManager send and accept 0-RTT data on resumed sessions.
+// Sessions are only resumed by the transports that enable it, see
+// libp2pquic.EnableSessionResumption.
+//
+// Connections using 0-RTT are returned before the handshake completes, so that the
+// first streams are opened in the first flight. 0-RTT data can be replayed by an
+// attacker: only enable it if the protocols used on the first streams of a connection
+// are safe to replay. Inbound connections that don't use 0-RTT are still only
+// accepted once the handshake completes.
+func Enable0RTT() Option {
+	return func(m *ConnManager) error {
+		m.enable0RTT = true
+		return nil
+	}
+}
@@ -0,0 +1,370 @@
+package quicreuse
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
+)
+
+// MASQUEProxy configures a MASQUE proxy that QUIC connections are dialed through, see
+// DialViaMASQUEProxy.
+type MASQUEProxy struct {
+	// Addr is the UDP address of the proxy, as host:port.
+	Addr string
+	// Template is the URI template of the proxy, see RFC 9298, section 2. The
+	// {target_host} and {target_port} variables are expanded for every dial.
+	// Defaults to https://<Addr>/.well-known/masque/udp/{target_host}/{target_port}/.
+	Template string
+	// TLSConfig is used for the connection to the proxy. The ServerName defaults to
+	// the host of the template.
+	TLSConfig *tls.Config
+	// Header is sent with every CONNECT-UDP request. It can be used to authenticate
+	// to the proxy, e.g. with a Proxy-Authorization header.
+	Header http.Header
+}
+
+const (
+	// masqueInitialPacketSize is the packet size of the connection to the proxy. The
+	// packets need to fit the QUIC packets of the proxied connections, which are
+	// masqueProxiedPacketSize bytes, plus the framing.
+	masqueInitialPacketSize = 1350
+	masqueProxiedPacketSize = 1200
+
+	masqueConnectUDP = "connect-udp"
+)
+
+// masqueClient dials QUIC connections through a MASQUE proxy. All proxied
+// connections share a single HTTP/3 connection to the proxy.
+type masqueClient struct {
+	template  string
+	host      string
+	tlsConf   *tls.Config
+	header    http.Header
+	listenUDP listenUDP
+
+	mx     sync.Mutex
+	closed bool
+	conn   quic.Connection
+	cc     *http3.ClientConn
+	// dialing is closed when the ongoing dial to the proxy completes
+	dialing chan struct{}
+}
+
+func newMASQUEClient(p MASQUEProxy, listenUDP listenUDP) (*masqueClient, error) {
+	if p.Addr == "" {
+		return nil, errors.New("missing proxy address")
+	}
+	if _, _, err := net.SplitHostPort(p.Addr); err != nil {
+		return nil, fmt.Errorf("invalid proxy address %s: %w", p.Addr, err)
+	}
+	tmpl := p.Template
+	if tmpl == "" {
+		tmpl = "https://" + p.Addr + "/.well-known/masque/udp/{target_host}/{target_port}/"
+	}
+	if !strings.Contains(tmpl, "{target_host}") || !strings.Contains(tmpl, "{target_port}") {
+		return nil, fmt.Errorf("invalid proxy template %s: missing {target_host} or {target_port}", tmpl)
+	}
+	u, err := url.Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy template %s: %w", tmpl, err)
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid proxy template %s: scheme must be https", tmpl)
+	}
+
+	var tlsConf *tls.Config
+	if p.TLSConfig != nil {
+		tlsConf = p.TLSConfig.Clone()
+	} else {
+		tlsConf = &tls.Config{}
+	}
+	tlsConf.NextProtos = []string{http3.NextProtoH3}
+	if tlsConf.ServerName == "" {
+		tlsConf.ServerName = u.Hostname()
+	}
+	return &masqueClient{
+		template:  tmpl,
+		host:      u.Host,
+		tlsConf:   tlsConf,
+		header:    p.Header,
+		listenUDP: listenUDP,
+	}, nil
+}
+
+// proxyURL expands the template for the target address.
+func (m *masqueClient) proxyURL(raddr *net.UDPAddr) (*url.URL, error) {
+	// IPv6 addresses are percent-encoded, see RFC 9298, section 2.
+	host := strings.ReplaceAll(raddr.IP.String(), ":", "%3A")
+	s := strings.ReplaceAll(m.template, "{target_host}", host)
+	s = strings.ReplaceAll(s, "{target_port}", strconv.Itoa(raddr.Port))
+	return url.Parse(s)
+}
+
+// clientConn returns the HTTP/3 connection to the proxy, establishing a new one if
+// there's none yet or if it was closed. The lock isn't held while dialing, concurrent
+// callers wait for the ongoing dial.
+func (m *masqueClient) clientConn(ctx context.Context) (*http3.ClientConn, quic.Connection, error) {
+	for {
+		m.mx.Lock()
+		if m.closed {
+			m.mx.Unlock()
+			return nil, nil, net.ErrClosed
+		}
+		if m.conn != nil && m.conn.Context().Err() == nil {
+			cc, conn := m.cc, m.conn
+			m.mx.Unlock()
+			return cc, conn, nil
+		}
+		if dialing := m.dialing; dialing != nil {
+			m.mx.Unlock()
+			select {
+			case <-dialing:
+				continue
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+		dialing := make(chan struct{})
+		m.dialing = dialing
+		m.mx.Unlock()
+
+		cc, conn, err := m.dialProxy(ctx)
+
+		m.mx.Lock()
+		m.dialing = nil
+		close(dialing)
+		if err == nil && m.closed {
+			conn.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "")
+			err = net.ErrClosed
+		}
+		if err != nil {
+			m.mx.Unlock()
+			return nil, nil, err
+		}
+		m.conn, m.cc = conn, cc
+		m.mx.Unlock()
+		return cc, conn, nil
+	}
+}
+
+// dialProxy establishes a new HTTP/3 connection to the proxy.
+func (m *masqueClient) dialProxy(ctx context.Context) (*http3.ClientConn, quic.Connection, error) {
+	raddr, err := net.ResolveUDPAddr("udp", m.host)
+	if err != nil {
+		return nil, nil, err
+	}
+	netw := "udp4"
+	laddr := &net.UDPAddr{IP: net.IPv4zero}
+	if raddr.IP.To4() == nil {
+		netw = "udp6"
+		laddr = &net.UDPAddr{IP: net.IPv6zero}
+	}
+	pconn, err := m.listenUDP(netw, laddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	tr := &quic.Transport{Conn: pconn}
+	conn, err := tr.Dial(ctx, raddr, m.tlsConf, &quic.Config{
+		EnableDatagrams:    true,
+		InitialPacketSize:  masqueInitialPacketSize,
+		MaxIncomingStreams: -1,
+		KeepAlivePeriod:    15 * time.Second,
+	})
+	if err != nil {
+		tr.Close()
+		pconn.Close()
+		return nil, nil, fmt.Errorf("failed to dial proxy: %w", err)
+	}
+	cc := (&http3.Transport{EnableDatagrams: true}).NewClientConn(conn)
+	select {
+	case <-cc.ReceivedSettings():
+	case <-ctx.Done():
+		conn.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "")
+		tr.Close()
+		pconn.Close()
+		return nil, nil, ctx.Err()
+	}
+	if s := cc.Settings(); !s.EnableDatagrams || !s.EnableExtendedConnect {
+		conn.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "")
+		tr.Close()
+		pconn.Close()
+		return nil, nil, errors.New("proxy doesn't support HTTP datagrams and extended CONNECT")
+	}
+	go func() {
+		<-conn.Context().Done()
+		tr.Close()
+		pconn.Close()
+	}()
+	return cc, conn, nil
+}
+
+// dial opens a CONNECT-UDP tunnel to raddr, and returns a PacketConn sending and
+// receiving the UDP payloads through the tunnel.
+func (m *masqueClient) dial(ctx context.Context, raddr *net.UDPAddr) (*proxiedPacketConn, error) {
+	u, err := m.proxyURL(raddr)
+	if err != nil {
+		return nil, err
+	}
+	cc, conn, err := m.clientConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	str, err := cc.OpenRequestStream(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := m.header.Clone()
+	if header == nil {
+		header = make(http.Header, 1)
+	}
+	header.Set("Capsule-Protocol", "?1")
+	req := &http.Request{
+		Method: http.MethodConnect,
+		Proto:  masqueConnectUDP,
+		Host:   u.Host,
+		URL:    u,
+		Header: header,
+	}
+	if err := str.SendRequestHeader(req.WithContext(ctx)); err != nil {
+		str.CancelRead(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		str.CancelWrite(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		return nil, err
+	}
+	rsp, err := str.ReadResponse()
+	if err != nil {
+		str.CancelRead(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		str.CancelWrite(quic.StreamErrorCode(http3.ErrCodeRequestCanceled))
+		return nil, err
+	}
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		str.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+		str.Close()
+		return nil, fmt.Errorf("proxy refused CONNECT-UDP to %s: %s", raddr, rsp.Status)
+	}
+	return newProxiedPacketConn(str, conn.LocalAddr(), raddr), nil
+}
+
+func (m *masqueClient) Close() error {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.closed = true
+	if m.conn != nil {
+		return m.conn.CloseWithError(quic.ApplicationErrorCode(http3.ErrCodeNoError), "")
+	}
+	return nil
+}
+
+// proxiedPacketConn is a PacketConn tunneling UDP payloads to a single target through
+// a CONNECT-UDP request stream, as HTTP datagrams with context ID 0.
+type proxiedPacketConn struct {
+	str    http3.RequestStream
+	laddr  net.Addr
+	raddr  *net.UDPAddr
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mx           sync.Mutex
+	readDeadline time.Time
+	// cancelRead interrupts the ongoing read, to apply a new read deadline.
+	cancelRead context.CancelFunc
+}
+
+var _ net.PacketConn = &proxiedPacketConn{}
+
+func newProxiedPacketConn(str http3.RequestStream, laddr net.Addr, raddr *net.UDPAddr) *proxiedPacketConn {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &proxiedPacketConn{str: str, laddr: laddr, raddr: raddr, ctx: ctx, cancel: cancel}
+}
+
+func (c *proxiedPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	for {
+		c.mx.Lock()
+		var ctx context.Context
+		var cancel context.CancelFunc
+		if c.readDeadline.IsZero() {
+			ctx, cancel = context.WithCancel(c.ctx)
+		} else {
+			ctx, cancel = context.WithDeadline(c.ctx, c.readDeadline)
+		}
+		c.cancelRead = cancel
+		c.mx.Unlock()
+
+		data, err := c.str.ReceiveDatagram(ctx)
+		// check why the read was interrupted before cancelling the context
+		ctxErr := ctx.Err()
+		cancel()
+		if err != nil {
+			switch {
+			case c.ctx.Err() != nil:
+				return 0, nil, net.ErrClosed
+			case errors.Is(ctxErr, context.DeadlineExceeded):
+				return 0, nil, os.ErrDeadlineExceeded
+			case errors.Is(ctxErr, context.Canceled):
+				// the read deadline was changed
+				continue
+			default:
+				return 0, nil, err
+			}
+		}
+		contextID, n, err := quicvarint.Parse(data)
+		if err != nil || contextID != 0 {
+			// drop datagrams that don't carry UDP payloads
+			continue
+		}
+		return copy(b, data[n:]), c.raddr, nil
+	}
+}
+
+func (c *proxiedPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	if c.ctx.Err() != nil {
+		return 0, net.ErrClosed
+	}
+	if ua, ok := addr.(*net.UDPAddr); !ok || !ua.IP.Equal(c.raddr.IP) || ua.Port != c.raddr.Port {
+		return 0, fmt.Errorf("proxied conn can only send to %s", c.raddr)
+	}
+	data := make([]byte, 0, 1+len(b))
+	data = quicvarint.Append(data, 0) // context ID
+	data = append(data, b...)
+	if err := c.str.SendDatagram(data); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *proxiedPacketConn) Close() error {
+	c.cancel()
+	c.str.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+	return c.str.Close()
+}
+
+func (c *proxiedPacketConn) LocalAddr() net.Addr { return c.laddr }
+
+func (c *proxiedPacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *proxiedPacketConn) SetReadDeadline(t time.Time) error {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.readDeadline = t
+	if c.cancelRead != nil {
+		c.cancelRead()
+	}
+	return nil
+}
+
+// SetWriteDeadline is a no-op, sending datagrams doesn't block.
+func (c *proxiedPacketConn) SetWriteDeadline(time.Time) error { return nil }
@@ -0,0 +1,251 @@
+package quicreuse
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	libp2ptls "github.com/TheNoobiCat/go-libp2p/p2p/security/tls"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+	"github.com/quic-go/quic-go/quicvarint"
+	"github.com/stretchr/testify/require"
+)
+
+// masqueProxy is a minimal CONNECT-UDP proxy.
+type masqueProxy struct {
+	auth    string
+	tunnels atomic.Int32
+	// closeTunnels makes the proxy close the tunnels right after accepting them
+	closeTunnels bool
+}
+
+func (p *masqueProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodConnect || r.Proto != masqueConnectUDP {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if p.auth != "" && r.Header.Get("Proxy-Authorization") != p.auth {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+		return
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) != 5 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	host := strings.ReplaceAll(parts[3], "%3A", ":")
+	port, err := strconv.Atoi(parts[4])
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	conn, err := net.DialUDP("udp", nil, &net.UDPAddr{IP: net.ParseIP(host), Port: port})
+	if err != nil {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+	defer conn.Close()
+	p.tunnels.Add(1)
+
+	w.Header().Set("Capsule-Protocol", "?1")
+	w.WriteHeader(http.StatusOK)
+	w.(http.Flusher).Flush()
+	str := w.(http3.HTTPStreamer).HTTPStream()
+	if p.closeTunnels {
+		str.CancelRead(quic.StreamErrorCode(http3.ErrCodeNoError))
+		str.Close()
+		return
+	}
+	go func() {
+		b := make([]byte, 1500)
+		for {
+			n, err := conn.Read(b)
+			if err != nil {
+				return
+			}
+			if err := str.SendDatagram(append([]byte{0}, b[:n]...)); err != nil {
+				return
+			}
+		}
+	}()
+	go func() {
+		// the client closes the stream when closing the tunnel
+		io.Copy(io.Discard, str)
+		conn.Close()
+	}()
+	for {
+		data, err := str.ReceiveDatagram(r.Context())
+		if err != nil {
+			return
+		}
+		contextID, n, err := quicvarint.Parse(data)
+		if err != nil || contextID != 0 {
+			continue
+		}
+		if _, err := conn.Write(data[n:]); err != nil {
+			return
+		}
+	}
+}
+
+// startMASQUEProxy starts the proxy, and returns a function returning the client config.
+func startMASQUEProxy(t *testing.T, p *masqueProxy) func() MASQUEProxy {
+	t.Helper()
+	_, tlsConf := getTLSConfForProto(t, http3.NextProtoH3)
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	s := &http3.Server{
+		Handler:         p,
+		EnableDatagrams: true,
+		TLSConfig:       tlsConf,
+		// the datagrams need to fit the QUIC packets of the proxied connections
+		QUICConfig: &quic.Config{EnableDatagrams: true, InitialPacketSize: masqueInitialPacketSize},
+	}
+	go s.Serve(conn)
+	t.Cleanup(func() {
+		s.Close()
+		conn.Close()
+	})
+
+	addr := conn.LocalAddr().String()
+	return func() MASQUEProxy {
+		key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		require.NoError(t, err)
+		identity, err := libp2ptls.NewIdentity(key)
+		require.NoError(t, err)
+		tlsConf, _ := identity.ConfigForPeer("")
+		return MASQUEProxy{Addr: addr, TLSConfig: tlsConf}
+	}
+}
+
+func TestDialViaMASQUEProxy(t *testing.T) {
+	proxy := &masqueProxy{auth: "Bearer secret"}
+	proxyConf := startMASQUEProxy(t, proxy)
+
+	server, err := NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{})
+	require.NoError(t, err)
+	defer server.Close()
+	_, serverTLSConf := getTLSConfForProto(t, "proto")
+	ln, err := server.ListenQUIC(ma.StringCast("/ip4/127.0.0.1/udp/0/quic-v1"), serverTLSConf, nil)
+	require.NoError(t, err)
+	defer ln.Close()
+	raddr, err := ToQuicMultiaddr(ln.Addr(), quic.Version1)
+	require.NoError(t, err)
+
+	newClient := func(auth string) *ConnManager {
+		p := proxyConf()
+		p.Header = http.Header{"Proxy-Authorization": []string{auth}}
+		cm, err := NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{}, DialViaMASQUEProxy(p))
+		require.NoError(t, err)
+		t.Cleanup(func() { cm.Close() })
+		return cm
+	}
+	clientTLSConf := func() *tls.Config {
+		key, _, err := crypto.GenerateEd25519Key(rand.Reader)
+		require.NoError(t, err)
+		identity, err := libp2ptls.NewIdentity(key)
+		require.NoError(t, err)
+		tlsConf, _ := identity.ConfigForPeer("")
+		tlsConf.NextProtos = []string{"proto"}
+		return tlsConf
+	}
+
+	t.Run("unauthorized", func(t *testing.T) {
+		cm := newClient("Bearer wrong")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err := cm.DialQUIC(ctx, raddr, clientTLSConf(), nil)
+		require.ErrorContains(t, err, "407")
+		require.Zero(t, proxy.tunnels.Load())
+	})
+
+	t.Run("authorized", func(t *testing.T) {
+		cm := newClient("Bearer secret")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		conn, err := cm.DialQUIC(ctx, raddr, clientTLSConf(), nil)
+		require.NoError(t, err)
+		defer conn.CloseWithError(0, "")
+		require.Equal(t, int32(1), proxy.tunnels.Load())
+
+		sconn, err := ln.Accept(ctx)
+		require.NoError(t, err)
+		// the server sees the proxy's address
+		require.NotEqual(t, conn.LocalAddr().String(), sconn.RemoteAddr().String())
+
+		str, err := conn.OpenStream()
+		require.NoError(t, err)
+		_, err = str.Write([]byte("foobar"))
+		require.NoError(t, err)
+		require.NoError(t, str.Close())
+		sstr, err := sconn.AcceptStream(ctx)
+		require.NoError(t, err)
+		b, err := io.ReadAll(sstr)
+		require.NoError(t, err)
+		require.Equal(t, "foobar", string(b))
+
+		// the connection to the proxy is reused
+		conn2, err := cm.DialQUIC(ctx, raddr, clientTLSConf(), nil)
+		require.NoError(t, err)
+		defer conn2.CloseWithError(0, "")
+		require.Equal(t, int32(2), proxy.tunnels.Load())
+		require.Equal(t, conn.LocalAddr(), conn2.LocalAddr())
+	})
+}
+
+func TestMASQUEProxyURL(t *testing.T) {
+	m, err := newMASQUEClient(MASQUEProxy{Addr: "proxy.example:443"}, nil)
+	require.NoError(t, err)
+	u, err := m.proxyURL(&net.UDPAddr{IP: net.ParseIP("2001:db8::1"), Port: 4001})
+	require.NoError(t, err)
+	require.Equal(t, "https://proxy.example:443/.well-known/masque/udp/2001%3Adb8%3A%3A1/4001/", u.String())
+	require.Equal(t, "proxy.example", m.tlsConf.ServerName)
+
+	_, err = newMASQUEClient(MASQUEProxy{Addr: "proxy.example:443", Template: "https://proxy.example/udp"}, nil)
+	require.Error(t, err)
+	_, err = newMASQUEClient(MASQUEProxy{}, nil)
+	require.Error(t, err)
+}
+
+func TestMASQUEProxyClosesTunnel(t *testing.T) {
+	proxyConf := startMASQUEProxy(t, &masqueProxy{closeTunnels: true})
+	m, err := newMASQUEClient(proxyConf(), func(network string, laddr *net.UDPAddr) (net.PacketConn, error) {
+		return net.ListenUDP(network, laddr)
+	})
+	require.NoError(t, err)
+	defer m.Close()
+
+	target, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer target.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := m.dial(ctx, target.LocalAddr().(*net.UDPAddr))
+	require.NoError(t, err)
+	defer conn.Close()
+
+	errC := make(chan error, 1)
+	go func() {
+		_, _, err := conn.ReadFrom(make([]byte, 1500))
+		errC <- err
+	}()
+	select {
+	case err := <-errC:
+		require.Error(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadFrom didn't return after the proxy closed the tunnel")
+	}
+}
@@ -0,0 +1,575 @@
+package quicreuse
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	"github.com/quic-go/quic-go"
+	quiclogging "github.com/quic-go/quic-go/logging"
+	"github.com/quic-go/quic-go/qlog"
+)
+
+// maxPendingQlogSize is the maximum size of the qlog buffered for a connection until
+// its peer is known. The capture of connections exceeding it is abandoned.
+const maxPendingQlogSize = 1 << 20
+
+// QlogConnInfo describes a captured connection.
+type QlogConnInfo struct {
+	Perspective quiclogging.Perspective
+	// ConnectionID is the original destination connection ID of the connection.
+	ConnectionID quic.ConnectionID
+	// Peer is the remote peer. It is empty for connections captured by sampling, as
+	// their capture starts before the peer is known.
+	Peer peer.ID
+}
+
+// QlogSink creates the outputs of qlog captures.
+type QlogSink interface {
+	// NewWriter returns the writer the qlog of the connection is written to. It is
+	// closed when the connection is closed or the capture is stopped.
+	NewWriter(info QlogConnInfo) (io.WriteCloser, error)
+}
+
+type qlogDirSink string
+
+// QlogDirSink returns a QlogSink writing zstd compressed qlog files to dir, like the
+// QLOGDIR environment variable.
+func QlogDirSink(dir string) QlogSink {
+	return qlogDirSink(dir)
+}
+
+func (s qlogDirSink) NewWriter(info QlogConnInfo) (io.WriteCloser, error) {
+	if err := os.MkdirAll(string(s), 0777); err != nil {
+		return nil, err
+	}
+	w := newQlogger(string(s), info.Perspective, info.ConnectionID)
+	if w == nil {
+		return nil, errors.New("failed to create qlog file")
+	}
+	return w, nil
+}
+
+// QlogCaptureConfig selects the connections captured by a qlog capture.
+type QlogCaptureConfig struct {
+	// Sink creates the outputs of the capture.
+	Sink QlogSink
+	// Peers are the peers whose connections are captured. The peer of a connection
+	// is only known once its handshake completed, the qlog is buffered in memory
+	// until then.
+	Peers []peer.ID
+	// SampleRate is the fraction of the other connections captured, between 0 and 1.
+	SampleRate float64
+}
+
+// EnableQlogCapture starts capturing the qlog of the connections selected by cfg.
+// The capture can be replaced and stopped at runtime with StartQlogCapture and
+// StopQlogCapture.
+func EnableQlogCapture(cfg QlogCaptureConfig) Option {
+	return func(m *ConnManager) error {
+		return m.qlog.start(cfg)
+	}
+}
+
+// StartQlogCapture starts capturing the qlog of the connections selected by cfg,
+// replacing the running capture if any. Only connections established after the call
+// are captured.
+func (c *ConnManager) StartQlogCapture(cfg QlogCaptureConfig) error {
+	return c.qlog.start(cfg)
+}
+
+// StopQlogCapture stops the running qlog capture, closing the outputs of the
+// connections it captured.
+func (c *ConnManager) StopQlogCapture() {
+	c.qlog.stop()
+}
+
+// SetConnPeer records the remote peer of conn once its handshake completed, to
+// select the connection for a qlog capture by peer. Transports using the ConnManager
+// must call it for every connection.
+func (c *ConnManager) SetConnPeer(conn quic.Connection, p peer.ID) {
+	c.qlog.setPeer(conn, p)
+}
+
+// qlogCapturer captures the qlog of QUIC connections. Like the RTT, connections are
+// matched with their tracer by their tracing ID.
+type qlogCapturer struct {
+	mx      sync.Mutex
+	capture *qlogCapture
+	// pending are the writers of the connections waiting for their peer.
+	pending map[quic.ConnectionTracingID]*qlogWriter
+}
+
+type qlogCapture struct {
+	cfg   QlogCaptureConfig
+	peers map[peer.ID]struct{}
+
+	mx      sync.Mutex
+	stopped bool
+	writers map[*qlogWriter]struct{}
+}
+
+func newQlogCapturer() *qlogCapturer {
+	return &qlogCapturer{pending: make(map[quic.ConnectionTracingID]*qlogWriter)}
+}
+
+func (q *qlogCapturer) start(cfg QlogCaptureConfig) error {
+	if cfg.Sink == nil {
+		return errors.New("qlog capture requires a sink")
+	}
+	if cfg.SampleRate < 0 || cfg.SampleRate > 1 {
+		return errors.New("qlog capture sample rate must be between 0 and 1")
+	}
+	c := &qlogCapture{
+		cfg:     cfg,
+		peers:   make(map[peer.ID]struct{}, len(cfg.Peers)),
+		writers: make(map[*qlogWriter]struct{}),
+	}
+	for _, p := range cfg.Peers {
+		c.peers[p] = struct{}{}
+	}
+	q.mx.Lock()
+	old := q.capture
+	q.capture = c
+	q.mx.Unlock()
+	if old != nil {
+		old.stop()
+	}
+	return nil
+}
+
+func (q *qlogCapturer) stop() {
+	q.mx.Lock()
+	c := q.capture
+	q.capture = nil
+	q.mx.Unlock()
+	if c != nil {
+		c.stop()
+	}
+}
+
+// tracer returns a tracer capturing the qlog of the connection, or nil if the
+// connection isn't captured.
+func (q *qlogCapturer) tracer(ctx context.Context, p quiclogging.Perspective, ci quic.ConnectionID) *quiclogging.ConnectionTracer {
+	q.mx.Lock()
+	c := q.capture
+	q.mx.Unlock()
+	if c == nil {
+		return nil
+	}
+	info := QlogConnInfo{Perspective: p, ConnectionID: ci}
+
+	if c.cfg.SampleRate > 0 && rand.Float64() < c.cfg.SampleRate {
+		// the sink may be slow, don't block the other connections
+		w, err := c.cfg.Sink.NewWriter(info)
+		if err != nil {
+			log.Errorf("creating the qlog output failed: %s", err)
+			return nil
+		}
+		qw := &qlogWriter{capture: c, info: info, w: w, state: qlogWriterActive}
+		if !c.add(qw) {
+			w.Close()
+			return nil
+		}
+		return qw.tracer(qlog.NewConnectionTracer(qw, p, ci))
+	}
+
+	if len(c.peers) == 0 {
+		return nil
+	}
+	id, ok := ctx.Value(quic.ConnectionTracingKey).(quic.ConnectionTracingID)
+	if !ok {
+		return nil
+	}
+	qw := &qlogWriter{capture: c, info: info}
+	if !c.add(qw) {
+		return nil
+	}
+	qw.onClose = func() {
+		q.mx.Lock()
+		if q.pending[id] == qw {
+			delete(q.pending, id)
+		}
+		q.mx.Unlock()
+	}
+	q.mx.Lock()
+	q.pending[id] = qw
+	q.mx.Unlock()
+	return qw.tracer(qlog.NewConnectionTracer(qw, p, ci))
+}
+
+func (q *qlogCapturer) setPeer(conn quic.Connection, p peer.ID) {
+	id, ok := conn.Context().Value(quic.ConnectionTracingKey).(quic.ConnectionTracingID)
+	if !ok {
+		return
+	}
+	q.mx.Lock()
+	w, ok := q.pending[id]
+	delete(q.pending, id)
+	q.mx.Unlock()
+	if ok {
+		w.resolve(p)
+	}
+}
+
+// add registers w with the capture. It returns false if the capture was stopped.
+func (c *qlogCapture) add(w *qlogWriter) bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if c.stopped {
+		return false
+	}
+	c.writers[w] = struct{}{}
+	return true
+}
+
+func (c *qlogCapture) remove(w *qlogWriter) {
+	c.mx.Lock()
+	delete(c.writers, w)
+	c.mx.Unlock()
+}
+
+func (c *qlogCapture) stop() {
+	c.mx.Lock()
+	c.stopped = true
+	writers := c.writers
+	c.writers = nil
+	c.mx.Unlock()
+	for w := range writers {
+		w.discard()
+	}
+}
+
+type qlogWriterState uint8
+
+const (
+	// qlogWriterPending buffers the qlog until the peer of the connection is known.
+	qlogWriterPending qlogWriterState = iota
+	qlogWriterActive
+	// qlogWriterDiscarded drops the qlog.
+	qlogWriterDiscarded
+)
+
+// qlogWriter is the writer of the qlog of a captured connection.
+type qlogWriter struct {
+	capture *qlogCapture
+	info    QlogConnInfo
+	onClose func()
+
+	// discarded is set once the qlog is dropped, to stop encoding the events.
+	discarded atomic.Bool
+
+	mx    sync.Mutex
+	state qlogWriterState
+	buf   []byte
+	w     io.WriteCloser
+}
+
+// setDiscarded drops the qlog. w.mx must be held.
+func (w *qlogWriter) setDiscarded() {
+	w.state = qlogWriterDiscarded
+	w.buf = nil
+	w.discarded.Store(true)
+}
+
+func (w *qlogWriter) Write(b []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	switch w.state {
+	case qlogWriterPending:
+		if len(w.buf)+len(b) > maxPendingQlogSize {
+			log.Debugf("abandoning qlog capture of connection %s: peer unknown after %d bytes", w.info.ConnectionID, len(w.buf))
+			w.setDiscarded()
+			return len(b), nil
+		}
+		w.buf = append(w.buf, b...)
+	case qlogWriterActive:
+		return w.w.Write(b)
+	}
+	return len(b), nil
+}
+
+func (w *qlogWriter) Close() error {
+	if w.onClose != nil {
+		w.onClose()
+	}
+	w.capture.remove(w)
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	active := w.state == qlogWriterActive
+	w.setDiscarded()
+	if active {
+		return w.w.Close()
+	}
+	return nil
+}
+
+// resolve starts writing the buffered qlog to the sink if p is one of the captured
+// peers, and discards it otherwise.
+func (w *qlogWriter) resolve(p peer.ID) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if w.state != qlogWriterPending {
+		return
+	}
+	buf := w.buf
+	if _, ok := w.capture.peers[p]; !ok {
+		w.setDiscarded()
+		return
+	}
+	w.info.Peer = p
+	out, err := w.capture.cfg.Sink.NewWriter(w.info)
+	if err != nil {
+		log.Errorf("creating the qlog output failed: %s", err)
+		w.setDiscarded()
+		return
+	}
+	if _, err := out.Write(buf); err != nil {
+		log.Errorf("writing the qlog failed: %s", err)
+		out.Close()
+		w.setDiscarded()
+		return
+	}
+	w.w = out
+	w.buf = nil
+	w.state = qlogWriterActive
+}
+
+// discard stops the capture of the connection, closing its output.
+func (w *qlogWriter) discard() {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	if w.state == qlogWriterActive {
+		if err := w.w.Close(); err != nil {
+			log.Errorf("closing the qlog output failed: %s", err)
+		}
+	}
+	w.setDiscarded()
+}
+
+// tracer wraps t, the qlog tracer writing to w, so that the events are no longer
+// encoded once the qlog is dropped, e.g. when the peer of the connection isn't
+// captured. Close is always called, to close w.
+func (w *qlogWriter) tracer(t *quiclogging.ConnectionTracer) *quiclogging.ConnectionTracer {
+	on := func() bool { return !w.discarded.Load() }
+	g := &quiclogging.ConnectionTracer{Close: t.Close}
+	if f := t.StartedConnection; f != nil {
+		g.StartedConnection = func(local, remote net.Addr, srcConnID, destConnID quiclogging.ConnectionID) {
+			if on() {
+				f(local, remote, srcConnID, destConnID)
+			}
+		}
+	}
+	if f := t.NegotiatedVersion; f != nil {
+		g.NegotiatedVersion = func(chosen quiclogging.Version, clientVersions, serverVersions []quiclogging.Version) {
+			if on() {
+				f(chosen, clientVersions, serverVersions)
+			}
+		}
+	}
+	if f := t.ClosedConnection; f != nil {
+		g.ClosedConnection = func(err error) {
+			if on() {
+				f(err)
+			}
+		}
+	}
+	if f := t.SentTransportParameters; f != nil {
+		g.SentTransportParameters = func(parameters *quiclogging.TransportParameters) {
+			if on() {
+				f(parameters)
+			}
+		}
+	}
+	if f := t.ReceivedTransportParameters; f != nil {
+		g.ReceivedTransportParameters = func(parameters *quiclogging.TransportParameters) {
+			if on() {
+				f(parameters)
+			}
+		}
+	}
+	if f := t.RestoredTransportParameters; f != nil {
+		g.RestoredTransportParameters = func(parameters *quiclogging.TransportParameters) {
+			if on() {
+				f(parameters)
+			}
+		}
+	}
+	if f := t.SentLongHeaderPacket; f != nil {
+		g.SentLongHeaderPacket = func(hdr *quiclogging.ExtendedHeader, size quiclogging.ByteCount, ecn quiclogging.ECN, ack *quiclogging.AckFrame, frames []quiclogging.Frame) {
+			if on() {
+				f(hdr, size, ecn, ack, frames)
+			}
+		}
+	}
+	if f := t.SentShortHeaderPacket; f != nil {
+		g.SentShortHeaderPacket = func(hdr *quiclogging.ShortHeader, size quiclogging.ByteCount, ecn quiclogging.ECN, ack *quiclogging.AckFrame, frames []quiclogging.Frame) {
+			if on() {
+				f(hdr, size, ecn, ack, frames)
+			}
+		}
+	}
+	if f := t.ReceivedVersionNegotiationPacket; f != nil {
+		g.ReceivedVersionNegotiationPacket = func(dest, src quiclogging.ArbitraryLenConnectionID, versions []quiclogging.Version) {
+			if on() {
+				f(dest, src, versions)
+			}
+		}
+	}
+	if f := t.ReceivedRetry; f != nil {
+		g.ReceivedRetry = func(hdr *quiclogging.Header) {
+			if on() {
+				f(hdr)
+			}
+		}
+	}
+	if f := t.ReceivedLongHeaderPacket; f != nil {
+		g.ReceivedLongHeaderPacket = func(hdr *quiclogging.ExtendedHeader, size quiclogging.ByteCount, ecn quiclogging.ECN, frames []quiclogging.Frame) {
+			if on() {
+				f(hdr, size, ecn, frames)
+			}
+		}
+	}
+	if f := t.ReceivedShortHeaderPacket; f != nil {
+		g.ReceivedShortHeaderPacket = func(hdr *quiclogging.ShortHeader, size quiclogging.ByteCount, ecn quiclogging.ECN, frames []quiclogging.Frame) {
+			if on() {
+				f(hdr, size, ecn, frames)
+			}
+		}
+	}
+	if f := t.BufferedPacket; f != nil {
+		g.BufferedPacket = func(packetType quiclogging.PacketType, size quiclogging.ByteCount) {
+			if on() {
+				f(packetType, size)
+			}
+		}
+	}
+	if f := t.DroppedPacket; f != nil {
+		g.DroppedPacket = func(packetType quiclogging.PacketType, pn quiclogging.PacketNumber, size quiclogging.ByteCount, reason quiclogging.PacketDropReason) {
+			if on() {
+				f(packetType, pn, size, reason)
+			}
+		}
+	}
+	if f := t.UpdatedMetrics; f != nil {
+		g.UpdatedMetrics = func(rttStats *quiclogging.RTTStats, cwnd, bytesInFlight quiclogging.ByteCount, packetsInFlight int) {
+			if on() {
+				f(rttStats, cwnd, bytesInFlight, packetsInFlight)
+			}
+		}
+	}
+	if f := t.AcknowledgedPacket; f != nil {
+		g.AcknowledgedPacket = func(encLevel quiclogging.EncryptionLevel, pn quiclogging.PacketNumber) {
+			if on() {
+				f(encLevel, pn)
+			}
+		}
+	}
+	if f := t.LostPacket; f != nil {
+		g.LostPacket = func(encLevel quiclogging.EncryptionLevel, pn quiclogging.PacketNumber, reason quiclogging.PacketLossReason) {
+			if on() {
+				f(encLevel, pn, reason)
+			}
+		}
+	}
+	if f := t.UpdatedMTU; f != nil {
+		g.UpdatedMTU = func(mtu quiclogging.ByteCount, done bool) {
+			if on() {
+				f(mtu, done)
+			}
+		}
+	}
+	if f := t.UpdatedCongestionState; f != nil {
+		g.UpdatedCongestionState = func(state quiclogging.CongestionState) {
+			if on() {
+				f(state)
+			}
+		}
+	}
+	if f := t.UpdatedPTOCount; f != nil {
+		g.UpdatedPTOCount = func(value uint32) {
+			if on() {
+				f(value)
+			}
+		}
+	}
+	if f := t.UpdatedKeyFromTLS; f != nil {
+		g.UpdatedKeyFromTLS = func(encLevel quiclogging.EncryptionLevel, p quiclogging.Perspective) {
+			if on() {
+				f(encLevel, p)
+			}
+		}
+	}
+	if f := t.UpdatedKey; f != nil {
+		g.UpdatedKey = func(keyPhase quiclogging.KeyPhase, remote bool) {
+			if on() {
+				f(keyPhase, remote)
+			}
+		}
+	}
+	if f := t.DroppedEncryptionLevel; f != nil {
+		g.DroppedEncryptionLevel = func(encLevel quiclogging.EncryptionLevel) {
+			if on() {
+				f(encLevel)
+			}
+		}
+	}
+	if f := t.DroppedKey; f != nil {
+		g.DroppedKey = func(keyPhase quiclogging.KeyPhase) {
+			if on() {
+				f(keyPhase)
+			}
+		}
+	}
+	if f := t.SetLossTimer; f != nil {
+		g.SetLossTimer = func(timerType quiclogging.TimerType, encLevel quiclogging.EncryptionLevel, time time.Time) {
+			if on() {
+				f(timerType, encLevel, time)
+			}
+		}
+	}
+	if f := t.LossTimerExpired; f != nil {
+		g.LossTimerExpired = func(timerType quiclogging.TimerType, encLevel quiclogging.EncryptionLevel) {
+			if on() {
+				f(timerType, encLevel)
+			}
+		}
+	}
+	if f := t.LossTimerCanceled; f != nil {
+		g.LossTimerCanceled = func() {
+			if on() {
+				f()
+			}
+		}
+	}
+	if f := t.ECNStateUpdated; f != nil {
+		g.ECNStateUpdated = func(state quiclogging.ECNState, trigger quiclogging.ECNStateTrigger) {
+			if on() {
+				f(state, trigger)
+			}
+		}
+	}
+	if f := t.ChoseALPN; f != nil {
+		g.ChoseALPN = func(protocol string) {
+			if on() {
+				f(protocol)
+			}
+		}
+	}
+	if f := t.Debug; f != nil {
+		g.Debug = func(name, msg string) {
+			if on() {
+				f(name, msg)
+			}
+		}
+	}
+	return g
+}
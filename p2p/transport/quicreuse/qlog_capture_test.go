@@ -0,0 +1,188 @@
+package quicreuse
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"github.com/TheNoobiCat/go-libp2p/core/test"
+	libp2ptls "github.com/TheNoobiCat/go-libp2p/p2p/security/tls"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/quic-go/quic-go"
+	quiclogging "github.com/quic-go/quic-go/logging"
+	"github.com/stretchr/testify/require"
+)
+
+type memQlogWriter struct {
+	info QlogConnInfo
+
+	mx     sync.Mutex
+	buf    bytes.Buffer
+	closed bool
+}
+
+func (w *memQlogWriter) Write(b []byte) (int, error) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.buf.Write(b)
+}
+
+func (w *memQlogWriter) Close() error {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	w.closed = true
+	return nil
+}
+
+func (w *memQlogWriter) result() (int, bool) {
+	w.mx.Lock()
+	defer w.mx.Unlock()
+	return w.buf.Len(), w.closed
+}
+
+type memQlogSink struct {
+	mx      sync.Mutex
+	writers []*memQlogWriter
+}
+
+func (s *memQlogSink) NewWriter(info QlogConnInfo) (io.WriteCloser, error) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	w := &memQlogWriter{info: info}
+	s.writers = append(s.writers, w)
+	return w, nil
+}
+
+func (s *memQlogSink) get() []*memQlogWriter {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	return append([]*memQlogWriter(nil), s.writers...)
+}
+
+// acceptConn dials the listener ln and returns the accepted connection.
+func acceptConn(t *testing.T, ln QUICListener, alpn string) quic.Connection {
+	t.Helper()
+	clientKey, _, err := crypto.GenerateEd25519Key(rand.Reader)
+	require.NoError(t, err)
+	clientIdentity, err := libp2ptls.NewIdentity(clientKey)
+	require.NoError(t, err)
+	clientTLSConf, _ := clientIdentity.ConfigForPeer("")
+	clientTLSConf.NextProtos = []string{alpn}
+	cconn, err := net.ListenUDP("udp4", nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { cconn.Close() })
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		c, err := quic.Dial(ctx, cconn, ln.Addr(), clientTLSConf, nil)
+		if err == nil {
+			t.Cleanup(func() { c.CloseWithError(0, "") })
+		}
+		done <- err
+	}()
+	conn, err := ln.Accept(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-done)
+	return conn
+}
+
+func TestQlogCaptureConfig(t *testing.T) {
+	cm, err := NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{})
+	require.NoError(t, err)
+	defer cm.Close()
+
+	require.Error(t, cm.StartQlogCapture(QlogCaptureConfig{SampleRate: 1}))
+	require.Error(t, cm.StartQlogCapture(QlogCaptureConfig{Sink: &memQlogSink{}, SampleRate: 1.5}))
+	_, err = NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{}, EnableQlogCapture(QlogCaptureConfig{}))
+	require.Error(t, err)
+}
+
+func TestQlogCaptureSampled(t *testing.T) {
+	sink := &memQlogSink{}
+	cm, err := NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{},
+		EnableQlogCapture(QlogCaptureConfig{Sink: sink, SampleRate: 1}))
+	require.NoError(t, err)
+	defer cm.Close()
+
+	_, tlsConf := getTLSConfForProto(t, "proto")
+	ln, err := cm.ListenQUIC(ma.StringCast("/ip4/127.0.0.1/udp/0/quic-v1"), tlsConf, nil)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	conn := acceptConn(t, ln, "proto")
+	writers := sink.get()
+	require.Len(t, writers, 1)
+	require.Empty(t, writers[0].info.Peer)
+
+	// Stopping the capture closes the outputs.
+	cm.StopQlogCapture()
+	_, closed := writers[0].result()
+	require.True(t, closed)
+	conn.CloseWithError(0, "")
+
+	acceptConn(t, ln, "proto")
+	require.Len(t, sink.get(), 1)
+}
+
+func TestQlogCaptureByPeer(t *testing.T) {
+	sink := &memQlogSink{}
+	p := test.RandPeerIDFatal(t)
+	cm, err := NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{},
+		EnableQlogCapture(QlogCaptureConfig{Sink: sink, Peers: []peer.ID{p}}))
+	require.NoError(t, err)
+	defer cm.Close()
+
+	_, tlsConf := getTLSConfForProto(t, "proto")
+	ln, err := cm.ListenQUIC(ma.StringCast("/ip4/127.0.0.1/udp/0/quic-v1"), tlsConf, nil)
+	require.NoError(t, err)
+	defer ln.Close()
+
+	other := acceptConn(t, ln, "proto")
+	cm.SetConnPeer(other, test.RandPeerIDFatal(t))
+	require.Empty(t, sink.get())
+
+	conn := acceptConn(t, ln, "proto")
+	cm.SetConnPeer(conn, p)
+	writers := sink.get()
+	require.Len(t, writers, 1)
+	require.Equal(t, p, writers[0].info.Peer)
+
+	conn.CloseWithError(0, "")
+	require.Eventually(t, func() bool {
+		n, closed := writers[0].result()
+		// the handshake was buffered until the peer was known
+		return n > 0 && closed
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Len(t, sink.get(), 1)
+}
+
+func TestQlogWriterTracerDetached(t *testing.T) {
+	c := &qlogCapture{peers: map[peer.ID]struct{}{}, writers: map[*qlogWriter]struct{}{}}
+	w := &qlogWriter{capture: c}
+	require.True(t, c.add(w))
+	var events, closed int
+	tr := w.tracer(&quiclogging.ConnectionTracer{
+		UpdatedMTU: func(quiclogging.ByteCount, bool) { events++ },
+		Close:      func() { closed++ },
+	})
+	require.Nil(t, tr.UpdatedMetrics)
+
+	tr.UpdatedMTU(1200, false)
+	require.Equal(t, 1, events)
+
+	// the events of connections to other peers are no longer encoded
+	w.resolve(test.RandPeerIDFatal(t))
+	tr.UpdatedMTU(1400, true)
+	require.Equal(t, 1, events)
+	tr.Close()
+	require.Equal(t, 1, closed)
+}
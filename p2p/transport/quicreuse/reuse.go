@@ -49,6 +49,14 @@ func (c *singleOwnerTransport) Dial(ctx context.Context, addr net.Addr, tlsConf
 	return c.Transport.Dial(ctx, addr, tlsConf, conf)
 }
 
+func (c *singleOwnerTransport) DialEarly(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *quic.Config) (quic.EarlyConnection, error) {
+	et, ok := c.Transport.(earlyTransport)
+	if !ok {
+		return nil, errEarlyNotSupported
+	}
+	return et.DialEarly(ctx, addr, tlsConf, conf)
+}
+
 func (c *singleOwnerTransport) ReadNonQUICPacket(ctx context.Context, b []byte) (int, net.Addr, error) {
 	return c.Transport.ReadNonQUICPacket(ctx, b)
 }
@@ -67,6 +75,16 @@ func (c *singleOwnerTransport) Listen(tlsConf *tls.Config, conf *quic.Config) (Q
 	return c.Transport.Listen(tlsConf, conf)
 }
 
+func (c *singleOwnerTransport) ListenEarly(tlsConf *tls.Config, conf *quic.Config) (QUICListener, error) {
+	et, ok := c.Transport.(earlyTransport)
+	if !ok {
+		return nil, errEarlyNotSupported
+	}
+	return et.ListenEarly(tlsConf, conf)
+}
+
+var errEarlyNotSupported = errors.New("transport doesn't support 0-RTT")
+
 // Constant. Defined as variables to simplify testing.
 var (
 	garbageCollectInterval = 30 * time.Second
@@ -89,6 +107,13 @@ type refcountedTransport struct {
 	borrowDoneSignal chan struct{}
 
 	assocations map[any]struct{}
+
+	// nonQUICUsers is the number of non QUIC users of the transport, see
+	// ConnManager.SharedNonQUICPacketConn.
+	nonQUICUsers int
+	// dedicated transports are pinned to a listener with DedicatedListenSocket. They
+	// aren't used for dialing, and are closed once they're unused.
+	dedicated bool
 }
 
 type connContextFunc = func(context.Context, *quic.ClientInfo) (context.Context, error)
@@ -149,13 +174,47 @@ func (c *refcountedTransport) Listen(tlsConf *tls.Config, conf *quic.Config) (QU
 	return c.QUICTransport.Listen(tlsConf, conf)
 }
 
+func (c *refcountedTransport) DialEarly(ctx context.Context, addr net.Addr, tlsConf *tls.Config, conf *quic.Config) (quic.EarlyConnection, error) {
+	et, ok := c.QUICTransport.(earlyTransport)
+	if !ok {
+		return nil, errEarlyNotSupported
+	}
+	return et.DialEarly(ctx, addr, tlsConf, conf)
+}
+
+func (c *refcountedTransport) ListenEarly(tlsConf *tls.Config, conf *quic.Config) (QUICListener, error) {
+	et, ok := c.QUICTransport.(earlyTransport)
+	if !ok {
+		return nil, errEarlyNotSupported
+	}
+	return et.ListenEarly(tlsConf, conf)
+}
+
 func (c *refcountedTransport) DecreaseCount() {
 	c.mutex.Lock()
 	c.refCount--
 	if c.refCount == 0 {
 		c.unusedSince = time.Now()
 	}
+	closeUnused := c.dedicated && c.refCount == 0
 	c.mutex.Unlock()
+	if closeUnused {
+		c.Close()
+	}
+}
+
+// addNonQUICUser records that a non QUIC user started (delta 1) or stopped (delta -1)
+// using the transport.
+func (c *refcountedTransport) addNonQUICUser(delta int) {
+	c.mutex.Lock()
+	c.nonQUICUsers += delta
+	c.mutex.Unlock()
+}
+
+func (c *refcountedTransport) usage() (refCount, nonQUICUsers int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.refCount, c.nonQUICUsers
 }
 
 func (c *refcountedTransport) ShouldGarbageCollect(now time.Time) bool {
@@ -458,6 +517,25 @@ func (r *reuse) TransportForListen(network string, laddr *net.UDPAddr) (*refcoun
 	return tr, nil
 }
 
+// transports returns all transports, which can all be used for dialing.
+func (r *reuse) transports() []*refcountedTransport {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	trs := make([]*refcountedTransport, 0, len(r.globalListeners)+len(r.globalDialers))
+	for _, tr := range r.globalListeners {
+		trs = append(trs, tr)
+	}
+	for _, tr := range r.globalDialers {
+		trs = append(trs, tr)
+	}
+	for _, m := range r.unicast {
+		for _, tr := range m {
+			trs = append(trs, tr)
+		}
+	}
+	return trs
+}
+
 func (r *reuse) newTransport(conn net.PacketConn) *refcountedTransport {
 	return &refcountedTransport{
 		QUICTransport: &wrappedQUICTransport{
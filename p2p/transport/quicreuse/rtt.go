@@ -0,0 +1,71 @@
+package quicreuse
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	quiclogging "github.com/quic-go/quic-go/logging"
+)
+
+// rttTracker tracks the RTT of the QUIC connections. quic-go only exposes it to
+// connection tracers, the connections are matched with their tracer by their tracing
+// ID.
+type rttTracker struct {
+	mx   sync.Mutex
+	rtts map[quic.ConnectionTracingID]*RTTStats
+}
+
+// RTTStats are the RTT statistics of a QUIC connection. They remain readable after the
+// connection is closed. The methods of a nil *RTTStats return 0.
+type RTTStats struct {
+	smoothed atomic.Int64
+}
+
+// Smoothed returns the smoothed RTT, or 0 if it's unknown.
+func (s *RTTStats) Smoothed() time.Duration {
+	if s == nil {
+		return 0
+	}
+	return time.Duration(s.smoothed.Load())
+}
+
+func newRTTTracker() *rttTracker {
+	return &rttTracker{rtts: make(map[quic.ConnectionTracingID]*RTTStats)}
+}
+
+// tracer returns a tracer recording the RTT of the connection with the tracing ID in
+// ctx. It returns nil if ctx has no tracing ID.
+func (t *rttTracker) tracer(ctx context.Context) *quiclogging.ConnectionTracer {
+	id, ok := ctx.Value(quic.ConnectionTracingKey).(quic.ConnectionTracingID)
+	if !ok {
+		return nil
+	}
+	rtt := new(RTTStats)
+	t.mx.Lock()
+	t.rtts[id] = rtt
+	t.mx.Unlock()
+	return &quiclogging.ConnectionTracer{
+		UpdatedMetrics: func(rttStats *quiclogging.RTTStats, _, _ quiclogging.ByteCount, _ int) {
+			rtt.smoothed.Store(int64(rttStats.SmoothedRTT()))
+		},
+		Close: func() {
+			// The connections hold on to their RTTStats, see ConnManager.RTTStats.
+			t.mx.Lock()
+			delete(t.rtts, id)
+			t.mx.Unlock()
+		},
+	}
+}
+
+func (t *rttTracker) get(conn quic.Connection) *RTTStats {
+	id, ok := conn.Context().Value(quic.ConnectionTracingKey).(quic.ConnectionTracingID)
+	if !ok {
+		return nil
+	}
+	t.mx.Lock()
+	defer t.mx.Unlock()
+	return t.rtts[id]
+}
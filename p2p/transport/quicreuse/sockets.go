@@ -0,0 +1,108 @@
+package quicreuse
+
+import (
+	"net"
+	"slices"
+	"strings"
+)
+
+// alpnTransports maps the ALPNs of the QUIC based transports to the transport names.
+var alpnTransports = map[string]string{
+	"libp2p": "quic-v1",
+	"h3":     "webtransport",
+}
+
+// SocketInfo describes a UDP socket held by the ConnManager.
+type SocketInfo struct {
+	LocalAddr net.Addr
+	// ALPNs are the ALPNs accepted by the QUIC listener on the socket, sorted. Empty if
+	// no QUIC listener uses the socket.
+	ALPNs []string
+	// Transports are the QUIC based transports listening on the socket, derived from
+	// the ALPNs: "libp2p" is reported as "quic-v1" and "h3" as "webtransport". Unknown
+	// ALPNs are reported as is.
+	Transports []string
+	// NonQUICUsers is the number of non QUIC users sharing the socket, e.g. the WebRTC
+	// transport.
+	NonQUICUsers int
+	// RefCount is the number of users of the socket: listeners, dialed connections and
+	// non QUIC users.
+	RefCount int
+	// Dialing is true if the socket is used for dialing.
+	Dialing bool
+	// Dedicated is true if the socket was pinned to its listener with
+	// DedicatedListenSocket, or reuseport is disabled.
+	Dedicated bool
+}
+
+// Sockets returns the UDP sockets the ConnManager holds, sorted by local address, to
+// help debugging port sharing. Sockets used by a single dial when reuseport is disabled
+// are not reported.
+func (c *ConnManager) Sockets() []SocketInfo {
+	c.quicListenersMu.Lock()
+	listeners := make(map[RefCountedQUICTransport]*quicListener, len(c.quicListeners))
+	for _, e := range c.quicListeners {
+		listeners[e.ln.transport] = e.ln
+	}
+	dedicated := make([]*refcountedTransport, 0, len(c.dedicatedTransports))
+	for tr := range c.dedicatedTransports {
+		if refCount, _ := tr.usage(); refCount <= 0 {
+			// closed
+			delete(c.dedicatedTransports, tr)
+			continue
+		}
+		dedicated = append(dedicated, tr)
+	}
+	c.quicListenersMu.Unlock()
+
+	var infos []SocketInfo
+	add := func(tr RefCountedQUICTransport) {
+		info := SocketInfo{LocalAddr: tr.LocalAddr()}
+		switch t := tr.(type) {
+		case *refcountedTransport:
+			info.RefCount, info.NonQUICUsers = t.usage()
+			info.Dedicated = t.dedicated
+			info.Dialing = !t.dedicated
+		case *singleOwnerTransport:
+			info.RefCount = 1
+			info.Dedicated = true
+		}
+		if ln, ok := listeners[tr]; ok {
+			info.ALPNs = ln.alpns()
+			for _, alpn := range info.ALPNs {
+				t, ok := alpnTransports[alpn]
+				if !ok {
+					t = alpn
+				}
+				if !slices.Contains(info.Transports, t) {
+					info.Transports = append(info.Transports, t)
+				}
+			}
+			delete(listeners, tr)
+		}
+		infos = append(infos, info)
+	}
+	if c.enableReuseport {
+		for _, r := range []*reuse{c.reuseUDP4, c.reuseUDP6} {
+			for _, tr := range r.transports() {
+				add(tr)
+			}
+		}
+	}
+	for _, tr := range dedicated {
+		add(tr)
+	}
+	// Listeners that don't use a reuse socket.
+	for tr := range listeners {
+		add(tr)
+	}
+	slices.SortFunc(infos, func(a, b SocketInfo) int {
+		return strings.Compare(a.LocalAddr.String(), b.LocalAddr.String())
+	})
+	return infos
+}
+
+func (c *ConnManager) isDedicated(laddr *net.UDPAddr) bool {
+	_, ok := c.dedicatedListenAddrs[laddr.String()]
+	return ok
+}
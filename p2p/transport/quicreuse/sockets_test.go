@@ -0,0 +1,72 @@
+package quicreuse
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"testing"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/quic-go/quic-go"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSockets(t *testing.T) {
+	udpConn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	port := udpConn.LocalAddr().(*net.UDPAddr).Port
+	udpConn.Close()
+	dedicated := ma.StringCast(fmt.Sprintf("/ip4/127.0.0.1/udp/%d/quic-v1", port))
+
+	cm, err := NewConnManager(quic.StatelessResetKey{}, quic.TokenGeneratorKey{}, DedicatedListenSocket(dedicated))
+	require.NoError(t, err)
+	defer cm.Close()
+
+	shared, err := cm.ListenQUIC(ma.StringCast("/ip4/127.0.0.1/udp/0/quic-v1"), &tls.Config{NextProtos: []string{"libp2p"}}, nil)
+	require.NoError(t, err)
+	defer shared.Close()
+	quicLn, err := cm.ListenQUICAndAssociate("quic", dedicated, &tls.Config{NextProtos: []string{"libp2p"}}, nil)
+	require.NoError(t, err)
+	wtLn, err := cm.ListenQUICAndAssociate("webtransport", dedicated, &tls.Config{NextProtos: []string{"h3"}}, nil)
+	require.NoError(t, err)
+	pc, err := cm.SharedNonQUICPacketConn("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	require.NoError(t, err)
+
+	sockets := cm.Sockets()
+	require.Len(t, sockets, 2)
+	var ded, sh SocketInfo
+	for _, s := range sockets {
+		if s.LocalAddr.(*net.UDPAddr).Port == port {
+			ded = s
+		} else {
+			sh = s
+		}
+	}
+	require.Equal(t, []string{"h3", "libp2p"}, ded.ALPNs)
+	require.ElementsMatch(t, []string{"quic-v1", "webtransport"}, ded.Transports)
+	require.Equal(t, 1, ded.NonQUICUsers)
+	require.True(t, ded.Dedicated)
+	require.False(t, ded.Dialing)
+
+	require.Equal(t, []string{"libp2p"}, sh.ALPNs)
+	require.Equal(t, []string{"quic-v1"}, sh.Transports)
+	require.Zero(t, sh.NonQUICUsers)
+	require.False(t, sh.Dedicated)
+	require.True(t, sh.Dialing)
+
+	// the dedicated socket isn't used for dialing, even with its association
+	tr, err := cm.TransportWithAssociationForDial("quic", "udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234})
+	require.NoError(t, err)
+	require.NotEqual(t, port, tr.LocalAddr().(*net.UDPAddr).Port)
+	tr.DecreaseCount()
+
+	// the dedicated socket is closed once it's unused
+	require.NoError(t, quicLn.Close())
+	require.NoError(t, wtLn.Close())
+	require.Len(t, cm.Sockets(), 2)
+	require.NoError(t, pc.Close())
+	require.Len(t, cm.Sockets(), 1)
+	udpConn, err = net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: port})
+	require.NoError(t, err)
+	udpConn.Close()
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"os"
 	"runtime"
 	"syscall"
@@ -127,6 +128,20 @@ func WithDialerForAddr(d DialerForAddr) Option {
 	}
 }
 
+// WithEgressPolicy sets the policy used to select the network interface to dial from
+// on multi-homed hosts. Dials to destinations matching the policy reuse the port of
+// a listener bound to the selected interface, if there is one.
+//
+// Dials matching the policy don't use the TCP listener shared with other transports,
+// see libp2p.ShareTCPListener. The policy is ignored if a custom dialer is set with
+// WithDialerForAddr.
+func WithEgressPolicy(p *reuseport.EgressPolicy) Option {
+	return func(tr *TcpTransport) error {
+		tr.egress = p
+		return nil
+	}
+}
+
 type ContextDialer interface {
 	DialContext(ctx context.Context, network, address string) (net.Conn, error)
 }
@@ -150,6 +165,9 @@ type TcpTransport struct {
 	disableReuseport bool // Explicitly disable reuseport.
 	enableMetrics    bool
 
+	// optional policy selecting the interface to dial from
+	egress *reuseport.EgressPolicy
+
 	// share and demultiplex TCP listeners across multiple transports
 	sharedTcp *tcpreuse.ConnMgr
 
@@ -183,6 +201,7 @@ func NewTCPTransport(upgrader transport.Upgrader, rcmgr network.ResourceManager,
 			return nil, err
 		}
 	}
+	tr.reuse.SetEgressPolicy(tr.egress)
 	return tr, nil
 }
 
@@ -235,7 +254,23 @@ func (t *TcpTransport) maDial(ctx context.Context, raddr ma.Multiaddr) (manet.Co
 		return t.customDial(ctx, raddr)
 	}
 
-	if t.sharedTcp != nil {
+	var egressSrcs []netip.Addr
+	if t.egress != nil {
+		ip, err := manet.ToIP(raddr)
+		if err != nil {
+			return nil, err
+		}
+		dst, _ := netip.AddrFromSlice(ip)
+		srcs, ok, err := t.egress.SourceAddrs(dst)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			egressSrcs = srcs
+		}
+	}
+
+	if t.sharedTcp != nil && egressSrcs == nil {
 		return t.sharedTcp.DialContext(ctx, raddr)
 	}
 
@@ -243,6 +278,9 @@ func (t *TcpTransport) maDial(ctx context.Context, raddr ma.Multiaddr) (manet.Co
 		return t.reuse.DialContext(ctx, raddr)
 	}
 	var d manet.Dialer
+	if egressSrcs != nil {
+		d.Dialer.LocalAddr = &net.TCPAddr{IP: egressSrcs[0].AsSlice()}
+	}
 	return d.DialContext(ctx, raddr)
 }
 
@@ -1,8 +1,12 @@
 package tcpreuse
 
 import (
+	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/p2p/transport/tcpreuse/internal/sampledconn"
@@ -15,6 +19,10 @@ import (
 // A var so we can change it in tests.
 var identifyConnTimeout = 5 * time.Second
 
+// maxHTTPHeaderPeek is the maximum size of the HTTP request header read to tell
+// WebSocket upgrade requests from other HTTP requests.
+const maxHTTPHeaderPeek = 8 << 10
+
 type DemultiplexedConnType int
 
 const (
@@ -22,6 +30,10 @@ const (
 	DemultiplexedConnType_MultistreamSelect
 	DemultiplexedConnType_HTTP
 	DemultiplexedConnType_TLS
+	// DemultiplexedConnType_WebSocket is a plain HTTP WebSocket upgrade request.
+	// These connections are routed to the DemultiplexedConnType_HTTP listener if
+	// there's no DemultiplexedConnType_WebSocket listener.
+	DemultiplexedConnType_WebSocket
 )
 
 func (t DemultiplexedConnType) String() string {
@@ -32,13 +44,24 @@ func (t DemultiplexedConnType) String() string {
 		return "HTTP"
 	case DemultiplexedConnType_TLS:
 		return "TLS"
+	case DemultiplexedConnType_WebSocket:
+		return "WebSocket"
 	default:
 		return fmt.Sprintf("Unknown(%d)", int(t))
 	}
 }
 
 func (t DemultiplexedConnType) IsKnown() bool {
-	return t >= 1 || t <= 3
+	return t >= DemultiplexedConnType_MultistreamSelect && t <= DemultiplexedConnType_WebSocket
+}
+
+// fallback returns the type of the listener accepting connections of type t if
+// there's no listener for t.
+func (t DemultiplexedConnType) fallback() (DemultiplexedConnType, bool) {
+	if t == DemultiplexedConnType_WebSocket {
+		return DemultiplexedConnType_HTTP, true
+	}
+	return 0, false
 }
 
 // identifyConnType attempts to identify the connection type by peeking at the
@@ -57,21 +80,31 @@ func identifyConnType(c manet.Conn) (DemultiplexedConnType, manet.Conn, error) {
 		return 0, nil, errors.Join(err, closeErr)
 	}
 
+	t := DemultiplexedConnType_Unknown
+	switch {
+	case IsMultistreamSelect(s):
+		t = DemultiplexedConnType_MultistreamSelect
+	case IsTLS(s):
+		t = DemultiplexedConnType_TLS
+	case IsHTTP(s):
+		t = DemultiplexedConnType_HTTP
+		// The client sends the whole request header before waiting for the response,
+		// so we can read it while the deadline is set.
+		header, err := peekedConn.(sampledconn.Peeker).PeekUntil([]byte("\r\n\r\n"), maxHTTPHeaderPeek)
+		if err != nil && !errors.Is(err, sampledconn.ErrPeekLimit) {
+			closeErr := peekedConn.Close()
+			return 0, nil, errors.Join(err, closeErr)
+		}
+		if err == nil && IsWebSocketUpgrade(header) {
+			t = DemultiplexedConnType_WebSocket
+		}
+	}
+
 	if err := peekedConn.SetReadDeadline(time.Time{}); err != nil {
 		closeErr := peekedConn.Close()
 		return 0, nil, errors.Join(err, closeErr)
 	}
-
-	if IsMultistreamSelect(s) {
-		return DemultiplexedConnType_MultistreamSelect, peekedConn, nil
-	}
-	if IsTLS(s) {
-		return DemultiplexedConnType_TLS, peekedConn, nil
-	}
-	if IsHTTP(s) {
-		return DemultiplexedConnType_HTTP, peekedConn, nil
-	}
-	return DemultiplexedConnType_Unknown, peekedConn, nil
+	return t, peekedConn, nil
 }
 
 // Matchers are implemented here instead of in the transports so we can easily fuzz them together.
@@ -98,3 +131,20 @@ func IsTLS(s Prefix) bool {
 		return false
 	}
 }
+
+// IsWebSocketUpgrade returns whether header, the header of an HTTP/1.1 request, is a
+// WebSocket upgrade request.
+func IsWebSocketUpgrade(header []byte) bool {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(header)))
+	if err != nil {
+		return false
+	}
+	for _, v := range req.Header.Values("Upgrade") {
+		for _, p := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(p), "websocket") {
+				return true
+			}
+		}
+	}
+	return false
+}
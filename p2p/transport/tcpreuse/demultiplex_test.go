@@ -48,3 +48,20 @@ func FuzzClash(f *testing.F) {
 		}
 	})
 }
+
+func TestIsWebSocketUpgrade(t *testing.T) {
+	for _, tc := range []struct {
+		header string
+		ws     bool
+	}{
+		{"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n", false},
+		{"GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n", true},
+		{"GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: foo, WebSocket\r\n\r\n", true},
+		{"GET / HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n", false},
+		{"not http\r\n\r\n", false},
+	} {
+		if got := IsWebSocketUpgrade([]byte(tc.header)); got != tc.ws {
+			t.Errorf("%q: expected %t, got %t", tc.header, tc.ws, got)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package sampledconn
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"net"
@@ -16,6 +17,17 @@ type PeekedBytes = [peekSize]byte
 
 var ErrNotTCPConn = errors.New("passed conn is not a TCPConn")
 
+// ErrPeekLimit is returned by PeekUntil if the delimiter isn't found within the limit.
+var ErrPeekLimit = errors.New("peek limit reached")
+
+// Peeker is implemented by the conns returned by PeekBytes.
+type Peeker interface {
+	// PeekUntil reads from the conn until the read bytes contain delim, or limit bytes
+	// were read, and returns all bytes peeked so far. The peeked bytes are still
+	// returned by Read. It must be called before the first Read.
+	PeekUntil(delim []byte, limit int) ([]byte, error)
+}
+
 func PeekBytes(conn manet.Conn) (PeekedBytes, manet.Conn, error) {
 	if c, ok := conn.(ManetTCPConnInterface); ok {
 		return newWrappedSampledConn(c)
@@ -26,10 +38,13 @@ func PeekBytes(conn manet.Conn) (PeekedBytes, manet.Conn, error) {
 
 type wrappedSampledConn struct {
 	ManetTCPConnInterface
-	peekedBytes PeekedBytes
-	bytesPeeked uint8
+	peeked []byte
+	// read is the number of peeked bytes returned by Read
+	read int
 }
 
+var _ Peeker = &wrappedSampledConn{}
+
 // tcpConnInterface is the interface for TCPConn's functions
 // NOTE: `SyscallConn() (syscall.RawConn, error)` is here to make using this as
 // a TCP Conn easier, but it's a potential footgun as you could skipped the
@@ -58,20 +73,40 @@ type ManetTCPConnInterface interface {
 
 func newWrappedSampledConn(conn ManetTCPConnInterface) (PeekedBytes, *wrappedSampledConn, error) {
 	s := &wrappedSampledConn{ManetTCPConnInterface: conn}
-	n, err := io.ReadFull(conn, s.peekedBytes[:])
+	var peeked PeekedBytes
+	n, err := io.ReadFull(conn, peeked[:])
 	if err != nil {
 		if n == 0 && err == io.EOF {
 			err = io.ErrUnexpectedEOF
 		}
-		return s.peekedBytes, nil, err
+		return peeked, nil, err
+	}
+	s.peeked = peeked[:]
+	return peeked, s, nil
+}
+
+func (sc *wrappedSampledConn) PeekUntil(delim []byte, limit int) ([]byte, error) {
+	if sc.read != 0 {
+		return nil, errors.New("conn already read from")
+	}
+	buf := make([]byte, 512)
+	for !bytes.Contains(sc.peeked, delim) {
+		if len(sc.peeked) >= limit {
+			return sc.peeked, ErrPeekLimit
+		}
+		n, err := sc.ManetTCPConnInterface.Read(buf[:min(len(buf), limit-len(sc.peeked))])
+		sc.peeked = append(sc.peeked, buf[:n]...)
+		if err != nil {
+			return sc.peeked, err
+		}
 	}
-	return s.peekedBytes, s, nil
+	return sc.peeked, nil
 }
 
 func (sc *wrappedSampledConn) Read(b []byte) (int, error) {
-	if int(sc.bytesPeeked) != len(sc.peekedBytes) {
-		red := copy(b, sc.peekedBytes[sc.bytesPeeked:])
-		sc.bytesPeeked += uint8(red)
+	if sc.read != len(sc.peeked) {
+		red := copy(b, sc.peeked[sc.read:])
+		sc.read += red
 		return red, nil
 	}
 
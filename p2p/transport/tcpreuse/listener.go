@@ -29,17 +29,33 @@ type ConnMgr struct {
 	reuse           reuseport.Transport
 	upgrader        transport.Upgrader
 
+	metricsTracer MetricsTracer
+
 	mx        sync.Mutex
 	listeners map[string]*multiplexedListener
 }
 
-func NewConnMgr(enableReuseport bool, upgrader transport.Upgrader) *ConnMgr {
-	return &ConnMgr{
+// ConnMgrOption configures a ConnMgr.
+type ConnMgrOption func(*ConnMgr)
+
+// WithMetricsTracer configures the ConnMgr to report its demultiplexing decisions to mt.
+func WithMetricsTracer(mt MetricsTracer) ConnMgrOption {
+	return func(t *ConnMgr) {
+		t.metricsTracer = mt
+	}
+}
+
+func NewConnMgr(enableReuseport bool, upgrader transport.Upgrader, opts ...ConnMgrOption) *ConnMgr {
+	t := &ConnMgr{
 		enableReuseport: enableReuseport,
 		reuse:           reuseport.Transport{},
 		upgrader:        upgrader,
 		listeners:       make(map[string]*multiplexedListener),
 	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
 }
 
 func (t *ConnMgr) gatedMaListen(listenAddr ma.Multiaddr) (transport.GatedMaListener, error) {
@@ -122,6 +138,7 @@ func (t *ConnMgr) DemultiplexedListen(laddr ma.Multiaddr, connType Demultiplexed
 		listeners:       make(map[DemultiplexedConnType]*demultiplexedListener),
 		ctx:             ctx,
 		closeFn:         cancelFunc,
+		metricsTracer:   t.metricsTracer,
 	}
 	t.listeners[laddr.String()] = ml
 	t.listeners[gmal.Multiaddr().String()] = ml
@@ -138,6 +155,50 @@ func (t *ConnMgr) DemultiplexedListen(laddr ma.Multiaddr, connType Demultiplexed
 	return dl, nil
 }
 
+// HTTPListener returns a listener for plain HTTP connections on laddr, except for
+// WebSocket upgrade requests if a WebSocket transport listens on laddr. It allows
+// serving HTTP, e.g. with libp2phttp, on the port shared with the TCP and WebSocket
+// transports. The resources of a connection are released when it's closed.
+func (t *ConnMgr) HTTPListener(laddr ma.Multiaddr) (net.Listener, error) {
+	l, err := t.DemultiplexedListen(laddr, DemultiplexedConnType_HTTP)
+	if err != nil {
+		return nil, err
+	}
+	return &httpListener{GatedMaListener: l}, nil
+}
+
+// HTTPSListener returns a listener for TLS connections on laddr, e.g. to serve HTTPS
+// with libp2phttp on the port shared with the TCP transport. TLS connections can't be
+// told apart before their handshake, so the port can't be shared with a secure
+// WebSocket transport: ErrListenerExists is returned if one listens on laddr. The
+// resources of a connection are released when it's closed.
+func (t *ConnMgr) HTTPSListener(laddr ma.Multiaddr) (net.Listener, error) {
+	l, err := t.DemultiplexedListen(laddr, DemultiplexedConnType_TLS)
+	if err != nil {
+		return nil, err
+	}
+	return &httpListener{GatedMaListener: l}, nil
+}
+
+// httpListener adapts a demultiplexed listener to a net.Listener, for HTTP servers.
+type httpListener struct {
+	transport.GatedMaListener
+}
+
+func (l *httpListener) Accept() (net.Conn, error) {
+	c, scope, err := l.GatedMaListener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	cs, err := manetConnWithScope(c, scope)
+	if err != nil {
+		scope.Done()
+		c.Close()
+		return nil, err
+	}
+	return cs, nil
+}
+
 var _ transport.GatedMaListener = &demultiplexedListener{}
 
 type multiplexedListener struct {
@@ -145,6 +206,8 @@ type multiplexedListener struct {
 	listeners map[DemultiplexedConnType]*demultiplexedListener
 	mx        sync.RWMutex
 
+	metricsTracer MetricsTracer
+
 	ctx     context.Context
 	closeFn func() error
 	wg      sync.WaitGroup
@@ -212,6 +275,7 @@ func (m *multiplexedListener) run() error {
 			if err != nil {
 				// conn closed by identifyConnType
 				connScope.Done()
+				m.trace(DemultiplexedConnType_Unknown, DemultiplexResultError)
 				log.Debugf("error demultiplexing connection: %s", err.Error())
 				return
 			}
@@ -227,8 +291,12 @@ func (m *multiplexedListener) run() error {
 
 			m.mx.RLock()
 			demux, ok := m.listeners[t]
+			if fb, hasFallback := t.fallback(); !ok && hasFallback {
+				demux, ok = m.listeners[fb]
+			}
 			m.mx.RUnlock()
 			if !ok {
+				m.trace(t, DemultiplexResultNoListener)
 				closeErr := connWithScope.Close()
 				if closeErr != nil {
 					log.Debugf("no registered listener for demultiplex connection %s. Error closing the connection %s", t, closeErr.Error())
@@ -240,7 +308,9 @@ func (m *multiplexedListener) run() error {
 
 			select {
 			case demux.buffer <- connWithScope:
+				m.trace(t, DemultiplexResultAccepted)
 			case <-ctx.Done():
+				m.trace(t, DemultiplexResultTimeout)
 				log.Debug("accept timeout; dropping connection from: %v", connWithScope.RemoteMultiaddr())
 				connWithScope.Close()
 			}
@@ -248,6 +318,12 @@ func (m *multiplexedListener) run() error {
 	}
 }
 
+func (m *multiplexedListener) trace(t DemultiplexedConnType, result DemultiplexResult) {
+	if m.metricsTracer != nil {
+		m.metricsTracer.ConnDemultiplexed(t, result)
+	}
+}
+
 func (m *multiplexedListener) Close() error {
 	m.mx.Lock()
 	for _, l := range m.listeners {
@@ -12,6 +12,7 @@ import (
 	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"sync"
 	"testing"
 	"time"
@@ -495,3 +496,99 @@ func TestHitTimeout(t *testing.T) {
 
 	tcpConn.Close()
 }
+
+type mockDemuxTracer struct {
+	mx      sync.Mutex
+	results map[DemultiplexedConnType][]DemultiplexResult
+}
+
+func (m *mockDemuxTracer) ConnDemultiplexed(t DemultiplexedConnType, result DemultiplexResult) {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	m.results[t] = append(m.results[t], result)
+}
+
+func (m *mockDemuxTracer) get(t DemultiplexedConnType) []DemultiplexResult {
+	m.mx.Lock()
+	defer m.mx.Unlock()
+	return append([]DemultiplexResult(nil), m.results[t]...)
+}
+
+func TestListenerHTTPAndWebSocket(t *testing.T) {
+	tr := &mockDemuxTracer{results: make(map[DemultiplexedConnType][]DemultiplexResult)}
+	cm := NewConnMgr(false, upgrader(t), WithMetricsTracer(tr))
+	listenAddr := ma.StringCast("/ip4/127.0.0.1/tcp/0")
+
+	wsl, err := cm.DemultiplexedListen(listenAddr, DemultiplexedConnType_WebSocket)
+	require.NoError(t, err)
+	defer wsl.Close()
+	wh := wsHandler{conns: make(chan *websocket.Conn, 1)}
+	go http.Serve(manet.NetListener(&maListener{GatedMaListener: wsl}), wh)
+
+	hl, err := cm.HTTPListener(wsl.Multiaddr())
+	require.NoError(t, err)
+	defer hl.Close()
+	require.Equal(t, wsl.Addr(), hl.Addr())
+	go http.Serve(hl, http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/", hl.Addr()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	d := websocket.Dialer{}
+	conn, _, err := d.Dial(fmt.Sprintf("ws://%s", wsl.Addr()), http.Header{})
+	require.NoError(t, err)
+	defer conn.Close()
+	select {
+	case c := <-wh.conns:
+		require.NotNil(t, c)
+		c.Close()
+	case <-time.After(5 * time.Second):
+		t.Fatal("websocket connection not accepted")
+	}
+
+	require.Equal(t, []DemultiplexResult{DemultiplexResultAccepted}, tr.get(DemultiplexedConnType_HTTP))
+	require.Equal(t, []DemultiplexResult{DemultiplexResultAccepted}, tr.get(DemultiplexedConnType_WebSocket))
+
+	// without a listener for multistream connections
+	c, err := net.Dial("tcp", hl.Addr().String())
+	require.NoError(t, err)
+	defer c.Close()
+	_, err = c.Write([]byte("\x13/multistream/1.0.0\n"))
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(tr.get(DemultiplexedConnType_MultistreamSelect)) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, []DemultiplexResult{DemultiplexResultNoListener}, tr.get(DemultiplexedConnType_MultistreamSelect))
+}
+
+func TestListenerHTTPS(t *testing.T) {
+	cm := NewConnMgr(false, upgrader(t))
+	listenAddr := ma.StringCast("/ip4/127.0.0.1/tcp/0")
+
+	ml, err := cm.DemultiplexedListen(listenAddr, DemultiplexedConnType_MultistreamSelect)
+	require.NoError(t, err)
+	defer ml.Close()
+
+	hl, err := cm.HTTPSListener(ml.Multiaddr())
+	require.NoError(t, err)
+	defer hl.Close()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Write([]byte("hello"))
+	}))
+	srv.Listener = hl
+	srv.StartTLS()
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// the TLS connections are taken
+	_, err = cm.DemultiplexedListen(ml.Multiaddr(), DemultiplexedConnType_TLS)
+	require.ErrorIs(t, err, ErrListenerExists)
+}
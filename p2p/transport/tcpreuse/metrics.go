@@ -0,0 +1,111 @@
+package tcpreuse
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricNamespace = "libp2p_tcpreuse"
+
+var (
+	demultiplexedConns = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "demultiplexed_conns_total",
+			Help:      "Connections demultiplexed by type and result",
+		},
+		[]string{"type", "result"},
+	)
+	collectors = []prometheus.Collector{
+		demultiplexedConns,
+	}
+)
+
+// DemultiplexResult is the result of demultiplexing a connection.
+type DemultiplexResult int
+
+const (
+	// DemultiplexResultAccepted means the connection was handed to the listener of its type.
+	DemultiplexResultAccepted DemultiplexResult = iota
+	// DemultiplexResultNoListener means there's no listener for the connection type.
+	DemultiplexResultNoListener
+	// DemultiplexResultTimeout means the listener didn't accept the connection in time.
+	DemultiplexResultTimeout
+	// DemultiplexResultError means the connection type couldn't be identified, e.g.
+	// because the client didn't send any data.
+	DemultiplexResultError
+)
+
+func (r DemultiplexResult) String() string {
+	switch r {
+	case DemultiplexResultAccepted:
+		return "accepted"
+	case DemultiplexResultNoListener:
+		return "no_listener"
+	case DemultiplexResultTimeout:
+		return "timeout"
+	case DemultiplexResultError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// MetricsTracer tracks the demultiplexing decisions of the ConnMgr.
+type MetricsTracer interface {
+	// ConnDemultiplexed is called for every accepted connection, with the identified
+	// connection type.
+	ConnDemultiplexed(t DemultiplexedConnType, result DemultiplexResult)
+}
+
+type metricsTracer struct{}
+
+var _ MetricsTracer = &metricsTracer{}
+
+type metricsTracerSetting struct {
+	reg prometheus.Registerer
+}
+
+type MetricsTracerOption func(*metricsTracerSetting)
+
+func WithRegisterer(reg prometheus.Registerer) MetricsTracerOption {
+	return func(s *metricsTracerSetting) {
+		if reg != nil {
+			s.reg = reg
+		}
+	}
+}
+
+func NewMetricsTracer(opts ...MetricsTracerOption) MetricsTracer {
+	setting := &metricsTracerSetting{reg: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt(setting)
+	}
+	metricshelper.RegisterCollectors(setting.reg, collectors...)
+	return &metricsTracer{}
+}
+
+func (m *metricsTracer) ConnDemultiplexed(t DemultiplexedConnType, result DemultiplexResult) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	*tags = append(*tags, connTypeLabel(t), result.String())
+	demultiplexedConns.WithLabelValues(*tags...).Inc()
+}
+
+// connTypeLabel is DemultiplexedConnType.String without allocating.
+func connTypeLabel(t DemultiplexedConnType) string {
+	switch t {
+	case DemultiplexedConnType_MultistreamSelect:
+		return "multistream_select"
+	case DemultiplexedConnType_HTTP:
+		return "http"
+	case DemultiplexedConnType_TLS:
+		return "tls"
+	case DemultiplexedConnType_WebSocket:
+		return "websocket"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,23 @@
+//go:build nocover
+
+package tcpreuse
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestMetricsNoAllocNoCover(t *testing.T) {
+	tr := NewMetricsTracer()
+	tests := map[string]func(){
+		"ConnDemultiplexed": func() {
+			tr.ConnDemultiplexed(DemultiplexedConnType(rand.Intn(5)), DemultiplexResult(rand.Intn(4)))
+		},
+	}
+	for method, f := range tests {
+		allocs := testing.AllocsPerRun(1000, f)
+		if allocs > 0 {
+			t.Fatalf("Alloc Test: %s, got: %0.2f, expected: 0 allocs", method, allocs)
+		}
+	}
+}
@@ -41,8 +41,10 @@ type dataChannel struct {
 
 type connection struct {
 	pc        *webrtc.PeerConnection
-	transport *WebRTCTransport
-	scope     network.ConnManagementScope
+	transport tpt.Transport
+	// transportName is reported by ConnState: webrtc-direct or webrtc.
+	transportName string
+	scope         network.ConnManagementScope
 
 	closeOnce sync.Once
 	closeErr  error
@@ -67,7 +69,8 @@ type connection struct {
 func newConnection(
 	direction network.Direction,
 	pc *webrtc.PeerConnection,
-	transport *WebRTCTransport,
+	transport tpt.Transport,
+	transportName string,
 	scope network.ConnManagementScope,
 
 	localPeer peer.ID,
@@ -81,9 +84,10 @@ func newConnection(
 ) (*connection, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	c := &connection{
-		pc:        pc,
-		transport: transport,
-		scope:     scope,
+		pc:            pc,
+		transport:     transport,
+		transportName: transportName,
+		scope:         scope,
 
 		localPeer:      localPeer,
 		localMultiaddr: localMultiaddr,
@@ -107,10 +111,14 @@ func newConnection(
 
 	pc.OnConnectionStateChange(c.onConnectionStateChange)
 	pc.SCTP().OnClose(func(err error) {
+		// The SCTP association is only closed before we close the connection if the
+		// remote peer closed it.
 		if err != nil {
-			c.closeWithError(fmt.Errorf("%w: %w", errConnClosed, err))
+			err = fmt.Errorf("%w: %w", errConnClosed, err)
+		} else {
+			err = errConnClosed
 		}
-		c.closeWithError(errConnClosed)
+		c.closeWithError(&network.ConnError{Remote: true, TransportError: err})
 	})
 	select {
 	case <-peerConnectionClosedCh:
@@ -123,7 +131,7 @@ func newConnection(
 
 // ConnState implements transport.CapableConn
 func (c *connection) ConnState() network.ConnectionState {
-	return network.ConnectionState{Transport: "webrtc-direct"}
+	return network.ConnectionState{Transport: c.transportName}
 }
 
 // Close closes the underlying peerconnection.
@@ -133,7 +141,8 @@ func (c *connection) Close() error {
 }
 
 // CloseWithError closes the connection ignoring the error code. As there's no way to signal
-// the remote peer on closing the underlying peerconnection, we ignore the error code.
+// the remote peer on closing the underlying peerconnection, we ignore the error code. The
+// remote peer observes the close with ConnNoError.
 func (c *connection) CloseWithError(_ network.ConnErrorCode) error {
 	return c.Close()
 }
@@ -273,6 +273,7 @@ func (l *listener) setupConnection(
 		network.DirInbound,
 		w.PeerConnection,
 		l.transport,
+		"webrtc-direct",
 		scope,
 		l.transport.localPeerId,
 		localMultiaddrWithoutCerthash,
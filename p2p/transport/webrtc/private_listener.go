@@ -0,0 +1,193 @@
+package libp2pwebrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	tpt "github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc/pb"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"github.com/pion/webrtc/v4"
+)
+
+// privateListener accepts the /webrtc connections. The connections are established
+// by the handler of the signaling streams.
+type privateListener struct {
+	transport *PrivateTransport
+
+	// inFlight limits the connections being established.
+	inFlight chan struct{}
+
+	// buffered incoming connections
+	acceptQueue chan tpt.CapableConn
+
+	// used to control the lifecycle of the listener
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var _ tpt.Listener = &privateListener{}
+
+func newPrivateListener(transport *PrivateTransport) *privateListener {
+	l := &privateListener{
+		transport:   transport,
+		inFlight:    make(chan struct{}, transport.maxInFlightConnections),
+		acceptQueue: make(chan tpt.CapableConn),
+	}
+	l.ctx, l.cancel = context.WithCancel(context.Background())
+	transport.host.SetStreamHandler(SignalingProtocol, l.handleSignalingStream)
+	return l
+}
+
+func (l *privateListener) handleSignalingStream(s network.Stream) {
+	// Signaling is only needed between peers that can't connect directly, so it's only
+	// accepted on limited relayed connections.
+	if !s.Conn().Stat().Limited || !isRelayed(s.Conn().RemoteMultiaddr()) {
+		log.Debugf("rejecting /webrtc signaling stream from %s: not on a limited relayed connection", s.Conn().RemotePeer())
+		s.Reset()
+		return
+	}
+	select {
+	case l.inFlight <- struct{}{}:
+	default:
+		log.Debugf("rejecting /webrtc connection from %s: too many connections in flight", s.Conn().RemotePeer())
+		s.Reset()
+		return
+	}
+	defer func() { <-l.inFlight }()
+
+	ctx, cancel := context.WithTimeout(l.ctx, signalingTimeout)
+	defer cancel()
+
+	conn, err := l.handleStream(ctx, s)
+	if err != nil {
+		log.Debugf("could not accept /webrtc connection from %s: %s", s.Conn().RemotePeer(), err)
+		return
+	}
+
+	select {
+	case <-l.ctx.Done():
+		log.Debug("dropping connection, listener closed")
+		conn.Close()
+	case l.acceptQueue <- conn:
+		// acceptQueue is an unbuffered channel, so this blocks until the connection is accepted.
+	}
+}
+
+func (l *privateListener) handleStream(ctx context.Context, s network.Stream) (tpt.CapableConn, error) {
+	// The connection isn't established yet: its remote address is the /webrtc address
+	// of the remote peer through the relay.
+	remoteMultiaddr := s.Conn().RemoteMultiaddr().Encapsulate(webrtcPrivateComponent)
+	if l.transport.gater != nil {
+		if !l.transport.gater.InterceptAccept(&connMultiaddrs{local: l.Multiaddr(), remote: remoteMultiaddr}) {
+			s.Reset()
+			return nil, errors.New("connection gated")
+		}
+	}
+	scope, err := l.transport.rcmgr.OpenConnection(network.DirInbound, false, remoteMultiaddr)
+	if err != nil {
+		s.Reset()
+		return nil, err
+	}
+	conn, err := l.setupConnection(ctx, scope, s)
+	if err != nil {
+		scope.Done()
+		return nil, err
+	}
+	if l.transport.gater != nil && !l.transport.gater.InterceptSecured(network.DirInbound, conn.RemotePeer(), conn) {
+		conn.Close()
+		return nil, errors.New("connection gated")
+	}
+	return conn, nil
+}
+
+func (l *privateListener) setupConnection(ctx context.Context, scope network.ConnManagementScope, s network.Stream) (tConn tpt.CapableConn, err error) {
+	sig, err := newSignalingStream(s)
+	if err != nil {
+		s.Reset()
+		return nil, err
+	}
+	defer sig.done(&err)
+
+	var w webRTCConnection
+	defer func() {
+		if err != nil {
+			if w.PeerConnection != nil {
+				_ = w.PeerConnection.Close()
+			}
+			if tConn != nil {
+				_ = tConn.Close()
+			}
+		}
+	}()
+
+	remotePeer := s.Conn().RemotePeer()
+	if err := scope.SetPeer(remotePeer); err != nil {
+		return nil, err
+	}
+	if err := scope.ReserveMemory(sctpReceiveBufferSize, network.ReservationPriorityMedium); err != nil {
+		return nil, err
+	}
+	// The data channels are opened by the dialer, there's no handshake channel.
+	w, err = newPeerConnection(l.transport.settingEngine(), l.transport.webrtcConfig)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating peer connection failed: %w", err)
+	}
+	errC := addOnConnectionStateChangeCallback(w.PeerConnection)
+
+	offer, err := sig.readSDP(pb.SignalingMessage_SDP_OFFER)
+	if err != nil {
+		return nil, fmt.Errorf("read offer: %w", err)
+	}
+	if err := w.PeerConnection.SetRemoteDescription(webrtc.SessionDescription{SDP: offer, Type: webrtc.SDPTypeOffer}); err != nil {
+		return nil, fmt.Errorf("set remote description: %w", err)
+	}
+	answer, err := w.PeerConnection.CreateAnswer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("create answer: %w", err)
+	}
+	if err := sig.write(pb.SignalingMessage_SDP_ANSWER, answer.SDP); err != nil {
+		return nil, fmt.Errorf("send answer: %w", err)
+	}
+	sig.sendCandidates(w.PeerConnection)
+	if err := w.PeerConnection.SetLocalDescription(answer); err != nil {
+		return nil, fmt.Errorf("set local description: %w", err)
+	}
+	if err := sig.connect(ctx, w.PeerConnection, errC); err != nil {
+		return nil, err
+	}
+
+	conn, err := l.transport.newConnection(network.DirInbound, w, scope, remotePeer, s.Conn().RemotePublicKey())
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (l *privateListener) Accept() (tpt.CapableConn, error) {
+	select {
+	case <-l.ctx.Done():
+		return nil, tpt.ErrListenerClosed
+	case conn := <-l.acceptQueue:
+		return conn, nil
+	}
+}
+
+func (l *privateListener) Close() error {
+	l.cancel()
+	l.transport.removeListener(l)
+	return nil
+}
+
+// Addr returns nil, as the /webrtc listener doesn't listen on a socket.
+func (l *privateListener) Addr() net.Addr {
+	return nil
+}
+
+func (l *privateListener) Multiaddr() ma.Multiaddr {
+	return ma.Multiaddr{*webrtcPrivateComponent}
+}
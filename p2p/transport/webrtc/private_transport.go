@@ -0,0 +1,313 @@
+package libp2pwebrtc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/TheNoobiCat/go-libp2p/core/connmgr"
+	ic "github.com/TheNoobiCat/go-libp2p/core/crypto"
+	"github.com/TheNoobiCat/go-libp2p/core/host"
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	tpt "github.com/TheNoobiCat/go-libp2p/core/transport"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc/pb"
+
+	ma "github.com/multiformats/go-multiaddr"
+	manet "github.com/multiformats/go-multiaddr/net"
+
+	"github.com/pion/webrtc/v4"
+)
+
+var webrtcPrivateComponent *ma.Component
+
+func init() {
+	var err error
+	webrtcPrivateComponent, err = ma.NewComponent(ma.ProtocolWithCode(ma.P_WEBRTC).Name, "")
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// PrivateTransport is the /webrtc transport. It establishes direct WebRTC connections
+// between peers that aren't publicly reachable, browsers included, as described in
+// https://github.com/libp2p/specs/blob/master/webrtc/webrtc.md.
+//
+// The peers exchange their SDPs and ICE candidates on a signaling stream, opened on
+// a relayed connection. The /webrtc addresses of a peer are its relay addresses
+// followed by /webrtc, e.g. /ip4/1.2.3.4/udp/4001/quic-v1/p2p/<relay>/p2p-circuit/webrtc.
+// The relay transport must therefore be enabled, and the peer must have relay
+// addresses, e.g. through AutoRelay.
+//
+// There's no security handshake on the connections: the certificate fingerprints in
+// the SDPs authenticate the DTLS handshake, and they're exchanged over the relayed
+// connection, that is already secured.
+type PrivateTransport struct {
+	host         host.Host
+	webrtcConfig webrtc.Configuration
+	rcmgr        network.ResourceManager
+	gater        connmgr.ConnectionGater
+
+	// timeouts
+	peerConnectionTimeouts iceTimeouts
+
+	// in-flight connections
+	maxInFlightConnections uint32
+
+	listenerMx sync.Mutex
+	listener   *privateListener
+}
+
+var _ tpt.Transport = &PrivateTransport{}
+
+type PrivateOption func(*PrivateTransport) error
+
+// WithICEServers sets the STUN and TURN servers used to gather the ICE candidates.
+// Without them, the peers can only connect if one of them is reachable on one of its
+// host addresses, or if their NATs allow the peer reflexive candidates discovered
+// during the connectivity checks.
+func WithICEServers(servers ...webrtc.ICEServer) PrivateOption {
+	return func(t *PrivateTransport) error {
+		t.webrtcConfig.ICEServers = append(t.webrtcConfig.ICEServers, servers...)
+		return nil
+	}
+}
+
+// NewPrivateTransport creates a /webrtc transport for h. It can be passed to
+// libp2p.Transport, and then listens for connections when /webrtc is one of the listen
+// addresses.
+func NewPrivateTransport(h host.Host, gater connmgr.ConnectionGater, rcmgr network.ResourceManager, opts ...PrivateOption) (*PrivateTransport, error) {
+	if rcmgr == nil {
+		rcmgr = &network.NullResourceManager{}
+	}
+	cert, err := generateCertificate()
+	if err != nil {
+		return nil, err
+	}
+	transport := &PrivateTransport{
+		host:  h,
+		rcmgr: rcmgr,
+		gater: gater,
+		webrtcConfig: webrtc.Configuration{
+			Certificates: []webrtc.Certificate{*cert},
+		},
+		peerConnectionTimeouts: iceTimeouts{
+			Disconnect: DefaultDisconnectedTimeout,
+			Failed:     DefaultFailedTimeout,
+			Keepalive:  DefaultKeepaliveTimeout,
+		},
+
+		maxInFlightConnections: DefaultMaxInFlightConnections,
+	}
+	for _, opt := range opts {
+		if err := opt(transport); err != nil {
+			return nil, err
+		}
+	}
+	return transport, nil
+}
+
+func (t *PrivateTransport) Protocols() []int {
+	return []int{ma.P_WEBRTC}
+}
+
+func (t *PrivateTransport) Proxy() bool {
+	return false
+}
+
+func (t *PrivateTransport) CanDial(addr ma.Multiaddr) bool {
+	return IsWebRTCMultiaddr(addr)
+}
+
+// Listen returns a listener for the /webrtc connections. addr must be /webrtc. There
+// can only be one listener at a time.
+func (t *PrivateTransport) Listen(addr ma.Multiaddr) (tpt.Listener, error) {
+	if !addr.Equal(ma.Multiaddr{*webrtcPrivateComponent}) {
+		return nil, fmt.Errorf("must listen on /webrtc, got %s", addr)
+	}
+	t.listenerMx.Lock()
+	defer t.listenerMx.Unlock()
+	if t.listener != nil {
+		return nil, errors.New("already listening on /webrtc")
+	}
+	t.listener = newPrivateListener(t)
+	return t.listener, nil
+}
+
+func (t *PrivateTransport) removeListener(l *privateListener) {
+	t.listenerMx.Lock()
+	defer t.listenerMx.Unlock()
+	if t.listener == l {
+		t.host.RemoveStreamHandler(SignalingProtocol)
+		t.listener = nil
+	}
+}
+
+func (t *PrivateTransport) Dial(ctx context.Context, remoteMultiaddr ma.Multiaddr, p peer.ID) (tpt.CapableConn, error) {
+	if !IsWebRTCMultiaddr(remoteMultiaddr) {
+		return nil, fmt.Errorf("can't dial %s: not a /webrtc address", remoteMultiaddr)
+	}
+	scope, err := t.rcmgr.OpenConnection(network.DirOutbound, false, remoteMultiaddr)
+	if err != nil {
+		return nil, err
+	}
+	if err := scope.SetPeer(p); err != nil {
+		scope.Done()
+		return nil, err
+	}
+	conn, err := t.dial(ctx, scope, remoteMultiaddr, p)
+	if err != nil {
+		scope.Done()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (t *PrivateTransport) dial(ctx context.Context, scope network.ConnManagementScope, remoteMultiaddr ma.Multiaddr, p peer.ID) (tConn tpt.CapableConn, err error) {
+	ctx, cancel := context.WithTimeout(ctx, signalingTimeout)
+	defer cancel()
+
+	// The signaling stream is opened on a relayed connection to p, through the relay
+	// of remoteMultiaddr.
+	relayAddr, _ := ma.SplitFunc(remoteMultiaddr, func(c ma.Component) bool { return c.Protocol().Code == ma.P_WEBRTC })
+	// Only dial the relay address: the /webrtc address is already being dialed by the
+	// swarm, waiting for this connection.
+	sigCtx := network.WithDialAddrs(ctx, []ma.Multiaddr{relayAddr})
+	s, err := t.host.NewStream(network.WithAllowLimitedConn(sigCtx, "webrtc signaling"), p, SignalingProtocol)
+	if err != nil {
+		return nil, fmt.Errorf("open signaling stream: %w", err)
+	}
+	sig, err := newSignalingStream(s)
+	if err != nil {
+		s.Reset()
+		return nil, err
+	}
+	defer sig.done(&err)
+
+	var w webRTCConnection
+	defer func() {
+		if err != nil {
+			if w.PeerConnection != nil {
+				_ = w.PeerConnection.Close()
+			}
+			if tConn != nil {
+				_ = tConn.Close()
+				tConn = nil
+			}
+		}
+	}()
+
+	settingEngine := t.settingEngine()
+	if err := scope.ReserveMemory(sctpReceiveBufferSize, network.ReservationPriorityMedium); err != nil {
+		return nil, err
+	}
+	// The offer needs a data channel to have an application section. The negotiated
+	// handshake channel doesn't open a stream on the remote peer.
+	w, err = newWebRTCConnection(settingEngine, t.webrtcConfig)
+	if err != nil {
+		return nil, fmt.Errorf("instantiating peer connection failed: %w", err)
+	}
+	errC := addOnConnectionStateChangeCallback(w.PeerConnection)
+
+	// do offer-answer exchange
+	offer, err := w.PeerConnection.CreateOffer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("create offer: %w", err)
+	}
+	if err := sig.write(pb.SignalingMessage_SDP_OFFER, offer.SDP); err != nil {
+		return nil, fmt.Errorf("send offer: %w", err)
+	}
+	sig.sendCandidates(w.PeerConnection)
+	if err := w.PeerConnection.SetLocalDescription(offer); err != nil {
+		return nil, fmt.Errorf("set local description: %w", err)
+	}
+	answer, err := sig.readSDP(pb.SignalingMessage_SDP_ANSWER)
+	if err != nil {
+		return nil, fmt.Errorf("read answer: %w", err)
+	}
+	if err := w.PeerConnection.SetRemoteDescription(webrtc.SessionDescription{SDP: answer, Type: webrtc.SDPTypeAnswer}); err != nil {
+		return nil, fmt.Errorf("set remote description: %w", err)
+	}
+	if err := sig.connect(ctx, w.PeerConnection, errC); err != nil {
+		return nil, err
+	}
+
+	conn, err := t.newConnection(network.DirOutbound, w, scope, p, s.Conn().RemotePublicKey())
+	if err != nil {
+		return nil, err
+	}
+	if t.gater != nil && !t.gater.InterceptSecured(network.DirOutbound, p, conn) {
+		return nil, fmt.Errorf("secured connection gated")
+	}
+	return conn, nil
+}
+
+func (t *PrivateTransport) settingEngine() webrtc.SettingEngine {
+	settingEngine := webrtc.SettingEngine{LoggerFactory: pionLoggerFactory}
+	// The answerer is the DTLS server, as for /webrtc-direct. A remote peer that isn't
+	// using pion may still pick the other role. Stream IDs don't depend on it: they're
+	// assigned by the direction of the connection, see newConnection.
+	settingEngine.SetAnsweringDTLSRole(webrtc.DTLSRoleServer)
+	settingEngine.DetachDataChannels()
+	settingEngine.SetICETimeouts(
+		t.peerConnectionTimeouts.Disconnect,
+		t.peerConnectionTimeouts.Failed,
+		t.peerConnectionTimeouts.Keepalive,
+	)
+	settingEngine.SetIncludeLoopbackCandidate(true)
+	settingEngine.SetSCTPMaxReceiveBufferSize(sctpReceiveBufferSize)
+	return settingEngine
+}
+
+// newConnection returns the connection over the connected peer connection of w.
+func (t *PrivateTransport) newConnection(dir network.Direction, w webRTCConnection, scope network.ConnManagementScope, p peer.ID, remoteKey ic.PubKey) (*connection, error) {
+	cp, err := w.PeerConnection.SCTP().Transport().ICETransport().GetSelectedCandidatePair()
+	if cp == nil {
+		return nil, errors.New("ice connection did not have selected candidate pair: nil result")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ice connection did not have selected candidate pair: error: %w", err)
+	}
+	localAddr, err := manet.FromNetAddr(&net.UDPAddr{IP: net.ParseIP(cp.Local.Address), Port: int(cp.Local.Port)})
+	if err != nil {
+		return nil, err
+	}
+	remoteAddr, err := manet.FromNetAddr(&net.UDPAddr{IP: net.ParseIP(cp.Remote.Address), Port: int(cp.Remote.Port)})
+	if err != nil {
+		return nil, err
+	}
+	return newConnection(
+		dir,
+		w.PeerConnection,
+		t,
+		"webrtc",
+		scope,
+		t.host.ID(),
+		localAddr.AppendComponent(webrtcPrivateComponent),
+		p,
+		remoteKey,
+		remoteAddr.AppendComponent(webrtcPrivateComponent),
+		w.IncomingDataChannels,
+		w.PeerConnectionClosedCh,
+	)
+}
+
+// isRelayed returns whether addr is a relayed address.
+func isRelayed(addr ma.Multiaddr) bool {
+	_, err := addr.ValueForProtocol(ma.P_CIRCUIT)
+	return err == nil
+}
+
+// IsWebRTCMultiaddr returns whether addr is a /webrtc multiaddr: a relay address
+// followed by /webrtc, and optionally by the /p2p component of the peer.
+func IsWebRTCMultiaddr(addr ma.Multiaddr) bool {
+	if len(addr) > 0 && addr[len(addr)-1].Protocol().Code == ma.P_P2P {
+		addr = addr[:len(addr)-1]
+	}
+	if len(addr) < 2 {
+		return false
+	}
+	return addr[len(addr)-1].Protocol().Code == ma.P_WEBRTC && addr[len(addr)-2].Protocol().Code == ma.P_CIRCUIT
+}
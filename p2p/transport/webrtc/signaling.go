@@ -0,0 +1,166 @@
+package libp2pwebrtc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/p2p/transport/webrtc/pb"
+	"github.com/libp2p/go-msgio/pbio"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// SignalingProtocol is the protocol of the streams on which the peers of a /webrtc
+// connection exchange their SDPs and ICE candidates.
+const SignalingProtocol = "/webrtc-signaling/0.0.1"
+
+const (
+	signalingServiceName = "libp2p.webrtc.signaling"
+
+	// maxSignalingMessageSize is the maximum size of a signaling message. The SDPs are
+	// usually less than 1KB.
+	maxSignalingMessageSize = 16 * 1024
+
+	// signalingTimeout is the timeout for establishing a /webrtc connection, from
+	// opening the signaling stream until the peer connection is connected. It's higher
+	// than the timeout of /webrtc-direct, as the candidates are exchanged through a
+	// relay and may be gathered from STUN servers.
+	signalingTimeout = 30 * time.Second
+)
+
+var errSignalingDone = errors.New("signaling done")
+
+// signalingStream is the signaling stream of a /webrtc connection. The ICE candidates
+// are sent as they're gathered, concurrently with the rest of the exchange.
+type signalingStream struct {
+	s network.Stream
+	r pbio.Reader
+
+	mx     sync.Mutex
+	w      pbio.Writer
+	closed bool
+}
+
+func newSignalingStream(s network.Stream) (*signalingStream, error) {
+	if err := s.Scope().SetService(signalingServiceName); err != nil {
+		return nil, fmt.Errorf("error attaching stream to webrtc signaling service: %w", err)
+	}
+	if err := s.Scope().ReserveMemory(maxSignalingMessageSize, network.ReservationPriorityAlways); err != nil {
+		return nil, fmt.Errorf("error reserving memory for stream: %w", err)
+	}
+	s.SetDeadline(time.Now().Add(signalingTimeout))
+	return &signalingStream{
+		s: s,
+		r: pbio.NewDelimitedReader(s, maxSignalingMessageSize),
+		w: pbio.NewDelimitedWriter(s),
+	}, nil
+}
+
+// done closes the stream once the connection is established, or resets it if *err
+// isn't nil. The candidates gathered later aren't sent.
+func (s *signalingStream) done(err *error) {
+	s.mx.Lock()
+	s.closed = true
+	s.mx.Unlock()
+	if *err != nil {
+		s.s.Reset()
+	} else {
+		s.s.Close()
+	}
+	s.s.Scope().ReleaseMemory(maxSignalingMessageSize)
+}
+
+func (s *signalingStream) write(typ pb.SignalingMessage_Type, data string) error {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	if s.closed {
+		return errSignalingDone
+	}
+	return s.w.WriteMsg(&pb.SignalingMessage{Type: typ.Enum(), Data: &data})
+}
+
+// readSDP reads the SDP of the offer or of the answer.
+func (s *signalingStream) readSDP(typ pb.SignalingMessage_Type) (string, error) {
+	var msg pb.SignalingMessage
+	if err := s.r.ReadMsg(&msg); err != nil {
+		return "", err
+	}
+	if msg.GetType() != typ {
+		return "", fmt.Errorf("expected %s message, got %s", typ, msg.GetType())
+	}
+	return msg.GetData(), nil
+}
+
+// sendCandidates sends the ICE candidates gathered by pc. It must be called before
+// setting the local description of pc, which starts the gathering.
+func (s *signalingStream) sendCandidates(pc *webrtc.PeerConnection) {
+	pc.OnICECandidate(func(c *webrtc.ICECandidate) {
+		// A nil candidate marks the end of the gathering, it's sent as null.
+		data := "null"
+		if c != nil {
+			b, err := json.Marshal(c.ToJSON())
+			if err != nil {
+				log.Debugf("failed to marshal ICE candidate: %s", err)
+				return
+			}
+			data = string(b)
+		}
+		if err := s.write(pb.SignalingMessage_ICE_CANDIDATE, data); err != nil && !errors.Is(err, errSignalingDone) {
+			log.Debugf("failed to send ICE candidate: %s", err)
+		}
+	})
+}
+
+// receiveCandidates adds the ICE candidates received to pc, until the remote peer
+// closes the stream.
+func (s *signalingStream) receiveCandidates(pc *webrtc.PeerConnection) error {
+	for {
+		var msg pb.SignalingMessage
+		if err := s.r.ReadMsg(&msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if msg.GetType() != pb.SignalingMessage_ICE_CANDIDATE {
+			return fmt.Errorf("expected %s message, got %s", pb.SignalingMessage_ICE_CANDIDATE, msg.GetType())
+		}
+		if msg.GetData() == "" || msg.GetData() == "null" {
+			continue
+		}
+		var candidate webrtc.ICECandidateInit
+		if err := json.Unmarshal([]byte(msg.GetData()), &candidate); err != nil {
+			return fmt.Errorf("invalid ICE candidate: %w", err)
+		}
+		if err := pc.AddICECandidate(candidate); err != nil {
+			return fmt.Errorf("add ICE candidate: %w", err)
+		}
+	}
+}
+
+// connect exchanges the ICE candidates until pc is connected. errC is the channel
+// returned by addOnConnectionStateChangeCallback.
+func (s *signalingStream) connect(ctx context.Context, pc *webrtc.PeerConnection, errC <-chan error) error {
+	readErr := make(chan error, 1)
+	go func() { readErr <- s.receiveCandidates(pc) }()
+	for {
+		select {
+		case err := <-errC:
+			return err
+		case err := <-readErr:
+			if err != nil {
+				return fmt.Errorf("signaling failed: %w", err)
+			}
+			// The remote peer sent all its candidates, and closed the stream.
+			readErr = nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -118,24 +118,9 @@ func New(privKey ic.PrivKey, psk pnet.PSK, gater connmgr.ConnectionGater, rcmgr
 	if err != nil {
 		return nil, fmt.Errorf("get local peer ID: %w", err)
 	}
-	// We use elliptic P-256 since it is widely supported by browsers.
-	//
-	// Implementation note: Testing with the browser,
-	// it seems like Chromium only supports ECDSA P-256 or RSA key signatures in the webrtc TLS certificate.
-	// We tried using P-228 and P-384 which caused the DTLS handshake to fail with Illegal Parameter
-	//
-	// Please refer to this is a list of suggested algorithms for the WebCrypto API.
-	// The algorithm for generating a certificate for an RTCPeerConnection
-	// must adhere to the WebCrpyto API. From my observation,
-	// RSA and ECDSA P-256 is supported on almost all browsers.
-	// Ed25519 is not present on the list.
-	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	cert, err := generateCertificate()
 	if err != nil {
-		return nil, fmt.Errorf("generate key for cert: %w", err)
-	}
-	cert, err := webrtc.GenerateCertificate(pk)
-	if err != nil {
-		return nil, fmt.Errorf("generate certificate: %w", err)
+		return nil, err
 	}
 	config := webrtc.Configuration{
 		Certificates: []webrtc.Certificate{*cert},
@@ -169,6 +154,30 @@ func New(privKey ic.PrivKey, psk pnet.PSK, gater connmgr.ConnectionGater, rcmgr
 	return transport, nil
 }
 
+// generateCertificate generates the certificate of the DTLS handshakes of a transport.
+func generateCertificate() (*webrtc.Certificate, error) {
+	// We use elliptic P-256 since it is widely supported by browsers.
+	//
+	// Implementation note: Testing with the browser,
+	// it seems like Chromium only supports ECDSA P-256 or RSA key signatures in the webrtc TLS certificate.
+	// We tried using P-228 and P-384 which caused the DTLS handshake to fail with Illegal Parameter
+	//
+	// Please refer to this is a list of suggested algorithms for the WebCrypto API.
+	// The algorithm for generating a certificate for an RTCPeerConnection
+	// must adhere to the WebCrpyto API. From my observation,
+	// RSA and ECDSA P-256 is supported on almost all browsers.
+	// Ed25519 is not present on the list.
+	pk, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("generate key for cert: %w", err)
+	}
+	cert, err := webrtc.GenerateCertificate(pk)
+	if err != nil {
+		return nil, fmt.Errorf("generate certificate: %w", err)
+	}
+	return cert, nil
+}
+
 func (t *WebRTCTransport) ListenOrder() int {
 	return libp2pquic.ListenOrder + 1 // We want to listen after QUIC listens so we can possibly reuse the same port.
 }
@@ -399,6 +408,7 @@ func (t *WebRTCTransport) dial(ctx context.Context, scope network.ConnManagement
 		network.DirOutbound,
 		w.PeerConnection,
 		t,
+		"webrtc-direct",
 		scope,
 		t.localPeerId,
 		localAddr,
@@ -587,21 +597,31 @@ type webRTCConnection struct {
 }
 
 func newWebRTCConnection(settings webrtc.SettingEngine, config webrtc.Configuration) (webRTCConnection, error) {
-	api := webrtc.NewAPI(webrtc.WithSettingEngine(settings))
-	pc, err := api.NewPeerConnection(config)
+	w, err := newPeerConnection(settings, config)
 	if err != nil {
-		return webRTCConnection{}, fmt.Errorf("failed to create peer connection: %w", err)
+		return webRTCConnection{}, err
 	}
 
 	negotiated, id := handshakeChannelNegotiated, handshakeChannelID
-	handshakeDataChannel, err := pc.CreateDataChannel("", &webrtc.DataChannelInit{
+	handshakeDataChannel, err := w.PeerConnection.CreateDataChannel("", &webrtc.DataChannelInit{
 		Negotiated: &negotiated,
 		ID:         &id,
 	})
 	if err != nil {
-		pc.Close()
+		w.PeerConnection.Close()
 		return webRTCConnection{}, fmt.Errorf("failed to create handshake channel: %w", err)
 	}
+	w.HandshakeDataChannel = handshakeDataChannel
+	return w, nil
+}
+
+// newPeerConnection creates a webRTCConnection without a handshake channel.
+func newPeerConnection(settings webrtc.SettingEngine, config webrtc.Configuration) (webRTCConnection, error) {
+	api := webrtc.NewAPI(webrtc.WithSettingEngine(settings))
+	pc, err := api.NewPeerConnection(config)
+	if err != nil {
+		return webRTCConnection{}, fmt.Errorf("failed to create peer connection: %w", err)
+	}
 
 	incomingDataChannels := make(chan dataChannel, maxAcceptQueueLen)
 	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
@@ -633,7 +653,6 @@ func newWebRTCConnection(settings webrtc.SettingEngine, config webrtc.Configurat
 	})
 	return webRTCConnection{
 		PeerConnection:         pc,
-		HandshakeDataChannel:   handshakeDataChannel,
 		IncomingDataChannels:   incomingDataChannels,
 		PeerConnectionClosedCh: connectionClosedCh,
 	}, nil
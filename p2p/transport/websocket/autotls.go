@@ -0,0 +1,79 @@
+package websocket
+
+import (
+	"crypto/tls"
+	"errors"
+
+	ma "github.com/multiformats/go-multiaddr"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// WithAutoTLS makes the listener obtain and renew a certificate for domain using
+// ACME (e.g. Let's Encrypt), so that it can serve /wss without a manually
+// provisioned certificate. Certificates are requested when the first wss listener
+// is opened and renewed before they expire.
+//
+// The ACME CA validates the domain using the TLS-ALPN-01 challenge on the listener
+// itself, which requires the listener to be reachable at domain on port 443. To use
+// the HTTP-01 challenge instead, serve m.HTTPHandler on port 80. The manager should
+// have a Cache, otherwise certificates are requested again on every restart.
+//
+// Listeners on IP addresses are advertised with a /tls/sni/<domain>/ws
+// multiaddr, so that peers validate the certificate against the domain.
+//
+// WithAutoTLS can't be combined with WithTLSConfig.
+func WithAutoTLS(m *autocert.Manager, domain string) Option {
+	return func(t *WebsocketTransport) error {
+		if m == nil || domain == "" {
+			return errors.New("auto TLS requires a manager and a domain")
+		}
+		t.autoTLS = m
+		t.autoTLSDomain = domain
+		return nil
+	}
+}
+
+// autoTLSConfig returns the listener TLS config for the ACME manager.
+func autoTLSConfig(m *autocert.Manager) *tls.Config {
+	conf := m.TLSConfig()
+	// Don't negotiate HTTP/2, WebSockets require HTTP/1.1.
+	conf.NextProtos = []string{"http/1.1", acme.ALPNProto}
+	return conf
+}
+
+// addAutoTLSSNI adds the auto TLS domain as SNI to wss multiaddrs listening on an
+// IP address.
+func (t *WebsocketTransport) addAutoTLSSNI(a ma.Multiaddr) ma.Multiaddr {
+	if t.autoTLS == nil {
+		return a
+	}
+	parsed, err := parseWebsocketMultiaddr(a)
+	if err != nil || !parsed.isWSS || parsed.sni != nil {
+		return a
+	}
+	switch parsed.restMultiaddr[0].Protocol().Code {
+	case ma.P_IP4, ma.P_IP6:
+	default:
+		return a
+	}
+	sni, err := ma.NewComponent("sni", t.autoTLSDomain)
+	if err != nil {
+		return a
+	}
+	parsed.sni = sni
+	return parsed.toMultiaddr()
+}
+
+// prefetchCertificate requests the certificate in the background, so that the
+// first peer connecting doesn't have to wait for it.
+func (t *WebsocketTransport) prefetchCertificate() {
+	t.autoTLSPrefetch.Do(func() {
+		go func() {
+			hello := &tls.ClientHelloInfo{ServerName: t.autoTLSDomain}
+			if _, err := t.autoTLS.GetCertificate(hello); err != nil {
+				log.Warnw("failed to obtain TLS certificate", "domain", t.autoTLSDomain, "error", err)
+			}
+		}()
+	})
+}
@@ -83,7 +83,7 @@ func newListener(a ma.Multiaddr, tlsConf *tls.Config, sharedTcp *tcpreuse.ConnMg
 		if parsed.isWSS {
 			connType = tcpreuse.DemultiplexedConnType_TLS
 		} else {
-			connType = tcpreuse.DemultiplexedConnType_HTTP
+			connType = tcpreuse.DemultiplexedConnType_WebSocket
 		}
 		gmal, err = sharedTcp.DemultiplexedListen(parsed.restMultiaddr, connType)
 		if err != nil {
@@ -4,7 +4,9 @@ package websocket
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"net"
+	"sync"
 	"time"
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
@@ -17,6 +19,7 @@ import (
 	manet "github.com/multiformats/go-multiaddr/net"
 
 	ws "github.com/gorilla/websocket"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 // WsFmt is multiaddr formatter for WsProtocol
@@ -90,6 +93,10 @@ type WebsocketTransport struct {
 	tlsConf          *tls.Config
 	sharedTcp        *tcpreuse.ConnMgr
 	handshakeTimeout time.Duration
+
+	autoTLS         *autocert.Manager
+	autoTLSDomain   string
+	autoTLSPrefetch sync.Once
 }
 
 var _ transport.Transport = (*WebsocketTransport)(nil)
@@ -110,6 +117,12 @@ func New(u transport.Upgrader, rcmgr network.ResourceManager, sharedTCP *tcpreus
 			return nil, err
 		}
 	}
+	if t.autoTLS != nil {
+		if t.tlsConf != nil {
+			return nil, errors.New("cannot use auto TLS with a TLS config")
+		}
+		t.tlsConf = autoTLSConfig(t.autoTLS)
+	}
 	return t, nil
 }
 
@@ -250,11 +263,14 @@ func (t *WebsocketTransport) gatedMaListen(a ma.Multiaddr) (transport.GatedMaLis
 	if t.tlsConf != nil {
 		tlsConf = t.tlsConf.Clone()
 	}
-	l, err := newListener(a, tlsConf, t.sharedTcp, t.upgrader, t.handshakeTimeout)
+	l, err := newListener(t.addAutoTLSSNI(a), tlsConf, t.sharedTcp, t.upgrader, t.handshakeTimeout)
 	if err != nil {
 		return nil, err
 	}
 	go l.serve()
+	if t.autoTLS != nil && l.isWss {
+		t.prefetchCertificate()
+	}
 	return l, nil
 }
 
@@ -37,6 +37,8 @@ import (
 	ma "github.com/multiformats/go-multiaddr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
 )
 
 func newUpgrader(t *testing.T) (peer.ID, transport.Upgrader) {
@@ -740,3 +742,29 @@ func TestHandshakeTimeout(t *testing.T) {
 		}
 	})
 }
+
+func TestAutoTLS(t *testing.T) {
+	_, upgrader := newUpgrader(t)
+	m := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist("example.com"),
+		// don't contact a real CA
+		Client: &acme.Client{DirectoryURL: "http://127.0.0.1:1/directory"},
+	}
+	_, err := New(upgrader, &network.NullResourceManager{}, nil, WithAutoTLS(m, "example.com"), WithTLSConfig(generateTLSConfig(t)))
+	require.Error(t, err)
+
+	tpt, err := New(upgrader, &network.NullResourceManager{}, nil, WithAutoTLS(m, "example.com"))
+	require.NoError(t, err)
+	require.NotContains(t, tpt.tlsConf.NextProtos, "h2")
+
+	l, err := tpt.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0/tls/ws"))
+	require.NoError(t, err)
+	defer l.Close()
+	require.Regexp(t, `^/ip4/127\.0\.0\.1/tcp/\d+/tls/sni/example\.com/ws$`, l.Multiaddr().String())
+
+	l, err = tpt.Listen(ma.StringCast("/ip4/127.0.0.1/tcp/0/ws"))
+	require.NoError(t, err)
+	defer l.Close()
+	require.Regexp(t, `^/ip4/127\.0\.0\.1/tcp/\d+/ws$`, l.Multiaddr().String())
+}
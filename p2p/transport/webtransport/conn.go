@@ -2,6 +2,7 @@ package libp2pwebtransport
 
 import (
 	"context"
+	"errors"
 
 	"github.com/TheNoobiCat/go-libp2p/core/network"
 	tpt "github.com/TheNoobiCat/go-libp2p/core/transport"
@@ -50,7 +51,7 @@ func newConn(tr *transport, sess *webtransport.Session, sconn *connSecurityMulti
 func (c *conn) OpenStream(ctx context.Context) (network.MuxedStream, error) {
 	str, err := c.session.OpenStreamSync(ctx)
 	if err != nil {
-		return nil, err
+		return nil, parseSessionError(err)
 	}
 	return &stream{str}, nil
 }
@@ -58,11 +59,26 @@ func (c *conn) OpenStream(ctx context.Context) (network.MuxedStream, error) {
 func (c *conn) AcceptStream() (network.MuxedStream, error) {
 	str, err := c.session.AcceptStream(context.Background())
 	if err != nil {
-		return nil, err
+		return nil, parseSessionError(err)
 	}
 	return &stream{str}, nil
 }
 
+// parseSessionError converts the error the session was closed with to a
+// network.ConnError.
+func parseSessionError(err error) error {
+	se := &webtransport.SessionError{}
+	if errors.As(err, &se) {
+		return &network.ConnError{
+			Remote:         se.Remote,
+			ErrorCode:      network.ConnErrorCode(se.ErrorCode),
+			Reason:         se.Message,
+			TransportError: err,
+		}
+	}
+	return err
+}
+
 func (c *conn) allowWindowIncrease(size uint64) bool {
 	return c.scope.ReserveMemory(int(size), network.ReservationPriorityMedium) == nil
 }
@@ -71,17 +87,19 @@ func (c *conn) allowWindowIncrease(size uint64) bool {
 // It must be called even if the peer closed the connection in order for
 // garbage collection to properly work in this package.
 func (c *conn) Close() error {
+	return c.CloseWithError(network.ConnNoError)
+}
+
+// CloseWithError closes the session with errCode. The errCode is sent to the peer
+// in the session close capsule.
+func (c *conn) CloseWithError(errCode network.ConnErrorCode) error {
 	defer c.scope.Done()
 	c.transport.removeConn(c.qconn)
-	err := c.session.CloseWithError(0, "")
+	err := c.session.CloseWithError(webtransport.SessionErrorCode(errCode), "")
 	_ = c.qconn.CloseWithError(1, "")
 	return err
 }
 
-func (c *conn) CloseWithError(_ network.ConnErrorCode) error {
-	return c.Close()
-}
-
 func (c *conn) IsClosed() bool           { return c.session.Context().Err() != nil }
 func (c *conn) Scope() network.ConnScope { return c.scope }
 func (c *conn) Transport() tpt.Transport { return c.transport }
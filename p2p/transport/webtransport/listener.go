@@ -219,6 +219,7 @@ func (l *listener) httpHandlerWithConnScope(w http.ResponseWriter, r *http.Reque
 		sess.CloseWithError(1, "")
 		return err
 	}
+	l.transport.connManager.SetConnPeer(qconn, sconn.RemotePeer())
 
 	conn := newConn(l.transport, sess, sconn, connScope, qconn)
 	l.transport.addConn(qconn, conn)
@@ -178,6 +178,7 @@ func (t *transport) dialWithScope(ctx context.Context, raddr ma.Multiaddr, p pee
 		qconn.CloseWithError(1, "")
 		return nil, err
 	}
+	t.connManager.SetConnPeer(qconn, p)
 	if t.gater != nil && !t.gater.InterceptSecured(network.DirOutbound, p, sconn) {
 		sess.CloseWithError(errorCodeConnectionGating, "")
 		qconn.CloseWithError(errorCodeConnectionGating, "")
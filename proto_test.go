@@ -10,8 +10,10 @@ import (
 	_ "github.com/TheNoobiCat/go-libp2p/core/peer/pb"
 	_ "github.com/TheNoobiCat/go-libp2p/core/record/pb"
 	_ "github.com/TheNoobiCat/go-libp2p/core/sec/insecure/pb"
+	_ "github.com/TheNoobiCat/go-libp2p/p2p/daemon/pb"
 	_ "github.com/TheNoobiCat/go-libp2p/p2p/host/autonat/pb"
 	_ "github.com/TheNoobiCat/go-libp2p/p2p/host/peerstore/pstoreds/pb"
+	_ "github.com/TheNoobiCat/go-libp2p/p2p/http/pb"
 	_ "github.com/TheNoobiCat/go-libp2p/p2p/protocol/autonatv2/pb"
 	_ "github.com/TheNoobiCat/go-libp2p/p2p/protocol/circuitv2/pb"
 	_ "github.com/TheNoobiCat/go-libp2p/p2p/protocol/holepunch/pb"
@@ -17,6 +17,8 @@ proto_array=(
   p2p/protocol/autonatv2/pb/autonatv2.proto
   p2p/protocol/holepunch/pb/holepunch.proto
   p2p/host/peerstore/pstoreds/pb/pstore.proto
+  p2p/daemon/pb/daemon.proto
+  p2p/http/pb/wellknown.proto
 )
 
 proto_paths=""
@@ -0,0 +1,68 @@
+package rate
+
+import (
+	"github.com/TheNoobiCat/go-libp2p/p2p/metricshelper"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const metricNamespace = "libp2p_rate"
+
+var (
+	peerRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: metricNamespace,
+			Name:      "peer_requests_total",
+			Help:      "Requests checked against per peer rate limits",
+		},
+		[]string{"service", "outcome"},
+	)
+	collectors = []prometheus.Collector{
+		peerRequestsTotal,
+	}
+)
+
+// MetricsTracer tracks the decisions made by rate limiters.
+type MetricsTracer interface {
+	// PeerRequest is called for every request checked by a PeerLimiter.
+	PeerRequest(service string, allowed bool)
+}
+
+type metricsTracer struct{}
+
+var _ MetricsTracer = &metricsTracer{}
+
+type metricsTracerSetting struct {
+	reg prometheus.Registerer
+}
+
+type MetricsTracerOption func(*metricsTracerSetting)
+
+func WithRegisterer(reg prometheus.Registerer) MetricsTracerOption {
+	return func(s *metricsTracerSetting) {
+		if reg != nil {
+			s.reg = reg
+		}
+	}
+}
+
+func NewMetricsTracer(opts ...MetricsTracerOption) MetricsTracer {
+	setting := &metricsTracerSetting{reg: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt(setting)
+	}
+	metricshelper.RegisterCollectors(setting.reg, collectors...)
+	return &metricsTracer{}
+}
+
+func (m *metricsTracer) PeerRequest(service string, allowed bool) {
+	tags := metricshelper.GetStringSlice()
+	defer metricshelper.PutStringSlice(tags)
+
+	outcome := "allowed"
+	if !allowed {
+		outcome = "refused"
+	}
+	*tags = append(*tags, service, outcome)
+	peerRequestsTotal.WithLabelValues(*tags...).Inc()
+}
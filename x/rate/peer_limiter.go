@@ -0,0 +1,112 @@
+package rate
+
+import (
+	"sync"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/network"
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+	"golang.org/x/time/rate"
+)
+
+// defaultPeerGracePeriod is the GracePeriod used by PeerLimiter when none is set.
+const defaultPeerGracePeriod = time.Minute
+
+// PeerLimiter rate limits requests per peer. Every peer gets its own token bucket
+// configured by PeerLimit. Use 0 for no rate limiting.
+//
+// Buckets of peers that have been idle long enough for their bucket to refill are
+// removed, so the limiter's state is bounded by the number of recently active peers.
+type PeerLimiter struct {
+	// PeerLimit is the limit for every peer.
+	PeerLimit Limit
+	// GracePeriod is the time to wait to remove a full capacity bucket.
+	// Defaults to one minute.
+	GracePeriod time.Duration
+	// Service is the name of the service being rate limited. It is only used for metrics.
+	Service string
+	// MetricsTracer, if set, is notified about allowed and refused requests.
+	MetricsTracer MetricsTracer
+
+	initOnce    sync.Once
+	mx          sync.Mutex
+	buckets     map[peer.ID]*peerBucketWithExpiry
+	nextCleanup time.Time
+}
+
+type peerBucketWithExpiry struct {
+	tokenBucket
+	Expiry time.Time
+}
+
+func (l *PeerLimiter) init() {
+	l.initOnce.Do(func() {
+		if l.GracePeriod <= 0 {
+			l.GracePeriod = defaultPeerGracePeriod
+		}
+		l.buckets = make(map[peer.ID]*peerBucketWithExpiry)
+	})
+}
+
+// Limit rate limits a StreamHandler function. Streams from peers that exceed their
+// limit are reset with network.StreamRateLimited.
+func (l *PeerLimiter) Limit(f func(s network.Stream)) func(s network.Stream) {
+	l.init()
+	return func(s network.Stream) {
+		if !l.Allow(s.Conn().RemotePeer()) {
+			_ = s.ResetWithError(network.StreamRateLimited)
+			return
+		}
+		f(s)
+	}
+}
+
+// Allow returns true if requests from `p` are within the per peer rate limit.
+func (l *PeerLimiter) Allow(p peer.ID) bool {
+	allowed := l.allow(p, time.Now())
+	if l.MetricsTracer != nil {
+		l.MetricsTracer.PeerRequest(l.Service, allowed)
+	}
+	return allowed
+}
+
+func (l *PeerLimiter) allow(p peer.ID, now time.Time) bool {
+	l.init()
+	if l.PeerLimit.RPS == 0 {
+		return true
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	l.cleanUp(now)
+
+	b, ok := l.buckets[p]
+	if !ok {
+		b = &peerBucketWithExpiry{
+			tokenBucket: tokenBucket{rate.NewLimiter(rate.Limit(l.PeerLimit.RPS), l.PeerLimit.Burst)},
+		}
+		l.buckets[p] = b
+	}
+	if !b.AllowN(now, 1) {
+		// bucket is empty, its expiry would have been set correctly the last time
+		// it allowed a request.
+		return false
+	}
+	b.Expiry = b.FullAt(now).Add(l.GracePeriod)
+	return true
+}
+
+// cleanUp removes buckets that have expired by now. It scans all buckets at most
+// once per GracePeriod.
+func (l *PeerLimiter) cleanUp(now time.Time) {
+	if now.Before(l.nextCleanup) {
+		return
+	}
+	l.nextCleanup = now.Add(l.GracePeriod)
+	for p, b := range l.buckets {
+		if !b.Expiry.After(now) {
+			delete(l.buckets, p)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package rate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/TheNoobiCat/go-libp2p/core/peer"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingTracer struct {
+	allowed, refused int
+}
+
+func (c *countingTracer) PeerRequest(_ string, allowed bool) {
+	if allowed {
+		c.allowed++
+	} else {
+		c.refused++
+	}
+}
+
+func TestPeerLimiter(t *testing.T) {
+	p1, p2 := peer.ID("peer1"), peer.ID("peer2")
+	tr := &countingTracer{}
+	l := &PeerLimiter{PeerLimit: Limit{RPS: 1, Burst: 3}, MetricsTracer: tr}
+	for range 3 {
+		require.True(t, l.Allow(p1))
+	}
+	require.False(t, l.Allow(p1))
+	// other peers have their own bucket
+	require.True(t, l.Allow(p2))
+	require.Equal(t, 4, tr.allowed)
+	require.Equal(t, 1, tr.refused)
+}
+
+func TestPeerLimiterNoLimit(t *testing.T) {
+	l := &PeerLimiter{}
+	for range 1000 {
+		require.True(t, l.Allow("peer"))
+	}
+}
+
+func TestPeerLimiterCleanup(t *testing.T) {
+	l := &PeerLimiter{PeerLimit: Limit{RPS: 1, Burst: 10}, GracePeriod: time.Second}
+	now := time.Now()
+	for range 10 {
+		require.True(t, l.allow("peer1", now))
+	}
+	require.True(t, l.allow("peer2", now))
+	require.Len(t, l.buckets, 2)
+
+	// peer2's bucket is full after 1s, peer1's after 10s
+	now = now.Add(5 * time.Second)
+	require.True(t, l.allow("peer3", now))
+	require.Len(t, l.buckets, 2)
+	require.Contains(t, l.buckets, peer.ID("peer1"))
+
+	now = now.Add(10 * time.Second)
+	require.True(t, l.allow("peer3", now))
+	require.Len(t, l.buckets, 1)
+}